@@ -1,29 +1,66 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/order-api-microservices/api-gateway/internal/gateway"
+	"github.com/order-api-microservices/pkg/cache"
+	"github.com/order-api-microservices/pkg/chaos"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/grpcserver"
+	"github.com/order-api-microservices/pkg/requestid"
+	analyticsPb "github.com/order-api-microservices/proto/analytics"
+	auditPb "github.com/order-api-microservices/proto/audit"
+	blockchainPb "github.com/order-api-microservices/proto/blockchain"
+	bulkOrderPb "github.com/order-api-microservices/proto/bulkorder"
+	chaosPb "github.com/order-api-microservices/proto/chaos"
+	chatPb "github.com/order-api-microservices/proto/chat"
+	contactPb "github.com/order-api-microservices/proto/contact"
+	denylistPb "github.com/order-api-microservices/proto/denylist"
+	dispatchPb "github.com/order-api-microservices/proto/dispatch"
+	disputePb "github.com/order-api-microservices/proto/dispute"
+	feePb "github.com/order-api-microservices/proto/fee"
+	incidentPb "github.com/order-api-microservices/proto/incident"
+	jobsPb "github.com/order-api-microservices/proto/jobs"
+	loyaltyPb "github.com/order-api-microservices/proto/loyalty"
+	merchantPb "github.com/order-api-microservices/proto/merchant"
+	notificationPb "github.com/order-api-microservices/proto/notification"
+	operationsPb "github.com/order-api-microservices/proto/operations"
 	orderPb "github.com/order-api-microservices/proto/order"
+	paymentMethodPb "github.com/order-api-microservices/proto/paymentmethod"
+	privacyPb "github.com/order-api-microservices/proto/privacy"
+	providerPb "github.com/order-api-microservices/proto/provider"
+	serviceAreaPb "github.com/order-api-microservices/proto/servicearea"
+	supportPb "github.com/order-api-microservices/proto/support"
+	timelinePb "github.com/order-api-microservices/proto/timeline"
+	trackingPb "github.com/order-api-microservices/proto/tracking"
+	userProviderPb "github.com/order-api-microservices/proto/userprovider"
+	walletPb "github.com/order-api-microservices/proto/wallet"
+	webhookPb "github.com/order-api-microservices/proto/webhook"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 var (
-	port        = flag.Int("port", 8080, "The server port")
-	configFile  = flag.String("config", "config.yaml", "Configuration file path")
-	orderSvc    = flag.String("order-svc", "", "Order service address")
-	userSvc     = flag.String("user-svc", "", "User service address")
-	paymentSvc  = flag.String("payment-svc", "", "Payment service address")
-	providerSvc = flag.String("provider-svc", "", "Provider service address")
+	port            = flag.Int("port", 8080, "The server port")
+	configFile      = flag.String("config", "config.yaml", "Configuration file path")
+	orderSvc        = flag.String("order-svc", "", "Order service address")
+	userSvc         = flag.String("user-svc", "", "User service address")
+	paymentSvc      = flag.String("payment-svc", "", "Payment service address")
+	providerSvc     = flag.String("provider-svc", "", "Provider service address")
+	blockchainSvc   = flag.String("blockchain-svc", "", "Blockchain service address")
+	notificationSvc = flag.String("notification-svc", "", "Notification service address")
+	openAPICheck    = flag.Bool("openapi-check", false, "Verify the OpenAPI document matches the registered routes and exit")
 )
 
 func main() {
@@ -32,33 +69,242 @@ func main() {
 	// Load configuration
 	initConfig()
 
+	// Inject faults into calls to the services only when enabled, and then only once they
+	// are set through the admin API
+	var faultInjector *chaos.Injector
+	var connOpts []grpc.DialOption
+	if viper.GetBool("chaos.enabled") {
+		faultInjector = chaos.NewInjector()
+		connOpts = append(connOpts, faultInjector.DialOption(), faultInjector.StreamDialOption())
+		log.Printf("Fault injection enabled")
+	}
+
 	// Create gRPC connections
-	orderConn, err := createGRPCConnection("services.order")
+	orderConn, err := createGRPCConnection("services.order", connOpts...)
 	if err != nil {
 		log.Fatalf("Failed to connect to order service: %v", err)
 	}
 	defer orderConn.Close()
 
+	providerConn, err := createGRPCConnection("services.provider", connOpts...)
+	if err != nil {
+		log.Fatalf("Failed to connect to provider service: %v", err)
+	}
+	defer providerConn.Close()
+
+	blockchainConn, err := createGRPCConnection("services.blockchain", connOpts...)
+	if err != nil {
+		log.Fatalf("Failed to connect to blockchain service: %v", err)
+	}
+	defer blockchainConn.Close()
+
+	notificationConn, err := createGRPCConnection("services.notification", connOpts...)
+	if err != nil {
+		log.Fatalf("Failed to connect to notification service: %v", err)
+	}
+	defer notificationConn.Close()
+
 	// Create gRPC clients
 	orderClient := orderPb.NewOrderServiceClient(orderConn)
+	providerClient := providerPb.NewProviderServiceClient(providerConn)
+	blockchainClient := blockchainPb.NewBlockchainServiceClient(blockchainConn)
+	inboxClient := notificationPb.NewNotificationInboxServiceClient(notificationConn)
+	campaignClient := notificationPb.NewNotificationCampaignServiceClient(notificationConn)
+	deviceClient := notificationPb.NewNotificationDeviceServiceClient(notificationConn)
+	disputeClient := disputePb.NewDisputeServiceClient(orderConn)                   // Disputes are served by the order service
+	feeClient := feePb.NewFeeServiceClient(orderConn)                               // So is the fee schedule
+	dispatchClient := dispatchPb.NewDispatchServiceClient(orderConn)                // And dispatch scoring
+	chatClient := chatPb.NewChatServiceClient(orderConn)                            // And chat
+	contactClient := contactPb.NewContactServiceClient(orderConn)                   // And contact tokens
+	incidentClient := incidentPb.NewIncidentServiceClient(orderConn)                // And SOS incidents
+	trackingClient := trackingPb.NewTrackingLinkServiceClient(orderConn)            // And tracking links
+	timelineClient := timelinePb.NewOrderTimelineServiceClient(orderConn)           // And order timelines
+	serviceAreaClient := serviceAreaPb.NewServiceAreaServiceClient(orderConn)       // And service areas
+	merchantClient := merchantPb.NewMerchantServiceClient(orderConn)                // And merchants' menus
+	userProviderClient := userProviderPb.NewUserProviderServiceClient(orderConn)    // And users' favorite and blocked providers
+	paymentMethodClient := paymentMethodPb.NewPaymentMethodServiceClient(orderConn) // And users' saved payment methods
+	walletClient := walletPb.NewWalletServiceClient(orderConn)                      // And users' wallets
+	loyaltyClient := loyaltyPb.NewLoyaltyServiceClient(orderConn)                   // And referrals and loyalty points
+	privacyClient := privacyPb.NewPrivacyServiceClient(orderConn)                   // And data export and erasure requests
+	webhookClient := webhookPb.NewWebhookServiceClient(orderConn)                   // And partners' webhooks
+	denylistClient := denylistPb.NewDenylistServiceClient(orderConn)                // And the users, API keys and networks denied access
+	supportClient := supportPb.NewSupportAccessServiceClient(orderConn)             // And admins' support sessions
+	bulkOrderClient := bulkOrderPb.NewBulkOrderServiceClient(orderConn)             // And bulk order imports
+	analyticsClient := analyticsPb.NewAnalyticsServiceClient(orderConn)             // And the daily order analytics
+	operationsClient := operationsPb.NewOperationsServiceClient(orderConn)          // And the operations dashboard's live counters
+	jobClient := jobsPb.NewJobServiceClient(orderConn)                              // And its background jobs
+
+	// Each service keeps its own audit log
+	auditClients := map[string]auditPb.AuditServiceClient{
+		gateway.AuditServiceOrder:    auditPb.NewAuditServiceClient(orderConn),
+		gateway.AuditServiceProvider: auditPb.NewAuditServiceClient(providerConn),
+	}
+
+	// As does its fault injection, when enabled on it
+	chaosClients := map[string]chaosPb.ChaosServiceClient{
+		gateway.ChaosServiceOrder:    chaosPb.NewChaosServiceClient(orderConn),
+		gateway.ChaosServiceProvider: chaosPb.NewChaosServiceClient(providerConn),
+	}
+
+	// Create the response cache, if enabled
+	var cacheConfig cache.Config
+	if err := viper.UnmarshalKey("cache", &cacheConfig); err != nil {
+		log.Fatalf("Failed to parse cache configuration: %v", err)
+	}
+	cacheStore, err := cache.NewStore(context.Background(), cacheConfig)
+	if err != nil {
+		log.Fatalf("Failed to create cache: %v", err)
+	}
+	responseCache := gateway.NewResponseCache(cacheStore, map[string]time.Duration{
+		gateway.CacheRouteGetOrder:       viper.GetDuration("cache.routes.get_order"),
+		gateway.CacheRouteGetProvider:    viper.GetDuration("cache.routes.get_provider"),
+		gateway.CacheRouteListUserOrders: viper.GetDuration("cache.routes.list_user_orders"),
+	})
+	if responseCache != nil {
+		log.Printf("Response caching enabled (%s)", cacheConfig.Backend)
+	}
 
 	// Create API handlers
-	orderHandler := gateway.NewOrderHandler(orderClient)
+	orderHandler := gateway.NewOrderHandler(orderClient, responseCache)
+	providerHandler := gateway.NewProviderHandler(providerClient, responseCache)
+	orderDetailsHandler := gateway.NewOrderDetailsHandler(orderClient, providerClient, blockchainClient)
+	disputeHandler := gateway.NewDisputeHandler(disputeClient, orderClient, responseCache)
+	feeHandler := gateway.NewFeeHandler(feeClient)
+	dispatchHandler := gateway.NewDispatchHandler(dispatchClient)
+	chatHandler := gateway.NewChatHandler(chatClient)
+	dispatchChannelHandler := gateway.NewDispatchChannelHandler(orderClient, providerClient)
+	contactHandler := gateway.NewContactHandler(contactClient)
+	incidentHandler := gateway.NewIncidentHandler(incidentClient, orderClient, responseCache)
+	trackingHandler := gateway.NewTrackingHandler(trackingClient)
+	timelineHandler := gateway.NewTimelineHandler(timelineClient)
+	serviceAreaHandler := gateway.NewServiceAreaHandler(serviceAreaClient)
+	merchantHandler := gateway.NewMerchantHandler(merchantClient)
+	userProviderHandler := gateway.NewUserProviderHandler(userProviderClient)
+	paymentMethodHandler := gateway.NewPaymentMethodHandler(paymentMethodClient)
+	walletHandler := gateway.NewWalletHandler(walletClient)
+	loyaltyHandler := gateway.NewLoyaltyHandler(loyaltyClient)
+	privacyHandler := gateway.NewPrivacyHandler(privacyClient)
+	webhookHandler := gateway.NewWebhookHandler(webhookClient)
+	bulkOrderHandler := gateway.NewBulkOrderHandler(bulkOrderClient)
+	analyticsHandler := gateway.NewAnalyticsHandler(analyticsClient)
+	operationsHandler := gateway.NewOperationsHandler(operationsClient)
+	auditHandler := gateway.NewAuditHandler(auditClients)
+	chaosHandler := gateway.NewChaosHandler(faultInjector, chaosClients)
+	jobHandler := gateway.NewJobHandler(jobClient)
+	notificationHandler := gateway.NewNotificationHandler(inboxClient, campaignClient, deviceClient)
+
+	// Maintenance mode starts as configured and is switched at runtime through the admin API
+	maintenance := gateway.NewMaintenance(viper.GetBool("maintenance.enabled"), viper.GetString("maintenance.message"), viper.GetDuration("maintenance.retry_after"))
+
+	// Banned users, API keys and networks are loaded from the order service once serving
+	denylist := gateway.NewDenylist(denylistClient)
+
+	// Admins act on behalf of users through support sessions
+	supportAccess := gateway.NewSupportAccess(supportClient)
 
 	// Create Gin router
-	router := gin.Default()
+	router := gin.New()
+
+	// Take the client's address from X-Forwarded-For only when a trusted proxy sent it, so
+	// clients cannot spoof the address the denylist and the audit logs see
+	if err := router.SetTrustedProxies(viper.GetStringSlice("http.trusted_proxies")); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+
+	// Give every request an ID, logged with it and passed on to the services
+	router.Use(gateway.RequestID(), gin.LoggerWithFormatter(gateway.LogFormatter), gin.Recovery())
+
+	// Make requests that carry a support token as the session's user
+	router.Use(supportAccess.Middleware())
+
+	// Pass the client's address and actor on to the services' audit logs, with the admin
+	// behind requests made in a support session
+	router.Use(gateway.ForwardClientMetadata())
+
+	// Refuse banned users, API keys and networks, recording each request refused
+	router.Use(denylist.Middleware())
+
+	// Allow cross-origin requests only from the configured origins
+	if origins := viper.GetStringSlice("cors.allow_origins"); len(origins) > 0 {
+		corsConfig := cors.Config{
+			AllowMethods:     viper.GetStringSlice("cors.allow_methods"),
+			AllowHeaders:     viper.GetStringSlice("cors.allow_headers"),
+			ExposeHeaders:    []string{"Content-Length", requestid.Header},
+			AllowCredentials: viper.GetBool("cors.allow_credentials"),
+			MaxAge:           viper.GetDuration("cors.max_age"),
+		}
+		if len(origins) == 1 && origins[0] == "*" {
+			// Browsers refuse credentials for any origin
+			corsConfig.AllowAllOrigins = true
+			corsConfig.AllowCredentials = false
+		} else {
+			corsConfig.AllowOrigins = origins
+		}
+		router.Use(cors.New(corsConfig))
+	}
+
+	// Harden responses, cap request bodies and compress what is sent back
+	router.Use(gateway.SecurityHeaders(viper.GetDuration("http.hsts_max_age")))
+	router.Use(gateway.LimitRequestBody(viper.GetInt64("http.max_body_bytes")))
+	if viper.GetBool("http.gzip") {
+		router.Use(gateway.Compress())
+	}
 
-	// Configure CORS
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
+	// Refuse changes while in maintenance mode
+	router.Use(maintenance.Middleware())
 
-	// Register API routes
-	orderHandler.RegisterRoutes(router)
+	// Register API routes for every version; versions differ only in response shape
+	v1 := gateway.NewV1()
+	if viper.GetBool("api.v1.deprecated") {
+		v1.Deprecate(viper.GetTime("api.v1.sunset"), "/api/v2")
+	}
+	for _, version := range []*gateway.APIVersion{v1, gateway.NewV2()} {
+		api := version.Group(router)
+		orderHandler.RegisterRoutes(api)
+		orderDetailsHandler.RegisterRoutes(api)
+		providerHandler.RegisterRoutes(api)
+		disputeHandler.RegisterRoutes(api)
+		feeHandler.RegisterRoutes(api)
+		dispatchHandler.RegisterRoutes(api)
+		chatHandler.RegisterRoutes(api)
+		dispatchChannelHandler.RegisterRoutes(api)
+		contactHandler.RegisterRoutes(api)
+		incidentHandler.RegisterRoutes(api)
+		trackingHandler.RegisterRoutes(api)
+		timelineHandler.RegisterRoutes(api)
+		serviceAreaHandler.RegisterRoutes(api)
+		merchantHandler.RegisterRoutes(api)
+		userProviderHandler.RegisterRoutes(api)
+		paymentMethodHandler.RegisterRoutes(api)
+		walletHandler.RegisterRoutes(api)
+		loyaltyHandler.RegisterRoutes(api)
+		notificationHandler.RegisterRoutes(api)
+		privacyHandler.RegisterRoutes(api)
+		webhookHandler.RegisterRoutes(api)
+		bulkOrderHandler.RegisterRoutes(api)
+		analyticsHandler.RegisterRoutes(api)
+		operationsHandler.RegisterRoutes(api)
+		auditHandler.RegisterRoutes(api)
+		jobHandler.RegisterRoutes(api)
+		chaosHandler.RegisterRoutes(api)
+		maintenance.RegisterRoutes(api)
+		denylist.RegisterRoutes(api)
+		supportAccess.RegisterRoutes(api)
+	}
+	trackingHandler.RegisterPublicRoutes(router)
+	gateway.RegisterSwaggerRoutes(router)
+
+	// Fail the build when the OpenAPI document drifts from the routes
+	if *openAPICheck {
+		if err := gateway.CheckOpenAPIRoutes(router.Routes(), "/api/v1/"); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("OpenAPI document matches registered routes")
+		return
+	}
+
+	// Keep the denylist fresh
+	go denylist.Run(context.Background(), viper.GetDuration("denylist.refresh_interval"))
 
 	// Add health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -96,6 +342,36 @@ func initConfig() {
 	viper.SetDefault("services.user", "localhost:50052")
 	viper.SetDefault("services.payment", "localhost:50054")
 	viper.SetDefault("services.provider", "localhost:50055")
+	viper.SetDefault("services.blockchain", "localhost:50052")
+	viper.SetDefault("services.notification", "localhost:50054")
+	viper.SetDefault("api.v1.deprecated", false)
+	viper.SetDefault("cache.backend", "")
+	viper.SetDefault("cache.redis_addr", "localhost:6379")
+	viper.SetDefault("cache.routes.get_order", "5s")
+	viper.SetDefault("cache.routes.get_provider", "30s")
+	viper.SetDefault("cache.routes.list_user_orders", "5s")
+	viper.SetDefault("cors.allow_origins", []string{})
+	viper.SetDefault("cors.allow_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
+	viper.SetDefault("cors.allow_headers", []string{"Origin", "Content-Type", "Accept", "Authorization", gateway.ActorIDHeader, gateway.APIKeyHeader, gateway.SupportTokenHeader, requestid.Header})
+	viper.SetDefault("cors.allow_credentials", true)
+	viper.SetDefault("cors.max_age", "12h")
+	viper.BindEnv("cors.allow_origins", "CORS_ALLOW_ORIGINS")
+	viper.SetDefault("http.hsts_max_age", "8760h")
+	viper.SetDefault("http.max_body_bytes", 1<<20)
+	viper.SetDefault("http.gzip", true)
+	viper.SetDefault("http.trusted_proxies", []string{})
+	viper.BindEnv("http.trusted_proxies", "TRUSTED_PROXIES")
+	viper.SetDefault("chaos.enabled", false)
+	viper.BindEnv("chaos.enabled", "CHAOS_ENABLED")
+	viper.SetDefault("maintenance.enabled", false)
+	viper.BindEnv("maintenance.enabled", "MAINTENANCE_MODE")
+	viper.SetDefault("maintenance.message", "")
+	viper.SetDefault("maintenance.retry_after", "5m")
+	viper.SetDefault("denylist.refresh_interval", "30s")
+	viper.SetDefault("grpc.auth_token", "")
+	viper.BindEnv("grpc.auth_token", "GRPC_AUTH_TOKEN")
+	viper.SetDefault("grpc.client_reserve", "200ms")
+	viper.BindEnv("grpc.client_reserve", "GRPC_CLIENT_RESERVE")
 
 	viper.SetConfigFile(*configFile)
 	viper.AutomaticEnv()
@@ -106,7 +382,7 @@ func initConfig() {
 	}
 }
 
-func createGRPCConnection(configKey string) (*grpc.ClientConn, error) {
+func createGRPCConnection(configKey string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	serviceAddr := viper.GetString(configKey)
 
 	// Override from command line if provided
@@ -127,11 +403,26 @@ func createGRPCConnection(configKey string) (*grpc.ClientConn, error) {
 		if *providerSvc != "" {
 			serviceAddr = *providerSvc
 		}
+	case "services.blockchain":
+		if *blockchainSvc != "" {
+			serviceAddr = *blockchainSvc
+		}
+	case "services.notification":
+		if *notificationSvc != "" {
+			serviceAddr = *notificationSvc
+		}
 	}
 
 	if serviceAddr == "" {
 		return nil, fmt.Errorf("service address not configured for %s", configKey)
 	}
 
-	return grpc.Dial(serviceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	return grpc.Dial(serviceAddr, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpcserver.WithToken(viper.GetString("grpc.auth_token")),
+		requestid.DialOption(),
+		requestid.StreamDialOption(),
+		// Handlers set each request's deadline; calls keep back time to write the response
+		deadline.DialOption(deadline.Timeouts{Reserve: viper.GetDuration("grpc.client_reserve")}),
+	}, opts...)...)
 } 
\ No newline at end of file
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	analyticsPb "github.com/order-api-microservices/proto/analytics"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AnalyticsHandler handles the admin dashboard endpoints for the daily order aggregates
+type AnalyticsHandler struct {
+	analyticsClient analyticsPb.AnalyticsServiceClient
+}
+
+// NewAnalyticsHandler creates a new analytics handler
+func NewAnalyticsHandler(analyticsClient analyticsPb.AnalyticsServiceClient) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsClient: analyticsClient,
+	}
+}
+
+// RegisterRoutes registers the analytics API routes on a version group
+func (h *AnalyticsHandler) RegisterRoutes(api *gin.RouterGroup) {
+	analytics := api.Group("/admin/analytics")
+	{
+		analytics.GET("/daily", h.GetDailyMetrics)
+		analytics.GET("/cancellation-reasons", h.ListCancellationReasons)
+		analytics.GET("/sla", h.GetSLAAttainment)
+	}
+}
+
+// GetDailyMetrics gets order counts, completion rate, pickup ETA error and revenue per
+// day, city and order type
+func (h *AnalyticsHandler) GetDailyMetrics(c *gin.Context) {
+	// Call the analytics service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.analyticsClient.GetDailyMetrics(ctx, &analyticsPb.GetDailyMetricsRequest{
+		From:      c.Query("from"),
+		To:        c.Query("to"),
+		City:      c.Query("city"),
+		OrderType: c.Query("order_type"),
+		GroupBy:   c.Query("group_by"),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to get daily metrics")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListCancellationReasons gets the reasons orders were cancelled for, most given first
+func (h *AnalyticsHandler) ListCancellationReasons(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Call the analytics service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.analyticsClient.ListCancellationReasons(ctx, &analyticsPb.ListCancellationReasonsRequest{
+		From:      c.Query("from"),
+		To:        c.Query("to"),
+		City:      c.Query("city"),
+		OrderType: c.Query("order_type"),
+		Limit:     int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list cancellation reasons")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetSLAAttainment gets how often each stage of an order finished within its SLA
+func (h *AnalyticsHandler) GetSLAAttainment(c *gin.Context) {
+	// Call the analytics service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.analyticsClient.GetSLAAttainment(ctx, &analyticsPb.GetSLAAttainmentRequest{
+		From:      c.Query("from"),
+		To:        c.Query("to"),
+		City:      c.Query("city"),
+		OrderType: c.Query("order_type"),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to get SLA attainment")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleError maps an analytics service error to an HTTP response
+func (h *AnalyticsHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
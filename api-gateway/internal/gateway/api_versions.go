@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	pb "github.com/order-api-microservices/proto/order"
+)
+
+// NewV1 returns the original API; orders are returned as the raw protobuf messages
+func NewV1() *APIVersion {
+	return NewAPIVersion("v1").
+		Transform(ResourceOrderList, orderListV1)
+}
+
+// NewV2 returns v2 of the API: enum names instead of numbers, RFC 3339 timestamps,
+// grouped pricing and payment fields, and paginated list envelopes
+func NewV2() *APIVersion {
+	return NewAPIVersion("v2").
+		Transform(ResourceOrder, orderV2).
+		Transform(ResourceOrderList, orderListV2)
+}
+
+func orderListV1(payload interface{}) interface{} {
+	resp, ok := payload.(*pb.ListOrdersResponse)
+	if !ok {
+		return payload
+	}
+	return gin.H{
+		"orders": resp.Orders,
+		"total":  resp.Total,
+		"page":   resp.Page,
+		"limit":  resp.Limit,
+	}
+}
+
+// OrderV2 is the v2 representation of an order
+type OrderV2 struct {
+	ID                  string                 `json:"id"`
+	UserID              string                 `json:"user_id"`
+	ProviderID          string                 `json:"provider_id,omitempty"`
+	Type                string                 `json:"type"`
+	Status              string                 `json:"status"`
+	PickupLocation      *pb.Location           `json:"pickup_location"`
+	DestinationLocation *pb.Location           `json:"destination_location"`
+	Items               []*pb.OrderItem        `json:"items"`
+	SizeClass           string                 `json:"size_class,omitempty"` // Package deliveries only
+	Vehicle             *pb.OrderVehicle       `json:"vehicle,omitempty"`    // The vehicle the provider accepted the order in
+	Pricing             OrderPricingV2         `json:"pricing"`
+	Payment             OrderPaymentV2         `json:"payment"`
+	BlockchainTxHash    string                 `json:"blockchain_tx_hash,omitempty"`
+	DeliveryProofHash   string                 `json:"delivery_proof_hash,omitempty"`
+	Frozen              bool                   `json:"frozen,omitempty"`
+	AtRisk              bool                   `json:"at_risk,omitempty"`
+	Notes               string                 `json:"notes,omitempty"`
+	CreatedAt           time.Time              `json:"created_at"`
+	UpdatedAt           time.Time              `json:"updated_at"`
+	StatusHistory       []OrderStatusHistoryV2 `json:"status_history"`
+}
+
+// OrderPricingV2 groups an order's price breakdown, in minor units
+type OrderPricingV2 struct {
+	Total           int64 `json:"total"`
+	PlatformFee     int64 `json:"platform_fee"`
+	ProviderFee     int64 `json:"provider_fee"`
+	Tip             int64 `json:"tip"`
+	CancellationFee int64 `json:"cancellation_fee,omitempty"`
+	PointsDiscount  int64 `json:"points_discount,omitempty"` // Taken off the total for redeemed loyalty points
+}
+
+// OrderPaymentV2 groups an order's payment fields
+type OrderPaymentV2 struct {
+	Method         string             `json:"method"`
+	TransactionID  string             `json:"transaction_id,omitempty"`
+	Shares         []*pb.PaymentShare `json:"shares,omitempty"`
+	WalletAmount   int64              `json:"wallet_amount,omitempty"` // Part of the total paid from the user's wallet
+	PointsRedeemed int64              `json:"points_redeemed,omitempty"`
+}
+
+// OrderStatusHistoryV2 is a status change in v2 form
+type OrderStatusHistoryV2 struct {
+	Status    string    `json:"status"`
+	UpdatedBy string    `json:"updated_by"`
+	Notes     string    `json:"notes,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func orderV2(payload interface{}) interface{} {
+	order, ok := payload.(*pb.Order)
+	if !ok || order == nil {
+		return payload
+	}
+	return toOrderV2(order)
+}
+
+func toOrderV2(order *pb.Order) OrderV2 {
+	history := make([]OrderStatusHistoryV2, 0, len(order.StatusHistory))
+	for _, h := range order.StatusHistory {
+		history = append(history, OrderStatusHistoryV2{
+			Status:    strings.TrimPrefix(h.Status.String(), "ORDER_STATUS_"),
+			UpdatedBy: h.UpdatedBy,
+			Notes:     h.Notes,
+			Timestamp: h.Timestamp.AsTime(),
+		})
+	}
+
+	return OrderV2{
+		ID:                  order.Id,
+		UserID:              order.UserId,
+		ProviderID:          order.ProviderId,
+		Type:                strings.TrimPrefix(order.OrderType.String(), "ORDER_TYPE_"),
+		Status:              strings.TrimPrefix(order.Status.String(), "ORDER_STATUS_"),
+		PickupLocation:      order.PickupLocation,
+		DestinationLocation: order.DestinationLocation,
+		Items:               order.Items,
+		SizeClass:           order.SizeClass,
+		Vehicle:             order.Vehicle,
+		Pricing: OrderPricingV2{
+			Total:           order.TotalPrice,
+			PlatformFee:     order.PlatformFee,
+			ProviderFee:     order.ProviderFee,
+			Tip:             order.TipAmount,
+			CancellationFee: order.CancellationFee,
+			PointsDiscount:  order.PointsDiscount,
+		},
+		Payment: OrderPaymentV2{
+			Method:         strings.TrimPrefix(order.PaymentMethod.String(), "PAYMENT_METHOD_"),
+			TransactionID:  order.TransactionId,
+			Shares:         order.PaymentShares,
+			WalletAmount:   order.WalletAmount,
+			PointsRedeemed: order.PointsRedeemed,
+		},
+		BlockchainTxHash:  order.BlockchainTxHash,
+		DeliveryProofHash: order.DeliveryProofHash,
+		Frozen:            order.Frozen,
+		AtRisk:            order.AtRisk,
+		Notes:             order.Notes,
+		CreatedAt:         order.CreatedAt.AsTime(),
+		UpdatedAt:         order.UpdatedAt.AsTime(),
+		StatusHistory:     history,
+	}
+}
+
+func orderListV2(payload interface{}) interface{} {
+	resp, ok := payload.(*pb.ListOrdersResponse)
+	if !ok {
+		return payload
+	}
+
+	orders := make([]OrderV2, 0, len(resp.Orders))
+	for _, order := range resp.Orders {
+		orders = append(orders, toOrderV2(order))
+	}
+
+	return gin.H{
+		"data": orders,
+		"pagination": gin.H{
+			"total": resp.Total,
+			"page":  resp.Page,
+			"limit": resp.Limit,
+		},
+	}
+}
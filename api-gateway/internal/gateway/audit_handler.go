@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	auditPb "github.com/order-api-microservices/proto/audit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Services whose audit logs can be queried
+const (
+	AuditServiceOrder    = "order"
+	AuditServiceProvider = "provider"
+)
+
+// AuditHandler handles the admin API endpoints for the services' audit logs
+type AuditHandler struct {
+	auditClients map[string]auditPb.AuditServiceClient
+}
+
+// NewAuditHandler creates a new audit handler from each service's audit client
+func NewAuditHandler(auditClients map[string]auditPb.AuditServiceClient) *AuditHandler {
+	return &AuditHandler{
+		auditClients: auditClients,
+	}
+}
+
+// RegisterRoutes registers the audit API routes on a version group
+func (h *AuditHandler) RegisterRoutes(api *gin.RouterGroup) {
+	auditLog := api.Group("/admin/audit")
+	{
+		auditLog.GET("", h.ListAuditEntries)
+		auditLog.GET("/verify", h.VerifyAuditChain)
+	}
+}
+
+// ListAuditEntries lists a service's recorded calls, newest first
+func (h *AuditHandler) ListAuditEntries(c *gin.Context) {
+	client, ok := h.client(c)
+	if !ok {
+		return
+	}
+
+	request := &auditPb.ListAuditEntriesRequest{
+		ActorId:          c.Query("actor_id"),
+		ResourceType:     c.Query("resource_type"),
+		ResourceId:       c.Query("resource_id"),
+		Method:           c.Query("method"),
+		SupportSessionId: c.Query("support_session_id"),
+	}
+	if request.From, ok = parseTimeQuery(c, "from"); !ok {
+		return
+	}
+	if request.To, ok = parseTimeQuery(c, "to"); !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	request.Page = int32(page)
+	request.Limit = int32(limit)
+
+	// Call the audit service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := client.ListAuditEntries(ctx, request)
+	if err != nil {
+		h.handleError(c, err, "Failed to list audit entries")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// VerifyAuditChain checks a service's hash-chained audit entries for tampering
+func (h *AuditHandler) VerifyAuditChain(c *gin.Context) {
+	client, ok := h.client(c)
+	if !ok {
+		return
+	}
+
+	// Verification reads the whole log, so it gets longer than other calls
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	resp, err := client.VerifyAuditChain(ctx, &auditPb.VerifyAuditChainRequest{})
+	if err != nil {
+		h.handleError(c, err, "Failed to verify audit chain")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// client picks the audit client of the service named by the service query parameter,
+// the order service by default
+func (h *AuditHandler) client(c *gin.Context) (auditPb.AuditServiceClient, bool) {
+	service := c.DefaultQuery("service", AuditServiceOrder)
+	client, ok := h.auditClients[service]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "service must be order or provider"})
+		return nil, false
+	}
+	return client, true
+}
+
+// parseTimeQuery parses an optional RFC 3339 query parameter, responding with 400 when
+// it is malformed
+func parseTimeQuery(c *gin.Context, param string) (*timestamppb.Timestamp, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, true
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 time"})
+		return nil, false
+	}
+	return timestamppb.New(t), true
+}
+
+// handleError maps an audit service error to an HTTP response
+func (h *AuditHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
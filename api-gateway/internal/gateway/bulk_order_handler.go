@@ -0,0 +1,355 @@
+package gateway
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	bulkOrderPb "github.com/order-api-microservices/proto/bulkorder"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBulkUploadBytes caps the size of a bulk order upload
+const maxBulkUploadBytes = 10 << 20
+
+// bulkCSVRequiredColumns must be in the header of a CSV upload
+var bulkCSVRequiredColumns = []string{
+	"user_id", "order_type", "payment_method",
+	"pickup_latitude", "pickup_longitude", "destination_latitude", "destination_longitude",
+}
+
+// bulkCSVOptionalColumns may be in the header of a CSV upload. Items are a JSON array
+// in the same format as the items of a single order.
+var bulkCSVOptionalColumns = []string{
+	"pickup_address", "pickup_postal_code", "pickup_city", "pickup_country",
+	"destination_address", "destination_postal_code", "destination_city", "destination_country",
+	"notes", "rental_hours", "items",
+}
+
+// BulkOrderHandler handles the API endpoints for importing batches of orders
+type BulkOrderHandler struct {
+	bulkOrderClient bulkOrderPb.BulkOrderServiceClient
+}
+
+// NewBulkOrderHandler creates a new bulk order handler
+func NewBulkOrderHandler(bulkOrderClient bulkOrderPb.BulkOrderServiceClient) *BulkOrderHandler {
+	return &BulkOrderHandler{
+		bulkOrderClient: bulkOrderClient,
+	}
+}
+
+// RegisterRoutes registers the bulk order API routes on a version group
+func (h *BulkOrderHandler) RegisterRoutes(api *gin.RouterGroup) {
+	bulk := api.Group("/orders/bulk")
+	{
+		bulk.POST("", h.SubmitBulkOrders)
+		bulk.GET("/:job_id", h.GetBulkOrderJob)
+		bulk.GET("/:job_id/rows", h.ListBulkOrderRows)
+	}
+}
+
+// SubmitBulkOrders accepts a batch of orders as a JSON array, a CSV body or a CSV file
+// uploaded as the "file" form field. Every row is validated on its own: invalid rows are
+// reported in the job's results and do not stop the others from being imported.
+func (h *BulkOrderHandler) SubmitBulkOrders(c *gin.Context) {
+	customerID := c.Query("customer_id")
+	if customerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id is required"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkUploadBytes)
+
+	var source string
+	var rows []*bulkOrderPb.SubmittedOrder
+	var err error
+	switch c.ContentType() {
+	case "application/json":
+		source = "JSON"
+		rows, err = parseBulkJSON(c.Request.Body)
+	case "text/csv":
+		source = "CSV"
+		rows, err = parseBulkCSV(c.Request.Body)
+	case "multipart/form-data":
+		source = "CSV"
+		file, formErr := c.FormFile("file")
+		if formErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a CSV file is required in the file field"})
+			return
+		}
+		upload, openErr := file.Open()
+		if openErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read uploaded file"})
+			return
+		}
+		defer upload.Close()
+		rows, err = parseBulkCSV(upload)
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "upload orders as application/json, text/csv or multipart/form-data"})
+		return
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("upload is larger than %d bytes", maxBulkUploadBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call the bulk order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.bulkOrderClient.SubmitBulkOrders(ctx, &bulkOrderPb.SubmitBulkOrdersRequest{
+		CustomerId: customerID,
+		Source:     source,
+		Rows:       rows,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to submit bulk orders")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, resp.Job)
+}
+
+// GetBulkOrderJob gets a bulk import and how many of its rows have each result
+func (h *BulkOrderHandler) GetBulkOrderJob(c *gin.Context) {
+	jobID := c.Param("job_id")
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "job ID is required"})
+		return
+	}
+
+	// Call the bulk order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.bulkOrderClient.GetBulkOrderJob(ctx, &bulkOrderPb.GetBulkOrderJobRequest{
+		JobId: jobID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to get bulk order job")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Job)
+}
+
+// ListBulkOrderRows lists the result of each row of a bulk import
+func (h *BulkOrderHandler) ListBulkOrderRows(c *gin.Context) {
+	jobID := c.Param("job_id")
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "job ID is required"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	// Call the bulk order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.bulkOrderClient.ListBulkOrderRows(ctx, &bulkOrderPb.ListBulkOrderRowsRequest{
+		JobId:  jobID,
+		Status: c.Query("status"),
+		Page:   int32(page),
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list bulk order rows")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleError maps a bulk order service error to an HTTP response
+func (h *BulkOrderHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// parseBulkJSON reads a JSON array of orders, each in the format of a single order
+func parseBulkJSON(body io.Reader) ([]*bulkOrderPb.SubmittedOrder, error) {
+	var elements []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&elements); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("body must be a JSON array of orders: %v", err)
+	}
+
+	rows := make([]*bulkOrderPb.SubmittedOrder, 0, len(elements))
+	for i, element := range elements {
+		var request CreateOrderRequest
+		var errs []string
+		if err := json.Unmarshal(element, &request); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				errs = []string{fmt.Sprintf("%s: must be of type %s", typeErr.Field, typeErr.Type.String())}
+			} else {
+				errs = []string{err.Error()}
+			}
+		} else {
+			errs = validationErrors(&request)
+		}
+		rows = append(rows, newSubmittedOrder(i+1, &request, errs))
+	}
+
+	return rows, nil
+}
+
+// parseBulkCSV reads orders from CSV with a header row naming the columns
+func parseBulkCSV(body io.Reader) ([]*bulkOrderPb.SubmittedOrder, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV upload is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	known := make(map[string]bool)
+	for _, name := range append(bulkCSVRequiredColumns, bulkCSVOptionalColumns...) {
+		known[name] = true
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("CSV column %q appears more than once", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range bulkCSVRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV column %q is required", name)
+		}
+	}
+
+	var rows []*bulkOrderPb.SubmittedOrder
+	for rowNumber := 1; ; rowNumber++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		rows = append(rows, parseBulkCSVRecord(rowNumber, record, len(header), columns))
+	}
+
+	return rows, nil
+}
+
+// parseBulkCSVRecord converts one CSV record to an order and validates it
+func parseBulkCSVRecord(rowNumber int, record []string, fields int, columns map[string]int) *bulkOrderPb.SubmittedOrder {
+	if len(record) != fields {
+		return newSubmittedOrder(rowNumber, nil, []string{fmt.Sprintf("row has %d fields, the header has %d", len(record), fields)})
+	}
+
+	var errs []string
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	number := func(name string) *float64 {
+		value := field(name)
+		if value == "" {
+			return nil
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			errs = append(errs, name+": must be a number")
+			return nil
+		}
+		return &parsed
+	}
+
+	request := CreateOrderRequest{
+		UserID:        field("user_id"),
+		OrderType:     strings.ToUpper(field("order_type")),
+		PaymentMethod: strings.ToUpper(field("payment_method")),
+		Notes:         field("notes"),
+		PickupLocation: &LocationRequest{
+			Latitude:   number("pickup_latitude"),
+			Longitude:  number("pickup_longitude"),
+			Address:    field("pickup_address"),
+			PostalCode: field("pickup_postal_code"),
+			City:       field("pickup_city"),
+			Country:    field("pickup_country"),
+		},
+		DestinationLocation: &LocationRequest{
+			Latitude:   number("destination_latitude"),
+			Longitude:  number("destination_longitude"),
+			Address:    field("destination_address"),
+			PostalCode: field("destination_postal_code"),
+			City:       field("destination_city"),
+			Country:    field("destination_country"),
+		},
+	}
+	if value := field("rental_hours"); value != "" {
+		hours, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			errs = append(errs, "rental_hours: must be a whole number")
+		}
+		request.RentalHours = int32(hours)
+	}
+	if value := field("items"); value != "" {
+		if err := json.Unmarshal([]byte(value), &request.Items); err != nil {
+			errs = append(errs, "items: must be a JSON array of items")
+		}
+	}
+
+	if len(errs) == 0 {
+		errs = validationErrors(&request)
+	}
+	return newSubmittedOrder(rowNumber, &request, errs)
+}
+
+// newSubmittedOrder builds a row of an upload: the order if it is valid, or its errors
+func newSubmittedOrder(rowNumber int, request *CreateOrderRequest, errs []string) *bulkOrderPb.SubmittedOrder {
+	row := &bulkOrderPb.SubmittedOrder{
+		RowNumber: int32(rowNumber),
+		Errors:    errs,
+	}
+	if len(errs) == 0 {
+		row.Order = convertCreateOrderFromRequest(request)
+	}
+	return row
+}
@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/order-api-microservices/pkg/cache"
+	"github.com/order-api-microservices/pkg/requestid"
+	pb "github.com/order-api-microservices/proto/order"
+)
+
+// Cacheable routes; each can be given its own TTL
+const (
+	CacheRouteGetOrder       = "get_order"
+	CacheRouteGetProvider    = "get_provider"
+	CacheRouteListUserOrders = "list_user_orders"
+)
+
+// ResponseCache caches successful GET responses for read-heavy routes.
+// A nil *ResponseCache is valid and caches nothing.
+type ResponseCache struct {
+	store cache.Store
+	ttls  map[string]time.Duration // Per-route TTL; routes without a TTL are not cached
+}
+
+// NewResponseCache creates a response cache; it returns nil when store is nil
+func NewResponseCache(store cache.Store, ttls map[string]time.Duration) *ResponseCache {
+	if store == nil {
+		return nil
+	}
+	return &ResponseCache{
+		store: store,
+		ttls:  ttls,
+	}
+}
+
+// cacheKeyFunc builds the cache key for a request; an empty key skips the cache
+type cacheKeyFunc func(c *gin.Context) string
+
+func orderCacheKey(orderID string) string {
+	return "gw:order:" + orderID + ":"
+}
+
+func providerCacheKey(providerID string) string {
+	return "gw:provider:" + providerID + ":"
+}
+
+func userOrdersCachePrefix(userID string) string {
+	return "gw:user_orders:" + userID + ":"
+}
+
+// Middleware serves the route from cache when possible and caches 200 responses otherwise
+func (rc *ResponseCache) Middleware(route string, keyFunc cacheKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rc == nil || rc.ttls[route] <= 0 {
+			c.Next()
+			return
+		}
+
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		// Versions shape responses differently, so each gets its own entry; keys end in ":"
+		if v := apiVersion(c); v != nil {
+			key += v.Name
+		}
+
+		body, found, err := rc.store.Get(c.Request.Context(), key)
+		if err != nil {
+			requestid.Logf(c.Request.Context(), "Cache read failed for %s: %v", key, err)
+		}
+		if found {
+			c.Header("X-Cache", "HIT")
+			c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+			c.Abort()
+			return
+		}
+
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Header("X-Cache", "MISS")
+		c.Next()
+
+		if writer.Status() == http.StatusOK {
+			if err := rc.store.Set(c.Request.Context(), key, writer.body.Bytes(), rc.ttls[route]); err != nil {
+				requestid.Logf(c.Request.Context(), "Cache write failed for %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// InvalidateOrder drops cached responses that include the order
+func (rc *ResponseCache) InvalidateOrder(ctx context.Context, order *pb.Order) {
+	if rc == nil || order == nil {
+		return
+	}
+
+	if err := rc.store.DeletePrefix(ctx, orderCacheKey(order.Id)); err != nil {
+		requestid.Logf(ctx, "Cache invalidation failed for order %s: %v", order.Id, err)
+	}
+	if order.UserId != "" {
+		if err := rc.store.DeletePrefix(ctx, userOrdersCachePrefix(order.UserId)); err != nil {
+			requestid.Logf(ctx, "Cache invalidation failed for user %s orders: %v", order.UserId, err)
+		}
+	}
+}
+
+// InvalidateProvider drops the cached provider profile
+func (rc *ResponseCache) InvalidateProvider(ctx context.Context, providerID string) {
+	if rc == nil || providerID == "" {
+		return
+	}
+
+	if err := rc.store.DeletePrefix(ctx, providerCacheKey(providerID)); err != nil {
+		requestid.Logf(ctx, "Cache invalidation failed for provider %s: %v", providerID, err)
+	}
+}
+
+// capturingWriter copies the response body so it can be cached
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
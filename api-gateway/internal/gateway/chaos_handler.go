@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/order-api-microservices/pkg/chaos"
+	chaosPb "github.com/order-api-microservices/proto/chaos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Services whose calls to other services faults can be injected into
+const (
+	ChaosServiceGateway  = "gateway"
+	ChaosServiceOrder    = "order"
+	ChaosServiceProvider = "provider"
+)
+
+// ChaosHandler handles the admin API endpoints that inject faults into the calls the
+// gateway and the services behind it make to other services
+type ChaosHandler struct {
+	local        *chaos.Server // nil unless fault injection is enabled on the gateway
+	chaosClients map[string]chaosPb.ChaosServiceClient
+}
+
+// NewChaosHandler creates a new chaos handler from the gateway's own fault injector, nil
+// when fault injection is not enabled on it, and each service's chaos client
+func NewChaosHandler(injector *chaos.Injector, chaosClients map[string]chaosPb.ChaosServiceClient) *ChaosHandler {
+	h := &ChaosHandler{
+		chaosClients: chaosClients,
+	}
+	if injector != nil {
+		h.local = chaos.NewServer(injector)
+	}
+	return h
+}
+
+// RegisterRoutes registers the chaos API routes on a version group
+func (h *ChaosHandler) RegisterRoutes(api *gin.RouterGroup) {
+	faults := api.Group("/admin/chaos")
+	{
+		faults.GET("", h.GetFaults)
+		faults.PUT("", h.SetFaults)
+	}
+}
+
+// GetFaults returns the faults a service injects into its calls
+func (h *ChaosHandler) GetFaults(c *gin.Context) {
+	service := c.DefaultQuery("service", ChaosServiceGateway)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var resp *chaosPb.Faults
+	var err error
+	if service == ChaosServiceGateway {
+		if h.local == nil {
+			h.notEnabled(c, service)
+			return
+		}
+		resp, err = h.local.GetFaults(ctx, &chaosPb.GetFaultsRequest{})
+	} else {
+		client, ok := h.client(c, service)
+		if !ok {
+			return
+		}
+		resp, err = client.GetFaults(ctx, &chaosPb.GetFaultsRequest{})
+	}
+	if err != nil {
+		h.handleError(c, err, service, "Failed to get faults")
+		return
+	}
+
+	c.JSON(http.StatusOK, newChaosFaultsResponse(resp))
+}
+
+// SetFaults replaces the faults a service injects into its calls
+func (h *ChaosHandler) SetFaults(c *gin.Context) {
+	service := c.DefaultQuery("service", ChaosServiceGateway)
+
+	var request ChaosFaultsRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	setRequest := &chaosPb.SetFaultsRequest{
+		Faults: &chaosPb.Faults{
+			Enabled:     *request.Enabled,
+			LatencyMs:   request.LatencyMs,
+			JitterMs:    request.JitterMs,
+			LatencyRate: request.LatencyRate,
+			ErrorRate:   request.ErrorRate,
+			ErrorCode:   request.ErrorCode,
+			Methods:     request.Methods,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var resp *chaosPb.Faults
+	var err error
+	if service == ChaosServiceGateway {
+		if h.local == nil {
+			h.notEnabled(c, service)
+			return
+		}
+		resp, err = h.local.SetFaults(ctx, setRequest)
+	} else {
+		client, ok := h.client(c, service)
+		if !ok {
+			return
+		}
+		resp, err = client.SetFaults(ctx, setRequest)
+	}
+	if err != nil {
+		h.handleError(c, err, service, "Failed to set faults")
+		return
+	}
+
+	c.JSON(http.StatusOK, newChaosFaultsResponse(resp))
+}
+
+// client picks the chaos client of a service
+func (h *ChaosHandler) client(c *gin.Context, service string) (chaosPb.ChaosServiceClient, bool) {
+	client, ok := h.chaosClients[service]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "service must be gateway, order or provider"})
+		return nil, false
+	}
+	return client, true
+}
+
+// notEnabled responds that fault injection is not enabled on a service
+func (h *ChaosHandler) notEnabled(c *gin.Context, service string) {
+	c.JSON(http.StatusNotFound, gin.H{"error": "fault injection is not enabled on " + service})
+}
+
+// handleError maps a chaos service error to an HTTP response
+func (h *ChaosHandler) handleError(c *gin.Context, err error, service, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.Unimplemented:
+		// Services serve the chaos API only when fault injection is enabled on them
+		h.notEnabled(c, service)
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.Unavailable:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// chaosFaultsResponse is the faults a service injects into its calls
+type chaosFaultsResponse struct {
+	Enabled     bool     `json:"enabled"`
+	LatencyMs   int64    `json:"latency_ms"`
+	JitterMs    int64    `json:"jitter_ms"`
+	LatencyRate float64  `json:"latency_rate"`
+	ErrorRate   float64  `json:"error_rate"`
+	ErrorCode   string   `json:"error_code"`
+	Methods     []string `json:"methods"`
+}
+
+// newChaosFaultsResponse converts faults for an API response
+func newChaosFaultsResponse(faults *chaosPb.Faults) chaosFaultsResponse {
+	methods := faults.Methods
+	if methods == nil {
+		methods = []string{}
+	}
+	return chaosFaultsResponse{
+		Enabled:     faults.Enabled,
+		LatencyMs:   faults.LatencyMs,
+		JitterMs:    faults.JitterMs,
+		LatencyRate: faults.LatencyRate,
+		ErrorRate:   faults.ErrorRate,
+		ErrorCode:   faults.ErrorCode,
+		Methods:     methods,
+	}
+}
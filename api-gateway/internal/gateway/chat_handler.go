@@ -0,0 +1,273 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	chatPb "github.com/order-api-microservices/proto/chat"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chatUpgrader upgrades chat connections to WebSockets. Any origin is accepted, matching
+// the gateway's CORS policy.
+var chatUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// chatFrame is a message exchanged with a chat WebSocket client
+type chatFrame struct {
+	Type    string              `json:"type"` // message, read or error
+	Body    string              `json:"body,omitempty"`
+	Message *chatPb.ChatMessage `json:"message,omitempty"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// ChatHandler handles the chat between an order's user and provider
+type ChatHandler struct {
+	chatClient chatPb.ChatServiceClient
+}
+
+// NewChatHandler creates a new chat handler
+func NewChatHandler(chatClient chatPb.ChatServiceClient) *ChatHandler {
+	return &ChatHandler{
+		chatClient: chatClient,
+	}
+}
+
+// RegisterRoutes registers the chat API routes on a version group
+func (h *ChatHandler) RegisterRoutes(api *gin.RouterGroup) {
+	orders := api.Group("/orders")
+	{
+		orders.GET("/:id/chat/messages", h.ListMessages)
+		orders.POST("/:id/chat/messages", h.SendMessage)
+		orders.POST("/:id/chat/read", h.MarkRead)
+		orders.GET("/:id/chat/ws", h.Connect) // WebSocket bridge to StreamMessages
+	}
+}
+
+// ListMessages lists an order's chat history, newest first
+func (h *ChatHandler) ListMessages(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	// Call the chat service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.chatClient.ListMessages(ctx, &chatPb.ListMessagesRequest{
+		OrderId:       orderID,
+		ParticipantId: c.Query("participant_id"),
+		Page:          int32(page),
+		Limit:         int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list messages")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// SendMessage sends a message to the other party of an active order
+func (h *ChatHandler) SendMessage(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request SendChatMessageRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the chat service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.chatClient.SendMessage(ctx, &chatPb.SendMessageRequest{
+		OrderId:  orderID,
+		SenderId: request.SenderID,
+		Body:     request.Body,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to send message")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.Message)
+}
+
+// MarkRead marks the messages the other party sent so far as read
+func (h *ChatHandler) MarkRead(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request MarkChatReadRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the chat service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.chatClient.MarkRead(ctx, &chatPb.MarkReadRequest{
+		OrderId:       orderID,
+		ParticipantId: request.ParticipantID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to mark messages read")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": resp.Success,
+		"marked":  resp.Marked,
+	})
+}
+
+// Connect bridges a WebSocket to the order's chat. The client sends message and read
+// frames; the gateway sends each new or newly read message as a message frame. The
+// socket closes once the chat closes.
+func (h *ChatHandler) Connect(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+	participantID := c.Query("participant_id")
+
+	// Check access before upgrading so errors are plain HTTP responses
+	checkCtx, checkCancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	_, err := h.chatClient.ListMessages(checkCtx, &chatPb.ListMessagesRequest{
+		OrderId:       orderID,
+		ParticipantId: participantID,
+		Limit:         1,
+	})
+	checkCancel()
+	if err != nil {
+		h.handleError(c, err, "Failed to open chat")
+		return
+	}
+
+	// Subscribe before upgrading so no message sent in between is missed
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	stream, err := h.chatClient.StreamMessages(ctx, &chatPb.StreamMessagesRequest{
+		OrderId:       orderID,
+		ParticipantId: participantID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to open chat")
+		return
+	}
+
+	conn, err := chatUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		return
+	}
+	defer conn.Close()
+
+	// A WebSocket allows one writer at a time
+	var writeMu sync.Mutex
+	write := func(frame chatFrame) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(frame)
+	}
+
+	// Relay client frames to the chat service until the client goes away
+	go func() {
+		defer cancel()
+		for {
+			var frame chatFrame
+			if err := conn.ReadJSON(&frame); err != nil {
+				return
+			}
+
+			var err error
+			reqCtx, reqCancel := context.WithTimeout(ctx, 10*time.Second)
+			switch frame.Type {
+			case "message":
+				_, err = h.chatClient.SendMessage(reqCtx, &chatPb.SendMessageRequest{
+					OrderId:  orderID,
+					SenderId: participantID,
+					Body:     frame.Body,
+				})
+			case "read":
+				_, err = h.chatClient.MarkRead(reqCtx, &chatPb.MarkReadRequest{
+					OrderId:       orderID,
+					ParticipantId: participantID,
+				})
+			default:
+				err = status.Errorf(codes.InvalidArgument, "unknown frame type %q", frame.Type)
+			}
+			reqCancel()
+
+			if err != nil {
+				if write(chatFrame{Type: "error", Error: status.Convert(err).Message()}) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	// Relay new messages and read receipts to the client
+	for {
+		message, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		if err := write(chatFrame{Type: "message", Message: message}); err != nil {
+			return
+		}
+	}
+
+	writeMu.Lock()
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "chat closed"))
+	writeMu.Unlock()
+}
+
+// handleError maps a chat service error to an HTTP response
+func (h *ChatHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.PermissionDenied:
+		c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
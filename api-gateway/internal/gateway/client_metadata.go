@@ -0,0 +1,30 @@
+package gateway
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/order-api-microservices/pkg/audit"
+	"google.golang.org/grpc/metadata"
+)
+
+// ActorIDHeader names who is making a request, for the services' audit logs
+const ActorIDHeader = "X-Actor-ID"
+
+// ForwardClientMetadata passes the client's address and the actor header on to the
+// services with every gRPC call a request makes, so their audit logs record who made a
+// change and from where. Requests made in a support session also pass on the admin and
+// the session, so the change is recorded as impersonated.
+func ForwardClientMetadata() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pairs := []string{audit.SourceIPKey, c.ClientIP()}
+		if actorID := c.GetHeader(ActorIDHeader); actorID != "" {
+			pairs = append(pairs, audit.ActorIDKey, actorID)
+		}
+		if session, ok := supportSession(c); ok {
+			pairs = append(pairs, audit.ImpersonatorIDKey, session.AdminId, audit.SupportSessionIDKey, session.Id)
+		}
+
+		ctx := metadata.AppendToOutgoingContext(c.Request.Context(), pairs...)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
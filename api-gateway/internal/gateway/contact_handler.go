@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	contactPb "github.com/order-api-microservices/proto/contact"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ContactHandler issues masked contact tokens so phone numbers never leave the platform
+type ContactHandler struct {
+	contactClient contactPb.ContactServiceClient
+}
+
+// NewContactHandler creates a new contact handler
+func NewContactHandler(contactClient contactPb.ContactServiceClient) *ContactHandler {
+	return &ContactHandler{
+		contactClient: contactClient,
+	}
+}
+
+// RegisterRoutes registers the contact API routes on a version group
+func (h *ContactHandler) RegisterRoutes(api *gin.RouterGroup) {
+	orders := api.Group("/orders")
+	{
+		orders.POST("/:id/contact-token", h.IssueContactToken)
+	}
+}
+
+// IssueContactToken issues a party to an order a token for calling the other party
+func (h *ContactHandler) IssueContactToken(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request IssueContactTokenRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the contact service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.contactClient.IssueContactToken(ctx, &contactPb.IssueContactTokenRequest{
+		OrderId:       orderID,
+		ParticipantId: request.ParticipantID,
+	})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			case codes.PermissionDenied:
+				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+				return
+			case codes.FailedPrecondition:
+				c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue contact token"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.Contact)
+}
@@ -0,0 +1,285 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/order-api-microservices/pkg/requestid"
+	denylistPb "github.com/order-api-microservices/proto/denylist"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// APIKeyHeader carries the API key a partner integration calls with
+const APIKeyHeader = "X-API-Key"
+
+// Kinds of denylist entries
+const (
+	DenylistKindUser   = "USER"
+	DenylistKindAPIKey = "API_KEY"
+	DenylistKindCIDR   = "CIDR"
+)
+
+// deniedNetwork is a CIDR entry parsed for matching
+type deniedNetwork struct {
+	network *net.IPNet
+	entry   *denylistPb.DenylistEntry
+}
+
+// Denylist refuses requests from banned users, API keys and networks with 403. Entries
+// are kept by the order service and managed through the admin API; each gateway instance
+// holds a copy, reloaded periodically and whenever it changes an entry, so it can check
+// requests without a call. Every blocked request is reported back and so recorded in the
+// order service's audit log against the entry that blocked it.
+type Denylist struct {
+	denylistClient denylistPb.DenylistServiceClient
+
+	mu       sync.RWMutex
+	users    map[string]*denylistPb.DenylistEntry // By user ID
+	apiKeys  map[string]*denylistPb.DenylistEntry // By hex SHA-256 of the key
+	networks []deniedNetwork
+}
+
+// NewDenylist creates an empty denylist; it is filled by Refresh
+func NewDenylist(denylistClient denylistPb.DenylistServiceClient) *Denylist {
+	return &Denylist{
+		denylistClient: denylistClient,
+		users:          map[string]*denylistPb.DenylistEntry{},
+		apiKeys:        map[string]*denylistPb.DenylistEntry{},
+	}
+}
+
+// Run reloads the denylist every interval until ctx is done. A failed reload keeps the
+// entries loaded before.
+func (d *Denylist) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Refresh(ctx); err != nil {
+			log.Printf("Failed to reload denylist: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh loads the entries in force from the order service
+func (d *Denylist) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	resp, err := d.denylistClient.ListDenylistEntries(ctx, &denylistPb.ListDenylistEntriesRequest{})
+	if err != nil {
+		return err
+	}
+
+	users := map[string]*denylistPb.DenylistEntry{}
+	apiKeys := map[string]*denylistPb.DenylistEntry{}
+	var networks []deniedNetwork
+	for _, entry := range resp.Entries {
+		switch entry.Kind {
+		case DenylistKindUser:
+			users[entry.Value] = entry
+		case DenylistKindAPIKey:
+			apiKeys[entry.Value] = entry
+		case DenylistKindCIDR:
+			if _, network, err := net.ParseCIDR(entry.Value); err == nil {
+				networks = append(networks, deniedNetwork{network: network, entry: entry})
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.users, d.apiKeys, d.networks = users, apiKeys, networks
+	return nil
+}
+
+// match returns the entry that denies a request, if any. The client's address is only
+// taken from X-Forwarded-For when the request came through a trusted proxy.
+func (d *Denylist) match(c *gin.Context) *denylistPb.DenylistEntry {
+	actorID := c.GetHeader(ActorIDHeader)
+	apiKey := c.GetHeader(APIKeyHeader)
+	ip := net.ParseIP(c.ClientIP())
+
+	// Entries expiring between reloads stop applying on time, without hiding entries of
+	// the other kinds
+	now := time.Now()
+	live := func(entry *denylistPb.DenylistEntry) bool {
+		return entry != nil && (entry.ExpiresAt == nil || now.Before(entry.ExpiresAt.AsTime()))
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if actorID != "" {
+		if entry := d.users[actorID]; live(entry) {
+			return entry
+		}
+	}
+	if apiKey != "" {
+		sum := sha256.Sum256([]byte(apiKey))
+		if entry := d.apiKeys[hex.EncodeToString(sum[:])]; live(entry) {
+			return entry
+		}
+	}
+	if ip != nil {
+		for _, denied := range d.networks {
+			if denied.network.Contains(ip) && live(denied.entry) {
+				return denied.entry
+			}
+		}
+	}
+	return nil
+}
+
+// Middleware refuses requests from denied users, API keys and networks with 403 and
+// records each one. It runs after ForwardClientMetadata, so the record carries the
+// client's address and actor.
+func (d *Denylist) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entry := d.match(c)
+		if entry == nil {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+		_, err := d.denylistClient.RecordBlockedRequest(ctx, &denylistPb.RecordBlockedRequestRequest{
+			DenylistEntryId: entry.Id,
+			Request:         c.Request.Method + " " + c.Request.URL.Path,
+		})
+		if err != nil {
+			requestid.Logf(c.Request.Context(), "Failed to record request blocked by denylist entry %s: %v", entry.Id, err)
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	}
+}
+
+// RegisterRoutes registers the denylist API routes on a version group
+func (d *Denylist) RegisterRoutes(api *gin.RouterGroup) {
+	denylist := api.Group("/admin/denylist")
+	{
+		denylist.GET("", d.ListDenylistEntries)
+		denylist.POST("", d.AddDenylistEntry)
+		denylist.DELETE("/:id", d.RemoveDenylistEntry)
+	}
+}
+
+// ListDenylistEntries lists the denylist, newest entry first
+func (d *Denylist) ListDenylistEntries(c *gin.Context) {
+	// Call the denylist service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := d.denylistClient.ListDenylistEntries(ctx, &denylistPb.ListDenylistEntriesRequest{
+		IncludeExpired: c.Query("include_expired") == "true",
+	})
+	if err != nil {
+		d.handleError(c, err, "Failed to list denylist entries")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// AddDenylistEntry bans a user, API key or network. This gateway instance enforces it at
+// once; others do from their next reload.
+func (d *Denylist) AddDenylistEntry(c *gin.Context) {
+	var request AddDenylistEntryRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	addRequest := &denylistPb.AddDenylistEntryRequest{
+		Kind:      request.Kind,
+		Value:     request.Value,
+		Reason:    request.Reason,
+		CreatedBy: request.CreatedBy,
+	}
+	if request.ExpiresAt != nil {
+		addRequest.ExpiresAt = timestamppb.New(*request.ExpiresAt)
+	}
+
+	// Call the denylist service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := d.denylistClient.AddDenylistEntry(ctx, addRequest)
+	if err != nil {
+		d.handleError(c, err, "Failed to add denylist entry")
+		return
+	}
+	d.refreshAfterChange(c)
+
+	c.JSON(http.StatusCreated, resp.Entry)
+}
+
+// RemoveDenylistEntry lifts a ban. This gateway instance stops enforcing it at once;
+// others do from their next reload.
+func (d *Denylist) RemoveDenylistEntry(c *gin.Context) {
+	entryID := c.Param("id")
+	if entryID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "denylist entry ID is required"})
+		return
+	}
+
+	// Call the denylist service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := d.denylistClient.RemoveDenylistEntry(ctx, &denylistPb.RemoveDenylistEntryRequest{
+		DenylistEntryId: entryID,
+	})
+	if err != nil {
+		d.handleError(c, err, "Failed to remove denylist entry")
+		return
+	}
+	d.refreshAfterChange(c)
+
+	c.Status(http.StatusNoContent)
+}
+
+// refreshAfterChange reloads the denylist once an admin changed it; on failure the
+// change still applies from the next periodic reload
+func (d *Denylist) refreshAfterChange(c *gin.Context) {
+	if err := d.Refresh(c.Request.Context()); err != nil {
+		requestid.Logf(c.Request.Context(), "Failed to reload denylist: %v", err)
+	}
+}
+
+// handleError maps a denylist service error to an HTTP response
+func (d *Denylist) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.AlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	denylistPb "github.com/order-api-microservices/proto/denylist"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeDenylistClient serves a fixed list of entries and takes every blocked request
+type fakeDenylistClient struct {
+	denylistPb.DenylistServiceClient
+	entries []*denylistPb.DenylistEntry
+}
+
+func (f *fakeDenylistClient) ListDenylistEntries(ctx context.Context, req *denylistPb.ListDenylistEntriesRequest, opts ...grpc.CallOption) (*denylistPb.ListDenylistEntriesResponse, error) {
+	return &denylistPb.ListDenylistEntriesResponse{Entries: f.entries}, nil
+}
+
+func (f *fakeDenylistClient) RecordBlockedRequest(ctx context.Context, req *denylistPb.RecordBlockedRequestRequest, opts ...grpc.CallOption) (*denylistPb.RecordBlockedRequestResponse, error) {
+	return &denylistPb.RecordBlockedRequestResponse{}, nil
+}
+
+func TestDenylistMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	expired := timestamppb.New(time.Now().Add(-time.Minute))
+	later := timestamppb.New(time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name       string
+		entries    []*denylistPb.DenylistEntry
+		remoteAddr string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{
+			name:       "address in a denied network",
+			entries:    []*denylistPb.DenylistEntry{{Id: "net", Kind: DenylistKindCIDR, Value: "203.0.113.0/24", ExpiresAt: later}},
+			remoteAddr: "203.0.113.7:4000",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "address outside every denied network",
+			entries:    []*denylistPb.DenylistEntry{{Id: "net", Kind: DenylistKindCIDR, Value: "203.0.113.0/24"}},
+			remoteAddr: "198.51.100.7:4000",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "forwarded address from an untrusted client",
+			entries:    []*denylistPb.DenylistEntry{{Id: "net", Kind: DenylistKindCIDR, Value: "198.51.100.0/24"}},
+			remoteAddr: "203.0.113.7:4000",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.1"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "denied address hidden behind a spoofed forwarded address",
+			entries:    []*denylistPb.DenylistEntry{{Id: "net", Kind: DenylistKindCIDR, Value: "203.0.113.0/24"}},
+			remoteAddr: "203.0.113.7:4000",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.1"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "forwarded address from a trusted proxy",
+			entries:    []*denylistPb.DenylistEntry{{Id: "net", Kind: DenylistKindCIDR, Value: "192.0.2.0/24"}},
+			remoteAddr: "10.0.0.5:4000",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.1"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "expired network",
+			entries:    []*denylistPb.DenylistEntry{{Id: "net", Kind: DenylistKindCIDR, Value: "203.0.113.0/24", ExpiresAt: expired}},
+			remoteAddr: "203.0.113.7:4000",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "expired user in a denied network",
+			entries: []*denylistPb.DenylistEntry{
+				{Id: "user", Kind: DenylistKindUser, Value: "user-1", ExpiresAt: expired},
+				{Id: "net", Kind: DenylistKindCIDR, Value: "203.0.113.0/24"},
+			},
+			remoteAddr: "203.0.113.7:4000",
+			headers:    map[string]string{ActorIDHeader: "user-1"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "expired user",
+			entries:    []*denylistPb.DenylistEntry{{Id: "user", Kind: DenylistKindUser, Value: "user-1", ExpiresAt: expired}},
+			remoteAddr: "203.0.113.7:4000",
+			headers:    map[string]string{ActorIDHeader: "user-1"},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			denylist := NewDenylist(&fakeDenylistClient{entries: tt.entries})
+			if err := denylist.Refresh(context.Background()); err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+
+			router := gin.New()
+			if err := router.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+				t.Fatalf("SetTrustedProxies: %v", err)
+			}
+			router.Use(denylist.Middleware())
+			router.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
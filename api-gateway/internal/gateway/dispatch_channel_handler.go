@@ -0,0 +1,226 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	pb "github.com/order-api-microservices/proto/order"
+	providerPb "github.com/order-api-microservices/proto/provider"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// dispatchFrame is a message exchanged with a provider app over its dispatch channel
+type dispatchFrame struct {
+	Type            string           `json:"type"` // offer, result, ack, answer or error
+	OfferID         string           `json:"offer_id,omitempty"`
+	OrderID         string           `json:"order_id,omitempty"`         // Offers
+	Details         json.RawMessage  `json:"details,omitempty"`          // Offers
+	ExpiresAt       *time.Time       `json:"expires_at,omitempty"`       // Offers
+	Accept          bool             `json:"accept,omitempty"`           // Answers
+	Reason          string           `json:"reason,omitempty"`           // Answers declining an offer
+	CurrentLocation *LocationRequest `json:"current_location,omitempty"` // Answers accepting an offer
+	Success         *bool            `json:"success,omitempty"`          // Results
+	Message         string           `json:"message,omitempty"`          // Results
+	Order           *pb.Order        `json:"order,omitempty"`            // Results of accepted offers
+	Error           string           `json:"error,omitempty"`
+}
+
+// DispatchChannelHandler bridges provider apps' WebSockets to their dispatch channels,
+// on which they are offered orders and answer the offers
+type DispatchChannelHandler struct {
+	orderClient    pb.OrderServiceClient
+	providerClient providerPb.ProviderServiceClient
+}
+
+// NewDispatchChannelHandler creates a new dispatch channel handler
+func NewDispatchChannelHandler(orderClient pb.OrderServiceClient, providerClient providerPb.ProviderServiceClient) *DispatchChannelHandler {
+	return &DispatchChannelHandler{
+		orderClient:    orderClient,
+		providerClient: providerClient,
+	}
+}
+
+// RegisterRoutes registers the dispatch channel routes on a version group
+func (h *DispatchChannelHandler) RegisterRoutes(api *gin.RouterGroup) {
+	providers := api.Group("/providers")
+	{
+		providers.GET("/:id/dispatch/ws", h.Connect) // WebSocket bridge to DispatchChannel
+	}
+}
+
+// Connect bridges a WebSocket to a provider's dispatch channel. The gateway sends each
+// order offered to the provider as an offer frame; the app sends an ack frame once it
+// shows the offer, and an answer frame to accept or decline it, which is answered with a
+// result frame.
+func (h *DispatchChannelHandler) Connect(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	// Check the provider before upgrading so errors are plain HTTP responses
+	checkCtx, checkCancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	_, err := h.providerClient.GetProvider(checkCtx, &providerPb.GetProviderRequest{ProviderId: providerID})
+	checkCancel()
+	if err != nil {
+		h.handleError(c, err, "Failed to open dispatch channel")
+		return
+	}
+
+	// Connect before upgrading so no offer made in between is missed
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	stream, err := h.orderClient.DispatchChannel(ctx)
+	if err != nil {
+		h.handleError(c, err, "Failed to open dispatch channel")
+		return
+	}
+	err = stream.Send(&pb.DispatchClientMessage{
+		Message: &pb.DispatchClientMessage_Connect{
+			Connect: &pb.DispatchConnect{ProviderId: providerID},
+		},
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to open dispatch channel")
+		return
+	}
+
+	conn, err := chatUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		return
+	}
+	defer conn.Close()
+
+	// A WebSocket allows one writer at a time
+	var writeMu sync.Mutex
+	write := func(frame dispatchFrame) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(frame)
+	}
+
+	// Relay the app's acknowledgements and answers until it goes away
+	go func() {
+		defer cancel()
+		for {
+			var frame dispatchFrame
+			if err := conn.ReadJSON(&frame); err != nil {
+				stream.CloseSend()
+				return
+			}
+
+			message, problem := convertDispatchFrame(frame)
+			if problem != "" {
+				if write(dispatchFrame{Type: "error", OfferID: frame.OfferID, Error: problem}) != nil {
+					return
+				}
+				continue
+			}
+			if err := stream.Send(message); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Relay offers and results to the app
+	for {
+		message, err := stream.Recv()
+		if err != nil {
+			break
+		}
+
+		var frame dispatchFrame
+		switch m := message.Message.(type) {
+		case *pb.DispatchServerMessage_Offer:
+			expiresAt := m.Offer.ExpiresAt.AsTime()
+			frame = dispatchFrame{
+				Type:      "offer",
+				OfferID:   m.Offer.OfferId,
+				OrderID:   m.Offer.OrderId,
+				Details:   json.RawMessage(m.Offer.Details),
+				ExpiresAt: &expiresAt,
+			}
+		case *pb.DispatchServerMessage_Result:
+			success := m.Result.Success
+			frame = dispatchFrame{
+				Type:    "result",
+				OfferID: m.Result.OfferId,
+				Success: &success,
+				Message: m.Result.Message,
+				Order:   m.Result.Order,
+			}
+		default:
+			continue
+		}
+		if err := write(frame); err != nil {
+			return
+		}
+	}
+
+	writeMu.Lock()
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "dispatch channel closed"))
+	writeMu.Unlock()
+}
+
+// convertDispatchFrame converts a frame from the app to a dispatch channel message, or
+// says what is wrong with it
+func convertDispatchFrame(frame dispatchFrame) (*pb.DispatchClientMessage, string) {
+	if frame.OfferID == "" {
+		return nil, "offer_id is required"
+	}
+
+	switch frame.Type {
+	case "ack":
+		return &pb.DispatchClientMessage{
+			Message: &pb.DispatchClientMessage_Ack{
+				Ack: &pb.DispatchAck{OfferId: frame.OfferID},
+			},
+		}, ""
+	case "answer":
+		answer := &pb.DispatchAnswer{
+			OfferId: frame.OfferID,
+			Accept:  frame.Accept,
+			Reason:  frame.Reason,
+		}
+		if frame.CurrentLocation != nil {
+			if frame.CurrentLocation.Latitude == nil || frame.CurrentLocation.Longitude == nil {
+				return nil, "current_location needs a latitude and a longitude"
+			}
+			answer.CurrentLocation = convertLocationFromRequest(frame.CurrentLocation)
+		}
+		return &pb.DispatchClientMessage{
+			Message: &pb.DispatchClientMessage_Answer{Answer: answer},
+		}, ""
+	default:
+		return nil, "type must be ack or answer"
+	}
+}
+
+// handleError maps an error opening a dispatch channel to an HTTP response
+func (h *DispatchChannelHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.Unavailable:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dispatchPb "github.com/order-api-microservices/proto/dispatch"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DispatchHandler handles the admin API endpoints for dispatch scoring
+type DispatchHandler struct {
+	dispatchClient dispatchPb.DispatchServiceClient
+}
+
+// NewDispatchHandler creates a new dispatch handler
+func NewDispatchHandler(dispatchClient dispatchPb.DispatchServiceClient) *DispatchHandler {
+	return &DispatchHandler{
+		dispatchClient: dispatchClient,
+	}
+}
+
+// RegisterRoutes registers the dispatch API routes on a version group
+func (h *DispatchHandler) RegisterRoutes(api *gin.RouterGroup) {
+	dispatch := api.Group("/admin/dispatch")
+	{
+		dispatch.GET("/weights", h.GetDispatchWeights)
+		dispatch.PUT("/weights", h.UpdateDispatchWeights)
+		dispatch.GET("/decisions", h.ListDispatchDecisions)
+		dispatch.GET("/demand", h.GetDemandForecast)
+	}
+}
+
+// GetDispatchWeights returns the weights the matcher scores providers with
+func (h *DispatchHandler) GetDispatchWeights(c *gin.Context) {
+	// Call the dispatch service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.dispatchClient.GetDispatchWeights(ctx, &dispatchPb.GetDispatchWeightsRequest{})
+	if err != nil {
+		h.handleError(c, err, "Failed to get dispatch weights")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Weights)
+}
+
+// UpdateDispatchWeights replaces the weights the matcher scores providers with
+func (h *DispatchHandler) UpdateDispatchWeights(c *gin.Context) {
+	var request UpdateDispatchWeightsRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the dispatch service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.dispatchClient.UpdateDispatchWeights(ctx, &dispatchPb.UpdateDispatchWeightsRequest{
+		Weights: &dispatchPb.DispatchWeights{
+			Distance:   request.Distance,
+			Rating:     request.Rating,
+			Idle:       request.Idle,
+			Earnings:   request.Earnings,
+			Favorite:   request.Favorite,
+			Acceptance: request.Acceptance,
+			Completion: request.Completion,
+			OnTime:     request.OnTime,
+		},
+		UpdatedBy: request.UpdatedBy,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to update dispatch weights")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Weights)
+}
+
+// ListDispatchDecisions lists dispatch decisions for auditing, newest first
+func (h *DispatchHandler) ListDispatchDecisions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Call the dispatch service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.dispatchClient.ListDispatchDecisions(ctx, &dispatchPb.ListDispatchDecisionsRequest{
+		OrderId:    c.Query("order_id"),
+		ProviderId: c.Query("provider_id"),
+		Page:       int32(page),
+		Limit:      int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list dispatch decisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetDemandForecast returns the orders expected in a service area during an hour
+func (h *DispatchHandler) GetDemandForecast(c *gin.Context) {
+	req := &dispatchPb.GetDemandForecastRequest{
+		Zone: c.Query("zone"),
+	}
+	if at := c.Query("at"); at != "" {
+		parsed, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 timestamp"})
+			return
+		}
+		req.At = timestamppb.New(parsed)
+	}
+
+	// Call the dispatch service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.dispatchClient.GetDemandForecast(ctx, req)
+	if err != nil {
+		h.handleError(c, err, "Failed to get demand forecast")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleError maps dispatch service errors to HTTP responses
+func (h *DispatchHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,250 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	disputePb "github.com/order-api-microservices/proto/dispute"
+	orderPb "github.com/order-api-microservices/proto/order"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DisputeHandler handles dispute API endpoints for users, providers and admins
+type DisputeHandler struct {
+	disputeClient disputePb.DisputeServiceClient
+	orderClient   orderPb.OrderServiceClient
+	cache         *ResponseCache
+}
+
+// NewDisputeHandler creates a new dispute handler; responseCache may be nil
+func NewDisputeHandler(disputeClient disputePb.DisputeServiceClient, orderClient orderPb.OrderServiceClient, responseCache *ResponseCache) *DisputeHandler {
+	return &DisputeHandler{
+		disputeClient: disputeClient,
+		orderClient:   orderClient,
+		cache:         responseCache,
+	}
+}
+
+// RegisterRoutes registers the dispute API routes on a version group
+func (h *DisputeHandler) RegisterRoutes(api *gin.RouterGroup) {
+	orders := api.Group("/orders")
+	{
+		orders.POST("/:id/disputes", h.OpenDispute)
+		orders.GET("/:id/disputes", h.ListOrderDisputes)
+	}
+
+	disputes := api.Group("/disputes")
+	{
+		disputes.GET("/:id", h.GetDispute)
+		disputes.POST("/:id/evidence", h.AddEvidence)
+	}
+
+	admin := api.Group("/admin/disputes")
+	{
+		admin.GET("", h.ListDisputes)
+		admin.POST("/:id/resolve", h.ResolveDispute)
+	}
+}
+
+// OpenDispute opens a dispute on an order as its user or provider
+func (h *DisputeHandler) OpenDispute(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request OpenDisputeRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the dispute service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.disputeClient.OpenDispute(ctx, &disputePb.OpenDisputeRequest{
+		OrderId:     orderID,
+		OpenedBy:    request.OpenedBy,
+		Role:        request.Role,
+		Reason:      request.Reason,
+		Description: request.Description,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to open dispute")
+		return
+	}
+
+	h.invalidateOrder(ctx, orderID)
+
+	respond(c, http.StatusCreated, ResourceDispute, resp.Dispute)
+}
+
+// ListOrderDisputes lists the disputes raised on an order
+func (h *DisputeHandler) ListOrderDisputes(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	h.listDisputes(c, orderID)
+}
+
+// GetDispute gets a dispute with its evidence and payment hold
+func (h *DisputeHandler) GetDispute(c *gin.Context) {
+	disputeID := c.Param("id")
+	if disputeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dispute ID is required"})
+		return
+	}
+
+	// Call the dispute service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.disputeClient.GetDispute(ctx, &disputePb.GetDisputeRequest{DisputeId: disputeID})
+	if err != nil {
+		h.handleError(c, err, "Failed to get dispute")
+		return
+	}
+
+	respond(c, http.StatusOK, ResourceDispute, resp.Dispute)
+}
+
+// AddEvidence adds a statement or attachment to an open dispute
+func (h *DisputeHandler) AddEvidence(c *gin.Context) {
+	disputeID := c.Param("id")
+	if disputeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dispute ID is required"})
+		return
+	}
+
+	var request AddEvidenceRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the dispute service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.disputeClient.AddEvidence(ctx, &disputePb.AddEvidenceRequest{
+		DisputeId:     disputeID,
+		SubmittedBy:   request.SubmittedBy,
+		Role:          request.Role,
+		Description:   request.Description,
+		AttachmentUrl: request.AttachmentURL,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to add evidence")
+		return
+	}
+
+	respond(c, http.StatusCreated, ResourceDispute, resp.Dispute)
+}
+
+// ListDisputes lists all disputes for admins, optionally filtered by status
+func (h *DisputeHandler) ListDisputes(c *gin.Context) {
+	h.listDisputes(c, "")
+}
+
+// ResolveDispute settles a dispute and its payment hold
+func (h *DisputeHandler) ResolveDispute(c *gin.Context) {
+	disputeID := c.Param("id")
+	if disputeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dispute ID is required"})
+		return
+	}
+
+	var request ResolveDisputeRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the dispute service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.disputeClient.ResolveDispute(ctx, &disputePb.ResolveDisputeRequest{
+		DisputeId:    disputeID,
+		ResolvedBy:   request.ResolvedBy,
+		Resolution:   request.Resolution,
+		RefundAmount: request.RefundAmount,
+		Notes:        request.Notes,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to resolve dispute")
+		return
+	}
+
+	h.invalidateOrder(ctx, resp.Dispute.OrderId)
+
+	respond(c, http.StatusOK, ResourceDispute, resp.Dispute)
+}
+
+func (h *DisputeHandler) listDisputes(c *gin.Context, orderID string) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Call the dispute service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.disputeClient.ListDisputes(ctx, &disputePb.ListDisputesRequest{
+		OrderId: orderID,
+		Status:  c.Query("status"),
+		Page:    int32(page),
+		Limit:   int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list disputes")
+		return
+	}
+
+	respond(c, http.StatusOK, ResourceDisputeList, resp)
+}
+
+// invalidateOrder drops cached responses for an order whose status a dispute changed
+func (h *DisputeHandler) invalidateOrder(ctx context.Context, orderID string) {
+	if h.cache == nil {
+		return
+	}
+
+	resp, err := h.orderClient.GetOrder(ctx, &orderPb.GetOrderRequest{OrderId: orderID})
+	if err != nil {
+		// Without the user ID only the order itself can be dropped
+		h.cache.InvalidateOrder(ctx, &orderPb.Order{Id: orderID})
+		return
+	}
+	h.cache.InvalidateOrder(ctx, resp.Order)
+}
+
+// handleError maps a dispute service error to an HTTP response
+func (h *DisputeHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.PermissionDenied:
+		c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+	case codes.AlreadyExists, codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,492 @@
+package gateway
+
+import "time"
+
+// LocationRequest is a location in an API request
+type LocationRequest struct {
+	Latitude       *float64                 `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude      *float64                 `json:"longitude" binding:"required,min=-180,max=180"`
+	Address        string                   `json:"address" binding:"max=500"`
+	PostalCode     string                   `json:"postal_code" binding:"max=20"`
+	City           string                   `json:"city" binding:"max=100"`
+	Country        string                   `json:"country" binding:"max=100"`
+	AdditionalInfo map[string]string        `json:"additional_info"`
+	Instructions   *StopInstructionsRequest `json:"instructions"`
+}
+
+// StopInstructionsRequest tells the provider how to get in and where to leave things at a stop
+type StopInstructionsRequest struct {
+	GateCode    string `json:"gate_code" binding:"max=50"`
+	Floor       string `json:"floor" binding:"max=50"`
+	LeaveAtDoor bool   `json:"leave_at_door"`
+	Notes       string `json:"notes" binding:"max=1000"`
+}
+
+// OrderItemRequest is an order item in an API request
+type OrderItemRequest struct {
+	ItemID      string            `json:"item_id"`
+	Name        string            `json:"name" binding:"required,max=200"`
+	Quantity    int32             `json:"quantity" binding:"omitempty,min=1,max=1000"`
+	Price       int64             `json:"price" binding:"gt=0"` // Minor units
+	Properties  map[string]string `json:"properties"`
+	WeightGrams int32             `json:"weight_grams" binding:"gte=0,max=1000000"` // Each; required for package deliveries
+	LengthCm    int32             `json:"length_cm" binding:"gte=0,max=1000"`       // Required for package deliveries
+	WidthCm     int32             `json:"width_cm" binding:"gte=0,max=1000"`        // Required for package deliveries
+	HeightCm    int32             `json:"height_cm" binding:"gte=0,max=1000"`       // Required for package deliveries
+}
+
+// CreateOrderRequest is the request body for creating an order
+type CreateOrderRequest struct {
+	UserID              string                `json:"user_id" binding:"required"`
+	OrderType           string                `json:"order_type" binding:"required,oneof=RIDE FOOD_DELIVERY PACKAGE_DELIVERY GROCERY_DELIVERY SERVICE_BOOKING RENTAL RETURN"`
+	PickupLocation      *LocationRequest      `json:"pickup_location" binding:"required_unless=OrderType RETURN"`      // Returns are picked up where the returned order was delivered
+	DestinationLocation *LocationRequest      `json:"destination_location" binding:"required_unless=OrderType RETURN"` // and dropped where it was picked up
+	Items               []OrderItemRequest    `json:"items" binding:"excluded_if=OrderType RETURN,omitempty,dive"`
+	ReturnItems         []ReturnItemRequest   `json:"return_items" binding:"excluded_unless=OrderType RETURN,omitempty,max=100,dive"` // Delivered items a return sends back; none returns them all
+	PaymentMethod       string                `json:"payment_method" binding:"omitempty,oneof=CREDIT_CARD DEBIT_CARD DIGITAL_WALLET CASH CRYPTO"`
+	PaymentMethodID     string                `json:"payment_method_id" binding:"omitempty,max=36"` // A saved payment method; with neither, the user's default is used
+	Notes               string                `json:"notes" binding:"max=1000"`
+	PaymentShares       []PaymentShareRequest `json:"payment_shares" binding:"omitempty,max=10,dive"`
+	RentalHours         int32                 `json:"rental_hours" binding:"required_if=OrderType RENTAL,gte=0"`        // Hours booked; rental orders only
+	MerchantID          string                `json:"merchant_id" binding:"max=36"`                                     // Items are then from the merchant's catalog, which checks their prices
+	QuotedTotal         int64                 `json:"quoted_total" binding:"gte=0"`                                     // The total shown to the user, in minor units
+	WalletAmount        int64                 `json:"wallet_amount" binding:"gte=0"`                                    // Paid from the user's wallet balance, at most the total; payment_method pays the rest
+	RedeemPoints        int64                 `json:"redeem_points" binding:"gte=0"`                                    // Loyalty points taken off the total, at most what it is worth
+	ReturnOfOrderID     string                `json:"return_of_order_id" binding:"required_if=OrderType RETURN,max=36"` // The delivered order a return sends goods back from
+}
+
+// ReturnItemRequest names a delivered item that a return order sends back, by its item ID
+// or else by its name
+type ReturnItemRequest struct {
+	ItemID   string `json:"item_id" binding:"required_without=Name,max=100"`
+	Name     string `json:"name" binding:"max=200"`
+	Quantity int32  `json:"quantity" binding:"omitempty,min=1,max=1000"` // Defaults to 1
+}
+
+// PaymentShareRequest is one payer's part of a split order payment
+type PaymentShareRequest struct {
+	UserID     string  `json:"user_id" binding:"required"`
+	Percentage float64 `json:"percentage" binding:"gt=0,max=100"`
+}
+
+// UpdateOrderStatusRequest is the request body for updating an order's status
+type UpdateOrderStatusRequest struct {
+	Status    string `json:"status" binding:"required,oneof=CREATED PAYMENT_PENDING PAYMENT_COMPLETED PROVIDER_ASSIGNED PROVIDER_ACCEPTED PROVIDER_REJECTED IN_PROGRESS PICKED_UP IN_TRANSIT ARRIVED DELIVERED COMPLETED CANCELLED REFUNDED DISPUTED"`
+	UpdatedBy string `json:"updated_by" binding:"required"`
+	Notes     string `json:"notes" binding:"max=1000"`
+}
+
+// CancelOrderRequest is the request body for cancelling an order
+type CancelOrderRequest struct {
+	CancelledBy string `json:"cancelled_by" binding:"required"`
+	Reason      string `json:"reason" binding:"required,max=500"`
+}
+
+// RefundOrderRequest is the request body for refunding an order
+type RefundOrderRequest struct {
+	RequestedBy string `json:"requested_by" binding:"required"`
+	Reason      string `json:"reason" binding:"required,max=500"`
+	Amount      int64  `json:"amount" binding:"omitempty,gt=0"` // Minor units; defaults to the full order total
+	ToWallet    bool   `json:"to_wallet"`                       // Credit all of it to the user's wallet at once
+}
+
+// AddTipRequest is the request body for tipping a completed order
+type AddTipRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Amount int64  `json:"amount" binding:"gt=0,max=100000"` // Minor units
+}
+
+// UpdateOrderDetailsRequest is the request body for changing an order before pickup;
+// fields left empty keep what the order has
+type UpdateOrderDetailsRequest struct {
+	UserID              string             `json:"user_id" binding:"required"`
+	DestinationLocation *LocationRequest   `json:"destination_location"`
+	Items               []OrderItemRequest `json:"items" binding:"omitempty,dive"`
+	Notes               string             `json:"notes" binding:"max=1000"`
+	QuotedTotal         int64              `json:"quoted_total" binding:"gte=0"` // The new total shown to the user, in minor units
+}
+
+// RentalExtensionRequest is the request body for asking to extend a rental
+type RentalExtensionRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Hours  int32  `json:"hours" binding:"required,min=1"`
+}
+
+// RespondRentalExtensionRequest is the request body for a provider answering a rental extension
+type RespondRentalExtensionRequest struct {
+	ProviderID string `json:"provider_id" binding:"required"`
+}
+
+// DestinationChangeRequest is the request body for asking to change a ride's destination mid-trip
+type DestinationChangeRequest struct {
+	UserID              string           `json:"user_id" binding:"required"`
+	DestinationLocation *LocationRequest `json:"destination_location" binding:"required"`
+}
+
+// RespondDestinationChangeRequest is the request body for a provider answering a destination change
+type RespondDestinationChangeRequest struct {
+	ProviderID string `json:"provider_id" binding:"required"`
+}
+
+// CompleteDeliveryRequest is the request body for a provider's proof of delivery
+type CompleteDeliveryRequest struct {
+	ProviderID    string `json:"provider_id" binding:"required"`
+	PhotoRef      string `json:"photo_ref" binding:"required_without=SignatureHash,max=500"`
+	SignatureHash string `json:"signature_hash" binding:"omitempty,hexadecimal,len=64"`
+	RecipientOTP  string `json:"recipient_otp" binding:"omitempty,numeric,min=4,max=6"` // The recipient's delivery PIN
+}
+
+// ResendDeliveryPINRequest is the request body for sending a user a new delivery PIN
+type ResendDeliveryPINRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// AssignProviderRequest is the request body for assigning a provider
+type AssignProviderRequest struct {
+	ProviderID string `json:"provider_id"` // Optional for manual assignment
+}
+
+// HandOffOrderRequest is the request body for a provider handing their order off to another
+type HandOffOrderRequest struct {
+	ProviderID    string `json:"provider_id" binding:"required"`
+	NewProviderID string `json:"new_provider_id" binding:"required"`
+	Reason        string `json:"reason" binding:"required,max=500"`
+}
+
+// AdminHandOffOrderRequest is the request body for an admin handing an order off to another provider
+type AdminHandOffOrderRequest struct {
+	HandedOffBy   string `json:"handed_off_by" binding:"required"`
+	NewProviderID string `json:"new_provider_id" binding:"required"`
+	Reason        string `json:"reason" binding:"required,max=500"`
+}
+
+// AcceptOrderRequest is the request body for a provider accepting an order
+type AcceptOrderRequest struct {
+	ProviderID      string           `json:"provider_id" binding:"required"`
+	CurrentLocation *LocationRequest `json:"current_location"`
+}
+
+// RejectOrderRequest is the request body for a provider rejecting an order
+type RejectOrderRequest struct {
+	ProviderID string `json:"provider_id" binding:"required"`
+	Reason     string `json:"reason" binding:"required,max=500"`
+}
+
+// UpdateLocationRequest is the request body for a provider location update
+type UpdateLocationRequest struct {
+	ProviderID string           `json:"provider_id" binding:"required"`
+	Location   *LocationRequest `json:"location" binding:"required"`
+}
+
+// BatchUpdateLocationRequest is the request body for a batch of provider locations
+type BatchUpdateLocationRequest struct {
+	ProviderID string                 `json:"provider_id" binding:"required"`
+	Points     []LocationPointRequest `json:"points" binding:"required,min=1,max=500,dive"` // Oldest first
+}
+
+// LocationPointRequest is a provider location recorded by the device
+type LocationPointRequest struct {
+	Latitude   *float64  `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude  *float64  `json:"longitude" binding:"required,min=-180,max=180"`
+	RecordedAt time.Time `json:"recorded_at" binding:"required"`
+}
+
+// OpenDisputeRequest is the request body for opening a dispute on an order
+type OpenDisputeRequest struct {
+	OpenedBy    string `json:"opened_by" binding:"required"`
+	Role        string `json:"role" binding:"required,oneof=USER PROVIDER"`
+	Reason      string `json:"reason" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+// AddEvidenceRequest is the request body for adding evidence to a dispute
+type AddEvidenceRequest struct {
+	SubmittedBy   string `json:"submitted_by" binding:"required"`
+	Role          string `json:"role" binding:"required,oneof=USER PROVIDER ADMIN"`
+	Description   string `json:"description" binding:"required_without=AttachmentURL,max=2000"`
+	AttachmentURL string `json:"attachment_url" binding:"omitempty,url"`
+}
+
+// ResolveDisputeRequest is the request body for an admin resolving a dispute
+type ResolveDisputeRequest struct {
+	ResolvedBy   string `json:"resolved_by" binding:"required"`
+	Resolution   string `json:"resolution" binding:"required,oneof=REFUND_FULL REFUND_PARTIAL NO_REFUND"`
+	RefundAmount int64  `json:"refund_amount" binding:"required_if=Resolution REFUND_PARTIAL,gte=0"` // Minor units
+	Notes        string `json:"notes" binding:"max=1000"`
+}
+
+// SendChatMessageRequest is the request body for a chat message between an order's user and provider
+type SendChatMessageRequest struct {
+	SenderID string `json:"sender_id" binding:"required"`
+	Body     string `json:"body" binding:"required,max=2000"`
+}
+
+// MarkChatReadRequest is the request body for marking an order's chat messages as read
+type MarkChatReadRequest struct {
+	ParticipantID string `json:"participant_id" binding:"required"`
+}
+
+// IssueContactTokenRequest is the request body for a masked contact token
+type IssueContactTokenRequest struct {
+	ParticipantID string `json:"participant_id" binding:"required"`
+}
+
+// UpdateProviderPreferencesRequest is the request body for a provider's order preferences
+type UpdateProviderPreferencesRequest struct {
+	AutoAcceptRadiusKm float64  `json:"auto_accept_radius_km" binding:"min=0"`
+	OrderTypes         []string `json:"order_types" binding:"dive,oneof=ride food_delivery package_delivery grocery_delivery service_booking rental general"`
+	MinFare            int64    `json:"min_fare" binding:"min=0"` // Minor units
+}
+
+// UpdateProviderServiceAreasRequest is the request body for the service areas a provider works in
+type UpdateProviderServiceAreasRequest struct {
+	ServiceAreaIDs []string `json:"service_area_ids" binding:"max=100,dive,required"` // Replaces the current areas
+}
+
+// VehicleCapacityRequest is the request body for what a provider's vehicle can carry
+type VehicleCapacityRequest struct {
+	MaxWeightGrams int32 `json:"max_weight_grams" binding:"required,min=1,max=10000000"`
+	LengthCm       int32 `json:"length_cm" binding:"required,min=1,max=2000"`
+	WidthCm        int32 `json:"width_cm" binding:"required,min=1,max=1000"`
+	HeightCm       int32 `json:"height_cm" binding:"required,min=1,max=1000"`
+}
+
+// VehicleRequest is the request body for a vehicle a provider registers or updates
+type VehicleRequest struct {
+	Plate              string                 `json:"plate" binding:"required,max=20"`
+	Type               string                 `json:"type" binding:"required,oneof=BICYCLE MOTORCYCLE CAR VAN TRUCK"`
+	Description        string                 `json:"description" binding:"max=255"` // Make, model and color
+	Capacity           VehicleCapacityRequest `json:"capacity" binding:"required"`
+	InsuranceExpiresAt time.Time              `json:"insurance_expires_at" binding:"required"`
+}
+
+// UpdateAvailabilityRequest is the request body for a provider going on or off shift
+type UpdateAvailabilityRequest struct {
+	IsAvailable *bool  `json:"is_available" binding:"required"`
+	VehicleID   string `json:"vehicle_id"` // Registered vehicle driven this shift, when going available
+}
+
+// UpdateDispatchWeightsRequest is the request body for the matcher's scoring weights
+type UpdateDispatchWeightsRequest struct {
+	Distance   float64 `json:"distance" binding:"min=0"`
+	Rating     float64 `json:"rating" binding:"min=0"`
+	Idle       float64 `json:"idle" binding:"min=0"`
+	Earnings   float64 `json:"earnings" binding:"min=0"`
+	Favorite   float64 `json:"favorite" binding:"min=0"`
+	Acceptance float64 `json:"acceptance" binding:"min=0"`
+	Completion float64 `json:"completion" binding:"min=0"`
+	OnTime     float64 `json:"on_time" binding:"min=0"`
+	UpdatedBy  string  `json:"updated_by" binding:"required"`
+}
+
+// CreateTrackingLinkRequest is the request body for sharing an order's live tracking
+type CreateTrackingLinkRequest struct {
+	UserID     string `json:"user_id" binding:"required"`
+	TTLMinutes int32  `json:"ttl_minutes" binding:"min=0"` // Defaults to the service's link TTL
+}
+
+// ReportIncidentRequest is the request body for an SOS raised on an order
+type ReportIncidentRequest struct {
+	ReportedBy  string           `json:"reported_by" binding:"required"`
+	Description string           `json:"description" binding:"max=2000"`
+	Location    *LocationRequest `json:"location"` // Defaults to the provider's last tracked location
+}
+
+// ResolveIncidentRequest is the request body for safety staff resolving an incident
+type ResolveIncidentRequest struct {
+	ResolvedBy string `json:"resolved_by" binding:"required"`
+	Notes      string `json:"notes" binding:"max=1000"`
+}
+
+// FeeRuleRequest is the request body for creating a fee rule. Empty order type or city matches any.
+type FeeRuleRequest struct {
+	OrderType          string  `json:"order_type" binding:"omitempty,oneof=RIDE FOOD_DELIVERY PACKAGE_DELIVERY GROCERY_DELIVERY SERVICE_BOOKING RENTAL RETURN"`
+	City               string  `json:"city" binding:"max=100"`
+	PlatformFeePercent float64 `json:"platform_fee_percent" binding:"min=0,max=100"`
+	ProviderFeePercent float64 `json:"provider_fee_percent" binding:"min=0,max=100"`
+	MinPlatformFee     int64   `json:"min_platform_fee" binding:"gte=0"` // Minor units
+}
+
+// UpdateFeeRuleRequest is the request body for changing the fees of a fee rule
+type UpdateFeeRuleRequest struct {
+	PlatformFeePercent float64 `json:"platform_fee_percent" binding:"min=0,max=100"`
+	ProviderFeePercent float64 `json:"provider_fee_percent" binding:"min=0,max=100"`
+	MinPlatformFee     int64   `json:"min_platform_fee" binding:"gte=0"` // Minor units
+}
+
+// FeeWaiverRequest is the request body for a promotion waiving the platform fee
+type FeeWaiverRequest struct {
+	Name      string    `json:"name" binding:"required,max=100"`
+	OrderType string    `json:"order_type" binding:"omitempty,oneof=RIDE FOOD_DELIVERY PACKAGE_DELIVERY GROCERY_DELIVERY SERVICE_BOOKING RENTAL RETURN"`
+	City      string    `json:"city" binding:"max=100"`
+	StartsAt  time.Time `json:"starts_at" binding:"required"`
+	EndsAt    time.Time `json:"ends_at" binding:"required"`
+}
+
+// ServiceAreaRequest is the request body for creating a service area
+type ServiceAreaRequest struct {
+	City     string                 `json:"city" binding:"required,max=100"`
+	Name     string                 `json:"name" binding:"required,max=100"`
+	Boundary []BoundaryPointRequest `json:"boundary" binding:"required,min=3,max=1000,dive"`
+	Active   *bool                  `json:"active"` // Defaults to true
+}
+
+// MerchantRequest is the request body for creating or replacing a merchant
+type MerchantRequest struct {
+	Name      string   `json:"name" binding:"required,max=100"`
+	City      string   `json:"city" binding:"required,max=100"`
+	Address   string   `json:"address" binding:"max=500"`
+	Latitude  *float64 `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required,min=-180,max=180"`
+	Active    *bool    `json:"active"` // Defaults to true
+}
+
+// CatalogItemRequest is the request body for creating or replacing an item on a merchant's menu
+type CatalogItemRequest struct {
+	Name        string `json:"name" binding:"required,max=200"`
+	Description string `json:"description" binding:"max=1000"`
+	Category    string `json:"category" binding:"max=100"`
+	Price       int64  `json:"price" binding:"gt=0"`            // Minor units
+	Available   *bool  `json:"available"`                       // Defaults to true
+	Stock       *int32 `json:"stock" binding:"omitempty,gte=0"` // Units left to order; omitted when stock is not tracked
+}
+
+// UpdateServiceAreaRequest is the request body for replacing a service area's name, boundary and active flag
+type UpdateServiceAreaRequest struct {
+	Name     string                 `json:"name" binding:"required,max=100"`
+	Boundary []BoundaryPointRequest `json:"boundary" binding:"required,min=3,max=1000,dive"`
+	Active   *bool                  `json:"active" binding:"required"`
+}
+
+// BoundaryPointRequest is a vertex of a service area's boundary
+type BoundaryPointRequest struct {
+	Latitude  *float64 `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required,min=-180,max=180"`
+}
+
+// SetUserProviderRequest is the request body for favoriting or blocking a provider
+type SetUserProviderRequest struct {
+	Preference string `json:"preference" binding:"required,oneof=FAVORITE BLOCKED"`
+}
+
+// SavePaymentMethodRequest is the request body for saving a card or wallet tokenized by the payment gateway
+type SavePaymentMethodRequest struct {
+	Type        string `json:"type" binding:"required,oneof=CREDIT_CARD DEBIT_CARD DIGITAL_WALLET"`
+	Gateway     string `json:"gateway" binding:"required,max=20"`
+	Token       string `json:"token" binding:"required,max=255"`
+	Fingerprint string `json:"fingerprint" binding:"max=100"`
+	Brand       string `json:"brand" binding:"max=20"`
+	Last4       string `json:"last4" binding:"omitempty,len=4,numeric"`
+	ExpMonth    int32  `json:"exp_month" binding:"gte=0,lte=12"`
+	ExpYear     int32  `json:"exp_year" binding:"gte=0"`
+	MakeDefault bool   `json:"make_default"`
+}
+
+// TopUpWalletRequest is the request body for topping up a user's wallet from a saved payment method
+type TopUpWalletRequest struct {
+	Amount          int64  `json:"amount" binding:"required,gt=0"`               // Minor units
+	PaymentMethodID string `json:"payment_method_id" binding:"omitempty,max=36"` // Defaults to the user's default saved method
+	IdempotencyKey  string `json:"idempotency_key" binding:"required,max=100"`   // Retries with the same key top up once
+}
+
+// CreditWalletRequest is the request body for an admin crediting a user's wallet as compensation
+type CreditWalletRequest struct {
+	Amount         int64  `json:"amount" binding:"required,gt=0"` // Minor units
+	OrderID        string `json:"order_id" binding:"omitempty,max=36"`
+	Reason         string `json:"reason" binding:"required,max=500"`
+	CreditedBy     string `json:"credited_by" binding:"required"`
+	IdempotencyKey string `json:"idempotency_key" binding:"required,max=100"`
+}
+
+// RegisterDeviceRequest is the request body for an app registering its device's push token
+type RegisterDeviceRequest struct {
+	Platform   string `json:"platform" binding:"required,oneof=FCM APNS"`
+	Token      string `json:"token" binding:"required,max=4096"`
+	AppVersion string `json:"app_version" binding:"max=50"`
+}
+
+// CreateCampaignRequest is the request body for an admin broadcasting a notification to a segment
+type CreateCampaignRequest struct {
+	Segment          string                 `json:"segment" binding:"required,oneof=PROVIDERS_IN_CITY RECENT_USERS"`
+	City             string                 `json:"city" binding:"required_if=Segment PROVIDERS_IN_CITY"`
+	ActiveWithinDays int32                  `json:"active_within_days" binding:"gte=0,lte=365"` // RECENT_USERS only; 30 when unset
+	NotificationType string                 `json:"notification_type" binding:"max=50"`
+	Title            string                 `json:"title" binding:"required,max=200"`
+	Message          string                 `json:"message" binding:"max=2000"`
+	Payload          map[string]interface{} `json:"payload"`
+	CreatedBy        string                 `json:"created_by" binding:"required"`
+}
+
+// CancelCampaignRequest is the request body for an admin stopping a campaign
+type CancelCampaignRequest struct {
+	CancelledBy string `json:"cancelled_by" binding:"required"`
+}
+
+// ForgetRequest is the request body for an admin erasing a user's or provider's personal data
+type ForgetRequest struct {
+	RequestedBy string `json:"requested_by" binding:"required"`
+}
+
+// WebhookRequest is the request body for registering a partner's callback URL
+type WebhookRequest struct {
+	PartnerID  string   `json:"partner_id" binding:"required"`
+	URL        string   `json:"url" binding:"required,url,max=2000"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=order.created order.status_changed"`
+	OrderTypes []string `json:"order_types"` // Empty sends events about every order type
+}
+
+// UpdateWebhookRequest is the request body for replacing a webhook's URL, event filters and active flag
+type UpdateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url,max=2000"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=order.created order.status_changed"`
+	OrderTypes []string `json:"order_types"`
+	Active     *bool    `json:"active" binding:"required"`
+}
+
+// AddDenylistEntryRequest is the request body for banning a user, API key or network
+type AddDenylistEntryRequest struct {
+	Kind      string     `json:"kind" binding:"required,oneof=USER API_KEY CIDR"`
+	Value     string     `json:"value" binding:"required,max=200"` // A user ID, an API key, or a network in CIDR notation or single address
+	Reason    string     `json:"reason" binding:"required,max=500"`
+	CreatedBy string     `json:"created_by" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"` // Optional; the ban is permanent without it
+}
+
+// StartSupportSessionRequest is the request body for an admin to start acting as a user
+type StartSupportSessionRequest struct {
+	RequestedBy string `json:"requested_by" binding:"required"` // The admin
+	UserID      string `json:"user_id" binding:"required"`
+	Reason      string `json:"reason" binding:"required,max=500"` // E.g. the support ticket
+	Scope       string `json:"scope" binding:"omitempty,oneof=READ_ONLY READ_WRITE"`
+	TTLMinutes  int32  `json:"ttl_minutes" binding:"gte=0"` // 0 for the default
+}
+
+// EndSupportSessionRequest is the request body for ending a support session early
+type EndSupportSessionRequest struct {
+	RequestedBy string `json:"requested_by" binding:"required"`
+}
+
+// MaintenanceRequest is the request body for turning maintenance mode on or off
+type MaintenanceRequest struct {
+	Enabled           *bool  `json:"enabled" binding:"required"`
+	Message           string `json:"message" binding:"max=500"`
+	RetryAfterSeconds int    `json:"retry_after_seconds" binding:"gte=0,lte=86400"` // 0 keeps the configured one
+}
+
+// ChaosFaultsRequest is the request body for setting the faults a service injects into
+// its calls to other services
+type ChaosFaultsRequest struct {
+	Enabled     *bool    `json:"enabled" binding:"required"`
+	LatencyMs   int64    `json:"latency_ms" binding:"gte=0,lte=600000"`
+	JitterMs    int64    `json:"jitter_ms" binding:"gte=0,lte=600000"`
+	LatencyRate float64  `json:"latency_rate" binding:"gte=0,lte=1"`
+	ErrorRate   float64  `json:"error_rate" binding:"gte=0,lte=1"`
+	ErrorCode   string   `json:"error_code"` // gRPC code such as UNAVAILABLE, the default
+	Methods     []string `json:"methods" binding:"max=50,dive,required"`
+}
+
+// ApplyReferralCodeRequest is the request body for a new user applying a friend's referral code
+type ApplyReferralCodeRequest struct {
+	Code     string `json:"code" binding:"required,max=20"`
+	DeviceID string `json:"device_id" binding:"required,max=200"` // The device the user signed up on
+}
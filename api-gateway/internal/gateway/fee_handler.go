@@ -0,0 +1,221 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	feePb "github.com/order-api-microservices/proto/fee"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FeeHandler handles the admin API endpoints for the fee schedule
+type FeeHandler struct {
+	feeClient feePb.FeeServiceClient
+}
+
+// NewFeeHandler creates a new fee handler
+func NewFeeHandler(feeClient feePb.FeeServiceClient) *FeeHandler {
+	return &FeeHandler{
+		feeClient: feeClient,
+	}
+}
+
+// RegisterRoutes registers the fee API routes on a version group
+func (h *FeeHandler) RegisterRoutes(api *gin.RouterGroup) {
+	rules := api.Group("/admin/fees/rules")
+	{
+		rules.GET("", h.ListFeeRules)
+		rules.POST("", h.CreateFeeRule)
+		rules.PUT("/:id", h.UpdateFeeRule)
+		rules.DELETE("/:id", h.DeleteFeeRule)
+	}
+
+	waivers := api.Group("/admin/fees/waivers")
+	{
+		waivers.GET("", h.ListFeeWaivers)
+		waivers.POST("", h.CreateFeeWaiver)
+		waivers.DELETE("/:id", h.DeleteFeeWaiver)
+	}
+}
+
+// ListFeeRules lists the fee rules and the default that applies when none matches
+func (h *FeeHandler) ListFeeRules(c *gin.Context) {
+	// Call the fee service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.feeClient.ListFeeRules(ctx, &feePb.ListFeeRulesRequest{})
+	if err != nil {
+		h.handleError(c, err, "Failed to list fee rules")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateFeeRule adds a fee rule for an order type, a city, or both
+func (h *FeeHandler) CreateFeeRule(c *gin.Context) {
+	var request FeeRuleRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the fee service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.feeClient.CreateFeeRule(ctx, &feePb.CreateFeeRuleRequest{
+		OrderType:          request.OrderType,
+		City:               request.City,
+		PlatformFeePercent: request.PlatformFeePercent,
+		ProviderFeePercent: request.ProviderFeePercent,
+		MinPlatformFee:     request.MinPlatformFee,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to create fee rule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.Rule)
+}
+
+// UpdateFeeRule changes the fees of a fee rule
+func (h *FeeHandler) UpdateFeeRule(c *gin.Context) {
+	ruleID := c.Param("id")
+	if ruleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rule ID is required"})
+		return
+	}
+
+	var request UpdateFeeRuleRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the fee service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.feeClient.UpdateFeeRule(ctx, &feePb.UpdateFeeRuleRequest{
+		RuleId:             ruleID,
+		PlatformFeePercent: request.PlatformFeePercent,
+		ProviderFeePercent: request.ProviderFeePercent,
+		MinPlatformFee:     request.MinPlatformFee,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to update fee rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Rule)
+}
+
+// DeleteFeeRule deletes a fee rule
+func (h *FeeHandler) DeleteFeeRule(c *gin.Context) {
+	ruleID := c.Param("id")
+	if ruleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rule ID is required"})
+		return
+	}
+
+	// Call the fee service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := h.feeClient.DeleteFeeRule(ctx, &feePb.DeleteFeeRuleRequest{RuleId: ruleID})
+	if err != nil {
+		h.handleError(c, err, "Failed to delete fee rule")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListFeeWaivers lists the current and upcoming fee waivers
+func (h *FeeHandler) ListFeeWaivers(c *gin.Context) {
+	// Call the fee service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.feeClient.ListFeeWaivers(ctx, &feePb.ListFeeWaiversRequest{})
+	if err != nil {
+		h.handleError(c, err, "Failed to list fee waivers")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateFeeWaiver adds a promotion that waives the platform fee for a period
+func (h *FeeHandler) CreateFeeWaiver(c *gin.Context) {
+	var request FeeWaiverRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the fee service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.feeClient.CreateFeeWaiver(ctx, &feePb.CreateFeeWaiverRequest{
+		Name:      request.Name,
+		OrderType: request.OrderType,
+		City:      request.City,
+		StartsAt:  timestamppb.New(request.StartsAt),
+		EndsAt:    timestamppb.New(request.EndsAt),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to create fee waiver")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.Waiver)
+}
+
+// DeleteFeeWaiver deletes a fee waiver, ending its promotion
+func (h *FeeHandler) DeleteFeeWaiver(c *gin.Context) {
+	waiverID := c.Param("id")
+	if waiverID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "waiver ID is required"})
+		return
+	}
+
+	// Call the fee service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := h.feeClient.DeleteFeeWaiver(ctx, &feePb.DeleteFeeWaiverRequest{WaiverId: waiverID})
+	if err != nil {
+		h.handleError(c, err, "Failed to delete fee waiver")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleError maps a fee service error to an HTTP response
+func (h *FeeHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.AlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
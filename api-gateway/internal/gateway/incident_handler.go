@@ -0,0 +1,201 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	incidentPb "github.com/order-api-microservices/proto/incident"
+	orderPb "github.com/order-api-microservices/proto/order"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IncidentHandler handles SOS reports from an order's parties and their review by safety staff
+type IncidentHandler struct {
+	incidentClient incidentPb.IncidentServiceClient
+	orderClient    orderPb.OrderServiceClient
+	cache          *ResponseCache
+}
+
+// NewIncidentHandler creates a new incident handler; responseCache may be nil
+func NewIncidentHandler(incidentClient incidentPb.IncidentServiceClient, orderClient orderPb.OrderServiceClient, responseCache *ResponseCache) *IncidentHandler {
+	return &IncidentHandler{
+		incidentClient: incidentClient,
+		orderClient:    orderClient,
+		cache:          responseCache,
+	}
+}
+
+// RegisterRoutes registers the incident API routes on a version group
+func (h *IncidentHandler) RegisterRoutes(api *gin.RouterGroup) {
+	orders := api.Group("/orders")
+	{
+		orders.POST("/:id/sos", h.ReportIncident)
+	}
+
+	admin := api.Group("/admin/incidents")
+	{
+		admin.GET("", h.ListIncidents)
+		admin.GET("/:id", h.GetIncident)
+		admin.POST("/:id/resolve", h.ResolveIncident)
+	}
+}
+
+// ReportIncident raises an SOS on an order, which freezes it until the incident is resolved
+func (h *IncidentHandler) ReportIncident(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request ReportIncidentRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	incidentReq := &incidentPb.ReportIncidentRequest{
+		OrderId:     orderID,
+		ReportedBy:  request.ReportedBy,
+		Description: request.Description,
+	}
+	if request.Location != nil {
+		incidentReq.Location = &incidentPb.Location{
+			Latitude:  *request.Location.Latitude,
+			Longitude: *request.Location.Longitude,
+			Address:   request.Location.Address,
+		}
+	}
+
+	// Call the incident service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.incidentClient.ReportIncident(ctx, incidentReq)
+	if err != nil {
+		h.handleError(c, err, "Failed to report incident")
+		return
+	}
+
+	h.invalidateOrder(ctx, orderID)
+
+	c.JSON(http.StatusCreated, resp.Incident)
+}
+
+// ListIncidents lists incidents for safety staff, optionally filtered by order and status
+func (h *IncidentHandler) ListIncidents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Call the incident service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.incidentClient.ListIncidents(ctx, &incidentPb.ListIncidentsRequest{
+		OrderId: c.Query("order_id"),
+		Status:  c.Query("status"),
+		Page:    int32(page),
+		Limit:   int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list incidents")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetIncident gets an incident by its ID
+func (h *IncidentHandler) GetIncident(c *gin.Context) {
+	incidentID := c.Param("id")
+	if incidentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "incident ID is required"})
+		return
+	}
+
+	// Call the incident service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.incidentClient.GetIncident(ctx, &incidentPb.GetIncidentRequest{IncidentId: incidentID})
+	if err != nil {
+		h.handleError(c, err, "Failed to get incident")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Incident)
+}
+
+// ResolveIncident closes an incident after review, unfreezing its order once none remain open
+func (h *IncidentHandler) ResolveIncident(c *gin.Context) {
+	incidentID := c.Param("id")
+	if incidentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "incident ID is required"})
+		return
+	}
+
+	var request ResolveIncidentRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the incident service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.incidentClient.ResolveIncident(ctx, &incidentPb.ResolveIncidentRequest{
+		IncidentId: incidentID,
+		ResolvedBy: request.ResolvedBy,
+		Notes:      request.Notes,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to resolve incident")
+		return
+	}
+
+	h.invalidateOrder(ctx, resp.Incident.OrderId)
+
+	c.JSON(http.StatusOK, resp.Incident)
+}
+
+// invalidateOrder drops cached responses for an order whose frozen flag an incident changed
+func (h *IncidentHandler) invalidateOrder(ctx context.Context, orderID string) {
+	if h.cache == nil {
+		return
+	}
+
+	resp, err := h.orderClient.GetOrder(ctx, &orderPb.GetOrderRequest{OrderId: orderID})
+	if err != nil {
+		// Without the user ID only the order itself can be dropped
+		h.cache.InvalidateOrder(ctx, &orderPb.Order{Id: orderID})
+		return
+	}
+	h.cache.InvalidateOrder(ctx, resp.Order)
+}
+
+// handleError maps an incident service error to an HTTP response
+func (h *IncidentHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.PermissionDenied:
+		c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
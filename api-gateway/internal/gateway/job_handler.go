@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jobsPb "github.com/order-api-microservices/proto/jobs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// JobHandler handles the admin API endpoints for the order service's background jobs
+type JobHandler struct {
+	jobClient jobsPb.JobServiceClient
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(jobClient jobsPb.JobServiceClient) *JobHandler {
+	return &JobHandler{
+		jobClient: jobClient,
+	}
+}
+
+// RegisterRoutes registers the job API routes on a version group
+func (h *JobHandler) RegisterRoutes(api *gin.RouterGroup) {
+	jobs := api.Group("/admin/jobs")
+	{
+		jobs.GET("", h.ListJobs)
+		jobs.GET("/counts", h.GetJobCounts)
+		jobs.POST("/requeue", h.RequeueDeadJobs)
+		jobs.POST("/:id/requeue", h.RequeueJob)
+	}
+}
+
+// ListJobs lists jobs in a status, the dead letter queue by default
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	// Call the job service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.jobClient.ListJobs(ctx, &jobsPb.ListJobsRequest{
+		Status: c.Query("status"),
+		Kind:   c.Query("kind"),
+		Page:   int32(page),
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list jobs")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetJobCounts reports how many jobs of each kind are in each status
+func (h *JobHandler) GetJobCounts(c *gin.Context) {
+	// Call the job service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.jobClient.GetJobCounts(ctx, &jobsPb.GetJobCountsRequest{})
+	if err != nil {
+		h.handleError(c, err, "Failed to count jobs")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RequeueJob gives a dead job a fresh set of attempts
+func (h *JobHandler) RequeueJob(c *gin.Context) {
+	jobID := c.Param("id")
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "job ID is required"})
+		return
+	}
+
+	// Call the job service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.jobClient.RequeueJob(ctx, &jobsPb.RequeueJobRequest{
+		JobId: jobID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to requeue job")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// RequeueDeadJobs gives every dead job, or those of the kind query parameter, a fresh set
+// of attempts
+func (h *JobHandler) RequeueDeadJobs(c *gin.Context) {
+	// Call the job service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.jobClient.RequeueDeadJobs(ctx, &jobsPb.RequeueDeadJobsRequest{
+		Kind: c.Query("kind"),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to requeue jobs")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// handleError maps a job service error to an HTTP response
+func (h *JobHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,185 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	loyaltyPb "github.com/order-api-microservices/proto/loyalty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LoyaltyHandler handles the API endpoints for referrals and loyalty points
+type LoyaltyHandler struct {
+	loyaltyClient loyaltyPb.LoyaltyServiceClient
+}
+
+// NewLoyaltyHandler creates a new loyalty handler
+func NewLoyaltyHandler(loyaltyClient loyaltyPb.LoyaltyServiceClient) *LoyaltyHandler {
+	return &LoyaltyHandler{
+		loyaltyClient: loyaltyClient,
+	}
+}
+
+// RegisterRoutes registers the loyalty API routes on a version group
+func (h *LoyaltyHandler) RegisterRoutes(api *gin.RouterGroup) {
+	users := api.Group("/users/:id")
+	{
+		users.GET("/referral-code", h.GetReferralCode)
+		users.GET("/referral", h.GetReferral)
+		users.POST("/referral", h.ApplyReferralCode)
+		users.GET("/points", h.GetPoints)
+		users.GET("/points/transactions", h.ListPointsTransactions)
+	}
+}
+
+// GetReferralCode returns the code a user shares with friends
+func (h *LoyaltyHandler) GetReferralCode(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	// Call the loyalty service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.loyaltyClient.GetReferralCode(ctx, &loyaltyPb.GetReferralCodeRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to get referral code")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.ReferralCode)
+}
+
+// GetReferral returns who referred a user and whether it earned points yet
+func (h *LoyaltyHandler) GetReferral(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	// Call the loyalty service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.loyaltyClient.GetReferral(ctx, &loyaltyPb.GetReferralRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to get referral")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Referral)
+}
+
+// ApplyReferralCode records the friend whose code a new user signed up with
+func (h *LoyaltyHandler) ApplyReferralCode(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	var request ApplyReferralCodeRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the loyalty service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.loyaltyClient.ApplyReferralCode(ctx, &loyaltyPb.ApplyReferralCodeRequest{
+		UserId:   userID,
+		Code:     request.Code,
+		DeviceId: request.DeviceID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to apply referral code")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetPoints returns a user's loyalty points balance
+func (h *LoyaltyHandler) GetPoints(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	// Call the loyalty service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.loyaltyClient.GetPoints(ctx, &loyaltyPb.GetPointsRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to get loyalty points")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListPointsTransactions lists a page of a user's points transactions, newest first
+func (h *LoyaltyHandler) ListPointsTransactions(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Call the loyalty service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.loyaltyClient.ListPointsTransactions(ctx, &loyaltyPb.ListPointsTransactionsRequest{
+		UserId: userID,
+		Page:   int32(page),
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list points transactions")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleError maps a loyalty service error to an HTTP response
+func (h *LoyaltyHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.AlreadyExists, codes.FailedPrecondition, codes.Aborted:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
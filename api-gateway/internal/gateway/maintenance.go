@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maintenanceRoute is the route that switches maintenance mode
+const maintenanceRoute = "/admin/maintenance"
+
+// maintenanceExemptRoutes stay open while maintenance mode is on: the switch itself, and
+// provider heartbeats, without which providers would be marked unavailable
+var maintenanceExemptRoutes = []string{maintenanceRoute, "/providers/:id/heartbeat"}
+
+// defaultMaintenanceMessage is returned when maintenance mode is on without a message
+const defaultMaintenanceMessage = "The API is read-only during maintenance; try again later"
+
+// MaintenanceState is whether maintenance mode is on and what refused requests are told
+type MaintenanceState struct {
+	Enabled    bool          `json:"enabled"`
+	Message    string        `json:"message"`
+	RetryAfter time.Duration `json:"-"`
+	Since      time.Time     `json:"since"` // When it was last turned on or off
+}
+
+// maintenanceResponse is a maintenance state in API responses
+type maintenanceResponse struct {
+	MaintenanceState
+	RetryAfterSeconds int64 `json:"retry_after_seconds"`
+}
+
+// Maintenance is the gateway's maintenance mode. While it is on, requests that change
+// anything are refused with 503 and Retry-After, so deployments and migrations can run
+// safely; reads and tracking streams keep working. It is switched by configuration or
+// through the admin API, and applies to one gateway instance.
+type Maintenance struct {
+	mu    sync.RWMutex
+	state MaintenanceState
+}
+
+// NewMaintenance creates the maintenance mode switch in its configured state
+func NewMaintenance(enabled bool, message string, retryAfter time.Duration) *Maintenance {
+	m := &Maintenance{}
+	m.Set(enabled, message, retryAfter)
+	return m
+}
+
+// Set turns maintenance mode on or off
+func (m *Maintenance) Set(enabled bool, message string, retryAfter time.Duration) {
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if enabled != m.state.Enabled || m.state.Since.IsZero() {
+		m.state.Since = time.Now()
+	}
+	m.state.Enabled = enabled
+	m.state.Message = message
+	m.state.RetryAfter = retryAfter
+}
+
+// State returns the current maintenance state
+func (m *Maintenance) State() MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Middleware refuses requests that change anything while maintenance mode is on. GET,
+// HEAD and OPTIONS requests, including tracking streams, always pass, and so do the
+// exempt routes.
+func (m *Maintenance) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		for _, route := range maintenanceExemptRoutes {
+			if strings.HasSuffix(c.FullPath(), route) {
+				c.Next()
+				return
+			}
+		}
+
+		state := m.State()
+		if !state.Enabled {
+			c.Next()
+			return
+		}
+
+		if state.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.FormatInt(int64(state.RetryAfter.Seconds()), 10))
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": state.Message})
+	}
+}
+
+// RegisterRoutes registers the maintenance API routes on a version group
+func (m *Maintenance) RegisterRoutes(api *gin.RouterGroup) {
+	api.GET(maintenanceRoute, m.GetMaintenance)
+	api.PUT(maintenanceRoute, m.SetMaintenance)
+}
+
+// GetMaintenance reports whether maintenance mode is on
+func (m *Maintenance) GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, newMaintenanceResponse(m.State()))
+}
+
+// SetMaintenance turns maintenance mode on or off on this gateway instance
+func (m *Maintenance) SetMaintenance(c *gin.Context) {
+	var request MaintenanceRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	retryAfter := m.State().RetryAfter
+	if request.RetryAfterSeconds > 0 {
+		retryAfter = time.Duration(request.RetryAfterSeconds) * time.Second
+	}
+	m.Set(*request.Enabled, request.Message, retryAfter)
+
+	c.JSON(http.StatusOK, newMaintenanceResponse(m.State()))
+}
+
+// newMaintenanceResponse converts a maintenance state for an API response
+func newMaintenanceResponse(state MaintenanceState) maintenanceResponse {
+	return maintenanceResponse{
+		MaintenanceState:  state,
+		RetryAfterSeconds: int64(state.RetryAfter.Seconds()),
+	}
+}
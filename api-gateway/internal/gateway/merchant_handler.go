@@ -0,0 +1,277 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	merchantPb "github.com/order-api-microservices/proto/merchant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MerchantHandler handles the API endpoints for merchants and their menus
+type MerchantHandler struct {
+	merchantClient merchantPb.MerchantServiceClient
+}
+
+// NewMerchantHandler creates a new merchant handler
+func NewMerchantHandler(merchantClient merchantPb.MerchantServiceClient) *MerchantHandler {
+	return &MerchantHandler{
+		merchantClient: merchantClient,
+	}
+}
+
+// RegisterRoutes registers the merchant API routes on a version group. Anyone can browse
+// merchants and their menus; only admins change them.
+func (h *MerchantHandler) RegisterRoutes(api *gin.RouterGroup) {
+	merchants := api.Group("/merchants")
+	{
+		merchants.GET("", h.ListMerchants)
+		merchants.GET("/:id", h.GetMerchant)
+	}
+
+	admin := api.Group("/admin/merchants")
+	{
+		admin.POST("", h.CreateMerchant)
+		admin.PUT("/:id", h.UpdateMerchant)
+		admin.POST("/:id/items", h.CreateCatalogItem)
+		admin.PUT("/:id/items/:item_id", h.UpdateCatalogItem)
+		admin.DELETE("/:id/items/:item_id", h.DeleteCatalogItem)
+	}
+}
+
+// ListMerchants lists the merchants, optionally of one city
+func (h *MerchantHandler) ListMerchants(c *gin.Context) {
+	// Call the merchant service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.merchantClient.ListMerchants(ctx, &merchantPb.ListMerchantsRequest{
+		City: c.Query("city"),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list merchants")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetMerchant gets a merchant with its menu
+func (h *MerchantHandler) GetMerchant(c *gin.Context) {
+	merchantID := c.Param("id")
+	if merchantID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "merchant ID is required"})
+		return
+	}
+
+	// Call the merchant service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.merchantClient.GetMerchant(ctx, &merchantPb.GetMerchantRequest{MerchantId: merchantID})
+	if err != nil {
+		h.handleError(c, err, "Failed to get merchant")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Merchant)
+}
+
+// CreateMerchant adds a merchant users can order from
+func (h *MerchantHandler) CreateMerchant(c *gin.Context) {
+	var request MerchantRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the merchant service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.merchantClient.CreateMerchant(ctx, &merchantPb.CreateMerchantRequest{
+		Merchant: convertMerchantFromRequest(&request),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to create merchant")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.Merchant)
+}
+
+// UpdateMerchant replaces a merchant's details
+func (h *MerchantHandler) UpdateMerchant(c *gin.Context) {
+	merchantID := c.Param("id")
+	if merchantID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "merchant ID is required"})
+		return
+	}
+
+	var request MerchantRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the merchant service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.merchantClient.UpdateMerchant(ctx, &merchantPb.UpdateMerchantRequest{
+		MerchantId: merchantID,
+		Merchant:   convertMerchantFromRequest(&request),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to update merchant")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Merchant)
+}
+
+// CreateCatalogItem adds an item to a merchant's menu
+func (h *MerchantHandler) CreateCatalogItem(c *gin.Context) {
+	merchantID := c.Param("id")
+	if merchantID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "merchant ID is required"})
+		return
+	}
+
+	var request CatalogItemRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the merchant service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.merchantClient.CreateCatalogItem(ctx, &merchantPb.CreateCatalogItemRequest{
+		MerchantId: merchantID,
+		Item:       convertCatalogItemFromRequest(&request),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to create catalog item")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.Item)
+}
+
+// UpdateCatalogItem replaces an item on a merchant's menu
+func (h *MerchantHandler) UpdateCatalogItem(c *gin.Context) {
+	merchantID := c.Param("id")
+	itemID := c.Param("item_id")
+	if merchantID == "" || itemID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "merchant ID and item ID are required"})
+		return
+	}
+
+	var request CatalogItemRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the merchant service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.merchantClient.UpdateCatalogItem(ctx, &merchantPb.UpdateCatalogItemRequest{
+		MerchantId: merchantID,
+		ItemId:     itemID,
+		Item:       convertCatalogItemFromRequest(&request),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to update catalog item")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Item)
+}
+
+// DeleteCatalogItem removes an item from a merchant's menu
+func (h *MerchantHandler) DeleteCatalogItem(c *gin.Context) {
+	merchantID := c.Param("id")
+	itemID := c.Param("item_id")
+	if merchantID == "" || itemID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "merchant ID and item ID are required"})
+		return
+	}
+
+	// Call the merchant service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := h.merchantClient.DeleteCatalogItem(ctx, &merchantPb.DeleteCatalogItemRequest{
+		MerchantId: merchantID,
+		ItemId:     itemID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to delete catalog item")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleError maps a merchant service error to an HTTP response
+func (h *MerchantHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// convertMerchantFromRequest converts a validated merchant request to protobuf
+func convertMerchantFromRequest(request *MerchantRequest) *merchantPb.MerchantDetails {
+	active := true
+	if request.Active != nil {
+		active = *request.Active
+	}
+
+	return &merchantPb.MerchantDetails{
+		Name:      request.Name,
+		City:      request.City,
+		Address:   request.Address,
+		Latitude:  *request.Latitude,
+		Longitude: *request.Longitude,
+		Active:    active,
+	}
+}
+
+// convertCatalogItemFromRequest converts a validated catalog item request to protobuf
+func convertCatalogItemFromRequest(request *CatalogItemRequest) *merchantPb.CatalogItemDetails {
+	available := true
+	if request.Available != nil {
+		available = *request.Available
+	}
+
+	details := &merchantPb.CatalogItemDetails{
+		Name:        request.Name,
+		Description: request.Description,
+		Category:    request.Category,
+		Price:       request.Price,
+		Available:   available,
+	}
+	if request.Stock != nil {
+		details.StockTracked = true
+		details.Stock = *request.Stock
+	}
+	return details
+}
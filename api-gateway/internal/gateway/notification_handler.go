@@ -0,0 +1,365 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	notificationPb "github.com/order-api-microservices/proto/notification"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// campaignResponse is a broadcast campaign as returned by the API, with its payload as JSON
+type campaignResponse struct {
+	*notificationPb.Campaign
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// NotificationHandler handles the API endpoints behind the apps' notification badges and
+// push devices, and the admin API endpoints for broadcast campaigns
+type NotificationHandler struct {
+	inboxClient    notificationPb.NotificationInboxServiceClient
+	campaignClient notificationPb.NotificationCampaignServiceClient
+	deviceClient   notificationPb.NotificationDeviceServiceClient
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(inboxClient notificationPb.NotificationInboxServiceClient, campaignClient notificationPb.NotificationCampaignServiceClient, deviceClient notificationPb.NotificationDeviceServiceClient) *NotificationHandler {
+	return &NotificationHandler{
+		inboxClient:    inboxClient,
+		campaignClient: campaignClient,
+		deviceClient:   deviceClient,
+	}
+}
+
+// RegisterRoutes registers the notification API routes on a version group. Users and
+// providers both receive notifications, so each gets the same routes.
+func (h *NotificationHandler) RegisterRoutes(api *gin.RouterGroup) {
+	recipients := []struct {
+		path      string
+		ownerType string
+	}{
+		{path: "/users", ownerType: "USER"},
+		{path: "/providers", ownerType: "PROVIDER"},
+	}
+	for _, recipient := range recipients {
+		notifications := api.Group(recipient.path + "/:id/notifications")
+		{
+			notifications.GET("/unread-count", h.GetUnreadCount)
+			notifications.POST("/read-all", h.MarkAllRead)
+		}
+
+		devices := api.Group(recipient.path + "/:id/devices")
+		{
+			devices.GET("", h.ListDevices)
+			devices.PUT("/:device_id", h.registerDevice(recipient.ownerType))
+			devices.DELETE("/:device_id", h.UnregisterDevice)
+		}
+	}
+
+	campaigns := api.Group("/admin/notifications/campaigns")
+	{
+		campaigns.GET("", h.ListCampaigns)
+		campaigns.POST("", h.CreateCampaign)
+		campaigns.GET("/:id", h.GetCampaign)
+		campaigns.POST("/:id/cancel", h.CancelCampaign)
+	}
+}
+
+// GetUnreadCount returns how many notifications a user or provider has not read
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	recipientID := c.Param("id")
+	if recipientID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "recipient ID is required"})
+		return
+	}
+
+	// Call the notification service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.inboxClient.GetUnreadCount(ctx, &notificationPb.GetUnreadCountRequest{
+		RecipientId: recipientID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to count unread notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": resp.UnreadCount})
+}
+
+// MarkAllRead marks a user's or provider's notifications as read, up to the optional
+// before time so ones that arrived after the app last listed them keep the badge lit
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	recipientID := c.Param("id")
+	if recipientID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "recipient ID is required"})
+		return
+	}
+
+	before, ok := parseTimeQuery(c, "before")
+	if !ok {
+		return
+	}
+
+	// Call the notification service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.inboxClient.MarkAllRead(ctx, &notificationPb.MarkAllReadRequest{
+		RecipientId: recipientID,
+		Before:      before,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to mark notifications as read")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": resp.Success, "marked": resp.Marked})
+}
+
+// registerDevice stores the push token of a user's or provider's device, replacing the
+// one it had. The apps call it on sign-in and whenever the platform refreshes the token.
+func (h *NotificationHandler) registerDevice(ownerType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID := c.Param("id")
+		deviceID := c.Param("device_id")
+		if ownerID == "" || deviceID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "owner ID and device ID are required"})
+			return
+		}
+
+		var request RegisterDeviceRequest
+
+		if !bindJSON(c, &request) {
+			return
+		}
+
+		// Call the notification service
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		resp, err := h.deviceClient.RegisterDevice(ctx, &notificationPb.RegisterDeviceRequest{
+			OwnerId:    ownerID,
+			OwnerType:  ownerType,
+			DeviceId:   deviceID,
+			Platform:   request.Platform,
+			Token:      request.Token,
+			AppVersion: request.AppVersion,
+		})
+		if err != nil {
+			h.handleError(c, err, "Failed to register device")
+			return
+		}
+
+		c.JSON(http.StatusOK, resp.Device)
+	}
+}
+
+// UnregisterDevice forgets a user's or provider's device, e.g. when they sign out on it
+func (h *NotificationHandler) UnregisterDevice(c *gin.Context) {
+	ownerID := c.Param("id")
+	deviceID := c.Param("device_id")
+	if ownerID == "" || deviceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner ID and device ID are required"})
+		return
+	}
+
+	// Call the notification service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := h.deviceClient.UnregisterDevice(ctx, &notificationPb.UnregisterDeviceRequest{
+		OwnerId:  ownerID,
+		DeviceId: deviceID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to unregister device")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDevices lists the devices a user or provider gets push notifications on
+func (h *NotificationHandler) ListDevices(c *gin.Context) {
+	ownerID := c.Param("id")
+	if ownerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner ID is required"})
+		return
+	}
+
+	// Call the notification service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.deviceClient.ListDevices(ctx, &notificationPb.ListDevicesRequest{
+		OwnerId:  ownerID,
+		Platform: c.Query("platform"),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list devices")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"devices": resp.Devices})
+}
+
+// CreateCampaign queues a notification for every user or provider in a segment. It is
+// sent in the background; the campaign reports how far it has got.
+func (h *NotificationHandler) CreateCampaign(c *gin.Context) {
+	var request CreateCampaignRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	var payload []byte
+	if request.Payload != nil {
+		var err error
+		payload, err = json.Marshal(request.Payload)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "payload must be a JSON object"})
+			return
+		}
+	}
+
+	// Call the notification service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.campaignClient.CreateCampaign(ctx, &notificationPb.CreateCampaignRequest{
+		Segment:          request.Segment,
+		City:             request.City,
+		ActiveWithinDays: request.ActiveWithinDays,
+		NotificationType: request.NotificationType,
+		Title:            request.Title,
+		Message:          request.Message,
+		Payload:          payload,
+		CreatedBy:        request.CreatedBy,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to create campaign")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, toCampaignResponse(resp.Campaign))
+}
+
+// ListCampaigns lists broadcast campaigns, newest first, optionally filtered by status
+func (h *NotificationHandler) ListCampaigns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Call the notification service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.campaignClient.ListCampaigns(ctx, &notificationPb.ListCampaignsRequest{
+		Status: c.Query("status"),
+		Page:   int32(page),
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list campaigns")
+		return
+	}
+
+	campaigns := make([]campaignResponse, 0, len(resp.Campaigns))
+	for _, campaign := range resp.Campaigns {
+		campaigns = append(campaigns, toCampaignResponse(campaign))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"campaigns": campaigns,
+		"total":     resp.Total,
+		"page":      resp.Page,
+		"limit":     resp.Limit,
+	})
+}
+
+// GetCampaign gets a broadcast campaign and how many it has notified so far
+func (h *NotificationHandler) GetCampaign(c *gin.Context) {
+	campaignID := c.Param("id")
+	if campaignID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "campaign ID is required"})
+		return
+	}
+
+	// Call the notification service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.campaignClient.GetCampaign(ctx, &notificationPb.GetCampaignRequest{CampaignId: campaignID})
+	if err != nil {
+		h.handleError(c, err, "Failed to get campaign")
+		return
+	}
+
+	c.JSON(http.StatusOK, toCampaignResponse(resp.Campaign))
+}
+
+// CancelCampaign stops a broadcast campaign that is still being sent
+func (h *NotificationHandler) CancelCampaign(c *gin.Context) {
+	campaignID := c.Param("id")
+	if campaignID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "campaign ID is required"})
+		return
+	}
+
+	var request CancelCampaignRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the notification service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.campaignClient.CancelCampaign(ctx, &notificationPb.CancelCampaignRequest{
+		CampaignId:  campaignID,
+		CancelledBy: request.CancelledBy,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to cancel campaign")
+		return
+	}
+
+	c.JSON(http.StatusOK, toCampaignResponse(resp.Campaign))
+}
+
+// toCampaignResponse shows a campaign's payload as JSON rather than encoded bytes
+func toCampaignResponse(campaign *notificationPb.Campaign) campaignResponse {
+	return campaignResponse{
+		Campaign: campaign,
+		Payload:  json.RawMessage(campaign.GetPayload()),
+	}
+}
+
+// handleError maps a notification service error to an HTTP response
+func (h *NotificationHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	case codes.Unavailable:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
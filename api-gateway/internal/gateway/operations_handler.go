@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	operationsPb "github.com/order-api-microservices/proto/operations"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Bounds of how often the operations stream sends counters, in seconds
+const (
+	defaultOperationsInterval = 5
+	minOperationsInterval     = 2
+	maxOperationsInterval     = 60
+)
+
+// OperationsHandler handles the operations dashboard endpoints for live system counters
+type OperationsHandler struct {
+	operationsClient operationsPb.OperationsServiceClient
+}
+
+// NewOperationsHandler creates a new operations handler
+func NewOperationsHandler(operationsClient operationsPb.OperationsServiceClient) *OperationsHandler {
+	return &OperationsHandler{
+		operationsClient: operationsClient,
+	}
+}
+
+// RegisterRoutes registers the operations API routes on a version group
+func (h *OperationsHandler) RegisterRoutes(api *gin.RouterGroup) {
+	operations := api.Group("/admin/operations")
+	{
+		operations.GET("/counters", h.GetLiveCounters)
+		operations.GET("/stream", h.StreamLiveCounters)
+	}
+}
+
+// GetLiveCounters gets the current active orders by status, online providers per zone
+// and dispatch latency
+func (h *OperationsHandler) GetLiveCounters(c *gin.Context) {
+	window, _ := strconv.Atoi(c.Query("latency_window"))
+
+	// Call the operations service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.operationsClient.GetLiveCounters(ctx, &operationsPb.GetLiveCountersRequest{
+		LatencyWindowSeconds: int32(window),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to get live counters")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// StreamLiveCounters streams the live counters using Server-Sent Events, sending them
+// right away and then every interval seconds until the client disconnects. A failed
+// refresh is sent as an error event and the stream carries on.
+func (h *OperationsHandler) StreamLiveCounters(c *gin.Context) {
+	interval, err := strconv.Atoi(c.DefaultQuery("interval", strconv.Itoa(defaultOperationsInterval)))
+	if err != nil || interval < minOperationsInterval || interval > maxOperationsInterval {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be between 2 and 60 seconds"})
+		return
+	}
+	window, _ := strconv.Atoi(c.Query("latency_window"))
+	request := &operationsPb.GetLiveCountersRequest{LatencyWindowSeconds: int32(window)}
+
+	// Set up SSE
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Transfer-Encoding", "chunked")
+
+	ctx := c.Request.Context()
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		counters, err := h.operationsClient.GetLiveCounters(callCtx, request)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			data, _ := json.Marshal(gin.H{"error": status.Convert(err).Message()})
+			c.SSEvent("error", string(data))
+		} else {
+			data, err := json.Marshal(counters)
+			if err == nil {
+				c.SSEvent("counters", string(data))
+			}
+		}
+		c.Writer.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleError maps an operations service error to an HTTP response
+func (h *OperationsHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	blockchainPb "github.com/order-api-microservices/proto/blockchain"
+	pb "github.com/order-api-microservices/proto/order"
+	providerPb "github.com/order-api-microservices/proto/provider"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OrderDetailsHandler composes order details from several services in one call
+type OrderDetailsHandler struct {
+	orderClient      pb.OrderServiceClient
+	providerClient   providerPb.ProviderServiceClient
+	blockchainClient blockchainPb.BlockchainServiceClient
+}
+
+// NewOrderDetailsHandler creates a new order details handler
+func NewOrderDetailsHandler(
+	orderClient pb.OrderServiceClient,
+	providerClient providerPb.ProviderServiceClient,
+	blockchainClient blockchainPb.BlockchainServiceClient,
+) *OrderDetailsHandler {
+	return &OrderDetailsHandler{
+		orderClient:      orderClient,
+		providerClient:   providerClient,
+		blockchainClient: blockchainClient,
+	}
+}
+
+// RegisterRoutes registers the order details API routes on a version group
+func (h *OrderDetailsHandler) RegisterRoutes(api *gin.RouterGroup) {
+	orders := api.Group("/orders")
+	{
+		orders.GET("/:id/full", h.GetOrderFull)
+		orders.GET("/:id/crypto-payment", h.GetCryptoPayment)
+	}
+}
+
+// section is one part of the aggregated response; Error is set when its service call failed
+type section struct {
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// GetOrderFull returns the order together with its provider profile, payment status,
+// latest location and blockchain verification. The order itself is required; the other
+// sections are fetched in parallel and reported individually if they fail.
+func (h *OrderDetailsHandler) GetOrderFull(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.GetOrder(ctx, &pb.GetOrderRequest{OrderId: orderID})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get order"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	order := resp.Order
+
+	// Each dependent call gets a shorter budget so one slow service cannot stall the response
+	subCtx, subCancel := context.WithTimeout(ctx, 3*time.Second)
+	defer subCancel()
+
+	var (
+		wg           sync.WaitGroup
+		provider     section
+		location     section
+		verification section
+	)
+
+	if order.ProviderId != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			provider = h.fetchProvider(subCtx, order.ProviderId)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		location = h.fetchLatestLocation(subCtx, order.Id)
+	}()
+
+	if order.BlockchainTxHash != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			verification = h.fetchVerification(subCtx, order.Id, order.BlockchainTxHash)
+		}()
+	}
+
+	wg.Wait()
+
+	partial := provider.Error != "" || location.Error != "" || verification.Error != ""
+
+	c.JSON(http.StatusOK, gin.H{
+		"order":      transform(c, ResourceOrder, order),
+		"provider":   provider,
+		"payment":    section{Data: paymentStatus(order)},
+		"location":   location,
+		"blockchain": verification,
+		"partial":    partial,
+	})
+}
+
+// GetCryptoPayment returns the deposit address and amount for paying an order in crypto,
+// and how many confirmations the payment has
+func (h *OrderDetailsHandler) GetCryptoPayment(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.blockchainClient.GetCryptoPayment(ctx, &blockchainPb.GetCryptoPaymentRequest{OrderId: orderID})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				return
+			case codes.FailedPrecondition:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			case codes.Unavailable:
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Blockchain service unavailable"})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get crypto payment"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Payment)
+}
+
+func (h *OrderDetailsHandler) fetchProvider(ctx context.Context, providerID string) section {
+	resp, err := h.providerClient.GetProvider(ctx, &providerPb.GetProviderRequest{ProviderId: providerID})
+	if err != nil {
+		return section{Error: describeError(err, "provider")}
+	}
+	return section{Data: maskProvider(resp.Provider)}
+}
+
+func (h *OrderDetailsHandler) fetchLatestLocation(ctx context.Context, orderID string) section {
+	resp, err := h.orderClient.GetLatestLocation(ctx, &pb.GetLatestLocationRequest{OrderId: orderID})
+	if err != nil {
+		// No location reported yet is not a failure
+		if status.Code(err) == codes.NotFound {
+			return section{}
+		}
+		return section{Error: describeError(err, "location")}
+	}
+	return section{Data: resp}
+}
+
+func (h *OrderDetailsHandler) fetchVerification(ctx context.Context, orderID, txHash string) section {
+	resp, err := h.blockchainClient.VerifyOrder(ctx, &blockchainPb.VerifyOrderRequest{
+		OrderId:         orderID,
+		TransactionHash: txHash,
+	})
+	if err != nil {
+		return section{Error: describeError(err, "blockchain verification")}
+	}
+	return section{Data: resp}
+}
+
+// paymentStatus derives the payment state from the order; there is no payment service yet
+func paymentStatus(order *pb.Order) gin.H {
+	var paymentState string
+	switch order.Status {
+	case pb.OrderStatus_ORDER_STATUS_CREATED, pb.OrderStatus_ORDER_STATUS_PAYMENT_PENDING:
+		paymentState = "PENDING"
+	case pb.OrderStatus_ORDER_STATUS_REFUNDED:
+		paymentState = "REFUNDED"
+	case pb.OrderStatus_ORDER_STATUS_CANCELLED:
+		paymentState = "CANCELLED"
+	default:
+		paymentState = "COMPLETED"
+	}
+
+	return gin.H{
+		"status":         paymentState,
+		"method":         order.PaymentMethod.String(),
+		"transaction_id": order.TransactionId,
+		"total_price":    order.TotalPrice,
+	}
+}
+
+func describeError(err error, what string) string {
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return what + " lookup timed out"
+	case codes.Unavailable:
+		return what + " service unavailable"
+	case codes.NotFound:
+		return what + " not found"
+	default:
+		return "failed to get " + what
+	}
+}
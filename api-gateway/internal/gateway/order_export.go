@@ -0,0 +1,257 @@
+package gateway
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/order-api-microservices/pkg/requestid"
+	pb "github.com/order-api-microservices/proto/order"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ExportFormatCSV is the only value the export query parameter takes
+const ExportFormatCSV = "csv"
+
+// exportFlushRows is how many rows are written between flushes to the client
+const exportFlushRows = 100
+
+// exportDateFormat is the day-only form from and to can be given in
+const exportDateFormat = "2006-01-02"
+
+// exportColumn is a column an order export can include
+type exportColumn struct {
+	name  string
+	value func(order *pb.Order) string
+}
+
+// exportColumns are every column an order export can include, in their default order.
+// Amounts are in minor units and times are RFC 3339 in UTC.
+var exportColumns = []exportColumn{
+	{"id", func(o *pb.Order) string { return o.Id }},
+	{"user_id", func(o *pb.Order) string { return o.UserId }},
+	{"provider_id", func(o *pb.Order) string { return o.ProviderId }},
+	{"order_type", func(o *pb.Order) string { return strings.TrimPrefix(o.OrderType.String(), "ORDER_TYPE_") }},
+	{"status", func(o *pb.Order) string { return strings.TrimPrefix(o.Status.String(), "ORDER_STATUS_") }},
+	{"payment_method", func(o *pb.Order) string { return strings.TrimPrefix(o.PaymentMethod.String(), "PAYMENT_METHOD_") }},
+	{"total_price", func(o *pb.Order) string { return strconv.FormatInt(o.TotalPrice, 10) }},
+	{"platform_fee", func(o *pb.Order) string { return strconv.FormatInt(o.PlatformFee, 10) }},
+	{"provider_fee", func(o *pb.Order) string { return strconv.FormatInt(o.ProviderFee, 10) }},
+	{"tip_amount", func(o *pb.Order) string { return strconv.FormatInt(o.TipAmount, 10) }},
+	{"cancellation_fee", func(o *pb.Order) string { return strconv.FormatInt(o.CancellationFee, 10) }},
+	{"transaction_id", func(o *pb.Order) string { return o.TransactionId }},
+	{"blockchain_tx_hash", func(o *pb.Order) string { return o.BlockchainTxHash }},
+	{"created_at", func(o *pb.Order) string { return formatExportTime(o.CreatedAt) }},
+	{"updated_at", func(o *pb.Order) string { return formatExportTime(o.UpdatedAt) }},
+	{"pickup_address", func(o *pb.Order) string { return o.GetPickupLocation().GetAddress() }},
+	{"pickup_city", func(o *pb.Order) string { return o.GetPickupLocation().GetCity() }},
+	{"destination_address", func(o *pb.Order) string { return o.GetDestinationLocation().GetAddress() }},
+	{"destination_city", func(o *pb.Order) string { return o.GetDestinationLocation().GetCity() }},
+	{"items", func(o *pb.Order) string { return strconv.Itoa(len(o.Items)) }},
+	{"notes", func(o *pb.Order) string { return o.Notes }},
+}
+
+// defaultExportColumns are exported when the request does not choose columns
+var defaultExportColumns = []string{
+	"id", "user_id", "provider_id", "order_type", "status", "payment_method",
+	"total_price", "platform_fee", "provider_fee", "tip_amount", "cancellation_fee",
+	"created_at", "updated_at",
+}
+
+// wantsExport reports whether a listing request asks for an export, responding with 400
+// when it asks for a format other than CSV
+func wantsExport(c *gin.Context) (bool, bool) {
+	format := c.Query("export")
+	if format == "" {
+		return false, true
+	}
+	if format != ExportFormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "export must be csv"})
+		return false, false
+	}
+	return true, true
+}
+
+// ListOrders exports every order matching the query. The admin listing is only
+// available as an export.
+func (h *OrderHandler) ListOrders(c *gin.Context) {
+	export, ok := wantsExport(c)
+	if !ok {
+		return
+	}
+	if !export {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the admin order listing is only available as an export; add export=csv"})
+		return
+	}
+
+	h.exportOrders(c, &pb.ExportOrdersRequest{
+		UserId:     c.Query("user_id"),
+		ProviderId: c.Query("provider_id"),
+	})
+}
+
+// exportOrders streams the orders selected by req, narrowed by the query's status,
+// order type and date range, as CSV with the query's columns. Rows are written as the
+// order service sends them, so nothing is buffered. Once the first row is out the status
+// can no longer change, so a failure mid-export ends the body early and is logged.
+func (h *OrderHandler) exportOrders(c *gin.Context, req *pb.ExportOrdersRequest) {
+	columns, err := parseExportColumns(c.Query("columns"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Status = convertOrderStatusFromString(c.Query("status"))
+	req.OrderType = convertOrderTypeFromString(c.Query("order_type"))
+	var ok bool
+	if req.From, ok = parseExportTime(c, "from", false); !ok {
+		return
+	}
+	if req.To, ok = parseExportTime(c, "to", true); !ok {
+		return
+	}
+
+	// Exports run for as long as the client keeps reading
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	stream, err := h.orderClient.ExportOrders(ctx, req)
+	if err != nil {
+		h.handleExportError(c, err)
+		return
+	}
+
+	// Wait for the first order so a rejected export can still be answered with an error
+	first, err := stream.Recv()
+	if err != nil && !errors.Is(err, io.EOF) {
+		h.handleExportError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("orders-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	writer.Write(header)
+
+	record := make([]string, len(columns))
+	rows := 0
+	for order := first; order != nil; {
+		for i, column := range columns {
+			record[i] = column.value(order)
+		}
+		if err := writer.Write(record); err != nil {
+			requestid.Logf(c.Request.Context(), "Order export stopped after %d rows: %v", rows, err)
+			return
+		}
+
+		rows++
+		if rows%exportFlushRows == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+		}
+
+		order, err = stream.Recv()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				requestid.Logf(c.Request.Context(), "Order export stopped after %d rows: %v", rows, err)
+			}
+			break
+		}
+	}
+
+	writer.Flush()
+	c.Writer.Flush()
+}
+
+// parseExportColumns resolves a comma-separated list of column names, or the default
+// columns when the list is empty
+func parseExportColumns(value string) ([]exportColumn, error) {
+	names := defaultExportColumns
+	if value != "" {
+		names = strings.Split(value, ",")
+	}
+
+	columns := make([]exportColumn, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, column := range exportColumns {
+			if column.name == name {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown export column %q", name)
+		}
+	}
+
+	return columns, nil
+}
+
+// parseExportTime parses an optional from or to query parameter given as an RFC 3339
+// time or a day. A day means its start, or for the end of a range the start of the next
+// day so the whole day is included. It responds with 400 when the value is malformed.
+func parseExportTime(c *gin.Context, param string, endOfRange bool) (*timestamppb.Timestamp, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, true
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return timestamppb.New(t), true
+	}
+
+	day, err := time.Parse(exportDateFormat, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be a day (YYYY-MM-DD) or an RFC 3339 time"})
+		return nil, false
+	}
+	if endOfRange {
+		day = day.AddDate(0, 0, 1)
+	}
+	return timestamppb.New(day), true
+}
+
+// formatExportTime formats a timestamp for an export, or returns an empty string when unset
+func formatExportTime(t *timestamppb.Timestamp) string {
+	if t == nil {
+		return ""
+	}
+	return t.AsTime().UTC().Format(time.RFC3339)
+}
+
+// handleExportError maps an order service error raised before an export started to an
+// HTTP response
+func (h *OrderHandler) handleExportError(c *gin.Context, err error) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export orders"})
+	}
+}
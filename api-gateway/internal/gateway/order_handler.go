@@ -10,31 +10,42 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	pb "github.com/order-api-microservices/proto/order"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// duplicateOrderIDKey is the trailer in which the order service names the order a refused
+// duplicate repeats
+const duplicateOrderIDKey = "duplicate-order-id"
+
 // OrderHandler handles order API endpoints
 type OrderHandler struct {
 	orderClient pb.OrderServiceClient
+	cache       *ResponseCache
 }
 
-// NewOrderHandler creates a new order handler
-func NewOrderHandler(orderClient pb.OrderServiceClient) *OrderHandler {
+// NewOrderHandler creates a new order handler; responseCache may be nil
+func NewOrderHandler(orderClient pb.OrderServiceClient, responseCache *ResponseCache) *OrderHandler {
 	return &OrderHandler{
 		orderClient: orderClient,
+		cache:       responseCache,
 	}
 }
 
-// RegisterRoutes registers the order API routes
-func (h *OrderHandler) RegisterRoutes(router *gin.Engine) {
-	orders := router.Group("/api/v1/orders")
+// RegisterRoutes registers the order API routes on a version group
+func (h *OrderHandler) RegisterRoutes(api *gin.RouterGroup) {
+	orders := api.Group("/orders")
 	{
 		orders.POST("", h.CreateOrder)
-		orders.GET("/:id", h.GetOrder)
+		orders.GET("/:id", h.cache.Middleware(CacheRouteGetOrder, orderIDCacheKey), h.GetOrder)
 		orders.PUT("/:id/status", h.UpdateOrderStatus)
 		orders.POST("/:id/cancel", h.CancelOrder)
-		orders.GET("/user/:id", h.ListUserOrders)
+		orders.GET("/:id/cancellation-preview", h.PreviewCancellation)
+		orders.POST("/:id/refund", h.RefundOrder)
+		orders.GET("/user/:id", h.cache.Middleware(CacheRouteListUserOrders, userOrdersCacheKey), h.ListUserOrders)
 		orders.GET("/provider/:id", h.ListProviderOrders)
 		orders.GET("/:id/track", h.TrackOrder) // WebSocket endpoint for tracking
 		
@@ -42,43 +53,54 @@ func (h *OrderHandler) RegisterRoutes(router *gin.Engine) {
 		orders.POST("/:id/assign", h.AssignProvider)
 		orders.POST("/:id/accept", h.AcceptOrder)
 		orders.POST("/:id/reject", h.RejectOrder)
+		orders.POST("/:id/handoff", h.HandOffOrder)
 		orders.POST("/:id/location", h.UpdateLocation)
+		orders.POST("/:id/locations", h.BatchUpdateLocation)
+		orders.GET("/:id/locations", h.GetLocationHistory)
+		orders.GET("/:id/route", h.GetOrderRoute)
+		orders.GET("/:id/batch", h.GetOrderBatch)
+		orders.GET("/:id/rental", h.GetRental)
+		orders.POST("/:id/rental/extensions", h.RequestRentalExtension)
+		orders.POST("/:id/rental/extensions/:extension_id/approve", h.ApproveRentalExtension)
+		orders.POST("/:id/rental/extensions/:extension_id/decline", h.DeclineRentalExtension)
+		orders.POST("/:id/destination-changes", h.RequestDestinationChange)
+		orders.POST("/:id/destination-changes/:change_id/accept", h.AcceptDestinationChange)
+		orders.POST("/:id/destination-changes/:change_id/decline", h.DeclineDestinationChange)
+		orders.POST("/:id/tip", h.AddTip)
+		orders.PUT("/:id/details", h.UpdateOrderDetails)
+		orders.POST("/:id/deliver", h.CompleteDelivery)
+		orders.POST("/:id/delivery-pin/resend", h.ResendDeliveryPIN)
+	}
+
+	providers := api.Group("/providers")
+	{
+		providers.GET("/:id/ledger", h.ListProviderLedger)
+	}
+
+	admin := api.Group("/admin/orders")
+	{
+		admin.GET("", h.ListOrders) // Export only, with export=csv
+		admin.POST("/:id/handoff", h.AdminHandOffOrder)
 	}
 }
 
 // CreateOrder creates a new order
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
-	var request struct {
-		UserID             string                 `json:"user_id" binding:"required"`
-		OrderType          string                 `json:"order_type" binding:"required"`
-		PickupLocation     map[string]interface{} `json:"pickup_location" binding:"required"`
-		DestinationLocation map[string]interface{} `json:"destination_location" binding:"required"`
-		Items              []map[string]interface{} `json:"items"`
-		PaymentMethod      string                 `json:"payment_method" binding:"required"`
-		Notes              string                 `json:"notes"`
-	}
+	var request CreateOrderRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &request) {
 		return
 	}
 
 	// Convert request to protobuf
-	req := &pb.CreateOrderRequest{
-		UserId:             request.UserID,
-		OrderType:          convertOrderTypeFromString(request.OrderType),
-		PickupLocation:     convertLocationFromMap(request.PickupLocation),
-		DestinationLocation: convertLocationFromMap(request.DestinationLocation),
-		Items:              convertOrderItemsFromSlice(request.Items),
-		PaymentMethod:      convertPaymentMethodFromString(request.PaymentMethod),
-		Notes:              request.Notes,
-	}
+	req := convertCreateOrderFromRequest(&request)
 
 	// Call the order service
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
-	resp, err := h.orderClient.CreateOrder(ctx, req)
+	var trailer metadata.MD
+	resp, err := h.orderClient.CreateOrder(ctx, req, grpc.Trailer(&trailer))
 	if err != nil {
 		st, ok := status.FromError(err)
 		if ok {
@@ -86,6 +108,25 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 			case codes.InvalidArgument:
 				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
 				return
+			case codes.FailedPrecondition:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": st.Message()})
+				return
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				return
+			case codes.PermissionDenied:
+				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+				return
+			case codes.Unavailable:
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Stock could not be reserved"})
+				return
+			case codes.AlreadyExists:
+				body := gin.H{"error": st.Message()}
+				if ids := trailer.Get(duplicateOrderIDKey); len(ids) > 0 {
+					body["order_id"] = ids[0]
+				}
+				c.JSON(http.StatusConflict, body)
+				return
 			default:
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
 				return
@@ -95,7 +136,7 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, resp.Order)
+	respond(c, http.StatusCreated, ResourceOrder, resp.Order)
 }
 
 // GetOrder gets an order by ID
@@ -127,7 +168,7 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, resp.Order)
+	respond(c, http.StatusOK, ResourceOrder, resp.Order)
 }
 
 // UpdateOrderStatus updates the status of an order
@@ -138,14 +179,9 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	var request struct {
-		Status    string `json:"status" binding:"required"`
-		UpdatedBy string `json:"updated_by" binding:"required"`
-		Notes     string `json:"notes"`
-	}
+	var request UpdateOrderStatusRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &request) {
 		return
 	}
 
@@ -172,6 +208,9 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 			case codes.InvalidArgument:
 				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
 				return
+			case codes.Unavailable:
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment service unavailable"})
+				return
 			default:
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
 				return
@@ -181,7 +220,9 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, resp.Order)
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	respond(c, http.StatusOK, ResourceOrder, resp.Order)
 }
 
 // CancelOrder cancels an order
@@ -192,13 +233,9 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 		return
 	}
 
-	var request struct {
-		CancelledBy string `json:"cancelled_by" binding:"required"`
-		Reason      string `json:"reason" binding:"required"`
-	}
+	var request CancelOrderRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &request) {
 		return
 	}
 
@@ -224,6 +261,9 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 			case codes.FailedPrecondition:
 				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
 				return
+			case codes.Unavailable:
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment service unavailable"})
+				return
 			default:
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel order"})
 				return
@@ -233,10 +273,109 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, resp.Order)
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	respond(c, http.StatusOK, ResourceOrder, resp.Order)
+}
+
+// PreviewCancellation says whether an order can be cancelled now and at what fee, for
+// the canceller named by the cancelled_by query parameter
+func (h *OrderHandler) PreviewCancellation(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.PreviewCancellation(ctx, &pb.PreviewCancellationRequest{
+		OrderId:     orderID,
+		CancelledBy: c.Query("cancelled_by"),
+	})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to preview cancellation"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RefundOrder refunds an order's payment
+func (h *OrderHandler) RefundOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request RefundOrderRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Convert request to protobuf
+	req := &pb.RefundOrderRequest{
+		OrderId:     orderID,
+		RequestedBy: request.RequestedBy,
+		Reason:      request.Reason,
+		Amount:      request.Amount,
+		ToWallet:    request.ToWallet,
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.RefundOrder(ctx, req)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				return
+			case codes.InvalidArgument, codes.FailedPrecondition:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			case codes.AlreadyExists:
+				c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+				return
+			case codes.Unavailable:
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment service unavailable"})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refund order"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	respond(c, http.StatusOK, ResourceOrder, resp.Order)
 }
 
-// ListUserOrders lists orders for a specific user
+// ListUserOrders lists orders for a specific user, or exports them all with export=csv
 func (h *OrderHandler) ListUserOrders(c *gin.Context) {
 	userID := c.Param("id")
 	if userID == "" {
@@ -244,6 +383,15 @@ func (h *OrderHandler) ListUserOrders(c *gin.Context) {
 		return
 	}
 
+	export, ok := wantsExport(c)
+	if !ok {
+		return
+	}
+	if export {
+		h.exportOrders(c, &pb.ExportOrdersRequest{UserId: userID})
+		return
+	}
+
 	// Get query parameters
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
@@ -267,15 +415,10 @@ func (h *OrderHandler) ListUserOrders(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"orders": resp.Orders,
-		"total":  resp.Total,
-		"page":   resp.Page,
-		"limit":  resp.Limit,
-	})
+	respond(c, http.StatusOK, ResourceOrderList, resp)
 }
 
-// ListProviderOrders lists orders for a specific provider
+// ListProviderOrders lists orders for a specific provider, or exports them all with export=csv
 func (h *OrderHandler) ListProviderOrders(c *gin.Context) {
 	providerID := c.Param("id")
 	if providerID == "" {
@@ -283,6 +426,15 @@ func (h *OrderHandler) ListProviderOrders(c *gin.Context) {
 		return
 	}
 
+	export, ok := wantsExport(c)
+	if !ok {
+		return
+	}
+	if export {
+		h.exportOrders(c, &pb.ExportOrdersRequest{ProviderId: providerID})
+		return
+	}
+
 	// Get query parameters
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
@@ -306,105 +458,55 @@ func (h *OrderHandler) ListProviderOrders(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"orders": resp.Orders,
-		"total":  resp.Total,
-		"page":   resp.Page,
-		"limit":  resp.Limit,
-	})
-}
-
-// TrackOrder streams location updates for an order using Server-Sent Events
-func (h *OrderHandler) TrackOrder(c *gin.Context) {
-	orderID := c.Param("id")
-	if orderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
-		return
-	}
-
-	// Set up SSE
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("Transfer-Encoding", "chunked")
-
-	// Create a channel for client disconnect
-	clientGone := c.Writer.CloseNotify()
-
-	// Call the order service
-	ctx, cancel := context.WithCancel(c.Request.Context())
-	defer cancel()
-
-	stream, err := h.orderClient.TrackOrder(ctx, &pb.TrackOrderRequest{OrderId: orderID})
-	if err != nil {
-		c.Status(http.StatusInternalServerError)
-		return
-	}
-
-	// Stream location updates
-	for {
-		select {
-		case <-clientGone:
-			return
-		default:
-			update, err := stream.Recv()
-			if err != nil {
-				return
-			}
-
-			// Convert to JSON
-			data, err := json.Marshal(update)
-			if err != nil {
-				continue
-			}
-
-			// Send SSE message
-			c.SSEvent("location", string(data))
-			c.Writer.Flush()
-		}
-	}
+	respond(c, http.StatusOK, ResourceOrderList, resp)
 }
 
-// AssignProvider assigns a provider to an order
-func (h *OrderHandler) AssignProvider(c *gin.Context) {
+// CompleteDelivery delivers an arrived order with the provider's proof of delivery
+func (h *OrderHandler) CompleteDelivery(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
 		return
 	}
 
-	var request struct {
-		ProviderID string `json:"provider_id"` // Optional for manual assignment
-	}
+	var request CompleteDeliveryRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &request) {
 		return
 	}
 
 	// Convert request to protobuf
-	req := &pb.AssignProviderRequest{
-		OrderId:   orderID,
-		ProviderId: request.ProviderID,
+	req := &pb.CompleteDeliveryRequest{
+		OrderId:       orderID,
+		ProviderId:    request.ProviderID,
+		PhotoRef:      request.PhotoRef,
+		SignatureHash: request.SignatureHash,
+		RecipientOtp:  request.RecipientOTP,
 	}
 
 	// Call the order service
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
-	resp, err := h.orderClient.AssignProvider(ctx, req)
+	resp, err := h.orderClient.CompleteDelivery(ctx, req)
 	if err != nil {
 		st, ok := status.FromError(err)
 		if ok {
 			switch st.Code() {
 			case codes.NotFound:
-				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 				return
-			case codes.InvalidArgument:
+			case codes.InvalidArgument, codes.FailedPrecondition:
 				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
 				return
+			case codes.PermissionDenied:
+				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+				return
+			case codes.ResourceExhausted:
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": st.Message()})
+				return
 			default:
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign provider"})
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete delivery"})
 				return
 			}
 		}
@@ -412,58 +514,54 @@ func (h *OrderHandler) AssignProvider(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, resp.Order)
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	respond(c, http.StatusOK, ResourceOrder, resp.Order)
 }
 
-// AcceptOrder handles a provider accepting an order
-func (h *OrderHandler) AcceptOrder(c *gin.Context) {
+// ResendDeliveryPIN sends the ordering user a new delivery PIN
+func (h *OrderHandler) ResendDeliveryPIN(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
 		return
 	}
 
-	var request struct {
-		ProviderID      string                 `json:"provider_id" binding:"required"`
-		CurrentLocation map[string]interface{} `json:"current_location"`
-	}
+	var request ResendDeliveryPINRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &request) {
 		return
 	}
 
 	// Convert request to protobuf
-	req := &pb.AcceptOrderRequest{
-		OrderId:   orderID,
-		ProviderId: request.ProviderID,
-	}
-
-	// Add location if provided
-	if request.CurrentLocation != nil {
-		req.CurrentLocation = convertLocationFromMap(request.CurrentLocation)
+	req := &pb.ResendDeliveryPINRequest{
+		OrderId: orderID,
+		UserId:  request.UserID,
 	}
 
 	// Call the order service
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
-	resp, err := h.orderClient.AcceptOrder(ctx, req)
+	resp, err := h.orderClient.ResendDeliveryPIN(ctx, req)
 	if err != nil {
 		st, ok := status.FromError(err)
 		if ok {
 			switch st.Code() {
 			case codes.NotFound:
-				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				return
+			case codes.InvalidArgument, codes.FailedPrecondition:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
 				return
 			case codes.PermissionDenied:
 				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
 				return
-			case codes.InvalidArgument:
-				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+			case codes.ResourceExhausted:
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": st.Message()})
 				return
 			default:
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept order"})
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resend delivery PIN"})
 				return
 			}
 		}
@@ -471,54 +569,59 @@ func (h *OrderHandler) AcceptOrder(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, resp.Order)
+	c.JSON(http.StatusOK, gin.H{
+		"success": resp.Success,
+		"message": resp.Message,
+	})
 }
 
-// RejectOrder handles a provider rejecting an order
-func (h *OrderHandler) RejectOrder(c *gin.Context) {
+// AddTip adds a tip to a completed order
+func (h *OrderHandler) AddTip(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
 		return
 	}
 
-	var request struct {
-		ProviderID string `json:"provider_id" binding:"required"`
-		Reason     string `json:"reason" binding:"required"`
-	}
+	var request AddTipRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &request) {
 		return
 	}
 
 	// Convert request to protobuf
-	req := &pb.RejectOrderRequest{
-		OrderId:   orderID,
-		ProviderId: request.ProviderID,
-		Reason:    request.Reason,
+	req := &pb.AddTipRequest{
+		OrderId: orderID,
+		UserId:  request.UserID,
+		Amount:  request.Amount,
 	}
 
 	// Call the order service
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
-	resp, err := h.orderClient.RejectOrder(ctx, req)
+	resp, err := h.orderClient.AddTip(ctx, req)
 	if err != nil {
 		st, ok := status.FromError(err)
 		if ok {
 			switch st.Code() {
 			case codes.NotFound:
-				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				return
+			case codes.InvalidArgument, codes.FailedPrecondition:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
 				return
 			case codes.PermissionDenied:
 				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
 				return
-			case codes.InvalidArgument:
-				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+			case codes.AlreadyExists:
+				c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+				return
+			case codes.Unavailable:
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment service unavailable"})
 				return
 			default:
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject order"})
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add tip"})
 				return
 			}
 		}
@@ -526,54 +629,60 @@ func (h *OrderHandler) RejectOrder(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, resp.Order)
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	respond(c, http.StatusOK, ResourceOrder, resp.Order)
 }
 
-// UpdateLocation updates the provider's location for an order
-func (h *OrderHandler) UpdateLocation(c *gin.Context) {
+// UpdateOrderDetails changes the destination, items or notes of an order before pickup
+func (h *OrderHandler) UpdateOrderDetails(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
 		return
 	}
 
-	var request struct {
-		ProviderID string                 `json:"provider_id" binding:"required"`
-		Location   map[string]interface{} `json:"location" binding:"required"`
-	}
+	var request UpdateOrderDetailsRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &request) {
 		return
 	}
 
 	// Convert request to protobuf
-	req := &pb.UpdateLocationRequest{
-		OrderId:   orderID,
-		ProviderId: request.ProviderID,
-		Location:  convertLocationFromMap(request.Location),
+	req := &pb.UpdateOrderDetailsRequest{
+		OrderId:     orderID,
+		UserId:      request.UserID,
+		Items:       convertOrderItemsFromRequest(request.Items),
+		Notes:       request.Notes,
+		QuotedTotal: request.QuotedTotal,
+	}
+	if request.DestinationLocation != nil {
+		req.DestinationLocation = convertLocationFromRequest(request.DestinationLocation)
 	}
 
 	// Call the order service
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
-	resp, err := h.orderClient.UpdateLocation(ctx, req)
+	resp, err := h.orderClient.UpdateOrderDetails(ctx, req)
 	if err != nil {
 		st, ok := status.FromError(err)
 		if ok {
 			switch st.Code() {
 			case codes.NotFound:
-				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
 				return
 			case codes.PermissionDenied:
 				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
 				return
-			case codes.InvalidArgument:
-				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+			case codes.FailedPrecondition:
+				c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
 				return
 			default:
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update location"})
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order details"})
 				return
 			}
 		}
@@ -581,27 +690,813 @@ func (h *OrderHandler) UpdateLocation(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": resp.Success,
-		"message": resp.Message,
-		"estimated_arrival_minutes": resp.EstimatedArrivalMinutes,
-	})
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	respond(c, http.StatusOK, ResourceOrder, resp.Order)
 }
 
-// Helper functions
+// GetRental returns the hourly booking of a rental order
+func (h *OrderHandler) GetRental(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
 
-func convertOrderTypeFromString(orderType string) pb.OrderType {
-	switch orderType {
-	case "RIDE":
-		return pb.OrderType_ORDER_TYPE_RIDE
-	case "FOOD_DELIVERY":
-		return pb.OrderType_ORDER_TYPE_FOOD_DELIVERY
-	case "PACKAGE_DELIVERY":
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.GetRental(ctx, &pb.GetRentalRequest{
+		OrderId: orderID,
+	})
+	if err != nil {
+		h.handleChangeRequestError(c, err, "Failed to get rental")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RequestRentalExtension asks the provider of a running rental for more hours
+func (h *OrderHandler) RequestRentalExtension(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request RentalExtensionRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.RequestRentalExtension(ctx, &pb.RequestRentalExtensionRequest{
+		OrderId: orderID,
+		UserId:  request.UserID,
+		Hours:   request.Hours,
+	})
+	if err != nil {
+		h.handleChangeRequestError(c, err, "Failed to request rental extension")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ApproveRentalExtension lets the provider approve a rental extension, charging the user
+func (h *OrderHandler) ApproveRentalExtension(c *gin.Context) {
+	h.respondRentalExtension(c, true)
+}
+
+// DeclineRentalExtension lets the provider decline a rental extension
+func (h *OrderHandler) DeclineRentalExtension(c *gin.Context) {
+	h.respondRentalExtension(c, false)
+}
+
+func (h *OrderHandler) respondRentalExtension(c *gin.Context, approve bool) {
+	orderID := c.Param("id")
+	extensionID := c.Param("extension_id")
+	if orderID == "" || extensionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID and extension ID are required"})
+		return
+	}
+
+	var request RespondRentalExtensionRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Approving captures a payment, so allow for the payment service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.RespondRentalExtension(ctx, &pb.RespondRentalExtensionRequest{
+		OrderId:     orderID,
+		ExtensionId: extensionID,
+		ProviderId:  request.ProviderID,
+		Approve:     approve,
+	})
+	if err != nil {
+		h.handleChangeRequestError(c, err, "Failed to answer rental extension")
+		return
+	}
+
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RequestDestinationChange lets the user of a ride in transit ask to go somewhere else
+func (h *OrderHandler) RequestDestinationChange(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request DestinationChangeRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.RequestDestinationChange(ctx, &pb.RequestDestinationChangeRequest{
+		OrderId:             orderID,
+		UserId:              request.UserID,
+		DestinationLocation: convertLocationFromRequest(request.DestinationLocation),
+	})
+	if err != nil {
+		h.handleChangeRequestError(c, err, "Failed to request destination change")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// AcceptDestinationChange lets the provider accept a destination change, charging any rise in the fare
+func (h *OrderHandler) AcceptDestinationChange(c *gin.Context) {
+	h.respondDestinationChange(c, true)
+}
+
+// DeclineDestinationChange lets the provider decline a destination change
+func (h *OrderHandler) DeclineDestinationChange(c *gin.Context) {
+	h.respondDestinationChange(c, false)
+}
+
+func (h *OrderHandler) respondDestinationChange(c *gin.Context, accept bool) {
+	orderID := c.Param("id")
+	changeID := c.Param("change_id")
+	if orderID == "" || changeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID and change ID are required"})
+		return
+	}
+
+	var request RespondDestinationChangeRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Accepting can capture a payment, so allow for the payment service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.RespondDestinationChange(ctx, &pb.RespondDestinationChangeRequest{
+		OrderId:    orderID,
+		ChangeId:   changeID,
+		ProviderId: request.ProviderID,
+		Accept:     accept,
+	})
+	if err != nil {
+		h.handleChangeRequestError(c, err, "Failed to answer destination change")
+		return
+	}
+
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleChangeRequestError maps an error from a rental or destination change RPC to an
+// HTTP response
+func (h *OrderHandler) handleChangeRequestError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.PermissionDenied:
+		c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+	case codes.AlreadyExists, codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	case codes.Unavailable:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment service unavailable"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// ListProviderLedger lists the fares and tips credited to a provider
+func (h *OrderHandler) ListProviderLedger(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	// Get query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.ListProviderLedger(ctx, &pb.ListProviderLedgerRequest{
+		ProviderId: providerID,
+		Page:       int32(page),
+		Limit:      int32(limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respond(c, http.StatusOK, ResourceLedger, resp)
+}
+
+// TrackOrder streams location updates for an order using Server-Sent Events
+func (h *OrderHandler) TrackOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	// Set up SSE
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Transfer-Encoding", "chunked")
+
+	// Create a channel for client disconnect
+	clientGone := c.Writer.CloseNotify()
+
+	// Call the order service
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	stream, err := h.orderClient.TrackOrder(ctx, &pb.TrackOrderRequest{OrderId: orderID})
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// Stream location updates
+	for {
+		select {
+		case <-clientGone:
+			return
+		default:
+			update, err := stream.Recv()
+			if err != nil {
+				return
+			}
+
+			// Convert to JSON
+			data, err := json.Marshal(update)
+			if err != nil {
+				continue
+			}
+
+			// Send SSE message
+			c.SSEvent("location", string(data))
+			c.Writer.Flush()
+		}
+	}
+}
+
+// AssignProvider assigns a provider to an order
+func (h *OrderHandler) AssignProvider(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request AssignProviderRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Convert request to protobuf
+	req := &pb.AssignProviderRequest{
+		OrderId:   orderID,
+		ProviderId: request.ProviderID,
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.AssignProvider(ctx, req)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			case codes.ResourceExhausted, codes.FailedPrecondition:
+				c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign provider"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	respond(c, http.StatusOK, ResourceOrder, resp.Order)
+}
+
+// AcceptOrder handles a provider accepting an order
+func (h *OrderHandler) AcceptOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request AcceptOrderRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Convert request to protobuf
+	req := &pb.AcceptOrderRequest{
+		OrderId:   orderID,
+		ProviderId: request.ProviderID,
+	}
+
+	// Add location if provided
+	if request.CurrentLocation != nil {
+		req.CurrentLocation = convertLocationFromRequest(request.CurrentLocation)
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.AcceptOrder(ctx, req)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				return
+			case codes.PermissionDenied:
+				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept order"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	respond(c, http.StatusOK, ResourceOrder, resp.Order)
+}
+
+// RejectOrder handles a provider rejecting an order
+func (h *OrderHandler) RejectOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request RejectOrderRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Convert request to protobuf
+	req := &pb.RejectOrderRequest{
+		OrderId:   orderID,
+		ProviderId: request.ProviderID,
+		Reason:    request.Reason,
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.RejectOrder(ctx, req)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				return
+			case codes.PermissionDenied:
+				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject order"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	respond(c, http.StatusOK, ResourceOrder, resp.Order)
+}
+
+// HandOffOrder lets a provider who broke down or has an emergency hand their order off to another
+func (h *OrderHandler) HandOffOrder(c *gin.Context) {
+	var request HandOffOrderRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	h.handOffOrder(c, &pb.HandOffOrderRequest{
+		NewProviderId: request.NewProviderID,
+		RequestedBy:   request.ProviderID,
+		Reason:        request.Reason,
+	})
+}
+
+// AdminHandOffOrder lets an admin hand any active order off to another provider
+func (h *OrderHandler) AdminHandOffOrder(c *gin.Context) {
+	var request AdminHandOffOrderRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	h.handOffOrder(c, &pb.HandOffOrderRequest{
+		NewProviderId: request.NewProviderID,
+		RequestedBy:   request.HandedOffBy,
+		ByAdmin:       true,
+		Reason:        request.Reason,
+	})
+}
+
+func (h *OrderHandler) handOffOrder(c *gin.Context, req *pb.HandOffOrderRequest) {
+	req.OrderId = c.Param("id")
+	if req.OrderId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.HandOffOrder(ctx, req)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		switch st.Code() {
+		case codes.NotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+		case codes.InvalidArgument:
+			c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+		case codes.PermissionDenied:
+			c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+		case codes.ResourceExhausted, codes.FailedPrecondition:
+			c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+		case codes.Unavailable:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Provider service unavailable"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hand off order"})
+		}
+		return
+	}
+
+	h.cache.InvalidateOrder(ctx, resp.Order)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateLocation updates the provider's location for an order
+func (h *OrderHandler) UpdateLocation(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request UpdateLocationRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Convert request to protobuf
+	req := &pb.UpdateLocationRequest{
+		OrderId:   orderID,
+		ProviderId: request.ProviderID,
+		Location:  convertLocationFromRequest(request.Location),
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.UpdateLocation(ctx, req)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				return
+			case codes.PermissionDenied:
+				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update location"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": resp.Success,
+		"message": resp.Message,
+		"estimated_arrival_minutes": resp.EstimatedArrivalMinutes,
+	})
+}
+
+// GetLocationHistory returns the path an order's provider travelled
+func (h *OrderHandler) GetLocationHistory(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "500"))
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.GetLocationHistory(ctx, &pb.GetLocationHistoryRequest{
+		OrderId: orderID,
+		Limit:   int32(limit),
+	})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get location history"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetOrderRoute returns an order's travelled path as a polyline with summary stats
+func (h *OrderHandler) GetOrderRoute(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.GetOrderRoute(ctx, &pb.GetOrderRouteRequest{
+		OrderId: orderID,
+	})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get order route"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetOrderBatch returns the stops of the batched trip an order is on, with per-stop ETAs
+func (h *OrderHandler) GetOrderBatch(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.GetOrderBatch(ctx, &pb.GetOrderBatchRequest{
+		OrderId: orderID,
+	})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get order batch"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// BatchUpdateLocation stores a batch of provider locations recorded by the device
+func (h *OrderHandler) BatchUpdateLocation(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request BatchUpdateLocationRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Convert request to protobuf
+	req := &pb.BatchUpdateLocationRequest{
+		OrderId:    orderID,
+		ProviderId: request.ProviderID,
+	}
+	for _, point := range request.Points {
+		req.Points = append(req.Points, &pb.LocationPoint{
+			Location: &pb.Location{
+				Latitude:  *point.Latitude,
+				Longitude: *point.Longitude,
+			},
+			RecordedAt: timestamppb.New(point.RecordedAt),
+		})
+	}
+
+	// Call the order service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.orderClient.BatchUpdateLocation(ctx, req)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+				return
+			case codes.PermissionDenied:
+				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update locations"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":                   resp.Success,
+		"message":                   resp.Message,
+		"received":                  resp.Received,
+		"stored":                    resp.Stored,
+		"estimated_arrival_minutes": resp.EstimatedArrivalMinutes,
+	})
+}
+
+// orderIDCacheKey keys GetOrder responses by order ID
+func orderIDCacheKey(c *gin.Context) string {
+	return orderCacheKey(c.Param("id"))
+}
+
+// userOrdersCacheKey caches only the first page of a user's orders, and never exports
+func userOrdersCacheKey(c *gin.Context) string {
+	if c.DefaultQuery("page", "1") != "1" || c.Query("export") != "" {
+		return ""
+	}
+	return userOrdersCachePrefix(c.Param("id")) + c.DefaultQuery("limit", "10") + ":" + c.Query("status") + ":"
+}
+
+// Helper functions
+
+// convertCreateOrderFromRequest converts a validated order request to protobuf
+func convertCreateOrderFromRequest(request *CreateOrderRequest) *pb.CreateOrderRequest {
+	items := convertOrderItemsFromRequest(request.Items)
+	if request.OrderType == "RETURN" {
+		items = convertReturnItemsFromRequest(request.ReturnItems)
+	}
+
+	return &pb.CreateOrderRequest{
+		UserId:              request.UserID,
+		OrderType:           convertOrderTypeFromString(request.OrderType),
+		PickupLocation:      convertLocationFromRequest(request.PickupLocation),
+		DestinationLocation: convertLocationFromRequest(request.DestinationLocation),
+		Items:               items,
+		PaymentMethod:       convertPaymentMethodFromString(request.PaymentMethod),
+		PaymentMethodId:     request.PaymentMethodID,
+		Notes:               request.Notes,
+		PaymentShares:       convertPaymentSharesFromRequest(request.PaymentShares),
+		RentalHours:         request.RentalHours,
+		MerchantId:          request.MerchantID,
+		QuotedTotal:         request.QuotedTotal,
+		WalletAmount:        request.WalletAmount,
+		RedeemPoints:        request.RedeemPoints,
+		ReturnOfOrderId:     request.ReturnOfOrderID,
+	}
+}
+
+func convertOrderTypeFromString(orderType string) pb.OrderType {
+	switch orderType {
+	case "RIDE":
+		return pb.OrderType_ORDER_TYPE_RIDE
+	case "FOOD_DELIVERY":
+		return pb.OrderType_ORDER_TYPE_FOOD_DELIVERY
+	case "PACKAGE_DELIVERY":
 		return pb.OrderType_ORDER_TYPE_PACKAGE_DELIVERY
 	case "GROCERY_DELIVERY":
 		return pb.OrderType_ORDER_TYPE_GROCERY_DELIVERY
 	case "SERVICE_BOOKING":
 		return pb.OrderType_ORDER_TYPE_SERVICE_BOOKING
+	case "RENTAL":
+		return pb.OrderType_ORDER_TYPE_RENTAL
+	case "RETURN":
+		return pb.OrderType_ORDER_TYPE_RETURN
 	default:
 		return pb.OrderType_ORDER_TYPE_UNSPECIFIED
 	}
@@ -661,86 +1556,95 @@ func convertPaymentMethodFromString(method string) pb.PaymentMethod {
 	}
 }
 
-func convertLocationFromMap(location map[string]interface{}) *pb.Location {
+func convertLocationFromRequest(location *LocationRequest) *pb.Location {
+	if location == nil {
+		return nil
+	}
 	loc := &pb.Location{
-		AdditionalInfo: make(map[string]string),
+		Latitude:       *location.Latitude,
+		Longitude:      *location.Longitude,
+		Address:        location.Address,
+		PostalCode:     location.PostalCode,
+		City:           location.City,
+		Country:        location.Country,
+		AdditionalInfo: location.AdditionalInfo,
 	}
 
-	// Extract fields from map
-	if lat, ok := location["latitude"].(float64); ok {
-		loc.Latitude = lat
-	}
-	if lng, ok := location["longitude"].(float64); ok {
-		loc.Longitude = lng
-	}
-	if addr, ok := location["address"].(string); ok {
-		loc.Address = addr
-	}
-	if postal, ok := location["postal_code"].(string); ok {
-		loc.PostalCode = postal
-	}
-	if city, ok := location["city"].(string); ok {
-		loc.City = city
-	}
-	if country, ok := location["country"].(string); ok {
-		loc.Country = country
+	if loc.AdditionalInfo == nil {
+		loc.AdditionalInfo = make(map[string]string)
 	}
 
-	// Process additional info if present
-	if addInfo, ok := location["additional_info"].(map[string]interface{}); ok {
-		for k, v := range addInfo {
-			if strValue, ok := v.(string); ok {
-				loc.AdditionalInfo[k] = strValue
-			}
+	if instructions := location.Instructions; instructions != nil {
+		loc.Instructions = &pb.StopInstructions{
+			GateCode:    instructions.GateCode,
+			Floor:       instructions.Floor,
+			LeaveAtDoor: instructions.LeaveAtDoor,
+			Notes:       instructions.Notes,
 		}
 	}
 
 	return loc
 }
 
-func convertOrderItemsFromSlice(items []map[string]interface{}) []*pb.OrderItem {
+// convertReturnItemsFromRequest converts the items a return sends back to protobuf. The
+// order service fills in the rest from the returned order's items.
+func convertReturnItemsFromRequest(items []ReturnItemRequest) []*pb.OrderItem {
+	result := []*pb.OrderItem{}
+	for _, item := range items {
+		quantity := item.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		result = append(result, &pb.OrderItem{ItemId: item.ItemID, Name: item.Name, Quantity: quantity})
+	}
+	return result
+}
+
+func convertOrderItemsFromRequest(items []OrderItemRequest) []*pb.OrderItem {
 	result := []*pb.OrderItem{}
 
 	for _, item := range items {
 		orderItem := &pb.OrderItem{
-			Properties: make(map[string]string),
+			ItemId:      item.ItemID,
+			Name:        item.Name,
+			Quantity:    item.Quantity,
+			Price:       item.Price,
+			Properties:  item.Properties,
+			WeightGrams: item.WeightGrams,
+			LengthCm:    item.LengthCm,
+			WidthCm:     item.WidthCm,
+			HeightCm:    item.HeightCm,
 		}
 
-		// Extract id and name
-		if id, ok := item["item_id"].(string); ok {
-			orderItem.ItemId = id
-		} else {
-			// Generate random ID if not provided
+		// Generate random ID if not provided
+		if orderItem.ItemId == "" {
 			orderItem.ItemId = uuid.New().String()
 		}
 
-		if name, ok := item["name"].(string); ok {
-			orderItem.Name = name
-		}
-
-		// Extract quantity
-		if qty, ok := item["quantity"].(float64); ok {
-			orderItem.Quantity = int32(qty)
-		} else {
+		if orderItem.Quantity == 0 {
 			orderItem.Quantity = 1
 		}
 
-		// Extract price
-		if price, ok := item["price"].(float64); ok {
-			orderItem.Price = float32(price)
-		}
-
-		// Process properties if present
-		if props, ok := item["properties"].(map[string]interface{}); ok {
-			for k, v := range props {
-				if strValue, ok := v.(string); ok {
-					orderItem.Properties[k] = strValue
-				}
-			}
+		if orderItem.Properties == nil {
+			orderItem.Properties = make(map[string]string)
 		}
 
 		result = append(result, orderItem)
 	}
 
 	return result
-} 
\ No newline at end of file
+}
+
+// convertPaymentSharesFromRequest converts the requested split payment to protobuf
+func convertPaymentSharesFromRequest(shares []PaymentShareRequest) []*pb.PaymentShare {
+	result := []*pb.PaymentShare{}
+
+	for _, share := range shares {
+		result = append(result, &pb.PaymentShare{
+			UserId:     share.UserID,
+			Percentage: share.Percentage,
+		})
+	}
+
+	return result
+}
\ No newline at end of file
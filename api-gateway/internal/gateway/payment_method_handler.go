@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	paymentMethodPb "github.com/order-api-microservices/proto/paymentmethod"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PaymentMethodHandler handles the API endpoints for the cards and wallets a user saved
+type PaymentMethodHandler struct {
+	paymentMethodClient paymentMethodPb.PaymentMethodServiceClient
+}
+
+// NewPaymentMethodHandler creates a new payment method handler
+func NewPaymentMethodHandler(paymentMethodClient paymentMethodPb.PaymentMethodServiceClient) *PaymentMethodHandler {
+	return &PaymentMethodHandler{
+		paymentMethodClient: paymentMethodClient,
+	}
+}
+
+// RegisterRoutes registers the payment method API routes on a version group
+func (h *PaymentMethodHandler) RegisterRoutes(api *gin.RouterGroup) {
+	methods := api.Group("/users/:id/payment-methods")
+	{
+		methods.GET("", h.ListPaymentMethods)
+		methods.POST("", h.SavePaymentMethod)
+		methods.PUT("/:method_id/default", h.SetDefaultPaymentMethod)
+		methods.DELETE("/:method_id", h.DeletePaymentMethod)
+	}
+}
+
+// ListPaymentMethods lists a user's saved payment methods, the default first
+func (h *PaymentMethodHandler) ListPaymentMethods(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	// Call the payment method service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.paymentMethodClient.ListPaymentMethods(ctx, &paymentMethodPb.ListPaymentMethodsRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list payment methods")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// SavePaymentMethod saves a card or wallet the app tokenized with the payment gateway
+func (h *PaymentMethodHandler) SavePaymentMethod(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	var request SavePaymentMethodRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the payment method service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.paymentMethodClient.SavePaymentMethod(ctx, &paymentMethodPb.SavePaymentMethodRequest{
+		UserId:      userID,
+		Type:        request.Type,
+		Gateway:     request.Gateway,
+		Token:       request.Token,
+		Fingerprint: request.Fingerprint,
+		Brand:       request.Brand,
+		Last4:       request.Last4,
+		ExpMonth:    request.ExpMonth,
+		ExpYear:     request.ExpYear,
+		MakeDefault: request.MakeDefault,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to save payment method")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.PaymentMethod)
+}
+
+// SetDefaultPaymentMethod makes one of a user's saved methods their default
+func (h *PaymentMethodHandler) SetDefaultPaymentMethod(c *gin.Context) {
+	userID := c.Param("id")
+	methodID := c.Param("method_id")
+	if userID == "" || methodID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID and payment method ID are required"})
+		return
+	}
+
+	// Call the payment method service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.paymentMethodClient.SetDefaultPaymentMethod(ctx, &paymentMethodPb.SetDefaultPaymentMethodRequest{
+		UserId:          userID,
+		PaymentMethodId: methodID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to set default payment method")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.PaymentMethod)
+}
+
+// DeletePaymentMethod removes one of a user's saved methods
+func (h *PaymentMethodHandler) DeletePaymentMethod(c *gin.Context) {
+	userID := c.Param("id")
+	methodID := c.Param("method_id")
+	if userID == "" || methodID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID and payment method ID are required"})
+		return
+	}
+
+	// Call the payment method service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := h.paymentMethodClient.DeletePaymentMethod(ctx, &paymentMethodPb.DeletePaymentMethodRequest{
+		UserId:          userID,
+		PaymentMethodId: methodID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to delete payment method")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleError maps a payment method service error to an HTTP response
+func (h *PaymentMethodHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
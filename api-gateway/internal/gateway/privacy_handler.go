@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	privacyPb "github.com/order-api-microservices/proto/privacy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PrivacyHandler handles the admin API endpoints for data export and erasure requests
+type PrivacyHandler struct {
+	privacyClient privacyPb.PrivacyServiceClient
+}
+
+// NewPrivacyHandler creates a new privacy handler
+func NewPrivacyHandler(privacyClient privacyPb.PrivacyServiceClient) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyClient: privacyClient,
+	}
+}
+
+// RegisterRoutes registers the privacy API routes on a version group
+func (h *PrivacyHandler) RegisterRoutes(api *gin.RouterGroup) {
+	privacy := api.Group("/admin/privacy")
+	{
+		privacy.GET("/users/:id/export", h.ExportUserData)
+		privacy.POST("/users/:id/forget", h.ForgetUser)
+		privacy.POST("/providers/:id/forget", h.ForgetProvider)
+	}
+}
+
+// ExportUserData downloads everything held about a user as a JSON archive
+func (h *PrivacyHandler) ExportUserData(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	// Call the privacy service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.privacyClient.ExportUserData(ctx, &privacyPb.ExportUserDataRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to export user data")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"user-%s-export.json\"", userID))
+	c.Data(http.StatusOK, "application/json", resp.Archive)
+}
+
+// ForgetUser anonymizes a user's personal data
+func (h *PrivacyHandler) ForgetUser(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	var request ForgetRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the privacy service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.privacyClient.ForgetUser(ctx, &privacyPb.ForgetUserRequest{
+		UserId:      userID,
+		RequestedBy: request.RequestedBy,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to erase user data")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ForgetProvider anonymizes a provider's personal data
+func (h *PrivacyHandler) ForgetProvider(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	var request ForgetRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the privacy service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.privacyClient.ForgetProvider(ctx, &privacyPb.ForgetProviderRequest{
+		ProviderId:  providerID,
+		RequestedBy: request.RequestedBy,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to erase provider data")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleError maps a privacy service error to an HTTP response
+func (h *PrivacyHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	case codes.Unavailable:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,448 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	providerPb "github.com/order-api-microservices/proto/provider"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ProviderHandler handles provider API endpoints
+type ProviderHandler struct {
+	providerClient providerPb.ProviderServiceClient
+	cache          *ResponseCache
+}
+
+// NewProviderHandler creates a new provider handler; responseCache may be nil
+func NewProviderHandler(providerClient providerPb.ProviderServiceClient, responseCache *ResponseCache) *ProviderHandler {
+	return &ProviderHandler{
+		providerClient: providerClient,
+		cache:          responseCache,
+	}
+}
+
+// RegisterRoutes registers the provider API routes on a version group
+func (h *ProviderHandler) RegisterRoutes(api *gin.RouterGroup) {
+	providers := api.Group("/providers")
+	{
+		providers.GET("/:id", h.cache.Middleware(CacheRouteGetProvider, providerIDCacheKey), h.GetProvider)
+		providers.GET("/:id/preferences", h.GetPreferences)
+		providers.PUT("/:id/preferences", h.UpdatePreferences)
+		providers.PUT("/:id/service-areas", h.UpdateServiceAreas)
+		providers.PUT("/:id/vehicle-capacity", h.UpdateVehicleCapacity)
+		providers.GET("/:id/vehicles", h.ListVehicles)
+		providers.POST("/:id/vehicles", h.CreateVehicle)
+		providers.PUT("/:id/vehicles/:vehicle_id", h.UpdateVehicle)
+		providers.DELETE("/:id/vehicles/:vehicle_id", h.DeleteVehicle)
+		providers.PUT("/:id/availability", h.UpdateAvailability)
+		providers.POST("/:id/heartbeat", h.Heartbeat)
+	}
+}
+
+// GetProvider gets a provider's profile by ID
+func (h *ProviderHandler) GetProvider(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.providerClient.GetProvider(ctx, &providerPb.GetProviderRequest{ProviderId: providerID})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get provider"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respond(c, http.StatusOK, ResourceProvider, maskProvider(resp.Provider))
+}
+
+// GetPreferences gets the preferences that filter the orders a provider is offered
+func (h *ProviderHandler) GetPreferences(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.providerClient.GetPreferences(ctx, &providerPb.GetPreferencesRequest{ProviderId: providerID})
+	if err != nil {
+		h.handlePreferencesError(c, err, "Failed to get provider preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Preferences)
+}
+
+// UpdatePreferences replaces a provider's preferences
+func (h *ProviderHandler) UpdatePreferences(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	var request UpdateProviderPreferencesRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.providerClient.UpdatePreferences(ctx, &providerPb.UpdatePreferencesRequest{
+		ProviderId: providerID,
+		Preferences: &providerPb.ProviderPreferences{
+			AutoAcceptRadiusKm: request.AutoAcceptRadiusKm,
+			OrderTypes:         request.OrderTypes,
+			MinFare:            request.MinFare,
+		},
+	})
+	if err != nil {
+		h.handlePreferencesError(c, err, "Failed to update provider preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Preferences)
+}
+
+// UpdateServiceAreas replaces the service areas a provider is registered to work in
+func (h *ProviderHandler) UpdateServiceAreas(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	var request UpdateProviderServiceAreasRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.providerClient.UpdateServiceAreas(ctx, &providerPb.UpdateServiceAreasRequest{
+		ProviderId:     providerID,
+		ServiceAreaIds: request.ServiceAreaIDs,
+	})
+	if err != nil {
+		h.handlePreferencesError(c, err, "Failed to update provider service areas")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"service_area_ids": resp.ServiceAreaIds})
+}
+
+// UpdateVehicleCapacity replaces what a provider's vehicle can carry
+func (h *ProviderHandler) UpdateVehicleCapacity(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	var request VehicleCapacityRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.providerClient.UpdateVehicleCapacity(ctx, &providerPb.UpdateVehicleCapacityRequest{
+		ProviderId: providerID,
+		VehicleCapacity: &providerPb.VehicleCapacity{
+			MaxWeightGrams: request.MaxWeightGrams,
+			LengthCm:       request.LengthCm,
+			WidthCm:        request.WidthCm,
+			HeightCm:       request.HeightCm,
+		},
+	})
+	if err != nil {
+		h.handlePreferencesError(c, err, "Failed to update provider vehicle capacity")
+		return
+	}
+
+	h.cache.InvalidateProvider(ctx, providerID)
+
+	c.JSON(http.StatusOK, resp.VehicleCapacity)
+}
+
+// ListVehicles lists the vehicles a provider has registered
+func (h *ProviderHandler) ListVehicles(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.providerClient.ListVehicles(ctx, &providerPb.ListVehiclesRequest{ProviderId: providerID})
+	if err != nil {
+		h.handleVehicleError(c, err, "Failed to list vehicles")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"vehicles": resp.Vehicles})
+}
+
+// CreateVehicle registers a vehicle for a provider
+func (h *ProviderHandler) CreateVehicle(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	var request VehicleRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.providerClient.CreateVehicle(ctx, &providerPb.CreateVehicleRequest{
+		ProviderId: providerID,
+		Vehicle:    convertVehicleRequest(request),
+	})
+	if err != nil {
+		h.handleVehicleError(c, err, "Failed to create vehicle")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.Vehicle)
+}
+
+// UpdateVehicle replaces a vehicle's details, such as when its insurance is renewed
+func (h *ProviderHandler) UpdateVehicle(c *gin.Context) {
+	providerID := c.Param("id")
+	vehicleID := c.Param("vehicle_id")
+	if providerID == "" || vehicleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID and vehicle ID are required"})
+		return
+	}
+
+	var request VehicleRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.providerClient.UpdateVehicle(ctx, &providerPb.UpdateVehicleRequest{
+		ProviderId: providerID,
+		VehicleId:  vehicleID,
+		Vehicle:    convertVehicleRequest(request),
+	})
+	if err != nil {
+		h.handleVehicleError(c, err, "Failed to update vehicle")
+		return
+	}
+
+	h.cache.InvalidateProvider(ctx, providerID)
+
+	c.JSON(http.StatusOK, resp.Vehicle)
+}
+
+// DeleteVehicle deletes one of a provider's vehicles
+func (h *ProviderHandler) DeleteVehicle(c *gin.Context) {
+	providerID := c.Param("id")
+	vehicleID := c.Param("vehicle_id")
+	if providerID == "" || vehicleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID and vehicle ID are required"})
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := h.providerClient.DeleteVehicle(ctx, &providerPb.DeleteVehicleRequest{
+		ProviderId: providerID,
+		VehicleId:  vehicleID,
+	})
+	if err != nil {
+		h.handleVehicleError(c, err, "Failed to delete vehicle")
+		return
+	}
+
+	h.cache.InvalidateProvider(ctx, providerID)
+
+	c.Status(http.StatusNoContent)
+}
+
+// UpdateAvailability puts a provider on or off shift. A provider going on shift may pick
+// one of their registered vehicles to drive.
+func (h *ProviderHandler) UpdateAvailability(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	var request UpdateAvailabilityRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.providerClient.UpdateAvailability(ctx, &providerPb.UpdateAvailabilityRequest{
+		ProviderId:  providerID,
+		IsAvailable: *request.IsAvailable,
+		VehicleId:   request.VehicleID,
+	})
+	if err != nil {
+		h.handleVehicleError(c, err, "Failed to update availability")
+		return
+	}
+
+	h.cache.InvalidateProvider(ctx, providerID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"is_available": *request.IsAvailable,
+		"message":      resp.Message,
+	})
+}
+
+// Heartbeat tells the provider service a provider's app is online, so the provider stays
+// available for matching
+func (h *ProviderHandler) Heartbeat(c *gin.Context) {
+	providerID := c.Param("id")
+	if providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		return
+	}
+
+	// Call the provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	resp, err := h.providerClient.Heartbeat(ctx, &providerPb.HeartbeatRequest{ProviderId: providerID})
+	if err != nil {
+		h.handlePreferencesError(c, err, "Failed to record heartbeat")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"is_available":     resp.IsAvailable,
+		"interval_seconds": resp.IntervalSeconds,
+	})
+}
+
+// handlePreferencesError maps provider service errors for the preferences, service area
+// and heartbeat endpoints
+func (h *ProviderHandler) handlePreferencesError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// handleVehicleError maps provider service errors for the vehicle and availability
+// endpoints
+func (h *ProviderHandler) handleVehicleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.AlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// convertVehicleRequest converts a vehicle request body to the provider service's details
+func convertVehicleRequest(request VehicleRequest) *providerPb.VehicleDetails {
+	return &providerPb.VehicleDetails{
+		Plate:       request.Plate,
+		Type:        request.Type,
+		Description: request.Description,
+		Capacity: &providerPb.VehicleCapacity{
+			MaxWeightGrams: request.Capacity.MaxWeightGrams,
+			LengthCm:       request.Capacity.LengthCm,
+			WidthCm:        request.Capacity.WidthCm,
+			HeightCm:       request.Capacity.HeightCm,
+		},
+		InsuranceExpiresAt: timestamppb.New(request.InsuranceExpiresAt),
+	}
+}
+
+// maskProvider returns a copy of a provider without its phone number. Users reach
+// providers through contact tokens instead.
+func maskProvider(provider *providerPb.Provider) *providerPb.Provider {
+	if provider == nil {
+		return nil
+	}
+	masked := proto.Clone(provider).(*providerPb.Provider)
+	masked.Phone = ""
+	return masked
+}
+
+// providerIDCacheKey keys GetProvider responses by provider ID
+func providerIDCacheKey(c *gin.Context) string {
+	return providerCacheKey(c.Param("id"))
+}
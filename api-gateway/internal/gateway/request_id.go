@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/order-api-microservices/pkg/requestid"
+)
+
+// requestIDKey is the gin context key holding a request's ID
+const requestIDKey = "request_id"
+
+// RequestID gives every request an ID, keeping a valid one the client sent in
+// X-Request-ID. The ID is returned in the X-Request-ID response header, logged with the
+// request, and sent to the services with every gRPC call the request makes.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// LogFormatter formats the gateway's access log like gin's default one, with each
+// request's ID at the end
+func LogFormatter(param gin.LogFormatterParams) string {
+	id, _ := param.Keys[requestIDKey].(string)
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | %s\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency.Truncate(time.Microsecond),
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		id,
+		param.ErrorMessage,
+	)
+}
@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	serviceAreaPb "github.com/order-api-microservices/proto/servicearea"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceAreaHandler handles the admin API endpoints for service areas
+type ServiceAreaHandler struct {
+	serviceAreaClient serviceAreaPb.ServiceAreaServiceClient
+}
+
+// NewServiceAreaHandler creates a new service area handler
+func NewServiceAreaHandler(serviceAreaClient serviceAreaPb.ServiceAreaServiceClient) *ServiceAreaHandler {
+	return &ServiceAreaHandler{
+		serviceAreaClient: serviceAreaClient,
+	}
+}
+
+// RegisterRoutes registers the service area API routes on a version group
+func (h *ServiceAreaHandler) RegisterRoutes(api *gin.RouterGroup) {
+	areas := api.Group("/admin/service-areas")
+	{
+		areas.GET("", h.ListServiceAreas)
+		areas.POST("", h.CreateServiceArea)
+		areas.GET("/:id", h.GetServiceArea)
+		areas.PUT("/:id", h.UpdateServiceArea)
+		areas.DELETE("/:id", h.DeleteServiceArea)
+	}
+}
+
+// ListServiceAreas lists the service areas, optionally of one city
+func (h *ServiceAreaHandler) ListServiceAreas(c *gin.Context) {
+	// Call the service area service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.serviceAreaClient.ListServiceAreas(ctx, &serviceAreaPb.ListServiceAreasRequest{
+		City: c.Query("city"),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list service areas")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetServiceArea gets a service area by ID
+func (h *ServiceAreaHandler) GetServiceArea(c *gin.Context) {
+	areaID := c.Param("id")
+	if areaID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "area ID is required"})
+		return
+	}
+
+	// Call the service area service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.serviceAreaClient.GetServiceArea(ctx, &serviceAreaPb.GetServiceAreaRequest{AreaId: areaID})
+	if err != nil {
+		h.handleError(c, err, "Failed to get service area")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Area)
+}
+
+// CreateServiceArea adds a zone to a city
+func (h *ServiceAreaHandler) CreateServiceArea(c *gin.Context) {
+	var request ServiceAreaRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	active := true
+	if request.Active != nil {
+		active = *request.Active
+	}
+
+	// Call the service area service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.serviceAreaClient.CreateServiceArea(ctx, &serviceAreaPb.CreateServiceAreaRequest{
+		City:     request.City,
+		Name:     request.Name,
+		Boundary: convertBoundaryFromRequest(request.Boundary),
+		Active:   active,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to create service area")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.Area)
+}
+
+// UpdateServiceArea replaces a service area's name, boundary and active flag
+func (h *ServiceAreaHandler) UpdateServiceArea(c *gin.Context) {
+	areaID := c.Param("id")
+	if areaID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "area ID is required"})
+		return
+	}
+
+	var request UpdateServiceAreaRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the service area service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.serviceAreaClient.UpdateServiceArea(ctx, &serviceAreaPb.UpdateServiceAreaRequest{
+		AreaId:   areaID,
+		Name:     request.Name,
+		Boundary: convertBoundaryFromRequest(request.Boundary),
+		Active:   *request.Active,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to update service area")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Area)
+}
+
+// DeleteServiceArea deletes a service area
+func (h *ServiceAreaHandler) DeleteServiceArea(c *gin.Context) {
+	areaID := c.Param("id")
+	if areaID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "area ID is required"})
+		return
+	}
+
+	// Call the service area service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := h.serviceAreaClient.DeleteServiceArea(ctx, &serviceAreaPb.DeleteServiceAreaRequest{AreaId: areaID})
+	if err != nil {
+		h.handleError(c, err, "Failed to delete service area")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleError maps a service area service error to an HTTP response
+func (h *ServiceAreaHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// convertBoundaryFromRequest converts validated boundary points to protobuf
+func convertBoundaryFromRequest(points []BoundaryPointRequest) []*serviceAreaPb.BoundaryPoint {
+	boundary := make([]*serviceAreaPb.BoundaryPoint, 0, len(points))
+	for _, point := range points {
+		boundary = append(boundary, &serviceAreaPb.BoundaryPoint{
+			Latitude:  *point.Latitude,
+			Longitude: *point.Longitude,
+		})
+	}
+	return boundary
+}
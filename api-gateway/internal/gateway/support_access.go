@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	supportPb "github.com/order-api-microservices/proto/support"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SupportTokenHeader carries the token of a support session, with which an admin makes
+// requests as the session's user
+const SupportTokenHeader = "X-Support-Token"
+
+// supportSessionKey is the gin context key of the support session a request is made in
+const supportSessionKey = "supportSession"
+
+// Scopes of support sessions
+const (
+	SupportScopeReadOnly  = "READ_ONLY"
+	SupportScopeReadWrite = "READ_WRITE"
+)
+
+// SupportAccess lets admins act on behalf of a user while troubleshooting. An admin
+// starts a support session through the admin API and sends its token with each request;
+// the request is then made as the session's user, and ForwardClientMetadata tags every
+// call it makes with the admin and session so the services' audit logs tell them apart
+// from the user's own actions.
+type SupportAccess struct {
+	supportClient supportPb.SupportAccessServiceClient
+}
+
+// NewSupportAccess creates a new support access handler
+func NewSupportAccess(supportClient supportPb.SupportAccessServiceClient) *SupportAccess {
+	return &SupportAccess{
+		supportClient: supportClient,
+	}
+}
+
+// Middleware verifies the support token of requests that carry one and makes them as
+// the session's user. A session cannot reach admin routes or another user's routes, and
+// a READ_ONLY session can only read. It runs before ForwardClientMetadata.
+func (s *SupportAccess) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(SupportTokenHeader)
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+		defer cancel()
+
+		session, err := s.supportClient.VerifySupportToken(ctx, &supportPb.VerifySupportTokenRequest{Token: token})
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "support session is invalid, ended or expired"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to verify support session"})
+			return
+		}
+
+		route := c.FullPath()
+		switch {
+		case strings.Contains(route, "/admin/"):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "support sessions cannot use admin routes"})
+			return
+		case strings.Contains(route, "/users/:id") && c.Param("id") != session.UserId:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "support session is for another user"})
+			return
+		}
+		if session.Scope != SupportScopeReadWrite {
+			switch c.Request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "support session is read-only"})
+				return
+			}
+		}
+
+		c.Request.Header.Set(ActorIDHeader, session.UserId)
+		c.Set(supportSessionKey, session)
+		c.Next()
+	}
+}
+
+// supportSession returns the support session a request is made in, if any
+func supportSession(c *gin.Context) (*supportPb.SupportSession, bool) {
+	value, ok := c.Get(supportSessionKey)
+	if !ok {
+		return nil, false
+	}
+	session, ok := value.(*supportPb.SupportSession)
+	return session, ok
+}
+
+// RegisterRoutes registers the support session API routes on a version group
+func (s *SupportAccess) RegisterRoutes(api *gin.RouterGroup) {
+	sessions := api.Group("/admin/support-sessions")
+	{
+		sessions.GET("", s.ListSupportSessions)
+		sessions.POST("", s.StartSupportSession)
+		sessions.POST("/:id/end", s.EndSupportSession)
+	}
+}
+
+// StartSupportSession gives an admin a time-limited token to act as a user
+func (s *SupportAccess) StartSupportSession(c *gin.Context) {
+	var request StartSupportSessionRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the support access service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := s.supportClient.StartSupportSession(ctx, &supportPb.StartSupportSessionRequest{
+		RequestedBy: request.RequestedBy,
+		UserId:      request.UserID,
+		Reason:      request.Reason,
+		Scope:       request.Scope,
+		TtlMinutes:  request.TTLMinutes,
+	})
+	if err != nil {
+		s.handleError(c, err, "Failed to start support session")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListSupportSessions lists the latest support sessions for a user, by an admin, or both
+func (s *SupportAccess) ListSupportSessions(c *gin.Context) {
+	// Call the support access service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := s.supportClient.ListSupportSessions(ctx, &supportPb.ListSupportSessionsRequest{
+		UserId:     c.Query("user_id"),
+		AdminId:    c.Query("admin_id"),
+		ActiveOnly: c.Query("active_only") == "true",
+	})
+	if err != nil {
+		s.handleError(c, err, "Failed to list support sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// EndSupportSession revokes a support session's token before it expires
+func (s *SupportAccess) EndSupportSession(c *gin.Context) {
+	sessionID := c.Param("id")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "support session ID is required"})
+		return
+	}
+
+	var request EndSupportSessionRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the support access service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := s.supportClient.EndSupportSession(ctx, &supportPb.EndSupportSessionRequest{
+		SupportSessionId: sessionID,
+		RequestedBy:      request.RequestedBy,
+	})
+	if err != nil {
+		s.handleError(c, err, "Failed to end support session")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Session)
+}
+
+// handleError maps a support access service error to an HTTP response
+func (s *SupportAccess) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec is the OpenAPI 3 document for all /api/v1 routes
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>Order API Gateway</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/swagger/openapi.yaml", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>`
+
+// RegisterSwaggerRoutes serves the OpenAPI document and a Swagger UI at /swagger
+func RegisterSwaggerRoutes(router *gin.Engine) {
+	router.GET("/swagger", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+	})
+	router.GET("/swagger/openapi.yaml", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/yaml", openAPISpec)
+	})
+}
+
+// CheckOpenAPIRoutes reports routes under prefix (e.g. /api/v1/) missing from the OpenAPI
+// document and documented operations under prefix that no longer have a route
+func CheckOpenAPIRoutes(routes gin.RoutesInfo, prefix string) error {
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(openAPISpec, &spec); err != nil {
+		return fmt.Errorf("failed to parse OpenAPI document: %v", err)
+	}
+
+	documented := make(map[string]bool)
+	for path, operations := range spec.Paths {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	var problems []string
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, prefix) {
+			continue
+		}
+		key := route.Method + " " + openAPIPath(route.Path)
+		registered[key] = true
+		if !documented[key] {
+			problems = append(problems, "undocumented route: "+key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			problems = append(problems, "documented route not registered: "+key)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("OpenAPI document out of sync:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// openAPIPath converts a Gin path such as /orders/:id to OpenAPI form /orders/{id}
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	timelinePb "github.com/order-api-microservices/proto/timeline"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TimelineHandler serves an order's timeline, the merged feed of everything that
+// happened to it
+type TimelineHandler struct {
+	timelineClient timelinePb.OrderTimelineServiceClient
+}
+
+// NewTimelineHandler creates a new timeline handler
+func NewTimelineHandler(timelineClient timelinePb.OrderTimelineServiceClient) *TimelineHandler {
+	return &TimelineHandler{
+		timelineClient: timelineClient,
+	}
+}
+
+// RegisterRoutes registers the timeline API routes on a version group
+func (h *TimelineHandler) RegisterRoutes(api *gin.RouterGroup) {
+	orders := api.Group("/orders")
+	{
+		orders.GET("/:id/timeline", h.GetOrderTimeline)
+	}
+}
+
+// GetOrderTimeline returns an order's status changes, location milestones, notifications,
+// payment events and blockchain confirmations, oldest first. Sources the order service
+// could not read are listed in errors and the timeline is marked partial.
+func (h *TimelineHandler) GetOrderTimeline(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	// Call the timeline service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.timelineClient.GetOrderTimeline(ctx, &timelinePb.GetOrderTimelineRequest{OrderId: orderID})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get order timeline"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	trackingPb "github.com/order-api-microservices/proto/tracking"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TrackingHandler shares an order's live tracking through public links
+type TrackingHandler struct {
+	trackingClient trackingPb.TrackingLinkServiceClient
+}
+
+// NewTrackingHandler creates a new tracking handler
+func NewTrackingHandler(trackingClient trackingPb.TrackingLinkServiceClient) *TrackingHandler {
+	return &TrackingHandler{
+		trackingClient: trackingClient,
+	}
+}
+
+// RegisterRoutes registers the tracking link API routes on a version group
+func (h *TrackingHandler) RegisterRoutes(api *gin.RouterGroup) {
+	orders := api.Group("/orders")
+	{
+		orders.POST("/:id/tracking-links", h.CreateTrackingLink)
+	}
+}
+
+// RegisterPublicRoutes registers the unversioned page data that tracking links point to.
+// It needs no authentication; the token only reveals the one order it was created for.
+func (h *TrackingHandler) RegisterPublicRoutes(router *gin.Engine) {
+	router.GET("/track/:token", h.GetSharedTracking)
+}
+
+// CreateTrackingLink creates a time-limited link to an order's live tracking
+func (h *TrackingHandler) CreateTrackingLink(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var request CreateTrackingLinkRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the tracking link service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.trackingClient.CreateTrackingLink(ctx, &trackingPb.CreateTrackingLinkRequest{
+		OrderId:    orderID,
+		UserId:     request.UserID,
+		TtlMinutes: request.TTLMinutes,
+	})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				return
+			case codes.InvalidArgument:
+				c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+				return
+			case codes.PermissionDenied:
+				c.JSON(http.StatusForbidden, gin.H{"error": st.Message()})
+				return
+			case codes.FailedPrecondition:
+				c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tracking link"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp.Link)
+}
+
+// GetSharedTracking returns the live tracking behind a tracking link
+func (h *TrackingHandler) GetSharedTracking(c *gin.Context) {
+	token := c.Param("token")
+
+	// The response follows the provider, so it must never be cached
+	c.Header("Cache-Control", "no-store")
+
+	// Call the tracking link service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.trackingClient.GetSharedTracking(ctx, &trackingPb.GetSharedTrackingRequest{
+		Token: token,
+	})
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok {
+			switch st.Code() {
+			case codes.NotFound, codes.InvalidArgument:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Tracking link not found or expired"})
+				return
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tracking"})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	userProviderPb "github.com/order-api-microservices/proto/userprovider"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UserProviderHandler handles the API endpoints for the providers a user has favorited or blocked
+type UserProviderHandler struct {
+	userProviderClient userProviderPb.UserProviderServiceClient
+}
+
+// NewUserProviderHandler creates a new user provider handler
+func NewUserProviderHandler(userProviderClient userProviderPb.UserProviderServiceClient) *UserProviderHandler {
+	return &UserProviderHandler{
+		userProviderClient: userProviderClient,
+	}
+}
+
+// RegisterRoutes registers the user provider API routes on a version group
+func (h *UserProviderHandler) RegisterRoutes(api *gin.RouterGroup) {
+	providers := api.Group("/users/:id/providers")
+	{
+		providers.GET("", h.ListUserProviders)
+		providers.PUT("/:provider_id", h.SetUserProvider)
+		providers.DELETE("/:provider_id", h.RemoveUserProvider)
+	}
+}
+
+// ListUserProviders lists the providers a user has favorited or blocked
+func (h *UserProviderHandler) ListUserProviders(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	// Call the user provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.userProviderClient.ListUserProviders(ctx, &userProviderPb.ListUserProvidersRequest{
+		UserId:     userID,
+		Preference: c.Query("preference"),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list user providers")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// SetUserProvider favorites or blocks a provider for a user
+func (h *UserProviderHandler) SetUserProvider(c *gin.Context) {
+	userID := c.Param("id")
+	providerID := c.Param("provider_id")
+	if userID == "" || providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID and provider ID are required"})
+		return
+	}
+
+	var request SetUserProviderRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the user provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.userProviderClient.SetUserProvider(ctx, &userProviderPb.SetUserProviderRequest{
+		UserId:     userID,
+		ProviderId: providerID,
+		Preference: request.Preference,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to set user provider")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Provider)
+}
+
+// RemoveUserProvider clears a user's favorite or block of a provider
+func (h *UserProviderHandler) RemoveUserProvider(c *gin.Context) {
+	userID := c.Param("id")
+	providerID := c.Param("provider_id")
+	if userID == "" || providerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID and provider ID are required"})
+		return
+	}
+
+	// Call the user provider service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := h.userProviderClient.RemoveUserProvider(ctx, &userProviderPb.RemoveUserProviderRequest{
+		UserId:     userID,
+		ProviderId: providerID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to remove user provider")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleError maps a user provider service error to an HTTP response
+func (h *UserProviderHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
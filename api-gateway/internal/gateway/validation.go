@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes a single invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Report fields by their JSON names rather than Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// bindJSON binds and validates the request body, writing an error response if it is invalid.
+// Malformed JSON gets a 400; well-formed JSON with invalid fields gets a 422 listing each field.
+func bindJSON(c *gin.Context, request interface{}) bool {
+	err := c.ShouldBindJSON(request)
+	if err == nil {
+		return true
+	}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": bodyTooLargeMessage(tooLarge.Limit)})
+	case errors.As(err, &validationErrs):
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   fieldPath(fe.Namespace()),
+				Message: validationMessage(fe),
+			})
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "validation failed", "fields": fields})
+	case errors.As(err, &typeErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "validation failed",
+			"fields": []FieldError{{
+				Field:   typeErr.Field,
+				Message: fmt.Sprintf("must be of type %s", typeErr.Type.String()),
+			}},
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+
+	return false
+}
+
+// validationErrors validates a request decoded without binding, such as one row of a
+// bulk upload, and describes each invalid field as "field: message"
+func validationErrors(request interface{}) []string {
+	err := binding.Validator.ValidateStruct(request)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return []string{err.Error()}
+	}
+
+	messages := make([]string, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		messages = append(messages, fieldPath(fe.Namespace())+": "+validationMessage(fe))
+	}
+	return messages
+}
+
+// fieldPath strips the top-level struct name from a validator namespace
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_if", "required_without":
+		return "is required"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters", fe.Param())
+	case "hexadecimal":
+		return "must be hexadecimal"
+	case "numeric":
+		return "must contain only digits"
+	case "url":
+		return "must be a valid URL"
+	default:
+		return fmt.Sprintf("failed %s validation", fe.Tag())
+	}
+}
@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Resources whose response shape may differ between API versions
+const (
+	ResourceOrder       = "order"
+	ResourceOrderList   = "order_list"
+	ResourceProvider    = "provider"
+	ResourceDispute     = "dispute"
+	ResourceDisputeList = "dispute_list"
+	ResourceLedger      = "ledger"
+)
+
+const versionContextKey = "api_version"
+
+// ResponseTransformer converts a handler's payload into a version's response shape
+type ResponseTransformer func(payload interface{}) interface{}
+
+// APIVersion is one version of the public API, mounted under /api/<name>
+type APIVersion struct {
+	Name         string
+	Deprecated   bool
+	Sunset       time.Time // Zero when no removal date is announced
+	Successor    string    // Path prefix of the replacement version, e.g. /api/v2
+	transformers map[string]ResponseTransformer
+}
+
+// NewAPIVersion creates an API version
+func NewAPIVersion(name string) *APIVersion {
+	return &APIVersion{
+		Name:         name,
+		transformers: make(map[string]ResponseTransformer),
+	}
+}
+
+// Transform registers the response transformer for a resource in this version
+func (v *APIVersion) Transform(resource string, transformer ResponseTransformer) *APIVersion {
+	v.transformers[resource] = transformer
+	return v
+}
+
+// Deprecate marks the version as deprecated; responses advertise the sunset date and successor
+func (v *APIVersion) Deprecate(sunset time.Time, successor string) *APIVersion {
+	v.Deprecated = true
+	v.Sunset = sunset
+	v.Successor = successor
+	return v
+}
+
+// Group creates the router group for this version
+func (v *APIVersion) Group(router *gin.Engine) *gin.RouterGroup {
+	return router.Group("/api/"+v.Name, v.middleware())
+}
+
+func (v *APIVersion) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(versionContextKey, v)
+		c.Header("API-Version", v.Name)
+
+		// Deprecation headers per RFC 8594 and draft-ietf-httpapi-deprecation-header
+		if v.Deprecated {
+			c.Header("Deprecation", "true")
+			if !v.Sunset.IsZero() {
+				c.Header("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if v.Successor != "" {
+				c.Header("Link", "<"+v.Successor+">; rel=\"successor-version\"")
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// apiVersion returns the version serving the request, or nil outside a version group
+func apiVersion(c *gin.Context) *APIVersion {
+	if value, ok := c.Get(versionContextKey); ok {
+		return value.(*APIVersion)
+	}
+	return nil
+}
+
+// transform shapes a payload for the request's API version
+func transform(c *gin.Context, resource string, payload interface{}) interface{} {
+	if v := apiVersion(c); v != nil {
+		if transformer, ok := v.transformers[resource]; ok {
+			return transformer(payload)
+		}
+	}
+	return payload
+}
+
+// respond writes a JSON response shaped for the request's API version
+func respond(c *gin.Context, code int, resource string, payload interface{}) {
+	c.JSON(code, transform(c, resource, payload))
+}
@@ -0,0 +1,179 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	walletPb "github.com/order-api-microservices/proto/wallet"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WalletHandler handles the API endpoints for users' wallet balances
+type WalletHandler struct {
+	walletClient walletPb.WalletServiceClient
+}
+
+// NewWalletHandler creates a new wallet handler
+func NewWalletHandler(walletClient walletPb.WalletServiceClient) *WalletHandler {
+	return &WalletHandler{
+		walletClient: walletClient,
+	}
+}
+
+// RegisterRoutes registers the wallet API routes on a version group
+func (h *WalletHandler) RegisterRoutes(api *gin.RouterGroup) {
+	wallet := api.Group("/users/:id/wallet")
+	{
+		wallet.GET("", h.GetWallet)
+		wallet.POST("/top-ups", h.TopUpWallet)
+		wallet.GET("/transactions", h.ListWalletTransactions)
+	}
+
+	admin := api.Group("/admin/users/:id/wallet")
+	{
+		admin.POST("/credits", h.CreditWallet)
+	}
+}
+
+// GetWallet returns a user's wallet balance
+func (h *WalletHandler) GetWallet(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	// Call the wallet service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.walletClient.GetWallet(ctx, &walletPb.GetWalletRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to get wallet")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Wallet)
+}
+
+// TopUpWallet charges one of a user's saved payment methods and adds it to their balance
+func (h *WalletHandler) TopUpWallet(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	var request TopUpWalletRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the wallet service; charging the card can take a while
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	resp, err := h.walletClient.TopUpWallet(ctx, &walletPb.TopUpWalletRequest{
+		UserId:          userID,
+		Amount:          request.Amount,
+		PaymentMethodId: request.PaymentMethodID,
+		IdempotencyKey:  request.IdempotencyKey,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to top up wallet")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListWalletTransactions lists a page of a user's wallet transactions, newest first
+func (h *WalletHandler) ListWalletTransactions(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Call the wallet service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.walletClient.ListWalletTransactions(ctx, &walletPb.ListWalletTransactionsRequest{
+		UserId: userID,
+		Page:   int32(page),
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list wallet transactions")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreditWallet gives a user wallet credit as compensation
+func (h *WalletHandler) CreditWallet(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	var request CreditWalletRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the wallet service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.walletClient.CreditWallet(ctx, &walletPb.CreditWalletRequest{
+		UserId:         userID,
+		Amount:         request.Amount,
+		OrderId:        request.OrderID,
+		Reason:         request.Reason,
+		CreditedBy:     request.CreditedBy,
+		IdempotencyKey: request.IdempotencyKey,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to credit wallet")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// handleError maps a wallet service error to an HTTP response
+func (h *WalletHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	case codes.FailedPrecondition:
+		c.JSON(http.StatusConflict, gin.H{"error": st.Message()})
+	case codes.Unavailable:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
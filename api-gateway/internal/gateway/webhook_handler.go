@@ -0,0 +1,237 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	webhookPb "github.com/order-api-microservices/proto/webhook"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WebhookHandler handles the API endpoints for partners' webhooks and their delivery log
+type WebhookHandler struct {
+	webhookClient webhookPb.WebhookServiceClient
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhookClient webhookPb.WebhookServiceClient) *WebhookHandler {
+	return &WebhookHandler{
+		webhookClient: webhookClient,
+	}
+}
+
+// RegisterRoutes registers the webhook API routes on a version group
+func (h *WebhookHandler) RegisterRoutes(api *gin.RouterGroup) {
+	webhooks := api.Group("/webhooks")
+	{
+		webhooks.POST("", h.CreateWebhook)
+		webhooks.GET("", h.ListWebhooks)
+		webhooks.GET("/:id", h.GetWebhook)
+		webhooks.PUT("/:id", h.UpdateWebhook)
+		webhooks.DELETE("/:id", h.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", h.ListWebhookDeliveries)
+		webhooks.POST("/:id/deliveries/:delivery_id/redeliver", h.RedeliverWebhook)
+	}
+}
+
+// CreateWebhook registers a partner's callback URL. The response carries the signing
+// secret, which is not shown again.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var request WebhookRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the webhook service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.webhookClient.CreateWebhook(ctx, &webhookPb.CreateWebhookRequest{
+		PartnerId:  request.PartnerID,
+		Url:        request.URL,
+		EventTypes: request.EventTypes,
+		OrderTypes: request.OrderTypes,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": resp.Webhook,
+		"secret":  resp.Secret,
+	})
+}
+
+// ListWebhooks lists webhooks, optionally only a single partner's
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	// Call the webhook service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.webhookClient.ListWebhooks(ctx, &webhookPb.ListWebhooksRequest{
+		PartnerId: c.Query("partner_id"),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list webhooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetWebhook gets a webhook
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhookID := c.Param("id")
+	if webhookID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "webhook ID is required"})
+		return
+	}
+
+	// Call the webhook service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.webhookClient.GetWebhook(ctx, &webhookPb.GetWebhookRequest{
+		Id: webhookID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to get webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Webhook)
+}
+
+// UpdateWebhook replaces a webhook's URL and event filters, or pauses or resumes it
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	webhookID := c.Param("id")
+	if webhookID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "webhook ID is required"})
+		return
+	}
+
+	var request UpdateWebhookRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	// Call the webhook service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.webhookClient.UpdateWebhook(ctx, &webhookPb.UpdateWebhookRequest{
+		Id:         webhookID,
+		Url:        request.URL,
+		EventTypes: request.EventTypes,
+		OrderTypes: request.OrderTypes,
+		Active:     *request.Active,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp.Webhook)
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	webhookID := c.Param("id")
+	if webhookID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "webhook ID is required"})
+		return
+	}
+
+	// Call the webhook service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := h.webhookClient.DeleteWebhook(ctx, &webhookPb.DeleteWebhookRequest{
+		Id: webhookID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries lists the events delivered, or still to be delivered, to a webhook
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	webhookID := c.Param("id")
+	if webhookID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "webhook ID is required"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	// Call the webhook service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.webhookClient.ListWebhookDeliveries(ctx, &webhookPb.ListWebhookDeliveriesRequest{
+		WebhookId: webhookID,
+		Status:    c.Query("status"),
+		Page:      int32(page),
+		Limit:     int32(limit),
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to list webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RedeliverWebhook queues a delivery to be sent again
+func (h *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	webhookID := c.Param("id")
+	deliveryID := c.Param("delivery_id")
+	if webhookID == "" || deliveryID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "webhook ID and delivery ID are required"})
+		return
+	}
+
+	// Call the webhook service
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.webhookClient.RedeliverWebhook(ctx, &webhookPb.RedeliverWebhookRequest{
+		WebhookId:  webhookID,
+		DeliveryId: deliveryID,
+	})
+	if err != nil {
+		h.handleError(c, err, "Failed to redeliver webhook")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, resp.Delivery)
+}
+
+// handleError maps a webhook service error to an HTTP response
+func (h *WebhookHandler) handleError(c *gin.Context, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
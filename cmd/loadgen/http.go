@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// post sends a JSON request to the gateway and records how long it took. The response,
+// when out is set, is decoded into it.
+func (g *generator) post(ctx context.Context, op, path string, body, out interface{}) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", op, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.cfg.gateway+path, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	g.authorize(req)
+
+	started := time.Now()
+	resp, err := g.client.Do(req)
+	elapsed := time.Since(started)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Cut off by the end of the run, not a failure of the gateway
+			return err
+		}
+		g.stats.fail(op, "request failed")
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, resp.Body)
+		g.stats.fail(op, fmt.Sprintf("HTTP %d", resp.StatusCode))
+		return fmt.Errorf("%s returned %d", op, resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			g.stats.fail(op, "undecodable response")
+			return fmt.Errorf("failed to decode %s response: %w", op, err)
+		}
+	} else {
+		io.Copy(io.Discard, resp.Body)
+	}
+
+	g.stats.record(op, elapsed)
+	return nil
+}
+
+// trackedLocation is the part of a tracking event the generator reads
+type trackedLocation struct {
+	CurrentLocation struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	} `json:"current_location"`
+}
+
+// track subscribes to an order's tracking stream until ctx is done, recording how long
+// subscribing took and how long after it was sent each location arrived
+func (g *generator) track(ctx context.Context, orderID string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.cfg.gateway+"/api/v1/orders/"+orderID+"/track", nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	g.authorize(req)
+
+	started := time.Now()
+	resp, err := g.streams.Do(req)
+	elapsed := time.Since(started)
+	if err != nil {
+		if ctx.Err() == nil {
+			g.stats.fail(opTrackSubscribe, "request failed")
+		}
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		g.stats.fail(opTrackSubscribe, fmt.Sprintf("HTTP %d", resp.StatusCode))
+		return
+	}
+	g.stats.record(opTrackSubscribe, elapsed)
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		received := time.Now()
+
+		var update trackedLocation
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &update); err != nil {
+			g.stats.fail(opTrackPush, "undecodable event")
+			continue
+		}
+		position := point{lat: update.CurrentLocation.Latitude, lng: update.CurrentLocation.Longitude}
+		// Locations sent before the subscription, such as the one it starts with, have
+		// no send time to measure from
+		if sent, ok := g.sent.Load(sentKey(orderID, position)); ok {
+			g.stats.record(opTrackPush, received.Sub(sent.(time.Time)))
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		g.stats.fail(opTrackPush, "stream broken")
+	}
+}
+
+// authorize adds the configured token to a request
+func (g *generator) authorize(req *http.Request) {
+	if g.cfg.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.cfg.token)
+	}
+}
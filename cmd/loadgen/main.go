@@ -0,0 +1,348 @@
+// Command loadgen drives realistic traffic at the API gateway and reports the latency of
+// each kind of request. Users create orders at a steady rate, and simulated drivers run
+// trips: each takes an order, sends its location at driver cadence and is followed by
+// tracking subscribers, whose push latency shows how quickly a location reaches them.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// config is the shape of the traffic to generate
+type config struct {
+	gateway          string
+	token            string
+	duration         time.Duration
+	orderRPS         float64
+	maxInFlight      int
+	drivers          int
+	providerIDs      []string
+	locationInterval time.Duration
+	tripUpdates      int
+	trackers         int
+	userID           string
+	orderType        string
+	centerLat        float64
+	centerLng        float64
+	radiusKm         float64
+}
+
+func main() {
+	gateway := flag.String("gateway", getEnv("LOADGEN_GATEWAY", "http://localhost:8080"), "Base URL of the API gateway")
+	token := flag.String("token", getEnv("LOADGEN_TOKEN", ""), "Bearer token sent with every request, if the gateway needs one")
+	duration := flag.Duration("duration", time.Minute, "How long to generate traffic")
+	orderRPS := flag.Float64("order-rps", 5, "Orders created per second by users (0 creates none besides the drivers' trips)")
+	maxInFlight := flag.Int("max-in-flight", 200, "Most order creations waiting for a response at once; more are counted as dropped")
+	drivers := flag.Int("drivers", 20, "Drivers running trips at once, each sending location updates")
+	providerIDs := flag.String("provider-ids", getEnv("LOADGEN_PROVIDER_IDS", ""), "IDs of existing providers the drivers act as, separated by commas; drivers share them when there are fewer")
+	locationInterval := flag.Duration("location-interval", 4*time.Second, "How often a driver sends its location")
+	tripUpdates := flag.Int("trip-updates", 30, "Location updates in a trip before the driver starts another")
+	trackers := flag.Int("trackers", 1, "Tracking subscriptions following each trip")
+	userID := flag.String("user-id", "loadgen-user", "User ID orders are created for")
+	orderType := flag.String("order-type", "RIDE", "Type of the orders created")
+	center := flag.String("center", "-6.2088,106.8456", "Latitude and longitude around which pickups and destinations are chosen")
+	radiusKm := flag.Float64("radius-km", 5, "Distance from the center within which pickups and destinations are chosen")
+	flag.Parse()
+
+	cfg := config{
+		gateway:          strings.TrimRight(*gateway, "/"),
+		token:            *token,
+		duration:         *duration,
+		orderRPS:         *orderRPS,
+		maxInFlight:      *maxInFlight,
+		drivers:          *drivers,
+		locationInterval: *locationInterval,
+		tripUpdates:      *tripUpdates,
+		trackers:         *trackers,
+		userID:           *userID,
+		orderType:        *orderType,
+		radiusKm:         *radiusKm,
+	}
+	for _, id := range strings.Split(*providerIDs, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			cfg.providerIDs = append(cfg.providerIDs, id)
+		}
+	}
+	var err error
+	if cfg.centerLat, cfg.centerLng, err = parseCenter(*center); err != nil {
+		log.Fatalf("Invalid center: %v", err)
+	}
+	if cfg.drivers > 0 && len(cfg.providerIDs) == 0 {
+		log.Printf("No provider IDs given; running without drivers")
+		cfg.drivers = 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.duration)
+	defer cancel()
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+		log.Println("Received signal, stopping early...")
+		cancel()
+	}()
+
+	gen := newGenerator(cfg)
+	log.Printf("Generating traffic against %s for %s: %.1f orders/s, %d drivers every %s, %d trackers per trip",
+		cfg.gateway, cfg.duration, cfg.orderRPS, cfg.drivers, cfg.locationInterval, cfg.trackers)
+
+	started := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		gen.createOrders(ctx)
+	}()
+	for i := 0; i < cfg.drivers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Spread the drivers' first updates over one interval, as real ones would be
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(rand.Int63n(int64(cfg.locationInterval) + 1))):
+			}
+			gen.drive(ctx, cfg.providerIDs[i%len(cfg.providerIDs)])
+		}(i)
+	}
+	wg.Wait()
+
+	gen.stats.report(os.Stdout, time.Since(started))
+}
+
+// generator sends the traffic and records how it went
+type generator struct {
+	cfg      config
+	client   *http.Client
+	streams  *http.Client // Without a timeout, for tracking subscriptions
+	stats    *stats
+	inFlight chan struct{}
+	sent     sync.Map // Time each location was sent, by order ID and position
+}
+
+// newGenerator creates a generator of the configured traffic
+func newGenerator(cfg config) *generator {
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: cfg.maxInFlight + cfg.drivers*(cfg.trackers+1),
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &generator{
+		cfg:      cfg,
+		client:   &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		streams:  &http.Client{Transport: transport},
+		stats:    newStats(),
+		inFlight: make(chan struct{}, cfg.maxInFlight),
+	}
+}
+
+// createOrders creates orders at the configured rate until ctx is done. Creations that
+// would go over the in-flight limit are dropped, so a slow gateway does not make the
+// generator queue requests without bound.
+func (g *generator) createOrders(ctx context.Context) {
+	if g.cfg.orderRPS <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / g.cfg.orderRPS))
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		select {
+		case g.inFlight <- struct{}{}:
+		default:
+			g.stats.drop(opCreateOrder)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-g.inFlight }()
+			g.createOrder(ctx)
+		}()
+	}
+}
+
+// drive runs trips as a provider until ctx is done
+func (g *generator) drive(ctx context.Context, providerID string) {
+	for ctx.Err() == nil {
+		if err := g.trip(ctx, providerID); err != nil && ctx.Err() == nil {
+			// Back off a little, so a failing gateway is not hammered by retrying trips
+			select {
+			case <-ctx.Done():
+			case <-time.After(g.cfg.locationInterval):
+			}
+		}
+	}
+}
+
+// trip creates an order, has the provider take it, and sends the provider's location as
+// it travels from the pickup to the destination while subscribers track the order
+func (g *generator) trip(ctx context.Context, providerID string) error {
+	orderID, pickup, destination, err := g.createOrder(ctx)
+	if err != nil {
+		return err
+	}
+	if err := g.post(ctx, opAssignProvider, "/api/v1/orders/"+orderID+"/assign", map[string]interface{}{
+		"provider_id": providerID,
+	}, nil); err != nil {
+		return err
+	}
+	if err := g.post(ctx, opAcceptOrder, "/api/v1/orders/"+orderID+"/accept", map[string]interface{}{
+		"provider_id":      providerID,
+		"current_location": pickup.request(),
+	}, nil); err != nil {
+		return err
+	}
+
+	trackCtx, stopTracking := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		stopTracking()
+		wg.Wait()
+		g.forget(orderID)
+	}()
+	for i := 0; i < g.cfg.trackers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.track(trackCtx, orderID)
+		}()
+	}
+
+	ticker := time.NewTicker(g.cfg.locationInterval)
+	defer ticker.Stop()
+	for step := 1; step <= g.cfg.tripUpdates; step++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		position := pickup.toward(destination, float64(step)/float64(g.cfg.tripUpdates))
+		g.sent.Store(sentKey(orderID, position), time.Now())
+		g.post(ctx, opUpdateLocation, "/api/v1/orders/"+orderID+"/location", map[string]interface{}{
+			"provider_id": providerID,
+			"location":    position.request(),
+		}, nil)
+	}
+	return nil
+}
+
+// createOrder creates an order between two random points near the center
+func (g *generator) createOrder(ctx context.Context) (string, point, point, error) {
+	pickup, destination := g.randomPoint(), g.randomPoint()
+	var created struct {
+		ID string `json:"id"`
+	}
+	err := g.post(ctx, opCreateOrder, "/api/v1/orders", map[string]interface{}{
+		"user_id":              g.cfg.userID,
+		"order_type":           g.cfg.orderType,
+		"pickup_location":      pickup.request(),
+		"destination_location": destination.request(),
+		"payment_method":       "CASH",
+		"items": []map[string]interface{}{
+			{"name": "Load test item", "quantity": 1, "price": 1000},
+		},
+	}, &created)
+	if err != nil {
+		return "", point{}, point{}, err
+	}
+	if created.ID == "" {
+		g.stats.fail(opCreateOrder, "no order ID in response")
+		return "", point{}, point{}, fmt.Errorf("no order ID in response")
+	}
+	return created.ID, pickup, destination, nil
+}
+
+// forget drops the send times of a finished trip's locations
+func (g *generator) forget(orderID string) {
+	g.sent.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), orderID+"/") {
+			g.sent.Delete(key)
+		}
+		return true
+	})
+}
+
+// point is a position on the map
+type point struct {
+	lat, lng float64
+}
+
+// randomPoint picks a point within the configured radius of the center
+func (g *generator) randomPoint() point {
+	distance := g.cfg.radiusKm * math.Sqrt(rand.Float64())
+	bearing := 2 * math.Pi * rand.Float64()
+	dLat := distance / 111.32 * math.Cos(bearing)
+	dLng := distance / (111.32 * math.Cos(g.cfg.centerLat*math.Pi/180)) * math.Sin(bearing)
+	return point{lat: g.cfg.centerLat + dLat, lng: g.cfg.centerLng + dLng}
+}
+
+// toward returns the point a fraction of the way to another
+func (p point) toward(to point, fraction float64) point {
+	return point{
+		lat: p.lat + (to.lat-p.lat)*fraction,
+		lng: p.lng + (to.lng-p.lng)*fraction,
+	}
+}
+
+// request converts a point to a location in an API request
+func (p point) request() map[string]interface{} {
+	return map[string]interface{}{
+		"latitude":  p.lat,
+		"longitude": p.lng,
+	}
+}
+
+// sentKey identifies a location sent for an order. Positions are rounded as the gateway
+// echoes them, so a pushed location finds the time it was sent.
+func sentKey(orderID string, p point) string {
+	return fmt.Sprintf("%s/%.5f,%.5f", orderID, p.lat, p.lng)
+}
+
+// parseCenter parses a latitude and longitude separated by a comma
+func parseCenter(s string) (float64, float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("want latitude,longitude, got %q", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("invalid latitude %q", parts[0])
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return 0, 0, fmt.Errorf("invalid longitude %q", parts[1])
+	}
+	return lat, lng, nil
+}
+
+// Helper function to get environment variables
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Operations whose latency is reported
+const (
+	opCreateOrder    = "create_order"
+	opAssignProvider = "assign_provider"
+	opAcceptOrder    = "accept_order"
+	opUpdateLocation = "update_location"
+	opTrackSubscribe = "track_subscribe"
+	opTrackPush      = "track_push" // From sending a location to a subscriber receiving it
+)
+
+// operations are the operations in the order they are reported
+var operations = []string{opCreateOrder, opAssignProvider, opAcceptOrder, opUpdateLocation, opTrackSubscribe, opTrackPush}
+
+// opStats are the outcomes of one operation
+type opStats struct {
+	latencies []time.Duration
+	failures  map[string]int // By reason
+	dropped   int
+}
+
+// stats collects the outcomes of every operation
+type stats struct {
+	mu  sync.Mutex
+	ops map[string]*opStats
+}
+
+// newStats creates empty stats
+func newStats() *stats {
+	s := &stats{ops: make(map[string]*opStats)}
+	for _, op := range operations {
+		s.ops[op] = &opStats{failures: make(map[string]int)}
+	}
+	return s
+}
+
+// record records a successful operation and how long it took
+func (s *stats) record(op string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops[op].latencies = append(s.ops[op].latencies, latency)
+}
+
+// fail records a failed operation and why it failed
+func (s *stats) fail(op, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops[op].failures[reason]++
+}
+
+// drop records an operation that was not sent because too many were in flight
+func (s *stats) drop(op string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops[op].dropped++
+}
+
+// report writes the count, rate and latency percentiles of each operation
+func (s *stats) report(w io.Writer, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "operation\tok\tfailed\tdropped\trate/s\tp50\tp90\tp99\tmax\t\n")
+	for _, op := range operations {
+		o := s.ops[op]
+		failed := 0
+		for _, n := range o.failures {
+			failed += n
+		}
+		if len(o.latencies) == 0 && failed == 0 && o.dropped == 0 {
+			continue
+		}
+
+		sort.Slice(o.latencies, func(i, j int) bool { return o.latencies[i] < o.latencies[j] })
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", op, len(o.latencies), failed, o.dropped,
+			float64(len(o.latencies)+failed)/elapsed.Seconds(),
+			percentile(o.latencies, 0.50), percentile(o.latencies, 0.90), percentile(o.latencies, 0.99), percentile(o.latencies, 1))
+	}
+	tw.Flush()
+
+	for _, op := range operations {
+		for reason, n := range s.ops[op].failures {
+			fmt.Fprintf(w, "%s failed %d times: %s\n", op, n, reason)
+		}
+	}
+}
+
+// percentile returns the latency below which a fraction of sorted latencies fall
+func percentile(sorted []time.Duration, fraction float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(fraction*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i].Round(time.Millisecond)
+}
@@ -0,0 +1,85 @@
+package testharness
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// anvilImage runs anvil, Foundry's local Ethereum node
+const anvilImage = "ghcr.io/foundry-rs/foundry:latest"
+
+// Anvil's first default account, which it funds with 10000 ETH. The key is public and
+// must never hold real funds.
+const (
+	anvilChainID    = 31337
+	anvilAddress    = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	anvilPrivateKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+)
+
+var (
+	sharedChain     *Chain
+	sharedChainErr  error
+	sharedChainOnce sync.Once
+)
+
+// Chain is a local Ethereum node, shared by every test in a package
+type Chain struct {
+	RPCURL     string
+	ChainID    int64
+	Address    string // Funded account
+	PrivateKey string // Hex key of Address, without 0x
+}
+
+// EthereumChain returns the package's local Ethereum node, starting it on first use.
+// Blocks are mined as soon as a transaction arrives. The test is skipped when Docker is
+// not available.
+func EthereumChain(t testing.TB) *Chain {
+	t.Helper()
+
+	sharedChainOnce.Do(func() {
+		sharedChain, sharedChainErr = startChain(context.Background())
+	})
+	if sharedChainErr != nil {
+		skipOrFail(t, "start the Ethereum node", sharedChainErr)
+	}
+
+	return sharedChain
+}
+
+// startChain starts the anvil container
+func startChain(ctx context.Context) (*Chain, error) {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        anvilImage,
+			ExposedPorts: []string{"8545/tcp"},
+			Entrypoint:   []string{"anvil"},
+			Cmd:          []string{"--host", "0.0.0.0", "--chain-id", fmt.Sprint(anvilChainID)},
+			WaitingFor:   wait.ForListeningPort("8545/tcp").WithStartupTimeout(startupTimeout),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		return nil, err
+	}
+	port, err := container.MappedPort(ctx, "8545/tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Chain{
+		RPCURL:     fmt.Sprintf("http://%s:%d", host, port.Int()),
+		ChainID:    anvilChainID,
+		Address:    anvilAddress,
+		PrivateKey: anvilPrivateKey,
+	}, nil
+}
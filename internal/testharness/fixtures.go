@@ -0,0 +1,164 @@
+package testharness
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/database"
+)
+
+// OrderFixture is an order row to insert into an order service database. Zero fields
+// get the defaults below, so a test sets only what it is about.
+type OrderFixture struct {
+	ID            string  // Defaults to a new UUID
+	UserID        string  // Defaults to a new UUID
+	ProviderID    string  // Empty leaves the order unassigned
+	OrderType     string  // Defaults to RIDE
+	Status        string  // Defaults to CREATED
+	PaymentMethod string  // Defaults to CREDIT_CARD
+	PickupLat     float64 // Pickup defaults to central Jakarta
+	PickupLon     float64
+	DestLat       float64 // Destination defaults to about 2 km from the default pickup
+	DestLon       float64
+	City          string    // City of both locations; defaults to Jakarta
+	TotalPrice    int64     // Defaults to 10000 minor units, with a 20% platform fee
+	CreatedAt     time.Time // Defaults to now
+}
+
+// ProviderFixture is a provider row to insert into a provider service database
+type ProviderFixture struct {
+	ID             string   // Defaults to a new UUID
+	Name           string   // Defaults to "Test Provider"
+	ServiceTypes   []string // Defaults to RIDE
+	Lat            float64  // Defaults to central Jakarta
+	Lon            float64
+	Available      bool
+	ServiceAreaIDs []string
+}
+
+// InsertOrder inserts an order with a single status history entry and returns its ID.
+// Addresses and notes are stored in plaintext, as a repository without keys stores them.
+func InsertOrder(t testing.TB, db *database.PostgresDB, f OrderFixture) string {
+	t.Helper()
+
+	if f.ID == "" {
+		f.ID = uuid.New().String()
+	}
+	if f.UserID == "" {
+		f.UserID = uuid.New().String()
+	}
+	if f.OrderType == "" {
+		f.OrderType = "RIDE"
+	}
+	if f.Status == "" {
+		f.Status = "CREATED"
+	}
+	if f.PaymentMethod == "" {
+		f.PaymentMethod = "CREDIT_CARD"
+	}
+	if f.PickupLat == 0 && f.PickupLon == 0 {
+		f.PickupLat, f.PickupLon = -6.2000, 106.8166
+	}
+	if f.DestLat == 0 && f.DestLon == 0 {
+		f.DestLat, f.DestLon = f.PickupLat+0.018, f.PickupLon
+	}
+	if f.City == "" {
+		f.City = "Jakarta"
+	}
+	if f.TotalPrice == 0 {
+		f.TotalPrice = 10000
+	}
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = time.Now()
+	}
+	platformFee := f.TotalPrice / 5
+
+	pickup := mustJSON(t, map[string]interface{}{"latitude": f.PickupLat, "longitude": f.PickupLon, "address": "Pickup", "city": f.City})
+	destination := mustJSON(t, map[string]interface{}{"latitude": f.DestLat, "longitude": f.DestLon, "address": "Destination", "city": f.City})
+	history := mustJSON(t, []map[string]interface{}{{"status": f.Status, "updated_by": f.UserID, "timestamp": f.CreatedAt}})
+
+	query := `
+		INSERT INTO orders (
+			id, user_id, provider_id, order_type, status,
+			pickup_location, destination_location, items,
+			total_price, platform_fee, provider_fee,
+			transaction_id, blockchain_tx_hash, payment_method,
+			notes, created_at, updated_at, status_history
+		) VALUES (
+			$1, $2, NULLIF($3, ''), $4, $5,
+			$6, $7, '[]',
+			$8, $9, $10,
+			'', '', $11,
+			'', $12, $12, $13
+		)
+	`
+
+	_, err := db.ExecContext(context.Background(), query,
+		f.ID, f.UserID, f.ProviderID, f.OrderType, f.Status,
+		pickup, destination,
+		f.TotalPrice, platformFee, f.TotalPrice-platformFee,
+		f.PaymentMethod,
+		f.CreatedAt, history,
+	)
+	if err != nil {
+		t.Fatalf("Failed to insert order fixture: %v", err)
+	}
+
+	return f.ID
+}
+
+// InsertProvider inserts a provider and returns its ID. Contact details are stored in
+// plaintext, as a repository without keys stores them.
+func InsertProvider(t testing.TB, db *database.PostgresDB, f ProviderFixture) string {
+	t.Helper()
+
+	if f.ID == "" {
+		f.ID = uuid.New().String()
+	}
+	if f.Name == "" {
+		f.Name = "Test Provider"
+	}
+	if len(f.ServiceTypes) == 0 {
+		f.ServiceTypes = []string{"RIDE"}
+	}
+	if f.Lat == 0 && f.Lon == 0 {
+		f.Lat, f.Lon = -6.2000, 106.8166
+	}
+	if f.ServiceAreaIDs == nil {
+		f.ServiceAreaIDs = []string{}
+	}
+
+	query := `
+		INSERT INTO providers (
+			id, name, email, phone, rating, service_types, location, is_available,
+			max_concurrent_orders, service_area_ids, profile_image, metadata, created_at, updated_at
+		)
+		VALUES ($1, $2, '', '', 5, $3, $4, $5, 0, $6, '', '{}', NOW(), NOW())
+	`
+
+	_, err := db.ExecContext(context.Background(), query,
+		f.ID, f.Name,
+		mustJSON(t, f.ServiceTypes),
+		mustJSON(t, map[string]interface{}{"latitude": f.Lat, "longitude": f.Lon, "address": "Test"}),
+		f.Available, f.ServiceAreaIDs,
+	)
+	if err != nil {
+		t.Fatalf("Failed to insert provider fixture: %v", err)
+	}
+
+	return f.ID
+}
+
+// mustJSON encodes a fixture column, failing the test if it cannot be encoded
+func mustJSON(t testing.TB, value interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Failed to encode fixture: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,47 @@
+package testharness
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// bufferSize is the size of the in-memory connection between client and server
+const bufferSize = 1024 * 1024
+
+// ServeGRPC serves the services register adds on an in-memory listener and returns a
+// client connection to them, so end-to-end tests exercise the real server stack,
+// interceptors included, without opening ports. Both are closed when the test ends.
+func ServeGRPC(t testing.TB, register func(server *grpc.Server), opts ...grpc.ServerOption) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(bufferSize)
+	server := grpc.NewServer(opts...)
+	register(server)
+
+	go func() {
+		// Serve returns once the server is stopped at the end of the test
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial in-memory gRPC server: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+
+	return conn
+}
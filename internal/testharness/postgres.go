@@ -0,0 +1,234 @@
+// Package testharness runs the infrastructure integration tests need in throwaway
+// containers: Postgres with a service's migrations applied, and optionally a local
+// Ethereum chain. It also serves gRPC services in memory and inserts fixtures, so
+// repository and end-to-end tests run the same on a laptop and in any CI with Docker.
+//
+// Integration tests are built with the integration tag and skip themselves when Docker
+// is not available, unless INTEGRATION_REQUIRED is true:
+//
+//	//go:build integration
+//
+//	func TestCreateOrder(t *testing.T) {
+//		db := testharness.Postgres(t).Database(t, "order")
+//		repo := repository.NewOrderRepository(db, nil)
+//		...
+//	}
+package testharness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// postgresImage is the image docker-compose runs, so tests see the same Postgres
+const postgresImage = "postgres:14-alpine"
+
+// Credentials of the test Postgres server
+const (
+	postgresUser     = "postgres"
+	postgresPassword = "postgres"
+)
+
+// startupTimeout is how long a container has to become ready
+const startupTimeout = 2 * time.Minute
+
+var (
+	sharedPostgres     *PostgresServer
+	sharedPostgresErr  error
+	sharedPostgresOnce sync.Once
+)
+
+// PostgresServer is a Postgres container shared by every test in a package. Each test
+// gets its own database on it, so tests never see each other's rows.
+type PostgresServer struct {
+	host string
+	port int
+
+	mu        sync.Mutex
+	templates map[string]bool // Services whose migrated template database exists
+	databases int
+}
+
+// Postgres returns the package's Postgres server, starting it on first use. The
+// container is removed when the test binary exits. The test is skipped when Docker is
+// not available.
+func Postgres(t testing.TB) *PostgresServer {
+	t.Helper()
+
+	sharedPostgresOnce.Do(func() {
+		sharedPostgres, sharedPostgresErr = startPostgres(context.Background())
+	})
+	if sharedPostgresErr != nil {
+		skipOrFail(t, "start Postgres", sharedPostgresErr)
+	}
+
+	return sharedPostgres
+}
+
+// Database creates an empty database with a service's migrations applied and connects to
+// it. The service is the name of its directory under services, such as "order" or
+// "provider". The database is dropped when the test ends.
+func (s *PostgresServer) Database(t testing.TB, service string) *database.PostgresDB {
+	t.Helper()
+	ctx := context.Background()
+
+	template, err := s.template(ctx, service)
+	if err != nil {
+		t.Fatalf("Failed to migrate %s database: %v", service, err)
+	}
+
+	s.mu.Lock()
+	s.databases++
+	name := fmt.Sprintf("%s_test_%d", service, s.databases)
+	s.mu.Unlock()
+
+	err = s.exec(ctx, "postgres", fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s",
+		pgx.Identifier{name}.Sanitize(), pgx.Identifier{template}.Sanitize()))
+	if err != nil {
+		t.Fatalf("Failed to create %s database: %v", service, err)
+	}
+
+	db, err := database.NewPostgresDB(s.config(name))
+	if err != nil {
+		t.Fatalf("Failed to connect to %s database: %v", service, err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		_ = s.exec(context.Background(), "postgres", fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", pgx.Identifier{name}.Sanitize()))
+	})
+
+	return db
+}
+
+// Config returns the connection settings of one of the server's databases, for code
+// under test that connects by itself
+func (s *PostgresServer) Config(databaseName string) *database.PostgresConfig {
+	return s.config(databaseName)
+}
+
+// template creates the template database holding a service's migrated schema, once per
+// service, and returns its name. Test databases are copied from it, which is much faster
+// than migrating each one.
+func (s *PostgresServer) template(ctx context.Context, service string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := service + "_template"
+	if s.templates[service] {
+		return name, nil
+	}
+
+	script, err := os.ReadFile(filepath.Join(RepoRoot(), "services", service, "scripts", "init.sql"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	if err := s.exec(ctx, "postgres", "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()); err != nil {
+		return "", err
+	}
+	// Without arguments the script is sent as one simple query, so it can hold many statements
+	if err := s.exec(ctx, name, string(script)); err != nil {
+		return "", fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	s.templates[service] = true
+	return name, nil
+}
+
+// exec runs SQL against one of the server's databases on a connection of its own, since
+// CREATE and DROP DATABASE cannot run on a database that is in use
+func (s *PostgresServer) exec(ctx context.Context, databaseName, sql string) error {
+	conn, err := pgx.Connect(ctx, s.config(databaseName).ConnectionString())
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", databaseName, err)
+	}
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, sql)
+	return err
+}
+
+// config returns the connection settings of one of the server's databases
+func (s *PostgresServer) config(databaseName string) *database.PostgresConfig {
+	config := database.NewPostgresConfig(s.host, s.port, postgresUser, postgresPassword, databaseName, "disable")
+	config.MaxConns = 4
+	// Slow queries are expected on a container and only add noise to test output
+	config.SlowQueryThreshold = 0
+	return config
+}
+
+// startPostgres starts the Postgres container
+func startPostgres(ctx context.Context) (*PostgresServer, error) {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        postgresImage,
+			ExposedPorts: []string{"5432/tcp"},
+			Env: map[string]string{
+				"POSTGRES_USER":     postgresUser,
+				"POSTGRES_PASSWORD": postgresPassword,
+			},
+			// Postgres restarts once after running its init scripts
+			WaitingFor: wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(startupTimeout),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		return nil, err
+	}
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresServer{
+		host:      host,
+		port:      port.Int(),
+		templates: make(map[string]bool),
+	}, nil
+}
+
+// RepoRoot is the directory holding the module's go.mod
+func RepoRoot() string {
+	_, file, _, _ := runtime.Caller(0)
+	dir := filepath.Dir(file)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			panic(errors.New("testharness: go.mod not found above " + filepath.Dir(file)))
+		}
+		dir = parent
+	}
+}
+
+// skipOrFail skips a test that needs a container that could not be started, or fails
+// it when INTEGRATION_REQUIRED is true so CI cannot pass by skipping everything
+func skipOrFail(t testing.TB, what string, err error) {
+	t.Helper()
+	if os.Getenv("INTEGRATION_REQUIRED") == "true" {
+		t.Fatalf("Failed to %s: %v", what, err)
+	}
+	t.Skipf("Skipping, failed to %s (is Docker running?): %v", what, err)
+}
@@ -0,0 +1,102 @@
+package anchor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// IPFSStore stores payloads through the HTTP API of an IPFS node
+type IPFSStore struct {
+	apiURL     string
+	httpClient *http.Client
+}
+
+// NewIPFSStore creates a payload store backed by the IPFS node at apiURL (e.g. http://ipfs:5001)
+func NewIPFSStore(apiURL string) (*IPFSStore, error) {
+	if apiURL == "" {
+		return nil, fmt.Errorf("IPFS API URL is required")
+	}
+
+	return &IPFSStore{
+		apiURL:     strings.TrimRight(apiURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Put adds and pins the payload, returning its CID
+func (s *IPFSStore) Put(ctx context.Context, payload []byte) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "order.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to create multipart body: %v", err)
+	}
+	if _, err := part.Write(payload); err != nil {
+		return "", fmt.Errorf("failed to write payload: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to close multipart body: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiURL+"/api/v0/add?pin=true&cid-version=1", &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create IPFS request: %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to add payload to IPFS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("IPFS add returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Hash string `json:"Hash"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode IPFS response: %v", err)
+	}
+	if result.Hash == "" {
+		return "", fmt.Errorf("IPFS returned an empty CID")
+	}
+
+	return result.Hash, nil
+}
+
+// Get fetches the payload with the given CID
+func (s *IPFSStore) Get(ctx context.Context, ref string) ([]byte, error) {
+	endpoint := s.apiURL + "/api/v0/cat?arg=" + url.QueryEscape(ref)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IPFS request: %v", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch payload from IPFS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("IPFS cat returned status %d", resp.StatusCode)
+	}
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IPFS payload: %v", err)
+	}
+
+	return payload, nil
+}
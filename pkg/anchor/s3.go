@@ -0,0 +1,86 @@
+package anchor
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Store stores payloads as content-addressed objects in an S3 bucket
+type S3Store struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Store creates a payload store backed by the given S3 bucket
+func NewS3Store(ctx context.Context, bucket, region, prefix string) (*S3Store, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+
+	var opts []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %v", err)
+	}
+
+	return &S3Store{
+		client: s3.NewFromConfig(cfg),
+		bucket: bucket,
+		prefix: strings.Trim(prefix, "/"),
+	}, nil
+}
+
+// Put stores the payload under its SHA-256 hash and returns an s3:// reference
+func (s *S3Store) Put(ctx context.Context, payload []byte) (string, error) {
+	hash := HashPayload(payload)
+	key := path.Join(s.prefix, hex.EncodeToString(hash[:])+".json")
+
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(payload),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload payload to S3: %v", err)
+	}
+
+	return fmt.Sprintf("s3://%s/%s", s.bucket, key), nil
+}
+
+// Get fetches the payload referenced by an s3:// URL
+func (s *S3Store) Get(ctx context.Context, ref string) ([]byte, error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(ref, "s3://"), "/")
+	if !strings.HasPrefix(ref, "s3://") || !ok {
+		return nil, fmt.Errorf("invalid S3 reference %q", ref)
+	}
+
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch payload from S3: %v", err)
+	}
+	defer out.Body.Close()
+
+	payload, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read S3 payload: %v", err)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,49 @@
+package anchor
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+)
+
+// Supported payload store backends
+const (
+	BackendIPFS = "ipfs"
+	BackendS3   = "s3"
+)
+
+// PayloadStore stores immutable order payload snapshots off-chain
+type PayloadStore interface {
+	// Put stores the payload and returns a reference (CID or object URL) to it
+	Put(ctx context.Context, payload []byte) (string, error)
+	// Get fetches a previously stored payload by reference
+	Get(ctx context.Context, ref string) ([]byte, error)
+}
+
+// Config selects and configures the payload store
+type Config struct {
+	Backend    string `mapstructure:"backend"`
+	IPFSAPIURL string `mapstructure:"ipfs_api_url"`
+	S3Bucket   string `mapstructure:"s3_bucket"`
+	S3Region   string `mapstructure:"s3_region"`
+	S3Prefix   string `mapstructure:"s3_prefix"`
+}
+
+// NewPayloadStore creates the configured payload store, or returns nil if anchoring is disabled
+func NewPayloadStore(ctx context.Context, config Config) (PayloadStore, error) {
+	switch config.Backend {
+	case "":
+		return nil, nil
+	case BackendIPFS:
+		return NewIPFSStore(config.IPFSAPIURL)
+	case BackendS3:
+		return NewS3Store(ctx, config.S3Bucket, config.S3Region, config.S3Prefix)
+	default:
+		return nil, fmt.Errorf("unknown payload store backend %q", config.Backend)
+	}
+}
+
+// HashPayload returns the hash recorded on-chain for a payload
+func HashPayload(payload []byte) [32]byte {
+	return sha256.Sum256(payload)
+}
@@ -0,0 +1,356 @@
+// Package audit keeps an append-only log of every mutating gRPC call a service handles:
+// who made it, from where, what it changed and how it ended. Entries can optionally be
+// hash-chained so that rewriting the log in the database is detectable.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/order-api-microservices/pkg/database"
+)
+
+// chainLockID is the advisory lock that serializes appends to a hash-chained log
+const chainLockID = 0x61756469
+
+// Entry is one recorded call
+type Entry struct {
+	Seq              int64           `json:"seq"`
+	Service          string          `json:"service"`
+	Method           string          `json:"method"`
+	ActorID          string          `json:"actor_id"`
+	SourceIP         string          `json:"source_ip"`
+	ResourceType     string          `json:"resource_type"`
+	ResourceID       string          `json:"resource_id"`
+	Before           json.RawMessage `json:"before,omitempty"` // Resource state before the call
+	After            json.RawMessage `json:"after,omitempty"`  // Resource state after the call
+	Diff             json.RawMessage `json:"diff,omitempty"`   // Fields that changed, with their old and new values
+	StatusCode       string          `json:"status_code"`
+	Error            string          `json:"error,omitempty"`
+	ImpersonatedBy   string          `json:"impersonated_by,omitempty"` // Admin who made the call as the actor, in a support session
+	SupportSessionID string          `json:"support_session_id,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	PrevHash         string          `json:"prev_hash,omitempty"`
+	Hash             string          `json:"hash,omitempty"`
+}
+
+// Filter narrows a query of the log. Empty fields match everything.
+type Filter struct {
+	ActorID          string
+	ResourceType     string
+	ResourceID       string
+	Method           string
+	SupportSessionID string // Calls made in one support session
+	From             time.Time
+	To               time.Time
+}
+
+// VerifyResult reports the outcome of checking a hash-chained log
+type VerifyResult struct {
+	EntriesChecked  int64
+	FirstInvalidSeq int64 // 0 when the chain is intact
+	Reason          string
+}
+
+// Log stores entries in the audit_log table
+type Log struct {
+	db        *database.PostgresDB
+	service   string
+	hashChain bool
+}
+
+// NewLog creates a log for a service's entries. With hashChain, every entry stores the
+// hash of the one before it and a hash over its own contents.
+func NewLog(db *database.PostgresDB, service string, hashChain bool) *Log {
+	return &Log{
+		db:        db,
+		service:   service,
+		hashChain: hashChain,
+	}
+}
+
+// Append records an entry, filling in its service, time and, on a chained log, hashes
+func (l *Log) Append(ctx context.Context, entry *Entry) error {
+	entry.Service = l.service
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	insert := `
+		INSERT INTO audit_log (
+			service, method, actor_id, source_ip, resource_type, resource_id,
+			before, after, diff, status_code, error, impersonated_by, support_session_id,
+			created_at, prev_hash, hash
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NULLIF($15, ''), NULLIF($16, ''))
+		RETURNING seq
+	`
+
+	if !l.hashChain {
+		err := l.db.QueryRowContext(ctx, insert, entryArgs(entry)...).Scan(&entry.Seq)
+		if err != nil {
+			return fmt.Errorf("failed to append audit entry: %w", err)
+		}
+		return nil
+	}
+
+	tx, err := l.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Appends are serialized so every entry chains to the one committed just before it
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, chainLockID); err != nil {
+		return fmt.Errorf("failed to lock audit chain: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `SELECT COALESCE((SELECT hash FROM audit_log WHERE hash IS NOT NULL ORDER BY seq DESC LIMIT 1), '')`).Scan(&entry.PrevHash)
+	if err != nil {
+		return fmt.Errorf("failed to get previous audit hash: %w", err)
+	}
+	entry.Hash = hashEntry(entry)
+
+	if err := tx.QueryRow(ctx, insert, entryArgs(entry)...).Scan(&entry.Seq); err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// List gets entries matching filter, newest first, along with how many match in total
+func (l *Log) List(ctx context.Context, filter Filter, page, limit int) ([]*Entry, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorID != "" {
+		addCondition("actor_id = $%d", filter.ActorID)
+	}
+	if filter.ResourceType != "" {
+		addCondition("resource_type = $%d", filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		addCondition("resource_id = $%d", filter.ResourceID)
+	}
+	if filter.Method != "" {
+		addCondition("method = $%d", filter.Method)
+	}
+	if filter.SupportSessionID != "" {
+		addCondition("support_session_id = $%d", filter.SupportSessionID)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To.UTC())
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := l.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM audit_log%s
+		ORDER BY seq DESC
+		LIMIT $%d OFFSET $%d
+	`, entryColumns, where, len(args)+1, len(args)+2)
+	args = append(args, limit, (page-1)*limit)
+
+	entries, err := l.query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// Verify walks the hash-chained entries in order, recomputing each hash and checking
+// that it links to the entry before it. Entries recorded while chaining was off are
+// skipped.
+func (l *Log) Verify(ctx context.Context) (VerifyResult, error) {
+	const pageSize = 1000
+
+	var result VerifyResult
+	var prevHash string
+	var afterSeq int64
+	for {
+		query := fmt.Sprintf(`
+			SELECT %s
+			FROM audit_log
+			WHERE hash IS NOT NULL AND seq > $1
+			ORDER BY seq
+			LIMIT $2
+		`, entryColumns)
+
+		entries, err := l.query(ctx, query, afterSeq, pageSize)
+		if err != nil {
+			return result, err
+		}
+
+		for _, entry := range entries {
+			result.EntriesChecked++
+			if entry.PrevHash != prevHash {
+				result.FirstInvalidSeq = entry.Seq
+				result.Reason = "entry does not link to the entry before it"
+				return result, nil
+			}
+			if hashEntry(entry) != entry.Hash {
+				result.FirstInvalidSeq = entry.Seq
+				result.Reason = "entry contents do not match its hash"
+				return result, nil
+			}
+			prevHash = entry.Hash
+			afterSeq = entry.Seq
+		}
+
+		if len(entries) < pageSize {
+			return result, nil
+		}
+	}
+}
+
+// entryColumns are the audit_log columns scanned by query, in order
+const entryColumns = `seq, service, method, actor_id, source_ip, resource_type, resource_id,
+		       before, after, diff, status_code, error, impersonated_by, support_session_id,
+		       created_at, COALESCE(prev_hash, ''), COALESCE(hash, '')`
+
+// query runs a query selecting entryColumns
+func (l *Log) query(ctx context.Context, query string, args ...interface{}) ([]*Entry, error) {
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*Entry{}
+	for rows.Next() {
+		entry := &Entry{}
+		var before, after, diff []byte
+		err := rows.Scan(
+			&entry.Seq,
+			&entry.Service,
+			&entry.Method,
+			&entry.ActorID,
+			&entry.SourceIP,
+			&entry.ResourceType,
+			&entry.ResourceID,
+			&before,
+			&after,
+			&diff,
+			&entry.StatusCode,
+			&entry.Error,
+			&entry.ImpersonatedBy,
+			&entry.SupportSessionID,
+			&entry.CreatedAt,
+			&entry.PrevHash,
+			&entry.Hash,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.Before, entry.After, entry.Diff = before, after, diff
+		entry.CreatedAt = entry.CreatedAt.UTC()
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// entryArgs are the insert arguments for an entry. Snapshots are stored as JSON text,
+// not JSONB, so the bytes that were hashed are the bytes that are read back.
+func entryArgs(entry *Entry) []interface{} {
+	return []interface{}{
+		entry.Service,
+		entry.Method,
+		entry.ActorID,
+		entry.SourceIP,
+		entry.ResourceType,
+		entry.ResourceID,
+		nullableJSON(entry.Before),
+		nullableJSON(entry.After),
+		nullableJSON(entry.Diff),
+		entry.StatusCode,
+		entry.Error,
+		entry.ImpersonatedBy,
+		entry.SupportSessionID,
+		entry.CreatedAt,
+		entry.PrevHash,
+		entry.Hash,
+	}
+}
+
+// nullableJSON stores an empty snapshot as NULL
+func nullableJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+// hashEntry hashes an entry's contents together with the hash of the entry before it.
+// Fields added to entries later are left out of the hashed contents when empty, so
+// entries recorded before them still verify.
+func hashEntry(entry *Entry) string {
+	contents, _ := json.Marshal(struct {
+		Service          string `json:"service"`
+		Method           string `json:"method"`
+		ActorID          string `json:"actor_id"`
+		SourceIP         string `json:"source_ip"`
+		ResourceType     string `json:"resource_type"`
+		ResourceID       string `json:"resource_id"`
+		Before           string `json:"before"`
+		After            string `json:"after"`
+		Diff             string `json:"diff"`
+		StatusCode       string `json:"status_code"`
+		Error            string `json:"error"`
+		CreatedAt        string `json:"created_at"`
+		ImpersonatedBy   string `json:"impersonated_by,omitempty"`
+		SupportSessionID string `json:"support_session_id,omitempty"`
+	}{
+		Service:          entry.Service,
+		Method:           entry.Method,
+		ActorID:          entry.ActorID,
+		SourceIP:         entry.SourceIP,
+		ResourceType:     entry.ResourceType,
+		ResourceID:       entry.ResourceID,
+		Before:           string(entry.Before),
+		After:            string(entry.After),
+		Diff:             string(entry.Diff),
+		StatusCode:       entry.StatusCode,
+		Error:            entry.Error,
+		CreatedAt:        entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ImpersonatedBy:   entry.ImpersonatedBy,
+		SupportSessionID: entry.SupportSessionID,
+	})
+
+	sum := sha256.Sum256(append([]byte(entry.PrevHash+"\n"), contents...))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys the API gateway sets on every call it forwards
+const (
+	ActorIDKey  = "x-actor-id"
+	SourceIPKey = "x-source-ip"
+	// Set only on calls made in a support session, where an admin acts as the actor
+	ImpersonatorIDKey   = "x-impersonator-id"
+	SupportSessionIDKey = "x-support-session-id"
+)
+
+// readOnlyPrefixes start the names of methods that change nothing and are not recorded
+var readOnlyPrefixes = []string{"Get", "List", "Find", "Search", "Stream", "Subscribe", "Verify"}
+
+// Snapshotter lets the interceptor record the state of the resource a call changes
+type Snapshotter interface {
+	// Resource names the resource a request or response refers to, or returns ok false
+	Resource(message interface{}) (resourceType, resourceID string, ok bool)
+	// Snapshot loads a resource's current state. A nil state means it does not exist.
+	Snapshot(ctx context.Context, resourceType, resourceID string) (interface{}, error)
+}
+
+// actorFields read who a request says is making it, in order of preference. The
+// gateway's actor header takes precedence over all of them.
+var actorFields = []func(req interface{}) string{
+	func(req interface{}) string {
+		if r, ok := req.(interface{ GetUpdatedBy() string }); ok {
+			return r.GetUpdatedBy()
+		}
+		return ""
+	},
+	func(req interface{}) string {
+		if r, ok := req.(interface{ GetRequestedBy() string }); ok {
+			return r.GetRequestedBy()
+		}
+		return ""
+	},
+	func(req interface{}) string {
+		if r, ok := req.(interface{ GetResolvedBy() string }); ok {
+			return r.GetResolvedBy()
+		}
+		return ""
+	},
+	func(req interface{}) string {
+		if r, ok := req.(interface{ GetUserId() string }); ok {
+			return r.GetUserId()
+		}
+		return ""
+	},
+}
+
+// UnaryServerInterceptor records every call to a mutating method in the log, except the
+// methods named in exclude, such as high-volume location pings. With a snapshotter, the
+// changed resource is loaded before and after the call so the entry holds both states
+// and their difference. Recording happens after the call has been handled; a failure to
+// record is logged and does not fail the call.
+func UnaryServerInterceptor(auditLog *Log, snapshotter Snapshotter, exclude ...string) grpc.UnaryServerInterceptor {
+	excluded := make(map[string]bool, len(exclude))
+	for _, method := range exclude {
+		excluded[method] = true
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+		if !Mutating(method) || excluded[method] {
+			return handler(ctx, req)
+		}
+
+		entry := &Entry{
+			Method:           info.FullMethod,
+			ActorID:          actorID(ctx, req),
+			SourceIP:         sourceIP(ctx),
+			ImpersonatedBy:   incomingValue(ctx, ImpersonatorIDKey),
+			SupportSessionID: incomingValue(ctx, SupportSessionIDKey),
+		}
+
+		var before interface{}
+		if snapshotter != nil {
+			if resourceType, resourceID, ok := snapshotter.Resource(req); ok {
+				entry.ResourceType, entry.ResourceID = resourceType, resourceID
+				before, _ = snapshotter.Snapshot(ctx, resourceType, resourceID)
+			}
+		}
+
+		resp, err := handler(ctx, req)
+
+		st, _ := status.FromError(err)
+		entry.StatusCode = st.Code().String()
+		if err != nil {
+			entry.Error = st.Message()
+		}
+
+		if snapshotter != nil {
+			if entry.ResourceID == "" && resp != nil {
+				if resourceType, resourceID, ok := snapshotter.Resource(resp); ok {
+					entry.ResourceType, entry.ResourceID = resourceType, resourceID
+				}
+			}
+			if entry.ResourceID != "" {
+				after, _ := snapshotter.Snapshot(ctx, entry.ResourceType, entry.ResourceID)
+				entry.Before, entry.After, entry.Diff = snapshots(before, after)
+			}
+		}
+
+		// Record even if the caller has gone away, so no change goes unlogged
+		recordCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if appendErr := auditLog.Append(recordCtx, entry); appendErr != nil {
+			log.Printf("Failed to record audit entry for %s: %v", info.FullMethod, appendErr)
+		}
+
+		return resp, err
+	}
+}
+
+// Mutating reports whether a method can change state and so is recorded
+func Mutating(method string) bool {
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// actorID is who made a call: the gateway's actor header, or else who the request names
+func actorID(ctx context.Context, req interface{}) string {
+	if values := metadata.ValueFromIncomingContext(ctx, ActorIDKey); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+
+	for _, field := range actorFields {
+		if actor := field(req); actor != "" {
+			return actor
+		}
+	}
+	return ""
+}
+
+// sourceIP is the client address the gateway saw, or else the address of the direct peer
+func sourceIP(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, SourceIPKey); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// incomingValue is the first value of a metadata key the call came with, or ""
+func incomingValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// snapshots encodes a resource's states before and after a call, and the top-level
+// fields that differ between them
+func snapshots(before, after interface{}) (json.RawMessage, json.RawMessage, json.RawMessage) {
+	beforeJSON := encodeSnapshot(before)
+	afterJSON := encodeSnapshot(after)
+
+	var beforeFields, afterFields map[string]json.RawMessage
+	_ = json.Unmarshal(beforeJSON, &beforeFields)
+	_ = json.Unmarshal(afterJSON, &afterFields)
+
+	type change struct {
+		Before json.RawMessage `json:"before,omitempty"`
+		After  json.RawMessage `json:"after,omitempty"`
+	}
+	changes := make(map[string]change)
+	for field, value := range beforeFields {
+		if string(afterFields[field]) != string(value) {
+			changes[field] = change{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = change{After: value}
+		}
+	}
+
+	var diff json.RawMessage
+	if len(changes) > 0 {
+		diff, _ = json.Marshal(changes)
+	}
+
+	return beforeJSON, afterJSON, diff
+}
+
+// encodeSnapshot encodes a resource state as JSON, or nil when there is none
+func encodeSnapshot(state interface{}) json.RawMessage {
+	if state == nil {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"context"
+
+	pb "github.com/order-api-microservices/proto/audit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server serves queries of a service's audit log over gRPC
+type Server struct {
+	pb.UnimplementedAuditServiceServer
+	log *Log
+}
+
+// NewServer creates a new audit query server
+func NewServer(auditLog *Log) *Server {
+	return &Server{
+		log: auditLog,
+	}
+}
+
+// ListAuditEntries lists recorded calls, newest first
+func (s *Server) ListAuditEntries(ctx context.Context, req *pb.ListAuditEntriesRequest) (*pb.ListAuditEntriesResponse, error) {
+	filter := Filter{
+		ActorID:          req.ActorId,
+		ResourceType:     req.ResourceType,
+		ResourceID:       req.ResourceId,
+		Method:           req.Method,
+		SupportSessionID: req.SupportSessionId,
+	}
+	if req.From != nil {
+		filter.From = req.From.AsTime()
+	}
+	if req.To != nil {
+		filter.To = req.To.AsTime()
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		return nil, status.Errorf(codes.InvalidArgument, "to must be after from")
+	}
+
+	page, limit := int(req.Page), int(req.Limit)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	entries, total, err := s.log.List(ctx, filter, page, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list audit entries: %v", err)
+	}
+
+	protoEntries := make([]*pb.AuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		protoEntries = append(protoEntries, convertEntryToProto(entry))
+	}
+
+	return &pb.ListAuditEntriesResponse{
+		Entries: protoEntries,
+		Total:   int32(total),
+		Page:    int32(page),
+		Limit:   int32(limit),
+	}, nil
+}
+
+// VerifyAuditChain checks that no hash-chained entry has been altered or removed
+func (s *Server) VerifyAuditChain(ctx context.Context, req *pb.VerifyAuditChainRequest) (*pb.VerifyAuditChainResponse, error) {
+	result, err := s.log.Verify(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to verify audit chain: %v", err)
+	}
+
+	return &pb.VerifyAuditChainResponse{
+		Valid:           result.FirstInvalidSeq == 0,
+		EntriesChecked:  result.EntriesChecked,
+		FirstInvalidSeq: result.FirstInvalidSeq,
+		Reason:          result.Reason,
+	}, nil
+}
+
+// convertEntryToProto converts an audit entry to its protobuf representation
+func convertEntryToProto(entry *Entry) *pb.AuditEntry {
+	return &pb.AuditEntry{
+		Seq:              entry.Seq,
+		Service:          entry.Service,
+		Method:           entry.Method,
+		ActorId:          entry.ActorID,
+		SourceIp:         entry.SourceIP,
+		ResourceType:     entry.ResourceType,
+		ResourceId:       entry.ResourceID,
+		Before:           string(entry.Before),
+		After:            string(entry.After),
+		Diff:             string(entry.Diff),
+		StatusCode:       entry.StatusCode,
+		Error:            entry.Error,
+		ImpersonatedBy:   entry.ImpersonatedBy,
+		SupportSessionId: entry.SupportSessionID,
+		CreatedAt:        timestamppb.New(entry.CreatedAt),
+		PrevHash:         entry.PrevHash,
+		Hash:             entry.Hash,
+	}
+}
@@ -0,0 +1,117 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Well-known chain names
+const (
+	ChainEthereum = "ethereum"
+	ChainGanache  = "ganache"
+	ChainPolygon  = "polygon"
+	ChainBSC      = "bsc"
+	ChainBesu     = "besu"
+)
+
+// defaultChainIDs maps well-known chain names to their chain IDs.
+// Private networks such as Besu have no canonical ID and must be configured explicitly.
+var defaultChainIDs = map[string]int64{
+	ChainEthereum: 1,
+	ChainGanache:  1337,
+	ChainPolygon:  137,
+	ChainBSC:      56,
+}
+
+// ChainConfig describes a single network the blockchain service can record to
+type ChainConfig struct {
+	Name            string    `mapstructure:"name"`
+	RPCURL          string    `mapstructure:"rpc_url"`
+	ChainID         int64     `mapstructure:"chain_id"`
+	ContractAddress string    `mapstructure:"contract_address"`
+	Gas             GasConfig `mapstructure:"gas"`
+}
+
+// ResolveChainID returns the configured chain ID, falling back to the well-known ID for the chain name
+func (c ChainConfig) ResolveChainID() (*big.Int, error) {
+	if c.ChainID != 0 {
+		return big.NewInt(c.ChainID), nil
+	}
+	if id, ok := defaultChainIDs[strings.ToLower(c.Name)]; ok {
+		return big.NewInt(id), nil
+	}
+	return nil, fmt.Errorf("chain ID is required for chain %q", c.Name)
+}
+
+// ChainRegistry holds one client per configured chain and selects between them by name
+type ChainRegistry struct {
+	clients      map[string]*EthereumClient
+	defaultChain string
+}
+
+// NewChainRegistry connects to every configured chain using the given transaction signer
+func NewChainRegistry(ctx context.Context, chains []ChainConfig, defaultChain string, txSigner TxSigner) (*ChainRegistry, error) {
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("at least one chain must be configured")
+	}
+
+	registry := &ChainRegistry{
+		clients:      make(map[string]*EthereumClient, len(chains)),
+		defaultChain: strings.ToLower(defaultChain),
+	}
+
+	for _, chain := range chains {
+		name := strings.ToLower(chain.Name)
+		if name == "" {
+			return nil, fmt.Errorf("chain name is required")
+		}
+		if _, exists := registry.clients[name]; exists {
+			return nil, fmt.Errorf("chain %q configured more than once", name)
+		}
+
+		client, err := NewEthereumClientForChain(ctx, chain, txSigner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for chain %s: %v", name, err)
+		}
+		registry.clients[name] = client
+	}
+
+	if registry.defaultChain == "" {
+		registry.defaultChain = strings.ToLower(chains[0].Name)
+	}
+	if _, ok := registry.clients[registry.defaultChain]; !ok {
+		return nil, fmt.Errorf("default chain %q is not configured", registry.defaultChain)
+	}
+
+	return registry, nil
+}
+
+// Client returns the client for the named chain, or the default chain if name is empty
+func (r *ChainRegistry) Client(name string) (*EthereumClient, error) {
+	if name == "" {
+		name = r.defaultChain
+	}
+	client, ok := r.clients[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown chain %q", name)
+	}
+	return client, nil
+}
+
+// DefaultChain returns the name of the default chain
+func (r *ChainRegistry) DefaultChain() string {
+	return r.defaultChain
+}
+
+// Chains returns the names of all configured chains in sorted order
+func (r *ChainRegistry) Chains() []string {
+	names := make([]string, 0, len(r.clients))
+	for name := range r.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,19 +2,17 @@ package blockchain
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"math/big"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
@@ -43,65 +41,118 @@ const (
 // EthereumClient handles interactions with the Ethereum blockchain
 type EthereumClient struct {
 	client        *ethclient.Client
+	chainName     string
+	chainID       *big.Int
+	signer        types.Signer
 	contractAddr  common.Address
 	contractABI   abi.ABI
-	privateKey    *ecdsa.PrivateKey
+	txSigner      TxSigner
 	fromAddress   common.Address
-	gasPrice      *big.Int
-	gasLimit      uint64
+	gasConfig     GasConfig
 	retryAttempts int
 	retryDelay    time.Duration
 }
 
-// NewEthereumClient creates a new Ethereum client
-func NewEthereumClient(rpcURL, contractAddress, privateKeyHex string) (*EthereumClient, error) {
+// NewEthereumClient creates a new Ethereum client for a single, unnamed chain.
+// The chain ID is read from the node.
+func NewEthereumClient(rpcURL, contractAddress string, txSigner TxSigner) (*EthereumClient, error) {
 	client, err := ethclient.Dial(rpcURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Ethereum client: %v", err)
 	}
 
-	// Parse contract ABI
-	parsedABI, err := abi.JSON(strings.NewReader(orderRegistryABI))
+	chainID, err := client.ChainID(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse contract ABI: %v", err)
+		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+	}
+
+	return newEthereumClient(client, ChainEthereum, chainID, contractAddress, txSigner, DefaultGasConfig())
+}
+
+// NewEthereumClientForChain creates a new Ethereum client for the given chain configuration.
+// The configured chain ID must match the one reported by the node.
+func NewEthereumClientForChain(ctx context.Context, chain ChainConfig, txSigner TxSigner) (*EthereumClient, error) {
+	chainID, err := chain.ResolveChainID()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ethclient.Dial(chain.RPCURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s node: %v", chain.Name, err)
+	}
+
+	nodeChainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+	}
+	if nodeChainID.Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("chain ID mismatch for %s: configured %s, node reports %s", chain.Name, chainID, nodeChainID)
+	}
+
+	return newEthereumClient(client, strings.ToLower(chain.Name), chainID, chain.ContractAddress, txSigner, chain.Gas)
+}
+
+func newEthereumClient(client *ethclient.Client, chainName string, chainID *big.Int, contractAddress string, txSigner TxSigner, gasConfig GasConfig) (*EthereumClient, error) {
+	if err := gasConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid gas configuration: %v", err)
 	}
 
-	// Parse private key
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	// Parse contract ABI
+	parsedABI, err := abi.JSON(strings.NewReader(orderRegistryABI))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key: %v", err)
+		return nil, fmt.Errorf("failed to parse contract ABI: %v", err)
 	}
 
-	// Derive sender address from private key
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("error casting public key to ECDSA")
+	if txSigner == nil {
+		return nil, fmt.Errorf("transaction signer is required")
 	}
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
 
 	return &EthereumClient{
 		client:        client,
+		chainName:     chainName,
+		chainID:       chainID,
+		signer:        types.LatestSignerForChainID(chainID),
 		contractAddr:  common.HexToAddress(contractAddress),
 		contractABI:   parsedABI,
-		privateKey:    privateKey,
-		fromAddress:   fromAddress,
-		gasPrice:      big.NewInt(20000000000), // 20 Gwei
-		gasLimit:      uint64(300000),
+		txSigner:      txSigner,
+		fromAddress:   txSigner.Address(),
+		gasConfig:     gasConfig,
 		retryAttempts: 3,
 		retryDelay:    time.Second * 2,
 	}, nil
 }
 
-// FromAddress returns the address derived from the private key
+// ChainName returns the name of the chain this client records to
+func (c *EthereumClient) ChainName() string {
+	return c.chainName
+}
+
+// ChainID returns the chain ID used for signing
+func (c *EthereumClient) ChainID() *big.Int {
+	return new(big.Int).Set(c.chainID)
+}
+
+// Signer returns the transaction signer for this chain
+func (c *EthereumClient) Signer() types.Signer {
+	return c.signer
+}
+
+// FromAddress returns the address of the transaction signer
 func (c *EthereumClient) FromAddress() common.Address {
 	return c.fromAddress
 }
 
-// ComputeOrderHash computes a hash of the order data
-func ComputeOrderHash(orderID, userID, providerID string, totalPrice float64, items []string, status OrderStatus) ([32]byte, error) {
+// ComputeOrderHash computes a hash of the order data. totalPrice is in minor units, so the
+// hash does not depend on floating-point formatting.
+func ComputeOrderHash(orderID, userID, providerID string, totalPrice int64, items []string, status OrderStatus, deliveryProofHash string) ([32]byte, error) {
 	// Create a string representation of the order
-	orderStr := fmt.Sprintf("%s:%s:%s:%f:%s:%d", orderID, userID, providerID, totalPrice, strings.Join(items, ","), status)
+	orderStr := fmt.Sprintf("%s:%s:%s:%d:%s:%d", orderID, userID, providerID, totalPrice, strings.Join(items, ","), status)
+	
+	// Only delivered orders carry a proof, so earlier records hash as before
+	if deliveryProofHash != "" {
+		orderStr += ":" + deliveryProofHash
+	}
 	
 	// Compute SHA-256 hash
 	hash := sha256.Sum256([]byte(orderStr))
@@ -110,77 +161,159 @@ func ComputeOrderHash(orderID, userID, providerID string, totalPrice float64, it
 
 // RecordOrder records a new order on the blockchain
 func (c *EthereumClient) RecordOrder(ctx context.Context, orderID string, dataHash [32]byte, status OrderStatus) (string, error) {
-	auth, err := c.getTransactOpts(ctx)
+	// Pack the transaction data
+	data, err := c.contractABI.Pack("recordOrder", orderID, dataHash, uint8(status))
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to pack transaction data: %v", err)
 	}
 
+	return c.sendContractTransaction(ctx, data)
+}
+
+// UpdateOrderStatus updates an existing order's status on the blockchain
+func (c *EthereumClient) UpdateOrderStatus(ctx context.Context, orderID string, dataHash [32]byte, status OrderStatus) (string, error) {
 	// Pack the transaction data
-	data, err := c.contractABI.Pack("recordOrder", orderID, dataHash, uint8(status))
+	data, err := c.contractABI.Pack("updateOrderStatus", orderID, dataHash, uint8(status))
 	if err != nil {
 		return "", fmt.Errorf("failed to pack transaction data: %v", err)
 	}
 
-	// Create transaction
-	tx := types.NewTransaction(
-		auth.Nonce.Uint64(),
-		c.contractAddr,
-		big.NewInt(0),
-		c.gasLimit,
-		auth.GasPrice,
-		data,
-	)
+	return c.sendContractTransaction(ctx, data)
+}
 
-	// Sign transaction
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(auth.ChainID), c.privateKey)
+// AnchorPayload records the reference and hash of an off-chain payload snapshot for an order
+func (c *EthereumClient) AnchorPayload(ctx context.Context, orderID string, payloadHash [32]byte, payloadRef string) (string, error) {
+	// Pack the transaction data
+	data, err := c.contractABI.Pack("anchorPayload", orderID, payloadHash, payloadRef)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign transaction: %v", err)
+		return "", fmt.Errorf("failed to pack transaction data: %v", err)
 	}
 
-	// Send transaction
-	err = c.client.SendTransaction(ctx, signedTx)
+	return c.sendContractTransaction(ctx, data)
+}
+
+// PayloadAnchor is the on-chain anchor of an off-chain payload
+type PayloadAnchor struct {
+	Exists      bool
+	PayloadHash [32]byte
+	PayloadRef  string
+	Timestamp   uint64
+}
+
+// GetPayloadAnchor retrieves the latest payload anchor for an order
+func (c *EthereumClient) GetPayloadAnchor(ctx context.Context, orderID string) (*PayloadAnchor, error) {
+	// Pack the call data
+	data, err := c.contractABI.Pack("getPayloadAnchor", orderID)
 	if err != nil {
-		return "", fmt.Errorf("failed to send transaction: %v", err)
+		return nil, fmt.Errorf("failed to pack call data: %v", err)
 	}
 
-	// Wait for transaction to be mined
-	receipt, err := bind.WaitMined(ctx, c.client, signedTx)
+	// Make the call
+	msg := ethereum.CallMsg{
+		To:   &c.contractAddr,
+		Data: data,
+	}
+	result, err := c.client.CallContract(ctx, msg, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed waiting for transaction to be mined: %v", err)
+		return nil, fmt.Errorf("contract call failed: %v", err)
 	}
 
-	if receipt.Status == 0 {
-		return "", fmt.Errorf("transaction failed")
+	// Unpack result
+	var unpacked struct {
+		Exists      bool
+		PayloadHash [32]byte
+		PayloadRef  string
+		Timestamp   *big.Int
+	}
+	err = c.contractABI.UnpackIntoInterface(&unpacked, "getPayloadAnchor", result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack result: %v", err)
 	}
 
-	return signedTx.Hash().Hex(), nil
+	return &PayloadAnchor{
+		Exists:      unpacked.Exists,
+		PayloadHash: unpacked.PayloadHash,
+		PayloadRef:  unpacked.PayloadRef,
+		Timestamp:   unpacked.Timestamp.Uint64(),
+	}, nil
 }
 
-// UpdateOrderStatus updates an existing order's status on the blockchain
-func (c *EthereumClient) UpdateOrderStatus(ctx context.Context, orderID string, dataHash [32]byte, status OrderStatus) (string, error) {
-	auth, err := c.getTransactOpts(ctx)
+// CommitBatch records the Merkle root of a batch of order hashes on the blockchain
+func (c *EthereumClient) CommitBatch(ctx context.Context, root [32]byte, leafCount int) (string, error) {
+	// Pack the transaction data
+	data, err := c.contractABI.Pack("commitBatch", root, big.NewInt(int64(leafCount)))
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to pack transaction data: %v", err)
 	}
 
-	// Pack the transaction data
-	data, err := c.contractABI.Pack("updateOrderStatus", orderID, dataHash, uint8(status))
+	return c.sendContractTransaction(ctx, data)
+}
+
+// GetBatch retrieves a committed batch by its Merkle root
+func (c *EthereumClient) GetBatch(ctx context.Context, root [32]byte) (bool, uint64, uint64, error) {
+	// Pack the call data
+	data, err := c.contractABI.Pack("getBatch", root)
 	if err != nil {
-		return "", fmt.Errorf("failed to pack transaction data: %v", err)
+		return false, 0, 0, fmt.Errorf("failed to pack call data: %v", err)
+	}
+
+	// Make the call
+	msg := ethereum.CallMsg{
+		To:   &c.contractAddr,
+		Data: data,
+	}
+	result, err := c.client.CallContract(ctx, msg, nil)
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("contract call failed: %v", err)
 	}
 
-	// Create transaction
-	tx := types.NewTransaction(
-		auth.Nonce.Uint64(),
-		c.contractAddr,
-		big.NewInt(0),
-		c.gasLimit,
-		auth.GasPrice,
-		data,
-	)
+	// Unpack result
+	var unpacked struct {
+		Exists    bool
+		LeafCount *big.Int
+		Timestamp *big.Int
+	}
+	err = c.contractABI.UnpackIntoInterface(&unpacked, "getBatch", result)
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("failed to unpack result: %v", err)
+	}
+
+	return unpacked.Exists, unpacked.LeafCount.Uint64(), unpacked.Timestamp.Uint64(), nil
+}
+
+// sendContractTransaction signs and sends a call to the registry contract and waits for it to be mined
+func (c *EthereumClient) sendContractTransaction(ctx context.Context, data []byte) (string, error) {
+	auth, err := c.getTransactOpts(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// Create transaction, using a dynamic fee transaction when the chain supports it
+	var txData types.TxData
+	if auth.GasFeeCap != nil {
+		txData = &types.DynamicFeeTx{
+			ChainID:   c.chainID,
+			Nonce:     auth.Nonce.Uint64(),
+			GasTipCap: auth.GasTipCap,
+			GasFeeCap: auth.GasFeeCap,
+			Gas:       auth.GasLimit,
+			To:        &c.contractAddr,
+			Value:     big.NewInt(0),
+			Data:      data,
+		}
+	} else {
+		txData = &types.LegacyTx{
+			Nonce:    auth.Nonce.Uint64(),
+			GasPrice: auth.GasPrice,
+			Gas:      auth.GasLimit,
+			To:       &c.contractAddr,
+			Value:    big.NewInt(0),
+			Data:     data,
+		}
+	}
 
 	// Sign transaction
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(auth.ChainID), c.privateKey)
+	signedTx, err := c.txSigner.SignTx(ctx, types.NewTx(txData), c.signer)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign transaction: %v", err)
 	}
@@ -213,7 +346,7 @@ func (c *EthereumClient) VerifyOrderHash(ctx context.Context, orderID string, da
 	}
 
 	// Make the call
-	msg := common.CallMsg{
+	msg := ethereum.CallMsg{
 		To:   &c.contractAddr,
 		Data: data,
 	}
@@ -241,7 +374,7 @@ func (c *EthereumClient) GetOrderStatus(ctx context.Context, orderID string) (bo
 	}
 
 	// Make the call
-	msg := common.CallMsg{
+	msg := ethereum.CallMsg{
 		To:   &c.contractAddr,
 		Data: data,
 	}
@@ -295,27 +428,27 @@ func (c *EthereumClient) getTransactOpts(ctx context.Context) (*bind.TransactOpt
 		return nil, fmt.Errorf("failed to get nonce: %v", err)
 	}
 
-	gasPrice, err := c.client.SuggestGasPrice(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to suggest gas price: %v", err)
-	}
-
-	chainID, err := c.client.ChainID(ctx)
+	price, err := c.suggestGasPrice(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+		return nil, err
 	}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(c.privateKey, chainID)
-	if err != nil {
-		return nil, err
+	auth := &bind.TransactOpts{
+		From:    c.fromAddress,
+		Context: ctx,
 	}
 	auth.Nonce = big.NewInt(int64(nonce))
 	auth.Value = big.NewInt(0)
-	auth.GasLimit = c.gasLimit
-	auth.GasPrice = gasPrice
+	auth.GasLimit = c.gasConfig.GasLimit
+	if price.IsDynamic() {
+		auth.GasFeeCap = price.GasFeeCap
+		auth.GasTipCap = price.GasTipCap
+	} else {
+		auth.GasPrice = price.GasPrice
+	}
 
 	return auth, nil
 }
 
 // ABI for the OrderRegistry contract
-const orderRegistryABI = `[{"inputs":[],"stateMutability":"nonpayable","type":"constructor"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"string","name":"orderId","type":"string"},{"indexed":false,"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"timestamp","type":"uint256"},{"indexed":false,"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"}],"name":"OrderRecorded","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"string","name":"orderId","type":"string"},{"indexed":false,"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"timestamp","type":"uint256"},{"indexed":false,"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"}],"name":"OrderUpdated","type":"event"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"}],"name":"getOrderHistoryCount","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"},{"internalType":"uint256","name":"index","type":"uint256"}],"name":"getOrderHistoryEntry","outputs":[{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"},{"internalType":"address","name":"updatedBy","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"}],"name":"getOrderStatus","outputs":[{"internalType":"bool","name":"exists","type":"bool"},{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"","type":"string"}],"name":"orderHistory","outputs":[{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"},{"internalType":"address","name":"updatedBy","type":"address"},{"internalType":"bool","name":"exists","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"","type":"string"}],"name":"orders","outputs":[{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"},{"internalType":"address","name":"updatedBy","type":"address"},{"internalType":"bool","name":"exists","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"owner","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"},{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"}],"name":"recordOrder","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"newOwner","type":"address"}],"name":"transferOwnership","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"},{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"}],"name":"updateOrderStatus","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"},{"internalType":"bytes32","name":"dataHash","type":"bytes32"}],"name":"verifyOrderHash","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"}]` 
\ No newline at end of file
+const orderRegistryABI = `[{"inputs":[],"stateMutability":"nonpayable","type":"constructor"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"bytes32","name":"root","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"leafCount","type":"uint256"},{"indexed":false,"internalType":"uint256","name":"timestamp","type":"uint256"}],"name":"BatchCommitted","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"string","name":"orderId","type":"string"},{"indexed":true,"internalType":"address","name":"payer","type":"address"},{"indexed":false,"internalType":"uint256","name":"amount","type":"uint256"},{"indexed":false,"internalType":"uint256","name":"timestamp","type":"uint256"}],"name":"OrderPaid","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"string","name":"orderId","type":"string"},{"indexed":false,"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"timestamp","type":"uint256"},{"indexed":false,"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"}],"name":"OrderRecorded","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"string","name":"orderId","type":"string"},{"indexed":false,"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"indexed":false,"internalType":"uint256","name":"timestamp","type":"uint256"},{"indexed":false,"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"}],"name":"OrderUpdated","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"string","name":"orderId","type":"string"},{"indexed":false,"internalType":"bytes32","name":"payloadHash","type":"bytes32"},{"indexed":false,"internalType":"string","name":"payloadRef","type":"string"},{"indexed":false,"internalType":"uint256","name":"timestamp","type":"uint256"}],"name":"PayloadAnchored","type":"event"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"},{"internalType":"bytes32","name":"payloadHash","type":"bytes32"},{"internalType":"string","name":"payloadRef","type":"string"}],"name":"anchorPayload","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"name":"batches","outputs":[{"internalType":"uint256","name":"leafCount","type":"uint256"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"bool","name":"exists","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"bytes32","name":"root","type":"bytes32"},{"internalType":"uint256","name":"leafCount","type":"uint256"}],"name":"commitBatch","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"bytes32","name":"root","type":"bytes32"}],"name":"getBatch","outputs":[{"internalType":"bool","name":"exists","type":"bool"},{"internalType":"uint256","name":"leafCount","type":"uint256"},{"internalType":"uint256","name":"timestamp","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"}],"name":"getOrderHistoryCount","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"},{"internalType":"uint256","name":"index","type":"uint256"}],"name":"getOrderHistoryEntry","outputs":[{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"},{"internalType":"address","name":"updatedBy","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"}],"name":"getOrderStatus","outputs":[{"internalType":"bool","name":"exists","type":"bool"},{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"}],"name":"getPayloadAnchor","outputs":[{"internalType":"bool","name":"exists","type":"bool"},{"internalType":"bytes32","name":"payloadHash","type":"bytes32"},{"internalType":"string","name":"payloadRef","type":"string"},{"internalType":"uint256","name":"timestamp","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"","type":"string"},{"internalType":"uint256","name":"","type":"uint256"}],"name":"orderHistory","outputs":[{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"},{"internalType":"address","name":"updatedBy","type":"address"},{"internalType":"bool","name":"exists","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"","type":"string"}],"name":"orders","outputs":[{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"},{"internalType":"address","name":"updatedBy","type":"address"},{"internalType":"bool","name":"exists","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"owner","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"}],"name":"payOrder","outputs":[],"stateMutability":"payable","type":"function"},{"inputs":[{"internalType":"string","name":"","type":"string"}],"name":"payloadAnchors","outputs":[{"internalType":"bytes32","name":"payloadHash","type":"bytes32"},{"internalType":"string","name":"payloadRef","type":"string"},{"internalType":"uint256","name":"timestamp","type":"uint256"},{"internalType":"bool","name":"exists","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"},{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"}],"name":"recordOrder","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"newOwner","type":"address"}],"name":"transferOwnership","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"},{"internalType":"bytes32","name":"dataHash","type":"bytes32"},{"internalType":"enum OrderRegistry.OrderStatus","name":"status","type":"uint8"}],"name":"updateOrderStatus","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"bytes32","name":"root","type":"bytes32"},{"internalType":"bytes32","name":"leaf","type":"bytes32"},{"internalType":"bytes32[]","name":"proof","type":"bytes32[]"}],"name":"verifyBatchProof","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"string","name":"orderId","type":"string"},{"internalType":"bytes32","name":"dataHash","type":"bytes32"}],"name":"verifyOrderHash","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address payable","name":"to","type":"address"},{"internalType":"uint256","name":"amount","type":"uint256"}],"name":"withdraw","outputs":[],"stateMutability":"nonpayable","type":"function"}]` 
\ No newline at end of file
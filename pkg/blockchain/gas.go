@@ -0,0 +1,161 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// GasStrategy controls how aggressively transactions are priced
+type GasStrategy string
+
+const (
+	GasStrategySlow   GasStrategy = "slow"
+	GasStrategyNormal GasStrategy = "normal"
+	GasStrategyFast   GasStrategy = "fast"
+)
+
+// Gwei is the number of wei in one gwei
+const Gwei = 1_000_000_000
+
+// tipMultipliers scale the node-suggested priority fee (in percent) for each strategy
+var tipMultipliers = map[GasStrategy]int64{
+	GasStrategySlow:   80,
+	GasStrategyNormal: 100,
+	GasStrategyFast:   150,
+}
+
+// baseFeeMultipliers scale the latest base fee (in percent) to absorb base fee growth
+// over the next few blocks before the transaction is mined
+var baseFeeMultipliers = map[GasStrategy]int64{
+	GasStrategySlow:   125,
+	GasStrategyNormal: 200,
+	GasStrategyFast:   300,
+}
+
+// GasConfig configures transaction pricing for a chain
+type GasConfig struct {
+	Strategy GasStrategy `mapstructure:"strategy"`
+	// DisableDynamicFees forces legacy transactions even on chains supporting EIP-1559
+	DisableDynamicFees bool `mapstructure:"disable_dynamic_fees"`
+	// MaxFeePerGasGwei caps maxFeePerGas (or gasPrice for legacy transactions); 0 means no cap
+	MaxFeePerGasGwei int64 `mapstructure:"max_fee_per_gas_gwei"`
+	// MaxPriorityFeePerGasGwei caps maxPriorityFeePerGas; 0 means no cap
+	MaxPriorityFeePerGasGwei int64  `mapstructure:"max_priority_fee_per_gas_gwei"`
+	GasLimit                 uint64 `mapstructure:"gas_limit"`
+}
+
+// DefaultGasConfig returns the gas configuration used when none is provided
+func DefaultGasConfig() GasConfig {
+	return GasConfig{
+		Strategy:         GasStrategyNormal,
+		MaxFeePerGasGwei: 200,
+		GasLimit:         300000,
+	}
+}
+
+// Validate checks the gas configuration and fills in defaults
+func (g *GasConfig) Validate() error {
+	if g.Strategy == "" {
+		g.Strategy = GasStrategyNormal
+	}
+	g.Strategy = GasStrategy(strings.ToLower(string(g.Strategy)))
+	if _, ok := tipMultipliers[g.Strategy]; !ok {
+		return fmt.Errorf("unknown gas strategy %q", g.Strategy)
+	}
+	if g.MaxFeePerGasGwei < 0 || g.MaxPriorityFeePerGasGwei < 0 {
+		return fmt.Errorf("gas price caps must not be negative")
+	}
+	if g.GasLimit == 0 {
+		g.GasLimit = DefaultGasConfig().GasLimit
+	}
+	return nil
+}
+
+// GasPrice holds the fees chosen for a transaction. For legacy transactions only
+// GasPrice is set; for dynamic fee transactions GasFeeCap and GasTipCap are set.
+type GasPrice struct {
+	GasPrice  *big.Int
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+}
+
+// IsDynamic reports whether the price describes an EIP-1559 transaction
+func (p GasPrice) IsDynamic() bool {
+	return p.GasFeeCap != nil
+}
+
+// suggestGasPrice prices a transaction according to the client's gas configuration
+func (c *EthereumClient) suggestGasPrice(ctx context.Context) (GasPrice, error) {
+	if !c.gasConfig.DisableDynamicFees {
+		header, err := c.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return GasPrice{}, fmt.Errorf("failed to get latest header: %v", err)
+		}
+
+		// A nil base fee means the chain has not activated EIP-1559
+		if header.BaseFee != nil {
+			tip, err := c.client.SuggestGasTipCap(ctx)
+			if err != nil {
+				return GasPrice{}, fmt.Errorf("failed to suggest gas tip cap: %v", err)
+			}
+			return c.dynamicGasPrice(header.BaseFee, tip)
+		}
+	}
+
+	gasPrice, err := c.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return GasPrice{}, fmt.Errorf("failed to suggest gas price: %v", err)
+	}
+	return c.legacyGasPrice(gasPrice)
+}
+
+// dynamicGasPrice derives maxFeePerGas and maxPriorityFeePerGas from the base fee and suggested tip
+func (c *EthereumClient) dynamicGasPrice(baseFee, suggestedTip *big.Int) (GasPrice, error) {
+	strategy := c.gasConfig.Strategy
+
+	tip := percentOf(suggestedTip, tipMultipliers[strategy])
+	if capTip := gweiToWei(c.gasConfig.MaxPriorityFeePerGasGwei); capTip != nil && tip.Cmp(capTip) > 0 {
+		tip = capTip
+	}
+
+	feeCap := new(big.Int).Add(percentOf(baseFee, baseFeeMultipliers[strategy]), tip)
+	if maxFee := gweiToWei(c.gasConfig.MaxFeePerGasGwei); maxFee != nil && feeCap.Cmp(maxFee) > 0 {
+		// The cap must still cover the current base fee or the transaction can never be mined
+		if maxFee.Cmp(baseFee) < 0 {
+			return GasPrice{}, fmt.Errorf("base fee %s wei exceeds configured max fee per gas %s wei", baseFee, maxFee)
+		}
+		feeCap = maxFee
+	}
+
+	// The tip can never exceed the fee cap
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+
+	return GasPrice{GasFeeCap: feeCap, GasTipCap: tip}, nil
+}
+
+// legacyGasPrice applies the strategy and cap to a suggested legacy gas price
+func (c *EthereumClient) legacyGasPrice(suggested *big.Int) (GasPrice, error) {
+	gasPrice := percentOf(suggested, tipMultipliers[c.gasConfig.Strategy])
+	if maxFee := gweiToWei(c.gasConfig.MaxFeePerGasGwei); maxFee != nil && gasPrice.Cmp(maxFee) > 0 {
+		gasPrice = maxFee
+	}
+	return GasPrice{GasPrice: gasPrice}, nil
+}
+
+// percentOf returns value * percent / 100
+func percentOf(value *big.Int, percent int64) *big.Int {
+	result := new(big.Int).Mul(value, big.NewInt(percent))
+	return result.Div(result, big.NewInt(100))
+}
+
+// gweiToWei converts a gwei amount to wei, returning nil for zero (no cap)
+func gweiToWei(gwei int64) *big.Int {
+	if gwei == 0 {
+		return nil
+	}
+	return new(big.Int).Mul(big.NewInt(gwei), big.NewInt(Gwei))
+}
@@ -0,0 +1,38 @@
+package blockchain
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+// NewKeystoreSigner creates a signer from an encrypted JSON keystore file.
+// The passphrase is read from a separate file so it never appears in config or flags.
+func NewKeystoreSigner(keystorePath, passphraseFile string) (*PrivateKeySigner, error) {
+	if keystorePath == "" {
+		return nil, fmt.Errorf("keystore path is required")
+	}
+
+	keyJSON, err := os.ReadFile(keystorePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore file: %v", err)
+	}
+
+	var passphrase string
+	if passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keystore passphrase file: %v", err)
+		}
+		passphrase = strings.TrimRight(string(data), "\r\n")
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore: %v", err)
+	}
+
+	return NewPrivateKeySigner(key.PrivateKey)
+}
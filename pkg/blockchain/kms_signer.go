@@ -0,0 +1,121 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Div(secp256k1N, big.NewInt(2))
+)
+
+// KMSSigner signs transactions with an AWS KMS ECC_SECG_P256K1 key; the key never leaves KMS
+type KMSSigner struct {
+	client    *kms.Client
+	keyID     string
+	publicKey []byte // uncompressed secp256k1 public key
+	address   common.Address
+}
+
+// NewKMSSigner creates a signer backed by the given AWS KMS key
+func NewKMSSigner(ctx context.Context, keyID, region string) (*KMSSigner, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("KMS key ID is required")
+	}
+
+	var opts []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %v", err)
+	}
+	client := kms.NewFromConfig(cfg)
+
+	out, err := client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get KMS public key: %v", err)
+	}
+
+	// The public key is a DER-encoded SubjectPublicKeyInfo
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(out.PublicKey, &spki); err != nil {
+		return nil, fmt.Errorf("failed to parse KMS public key: %v", err)
+	}
+
+	pubKey, err := crypto.UnmarshalPubkey(spki.PublicKey.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("KMS key is not a secp256k1 key: %v", err)
+	}
+
+	return &KMSSigner{
+		client:    client,
+		keyID:     keyID,
+		publicKey: spki.PublicKey.Bytes,
+		address:   crypto.PubkeyToAddress(*pubKey),
+	}, nil
+}
+
+// Address returns the account address derived from the KMS public key
+func (s *KMSSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx signs the transaction hash with KMS and attaches the recovered signature
+func (s *KMSSigner) SignTx(ctx context.Context, tx *types.Transaction, signer types.Signer) (*types.Transaction, error) {
+	digest := signer.Hash(tx).Bytes()
+
+	out, err := s.client.Sign(ctx, &kms.SignInput{
+		KeyId:            aws.String(s.keyID),
+		Message:          digest,
+		MessageType:      kmstypes.MessageTypeDigest,
+		SigningAlgorithm: kmstypes.SigningAlgorithmSpecEcdsaSha256,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with KMS: %v", err)
+	}
+
+	var derSig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(out.Signature, &derSig); err != nil {
+		return nil, fmt.Errorf("failed to parse KMS signature: %v", err)
+	}
+
+	// Ethereum only accepts signatures in the lower half of the curve order
+	if derSig.S.Cmp(secp256k1HalfN) > 0 {
+		derSig.S = new(big.Int).Sub(secp256k1N, derSig.S)
+	}
+
+	sig := make([]byte, 65)
+	derSig.R.FillBytes(sig[0:32])
+	derSig.S.FillBytes(sig[32:64])
+
+	// KMS does not return the recovery ID, so find the one that recovers our key
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		recovered, err := crypto.Ecrecover(digest, sig)
+		if err == nil && bytes.Equal(recovered, s.publicKey) {
+			return tx.WithSignature(signer, sig)
+		}
+	}
+
+	return nil, fmt.Errorf("failed to recover public key from KMS signature")
+}
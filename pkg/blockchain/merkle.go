@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// MerkleTree is a binary Merkle tree over keccak256 leaves using sorted-pair hashing,
+// compatible with OpenZeppelin's MerkleProof and OrderRegistry.verifyBatchProof
+type MerkleTree struct {
+	levels [][][32]byte // levels[0] are the leaves, the last level holds the root
+}
+
+// ComputeBatchLeaf computes the Merkle leaf for an order's data hash
+func ComputeBatchLeaf(orderID string, dataHash [32]byte) [32]byte {
+	return crypto.Keccak256Hash([]byte(orderID), dataHash[:])
+}
+
+// NewMerkleTree builds a Merkle tree from the given leaves
+func NewMerkleTree(leaves [][32]byte) (*MerkleTree, error) {
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("cannot build a Merkle tree without leaves")
+	}
+
+	level := make([][32]byte, len(leaves))
+	copy(level, leaves)
+	levels := [][][32]byte{level}
+
+	for len(level) > 1 {
+		next := make([][32]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				// Odd node out is promoted unchanged
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, hashPair(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+
+	return &MerkleTree{levels: levels}, nil
+}
+
+// Root returns the Merkle root
+func (t *MerkleTree) Root() [32]byte {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// LeafCount returns the number of leaves in the tree
+func (t *MerkleTree) LeafCount() int {
+	return len(t.levels[0])
+}
+
+// Proof returns the sibling hashes needed to prove the leaf at index
+func (t *MerkleTree) Proof(index int) ([][32]byte, error) {
+	if index < 0 || index >= t.LeafCount() {
+		return nil, fmt.Errorf("leaf index %d out of range", index)
+	}
+
+	var proof [][32]byte
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyMerkleProof checks that leaf is included in the tree with the given root
+func VerifyMerkleProof(leaf [32]byte, proof [][32]byte, root [32]byte) bool {
+	computed := leaf
+	for _, sibling := range proof {
+		computed = hashPair(computed, sibling)
+	}
+	return computed == root
+}
+
+// hashPair hashes two nodes in sorted order so proofs need no position bits
+func hashPair(a, b [32]byte) [32]byte {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return crypto.Keccak256Hash(a[:], b[:])
+}
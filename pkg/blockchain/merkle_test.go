@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func testLeaves(n int) [][32]byte {
+	leaves := make([][32]byte, n)
+	for i := range leaves {
+		leaves[i] = ComputeBatchLeaf(fmt.Sprintf("order-%d", i), crypto.Keccak256Hash([]byte{byte(i)}))
+	}
+	return leaves
+}
+
+func TestMerkleProofsVerifyForEveryLeaf(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		leaves := testLeaves(n)
+		tree, err := NewMerkleTree(leaves)
+		if err != nil {
+			t.Fatalf("%d leaves: %v", n, err)
+		}
+		if tree.LeafCount() != n {
+			t.Fatalf("%d leaves: LeafCount() = %d", n, tree.LeafCount())
+		}
+		for i, leaf := range leaves {
+			proof, err := tree.Proof(i)
+			if err != nil {
+				t.Fatalf("%d leaves: Proof(%d): %v", n, i, err)
+			}
+			if !VerifyMerkleProof(leaf, proof, tree.Root()) {
+				t.Errorf("%d leaves: proof of leaf %d does not verify", n, i)
+			}
+		}
+	}
+}
+
+func TestMerkleSingleLeafIsRoot(t *testing.T) {
+	leaves := testLeaves(1)
+	tree, err := NewMerkleTree(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Root() != leaves[0] {
+		t.Errorf("root of a single leaf tree should be the leaf")
+	}
+	proof, _ := tree.Proof(0)
+	if len(proof) != 0 {
+		t.Errorf("proof of a single leaf tree has %d nodes, want 0", len(proof))
+	}
+}
+
+func TestMerkleRootUsesSortedPairs(t *testing.T) {
+	leaves := testLeaves(2)
+	tree, err := NewMerkleTree(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swapped, err := NewMerkleTree([][32]byte{leaves[1], leaves[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Root() != swapped.Root() {
+		t.Errorf("root depends on leaf order; OrderRegistry.verifyBatchProof hashes sorted pairs")
+	}
+}
+
+func TestMerkleProofRejectsOtherLeaves(t *testing.T) {
+	leaves := testLeaves(5)
+	tree, err := NewMerkleTree(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := tree.Proof(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if VerifyMerkleProof(leaves[3], proof, tree.Root()) {
+		t.Errorf("proof of leaf 2 verified leaf 3")
+	}
+	forged := ComputeBatchLeaf("order-2", crypto.Keccak256Hash([]byte("tampered")))
+	if VerifyMerkleProof(forged, proof, tree.Root()) {
+		t.Errorf("proof verified a leaf whose data hash was changed")
+	}
+}
+
+func TestMerkleTreeErrors(t *testing.T) {
+	if _, err := NewMerkleTree(nil); err == nil {
+		t.Errorf("NewMerkleTree(nil) should fail")
+	}
+
+	tree, err := NewMerkleTree(testLeaves(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []int{-1, 3} {
+		if _, err := tree.Proof(index); err == nil {
+			t.Errorf("Proof(%d) should fail", index)
+		}
+	}
+}
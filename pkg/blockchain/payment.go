@@ -0,0 +1,102 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/url"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// OrderPayment is a native-token payment made to the registry contract for an order
+type OrderPayment struct {
+	TransactionHash string
+	Payer           common.Address
+	Amount          *big.Int
+	BlockNumber     uint64
+}
+
+// ContractAddress returns the registry contract address, which also receives order payments
+func (c *EthereumClient) ContractAddress() common.Address {
+	return c.contractAddr
+}
+
+// PayOrderCallData packs the payOrder call a wallet sends, with the amount due as value, to pay for an order
+func (c *EthereumClient) PayOrderCallData(orderID string) ([]byte, error) {
+	data, err := c.contractABI.Pack("payOrder", orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack call data: %v", err)
+	}
+	return data, nil
+}
+
+// PaymentURI builds an EIP-681 URI asking a wallet to pay amountWei for an order
+func (c *EthereumClient) PaymentURI(orderID string, amountWei *big.Int) string {
+	return fmt.Sprintf("ethereum:%s@%s/payOrder?string=%s&value=%s",
+		c.contractAddr.Hex(), c.chainID, url.QueryEscape(orderID), amountWei)
+}
+
+// LatestBlockNumber returns the current head of the chain
+func (c *EthereumClient) LatestBlockNumber(ctx context.Context) (uint64, error) {
+	number, err := c.client.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block number: %v", err)
+	}
+	return number, nil
+}
+
+// FindOrderPayments returns the payments made for an order since fromBlock, oldest first
+func (c *EthereumClient) FindOrderPayments(ctx context.Context, orderID string, fromBlock uint64) ([]OrderPayment, error) {
+	event, ok := c.contractABI.Events["OrderPaid"]
+	if !ok {
+		return nil, fmt.Errorf("contract ABI has no OrderPaid event")
+	}
+
+	// Indexed strings are logged as their keccak256 hash
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		Addresses: []common.Address{c.contractAddr},
+		Topics:    [][]common.Hash{{event.ID}, {crypto.Keccak256Hash([]byte(orderID))}},
+	}
+	logs, err := c.client.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter payment logs: %v", err)
+	}
+
+	payments := make([]OrderPayment, 0, len(logs))
+	for _, log := range logs {
+		if log.Removed || len(log.Topics) < 3 {
+			continue
+		}
+
+		payment, err := c.unpackOrderPayment(log)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
+
+// unpackOrderPayment reads an OrderPaid log; the payer is its second indexed topic
+func (c *EthereumClient) unpackOrderPayment(log types.Log) (OrderPayment, error) {
+	var unpacked struct {
+		Amount    *big.Int
+		Timestamp *big.Int
+	}
+	if err := c.contractABI.UnpackIntoInterface(&unpacked, "OrderPaid", log.Data); err != nil {
+		return OrderPayment{}, fmt.Errorf("failed to unpack payment log: %v", err)
+	}
+
+	return OrderPayment{
+		TransactionHash: log.TxHash.Hex(),
+		Payer:           common.BytesToAddress(log.Topics[2].Bytes()),
+		Amount:          unpacked.Amount,
+		BlockNumber:     log.BlockNumber,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package blockchain
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func testRegistryClient(t *testing.T) *EthereumClient {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(orderRegistryABI))
+	if err != nil {
+		t.Fatalf("failed to parse contract ABI: %v", err)
+	}
+	return &EthereumClient{contractABI: parsed}
+}
+
+func TestPayOrderCallData(t *testing.T) {
+	client := testRegistryClient(t)
+
+	data, err := client.PayOrderCallData("order-1")
+	if err != nil {
+		t.Fatalf("PayOrderCallData: %v", err)
+	}
+
+	selector := crypto.Keccak256([]byte("payOrder(string)"))[:4]
+	if !bytes.Equal(data[:4], selector) {
+		t.Fatalf("selector = %x, want %x", data[:4], selector)
+	}
+	args, err := client.contractABI.Methods["payOrder"].Inputs.Unpack(data[4:])
+	if err != nil {
+		t.Fatalf("failed to unpack call data: %v", err)
+	}
+	if len(args) != 1 || args[0] != "order-1" {
+		t.Errorf("call data carries %v, want [order-1]", args)
+	}
+	if !client.contractABI.Methods["payOrder"].IsPayable() {
+		t.Errorf("payOrder must be payable")
+	}
+}
+
+func TestUnpackOrderPayment(t *testing.T) {
+	client := testRegistryClient(t)
+	event, ok := client.contractABI.Events["OrderPaid"]
+	if !ok {
+		t.Fatal("contract ABI has no OrderPaid event")
+	}
+	if want := crypto.Keccak256Hash([]byte("OrderPaid(string,address,uint256,uint256)")); event.ID != want {
+		t.Fatalf("OrderPaid topic = %s, want %s", event.ID, want)
+	}
+
+	payer := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	amount := big.NewInt(1_500_000_000_000_000)
+	data, err := event.Inputs.NonIndexed().Pack(amount, big.NewInt(1700000000))
+	if err != nil {
+		t.Fatalf("failed to pack log data: %v", err)
+	}
+	log := types.Log{
+		Topics: []common.Hash{
+			event.ID,
+			crypto.Keccak256Hash([]byte("order-1")),
+			common.BytesToHash(payer.Bytes()),
+		},
+		Data:        data,
+		TxHash:      common.HexToHash("0x01"),
+		BlockNumber: 42,
+	}
+
+	payment, err := client.unpackOrderPayment(log)
+	if err != nil {
+		t.Fatalf("unpackOrderPayment: %v", err)
+	}
+	if payment.Payer != payer {
+		t.Errorf("Payer = %s, want %s", payment.Payer, payer)
+	}
+	if payment.Amount.Cmp(amount) != 0 {
+		t.Errorf("Amount = %s, want %s", payment.Amount, amount)
+	}
+	if payment.BlockNumber != 42 || payment.TransactionHash != log.TxHash.Hex() {
+		t.Errorf("payment = %+v, want block 42 and tx %s", payment, log.TxHash.Hex())
+	}
+}
+
+func TestRegistryABIMatchesContract(t *testing.T) {
+	client := testRegistryClient(t)
+	for _, method := range []string{
+		"recordOrder", "updateOrderStatus", "getOrderStatus", "verifyOrderHash",
+		"anchorPayload", "getPayloadAnchor", "commitBatch", "getBatch", "verifyBatchProof",
+		"payOrder", "withdraw", "transferOwnership",
+	} {
+		if _, ok := client.contractABI.Methods[method]; !ok {
+			t.Errorf("contract ABI has no %s method", method)
+		}
+	}
+	for _, event := range []string{"OrderRecorded", "OrderUpdated", "PayloadAnchored", "BatchCommitted", "OrderPaid"} {
+		if _, ok := client.contractABI.Events[event]; !ok {
+			t.Errorf("contract ABI has no %s event", event)
+		}
+	}
+}
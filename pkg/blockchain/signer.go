@@ -0,0 +1,109 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer types selectable via configuration
+const (
+	SignerTypeKeystore   = "keystore"
+	SignerTypeVault      = "vault"
+	SignerTypeKMS        = "kms"
+	SignerTypePrivateKey = "private_key"
+)
+
+// TxSigner signs transactions on behalf of a single account without exposing its key
+type TxSigner interface {
+	// Address returns the account address transactions are sent from
+	Address() common.Address
+	// SignTx signs the transaction using the chain-specific signer
+	SignTx(ctx context.Context, tx *types.Transaction, signer types.Signer) (*types.Transaction, error)
+}
+
+// SignerConfig selects and configures the transaction signer
+type SignerConfig struct {
+	Type string `mapstructure:"type"`
+
+	// Keystore signer
+	KeystorePath           string `mapstructure:"keystore_path"`
+	KeystorePassphraseFile string `mapstructure:"keystore_passphrase_file"`
+
+	// Vault signer
+	VaultAddress string `mapstructure:"vault_address"`
+	VaultToken   string `mapstructure:"vault_token"`
+	VaultPath    string `mapstructure:"vault_path"`
+	VaultField   string `mapstructure:"vault_field"`
+
+	// AWS KMS signer
+	KMSKeyID  string `mapstructure:"kms_key_id"`
+	KMSRegion string `mapstructure:"kms_region"`
+
+	// Raw private key, only honoured when AllowRawPrivateKey is set
+	PrivateKey         string `mapstructure:"private_key"`
+	AllowRawPrivateKey bool   `mapstructure:"-"`
+}
+
+// NewSigner creates the transaction signer described by the configuration
+func NewSigner(ctx context.Context, config SignerConfig) (TxSigner, error) {
+	switch config.Type {
+	case SignerTypeKeystore:
+		return NewKeystoreSigner(config.KeystorePath, config.KeystorePassphraseFile)
+	case SignerTypeVault:
+		return NewVaultSigner(ctx, config.VaultAddress, config.VaultToken, config.VaultPath, config.VaultField)
+	case SignerTypeKMS:
+		return NewKMSSigner(ctx, config.KMSKeyID, config.KMSRegion)
+	case SignerTypePrivateKey:
+		if !config.AllowRawPrivateKey {
+			return nil, fmt.Errorf("raw private keys are not allowed in this build")
+		}
+		return NewPrivateKeySignerFromHex(config.PrivateKey)
+	case "":
+		return nil, fmt.Errorf("signer type is required")
+	default:
+		return nil, fmt.Errorf("unknown signer type %q", config.Type)
+	}
+}
+
+// PrivateKeySigner signs with an in-memory ECDSA key
+type PrivateKeySigner struct {
+	privateKey *ecdsa.PrivateKey
+	address    common.Address
+}
+
+// NewPrivateKeySigner creates a signer from an ECDSA private key
+func NewPrivateKeySigner(privateKey *ecdsa.PrivateKey) (*PrivateKeySigner, error) {
+	publicKey, ok := privateKey.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("error casting public key to ECDSA")
+	}
+
+	return &PrivateKeySigner{
+		privateKey: privateKey,
+		address:    crypto.PubkeyToAddress(*publicKey),
+	}, nil
+}
+
+// NewPrivateKeySignerFromHex creates a signer from a hex-encoded private key
+func NewPrivateKeySignerFromHex(privateKeyHex string) (*PrivateKeySigner, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	return NewPrivateKeySigner(privateKey)
+}
+
+// Address returns the signer's account address
+func (s *PrivateKeySigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx signs the transaction with the private key
+func (s *PrivateKeySigner) SignTx(ctx context.Context, tx *types.Transaction, signer types.Signer) (*types.Transaction, error) {
+	return types.SignTx(tx, signer, s.privateKey)
+}
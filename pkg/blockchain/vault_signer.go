@@ -0,0 +1,58 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewVaultSigner creates a signer from a private key stored in a HashiCorp Vault KV v2 secret.
+// vaultPath is the API path of the secret, e.g. "secret/data/blockchain/signer".
+func NewVaultSigner(ctx context.Context, vaultAddress, vaultToken, vaultPath, vaultField string) (*PrivateKeySigner, error) {
+	if vaultAddress == "" || vaultPath == "" {
+		return nil, fmt.Errorf("vault address and path are required")
+	}
+	if vaultToken == "" {
+		return nil, fmt.Errorf("vault token is required")
+	}
+	if vaultField == "" {
+		vaultField = "private_key"
+	}
+
+	url := strings.TrimRight(vaultAddress, "/") + "/v1/" + strings.TrimLeft(vaultPath, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault request: %v", err)
+	}
+	req.Header.Set("X-Vault-Token", vaultToken)
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret from vault: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("failed to decode vault secret: %v", err)
+	}
+
+	privateKeyHex, ok := secret.Data.Data[vaultField]
+	if !ok || privateKeyHex == "" {
+		return nil, fmt.Errorf("vault secret has no field %q", vaultField)
+	}
+
+	return NewPrivateKeySignerFromHex(strings.TrimPrefix(privateKeyHex, "0x"))
+}
@@ -0,0 +1,119 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// stateGauge reports each breaker's state: 0 closed, 1 half-open, 2 open
+	stateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "Circuit breaker state (0 closed, 1 half-open, 2 open)",
+	}, []string{"name"})
+
+	transitionsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_transitions_total",
+		Help: "Number of circuit breaker state transitions",
+	}, []string{"name", "from", "to"})
+
+	rejectedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_rejected_total",
+		Help: "Number of calls rejected by an open circuit breaker",
+	}, []string{"name"})
+)
+
+// Config configures a circuit breaker
+type Config struct {
+	// MaxRequests is the number of trial calls allowed while half-open
+	MaxRequests uint32
+	// Interval is how often the failure counts are reset while closed
+	Interval time.Duration
+	// Timeout is how long the breaker stays open before going half-open
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive failures that opens the breaker
+	FailureThreshold uint32
+}
+
+// DefaultConfig returns the default circuit breaker configuration
+func DefaultConfig() Config {
+	return Config{
+		MaxRequests:      1,
+		Interval:         time.Minute,
+		Timeout:          30 * time.Second,
+		FailureThreshold: 5,
+	}
+}
+
+// New creates a circuit breaker for the named downstream dependency
+func New(name string, config Config) *gobreaker.CircuitBreaker {
+	stateGauge.WithLabelValues(name).Set(stateValue(gobreaker.StateClosed))
+
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        name,
+		MaxRequests: config.MaxRequests,
+		Interval:    config.Interval,
+		Timeout:     config.Timeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= config.FailureThreshold
+		},
+		IsSuccessful: isSuccessful,
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			stateGauge.WithLabelValues(name).Set(stateValue(to))
+			transitionsCounter.WithLabelValues(name, from.String(), to.String()).Inc()
+		},
+	})
+}
+
+// UnaryClientInterceptor fails calls fast with codes.Unavailable while the breaker is open
+func UnaryClientInterceptor(cb *gobreaker.CircuitBreaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		_, err := cb.Execute(func() (interface{}, error) {
+			return nil, invoker(ctx, method, req, reply, cc, opts...)
+		})
+		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+			rejectedCounter.WithLabelValues(cb.Name()).Inc()
+			return status.Errorf(codes.Unavailable, "%s: %v", cb.Name(), err)
+		}
+		return err
+	}
+}
+
+// DialOption returns a dial option that wraps every unary call in a new breaker for name
+func DialOption(name string, config Config) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(New(name, config)))
+}
+
+// isSuccessful counts only errors that indicate the dependency is unhealthy as failures;
+// client errors such as NotFound or InvalidArgument must not open the breaker
+func isSuccessful(err error) bool {
+	if err == nil {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return false
+	default:
+		return true
+	}
+}
+
+func stateValue(state gobreaker.State) float64 {
+	switch state {
+	case gobreaker.StateHalfOpen:
+		return 1
+	case gobreaker.StateOpen:
+		return 2
+	default:
+		return 0
+	}
+}
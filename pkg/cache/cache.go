@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Cache backends selectable via configuration
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Store is a byte-oriented key/value cache with per-entry TTLs
+type Store interface {
+	// Get returns the cached value and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores a value for the given TTL
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the given keys
+	Delete(ctx context.Context, keys ...string) error
+	// DeletePrefix removes every key starting with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// Config configures the cache store
+type Config struct {
+	Backend       string `mapstructure:"backend"`
+	RedisAddr     string `mapstructure:"redis_addr"`
+	RedisPassword string `mapstructure:"redis_password"`
+	RedisDB       int    `mapstructure:"redis_db"`
+}
+
+// NewStore creates the store described by the configuration.
+// It returns nil when no backend is configured, which disables caching.
+func NewStore(ctx context.Context, config Config) (Store, error) {
+	switch config.Backend {
+	case "":
+		return nil, nil
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendRedis:
+		return NewRedisStore(ctx, config.RedisAddr, config.RedisPassword, config.RedisDB)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", config.Backend)
+	}
+}
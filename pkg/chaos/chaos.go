@@ -0,0 +1,204 @@
+// Package chaos injects faults into a service's calls to other services, so teams can
+// check in staging that timeouts, retries and circuit breakers behave as intended. It is
+// opt-in: a service installs the interceptors only when fault injection is enabled, and
+// even then injects nothing until faults are set through the admin API.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxLatency caps the latency added to a call
+const maxLatency = 10 * time.Minute
+
+// adminMethods prefixes the methods of the chaos admin API, which faults never affect so
+// they can always be turned off again
+const adminMethods = "/chaos.ChaosService/"
+
+var injectedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "chaos_faults_injected_total",
+	Help: "Number of faults injected into outgoing gRPC calls",
+}, []string{"fault", "method"})
+
+// Faults are the faults injected into outgoing calls
+type Faults struct {
+	Enabled     bool
+	Latency     time.Duration // Added to each delayed call
+	Jitter      time.Duration // Up to this much more, chosen at random per call
+	LatencyRate float64       // Fraction of calls delayed
+	ErrorRate   float64       // Fraction of calls failed without being sent
+	ErrorCode   codes.Code    // Code of injected errors
+	Methods     []string      // Prefixes of the full method names affected; all when empty
+}
+
+// Validate checks that faults can be injected
+func (f Faults) Validate() error {
+	if f.Latency < 0 || f.Latency > maxLatency || f.Jitter < 0 || f.Jitter > maxLatency {
+		return fmt.Errorf("latency and jitter must be between 0 and %s", maxLatency)
+	}
+	if f.LatencyRate < 0 || f.LatencyRate > 1 || f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("rates must be between 0 and 1")
+	}
+	if _, ok := codeNames[f.ErrorCode]; !ok {
+		return fmt.Errorf("error code must be a gRPC error code")
+	}
+	return nil
+}
+
+// affects reports whether faults apply to calls of a method
+func (f Faults) affects(method string) bool {
+	if strings.HasPrefix(method, adminMethods) {
+		return false
+	}
+	if len(f.Methods) == 0 {
+		return true
+	}
+	for _, prefix := range f.Methods {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// codeNames are the names of the gRPC codes injected errors can have
+var codeNames = map[codes.Code]string{
+	codes.Canceled:           "CANCELLED",
+	codes.Unknown:            "UNKNOWN",
+	codes.InvalidArgument:    "INVALID_ARGUMENT",
+	codes.DeadlineExceeded:   "DEADLINE_EXCEEDED",
+	codes.NotFound:           "NOT_FOUND",
+	codes.AlreadyExists:      "ALREADY_EXISTS",
+	codes.PermissionDenied:   "PERMISSION_DENIED",
+	codes.ResourceExhausted:  "RESOURCE_EXHAUSTED",
+	codes.FailedPrecondition: "FAILED_PRECONDITION",
+	codes.Aborted:            "ABORTED",
+	codes.OutOfRange:         "OUT_OF_RANGE",
+	codes.Unimplemented:      "UNIMPLEMENTED",
+	codes.Internal:           "INTERNAL",
+	codes.Unavailable:        "UNAVAILABLE",
+	codes.DataLoss:           "DATA_LOSS",
+	codes.Unauthenticated:    "UNAUTHENTICATED",
+}
+
+// ParseCode parses the name of a gRPC error code, such as UNAVAILABLE
+func ParseCode(name string) (codes.Code, error) {
+	for code, codeName := range codeNames {
+		if strings.EqualFold(name, codeName) {
+			return code, nil
+		}
+	}
+	return codes.OK, fmt.Errorf("unknown gRPC error code %q", name)
+}
+
+// CodeName returns the name of a gRPC error code
+func CodeName(code codes.Code) string {
+	return codeNames[code]
+}
+
+// Injector injects faults into the outgoing calls of the connections dialled with its
+// options
+type Injector struct {
+	mu     sync.RWMutex
+	faults Faults
+}
+
+// NewInjector creates an injector that injects nothing until faults are set
+func NewInjector() *Injector {
+	return &Injector{
+		faults: Faults{ErrorCode: codes.Unavailable},
+	}
+}
+
+// Set replaces the faults injected
+func (i *Injector) Set(faults Faults) error {
+	if err := faults.Validate(); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.faults = faults
+	return nil
+}
+
+// Faults returns the faults injected
+func (i *Injector) Faults() Faults {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.faults
+}
+
+// inject delays or fails a call to a method as the faults say. It returns the error to
+// fail the call with, if any.
+func (i *Injector) inject(ctx context.Context, method string) error {
+	f := i.Faults()
+	if !f.Enabled || !f.affects(method) {
+		return nil
+	}
+
+	if (f.Latency > 0 || f.Jitter > 0) && rand.Float64() < f.LatencyRate {
+		delay := f.Latency
+		if f.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(f.Jitter)))
+		}
+		injectedCounter.WithLabelValues("latency", method).Inc()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+	}
+
+	if rand.Float64() < f.ErrorRate {
+		injectedCounter.WithLabelValues("error", method).Inc()
+		return status.Errorf(f.ErrorCode, "chaos: injected fault in %s", method)
+	}
+	return nil
+}
+
+// UnaryClientInterceptor injects faults into unary calls before they are sent
+func (i *Injector) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := i.inject(ctx, method); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor injects faults into streams before they are opened
+func (i *Injector) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if err := i.inject(ctx, method); err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// DialOption returns a dial option that injects faults into unary calls. It should come
+// after the deadline and circuit breaker options, so injected latency counts against
+// calls' deadlines and injected errors trip breakers.
+func (i *Injector) DialOption() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(i.UnaryClientInterceptor())
+}
+
+// StreamDialOption returns a dial option that injects faults into streams
+func (i *Injector) StreamDialOption() grpc.DialOption {
+	return grpc.WithChainStreamInterceptor(i.StreamClientInterceptor())
+}
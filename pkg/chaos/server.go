@@ -0,0 +1,71 @@
+package chaos
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/chaos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server serves the admin API of a service's fault injection over gRPC
+type Server struct {
+	pb.UnimplementedChaosServiceServer
+	injector *Injector
+}
+
+// NewServer creates a new fault injection admin server
+func NewServer(injector *Injector) *Server {
+	return &Server{
+		injector: injector,
+	}
+}
+
+// GetFaults returns the faults injected
+func (s *Server) GetFaults(ctx context.Context, req *pb.GetFaultsRequest) (*pb.Faults, error) {
+	return convertFaultsToProto(s.injector.Faults()), nil
+}
+
+// SetFaults replaces the faults injected
+func (s *Server) SetFaults(ctx context.Context, req *pb.SetFaultsRequest) (*pb.Faults, error) {
+	faults := Faults{
+		Enabled:     req.Faults.GetEnabled(),
+		Latency:     time.Duration(req.Faults.GetLatencyMs()) * time.Millisecond,
+		Jitter:      time.Duration(req.Faults.GetJitterMs()) * time.Millisecond,
+		LatencyRate: req.Faults.GetLatencyRate(),
+		ErrorRate:   req.Faults.GetErrorRate(),
+		ErrorCode:   codes.Unavailable,
+		Methods:     req.Faults.GetMethods(),
+	}
+	if name := req.Faults.GetErrorCode(); name != "" {
+		code, err := ParseCode(name)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		faults.ErrorCode = code
+	}
+
+	if err := s.injector.Set(faults); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	log.Printf("Fault injection set: enabled %t, latency %s+%s at %.2f, errors %s at %.2f, methods [%s]",
+		faults.Enabled, faults.Latency, faults.Jitter, faults.LatencyRate, faults.ErrorCode, faults.ErrorRate, strings.Join(faults.Methods, " "))
+
+	return convertFaultsToProto(s.injector.Faults()), nil
+}
+
+// convertFaultsToProto converts faults to their protobuf representation
+func convertFaultsToProto(faults Faults) *pb.Faults {
+	return &pb.Faults{
+		Enabled:     faults.Enabled,
+		LatencyMs:   faults.Latency.Milliseconds(),
+		JitterMs:    faults.Jitter.Milliseconds(),
+		LatencyRate: faults.LatencyRate,
+		ErrorRate:   faults.ErrorRate,
+		ErrorCode:   CodeName(faults.ErrorCode),
+		Methods:     faults.Methods,
+	}
+}
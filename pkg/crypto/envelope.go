@@ -0,0 +1,251 @@
+// Package crypto encrypts sensitive values at rest with envelope encryption. Every value
+// gets its own random data key, and the data key is stored wrapped by a key-encryption
+// key from a KeyRing. Rotating the key-encryption key only re-wraps data keys.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix starts every encrypted value. Values without it are plaintext written before
+// encryption was turned on, and are returned unchanged by Decrypt.
+const Prefix = "enc:v1:"
+
+// KeySize is the length of key-encryption and data keys, for AES-256
+const KeySize = 32
+
+var (
+	ErrUnknownKey = errors.New("value is encrypted with an unknown key")
+	ErrMalformed  = errors.New("malformed encrypted value")
+	ErrNoKeyRing  = errors.New("value is encrypted but no keys are configured")
+)
+
+// KeyRing holds the key-encryption keys. New values are encrypted with the primary key;
+// older keys are kept so values encrypted before a rotation can still be read. A nil
+// KeyRing stores values in plaintext.
+type KeyRing struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyRing creates a key ring from key-encryption keys by ID. Key IDs may only contain
+// letters, digits and hyphens, and keys must be KeySize bytes.
+func NewKeyRing(primaryID string, keys map[string][]byte) (*KeyRing, error) {
+	if _, ok := keys[primaryID]; !ok {
+		return nil, fmt.Errorf("primary key %q is not in the key ring", primaryID)
+	}
+
+	ring := &KeyRing{
+		primary: primaryID,
+		keys:    make(map[string]cipher.AEAD, len(keys)),
+	}
+	for id, key := range keys {
+		if !validKeyID(id) {
+			return nil, fmt.Errorf("invalid key ID %q", id)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, KeySize, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", id, err)
+		}
+		ring.keys[id] = aead
+	}
+
+	return ring, nil
+}
+
+// PrimaryKeyID returns the ID of the key new values are encrypted with
+func (k *KeyRing) PrimaryKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.primary
+}
+
+// PrimaryPrefix is the prefix of every value encrypted with the primary key. Repositories
+// use it to find values that still need re-encrypting after a rotation.
+func (k *KeyRing) PrimaryPrefix() string {
+	return Prefix + k.PrimaryKeyID() + ":"
+}
+
+// Encrypt encrypts a value with a new data key wrapped by the primary key. Empty values
+// stay empty so optional fields remain distinguishable, and a nil KeyRing returns the
+// value unchanged.
+func (k *KeyRing) Encrypt(plaintext string) (string, error) {
+	if k == nil || plaintext == "" {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %v", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := seal(dataAEAD, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		return "", err
+	}
+
+	return k.PrimaryPrefix() + encode(wrapped) + ":" + encode(data), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt. Plaintext values are returned unchanged.
+func (k *KeyRing) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if k == nil {
+		return "", ErrNoKeyRing
+	}
+
+	keyID, wrapped, data, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dataAEAD, data, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %v", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// Rotate brings a value under the primary key and reports whether it changed. A value
+// encrypted with an older key has its data key re-wrapped; the data itself is not
+// decrypted. A plaintext value is encrypted.
+func (k *KeyRing) Rotate(value string) (string, bool, error) {
+	if k == nil || value == "" || strings.HasPrefix(value, k.PrimaryPrefix()) {
+		return value, false, nil
+	}
+	if !IsEncrypted(value) {
+		encrypted, err := k.Encrypt(value)
+		return encrypted, err == nil, err
+	}
+
+	keyID, wrapped, data, err := parse(value)
+	if err != nil {
+		return "", false, err
+	}
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	rewrapped, err := seal(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		return "", false, err
+	}
+
+	return k.PrimaryPrefix() + encode(rewrapped) + ":" + encode(data), true, nil
+}
+
+// IsEncrypted reports whether a value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// unwrap decrypts a data key wrapped by the key with the given ID
+func (k *KeyRing) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	kek, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	dataKey, err := open(kek, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+
+	return dataKey, nil
+}
+
+// parse splits an encrypted value into its key ID, wrapped data key and encrypted data
+func parse(value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, Prefix), ":")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", nil, nil, ErrMalformed
+	}
+
+	wrapped, err := decode(parts[1])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	data, err := decode(parts[2])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	return parts[0], wrapped, data, nil
+}
+
+// newAEAD creates an AES-GCM cipher for a key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext under a random nonce and returns the nonce followed by the ciphertext
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts the output of seal
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(data string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(data)
+}
+
+// validKeyID reports whether id is safe to embed in encrypted values and SQL LIKE patterns
+func validKeyID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// LoadKeyRing reads key-encryption keys from a file with one "id=base64key" per line.
+// The first key is the primary; to rotate, add a new key at the top and keep the old
+// ones until every value has been re-encrypted. Blank lines and lines starting with #
+// are ignored. An empty path returns a nil KeyRing, which leaves values in plaintext.
+func LoadKeyRing(path string) (*KeyRing, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open key file: %v", err)
+	}
+	defer file.Close()
+
+	var primary string
+	keys := make(map[string][]byte)
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("key file line %d: expected id=base64key", line)
+		}
+		id = strings.TrimSpace(id)
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("key file line %d: invalid base64: %v", line, err)
+		}
+		if _, exists := keys[id]; exists {
+			return nil, fmt.Errorf("key file line %d: duplicate key ID %q", line, id)
+		}
+
+		keys[id] = key
+		if primary == "" {
+			primary = id
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read key file: %v", err)
+	}
+	if primary == "" {
+		return nil, fmt.Errorf("key file %s has no keys", path)
+	}
+
+	return NewKeyRing(primary, keys)
+}
+
+// Reencrypt brings stored values under the primary key. It calls batch, which should
+// re-encrypt some of the values not yet under the primary key and return how many it
+// changed, until a call changes nothing.
+func Reencrypt(ctx context.Context, name string, batch func(ctx context.Context) (int, error)) error {
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		changed, err := batch(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt %s: %v", name, err)
+		}
+		if changed == 0 {
+			break
+		}
+		total += changed
+	}
+
+	if total > 0 {
+		log.Printf("Re-encrypted %d %s under the primary key", total, name)
+	}
+	return nil
+}
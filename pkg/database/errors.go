@@ -0,0 +1,20 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolationCode is the Postgres error code of a row that breaks a unique constraint
+const uniqueViolationCode = "23505"
+
+// IsUniqueViolation reports whether err is Postgres refusing a row that breaks a unique
+// constraint. When constraint is set, only a violation of that constraint counts.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolationCode {
+		return false
+	}
+	return constraint == "" || pgErr.ConstraintName == constraint
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -19,6 +20,8 @@ type PostgresConfig struct {
 	SSLMode  string
 	MaxConns int
 	Timeout  time.Duration
+	// SlowQueryThreshold is how long a query can take before it is logged; 0 logs none
+	SlowQueryThreshold time.Duration
 }
 
 // NewPostgresConfig creates a new PostgreSQL database configuration
@@ -32,6 +35,8 @@ func NewPostgresConfig(host string, port int, user, password, database, sslMode
 		SSLMode:  sslMode,
 		MaxConns: 10,
 		Timeout:  10 * time.Second,
+
+		SlowQueryThreshold: 200 * time.Millisecond,
 	}
 }
 
@@ -57,6 +62,9 @@ func NewPostgresDB(config *PostgresConfig) (*PostgresDB, error) {
 	
 	// Set max connection pool size
 	poolConfig.MaxConns = int32(config.MaxConns)
+
+	// Record query metrics and log slow queries
+	poolConfig.ConnConfig.Tracer = newQueryTracer(config.SlowQueryThreshold)
 	
 	// Create connection pool
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
@@ -93,7 +101,7 @@ func (db *PostgresDB) Ping(ctx context.Context) error {
 }
 
 // ExecContext executes an SQL query with no rows returned
-func (db *PostgresDB) ExecContext(ctx context.Context, sql string, args ...interface{}) (pgx.CommandTag, error) {
+func (db *PostgresDB) ExecContext(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
 	return db.pool.Exec(ctx, sql, args...)
 }
 
@@ -107,6 +115,11 @@ func (db *PostgresDB) QueryRowContext(ctx context.Context, sql string, args ...i
 	return db.pool.QueryRow(ctx, sql, args...)
 }
 
+// CopyFrom bulk inserts rows into a table using the COPY protocol
+func (db *PostgresDB) CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, rows pgx.CopyFromSource) (int64, error) {
+	return db.pool.CopyFrom(ctx, table, columns, rows)
+}
+
 // BeginTx starts a transaction
 func (db *PostgresDB) BeginTx(ctx context.Context) (pgx.Tx, error) {
 	return db.pool.Begin(ctx)
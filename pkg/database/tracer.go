@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// maxLoggedSQLLength caps how much of a slow query's SQL is logged
+const maxLoggedSQLLength = 2000
+
+var (
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Time taken by database queries",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"caller", "operation"})
+
+	queryRows = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_rows",
+		Help:    "Rows returned or affected by database queries",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 9),
+	}, []string{"caller", "operation"})
+
+	queryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Number of database queries that failed",
+	}, []string{"caller", "operation"})
+
+	slowQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_slow_queries_total",
+		Help: "Number of database queries that took longer than the slow query threshold",
+	}, []string{"caller", "operation"})
+)
+
+// uuidPattern matches the IDs that are safe to show in a logged query's arguments
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// queryTracer records every query's duration, rows and errors as metrics, labelled with
+// the function that ran the query, and logs queries slower than a threshold
+type queryTracer struct {
+	slowThreshold time.Duration
+}
+
+// queryTraceKey is the context key under which a query's start is kept until it ends
+type queryTraceKey struct{}
+
+// queryTrace is a query in progress
+type queryTrace struct {
+	start     time.Time
+	sql       string
+	args      []interface{}
+	caller    string
+	operation string
+}
+
+// newQueryTracer creates a tracer. A zero slowThreshold turns off the slow query log.
+func newQueryTracer(slowThreshold time.Duration) *queryTracer {
+	return &queryTracer{slowThreshold: slowThreshold}
+}
+
+// TraceQueryStart notes when and from where a query was started
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{
+		start:     time.Now(),
+		sql:       data.SQL,
+		args:      data.Args,
+		caller:    queryCaller(),
+		operation: queryOperation(data.SQL),
+	})
+}
+
+// TraceQueryEnd records a finished query. Queries that return rows end when their rows
+// are closed, so the duration includes reading them.
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+	t.record(trace, data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceCopyFromStart notes when and from where a bulk insert was started
+func (t *queryTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{
+		start:     time.Now(),
+		sql:       fmt.Sprintf("COPY %s (%s) FROM STDIN", data.TableName.Sanitize(), strings.Join(data.ColumnNames, ", ")),
+		caller:    queryCaller(),
+		operation: "COPY",
+	})
+}
+
+// TraceCopyFromEnd records a finished bulk insert
+func (t *queryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+	t.record(trace, data.CommandTag.RowsAffected(), data.Err)
+}
+
+// record updates the metrics for a finished query and logs it if it was slow
+func (t *queryTracer) record(trace *queryTrace, rows int64, err error) {
+	elapsed := time.Since(trace.start)
+
+	queryDuration.WithLabelValues(trace.caller, trace.operation).Observe(elapsed.Seconds())
+	if err != nil {
+		queryErrors.WithLabelValues(trace.caller, trace.operation).Inc()
+	} else {
+		queryRows.WithLabelValues(trace.caller, trace.operation).Observe(float64(rows))
+	}
+
+	if t.slowThreshold <= 0 || elapsed < t.slowThreshold {
+		return
+	}
+	slowQueries.WithLabelValues(trace.caller, trace.operation).Inc()
+
+	outcome := fmt.Sprintf("%d rows", rows)
+	if err != nil {
+		outcome = fmt.Sprintf("error: %v", err)
+	}
+	log.Printf("Slow query took %s in %s (%s): %s; args: %s",
+		elapsed.Round(time.Millisecond), trace.caller, outcome, compactSQL(trace.sql), sanitizeArgs(trace.args))
+}
+
+// queryCaller names the function outside the database drivers and this package that
+// ran a query, such as "repository.(*ProviderRepository).FindNearbyProviders"
+func queryCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/jackc/") &&
+			!strings.HasPrefix(frame.Function, "github.com/order-api-microservices/pkg/database.") {
+			return frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// queryOperation is the statement's leading keyword, such as SELECT or UPDATE
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return strings.ToUpper(strings.TrimRight(fields[0], "(;"))
+}
+
+// compactSQL puts a query on one line and cuts it to a loggable length
+func compactSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxLoggedSQLLength {
+		sql = sql[:maxLoggedSQLLength] + "..."
+	}
+	return sql
+}
+
+// sanitizeArgs describes a query's arguments for the log without revealing personal
+// data. Numbers, booleans, times, UUIDs and named string types such as order statuses
+// are shown; other strings and binary values are replaced by their length.
+func sanitizeArgs(args []interface{}) string {
+	described := make([]string, len(args))
+	for i, arg := range args {
+		described[i] = fmt.Sprintf("$%d=%s", i+1, sanitizeArg(arg))
+	}
+	return "[" + strings.Join(described, " ") + "]"
+}
+
+// sanitizeArg describes one query argument for the log
+func sanitizeArg(arg interface{}) string {
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case string:
+		if uuidPattern.MatchString(v) {
+			return v
+		}
+		return fmt.Sprintf("<string of %d bytes>", len(v))
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	case []string:
+		return fmt.Sprintf("<%d strings>", len(v))
+	default:
+		// Named string types are enums, not free text
+		if value := reflect.ValueOf(v); value.Kind() == reflect.String {
+			return value.String()
+		}
+		return fmt.Sprintf("<%T>", v)
+	}
+}
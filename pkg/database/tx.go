@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Transaction retry settings
+const (
+	maxTxAttempts  = 4
+	txRetryBackoff = 20 * time.Millisecond
+)
+
+// Postgres error codes of transactions that failed only because of other transactions
+// and can be run again
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+)
+
+// WithTx runs fn in a transaction, committing it if fn returns nil and rolling it back
+// otherwise. A transaction that fails with a serialization failure or a deadlock is
+// retried from the start, after an increasing, jittered backoff, up to 4 attempts in all.
+// fn can therefore run more than once and must not have effects outside the transaction.
+func (db *PostgresDB) WithTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return retryTx(ctx, func() error {
+		return db.runTx(ctx, fn)
+	})
+}
+
+// retryTx calls run until it succeeds, fails with an error that running it again cannot
+// fix, or has been attempted maxTxAttempts times
+func retryTx(ctx context.Context, run func() error) error {
+	backoff := txRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || attempt == maxTxAttempts || !retryableTxError(err) {
+			return err
+		}
+
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// runTx runs fn in a single transaction
+func (db *PostgresDB) runTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// retryableTxError reports whether a transaction failed because it conflicted with
+// another one, so running it again can succeed
+func retryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailureCode || pgErr.Code == deadlockDetectedCode
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRetryTxRetriesConflicts(t *testing.T) {
+	for _, code := range []string{serializationFailureCode, deadlockDetectedCode} {
+		attempts := 0
+		err := retryTx(context.Background(), func() error {
+			attempts++
+			if attempts < 3 {
+				return fmt.Errorf("failed to commit transaction: %w", &pgconn.PgError{Code: code})
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("code %s: retryTx returned %v, want nil", code, err)
+		}
+		if attempts != 3 {
+			t.Errorf("code %s: ran %d times, want 3", code, attempts)
+		}
+	}
+}
+
+func TestRetryTxGivesUpAfterMaxAttempts(t *testing.T) {
+	conflict := &pgconn.PgError{Code: serializationFailureCode}
+	attempts := 0
+	err := retryTx(context.Background(), func() error {
+		attempts++
+		return conflict
+	})
+	if !errors.Is(err, conflict) {
+		t.Errorf("retryTx returned %v, want the last conflict", err)
+	}
+	if attempts != maxTxAttempts {
+		t.Errorf("ran %d times, want %d", attempts, maxTxAttempts)
+	}
+}
+
+func TestRetryTxDoesNotRetryOtherErrors(t *testing.T) {
+	for _, failure := range []error{
+		errors.New("insufficient balance"),
+		&pgconn.PgError{Code: "23505"}, // unique violation
+	} {
+		attempts := 0
+		err := retryTx(context.Background(), func() error {
+			attempts++
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Errorf("retryTx returned %v, want %v", err, failure)
+		}
+		if attempts != 1 {
+			t.Errorf("%v: ran %d times, want 1", failure, attempts)
+		}
+	}
+}
+
+func TestRetryTxStopsWhenContextEnds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := retryTx(ctx, func() error {
+		attempts++
+		cancel()
+		return &pgconn.PgError{Code: deadlockDetectedCode}
+	})
+	if err == nil {
+		t.Fatal("retryTx returned nil after the context ended")
+	}
+	if attempts != 1 {
+		t.Errorf("ran %d times after the context ended, want 1", attempts)
+	}
+}
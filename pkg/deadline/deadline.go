@@ -0,0 +1,130 @@
+// Package deadline bounds outgoing gRPC calls by what is left of the caller's own
+// deadline, so a call made late in a request does not outlive the request. Each call
+// gets its method's timeout, shortened to the time left before the incoming deadline
+// minus a reserve the caller keeps to handle the reply.
+package deadline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var exhaustedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "grpc_client_budget_exhausted_total",
+	Help: "Outgoing calls not made because the caller's deadline left no time for them",
+}, []string{"method"})
+
+// Timeouts configure how long outgoing calls may take
+type Timeouts struct {
+	// Default is the timeout of methods not in Methods; 0 leaves them the caller's deadline
+	Default time.Duration
+	// Methods are the timeouts of single methods, by full name, such as
+	// /provider.ProviderService/FindProviders
+	Methods map[string]time.Duration
+	// Reserve is kept back from the caller's deadline to handle the reply
+	Reserve time.Duration
+}
+
+// Merge returns t with what override sets replacing it: its default and reserve when
+// not zero, and each of its methods
+func (t Timeouts) Merge(override Timeouts) Timeouts {
+	merged := Timeouts{
+		Default: t.Default,
+		Methods: make(map[string]time.Duration, len(t.Methods)+len(override.Methods)),
+		Reserve: t.Reserve,
+	}
+	if override.Default > 0 {
+		merged.Default = override.Default
+	}
+	if override.Reserve > 0 {
+		merged.Reserve = override.Reserve
+	}
+	for method, timeout := range t.Methods {
+		merged.Methods[method] = timeout
+	}
+	for method, timeout := range override.Methods {
+		merged.Methods[method] = timeout
+	}
+	return merged
+}
+
+// timeout is the timeout of a method
+func (t Timeouts) timeout(method string) time.Duration {
+	if timeout, ok := t.Methods[method]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+// Budget derives the context of one outgoing call from ctx. The call may take up to
+// timeout, or any time when it is 0, but no longer than what is left of ctx's deadline
+// after reserve. It reports false when nothing would be left.
+func Budget(ctx context.Context, timeout, reserve time.Duration) (context.Context, context.CancelFunc, bool) {
+	if deadline, ok := ctx.Deadline(); ok {
+		left := time.Until(deadline) - reserve
+		if left <= 0 {
+			return ctx, func() {}, false
+		}
+		if timeout <= 0 || left < timeout {
+			timeout = left
+		}
+	}
+
+	if timeout <= 0 {
+		return ctx, func() {}, true
+	}
+	budgetCtx, cancel := context.WithTimeout(ctx, timeout)
+	return budgetCtx, cancel, true
+}
+
+// UnaryClientInterceptor gives every call its budget, and fails calls that would have
+// none with DeadlineExceeded without making them
+func UnaryClientInterceptor(timeouts Timeouts) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel, ok := Budget(ctx, timeouts.timeout(method), timeouts.Reserve)
+		if !ok {
+			exhaustedCounter.WithLabelValues(method).Inc()
+			return status.Errorf(codes.DeadlineExceeded, "no time left to call %s", method)
+		}
+		defer cancel()
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// DialOption returns a dial option that gives every unary call its budget. It must come
+// before the circuit breaker's, so calls never made do not count against the dependency.
+func DialOption(timeouts Timeouts) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(timeouts))
+}
+
+// ParseMethods parses method timeouts written as a comma-separated list of
+// method=duration, such as "/provider.ProviderService/FindProviders=3s"
+func ParseMethods(spec string) (map[string]time.Duration, error) {
+	methods := make(map[string]time.Duration)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		method, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("method timeout %q is not method=duration", entry)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout for %s: %w", method, err)
+		}
+		methods[strings.TrimSpace(method)] = timeout
+	}
+	return methods, nil
+}
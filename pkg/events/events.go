@@ -0,0 +1,187 @@
+// Package events defines the domain events the services publish as versioned CloudEvents.
+// An event is a CloudEvent message of proto/events carrying one of its data messages, and
+// encodes either as protobuf or in the CloudEvents JSON format, so webhooks and every
+// other consumer share one contract.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/events"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SpecVersion is the CloudEvents version events follow
+const SpecVersion = "1.0"
+
+// ContentTypeJSON is the media type of an event in the CloudEvents JSON format
+const ContentTypeJSON = "application/cloudevents+json"
+
+// Event types
+const (
+	TypeOrderCreated            = "order.created"
+	TypeOrderStatusChanged      = "order.status_changed"
+	TypeProviderLocationUpdated = "provider.location_updated"
+)
+
+// Attributes set on every event besides the required ones
+const (
+	attributeTime       = "time"
+	attributeDataSchema = "dataschema"
+)
+
+// schema is the current version of an event type's data and the message holding it. A
+// change that would break consumers adds a version instead of changing the message.
+type schema struct {
+	version int
+	newData func() proto.Message
+}
+
+// schemas are the schemas of every event type
+var schemas = map[string]schema{
+	TypeOrderCreated:            {version: 1, newData: func() proto.Message { return &pb.OrderCreated{} }},
+	TypeOrderStatusChanged:      {version: 1, newData: func() proto.Message { return &pb.OrderStatusChanged{} }},
+	TypeProviderLocationUpdated: {version: 1, newData: func() proto.Message { return &pb.ProviderLocationUpdated{} }},
+}
+
+// DataSchema returns the URI naming the current version of an event type's data, such as
+// urn:order-api:events:order.created:v1
+func DataSchema(eventType string) (string, error) {
+	s, ok := schemas[eventType]
+	if !ok {
+		return "", fmt.Errorf("unknown event type %q", eventType)
+	}
+	return fmt.Sprintf("urn:order-api:events:%s:v%d", eventType, s.version), nil
+}
+
+// New creates an event of a type, produced by source at a time, with a new ID. data must
+// be the type's data message.
+func New(source, eventType string, data proto.Message, at time.Time) (*pb.CloudEvent, error) {
+	return build(uuid.New().String(), source, eventType, data, at)
+}
+
+// build creates an event from its parts
+func build(id, source, eventType string, data proto.Message, at time.Time) (*pb.CloudEvent, error) {
+	dataSchema, err := DataSchema(eventType)
+	if err != nil {
+		return nil, err
+	}
+	if expected := schemas[eventType].newData(); data.ProtoReflect().Descriptor().FullName() != expected.ProtoReflect().Descriptor().FullName() {
+		return nil, fmt.Errorf("%s carries %s, not %s", eventType,
+			expected.ProtoReflect().Descriptor().FullName(), data.ProtoReflect().Descriptor().FullName())
+	}
+
+	packed, err := anypb.New(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack event data: %w", err)
+	}
+
+	return &pb.CloudEvent{
+		Id:          id,
+		Source:      source,
+		SpecVersion: SpecVersion,
+		Type:        eventType,
+		Attributes: map[string]*pb.CloudEventAttributeValue{
+			attributeTime: {
+				Attr: &pb.CloudEventAttributeValue_CeTimestamp{CeTimestamp: timestamppb.New(at)},
+			},
+			attributeDataSchema: {
+				Attr: &pb.CloudEventAttributeValue_CeUri{CeUri: dataSchema},
+			},
+		},
+		Data: &pb.CloudEvent_ProtoData{ProtoData: packed},
+	}, nil
+}
+
+// Data unpacks an event's data into its type's data message
+func Data(event *pb.CloudEvent) (proto.Message, error) {
+	s, ok := schemas[event.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", event.Type)
+	}
+
+	data := s.newData()
+	if err := event.GetProtoData().UnmarshalTo(data); err != nil {
+		return nil, fmt.Errorf("failed to unpack %s data: %w", event.Type, err)
+	}
+	return data, nil
+}
+
+// Marshal encodes an event as protobuf
+func Marshal(event *pb.CloudEvent) ([]byte, error) {
+	return proto.Marshal(event)
+}
+
+// Unmarshal decodes an event encoded as protobuf
+func Unmarshal(b []byte) (*pb.CloudEvent, error) {
+	event := &pb.CloudEvent{}
+	if err := proto.Unmarshal(b, event); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+	return event, nil
+}
+
+// jsonEvent is an event in the CloudEvents JSON format
+type jsonEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// MarshalJSON encodes an event in the CloudEvents JSON format. Its data is a JSON object
+// in the protobuf JSON mapping, with the field names of the proto, so 64-bit integers
+// such as prices are strings.
+func MarshalJSON(event *pb.CloudEvent) ([]byte, error) {
+	data, err := Data(event)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s data: %w", event.Type, err)
+	}
+
+	return json.Marshal(jsonEvent{
+		SpecVersion:     event.SpecVersion,
+		ID:              event.Id,
+		Source:          event.Source,
+		Type:            event.Type,
+		Time:            event.Attributes[attributeTime].GetCeTimestamp().AsTime(),
+		DataContentType: "application/json",
+		DataSchema:      event.Attributes[attributeDataSchema].GetCeUri(),
+		Data:            encoded,
+	})
+}
+
+// UnmarshalJSON decodes an event in the CloudEvents JSON format. Data fields it does not
+// know are ignored, so consumers keep working when a version gains fields.
+func UnmarshalJSON(b []byte) (*pb.CloudEvent, error) {
+	var decoded jsonEvent
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+	if decoded.SpecVersion != SpecVersion {
+		return nil, fmt.Errorf("unsupported CloudEvents version %q", decoded.SpecVersion)
+	}
+	s, ok := schemas[decoded.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", decoded.Type)
+	}
+
+	data := s.newData()
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(decoded.Data, data); err != nil {
+		return nil, fmt.Errorf("failed to decode %s data: %w", decoded.Type, err)
+	}
+	return build(decoded.ID, decoded.Source, decoded.Type, data, decoded.Time)
+}
@@ -0,0 +1,149 @@
+// Package fanout delivers messages published on a topic to every subscriber of that
+// topic, so a stream held open by one instance of a service sees what another instance
+// publishes. The memory backend serves a single instance; the Redis backend, built on
+// Redis pub/sub, serves any number of them.
+package fanout
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Fan-out backends selectable via configuration
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// subscriptionBuffer is how many messages a subscriber can fall behind before newer ones
+// are dropped
+const subscriptionBuffer = 16
+
+var droppedCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "fanout_dropped_total",
+	Help: "Messages dropped because a subscriber was too far behind to take them",
+})
+
+// Bus publishes messages on topics and delivers them to the topics' subscribers.
+// Delivery is best effort: a message published while no instance is subscribed, or that
+// a subscriber is too far behind to take, is lost.
+type Bus interface {
+	// Publish sends a message to every subscriber of topic
+	Publish(ctx context.Context, topic string, message []byte) error
+	// Subscribe starts receiving the messages published on topic
+	Subscribe(ctx context.Context, topic string) (*Subscription, error)
+	// Close stops the bus
+	Close() error
+}
+
+// Config configures the bus
+type Config struct {
+	Backend       string `mapstructure:"backend"`
+	RedisAddr     string `mapstructure:"redis_addr"`
+	RedisPassword string `mapstructure:"redis_password"`
+	RedisDB       int    `mapstructure:"redis_db"`
+}
+
+// NewBus creates the bus described by the configuration. With no backend configured it
+// uses the memory backend.
+func NewBus(ctx context.Context, config Config) (Bus, error) {
+	switch config.Backend {
+	case "", BackendMemory:
+		return NewMemoryBus(), nil
+	case BackendRedis:
+		return NewRedisBus(ctx, config.RedisAddr, config.RedisPassword, config.RedisDB)
+	default:
+		return nil, fmt.Errorf("unknown fan-out backend %q", config.Backend)
+	}
+}
+
+// Subscription receives the messages published on a topic until it is closed
+type Subscription struct {
+	C     <-chan []byte
+	ch    chan []byte
+	topic string
+	hub   *hub
+	once  sync.Once
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.remove(s)
+	})
+}
+
+// hub hands messages to an instance's subscribers. It reports when a topic gains its
+// first subscriber or loses its last, so a backend subscribes only to topics in use.
+type hub struct {
+	mu     sync.Mutex
+	topics map[string]map[*Subscription]struct{}
+	// onFirst and onLast are called with the hub locked
+	onFirst func(topic string) error
+	onLast  func(topic string)
+}
+
+// newHub creates a hub
+func newHub(onFirst func(topic string) error, onLast func(topic string)) *hub {
+	return &hub{
+		topics:  make(map[string]map[*Subscription]struct{}),
+		onFirst: onFirst,
+		onLast:  onLast,
+	}
+}
+
+// add subscribes to a topic
+func (h *hub) add(topic string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subscribers, ok := h.topics[topic]
+	if !ok {
+		if h.onFirst != nil {
+			if err := h.onFirst(topic); err != nil {
+				return nil, err
+			}
+		}
+		subscribers = make(map[*Subscription]struct{})
+		h.topics[topic] = subscribers
+	}
+
+	ch := make(chan []byte, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, topic: topic, hub: h}
+	subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// remove ends a subscription
+func (h *hub) remove(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subscribers := h.topics[sub.topic]
+	delete(subscribers, sub)
+	if len(subscribers) == 0 {
+		delete(h.topics, sub.topic)
+		if h.onLast != nil {
+			h.onLast(sub.topic)
+		}
+	}
+}
+
+// deliver hands a message to every subscriber of its topic, dropping it for those whose
+// buffer is full rather than holding up the rest
+func (h *hub) deliver(topic string, message []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.topics[topic] {
+		select {
+		case sub.ch <- message:
+		default:
+			droppedCounter.Inc()
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package fanout
+
+import "context"
+
+// MemoryBus delivers messages within one process, suitable for a single instance
+type MemoryBus struct {
+	hub *hub
+}
+
+// NewMemoryBus creates a new in-memory bus
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		hub: newHub(nil, nil),
+	}
+}
+
+// Publish delivers a message to the topic's subscribers
+func (b *MemoryBus) Publish(ctx context.Context, topic string, message []byte) error {
+	b.hub.deliver(topic, message)
+	return nil
+}
+
+// Subscribe starts receiving the messages published on topic
+func (b *MemoryBus) Subscribe(ctx context.Context, topic string) (*Subscription, error) {
+	return b.hub.add(topic)
+}
+
+// Close does nothing; the memory bus holds no connections
+func (b *MemoryBus) Close() error {
+	return nil
+}
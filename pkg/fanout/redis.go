@@ -0,0 +1,93 @@
+package fanout
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// channelPrefix namespaces the Redis channels of fan-out topics
+const channelPrefix = "fanout:"
+
+// RedisBus delivers messages through Redis pub/sub to every instance sharing it. Each
+// instance subscribes to a topic's channel only while it has subscribers of its own.
+type RedisBus struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+	hub    *hub
+	cancel context.CancelFunc
+}
+
+// NewRedisBus connects to Redis and returns a bus backed by it
+func NewRedisBus(ctx context.Context, addr, password string, db int) (*RedisBus, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("redis address is required")
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %v", err)
+	}
+
+	// Subscribed to no channel yet; the hub adds them as topics gain subscribers
+	receiveCtx, cancel := context.WithCancel(context.Background())
+	b := &RedisBus{
+		client: client,
+		pubsub: client.Subscribe(receiveCtx),
+		cancel: cancel,
+	}
+	b.hub = newHub(
+		func(topic string) error {
+			if err := b.pubsub.Subscribe(receiveCtx, channelPrefix+topic); err != nil {
+				return fmt.Errorf("failed to subscribe to %s: %v", topic, err)
+			}
+			return nil
+		},
+		func(topic string) {
+			if err := b.pubsub.Unsubscribe(receiveCtx, channelPrefix+topic); err != nil {
+				log.Printf("Failed to unsubscribe from %s: %v", topic, err)
+			}
+		},
+	)
+	go b.receive()
+
+	return b, nil
+}
+
+// Publish sends a message to the topic's subscribers on every instance
+func (b *RedisBus) Publish(ctx context.Context, topic string, message []byte) error {
+	if err := b.client.Publish(ctx, channelPrefix+topic, message).Err(); err != nil {
+		return fmt.Errorf("failed to publish to %s: %v", topic, err)
+	}
+	return nil
+}
+
+// Subscribe starts receiving the messages published on topic
+func (b *RedisBus) Subscribe(ctx context.Context, topic string) (*Subscription, error) {
+	return b.hub.add(topic)
+}
+
+// receive hands the messages of subscribed channels to the hub until the bus is closed.
+// The Redis client reconnects and resubscribes by itself; messages published meanwhile
+// are lost.
+func (b *RedisBus) receive() {
+	for message := range b.pubsub.Channel() {
+		b.hub.deliver(message.Channel[len(channelPrefix):], []byte(message.Payload))
+	}
+}
+
+// Close unsubscribes and closes the Redis connection
+func (b *RedisBus) Close() error {
+	b.cancel()
+	if err := b.pubsub.Close(); err != nil {
+		return err
+	}
+	return b.client.Close()
+}
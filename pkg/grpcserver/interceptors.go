@@ -0,0 +1,216 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/order-api-microservices/pkg/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// validator is implemented by messages with generated validation rules
+type validator interface {
+	Validate() error
+}
+
+// allValidator is implemented by messages whose generated validation can report every
+// broken rule at once
+type allValidator interface {
+	ValidateAll() error
+}
+
+// unaryRequestID puts the call's request ID in its context, taken from the caller or
+// new, and returns it to the caller in the response header
+func unaryRequestID(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id := requestid.FromIncoming(ctx)
+	ctx = requestid.NewContext(ctx, id)
+	grpc.SetHeader(ctx, metadata.Pairs(requestid.MetadataKey, id))
+	return handler(ctx, req)
+}
+
+// streamRequestID puts the stream's request ID in its context, taken from the caller or
+// new, and returns it to the caller in the response header
+func streamRequestID(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id := requestid.FromIncoming(ss.Context())
+	ss.SetHeader(metadata.Pairs(requestid.MetadataKey, id))
+	return handler(srv, &contextStream{ServerStream: ss, ctx: requestid.NewContext(ss.Context(), id)})
+}
+
+// contextStream is a stream with a context of its own
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the stream's context
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// unaryRecovery turns a panicking handler into an Internal error instead of a crash
+func unaryRecovery(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ctx, info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// streamRecovery turns a panicking stream handler into an Internal error instead of a crash
+func streamRecovery(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ss.Context(), info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// recovered logs a panic with its stack and returns the error the caller gets
+func recovered(ctx context.Context, method string, r interface{}) error {
+	requestid.Logf(ctx, "Panic in %s: %v\n%s", method, r, debug.Stack())
+	return status.Errorf(codes.Internal, "internal error")
+}
+
+// unaryLogging logs every call with its outcome and how long it took
+func unaryLogging(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// streamLogging logs every stream with its outcome once it ends
+func streamLogging(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logCall(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// logCall logs a finished call
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	caller := "unknown"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		caller = p.Addr.String()
+	}
+
+	st, _ := status.FromError(err)
+	if err != nil {
+		requestid.Logf(ctx, "%s %s from %s in %s: %s", method, st.Code(), caller, time.Since(start), st.Message())
+		return
+	}
+	requestid.Logf(ctx, "%s %s from %s in %s", method, st.Code(), caller, time.Since(start))
+}
+
+// unaryAuth rejects calls that do not carry token, unless token is empty
+func unaryAuth(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authenticate(ctx, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamAuth rejects streams that do not carry token, unless token is empty
+func streamAuth(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authenticate(ss.Context(), token); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// authenticate checks the bearer token in a call's metadata
+func authenticate(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+
+	values := metadata.ValueFromIncomingContext(ctx, AuthorizationKey)
+	if len(values) == 0 {
+		return status.Errorf(codes.Unauthenticated, "missing %s metadata", AuthorizationKey)
+	}
+	presented := strings.TrimPrefix(values[0], "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+		return status.Errorf(codes.Unauthenticated, "invalid token")
+	}
+	return nil
+}
+
+// unaryDeadline gives calls without a deadline the default one and shortens deadlines
+// beyond max, so no call can hold its resources indefinitely
+func unaryDeadline(defaultTimeout, max time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+
+		deadline, ok := ctx.Deadline()
+		switch {
+		case !ok && defaultTimeout > 0:
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+			defer cancel()
+		case max > 0 && (!ok || time.Until(deadline) > max):
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, max)
+			defer cancel()
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// unaryValidation rejects requests that break their message's validation rules
+func unaryValidation(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := Validate(req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamValidation rejects every message received on a stream that breaks its rules
+func streamValidation(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &validatingStream{ServerStream: ss})
+}
+
+// validatingStream validates the messages received on a stream
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+// RecvMsg receives a message and validates it
+func (s *validatingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return Validate(m)
+}
+
+// Validate runs a message's generated validation, if it has any, and returns an
+// InvalidArgument error for broken rules. Servers run it on every request; callers use
+// it for messages that reach a service without passing through its server.
+func Validate(message interface{}) error {
+	var err error
+	switch m := message.(type) {
+	case allValidator:
+		err = m.ValidateAll()
+	case validator:
+		err = m.Validate()
+	}
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return nil
+}
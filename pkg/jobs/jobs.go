@@ -0,0 +1,390 @@
+// Package jobs runs a service's background work, such as blockchain writes and
+// notifications, from persistent records in the jobs table. A failing job is retried with
+// exponential backoff until its kind's attempts run out; it is then kept as DEAD, in a
+// dead letter queue that admins list and requeue through Server.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrJobNotFound is returned when a job to requeue does not exist or is not dead
+var ErrJobNotFound = errors.New("job not found")
+
+// maxErrorLength caps how much of a failed attempt's error is kept
+const maxErrorLength = 1000
+
+var attemptCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "jobs_attempts_total",
+	Help: "Background job attempts, by service, kind and result (succeeded, retried or dead)",
+}, []string{"service", "kind", "result"})
+
+// Status is where a job is in its lifecycle. Jobs that succeed are deleted.
+type Status string
+
+// Job statuses
+const (
+	StatusPending Status = "PENDING" // Waiting for its first or next attempt
+	StatusRunning Status = "RUNNING" // Claimed by a runner until its lease ends
+	StatusDead    Status = "DEAD"    // Every attempt failed; waits for an admin
+)
+
+// Job is one piece of background work with the outcome of its attempts
+type Job struct {
+	ID            string          `json:"id"`
+	Service       string          `json:"service"`
+	Kind          string          `json:"kind"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        Status          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// Handler does a job's work from its payload; an error fails the attempt
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// Policy controls how the jobs of a kind are retried
+type Policy struct {
+	MaxAttempts int           // After this many failed attempts a job is dead
+	BackoffBase time.Duration // Wait after the first failed attempt; doubles after each one
+	BackoffMax  time.Duration // Longest wait between attempts
+	Timeout     time.Duration // How long one attempt may take
+}
+
+// Config controls how often a queue runs its jobs
+type Config struct {
+	Interval  time.Duration // How often due jobs are picked up
+	BatchSize int           // Most jobs run per interval
+}
+
+// KindCount is how many jobs of a kind are in a status
+type KindCount struct {
+	Kind   string
+	Status Status
+	Count  int
+}
+
+// kind is a registered kind of job
+type kind struct {
+	policy  Policy
+	handler Handler
+}
+
+// Queue stores a service's jobs in the jobs table and runs those of the kinds registered
+// with it. Several instances of a service can run the same queue side by side.
+type Queue struct {
+	db      *database.PostgresDB
+	service string
+	cfg     Config
+	kinds   map[string]kind
+}
+
+// NewQueue creates a queue for a service's jobs
+func NewQueue(db *database.PostgresDB, service string, cfg Config) *Queue {
+	return &Queue{
+		db:      db,
+		service: service,
+		cfg:     cfg,
+		kinds:   make(map[string]kind),
+	}
+}
+
+// Register sets the handler and policy of a kind of job. Kinds are registered before Run.
+func (q *Queue) Register(kindName string, policy Policy, handler Handler) {
+	q.kinds[kindName] = kind{policy: policy, handler: handler}
+}
+
+// Enqueue stores a job of a registered kind to run as soon as possible, with payload
+// encoded as JSON
+func (q *Queue) Enqueue(ctx context.Context, kindName string, payload interface{}) error {
+	if _, ok := q.kinds[kindName]; !ok {
+		return fmt.Errorf("job kind %q is not registered", kindName)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s job: %w", kindName, err)
+	}
+
+	now := time.Now().UTC()
+	_, err = q.db.ExecContext(ctx, `
+		INSERT INTO jobs (id, service, kind, payload, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 0, $6, $6, $6)
+	`, uuid.New().String(), q.service, kindName, data, StatusPending, now)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s job: %w", kindName, err)
+	}
+	return nil
+}
+
+// Run runs due jobs every interval until ctx is cancelled
+func (q *Queue) Run(ctx context.Context) {
+	ticker := time.NewTicker(q.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			jobs, err := q.claimDue(ctx, time.Now().UTC())
+			if err != nil {
+				log.Printf("Failed to claim %s jobs: %v", q.service, err)
+				continue
+			}
+			for _, job := range jobs {
+				if err := q.attempt(ctx, job); err != nil {
+					log.Printf("Failed to record %s job %s: %v", job.Kind, job.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// claimDue picks up to a batch of due jobs of the registered kinds, with those whose
+// runner's lease ended, and leases them long enough to run the whole batch. A claim
+// counts as an attempt, so a job that crashes its runner still runs out of attempts.
+func (q *Queue) claimDue(ctx context.Context, now time.Time) ([]*Job, error) {
+	kinds := make([]string, 0, len(q.kinds))
+	var longest time.Duration
+	for name, k := range q.kinds {
+		kinds = append(kinds, name)
+		if k.policy.Timeout > longest {
+			longest = k.policy.Timeout
+		}
+	}
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+	leaseUntil := now.Add(longest*time.Duration(q.cfg.BatchSize) + q.cfg.Interval)
+
+	query := `
+		WITH due AS (
+			SELECT id
+			FROM jobs
+			WHERE service = $1 AND kind = ANY($2) AND status IN ($3, $4) AND next_attempt_at <= $5
+			ORDER BY next_attempt_at
+			LIMIT $7
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE jobs j
+		SET status = $4, attempts = j.attempts + 1, next_attempt_at = $6, updated_at = $5
+		FROM due
+		WHERE j.id = due.id
+		RETURNING ` + prefixedJobColumns + `
+	`
+	return q.query(ctx, query, q.service, kinds, StatusPending, StatusRunning, now, leaseUntil, q.cfg.BatchSize)
+}
+
+// attempt runs a claimed job and records its outcome: deleted when it succeeds, retried
+// after a backoff while it has attempts left, dead otherwise
+func (q *Queue) attempt(ctx context.Context, job *Job) error {
+	k := q.kinds[job.Kind]
+
+	runCtx := ctx
+	if k.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, k.policy.Timeout)
+		defer cancel()
+	}
+	err := k.handler(runCtx, job.Payload)
+
+	now := time.Now().UTC()
+	switch {
+	case err == nil:
+		attemptCounter.WithLabelValues(q.service, job.Kind, "succeeded").Inc()
+		_, err := q.db.ExecContext(ctx, `DELETE FROM jobs WHERE id = $1`, job.ID)
+		return err
+	case job.Attempts >= k.policy.MaxAttempts:
+		attemptCounter.WithLabelValues(q.service, job.Kind, "dead").Inc()
+		log.Printf("%s job %s failed after %d attempts: %v", job.Kind, job.ID, job.Attempts, err)
+		return q.recordFailure(ctx, job.ID, StatusDead, now, err)
+	default:
+		attemptCounter.WithLabelValues(q.service, job.Kind, "retried").Inc()
+		return q.recordFailure(ctx, job.ID, StatusPending, now.Add(backoff(k.policy, job.Attempts)), err)
+	}
+}
+
+// recordFailure stores a failed attempt's error and the job's new status
+func (q *Queue) recordFailure(ctx context.Context, jobID string, status Status, nextAttemptAt time.Time, cause error) error {
+	message := cause.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+	_, err := q.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = $2, next_attempt_at = $3, last_error = $4, updated_at = $5
+		WHERE id = $1
+	`, jobID, status, nextAttemptAt, message, time.Now().UTC())
+	return err
+}
+
+// backoff is how long to wait after the given number of failed attempts: the base wait
+// doubled for each attempt after the first, capped, less up to a fifth at random so jobs
+// that failed together are not all retried together
+func backoff(policy Policy, attempts int) time.Duration {
+	wait := policy.BackoffMax
+	if attempts < 32 {
+		if doubled := policy.BackoffBase << (attempts - 1); doubled > 0 && doubled < wait {
+			wait = doubled
+		}
+	}
+	return wait - time.Duration(rand.Int63n(int64(wait)/5+1))
+}
+
+// List gets the service's jobs in a status, optionally of one kind, oldest first, along
+// with how many there are in total
+func (q *Queue) List(ctx context.Context, status Status, kindName string, page, limit int) ([]*Job, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	conditions := []string{"service = $1", "status = $2"}
+	args := []interface{}{q.service, status}
+	if kindName != "" {
+		args = append(args, kindName)
+		conditions = append(conditions, fmt.Sprintf("kind = $%d", len(args)))
+	}
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := q.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM jobs%s
+		ORDER BY created_at
+		LIMIT $%d OFFSET $%d
+	`, jobColumns, where, len(args)+1, len(args)+2)
+	args = append(args, limit, (page-1)*limit)
+
+	jobs, err := q.query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// Counts reports how many of the service's jobs of each kind are in each status
+func (q *Queue) Counts(ctx context.Context) ([]KindCount, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT kind, status, COUNT(*)
+		FROM jobs
+		WHERE service = $1
+		GROUP BY kind, status
+		ORDER BY kind, status
+	`, q.service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []KindCount{}
+	for rows.Next() {
+		var count KindCount
+		if err := rows.Scan(&count.Kind, &count.Status, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan job count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating job counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// Requeue gives a dead job a fresh set of attempts, starting now
+func (q *Queue) Requeue(ctx context.Context, jobID string) error {
+	now := time.Now().UTC()
+	tag, err := q.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = $3, attempts = 0, next_attempt_at = $5, updated_at = $5
+		WHERE id = $1 AND service = $2 AND status = $4
+	`, jobID, q.service, StatusPending, StatusDead, now)
+	if err != nil {
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// RequeueAll gives every dead job, optionally of one kind, a fresh set of attempts and
+// reports how many it requeued
+func (q *Queue) RequeueAll(ctx context.Context, kindName string) (int64, error) {
+	now := time.Now().UTC()
+	tag, err := q.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = $2, attempts = 0, next_attempt_at = $4, updated_at = $4
+		WHERE service = $1 AND status = $3 AND ($5 = '' OR kind = $5)
+	`, q.service, StatusPending, StatusDead, now, kindName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue jobs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// jobColumns are the jobs columns scanned by query, in order
+const jobColumns = `id, service, kind, payload, status, attempts, next_attempt_at, last_error, created_at, updated_at`
+
+// prefixedJobColumns are jobColumns qualified for queries that join jobs
+const prefixedJobColumns = `j.id, j.service, j.kind, j.payload, j.status, j.attempts, j.next_attempt_at, j.last_error, j.created_at, j.updated_at`
+
+// query runs a query selecting jobColumns
+func (q *Queue) query(ctx context.Context, query string, args ...interface{}) ([]*Job, error) {
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		job := &Job{}
+		var payload []byte
+		err := rows.Scan(
+			&job.ID,
+			&job.Service,
+			&job.Kind,
+			&payload,
+			&job.Status,
+			&job.Attempts,
+			&job.NextAttemptAt,
+			&job.LastError,
+			&job.CreatedAt,
+			&job.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		job.Payload = payload
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating jobs: %w", err)
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,123 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "github.com/order-api-microservices/proto/jobs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server serves the admin view of a service's jobs over gRPC
+type Server struct {
+	pb.UnimplementedJobServiceServer
+	queue *Queue
+}
+
+// NewServer creates a new job admin server
+func NewServer(queue *Queue) *Server {
+	return &Server{
+		queue: queue,
+	}
+}
+
+// ListJobs lists jobs in a status, dead ones by default, oldest first
+func (s *Server) ListJobs(ctx context.Context, req *pb.ListJobsRequest) (*pb.ListJobsResponse, error) {
+	jobStatus := StatusDead
+	if req.Status != "" {
+		jobStatus = Status(req.Status)
+	}
+
+	page, limit := int(req.Page), int(req.Limit)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	jobs, total, err := s.queue.List(ctx, jobStatus, req.Kind, page, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list jobs: %v", err)
+	}
+
+	protoJobs := make([]*pb.Job, 0, len(jobs))
+	for _, job := range jobs {
+		protoJobs = append(protoJobs, convertJobToProto(job))
+	}
+
+	return &pb.ListJobsResponse{
+		Jobs:  protoJobs,
+		Total: int32(total),
+		Page:  int32(page),
+		Limit: int32(limit),
+	}, nil
+}
+
+// GetJobCounts reports how many jobs of each kind are in each status
+func (s *Server) GetJobCounts(ctx context.Context, req *pb.GetJobCountsRequest) (*pb.GetJobCountsResponse, error) {
+	counts, err := s.queue.Counts(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to count jobs: %v", err)
+	}
+
+	protoCounts := make([]*pb.JobCount, 0, len(counts))
+	for _, count := range counts {
+		protoCounts = append(protoCounts, &pb.JobCount{
+			Kind:   count.Kind,
+			Status: string(count.Status),
+			Count:  int32(count.Count),
+		})
+	}
+
+	return &pb.GetJobCountsResponse{
+		Counts: protoCounts,
+	}, nil
+}
+
+// RequeueJob gives a dead job a fresh set of attempts
+func (s *Server) RequeueJob(ctx context.Context, req *pb.RequeueJobRequest) (*pb.RequeueJobResponse, error) {
+	if err := s.queue.Requeue(ctx, req.JobId); err != nil {
+		if errors.Is(err, ErrJobNotFound) {
+			return nil, status.Errorf(codes.NotFound, "no dead job %s", req.JobId)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to requeue job: %v", err)
+	}
+
+	return &pb.RequeueJobResponse{
+		Success: true,
+		Message: "Job requeued",
+	}, nil
+}
+
+// RequeueDeadJobs gives every dead job, optionally of one kind, a fresh set of attempts
+func (s *Server) RequeueDeadJobs(ctx context.Context, req *pb.RequeueDeadJobsRequest) (*pb.RequeueDeadJobsResponse, error) {
+	requeued, err := s.queue.RequeueAll(ctx, req.Kind)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to requeue jobs: %v", err)
+	}
+
+	return &pb.RequeueDeadJobsResponse{
+		Requeued: requeued,
+		Success:  true,
+		Message:  fmt.Sprintf("Requeued %d jobs", requeued),
+	}, nil
+}
+
+// convertJobToProto converts a job to its protobuf representation
+func convertJobToProto(job *Job) *pb.Job {
+	return &pb.Job{
+		Id:            job.ID,
+		Kind:          job.Kind,
+		Payload:       string(job.Payload),
+		Status:        string(job.Status),
+		Attempts:      int32(job.Attempts),
+		NextAttemptAt: timestamppb.New(job.NextAttemptAt),
+		LastError:     job.LastError,
+		CreatedAt:     timestamppb.New(job.CreatedAt),
+		UpdatedAt:     timestamppb.New(job.UpdatedAt),
+	}
+}
@@ -0,0 +1,231 @@
+// Package lock elects one instance of a service to run each of its schedulers, so
+// replicas do not all charge the same split payment or alert on the same order. An
+// election is a Postgres advisory lock: the instance holding it runs the scheduler, and
+// the others wait to take over when it stops or its connection is lost. Each instance
+// holds all of its locks on one session opened outside the connection pool, so electing
+// does not take connections from queries.
+package lock
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var leaderGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "scheduler_leader",
+	Help: "Whether this instance holds a scheduler's lock and runs it (1) or not (0)",
+}, []string{"service", "scheduler"})
+
+// Elector elects the instance of a service that runs each scheduler
+type Elector struct {
+	db      *database.PostgresDB
+	service string
+	retry   time.Duration
+
+	// mu guards the session, whose connection runs one query at a time, and held
+	mu      sync.Mutex
+	session *session
+	held    map[string]bool
+}
+
+// session is the connection an instance holds its locks on. lost is closed when the
+// connection fails, since every lock held on it goes with it.
+type session struct {
+	conn *pgx.Conn
+	lost chan struct{}
+}
+
+// NewElector creates an elector for a service's schedulers. Instances that do not hold a
+// scheduler's lock try to take it every retry, and the one that holds it checks its
+// connection as often.
+func NewElector(db *database.PostgresDB, service string, retry time.Duration) *Elector {
+	return &Elector{
+		db:      db,
+		service: service,
+		retry:   retry,
+		held:    make(map[string]bool),
+	}
+}
+
+// Run runs run whenever this instance holds the lock of the scheduler named name, until
+// ctx is cancelled. run is given a context cancelled when the lock is lost, and must
+// return once it is. A lock whose connection fails can be taken by another instance up to
+// one retry before run is stopped, so schedulers must still tolerate a rare overlap.
+func (e *Elector) Run(ctx context.Context, name string, run func(ctx context.Context)) {
+	for {
+		err := e.hold(ctx, name, run)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Lost the %s scheduler lock, retrying in %s: %v", name, e.retry, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.retry):
+		}
+	}
+}
+
+// Close closes the session, releasing every lock this instance holds
+func (e *Elector) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.session != nil {
+		e.drop(e.session)
+	}
+}
+
+// hold takes the scheduler's lock if it is free and runs run until the lock is lost or
+// run returns. It returns nil at once when another instance holds the lock.
+func (e *Elector) hold(ctx context.Context, name string, run func(ctx context.Context)) error {
+	s, acquired, err := e.tryLock(ctx, name)
+	if err != nil || !acquired {
+		return err
+	}
+	defer e.unlock(s, name)
+
+	log.Printf("Elected to run the %s scheduler", name)
+	leader := leaderGauge.WithLabelValues(e.service, name)
+	leader.Set(1)
+	defer leader.Set(0)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(runCtx)
+	}()
+	// Stop run before the deferred unlock gives the lock up
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	return e.watch(ctx, s, done)
+}
+
+// tryLock takes the scheduler's lock on the session, opening one if there is none. A
+// session's locks are reentrant, so a lock this instance already holds is not taken twice.
+func (e *Elector) tryLock(ctx context.Context, name string) (*session, bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.held[name] {
+		return nil, false, nil
+	}
+	s, err := e.connect(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	if err := s.conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key(e.service, name)).Scan(&acquired); err != nil {
+		e.drop(s)
+		return nil, false, fmt.Errorf("failed to take lock: %w", err)
+	}
+	if acquired {
+		e.held[name] = true
+	}
+	return s, acquired, nil
+}
+
+// unlock gives up the scheduler's lock. A lock on a lost session is already gone.
+func (e *Elector) unlock(s *session, name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.held, name)
+	if e.session != s {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.retry)
+	defer cancel()
+	if _, err := s.conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, key(e.service, name)); err != nil {
+		log.Printf("Failed to release the %s scheduler lock: %v", name, err)
+		e.drop(s)
+	}
+}
+
+// connect returns the session, opening a connection outside the pool when there is none.
+// It must be called with mu held.
+func (e *Elector) connect(ctx context.Context) (*session, error) {
+	if e.session != nil {
+		return e.session, nil
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, e.db.Pool().Config().ConnConfig.Copy())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock session: %w", err)
+	}
+	e.session = &session{conn: conn, lost: make(chan struct{})}
+	return e.session, nil
+}
+
+// drop closes a session whose connection failed, telling every holder its lock is lost.
+// It must be called with mu held.
+func (e *Elector) drop(s *session) {
+	if e.session != s {
+		return
+	}
+	e.session = nil
+	close(s.lost)
+	s.conn.Close(context.Background())
+}
+
+// watch checks every retry that the session holding a lock is alive, until run is done or
+// the session is lost
+func (e *Elector) watch(ctx context.Context, s *session, done <-chan struct{}) error {
+	ticker := time.NewTicker(e.retry)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-s.lost:
+			return fmt.Errorf("lock session lost")
+		case <-ticker.C:
+			if err := e.ping(ctx, s); err != nil && ctx.Err() == nil {
+				return fmt.Errorf("lock connection failed: %w", err)
+			}
+		}
+	}
+}
+
+// ping checks the session's connection, dropping it if it has failed
+func (e *Elector) ping(ctx context.Context, s *session) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.session != s {
+		return fmt.Errorf("lock session lost")
+	}
+	if err := s.conn.Ping(ctx); err != nil {
+		if ctx.Err() == nil {
+			e.drop(s)
+		}
+		return err
+	}
+	return nil
+}
+
+// key derives the advisory lock key of a service's scheduler from its name
+func key(service, name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(service + ":" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,143 @@
+//go:build integration
+
+package lock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/order-api-microservices/internal/testharness"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/pkg/lock"
+)
+
+const retry = 100 * time.Millisecond
+
+// thisDatabase limits pg_locks to the advisory locks of the test's own database
+const thisDatabase = `WHERE locktype = 'advisory' AND database = (SELECT oid FROM pg_database WHERE datname = current_database())`
+
+// scheduler records when an instance starts and stops running it
+type scheduler struct {
+	started chan string
+	stopped chan string
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{started: make(chan string, 8), stopped: make(chan string, 8)}
+}
+
+func (s *scheduler) run(instance string) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		s.started <- instance
+		<-ctx.Done()
+		s.stopped <- instance
+	}
+}
+
+func receive(t *testing.T, ch <-chan string, what string) string {
+	t.Helper()
+	select {
+	case instance := <-ch:
+		return instance
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		return ""
+	}
+}
+
+func TestElectorFailsOverWhenSessionIsLost(t *testing.T) {
+	db := testharness.Postgres(t).Database(t, "order")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sched := newScheduler()
+	first := lock.NewElector(db, "test", retry)
+	defer first.Close()
+	go first.Run(ctx, "sweeper", sched.run("first"))
+	if got := receive(t, sched.started, "the first instance to be elected"); got != "first" {
+		t.Fatalf("%s was elected, want first", got)
+	}
+
+	second := lock.NewElector(db, "test", retry)
+	defer second.Close()
+	go second.Run(ctx, "sweeper", sched.run("second"))
+	select {
+	case instance := <-sched.started:
+		t.Fatalf("%s was elected while first held the lock", instance)
+	case <-time.After(3 * retry):
+	}
+
+	// Kill the first instance's session as if its connection dropped
+	if _, err := db.ExecContext(ctx, `SELECT pg_terminate_backend(pid) FROM pg_locks `+thisDatabase); err != nil {
+		t.Fatalf("failed to terminate lock session: %v", err)
+	}
+
+	if got := receive(t, sched.stopped, "the first instance to stop"); got != "first" {
+		t.Fatalf("%s stopped, want first", got)
+	}
+	if got := receive(t, sched.started, "the second instance to take over"); got != "second" {
+		t.Fatalf("%s took over, want second", got)
+	}
+}
+
+func TestElectorReleasesLockWhenStopped(t *testing.T) {
+	db := testharness.Postgres(t).Database(t, "order")
+
+	sched := newScheduler()
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	first := lock.NewElector(db, "test", retry)
+	defer first.Close()
+	go first.Run(firstCtx, "sweeper", sched.run("first"))
+	receive(t, sched.started, "the first instance to be elected")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	second := lock.NewElector(db, "test", retry)
+	defer second.Close()
+	go second.Run(ctx, "sweeper", sched.run("second"))
+
+	stopFirst()
+	if got := receive(t, sched.stopped, "the first instance to stop"); got != "first" {
+		t.Fatalf("%s stopped, want first", got)
+	}
+	if got := receive(t, sched.started, "the second instance to take over"); got != "second" {
+		t.Fatalf("%s took over, want second", got)
+	}
+}
+
+func TestElectorHoldsLocksOnOneSession(t *testing.T) {
+	db := testharness.Postgres(t).Database(t, "order")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sched := newScheduler()
+	elector := lock.NewElector(db, "test", retry)
+	defer elector.Close()
+	names := []string{"split-payments", "crypto-payments", "sweeper"}
+	for _, name := range names {
+		go elector.Run(ctx, name, sched.run(name))
+	}
+	for range names {
+		receive(t, sched.started, "a scheduler to be elected")
+	}
+
+	locks, sessions := advisoryLocks(t, db)
+	if locks != len(names) || sessions != 1 {
+		t.Errorf("got %d locks on %d sessions, want %d on 1", locks, sessions, len(names))
+	}
+	if acquired := db.Pool().Stat().AcquiredConns(); acquired != 0 {
+		t.Errorf("elector holds %d pooled connections, want 0", acquired)
+	}
+}
+
+func advisoryLocks(t *testing.T, db *database.PostgresDB) (locks, sessions int) {
+	t.Helper()
+	err := db.QueryRowContext(context.Background(),
+		`SELECT count(*), count(DISTINCT pid) FROM pg_locks `+thisDatabase+` AND granted`,
+	).Scan(&locks, &sessions)
+	if err != nil {
+		t.Fatalf("failed to count advisory locks: %v", err)
+	}
+	return locks, sessions
+}
@@ -0,0 +1,26 @@
+// Package money handles amounts in minor currency units (cents), so prices, fees
+// and order hashes never drift through floating-point rounding
+package money
+
+import (
+	"fmt"
+	"math"
+)
+
+// MinorPerUnit is the number of minor units in one currency unit
+const MinorPerUnit = 100
+
+// Percent returns percent of amount, rounded half away from zero to the nearest minor unit
+func Percent(amount int64, percent float64) int64 {
+	return int64(math.Round(float64(amount) * percent / 100))
+}
+
+// Format renders an amount as a decimal string in currency units, e.g. 1234 as "12.34"
+func Format(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/MinorPerUnit, amount%MinorPerUnit)
+}
@@ -0,0 +1,55 @@
+package money
+
+import "testing"
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		amount  int64
+		percent float64
+		want    int64
+	}{
+		{amount: 10000, percent: 10, want: 1000},
+		{amount: 1999, percent: 15, want: 300}, // 299.85
+		{amount: 1005, percent: 10, want: 101}, // 100.5 rounds away from zero
+		{amount: 10, percent: 5, want: 1},      // 0.5
+		{amount: -10, percent: 5, want: -1},    // -0.5
+		{amount: 1234, percent: 0, want: 0},
+		{amount: 1234, percent: 100, want: 1234},
+		{amount: 333, percent: 33.3, want: 111}, // 110.889
+		{amount: 0, percent: 20, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := Percent(tt.amount, tt.percent); got != tt.want {
+			t.Errorf("Percent(%d, %v) = %d, want %d", tt.amount, tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestPercentOfLargeAmountsStaysExact(t *testing.T) {
+	// 1,000,000.00 at 2.5% is exactly 25,000.00
+	if got := Percent(100_000_000, 2.5); got != 2_500_000 {
+		t.Errorf("Percent(100000000, 2.5) = %d, want 2500000", got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{amount: 0, want: "0.00"},
+		{amount: 5, want: "0.05"},
+		{amount: 50, want: "0.50"},
+		{amount: 1234, want: "12.34"},
+		{amount: 100000, want: "1000.00"},
+		{amount: -5, want: "-0.05"},
+		{amount: -1234, want: "-12.34"},
+	}
+
+	for _, tt := range tests {
+		if got := Format(tt.amount); got != tt.want {
+			t.Errorf("Format(%d) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
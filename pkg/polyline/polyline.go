@@ -0,0 +1,96 @@
+// Package polyline encodes paths in the Encoded Polyline Algorithm Format used by map
+// clients, at five decimal places of precision
+package polyline
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// precision is the factor coordinates are scaled by before encoding
+const precision = 1e5
+
+// ErrInvalid is returned when an encoded polyline is malformed
+var ErrInvalid = errors.New("invalid encoded polyline")
+
+// Point is a coordinate on a path
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Encode encodes a path as a polyline
+func Encode(points []Point) string {
+	var b strings.Builder
+	var prevLat, prevLng int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Latitude * precision))
+		lng := int64(math.Round(p.Longitude * precision))
+		encodeValue(&b, lat-prevLat)
+		encodeValue(&b, lng-prevLng)
+		prevLat, prevLng = lat, lng
+	}
+	return b.String()
+}
+
+// Decode decodes a polyline into its path
+func Decode(encoded string) ([]Point, error) {
+	points := []Point{}
+	var lat, lng int64
+	for i := 0; i < len(encoded); {
+		dLat, n, err := decodeValue(encoded[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+
+		dLng, n, err := decodeValue(encoded[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+
+		lat += dLat
+		lng += dLng
+		points = append(points, Point{
+			Latitude:  float64(lat) / precision,
+			Longitude: float64(lng) / precision,
+		})
+	}
+	return points, nil
+}
+
+// encodeValue writes one signed delta as 5-bit chunks, least significant first
+func encodeValue(b *strings.Builder, value int64) {
+	v := value << 1
+	if value < 0 {
+		v = ^v
+	}
+	for v >= 0x20 {
+		b.WriteByte(byte((0x20 | (v & 0x1f)) + 63))
+		v >>= 5
+	}
+	b.WriteByte(byte(v + 63))
+}
+
+// decodeValue reads one signed delta and reports how many bytes it used
+func decodeValue(s string) (int64, int, error) {
+	var result int64
+	var shift uint
+	for i := 0; i < len(s); i++ {
+		c := int64(s[i]) - 63
+		if c < 0 || c > 0x3f || shift > 60 {
+			return 0, 0, ErrInvalid
+		}
+		result |= (c & 0x1f) << shift
+		shift += 5
+		if c < 0x20 {
+			if result&1 != 0 {
+				return ^(result >> 1), i + 1, nil
+			}
+			return result >> 1, i + 1, nil
+		}
+	}
+	return 0, 0, ErrInvalid
+}
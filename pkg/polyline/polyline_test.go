@@ -0,0 +1,94 @@
+package polyline
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// The example from the Encoded Polyline Algorithm Format documentation
+var (
+	examplePath = []Point{
+		{Latitude: 38.5, Longitude: -120.2},
+		{Latitude: 40.7, Longitude: -120.95},
+		{Latitude: 43.252, Longitude: -126.453},
+	}
+	exampleEncoded = "_p~iF~ps|U_ulLnnqC_mqNvxq`@"
+)
+
+func TestEncode(t *testing.T) {
+	if got := Encode(examplePath); got != exampleEncoded {
+		t.Errorf("Encode = %q, want %q", got, exampleEncoded)
+	}
+	if got := Encode(nil); got != "" {
+		t.Errorf("Encode(nil) = %q, want empty", got)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	points, err := Decode(exampleEncoded)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(points) != len(examplePath) {
+		t.Fatalf("got %d points, want %d", len(points), len(examplePath))
+	}
+	for i, p := range points {
+		if p != examplePath[i] {
+			t.Errorf("point %d = %v, want %v", i, p, examplePath[i])
+		}
+	}
+
+	empty, err := Decode("")
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Decode(\"\") = %v, %v; want no points", empty, err)
+	}
+}
+
+func TestRoundTripRoundsToFiveDecimals(t *testing.T) {
+	path := []Point{
+		{Latitude: -6.2087634, Longitude: 106.845599},
+		{Latitude: -6.2087634, Longitude: 106.845599},
+		{Latitude: 0, Longitude: 0},
+		{Latitude: 89.999999, Longitude: -179.999999},
+		{Latitude: -90, Longitude: 180},
+	}
+
+	points, err := Decode(Encode(path))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(points) != len(path) {
+		t.Fatalf("got %d points, want %d", len(points), len(path))
+	}
+	for i, p := range points {
+		want := Point{
+			Latitude:  math.Round(path[i].Latitude*precision) / precision,
+			Longitude: math.Round(path[i].Longitude*precision) / precision,
+		}
+		if math.Abs(p.Latitude-want.Latitude) > 1e-9 || math.Abs(p.Longitude-want.Longitude) > 1e-9 {
+			t.Errorf("point %d = %v, want %v", i, p, want)
+		}
+	}
+}
+
+func TestDecodeRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "truncated value", encoded: "_p~iF~ps|"},
+		{name: "latitude without longitude", encoded: "_p~iF"},
+		{name: "character below range", encoded: "_p~iF~ps| "},
+		{name: "character above range", encoded: "\x7f?"},
+		{name: "value too long", encoded: "______________?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.encoded); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Decode(%q) error = %v, want ErrInvalid", tt.encoded, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Package requestid gives every request entering the platform an ID that follows it to
+// each service it reaches, so one failure can be traced through the gateway, order,
+// provider and blockchain logs. The gateway assigns the ID, or keeps a valid one sent
+// by the client; it travels between services in gRPC metadata.
+package requestid
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Header is the HTTP header carrying a request's ID, in requests and responses
+const Header = "X-Request-ID"
+
+// MetadataKey is the gRPC metadata key carrying a request's ID
+const MetadataKey = "x-request-id"
+
+// maxLength caps the length of an ID sent by a client
+const maxLength = 128
+
+type contextKey struct{}
+
+// New returns a new request ID
+func New() string {
+	return uuid.New().String()
+}
+
+// Valid reports whether an ID sent by a client can be kept: up to 128 printable ASCII
+// characters, without spaces
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns ctx carrying a request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID ctx carries, or "" when it carries none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// FromIncoming returns the request ID in a call's incoming metadata, or a new one when
+// the caller sent none or an invalid one
+func FromIncoming(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, MetadataKey); len(values) > 0 && Valid(values[0]) {
+		return values[0]
+	}
+	return New()
+}
+
+// Logf logs a message prefixed with the request ID ctx carries, if any
+func Logf(ctx context.Context, format string, args ...interface{}) {
+	if id := FromContext(ctx); id != "" {
+		log.Printf("[%s] %s", id, fmt.Sprintf(format, args...))
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// outgoing adds the request ID ctx carries to the metadata of an outgoing call
+func outgoing(ctx context.Context) context.Context {
+	id := FromContext(ctx)
+	if id == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(MetadataKey)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, id)
+}
+
+// UnaryClientInterceptor sends the request ID of a call's context with the call
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor sends the request ID of a stream's context when opening it
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+// DialOption returns a dial option that forwards request IDs on unary calls
+func DialOption() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(UnaryClientInterceptor())
+}
+
+// StreamDialOption returns a dial option that forwards request IDs on streams
+func StreamDialOption() grpc.DialOption {
+	return grpc.WithChainStreamInterceptor(StreamClientInterceptor())
+}
@@ -9,12 +9,16 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/order-api-microservices/pkg/anchor"
 	"github.com/order-api-microservices/pkg/blockchain"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/pkg/grpcserver"
+	"github.com/order-api-microservices/services/blockchain/internal/repository"
 	"github.com/order-api-microservices/services/blockchain/internal/service"
 	pb "github.com/order-api-microservices/proto/blockchain"
 	"github.com/spf13/viper"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -23,7 +27,6 @@ var (
 	configFile   = flag.String("config", "config.yaml", "Configuration file path")
 	contractAddr = flag.String("contract", "", "Ethereum contract address")
 	ethEndpoint  = flag.String("eth-endpoint", "http://localhost:8545", "Ethereum node endpoint")
-	privateKey   = flag.String("key", "", "Private key for Ethereum transactions")
 )
 
 func main() {
@@ -43,24 +46,121 @@ func main() {
 		ethRpcUrl = *ethEndpoint
 	}
 	
-	privKey := viper.GetString("ethereum.private_key")
-	if *privateKey != "" {
-		privKey = *privateKey
+	// Create the transaction signer
+	var signerConfig blockchain.SignerConfig
+	if err := viper.UnmarshalKey("signer", &signerConfig); err != nil {
+		log.Fatalf("Failed to parse signer configuration: %v", err)
 	}
+	applyDevSignerDefaults(&signerConfig)
 
-	// For development, use a default private key if none is provided
-	if privKey == "" {
-		privKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80" // Default Ganache account
-		log.Println("Warning: Using default private key for development. DO NOT use in production!")
+	signerCtx, signerCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	txSigner, err := blockchain.NewSigner(signerCtx, signerConfig)
+	signerCancel()
+	if err != nil {
+		log.Fatalf("Failed to create transaction signer: %v", err)
+	}
+	log.Printf("Using %s signer for account %s", signerConfig.Type, txSigner.Address().Hex())
+
+	// Load the configured chains; fall back to a single chain built from the legacy settings
+	var chains []blockchain.ChainConfig
+	if err := viper.UnmarshalKey("chains", &chains); err != nil {
+		log.Fatalf("Failed to parse chain configuration: %v", err)
+	}
+	if len(chains) == 0 {
+		chains = []blockchain.ChainConfig{
+			{
+				Name:            viper.GetString("ethereum.chain"),
+				RPCURL:          ethRpcUrl,
+				ChainID:         viper.GetInt64("ethereum.chain_id"),
+				ContractAddress: contractAddress,
+			},
+		}
 	}
 
-	ethClient, err := blockchain.NewEthereumClient(ethRpcUrl, contractAddress, privKey)
+	// Chains without their own gas settings use the service-wide gas configuration
+	defaultGas := blockchain.DefaultGasConfig()
+	if err := viper.UnmarshalKey("gas", &defaultGas); err != nil {
+		log.Fatalf("Failed to parse gas configuration: %v", err)
+	}
+	for i := range chains {
+		if chains[i].Gas == (blockchain.GasConfig{}) {
+			chains[i].Gas = defaultGas
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	chainRegistry, err := blockchain.NewChainRegistry(ctx, chains, viper.GetString("default_chain"), txSigner)
+	cancel()
 	if err != nil {
-		log.Fatalf("Failed to create Ethereum client: %v", err)
+		log.Fatalf("Failed to create blockchain clients: %v", err)
+	}
+	log.Printf("Configured chains: %v (default: %s)", chainRegistry.Chains(), chainRegistry.DefaultChain())
+
+	// Create the off-chain payload store, if anchoring is enabled
+	var anchorConfig anchor.Config
+	if err := viper.UnmarshalKey("anchoring", &anchorConfig); err != nil {
+		log.Fatalf("Failed to parse anchoring configuration: %v", err)
+	}
+	payloadStore, err := anchor.NewPayloadStore(context.Background(), anchorConfig)
+	if err != nil {
+		log.Fatalf("Failed to create payload store: %v", err)
+	}
+	if payloadStore != nil {
+		log.Printf("Off-chain payload anchoring enabled (%s)", anchorConfig.Backend)
+	}
+
+	// Batch anchoring and crypto payments keep their state in the database
+	var batchConfig service.BatchConfig
+	if err := viper.UnmarshalKey("batching", &batchConfig); err != nil {
+		log.Fatalf("Failed to parse batching configuration: %v", err)
+	}
+	var paymentConfig service.CryptoPaymentConfig
+	if err := viper.UnmarshalKey("crypto_payments", &paymentConfig); err != nil {
+		log.Fatalf("Failed to parse crypto payment configuration: %v", err)
+	}
+
+	var db *database.PostgresDB
+	if batchConfig.Enabled || paymentConfig.Enabled {
+		dbConfig := database.NewPostgresConfig(
+			viper.GetString("database.host"),
+			viper.GetInt("database.port"),
+			viper.GetString("database.user"),
+			viper.GetString("database.password"),
+			viper.GetString("database.name"),
+			viper.GetString("database.sslmode"),
+		)
+		db, err = database.NewPostgresDB(dbConfig)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+	}
+
+	// Create the batcher, if batch anchoring is enabled
+	var batcher *service.Batcher
+	batchCtx, batchCancel := context.WithCancel(context.Background())
+	defer batchCancel()
+	if batchConfig.Enabled {
+		batcher = service.NewBatcher(chainRegistry, repository.NewBatchRepository(db), batchConfig)
+		go batcher.Run(batchCtx)
+		log.Printf("Batch anchoring enabled (interval %s)", batchConfig.Interval)
+	}
+
+	// Create the crypto payment watcher, if crypto payments are enabled
+	var paymentWatcher *service.CryptoPaymentWatcher
+	paymentCtx, paymentCancel := context.WithCancel(context.Background())
+	defer paymentCancel()
+	if paymentConfig.Enabled {
+		paymentWatcher, err = service.NewCryptoPaymentWatcher(chainRegistry, repository.NewCryptoPaymentRepository(db), paymentConfig)
+		if err != nil {
+			log.Fatalf("Failed to create crypto payment watcher: %v", err)
+		}
+		go paymentWatcher.Run(paymentCtx)
+		log.Printf("Crypto payments enabled (%d confirmations)", paymentConfig.Confirmations)
 	}
 
 	// Create the service
-	blockchainService := service.NewBlockchainService(ethClient)
+	blockchainService := service.NewBlockchainService(chainRegistry, payloadStore, batcher, paymentWatcher)
 
 	// Create gRPC server
 	serverPort := viper.GetInt("server.port")
@@ -73,7 +173,11 @@ func main() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpcserver.New(grpcserver.Config{
+		AuthToken:      viper.GetString("grpc.auth_token"),
+		DefaultTimeout: viper.GetDuration("grpc.default_timeout"),
+		MaxTimeout:     viper.GetDuration("grpc.max_timeout"),
+	})
 	pb.RegisterBlockchainServiceServer(grpcServer, blockchainService)
 	
 	// Register reflection service for development
@@ -94,13 +198,61 @@ func main() {
 	<-c
 	log.Println("Shutting down blockchain service...")
 	grpcServer.GracefulStop()
+	paymentCancel()
+
+	// Commit whatever is still pending before exiting
+	if batcher != nil {
+		batchCancel()
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		if err := batcher.Flush(flushCtx); err != nil {
+			log.Printf("Failed to commit final anchor batch: %v", err)
+		}
+		flushCancel()
+	}
 }
 
 func initConfig() {
 	viper.SetDefault("server.port", 50053)
 	viper.SetDefault("ethereum.rpc_url", "http://localhost:8545")
 	viper.SetDefault("ethereum.contract_address", "")
-	viper.SetDefault("ethereum.private_key", "")
+	viper.SetDefault("signer.type", "")
+	viper.SetDefault("signer.vault_field", "private_key")
+	viper.SetDefault("ethereum.chain", "ganache")
+	viper.SetDefault("ethereum.chain_id", 1337)
+	viper.SetDefault("default_chain", "")
+	viper.SetDefault("anchoring.backend", "")
+	viper.SetDefault("anchoring.ipfs_api_url", "http://localhost:5001")
+	viper.SetDefault("gas.strategy", "normal")
+	viper.SetDefault("gas.max_fee_per_gas_gwei", 200)
+	viper.SetDefault("gas.max_priority_fee_per_gas_gwei", 0)
+	viper.SetDefault("gas.gas_limit", 300000)
+	viper.SetDefault("batching.enabled", false)
+	viper.SetDefault("batching.interval", "1m")
+	viper.SetDefault("batching.max_leaves", 1000)
+	viper.SetDefault("crypto_payments.enabled", false)
+	viper.SetDefault("crypto_payments.confirmations", 12)
+	viper.SetDefault("crypto_payments.interval", "15s")
+	viper.SetDefault("crypto_payments.expiry", "30m")
+	viper.SetDefault("database.host", "localhost")
+	viper.SetDefault("database.port", 5432)
+	viper.SetDefault("database.user", "postgres")
+	viper.SetDefault("database.password", "postgres")
+	viper.SetDefault("database.name", "blockchain_service")
+	viper.SetDefault("database.sslmode", "disable")
+	viper.SetDefault("grpc.auth_token", "")
+	viper.SetDefault("grpc.default_timeout", "30s")
+	viper.SetDefault("grpc.max_timeout", "2m")
+
+	// Database settings follow the same environment variables as the other services
+	viper.BindEnv("database.host", "DB_HOST")
+	viper.BindEnv("database.port", "DB_PORT")
+	viper.BindEnv("database.user", "DB_USER")
+	viper.BindEnv("database.password", "DB_PASSWORD")
+	viper.BindEnv("database.name", "DB_NAME")
+	viper.BindEnv("database.sslmode", "DB_SSLMODE")
+	viper.BindEnv("grpc.auth_token", "GRPC_AUTH_TOKEN")
+	viper.BindEnv("grpc.default_timeout", "GRPC_DEFAULT_TIMEOUT")
+	viper.BindEnv("grpc.max_timeout", "GRPC_MAX_TIMEOUT")
 
 	viper.SetConfigFile(*configFile)
 	viper.AutomaticEnv()
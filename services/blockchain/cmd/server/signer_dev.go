@@ -0,0 +1,23 @@
+//go:build dev
+
+package main
+
+import (
+	"log"
+
+	"github.com/order-api-microservices/pkg/blockchain"
+)
+
+// defaultDevPrivateKey is the first deterministic Ganache account
+const defaultDevPrivateKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+// applyDevSignerDefaults allows raw private keys in development builds and falls back
+// to the default Ganache account when no signer is configured
+func applyDevSignerDefaults(config *blockchain.SignerConfig) {
+	config.AllowRawPrivateKey = true
+	if config.Type == "" {
+		config.Type = blockchain.SignerTypePrivateKey
+		config.PrivateKey = defaultDevPrivateKey
+		log.Println("Warning: Using default private key for development. DO NOT use in production!")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !dev
+
+package main
+
+import "github.com/order-api-microservices/pkg/blockchain"
+
+// applyDevSignerDefaults is a no-op in production builds: raw private keys are rejected
+// and a keystore, Vault or KMS signer must be configured
+func applyDevSignerDefaults(config *blockchain.SignerConfig) {}
@@ -39,9 +39,33 @@ contract OrderRegistry {
     // Maps order IDs to their history of updates
     mapping(string => OrderRecord[]) public orderHistory;
     
+    // Off-chain payload anchor (e.g. IPFS CID) for an order's latest snapshot
+    struct PayloadAnchor {
+        bytes32 payloadHash;
+        string payloadRef;
+        uint256 timestamp;
+        bool exists;
+    }
+    
+    // Maps order IDs to their latest payload anchor
+    mapping(string => PayloadAnchor) public payloadAnchors;
+    
+    // Merkle root of a batch of order hashes committed in a single transaction
+    struct BatchRecord {
+        uint256 leafCount;
+        uint256 timestamp;
+        bool exists;
+    }
+    
+    // Maps Merkle roots to their batch record
+    mapping(bytes32 => BatchRecord) public batches;
+    
     // Events
     event OrderRecorded(string indexed orderId, bytes32 dataHash, uint256 timestamp, OrderStatus status);
     event OrderUpdated(string indexed orderId, bytes32 dataHash, uint256 timestamp, OrderStatus status);
+    event PayloadAnchored(string indexed orderId, bytes32 payloadHash, string payloadRef, uint256 timestamp);
+    event BatchCommitted(bytes32 indexed root, uint256 leafCount, uint256 timestamp);
+    event OrderPaid(string indexed orderId, address indexed payer, uint256 amount, uint256 timestamp);
     
     // Modifiers
     modifier onlyOwner() {
@@ -113,6 +137,74 @@ contract OrderRegistry {
         return orders[orderId].dataHash == dataHash;
     }
     
+    // Anchor an off-chain payload snapshot for an existing order
+    function anchorPayload(string memory orderId, bytes32 payloadHash, string memory payloadRef) public {
+        require(orders[orderId].exists, "Order does not exist");
+        
+        payloadAnchors[orderId] = PayloadAnchor({
+            payloadHash: payloadHash,
+            payloadRef: payloadRef,
+            timestamp: block.timestamp,
+            exists: true
+        });
+        
+        emit PayloadAnchored(orderId, payloadHash, payloadRef, block.timestamp);
+    }
+    
+    // Get the latest payload anchor for an order
+    function getPayloadAnchor(string memory orderId) public view returns (bool exists, bytes32 payloadHash, string memory payloadRef, uint256 timestamp) {
+        PayloadAnchor memory anchor = payloadAnchors[orderId];
+        return (anchor.exists, anchor.payloadHash, anchor.payloadRef, anchor.timestamp);
+    }
+    
+    // Commit the Merkle root of a batch of order hashes
+    function commitBatch(bytes32 root, uint256 leafCount) public {
+        require(!batches[root].exists, "Batch already committed");
+        require(leafCount > 0, "Batch must not be empty");
+        
+        batches[root] = BatchRecord({
+            leafCount: leafCount,
+            timestamp: block.timestamp,
+            exists: true
+        });
+        
+        emit BatchCommitted(root, leafCount, block.timestamp);
+    }
+    
+    // Get a committed batch by its Merkle root
+    function getBatch(bytes32 root) public view returns (bool exists, uint256 leafCount, uint256 timestamp) {
+        BatchRecord memory batch = batches[root];
+        return (batch.exists, batch.leafCount, batch.timestamp);
+    }
+    
+    // Verify that a leaf is part of a committed batch using sorted-pair hashing
+    function verifyBatchProof(bytes32 root, bytes32 leaf, bytes32[] memory proof) public view returns (bool) {
+        require(batches[root].exists, "Batch does not exist");
+        
+        bytes32 computed = leaf;
+        for (uint256 i = 0; i < proof.length; i++) {
+            bytes32 sibling = proof[i];
+            if (computed <= sibling) {
+                computed = keccak256(abi.encodePacked(computed, sibling));
+            } else {
+                computed = keccak256(abi.encodePacked(sibling, computed));
+            }
+        }
+        return computed == root;
+    }
+    
+    // Pay for an order in the chain's native token; the off-chain watcher matches payments by order ID
+    function payOrder(string memory orderId) public payable {
+        require(msg.value > 0, "Payment must not be empty");
+        emit OrderPaid(orderId, msg.sender, msg.value, block.timestamp);
+    }
+    
+    // Withdraw collected order payments
+    function withdraw(address payable to, uint256 amount) public onlyOwner {
+        require(amount <= address(this).balance, "Insufficient balance");
+        to.transfer(amount);
+    }
+    
     // Administrative function to transfer ownership
     function transferOwnership(address newOwner) public onlyOwner {
         require(newOwner != address(0), "New owner cannot be the zero address");
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+)
+
+// BatchLeaf is an order hash queued for, or included in, a Merkle batch
+type BatchLeaf struct {
+	ID        int64
+	OrderID   string
+	Chain     string
+	LeafHash  [32]byte
+	BatchID   *int64
+	LeafIndex *int
+	CreatedAt time.Time
+}
+
+// Batch is a Merkle root committed on-chain
+type Batch struct {
+	ID              int64
+	Chain           string
+	MerkleRoot      [32]byte
+	LeafCount       int
+	TransactionHash string
+	CreatedAt       time.Time
+}
+
+// BatchRepository handles database operations for anchor batches
+type BatchRepository struct {
+	db *database.PostgresDB
+}
+
+// NewBatchRepository creates a new batch repository
+func NewBatchRepository(db *database.PostgresDB) *BatchRepository {
+	return &BatchRepository{
+		db: db,
+	}
+}
+
+// AddLeaf queues an order hash for the next batch on the given chain
+func (r *BatchRepository) AddLeaf(ctx context.Context, orderID, chain string, leafHash [32]byte) error {
+	query := `
+		INSERT INTO anchor_batch_leaves (order_id, chain, leaf_hash, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, orderID, chain, leafHash[:], time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to add batch leaf: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingLeaves returns the leaves not yet included in a batch, oldest first
+func (r *BatchRepository) GetPendingLeaves(ctx context.Context, chain string, limit int) ([]*BatchLeaf, error) {
+	query := `
+		SELECT id, order_id, chain, leaf_hash, created_at
+		FROM anchor_batch_leaves
+		WHERE chain = $1 AND batch_id IS NULL
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, chain, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending leaves: %w", err)
+	}
+	defer rows.Close()
+
+	var leaves []*BatchLeaf
+	for rows.Next() {
+		var leaf BatchLeaf
+		var leafHash []byte
+		if err := rows.Scan(&leaf.ID, &leaf.OrderID, &leaf.Chain, &leafHash, &leaf.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan batch leaf: %w", err)
+		}
+		copy(leaf.LeafHash[:], leafHash)
+		leaves = append(leaves, &leaf)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating batch leaves: %w", err)
+	}
+
+	return leaves, nil
+}
+
+// SaveBatch stores a committed batch and assigns each leaf its index in a single transaction
+func (r *BatchRepository) SaveBatch(ctx context.Context, batch *Batch, leaves []*BatchLeaf) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO anchor_batches (chain, merkle_root, leaf_count, transaction_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	err = tx.QueryRow(ctx, query, batch.Chain, batch.MerkleRoot[:], batch.LeafCount, batch.TransactionHash, batch.CreatedAt).Scan(&batch.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save batch: %w", err)
+	}
+
+	for i, leaf := range leaves {
+		_, err := tx.Exec(ctx, `UPDATE anchor_batch_leaves SET batch_id = $1, leaf_index = $2 WHERE id = $3`, batch.ID, i, leaf.ID)
+		if err != nil {
+			return fmt.Errorf("failed to assign batch leaf: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetLatestLeaf returns the most recent leaf for an order on the given chain
+func (r *BatchRepository) GetLatestLeaf(ctx context.Context, orderID, chain string) (*BatchLeaf, error) {
+	query := `
+		SELECT id, order_id, chain, leaf_hash, batch_id, leaf_index, created_at
+		FROM anchor_batch_leaves
+		WHERE order_id = $1 AND chain = $2
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var leaf BatchLeaf
+	var leafHash []byte
+	err := r.db.QueryRowContext(ctx, query, orderID, chain).Scan(
+		&leaf.ID, &leaf.OrderID, &leaf.Chain, &leafHash, &leaf.BatchID, &leaf.LeafIndex, &leaf.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLeafNotFound
+		}
+		return nil, fmt.Errorf("failed to get batch leaf: %w", err)
+	}
+	copy(leaf.LeafHash[:], leafHash)
+
+	return &leaf, nil
+}
+
+// GetBatch returns a batch along with its leaf hashes in index order
+func (r *BatchRepository) GetBatch(ctx context.Context, batchID int64) (*Batch, [][32]byte, error) {
+	var batch Batch
+	var root []byte
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, chain, merkle_root, leaf_count, transaction_hash, created_at
+		FROM anchor_batches
+		WHERE id = $1
+	`, batchID).Scan(&batch.ID, &batch.Chain, &root, &batch.LeafCount, &batch.TransactionHash, &batch.CreatedAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	copy(batch.MerkleRoot[:], root)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT leaf_hash
+		FROM anchor_batch_leaves
+		WHERE batch_id = $1
+		ORDER BY leaf_index
+	`, batchID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get batch leaves: %w", err)
+	}
+	defer rows.Close()
+
+	var leaves [][32]byte
+	for rows.Next() {
+		var leafHash []byte
+		if err := rows.Scan(&leafHash); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan batch leaf: %w", err)
+		}
+		var leaf [32]byte
+		copy(leaf[:], leafHash)
+		leaves = append(leaves, leaf)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating batch leaves: %w", err)
+	}
+
+	return &batch, leaves, nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+)
+
+// CryptoPaymentStatus is the state of a crypto payment request
+type CryptoPaymentStatus string
+
+const (
+	CryptoPaymentPending   CryptoPaymentStatus = "PENDING"   // Waiting for the full amount
+	CryptoPaymentDetected  CryptoPaymentStatus = "DETECTED"  // Full amount received, waiting for confirmations
+	CryptoPaymentConfirmed CryptoPaymentStatus = "CONFIRMED" // Full amount has enough confirmations
+	CryptoPaymentExpired   CryptoPaymentStatus = "EXPIRED"   // Full amount was not received in time
+)
+
+// CryptoPayment is a request to pay for an order in a chain's native token
+type CryptoPayment struct {
+	ID                    string
+	OrderID               string
+	Chain                 string
+	Amount                int64    // Order total in minor units of the order currency
+	AmountWei             *big.Int // Amount due in wei
+	ReceivedWei           *big.Int
+	StartBlock            uint64 // Payments are only looked for from this block on
+	Status                CryptoPaymentStatus
+	TransactionHash       string // Payment that completed the amount due
+	Confirmations         int
+	RequiredConfirmations int
+	ExpiresAt             time.Time
+	ConfirmedAt           *time.Time
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
+
+// CryptoPaymentRepository handles database operations for crypto payments
+type CryptoPaymentRepository struct {
+	db *database.PostgresDB
+}
+
+// NewCryptoPaymentRepository creates a new crypto payment repository
+func NewCryptoPaymentRepository(db *database.PostgresDB) *CryptoPaymentRepository {
+	return &CryptoPaymentRepository{
+		db: db,
+	}
+}
+
+// CreatePayment stores a payment request. An order has at most one request, so
+// creating it again is a no-op and the existing request is returned.
+func (r *CryptoPaymentRepository) CreatePayment(ctx context.Context, payment *CryptoPayment) (*CryptoPayment, error) {
+	query := `
+		INSERT INTO crypto_payments (
+			id, order_id, chain, amount, amount_wei, received_wei, start_block, status,
+			required_confirmations, expires_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5::numeric, 0, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (order_id) DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		payment.ID,
+		payment.OrderID,
+		payment.Chain,
+		payment.Amount,
+		payment.AmountWei.String(),
+		payment.StartBlock,
+		payment.Status,
+		payment.RequiredConfirmations,
+		payment.ExpiresAt,
+		payment.CreatedAt,
+		payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create crypto payment: %w", err)
+	}
+
+	return r.GetPaymentByOrder(ctx, payment.OrderID)
+}
+
+// GetPaymentByOrder returns the payment request of an order
+func (r *CryptoPaymentRepository) GetPaymentByOrder(ctx context.Context, orderID string) (*CryptoPayment, error) {
+	query := `
+		SELECT id, order_id, chain, amount, amount_wei::text, received_wei::text, start_block, status,
+			COALESCE(transaction_hash, ''), confirmations, required_confirmations, expires_at,
+			confirmed_at, created_at, updated_at
+		FROM crypto_payments
+		WHERE order_id = $1
+	`
+
+	payment, err := scanCryptoPayment(r.db.QueryRowContext(ctx, query, orderID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCryptoPaymentNotFound
+		}
+		return nil, fmt.Errorf("failed to get crypto payment: %w", err)
+	}
+
+	return payment, nil
+}
+
+// ListActivePayments returns the payment requests still being watched, oldest first
+func (r *CryptoPaymentRepository) ListActivePayments(ctx context.Context) ([]*CryptoPayment, error) {
+	query := `
+		SELECT id, order_id, chain, amount, amount_wei::text, received_wei::text, start_block, status,
+			COALESCE(transaction_hash, ''), confirmations, required_confirmations, expires_at,
+			confirmed_at, created_at, updated_at
+		FROM crypto_payments
+		WHERE status IN ($1, $2)
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, CryptoPaymentPending, CryptoPaymentDetected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crypto payments: %w", err)
+	}
+	defer rows.Close()
+
+	var payments []*CryptoPayment
+	for rows.Next() {
+		payment, err := scanCryptoPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan crypto payment: %w", err)
+		}
+		payments = append(payments, payment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating crypto payments: %w", err)
+	}
+
+	return payments, nil
+}
+
+// UpdateProgress stores what the watcher last saw on-chain for a payment
+func (r *CryptoPaymentRepository) UpdateProgress(ctx context.Context, payment *CryptoPayment) error {
+	query := `
+		UPDATE crypto_payments
+		SET received_wei = $2::numeric, status = $3, transaction_hash = NULLIF($4, ''),
+			confirmations = $5, confirmed_at = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		payment.ID,
+		payment.ReceivedWei.String(),
+		payment.Status,
+		payment.TransactionHash,
+		payment.Confirmations,
+		payment.ConfirmedAt,
+		payment.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update crypto payment: %w", err)
+	}
+
+	return nil
+}
+
+// scanCryptoPayment scans a crypto_payments row selected in the column order used above
+func scanCryptoPayment(row pgx.Row) (*CryptoPayment, error) {
+	var payment CryptoPayment
+	var amountWei, receivedWei string
+	err := row.Scan(
+		&payment.ID,
+		&payment.OrderID,
+		&payment.Chain,
+		&payment.Amount,
+		&amountWei,
+		&receivedWei,
+		&payment.StartBlock,
+		&payment.Status,
+		&payment.TransactionHash,
+		&payment.Confirmations,
+		&payment.RequiredConfirmations,
+		&payment.ExpiresAt,
+		&payment.ConfirmedAt,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	if payment.AmountWei, ok = new(big.Int).SetString(amountWei, 10); !ok {
+		return nil, fmt.Errorf("invalid amount %q", amountWei)
+	}
+	if payment.ReceivedWei, ok = new(big.Int).SetString(receivedWei, 10); !ok {
+		return nil, fmt.Errorf("invalid received amount %q", receivedWei)
+	}
+
+	return &payment, nil
+}
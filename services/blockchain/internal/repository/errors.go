@@ -0,0 +1,11 @@
+package repository
+
+import "errors"
+
+var (
+	// ErrLeafNotFound is returned when no batch leaf exists for an order
+	ErrLeafNotFound = errors.New("batch leaf not found")
+
+	// ErrCryptoPaymentNotFound is returned when no crypto payment request exists for an order
+	ErrCryptoPaymentNotFound = errors.New("crypto payment not found")
+)
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/pkg/blockchain"
+	"github.com/order-api-microservices/services/blockchain/internal/repository"
+)
+
+// BatchConfig configures batch anchoring
+type BatchConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Interval  time.Duration `mapstructure:"interval"`
+	MaxLeaves int           `mapstructure:"max_leaves"`
+}
+
+// Batcher accumulates order hashes and periodically commits their Merkle root on-chain
+type Batcher struct {
+	chains    *blockchain.ChainRegistry
+	repo      *repository.BatchRepository
+	interval  time.Duration
+	maxLeaves int
+}
+
+// NewBatcher creates a new batcher
+func NewBatcher(chains *blockchain.ChainRegistry, repo *repository.BatchRepository, config BatchConfig) *Batcher {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.MaxLeaves <= 0 {
+		config.MaxLeaves = 1000
+	}
+
+	return &Batcher{
+		chains:    chains,
+		repo:      repo,
+		interval:  config.Interval,
+		maxLeaves: config.MaxLeaves,
+	}
+}
+
+// Add queues an order hash for the next batch on the given chain
+func (b *Batcher) Add(ctx context.Context, orderID, chain string, dataHash [32]byte) error {
+	return b.repo.AddLeaf(ctx, orderID, chain, blockchain.ComputeBatchLeaf(orderID, dataHash))
+}
+
+// Run commits pending batches every interval until the context is cancelled
+func (b *Batcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.Flush(ctx); err != nil {
+				log.Printf("Failed to commit anchor batch: %v", err)
+			}
+		}
+	}
+}
+
+// Flush commits all pending leaves on every chain
+func (b *Batcher) Flush(ctx context.Context) error {
+	for _, chain := range b.chains.Chains() {
+		for {
+			committed, err := b.flushChain(ctx, chain)
+			if err != nil {
+				return fmt.Errorf("chain %s: %v", chain, err)
+			}
+			// A partial batch means the queue is drained
+			if committed < b.maxLeaves {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// flushChain commits up to maxLeaves pending leaves on one chain and returns how many were committed
+func (b *Batcher) flushChain(ctx context.Context, chain string) (int, error) {
+	leaves, err := b.repo.GetPendingLeaves(ctx, chain, b.maxLeaves)
+	if err != nil {
+		return 0, err
+	}
+	if len(leaves) == 0 {
+		return 0, nil
+	}
+
+	hashes := make([][32]byte, len(leaves))
+	for i, leaf := range leaves {
+		hashes[i] = leaf.LeafHash
+	}
+
+	tree, err := blockchain.NewMerkleTree(hashes)
+	if err != nil {
+		return 0, err
+	}
+
+	ethClient, err := b.chains.Client(chain)
+	if err != nil {
+		return 0, err
+	}
+
+	txHash, err := ethClient.CommitBatch(ctx, tree.Root(), tree.LeafCount())
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit batch root: %v", err)
+	}
+
+	batch := &repository.Batch{
+		Chain:           chain,
+		MerkleRoot:      tree.Root(),
+		LeafCount:       tree.LeafCount(),
+		TransactionHash: txHash,
+		CreatedAt:       time.Now(),
+	}
+	if err := b.repo.SaveBatch(ctx, batch, leaves); err != nil {
+		return 0, err
+	}
+
+	log.Printf("Committed anchor batch %d on %s with %d orders (tx %s)", batch.ID, chain, batch.LeafCount, txHash)
+	return len(leaves), nil
+}
+
+// Proof builds the Merkle proof for an order's latest batched hash
+func (b *Batcher) Proof(ctx context.Context, orderID, chain string) (*repository.BatchLeaf, *repository.Batch, [][32]byte, error) {
+	leaf, err := b.repo.GetLatestLeaf(ctx, orderID, chain)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if leaf.BatchID == nil || leaf.LeafIndex == nil {
+		// Still waiting for the next commit
+		return leaf, nil, nil, nil
+	}
+
+	batch, hashes, err := b.repo.GetBatch(ctx, *leaf.BatchID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	tree, err := blockchain.NewMerkleTree(hashes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if tree.Root() != batch.MerkleRoot {
+		return nil, nil, nil, fmt.Errorf("stored leaves do not match batch root")
+	}
+
+	proof, err := tree.Proof(*leaf.LeafIndex)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return leaf, batch, proof, nil
+}
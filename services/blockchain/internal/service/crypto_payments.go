@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/blockchain"
+	"github.com/order-api-microservices/pkg/money"
+	"github.com/order-api-microservices/services/blockchain/internal/repository"
+)
+
+// CryptoPaymentConfig configures crypto payment processing
+type CryptoPaymentConfig struct {
+	Enabled       bool              `mapstructure:"enabled"`
+	Confirmations int               `mapstructure:"confirmations"`
+	Interval      time.Duration     `mapstructure:"interval"`
+	Expiry        time.Duration     `mapstructure:"expiry"`
+	Rates         map[string]string `mapstructure:"rates"` // Wei per unit of order currency, keyed by chain
+}
+
+// CryptoPaymentWatcher creates payment requests for orders paid in a chain's native token
+// and watches the registry contract until each payment has enough confirmations
+type CryptoPaymentWatcher struct {
+	chains        *blockchain.ChainRegistry
+	repo          *repository.CryptoPaymentRepository
+	confirmations int
+	interval      time.Duration
+	expiry        time.Duration
+	rates         map[string]*big.Int
+}
+
+// NewCryptoPaymentWatcher creates a new crypto payment watcher
+func NewCryptoPaymentWatcher(chains *blockchain.ChainRegistry, repo *repository.CryptoPaymentRepository, config CryptoPaymentConfig) (*CryptoPaymentWatcher, error) {
+	if config.Confirmations <= 0 {
+		config.Confirmations = 12
+	}
+	if config.Interval <= 0 {
+		config.Interval = 15 * time.Second
+	}
+	if config.Expiry <= 0 {
+		config.Expiry = 30 * time.Minute
+	}
+
+	rates := make(map[string]*big.Int, len(config.Rates))
+	for chain, rate := range config.Rates {
+		weiPerUnit, ok := new(big.Int).SetString(rate, 10)
+		if !ok || weiPerUnit.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid crypto payment rate for chain %s: %q", chain, rate)
+		}
+		rates[strings.ToLower(chain)] = weiPerUnit
+	}
+
+	return &CryptoPaymentWatcher{
+		chains:        chains,
+		repo:          repo,
+		confirmations: config.Confirmations,
+		interval:      config.Interval,
+		expiry:        config.Expiry,
+		rates:         rates,
+	}, nil
+}
+
+// Create requests payment for an order on the given chain. Requesting payment for the
+// same order again returns the existing request.
+func (w *CryptoPaymentWatcher) Create(ctx context.Context, orderID, chain string, amount int64) (*repository.CryptoPayment, error) {
+	existing, err := w.repo.GetPaymentByOrder(ctx, orderID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, repository.ErrCryptoPaymentNotFound) {
+		return nil, err
+	}
+
+	ethClient, err := w.chains.Client(chain)
+	if err != nil {
+		return nil, err
+	}
+	weiPerUnit, ok := w.rates[strings.ToLower(ethClient.ChainName())]
+	if !ok {
+		return nil, fmt.Errorf("crypto payments are not accepted on chain %s", ethClient.ChainName())
+	}
+
+	// Rates are per currency unit and amounts are in minor units
+	amountWei := new(big.Int).Mul(weiPerUnit, big.NewInt(amount))
+	amountWei.Div(amountWei, big.NewInt(money.MinorPerUnit))
+
+	head, err := ethClient.LatestBlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return w.repo.CreatePayment(ctx, &repository.CryptoPayment{
+		ID:                    uuid.New().String(),
+		OrderID:               orderID,
+		Chain:                 ethClient.ChainName(),
+		Amount:                amount,
+		AmountWei:             amountWei,
+		ReceivedWei:           new(big.Int),
+		StartBlock:            head,
+		Status:                repository.CryptoPaymentPending,
+		RequiredConfirmations: w.confirmations,
+		ExpiresAt:             now.Add(w.expiry),
+		CreatedAt:             now,
+		UpdatedAt:             now,
+	})
+}
+
+// Get returns the payment request of an order
+func (w *CryptoPaymentWatcher) Get(ctx context.Context, orderID string) (*repository.CryptoPayment, error) {
+	return w.repo.GetPaymentByOrder(ctx, orderID)
+}
+
+// Run checks active payments every interval until the context is cancelled
+func (w *CryptoPaymentWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			payments, err := w.repo.ListActivePayments(ctx)
+			if err != nil {
+				log.Printf("Failed to list active crypto payments: %v", err)
+				continue
+			}
+			for _, payment := range payments {
+				if err := w.check(ctx, payment); err != nil {
+					log.Printf("Failed to check crypto payment for order %s: %v", payment.OrderID, err)
+				}
+			}
+		}
+	}
+}
+
+// check recomputes a payment's progress from the chain. Progress is rebuilt from the
+// logs every time, so a reorg that drops a payment moves it back to PENDING.
+func (w *CryptoPaymentWatcher) check(ctx context.Context, payment *repository.CryptoPayment) error {
+	ethClient, err := w.chains.Client(payment.Chain)
+	if err != nil {
+		return err
+	}
+
+	head, err := ethClient.LatestBlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	paid, err := ethClient.FindOrderPayments(ctx, payment.OrderID, payment.StartBlock)
+	if err != nil {
+		return err
+	}
+
+	// Find the payment that completed the amount due; its depth is the confirmation count
+	received := new(big.Int)
+	var completedBy *blockchain.OrderPayment
+	for i := range paid {
+		received.Add(received, paid[i].Amount)
+		if completedBy == nil && received.Cmp(payment.AmountWei) >= 0 {
+			completedBy = &paid[i]
+		}
+	}
+
+	now := time.Now()
+	payment.ReceivedWei = received
+	payment.UpdatedAt = now
+	switch {
+	case completedBy != nil:
+		payment.TransactionHash = completedBy.TransactionHash
+		payment.Confirmations = 0
+		if head >= completedBy.BlockNumber {
+			payment.Confirmations = int(head - completedBy.BlockNumber + 1)
+		}
+		payment.Status = repository.CryptoPaymentDetected
+		if payment.Confirmations >= payment.RequiredConfirmations {
+			payment.Status = repository.CryptoPaymentConfirmed
+			payment.ConfirmedAt = &now
+			log.Printf("Crypto payment for order %s confirmed (tx %s)", payment.OrderID, payment.TransactionHash)
+		}
+	case now.After(payment.ExpiresAt):
+		payment.Status = repository.CryptoPaymentExpired
+		payment.TransactionHash = ""
+		payment.Confirmations = 0
+	default:
+		payment.Status = repository.CryptoPaymentPending
+		payment.TransactionHash = ""
+		payment.Confirmations = 0
+	}
+
+	return w.repo.UpdateProgress(ctx, payment)
+}
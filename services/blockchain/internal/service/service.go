@@ -2,12 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/order-api-microservices/pkg/anchor"
 	"github.com/order-api-microservices/pkg/blockchain"
 	pb "github.com/order-api-microservices/proto/blockchain"
+	"github.com/order-api-microservices/services/blockchain/internal/repository"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -16,73 +19,242 @@ import (
 // BlockchainService handles interactions with the blockchain
 type BlockchainService struct {
 	pb.UnimplementedBlockchainServiceServer
-	ethClient *blockchain.EthereumClient
+	chains       *blockchain.ChainRegistry
+	payloadStore anchor.PayloadStore   // nil when off-chain anchoring is disabled
+	batcher      *Batcher              // nil when batch anchoring is disabled
+	payments     *CryptoPaymentWatcher // nil when crypto payments are disabled
 }
 
 // NewBlockchainService creates a new blockchain service
-func NewBlockchainService(ethClient *blockchain.EthereumClient) *BlockchainService {
+func NewBlockchainService(chains *blockchain.ChainRegistry, payloadStore anchor.PayloadStore, batcher *Batcher, payments *CryptoPaymentWatcher) *BlockchainService {
 	return &BlockchainService{
-		ethClient: ethClient,
+		chains:       chains,
+		payloadStore: payloadStore,
+		batcher:      batcher,
+		payments:     payments,
 	}
 }
 
+// clientFor resolves the Ethereum client for the requested chain
+func (s *BlockchainService) clientFor(chain string) (*blockchain.EthereumClient, error) {
+	ethClient, err := s.chains.Client(chain)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return ethClient, nil
+}
+
 // RecordOrder records a new order on the blockchain
 func (s *BlockchainService) RecordOrder(ctx context.Context, req *pb.RecordOrderRequest) (*pb.RecordOrderResponse, error) {
+	ethClient, err := s.clientFor(req.Chain)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert order data to a hash
 	items := make([]string, 0, len(req.OrderData.Items))
 	for _, item := range req.OrderData.Items {
-		items = append(items, fmt.Sprintf("%s:%s:%d:%f", item.ItemId, item.Name, item.Quantity, item.Price))
+		items = append(items, fmt.Sprintf("%s:%s:%d:%d", item.ItemId, item.Name, item.Quantity, item.Price))
 	}
 
 	dataHash, err := blockchain.ComputeOrderHash(
 		req.OrderId,
 		req.UserId,
 		req.ProviderId,
-		float64(req.OrderData.TotalPrice),
+		req.OrderData.TotalPrice,
 		items,
 		blockchain.OrderStatus(req.OrderData.Status),
+		req.OrderData.DeliveryProofHash,
 	)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to compute order hash: %v", err)
 	}
 
+	// When anchoring is enabled, store the full snapshot off-chain and record its hash instead
+	var payloadRef string
+	if s.payloadStore != nil && len(req.Payload) > 0 {
+		payloadRef, err = s.payloadStore.Put(ctx, req.Payload)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to store order payload: %v", err)
+		}
+		dataHash = anchor.HashPayload(req.Payload)
+	}
+
+	// In batch mode the hash is committed with the next Merkle root instead of its own transaction
+	if s.batcher != nil {
+		if err := s.batcher.Add(ctx, req.OrderId, ethClient.ChainName(), dataHash); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to queue order for batch anchoring: %v", err)
+		}
+
+		response := &pb.RecordOrderResponse{
+			Success:   true,
+			Message:   "Order queued for the next anchor batch",
+			Timestamp: timestamppb.Now(),
+			Chain:     ethClient.ChainName(),
+			Batched:   true,
+		}
+		if payloadRef != "" {
+			response.PayloadRef = payloadRef
+			response.PayloadHash = dataHash[:]
+		}
+		return response, nil
+	}
+
 	// Record order on blockchain
-	txHash, err := s.ethClient.RecordOrder(ctx, req.OrderId, dataHash, blockchain.OrderStatus(req.OrderData.Status))
+	txHash, err := ethClient.RecordOrder(ctx, req.OrderId, dataHash, blockchain.OrderStatus(req.OrderData.Status))
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to record order on blockchain: %v", err)
 	}
 
+	response := &pb.RecordOrderResponse{
+		Success:         true,
+		TransactionHash: txHash,
+		Message:         "Order successfully recorded on blockchain",
+		Timestamp:       timestamppb.Now(),
+		Chain:           ethClient.ChainName(),
+	}
+
+	// Anchor the off-chain payload reference
+	if payloadRef != "" {
+		anchorTxHash, err := ethClient.AnchorPayload(ctx, req.OrderId, dataHash, payloadRef)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to anchor order payload: %v", err)
+		}
+		response.PayloadRef = payloadRef
+		response.PayloadHash = dataHash[:]
+		response.AnchorTransactionHash = anchorTxHash
+	}
+
 	// Get transaction details
-	tx, receipt, err := s.ethClient.GetTransactionDetails(ctx, txHash)
+	_, receipt, err := ethClient.GetTransactionDetails(ctx, txHash)
 	if err != nil {
 		// Still return success but include error in message
-		return &pb.RecordOrderResponse{
-			Success:        true,
-			TransactionHash: txHash,
-			Message:        fmt.Sprintf("Order recorded but failed to get transaction details: %v", err),
-			Timestamp:      timestamppb.Now(),
+		response.Message = fmt.Sprintf("Order recorded but failed to get transaction details: %v", err)
+		return response, nil
+	}
+
+	response.BlockNumber = fmt.Sprintf("%d", receipt.BlockNumber)
+	return response, nil
+}
+
+// GetAnchoredPayload fetches an order's off-chain payload and verifies it against the on-chain hash
+func (s *BlockchainService) GetAnchoredPayload(ctx context.Context, req *pb.GetAnchoredPayloadRequest) (*pb.GetAnchoredPayloadResponse, error) {
+	if s.payloadStore == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "off-chain payload anchoring is not enabled")
+	}
+
+	ethClient, err := s.clientFor(req.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadAnchor, err := ethClient.GetPayloadAnchor(ctx, req.OrderId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get payload anchor from blockchain: %v", err)
+	}
+	if !payloadAnchor.Exists {
+		return nil, status.Errorf(codes.NotFound, "no payload anchored for order")
+	}
+
+	payload, err := s.payloadStore.Get(ctx, payloadAnchor.PayloadRef)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to fetch anchored payload: %v", err)
+	}
+
+	verified := anchor.HashPayload(payload) == payloadAnchor.PayloadHash
+	message := "Payload matches the on-chain hash"
+	if !verified {
+		message = "Payload does not match the on-chain hash"
+	}
+
+	return &pb.GetAnchoredPayloadResponse{
+		OrderId:     req.OrderId,
+		Payload:     payload,
+		PayloadRef:  payloadAnchor.PayloadRef,
+		PayloadHash: payloadAnchor.PayloadHash[:],
+		Verified:    verified,
+		AnchoredAt:  timestamppb.New(time.Unix(int64(payloadAnchor.Timestamp), 0)),
+		Message:     message,
+		Success:     true,
+	}, nil
+}
+
+// GetBatchProof returns the Merkle proof that an order's hash is included in a committed batch
+func (s *BlockchainService) GetBatchProof(ctx context.Context, req *pb.GetBatchProofRequest) (*pb.GetBatchProofResponse, error) {
+	if s.batcher == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "batch anchoring is not enabled")
+	}
+
+	ethClient, err := s.clientFor(req.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, batch, proof, err := s.batcher.Proof(ctx, req.OrderId, ethClient.ChainName())
+	if err != nil {
+		if errors.Is(err, repository.ErrLeafNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order has not been queued for batch anchoring")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to build batch proof: %v", err)
+	}
+
+	if batch == nil {
+		return &pb.GetBatchProofResponse{
+			OrderId: req.OrderId,
+			Leaf:    leaf.LeafHash[:],
+			Message: "Order is waiting for the next batch commit",
+			Success: true,
 		}, nil
 	}
 
-	return &pb.RecordOrderResponse{
-		Success:        true,
-		TransactionHash: txHash,
-		BlockNumber:    fmt.Sprintf("%d", receipt.BlockNumber),
-		Message:        "Order successfully recorded on blockchain",
-		Timestamp:      timestamppb.Now(),
+	proofBytes := make([][]byte, len(proof))
+	for i, sibling := range proof {
+		sibling := sibling
+		proofBytes[i] = sibling[:]
+	}
+
+	// Check the proof against the root recorded on-chain
+	committed, _, _, err := ethClient.GetBatch(ctx, batch.MerkleRoot)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get batch from blockchain: %v", err)
+	}
+	verified := committed && blockchain.VerifyMerkleProof(leaf.LeafHash, proof, batch.MerkleRoot)
+
+	message := "Order hash is included in the committed batch"
+	if !verified {
+		message = "Order hash could not be verified against the on-chain batch root"
+	}
+
+	return &pb.GetBatchProofResponse{
+		OrderId:         req.OrderId,
+		BatchId:         batch.ID,
+		MerkleRoot:      batch.MerkleRoot[:],
+		Leaf:            leaf.LeafHash[:],
+		Proof:           proofBytes,
+		LeafIndex:       int32(*leaf.LeafIndex),
+		TransactionHash: batch.TransactionHash,
+		Committed:       committed,
+		Verified:        verified,
+		Message:         message,
+		Success:         true,
 	}, nil
 }
 
 // VerifyOrder verifies an order on the blockchain
 func (s *BlockchainService) VerifyOrder(ctx context.Context, req *pb.VerifyOrderRequest) (*pb.VerifyOrderResponse, error) {
+	ethClient, err := s.clientFor(req.Chain)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get transaction details
-	tx, receipt, err := s.ethClient.GetTransactionDetails(ctx, req.TransactionHash)
+	_, receipt, err := ethClient.GetTransactionDetails(ctx, req.TransactionHash)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get transaction details: %v", err)
 	}
 
 	// Get order data from blockchain
-	exists, dataHash, timestamp, orderStatus, err := s.ethClient.GetOrderStatus(ctx, req.OrderId)
+	exists, _, timestamp, _, err := ethClient.GetOrderStatus(ctx, req.OrderId)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get order status from blockchain: %v", err)
 	}
@@ -106,8 +278,13 @@ func (s *BlockchainService) VerifyOrder(ctx context.Context, req *pb.VerifyOrder
 
 // GetOrderHistory gets the history of an order from the blockchain
 func (s *BlockchainService) GetOrderHistory(ctx context.Context, req *pb.GetOrderHistoryRequest) (*pb.GetOrderHistoryResponse, error) {
+	ethClient, err := s.clientFor(req.Chain)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if order exists
-	exists, _, _, _, err := s.ethClient.GetOrderStatus(ctx, req.OrderId)
+	exists, _, _, _, err := ethClient.GetOrderStatus(ctx, req.OrderId)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to check order existence: %v", err)
 	}
@@ -142,7 +319,12 @@ func (s *BlockchainService) GetOrderHistory(ctx context.Context, req *pb.GetOrde
 
 // GetTransactionDetails gets details about a transaction
 func (s *BlockchainService) GetTransactionDetails(ctx context.Context, req *pb.GetTransactionDetailsRequest) (*pb.GetTransactionDetailsResponse, error) {
-	tx, receipt, err := s.ethClient.GetTransactionDetails(ctx, req.TransactionHash)
+	ethClient, err := s.clientFor(req.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, receipt, err := ethClient.GetTransactionDetails(ctx, req.TransactionHash)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get transaction details: %v", err)
 	}
@@ -153,9 +335,9 @@ func (s *BlockchainService) GetTransactionDetails(ctx context.Context, req *pb.G
 		status = "failed"
 	}
 
-	from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+	from, err := types.Sender(ethClient.Signer(), tx)
 	if err != nil {
-		from = s.ethClient.FromAddress()
+		from = ethClient.FromAddress()
 	}
 
 	return &pb.GetTransactionDetailsResponse{
@@ -171,5 +353,82 @@ func (s *BlockchainService) GetTransactionDetails(ctx context.Context, req *pb.G
 		Status:          status,
 		Success:         true,
 		Message:         "Transaction details retrieved",
+		Chain:           ethClient.ChainName(),
+	}, nil
+}
+
+// CreateCryptoPayment requests payment for an order in a chain's native token
+func (s *BlockchainService) CreateCryptoPayment(ctx context.Context, req *pb.CreateCryptoPaymentRequest) (*pb.CryptoPaymentResponse, error) {
+	if s.payments == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "crypto payments are not enabled")
+	}
+
+	if _, err := s.clientFor(req.Chain); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.payments.Create(ctx, req.OrderId, req.Chain, req.Amount)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create crypto payment: %v", err)
+	}
+
+	return s.cryptoPaymentResponse(payment, "Crypto payment requested")
+}
+
+// GetCryptoPayment returns the payment request of an order and how far it has been confirmed
+func (s *BlockchainService) GetCryptoPayment(ctx context.Context, req *pb.GetCryptoPaymentRequest) (*pb.CryptoPaymentResponse, error) {
+	if s.payments == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "crypto payments are not enabled")
+	}
+
+	payment, err := s.payments.Get(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrCryptoPaymentNotFound) {
+			return nil, status.Errorf(codes.NotFound, "no crypto payment has been requested for this order")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get crypto payment: %v", err)
+	}
+
+	return s.cryptoPaymentResponse(payment, "Crypto payment retrieved")
+}
+
+// cryptoPaymentResponse converts a stored payment request into what wallets need to pay it
+func (s *BlockchainService) cryptoPaymentResponse(payment *repository.CryptoPayment, message string) (*pb.CryptoPaymentResponse, error) {
+	ethClient, err := s.clientFor(payment.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	callData, err := ethClient.PayOrderCallData(payment.OrderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to build payment call data: %v", err)
+	}
+
+	protoPayment := &pb.CryptoPayment{
+		Id:                    payment.ID,
+		OrderId:               payment.OrderID,
+		Chain:                 payment.Chain,
+		ChainId:               ethClient.ChainID().String(),
+		DepositAddress:        ethClient.ContractAddress().Hex(),
+		Amount:                payment.Amount,
+		AmountWei:             payment.AmountWei.String(),
+		ReceivedWei:           payment.ReceivedWei.String(),
+		PaymentUri:            ethClient.PaymentURI(payment.OrderID, payment.AmountWei),
+		CallData:              callData,
+		Status:                string(payment.Status),
+		TransactionHash:       payment.TransactionHash,
+		Confirmations:         int32(payment.Confirmations),
+		RequiredConfirmations: int32(payment.RequiredConfirmations),
+		ExpiresAt:             timestamppb.New(payment.ExpiresAt),
+		CreatedAt:             timestamppb.New(payment.CreatedAt),
+	}
+	if payment.ConfirmedAt != nil {
+		protoPayment.ConfirmedAt = timestamppb.New(*payment.ConfirmedAt)
+	}
+
+	return &pb.CryptoPaymentResponse{
+		Payment: protoPayment,
+		Message: message,
+		Success: true,
 	}, nil
-} 
\ No newline at end of file
+}
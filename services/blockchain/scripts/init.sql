@@ -0,0 +1,56 @@
+-- Create anchor_batches table for Merkle roots committed on-chain
+CREATE TABLE IF NOT EXISTS anchor_batches (
+    id BIGSERIAL PRIMARY KEY,
+    chain VARCHAR(50) NOT NULL,
+    merkle_root BYTEA NOT NULL,
+    leaf_count INTEGER NOT NULL,
+    transaction_hash VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Create anchor_batch_leaves table for order hashes waiting for or included in a batch
+CREATE TABLE IF NOT EXISTS anchor_batch_leaves (
+    id BIGSERIAL PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    chain VARCHAR(50) NOT NULL,
+    leaf_hash BYTEA NOT NULL,
+    batch_id BIGINT REFERENCES anchor_batches(id),
+    leaf_index INTEGER,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Create crypto_payments table for orders paid in a chain's native token
+CREATE TABLE IF NOT EXISTS crypto_payments (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    chain VARCHAR(50) NOT NULL,
+    amount BIGINT NOT NULL,
+    amount_wei NUMERIC(78, 0) NOT NULL,
+    received_wei NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    start_block BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    transaction_hash VARCHAR(100),
+    confirmations INTEGER NOT NULL DEFAULT 0,
+    required_confirmations INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    confirmed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_anchor_batches_chain_root ON anchor_batches(chain, merkle_root);
+CREATE INDEX IF NOT EXISTS idx_anchor_batch_leaves_order_chain ON anchor_batch_leaves(order_id, chain);
+CREATE INDEX IF NOT EXISTS idx_anchor_batch_leaves_pending ON anchor_batch_leaves(chain) WHERE batch_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_crypto_payments_order_id ON crypto_payments(order_id);
+CREATE INDEX IF NOT EXISTS idx_crypto_payments_active ON crypto_payments(status) WHERE status IN ('PENDING', 'DETECTED');
+
+-- Migrate crypto payment amounts from NUMERIC currency units to BIGINT minor units (cents)
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'crypto_payments' AND column_name = 'amount') = 'numeric' THEN
+        ALTER TABLE crypto_payments ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100);
+    END IF;
+END
+$$;
\ No newline at end of file
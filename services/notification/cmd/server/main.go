@@ -8,10 +8,15 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/pkg/grpcserver"
+	"github.com/order-api-microservices/pkg/lock"
+	"github.com/order-api-microservices/pkg/metrics"
+	"github.com/order-api-microservices/services/notification/internal/clients"
 	"github.com/order-api-microservices/services/notification/internal/repository"
 	"github.com/order-api-microservices/services/notification/internal/service"
 	pb "github.com/order-api-microservices/proto/notification"
@@ -28,9 +33,31 @@ func main() {
 	dbSSLMode := flag.String("db-sslmode", getEnv("DB_SSLMODE", "disable"), "Database SSL mode")
 	
 	port := flag.Int("port", getEnvInt("PORT", 50054), "Server port")
+	orderServiceAddr := flag.String("order-service", getEnv("ORDER_SERVICE", "localhost:50051"), "Order service address")
+	providerServiceAddr := flag.String("provider-service", getEnv("PROVIDER_SERVICE", "localhost:50053"), "Provider service address")
+	grpcAuthToken := flag.String("grpc-auth-token", getEnv("GRPC_AUTH_TOKEN", ""), "Token gRPC callers must present, and that this service presents to the services it calls; empty turns authentication off")
+	grpcDefaultTimeout := flag.Duration("grpc-default-timeout", getEnvDuration("GRPC_DEFAULT_TIMEOUT", 30*time.Second), "Deadline of gRPC calls that arrive without one (0 leaves them without)")
+	grpcMaxTimeout := flag.Duration("grpc-max-timeout", getEnvDuration("GRPC_MAX_TIMEOUT", 2*time.Minute), "Longest deadline a gRPC call may have (0 leaves it uncapped)")
+	metricsPort := flag.Int("metrics-port", getEnvInt("METRICS_PORT", 9094), "Metrics server port")
+	schedulerLockRetry := flag.Duration("scheduler-lock-retry", getEnvDuration("SCHEDULER_LOCK_RETRY", 10*time.Second), "How often instances not running a scheduler try to take it over, and the one running it checks its lock")
+
+	retentionAge := flag.Duration("retention-age", getEnvDuration("RETENTION_AGE", 90*24*time.Hour), "Age after which read notifications are purged (0 keeps them unless a type override applies)")
+	retentionTypeAges := flag.String("retention-type-ages", getEnv("RETENTION_TYPE_AGES", ""), "Per-type retention ages overriding retention-age, as TYPE=duration pairs separated by commas; 0 keeps that type for good")
+	retentionArchive := flag.Bool("retention-archive", getEnv("RETENTION_ARCHIVE", "") == "true", "Move purged notifications to the archive table instead of deleting them")
+	retentionInterval := flag.Duration("retention-interval", getEnvDuration("RETENTION_INTERVAL", time.Hour), "How often old read notifications are purged")
+	retentionBatch := flag.Int("retention-batch", getEnvInt("RETENTION_BATCH", 1000), "Most notifications purged in one statement")
+
+	campaignInterval := flag.Duration("campaign-interval", getEnvDuration("CAMPAIGN_INTERVAL", time.Second), "How often a batch of each campaign being sent is sent")
+	campaignBatch := flag.Int("campaign-batch", getEnvInt("CAMPAIGN_BATCH", 500), "Most recipients a campaign notifies per batch")
+	campaignMaxActive := flag.Int("campaign-max-active", getEnvInt("CAMPAIGN_MAX_ACTIVE", 10), "Most campaigns sent at once; later ones wait their turn")
 	
 	flag.Parse()
 
+	typeAges, err := service.ParseRetentionAges(*retentionTypeAges)
+	if err != nil {
+		log.Fatalf("Invalid retention type ages: %v", err)
+	}
+
 	// Set up database connection
 	dbConfig := database.NewPostgresConfig(
 		*dbHost,
@@ -49,9 +76,58 @@ func main() {
 
 	// Initialize repository
 	notificationRepo := repository.NewNotificationRepository(db)
+	privacyRepo := repository.NewPrivacyRepository(db)
+	campaignRepo := repository.NewCampaignRepository(db)
+	deviceRepo := repository.NewDeviceRepository(db)
 
 	// Initialize service
 	notificationService := service.NewNotificationService(notificationRepo)
+	privacyService := service.NewPrivacyService(privacyRepo)
+	inboxService := service.NewInboxService(notificationRepo)
+	campaignService := service.NewCampaignService(campaignRepo)
+	deviceService := service.NewDeviceService(deviceRepo)
+
+	// Initialize clients, which look up the recipients of campaigns
+	clientOpts := []grpc.DialOption{grpcserver.WithToken(*grpcAuthToken)}
+
+	orderClient, err := clients.NewOrderGRPCClient(*orderServiceAddr, clientOpts...)
+	if err != nil {
+		log.Fatalf("Failed to connect to order service: %v", err)
+	}
+	defer orderClient.Close()
+
+	providerClient, err := clients.NewProviderGRPCClient(*providerServiceAddr, clientOpts...)
+	if err != nil {
+		log.Fatalf("Failed to connect to provider service: %v", err)
+	}
+	defer providerClient.Close()
+
+	// Expose metrics, including the retention job's purge volume
+	metrics.Serve(*metricsPort)
+
+	// Purge old read notifications in the background, from one instance at a time
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	elector := lock.NewElector(db, "notification", *schedulerLockRetry)
+	defer elector.Close()
+
+	retention := service.NewRetention(notificationRepo, service.RetentionConfig{
+		Age:       *retentionAge,
+		TypeAges:  typeAges,
+		Archive:   *retentionArchive,
+		Interval:  *retentionInterval,
+		BatchSize: *retentionBatch,
+	})
+	go elector.Run(jobsCtx, "retention", retention.Run)
+
+	// Send broadcast campaigns in the background, also from one instance at a time
+	campaignSender := service.NewCampaignSender(campaignRepo, providerClient, orderClient, service.CampaignSenderConfig{
+		Interval:  *campaignInterval,
+		BatchSize: *campaignBatch,
+		MaxActive: *campaignMaxActive,
+	})
+	go elector.Run(jobsCtx, "campaigns", campaignSender.Run)
 
 	// Set up gRPC server
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
@@ -59,8 +135,16 @@ func main() {
 		log.Fatalf("Failed to listen on port %d: %v", *port, err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpcserver.New(grpcserver.Config{
+		AuthToken:      *grpcAuthToken,
+		DefaultTimeout: *grpcDefaultTimeout,
+		MaxTimeout:     *grpcMaxTimeout,
+	})
 	pb.RegisterNotificationServiceServer(grpcServer, notificationService)
+	pb.RegisterNotificationPrivacyServiceServer(grpcServer, privacyService)
+	pb.RegisterNotificationInboxServiceServer(grpcServer, inboxService)
+	pb.RegisterNotificationCampaignServiceServer(grpcServer, campaignService)
+	pb.RegisterNotificationDeviceServiceServer(grpcServer, deviceService)
 
 	// Handle graceful shutdown
 	go func() {
@@ -112,10 +196,25 @@ func getEnvInt(key string, defaultValue int) int {
 		return defaultValue
 	}
 	
-	intValue, err := fmt.Sscanf(value, "%d")
-	if err != nil || len(intValue) == 0 {
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	
+	return intValue
+} 
+
+// Helper function to get environment variables as durations
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	
+	duration, err := time.ParseDuration(value)
+	if err != nil {
 		return defaultValue
 	}
 	
-	return intValue[0]
-} 
\ No newline at end of file
+	return duration
+}
\ No newline at end of file
@@ -0,0 +1,89 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/pkg/breaker"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/requestid"
+	orderPb "github.com/order-api-microservices/proto/order"
+	serviceAreaPb "github.com/order-api-microservices/proto/servicearea"
+	"github.com/order-api-microservices/services/notification/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The order client looks up campaign recipients
+var _ service.OrderDirectory = (*OrderGRPCClient)(nil)
+
+// orderTimeouts are the timeouts of calls to the order service
+var orderTimeouts = deadline.Timeouts{
+	Default: 10 * time.Second,
+}
+
+// OrderGRPCClient is a client for the order service, and the service areas it serves
+type OrderGRPCClient struct {
+	orders       orderPb.OrderServiceClient
+	serviceAreas serviceAreaPb.ServiceAreaServiceClient
+	conn         *grpc.ClientConn
+}
+
+// NewOrderGRPCClient creates a new order service client, dialled with any extra opts
+func NewOrderGRPCClient(address string, opts ...grpc.DialOption) (*OrderGRPCClient, error) {
+	conn, err := grpc.Dial(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		deadline.DialOption(orderTimeouts),
+		requestid.DialOption(),
+		breaker.DialOption("order", breaker.DefaultConfig()),
+	}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to order service: %v", err)
+	}
+
+	return &OrderGRPCClient{
+		orders:       orderPb.NewOrderServiceClient(conn),
+		serviceAreas: serviceAreaPb.NewServiceAreaServiceClient(conn),
+		conn:         conn,
+	}, nil
+}
+
+// Close closes the connection to the order service
+func (c *OrderGRPCClient) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// ListServiceAreaIDs lists the IDs of a city's service areas, active or not, since
+// providers stay registered in areas that are paused
+func (c *OrderGRPCClient) ListServiceAreaIDs(ctx context.Context, city string) ([]string, error) {
+	resp, err := c.serviceAreas.ListServiceAreas(ctx, &serviceAreaPb.ListServiceAreasRequest{City: city})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service areas: %v", err)
+	}
+
+	serviceAreaIDs := make([]string, 0, len(resp.Areas))
+	for _, area := range resp.Areas {
+		serviceAreaIDs = append(serviceAreaIDs, area.Id)
+	}
+	return serviceAreaIDs, nil
+}
+
+// ListActiveUserIDs lists up to limit IDs, after afterID in ID order, of the users who
+// placed an order since a time
+func (c *OrderGRPCClient) ListActiveUserIDs(ctx context.Context, since time.Time, afterID string, limit int) ([]string, error) {
+	resp, err := c.orders.ListActiveUserIDs(ctx, &orderPb.ListActiveUserIDsRequest{
+		Since:   timestamppb.New(since),
+		AfterId: afterID,
+		Limit:   int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active users: %v", err)
+	}
+
+	return resp.UserIds, nil
+}
@@ -0,0 +1,70 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/pkg/breaker"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/requestid"
+	pb "github.com/order-api-microservices/proto/provider"
+	"github.com/order-api-microservices/services/notification/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// The provider client looks up campaign recipients
+var _ service.ProviderDirectory = (*ProviderGRPCClient)(nil)
+
+// providerTimeouts are the timeouts of calls to the provider service
+var providerTimeouts = deadline.Timeouts{
+	Default: 10 * time.Second,
+}
+
+// ProviderGRPCClient is a client for the provider service
+type ProviderGRPCClient struct {
+	client pb.ProviderServiceClient
+	conn   *grpc.ClientConn
+}
+
+// NewProviderGRPCClient creates a new provider service client, dialled with any extra opts
+func NewProviderGRPCClient(address string, opts ...grpc.DialOption) (*ProviderGRPCClient, error) {
+	conn, err := grpc.Dial(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		deadline.DialOption(providerTimeouts),
+		requestid.DialOption(),
+		breaker.DialOption("provider", breaker.DefaultConfig()),
+	}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to provider service: %v", err)
+	}
+
+	return &ProviderGRPCClient{
+		client: pb.NewProviderServiceClient(conn),
+		conn:   conn,
+	}, nil
+}
+
+// Close closes the connection to the provider service
+func (c *ProviderGRPCClient) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// ListProviderIDs lists up to limit IDs, after afterID in ID order, of the providers
+// registered in any of serviceAreaIDs
+func (c *ProviderGRPCClient) ListProviderIDs(ctx context.Context, serviceAreaIDs []string, afterID string, limit int) ([]string, error) {
+	resp, err := c.client.ListProviderIDs(ctx, &pb.ListProviderIDsRequest{
+		ServiceAreaIds: serviceAreaIDs,
+		AfterId:        afterID,
+		Limit:          int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list providers: %v", err)
+	}
+
+	return resp.ProviderIds, nil
+}
@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// CampaignSegment is who a campaign is sent to
+type CampaignSegment string
+
+const (
+	// SegmentProvidersInCity is every provider registered in one of a city's service areas
+	SegmentProvidersInCity CampaignSegment = "PROVIDERS_IN_CITY"
+
+	// SegmentRecentUsers is every user who placed an order within some days of the
+	// campaign's creation
+	SegmentRecentUsers CampaignSegment = "RECENT_USERS"
+)
+
+// CampaignStatus is how far sending a campaign has got
+type CampaignStatus string
+
+const (
+	// CampaignQueued is a campaign no batch has been sent of yet
+	CampaignQueued CampaignStatus = "QUEUED"
+
+	// CampaignSending is a campaign part of whose segment has been notified
+	CampaignSending CampaignStatus = "SENDING"
+
+	// CampaignCompleted is a campaign sent to its whole segment
+	CampaignCompleted CampaignStatus = "COMPLETED"
+
+	// CampaignCancelled is a campaign an admin stopped before it completed
+	CampaignCancelled CampaignStatus = "CANCELLED"
+)
+
+// NotificationTypeCampaign is the type of campaign notifications unless the admin picks
+// another
+const NotificationTypeCampaign NotificationType = "CAMPAIGN"
+
+// Campaign is a notification broadcast to a segment of users or providers. Recipients are
+// notified in ID order, so sending resumes after LastRecipientID.
+type Campaign struct {
+	ID               string
+	Segment          CampaignSegment
+	City             string
+	ActiveWithinDays int
+	NotificationType NotificationType
+	Title            string
+	Message          string
+	Payload          Payload
+	Status           CampaignStatus
+	LastRecipientID  string
+	Sent             int64
+	CreatedBy        string
+	CancelledBy      string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	FinishedAt       *time.Time
+}
+
+// Active reports whether the campaign is still to be sent to some of its segment
+func (c *Campaign) Active() bool {
+	return c.Status == CampaignQueued || c.Status == CampaignSending
+}
+
+// RecipientType is the type of the campaign's recipients
+func (c *Campaign) RecipientType() RecipientType {
+	if c.Segment == SegmentProvidersInCity {
+		return RecipientTypeProvider
+	}
+	return RecipientTypeUser
+}
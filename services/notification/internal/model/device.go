@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// DevicePlatform is the push service a device's token belongs to
+type DevicePlatform string
+
+const (
+	// PlatformFCM is Firebase Cloud Messaging, for Android devices
+	PlatformFCM DevicePlatform = "FCM"
+
+	// PlatformAPNS is the Apple Push Notification service, for iOS devices
+	PlatformAPNS DevicePlatform = "APNS"
+)
+
+// DeliveryFailureReason is why a push platform refused a token
+type DeliveryFailureReason string
+
+const (
+	// FailureUnregistered is a token whose app was uninstalled or that expired
+	FailureUnregistered DeliveryFailureReason = "UNREGISTERED"
+
+	// FailureInvalidToken is a token the platform does not know
+	FailureInvalidToken DeliveryFailureReason = "INVALID_TOKEN"
+
+	// FailureTransient is a failure worth retrying later, e.g. the platform was unavailable
+	FailureTransient DeliveryFailureReason = "TRANSIENT"
+)
+
+// Permanent reports whether the token will never be delivered to again
+func (r DeliveryFailureReason) Permanent() bool {
+	return r == FailureUnregistered || r == FailureInvalidToken
+}
+
+// Device is a device a user or provider is signed in on, with the token push
+// notifications to it are sent with. A token belongs to one device at a time.
+type Device struct {
+	OwnerID    string
+	OwnerType  RecipientType
+	DeviceID   string
+	Platform   DevicePlatform
+	Token      string
+	AppVersion string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
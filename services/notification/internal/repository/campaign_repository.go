@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/notification/internal/model"
+)
+
+// campaignColumns are the columns of a campaign, in the order scanCampaign reads them
+const campaignColumns = `
+	id, segment, city, active_within_days, notification_type, title, message, payload,
+	status, last_recipient_id, sent, created_by, cancelled_by, created_at, updated_at, finished_at
+`
+
+// CampaignRepository handles database operations for broadcast campaigns
+type CampaignRepository struct {
+	db *database.PostgresDB
+}
+
+// NewCampaignRepository creates a new campaign repository
+func NewCampaignRepository(db *database.PostgresDB) *CampaignRepository {
+	return &CampaignRepository{
+		db: db,
+	}
+}
+
+// CreateCampaign stores a new campaign
+func (r *CampaignRepository) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	query := `
+		INSERT INTO campaigns (` + campaignColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		campaign.ID,
+		campaign.Segment,
+		campaign.City,
+		campaign.ActiveWithinDays,
+		campaign.NotificationType,
+		campaign.Title,
+		campaign.Message,
+		campaign.Payload,
+		campaign.Status,
+		campaign.LastRecipientID,
+		campaign.Sent,
+		campaign.CreatedBy,
+		campaign.CancelledBy,
+		campaign.CreatedAt,
+		campaign.UpdatedAt,
+		campaign.FinishedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	return nil
+}
+
+// GetCampaign gets a campaign by ID
+func (r *CampaignRepository) GetCampaign(ctx context.Context, campaignID string) (*model.Campaign, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+campaignColumns+` FROM campaigns WHERE id = $1`, campaignID)
+
+	campaign, err := scanCampaign(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	return campaign, nil
+}
+
+// ListCampaigns lists a page of campaigns, newest first, with how many there are in all.
+// An empty status lists campaigns in any status.
+func (r *CampaignRepository) ListCampaigns(ctx context.Context, status model.CampaignStatus, page, limit int) ([]*model.Campaign, int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM campaigns WHERE $1 = '' OR status = $1`, status).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count campaigns: %w", err)
+	}
+
+	// Set reasonable defaults and boundaries
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	campaigns, err := scanCampaigns(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return campaigns, total, nil
+}
+
+// ListActiveCampaigns lists up to limit campaigns still being sent, oldest first
+func (r *CampaignRepository) ListActiveCampaigns(ctx context.Context, limit int) ([]*model.Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns
+		WHERE status IN ($1, $2)
+		ORDER BY created_at
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, model.CampaignQueued, model.CampaignSending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	return scanCampaigns(rows)
+}
+
+// CancelCampaign stops a campaign still being sent
+func (r *CampaignRepository) CancelCampaign(ctx context.Context, campaignID, cancelledBy string, at time.Time) error {
+	query := `
+		UPDATE campaigns
+		SET status = $2, cancelled_by = $3, updated_at = $4, finished_at = $4
+		WHERE id = $1 AND status IN ($5, $6)
+	`
+
+	tag, err := r.db.ExecContext(ctx, query, campaignID, model.CampaignCancelled, cancelledBy, at, model.CampaignQueued, model.CampaignSending)
+	if err != nil {
+		return fmt.Errorf("failed to cancel campaign: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return r.finishedOrMissing(ctx, campaignID)
+	}
+
+	return nil
+}
+
+// DeliverCampaignBatch stores a batch of a campaign's notifications and moves the
+// campaign past its last recipient, completing it when done is set, all in one
+// transaction. A campaign cancelled in the meantime is left as it is and
+// ErrCampaignFinished returned, so no notification is stored after cancellation.
+func (r *CampaignRepository) DeliverCampaignBatch(ctx context.Context, campaignID string, notifications []*model.Notification, lastRecipientID string, done bool, at time.Time) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		var status model.CampaignStatus
+		err := tx.QueryRow(ctx, `SELECT status FROM campaigns WHERE id = $1 FOR UPDATE`, campaignID).Scan(&status)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrCampaignNotFound
+			}
+			return fmt.Errorf("failed to lock campaign: %w", err)
+		}
+		if status != model.CampaignQueued && status != model.CampaignSending {
+			return ErrCampaignFinished
+		}
+
+		columns := []string{
+			"id", "recipient_id", "recipient_type", "notification_type", "title", "message",
+			"payload", "reference_id", "read", "created_at",
+		}
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"notifications"}, columns,
+			pgx.CopyFromSlice(len(notifications), func(i int) ([]interface{}, error) {
+				notification := notifications[i]
+				payload, err := json.Marshal(notification.Payload)
+				if err != nil {
+					return nil, err
+				}
+				return []interface{}{
+					notification.ID,
+					notification.RecipientID,
+					string(notification.RecipientType),
+					string(notification.NotificationType),
+					notification.Title,
+					notification.Message,
+					payload,
+					notification.ReferenceID,
+					notification.Read,
+					notification.CreatedAt,
+				}, nil
+			}),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to copy campaign notifications: %w", err)
+		}
+
+		next := model.CampaignSending
+		var finishedAt *time.Time
+		if done {
+			next = model.CampaignCompleted
+			finishedAt = &at
+		}
+
+		query := `
+			UPDATE campaigns
+			SET status = $2, last_recipient_id = $3, sent = sent + $4, updated_at = $5, finished_at = $6
+			WHERE id = $1
+		`
+		if _, err := tx.Exec(ctx, query, campaignID, next, lastRecipientID, len(notifications), at, finishedAt); err != nil {
+			return fmt.Errorf("failed to update campaign progress: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// finishedOrMissing tells apart a campaign that has finished from one that does not exist
+func (r *CampaignRepository) finishedOrMissing(ctx context.Context, campaignID string) error {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM campaigns WHERE id = $1)`, campaignID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to look up campaign: %w", err)
+	}
+	if !exists {
+		return ErrCampaignNotFound
+	}
+	return ErrCampaignFinished
+}
+
+// scanCampaigns scans every row of a campaign query
+func scanCampaigns(rows pgx.Rows) ([]*model.Campaign, error) {
+	campaigns := []*model.Campaign{}
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		campaigns = append(campaigns, campaign)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating campaigns: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+// scanCampaign scans a row of campaignColumns
+func scanCampaign(row pgx.Row) (*model.Campaign, error) {
+	var campaign model.Campaign
+	err := row.Scan(
+		&campaign.ID,
+		&campaign.Segment,
+		&campaign.City,
+		&campaign.ActiveWithinDays,
+		&campaign.NotificationType,
+		&campaign.Title,
+		&campaign.Message,
+		&campaign.Payload,
+		&campaign.Status,
+		&campaign.LastRecipientID,
+		&campaign.Sent,
+		&campaign.CreatedBy,
+		&campaign.CancelledBy,
+		&campaign.CreatedAt,
+		&campaign.UpdatedAt,
+		&campaign.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/notification/internal/model"
+)
+
+// DeviceRepository handles database operations for the devices push notifications go to
+type DeviceRepository struct {
+	db *database.PostgresDB
+}
+
+// NewDeviceRepository creates a new device repository
+func NewDeviceRepository(db *database.PostgresDB) *DeviceRepository {
+	return &DeviceRepository{
+		db: db,
+	}
+}
+
+// RegisterDevice stores a device's push token, replacing the token it had. The token is
+// taken from any other device holding it, and the device's CreatedAt is set to when it
+// was first registered.
+func (r *DeviceRepository) RegisterDevice(ctx context.Context, device *model.Device) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			DELETE FROM devices
+			WHERE platform = $1 AND token = $2
+			  AND (owner_id <> $3 OR device_id <> $4)
+		`, device.Platform, device.Token, device.OwnerID, device.DeviceID)
+		if err != nil {
+			return fmt.Errorf("failed to release token: %w", err)
+		}
+
+		query := `
+			INSERT INTO devices (
+				owner_id, owner_type, device_id, platform, token, app_version, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (owner_id, device_id) DO UPDATE
+			SET owner_type = EXCLUDED.owner_type,
+			    platform = EXCLUDED.platform,
+			    token = EXCLUDED.token,
+			    app_version = EXCLUDED.app_version,
+			    updated_at = EXCLUDED.updated_at
+			RETURNING created_at
+		`
+
+		err = tx.QueryRow(ctx, query,
+			device.OwnerID,
+			device.OwnerType,
+			device.DeviceID,
+			device.Platform,
+			device.Token,
+			device.AppVersion,
+			device.CreatedAt,
+			device.UpdatedAt,
+		).Scan(&device.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to register device: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// UnregisterDevice removes one of an owner's devices
+func (r *DeviceRepository) UnregisterDevice(ctx context.Context, ownerID, deviceID string) error {
+	tag, err := r.db.ExecContext(ctx, `DELETE FROM devices WHERE owner_id = $1 AND device_id = $2`, ownerID, deviceID)
+	if err != nil {
+		return fmt.Errorf("failed to unregister device: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDeviceNotFound
+	}
+
+	return nil
+}
+
+// ListDevices lists an owner's devices, most recently registered first. An empty
+// platform lists devices on any platform.
+func (r *DeviceRepository) ListDevices(ctx context.Context, ownerID string, platform model.DevicePlatform) ([]*model.Device, error) {
+	query := `
+		SELECT owner_id, owner_type, device_id, platform, token, app_version, created_at, updated_at
+		FROM devices
+		WHERE owner_id = $1 AND ($2 = '' OR platform = $2)
+		ORDER BY updated_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerID, platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []*model.Device{}
+	for rows.Next() {
+		var device model.Device
+		err := rows.Scan(
+			&device.OwnerID,
+			&device.OwnerType,
+			&device.DeviceID,
+			&device.Platform,
+			&device.Token,
+			&device.AppVersion,
+			&device.CreatedAt,
+			&device.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		devices = append(devices, &device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+// PruneTokens removes the devices holding any of tokens on a platform and reports how
+// many it removed
+func (r *DeviceRepository) PruneTokens(ctx context.Context, platform model.DevicePlatform, tokens []string) (int64, error) {
+	tag, err := r.db.ExecContext(ctx, `DELETE FROM devices WHERE platform = $1 AND token = ANY($2)`, platform, tokens)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune device tokens: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,19 @@
+package repository
+
+import "errors"
+
+var (
+	// ErrNotificationNotFound is returned when a notification is not found, or was not
+	// sent to the recipient asking for it
+	ErrNotificationNotFound = errors.New("notification not found")
+
+	// ErrCampaignNotFound is returned when a campaign is not found
+	ErrCampaignNotFound = errors.New("campaign not found")
+
+	// ErrCampaignFinished is returned when a campaign that completed or was cancelled is
+	// cancelled or sent to
+	ErrCampaignFinished = errors.New("campaign already finished")
+
+	// ErrDeviceNotFound is returned when a device is not registered to its owner
+	ErrDeviceNotFound = errors.New("device not found")
+)
@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository"
+	"github.com/order-api-microservices/services/notification/internal/service"
+)
+
+// CampaignRepository stands in for the Postgres one the campaign service and sender use
+var _ service.CampaignRepository = (*CampaignRepository)(nil)
+
+// CampaignRepository keeps campaigns in memory, storing the notifications they send in a
+// notification repository
+type CampaignRepository struct {
+	mu            sync.RWMutex
+	campaigns     map[string]*model.Campaign
+	notifications *NotificationRepository
+}
+
+// NewCampaignRepository creates an empty campaign repository sending into notifications
+func NewCampaignRepository(notifications *NotificationRepository) *CampaignRepository {
+	return &CampaignRepository{
+		campaigns:     make(map[string]*model.Campaign),
+		notifications: notifications,
+	}
+}
+
+// CreateCampaign stores a new campaign
+func (r *CampaignRepository) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.campaigns[campaign.ID] = cloneCampaign(campaign)
+	return nil
+}
+
+// GetCampaign gets a campaign by ID
+func (r *CampaignRepository) GetCampaign(ctx context.Context, campaignID string) (*model.Campaign, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	campaign, ok := r.campaigns[campaignID]
+	if !ok {
+		return nil, repository.ErrCampaignNotFound
+	}
+	return cloneCampaign(campaign), nil
+}
+
+// ListCampaigns lists a page of campaigns, newest first, with how many there are in all
+func (r *CampaignRepository) ListCampaigns(ctx context.Context, status model.CampaignStatus, page, limit int) ([]*model.Campaign, int, error) {
+	matching := r.list(func(campaign *model.Campaign) bool {
+		return status == "" || campaign.Status == status
+	})
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].CreatedAt.After(matching[j].CreatedAt)
+	})
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	campaigns := []*model.Campaign{}
+	for i := (page - 1) * limit; i < len(matching) && i < page*limit; i++ {
+		campaigns = append(campaigns, matching[i])
+	}
+
+	return campaigns, len(matching), nil
+}
+
+// ListActiveCampaigns lists up to limit campaigns still being sent, oldest first
+func (r *CampaignRepository) ListActiveCampaigns(ctx context.Context, limit int) ([]*model.Campaign, error) {
+	campaigns := r.list((*model.Campaign).Active)
+	sort.SliceStable(campaigns, func(i, j int) bool {
+		return campaigns[i].CreatedAt.Before(campaigns[j].CreatedAt)
+	})
+	if len(campaigns) > limit {
+		campaigns = campaigns[:limit]
+	}
+	return campaigns, nil
+}
+
+// CancelCampaign stops a campaign still being sent
+func (r *CampaignRepository) CancelCampaign(ctx context.Context, campaignID, cancelledBy string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	campaign, ok := r.campaigns[campaignID]
+	if !ok {
+		return repository.ErrCampaignNotFound
+	}
+	if !campaign.Active() {
+		return repository.ErrCampaignFinished
+	}
+
+	campaign.Status = model.CampaignCancelled
+	campaign.CancelledBy = cancelledBy
+	campaign.UpdatedAt = at
+	campaign.FinishedAt = &at
+	return nil
+}
+
+// DeliverCampaignBatch stores a batch of a campaign's notifications and moves the
+// campaign past its last recipient, unless it was cancelled
+func (r *CampaignRepository) DeliverCampaignBatch(ctx context.Context, campaignID string, notifications []*model.Notification, lastRecipientID string, done bool, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	campaign, ok := r.campaigns[campaignID]
+	if !ok {
+		return repository.ErrCampaignNotFound
+	}
+	if !campaign.Active() {
+		return repository.ErrCampaignFinished
+	}
+
+	for _, notification := range notifications {
+		r.notifications.AddNotification(notification)
+	}
+
+	campaign.Status = model.CampaignSending
+	if done {
+		campaign.Status = model.CampaignCompleted
+		campaign.FinishedAt = &at
+	}
+	campaign.LastRecipientID = lastRecipientID
+	campaign.Sent += int64(len(notifications))
+	campaign.UpdatedAt = at
+	return nil
+}
+
+// list returns copies of the campaigns matching keep
+func (r *CampaignRepository) list(keep func(*model.Campaign) bool) []*model.Campaign {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	campaigns := []*model.Campaign{}
+	for _, campaign := range r.campaigns {
+		if keep(campaign) {
+			campaigns = append(campaigns, cloneCampaign(campaign))
+		}
+	}
+	return campaigns
+}
+
+// cloneCampaign copies a campaign so callers cannot change what is stored
+func cloneCampaign(campaign *model.Campaign) *model.Campaign {
+	clone := *campaign
+	if campaign.Payload != nil {
+		clone.Payload = make(model.Payload, len(campaign.Payload))
+		for key, value := range campaign.Payload {
+			clone.Payload[key] = value
+		}
+	}
+	if campaign.FinishedAt != nil {
+		finishedAt := *campaign.FinishedAt
+		clone.FinishedAt = &finishedAt
+	}
+	return &clone
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository"
+	"github.com/order-api-microservices/services/notification/internal/service"
+)
+
+// DeviceRepository stands in for the Postgres one the device service uses
+var _ service.DeviceRepository = (*DeviceRepository)(nil)
+
+// DeviceRepository keeps devices in memory
+type DeviceRepository struct {
+	mu      sync.RWMutex
+	devices []*model.Device
+}
+
+// NewDeviceRepository creates an empty device repository
+func NewDeviceRepository() *DeviceRepository {
+	return &DeviceRepository{}
+}
+
+// RegisterDevice stores a device's push token, taking it from any other device holding it
+func (r *DeviceRepository) RegisterDevice(ctx context.Context, device *model.Device) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.devices[:0]
+	for _, existing := range r.devices {
+		sameDevice := existing.OwnerID == device.OwnerID && existing.DeviceID == device.DeviceID
+		if sameDevice {
+			device.CreatedAt = existing.CreatedAt
+			continue
+		}
+		if existing.Platform == device.Platform && existing.Token == device.Token {
+			continue
+		}
+		kept = append(kept, existing)
+	}
+
+	clone := *device
+	r.devices = append(kept, &clone)
+	return nil
+}
+
+// UnregisterDevice removes one of an owner's devices
+func (r *DeviceRepository) UnregisterDevice(ctx context.Context, ownerID, deviceID string) error {
+	removed := r.remove(func(device *model.Device) bool {
+		return device.OwnerID == ownerID && device.DeviceID == deviceID
+	})
+	if removed == 0 {
+		return repository.ErrDeviceNotFound
+	}
+	return nil
+}
+
+// ListDevices lists an owner's devices, most recently registered first
+func (r *DeviceRepository) ListDevices(ctx context.Context, ownerID string, platform model.DevicePlatform) ([]*model.Device, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	devices := []*model.Device{}
+	for _, device := range r.devices {
+		if device.OwnerID == ownerID && (platform == "" || device.Platform == platform) {
+			clone := *device
+			devices = append(devices, &clone)
+		}
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		return devices[i].UpdatedAt.After(devices[j].UpdatedAt)
+	})
+
+	return devices, nil
+}
+
+// PruneTokens removes the devices holding any of tokens on a platform
+func (r *DeviceRepository) PruneTokens(ctx context.Context, platform model.DevicePlatform, tokens []string) (int64, error) {
+	pruned := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		pruned[token] = true
+	}
+
+	return r.remove(func(device *model.Device) bool {
+		return device.Platform == platform && pruned[device.Token]
+	}), nil
+}
+
+// remove deletes the devices matching match and reports how many it deleted
+func (r *DeviceRepository) remove(match func(*model.Device) bool) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var removed int64
+	kept := r.devices[:0]
+	for _, device := range r.devices {
+		if match(device) {
+			removed++
+			continue
+		}
+		kept = append(kept, device)
+	}
+	r.devices = kept
+
+	return removed
+}
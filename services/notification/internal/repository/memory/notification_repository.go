@@ -0,0 +1,191 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository"
+	"github.com/order-api-microservices/services/notification/internal/service"
+)
+
+// NotificationRepository stands in for the Postgres one the notification and inbox
+// services and the retention job use
+var (
+	_ service.NotificationRepository = (*NotificationRepository)(nil)
+	_ service.InboxRepository        = (*NotificationRepository)(nil)
+	_ service.RetentionRepository    = (*NotificationRepository)(nil)
+)
+
+// NotificationRepository keeps notifications in memory
+type NotificationRepository struct {
+	mu            sync.RWMutex
+	notifications []*model.Notification
+}
+
+// NewNotificationRepository creates an empty notification repository
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{}
+}
+
+// AddNotification stores a notification as if it had been sent
+func (r *NotificationRepository) AddNotification(notification *model.Notification) {
+	if notification.ID == "" {
+		notification.ID = uuid.New().String()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notifications = append(r.notifications, cloneNotification(notification))
+}
+
+// CreateNotification stores a notification
+func (r *NotificationRepository) CreateNotification(ctx context.Context, notification *model.Notification) error {
+	r.AddNotification(notification)
+	return nil
+}
+
+// ListRecipientNotifications lists a page of the notifications sent to a recipient, newest
+// first, with how many there are in all
+func (r *NotificationRepository) ListRecipientNotifications(ctx context.Context, recipientID string, includeRead bool, page, limit int) ([]*model.Notification, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matching := []*model.Notification{}
+	for _, notification := range r.notifications {
+		if notification.RecipientID != recipientID || (notification.Read && !includeRead) {
+			continue
+		}
+		matching = append(matching, notification)
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].CreatedAt.After(matching[j].CreatedAt)
+	})
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	notifications := []*model.Notification{}
+	for i := (page - 1) * limit; i < len(matching) && i < page*limit; i++ {
+		notifications = append(notifications, cloneNotification(matching[i]))
+	}
+
+	return notifications, len(matching), nil
+}
+
+// ListReferenceNotifications lists the notifications about something sent to any
+// recipient, oldest first
+func (r *NotificationRepository) ListReferenceNotifications(ctx context.Context, referenceID string) ([]*model.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notifications := []*model.Notification{}
+	for _, notification := range r.notifications {
+		if notification.ReferenceID == referenceID {
+			notifications = append(notifications, cloneNotification(notification))
+		}
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.Before(notifications[j].CreatedAt)
+	})
+
+	return notifications, nil
+}
+
+// CountUnread counts the notifications a recipient has not read
+func (r *NotificationRepository) CountUnread(ctx context.Context, recipientID string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, notification := range r.notifications {
+		if notification.RecipientID == recipientID && !notification.Read {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// MarkAsRead marks a notification sent to a recipient as read
+func (r *NotificationRepository) MarkAsRead(ctx context.Context, notificationID, recipientID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, notification := range r.notifications {
+		if notification.ID != notificationID || notification.RecipientID != recipientID {
+			continue
+		}
+		if !notification.Read {
+			notification.Read = true
+			notification.ReadAt = &at
+		}
+		return nil
+	}
+
+	return repository.ErrNotificationNotFound
+}
+
+// MarkAllRead marks every unread notification sent to a recipient up to a time as read. A
+// zero before marks them all.
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, recipientID string, before, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var marked int64
+	for _, notification := range r.notifications {
+		if notification.RecipientID != recipientID || notification.Read {
+			continue
+		}
+		if !before.IsZero() && notification.CreatedAt.After(before) {
+			continue
+		}
+		readAt := at
+		notification.Read = true
+		notification.ReadAt = &readAt
+		marked++
+	}
+
+	return marked, nil
+}
+
+// PurgeReadNotifications removes up to limit read notifications matching filter. There is
+// no archive in memory, so archiving drops them like deleting does.
+func (r *NotificationRepository) PurgeReadNotifications(ctx context.Context, filter repository.PurgeFilter, archive bool, limit int) (map[model.NotificationType]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := make(map[model.NotificationType]int64)
+	kept := r.notifications[:0]
+	var removed int
+	for _, notification := range r.notifications {
+		if removed < limit && notification.Read && notification.CreatedAt.Before(filter.CreatedBefore) &&
+			(len(filter.Types) == 0 || hasType(filter.Types, notification.NotificationType)) &&
+			!hasType(filter.ExceptTypes, notification.NotificationType) {
+			purged[notification.NotificationType]++
+			removed++
+			continue
+		}
+		kept = append(kept, notification)
+	}
+	r.notifications = kept
+
+	return purged, nil
+}
+
+func hasType(types []model.NotificationType, notificationType model.NotificationType) bool {
+	for _, t := range types {
+		if t == notificationType {
+			return true
+		}
+	}
+	return false
+}
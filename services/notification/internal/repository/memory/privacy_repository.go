@@ -0,0 +1,96 @@
+// Package memory holds in-memory implementations of the notification service's
+// repositories, so the service can be exercised without Postgres
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/service"
+)
+
+// PrivacyRepository stands in for the Postgres one the privacy service uses
+var _ service.PrivacyRepository = (*PrivacyRepository)(nil)
+
+// PrivacyRepository keeps notifications in memory
+type PrivacyRepository struct {
+	mu            sync.RWMutex
+	notifications []*model.Notification
+}
+
+// NewPrivacyRepository creates an empty privacy repository
+func NewPrivacyRepository() *PrivacyRepository {
+	return &PrivacyRepository{}
+}
+
+// AddNotification stores a notification as if it had been sent
+func (r *PrivacyRepository) AddNotification(notification *model.Notification) {
+	if notification.ID == "" {
+		notification.ID = uuid.New().String()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notifications = append(r.notifications, cloneNotification(notification))
+}
+
+// ListRecipientNotifications lists every notification sent to a recipient, oldest first
+func (r *PrivacyRepository) ListRecipientNotifications(ctx context.Context, recipientID string) ([]*model.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notifications := []*model.Notification{}
+	for _, notification := range r.notifications {
+		if notification.RecipientID == recipientID {
+			notifications = append(notifications, cloneNotification(notification))
+		}
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.Before(notifications[j].CreatedAt)
+	})
+
+	return notifications, nil
+}
+
+// AnonymizeRecipientNotifications erases the title, message and payload of every
+// notification sent to a recipient and reports how many were changed
+func (r *PrivacyRepository) AnonymizeRecipientNotifications(ctx context.Context, recipientID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var anonymized int64
+	for _, notification := range r.notifications {
+		if notification.RecipientID != recipientID {
+			continue
+		}
+		if notification.Title == "" && notification.Message == "" && len(notification.Payload) == 0 {
+			continue
+		}
+		notification.Title = ""
+		notification.Message = ""
+		notification.Payload = model.Payload{}
+		anonymized++
+	}
+
+	return anonymized, nil
+}
+
+// cloneNotification copies a notification so callers cannot change what is stored
+func cloneNotification(notification *model.Notification) *model.Notification {
+	clone := *notification
+	if notification.Payload != nil {
+		clone.Payload = make(model.Payload, len(notification.Payload))
+		for key, value := range notification.Payload {
+			clone.Payload[key] = value
+		}
+	}
+	if notification.ReadAt != nil {
+		readAt := *notification.ReadAt
+		clone.ReadAt = &readAt
+	}
+	return &clone
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/notification/internal/model"
+)
+
+// NotificationRepository handles database operations for notifications
+type NotificationRepository struct {
+	db *database.PostgresDB
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *database.PostgresDB) *NotificationRepository {
+	return &NotificationRepository{
+		db: db,
+	}
+}
+
+// CreateNotification stores a notification
+func (r *NotificationRepository) CreateNotification(ctx context.Context, notification *model.Notification) error {
+	query := `
+		INSERT INTO notifications (
+			id, recipient_id, recipient_type, notification_type, title, message,
+			payload, reference_id, read, created_at, read_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		notification.ID,
+		notification.RecipientID,
+		notification.RecipientType,
+		notification.NotificationType,
+		notification.Title,
+		notification.Message,
+		notification.Payload,
+		notification.ReferenceID,
+		notification.Read,
+		notification.CreatedAt,
+		notification.ReadAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return nil
+}
+
+// ListRecipientNotifications lists a page of the notifications sent to a recipient, newest
+// first, with how many there are in all. Read notifications are left out unless
+// includeRead is set.
+func (r *NotificationRepository) ListRecipientNotifications(ctx context.Context, recipientID string, includeRead bool, page, limit int) ([]*model.Notification, int, error) {
+	whereClause := "recipient_id = $1"
+	if !includeRead {
+		whereClause += " AND NOT read"
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE `+whereClause, recipientID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	// Set reasonable defaults and boundaries
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	query := `
+		SELECT id, recipient_id, recipient_type, notification_type, title, message,
+		       payload, reference_id, read, created_at, read_at
+		FROM notifications
+		WHERE ` + whereClause + `
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, recipientID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*model.Notification{}
+	for rows.Next() {
+		var notification model.Notification
+		err := rows.Scan(
+			&notification.ID,
+			&notification.RecipientID,
+			&notification.RecipientType,
+			&notification.NotificationType,
+			&notification.Title,
+			&notification.Message,
+			&notification.Payload,
+			&notification.ReferenceID,
+			&notification.Read,
+			&notification.CreatedAt,
+			&notification.ReadAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, &notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating notifications: %w", err)
+	}
+
+	return notifications, total, nil
+}
+
+// ListReferenceNotifications lists the notifications about something, such as an order,
+// sent to any recipient, oldest first
+func (r *NotificationRepository) ListReferenceNotifications(ctx context.Context, referenceID string) ([]*model.Notification, error) {
+	query := `
+		SELECT id, recipient_id, recipient_type, notification_type, title, message,
+		       payload, reference_id, read, created_at, read_at
+		FROM notifications
+		WHERE reference_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, referenceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*model.Notification{}
+	for rows.Next() {
+		var notification model.Notification
+		err := rows.Scan(
+			&notification.ID,
+			&notification.RecipientID,
+			&notification.RecipientType,
+			&notification.NotificationType,
+			&notification.Title,
+			&notification.Message,
+			&notification.Payload,
+			&notification.ReferenceID,
+			&notification.Read,
+			&notification.CreatedAt,
+			&notification.ReadAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, &notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// CountUnread counts the notifications a recipient has not read. It only reads the
+// partial index of unread notifications.
+func (r *NotificationRepository) CountUnread(ctx context.Context, recipientID string) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE recipient_id = $1 AND NOT read`, recipientID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	return count, nil
+}
+
+// MarkAsRead marks a notification sent to a recipient as read. Marking a read
+// notification again keeps when it was first read.
+func (r *NotificationRepository) MarkAsRead(ctx context.Context, notificationID, recipientID string, at time.Time) error {
+	query := `
+		UPDATE notifications
+		SET read = TRUE, read_at = COALESCE(read_at, $3)
+		WHERE id = $1 AND recipient_id = $2
+	`
+
+	tag, err := r.db.ExecContext(ctx, query, notificationID, recipientID, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification as read: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification sent to a recipient up to a time as read,
+// and reports how many there were. A zero before marks them all.
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, recipientID string, before, at time.Time) (int64, error) {
+	query := `
+		UPDATE notifications
+		SET read = TRUE, read_at = $3
+		WHERE recipient_id = $1 AND NOT read
+		  AND ($2::TIMESTAMP IS NULL OR created_at <= $2)
+	`
+
+	var cutoff *time.Time
+	if !before.IsZero() {
+		cutoff = &before
+	}
+
+	tag, err := r.db.ExecContext(ctx, query, recipientID, cutoff, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,276 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/internal/testharness"
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository"
+)
+
+func TestNotificationRepositoryUnreadCount(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "notification")
+	repo := repository.NewNotificationRepository(db)
+
+	recipient := uuid.New().String()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for _, createdAt := range []time.Time{now.Add(-time.Hour), now.Add(-30 * time.Minute), now} {
+		err := repo.CreateNotification(ctx, &model.Notification{
+			ID:               uuid.New().String(),
+			RecipientID:      recipient,
+			RecipientType:    model.RecipientTypeUser,
+			NotificationType: model.NotificationTypeOrderCreated,
+			Title:            "Order created",
+			Payload:          model.Payload{},
+			CreatedAt:        createdAt,
+		})
+		if err != nil {
+			t.Fatalf("CreateNotification: %v", err)
+		}
+	}
+
+	count, err := repo.CountUnread(ctx, recipient)
+	if err != nil {
+		t.Fatalf("CountUnread: %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("unread count = %d, want 3", count)
+	}
+
+	marked, err := repo.MarkAllRead(ctx, recipient, now.Add(-time.Minute), now)
+	if err != nil {
+		t.Fatalf("MarkAllRead: %v", err)
+	}
+	if marked != 2 {
+		t.Errorf("marked %d notifications, want 2", marked)
+	}
+
+	marked, err = repo.MarkAllRead(ctx, recipient, time.Time{}, now)
+	if err != nil {
+		t.Fatalf("MarkAllRead: %v", err)
+	}
+	if marked != 1 {
+		t.Errorf("marked %d notifications, want 1", marked)
+	}
+
+	count, err = repo.CountUnread(ctx, recipient)
+	if err != nil {
+		t.Fatalf("CountUnread: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("unread count = %d, want 0", count)
+	}
+}
+
+func TestNotificationRepositoryPurgeReadNotifications(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "notification")
+	repo := repository.NewNotificationRepository(db)
+	privacyRepo := repository.NewPrivacyRepository(db)
+
+	recipient := uuid.New().String()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	add := func(notificationType model.NotificationType, age time.Duration, read bool) {
+		err := repo.CreateNotification(ctx, &model.Notification{
+			ID:               uuid.New().String(),
+			RecipientID:      recipient,
+			RecipientType:    model.RecipientTypeUser,
+			NotificationType: notificationType,
+			Title:            "Order update",
+			Payload:          model.Payload{},
+			Read:             read,
+			CreatedAt:        now.Add(-age),
+		})
+		if err != nil {
+			t.Fatalf("CreateNotification: %v", err)
+		}
+	}
+	add(model.NotificationTypeOrderCreated, 48*time.Hour, false)
+	add(model.NotificationTypeOrderCreated, 48*time.Hour, true)
+	add(model.NotificationTypeOrderCreated, time.Hour, true)
+	add(model.NotificationTypePaymentProcessed, 48*time.Hour, true)
+
+	purged, err := repo.PurgeReadNotifications(ctx, repository.PurgeFilter{
+		CreatedBefore: now.Add(-24 * time.Hour),
+		ExceptTypes:   []model.NotificationType{model.NotificationTypePaymentProcessed},
+	}, true, 10)
+	if err != nil {
+		t.Fatalf("PurgeReadNotifications: %v", err)
+	}
+	if len(purged) != 1 || purged[model.NotificationTypeOrderCreated] != 1 {
+		t.Errorf("purged = %v, want one ORDER_CREATED", purged)
+	}
+
+	purged, err = repo.PurgeReadNotifications(ctx, repository.PurgeFilter{
+		CreatedBefore: now.Add(-24 * time.Hour),
+		Types:         []model.NotificationType{model.NotificationTypePaymentProcessed},
+	}, false, 10)
+	if err != nil {
+		t.Fatalf("PurgeReadNotifications: %v", err)
+	}
+	if len(purged) != 1 || purged[model.NotificationTypePaymentProcessed] != 1 {
+		t.Errorf("purged = %v, want one PAYMENT_PROCESSED", purged)
+	}
+
+	// The archived notification is still exported; the deleted one is gone
+	notifications, err := privacyRepo.ListRecipientNotifications(ctx, recipient)
+	if err != nil {
+		t.Fatalf("ListRecipientNotifications: %v", err)
+	}
+	if len(notifications) != 3 {
+		t.Errorf("exported %d notifications, want 3", len(notifications))
+	}
+
+	anonymized, err := privacyRepo.AnonymizeRecipientNotifications(ctx, recipient)
+	if err != nil {
+		t.Fatalf("AnonymizeRecipientNotifications: %v", err)
+	}
+	if anonymized != 3 {
+		t.Errorf("anonymized %d notifications, want 3", anonymized)
+	}
+}
+
+func TestCampaignRepositoryDeliverCampaignBatch(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "notification")
+	notifications := repository.NewNotificationRepository(db)
+	repo := repository.NewCampaignRepository(db)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	campaign := &model.Campaign{
+		ID:               uuid.New().String(),
+		Segment:          model.SegmentRecentUsers,
+		ActiveWithinDays: 30,
+		NotificationType: model.NotificationTypeCampaign,
+		Title:            "We miss you",
+		Payload:          model.Payload{},
+		Status:           model.CampaignQueued,
+		CreatedBy:        "admin-1",
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := repo.CreateCampaign(ctx, campaign); err != nil {
+		t.Fatalf("CreateCampaign: %v", err)
+	}
+
+	batch := func(recipients ...string) []*model.Notification {
+		var result []*model.Notification
+		for _, recipient := range recipients {
+			result = append(result, &model.Notification{
+				ID:               uuid.New().String(),
+				RecipientID:      recipient,
+				RecipientType:    model.RecipientTypeUser,
+				NotificationType: campaign.NotificationType,
+				Title:            campaign.Title,
+				Payload:          model.Payload{},
+				ReferenceID:      campaign.ID,
+				CreatedAt:        now,
+			})
+		}
+		return result
+	}
+
+	first, second := uuid.New().String(), uuid.New().String()
+	if err := repo.DeliverCampaignBatch(ctx, campaign.ID, batch(first, second), second, false, now); err != nil {
+		t.Fatalf("DeliverCampaignBatch: %v", err)
+	}
+
+	got, err := repo.GetCampaign(ctx, campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaign: %v", err)
+	}
+	if got.Status != model.CampaignSending || got.Sent != 2 || got.LastRecipientID != second {
+		t.Errorf("campaign = %s, %d sent, last %s; want SENDING, 2 sent, last %s", got.Status, got.Sent, got.LastRecipientID, second)
+	}
+	if count, _ := notifications.CountUnread(ctx, first); count != 1 {
+		t.Errorf("first recipient has %d notifications, want 1", count)
+	}
+
+	active, err := repo.ListActiveCampaigns(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListActiveCampaigns: %v", err)
+	}
+	if len(active) != 1 || active[0].ID != campaign.ID {
+		t.Errorf("active campaigns = %v, want the one campaign", active)
+	}
+
+	if err := repo.CancelCampaign(ctx, campaign.ID, "admin-2", now); err != nil {
+		t.Fatalf("CancelCampaign: %v", err)
+	}
+	third := uuid.New().String()
+	err = repo.DeliverCampaignBatch(ctx, campaign.ID, batch(third), third, true, now)
+	if !errors.Is(err, repository.ErrCampaignFinished) {
+		t.Fatalf("DeliverCampaignBatch after cancel = %v, want ErrCampaignFinished", err)
+	}
+	if count, _ := notifications.CountUnread(ctx, third); count != 0 {
+		t.Errorf("recipient was notified after the cancel")
+	}
+	if err := repo.CancelCampaign(ctx, campaign.ID, "admin-2", now); !errors.Is(err, repository.ErrCampaignFinished) {
+		t.Errorf("cancelling twice = %v, want ErrCampaignFinished", err)
+	}
+
+	got, _ = repo.GetCampaign(ctx, campaign.ID)
+	if got.Status != model.CampaignCancelled || got.CancelledBy != "admin-2" || got.FinishedAt == nil {
+		t.Errorf("campaign = %s by %q, finished %v; want CANCELLED by admin-2", got.Status, got.CancelledBy, got.FinishedAt)
+	}
+}
+
+func TestDeviceRepositoryRegisterDevice(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "notification")
+	repo := repository.NewDeviceRepository(db)
+
+	alice, bob := uuid.New().String(), uuid.New().String()
+	first := time.Now().UTC().Truncate(time.Microsecond)
+	register := func(ownerID, deviceID, token string, at time.Time) *model.Device {
+		t.Helper()
+		device := &model.Device{
+			OwnerID:   ownerID,
+			OwnerType: model.RecipientTypeUser,
+			DeviceID:  deviceID,
+			Platform:  model.PlatformFCM,
+			Token:     token,
+			CreatedAt: at,
+			UpdatedAt: at,
+		}
+		if err := repo.RegisterDevice(ctx, device); err != nil {
+			t.Fatalf("RegisterDevice: %v", err)
+		}
+		return device
+	}
+
+	register(alice, "phone", "token-1", first)
+	refreshed := register(alice, "phone", "token-2", first.Add(time.Hour))
+	if !refreshed.CreatedAt.Equal(first) {
+		t.Errorf("refreshed device created at %v, want when first registered %v", refreshed.CreatedAt, first)
+	}
+
+	// The token moves to whoever registers it last
+	register(bob, "phone", "token-2", first.Add(2*time.Hour))
+	devices, err := repo.ListDevices(ctx, alice, "")
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	if len(devices) != 0 {
+		t.Errorf("alice has %d devices, want 0", len(devices))
+	}
+
+	pruned, err := repo.PruneTokens(ctx, model.PlatformFCM, []string{"token-2", "unknown"})
+	if err != nil {
+		t.Fatalf("PruneTokens: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("pruned %d devices, want 1", pruned)
+	}
+
+	if err := repo.UnregisterDevice(ctx, bob, "phone"); !errors.Is(err, repository.ErrDeviceNotFound) {
+		t.Errorf("unregistering a pruned device = %v, want ErrDeviceNotFound", err)
+	}
+}
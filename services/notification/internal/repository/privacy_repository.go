@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/notification/internal/model"
+)
+
+// PrivacyRepository handles the database operations behind data protection requests
+type PrivacyRepository struct {
+	db *database.PostgresDB
+}
+
+// NewPrivacyRepository creates a new privacy repository
+func NewPrivacyRepository(db *database.PostgresDB) *PrivacyRepository {
+	return &PrivacyRepository{
+		db: db,
+	}
+}
+
+// ListRecipientNotifications lists every notification sent to a recipient, archived ones
+// included, oldest first
+func (r *PrivacyRepository) ListRecipientNotifications(ctx context.Context, recipientID string) ([]*model.Notification, error) {
+	query := `
+		SELECT id, recipient_id, recipient_type, notification_type, title, message,
+		       payload, reference_id, read, created_at, read_at
+		FROM notifications
+		WHERE recipient_id = $1
+		UNION ALL
+		SELECT id, recipient_id, recipient_type, notification_type, title, message,
+		       payload, reference_id, read, created_at, read_at
+		FROM notifications_archive
+		WHERE recipient_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, recipientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*model.Notification{}
+	for rows.Next() {
+		var notification model.Notification
+		err := rows.Scan(
+			&notification.ID,
+			&notification.RecipientID,
+			&notification.RecipientType,
+			&notification.NotificationType,
+			&notification.Title,
+			&notification.Message,
+			&notification.Payload,
+			&notification.ReferenceID,
+			&notification.Read,
+			&notification.CreatedAt,
+			&notification.ReadAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, &notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// AnonymizeRecipientNotifications erases the title, message and payload of every
+// notification sent to a recipient, archived ones included, and reports how many were
+// changed. The rows are kept, with their type and reference, so delivery history still
+// adds up.
+func (r *PrivacyRepository) AnonymizeRecipientNotifications(ctx context.Context, recipientID string) (int64, error) {
+	var anonymized int64
+	for _, table := range []string{"notifications", "notifications_archive"} {
+		query := `
+			UPDATE ` + table + `
+			SET title = '', message = '', payload = '{}'
+			WHERE recipient_id = $1
+			  AND (title <> '' OR message <> '' OR payload <> '{}'::JSONB)
+		`
+
+		tag, err := r.db.ExecContext(ctx, query, recipientID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to anonymize %s: %w", table, err)
+		}
+		anonymized += tag.RowsAffected()
+	}
+
+	return anonymized, nil
+}
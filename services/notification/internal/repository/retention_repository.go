@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/services/notification/internal/model"
+)
+
+// PurgeFilter picks the read notifications a purge removes
+type PurgeFilter struct {
+	CreatedBefore time.Time                // Only notifications sent before this
+	Types         []model.NotificationType // Only these types, when set
+	ExceptTypes   []model.NotificationType // Not these types
+}
+
+// PurgeReadNotifications removes up to limit read notifications matching filter, moving
+// them to notifications_archive when archive is set and deleting them otherwise. It
+// reports how many it removed of each type.
+func (r *NotificationRepository) PurgeReadNotifications(ctx context.Context, filter PurgeFilter, archive bool, limit int) (map[model.NotificationType]int64, error) {
+	purge := `
+		DELETE FROM notifications
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE read AND created_at < $1
+			  AND (CARDINALITY($2::TEXT[]) = 0 OR notification_type = ANY($2))
+			  AND NOT (notification_type = ANY($3))
+			LIMIT $4
+		)
+	`
+
+	var query string
+	if archive {
+		query = `
+			WITH purged AS (` + purge + ` RETURNING *),
+			archived AS (
+				INSERT INTO notifications_archive (
+					id, recipient_id, recipient_type, notification_type, title, message,
+					payload, reference_id, read, created_at, read_at, archived_at
+				)
+				SELECT id, recipient_id, recipient_type, notification_type, title, message,
+				       payload, reference_id, read, created_at, read_at, NOW()
+				FROM purged
+				RETURNING notification_type
+			)
+			SELECT notification_type, COUNT(*) FROM archived GROUP BY notification_type
+		`
+	} else {
+		query = `
+			WITH purged AS (` + purge + ` RETURNING notification_type)
+			SELECT notification_type, COUNT(*) FROM purged GROUP BY notification_type
+		`
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, filter.CreatedBefore, typeNames(filter.Types), typeNames(filter.ExceptTypes), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge notifications: %w", err)
+	}
+	defer rows.Close()
+
+	purged := make(map[model.NotificationType]int64)
+	for rows.Next() {
+		var notificationType model.NotificationType
+		var count int64
+		if err := rows.Scan(&notificationType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan purged notifications: %w", err)
+		}
+		purged[notificationType] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating purged notifications: %w", err)
+	}
+
+	return purged, nil
+}
+
+// typeNames converts notification types to the text array Postgres compares them against
+func typeNames(types []model.NotificationType) []string {
+	names := make([]string, len(types))
+	for i, notificationType := range types {
+		names[i] = string(notificationType)
+	}
+	return names
+}
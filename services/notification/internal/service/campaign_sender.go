@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// campaignSentCounter counts the notifications campaigns have sent
+var campaignSentCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notification_campaign_sent_total",
+	Help: "Notifications sent by broadcast campaigns, by segment",
+}, []string{"segment"})
+
+// ProviderDirectory pages through the providers of some service areas. It is implemented
+// by clients.ProviderGRPCClient.
+type ProviderDirectory interface {
+	ListProviderIDs(ctx context.Context, serviceAreaIDs []string, afterID string, limit int) ([]string, error)
+}
+
+// OrderDirectory looks up a city's service areas and pages through the users who ordered
+// recently. It is implemented by clients.OrderGRPCClient.
+type OrderDirectory interface {
+	ListServiceAreaIDs(ctx context.Context, city string) ([]string, error)
+	ListActiveUserIDs(ctx context.Context, since time.Time, afterID string, limit int) ([]string, error)
+}
+
+// CampaignSenderConfig controls how quickly campaigns are sent
+type CampaignSenderConfig struct {
+	Interval  time.Duration // How often a batch of each active campaign is sent
+	BatchSize int           // Most recipients notified per batch
+	MaxActive int           // Most campaigns sent at once; later ones wait their turn
+}
+
+// CampaignSender sends active campaigns in the background, a batch of each at a time,
+// oldest first. Each batch is stored with the campaign's progress in one transaction, so
+// a restart resumes after the last recipient notified and notifies nobody twice.
+type CampaignSender struct {
+	repo      CampaignRepository
+	providers ProviderDirectory
+	orders    OrderDirectory
+	cfg       CampaignSenderConfig
+}
+
+// NewCampaignSender creates a new campaign sender
+func NewCampaignSender(repo CampaignRepository, providers ProviderDirectory, orders OrderDirectory, cfg CampaignSenderConfig) *CampaignSender {
+	return &CampaignSender{
+		repo:      repo,
+		providers: providers,
+		orders:    orders,
+		cfg:       cfg,
+	}
+}
+
+// Run sends a batch of every active campaign each interval until ctx is cancelled
+func (s *CampaignSender) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Send(ctx, time.Now()); err != nil {
+				log.Printf("Failed to send campaigns: %v", err)
+			}
+		}
+	}
+}
+
+// Send sends the next batch of every active campaign and reports how many notifications
+// it sent. A campaign whose batch fails is retried on the next call.
+func (s *CampaignSender) Send(ctx context.Context, now time.Time) (int64, error) {
+	campaigns, err := s.repo.ListActiveCampaigns(ctx, s.cfg.MaxActive)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, campaign := range campaigns {
+		if ctx.Err() != nil {
+			break
+		}
+		sent, err := s.sendBatch(ctx, campaign, now)
+		if err != nil {
+			log.Printf("Failed to send a batch of campaign %s: %v", campaign.ID, err)
+			continue
+		}
+		total += int64(sent)
+	}
+
+	return total, nil
+}
+
+// sendBatch notifies the next batch of a campaign's segment and reports how many it
+// notified
+func (s *CampaignSender) sendBatch(ctx context.Context, campaign *model.Campaign, now time.Time) (int, error) {
+	recipientIDs, err := s.recipients(ctx, campaign)
+	if err != nil {
+		return 0, err
+	}
+
+	notifications := make([]*model.Notification, len(recipientIDs))
+	for i, recipientID := range recipientIDs {
+		notifications[i] = &model.Notification{
+			ID:               uuid.New().String(),
+			RecipientID:      recipientID,
+			RecipientType:    campaign.RecipientType(),
+			NotificationType: campaign.NotificationType,
+			Title:            campaign.Title,
+			Message:          campaign.Message,
+			Payload:          campaign.Payload,
+			ReferenceID:      campaign.ID,
+			CreatedAt:        now,
+		}
+	}
+
+	lastRecipientID := campaign.LastRecipientID
+	if len(recipientIDs) > 0 {
+		lastRecipientID = recipientIDs[len(recipientIDs)-1]
+	}
+	done := len(recipientIDs) < s.cfg.BatchSize
+
+	err = s.repo.DeliverCampaignBatch(ctx, campaign.ID, notifications, lastRecipientID, done, now)
+	if errors.Is(err, repository.ErrCampaignFinished) {
+		// Cancelled while the batch was being put together
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	campaignSentCounter.WithLabelValues(string(campaign.Segment)).Add(float64(len(notifications)))
+	if done {
+		log.Printf("Campaign %s completed", campaign.ID)
+	}
+
+	return len(notifications), nil
+}
+
+// recipients lists the next batch of a campaign's segment, after its last recipient
+func (s *CampaignSender) recipients(ctx context.Context, campaign *model.Campaign) ([]string, error) {
+	switch campaign.Segment {
+	case model.SegmentProvidersInCity:
+		serviceAreaIDs, err := s.orders.ListServiceAreaIDs(ctx, campaign.City)
+		if err != nil {
+			return nil, err
+		}
+		if len(serviceAreaIDs) == 0 {
+			return nil, nil
+		}
+		return s.providers.ListProviderIDs(ctx, serviceAreaIDs, campaign.LastRecipientID, s.cfg.BatchSize)
+	case model.SegmentRecentUsers:
+		since := campaign.CreatedAt.AddDate(0, 0, -campaign.ActiveWithinDays)
+		return s.orders.ListActiveUserIDs(ctx, since, campaign.LastRecipientID, s.cfg.BatchSize)
+	default:
+		return nil, fmt.Errorf("unknown segment %q", campaign.Segment)
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/notification"
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// defaultActiveWithinDays is how recently users must have ordered to be in a RECENT_USERS
+// campaign that does not say
+const defaultActiveWithinDays = 30
+
+// CampaignRepository stores broadcast campaigns and the notifications they send. It is
+// implemented by repository.CampaignRepository on Postgres and by
+// memory.CampaignRepository for tests.
+type CampaignRepository interface {
+	CreateCampaign(ctx context.Context, campaign *model.Campaign) error
+	GetCampaign(ctx context.Context, campaignID string) (*model.Campaign, error)
+	ListCampaigns(ctx context.Context, status model.CampaignStatus, page, limit int) ([]*model.Campaign, int, error)
+	ListActiveCampaigns(ctx context.Context, limit int) ([]*model.Campaign, error)
+	CancelCampaign(ctx context.Context, campaignID, cancelledBy string, at time.Time) error
+	DeliverCampaignBatch(ctx context.Context, campaignID string, notifications []*model.Notification, lastRecipientID string, done bool, at time.Time) error
+}
+
+// CampaignService lets admins broadcast notifications to a segment and follow or stop
+// their sending, which CampaignSender does in the background
+type CampaignService struct {
+	pb.UnimplementedNotificationCampaignServiceServer
+	repo CampaignRepository
+}
+
+// NewCampaignService creates a new campaign service
+func NewCampaignService(repo CampaignRepository) *CampaignService {
+	return &CampaignService{
+		repo: repo,
+	}
+}
+
+// CreateCampaign queues a notification for every recipient in a segment. The segment is
+// worked out as the campaign is sent, so a provider joining the city meanwhile may get it.
+func (s *CampaignService) CreateCampaign(ctx context.Context, req *pb.CreateCampaignRequest) (*pb.CampaignResponse, error) {
+	segment := model.CampaignSegment(req.Segment)
+	city := strings.TrimSpace(req.City)
+	activeWithinDays := int(req.ActiveWithinDays)
+	switch segment {
+	case model.SegmentProvidersInCity:
+		if city == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "city is required for %s campaigns", segment)
+		}
+		activeWithinDays = 0
+	case model.SegmentRecentUsers:
+		if activeWithinDays == 0 {
+			activeWithinDays = defaultActiveWithinDays
+		}
+		city = ""
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown segment %q", req.Segment)
+	}
+
+	payload, err := parsePayload(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	notificationType := model.NotificationType(req.NotificationType)
+	if notificationType == "" {
+		notificationType = model.NotificationTypeCampaign
+	}
+
+	now := time.Now()
+	campaign := &model.Campaign{
+		ID:               uuid.New().String(),
+		Segment:          segment,
+		City:             city,
+		ActiveWithinDays: activeWithinDays,
+		NotificationType: notificationType,
+		Title:            req.Title,
+		Message:          req.Message,
+		Payload:          payload,
+		Status:           model.CampaignQueued,
+		CreatedBy:        req.CreatedBy,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.repo.CreateCampaign(ctx, campaign); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create campaign: %v", err)
+	}
+
+	return campaignResponse(campaign, "Campaign queued")
+}
+
+// GetCampaign returns a campaign and how far sending it has got
+func (s *CampaignService) GetCampaign(ctx context.Context, req *pb.GetCampaignRequest) (*pb.CampaignResponse, error) {
+	campaign, err := s.repo.GetCampaign(ctx, req.CampaignId)
+	if err != nil {
+		return nil, campaignError(err, "failed to get campaign")
+	}
+
+	return campaignResponse(campaign, "")
+}
+
+// ListCampaigns lists a page of campaigns, newest first
+func (s *CampaignService) ListCampaigns(ctx context.Context, req *pb.ListCampaignsRequest) (*pb.ListCampaignsResponse, error) {
+	campaigns, total, err := s.repo.ListCampaigns(ctx, model.CampaignStatus(req.Status), int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list campaigns: %v", err)
+	}
+
+	protoCampaigns := make([]*pb.Campaign, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		protoCampaign, err := convertCampaignToProto(campaign)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to convert campaign: %v", err)
+		}
+		protoCampaigns = append(protoCampaigns, protoCampaign)
+	}
+
+	return &pb.ListCampaignsResponse{
+		Campaigns: protoCampaigns,
+		Total:     int32(total),
+		Page:      req.Page,
+		Limit:     req.Limit,
+	}, nil
+}
+
+// CancelCampaign stops a campaign that is still being sent. A batch being sent as it is
+// cancelled is not stored, so nobody is notified once this returns.
+func (s *CampaignService) CancelCampaign(ctx context.Context, req *pb.CancelCampaignRequest) (*pb.CampaignResponse, error) {
+	if err := s.repo.CancelCampaign(ctx, req.CampaignId, req.CancelledBy, time.Now()); err != nil {
+		return nil, campaignError(err, "failed to cancel campaign")
+	}
+
+	campaign, err := s.repo.GetCampaign(ctx, req.CampaignId)
+	if err != nil {
+		return nil, campaignError(err, "failed to get campaign")
+	}
+
+	return campaignResponse(campaign, "Campaign cancelled")
+}
+
+// campaignError maps a campaign repository error to a gRPC status
+func campaignError(err error, message string) error {
+	switch {
+	case errors.Is(err, repository.ErrCampaignNotFound):
+		return status.Errorf(codes.NotFound, "campaign not found")
+	case errors.Is(err, repository.ErrCampaignFinished):
+		return status.Errorf(codes.FailedPrecondition, "campaign already finished")
+	default:
+		return status.Errorf(codes.Internal, "%s: %v", message, err)
+	}
+}
+
+func campaignResponse(campaign *model.Campaign, message string) (*pb.CampaignResponse, error) {
+	protoCampaign, err := convertCampaignToProto(campaign)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert campaign: %v", err)
+	}
+
+	return &pb.CampaignResponse{
+		Campaign: protoCampaign,
+		Success:  true,
+		Message:  message,
+	}, nil
+}
+
+func convertCampaignToProto(campaign *model.Campaign) (*pb.Campaign, error) {
+	payload, err := json.Marshal(campaign.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	protoCampaign := &pb.Campaign{
+		Id:               campaign.ID,
+		Segment:          string(campaign.Segment),
+		City:             campaign.City,
+		ActiveWithinDays: int32(campaign.ActiveWithinDays),
+		NotificationType: string(campaign.NotificationType),
+		Title:            campaign.Title,
+		Message:          campaign.Message,
+		Payload:          payload,
+		Status:           string(campaign.Status),
+		Sent:             campaign.Sent,
+		CreatedBy:        campaign.CreatedBy,
+		CancelledBy:      campaign.CancelledBy,
+		CreatedAt:        timestamppb.New(campaign.CreatedAt),
+		UpdatedAt:        timestamppb.New(campaign.UpdatedAt),
+	}
+	if campaign.FinishedAt != nil {
+		protoCampaign.FinishedAt = timestamppb.New(*campaign.FinishedAt)
+	}
+	return protoCampaign, nil
+}
@@ -0,0 +1,213 @@
+package service_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/notification"
+	"github.com/order-api-microservices/services/notification/internal/repository/memory"
+	"github.com/order-api-microservices/services/notification/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeDirectory serves fixed providers and users in ID order, as the order and provider
+// services do
+type fakeDirectory struct {
+	areas     map[string][]string // city to service area IDs
+	providers map[string][]string // service area ID to provider IDs
+	users     []string
+	since     time.Time
+}
+
+func (d *fakeDirectory) ListServiceAreaIDs(ctx context.Context, city string) ([]string, error) {
+	return d.areas[city], nil
+}
+
+func (d *fakeDirectory) ListProviderIDs(ctx context.Context, serviceAreaIDs []string, afterID string, limit int) ([]string, error) {
+	var ids []string
+	for _, areaID := range serviceAreaIDs {
+		ids = append(ids, d.providers[areaID]...)
+	}
+	return page(ids, afterID, limit), nil
+}
+
+func (d *fakeDirectory) ListActiveUserIDs(ctx context.Context, since time.Time, afterID string, limit int) ([]string, error) {
+	d.since = since
+	return page(d.users, afterID, limit), nil
+}
+
+func page(ids []string, afterID string, limit int) []string {
+	sort.Strings(ids)
+	var result []string
+	for _, id := range ids {
+		if id > afterID && len(result) < limit {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func newCampaignTest() (*memory.NotificationRepository, *service.CampaignService, *service.CampaignSender, *fakeDirectory) {
+	notifications := memory.NewNotificationRepository()
+	repo := memory.NewCampaignRepository(notifications)
+	directory := &fakeDirectory{
+		areas:     map[string][]string{"Jakarta": {"area-1", "area-2"}, "Bandung": {"area-3"}},
+		providers: map[string][]string{"area-1": {"p1", "p3"}, "area-2": {"p2"}, "area-3": {"p4"}},
+		users:     []string{"u1", "u2", "u3", "u4", "u5"},
+	}
+	sender := service.NewCampaignSender(repo, directory, directory, service.CampaignSenderConfig{
+		Interval:  time.Second,
+		BatchSize: 2,
+		MaxActive: 10,
+	})
+	return notifications, service.NewCampaignService(repo), sender, directory
+}
+
+func TestCampaignSendsEachRecipientOnce(t *testing.T) {
+	ctx := context.Background()
+	notifications, campaigns, sender, _ := newCampaignTest()
+
+	created, err := campaigns.CreateCampaign(ctx, &pb.CreateCampaignRequest{
+		Segment:   "PROVIDERS_IN_CITY",
+		City:      "Jakarta",
+		Title:     "New surge zones",
+		CreatedBy: "admin-1",
+	})
+	if err != nil {
+		t.Fatalf("CreateCampaign: %v", err)
+	}
+	id := created.Campaign.Id
+	if created.Campaign.Status != "QUEUED" || created.Campaign.NotificationType != "CAMPAIGN" {
+		t.Fatalf("created campaign = %s %s, want QUEUED CAMPAIGN", created.Campaign.Status, created.Campaign.NotificationType)
+	}
+
+	// Three providers in batches of two: a full batch, then a short one that completes it
+	for i, want := range []int64{2, 1, 0} {
+		sent, err := sender.Send(ctx, time.Now())
+		if err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+		if sent != want {
+			t.Fatalf("Send %d sent %d, want %d", i, sent, want)
+		}
+	}
+
+	got, err := campaigns.GetCampaign(ctx, &pb.GetCampaignRequest{CampaignId: id})
+	if err != nil {
+		t.Fatalf("GetCampaign: %v", err)
+	}
+	if got.Campaign.Status != "COMPLETED" || got.Campaign.Sent != 3 || got.Campaign.FinishedAt == nil {
+		t.Errorf("campaign = %s, %d sent, finished %v; want COMPLETED, 3 sent, finished", got.Campaign.Status, got.Campaign.Sent, got.Campaign.FinishedAt)
+	}
+
+	for _, recipient := range []string{"p1", "p2", "p3"} {
+		if count, _ := notifications.CountUnread(ctx, recipient); count != 1 {
+			t.Errorf("%s has %d notifications, want 1", recipient, count)
+		}
+	}
+	if count, _ := notifications.CountUnread(ctx, "p4"); count != 0 {
+		t.Errorf("p4 outside the city has %d notifications, want 0", count)
+	}
+}
+
+func TestCampaignRecentUsers(t *testing.T) {
+	ctx := context.Background()
+	_, campaigns, sender, directory := newCampaignTest()
+
+	created, err := campaigns.CreateCampaign(ctx, &pb.CreateCampaignRequest{
+		Segment:   "RECENT_USERS",
+		Title:     "We miss you",
+		Payload:   []byte(`{"promo":"BACK10"}`),
+		CreatedBy: "admin-1",
+	})
+	if err != nil {
+		t.Fatalf("CreateCampaign: %v", err)
+	}
+	if created.Campaign.ActiveWithinDays != 30 {
+		t.Errorf("active within %d days, want the default 30", created.Campaign.ActiveWithinDays)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := sender.Send(ctx, time.Now()); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	got, _ := campaigns.GetCampaign(ctx, &pb.GetCampaignRequest{CampaignId: created.Campaign.Id})
+	if got.Campaign.Status != "COMPLETED" || got.Campaign.Sent != 5 {
+		t.Errorf("campaign = %s with %d sent, want COMPLETED with 5", got.Campaign.Status, got.Campaign.Sent)
+	}
+	wantSince := created.Campaign.CreatedAt.AsTime().AddDate(0, 0, -30)
+	if !directory.since.Equal(wantSince) {
+		t.Errorf("users active since %v, want %v", directory.since, wantSince)
+	}
+}
+
+func TestCancelCampaignStopsSending(t *testing.T) {
+	ctx := context.Background()
+	notifications, campaigns, sender, _ := newCampaignTest()
+
+	created, err := campaigns.CreateCampaign(ctx, &pb.CreateCampaignRequest{
+		Segment:   "RECENT_USERS",
+		Title:     "Maintenance tonight",
+		CreatedBy: "admin-1",
+	})
+	if err != nil {
+		t.Fatalf("CreateCampaign: %v", err)
+	}
+	id := created.Campaign.Id
+
+	if sent, err := sender.Send(ctx, time.Now()); err != nil || sent != 2 {
+		t.Fatalf("Send = %d, %v; want 2", sent, err)
+	}
+
+	cancelled, err := campaigns.CancelCampaign(ctx, &pb.CancelCampaignRequest{CampaignId: id, CancelledBy: "admin-2"})
+	if err != nil {
+		t.Fatalf("CancelCampaign: %v", err)
+	}
+	if cancelled.Campaign.Status != "CANCELLED" || cancelled.Campaign.CancelledBy != "admin-2" {
+		t.Errorf("campaign = %s by %q, want CANCELLED by admin-2", cancelled.Campaign.Status, cancelled.Campaign.CancelledBy)
+	}
+
+	if sent, err := sender.Send(ctx, time.Now()); err != nil || sent != 0 {
+		t.Errorf("Send after cancel = %d, %v; want 0", sent, err)
+	}
+	if count, _ := notifications.CountUnread(ctx, "u3"); count != 0 {
+		t.Errorf("u3 was notified after the cancel")
+	}
+
+	_, err = campaigns.CancelCampaign(ctx, &pb.CancelCampaignRequest{CampaignId: id, CancelledBy: "admin-2"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("cancelling twice = %v, want FailedPrecondition", err)
+	}
+}
+
+func TestCreateCampaignValidation(t *testing.T) {
+	ctx := context.Background()
+	_, campaigns, _, _ := newCampaignTest()
+
+	tests := []struct {
+		name string
+		req  *pb.CreateCampaignRequest
+	}{
+		{name: "no city", req: &pb.CreateCampaignRequest{Segment: "PROVIDERS_IN_CITY", Title: "t", CreatedBy: "admin-1"}},
+		{name: "unknown segment", req: &pb.CreateCampaignRequest{Segment: "EVERYONE", Title: "t", CreatedBy: "admin-1"}},
+		{name: "payload not JSON", req: &pb.CreateCampaignRequest{Segment: "RECENT_USERS", Title: "t", Payload: []byte("{"), CreatedBy: "admin-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := campaigns.CreateCampaign(ctx, tt.req); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("CreateCampaign = %v, want InvalidArgument", err)
+			}
+		})
+	}
+
+	_, err := campaigns.GetCampaign(ctx, &pb.GetCampaignRequest{CampaignId: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetCampaign of a missing campaign = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/notification"
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// devicePrunedCounter counts the devices removed because their push token stopped working
+var devicePrunedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notification_device_tokens_pruned_total",
+	Help: "Devices removed after their push platform refused their token for good, by platform",
+}, []string{"platform"})
+
+// DeviceRepository stores the devices push notifications go to. It is implemented by
+// repository.DeviceRepository on Postgres and by memory.DeviceRepository for tests.
+type DeviceRepository interface {
+	RegisterDevice(ctx context.Context, device *model.Device) error
+	UnregisterDevice(ctx context.Context, ownerID, deviceID string) error
+	ListDevices(ctx context.Context, ownerID string, platform model.DevicePlatform) ([]*model.Device, error)
+	PruneTokens(ctx context.Context, platform model.DevicePlatform, tokens []string) (int64, error)
+}
+
+// DeviceService keeps the push tokens of users' and providers' devices. The apps register
+// a device on sign-in and whenever the platform refreshes its token, and unregister it on
+// sign-out; push senders list a recipient's devices and report the tokens the platforms
+// refuse, so dead ones are pruned.
+type DeviceService struct {
+	pb.UnimplementedNotificationDeviceServiceServer
+	repo DeviceRepository
+}
+
+// NewDeviceService creates a new device service
+func NewDeviceService(repo DeviceRepository) *DeviceService {
+	return &DeviceService{
+		repo: repo,
+	}
+}
+
+// RegisterDevice stores a device's current push token
+func (s *DeviceService) RegisterDevice(ctx context.Context, req *pb.RegisterDeviceRequest) (*pb.DeviceResponse, error) {
+	platform := model.DevicePlatform(req.Platform)
+	if !validPlatform(platform) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown platform %q", req.Platform)
+	}
+	token := strings.TrimSpace(req.Token)
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	now := time.Now()
+	device := &model.Device{
+		OwnerID:    req.OwnerId,
+		OwnerType:  model.RecipientType(req.OwnerType),
+		DeviceID:   req.DeviceId,
+		Platform:   platform,
+		Token:      token,
+		AppVersion: req.AppVersion,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.repo.RegisterDevice(ctx, device); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to register device: %v", err)
+	}
+
+	return &pb.DeviceResponse{
+		Device:  convertDeviceToProto(device),
+		Success: true,
+		Message: "Device registered",
+	}, nil
+}
+
+// UnregisterDevice forgets one of an owner's devices, so nothing more is pushed to it
+func (s *DeviceService) UnregisterDevice(ctx context.Context, req *pb.UnregisterDeviceRequest) (*pb.UnregisterDeviceResponse, error) {
+	err := s.repo.UnregisterDevice(ctx, req.OwnerId, req.DeviceId)
+	if errors.Is(err, repository.ErrDeviceNotFound) {
+		return nil, status.Error(codes.NotFound, "device not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to unregister device: %v", err)
+	}
+
+	return &pb.UnregisterDeviceResponse{
+		Success: true,
+		Message: "Device unregistered",
+	}, nil
+}
+
+// ListDevices lists the devices a push notification to the owner goes to
+func (s *DeviceService) ListDevices(ctx context.Context, req *pb.ListDevicesRequest) (*pb.ListDevicesResponse, error) {
+	platform := model.DevicePlatform(req.Platform)
+	if platform != "" && !validPlatform(platform) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown platform %q", req.Platform)
+	}
+
+	devices, err := s.repo.ListDevices(ctx, req.OwnerId, platform)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list devices: %v", err)
+	}
+
+	protoDevices := make([]*pb.Device, 0, len(devices))
+	for _, device := range devices {
+		protoDevices = append(protoDevices, convertDeviceToProto(device))
+	}
+
+	return &pb.ListDevicesResponse{
+		Devices: protoDevices,
+	}, nil
+}
+
+// ReportDeliveryFailures prunes the devices whose token the platform refused for good.
+// Transient failures are left alone, so the token is tried again next time.
+func (s *DeviceService) ReportDeliveryFailures(ctx context.Context, req *pb.ReportDeliveryFailuresRequest) (*pb.ReportDeliveryFailuresResponse, error) {
+	tokens := make(map[model.DevicePlatform][]string)
+	for _, failure := range req.Failures {
+		platform := model.DevicePlatform(failure.Platform)
+		if !validPlatform(platform) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown platform %q", failure.Platform)
+		}
+		if model.DeliveryFailureReason(failure.Reason).Permanent() {
+			tokens[platform] = append(tokens[platform], failure.Token)
+		}
+	}
+
+	var pruned int64
+	for platform, platformTokens := range tokens {
+		removed, err := s.repo.PruneTokens(ctx, platform, platformTokens)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to prune device tokens: %v", err)
+		}
+		devicePrunedCounter.WithLabelValues(string(platform)).Add(float64(removed))
+		pruned += removed
+	}
+
+	return &pb.ReportDeliveryFailuresResponse{
+		Pruned: pruned,
+	}, nil
+}
+
+func validPlatform(platform model.DevicePlatform) bool {
+	return platform == model.PlatformFCM || platform == model.PlatformAPNS
+}
+
+func convertDeviceToProto(device *model.Device) *pb.Device {
+	return &pb.Device{
+		OwnerId:    device.OwnerID,
+		OwnerType:  string(device.OwnerType),
+		DeviceId:   device.DeviceID,
+		Platform:   string(device.Platform),
+		Token:      device.Token,
+		AppVersion: device.AppVersion,
+		CreatedAt:  timestamppb.New(device.CreatedAt),
+		UpdatedAt:  timestamppb.New(device.UpdatedAt),
+	}
+}
@@ -0,0 +1,117 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/notification"
+	"github.com/order-api-microservices/services/notification/internal/repository/memory"
+	"github.com/order-api-microservices/services/notification/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRegisterDevice(t *testing.T) {
+	ctx := context.Background()
+	devices := service.NewDeviceService(memory.NewDeviceRepository())
+	alice, bob := uuid.New().String(), uuid.New().String()
+
+	register := func(ownerID, deviceID, token string) {
+		t.Helper()
+		_, err := devices.RegisterDevice(ctx, &pb.RegisterDeviceRequest{
+			OwnerId:   ownerID,
+			OwnerType: "USER",
+			DeviceId:  deviceID,
+			Platform:  "FCM",
+			Token:     token,
+		})
+		if err != nil {
+			t.Fatalf("RegisterDevice: %v", err)
+		}
+	}
+	list := func(ownerID string) []*pb.Device {
+		t.Helper()
+		resp, err := devices.ListDevices(ctx, &pb.ListDevicesRequest{OwnerId: ownerID})
+		if err != nil {
+			t.Fatalf("ListDevices: %v", err)
+		}
+		return resp.Devices
+	}
+
+	register(alice, "phone", "token-1")
+	register(alice, "tablet", "token-2")
+
+	// A refreshed token replaces the device's old one
+	register(alice, "phone", "token-3")
+	got := list(alice)
+	if len(got) != 2 || got[0].DeviceId != "phone" || got[0].Token != "token-3" {
+		t.Fatalf("alice's devices = %v, want the phone with token-3 first and the tablet", got)
+	}
+
+	// Bob signing in on alice's tablet takes its token
+	register(bob, "tablet", "token-2")
+	if got := list(alice); len(got) != 1 || got[0].DeviceId != "phone" {
+		t.Errorf("alice's devices = %v, want only the phone", got)
+	}
+	if got := list(bob); len(got) != 1 || got[0].Token != "token-2" {
+		t.Errorf("bob's devices = %v, want the tablet", got)
+	}
+
+	if _, err := devices.UnregisterDevice(ctx, &pb.UnregisterDeviceRequest{OwnerId: alice, DeviceId: "phone"}); err != nil {
+		t.Fatalf("UnregisterDevice: %v", err)
+	}
+	_, err := devices.UnregisterDevice(ctx, &pb.UnregisterDeviceRequest{OwnerId: alice, DeviceId: "phone"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("unregistering twice = %v, want NotFound", err)
+	}
+
+	_, err = devices.RegisterDevice(ctx, &pb.RegisterDeviceRequest{OwnerId: alice, DeviceId: "phone", Platform: "SMS", Token: "x"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("registering on an unknown platform = %v, want InvalidArgument", err)
+	}
+}
+
+func TestReportDeliveryFailuresPrunesDeadTokens(t *testing.T) {
+	ctx := context.Background()
+	devices := service.NewDeviceService(memory.NewDeviceRepository())
+	owner := uuid.New().String()
+
+	for _, device := range []struct{ id, platform, token string }{
+		{"android", "FCM", "fcm-dead"},
+		{"iphone", "APNS", "apns-dead"},
+		{"old-iphone", "APNS", "apns-flaky"},
+	} {
+		_, err := devices.RegisterDevice(ctx, &pb.RegisterDeviceRequest{
+			OwnerId:   owner,
+			OwnerType: "PROVIDER",
+			DeviceId:  device.id,
+			Platform:  device.platform,
+			Token:     device.token,
+		})
+		if err != nil {
+			t.Fatalf("RegisterDevice: %v", err)
+		}
+	}
+
+	resp, err := devices.ReportDeliveryFailures(ctx, &pb.ReportDeliveryFailuresRequest{
+		Failures: []*pb.DeliveryFailure{
+			{Platform: "FCM", Token: "fcm-dead", Reason: "UNREGISTERED"},
+			{Platform: "APNS", Token: "apns-dead", Reason: "INVALID_TOKEN"},
+			{Platform: "APNS", Token: "apns-flaky", Reason: "TRANSIENT"},
+			// The same token on the other platform is a different token
+			{Platform: "FCM", Token: "apns-flaky", Reason: "UNREGISTERED"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ReportDeliveryFailures: %v", err)
+	}
+	if resp.Pruned != 2 {
+		t.Errorf("pruned %d devices, want 2", resp.Pruned)
+	}
+
+	left, _ := devices.ListDevices(ctx, &pb.ListDevicesRequest{OwnerId: owner})
+	if len(left.Devices) != 1 || left.Devices[0].DeviceId != "old-iphone" {
+		t.Errorf("devices left = %v, want only old-iphone", left.Devices)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/notification"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// InboxRepository counts and clears a recipient's unread notifications. It is implemented
+// by repository.NotificationRepository on Postgres and by memory.NotificationRepository
+// for tests.
+type InboxRepository interface {
+	CountUnread(ctx context.Context, recipientID string) (int64, error)
+	MarkAllRead(ctx context.Context, recipientID string, before, at time.Time) (int64, error)
+}
+
+// InboxService backs the unread badge of the client apps. Counting and clearing only
+// touch unread notifications, so neither slows down as a recipient's history grows.
+type InboxService struct {
+	pb.UnimplementedNotificationInboxServiceServer
+	repo InboxRepository
+}
+
+// NewInboxService creates a new inbox service
+func NewInboxService(repo InboxRepository) *InboxService {
+	return &InboxService{
+		repo: repo,
+	}
+}
+
+// GetUnreadCount returns how many notifications a recipient has not read
+func (s *InboxService) GetUnreadCount(ctx context.Context, req *pb.GetUnreadCountRequest) (*pb.GetUnreadCountResponse, error) {
+	count, err := s.repo.CountUnread(ctx, req.RecipientId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to count unread notifications: %v", err)
+	}
+
+	return &pb.GetUnreadCountResponse{
+		UnreadCount: count,
+	}, nil
+}
+
+// MarkAllRead marks a recipient's unread notifications as read, up to the time the app
+// asks for so newer ones keep the badge lit
+func (s *InboxService) MarkAllRead(ctx context.Context, req *pb.MarkAllReadRequest) (*pb.MarkAllReadResponse, error) {
+	var before time.Time
+	if req.Before != nil {
+		before = req.Before.AsTime()
+	}
+
+	marked, err := s.repo.MarkAllRead(ctx, req.RecipientId, before, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to mark notifications as read: %v", err)
+	}
+
+	return &pb.MarkAllReadResponse{
+		Marked:  marked,
+		Success: true,
+		Message: "Notifications marked as read",
+	}, nil
+}
@@ -0,0 +1,106 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/notification"
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository/memory"
+	"github.com/order-api-microservices/services/notification/internal/service"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestInboxServiceMarkAllRead(t *testing.T) {
+	ctx := context.Background()
+	recipient := uuid.New().String()
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		before     *timestamppb.Timestamp
+		wantMarked int64
+		wantUnread int64
+	}{
+		{name: "everything", wantMarked: 3, wantUnread: 0},
+		{name: "up to a time", before: timestamppb.New(now.Add(-time.Minute)), wantMarked: 2, wantUnread: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := memory.NewNotificationRepository()
+			repo.AddNotification(&model.Notification{RecipientID: recipient, CreatedAt: now.Add(-time.Hour)})
+			repo.AddNotification(&model.Notification{RecipientID: recipient, CreatedAt: now.Add(-30 * time.Minute)})
+			repo.AddNotification(&model.Notification{RecipientID: recipient, CreatedAt: now})
+			repo.AddNotification(&model.Notification{RecipientID: recipient, CreatedAt: now.Add(-2 * time.Hour), Read: true})
+			repo.AddNotification(&model.Notification{RecipientID: uuid.New().String(), CreatedAt: now.Add(-time.Hour)})
+			inbox := service.NewInboxService(repo)
+
+			count, err := inbox.GetUnreadCount(ctx, &pb.GetUnreadCountRequest{RecipientId: recipient})
+			if err != nil {
+				t.Fatalf("GetUnreadCount: %v", err)
+			}
+			if count.UnreadCount != 3 {
+				t.Fatalf("unread count = %d, want 3", count.UnreadCount)
+			}
+
+			resp, err := inbox.MarkAllRead(ctx, &pb.MarkAllReadRequest{RecipientId: recipient, Before: tt.before})
+			if err != nil {
+				t.Fatalf("MarkAllRead: %v", err)
+			}
+			if resp.Marked != tt.wantMarked {
+				t.Errorf("marked = %d, want %d", resp.Marked, tt.wantMarked)
+			}
+
+			count, err = inbox.GetUnreadCount(ctx, &pb.GetUnreadCountRequest{RecipientId: recipient})
+			if err != nil {
+				t.Fatalf("GetUnreadCount: %v", err)
+			}
+			if count.UnreadCount != tt.wantUnread {
+				t.Errorf("unread count = %d, want %d", count.UnreadCount, tt.wantUnread)
+			}
+		})
+	}
+}
+
+func TestNotificationServiceCountsUnread(t *testing.T) {
+	ctx := context.Background()
+	recipient := uuid.New().String()
+	repo := memory.NewNotificationRepository()
+	notifications := service.NewNotificationService(repo)
+
+	var sent []string
+	for i := 0; i < 2; i++ {
+		resp, err := notifications.SendNotification(ctx, &pb.SendNotificationRequest{
+			RecipientId:      recipient,
+			RecipientType:    string(model.RecipientTypeUser),
+			NotificationType: string(model.NotificationTypeOrderCreated),
+			Title:            "Order created",
+			Payload:          []byte(`{"order_id":"o-1"}`),
+		})
+		if err != nil {
+			t.Fatalf("SendNotification: %v", err)
+		}
+		sent = append(sent, resp.NotificationId)
+	}
+
+	_, err := notifications.MarkNotificationAsRead(ctx, &pb.MarkNotificationAsReadRequest{NotificationId: sent[0], UserId: recipient})
+	if err != nil {
+		t.Fatalf("MarkNotificationAsRead: %v", err)
+	}
+
+	list, err := notifications.GetUserNotifications(ctx, &pb.GetUserNotificationsRequest{UserId: recipient, IncludeRead: true})
+	if err != nil {
+		t.Fatalf("GetUserNotifications: %v", err)
+	}
+	if list.Total != 2 || list.UnreadCount != 1 {
+		t.Errorf("total = %d, unread = %d, want 2 and 1", list.Total, list.UnreadCount)
+	}
+
+	_, err = notifications.MarkNotificationAsRead(ctx, &pb.MarkNotificationAsReadRequest{NotificationId: sent[1], UserId: uuid.New().String()})
+	if err == nil {
+		t.Error("marked another recipient's notification as read")
+	}
+}
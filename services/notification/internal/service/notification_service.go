@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/notification"
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NotificationRepository stores notifications. It is implemented by
+// repository.NotificationRepository on Postgres and by memory.NotificationRepository for
+// tests.
+type NotificationRepository interface {
+	CreateNotification(ctx context.Context, notification *model.Notification) error
+	ListRecipientNotifications(ctx context.Context, recipientID string, includeRead bool, page, limit int) ([]*model.Notification, int, error)
+	ListReferenceNotifications(ctx context.Context, referenceID string) ([]*model.Notification, error)
+	CountUnread(ctx context.Context, recipientID string) (int64, error)
+	MarkAsRead(ctx context.Context, notificationID, recipientID string, at time.Time) error
+}
+
+// subscriberBuffer is how many notifications a subscriber may fall behind by before new
+// ones are dropped from its stream; they can still be listed
+const subscriberBuffer = 16
+
+// NotificationService stores the notifications the other services send and streams them
+// to the recipients' apps
+type NotificationService struct {
+	pb.UnimplementedNotificationServiceServer
+	repo NotificationRepository
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan *model.Notification]struct{} // By recipient ID
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(repo NotificationRepository) *NotificationService {
+	return &NotificationService{
+		repo:        repo,
+		subscribers: make(map[string]map[chan *model.Notification]struct{}),
+	}
+}
+
+// SendNotification stores a notification and passes it to the recipient's open streams
+func (s *NotificationService) SendNotification(ctx context.Context, req *pb.SendNotificationRequest) (*pb.SendNotificationResponse, error) {
+	payload, err := parsePayload(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	notification := &model.Notification{
+		ID:               uuid.New().String(),
+		RecipientID:      req.RecipientId,
+		RecipientType:    model.RecipientType(req.RecipientType),
+		NotificationType: model.NotificationType(req.NotificationType),
+		Title:            req.Title,
+		Message:          req.Message,
+		Payload:          payload,
+		ReferenceID:      req.ReferenceId,
+		CreatedAt:        time.Now(),
+	}
+	if err := s.repo.CreateNotification(ctx, notification); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to store notification: %v", err)
+	}
+
+	s.publish(notification)
+
+	return &pb.SendNotificationResponse{
+		Success:        true,
+		Message:        "Notification sent",
+		NotificationId: notification.ID,
+	}, nil
+}
+
+// GetUserNotifications lists a page of a recipient's notifications, newest first, with
+// how many are unread
+func (s *NotificationService) GetUserNotifications(ctx context.Context, req *pb.GetUserNotificationsRequest) (*pb.GetUserNotificationsResponse, error) {
+	notifications, total, err := s.repo.ListRecipientNotifications(ctx, req.UserId, req.IncludeRead, int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list notifications: %v", err)
+	}
+	unread, err := s.repo.CountUnread(ctx, req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to count unread notifications: %v", err)
+	}
+
+	protoNotifications := make([]*pb.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		protoNotification, err := convertNotificationToProto(notification)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to convert notification: %v", err)
+		}
+		protoNotifications = append(protoNotifications, protoNotification)
+	}
+
+	return &pb.GetUserNotificationsResponse{
+		Notifications: protoNotifications,
+		Total:         int32(total),
+		UnreadCount:   int32(unread),
+		Page:          req.Page,
+		Limit:         req.Limit,
+	}, nil
+}
+
+// ListReferenceNotifications lists the notifications sent about something, such as an
+// order, to any recipient, oldest first
+func (s *NotificationService) ListReferenceNotifications(ctx context.Context, req *pb.ListReferenceNotificationsRequest) (*pb.ListReferenceNotificationsResponse, error) {
+	notifications, err := s.repo.ListReferenceNotifications(ctx, req.ReferenceId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list notifications: %v", err)
+	}
+
+	protoNotifications := make([]*pb.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		protoNotification, err := convertNotificationToProto(notification)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to convert notification: %v", err)
+		}
+		protoNotifications = append(protoNotifications, protoNotification)
+	}
+
+	return &pb.ListReferenceNotificationsResponse{Notifications: protoNotifications}, nil
+}
+
+// MarkNotificationAsRead marks one of a recipient's notifications as read
+func (s *NotificationService) MarkNotificationAsRead(ctx context.Context, req *pb.MarkNotificationAsReadRequest) (*pb.MarkNotificationAsReadResponse, error) {
+	err := s.repo.MarkAsRead(ctx, req.NotificationId, req.UserId, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotificationNotFound) {
+			return nil, status.Errorf(codes.NotFound, "notification not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to mark notification as read: %v", err)
+	}
+
+	return &pb.MarkNotificationAsReadResponse{
+		Success: true,
+		Message: "Notification marked as read",
+	}, nil
+}
+
+// SubscribeToNotifications streams the notifications sent to a recipient from now on,
+// optionally only those of some types, until the caller hangs up. Only notifications sent
+// through this instance reach the stream.
+func (s *NotificationService) SubscribeToNotifications(req *pb.SubscribeToNotificationsRequest, stream pb.NotificationService_SubscribeToNotificationsServer) error {
+	types := make(map[string]bool, len(req.NotificationTypes))
+	for _, notificationType := range req.NotificationTypes {
+		types[notificationType] = true
+	}
+
+	notifications := s.subscribe(req.UserId)
+	defer s.unsubscribe(req.UserId, notifications)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case notification := <-notifications:
+			if len(types) > 0 && !types[string(notification.NotificationType)] {
+				continue
+			}
+			protoNotification, err := convertNotificationToProto(notification)
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to convert notification: %v", err)
+			}
+			if err := stream.Send(protoNotification); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parsePayload decodes a JSON-encoded payload, which may be empty
+func parsePayload(data []byte) (model.Payload, error) {
+	payload := model.Payload{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "payload is not a JSON object: %v", err)
+		}
+	}
+	return payload, nil
+}
+
+// subscribe opens a channel receiving the notifications sent to a recipient
+func (s *NotificationService) subscribe(recipientID string) chan *model.Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifications := make(chan *model.Notification, subscriberBuffer)
+	if s.subscribers[recipientID] == nil {
+		s.subscribers[recipientID] = make(map[chan *model.Notification]struct{})
+	}
+	s.subscribers[recipientID][notifications] = struct{}{}
+	return notifications
+}
+
+// unsubscribe stops passing notifications to a subscriber's channel
+func (s *NotificationService) unsubscribe(recipientID string, notifications chan *model.Notification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subscribers[recipientID], notifications)
+	if len(s.subscribers[recipientID]) == 0 {
+		delete(s.subscribers, recipientID)
+	}
+}
+
+// publish passes a notification to its recipient's subscribers, dropping it for any that
+// have fallen behind
+func (s *NotificationService) publish(notification *model.Notification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for notifications := range s.subscribers[notification.RecipientID] {
+		select {
+		case notifications <- notification:
+		default:
+			log.Printf("Dropped notification %s for a slow subscriber of %s", notification.ID, notification.RecipientID)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	pb "github.com/order-api-microservices/proto/notification"
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// PrivacyRepository stores the notifications sent to each recipient. It is implemented by
+// repository.PrivacyRepository on Postgres and by memory.PrivacyRepository for tests.
+type PrivacyRepository interface {
+	ListRecipientNotifications(ctx context.Context, recipientID string) ([]*model.Notification, error)
+	AnonymizeRecipientNotifications(ctx context.Context, recipientID string) (int64, error)
+}
+
+// PrivacyService hands over and erases the notifications sent to someone. The order
+// service calls it while exporting or forgetting a user or provider.
+type PrivacyService struct {
+	pb.UnimplementedNotificationPrivacyServiceServer
+	repo PrivacyRepository
+}
+
+// NewPrivacyService creates a new privacy service
+func NewPrivacyService(repo PrivacyRepository) *PrivacyService {
+	return &PrivacyService{
+		repo: repo,
+	}
+}
+
+// ExportNotifications returns every notification sent to a recipient, read or not
+func (s *PrivacyService) ExportNotifications(ctx context.Context, req *pb.ExportNotificationsRequest) (*pb.ExportNotificationsResponse, error) {
+	notifications, err := s.repo.ListRecipientNotifications(ctx, req.RecipientId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list notifications: %v", err)
+	}
+
+	protoNotifications := make([]*pb.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		protoNotification, err := convertNotificationToProto(notification)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to convert notification: %v", err)
+		}
+		protoNotifications = append(protoNotifications, protoNotification)
+	}
+
+	return &pb.ExportNotificationsResponse{
+		Notifications: protoNotifications,
+	}, nil
+}
+
+// AnonymizeNotifications erases the content of every notification sent to a recipient.
+// Anonymizing them again changes nothing.
+func (s *PrivacyService) AnonymizeNotifications(ctx context.Context, req *pb.AnonymizeNotificationsRequest) (*pb.AnonymizeNotificationsResponse, error) {
+	anonymized, err := s.repo.AnonymizeRecipientNotifications(ctx, req.RecipientId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to anonymize notifications: %v", err)
+	}
+
+	return &pb.AnonymizeNotificationsResponse{
+		Anonymized: anonymized,
+		Success:    true,
+		Message:    "Notifications anonymized",
+	}, nil
+}
+
+func convertNotificationToProto(notification *model.Notification) (*pb.Notification, error) {
+	payload, err := json.Marshal(notification.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	protoNotification := &pb.Notification{
+		Id:               notification.ID,
+		RecipientId:      notification.RecipientID,
+		RecipientType:    string(notification.RecipientType),
+		NotificationType: string(notification.NotificationType),
+		Title:            notification.Title,
+		Message:          notification.Message,
+		Payload:          payload,
+		ReferenceId:      notification.ReferenceID,
+		Read:             notification.Read,
+		CreatedAt:        timestamppb.New(notification.CreatedAt),
+	}
+	if notification.ReadAt != nil {
+		protoNotification.ReadAt = timestamppb.New(*notification.ReadAt)
+	}
+	return protoNotification, nil
+}
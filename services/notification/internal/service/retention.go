@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// retentionPurgedCounter counts the read notifications the retention job removed
+var retentionPurgedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notification_retention_purged_total",
+	Help: "Read notifications removed by the retention job, by notification type and whether they were archived or deleted",
+}, []string{"notification_type", "action"})
+
+// RetentionRepository removes old read notifications. It is implemented by
+// repository.NotificationRepository on Postgres and by memory.NotificationRepository for
+// tests.
+type RetentionRepository interface {
+	PurgeReadNotifications(ctx context.Context, filter repository.PurgeFilter, archive bool, limit int) (map[model.NotificationType]int64, error)
+}
+
+// RetentionConfig controls how long read notifications are kept
+type RetentionConfig struct {
+	Age       time.Duration                            // Read notifications sent longer ago than this are removed
+	TypeAges  map[model.NotificationType]time.Duration // Overrides Age for some types; 0 keeps them for good
+	Archive   bool                                     // Move removed notifications to the archive instead of deleting them
+	Interval  time.Duration                            // How often old notifications are removed
+	BatchSize int                                      // Most notifications removed in one statement
+}
+
+// Retention removes read notifications once they pass their retention age. Unread ones
+// are kept however old they are.
+type Retention struct {
+	repo RetentionRepository
+	cfg  RetentionConfig
+}
+
+// NewRetention creates a new notification retention job
+func NewRetention(repo RetentionRepository, cfg RetentionConfig) *Retention {
+	return &Retention{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+// Run purges old notifications every interval until ctx is cancelled
+func (r *Retention) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := r.Purge(ctx, time.Now())
+			if err != nil {
+				log.Printf("Failed to purge notifications: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("Purged %d read notifications", purged)
+			}
+		}
+	}
+}
+
+// Purge removes every read notification that is past its retention age at now, a batch
+// at a time, and reports how many it removed
+func (r *Retention) Purge(ctx context.Context, now time.Time) (int64, error) {
+	// Types with an override are purged on their own; the rest share the default age
+	overridden := make([]model.NotificationType, 0, len(r.cfg.TypeAges))
+	for notificationType := range r.cfg.TypeAges {
+		overridden = append(overridden, notificationType)
+	}
+	sort.Slice(overridden, func(i, j int) bool { return overridden[i] < overridden[j] })
+
+	var total int64
+	if r.cfg.Age > 0 {
+		purged, err := r.purge(ctx, repository.PurgeFilter{
+			CreatedBefore: now.Add(-r.cfg.Age),
+			ExceptTypes:   overridden,
+		})
+		total += purged
+		if err != nil {
+			return total, err
+		}
+	}
+	for _, notificationType := range overridden {
+		age := r.cfg.TypeAges[notificationType]
+		if age <= 0 {
+			continue
+		}
+		purged, err := r.purge(ctx, repository.PurgeFilter{
+			CreatedBefore: now.Add(-age),
+			Types:         []model.NotificationType{notificationType},
+		})
+		total += purged
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// purge removes the notifications matching filter in batches
+func (r *Retention) purge(ctx context.Context, filter repository.PurgeFilter) (int64, error) {
+	action := "deleted"
+	if r.cfg.Archive {
+		action = "archived"
+	}
+
+	var total int64
+	for ctx.Err() == nil {
+		purged, err := r.repo.PurgeReadNotifications(ctx, filter, r.cfg.Archive, r.cfg.BatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		var batch int64
+		for notificationType, count := range purged {
+			retentionPurgedCounter.WithLabelValues(string(notificationType), action).Add(float64(count))
+			batch += count
+		}
+		total += batch
+		if batch < int64(r.cfg.BatchSize) {
+			return total, nil
+		}
+	}
+
+	return total, nil
+}
+
+// ParseRetentionAges parses per-type retention overrides written as comma separated
+// TYPE=duration pairs, such as "ORDER_CREATED=720h,PAYMENT_PROCESSED=0"
+func ParseRetentionAges(value string) (map[model.NotificationType]time.Duration, error) {
+	ages := make(map[model.NotificationType]time.Duration)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		notificationType, age, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(notificationType) == "" {
+			return nil, fmt.Errorf("retention override %q is not TYPE=duration", pair)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(age))
+		if err != nil {
+			return nil, fmt.Errorf("retention override %q: %w", pair, err)
+		}
+		if duration < 0 {
+			return nil, fmt.Errorf("retention override %q is negative", pair)
+		}
+		ages[model.NotificationType(strings.TrimSpace(notificationType))] = duration
+	}
+	return ages, nil
+}
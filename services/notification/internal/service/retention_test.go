@@ -0,0 +1,107 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/notification"
+	"github.com/order-api-microservices/services/notification/internal/model"
+	"github.com/order-api-microservices/services/notification/internal/repository/memory"
+	"github.com/order-api-microservices/services/notification/internal/service"
+)
+
+func TestRetentionPurge(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		typeAges map[model.NotificationType]time.Duration
+		want     int64
+		wantLeft []string
+	}{
+		{name: "default age", want: 2, wantLeft: []string{"unread", "recent"}},
+		{
+			name:     "longer age for a type",
+			typeAges: map[model.NotificationType]time.Duration{model.NotificationTypePaymentProcessed: 365 * day},
+			want:     1,
+			wantLeft: []string{"unread", "recent", "payment"},
+		},
+		{
+			name:     "type kept for good",
+			typeAges: map[model.NotificationType]time.Duration{model.NotificationTypeOrderCreated: 0},
+			want:     1,
+			wantLeft: []string{"unread", "recent", "old"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipient := uuid.New().String()
+			repo := memory.NewNotificationRepository()
+			add := func(title string, notificationType model.NotificationType, age time.Duration, read bool) {
+				repo.AddNotification(&model.Notification{
+					RecipientID:      recipient,
+					NotificationType: notificationType,
+					Title:            title,
+					Read:             read,
+					CreatedAt:        now.Add(-age),
+				})
+			}
+			add("unread", model.NotificationTypeOrderCreated, 200*day, false)
+			add("recent", model.NotificationTypeOrderCreated, 10*day, true)
+			add("old", model.NotificationTypeOrderCreated, 100*day, true)
+			add("payment", model.NotificationTypePaymentProcessed, 100*day, true)
+
+			retention := service.NewRetention(repo, service.RetentionConfig{
+				Age:       90 * day,
+				TypeAges:  tt.typeAges,
+				BatchSize: 1,
+			})
+			purged, err := retention.Purge(ctx, now)
+			if err != nil {
+				t.Fatalf("Purge: %v", err)
+			}
+			if purged != tt.want {
+				t.Errorf("purged %d notifications, want %d", purged, tt.want)
+			}
+
+			inbox := service.NewNotificationService(repo)
+			resp, err := inbox.GetUserNotifications(ctx, &pb.GetUserNotificationsRequest{UserId: recipient, IncludeRead: true})
+			if err != nil {
+				t.Fatalf("GetUserNotifications: %v", err)
+			}
+			left := make(map[string]bool)
+			for _, notification := range resp.Notifications {
+				left[notification.Title] = true
+			}
+			if len(left) != len(tt.wantLeft) {
+				t.Errorf("%d notifications left, want %d", len(left), len(tt.wantLeft))
+			}
+			for _, title := range tt.wantLeft {
+				if !left[title] {
+					t.Errorf("notification %q was purged", title)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetentionAges(t *testing.T) {
+	ages, err := service.ParseRetentionAges("ORDER_CREATED=720h, PAYMENT_PROCESSED=0")
+	if err != nil {
+		t.Fatalf("ParseRetentionAges: %v", err)
+	}
+	if ages[model.NotificationTypeOrderCreated] != 720*time.Hour || len(ages) != 2 {
+		t.Errorf("ages = %v", ages)
+	}
+
+	for _, value := range []string{"ORDER_CREATED", "=1h", "ORDER_CREATED=soon", "ORDER_CREATED=-1h"} {
+		if _, err := service.ParseRetentionAges(value); err == nil {
+			t.Errorf("ParseRetentionAges(%q) succeeded", value)
+		}
+	}
+}
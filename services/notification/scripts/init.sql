@@ -0,0 +1,85 @@
+-- Create notifications table
+CREATE TABLE IF NOT EXISTS notifications (
+    id VARCHAR(36) PRIMARY KEY,
+    recipient_id VARCHAR(36) NOT NULL,
+    recipient_type VARCHAR(20) NOT NULL,
+    notification_type VARCHAR(50) NOT NULL,
+    title TEXT NOT NULL,
+    message TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    reference_id VARCHAR(36) NOT NULL DEFAULT '',
+    read BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    read_at TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient_created ON notifications(recipient_id, created_at DESC);
+
+-- Unread notifications are counted for the apps' badges on every open, so they are indexed
+-- on their own; the index stays small since most notifications are read
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient_unread ON notifications(recipient_id, created_at) WHERE NOT read;
+
+-- The order service lists an order's notifications for its timeline
+CREATE INDEX IF NOT EXISTS idx_notifications_reference_created ON notifications(reference_id, created_at) WHERE reference_id <> '';
+
+-- The retention job purges old read notifications
+CREATE INDEX IF NOT EXISTS idx_notifications_read_created ON notifications(created_at) WHERE read;
+
+-- Read notifications the retention job archives instead of deleting
+CREATE TABLE IF NOT EXISTS notifications_archive (
+    id VARCHAR(36) PRIMARY KEY,
+    recipient_id VARCHAR(36) NOT NULL,
+    recipient_type VARCHAR(20) NOT NULL,
+    notification_type VARCHAR(50) NOT NULL,
+    title TEXT NOT NULL,
+    message TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    reference_id VARCHAR(36) NOT NULL DEFAULT '',
+    read BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    read_at TIMESTAMP,
+    archived_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_archive_recipient ON notifications_archive(recipient_id, created_at);
+
+-- Create campaigns table
+CREATE TABLE IF NOT EXISTS campaigns (
+    id VARCHAR(36) PRIMARY KEY,
+    segment VARCHAR(30) NOT NULL,
+    city TEXT NOT NULL DEFAULT '',
+    active_within_days INT NOT NULL DEFAULT 0,
+    notification_type VARCHAR(50) NOT NULL,
+    title TEXT NOT NULL,
+    message TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL,
+    last_recipient_id VARCHAR(36) NOT NULL DEFAULT '',
+    sent BIGINT NOT NULL DEFAULT 0,
+    created_by TEXT NOT NULL,
+    cancelled_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_created_at ON campaigns(created_at DESC);
+
+-- The sender only looks at campaigns still being sent
+CREATE INDEX IF NOT EXISTS idx_campaigns_active ON campaigns(created_at) WHERE status IN ('QUEUED', 'SENDING');
+
+-- Create devices table; push notifications go to every device of their recipient
+CREATE TABLE IF NOT EXISTS devices (
+    owner_id VARCHAR(36) NOT NULL,
+    owner_type VARCHAR(20) NOT NULL,
+    device_id VARCHAR(100) NOT NULL,
+    platform VARCHAR(10) NOT NULL,
+    token TEXT NOT NULL,
+    app_version VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (owner_id, device_id),
+    -- A token is delivered to one device; whoever signed in last on it gets the pushes
+    UNIQUE (platform, token)
+);
@@ -8,14 +8,50 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/order-api-microservices/pkg/audit"
+	"github.com/order-api-microservices/pkg/chaos"
+	"github.com/order-api-microservices/pkg/cache"
+	"github.com/order-api-microservices/pkg/crypto"
 	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/fanout"
+	"github.com/order-api-microservices/pkg/grpcserver"
+	"github.com/order-api-microservices/pkg/jobs"
+	"github.com/order-api-microservices/pkg/lock"
+	"github.com/order-api-microservices/pkg/metrics"
 	"github.com/order-api-microservices/services/order/internal/clients"
+	"github.com/order-api-microservices/services/order/internal/model"
 	"github.com/order-api-microservices/services/order/internal/repository"
 	"github.com/order-api-microservices/services/order/internal/service"
+	analyticsPb "github.com/order-api-microservices/proto/analytics"
+	auditPb "github.com/order-api-microservices/proto/audit"
+	chaosPb "github.com/order-api-microservices/proto/chaos"
+	bulkOrderPb "github.com/order-api-microservices/proto/bulkorder"
+	chatPb "github.com/order-api-microservices/proto/chat"
+	contactPb "github.com/order-api-microservices/proto/contact"
+	denylistPb "github.com/order-api-microservices/proto/denylist"
+	dispatchPb "github.com/order-api-microservices/proto/dispatch"
+	disputePb "github.com/order-api-microservices/proto/dispute"
+	feePb "github.com/order-api-microservices/proto/fee"
+	incidentPb "github.com/order-api-microservices/proto/incident"
+	jobsPb "github.com/order-api-microservices/proto/jobs"
+	loyaltyPb "github.com/order-api-microservices/proto/loyalty"
+	merchantPb "github.com/order-api-microservices/proto/merchant"
+	operationsPb "github.com/order-api-microservices/proto/operations"
 	pb "github.com/order-api-microservices/proto/order"
+	paymentMethodPb "github.com/order-api-microservices/proto/paymentmethod"
+	privacyPb "github.com/order-api-microservices/proto/privacy"
+	serviceAreaPb "github.com/order-api-microservices/proto/servicearea"
+	supportPb "github.com/order-api-microservices/proto/support"
+	timelinePb "github.com/order-api-microservices/proto/timeline"
+	trackingPb "github.com/order-api-microservices/proto/tracking"
+	userProviderPb "github.com/order-api-microservices/proto/userprovider"
+	walletPb "github.com/order-api-microservices/proto/wallet"
+	webhookPb "github.com/order-api-microservices/proto/webhook"
 	"google.golang.org/grpc"
 )
 
@@ -27,13 +63,148 @@ func main() {
 	dbPassword := flag.String("db-password", getEnv("DB_PASSWORD", "postgres"), "Database password")
 	dbName := flag.String("db-name", getEnv("DB_NAME", "orderdb"), "Database name")
 	dbSSLMode := flag.String("db-sslmode", getEnv("DB_SSLMODE", "disable"), "Database SSL mode")
+	dbSlowQueryThreshold := flag.Duration("db-slow-query-threshold", getEnvDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond), "Queries taking longer than this are logged with their arguments sanitized (0 logs none)")
 	
 	blockchainServiceAddr := flag.String("blockchain-service", getEnv("BLOCKCHAIN_SERVICE", "localhost:50052"), "Blockchain service address")
 	providerServiceAddr := flag.String("provider-service", getEnv("PROVIDER_SERVICE", "localhost:50053"), "Provider service address")
+	paymentServiceAddr := flag.String("payment-service", getEnv("PAYMENT_SERVICE", "localhost:50056"), "Payment service address")
+	notificationServiceAddr := flag.String("notification-service", getEnv("NOTIFICATION_SERVICE", "localhost:50054"), "Notification service address")
+	predictorServiceAddr := flag.String("predictor-service", getEnv("PREDICTOR_SERVICE", ""), "Address of an external demand and ETA prediction service; empty uses the built-in heuristics")
 	port := flag.Int("port", getEnvInt("PORT", 50051), "Server port")
+	metricsPort := flag.Int("metrics-port", getEnvInt("METRICS_PORT", 9091), "Metrics server port")
+	splitPaymentTimeout := flag.Duration("split-payment-timeout", getEnvDuration("SPLIT_PAYMENT_TIMEOUT", 15*time.Minute), "Time to collect every share of a split payment before charging the primary payer")
+	splitPaymentInterval := flag.Duration("split-payment-interval", getEnvDuration("SPLIT_PAYMENT_INTERVAL", 30*time.Second), "How often outstanding payment shares are retried")
+	paymentAuthorization := flag.Bool("payment-authorization", getEnv("PAYMENT_AUTHORIZATION", "") == "true", "Hold the total of card and wallet orders when they are placed and only capture it when they complete; needs a payment service that supports authorizations")
+	authorizationCaptureMargin := flag.Duration("authorization-capture-margin", getEnvDuration("AUTHORIZATION_CAPTURE_MARGIN", 6*time.Hour), "How long before a held payment lapses it is settled, capturing it if its order is still under way")
+	authorizationSweepInterval := flag.Duration("authorization-sweep-interval", getEnvDuration("AUTHORIZATION_SWEEP_INTERVAL", 5*time.Minute), "How often lapsing held payments are settled")
+	authorizationSweepBatch := flag.Int("authorization-sweep-batch", getEnvInt("AUTHORIZATION_SWEEP_BATCH", 100), "Most held payments settled per sweep")
+	cryptoPaymentInterval := flag.Duration("crypto-payment-interval", getEnvDuration("CRYPTO_PAYMENT_INTERVAL", 30*time.Second), "How often orders awaiting a crypto payment are checked")
+	cancellationFreeWindow := flag.Duration("cancellation-free-window", getEnvDuration("CANCELLATION_FREE_WINDOW", 2*time.Minute), "How long after ordering a user can cancel for free before pickup")
+	cancellationFeePercent := flag.Int("cancellation-fee-percent", getEnvInt("CANCELLATION_FEE_PERCENT", 20), "Percent of the total charged for cancelling after a provider accepted")
+	dispatchEarningsWindow := flag.Duration("dispatch-earnings-window", getEnvDuration("DISPATCH_EARNINGS_WINDOW", 24*time.Hour), "How far back a provider's earnings count towards the dispatch earnings boost")
+	dispatchIdleCap := flag.Duration("dispatch-idle-cap", getEnvDuration("DISPATCH_IDLE_CAP", 2*time.Hour), "Idle time at which a provider gets the full dispatch idle boost")
+	dispatchRefreshInterval := flag.Duration("dispatch-refresh-interval", getEnvDuration("DISPATCH_REFRESH_INTERVAL", time.Minute), "How often dispatch weights are reloaded from the database")
+	dispatchOfferTTL := flag.Duration("dispatch-offer-ttl", getEnvDuration("DISPATCH_OFFER_TTL", 30*time.Second), "How long a provider has to answer an order offered over their dispatch channel")
+	dispatchOfferSweepInterval := flag.Duration("dispatch-offer-sweep-interval", getEnvDuration("DISPATCH_OFFER_SWEEP_INTERVAL", 15*time.Second), "How often offers left unanswered past their expiry are closed")
+	dispatchOfferSweepBatch := flag.Int("dispatch-offer-sweep-batch", getEnvInt("DISPATCH_OFFER_SWEEP_BATCH", 500), "Most expired offers closed per query")
+	dispatchDeclineWindow := flag.Duration("dispatch-decline-window", getEnvDuration("DISPATCH_DECLINE_WINDOW", 7*24*time.Hour), "How far back a provider's answers to offers count towards the decline penalty")
+	dispatchDeclineMinOffers := flag.Int("dispatch-decline-min-offers", getEnvInt("DISPATCH_DECLINE_MIN_OFFERS", 10), "Offers a provider must have answered in the window before they can be penalized for declining")
+	dispatchDeclineRate := flag.Int("dispatch-decline-rate", getEnvInt("DISPATCH_DECLINE_RATE", 80), "Percentage of answered offers declined or let expire at which a provider's dispatch score is penalized")
+	dispatchDeclinePenalty := flag.Int("dispatch-decline-penalty", getEnvInt("DISPATCH_DECLINE_PENALTY", 50), "Percentage of a chronic decliner's dispatch score taken off (0 turns the penalty off)")
+	providerQualityWindow := flag.Duration("provider-quality-window", getEnvDuration("PROVIDER_QUALITY_WINDOW", 30*24*time.Hour), "How far back offers and orders count towards providers' acceptance, completion and on-time rates")
+	providerQualityInterval := flag.Duration("provider-quality-interval", getEnvDuration("PROVIDER_QUALITY_INTERVAL", 15*time.Minute), "How often providers' quality is stored on their provider records")
+	providerQualityBatch := flag.Int("provider-quality-batch", getEnvInt("PROVIDER_QUALITY_BATCH", 500), "Most providers' quality loaded per query")
+	dispatchOfferAckTimeout := flag.Duration("dispatch-offer-ack-timeout", getEnvDuration("DISPATCH_OFFER_ACK_TIMEOUT", 2*time.Second), "How long a provider's app has to acknowledge an offer before it is sent as a notification instead")
+	predictorAverageSpeed := flag.Int("predictor-average-speed-kmh", getEnvInt("PREDICTOR_AVERAGE_SPEED_KMH", 30), "Speed the built-in ETA heuristic assumes along a route")
+	predictorDemandWeeks := flag.Int("predictor-demand-weeks", getEnvInt("PREDICTOR_DEMAND_WEEKS", 4), "Past weeks the built-in demand heuristic averages")
+	feeRefreshInterval := flag.Duration("fee-refresh-interval", getEnvDuration("FEE_REFRESH_INTERVAL", time.Minute), "How often fee rules and waivers are reloaded from the database")
+	serviceAreaRefreshInterval := flag.Duration("service-area-refresh-interval", getEnvDuration("SERVICE_AREA_REFRESH_INTERVAL", time.Minute), "How often service areas are reloaded from the database")
+	deliveryPINLength := flag.Int("delivery-pin-length", getEnvInt("DELIVERY_PIN_LENGTH", 4), "Digits in the PIN a user gives their provider to confirm a delivery (4 to 6)")
+	deliveryPINMaxAttempts := flag.Int("delivery-pin-max-attempts", getEnvInt("DELIVERY_PIN_MAX_ATTEMPTS", 5), "Wrong delivery PINs allowed before PIN attempts are locked")
+	deliveryPINLockout := flag.Duration("delivery-pin-lockout", getEnvDuration("DELIVERY_PIN_LOCKOUT", 15*time.Minute), "How long delivery PIN attempts are locked after too many wrong PINs")
+	deliveryPINResendInterval := flag.Duration("delivery-pin-resend-interval", getEnvDuration("DELIVERY_PIN_RESEND_INTERVAL", 30*time.Second), "Minimum time between delivery PINs sent for an order")
+	contactTokenTTL := flag.Duration("contact-token-ttl", getEnvDuration("CONTACT_TOKEN_TTL", 30*time.Minute), "How long a masked contact token works unless its order finishes first")
+	contactBridgeNumber := flag.String("contact-bridge-number", getEnv("CONTACT_BRIDGE_NUMBER", ""), "Number the parties to an order dial to reach the call bridge")
+	trackingLinkTTL := flag.Duration("tracking-link-ttl", getEnvDuration("TRACKING_LINK_TTL", 2*time.Hour), "How long a shared tracking link works when the user does not ask for a TTL")
+	trackingLinkMaxTTL := flag.Duration("tracking-link-max-ttl", getEnvDuration("TRACKING_LINK_MAX_TTL", 24*time.Hour), "Longest TTL a user can ask for on a shared tracking link")
+	supportSessionTTL := flag.Duration("support-session-ttl", getEnvDuration("SUPPORT_SESSION_TTL", 30*time.Minute), "How long an admin's support session lasts when no TTL is asked for")
+	supportSessionMaxTTL := flag.Duration("support-session-max-ttl", getEnvDuration("SUPPORT_SESSION_MAX_TTL", 4*time.Hour), "Longest TTL an admin can ask for on a support session")
+	timelineSourceTimeout := flag.Duration("timeline-source-timeout", getEnvDuration("TIMELINE_SOURCE_TIMEOUT", 3*time.Second), "How long each source of an order's timeline has to answer before it is left out")
+	sosAdminChannel := flag.String("sos-admin-channel", getEnv("SOS_ADMIN_CHANNEL", "safety"), "Notification channel that safety staff watch for SOS and route deviation alerts")
+	routeDeviationMeters := flag.Int("route-deviation-meters", getEnvInt("ROUTE_DEVIATION_METERS", 1000), "How far from the expected route a provider can stray before the order is flagged")
+	routeDeviationDuration := flag.Duration("route-deviation-duration", getEnvDuration("ROUTE_DEVIATION_DURATION", 3*time.Minute), "How long a provider must stay off route before the order is flagged")
+	routeDeviationInterval := flag.Duration("route-deviation-interval", getEnvDuration("ROUTE_DEVIATION_INTERVAL", 30*time.Second), "How often the routes of orders in transit are analyzed")
+	slaAssignment := flag.Duration("sla-assignment", getEnvDuration("SLA_ASSIGNMENT", 2*time.Minute), "Longest an order may wait for a provider to accept it (0 stops tracking it)")
+	slaPickup := flag.Duration("sla-pickup", getEnvDuration("SLA_PICKUP", 20*time.Minute), "Longest a provider may take from accepting an order to picking it up (0 stops tracking it)")
+	slaDelivery := flag.Duration("sla-delivery", getEnvDuration("SLA_DELIVERY", time.Hour), "Longest an order may take from pickup to delivery (0 stops tracking it)")
+	slaCheckInterval := flag.Duration("sla-check-interval", getEnvDuration("SLA_CHECK_INTERVAL", 30*time.Second), "How often orders are checked against their SLAs")
+	slaLookback := flag.Duration("sla-lookback", getEnvDuration("SLA_LOOKBACK", 10*time.Minute), "How far back SLA checks look for orders that left a stage; keep it well above the check interval")
+	slaOpsChannel := flag.String("sla-ops-channel", getEnv("SLA_OPS_CHANNEL", "operations"), "Notification channel that operations staff watch for SLA breaches")
+	geofencePickupMeters := flag.Int("geofence-pickup-meters", getEnvInt("GEOFENCE_PICKUP_METERS", 100), "Radius around the pickup inside which a provider has arrived for pickup (0 turns it off)")
+	geofenceDestinationMeters := flag.Int("geofence-destination-meters", getEnvInt("GEOFENCE_DESTINATION_METERS", 100), "Radius around the destination inside which an order moves to ARRIVED (0 turns it off)")
+	concurrentOrderLimits := flag.String("concurrent-order-limits", getEnv("CONCURRENT_ORDER_LIMITS", "RIDE=1,FOOD_DELIVERY=3,GROCERY_DELIVERY=3,PACKAGE_DELIVERY=3,SERVICE_BOOKING=1,RENTAL=1,RETURN=3"), "Active orders of each type a provider can hold at once, as TYPE=N pairs")
+	batchPickupRadiusMeters := flag.Int("batch-pickup-radius-meters", getEnvInt("BATCH_PICKUP_RADIUS_METERS", 1000), "Farthest a delivery's pickup can be from a trip's pickup for it to be stacked onto the trip")
+	batchMaxBearingDegrees := flag.Int("batch-max-bearing-degrees", getEnvInt("BATCH_MAX_BEARING_DEGREES", 45), "Widest angle between the directions of two deliveries stacked onto one trip")
+	batchMaxOrders := flag.Int("batch-max-orders", getEnvInt("BATCH_MAX_ORDERS", 2), "Most unfinished deliveries on one trip (below 2 turns batching off)")
+	rentalHourlyRate := flag.Int("rental-hourly-rate", getEnvInt("RENTAL_HOURLY_RATE", 1500), "Price of an hour of a rental order, in minor units")
+	rentalMinHours := flag.Int("rental-min-hours", getEnvInt("RENTAL_MIN_HOURS", 1), "Fewest hours a rental order can be booked for")
+	rentalMaxHours := flag.Int("rental-max-hours", getEnvInt("RENTAL_MAX_HOURS", 12), "Most hours a rental order can be booked for, extensions included")
+	rentalOvertimePercent := flag.Int("rental-overtime-percent", getEnvInt("RENTAL_OVERTIME_PERCENT", 150), "Overtime rate of a rental as a percent of its hourly rate")
+	rentalOvertimeIncrement := flag.Duration("rental-overtime-increment", getEnvDuration("RENTAL_OVERTIME_INCREMENT", 15*time.Minute), "Rental overtime is billed in multiples of this")
+	rentalOvertimeGrace := flag.Duration("rental-overtime-grace", getEnvDuration("RENTAL_OVERTIME_GRACE", 5*time.Minute), "Rental overtime up to this long is not billed")
+	packageSurchargeMedium := flag.Int("package-surcharge-medium", getEnvInt("PACKAGE_SURCHARGE_MEDIUM", 500), "Added to the total of medium packages, in minor units")
+	packageSurchargeLarge := flag.Int("package-surcharge-large", getEnvInt("PACKAGE_SURCHARGE_LARGE", 1500), "Added to the total of large packages, in minor units")
+	packageSurchargeOversized := flag.Int("package-surcharge-oversized", getEnvInt("PACKAGE_SURCHARGE_OVERSIZED", 4000), "Added to the total of oversized packages, in minor units")
+	locationSampleMeters := flag.Int("location-sample-meters", getEnvInt("LOCATION_SAMPLE_METERS", 20), "Distance a provider must move before a batched location is stored")
+	locationSampleInterval := flag.Duration("location-sample-interval", getEnvDuration("LOCATION_SAMPLE_INTERVAL", 10*time.Second), "Time after which a batched location is stored even if the provider has not moved")
+	locationBatchMaxPoints := flag.Int("location-batch-max-points", getEnvInt("LOCATION_BATCH_MAX_POINTS", 500), "Most locations accepted in one batch")
+	locationRetention := flag.Duration("location-retention", getEnvDuration("LOCATION_RETENTION", 30*24*time.Hour), "Age after which raw locations of orders with an archived track are deleted")
+	locationArchiveInterval := flag.Duration("location-archive-interval", getEnvDuration("LOCATION_ARCHIVE_INTERVAL", time.Hour), "How often finished orders are archived and old locations deleted")
+	locationArchiveBatch := flag.Int("location-archive-batch", getEnvInt("LOCATION_ARCHIVE_BATCH", 100), "Most orders archived per run")
+	orderArchiveAge := flag.Duration("order-archive-age", getEnvDuration("ORDER_ARCHIVE_AGE", 180*24*time.Hour), "Age since their last update after which completed, cancelled and refunded orders move to the archive (0 turns archiving off)")
+	orderArchiveInterval := flag.Duration("order-archive-interval", getEnvDuration("ORDER_ARCHIVE_INTERVAL", time.Hour), "How often old orders are moved to the archive")
+	orderArchiveBatch := flag.Int("order-archive-batch", getEnvInt("ORDER_ARCHIVE_BATCH", 500), "Most orders moved to the archive in one transaction")
+	piiKeysFile := flag.String("pii-keys-file", getEnv("PII_KEYS_FILE", ""), "File of keys that encrypt order addresses and notes, one id=base64key per line with the primary first; empty stores them in plaintext")
+	auditHashChain := flag.Bool("audit-hash-chain", getEnv("AUDIT_HASH_CHAIN", "") == "true", "Chain each audit log entry to the one before it with a hash, so rewriting the log is detectable")
+	piiReencryptBatch := flag.Int("pii-reencrypt-batch", getEnvInt("PII_REENCRYPT_BATCH", 100), "Orders re-encrypted at a time after a key rotation")
+	webhookDispatchInterval := flag.Duration("webhook-dispatch-interval", getEnvDuration("WEBHOOK_DISPATCH_INTERVAL", 5*time.Second), "How often due webhook deliveries are sent")
+	webhookTimeout := flag.Duration("webhook-timeout", getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second), "How long a partner has to answer a webhook request")
+	webhookMaxAttempts := flag.Int("webhook-max-attempts", getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8), "Attempts at a webhook delivery before it is marked failed")
+	webhookBackoffBase := flag.Duration("webhook-backoff-base", getEnvDuration("WEBHOOK_BACKOFF_BASE", 30*time.Second), "Wait after a first failed webhook attempt; doubles after each further failure")
+	webhookBackoffMax := flag.Duration("webhook-backoff-max", getEnvDuration("WEBHOOK_BACKOFF_MAX", time.Hour), "Longest wait between webhook attempts")
+	webhookBatch := flag.Int("webhook-batch", getEnvInt("WEBHOOK_BATCH", 50), "Most webhook deliveries sent per dispatch")
+	bulkOrderMaxRows := flag.Int("bulk-order-max-rows", getEnvInt("BULK_ORDER_MAX_ROWS", 1000), "Most orders accepted in one bulk import")
+	bulkOrderInterval := flag.Duration("bulk-order-interval", getEnvDuration("BULK_ORDER_INTERVAL", 5*time.Second), "How often waiting bulk imports are picked up")
+	bulkOrderBatch := flag.Int("bulk-order-batch", getEnvInt("BULK_ORDER_BATCH", 50), "Orders of a bulk import created between renewals of its lease")
+	bulkOrderLease := flag.Duration("bulk-order-lease", getEnvDuration("BULK_ORDER_LEASE", 2*time.Minute), "How long a bulk import stays with an instance that stops renewing it")
+	analyticsInterval := flag.Duration("analytics-interval", getEnvDuration("ANALYTICS_INTERVAL", time.Minute), "How often order events are folded into the daily analytics aggregates")
+	stockHold := flag.Duration("stock-hold", getEnvDuration("STOCK_HOLD", 30*time.Minute), "How long catalog stock is held for an order waiting for a provider before the order is cancelled and the stock released")
+	stockSweepInterval := flag.Duration("stock-sweep-interval", getEnvDuration("STOCK_SWEEP_INTERVAL", time.Minute), "How often expired stock holds are settled")
+	stockSweepBatch := flag.Int("stock-sweep-batch", getEnvInt("STOCK_SWEEP_BATCH", 100), "Most orders' stock holds settled per sweep")
+	unpaidOrderTTL := flag.Duration("unpaid-order-ttl", getEnvDuration("UNPAID_ORDER_TTL", time.Hour), "How long an order can wait for payment before it is cancelled (0 turns cancelling off)")
+	unpaidOrderInterval := flag.Duration("unpaid-order-interval", getEnvDuration("UNPAID_ORDER_INTERVAL", time.Minute), "How often orders waiting too long for payment are cancelled")
+	unpaidOrderBatch := flag.Int("unpaid-order-batch", getEnvInt("UNPAID_ORDER_BATCH", 100), "Most unpaid orders cancelled per run")
+	baseFares := flag.String("base-fares", getEnv("BASE_FARES", "RIDE=300,SERVICE_BOOKING=500,RETURN=300"), "What a client-priced or return order of each type costs before distance, as TYPE=N pairs in minor units; other types start from 0")
+	pricePerKm := flag.Int("price-per-km", getEnvInt("PRICE_PER_KM", 100), "Added to an order's base fare per kilometer from pickup to destination, in minor units")
+	priceTolerancePercent := flag.Int("price-tolerance-percent", getEnvInt("PRICE_TOLERANCE_PERCENT", 20), "How far from its expected total, or from the total quoted to the user, an order can be, from 0 to 100")
+	materialChangeMeters := flag.Int("material-change-meters", getEnvInt("MATERIAL_CHANGE_METERS", 500), "How far a user can move an accepted order's destination before its provider has to accept it again")
+	materialChangePercent := flag.Int("material-change-percent", getEnvInt("MATERIAL_CHANGE_PERCENT", 10), "How far, as a percent, a user's change can move an accepted order's total before its provider has to accept it again")
+	destinationChangeTTL := flag.Duration("destination-change-ttl", getEnvDuration("DESTINATION_CHANGE_TTL", 2*time.Minute), "How long a ride's destination change waits for the provider before it expires (0 waits until answered)")
+	returnWindow := flag.Duration("return-window", getEnvDuration("RETURN_WINDOW", 14*24*time.Hour), "How long after its delivery a package or grocery order can be sent back with a return order")
+	returnDiscountPercent := flag.Int("return-discount-percent", getEnvInt("RETURN_DISCOUNT_PERCENT", 0), "Taken off the trip fare of a return order, from 0 to 100")
+	duplicateOrderWindow := flag.Duration("duplicate-order-window", getEnvDuration("DUPLICATE_ORDER_WINDOW", 30*time.Second), "How long an order blocks an identical one from the same user, with the same type, pickup and destination (0 turns the check off)")
+	referrerPoints := flag.Int("referrer-points", getEnvInt("REFERRER_POINTS", 10000), "Loyalty points a user earns when a user they referred completes a first order")
+	referredPoints := flag.Int("referred-points", getEnvInt("REFERRED_POINTS", 10000), "Loyalty points a referred user earns when their first order completes")
+	loyaltyPointValue := flag.Int("loyalty-point-value", getEnvInt("LOYALTY_POINT_VALUE", 1), "What a redeemed loyalty point takes off an order's total, in minor units")
+	analyticsBatch := flag.Int("analytics-batch", getEnvInt("ANALYTICS_BATCH", 500), "Most order events aggregated in one transaction")
+	orderCacheBackend := flag.String("order-cache-backend", getEnv("ORDER_CACHE_BACKEND", ""), "Where orders read by ID are cached: memory, redis, or empty for no cache")
+	orderCacheRedisAddr := flag.String("order-cache-redis-addr", getEnv("ORDER_CACHE_REDIS_ADDR", "localhost:6379"), "Redis address of the order cache")
+	orderCacheRedisPassword := flag.String("order-cache-redis-password", getEnv("ORDER_CACHE_REDIS_PASSWORD", ""), "Redis password of the order cache")
+	orderCacheTTL := flag.Duration("order-cache-ttl", getEnvDuration("ORDER_CACHE_TTL", 10*time.Second), "How long an order read by ID stays cached")
+	grpcAuthToken := flag.String("grpc-auth-token", getEnv("GRPC_AUTH_TOKEN", ""), "Token gRPC callers must present, and that this service presents to the services it calls; empty turns authentication off")
+	grpcDefaultTimeout := flag.Duration("grpc-default-timeout", getEnvDuration("GRPC_DEFAULT_TIMEOUT", 30*time.Second), "Deadline of gRPC calls that arrive without one (0 leaves them without)")
+	grpcMaxTimeout := flag.Duration("grpc-max-timeout", getEnvDuration("GRPC_MAX_TIMEOUT", 2*time.Minute), "Longest deadline a gRPC call may have (0 leaves it uncapped)")
+	grpcClientTimeouts := flag.String("grpc-client-timeouts", getEnv("GRPC_CLIENT_TIMEOUTS", ""), "Timeouts of calls to other services that replace the built-in ones, as method=duration pairs separated by commas, such as /provider.ProviderService/FindProviders=3s")
+	grpcClientReserve := flag.Duration("grpc-client-reserve", getEnvDuration("GRPC_CLIENT_RESERVE", 200*time.Millisecond), "Time kept back from a call's own deadline to handle replies from the services it calls")
+	jobInterval := flag.Duration("job-interval", getEnvDuration("JOB_INTERVAL", time.Second), "How often due background jobs are picked up")
+	jobBatch := flag.Int("job-batch", getEnvInt("JOB_BATCH", 50), "Most background jobs run per interval")
+	jobTimeout := flag.Duration("job-timeout", getEnvDuration("JOB_TIMEOUT", 30*time.Second), "How long one attempt at a background job may take")
+	jobBackoffBase := flag.Duration("job-backoff-base", getEnvDuration("JOB_BACKOFF_BASE", 10*time.Second), "Wait after a first failed background job attempt; doubles after each further failure")
+	jobBackoffMax := flag.Duration("job-backoff-max", getEnvDuration("JOB_BACKOFF_MAX", time.Hour), "Longest wait between background job attempts")
+	jobBlockchainMaxAttempts := flag.Int("job-blockchain-max-attempts", getEnvInt("JOB_BLOCKCHAIN_MAX_ATTEMPTS", 10), "Attempts at recording an order on the blockchain before the job is dead")
+	jobNotificationMaxAttempts := flag.Int("job-notification-max-attempts", getEnvInt("JOB_NOTIFICATION_MAX_ATTEMPTS", 5), "Attempts at sending a notification before the job is dead")
+	locationFanoutBackend := flag.String("location-fanout-backend", getEnv("LOCATION_FANOUT_BACKEND", "memory"), "How stored locations reach TrackOrder streams, and offers reach dispatch channels: memory for a single instance, or redis to reach every instance")
+	locationFanoutRedisAddr := flag.String("location-fanout-redis-addr", getEnv("LOCATION_FANOUT_REDIS_ADDR", "localhost:6379"), "Redis address of the location fan-out")
+	locationFanoutRedisPassword := flag.String("location-fanout-redis-password", getEnv("LOCATION_FANOUT_REDIS_PASSWORD", ""), "Redis password of the location fan-out")
+	schedulerLockRetry := flag.Duration("scheduler-lock-retry", getEnvDuration("SCHEDULER_LOCK_RETRY", 10*time.Second), "How often instances not running a scheduler try to take it over, and the one running it checks its lock")
+	chaosEnabled := flag.Bool("chaos-enabled", getEnv("CHAOS_ENABLED", "") == "true", "Let admins inject latency and errors into calls to other services, for resilience testing in staging")
 	
 	flag.Parse()
 
+	if *deliveryPINLength < 4 || *deliveryPINLength > 6 {
+		log.Fatalf("Delivery PIN length must be between 4 and 6, got %d", *deliveryPINLength)
+	}
+
 	// Set up database connection
 	dbConfig := database.NewPostgresConfig(
 		*dbHost,
@@ -43,6 +214,7 @@ func main() {
 		*dbName,
 		*dbSSLMode,
 	)
+	dbConfig.SlowQueryThreshold = *dbSlowQueryThreshold
 	
 	db, err := database.NewPostgresDB(dbConfig)
 	if err != nil {
@@ -50,25 +222,436 @@ func main() {
 	}
 	defer db.Close()
 
+	// Load the keys that encrypt personal data at rest
+	keyRing, err := crypto.LoadKeyRing(*piiKeysFile)
+	if err != nil {
+		log.Fatalf("Failed to load PII encryption keys: %v", err)
+	}
+
 	// Initialize repositories
-	orderRepo := repository.NewOrderRepository(db)
+	orderRepo := repository.NewOrderRepository(db, keyRing)
+	cacheStore, err := cache.NewStore(context.Background(), cache.Config{
+		Backend:       *orderCacheBackend,
+		RedisAddr:     *orderCacheRedisAddr,
+		RedisPassword: *orderCacheRedisPassword,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create order cache: %v", err)
+	}
+	var orderCache *repository.OrderCache
+	if cacheStore != nil {
+		orderCache = repository.NewOrderCache(cacheStore, *orderCacheTTL)
+		orderRepo.UseCache(orderCache)
+	}
 	locationRepo := repository.NewOrderLocationRepository(db)
 
+	disputeRepo := repository.NewDisputeRepository(db)
+	refundRepo := repository.NewRefundRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	shareRepo := repository.NewPaymentShareRepository(db)
+	feeRepo := repository.NewFeeRepository(db)
+	dispatchRepo := repository.NewDispatchRepository(db)
+	serviceAreaRepo := repository.NewServiceAreaRepository(db)
+	proofRepo := repository.NewDeliveryProofRepository(db)
+	pinRepo := repository.NewDeliveryPINRepository(db)
+	batchRepo := repository.NewOrderBatchRepository(db)
+	rentalRepo := repository.NewRentalRepository(db)
+	vehicleRepo := repository.NewOrderVehicleRepository(db)
+	merchantRepo := repository.NewMerchantRepository(db)
+	paymentMethodRepo := repository.NewPaymentMethodRepository(db)
+	walletRepo := repository.NewWalletRepository(db)
+	loyaltyRepo := repository.NewLoyaltyRepository(db)
+	stockRepo := repository.NewStockRepository(db)
+	userProviderRepo := repository.NewUserProviderRepository(db)
+	chatRepo := repository.NewChatRepository(db)
+	contactRepo := repository.NewContactTokenRepository(db)
+	trackingLinkRepo := repository.NewTrackingLinkRepository(db)
+	incidentRepo := repository.NewIncidentRepository(db)
+	deviationRepo := repository.NewRouteDeviationRepository(db)
+	authorizationRepo := repository.NewPaymentAuthorizationRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	bulkOrderRepo := repository.NewBulkOrderRepository(db, keyRing)
+	analyticsRepo := repository.NewAnalyticsRepository(db)
+	privacyRepo := repository.NewPrivacyRepository(db)
+	denylistRepo := repository.NewDenylistRepository(db)
+	supportRepo := repository.NewSupportSessionRepository(db)
+	slaRepo := repository.NewSLARepository(db)
+
+	// Bound calls to other services by the deadline of the call that makes them
+	clientMethodTimeouts, err := deadline.ParseMethods(*grpcClientTimeouts)
+	if err != nil {
+		log.Fatalf("Failed to parse gRPC client timeouts: %v", err)
+	}
+	clientTimeouts := deadline.Timeouts{Methods: clientMethodTimeouts, Reserve: *grpcClientReserve}
+
+	// Inject faults into calls to other services only when enabled, and then only once
+	// they are set through the admin API
+	clientOpts := []grpc.DialOption{grpcserver.WithToken(*grpcAuthToken)}
+	var faultInjector *chaos.Injector
+	if *chaosEnabled {
+		faultInjector = chaos.NewInjector()
+		clientOpts = append(clientOpts, faultInjector.DialOption(), faultInjector.StreamDialOption())
+		log.Printf("Fault injection enabled")
+	}
+
+	// Push stored locations to TrackOrder streams and offers to dispatch channels, on every
+	// instance with Redis
+	locationBus, err := fanout.NewBus(context.Background(), fanout.Config{
+		Backend:       *locationFanoutBackend,
+		RedisAddr:     *locationFanoutRedisAddr,
+		RedisPassword: *locationFanoutRedisPassword,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create location fan-out: %v", err)
+	}
+	defer locationBus.Close()
+	dispatchOffers := service.NewDispatchOffers(dispatchRepo, locationBus, service.DispatchOfferConfig{
+		TTL:           *dispatchOfferTTL,
+		AckTimeout:    *dispatchOfferAckTimeout,
+		SweepInterval: *dispatchOfferSweepInterval,
+		SweepBatch:    *dispatchOfferSweepBatch,
+	})
+
 	// Initialize clients
-	blockchainClient, err := clients.NewBlockchainGRPCClient(*blockchainServiceAddr)
+	blockchainClient, err := clients.NewBlockchainGRPCClient(*blockchainServiceAddr, clientTimeouts, clientOpts...)
 	if err != nil {
 		log.Fatalf("Failed to connect to blockchain service: %v", err)
 	}
 	defer blockchainClient.Close()
 	
-	providerClient, err := clients.NewProviderGRPCClient(*providerServiceAddr)
+	providerClient, err := clients.NewProviderGRPCClient(*providerServiceAddr, clientTimeouts, clientOpts...)
 	if err != nil {
 		log.Fatalf("Failed to connect to provider service: %v", err)
 	}
 	defer providerClient.Close()
 
-	// Initialize service
-	orderService := service.NewOrderService(orderRepo, locationRepo, blockchainClient, providerClient)
+	paymentClient, err := clients.NewPaymentGRPCClient(*paymentServiceAddr, clientTimeouts, clientOpts...)
+	if err != nil {
+		log.Fatalf("Failed to connect to payment service: %v", err)
+	}
+	defer paymentClient.Close()
+
+	notificationClient, err := clients.NewNotificationGRPCClient(*notificationServiceAddr, clientTimeouts, clientOpts...)
+	if err != nil {
+		log.Fatalf("Failed to connect to notification service: %v", err)
+	}
+	defer notificationClient.Close()
+
+	// Record orders on the blockchain and send notifications as retried background jobs
+	jobQueue := jobs.NewQueue(db, "order", jobs.Config{
+		Interval:  *jobInterval,
+		BatchSize: *jobBatch,
+	})
+	blockchainRecorder := service.NewBlockchainRecorder(jobQueue, orderRepo, blockchainClient, jobs.Policy{
+		MaxAttempts: *jobBlockchainMaxAttempts,
+		BackoffBase: *jobBackoffBase,
+		BackoffMax:  *jobBackoffMax,
+		Timeout:     *jobTimeout,
+	})
+	notifications := service.NewQueuedNotifications(jobQueue, notificationClient, jobs.Policy{
+		MaxAttempts: *jobNotificationMaxAttempts,
+		BackoffBase: *jobBackoffBase,
+		BackoffMax:  *jobBackoffMax,
+		Timeout:     *jobTimeout,
+	})
+
+	// Expose metrics, including downstream circuit breaker state
+	metrics.Serve(*metricsPort)
+
+	// Collect split payments in the background
+	splitCollector := service.NewSplitPaymentCollector(orderRepo, shareRepo, paymentClient, notifications, service.SplitPaymentConfig{
+		Timeout:  *splitPaymentTimeout,
+		Interval: *splitPaymentInterval,
+	})
+	collectorCtx, stopCollector := context.WithCancel(context.Background())
+	defer stopCollector()
+
+	// Only one instance runs each scheduler that acts on shared orders
+	elector := lock.NewElector(db, "order", *schedulerLockRetry)
+	defer elector.Close()
+	go elector.Run(collectorCtx, "split-payments", splitCollector.Run)
+
+	// Hold card and wallet payments until their orders complete, settling holds about to lapse
+	var authorizations *service.PaymentAuthorizations
+	if *paymentAuthorization {
+		authorizations = service.NewPaymentAuthorizations(authorizationRepo, orderRepo, paymentClient, service.PaymentAuthorizationConfig{
+			CaptureMargin: *authorizationCaptureMargin,
+			Interval:      *authorizationSweepInterval,
+			BatchSize:     *authorizationSweepBatch,
+		})
+		go elector.Run(collectorCtx, "payment-authorizations", authorizations.Run)
+	}
+
+	// Drop cached orders as they change
+	if orderCache != nil {
+		go orderCache.Listen(collectorCtx, db)
+	}
+
+	// Complete crypto orders once their payment is confirmed on-chain
+	cryptoMonitor := service.NewCryptoPaymentMonitor(orderRepo, blockchainClient, notifications, *cryptoPaymentInterval)
+	go elector.Run(collectorCtx, "crypto-payments", cryptoMonitor.Run)
+
+	// Load the fee schedule and keep it in sync with admin changes
+	feeSchedule := service.NewFeeSchedule(feeRepo, *feeRefreshInterval)
+	if err := feeSchedule.Refresh(context.Background()); err != nil {
+		log.Fatalf("Failed to load fee schedule: %v", err)
+	}
+	go feeSchedule.Run(collectorCtx)
+
+	// Load the service areas and keep them in sync with admin changes
+	serviceAreas := service.NewServiceAreas(serviceAreaRepo, *serviceAreaRefreshInterval)
+	if err := serviceAreas.Refresh(context.Background()); err != nil {
+		log.Fatalf("Failed to load service areas: %v", err)
+	}
+	go serviceAreas.Run(collectorCtx)
+
+	// Predict with the external model when one is configured, falling back to the heuristics
+	var predictor service.Predictor = service.NewHeuristicPredictor(orderRepo, serviceAreas, service.HeuristicPredictorConfig{
+		AverageSpeedKmh: float64(*predictorAverageSpeed),
+		DemandWeeks:     *predictorDemandWeeks,
+	})
+	if *predictorServiceAddr != "" {
+		predictorClient, err := clients.NewPredictorGRPCClient(*predictorServiceAddr, clientTimeouts, clientOpts...)
+		if err != nil {
+			log.Fatalf("Failed to connect to prediction service: %v", err)
+		}
+		defer predictorClient.Close()
+		predictor = service.NewFallbackPredictor(predictorClient, predictor)
+	}
+
+	// Load the dispatch weights and keep them in sync with admin changes
+	dispatcher := service.NewDispatcher(dispatchRepo, predictor, service.DispatchConfig{
+		EarningsWindow:   *dispatchEarningsWindow,
+		IdleCap:          *dispatchIdleCap,
+		RefreshInterval:  *dispatchRefreshInterval,
+		DeclineWindow:    *dispatchDeclineWindow,
+		DeclineMinOffers: *dispatchDeclineMinOffers,
+		DeclineRate:      float64(*dispatchDeclineRate) / 100,
+		DeclinePenalty:   float64(*dispatchDeclinePenalty) / 100,
+	})
+	if err := dispatcher.Refresh(context.Background()); err != nil {
+		log.Fatalf("Failed to load dispatch weights: %v", err)
+	}
+	go dispatcher.Run(collectorCtx)
+
+	// Close offers left unanswered past their expiry; several instances can do so together
+	go dispatchOffers.Run(collectorCtx)
+
+	// Alert users and safety staff when a provider leaves an order's route
+	deviationAnalyzer := service.NewRouteDeviationAnalyzer(deviationRepo, orderRepo, locationRepo, notifications, service.RouteDeviationConfig{
+		MaxDistanceKm: float64(*routeDeviationMeters) / 1000,
+		MinDuration:   *routeDeviationDuration,
+		Interval:      *routeDeviationInterval,
+		AdminChannel:  *sosAdminChannel,
+	})
+	go elector.Run(collectorCtx, "route-deviation", deviationAnalyzer.Run)
+
+	// Mark orders at risk and alert operations staff when a stage takes longer than its SLA
+	slaMonitor := service.NewSLAMonitor(slaRepo, orderRepo, notifications, service.SLAConfig{
+		MaxDurations: map[model.SLAStage]time.Duration{
+			model.SLAAssignment: *slaAssignment,
+			model.SLAPickup:     *slaPickup,
+			model.SLADelivery:   *slaDelivery,
+		},
+		Interval:   *slaCheckInterval,
+		Lookback:   *slaLookback,
+		OpsChannel: *slaOpsChannel,
+	})
+	go elector.Run(collectorCtx, "sla", slaMonitor.Run)
+
+	// Archive finished orders' tracks and delete old raw locations
+	retention := service.NewLocationRetention(locationRepo, service.LocationRetentionConfig{
+		Retention: *locationRetention,
+		Interval:  *locationArchiveInterval,
+		BatchSize: *locationArchiveBatch,
+	})
+	go elector.Run(collectorCtx, "location-retention", retention.Run)
+
+	// Move old finished orders out of the orders table
+	if *orderArchiveAge > 0 {
+		archiver := service.NewOrderArchiver(orderRepo, service.OrderArchiveConfig{
+			Age:       *orderArchiveAge,
+			Interval:  *orderArchiveInterval,
+			BatchSize: *orderArchiveBatch,
+		})
+		go elector.Run(collectorCtx, "order-archive", archiver.Run)
+	}
+
+	// Send queued webhook deliveries to partners
+	webhookDispatcher := service.NewWebhookDispatcher(webhookRepo, service.WebhookConfig{
+		Interval:    *webhookDispatchInterval,
+		Timeout:     *webhookTimeout,
+		MaxAttempts: *webhookMaxAttempts,
+		BackoffBase: *webhookBackoffBase,
+		BackoffMax:  *webhookBackoffMax,
+		BatchSize:   *webhookBatch,
+	})
+	go webhookDispatcher.Run(collectorCtx)
+
+	// Fold order events into the daily analytics aggregates
+	analyticsAggregator := service.NewAnalyticsAggregator(analyticsRepo, service.AnalyticsConfig{
+		Interval:  *analyticsInterval,
+		BatchSize: *analyticsBatch,
+	})
+	go analyticsAggregator.Run(collectorCtx)
+
+	// Store providers' quality, worked out from offers and order events, on their records
+	qualityPublisher := service.NewProviderQualityPublisher(analyticsRepo, providerClient, service.ProviderQualityConfig{
+		Window:    *providerQualityWindow,
+		Interval:  *providerQualityInterval,
+		BatchSize: *providerQualityBatch,
+	})
+	go elector.Run(collectorCtx, "provider-quality", qualityPublisher.Run)
+
+	// Encrypt addresses and notes stored in plaintext or under a retired key
+	if keyRing != nil {
+		go func() {
+			err := crypto.Reencrypt(collectorCtx, "order addresses and notes", func(ctx context.Context) (int, error) {
+				return orderRepo.ReencryptOrders(ctx, *piiReencryptBatch)
+			})
+			if err != nil && collectorCtx.Err() == nil {
+				log.Printf("Failed to re-encrypt orders: %v", err)
+			}
+		}()
+	}
+
+	// Initialize services
+	concurrencyLimits, err := service.ParseConcurrencyLimits(*concurrentOrderLimits)
+	if err != nil {
+		log.Fatalf("Invalid concurrent order limits: %v", err)
+	}
+	fares, err := service.ParseBaseFares(*baseFares)
+	if err != nil {
+		log.Fatalf("Invalid base fares: %v", err)
+	}
+	pricingPolicy := service.PricingPolicy{
+		BaseFares:        fares,
+		PerKmRate:        int64(*pricePerKm),
+		TolerancePercent: *priceTolerancePercent,
+	}
+	if err := pricingPolicy.Validate(); err != nil {
+		log.Fatalf("Invalid pricing policy: %v", err)
+	}
+	editPolicy := service.OrderEditPolicy{
+		MaterialDistanceKm:   float64(*materialChangeMeters) / 1000,
+		MaterialPricePercent: *materialChangePercent,
+		DestinationChangeTTL: *destinationChangeTTL,
+	}
+	if err := editPolicy.Validate(); err != nil {
+		log.Fatalf("Invalid order edit policy: %v", err)
+	}
+	returnPolicy := service.ReturnPolicy{
+		Window:          *returnWindow,
+		DiscountPercent: *returnDiscountPercent,
+	}
+	if err := returnPolicy.Validate(); err != nil {
+		log.Fatalf("Invalid return policy: %v", err)
+	}
+	loyaltyPolicy := service.LoyaltyPolicy{
+		ReferrerPoints: int64(*referrerPoints),
+		ReferredPoints: int64(*referredPoints),
+		PointValue:     int64(*loyaltyPointValue),
+	}
+	if err := loyaltyPolicy.Validate(); err != nil {
+		log.Fatalf("Invalid loyalty policy: %v", err)
+	}
+	loyalty := service.NewLoyalty(loyaltyRepo, paymentMethodRepo, loyaltyPolicy)
+	orderService := service.NewOrderService(orderRepo, locationRepo, refundRepo, ledgerRepo, shareRepo, proofRepo, pinRepo, batchRepo, rentalRepo, vehicleRepo, merchantRepo, paymentMethodRepo, walletRepo, loyalty, stockRepo, *stockHold, userProviderRepo, blockchainClient, blockchainRecorder, providerClient, paymentClient, notifications, splitCollector, authorizations, feeSchedule, service.CancellationPolicy{
+		FreeWindow:         *cancellationFreeWindow,
+		AcceptedFeePercent: float64(*cancellationFeePercent),
+	}, service.DeliveryPINPolicy{
+		Length:         *deliveryPINLength,
+		MaxAttempts:    *deliveryPINMaxAttempts,
+		Lockout:        *deliveryPINLockout,
+		ResendInterval: *deliveryPINResendInterval,
+	}, service.GeofencePolicy{
+		PickupRadiusKm:      float64(*geofencePickupMeters) / 1000,
+		DestinationRadiusKm: float64(*geofenceDestinationMeters) / 1000,
+	}, service.LocationSamplingPolicy{
+		MinDistanceKm: float64(*locationSampleMeters) / 1000,
+		MinInterval:   *locationSampleInterval,
+		MaxBatchSize:  *locationBatchMaxPoints,
+	}, service.ConcurrencyPolicy{
+		Limits: concurrencyLimits,
+	}, service.BatchingPolicy{
+		PickupRadiusKm:    float64(*batchPickupRadiusMeters) / 1000,
+		MaxBearingDegrees: float64(*batchMaxBearingDegrees),
+		MaxOrders:         *batchMaxOrders,
+	}, service.RentalPolicy{
+		HourlyRate:         int64(*rentalHourlyRate),
+		MinHours:           *rentalMinHours,
+		MaxHours:           *rentalMaxHours,
+		OvertimeMultiplier: float64(*rentalOvertimePercent) / 100,
+		OvertimeIncrement:  *rentalOvertimeIncrement,
+		OvertimeGrace:      *rentalOvertimeGrace,
+	}, service.PackagePolicy{
+		MediumSurcharge:    int64(*packageSurchargeMedium),
+		LargeSurcharge:     int64(*packageSurchargeLarge),
+		OversizedSurcharge: int64(*packageSurchargeOversized),
+	}, service.DuplicatePolicy{
+		Window: *duplicateOrderWindow,
+	}, pricingPolicy, editPolicy, returnPolicy, dispatcher, dispatchOffers, serviceAreas, predictor, locationBus)
+
+	// Settle catalog stock held for orders past its hold, cancelling orders no provider took
+	stockSweeper := service.NewStockSweeper(stockRepo, orderRepo, orderService, service.StockSweeperConfig{
+		Interval:  *stockSweepInterval,
+		BatchSize: *stockSweepBatch,
+	})
+	go elector.Run(collectorCtx, "stock-reservations", stockSweeper.Run)
+
+	// Cancel orders left waiting for payment, giving back whatever they hold
+	if *unpaidOrderTTL > 0 {
+		unpaidSweeper := service.NewUnpaidOrderSweeper(orderRepo, orderService, notifications, service.UnpaidOrderConfig{
+			TTL:       *unpaidOrderTTL,
+			Interval:  *unpaidOrderInterval,
+			BatchSize: *unpaidOrderBatch,
+		})
+		go elector.Run(collectorCtx, "unpaid-orders", unpaidSweeper.Run)
+	}
+
+	disputeService := service.NewDisputeService(disputeRepo, orderRepo, blockchainRecorder, paymentClient, authorizations)
+	feeService := service.NewFeeService(feeRepo, feeSchedule)
+	dispatchService := service.NewDispatchService(dispatchRepo, dispatcher, predictor, serviceAreas)
+	serviceAreaService := service.NewServiceAreaService(serviceAreaRepo, serviceAreas)
+	merchantService := service.NewMerchantService(merchantRepo)
+	userProviderService := service.NewUserProviderService(userProviderRepo)
+	paymentMethodService := service.NewPaymentMethodService(paymentMethodRepo)
+	walletService := service.NewWalletService(walletRepo, paymentMethodRepo, paymentClient)
+	loyaltyService := service.NewLoyaltyService(loyaltyRepo, orderRepo, loyaltyPolicy)
+	chatService := service.NewChatService(chatRepo, orderRepo, notifications)
+	contactService := service.NewContactService(contactRepo, orderRepo, providerClient, service.ContactPolicy{
+		TokenTTL:     *contactTokenTTL,
+		BridgeNumber: *contactBridgeNumber,
+	})
+	trackingLinkService := service.NewTrackingLinkService(trackingLinkRepo, orderRepo, locationRepo, service.TrackingLinkPolicy{
+		DefaultTTL: *trackingLinkTTL,
+		MaxTTL:     *trackingLinkMaxTTL,
+	})
+	timelineService := service.NewOrderTimelineService(orderRepo, deviationRepo, authorizationRepo, shareRepo, refundRepo, notificationClient, blockchainClient, service.TimelinePolicy{
+		SourceTimeout: *timelineSourceTimeout,
+	})
+	incidentService := service.NewIncidentService(incidentRepo, orderRepo, notifications, *sosAdminChannel)
+	privacyService := service.NewPrivacyService(privacyRepo, orderRepo, locationRepo, chatRepo, userProviderRepo, paymentMethodRepo, walletRepo, loyaltyRepo, notificationClient, providerClient)
+	webhookService := service.NewWebhookService(webhookRepo)
+	denylistService := service.NewDenylistService(denylistRepo)
+	supportAccessService := service.NewSupportAccessService(supportRepo, service.SupportAccessPolicy{
+		DefaultTTL: *supportSessionTTL,
+		MaxTTL:     *supportSessionMaxTTL,
+	})
+	bulkOrderService := service.NewBulkOrderService(bulkOrderRepo, *bulkOrderMaxRows)
+	analyticsService := service.NewAnalyticsService(analyticsRepo)
+	operationsService := service.NewOperationsService(orderRepo, dispatchRepo, serviceAreas, providerClient)
+
+	// Create the orders of bulk imports in the background
+	bulkOrderImporter := service.NewBulkOrderImporter(bulkOrderRepo, orderService, service.BulkOrderConfig{
+		Interval:  *bulkOrderInterval,
+		BatchSize: *bulkOrderBatch,
+		Lease:     *bulkOrderLease,
+	})
+	go bulkOrderImporter.Run(collectorCtx)
+
+	// Run background jobs once every kind is registered
+	go jobQueue.Run(collectorCtx)
 
 	// Set up gRPC server
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
@@ -76,8 +659,43 @@ func main() {
 		log.Fatalf("Failed to listen on port %d: %v", *port, err)
 	}
 
-	grpcServer := grpc.NewServer()
+	// Record every mutating call except location pings, which order_locations already keeps
+	auditLog := audit.NewLog(db, "order", *auditHashChain)
+	grpcServer := grpcserver.New(grpcserver.Config{
+		AuthToken:      *grpcAuthToken,
+		DefaultTimeout: *grpcDefaultTimeout,
+		MaxTimeout:     *grpcMaxTimeout,
+		UnaryInterceptors: []grpc.UnaryServerInterceptor{
+			audit.UnaryServerInterceptor(auditLog, service.NewOrderAuditSnapshotter(orderRepo, denylistRepo, supportRepo), "UpdateLocation", "BatchUpdateLocation"),
+		},
+	})
 	pb.RegisterOrderServiceServer(grpcServer, orderService)
+	disputePb.RegisterDisputeServiceServer(grpcServer, disputeService)
+	feePb.RegisterFeeServiceServer(grpcServer, feeService)
+	dispatchPb.RegisterDispatchServiceServer(grpcServer, dispatchService)
+	serviceAreaPb.RegisterServiceAreaServiceServer(grpcServer, serviceAreaService)
+	merchantPb.RegisterMerchantServiceServer(grpcServer, merchantService)
+	userProviderPb.RegisterUserProviderServiceServer(grpcServer, userProviderService)
+	paymentMethodPb.RegisterPaymentMethodServiceServer(grpcServer, paymentMethodService)
+	walletPb.RegisterWalletServiceServer(grpcServer, walletService)
+	loyaltyPb.RegisterLoyaltyServiceServer(grpcServer, loyaltyService)
+	chatPb.RegisterChatServiceServer(grpcServer, chatService)
+	contactPb.RegisterContactServiceServer(grpcServer, contactService)
+	trackingPb.RegisterTrackingLinkServiceServer(grpcServer, trackingLinkService)
+	timelinePb.RegisterOrderTimelineServiceServer(grpcServer, timelineService)
+	incidentPb.RegisterIncidentServiceServer(grpcServer, incidentService)
+	privacyPb.RegisterPrivacyServiceServer(grpcServer, privacyService)
+	webhookPb.RegisterWebhookServiceServer(grpcServer, webhookService)
+	denylistPb.RegisterDenylistServiceServer(grpcServer, denylistService)
+	supportPb.RegisterSupportAccessServiceServer(grpcServer, supportAccessService)
+	bulkOrderPb.RegisterBulkOrderServiceServer(grpcServer, bulkOrderService)
+	analyticsPb.RegisterAnalyticsServiceServer(grpcServer, analyticsService)
+	operationsPb.RegisterOperationsServiceServer(grpcServer, operationsService)
+	auditPb.RegisterAuditServiceServer(grpcServer, audit.NewServer(auditLog))
+	jobsPb.RegisterJobServiceServer(grpcServer, jobs.NewServer(jobQueue))
+	if faultInjector != nil {
+		chaosPb.RegisterChaosServiceServer(grpcServer, chaos.NewServer(faultInjector))
+	}
 
 	// Handle graceful shutdown
 	go func() {
@@ -129,10 +747,25 @@ func getEnvInt(key string, defaultValue int) int {
 		return defaultValue
 	}
 	
-	intValue, err := fmt.Sscanf(value, "%d")
-	if err != nil || len(intValue) == 0 {
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	
+	return intValue
+}
+
+// Helper function to get environment variables as durations
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	
+	duration, err := time.ParseDuration(value)
+	if err != nil {
 		return defaultValue
 	}
 	
-	return intValue[0]
-} 
\ No newline at end of file
+	return duration
+}
\ No newline at end of file
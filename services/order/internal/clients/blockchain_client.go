@@ -6,20 +6,35 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/order-api-microservices/pkg/breaker"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/requestid"
 	pb "github.com/order-api-microservices/proto/blockchain"
+	"github.com/order-api-microservices/services/order/internal/model"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
+// blockchainTimeouts are the timeouts of calls to the blockchain service, unless configured otherwise
+var blockchainTimeouts = deadline.Timeouts{Default: 10 * time.Second}
+
 // BlockchainGRPCClient is a client for the blockchain service
 type BlockchainGRPCClient struct {
 	client pb.BlockchainServiceClient
 	conn   *grpc.ClientConn
 }
 
-// NewBlockchainGRPCClient creates a new blockchain service client
-func NewBlockchainGRPCClient(address string) (*BlockchainGRPCClient, error) {
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewBlockchainGRPCClient creates a new blockchain service client. Its calls take the
+// timeouts of blockchainTimeouts merged with timeouts, and are dialled with any extra opts.
+func NewBlockchainGRPCClient(address string, timeouts deadline.Timeouts, opts ...grpc.DialOption) (*BlockchainGRPCClient, error) {
+	conn, err := grpc.Dial(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		deadline.DialOption(blockchainTimeouts.Merge(timeouts)),
+		requestid.DialOption(),
+		breaker.DialOption("blockchain", breaker.DefaultConfig()),
+	}, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to blockchain service: %v", err)
 	}
@@ -62,11 +77,13 @@ func (c *BlockchainGRPCClient) RecordOrder(ctx context.Context, orderID, userID,
 			DataHash:  orderDataHash,
 		},
 		Signature: "", // In a real implementation, this would be a digital signature
+		Payload:   orderDataBytes, // Full snapshot, anchored off-chain when the blockchain service enables it
 	}
 
-	// Set context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+	// Commit the on-chain hash to the proof of delivery
+	if order, ok := orderData.(*model.Order); ok {
+		req.OrderData.DeliveryProofHash = order.DeliveryProofHash
+	}
 
 	// Call the service
 	resp, err := c.client.RecordOrder(ctx, req)
@@ -89,10 +106,6 @@ func (c *BlockchainGRPCClient) VerifyOrder(ctx context.Context, orderID, txHash
 		TransactionHash: txHash,
 	}
 
-	// Set context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
 	// Call the service
 	resp, err := c.client.VerifyOrder(ctx, req)
 	if err != nil {
@@ -109,10 +122,6 @@ func (c *BlockchainGRPCClient) GetOrderHistory(ctx context.Context, orderID stri
 		OrderId: orderID,
 	}
 
-	// Set context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
 	// Call the service
 	resp, err := c.client.GetOrderHistory(ctx, req)
 	if err != nil {
@@ -133,10 +142,6 @@ func (c *BlockchainGRPCClient) GetTransactionDetails(ctx context.Context, txHash
 		TransactionHash: txHash,
 	}
 
-	// Set context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
 	// Call the service
 	resp, err := c.client.GetTransactionDetails(ctx, req)
 	if err != nil {
@@ -148,4 +153,39 @@ func (c *BlockchainGRPCClient) GetTransactionDetails(ctx context.Context, txHash
 	}
 
 	return resp, nil
-} 
\ No newline at end of file
+}
+
+// CreateCryptoPayment requests payment for an order in the default chain's native token
+func (c *BlockchainGRPCClient) CreateCryptoPayment(ctx context.Context, orderID string, amount int64) error {
+	// Call the service
+	resp, err := c.client.CreateCryptoPayment(ctx, &pb.CreateCryptoPaymentRequest{
+		OrderId: orderID,
+		Amount:  amount,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create crypto payment: %v", err)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("blockchain service failed to create crypto payment: %s", resp.Message)
+	}
+
+	return nil
+}
+
+// GetCryptoPaymentStatus returns the status of an order's crypto payment, or an empty
+// status if no payment has been requested for the order
+func (c *BlockchainGRPCClient) GetCryptoPaymentStatus(ctx context.Context, orderID string) (string, error) {
+	// Call the service
+	resp, err := c.client.GetCryptoPayment(ctx, &pb.GetCryptoPaymentRequest{
+		OrderId: orderID,
+	})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get crypto payment: %v", err)
+	}
+
+	return resp.Payment.Status, nil
+}
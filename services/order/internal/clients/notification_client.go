@@ -0,0 +1,151 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/pkg/breaker"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/requestid"
+	pb "github.com/order-api-microservices/proto/notification"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// notificationTimeouts are the timeouts of calls to the notification service, unless configured otherwise
+var notificationTimeouts = deadline.Timeouts{Default: 10 * time.Second}
+
+// NotificationGRPCClient is a client for the notification service
+type NotificationGRPCClient struct {
+	client  pb.NotificationServiceClient
+	privacy pb.NotificationPrivacyServiceClient
+	conn    *grpc.ClientConn
+}
+
+// NewNotificationGRPCClient creates a new notification service client. Its calls take the
+// timeouts of notificationTimeouts merged with timeouts, and are dialled with any extra opts.
+func NewNotificationGRPCClient(address string, timeouts deadline.Timeouts, opts ...grpc.DialOption) (*NotificationGRPCClient, error) {
+	conn, err := grpc.Dial(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		deadline.DialOption(notificationTimeouts.Merge(timeouts)),
+		requestid.DialOption(),
+		breaker.DialOption("notification", breaker.DefaultConfig()),
+	}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to notification service: %v", err)
+	}
+
+	client := pb.NewNotificationServiceClient(conn)
+	return &NotificationGRPCClient{
+		client:  client,
+		privacy: pb.NewNotificationPrivacyServiceClient(conn),
+		conn:    conn,
+	}, nil
+}
+
+// Close closes the connection to the notification service
+func (c *NotificationGRPCClient) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// SendNotification sends a notification about an order to a user or provider
+func (c *NotificationGRPCClient) SendNotification(ctx context.Context, recipientID, recipientType, notificationType, title, message string, payload map[string]interface{}) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %v", err)
+	}
+
+	// Create the request
+	req := &pb.SendNotificationRequest{
+		RecipientId:      recipientID,
+		RecipientType:    recipientType,
+		NotificationType: notificationType,
+		Title:            title,
+		Message:          message,
+		Payload:          payloadBytes,
+	}
+	if orderID, ok := payload["order_id"].(string); ok {
+		req.ReferenceId = orderID
+	}
+
+	// Call the service
+	resp, err := c.client.SendNotification(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %v", err)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("notification service failed to send notification: %s", resp.Message)
+	}
+
+	return nil
+}
+
+// ExportNotifications gets every notification sent to a user or provider, oldest first
+func (c *NotificationGRPCClient) ExportNotifications(ctx context.Context, recipientID string) ([]*model.ExportedNotification, error) {
+	resp, err := c.privacy.ExportNotifications(ctx, &pb.ExportNotificationsRequest{RecipientId: recipientID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export notifications: %v", err)
+	}
+
+	return convertNotifications(resp.Notifications)
+}
+
+// ListOrderNotifications gets every notification sent about an order, to its user or
+// provider, oldest first
+func (c *NotificationGRPCClient) ListOrderNotifications(ctx context.Context, orderID string) ([]*model.ExportedNotification, error) {
+	resp, err := c.client.ListReferenceNotifications(ctx, &pb.ListReferenceNotificationsRequest{ReferenceId: orderID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order notifications: %v", err)
+	}
+
+	return convertNotifications(resp.Notifications)
+}
+
+// convertNotifications converts notifications from the notification service, decoding
+// their payloads
+func convertNotifications(protoNotifications []*pb.Notification) ([]*model.ExportedNotification, error) {
+	notifications := make([]*model.ExportedNotification, 0, len(protoNotifications))
+	for _, n := range protoNotifications {
+		notification := &model.ExportedNotification{
+			ID:               n.Id,
+			RecipientType:    n.RecipientType,
+			NotificationType: n.NotificationType,
+			Title:            n.Title,
+			Message:          n.Message,
+			ReferenceID:      n.ReferenceId,
+			Read:             n.Read,
+			CreatedAt:        n.CreatedAt.AsTime(),
+		}
+		if len(n.Payload) > 0 {
+			if err := json.Unmarshal(n.Payload, &notification.Payload); err != nil {
+				return nil, fmt.Errorf("failed to decode payload of notification %s: %v", n.Id, err)
+			}
+		}
+		if n.ReadAt != nil {
+			readAt := n.ReadAt.AsTime()
+			notification.ReadAt = &readAt
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+// AnonymizeNotifications erases the content of every notification sent to a user or
+// provider and reports how many were changed
+func (c *NotificationGRPCClient) AnonymizeNotifications(ctx context.Context, recipientID string) (int64, error) {
+	resp, err := c.privacy.AnonymizeNotifications(ctx, &pb.AnonymizeNotificationsRequest{RecipientId: recipientID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize notifications: %v", err)
+	}
+
+	return resp.Anonymized, nil
+}
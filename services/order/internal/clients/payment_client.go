@@ -0,0 +1,197 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/pkg/breaker"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/requestid"
+	pb "github.com/order-api-microservices/proto/payment"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// paymentTimeouts are the timeouts of calls to the payment service, unless configured otherwise
+var paymentTimeouts = deadline.Timeouts{Default: 30 * time.Second}
+
+// PaymentGRPCClient is a client for the payment service
+type PaymentGRPCClient struct {
+	client pb.PaymentServiceClient
+	conn   *grpc.ClientConn
+}
+
+// NewPaymentGRPCClient creates a new payment service client. Its calls take the
+// timeouts of paymentTimeouts merged with timeouts, and are dialled with any extra opts.
+func NewPaymentGRPCClient(address string, timeouts deadline.Timeouts, opts ...grpc.DialOption) (*PaymentGRPCClient, error) {
+	conn, err := grpc.Dial(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		deadline.DialOption(paymentTimeouts.Merge(timeouts)),
+		requestid.DialOption(),
+		breaker.DialOption("payment", breaker.DefaultConfig()),
+	}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to payment service: %v", err)
+	}
+
+	client := pb.NewPaymentServiceClient(conn)
+	return &PaymentGRPCClient{
+		client: client,
+		conn:   conn,
+	}, nil
+}
+
+// Close closes the connection to the payment service
+func (c *PaymentGRPCClient) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// RefundPayment returns amount of an order's payment to the payer and gives back the refund ID
+func (c *PaymentGRPCClient) RefundPayment(ctx context.Context, orderID, userID string, amount int64, reason, idempotencyKey string) (string, error) {
+	// Create the request
+	req := &pb.RefundPaymentRequest{
+		OrderId:        orderID,
+		UserId:         userID,
+		Amount:         amount,
+		Reason:         reason,
+		IdempotencyKey: idempotencyKey,
+	}
+
+	// Call the service
+	resp, err := c.client.RefundPayment(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to refund payment: %v", err)
+	}
+
+	if !resp.Success {
+		return "", fmt.Errorf("payment service failed to refund payment: %s", resp.Message)
+	}
+
+	return resp.RefundId, nil
+}
+
+// CapturePayment charges the payer an additional amount for an order and gives back the payment ID
+func (c *PaymentGRPCClient) CapturePayment(ctx context.Context, orderID, userID string, amount int64, description, idempotencyKey string) (string, error) {
+	// Create the request
+	req := &pb.CapturePaymentRequest{
+		OrderId:        orderID,
+		UserId:         userID,
+		Amount:         amount,
+		Description:    description,
+		IdempotencyKey: idempotencyKey,
+	}
+
+	// Call the service
+	resp, err := c.client.CapturePayment(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to capture payment: %v", err)
+	}
+
+	if !resp.Success {
+		return "", fmt.Errorf("payment service failed to capture payment: %s", resp.Message)
+	}
+
+	return resp.PaymentId, nil
+}
+
+// AuthorizePayment holds an order's amount on the payer's card or wallet and gives back
+// the authorization ID and when the hold lapses
+func (c *PaymentGRPCClient) AuthorizePayment(ctx context.Context, orderID, userID, paymentMethod, paymentMethodID string, amount int64, idempotencyKey string) (string, time.Time, error) {
+	// Create the request
+	req := &pb.AuthorizePaymentRequest{
+		OrderId:         orderID,
+		UserId:          userID,
+		Amount:          amount,
+		PaymentMethod:   paymentMethod,
+		PaymentMethodId: paymentMethodID,
+		IdempotencyKey:  idempotencyKey,
+	}
+
+	// Call the service
+	resp, err := c.client.AuthorizePayment(ctx, req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to authorize payment: %v", err)
+	}
+
+	if !resp.Success {
+		return "", time.Time{}, fmt.Errorf("payment service declined the authorization: %s", resp.Message)
+	}
+
+	return resp.AuthorizationId, resp.ExpiresAt.AsTime(), nil
+}
+
+// CaptureAuthorization charges up to the authorized amount of an order's payment and
+// gives back the payment ID. The rest of the hold is released.
+func (c *PaymentGRPCClient) CaptureAuthorization(ctx context.Context, orderID, authorizationID string, amount int64, idempotencyKey string) (string, error) {
+	// Create the request
+	req := &pb.CaptureAuthorizationRequest{
+		OrderId:         orderID,
+		AuthorizationId: authorizationID,
+		Amount:          amount,
+		IdempotencyKey:  idempotencyKey,
+	}
+
+	// Call the service
+	resp, err := c.client.CaptureAuthorization(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to capture authorization: %v", err)
+	}
+
+	if !resp.Success {
+		return "", fmt.Errorf("payment service failed to capture authorization: %s", resp.Message)
+	}
+
+	return resp.PaymentId, nil
+}
+
+// VoidAuthorization releases the hold on an order's payment without charging any of it
+func (c *PaymentGRPCClient) VoidAuthorization(ctx context.Context, orderID, authorizationID, reason, idempotencyKey string) error {
+	// Create the request
+	req := &pb.VoidAuthorizationRequest{
+		OrderId:         orderID,
+		AuthorizationId: authorizationID,
+		Reason:          reason,
+		IdempotencyKey:  idempotencyKey,
+	}
+
+	// Call the service
+	resp, err := c.client.VoidAuthorization(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to void authorization: %v", err)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("payment service failed to void authorization: %s", resp.Message)
+	}
+
+	return nil
+}
+
+// ChargePaymentMethod charges one of a user's saved payment methods for something other
+// than an order, such as a wallet top-up, and gives back the payment ID
+func (c *PaymentGRPCClient) ChargePaymentMethod(ctx context.Context, userID, paymentMethodID string, amount int64, description, idempotencyKey string) (string, error) {
+	// Create the request
+	req := &pb.ChargePaymentMethodRequest{
+		UserId:          userID,
+		PaymentMethodId: paymentMethodID,
+		Amount:          amount,
+		Description:     description,
+		IdempotencyKey:  idempotencyKey,
+	}
+
+	// Call the service
+	resp, err := c.client.ChargePaymentMethod(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to charge payment method: %v", err)
+	}
+
+	if !resp.Success {
+		return "", fmt.Errorf("payment service failed to charge payment method: %s", resp.Message)
+	}
+
+	return resp.PaymentId, nil
+}
@@ -0,0 +1,90 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/pkg/breaker"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/requestid"
+	pb "github.com/order-api-microservices/proto/predictor"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// predictorTimeouts are the timeouts of calls to the prediction service, unless configured
+// otherwise. Predictions sit on the dispatch path, so calls fail fast and let the caller
+// fall back.
+var predictorTimeouts = deadline.Timeouts{Default: 2 * time.Second}
+
+// PredictorGRPCClient adapts an external prediction service to the order service's
+// Predictor interface
+type PredictorGRPCClient struct {
+	client pb.PredictorServiceClient
+	conn   *grpc.ClientConn
+}
+
+// NewPredictorGRPCClient creates a new prediction service client. Its calls take the
+// timeouts of predictorTimeouts merged with timeouts, and are dialled with any extra opts.
+func NewPredictorGRPCClient(address string, timeouts deadline.Timeouts, opts ...grpc.DialOption) (*PredictorGRPCClient, error) {
+	conn, err := grpc.Dial(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		deadline.DialOption(predictorTimeouts.Merge(timeouts)),
+		requestid.DialOption(),
+		breaker.DialOption("predictor", breaker.DefaultConfig()),
+	}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to prediction service: %v", err)
+	}
+
+	client := pb.NewPredictorServiceClient(conn)
+	return &PredictorGRPCClient{
+		client: client,
+		conn:   conn,
+	}, nil
+}
+
+// Close closes the connection to the prediction service
+func (c *PredictorGRPCClient) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// PredictDemand asks the model for the orders expected in a zone during an hour
+func (c *PredictorGRPCClient) PredictDemand(ctx context.Context, zone string, at time.Time) (float64, error) {
+	resp, err := c.client.PredictDemand(ctx, &pb.PredictDemandRequest{
+		Zone: zone,
+		At:   timestamppb.New(at),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to predict demand: %v", err)
+	}
+
+	return resp.ExpectedOrders, nil
+}
+
+// PredictETA asks the model for the time to travel a route
+func (c *PredictorGRPCClient) PredictETA(ctx context.Context, route []model.Location) (time.Duration, error) {
+	points := make([]*pb.RoutePoint, 0, len(route))
+	for _, location := range route {
+		points = append(points, &pb.RoutePoint{
+			Latitude:  location.Latitude,
+			Longitude: location.Longitude,
+		})
+	}
+
+	resp, err := c.client.PredictETA(ctx, &pb.PredictETARequest{Route: points})
+	if err != nil {
+		return 0, fmt.Errorf("failed to predict ETA: %v", err)
+	}
+	if resp.EtaSeconds < 0 {
+		return 0, fmt.Errorf("prediction service returned a negative ETA")
+	}
+
+	return time.Duration(resp.EtaSeconds * float64(time.Second)), nil
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/order-api-microservices/pkg/breaker"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/requestid"
 	"github.com/order-api-microservices/services/order/internal/model"
 	"github.com/order-api-microservices/services/order/internal/service"
 	pb "github.com/order-api-microservices/proto/provider"
@@ -13,15 +16,29 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// providerTimeouts are the timeouts of calls to the provider service, unless configured otherwise
+var providerTimeouts = deadline.Timeouts{
+	Default: 10 * time.Second,
+	Methods: map[string]time.Duration{
+		"/provider.ProviderService/GetAvailabilityCounts": 5 * time.Second,
+	},
+}
+
 // ProviderGRPCClient is a client for the provider service
 type ProviderGRPCClient struct {
 	client pb.ProviderServiceClient
 	conn   *grpc.ClientConn
 }
 
-// NewProviderGRPCClient creates a new provider service client
-func NewProviderGRPCClient(address string) (*ProviderGRPCClient, error) {
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewProviderGRPCClient creates a new provider service client. Its calls take the
+// timeouts of providerTimeouts merged with timeouts, and are dialled with any extra opts.
+func NewProviderGRPCClient(address string, timeouts deadline.Timeouts, opts ...grpc.DialOption) (*ProviderGRPCClient, error) {
+	conn, err := grpc.Dial(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		deadline.DialOption(providerTimeouts.Merge(timeouts)),
+		requestid.DialOption(),
+		breaker.DialOption("provider", breaker.DefaultConfig()),
+	}, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to provider service: %v", err)
 	}
@@ -41,8 +58,9 @@ func (c *ProviderGRPCClient) Close() error {
 	return nil
 }
 
-// FindAvailableProviders finds available providers near a location
-func (c *ProviderGRPCClient) FindAvailableProviders(ctx context.Context, location model.Location, radius float64, serviceType string) ([]service.Provider, error) {
+// FindAvailableProviders finds available providers near a location, limited to those
+// registered in a service area when serviceAreaID is set
+func (c *ProviderGRPCClient) FindAvailableProviders(ctx context.Context, location model.Location, radius float64, serviceType, serviceAreaID string) ([]service.Provider, error) {
 	// Create the request
 	req := &pb.FindProvidersRequest{
 		Location: &pb.Location{
@@ -50,14 +68,11 @@ func (c *ProviderGRPCClient) FindAvailableProviders(ctx context.Context, locatio
 			Longitude: location.Longitude,
 			Address:   location.Address,
 		},
-		Radius:      float32(radius),
-		ServiceType: serviceType,
+		Radius:        float32(radius),
+		ServiceType:   serviceType,
+		ServiceAreaId: serviceAreaID,
 	}
 
-	// Set context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
 	// Call the service
 	resp, err := c.client.FindProviders(ctx, req)
 	if err != nil {
@@ -86,8 +101,30 @@ func (c *ProviderGRPCClient) FindAvailableProviders(ctx context.Context, locatio
 				Longitude: p.Location.Longitude,
 				Address:   p.Location.Address,
 			},
-			IsAvailable: p.IsAvailable,
-			Distance:    float64(p.Distance),
+			IsAvailable:         p.IsAvailable,
+			Distance:            float64(p.Distance),
+			MaxConcurrentOrders: int(p.MaxConcurrentOrders),
+			CompletionRate:      1,
+			OnTimeRate:          1,
+		}
+		if p.Quality != nil {
+			provider.CompletionRate = p.Quality.CompletionRate
+			provider.OnTimeRate = p.Quality.OnTimeRate
+		}
+		if p.VehicleCapacity != nil {
+			provider.VehicleCapacity = model.VehicleCapacity{
+				MaxWeightGrams: int(p.VehicleCapacity.MaxWeightGrams),
+				LengthCm:       int(p.VehicleCapacity.LengthCm),
+				WidthCm:        int(p.VehicleCapacity.WidthCm),
+				HeightCm:       int(p.VehicleCapacity.HeightCm),
+			}
+		}
+		if p.Preferences != nil {
+			provider.Preferences = service.ProviderPreferences{
+				AutoAcceptRadiusKm: p.Preferences.AutoAcceptRadiusKm,
+				OrderTypes:         p.Preferences.OrderTypes,
+				MinFare:            p.Preferences.MinFare,
+			}
 		}
 		providers = append(providers, provider)
 	}
@@ -111,10 +148,6 @@ func (c *ProviderGRPCClient) NotifyProvider(ctx context.Context, providerID stri
 		NotificationType: "NEW_ORDER",
 	}
 
-	// Set context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
 	// Call the service
 	resp, err := c.client.NotifyProvider(ctx, req)
 	if err != nil {
@@ -140,10 +173,6 @@ func (c *ProviderGRPCClient) UpdateProviderLocation(ctx context.Context, provide
 		},
 	}
 
-	// Set context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
 	// Call the service
 	resp, err := c.client.UpdateLocation(ctx, req)
 	if err != nil {
@@ -164,10 +193,6 @@ func (c *ProviderGRPCClient) GetProviderDetails(ctx context.Context, providerID
 		ProviderId: providerID,
 	}
 
-	// Set context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
 	// Call the service
 	resp, err := c.client.GetProvider(ctx, req)
 	if err != nil {
@@ -190,8 +215,67 @@ func (c *ProviderGRPCClient) GetProviderDetails(ctx context.Context, providerID
 			Longitude: resp.Provider.Location.Longitude,
 			Address:   resp.Provider.Location.Address,
 		},
-		IsAvailable: resp.Provider.IsAvailable,
+		IsAvailable:         resp.Provider.IsAvailable,
+		Suspended:           resp.Provider.Suspension != nil,
+		Phone:               resp.Provider.Phone,
+		MaxConcurrentOrders: int(resp.Provider.MaxConcurrentOrders),
+	}
+	if v := resp.Provider.ActiveVehicle; v != nil {
+		provider.Vehicle = &model.OrderVehicle{
+			ProviderID:  resp.Provider.Id,
+			VehicleID:   v.Id,
+			Plate:       v.Plate,
+			Type:        v.Type,
+			Description: v.Description,
+		}
 	}
 
 	return provider, nil
-} 
\ No newline at end of file
+}
+
+// UpdateProviderQuality stores a provider's quality on their provider record
+func (c *ProviderGRPCClient) UpdateProviderQuality(ctx context.Context, quality *model.ProviderQuality) error {
+	_, err := c.client.UpdateQuality(ctx, &pb.UpdateQualityRequest{
+		ProviderId: quality.ProviderID,
+		Quality: &pb.ProviderQuality{
+			AcceptanceRate: quality.AcceptanceRate(),
+			CompletionRate: quality.CompletionRate(),
+			OnTimeRate:     quality.OnTimeRate(),
+			OffersAnswered: int32(quality.Offers.Answered()),
+			OrdersFinished: int32(quality.OrdersFinished()),
+			PickupsTimed:   int32(quality.PickupsTimed),
+			Incidents:      int32(quality.Incidents),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update provider quality: %v", err)
+	}
+
+	return nil
+}
+
+// ForgetProvider erases a provider's personal data from the provider service and
+// reports how many location history entries were deleted
+func (c *ProviderGRPCClient) ForgetProvider(ctx context.Context, providerID string) (int64, error) {
+	resp, err := c.client.ForgetProvider(ctx, &pb.ForgetProviderRequest{ProviderId: providerID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to forget provider: %v", err)
+	}
+
+	return resp.LocationsDeleted, nil
+}
+
+// GetAvailabilityCounts counts the providers currently available for orders, in total
+// and by service area ID
+func (c *ProviderGRPCClient) GetAvailabilityCounts(ctx context.Context) (*service.ProviderAvailability, error) {
+	resp, err := c.client.GetAvailabilityCounts(ctx, &pb.GetAvailabilityCountsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider availability counts: %v", err)
+	}
+
+	return &service.ProviderAvailability{
+		Available:              resp.Available,
+		AvailableByServiceArea: resp.AvailableByServiceArea,
+		AvailableWithoutArea:   resp.AvailableWithoutServiceArea,
+	}, nil
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// OrderEventType is a kind of domain event recorded about an order
+type OrderEventType string
+
+// Order event types
+const (
+	OrderEventCreated       OrderEventType = "CREATED"
+	OrderEventStatusChanged OrderEventType = "STATUS_CHANGED"
+	OrderEventSLAMet        OrderEventType = "SLA_MET"
+	OrderEventSLABreached   OrderEventType = "SLA_BREACHED"
+)
+
+// MaxCancellationReasonLength caps how much of a cancellation reason is aggregated
+const MaxCancellationReasonLength = 100
+
+// OrderEvent is a change to an order, recorded in the same transaction as the change.
+// The analytics aggregator consumes events to build the daily aggregates.
+type OrderEvent struct {
+	Seq            int64          `json:"seq"`
+	OrderID        string         `json:"order_id"`
+	EventType      OrderEventType `json:"event_type"`
+	OrderType      OrderType      `json:"order_type"`
+	City           string         `json:"city"`                  // City of the pickup; empty when unknown
+	ProviderID     string         `json:"provider_id,omitempty"` // Provider of the order when the event happened
+	UpdatedBy      string         `json:"updated_by,omitempty"`  // Who made the change
+	Status         OrderStatus    `json:"status"`
+	PreviousStatus OrderStatus    `json:"previous_status,omitempty"`
+	TotalPrice     int64          `json:"total_price"`
+	PlatformFee    int64          `json:"platform_fee"`
+	Reason         string         `json:"reason,omitempty"`    // Why the order was cancelled
+	SLAStage       SLAStage       `json:"sla_stage,omitempty"` // The stage an SLA event is about
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// TableName returns the table name for the OrderEvent model
+func (OrderEvent) TableName() string {
+	return "order_events"
+}
+
+// ReachedPickup reports whether the event is an assigned provider starting the order,
+// the moment the pickup ETA predicted at dispatch is checked against
+func (e *OrderEvent) ReachedPickup() bool {
+	if e.Status != StatusPickedUp && e.Status != StatusInProgress {
+		return false
+	}
+	return e.PreviousStatus == StatusProviderAssigned || e.PreviousStatus == StatusProviderAccepted
+}
+
+// DailyMetrics are the aggregates of one day's orders of one type in one city. When
+// metrics are grouped, the fields grouped over are empty.
+type DailyMetrics struct {
+	Day                string    `json:"day,omitempty"` // YYYY-MM-DD, in UTC
+	City               string    `json:"city,omitempty"`
+	OrderType          OrderType `json:"order_type,omitempty"`
+	OrdersCreated      int64     `json:"orders_created"`
+	OrdersCompleted    int64     `json:"orders_completed"`
+	OrdersCancelled    int64     `json:"orders_cancelled"`
+	Revenue            int64     `json:"revenue"`          // Total price of completed orders
+	PlatformRevenue    int64     `json:"platform_revenue"` // Platform fees of completed orders
+	ETAErrorMinutesSum float64   `json:"-"`
+	ETASamples         int64     `json:"eta_samples"`
+}
+
+// CompletionRate is the share of finished orders that were completed rather than
+// cancelled, or 0 when none finished
+func (m *DailyMetrics) CompletionRate() float64 {
+	finished := m.OrdersCompleted + m.OrdersCancelled
+	if finished == 0 {
+		return 0
+	}
+	return float64(m.OrdersCompleted) / float64(finished)
+}
+
+// AverageETAErrorMinutes is how far off the predicted pickup ETA was on average, in
+// either direction, or 0 without samples
+func (m *DailyMetrics) AverageETAErrorMinutes() float64 {
+	if m.ETASamples == 0 {
+		return 0
+	}
+	return m.ETAErrorMinutesSum / float64(m.ETASamples)
+}
+
+// CancellationReasonCount is how often a cancellation reason was given
+type CancellationReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// AnalyticsGroupBy names what daily metrics are summed over
+type AnalyticsGroupBy string
+
+// Analytics groupings
+const (
+	AnalyticsGroupByNone      AnalyticsGroupBy = ""           // One row per day, city and order type
+	AnalyticsGroupByDay       AnalyticsGroupBy = "day"        // One row per day
+	AnalyticsGroupByCity      AnalyticsGroupBy = "city"       // One row per city
+	AnalyticsGroupByOrderType AnalyticsGroupBy = "order_type" // One row per order type
+)
+
+// AnalyticsFilter narrows an analytics query. Days are inclusive; empty fields match everything.
+type AnalyticsFilter struct {
+	From      time.Time
+	To        time.Time
+	City      string
+	OrderType OrderType
+}
+
+// NormalizeCancellationReason turns a free-text cancellation reason into the key it is
+// counted under, so the same reason typed differently is counted once
+func NormalizeCancellationReason(reason string) string {
+	reason = strings.ToLower(strings.Join(strings.Fields(reason), " "))
+	if reason == "" {
+		return "unspecified"
+	}
+	if len(reason) > MaxCancellationReasonLength {
+		reason = strings.ToValidUTF8(reason[:MaxCancellationReasonLength], "")
+	}
+	return reason
+}
@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+// BulkOrderJobStatus is where a bulk order import is in its processing
+type BulkOrderJobStatus string
+
+// Bulk order job statuses
+const (
+	BulkJobPending    BulkOrderJobStatus = "PENDING"    // Waiting for an importer to pick it up
+	BulkJobProcessing BulkOrderJobStatus = "PROCESSING" // Its orders are being created
+	BulkJobCompleted  BulkOrderJobStatus = "COMPLETED"  // Every row has a result
+)
+
+// BulkOrderRowStatus is the result of importing one row of a bulk order job
+type BulkOrderRowStatus string
+
+// Bulk order row statuses
+const (
+	BulkRowPending    BulkOrderRowStatus = "PENDING"    // Not yet processed
+	BulkRowProcessing BulkOrderRowStatus = "PROCESSING" // Its order is being created
+	BulkRowCreated    BulkOrderRowStatus = "CREATED"    // Its order was created
+	BulkRowFailed     BulkOrderRowStatus = "FAILED"     // The order service refused the order
+	BulkRowInvalid    BulkOrderRowStatus = "INVALID"    // The row failed validation and was never submitted
+)
+
+// BulkOrderJob is a batch of orders a B2B customer uploaded at once. Its orders are
+// created in the background, one row at a time.
+type BulkOrderJob struct {
+	ID          string             `json:"id"`
+	CustomerID  string             `json:"customer_id"` // B2B customer that uploaded the batch
+	Source      string             `json:"source"`      // JSON or CSV
+	Status      BulkOrderJobStatus `json:"status"`
+	TotalRows   int                `json:"total_rows"`
+	CreatedAt   time.Time          `json:"created_at"`
+	StartedAt   *time.Time         `json:"started_at,omitempty"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty"`
+
+	// Row counts by result, filled in when the job is read
+	PendingRows int `json:"pending_rows"`
+	CreatedRows int `json:"created_rows"`
+	FailedRows  int `json:"failed_rows"`
+	InvalidRows int `json:"invalid_rows"`
+}
+
+// TableName returns the table name for the BulkOrderJob model
+func (BulkOrderJob) TableName() string {
+	return "bulk_order_jobs"
+}
+
+// BulkOrderRow is one order of a bulk order job and the result of importing it
+type BulkOrderRow struct {
+	JobID       string             `json:"job_id"`
+	RowNumber   int                `json:"row_number"` // Position in the upload, starting at 1
+	Request     string             `json:"-"`          // The order request as JSON; cleared once processed
+	Status      BulkOrderRowStatus `json:"status"`
+	OrderID     string             `json:"order_id,omitempty"`
+	Errors      []string           `json:"errors,omitempty"`
+	ProcessedAt *time.Time         `json:"processed_at,omitempty"`
+}
+
+// TableName returns the table name for the BulkOrderRow model
+func (BulkOrderRow) TableName() string {
+	return "bulk_order_rows"
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// ChatMessage is a message between an order's user and provider
+type ChatMessage struct {
+	ID         string     `json:"id"`
+	OrderID    string     `json:"order_id"`
+	SenderID   string     `json:"sender_id"`
+	SenderRole PartyRole  `json:"sender_role"`
+	Body       string     `json:"body"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"` // Changes when the message is read, so streams pick up receipts
+}
+
+// TableName returns the table name for the ChatMessage model
+func (ChatMessage) TableName() string {
+	return "chat_messages"
+}
+
+// ChatOpen reports whether the user and provider can message each other. Chat opens once
+// a provider is assigned and closes for good when the order is finished.
+func (o *Order) ChatOpen() bool {
+	return o.ProviderID != "" && !o.Status.Finished()
+}
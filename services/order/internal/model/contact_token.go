@@ -0,0 +1,35 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// ContactToken lets one party to an order reach the other through the call bridge
+// without either seeing the other's phone number. Only a hash of the token is kept.
+type ContactToken struct {
+	ID            string     `json:"id"`
+	OrderID       string     `json:"order_id"`
+	ParticipantID string     `json:"participant_id"` // The party the token was issued to
+	TokenHash     string     `json:"-"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"` // Set when the order finishes
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// TableName returns the table name for the ContactToken model
+func (ContactToken) TableName() string {
+	return "contact_tokens"
+}
+
+// HashContactToken returns the SHA-256 of a contact token, hex encoded
+func HashContactToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Valid reports whether the token can still be used at the given time
+func (t *ContactToken) Valid(at time.Time) bool {
+	return t.RevokedAt == nil && at.Before(t.ExpiresAt)
+}
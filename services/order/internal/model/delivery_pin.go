@@ -0,0 +1,62 @@
+package model
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+)
+
+// DeliveryPIN is the code a user gives their provider to confirm a delivery. Only a hash
+// of the PIN is kept; resending it issues a new one.
+type DeliveryPIN struct {
+	OrderID        string     `json:"order_id"`
+	PINHash        string     `json:"-"`
+	FailedAttempts int        `json:"failed_attempts"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty"`
+	IssuedAt       time.Time  `json:"issued_at"` // When the current PIN was sent to the user
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for the DeliveryPIN model
+func (DeliveryPIN) TableName() string {
+	return "delivery_pins"
+}
+
+// HashDeliveryPIN returns the SHA-256 of an order's PIN, hex encoded. The order ID is
+// mixed in so equal PINs on different orders hash differently.
+func HashDeliveryPIN(orderID, pin string) string {
+	hash := sha256.Sum256([]byte(orderID + "|" + pin))
+	return hex.EncodeToString(hash[:])
+}
+
+// Locked reports whether PIN attempts are locked out at the given time
+func (p *DeliveryPIN) Locked(at time.Time) bool {
+	return p.LockedUntil != nil && at.Before(*p.LockedUntil)
+}
+
+// Attempt checks a submitted PIN hash and records the outcome. A match clears the failed
+// attempts and any lockout; a wrong PIN counts as a failed attempt, and reaching
+// maxAttempts locks the PIN until lockout has passed and starts the count again. It
+// returns whether the PIN matched and the attempts left before a lockout. Callers check
+// Locked first; a locked PIN must not be attempted.
+func (p *DeliveryPIN) Attempt(pinHash string, maxAttempts int, lockout time.Duration, at time.Time) (bool, int) {
+	p.UpdatedAt = at
+
+	if subtle.ConstantTimeCompare([]byte(p.PINHash), []byte(pinHash)) == 1 {
+		p.FailedAttempts = 0
+		p.LockedUntil = nil
+		return true, maxAttempts
+	}
+
+	p.FailedAttempts++
+	if p.FailedAttempts >= maxAttempts {
+		until := at.Add(lockout)
+		p.LockedUntil = &until
+		p.FailedAttempts = 0
+		return false, 0
+	}
+	p.LockedUntil = nil
+	return false, maxAttempts - p.FailedAttempts
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeliveryPINAttempt(t *testing.T) {
+	const maxAttempts = 3
+	lockout := 15 * time.Minute
+	issued := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	right := HashDeliveryPIN("order-1", "1234")
+	wrong := HashDeliveryPIN("order-1", "0000")
+
+	pin := &DeliveryPIN{OrderID: "order-1", PINHash: right, IssuedAt: issued}
+	at := issued.Add(time.Minute)
+
+	for want := maxAttempts - 1; want > 0; want-- {
+		matched, remaining := pin.Attempt(wrong, maxAttempts, lockout, at)
+		if matched || remaining != want {
+			t.Fatalf("wrong PIN: got matched=%v remaining=%d, want false %d", matched, remaining, want)
+		}
+		if pin.Locked(at) {
+			t.Fatalf("locked after %d failed attempts", pin.FailedAttempts)
+		}
+	}
+
+	matched, remaining := pin.Attempt(wrong, maxAttempts, lockout, at)
+	if matched || remaining != 0 {
+		t.Fatalf("last wrong PIN: got matched=%v remaining=%d, want false 0", matched, remaining)
+	}
+	if !pin.Locked(at) || !pin.Locked(at.Add(lockout-time.Second)) {
+		t.Fatal("PIN not locked after reaching max attempts")
+	}
+	if pin.FailedAttempts != 0 {
+		t.Errorf("failed attempts = %d after lockout, want 0", pin.FailedAttempts)
+	}
+
+	unlocked := at.Add(lockout)
+	if pin.Locked(unlocked) {
+		t.Fatal("PIN still locked after the lockout passed")
+	}
+	if matched, remaining := pin.Attempt(wrong, maxAttempts, lockout, unlocked); matched || remaining != maxAttempts-1 {
+		t.Fatalf("wrong PIN after lockout: got matched=%v remaining=%d, want a fresh count", matched, remaining)
+	}
+
+	matched, remaining = pin.Attempt(right, maxAttempts, lockout, unlocked)
+	if !matched || remaining != maxAttempts {
+		t.Fatalf("right PIN: got matched=%v remaining=%d, want true %d", matched, remaining, maxAttempts)
+	}
+	if pin.FailedAttempts != 0 || pin.LockedUntil != nil {
+		t.Errorf("match left failed_attempts=%d locked_until=%v", pin.FailedAttempts, pin.LockedUntil)
+	}
+	if !pin.UpdatedAt.Equal(unlocked) {
+		t.Errorf("updated_at = %v, want %v", pin.UpdatedAt, unlocked)
+	}
+}
+
+func TestHashDeliveryPINBindsOrder(t *testing.T) {
+	if HashDeliveryPIN("order-1", "1234") == HashDeliveryPIN("order-2", "1234") {
+		t.Error("same PIN hashes equal across orders")
+	}
+	if HashDeliveryPIN("order-1", "1234") != HashDeliveryPIN("order-1", "1234") {
+		t.Error("hash is not deterministic")
+	}
+}
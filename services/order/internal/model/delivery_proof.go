@@ -0,0 +1,40 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// DeliveryProof is the evidence a provider submits when handing over an order
+type DeliveryProof struct {
+	ID            string    `json:"id"`
+	OrderID       string    `json:"order_id"`
+	ProviderID    string    `json:"provider_id"`
+	PhotoRef      string    `json:"photo_ref,omitempty"`      // Reference to the uploaded photo
+	SignatureHash string    `json:"signature_hash,omitempty"` // SHA-256 of the recipient's signature, hex encoded
+	RecipientOTP  string    `json:"-"`                        // Committed to by ProofHash, never stored
+	ProofHash     string    `json:"proof_hash"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for the DeliveryProof model
+func (DeliveryProof) TableName() string {
+	return "delivery_proofs"
+}
+
+// ComputeHash returns the SHA-256 of the proof's fields, hex encoded. It is what the
+// order's blockchain record commits to.
+func (p *DeliveryProof) ComputeHash() string {
+	data := strings.Join([]string{
+		p.OrderID,
+		p.ProviderID,
+		p.PhotoRef,
+		p.SignatureHash,
+		p.RecipientOTP,
+		p.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}, "|")
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// DenylistKind is what a denylist entry matches requests by
+type DenylistKind string
+
+// Denylist kind constants
+const (
+	DenylistUser   DenylistKind = "USER"    // The user a request is made as
+	DenylistAPIKey DenylistKind = "API_KEY" // The API key a request presents
+	DenylistCIDR   DenylistKind = "CIDR"    // The network a request comes from
+)
+
+// DenylistEntry bans a user, API key or network from the API. The gateway refuses their
+// requests with 403 until the entry is removed or expires.
+type DenylistEntry struct {
+	ID                 string       `json:"id"`
+	Kind               DenylistKind `json:"kind"`
+	Value              string       `json:"value"` // User ID, hex SHA-256 of an API key, or CIDR network
+	Reason             string       `json:"reason"`
+	CreatedBy          string       `json:"created_by"`
+	CreatedAt          time.Time    `json:"created_at"`
+	ExpiresAt          *time.Time   `json:"expires_at,omitempty"`
+	BlockedCount       int64        `json:"blocked_count"`
+	LastBlockedAt      *time.Time   `json:"last_blocked_at,omitempty"`
+	LastBlockedRequest string       `json:"last_blocked_request"`
+}
+
+// TableName returns the table name for the DenylistEntry model
+func (DenylistEntry) TableName() string {
+	return "denylist_entries"
+}
+
+// Expired reports whether the entry no longer applies at now
+func (e *DenylistEntry) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// DestinationChangeStatus is where a request to change a ride's destination stands
+type DestinationChangeStatus string
+
+const (
+	DestinationChangePending  DestinationChangeStatus = "PENDING"
+	DestinationChangeAccepted DestinationChangeStatus = "ACCEPTED"
+	DestinationChangeDeclined DestinationChangeStatus = "DECLINED"
+	DestinationChangeExpired  DestinationChangeStatus = "EXPIRED"
+)
+
+// DestinationChange is a user's request to be taken somewhere else during a ride. The
+// provider accepts or declines it; accepting moves the order's destination and sets its
+// total to the fare for the new route.
+type DestinationChange struct {
+	ID          string                  `json:"id"`
+	OrderID     string                  `json:"order_id"`
+	Destination Location                `json:"destination"`
+	Fare        int64                   `json:"fare"` // The order's total for the new route, in minor units
+	Status      DestinationChangeStatus `json:"status"`
+	PaymentID   string                  `json:"payment_id,omitempty"` // The capture of a higher fare's difference
+	RequestedAt time.Time               `json:"requested_at"`
+	RespondedAt *time.Time              `json:"responded_at,omitempty"`
+}
+
+// TableName returns the table name for the DestinationChange model
+func (DestinationChange) TableName() string {
+	return "destination_changes"
+}
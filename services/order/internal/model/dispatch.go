@@ -0,0 +1,199 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DispatchWeights weigh the signals the matcher scores providers on. Each signal is
+// scored from 0 to 1; admins change the weights at runtime.
+type DispatchWeights struct {
+	Distance   float64   `json:"distance"`
+	Rating     float64   `json:"rating"`
+	Idle       float64   `json:"idle"`       // Boosts providers who have gone longest without an order
+	Earnings   float64   `json:"earnings"`   // Boosts providers who have earned least recently
+	Favorite   float64   `json:"favorite"`   // Boosts providers the user has favorited
+	Acceptance float64   `json:"acceptance"` // Boosts providers who accept most of the offers they answer
+	Completion float64   `json:"completion"` // Boosts providers who complete most of the orders they take
+	OnTime     float64   `json:"on_time"`    // Boosts providers who reach most pickups on time
+	UpdatedBy  string    `json:"updated_by,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// DefaultDispatchWeights apply until an admin sets weights
+var DefaultDispatchWeights = DispatchWeights{
+	Distance: 0.6,
+	Rating:   0.2,
+	Idle:     0.1,
+	Earnings: 0.1,
+	Favorite: 0.2,
+}
+
+// Value implements the driver.Valuer interface for JSON serialization
+func (w DispatchWeights) Value() (driver.Value, error) {
+	return json.Marshal(w)
+}
+
+// Scan implements the sql.Scanner interface for JSON deserialization
+func (w *DispatchWeights) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, w)
+}
+
+// ProviderActivity is what the matcher knows of a provider's recent work
+type ProviderActivity struct {
+	LastOrderAt    *time.Time // Nil for providers who never had an order
+	RecentEarnings int64      // Fares and tips within the earnings window, in minor units
+}
+
+// DispatchCandidate is one provider considered for an order, with the signals they were scored on
+type DispatchCandidate struct {
+	ProviderID       string  `json:"provider_id"`
+	DistanceKm       float64 `json:"distance_km"`
+	PickupETAMinutes float64 `json:"pickup_eta_minutes,omitempty"` // Predicted time to reach the pickup; 0 when unavailable
+	Rating           float64 `json:"rating"`
+	IdleMinutes      float64 `json:"idle_minutes"`
+	RecentEarnings   int64   `json:"recent_earnings"`
+	Favorite         bool    `json:"favorite,omitempty"`          // The user has favorited the provider
+	AcceptanceRate   float64 `json:"acceptance_rate"`             // Share of recently answered offers accepted
+	DeclinePenalized bool    `json:"decline_penalized,omitempty"` // The score was cut for declining too many offers
+	CompletionRate   float64 `json:"completion_rate"`             // Share of finished orders taken that were completed
+	OnTimeRate       float64 `json:"on_time_rate"`                // Share of timed pickups reached on time
+	Score            float64 `json:"score"`
+}
+
+// DispatchCandidates is a slice of DispatchCandidate, best first
+type DispatchCandidates []DispatchCandidate
+
+// Value implements the driver.Valuer interface for JSON serialization
+func (c DispatchCandidates) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface for JSON deserialization
+func (c *DispatchCandidates) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, c)
+}
+
+// DispatchDecision records which provider the matcher picked for an order and why, so
+// the fairness of dispatch can be audited
+type DispatchDecision struct {
+	ID                 string             `json:"id"`
+	OrderID            string             `json:"order_id"`
+	SelectedProviderID string             `json:"selected_provider_id"`
+	AutoAccepted       bool               `json:"auto_accepted"`
+	Weights            DispatchWeights    `json:"weights"`
+	Candidates         DispatchCandidates `json:"candidates"`
+	CreatedAt          time.Time          `json:"created_at"`
+}
+
+// TableName returns the table name for the DispatchDecision model
+func (DispatchDecision) TableName() string {
+	return "dispatch_decisions"
+}
+
+// OfferResponse is how a provider answered an order offered to them
+type OfferResponse string
+
+// Offer responses
+const (
+	OfferPending   OfferResponse = "PENDING"
+	OfferAccepted  OfferResponse = "ACCEPTED"
+	OfferDeclined  OfferResponse = "DECLINED"
+	OfferExpired   OfferResponse = "EXPIRED"   // Unanswered while the order waited on the provider
+	OfferWithdrawn OfferResponse = "WITHDRAWN" // Unanswered, but the order went to another provider or was cancelled
+)
+
+// DispatchOffer records an order offered to a provider and how they answered it, so
+// acceptance rates can be worked out and chronic decliners penalized
+type DispatchOffer struct {
+	ID             string        `json:"id"`
+	OrderID        string        `json:"order_id"`
+	ProviderID     string        `json:"provider_id"`
+	OfferedAt      time.Time     `json:"offered_at"`
+	ExpiresAt      time.Time     `json:"expires_at"`
+	AcknowledgedAt *time.Time    `json:"acknowledged_at,omitempty"` // When the app acknowledged it on a dispatch channel
+	Response       OfferResponse `json:"response"`
+	RespondedAt    *time.Time    `json:"responded_at,omitempty"`
+}
+
+// TableName returns the table name for the DispatchOffer model
+func (DispatchOffer) TableName() string {
+	return "dispatch_offers"
+}
+
+// ProviderOfferStats counts the offers a provider answered recently, by answer. Offers
+// still pending or withdrawn are left out.
+type ProviderOfferStats struct {
+	Accepted int
+	Declined int
+	Expired  int
+}
+
+// Answered is how many offers the provider accepted, declined or let expire
+func (s ProviderOfferStats) Answered() int {
+	return s.Accepted + s.Declined + s.Expired
+}
+
+// AcceptanceRate is the share of answered offers the provider accepted; 1 when they
+// answered none
+func (s ProviderOfferStats) AcceptanceRate() float64 {
+	if s.Answered() == 0 {
+		return 1
+	}
+	return float64(s.Accepted) / float64(s.Answered())
+}
+
+// OnTimePickupGrace is how long after the pickup time predicted at dispatch a provider
+// may reach the pickup and still be on time
+const OnTimePickupGrace = 5 * time.Minute
+
+// ProviderQuality is how reliably a provider served orders over the quality window,
+// worked out from their answers to offers and the order events of the orders they took
+type ProviderQuality struct {
+	ProviderID      string
+	Offers          ProviderOfferStats
+	OrdersCompleted int // Orders the provider took and completed
+	OrdersAbandoned int // Orders the provider took and then cancelled themselves
+	PickupsTimed    int // Pickups of auto-dispatched orders that had a predicted pickup time
+	PickupsOnTime   int // Timed pickups reached within the grace of the predicted time
+	Incidents       int // SOS incidents users raised on the provider's orders
+}
+
+// AcceptanceRate is the share of answered offers the provider accepted; 1 when they
+// answered none
+func (q ProviderQuality) AcceptanceRate() float64 {
+	return q.Offers.AcceptanceRate()
+}
+
+// OrdersFinished is how many orders the provider took that were completed or abandoned
+func (q ProviderQuality) OrdersFinished() int {
+	return q.OrdersCompleted + q.OrdersAbandoned
+}
+
+// CompletionRate is the share of finished orders the provider completed rather than
+// abandoned; 1 when none finished
+func (q ProviderQuality) CompletionRate() float64 {
+	if q.OrdersFinished() == 0 {
+		return 1
+	}
+	return float64(q.OrdersCompleted) / float64(q.OrdersFinished())
+}
+
+// OnTimeRate is the share of timed pickups the provider reached on time; 1 when none
+// were timed
+func (q ProviderQuality) OnTimeRate() float64 {
+	if q.PickupsTimed == 0 {
+		return 1
+	}
+	return float64(q.PickupsOnTime) / float64(q.PickupsTimed)
+}
@@ -0,0 +1,97 @@
+package model
+
+import "time"
+
+// DisputeStatus represents the status of a dispute
+type DisputeStatus string
+
+const (
+	DisputeOpen     DisputeStatus = "OPEN"
+	DisputeResolved DisputeStatus = "RESOLVED"
+)
+
+// DisputeResolution represents how a dispute was settled
+type DisputeResolution string
+
+const (
+	ResolutionRefundFull    DisputeResolution = "REFUND_FULL"
+	ResolutionRefundPartial DisputeResolution = "REFUND_PARTIAL"
+	ResolutionNoRefund      DisputeResolution = "NO_REFUND"
+)
+
+// DisputeRole identifies which party acted on a dispute
+type DisputeRole string
+
+const (
+	RoleUser     DisputeRole = "USER"
+	RoleProvider DisputeRole = "PROVIDER"
+	RoleAdmin    DisputeRole = "ADMIN"
+)
+
+// HoldStatus represents the state of a payment hold
+type HoldStatus string
+
+const (
+	HoldHeld              HoldStatus = "HELD"
+	HoldReleased          HoldStatus = "RELEASED"
+	HoldRefunded          HoldStatus = "REFUNDED"
+	HoldPartiallyRefunded HoldStatus = "PARTIALLY_REFUNDED"
+)
+
+// Dispute represents a dispute raised against an order
+type Dispute struct {
+	ID              string             `json:"id"`
+	OrderID         string             `json:"order_id"`
+	OpenedBy        string             `json:"opened_by"`
+	OpenedByRole    DisputeRole        `json:"opened_by_role"`
+	Reason          string             `json:"reason"`
+	Description     string             `json:"description,omitempty"`
+	Status          DisputeStatus      `json:"status"`
+	Resolution      DisputeResolution  `json:"resolution,omitempty"`
+	RefundAmount    int64              `json:"refund_amount"` // Minor units
+	ResolvedBy      string             `json:"resolved_by,omitempty"`
+	ResolutionNotes string             `json:"resolution_notes,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+	ResolvedAt      *time.Time         `json:"resolved_at,omitempty"`
+	Evidence        []*DisputeEvidence `json:"evidence,omitempty"`
+	PaymentHold     *PaymentHold       `json:"payment_hold,omitempty"`
+}
+
+// TableName returns the table name for the Dispute model
+func (Dispute) TableName() string {
+	return "disputes"
+}
+
+// DisputeEvidence is a statement or attachment submitted to a dispute
+type DisputeEvidence struct {
+	ID            string      `json:"id"`
+	DisputeID     string      `json:"dispute_id"`
+	SubmittedBy   string      `json:"submitted_by"`
+	Role          DisputeRole `json:"role"`
+	Description   string      `json:"description"`
+	AttachmentURL string      `json:"attachment_url,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// TableName returns the table name for the DisputeEvidence model
+func (DisputeEvidence) TableName() string {
+	return "dispute_evidence"
+}
+
+// PaymentHold freezes an order's payment while a dispute is open
+type PaymentHold struct {
+	ID             string     `json:"id"`
+	OrderID        string     `json:"order_id"`
+	DisputeID      string     `json:"dispute_id"`
+	Amount         int64      `json:"amount"` // Minor units
+	RefundedAmount int64      `json:"refunded_amount"`
+	Status         HoldStatus `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReleasedAt     *time.Time `json:"released_at,omitempty"`
+}
+
+// TableName returns the table name for the PaymentHold model
+func (PaymentHold) TableName() string {
+	return "payment_holds"
+}
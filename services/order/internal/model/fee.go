@@ -0,0 +1,73 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// FeeRule sets the fees charged on orders of a type in a city. An empty order type
+// or city matches any, so the rule with both empty is the platform-wide default.
+type FeeRule struct {
+	ID                 string    `json:"id"`
+	OrderType          OrderType `json:"order_type,omitempty"`
+	City               string    `json:"city,omitempty"` // Stored lower case
+	PlatformFeePercent float64   `json:"platform_fee_percent"`
+	ProviderFeePercent float64   `json:"provider_fee_percent"`
+	MinPlatformFee     int64     `json:"min_platform_fee"` // Minor units
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for the FeeRule model
+func (FeeRule) TableName() string {
+	return "fee_rules"
+}
+
+// DefaultFeeRule applies when no stored rule matches an order
+var DefaultFeeRule = FeeRule{
+	PlatformFeePercent: 10,
+	ProviderFeePercent: 80,
+}
+
+// Matches reports whether the rule applies to orders of a type in a city
+func (r *FeeRule) Matches(orderType OrderType, city string) bool {
+	return (r.OrderType == "" || r.OrderType == orderType) &&
+		(r.City == "" || strings.EqualFold(r.City, city))
+}
+
+// Specificity ranks matching rules; a rule for both the type and the city beats one for
+// the city alone, which beats one for the type alone, which beats the default
+func (r *FeeRule) Specificity() int {
+	specificity := 0
+	if r.City != "" {
+		specificity += 2
+	}
+	if r.OrderType != "" {
+		specificity++
+	}
+	return specificity
+}
+
+// FeeWaiver is a promotion that waives the platform fee on matching orders created
+// between StartsAt and EndsAt. Empty order type or city matches any.
+type FeeWaiver struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	OrderType OrderType `json:"order_type,omitempty"`
+	City      string    `json:"city,omitempty"` // Stored lower case
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for the FeeWaiver model
+func (FeeWaiver) TableName() string {
+	return "fee_waivers"
+}
+
+// Applies reports whether the waiver covers an order of a type in a city created at t
+func (w *FeeWaiver) Applies(orderType OrderType, city string, t time.Time) bool {
+	return (w.OrderType == "" || w.OrderType == orderType) &&
+		(w.City == "" || strings.EqualFold(w.City, city)) &&
+		!t.Before(w.StartsAt) && t.Before(w.EndsAt)
+}
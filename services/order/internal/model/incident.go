@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// IncidentStatus represents the status of a safety incident
+type IncidentStatus string
+
+const (
+	IncidentOpen     IncidentStatus = "OPEN"
+	IncidentResolved IncidentStatus = "RESOLVED"
+)
+
+// Incident is an SOS raised by a party to an order. The order is frozen while any
+// of its incidents is open.
+type Incident struct {
+	ID              string         `json:"id"`
+	OrderID         string         `json:"order_id"`
+	ReportedBy      string         `json:"reported_by"`
+	ReporterRole    PartyRole      `json:"reporter_role"`
+	Description     string         `json:"description,omitempty"`
+	Location        *Location      `json:"location,omitempty"` // Where the reporter was, if known
+	Status          IncidentStatus `json:"status"`
+	ResolvedBy      string         `json:"resolved_by,omitempty"`
+	ResolutionNotes string         `json:"resolution_notes,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	ResolvedAt      *time.Time     `json:"resolved_at,omitempty"`
+}
+
+// TableName returns the table name for the Incident model
+func (Incident) TableName() string {
+	return "incidents"
+}
@@ -0,0 +1,87 @@
+package model
+
+import "time"
+
+// ReferralStatus is where a referral is in earning its rewards
+type ReferralStatus string
+
+// Referral statuses
+const (
+	ReferralPending  ReferralStatus = "PENDING"  // The referred user has not completed an order yet
+	ReferralRewarded ReferralStatus = "REWARDED" // Both users earned their points
+	ReferralRejected ReferralStatus = "REJECTED" // Caught by the anti-abuse checks; no points
+)
+
+// PointsTransactionType says why a points balance changed
+type PointsTransactionType string
+
+// Points transaction types
+const (
+	PointsReferralReward     PointsTransactionType = "REFERRAL_REWARD"
+	PointsOrderRedemption    PointsTransactionType = "ORDER_REDEMPTION"
+	PointsRedemptionReturned PointsTransactionType = "REDEMPTION_RETURNED"
+)
+
+// ReferralCode is the code a user shares so that friends who sign up with it are
+// referred by them
+type ReferralCode struct {
+	UserID    string    `json:"user_id"`
+	Code      string    `json:"code"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for the ReferralCode model
+func (ReferralCode) TableName() string {
+	return "referral_codes"
+}
+
+// Referral is a user signing up with another user's referral code. A user is referred
+// at most once.
+type Referral struct {
+	ReferredUserID     string         `json:"referred_user_id"`
+	ReferrerID         string         `json:"referrer_id"`
+	Code               string         `json:"code"`
+	DeviceID           string         `json:"device_id"` // The device the referred user signed up on; each is used for one referral
+	Status             ReferralStatus `json:"status"`
+	RejectReason       string         `json:"reject_reason,omitempty"`
+	OrderID            string         `json:"order_id,omitempty"` // The completed order that settled the referral
+	PaymentFingerprint string         `json:"-"`                  // Of the card that paid for that order; each rewards one referral
+	CreatedAt          time.Time      `json:"created_at"`
+	SettledAt          *time.Time     `json:"settled_at,omitempty"`
+}
+
+// TableName returns the table name for the Referral model
+func (Referral) TableName() string {
+	return "referrals"
+}
+
+// PointsAccount is the loyalty points a user holds. Users without one have no points.
+type PointsAccount struct {
+	UserID    string    `json:"user_id"`
+	Balance   int64     `json:"balance"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for the PointsAccount model
+func (PointsAccount) TableName() string {
+	return "points_accounts"
+}
+
+// PointsTransaction is one change to a points balance. Earned points are positive and
+// redeemed ones negative.
+type PointsTransaction struct {
+	ID           string                `json:"id"`
+	UserID       string                `json:"user_id"`
+	Type         PointsTransactionType `json:"type"`
+	Points       int64                 `json:"points"`
+	BalanceAfter int64                 `json:"balance_after"`
+	OrderID      string                `json:"order_id,omitempty"`
+	Reference    string                `json:"reference"` // Unique per user, so a retried change applies once
+	Description  string                `json:"description"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+// TableName returns the table name for the PointsTransaction model
+func (PointsTransaction) TableName() string {
+	return "points_transactions"
+}
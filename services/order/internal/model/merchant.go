@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// Merchant is a restaurant or shop users order from. Orders placed with a merchant list
+// items from its catalog, priced by the catalog rather than by the client.
+type Merchant struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	City      string    `json:"city"` // Stored lower case
+	Address   string    `json:"address"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for the Merchant model
+func (Merchant) TableName() string {
+	return "merchants"
+}
+
+// CatalogItem is an item on a merchant's menu
+type CatalogItem struct {
+	ID          string    `json:"id"`
+	MerchantID  string    `json:"merchant_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Category    string    `json:"category,omitempty"` // Menu section
+	Price       int64     `json:"price"`              // Minor units
+	Available   bool      `json:"available"`
+	Stock       *int      `json:"stock,omitempty"` // Units left to order; nil when the merchant does not track stock
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for the CatalogItem model
+func (CatalogItem) TableName() string {
+	return "catalog_items"
+}
+
+// StockReservation is stock of a catalog item held for an order. The stock is taken off
+// the item when the order is placed and given back if the order is cancelled before it
+// is taken by a provider.
+type StockReservation struct {
+	OrderID   string    `json:"order_id"`
+	ItemID    string    `json:"item_id"`
+	Quantity  int       `json:"quantity"`
+	ExpiresAt time.Time `json:"expires_at"` // When a sweep settles the hold, whatever became of the order
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for the StockReservation model
+func (StockReservation) TableName() string {
+	return "stock_reservations"
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// DispatchLatency describes how long recent orders waited from being created until they
+// were first dispatched to a provider
+type DispatchLatency struct {
+	Window         time.Duration `json:"window"`    // How far back orders are counted
+	Decisions      int64         `json:"decisions"` // Orders first dispatched within the window
+	AverageSeconds float64       `json:"average_seconds"`
+	P50Seconds     float64       `json:"p50_seconds"`
+	P95Seconds     float64       `json:"p95_seconds"`
+	MaxSeconds     float64       `json:"max_seconds"`
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/order-api-microservices/pkg/money"
 )
 
 // OrderStatus represents the status of an order
@@ -28,6 +30,28 @@ const (
 	StatusDisputed        OrderStatus = "DISPUTED"
 )
 
+// Finished reports whether an order in this status is over for its user and provider,
+// who can then no longer chat or call each other
+func (s OrderStatus) Finished() bool {
+	switch s {
+	case StatusDelivered, StatusCompleted, StatusCancelled, StatusRefunded, StatusDisputed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Taken reports whether an order in this status has been accepted by its provider and
+// not yet finished
+func (s OrderStatus) Taken() bool {
+	switch s {
+	case StatusProviderAccepted, StatusInProgress, StatusPickedUp, StatusInTransit, StatusArrived:
+		return true
+	default:
+		return false
+	}
+}
+
 // OrderType represents the type of order
 type OrderType string
 
@@ -37,6 +61,16 @@ const (
 	TypePackageDelivery OrderType = "PACKAGE_DELIVERY"
 	TypeGroceryDelivery OrderType = "GROCERY_DELIVERY"
 	TypeServiceBooking  OrderType = "SERVICE_BOOKING"
+	TypeRental          OrderType = "RENTAL"
+	TypeReturn          OrderType = "RETURN" // Takes goods of a delivered order back from its destination to where they came from
+)
+
+// PartyRole identifies which party to an order someone is
+type PartyRole string
+
+const (
+	PartyUser     PartyRole = "USER"
+	PartyProvider PartyRole = "PROVIDER"
 )
 
 // PaymentMethod represents the payment method for an order
@@ -59,6 +93,16 @@ type Location struct {
 	City         string            `json:"city,omitempty"`
 	Country      string            `json:"country,omitempty"`
 	AdditionalInfo map[string]string `json:"additional_info,omitempty"`
+	Instructions *StopInstructions `json:"instructions,omitempty"` // For the provider at this stop
+}
+
+// StopInstructions tell the provider how to get in and where to leave things at a stop.
+// The gate code and notes are encrypted at rest like the address.
+type StopInstructions struct {
+	GateCode    string `json:"gate_code,omitempty"`
+	Floor       string `json:"floor,omitempty"`
+	LeaveAtDoor bool   `json:"leave_at_door,omitempty"`
+	Notes       string `json:"notes,omitempty"`
 }
 
 // Value implements the driver.Valuer interface for JSON serialization
@@ -80,8 +124,12 @@ type OrderItem struct {
 	ItemID     string            `json:"item_id"`
 	Name       string            `json:"name"`
 	Quantity   int               `json:"quantity"`
-	Price      float64           `json:"price"`
+	Price      int64             `json:"price"` // Minor units
 	Properties map[string]string `json:"properties,omitempty"`
+	WeightGrams int              `json:"weight_grams,omitempty"` // Each; package deliveries only
+	LengthCm    int              `json:"length_cm,omitempty"`    // Package deliveries only
+	WidthCm     int              `json:"width_cm,omitempty"`     // Package deliveries only
+	HeightCm    int              `json:"height_cm,omitempty"`    // Package deliveries only
 }
 
 // Value implements the driver.Valuer interface for JSON serialization
@@ -136,16 +184,29 @@ type Order struct {
 	PickupLocation     Location        `json:"pickup_location"`
 	DestinationLocation Location        `json:"destination_location"`
 	Items              OrderItems      `json:"items"`
-	TotalPrice         float64         `json:"total_price"`
-	PlatformFee        float64         `json:"platform_fee"`
-	ProviderFee        float64         `json:"provider_fee"`
+	TotalPrice         int64           `json:"total_price"` // Amounts are in minor units (cents)
+	PlatformFee        int64           `json:"platform_fee"`
+	ProviderFee        int64           `json:"provider_fee"`
+	TipAmount          int64           `json:"tip_amount"`
+	CancellationFee    int64           `json:"cancellation_fee"`
+	DeliveryProofHash  string          `json:"delivery_proof_hash,omitempty"`
+	Frozen             bool            `json:"frozen"` // Set while a safety incident is open; the status cannot change
+	AtRisk             bool            `json:"at_risk"` // Set once any stage of the order has taken longer than its SLA target
 	TransactionID      string          `json:"transaction_id,omitempty"`
 	BlockchainTxHash   string          `json:"blockchain_tx_hash,omitempty"`
 	PaymentMethod      PaymentMethod   `json:"payment_method"`
-	Notes              string          `json:"notes,omitempty"`
+	PaymentMethodID    string          `json:"payment_method_id,omitempty"` // The saved payment method the order is paid with, if any
+	WalletAmount       int64           `json:"wallet_amount,omitempty"`     // Part of the total paid from the user's wallet balance
+	PointsRedeemed     int64           `json:"points_redeemed,omitempty"`
+	PointsDiscount     int64           `json:"points_discount,omitempty"`   // Taken off the total for the redeemed points
+	ReturnOfOrderID    string          `json:"return_of_order_id,omitempty"` // The delivered order a return takes goods back from
+	Notes              string          `json:"notes,omitempty"` // Orders created before per-stop instructions; newer orders keep notes on their destination
 	CreatedAt          time.Time       `json:"created_at"`
 	UpdatedAt          time.Time       `json:"updated_at"`
 	StatusHistory      StatusHistories `json:"status_history"`
+	PaymentShares      []*PaymentShare `json:"payment_shares,omitempty"` // Loaded separately; empty unless the payment is split
+	Vehicle            *OrderVehicle   `json:"vehicle,omitempty"`        // Loaded separately; nil until a provider on shift in a registered vehicle accepts
+	Authorization      *PaymentAuthorization `json:"authorization,omitempty"` // Loaded separately; nil unless the payment was authorized up front
 }
 
 // TableName returns the table name for the Order model
@@ -168,11 +229,42 @@ func (o *Order) AddStatusHistory(status OrderStatus, updatedBy, notes string) {
 	o.StatusHistory = append(o.StatusHistory, historyEntry)
 }
 
-// CalculateFees calculates platform and provider fees
-func (o *Order) CalculateFees() {
-	// Basic fee calculation (would be more complex in production)
-	o.PlatformFee = o.TotalPrice * 0.1  // 10% platform fee
-	o.ProviderFee = o.TotalPrice * 0.8  // 80% goes to provider
+// StatusSince returns when the order last entered its current status, or when it was
+// created if its history does not say
+func (o *Order) StatusSince() time.Time {
+	for i := len(o.StatusHistory) - 1; i >= 0; i-- {
+		if o.StatusHistory[i].Status == o.Status {
+			return o.StatusHistory[i].Timestamp
+		}
+	}
+	return o.CreatedAt
+}
+
+// CalculateFees calculates platform and provider fees from a fee rule. The platform fee
+// is raised to the rule's minimum, but never above the order total.
+func (o *Order) CalculateFees(rule FeeRule) {
+	o.PlatformFee = money.Percent(o.TotalPrice, rule.PlatformFeePercent)
+	if o.PlatformFee < rule.MinPlatformFee {
+		o.PlatformFee = rule.MinPlatformFee
+	}
+	if o.PlatformFee > o.TotalPrice {
+		o.PlatformFee = o.TotalPrice
+	}
+	o.ProviderFee = money.Percent(o.TotalPrice, rule.ProviderFeePercent)
+}
+
+// RoleOf returns the role of a party to the order, or false if they are not one
+func (o *Order) RoleOf(participantID string) (PartyRole, bool) {
+	switch {
+	case participantID == "":
+		return "", false
+	case participantID == o.UserID:
+		return PartyUser, true
+	case participantID == o.ProviderID:
+		return PartyProvider, true
+	default:
+		return "", false
+	}
 }
 
 // Location represents a row in the locations table for tracking order movements
@@ -188,4 +280,39 @@ type OrderLocation struct {
 // TableName returns the table name for the OrderLocation model
 func (OrderLocation) TableName() string {
 	return "order_locations"
-} 
\ No newline at end of file
+}
+
+// LedgerEntryType identifies what a provider ledger entry pays for
+type LedgerEntryType string
+
+const (
+	LedgerFare        LedgerEntryType = "FARE"
+	LedgerTip         LedgerEntryType = "TIP"
+	LedgerHandoffFare LedgerEntryType = "HANDOFF_FARE" // A provider's share of an order they handed off
+)
+
+// LedgerEntry is a line in a provider's payout ledger
+type LedgerEntry struct {
+	ID         int64           `json:"id"`
+	ProviderID string          `json:"provider_id"`
+	OrderID    string          `json:"order_id"`
+	EntryType  LedgerEntryType `json:"entry_type"`
+	Amount     int64           `json:"amount"` // Minor units
+	PaymentID  string          `json:"payment_id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// TableName returns the table name for the LedgerEntry model
+func (LedgerEntry) TableName() string {
+	return "provider_ledger_entries"
+}
+
+// OrderExportFilter selects the orders to export. Empty fields match every order.
+type OrderExportFilter struct {
+	UserID     string
+	ProviderID string
+	Status     OrderStatus
+	OrderType  OrderType
+	From       time.Time // Created at or after
+	To         time.Time // Created before
+}
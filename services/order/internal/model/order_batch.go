@@ -0,0 +1,71 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// BatchStopKind is what a provider does at a stop of a batch
+type BatchStopKind string
+
+const (
+	StopPickup  BatchStopKind = "PICKUP"
+	StopDropoff BatchStopKind = "DROPOFF"
+)
+
+// DoneIn reports whether a stop of this kind is behind the provider once its order is
+// in status
+func (k BatchStopKind) DoneIn(status OrderStatus) bool {
+	if status.Finished() {
+		return true
+	}
+	if k == StopPickup {
+		switch status {
+		case StatusPickedUp, StatusInTransit, StatusArrived:
+			return true
+		}
+	}
+	return false
+}
+
+// BatchStop is a pickup or dropoff of one of a batch's orders
+type BatchStop struct {
+	OrderID  string        `json:"order_id"`
+	Kind     BatchStopKind `json:"kind"`
+	Location Location      `json:"location"`
+}
+
+// BatchStops is the sequence in which a provider visits a batch's stops
+type BatchStops []BatchStop
+
+// Value implements the driver.Valuer interface for JSON serialization
+func (s BatchStops) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan implements the sql.Scanner interface for JSON deserialization
+func (s *BatchStops) Scan(value interface{}) error {
+	data, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(data, s)
+}
+
+// OrderBatch is a trip on which a provider carries several delivery orders at once.
+// Each order keeps its own status; the batch only fixes the order of the stops.
+type OrderBatch struct {
+	ID         string     `json:"id"`
+	ProviderID string     `json:"provider_id"`
+	OrderIDs   []string   `json:"order_ids"` // In the order they joined the batch
+	Stops      BatchStops `json:"stops"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for the OrderBatch model
+func (OrderBatch) TableName() string {
+	return "order_batches"
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// OrderHandoff records an active order passing from one provider to another mid-delivery,
+// after a breakdown or an emergency. The provider handing off earns the part of the fare
+// for the share of the trip they made.
+type OrderHandoff struct {
+	ID             string    `json:"id"`
+	OrderID        string    `json:"order_id"`
+	FromProviderID string    `json:"from_provider_id"`
+	ToProviderID   string    `json:"to_provider_id"`
+	RequestedBy    string    `json:"requested_by"` // The admin, or the provider handing off
+	Reason         string    `json:"reason"`
+	Latitude       float64   `json:"latitude"` // Where the order was handed off
+	Longitude      float64   `json:"longitude"`
+	TripProgress   float64   `json:"trip_progress"` // The share of the trip made by then, 0 to 1
+	HandedOffAt    time.Time `json:"handed_off_at"`
+}
+
+// TableName returns the table name for the OrderHandoff model
+func (OrderHandoff) TableName() string {
+	return "order_handoffs"
+}
+
+// HandoffFareShares splits a provider fee between the providers an order was handed off
+// from, in the order of handoffs, by how far along the trip each took it. It returns one
+// entry per provider, with the amount of the fee left for the provider who finished it.
+func HandoffFareShares(providerFee int64, handoffs []*OrderHandoff) ([]LedgerEntry, int64) {
+	var shares []LedgerEntry
+	byProvider := make(map[string]int)
+	var progress float64
+	var credited int64
+	for _, handoff := range handoffs {
+		progress = math.Min(math.Max(progress, handoff.TripProgress), 1)
+		// Rounding the running total rather than each share never credits more than the fee
+		share := int64(math.Round(float64(providerFee)*progress)) - credited
+		if share <= 0 {
+			continue
+		}
+		credited += share
+
+		if i, ok := byProvider[handoff.FromProviderID]; ok {
+			shares[i].Amount += share
+			continue
+		}
+		byProvider[handoff.FromProviderID] = len(shares)
+		shares = append(shares, LedgerEntry{
+			ProviderID: handoff.FromProviderID,
+			OrderID:    handoff.OrderID,
+			EntryType:  LedgerHandoffFare,
+			Amount:     share,
+		})
+	}
+	return shares, providerFee - credited
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHandoffFareShares(t *testing.T) {
+	handoff := func(from string, progress float64) *OrderHandoff {
+		return &OrderHandoff{OrderID: "order", FromProviderID: from, TripProgress: progress}
+	}
+	share := func(provider string, amount int64) LedgerEntry {
+		return LedgerEntry{ProviderID: provider, OrderID: "order", EntryType: LedgerHandoffFare, Amount: amount}
+	}
+
+	tests := []struct {
+		name          string
+		handoffs      []*OrderHandoff
+		wantShares    []LedgerEntry
+		wantRemaining int64
+	}{
+		{name: "never handed off", wantRemaining: 10000},
+		{name: "handed off before pickup", handoffs: []*OrderHandoff{handoff("a", 0)}, wantRemaining: 10000},
+		{name: "handed off halfway", handoffs: []*OrderHandoff{handoff("a", 0.5)}, wantShares: []LedgerEntry{share("a", 5000)}, wantRemaining: 5000},
+		{
+			name:          "handed off twice",
+			handoffs:      []*OrderHandoff{handoff("a", 0.25), handoff("b", 0.6)},
+			wantShares:    []LedgerEntry{share("a", 2500), share("b", 3500)},
+			wantRemaining: 4000,
+		},
+		{
+			name:          "handed back",
+			handoffs:      []*OrderHandoff{handoff("a", 0.2), handoff("b", 0.3), handoff("a", 0.7)},
+			wantShares:    []LedgerEntry{share("a", 6000), share("b", 1000)},
+			wantRemaining: 3000,
+		},
+		{
+			name:          "handed off further back along the trip",
+			handoffs:      []*OrderHandoff{handoff("a", 0.4), handoff("b", 0.3)},
+			wantShares:    []LedgerEntry{share("a", 4000)},
+			wantRemaining: 6000,
+		},
+		{
+			name:          "shares round without exceeding the fee",
+			handoffs:      []*OrderHandoff{handoff("a", 1.0/3), handoff("b", 2.0/3)},
+			wantShares:    []LedgerEntry{share("a", 3333), share("b", 3334)},
+			wantRemaining: 3333,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, remaining := HandoffFareShares(10000, tt.handoffs)
+			if !reflect.DeepEqual(shares, tt.wantShares) || remaining != tt.wantRemaining {
+				t.Errorf("HandoffFareShares = %v, %d, want %v, %d", shares, remaining, tt.wantShares, tt.wantRemaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// OrderTrack is a finished order's location history compressed into an encoded polyline.
+// Raw points are deleted once they pass the retention period, leaving only the track.
+type OrderTrack struct {
+	OrderID    string    `json:"order_id"`
+	ProviderID string    `json:"provider_id"`
+	Polyline   string    `json:"polyline"`
+	PointCount int       `json:"point_count"`
+	StartedAt  time.Time `json:"started_at"` // Time of the first point
+	EndedAt    time.Time `json:"ended_at"`   // Time of the last point
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// TableName returns the table name for the OrderTrack model
+func (OrderTrack) TableName() string {
+	return "order_tracks"
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// OrderVehicle is the vehicle a provider accepted an order in, as it was then, so
+// receipts and support show the plate the user saw even after the provider changes
+// vehicles
+type OrderVehicle struct {
+	OrderID     string    `json:"order_id"`
+	ProviderID  string    `json:"provider_id"`
+	VehicleID   string    `json:"vehicle_id"`
+	Plate       string    `json:"plate"`
+	Type        string    `json:"type"`
+	Description string    `json:"description,omitempty"`
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+// TableName returns the table name for the OrderVehicle model
+func (OrderVehicle) TableName() string {
+	return "order_vehicles"
+}
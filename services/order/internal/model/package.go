@@ -0,0 +1,100 @@
+package model
+
+import "sort"
+
+// SizeClass groups packages by how heavy and how long they are, for pricing
+type SizeClass string
+
+// Package size classes, from the smallest
+const (
+	SizeSmall     SizeClass = "SMALL"
+	SizeMedium    SizeClass = "MEDIUM"
+	SizeLarge     SizeClass = "LARGE"
+	SizeOversized SizeClass = "OVERSIZED"
+)
+
+// sizeClassLimits are the heaviest and longest packages of each class but the last;
+// anything bigger is oversized
+var sizeClassLimits = []struct {
+	class          SizeClass
+	maxWeightGrams int
+	maxLengthCm    int
+}{
+	{SizeSmall, 5000, 40},
+	{SizeMedium, 20000, 80},
+	{SizeLarge, 50000, 150},
+}
+
+// PackageSize is how much a package order weighs, and the space its largest items need
+type PackageSize struct {
+	WeightGrams int // All items together
+	LengthCm    int // Longest side of any item
+	WidthCm     int // Longest middle side of any item
+	HeightCm    int // Longest short side of any item
+}
+
+// Class returns the size class of the package
+func (p PackageSize) Class() SizeClass {
+	for _, limit := range sizeClassLimits {
+		if p.WeightGrams <= limit.maxWeightGrams && p.LengthCm <= limit.maxLengthCm {
+			return limit.class
+		}
+	}
+	return SizeOversized
+}
+
+// Measured reports whether an item has a weight and all three dimensions
+func (i OrderItem) Measured() bool {
+	return i.WeightGrams > 0 && i.LengthCm > 0 && i.WidthCm > 0 && i.HeightCm > 0
+}
+
+// PackageSize returns the size of a package made of the items. Each item's sides are
+// sorted, so an item fits whichever way round it is loaded.
+func (items OrderItems) PackageSize() PackageSize {
+	var size PackageSize
+	for _, item := range items {
+		size.WeightGrams += item.WeightGrams * item.Quantity
+
+		sides := sortedSides(item.LengthCm, item.WidthCm, item.HeightCm)
+		size.LengthCm = max(size.LengthCm, sides[0])
+		size.WidthCm = max(size.WidthCm, sides[1])
+		size.HeightCm = max(size.HeightCm, sides[2])
+	}
+	return size
+}
+
+// SizeClass returns the size class of a package delivery, or "" for any other order
+func (o *Order) SizeClass() SizeClass {
+	if o.OrderType != TypePackageDelivery {
+		return ""
+	}
+	return o.Items.PackageSize().Class()
+}
+
+// VehicleCapacity is the heaviest load and the largest cargo space a provider's vehicle
+// takes. The zero value means the provider never gave it.
+type VehicleCapacity struct {
+	MaxWeightGrams int `json:"max_weight_grams"`
+	LengthCm       int `json:"length_cm"`
+	WidthCm        int `json:"width_cm"`
+	HeightCm       int `json:"height_cm"`
+}
+
+// Carries reports whether the vehicle can carry a package. A vehicle whose capacity was
+// never given is trusted with small packages only.
+func (c VehicleCapacity) Carries(size PackageSize) bool {
+	if c == (VehicleCapacity{}) {
+		return size.Class() == SizeSmall
+	}
+
+	space := sortedSides(c.LengthCm, c.WidthCm, c.HeightCm)
+	return size.WeightGrams <= c.MaxWeightGrams &&
+		size.LengthCm <= space[0] && size.WidthCm <= space[1] && size.HeightCm <= space[2]
+}
+
+// sortedSides returns three sides from the longest
+func sortedSides(a, b, c int) [3]int {
+	sides := []int{a, b, c}
+	sort.Sort(sort.Reverse(sort.IntSlice(sides)))
+	return [3]int{sides[0], sides[1], sides[2]}
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// AuthorizationStatus represents where an order's payment authorization stands
+type AuthorizationStatus string
+
+const (
+	AuthorizationAuthorized AuthorizationStatus = "AUTHORIZED" // Held on the payer's card or wallet, not yet charged
+	AuthorizationCaptured   AuthorizationStatus = "CAPTURED"   // Charged; any rest of the hold was released
+	AuthorizationReleased   AuthorizationStatus = "RELEASED"   // Voided without charging anything
+)
+
+// PaymentAuthorization holds an order's total on the payer's card or wallet from when the
+// order is placed. It is captured when the order completes, or released when the order is
+// cancelled, so the user is only charged for what was delivered.
+type PaymentAuthorization struct {
+	OrderID         string              `json:"order_id"`
+	AuthorizationID string              `json:"authorization_id"` // The payment service's ID of the hold
+	Amount          int64               `json:"amount"`           // Minor units
+	CapturedAmount  int64               `json:"captured_amount"`
+	PaymentID       string              `json:"payment_id,omitempty"` // Set once captured
+	Status          AuthorizationStatus `json:"status"`
+	ExpiresAt       time.Time           `json:"expires_at"` // The hold lapses after this unless captured
+	SettledAt       *time.Time          `json:"settled_at,omitempty"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// TableName returns the table name for the PaymentAuthorization model
+func (PaymentAuthorization) TableName() string {
+	return "payment_authorizations"
+}
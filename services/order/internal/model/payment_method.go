@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// SavedPaymentMethod is a card or wallet a user saved for paying orders. It is tokenized
+// by the payment gateway before it reaches the platform, so only the gateway's token and
+// what is needed to show the method are held: never a card number or security code.
+type SavedPaymentMethod struct {
+	ID           string        `json:"id"`
+	UserID       string        `json:"user_id"`
+	Type         PaymentMethod `json:"type"` // CREDIT_CARD, DEBIT_CARD or DIGITAL_WALLET
+	Gateway      string        `json:"gateway"`
+	GatewayToken string        `json:"-"` // Only the payment service charges with it
+	Fingerprint  string        `json:"-"` // The gateway's fingerprint of the card, the same each time it is tokenized
+	Brand        string        `json:"brand,omitempty"`
+	Last4        string        `json:"last4,omitempty"`
+	ExpMonth     int           `json:"exp_month,omitempty"`
+	ExpYear      int           `json:"exp_year,omitempty"`
+	IsDefault    bool          `json:"is_default"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// TableName returns the table name for the SavedPaymentMethod model
+func (SavedPaymentMethod) TableName() string {
+	return "payment_methods"
+}
+
+// Expired reports whether the method is a card that expired before a time. Cards are
+// valid to the end of their expiry month.
+func (m *SavedPaymentMethod) Expired(at time.Time) bool {
+	if m.ExpYear == 0 || m.ExpMonth == 0 {
+		return false
+	}
+	validUntil := time.Date(m.ExpYear, time.Month(m.ExpMonth)+1, 1, 0, 0, 0, 0, time.UTC)
+	return !at.Before(validUntil)
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// ShareStatus represents the collection state of a payment share
+type ShareStatus string
+
+const (
+	SharePending    ShareStatus = "PENDING"
+	ShareCaptured   ShareStatus = "CAPTURED"
+	ShareFailed     ShareStatus = "FAILED"
+	ShareReassigned ShareStatus = "REASSIGNED" // Collected from the primary payer after the split timed out
+)
+
+// PaymentShare is one payer's part of a split order payment
+type PaymentShare struct {
+	ID         string      `json:"id"`
+	OrderID    string      `json:"order_id"`
+	UserID     string      `json:"user_id"`
+	Percentage float64     `json:"percentage"`
+	Amount     int64       `json:"amount"` // Minor units
+	Status     ShareStatus `json:"status"`
+	PaymentID  string      `json:"payment_id,omitempty"`
+	Attempts   int         `json:"attempts"`
+	LastError  string      `json:"last_error,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// TableName returns the table name for the PaymentShare model
+func (PaymentShare) TableName() string {
+	return "payment_shares"
+}
+
+// Outstanding reports whether the share still has to be collected
+func (s *PaymentShare) Outstanding() bool {
+	return s.Status == SharePending || s.Status == ShareFailed
+}
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// ErasedCoordinateDecimals is how many decimal places of latitude and longitude an
+// anonymized order keeps, about a kilometre, so demand statistics still hold
+const ErasedCoordinateDecimals = 2
+
+// UserDataExport is everything the platform holds about a user, handed over as a JSON
+// archive on a data access request
+type UserDataExport struct {
+	UserID             string                    `json:"user_id"`
+	GeneratedAt        time.Time                 `json:"generated_at"`
+	Orders             []*Order                  `json:"orders"`
+	Locations          []*OrderLocation          `json:"locations"`     // Positions recorded during the user's orders
+	Tracks             []*OrderTrack             `json:"tracks"`        // Archived trips whose positions were pruned
+	ChatMessages       []*ChatMessage            `json:"chat_messages"` // Messages on the user's orders
+	Providers          []*UserProviderPreference `json:"providers"`     // Providers the user favorited or blocked
+	PaymentMethods     []*SavedPaymentMethod     `json:"payment_methods"`
+	Wallet             *Wallet                   `json:"wallet"`
+	WalletTransactions []*WalletTransaction      `json:"wallet_transactions"`
+	ReferralCode       *ReferralCode             `json:"referral_code,omitempty"`
+	Referral           *Referral                 `json:"referral,omitempty"` // Who referred the user, if anyone
+	Points             *PointsAccount            `json:"points"`
+	PointsTransactions []*PointsTransaction      `json:"points_transactions"`
+	Notifications      []*ExportedNotification   `json:"notifications"`
+}
+
+// ExportedNotification is a notification sent to a user or provider, as held by the
+// notification service
+type ExportedNotification struct {
+	ID               string                 `json:"id"`
+	RecipientType    string                 `json:"recipient_type"` // USER or PROVIDER
+	NotificationType string                 `json:"notification_type"`
+	Title            string                 `json:"title"`
+	Message          string                 `json:"message"`
+	Payload          map[string]interface{} `json:"payload,omitempty"`
+	ReferenceID      string                 `json:"reference_id,omitempty"`
+	Read             bool                   `json:"read"`
+	CreatedAt        time.Time              `json:"created_at"`
+	ReadAt           *time.Time             `json:"read_at,omitempty"`
+}
+
+// ErasureResult counts what forgetting a user or provider changed
+type ErasureResult struct {
+	OrdersAnonymized        int64 `json:"orders_anonymized"`
+	LocationsDeleted        int64 `json:"locations_deleted"`
+	ChatMessagesDeleted     int64 `json:"chat_messages_deleted"`
+	NotificationsAnonymized int64 `json:"notifications_anonymized"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Refund records money returned to the payer of an order
+type Refund struct {
+	ID              string    `json:"id"`
+	OrderID         string    `json:"order_id"`
+	Amount          int64     `json:"amount"` // Minor units
+	Reason          string    `json:"reason"`
+	RequestedBy     string    `json:"requested_by"`
+	DisputeID       string    `json:"dispute_id,omitempty"`
+	PaymentRefundID string    `json:"payment_refund_id"`       // Empty when the whole refund went to the wallet
+	WalletAmount    int64     `json:"wallet_amount,omitempty"` // Part of Amount credited to the user's wallet instead
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for the Refund model
+func (Refund) TableName() string {
+	return "refunds"
+}
+
+// WasPaid reports whether the order's payment was ever completed, or its held payment
+// captured in full. The authorization must be loaded for the latter.
+func (o *Order) WasPaid() bool {
+	if auth := o.Authorization; auth != nil && auth.Status == AuthorizationCaptured && auth.CapturedAmount >= auth.Amount {
+		return true
+	}
+	for _, entry := range o.StatusHistory {
+		if entry.Status == StatusPaymentComplete {
+			return true
+		}
+	}
+	return false
+}
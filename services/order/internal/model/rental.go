@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// Rental is the booking behind a RENTAL order, which hires a provider by the hour. The
+// clock starts when the order goes IN_PROGRESS; time past the booked hours is billed as
+// overtime when the order completes.
+type Rental struct {
+	OrderID           string     `json:"order_id"`
+	HourlyRate        int64      `json:"hourly_rate"`   // Minor units
+	OvertimeRate      int64      `json:"overtime_rate"` // Minor units per hour past the booking
+	BookedHours       int        `json:"booked_hours"`  // Hours booked with the order plus approved extensions
+	StartedAt         *time.Time `json:"started_at,omitempty"`
+	EndedAt           *time.Time `json:"ended_at,omitempty"`
+	OvertimeMinutes   int        `json:"overtime_minutes"` // Billed minutes, rounded up to the billing increment
+	OvertimeCharge    int64      `json:"overtime_charge"`  // Minor units
+	OvertimePaymentID string     `json:"overtime_payment_id,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for the Rental model
+func (Rental) TableName() string {
+	return "order_rentals"
+}
+
+// BookedUntil returns when the booked hours run out, or nil before the rental starts
+func (r *Rental) BookedUntil() *time.Time {
+	if r.StartedAt == nil {
+		return nil
+	}
+	until := r.StartedAt.Add(time.Duration(r.BookedHours) * time.Hour)
+	return &until
+}
+
+// RentalExtensionStatus is where a request for more rental hours stands
+type RentalExtensionStatus string
+
+const (
+	ExtensionPending  RentalExtensionStatus = "PENDING"
+	ExtensionApproved RentalExtensionStatus = "APPROVED"
+	ExtensionDeclined RentalExtensionStatus = "DECLINED"
+)
+
+// RentalExtension is a user's request to book more hours on a running rental. The
+// provider approves or declines it; approved hours are charged at the hourly rate.
+type RentalExtension struct {
+	ID          string                `json:"id"`
+	OrderID     string                `json:"order_id"`
+	Hours       int                   `json:"hours"`
+	Amount      int64                 `json:"amount"` // Minor units
+	Status      RentalExtensionStatus `json:"status"`
+	PaymentID   string                `json:"payment_id,omitempty"`
+	RequestedAt time.Time             `json:"requested_at"`
+	RespondedAt *time.Time            `json:"responded_at,omitempty"`
+}
+
+// TableName returns the table name for the RentalExtension model
+func (RentalExtension) TableName() string {
+	return "rental_extensions"
+}
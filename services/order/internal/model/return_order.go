@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Returnable reports whether goods of the order can be sent back with a return order:
+// it delivered a package or groceries to its user, and is not itself a return. Food is
+// not taken back.
+func (o *Order) Returnable() bool {
+	if o.OrderType != TypePackageDelivery && o.OrderType != TypeGroceryDelivery {
+		return false
+	}
+	_, delivered := o.DeliveredAt()
+	return delivered && (o.Status == StatusDelivered || o.Status == StatusCompleted)
+}
+
+// DeliveredAt returns when the order was delivered, which is when it first became
+// DELIVERED or, for an order completed without that step, COMPLETED
+func (o *Order) DeliveredAt() (time.Time, bool) {
+	for _, entry := range o.StatusHistory {
+		if entry.Status == StatusDelivered || entry.Status == StatusCompleted {
+			return entry.Timestamp, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// RouteDeviation records a provider staying off an order's expected route. A deviation
+// is active until the provider is back on route.
+type RouteDeviation struct {
+	ID         string     `json:"id"`
+	OrderID    string     `json:"order_id"`
+	ProviderID string     `json:"provider_id"`
+	DistanceKm float64    `json:"distance_km"` // Distance from the route when it was detected
+	Latitude   float64    `json:"latitude"`
+	Longitude  float64    `json:"longitude"`
+	StartedAt  time.Time  `json:"started_at"` // When the provider left the route
+	DetectedAt time.Time  `json:"detected_at"`
+	ClearedAt  *time.Time `json:"cleared_at,omitempty"`
+}
+
+// TableName returns the table name for the RouteDeviation model
+func (RouteDeviation) TableName() string {
+	return "route_deviations"
+}
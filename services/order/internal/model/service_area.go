@@ -0,0 +1,65 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ServiceArea is a zone of a city where orders are taken. While any area is active,
+// an order's pickup must fall inside one, and only providers registered in that area
+// are matched to it.
+type ServiceArea struct {
+	ID        string    `json:"id"`
+	City      string    `json:"city"` // Stored lower case
+	Name      string    `json:"name"`
+	Boundary  Boundary  `json:"boundary"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for the ServiceArea model
+func (ServiceArea) TableName() string {
+	return "service_areas"
+}
+
+// BoundaryPoint is a vertex of a service area's boundary
+type BoundaryPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Boundary is a polygon given as its vertices in order; the last vertex joins back to
+// the first
+type Boundary []BoundaryPoint
+
+// Value implements the driver.Valuer interface for JSON serialization
+func (b Boundary) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
+
+// Scan implements the sql.Scanner interface for JSON deserialization
+func (b *Boundary) Scan(value interface{}) error {
+	data, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(data, b)
+}
+
+// Contains reports whether a point is inside the boundary. It casts a ray east from the
+// point and counts the edges it crosses, treating coordinates as planar, which holds
+// for areas the size of a city.
+func (b Boundary) Contains(latitude, longitude float64) bool {
+	inside := false
+	for i, j := 0, len(b)-1; i < len(b); j, i = i, i+1 {
+		a, c := b[i], b[j]
+		if (a.Latitude > latitude) != (c.Latitude > latitude) &&
+			longitude < (c.Longitude-a.Longitude)*(latitude-a.Latitude)/(c.Latitude-a.Latitude)+a.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,116 @@
+package model
+
+import "time"
+
+// SLAStage is a part of an order's lifecycle that is expected to take at most a set time
+type SLAStage string
+
+// SLA stages
+const (
+	SLAAssignment SLAStage = "ASSIGNMENT" // From the order being placed, or paid for, until a provider accepts it
+	SLAPickup     SLAStage = "PICKUP"     // From a provider accepting the order until they pick it up
+	SLADelivery   SLAStage = "DELIVERY"   // From pickup until the order is delivered
+)
+
+// SLAStages lists every stage in the order they happen
+var SLAStages = []SLAStage{SLAAssignment, SLAPickup, SLADelivery}
+
+// During lists the statuses an order is in while the stage is under way
+func (s SLAStage) During() []OrderStatus {
+	switch s {
+	case SLAAssignment:
+		return []OrderStatus{StatusCreated, StatusPaymentComplete, StatusProviderAssigned, StatusProviderRejected}
+	case SLAPickup:
+		return []OrderStatus{StatusProviderAccepted}
+	case SLADelivery:
+		return []OrderStatus{StatusPickedUp, StatusInProgress, StatusInTransit, StatusArrived}
+	}
+	return nil
+}
+
+// End lists the statuses that complete the stage
+func (s SLAStage) End() []OrderStatus {
+	switch s {
+	case SLAAssignment:
+		return []OrderStatus{StatusProviderAccepted}
+	case SLAPickup:
+		return []OrderStatus{StatusPickedUp, StatusInProgress}
+	case SLADelivery:
+		return []OrderStatus{StatusDelivered, StatusCompleted}
+	}
+	return nil
+}
+
+// Span finds the stage in an order's status history. The stage starts when the order
+// enters one of its During statuses, and starts over if the order leaves them for any
+// status but an End one, as a crypto order does while it waits for payment. It reports
+// false if the stage has not started, or was left without completing, such as by
+// cancelling. end is zero while the stage is under way.
+func (s SLAStage) Span(history StatusHistories) (start, end time.Time, ok bool) {
+	during, ends := s.During(), s.End()
+	for _, entry := range history {
+		switch {
+		case hasStatus(ends, entry.Status):
+			if !start.IsZero() {
+				return start, entry.Timestamp, true
+			}
+		case hasStatus(during, entry.Status):
+			if start.IsZero() {
+				start = entry.Timestamp
+			}
+		default:
+			start = time.Time{}
+		}
+	}
+	return start, time.Time{}, !start.IsZero()
+}
+
+func hasStatus(statuses []OrderStatus, status OrderStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// SLAOutcome is whether an order's stage finished within its target
+type SLAOutcome string
+
+// SLA outcomes
+const (
+	SLAMet      SLAOutcome = "MET"
+	SLABreached SLAOutcome = "BREACHED" // Recorded as soon as the deadline passes, whether or not the stage has completed
+)
+
+// SLAResult is the outcome of one stage of an order. Each stage of an order has at most one.
+type SLAResult struct {
+	OrderID    string     `json:"order_id"`
+	Stage      SLAStage   `json:"stage"`
+	Outcome    SLAOutcome `json:"outcome"`
+	StartedAt  time.Time  `json:"started_at"`
+	Deadline   time.Time  `json:"deadline"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"` // Unset for a stage breached while still under way
+	RecordedAt time.Time  `json:"recorded_at"`
+}
+
+// TableName returns the table name for the SLAResult model
+func (SLAResult) TableName() string {
+	return "sla_results"
+}
+
+// SLAAttainment counts how often one stage met its target
+type SLAAttainment struct {
+	Stage    SLAStage `json:"stage"`
+	Met      int64    `json:"met"`
+	Breached int64    `json:"breached"`
+}
+
+// Rate is the share of stages that met their target, or 0 when none were measured
+func (a *SLAAttainment) Rate() float64 {
+	total := a.Met + a.Breached
+	if total == 0 {
+		return 0
+	}
+	return float64(a.Met) / float64(total)
+}
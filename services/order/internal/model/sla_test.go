@@ -0,0 +1,53 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSLAStageSpan(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	history := func(statuses ...OrderStatus) StatusHistories {
+		h := make(StatusHistories, len(statuses))
+		for i, status := range statuses {
+			h[i] = StatusHistory{Status: status, Timestamp: at(i)}
+		}
+		return h
+	}
+
+	tests := []struct {
+		name      string
+		stage     SLAStage
+		history   StatusHistories
+		wantStart time.Time
+		wantEnd   time.Time
+		wantOK    bool
+	}{
+		{name: "assignment under way", stage: SLAAssignment, history: history(StatusCreated, StatusProviderAssigned), wantStart: at(0), wantOK: true},
+		{name: "assignment completed", stage: SLAAssignment, history: history(StatusCreated, StatusProviderAssigned, StatusProviderAccepted, StatusPickedUp), wantStart: at(0), wantEnd: at(2), wantOK: true},
+		{name: "assignment starts over after payment", stage: SLAAssignment, history: history(StatusCreated, StatusPaymentPending, StatusPaymentComplete, StatusProviderAccepted), wantStart: at(2), wantEnd: at(3), wantOK: true},
+		{name: "cancelled before assignment", stage: SLAAssignment, history: history(StatusCreated, StatusCancelled)},
+		{name: "pickup not started", stage: SLAPickup, history: history(StatusCreated)},
+		{name: "pickup completed", stage: SLAPickup, history: history(StatusCreated, StatusProviderAccepted, StatusPickedUp), wantStart: at(1), wantEnd: at(2), wantOK: true},
+		{name: "delivery under way", stage: SLADelivery, history: history(StatusProviderAccepted, StatusPickedUp, StatusInTransit), wantStart: at(1), wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := tt.stage.Span(tt.history)
+			if ok != tt.wantOK || !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("Span = %v, %v, %v, want %v, %v, %v", start, end, ok, tt.wantStart, tt.wantEnd, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSLAAttainmentRate(t *testing.T) {
+	if rate := (&SLAAttainment{}).Rate(); rate != 0 {
+		t.Errorf("rate with nothing measured = %v, want 0", rate)
+	}
+	if rate := (&SLAAttainment{Met: 3, Breached: 1}).Rate(); rate != 0.75 {
+		t.Errorf("rate = %v, want 0.75", rate)
+	}
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// SupportScope is what an admin may do as the user in a support session
+type SupportScope string
+
+// Support scope constants
+const (
+	SupportScopeReadOnly  SupportScope = "READ_ONLY"  // Look only: GET requests
+	SupportScopeReadWrite SupportScope = "READ_WRITE" // Act as the user in full
+)
+
+// SupportSession lets an admin act on behalf of one user for troubleshooting until it
+// expires or is ended. Only a hash of its token is kept.
+type SupportSession struct {
+	ID        string       `json:"id"`
+	AdminID   string       `json:"admin_id"`
+	UserID    string       `json:"user_id"`
+	Reason    string       `json:"reason"`
+	Scope     SupportScope `json:"scope"`
+	TokenHash string       `json:"-"`
+	CreatedAt time.Time    `json:"created_at"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	EndedAt   *time.Time   `json:"ended_at,omitempty"`
+	EndedBy   string       `json:"ended_by,omitempty"`
+}
+
+// TableName returns the table name for the SupportSession model
+func (SupportSession) TableName() string {
+	return "support_sessions"
+}
+
+// HashSupportToken returns the SHA-256 of a support session token, hex encoded
+func HashSupportToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Active reports whether the session can still be used at the given time
+func (s *SupportSession) Active(at time.Time) bool {
+	return s.EndedAt == nil && at.Before(s.ExpiresAt)
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// TrackingLink lets anyone holding its token follow one order's delivery without an
+// account. Only a hash of the token is kept.
+type TrackingLink struct {
+	ID        string    `json:"id"`
+	OrderID   string    `json:"order_id"`
+	CreatedBy string    `json:"created_by"` // The user who shared the order
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for the TrackingLink model
+func (TrackingLink) TableName() string {
+	return "tracking_links"
+}
+
+// HashTrackingToken returns the SHA-256 of a tracking link token, hex encoded
+func HashTrackingToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Valid reports whether the link can still be used at the given time
+func (l *TrackingLink) Valid(at time.Time) bool {
+	return at.Before(l.ExpiresAt)
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// ProviderPreference is how a user feels about a provider
+type ProviderPreference string
+
+// Provider preference constants
+const (
+	PreferenceFavorite ProviderPreference = "FAVORITE" // Boosted when matching the user's orders
+	PreferenceBlocked  ProviderPreference = "BLOCKED"  // Never assigned to the user's orders
+)
+
+// UserProviderPreference records that a user has favorited or blocked a provider. A user
+// has at most one preference per provider; setting another replaces it.
+type UserProviderPreference struct {
+	UserID     string             `json:"user_id"`
+	ProviderID string             `json:"provider_id"`
+	Preference ProviderPreference `json:"preference"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// TableName returns the table name for the UserProviderPreference model
+func (UserProviderPreference) TableName() string {
+	return "user_provider_preferences"
+}
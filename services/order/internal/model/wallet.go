@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// WalletTransactionType says why a wallet balance changed
+type WalletTransactionType string
+
+// Wallet transaction types
+const (
+	WalletTopUp        WalletTransactionType = "TOP_UP"
+	WalletOrderPayment WalletTransactionType = "ORDER_PAYMENT"
+	WalletRefund       WalletTransactionType = "REFUND"
+	WalletCompensation WalletTransactionType = "COMPENSATION"
+)
+
+// Wallet is the balance a user holds with us, in minor units. Users without one have
+// an empty balance.
+type Wallet struct {
+	UserID    string    `json:"user_id"`
+	Balance   int64     `json:"balance"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for the Wallet model
+func (Wallet) TableName() string {
+	return "wallets"
+}
+
+// WalletTransaction is one change to a wallet balance. Credits are positive and debits
+// negative.
+type WalletTransaction struct {
+	ID           string                `json:"id"`
+	UserID       string                `json:"user_id"`
+	Type         WalletTransactionType `json:"type"`
+	Amount       int64                 `json:"amount"`
+	BalanceAfter int64                 `json:"balance_after"`
+	OrderID      string                `json:"order_id,omitempty"`
+	PaymentID    string                `json:"payment_id,omitempty"` // The charge that paid for a top-up
+	Reference    string                `json:"reference"`            // Unique per user, so a retried change applies once
+	Description  string                `json:"description"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+// TableName returns the table name for the WalletTransaction model
+func (WalletTransaction) TableName() string {
+	return "wallet_transactions"
+}
+
+// PaymentMethodAmount is the part of the order's total its payment method pays, after
+// what the wallet paid
+func (o *Order) PaymentMethodAmount() int64 {
+	return o.TotalPrice - o.WalletAmount
+}
+
+// WalletReturnedOnCancel is what cancelling the order gave back to the wallet: what the
+// wallet paid, less the part of the cancellation fee it covered
+func (o *Order) WalletReturnedOnCancel() int64 {
+	if o.Status != StatusCancelled {
+		return 0
+	}
+	return o.WalletAmount - min(o.CancellationFee, o.WalletAmount)
+}
+
+// WalletRefundable is the part of what the wallet paid that a refund of the order gives
+// back to the wallet: all of it, unless cancelling the order already did
+func (o *Order) WalletRefundable() int64 {
+	if o.Status == StatusCancelled {
+		return 0
+	}
+	return o.WalletAmount
+}
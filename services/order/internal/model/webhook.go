@@ -0,0 +1,75 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/order-api-microservices/pkg/events"
+)
+
+// WebhookEventType is a kind of event partners can subscribe to
+type WebhookEventType string
+
+// Webhook event types, which are the types of the events sent
+const (
+	WebhookOrderCreated       WebhookEventType = events.TypeOrderCreated
+	WebhookOrderStatusChanged WebhookEventType = events.TypeOrderStatusChanged
+)
+
+// WebhookEventTypes lists every event type partners can subscribe to
+var WebhookEventTypes = []WebhookEventType{WebhookOrderCreated, WebhookOrderStatusChanged}
+
+// WebhookDeliveryStatus is where a webhook delivery is in its lifecycle
+type WebhookDeliveryStatus string
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING"   // Waiting for its first or next attempt
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "DELIVERED" // The partner answered with a 2xx
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "FAILED"    // Every attempt failed
+)
+
+// WebhookSubscription is a partner's callback URL and the events sent to it. Every
+// request is signed with the subscription's secret.
+type WebhookSubscription struct {
+	ID         string             `json:"id"`
+	PartnerID  string             `json:"partner_id"`
+	URL        string             `json:"url"`
+	Secret     string             `json:"-"`
+	EventTypes []WebhookEventType `json:"event_types"`
+	OrderTypes []OrderType        `json:"order_types"` // Only events about orders of these types; empty means all
+	Active     bool               `json:"active"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// TableName returns the table name for the WebhookSubscription model
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// WebhookDelivery is one event queued for one subscription, with the outcome of its attempts
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	SubscriptionID string                `json:"subscription_id"`
+	EventID        string                `json:"event_id"` // Shared by the deliveries of one event to every subscription
+	EventType      WebhookEventType      `json:"event_type"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at,omitempty"`
+	ResponseCode   int                   `json:"response_code,omitempty"`
+	LastError      string                `json:"last_error,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+
+	// Filled in when a delivery is claimed for sending
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// TableName returns the table name for the WebhookDelivery model
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
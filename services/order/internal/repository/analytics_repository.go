@@ -0,0 +1,562 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// analyticsDayFormat is how days are passed to and read from the analytics tables
+const analyticsDayFormat = "2006-01-02"
+
+// AnalyticsRepository handles database operations for the daily analytics aggregates
+type AnalyticsRepository struct {
+	db *database.PostgresDB
+}
+
+// NewAnalyticsRepository creates a new analytics repository
+func NewAnalyticsRepository(db *database.PostgresDB) *AnalyticsRepository {
+	return &AnalyticsRepository{
+		db: db,
+	}
+}
+
+// AggregateEvents folds up to limit unaggregated order events into the daily aggregates
+// and marks them aggregated, all in one transaction so every event is counted exactly
+// once. Events locked by another aggregator are skipped. It returns how many events it
+// aggregated.
+func (r *AnalyticsRepository) AggregateEvents(ctx context.Context, limit int) (int, error) {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT seq, order_id, event_type, order_type, city, provider_id, updated_by, status,
+		       previous_status, total_price, platform_fee, reason, sla_stage, created_at
+		FROM order_events
+		WHERE aggregated_at IS NULL
+		ORDER BY seq
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query order events: %w", err)
+	}
+
+	var events []*model.OrderEvent
+	for rows.Next() {
+		event := &model.OrderEvent{}
+		err := rows.Scan(
+			&event.Seq,
+			&event.OrderID,
+			&event.EventType,
+			&event.OrderType,
+			&event.City,
+			&event.ProviderID,
+			&event.UpdatedBy,
+			&event.Status,
+			&event.PreviousStatus,
+			&event.TotalPrice,
+			&event.PlatformFee,
+			&event.Reason,
+			&event.SLAStage,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan order event: %w", err)
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating order events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	seqs := make([]int64, 0, len(events))
+	for _, event := range events {
+		if err := aggregateEventTx(ctx, tx, event); err != nil {
+			return 0, err
+		}
+		seqs = append(seqs, event.Seq)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE order_events SET aggregated_at = $2 WHERE seq = ANY($1)`, seqs, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark order events aggregated: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(events), nil
+}
+
+// GetDailyMetrics gets the daily aggregates matching filter, summed over whatever
+// groupBy leaves out, ordered by day, city and order type
+func (r *AnalyticsRepository) GetDailyMetrics(ctx context.Context, filter model.AnalyticsFilter, groupBy model.AnalyticsGroupBy) ([]*model.DailyMetrics, error) {
+	day, city, orderType := "''", "''", "''"
+	switch groupBy {
+	case model.AnalyticsGroupByDay:
+		day = "day"
+	case model.AnalyticsGroupByCity:
+		city = "city"
+	case model.AnalyticsGroupByOrderType:
+		orderType = "order_type"
+	default:
+		day, city, orderType = "day", "city", "order_type"
+	}
+
+	var groups []string
+	for _, column := range []string{day, city, orderType} {
+		if column != "''" {
+			groups = append(groups, column)
+		}
+	}
+	dayColumn := day
+	if day == "day" {
+		dayColumn = "to_char(day, 'YYYY-MM-DD')"
+	}
+
+	where, args := analyticsWhere(filter)
+	query := fmt.Sprintf(`
+		SELECT %s, %s, %s,
+		       SUM(orders_created)::BIGINT, SUM(orders_completed)::BIGINT, SUM(orders_cancelled)::BIGINT,
+		       SUM(revenue)::BIGINT, SUM(platform_revenue)::BIGINT, SUM(eta_error_minutes_sum), SUM(eta_samples)::BIGINT
+		FROM analytics_daily%s
+		GROUP BY %s
+		ORDER BY %s
+	`, dayColumn, city, orderType, where, strings.Join(groups, ", "), strings.Join(groups, ", "))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily metrics: %w", err)
+	}
+	defer rows.Close()
+
+	metrics := []*model.DailyMetrics{}
+	for rows.Next() {
+		m := &model.DailyMetrics{}
+		err := rows.Scan(
+			&m.Day,
+			&m.City,
+			&m.OrderType,
+			&m.OrdersCreated,
+			&m.OrdersCompleted,
+			&m.OrdersCancelled,
+			&m.Revenue,
+			&m.PlatformRevenue,
+			&m.ETAErrorMinutesSum,
+			&m.ETASamples,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan daily metrics: %w", err)
+		}
+		metrics = append(metrics, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily metrics: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// ListCancellationReasons gets the most given cancellation reasons matching filter,
+// most given first
+func (r *AnalyticsRepository) ListCancellationReasons(ctx context.Context, filter model.AnalyticsFilter, limit int) ([]*model.CancellationReasonCount, error) {
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	where, args := analyticsWhere(filter)
+	query := fmt.Sprintf(`
+		SELECT reason, SUM(count)::BIGINT AS total
+		FROM analytics_cancellation_reasons%s
+		GROUP BY reason
+		ORDER BY total DESC, reason
+		LIMIT $%d
+	`, where, len(args)+1)
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cancellation reasons: %w", err)
+	}
+	defer rows.Close()
+
+	reasons := []*model.CancellationReasonCount{}
+	for rows.Next() {
+		reason := &model.CancellationReasonCount{}
+		if err := rows.Scan(&reason.Reason, &reason.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan cancellation reason: %w", err)
+		}
+		reasons = append(reasons, reason)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cancellation reasons: %w", err)
+	}
+
+	return reasons, nil
+}
+
+// GetSLAAttainment gets how often each SLA stage met its target in the days matching
+// filter, ordered by stage
+func (r *AnalyticsRepository) GetSLAAttainment(ctx context.Context, filter model.AnalyticsFilter) ([]*model.SLAAttainment, error) {
+	where, args := analyticsWhere(filter)
+	query := fmt.Sprintf(`
+		SELECT stage, SUM(met)::BIGINT, SUM(breached)::BIGINT
+		FROM analytics_sla_daily%s
+		GROUP BY stage
+		ORDER BY stage
+	`, where)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SLA attainment: %w", err)
+	}
+	defer rows.Close()
+
+	attainments := []*model.SLAAttainment{}
+	for rows.Next() {
+		attainment := &model.SLAAttainment{}
+		if err := rows.Scan(&attainment.Stage, &attainment.Met, &attainment.Breached); err != nil {
+			return nil, fmt.Errorf("failed to scan SLA attainment: %w", err)
+		}
+		attainments = append(attainments, attainment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating SLA attainment: %w", err)
+	}
+
+	return attainments, nil
+}
+
+// ListProviderQuality gets the quality of up to limit providers with IDs after
+// afterProviderID, ordered by ID, from the offers they were made, the days they served
+// orders and the SOS incidents users raised on their orders since since. Providers with
+// none of these in that time are left out.
+func (r *AnalyticsRepository) ListProviderQuality(ctx context.Context, since time.Time, afterProviderID string, limit int) ([]*model.ProviderQuality, error) {
+	query := `
+		WITH served AS (
+			SELECT provider_id,
+			       SUM(orders_completed)::BIGINT AS orders_completed,
+			       SUM(orders_abandoned)::BIGINT AS orders_abandoned,
+			       SUM(pickups_timed)::BIGINT AS pickups_timed,
+			       SUM(pickups_on_time)::BIGINT AS pickups_on_time
+			FROM provider_quality_daily
+			WHERE day >= $1::DATE AND provider_id > $3
+			GROUP BY provider_id
+		), offers AS (
+			SELECT provider_id,
+			       COUNT(*) FILTER (WHERE response = 'ACCEPTED') AS accepted,
+			       COUNT(*) FILTER (WHERE response = 'DECLINED') AS declined,
+			       COUNT(*) FILTER (WHERE response = 'EXPIRED') AS expired
+			FROM dispatch_offers
+			WHERE offered_at >= $2 AND provider_id > $3
+			  AND response IN ('ACCEPTED', 'DECLINED', 'EXPIRED')
+			GROUP BY provider_id
+		), sos AS (
+			SELECT ord.provider_id, COUNT(*) AS incidents
+			FROM incidents i
+			JOIN orders ord ON ord.id = i.order_id
+			WHERE i.created_at >= $2 AND i.reporter_role = 'USER' AND ord.provider_id > $3
+			GROUP BY ord.provider_id
+		)
+		SELECT COALESCE(o.provider_id, f.provider_id, s.provider_id) AS provider_id,
+		       COALESCE(f.accepted, 0), COALESCE(f.declined, 0), COALESCE(f.expired, 0),
+		       COALESCE(o.orders_completed, 0), COALESCE(o.orders_abandoned, 0),
+		       COALESCE(o.pickups_timed, 0), COALESCE(o.pickups_on_time, 0),
+		       COALESCE(s.incidents, 0)
+		FROM served o
+		FULL OUTER JOIN offers f ON f.provider_id = o.provider_id
+		FULL OUTER JOIN sos s ON s.provider_id = COALESCE(o.provider_id, f.provider_id)
+		ORDER BY provider_id
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, since.UTC().Format(analyticsDayFormat), since, afterProviderID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query provider quality: %w", err)
+	}
+	defer rows.Close()
+
+	var qualities []*model.ProviderQuality
+	for rows.Next() {
+		quality := &model.ProviderQuality{}
+		err := rows.Scan(
+			&quality.ProviderID,
+			&quality.Offers.Accepted,
+			&quality.Offers.Declined,
+			&quality.Offers.Expired,
+			&quality.OrdersCompleted,
+			&quality.OrdersAbandoned,
+			&quality.PickupsTimed,
+			&quality.PickupsOnTime,
+			&quality.Incidents,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan provider quality: %w", err)
+		}
+		qualities = append(qualities, quality)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating provider quality: %w", err)
+	}
+
+	return qualities, nil
+}
+
+// recordOrderEventTx records a domain event about an order within tx, so the event
+// exists if and only if the change it describes is committed
+func recordOrderEventTx(ctx context.Context, tx pgx.Tx, event *model.OrderEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	err := tx.QueryRow(ctx, `
+		INSERT INTO order_events (
+			order_id, event_type, order_type, city, provider_id, updated_by, status,
+			previous_status, total_price, platform_fee, reason, sla_stage, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING seq
+	`,
+		event.OrderID,
+		event.EventType,
+		event.OrderType,
+		event.City,
+		event.ProviderID,
+		event.UpdatedBy,
+		event.Status,
+		event.PreviousStatus,
+		event.TotalPrice,
+		event.PlatformFee,
+		event.Reason,
+		event.SLAStage,
+		event.CreatedAt,
+	).Scan(&event.Seq)
+	if err != nil {
+		return fmt.Errorf("failed to record order event: %w", err)
+	}
+
+	return nil
+}
+
+// aggregateEventTx adds an event to the aggregates of the day it happened on
+func aggregateEventTx(ctx context.Context, tx pgx.Tx, event *model.OrderEvent) error {
+	var delta model.DailyMetrics
+	var quality model.ProviderQuality
+	switch {
+	case event.EventType == model.OrderEventSLAMet || event.EventType == model.OrderEventSLABreached:
+		return aggregateSLATx(ctx, tx, event)
+	case event.EventType == model.OrderEventCreated:
+		delta.OrdersCreated = 1
+	case event.Status == model.StatusCompleted:
+		delta.OrdersCompleted = 1
+		delta.Revenue = event.TotalPrice
+		delta.PlatformRevenue = event.PlatformFee
+		quality.OrdersCompleted = 1
+	case event.Status == model.StatusCancelled:
+		delta.OrdersCancelled = 1
+		if event.ProviderID != "" && event.UpdatedBy == event.ProviderID && event.PreviousStatus.Taken() {
+			quality.OrdersAbandoned = 1
+		}
+	case event.ReachedPickup():
+		actual, predicted, ok, err := pickupETATx(ctx, tx, event)
+		if err != nil {
+			return err
+		}
+		if ok {
+			delta.ETAErrorMinutesSum = math.Abs(actual - predicted)
+			delta.ETASamples = 1
+			quality.PickupsTimed = 1
+			if actual <= predicted+model.OnTimePickupGrace.Minutes() {
+				quality.PickupsOnTime = 1
+			}
+		}
+	}
+
+	day := event.CreatedAt.UTC().Format(analyticsDayFormat)
+	if event.ProviderID != "" && quality != (model.ProviderQuality{}) {
+		if err := aggregateProviderQualityTx(ctx, tx, day, event.ProviderID, quality); err != nil {
+			return err
+		}
+	}
+
+	if delta == (model.DailyMetrics{}) {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO analytics_daily (
+			day, city, order_type, orders_created, orders_completed, orders_cancelled,
+			revenue, platform_revenue, eta_error_minutes_sum, eta_samples
+		) VALUES ($1::DATE, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (day, city, order_type) DO UPDATE SET
+			orders_created = analytics_daily.orders_created + EXCLUDED.orders_created,
+			orders_completed = analytics_daily.orders_completed + EXCLUDED.orders_completed,
+			orders_cancelled = analytics_daily.orders_cancelled + EXCLUDED.orders_cancelled,
+			revenue = analytics_daily.revenue + EXCLUDED.revenue,
+			platform_revenue = analytics_daily.platform_revenue + EXCLUDED.platform_revenue,
+			eta_error_minutes_sum = analytics_daily.eta_error_minutes_sum + EXCLUDED.eta_error_minutes_sum,
+			eta_samples = analytics_daily.eta_samples + EXCLUDED.eta_samples
+	`,
+		day,
+		event.City,
+		event.OrderType,
+		delta.OrdersCreated,
+		delta.OrdersCompleted,
+		delta.OrdersCancelled,
+		delta.Revenue,
+		delta.PlatformRevenue,
+		delta.ETAErrorMinutesSum,
+		delta.ETASamples,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update daily metrics: %w", err)
+	}
+
+	if delta.OrdersCancelled == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO analytics_cancellation_reasons (day, city, order_type, reason, count)
+		VALUES ($1::DATE, $2, $3, $4, 1)
+		ON CONFLICT (day, city, order_type, reason) DO UPDATE SET
+			count = analytics_cancellation_reasons.count + 1
+	`, day, event.City, event.OrderType, model.NormalizeCancellationReason(event.Reason))
+	if err != nil {
+		return fmt.Errorf("failed to update cancellation reasons: %w", err)
+	}
+
+	return nil
+}
+
+// aggregateProviderQualityTx adds a provider's share of an event to their quality
+// aggregates of the day it happened on
+func aggregateProviderQualityTx(ctx context.Context, tx pgx.Tx, day, providerID string, delta model.ProviderQuality) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO provider_quality_daily (
+			day, provider_id, orders_completed, orders_abandoned, pickups_timed, pickups_on_time
+		) VALUES ($1::DATE, $2, $3, $4, $5, $6)
+		ON CONFLICT (day, provider_id) DO UPDATE SET
+			orders_completed = provider_quality_daily.orders_completed + EXCLUDED.orders_completed,
+			orders_abandoned = provider_quality_daily.orders_abandoned + EXCLUDED.orders_abandoned,
+			pickups_timed = provider_quality_daily.pickups_timed + EXCLUDED.pickups_timed,
+			pickups_on_time = provider_quality_daily.pickups_on_time + EXCLUDED.pickups_on_time
+	`,
+		day,
+		providerID,
+		delta.OrdersCompleted,
+		delta.OrdersAbandoned,
+		delta.PickupsTimed,
+		delta.PickupsOnTime,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update provider quality: %w", err)
+	}
+
+	return nil
+}
+
+// aggregateSLATx adds an SLA event to the attainment of its stage on the day it happened on
+func aggregateSLATx(ctx context.Context, tx pgx.Tx, event *model.OrderEvent) error {
+	var met, breached int64
+	if event.EventType == model.OrderEventSLAMet {
+		met = 1
+	} else {
+		breached = 1
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO analytics_sla_daily (day, city, order_type, stage, met, breached)
+		VALUES ($1::DATE, $2, $3, $4, $5, $6)
+		ON CONFLICT (day, city, order_type, stage) DO UPDATE SET
+			met = analytics_sla_daily.met + EXCLUDED.met,
+			breached = analytics_sla_daily.breached + EXCLUDED.breached
+	`,
+		event.CreatedAt.UTC().Format(analyticsDayFormat),
+		event.City,
+		event.OrderType,
+		event.SLAStage,
+		met,
+		breached,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update SLA attainment: %w", err)
+	}
+
+	return nil
+}
+
+// pickupETATx is how many minutes the provider chosen by the order's latest dispatch
+// decision took to reach the pickup, and how many were predicted for them. It reports
+// false when the order was not auto-dispatched or no ETA was predicted.
+func pickupETATx(ctx context.Context, tx pgx.Tx, event *model.OrderEvent) (float64, float64, bool, error) {
+	var actual, predicted float64
+	err := tx.QueryRow(ctx, `
+		SELECT EXTRACT(EPOCH FROM ($2::TIMESTAMP - d.created_at))::DOUBLE PRECISION / 60,
+		       COALESCE((c->>'pickup_eta_minutes')::DOUBLE PRECISION, 0)
+		FROM dispatch_decisions d
+		CROSS JOIN LATERAL jsonb_array_elements(d.candidates) AS c
+		WHERE d.order_id = $1 AND c->>'provider_id' = d.selected_provider_id
+		ORDER BY d.created_at DESC
+		LIMIT 1
+	`, event.OrderID, event.CreatedAt).Scan(&actual, &predicted)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, 0, false, nil
+		}
+		return 0, 0, false, fmt.Errorf("failed to get predicted pickup ETA: %w", err)
+	}
+
+	if predicted <= 0 || actual < 0 {
+		return 0, 0, false, nil
+	}
+
+	return actual, predicted, true, nil
+}
+
+// analyticsWhere builds the conditions and arguments that narrow an analytics table to filter
+func analyticsWhere(filter model.AnalyticsFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if !filter.From.IsZero() {
+		addCondition("day >= $%d::DATE", filter.From.Format(analyticsDayFormat))
+	}
+	if !filter.To.IsZero() {
+		addCondition("day <= $%d::DATE", filter.To.Format(analyticsDayFormat))
+	}
+	if filter.City != "" {
+		addCondition("city = $%d", filter.City)
+	}
+	if filter.OrderType != "" {
+		addCondition("order_type = $%d", filter.OrderType)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/crypto"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const bulkOrderJobColumns = `j.id, j.customer_id, j.source, j.status, j.total_rows, j.created_at, j.started_at, j.completed_at`
+
+// bulkOrderJobCounts counts a job's rows by result, alongside bulkOrderJobColumns
+const bulkOrderJobCounts = `
+	COUNT(r.row_number) FILTER (WHERE r.status IN ('PENDING', 'PROCESSING')),
+	COUNT(r.row_number) FILTER (WHERE r.status = 'CREATED'),
+	COUNT(r.row_number) FILTER (WHERE r.status = 'FAILED'),
+	COUNT(r.row_number) FILTER (WHERE r.status = 'INVALID')`
+
+// BulkOrderRepository handles database operations for bulk order imports. The order
+// request of each row holds addresses and notes, so it is encrypted like an order's and
+// cleared once the row is processed.
+type BulkOrderRepository struct {
+	db   *database.PostgresDB
+	keys *crypto.KeyRing
+}
+
+// NewBulkOrderRepository creates a new bulk order repository. A nil key ring stores row
+// requests in plaintext.
+func NewBulkOrderRepository(db *database.PostgresDB, keys *crypto.KeyRing) *BulkOrderRepository {
+	return &BulkOrderRepository{
+		db:   db,
+		keys: keys,
+	}
+}
+
+// CreateJob stores a job along with all of its rows
+func (r *BulkOrderRepository) CreateJob(ctx context.Context, job *model.BulkOrderJob, rows []*model.BulkOrderRow) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO bulk_order_jobs (id, customer_id, source, status, total_rows, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, job.ID, job.CustomerID, job.Source, job.Status, job.TotalRows, job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create bulk order job: %w", err)
+	}
+
+	for _, row := range rows {
+		request, err := r.keys.Encrypt(row.Request)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt row %d: %w", row.RowNumber, err)
+		}
+		errs := row.Errors
+		if errs == nil {
+			errs = []string{}
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO bulk_order_rows (job_id, row_number, request, status, errors, processed_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, job.ID, row.RowNumber, request, row.Status, errs, row.ProcessedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create bulk order row %d: %w", row.RowNumber, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetJob gets a job with its row counts
+func (r *BulkOrderRepository) GetJob(ctx context.Context, jobID string) (*model.BulkOrderJob, error) {
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM bulk_order_jobs j
+		LEFT JOIN bulk_order_rows r ON r.job_id = j.id
+		WHERE j.id = $1
+		GROUP BY j.id
+	`, bulkOrderJobColumns, bulkOrderJobCounts)
+
+	job := &model.BulkOrderJob{}
+	err := r.db.QueryRowContext(ctx, query, jobID).Scan(
+		&job.ID,
+		&job.CustomerID,
+		&job.Source,
+		&job.Status,
+		&job.TotalRows,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.CompletedAt,
+		&job.PendingRows,
+		&job.CreatedRows,
+		&job.FailedRows,
+		&job.InvalidRows,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrBulkOrderJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get bulk order job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ListRows lists a job's rows in upload order, optionally only those with one result,
+// along with how many match in total. Row requests are not loaded.
+func (r *BulkOrderRepository) ListRows(ctx context.Context, jobID string, status model.BulkOrderRowStatus, page, limit int) ([]*model.BulkOrderRow, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM bulk_order_rows
+		WHERE job_id = $1 AND ($2 = '' OR status = $2)
+	`, jobID, status).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count bulk order rows: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT job_id, row_number, '', status, COALESCE(order_id, ''), errors, processed_at
+		FROM bulk_order_rows
+		WHERE job_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY row_number
+		LIMIT $3 OFFSET $4
+	`, jobID, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query bulk order rows: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := scanBulkOrderRows(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+// ClaimJob picks the oldest job waiting to be processed, or one whose importer stopped
+// renewing its lease, and leases it until leaseUntil. It returns nil when there is none.
+func (r *BulkOrderRepository) ClaimJob(ctx context.Context, now, leaseUntil time.Time) (*model.BulkOrderJob, error) {
+	query := `
+		UPDATE bulk_order_jobs j
+		SET status = $1, started_at = COALESCE(j.started_at, $3), lease_until = $4
+		WHERE j.id = (
+			SELECT id FROM bulk_order_jobs
+			WHERE status = $2 OR (status = $1 AND lease_until < $3)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + bulkOrderJobColumns
+
+	job := &model.BulkOrderJob{}
+	err := r.db.QueryRowContext(ctx, query, model.BulkJobProcessing, model.BulkJobPending, now, leaseUntil).Scan(
+		&job.ID,
+		&job.CustomerID,
+		&job.Source,
+		&job.Status,
+		&job.TotalRows,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.CompletedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim bulk order job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ExtendLease keeps a job leased to the importer processing it
+func (r *BulkOrderRepository) ExtendLease(ctx context.Context, jobID string, leaseUntil time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE bulk_order_jobs SET lease_until = $2 WHERE id = $1`, jobID, leaseUntil)
+	if err != nil {
+		return fmt.Errorf("failed to extend bulk order job lease: %w", err)
+	}
+	return nil
+}
+
+// FailInterruptedRows fails the rows an earlier importer started but never recorded a
+// result for. Their orders may or may not have been created, so they are not retried.
+func (r *BulkOrderRepository) FailInterruptedRows(ctx context.Context, jobID string, now time.Time) (int64, error) {
+	tag, err := r.db.ExecContext(ctx, `
+		UPDATE bulk_order_rows
+		SET status = $2, request = '', processed_at = $3,
+		    errors = ARRAY['import was interrupted; check the user''s orders before submitting this row again']
+		WHERE job_id = $1 AND status = $4
+	`, jobID, model.BulkRowFailed, now, model.BulkRowProcessing)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted bulk order rows: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// NextPendingRows gets up to limit of a job's unprocessed rows in upload order, with
+// their requests decrypted
+func (r *BulkOrderRepository) NextPendingRows(ctx context.Context, jobID string, limit int) ([]*model.BulkOrderRow, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT job_id, row_number, request, status, COALESCE(order_id, ''), errors, processed_at
+		FROM bulk_order_rows
+		WHERE job_id = $1 AND status = $2
+		ORDER BY row_number
+		LIMIT $3
+	`, jobID, model.BulkRowPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bulk order rows: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := scanBulkOrderRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range result {
+		if row.Request, err = r.keys.Decrypt(row.Request); err != nil {
+			return nil, fmt.Errorf("failed to decrypt row %d: %w", row.RowNumber, err)
+		}
+	}
+
+	return result, nil
+}
+
+// MarkRowProcessing records that a row's order is about to be created
+func (r *BulkOrderRepository) MarkRowProcessing(ctx context.Context, jobID string, rowNumber int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE bulk_order_rows SET status = $3
+		WHERE job_id = $1 AND row_number = $2
+	`, jobID, rowNumber, model.BulkRowProcessing)
+	if err != nil {
+		return fmt.Errorf("failed to mark bulk order row processing: %w", err)
+	}
+	return nil
+}
+
+// CompleteRow records a row's result and clears its request
+func (r *BulkOrderRepository) CompleteRow(ctx context.Context, row *model.BulkOrderRow) error {
+	errs := row.Errors
+	if errs == nil {
+		errs = []string{}
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE bulk_order_rows
+		SET status = $3, order_id = NULLIF($4, ''), errors = $5, processed_at = $6, request = ''
+		WHERE job_id = $1 AND row_number = $2
+	`, row.JobID, row.RowNumber, row.Status, row.OrderID, errs, row.ProcessedAt)
+	if err != nil {
+		return fmt.Errorf("failed to complete bulk order row: %w", err)
+	}
+	return nil
+}
+
+// CompleteJob marks a job whose rows all have a result as completed
+func (r *BulkOrderRepository) CompleteJob(ctx context.Context, jobID string, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE bulk_order_jobs
+		SET status = $2, completed_at = $3, lease_until = NULL
+		WHERE id = $1
+	`, jobID, model.BulkJobCompleted, now)
+	if err != nil {
+		return fmt.Errorf("failed to complete bulk order job: %w", err)
+	}
+	return nil
+}
+
+func scanBulkOrderRows(rows pgx.Rows) ([]*model.BulkOrderRow, error) {
+	result := []*model.BulkOrderRow{}
+	for rows.Next() {
+		row := &model.BulkOrderRow{}
+		err := rows.Scan(
+			&row.JobID,
+			&row.RowNumber,
+			&row.Request,
+			&row.Status,
+			&row.OrderID,
+			&row.Errors,
+			&row.ProcessedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bulk order row: %w", err)
+		}
+		result = append(result, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bulk order rows: %w", err)
+	}
+
+	return result, nil
+}
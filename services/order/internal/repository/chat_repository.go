@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const chatMessageColumns = `id, order_id, sender_id, sender_role, body, read_at, created_at, updated_at`
+
+// ChatRepository handles database operations for chat messages
+type ChatRepository struct {
+	db *database.PostgresDB
+}
+
+// NewChatRepository creates a new chat repository
+func NewChatRepository(db *database.PostgresDB) *ChatRepository {
+	return &ChatRepository{
+		db: db,
+	}
+}
+
+// CreateMessage stores a chat message
+func (r *ChatRepository) CreateMessage(ctx context.Context, message *model.ChatMessage) error {
+	query := `
+		INSERT INTO chat_messages (` + chatMessageColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		message.ID,
+		message.OrderID,
+		message.SenderID,
+		message.SenderRole,
+		message.Body,
+		message.ReadAt,
+		message.CreatedAt,
+		message.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create chat message: %w", err)
+	}
+
+	return nil
+}
+
+// ListMessages lists an order's chat messages with pagination, newest first
+func (r *ChatRepository) ListMessages(ctx context.Context, orderID string, page, limit int) ([]*model.ChatMessage, int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM chat_messages WHERE order_id = $1`, orderID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count chat messages: %w", err)
+	}
+
+	// Set reasonable defaults and boundaries
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	offset := (page - 1) * limit
+
+	query := `
+		SELECT ` + chatMessageColumns + `
+		FROM chat_messages
+		WHERE order_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	messages, err := r.queryMessages(ctx, query, orderID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
+
+// ListMessagesSince lists an order's chat messages sent or read after a time, oldest first
+func (r *ChatRepository) ListMessagesSince(ctx context.Context, orderID string, since time.Time) ([]*model.ChatMessage, error) {
+	query := `
+		SELECT ` + chatMessageColumns + `
+		FROM chat_messages
+		WHERE order_id = $1 AND updated_at > $2
+		ORDER BY updated_at, id
+	`
+
+	return r.queryMessages(ctx, query, orderID, since)
+}
+
+// ListUserOrderMessages lists the chat messages on every order of a user, archived orders
+// included, oldest first
+func (r *ChatRepository) ListUserOrderMessages(ctx context.Context, userID string) ([]*model.ChatMessage, error) {
+	query := `
+		SELECT ` + chatMessageColumns + `
+		FROM chat_messages
+		WHERE order_id IN (SELECT id FROM orders WHERE user_id = $1 UNION ALL SELECT id FROM orders_archive WHERE user_id = $1)
+		ORDER BY created_at, id
+	`
+
+	return r.queryMessages(ctx, query, userID)
+}
+
+// MarkRead marks the unread messages of an order that were not sent by the reader as read.
+// It returns how many messages were marked.
+func (r *ChatRepository) MarkRead(ctx context.Context, orderID, readerID string, at time.Time) (int, error) {
+	query := `
+		UPDATE chat_messages
+		SET read_at = $3, updated_at = $3
+		WHERE order_id = $1 AND sender_id <> $2 AND read_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, orderID, readerID, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark chat messages read: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
+
+func (r *ChatRepository) queryMessages(ctx context.Context, query string, args ...interface{}) ([]*model.ChatMessage, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*model.ChatMessage{}
+	for rows.Next() {
+		message, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+func scanChatMessage(row pgx.Row) (*model.ChatMessage, error) {
+	var message model.ChatMessage
+	err := row.Scan(
+		&message.ID,
+		&message.OrderID,
+		&message.SenderID,
+		&message.SenderRole,
+		&message.Body,
+		&message.ReadAt,
+		&message.CreatedAt,
+		&message.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
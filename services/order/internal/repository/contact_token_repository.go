@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// ContactTokenRepository handles database operations for masked contact tokens
+type ContactTokenRepository struct {
+	db *database.PostgresDB
+}
+
+// NewContactTokenRepository creates a new contact token repository
+func NewContactTokenRepository(db *database.PostgresDB) *ContactTokenRepository {
+	return &ContactTokenRepository{
+		db: db,
+	}
+}
+
+// CreateToken stores a contact token
+func (r *ContactTokenRepository) CreateToken(ctx context.Context, token *model.ContactToken) error {
+	query := `
+		INSERT INTO contact_tokens (id, order_id, participant_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		token.ID,
+		token.OrderID,
+		token.ParticipantID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create contact token: %w", err)
+	}
+
+	return nil
+}
+
+// GetTokenByHash retrieves a contact token by the hash of its value
+func (r *ContactTokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*model.ContactToken, error) {
+	query := `
+		SELECT id, order_id, participant_id, token_hash, expires_at, revoked_at, created_at
+		FROM contact_tokens
+		WHERE token_hash = $1
+	`
+
+	var token model.ContactToken
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.OrderID,
+		&token.ParticipantID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrContactTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get contact token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// revokeContactTokensTx revokes every live contact token of an order
+func revokeContactTokensTx(ctx context.Context, tx pgx.Tx, orderID string, at time.Time) error {
+	query := `
+		UPDATE contact_tokens
+		SET revoked_at = $2
+		WHERE order_id = $1 AND revoked_at IS NULL
+	`
+	if _, err := tx.Exec(ctx, query, orderID, at); err != nil {
+		return fmt.Errorf("failed to revoke contact tokens: %w", err)
+	}
+	return nil
+}
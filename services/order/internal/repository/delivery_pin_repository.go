@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// DeliveryPINRepository handles database operations for delivery PINs
+type DeliveryPINRepository struct {
+	db *database.PostgresDB
+}
+
+// NewDeliveryPINRepository creates a new delivery PIN repository
+func NewDeliveryPINRepository(db *database.PostgresDB) *DeliveryPINRepository {
+	return &DeliveryPINRepository{
+		db: db,
+	}
+}
+
+// CreatePIN stores the delivery PIN of a new order
+func (r *DeliveryPINRepository) CreatePIN(ctx context.Context, pin *model.DeliveryPIN) error {
+	query := `
+		INSERT INTO delivery_pins (order_id, pin_hash, failed_attempts, issued_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		pin.OrderID,
+		pin.PINHash,
+		pin.FailedAttempts,
+		pin.IssuedAt,
+		pin.CreatedAt,
+		pin.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create delivery PIN: %w", err)
+	}
+
+	return nil
+}
+
+// GetPIN retrieves the delivery PIN of an order
+func (r *DeliveryPINRepository) GetPIN(ctx context.Context, orderID string) (*model.DeliveryPIN, error) {
+	query := `
+		SELECT order_id, pin_hash, failed_attempts, locked_until, issued_at, created_at, updated_at
+		FROM delivery_pins
+		WHERE order_id = $1
+	`
+
+	var pin model.DeliveryPIN
+	err := r.db.QueryRowContext(ctx, query, orderID).Scan(
+		&pin.OrderID,
+		&pin.PINHash,
+		&pin.FailedAttempts,
+		&pin.LockedUntil,
+		&pin.IssuedAt,
+		&pin.CreatedAt,
+		&pin.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrDeliveryPINNotFound
+		}
+		return nil, fmt.Errorf("failed to get delivery PIN: %w", err)
+	}
+
+	return &pin, nil
+}
+
+// ReplacePIN swaps an order's PIN for a newly issued one. Failed attempts and any
+// lockout carry over, so resending cannot be used to reset them.
+func (r *DeliveryPINRepository) ReplacePIN(ctx context.Context, orderID, pinHash string, at time.Time) error {
+	query := `
+		UPDATE delivery_pins
+		SET pin_hash = $2, issued_at = $3, updated_at = $3
+		WHERE order_id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, orderID, pinHash, at)
+	if err != nil {
+		return fmt.Errorf("failed to replace delivery PIN: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDeliveryPINNotFound
+	}
+
+	return nil
+}
+
+// VerifyPIN checks a submitted PIN hash against an order's PIN. A wrong PIN counts as a
+// failed attempt, and reaching maxAttempts locks the PIN for the lockout period. It
+// returns the attempts left before a lockout.
+func (r *DeliveryPINRepository) VerifyPIN(ctx context.Context, orderID, pinHash string, maxAttempts int, lockout time.Duration, at time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the PIN so concurrent guesses are all counted
+	var pin model.DeliveryPIN
+	err = tx.QueryRow(ctx, `
+		SELECT order_id, pin_hash, failed_attempts, locked_until
+		FROM delivery_pins
+		WHERE order_id = $1
+		FOR UPDATE
+	`, orderID).Scan(&pin.OrderID, &pin.PINHash, &pin.FailedAttempts, &pin.LockedUntil)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrDeliveryPINNotFound
+		}
+		return 0, fmt.Errorf("failed to get delivery PIN: %w", err)
+	}
+
+	if pin.Locked(at) {
+		return 0, ErrDeliveryPINLocked
+	}
+
+	// The attempt is committed even when verification fails
+	matched, remaining := pin.Attempt(pinHash, maxAttempts, lockout, at)
+	_, err = tx.Exec(ctx, `UPDATE delivery_pins SET failed_attempts = $2, locked_until = $3, updated_at = $4 WHERE order_id = $1`,
+		orderID, pin.FailedAttempts, pin.LockedUntil, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record delivery PIN attempt: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	switch {
+	case matched:
+		return remaining, nil
+	case remaining == 0:
+		return 0, ErrDeliveryPINLocked
+	default:
+		return remaining, ErrDeliveryPINMismatch
+	}
+}
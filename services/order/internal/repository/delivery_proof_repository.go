@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// DeliveryProofRepository handles database operations for proofs of delivery
+type DeliveryProofRepository struct {
+	db *database.PostgresDB
+}
+
+// NewDeliveryProofRepository creates a new delivery proof repository
+func NewDeliveryProofRepository(db *database.PostgresDB) *DeliveryProofRepository {
+	return &DeliveryProofRepository{
+		db: db,
+	}
+}
+
+// CompleteDelivery stores a proof of delivery and moves its order from ARRIVED to DELIVERED
+func (r *DeliveryProofRepository) CompleteDelivery(ctx context.Context, proof *model.DeliveryProof) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the order so two submissions cannot both deliver it
+	var currentStatus model.OrderStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, proof.OrderID).Scan(&currentStatus)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrOrderNotFound
+		}
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	if currentStatus != model.StatusArrived {
+		return ErrOrderNotArrived
+	}
+
+	query := `
+		INSERT INTO delivery_proofs (id, order_id, provider_id, photo_ref, signature_hash, proof_hash, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+	`
+	_, err = tx.Exec(ctx, query,
+		proof.ID,
+		proof.OrderID,
+		proof.ProviderID,
+		proof.PhotoRef,
+		proof.SignatureHash,
+		proof.ProofHash,
+		proof.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store delivery proof: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE orders SET delivery_proof_hash = $2 WHERE id = $1`, proof.OrderID, proof.ProofHash)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery proof on order: %w", err)
+	}
+
+	if err := updateOrderStatusTx(ctx, tx, proof.OrderID, model.StatusDelivered, proof.ProviderID, "Delivered with proof "+proof.ProofHash); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
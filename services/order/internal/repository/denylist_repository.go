@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const denylistEntryColumns = `id, kind, value, reason, created_by, created_at, expires_at, blocked_count, last_blocked_at, last_blocked_request`
+
+// DenylistRepository handles database operations for the users, API keys and networks
+// banned from the API
+type DenylistRepository struct {
+	db *database.PostgresDB
+}
+
+// NewDenylistRepository creates a new denylist repository
+func NewDenylistRepository(db *database.PostgresDB) *DenylistRepository {
+	return &DenylistRepository{
+		db: db,
+	}
+}
+
+// CreateEntry stores a new entry. An expired entry for the same value is replaced; an
+// active one makes it fail with ErrDenylistEntryExists.
+func (r *DenylistRepository) CreateEntry(ctx context.Context, entry *model.DenylistEntry) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM denylist_entries WHERE kind = $1 AND value = $2 AND expires_at <= $3`,
+		entry.Kind, entry.Value, entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to clear expired denylist entry: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO denylist_entries (id, kind, value, reason, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`,
+		entry.ID,
+		entry.Kind,
+		entry.Value,
+		entry.Reason,
+		entry.CreatedBy,
+		entry.CreatedAt,
+		entry.ExpiresAt,
+	)
+	if err != nil {
+		if database.IsUniqueViolation(err, "idx_denylist_entries_kind_value") {
+			return ErrDenylistEntryExists
+		}
+		return fmt.Errorf("failed to create denylist entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetEntry gets an entry by ID
+func (r *DenylistRepository) GetEntry(ctx context.Context, id string) (*model.DenylistEntry, error) {
+	query := fmt.Sprintf(`SELECT %s FROM denylist_entries WHERE id = $1`, denylistEntryColumns)
+
+	entry, err := scanDenylistEntry(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrDenylistEntryNotFound
+		}
+		return nil, fmt.Errorf("failed to get denylist entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// DeleteEntry removes an entry
+func (r *DenylistRepository) DeleteEntry(ctx context.Context, id string) error {
+	tag, err := r.db.ExecContext(ctx, `DELETE FROM denylist_entries WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete denylist entry: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDenylistEntryNotFound
+	}
+
+	return nil
+}
+
+// ListEntries lists the entries that apply at now, or every entry with includeExpired,
+// newest first
+func (r *DenylistRepository) ListEntries(ctx context.Context, now time.Time, includeExpired bool) ([]*model.DenylistEntry, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM denylist_entries
+		WHERE $2 OR expires_at IS NULL OR expires_at > $1
+		ORDER BY created_at DESC
+	`, denylistEntryColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, now, includeExpired)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query denylist entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*model.DenylistEntry{}
+	for rows.Next() {
+		entry, err := scanDenylistEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan denylist entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating denylist entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// RecordBlock counts a request refused because of an entry
+func (r *DenylistRepository) RecordBlock(ctx context.Context, id, request string, blockedAt time.Time) error {
+	tag, err := r.db.ExecContext(ctx, `
+		UPDATE denylist_entries
+		SET blocked_count = blocked_count + 1,
+		    last_blocked_at = $2,
+		    last_blocked_request = $3
+		WHERE id = $1
+	`, id, blockedAt, request)
+	if err != nil {
+		return fmt.Errorf("failed to record blocked request: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDenylistEntryNotFound
+	}
+
+	return nil
+}
+
+func scanDenylistEntry(row pgx.Row) (*model.DenylistEntry, error) {
+	entry := &model.DenylistEntry{}
+	err := row.Scan(
+		&entry.ID,
+		&entry.Kind,
+		&entry.Value,
+		&entry.Reason,
+		&entry.CreatedBy,
+		&entry.CreatedAt,
+		&entry.ExpiresAt,
+		&entry.BlockedCount,
+		&entry.LastBlockedAt,
+		&entry.LastBlockedRequest,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
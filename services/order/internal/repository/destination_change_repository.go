@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// CreateDestinationChange stores a pending destination change, with the new destination's
+// address and instructions encrypted like an order's. A change of the order still pending
+// from before lapsedBefore expires first; if one pending since later is left, it fails
+// with ErrDestinationChangePending.
+func (r *OrderRepository) CreateDestinationChange(ctx context.Context, change *model.DestinationChange, lapsedBefore time.Time) error {
+	destination := change.Destination
+	if err := r.encryptLocation(&destination); err != nil {
+		return fmt.Errorf("failed to encrypt destination: %w", err)
+	}
+
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			UPDATE destination_changes
+			SET status = $2, responded_at = $3
+			WHERE order_id = $1 AND status = $4 AND requested_at < $5
+		`, change.OrderID, model.DestinationChangeExpired, change.RequestedAt, model.DestinationChangePending, lapsedBefore)
+		if err != nil {
+			return fmt.Errorf("failed to expire destination changes: %w", err)
+		}
+
+		query := `
+			INSERT INTO destination_changes (id, order_id, destination_location, fare, status, requested_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (order_id) WHERE status = 'PENDING' DO NOTHING
+		`
+
+		tag, err := tx.Exec(ctx, query,
+			change.ID,
+			change.OrderID,
+			destination,
+			change.Fare,
+			change.Status,
+			change.RequestedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create destination change: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrDestinationChangePending
+		}
+		return nil
+	})
+}
+
+// GetDestinationChange gets a destination change by its ID
+func (r *OrderRepository) GetDestinationChange(ctx context.Context, changeID string) (*model.DestinationChange, error) {
+	query := `
+		SELECT id, order_id, destination_location, fare, status, COALESCE(payment_id, ''), requested_at, responded_at
+		FROM destination_changes
+		WHERE id = $1
+	`
+
+	change := &model.DestinationChange{}
+	err := r.db.QueryRowContext(ctx, query, changeID).Scan(
+		&change.ID,
+		&change.OrderID,
+		&change.Destination,
+		&change.Fare,
+		&change.Status,
+		&change.PaymentID,
+		&change.RequestedAt,
+		&change.RespondedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrDestinationChangeNotFound
+		}
+		return nil, fmt.Errorf("failed to get destination change: %w", err)
+	}
+	if err := r.decryptLocation(&change.Destination); err != nil {
+		return nil, fmt.Errorf("failed to decrypt destination of change %s: %w", change.ID, err)
+	}
+
+	return change, nil
+}
+
+// AcceptDestinationChange accepts a pending destination change, stores the order's new
+// destination, total and fees, given in order, and appends entry to its status history.
+// It fails with ErrDestinationChangeNotPending if the change was already answered, and
+// with ErrOrderStatusChanged if the order is no longer in transit or is frozen.
+func (r *OrderRepository) AcceptDestinationChange(ctx context.Context, change *model.DestinationChange, order *model.Order, entry model.StatusHistory) error {
+	defer r.invalidate(ctx, order.ID)
+
+	destination := order.DestinationLocation
+	if err := r.encryptLocation(&destination); err != nil {
+		return fmt.Errorf("failed to encrypt destination: %w", err)
+	}
+
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := respondDestinationChangeTx(ctx, tx, change); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `
+			UPDATE orders
+			SET destination_location = $3, total_price = $4, platform_fee = $5, provider_fee = $6,
+			    status_history = COALESCE(status_history, '[]'::JSONB) || $7::JSONB, updated_at = $8
+			WHERE id = $1 AND status = $2 AND NOT frozen
+		`, order.ID, model.StatusInTransit, destination, order.TotalPrice, order.PlatformFee, order.ProviderFee,
+			model.StatusHistories{entry}, entry.Timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to change order destination: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrOrderStatusChanged
+		}
+		return nil
+	})
+}
+
+// DeclineDestinationChange declines a pending destination change. It fails with
+// ErrDestinationChangeNotPending if the change was already answered.
+func (r *OrderRepository) DeclineDestinationChange(ctx context.Context, change *model.DestinationChange) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		return respondDestinationChangeTx(ctx, tx, change)
+	})
+}
+
+// respondDestinationChangeTx records the answer to a pending destination change within tx
+func respondDestinationChangeTx(ctx context.Context, tx pgx.Tx, change *model.DestinationChange) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE destination_changes
+		SET status = $2, payment_id = NULLIF($3, ''), responded_at = $4
+		WHERE id = $1 AND status = $5
+	`, change.ID, change.Status, change.PaymentID, change.RespondedAt, model.DestinationChangePending)
+	if err != nil {
+		return fmt.Errorf("failed to update destination change: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDestinationChangeNotPending
+	}
+
+	return nil
+}
@@ -0,0 +1,369 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// DispatchRepository handles database operations for dispatch weights and decisions
+type DispatchRepository struct {
+	db *database.PostgresDB
+}
+
+// NewDispatchRepository creates a new dispatch repository
+func NewDispatchRepository(db *database.PostgresDB) *DispatchRepository {
+	return &DispatchRepository{
+		db: db,
+	}
+}
+
+// GetWeights retrieves the dispatch weights, or the defaults if an admin never set any
+func (r *DispatchRepository) GetWeights(ctx context.Context) (model.DispatchWeights, error) {
+	query := `
+		SELECT distance_weight, rating_weight, idle_weight, earnings_weight, favorite_weight, updated_by, updated_at
+		FROM dispatch_weights
+		WHERE id = 1
+	`
+
+	var weights model.DispatchWeights
+	err := r.db.QueryRowContext(ctx, query).Scan(
+		&weights.Distance,
+		&weights.Rating,
+		&weights.Idle,
+		&weights.Earnings,
+		&weights.Favorite,
+		&weights.UpdatedBy,
+		&weights.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return model.DefaultDispatchWeights, nil
+		}
+		return model.DispatchWeights{}, fmt.Errorf("failed to get dispatch weights: %w", err)
+	}
+
+	return weights, nil
+}
+
+// SaveWeights replaces the dispatch weights
+func (r *DispatchRepository) SaveWeights(ctx context.Context, weights model.DispatchWeights) error {
+	query := `
+		INSERT INTO dispatch_weights (id, distance_weight, rating_weight, idle_weight, earnings_weight, favorite_weight, updated_by, updated_at)
+		VALUES (1, $1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE
+		SET distance_weight = EXCLUDED.distance_weight,
+		    rating_weight = EXCLUDED.rating_weight,
+		    idle_weight = EXCLUDED.idle_weight,
+		    earnings_weight = EXCLUDED.earnings_weight,
+		    favorite_weight = EXCLUDED.favorite_weight,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		weights.Distance,
+		weights.Rating,
+		weights.Idle,
+		weights.Earnings,
+		weights.Favorite,
+		weights.UpdatedBy,
+		weights.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save dispatch weights: %w", err)
+	}
+
+	return nil
+}
+
+// GetProviderActivity retrieves when each provider last had an order and what they have
+// earned since a time, keyed by provider ID
+func (r *DispatchRepository) GetProviderActivity(ctx context.Context, providerIDs []string, since time.Time) (map[string]model.ProviderActivity, error) {
+	query := `
+		SELECT p.id,
+		       (SELECT MAX(o.updated_at) FROM orders o WHERE o.provider_id = p.id),
+		       (SELECT COALESCE(SUM(l.amount), 0) FROM provider_ledger_entries l
+		        WHERE l.provider_id = p.id AND l.created_at >= $2)
+		FROM unnest($1::text[]) AS p(id)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, providerIDs, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query provider activity: %w", err)
+	}
+	defer rows.Close()
+
+	activity := make(map[string]model.ProviderActivity, len(providerIDs))
+	for rows.Next() {
+		var providerID string
+		var a model.ProviderActivity
+		if err := rows.Scan(&providerID, &a.LastOrderAt, &a.RecentEarnings); err != nil {
+			return nil, fmt.Errorf("failed to scan provider activity: %w", err)
+		}
+		activity[providerID] = a
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating provider activity: %w", err)
+	}
+
+	return activity, nil
+}
+
+// GetDispatchLatency measures the time from creating an order to its first dispatch
+// decision, over the orders first dispatched since a time
+func (r *DispatchRepository) GetDispatchLatency(ctx context.Context, since time.Time) (model.DispatchLatency, error) {
+	query := `
+		SELECT COUNT(*),
+		       COALESCE(AVG(latency), 0),
+		       COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY latency), 0),
+		       COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY latency), 0),
+		       COALESCE(MAX(latency), 0)
+		FROM (
+		    SELECT EXTRACT(EPOCH FROM (MIN(d.created_at) - o.created_at))::DOUBLE PRECISION AS latency
+		    FROM dispatch_decisions d
+		    JOIN orders o ON o.id = d.order_id
+		    WHERE d.created_at >= $1
+		    GROUP BY o.id, o.created_at
+		    HAVING NOT EXISTS (
+		        SELECT 1 FROM dispatch_decisions earlier
+		        WHERE earlier.order_id = o.id AND earlier.created_at < $1
+		    )
+		) AS latencies
+	`
+
+	latency := model.DispatchLatency{Window: time.Since(since)}
+	err := r.db.QueryRowContext(ctx, query, since).Scan(
+		&latency.Decisions,
+		&latency.AverageSeconds,
+		&latency.P50Seconds,
+		&latency.P95Seconds,
+		&latency.MaxSeconds,
+	)
+	if err != nil {
+		return latency, fmt.Errorf("failed to measure dispatch latency: %w", err)
+	}
+
+	return latency, nil
+}
+
+// CreateDecision stores a dispatch decision
+func (r *DispatchRepository) CreateDecision(ctx context.Context, decision *model.DispatchDecision) error {
+	query := `
+		INSERT INTO dispatch_decisions (id, order_id, selected_provider_id, auto_accepted, weights, candidates, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		decision.ID,
+		decision.OrderID,
+		decision.SelectedProviderID,
+		decision.AutoAccepted,
+		decision.Weights,
+		decision.Candidates,
+		decision.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create dispatch decision: %w", err)
+	}
+
+	return nil
+}
+
+// ListDecisions lists dispatch decisions, newest first, optionally filtered by order and
+// by the provider selected
+func (r *DispatchRepository) ListDecisions(ctx context.Context, orderID, providerID string, page, limit int) ([]*model.DispatchDecision, int, error) {
+	whereClause := " WHERE 1 = 1"
+	var args []interface{}
+
+	if orderID != "" {
+		args = append(args, orderID)
+		whereClause += fmt.Sprintf(" AND order_id = $%d", len(args))
+	}
+	if providerID != "" {
+		args = append(args, providerID)
+		whereClause += fmt.Sprintf(" AND selected_provider_id = $%d", len(args))
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM dispatch_decisions`+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dispatch decisions: %w", err)
+	}
+
+	// Set reasonable defaults and boundaries
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT id, order_id, selected_provider_id, auto_accepted, weights, candidates, created_at
+		FROM dispatch_decisions%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query dispatch decisions: %w", err)
+	}
+	defer rows.Close()
+
+	decisions := []*model.DispatchDecision{}
+	for rows.Next() {
+		var decision model.DispatchDecision
+		if err := rows.Scan(
+			&decision.ID,
+			&decision.OrderID,
+			&decision.SelectedProviderID,
+			&decision.AutoAccepted,
+			&decision.Weights,
+			&decision.Candidates,
+			&decision.CreatedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan dispatch decision: %w", err)
+		}
+		decisions = append(decisions, &decision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating dispatch decisions: %w", err)
+	}
+
+	return decisions, total, nil
+}
+
+// CreateOffer stores an order offered to a provider
+func (r *DispatchRepository) CreateOffer(ctx context.Context, offer *model.DispatchOffer) error {
+	query := `
+		INSERT INTO dispatch_offers (id, order_id, provider_id, offered_at, expires_at, response)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		offer.ID,
+		offer.OrderID,
+		offer.ProviderID,
+		offer.OfferedAt,
+		offer.ExpiresAt,
+		offer.Response,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create dispatch offer: %w", err)
+	}
+
+	return nil
+}
+
+// AcknowledgeOffer records when a provider's app first acknowledged an offer
+func (r *DispatchRepository) AcknowledgeOffer(ctx context.Context, offerID string, at time.Time) error {
+	query := `
+		UPDATE dispatch_offers
+		SET acknowledged_at = $2
+		WHERE id = $1 AND acknowledged_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, offerID, at); err != nil {
+		return fmt.Errorf("failed to acknowledge dispatch offer: %w", err)
+	}
+
+	return nil
+}
+
+// RespondToOffers records a provider's answer to their pending offers of an order, and
+// returns how many it answered
+func (r *DispatchRepository) RespondToOffers(ctx context.Context, orderID, providerID string, response model.OfferResponse, at time.Time) (int64, error) {
+	query := `
+		UPDATE dispatch_offers
+		SET response = $3, responded_at = $4
+		WHERE order_id = $1 AND provider_id = $2 AND response = 'PENDING'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, orderID, providerID, response, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record dispatch offer response: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// ExpireOffers closes up to limit pending offers that expired before a time. An offer
+// expires against the provider only if the order was still assigned to them and waiting
+// for them to accept; otherwise the order moved on without them and it is withdrawn.
+// Offers other callers are closing are skipped, so several instances can run it together.
+func (r *DispatchRepository) ExpireOffers(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		UPDATE dispatch_offers f
+		SET response = CASE
+		        WHEN EXISTS (
+		            SELECT 1 FROM orders o
+		            WHERE o.id = f.order_id AND o.provider_id = f.provider_id AND o.status = 'PROVIDER_ASSIGNED'
+		        ) THEN 'EXPIRED'
+		        ELSE 'WITHDRAWN'
+		    END,
+		    responded_at = f.expires_at
+		FROM (
+		    SELECT id FROM dispatch_offers
+		    WHERE response = 'PENDING' AND expires_at < $1
+		    ORDER BY expires_at
+		    LIMIT $2
+		    FOR UPDATE SKIP LOCKED
+		) AS due
+		WHERE f.id = due.id
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire dispatch offers: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// GetProviderOfferStats counts how each provider answered the offers made to them since
+// a time, keyed by provider ID. Providers with no answered offers are left out.
+func (r *DispatchRepository) GetProviderOfferStats(ctx context.Context, providerIDs []string, since time.Time) (map[string]model.ProviderOfferStats, error) {
+	query := `
+		SELECT provider_id,
+		       COUNT(*) FILTER (WHERE response = 'ACCEPTED'),
+		       COUNT(*) FILTER (WHERE response = 'DECLINED'),
+		       COUNT(*) FILTER (WHERE response = 'EXPIRED')
+		FROM dispatch_offers
+		WHERE provider_id = ANY($1) AND offered_at >= $2
+		  AND response IN ('ACCEPTED', 'DECLINED', 'EXPIRED')
+		GROUP BY provider_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, providerIDs, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dispatch offer stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]model.ProviderOfferStats, len(providerIDs))
+	for rows.Next() {
+		var providerID string
+		var s model.ProviderOfferStats
+		if err := rows.Scan(&providerID, &s.Accepted, &s.Declined, &s.Expired); err != nil {
+			return nil, fmt.Errorf("failed to scan dispatch offer stats: %w", err)
+		}
+		stats[providerID] = s
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dispatch offer stats: %w", err)
+	}
+
+	return stats, nil
+}
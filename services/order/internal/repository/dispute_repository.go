@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const disputeColumns = `
+	id, order_id, opened_by, opened_by_role, reason, COALESCE(description, ''),
+	status, COALESCE(resolution, ''), refund_amount, COALESCE(resolved_by, ''),
+	COALESCE(resolution_notes, ''), created_at, updated_at, resolved_at
+`
+
+// DisputeRepository handles database operations for disputes, their evidence and payment holds
+type DisputeRepository struct {
+	db *database.PostgresDB
+}
+
+// NewDisputeRepository creates a new dispute repository
+func NewDisputeRepository(db *database.PostgresDB) *DisputeRepository {
+	return &DisputeRepository{
+		db: db,
+	}
+}
+
+// OpenDispute creates a dispute and its payment hold, and marks the order as disputed
+func (r *DisputeRepository) OpenDispute(ctx context.Context, dispute *model.Dispute, hold *model.PaymentHold) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM disputes WHERE order_id = $1 AND status = $2)`,
+		dispute.OrderID, model.DisputeOpen).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check open disputes: %w", err)
+	}
+	if exists {
+		return ErrDisputeAlreadyOpen
+	}
+
+	insertDispute := `
+		INSERT INTO disputes (
+			id, order_id, opened_by, opened_by_role, reason, description,
+			status, refund_amount, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err = tx.Exec(ctx, insertDispute,
+		dispute.ID,
+		dispute.OrderID,
+		dispute.OpenedBy,
+		dispute.OpenedByRole,
+		dispute.Reason,
+		dispute.Description,
+		dispute.Status,
+		dispute.RefundAmount,
+		dispute.CreatedAt,
+		dispute.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create dispute: %w", err)
+	}
+
+	insertHold := `
+		INSERT INTO payment_holds (id, order_id, dispute_id, amount, refunded_amount, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = tx.Exec(ctx, insertHold,
+		hold.ID,
+		hold.OrderID,
+		hold.DisputeID,
+		hold.Amount,
+		hold.RefundedAmount,
+		hold.Status,
+		hold.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create payment hold: %w", err)
+	}
+
+	notes := fmt.Sprintf("Dispute %s opened: %s", dispute.ID, dispute.Reason)
+	if err := updateOrderStatusTx(ctx, tx, dispute.OrderID, model.StatusDisputed, dispute.OpenedBy, notes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetDispute gets a dispute by its ID, including its evidence and payment hold
+func (r *DisputeRepository) GetDispute(ctx context.Context, disputeID string) (*model.Dispute, error) {
+	query := fmt.Sprintf(`SELECT %s FROM disputes WHERE id = $1`, disputeColumns)
+
+	dispute, err := scanDispute(r.db.QueryRowContext(ctx, query, disputeID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrDisputeNotFound
+		}
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+
+	dispute.Evidence, err = r.listEvidence(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+
+	dispute.PaymentHold, err = r.getHold(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return dispute, nil
+}
+
+// ListDisputes lists disputes, newest first, optionally filtered by order and status
+func (r *DisputeRepository) ListDisputes(ctx context.Context, orderID string, status model.DisputeStatus, page, limit int) ([]*model.Dispute, int, error) {
+	whereClause := " WHERE 1 = 1"
+	var args []interface{}
+
+	if orderID != "" {
+		args = append(args, orderID)
+		whereClause += fmt.Sprintf(" AND order_id = $%d", len(args))
+	}
+	if status != "" {
+		args = append(args, status)
+		whereClause += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM disputes`+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count disputes: %w", err)
+	}
+
+	// Set reasonable defaults and boundaries
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM disputes%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, disputeColumns, whereClause, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query disputes: %w", err)
+	}
+	defer rows.Close()
+
+	disputes := []*model.Dispute{}
+	for rows.Next() {
+		dispute, err := scanDispute(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan dispute: %w", err)
+		}
+		disputes = append(disputes, dispute)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating disputes: %w", err)
+	}
+
+	return disputes, total, nil
+}
+
+// AddEvidence attaches evidence to an open dispute
+func (r *DisputeRepository) AddEvidence(ctx context.Context, evidence *model.DisputeEvidence) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the dispute so evidence cannot race with its resolution
+	var status model.DisputeStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM disputes WHERE id = $1 FOR UPDATE`, evidence.DisputeID).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrDisputeNotFound
+		}
+		return fmt.Errorf("failed to get dispute: %w", err)
+	}
+	if status != model.DisputeOpen {
+		return ErrDisputeNotOpen
+	}
+
+	query := `
+		INSERT INTO dispute_evidence (id, dispute_id, submitted_by, role, description, attachment_url, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = tx.Exec(ctx, query,
+		evidence.ID,
+		evidence.DisputeID,
+		evidence.SubmittedBy,
+		evidence.Role,
+		evidence.Description,
+		evidence.AttachmentURL,
+		evidence.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add dispute evidence: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE disputes SET updated_at = $2 WHERE id = $1`, evidence.DisputeID, evidence.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update dispute: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveDispute closes an open dispute, settles its payment hold, stores its refund, if any,
+// and moves the order to orderStatus
+func (r *DisputeRepository) ResolveDispute(ctx context.Context, dispute *model.Dispute, holdStatus model.HoldStatus, orderStatus model.OrderStatus, refund *model.Refund) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	updateDispute := `
+		UPDATE disputes
+		SET status = $2, resolution = $3, refund_amount = $4, resolved_by = $5,
+			resolution_notes = $6, updated_at = $7, resolved_at = $7
+		WHERE id = $1 AND status = $8
+	`
+	tag, err := tx.Exec(ctx, updateDispute,
+		dispute.ID,
+		model.DisputeResolved,
+		dispute.Resolution,
+		dispute.RefundAmount,
+		dispute.ResolvedBy,
+		dispute.ResolutionNotes,
+		now,
+		model.DisputeOpen,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resolve dispute: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDisputeNotOpen
+	}
+
+	updateHold := `
+		UPDATE payment_holds
+		SET status = $2, refunded_amount = $3, released_at = $4
+		WHERE dispute_id = $1
+	`
+	_, err = tx.Exec(ctx, updateHold, dispute.ID, holdStatus, dispute.RefundAmount, now)
+	if err != nil {
+		return fmt.Errorf("failed to settle payment hold: %w", err)
+	}
+
+	if refund != nil {
+		if err := insertRefundTx(ctx, tx, refund); err != nil {
+			return err
+		}
+	}
+
+	notes := fmt.Sprintf("Dispute %s resolved: %s", dispute.ID, dispute.Resolution)
+	if err := updateOrderStatusTx(ctx, tx, dispute.OrderID, orderStatus, dispute.ResolvedBy, notes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DisputeRepository) listEvidence(ctx context.Context, disputeID string) ([]*model.DisputeEvidence, error) {
+	query := `
+		SELECT id, dispute_id, submitted_by, role, description, COALESCE(attachment_url, ''), created_at
+		FROM dispute_evidence
+		WHERE dispute_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dispute evidence: %w", err)
+	}
+	defer rows.Close()
+
+	evidence := []*model.DisputeEvidence{}
+	for rows.Next() {
+		e := &model.DisputeEvidence{}
+		err := rows.Scan(&e.ID, &e.DisputeID, &e.SubmittedBy, &e.Role, &e.Description, &e.AttachmentURL, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dispute evidence: %w", err)
+		}
+		evidence = append(evidence, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dispute evidence: %w", err)
+	}
+
+	return evidence, nil
+}
+
+func (r *DisputeRepository) getHold(ctx context.Context, disputeID string) (*model.PaymentHold, error) {
+	query := `
+		SELECT id, order_id, dispute_id, amount, refunded_amount, status, created_at, released_at
+		FROM payment_holds
+		WHERE dispute_id = $1
+	`
+
+	hold := &model.PaymentHold{}
+	err := r.db.QueryRowContext(ctx, query, disputeID).Scan(
+		&hold.ID,
+		&hold.OrderID,
+		&hold.DisputeID,
+		&hold.Amount,
+		&hold.RefundedAmount,
+		&hold.Status,
+		&hold.CreatedAt,
+		&hold.ReleasedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get payment hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+func scanDispute(row pgx.Row) (*model.Dispute, error) {
+	dispute := &model.Dispute{}
+	err := row.Scan(
+		&dispute.ID,
+		&dispute.OrderID,
+		&dispute.OpenedBy,
+		&dispute.OpenedByRole,
+		&dispute.Reason,
+		&dispute.Description,
+		&dispute.Status,
+		&dispute.Resolution,
+		&dispute.RefundAmount,
+		&dispute.ResolvedBy,
+		&dispute.ResolutionNotes,
+		&dispute.CreatedAt,
+		&dispute.UpdatedAt,
+		&dispute.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
@@ -1,6 +1,9 @@
 package repository
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrOrderNotFound is returned when an order is not found
@@ -9,6 +12,181 @@ var (
 	// ErrOrderLocationNotFound is returned when an order location is not found
 	ErrOrderLocationNotFound = errors.New("order location not found")
 	
-	// ErrDuplicateOrder is returned when attempting to create an order with an ID that already exists
+	// ErrOrderTrackNotFound is returned when an order has no archived track
+	ErrOrderTrackNotFound = errors.New("order track not found")
+	
+	// ErrDuplicateOrder is returned when attempting to create an order with an ID that already exists,
+	// or one that repeats an order the user has just placed
 	ErrDuplicateOrder = errors.New("duplicate order")
-) 
\ No newline at end of file
+	
+	// ErrDisputeNotFound is returned when a dispute is not found
+	ErrDisputeNotFound = errors.New("dispute not found")
+	
+	// ErrDisputeAlreadyOpen is returned when an order already has an open dispute
+	ErrDisputeAlreadyOpen = errors.New("order already has an open dispute")
+	
+	// ErrDisputeNotOpen is returned when a resolved dispute is modified
+	ErrDisputeNotOpen = errors.New("dispute is not open")
+	
+	// ErrTipAlreadyAdded is returned when an order has already been tipped
+	ErrTipAlreadyAdded = errors.New("order has already been tipped")
+	
+	// ErrOrderNotArrived is returned when an order is delivered before its provider has arrived
+	ErrOrderNotArrived = errors.New("order has not arrived")
+	
+	// ErrOrderFrozen is returned when the status of an order under safety review is changed
+	ErrOrderFrozen = errors.New("order is frozen for review")
+	
+	// ErrOrderStatusChanged is returned when an order is no longer in the status a change was made from
+	ErrOrderStatusChanged = errors.New("order status has changed")
+	
+	// ErrDeliveryPINNotFound is returned when an order has no delivery PIN
+	ErrDeliveryPINNotFound = errors.New("delivery PIN not found")
+	
+	// ErrDeliveryPINMismatch is returned when a submitted delivery PIN is wrong
+	ErrDeliveryPINMismatch = errors.New("delivery PIN does not match")
+	
+	// ErrDeliveryPINLocked is returned when too many wrong delivery PINs were submitted
+	ErrDeliveryPINLocked = errors.New("delivery PIN is locked")
+	
+	// ErrContactTokenNotFound is returned when a contact token is not found
+	ErrContactTokenNotFound = errors.New("contact token not found")
+	
+	// ErrTrackingLinkNotFound is returned when a tracking link is not found
+	ErrTrackingLinkNotFound = errors.New("tracking link not found")
+	
+	// ErrIncidentNotFound is returned when a safety incident is not found
+	ErrIncidentNotFound = errors.New("incident not found")
+	
+	// ErrIncidentNotOpen is returned when a resolved incident is resolved again
+	ErrIncidentNotOpen = errors.New("incident is not open")
+	
+	// ErrRouteDeviationNotFound is returned when an order has no active route deviation
+	ErrRouteDeviationNotFound = errors.New("route deviation not found")
+	
+	// ErrFeeRuleNotFound is returned when a fee rule is not found
+	ErrFeeRuleNotFound = errors.New("fee rule not found")
+	
+	// ErrFeeRuleExists is returned when a fee rule already exists for an order type and city
+	ErrFeeRuleExists = errors.New("fee rule already exists for this order type and city")
+	
+	// ErrFeeWaiverNotFound is returned when a fee waiver is not found
+	ErrFeeWaiverNotFound = errors.New("fee waiver not found")
+	
+	// ErrServiceAreaNotFound is returned when a service area is not found
+	ErrServiceAreaNotFound = errors.New("service area not found")
+	
+	// ErrMerchantNotFound is returned when a merchant is not found
+	ErrMerchantNotFound = errors.New("merchant not found")
+	
+	// ErrCatalogItemNotFound is returned when an item is not in a merchant's catalog
+	ErrCatalogItemNotFound = errors.New("catalog item not found")
+	
+	// ErrOutOfStock is returned when a catalog item has too little stock left for an order
+	ErrOutOfStock = errors.New("out of stock")
+	
+	// ErrOrderBatchNotFound is returned when an order is not part of a batch
+	ErrOrderBatchNotFound = errors.New("order batch not found")
+	
+	// ErrRentalNotFound is returned when an order has no rental booking
+	ErrRentalNotFound = errors.New("rental not found")
+	
+	// ErrRentalEnded is returned when a rental's overtime has already been billed
+	ErrRentalEnded = errors.New("rental has already ended")
+	
+	// ErrRentalExtensionNotFound is returned when a rental extension is not found
+	ErrRentalExtensionNotFound = errors.New("rental extension not found")
+	
+	// ErrRentalExtensionPending is returned when a rental already has an extension awaiting its provider
+	ErrRentalExtensionPending = errors.New("rental already has a pending extension")
+	
+	// ErrRentalExtensionNotPending is returned when a rental extension has already been answered
+	ErrRentalExtensionNotPending = errors.New("rental extension has already been answered")
+	
+	// ErrDestinationChangeNotFound is returned when a destination change is not found
+	ErrDestinationChangeNotFound = errors.New("destination change not found")
+	
+	// ErrDestinationChangePending is returned when an order already has a destination change awaiting its provider
+	ErrDestinationChangePending = errors.New("order already has a pending destination change")
+	
+	// ErrDestinationChangeNotPending is returned when a destination change has already been answered
+	ErrDestinationChangeNotPending = errors.New("destination change has already been answered")
+	
+	// ErrReturnExists is returned when a return is created for an order that already has one open
+	ErrReturnExists = errors.New("order already has an open return")
+	
+	// ErrProviderChanged is returned when an order being handed off no longer has the provider it was handed off from
+	ErrProviderChanged = errors.New("order provider has changed")
+	
+	// ErrUserProviderPreferenceNotFound is returned when a user has neither favorited nor blocked a provider
+	ErrUserProviderPreferenceNotFound = errors.New("user provider preference not found")
+	
+	// ErrPaymentMethodNotFound is returned when a payment method is not one the user saved
+	ErrPaymentMethodNotFound = errors.New("payment method not found")
+	
+	// ErrPaymentAuthorizationNotFound is returned when an order's payment was not authorized up front
+	ErrPaymentAuthorizationNotFound = errors.New("payment authorization not found")
+	
+	// ErrPaymentAuthorizationSettled is returned when an order's payment authorization was already captured or released
+	ErrPaymentAuthorizationSettled = errors.New("payment authorization already settled")
+	
+	// ErrInsufficientWalletBalance is returned when a wallet debit is more than the wallet's balance
+	ErrInsufficientWalletBalance = errors.New("insufficient wallet balance")
+	
+	// ErrReferralCodeNotFound is returned when a referral code is not found
+	ErrReferralCodeNotFound = errors.New("referral code not found")
+	
+	// ErrReferralCodeTaken is returned when a new referral code is already another user's
+	ErrReferralCodeTaken = errors.New("referral code already taken")
+	
+	// ErrReferralNotFound is returned when a user was not referred
+	ErrReferralNotFound = errors.New("referral not found")
+	
+	// ErrAlreadyReferred is returned when a user who was already referred is referred again
+	ErrAlreadyReferred = errors.New("user already referred")
+	
+	// ErrReferralDeviceUsed is returned when a device was already used for another referral
+	ErrReferralDeviceUsed = errors.New("device already used for a referral")
+	
+	// ErrReferralFingerprintUsed is returned when the card paying for a referred user's first order already earned another referral its rewards
+	ErrReferralFingerprintUsed = errors.New("payment method already rewarded a referral")
+	
+	// ErrInsufficientPoints is returned when more loyalty points are redeemed than the user holds
+	ErrInsufficientPoints = errors.New("insufficient loyalty points")
+	
+	// ErrDenylistEntryNotFound is returned when a denylist entry is not found
+	ErrDenylistEntryNotFound = errors.New("denylist entry not found")
+	
+	// ErrDenylistEntryExists is returned when the user, API key or network of a new denylist entry is already denied
+	ErrDenylistEntryExists = errors.New("denylist entry already exists")
+	
+	// ErrSupportSessionNotFound is returned when a support session is not found
+	ErrSupportSessionNotFound = errors.New("support session not found")
+	
+	// ErrSupportSessionEnded is returned when a support session was already ended
+	ErrSupportSessionEnded = errors.New("support session already ended")
+	
+	// ErrWebhookNotFound is returned when a webhook subscription is not found
+	ErrWebhookNotFound = errors.New("webhook subscription not found")
+	
+	// ErrWebhookDeliveryNotFound is returned when a webhook delivery is not found
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	
+	// ErrBulkOrderJobNotFound is returned when a bulk order job is not found
+	ErrBulkOrderJobNotFound = errors.New("bulk order job not found")
+) 
+
+// DuplicateOrderError is returned when a new order repeats one the user has just placed.
+// It matches ErrDuplicateOrder with errors.Is.
+type DuplicateOrderError struct {
+	OrderID string // The order already placed
+}
+
+func (e *DuplicateOrderError) Error() string {
+	return fmt.Sprintf("duplicate of order %s", e.OrderID)
+}
+
+// Is makes errors.Is(err, ErrDuplicateOrder) true for a DuplicateOrderError
+func (e *DuplicateOrderError) Is(target error) bool {
+	return target == ErrDuplicateOrder
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const feeRuleColumns = `
+	id, order_type, city, platform_fee_percent, provider_fee_percent, min_platform_fee,
+	created_at, updated_at
+`
+
+const feeWaiverColumns = `id, name, order_type, city, starts_at, ends_at, created_at`
+
+// FeeRepository handles database operations for fee rules and fee waivers
+type FeeRepository struct {
+	db *database.PostgresDB
+}
+
+// NewFeeRepository creates a new fee repository
+func NewFeeRepository(db *database.PostgresDB) *FeeRepository {
+	return &FeeRepository{
+		db: db,
+	}
+}
+
+// CreateRule stores a fee rule; a type and city pair has at most one rule
+func (r *FeeRepository) CreateRule(ctx context.Context, rule *model.FeeRule) error {
+	query := `
+		INSERT INTO fee_rules (
+			id, order_type, city, platform_fee_percent, provider_fee_percent, min_platform_fee,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (order_type, city) DO NOTHING
+	`
+
+	tag, err := r.db.ExecContext(ctx, query,
+		rule.ID,
+		rule.OrderType,
+		rule.City,
+		rule.PlatformFeePercent,
+		rule.ProviderFeePercent,
+		rule.MinPlatformFee,
+		rule.CreatedAt,
+		rule.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create fee rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFeeRuleExists
+	}
+
+	return nil
+}
+
+// GetRule gets a fee rule by its ID
+func (r *FeeRepository) GetRule(ctx context.Context, ruleID string) (*model.FeeRule, error) {
+	query := fmt.Sprintf(`SELECT %s FROM fee_rules WHERE id = $1`, feeRuleColumns)
+
+	rule, err := scanFeeRule(r.db.QueryRowContext(ctx, query, ruleID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrFeeRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to get fee rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+// UpdateRule changes the fees of a rule; its order type and city cannot change
+func (r *FeeRepository) UpdateRule(ctx context.Context, rule *model.FeeRule) error {
+	query := `
+		UPDATE fee_rules
+		SET platform_fee_percent = $2, provider_fee_percent = $3, min_platform_fee = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	tag, err := r.db.ExecContext(ctx, query,
+		rule.ID,
+		rule.PlatformFeePercent,
+		rule.ProviderFeePercent,
+		rule.MinPlatformFee,
+		rule.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update fee rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFeeRuleNotFound
+	}
+
+	return nil
+}
+
+// DeleteRule deletes a fee rule
+func (r *FeeRepository) DeleteRule(ctx context.Context, ruleID string) error {
+	tag, err := r.db.ExecContext(ctx, `DELETE FROM fee_rules WHERE id = $1`, ruleID)
+	if err != nil {
+		return fmt.Errorf("failed to delete fee rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFeeRuleNotFound
+	}
+
+	return nil
+}
+
+// ListRules lists every fee rule, broadest first
+func (r *FeeRepository) ListRules(ctx context.Context) ([]*model.FeeRule, error) {
+	query := fmt.Sprintf(`SELECT %s FROM fee_rules ORDER BY city, order_type`, feeRuleColumns)
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fee rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []*model.FeeRule{}
+	for rows.Next() {
+		rule, err := scanFeeRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fee rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating fee rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// CreateWaiver stores a fee waiver
+func (r *FeeRepository) CreateWaiver(ctx context.Context, waiver *model.FeeWaiver) error {
+	query := `
+		INSERT INTO fee_waivers (id, name, order_type, city, starts_at, ends_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		waiver.ID,
+		waiver.Name,
+		waiver.OrderType,
+		waiver.City,
+		waiver.StartsAt,
+		waiver.EndsAt,
+		waiver.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create fee waiver: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWaiver deletes a fee waiver
+func (r *FeeRepository) DeleteWaiver(ctx context.Context, waiverID string) error {
+	tag, err := r.db.ExecContext(ctx, `DELETE FROM fee_waivers WHERE id = $1`, waiverID)
+	if err != nil {
+		return fmt.Errorf("failed to delete fee waiver: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFeeWaiverNotFound
+	}
+
+	return nil
+}
+
+// ListWaivers lists the fee waivers that have not ended by since, soonest first
+func (r *FeeRepository) ListWaivers(ctx context.Context, since time.Time) ([]*model.FeeWaiver, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM fee_waivers
+		WHERE ends_at > $1
+		ORDER BY starts_at
+	`, feeWaiverColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fee waivers: %w", err)
+	}
+	defer rows.Close()
+
+	waivers := []*model.FeeWaiver{}
+	for rows.Next() {
+		waiver := &model.FeeWaiver{}
+		err := rows.Scan(
+			&waiver.ID,
+			&waiver.Name,
+			&waiver.OrderType,
+			&waiver.City,
+			&waiver.StartsAt,
+			&waiver.EndsAt,
+			&waiver.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fee waiver: %w", err)
+		}
+		waivers = append(waivers, waiver)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating fee waivers: %w", err)
+	}
+
+	return waivers, nil
+}
+
+func scanFeeRule(row pgx.Row) (*model.FeeRule, error) {
+	rule := &model.FeeRule{}
+	err := row.Scan(
+		&rule.ID,
+		&rule.OrderType,
+		&rule.City,
+		&rule.PlatformFeePercent,
+		&rule.ProviderFeePercent,
+		&rule.MinPlatformFee,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const incidentColumns = `
+	id, order_id, reported_by, reporter_role, COALESCE(description, ''), location,
+	status, COALESCE(resolved_by, ''), COALESCE(resolution_notes, ''),
+	created_at, updated_at, resolved_at
+`
+
+// IncidentRepository handles database operations for safety incidents
+type IncidentRepository struct {
+	db *database.PostgresDB
+}
+
+// NewIncidentRepository creates a new incident repository
+func NewIncidentRepository(db *database.PostgresDB) *IncidentRepository {
+	return &IncidentRepository{
+		db: db,
+	}
+}
+
+// CreateIncident stores an incident and freezes its order
+func (r *IncidentRepository) CreateIncident(ctx context.Context, incident *model.Incident) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO incidents (
+			id, order_id, reported_by, reporter_role, description, location,
+			status, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err = tx.Exec(ctx, query,
+		incident.ID,
+		incident.OrderID,
+		incident.ReportedBy,
+		incident.ReporterRole,
+		incident.Description,
+		incident.Location,
+		incident.Status,
+		incident.CreatedAt,
+		incident.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create incident: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE orders SET frozen = TRUE, updated_at = $2 WHERE id = $1`, incident.OrderID, incident.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to freeze order: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOrderNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetIncident gets an incident by its ID
+func (r *IncidentRepository) GetIncident(ctx context.Context, incidentID string) (*model.Incident, error) {
+	query := fmt.Sprintf(`SELECT %s FROM incidents WHERE id = $1`, incidentColumns)
+
+	incident, err := scanIncident(r.db.QueryRowContext(ctx, query, incidentID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrIncidentNotFound
+		}
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+
+	return incident, nil
+}
+
+// ListIncidents lists incidents, newest first, optionally filtered by order and status
+func (r *IncidentRepository) ListIncidents(ctx context.Context, orderID string, status model.IncidentStatus, page, limit int) ([]*model.Incident, int, error) {
+	whereClause := " WHERE 1 = 1"
+	var args []interface{}
+
+	if orderID != "" {
+		args = append(args, orderID)
+		whereClause += fmt.Sprintf(" AND order_id = $%d", len(args))
+	}
+	if status != "" {
+		args = append(args, status)
+		whereClause += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM incidents`+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count incidents: %w", err)
+	}
+
+	// Set reasonable defaults and boundaries
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM incidents%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, incidentColumns, whereClause, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []*model.Incident{}
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, incident)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating incidents: %w", err)
+	}
+
+	return incidents, total, nil
+}
+
+// ResolveIncident closes an open incident and unfreezes its order once no other
+// incident for it is still open
+func (r *IncidentRepository) ResolveIncident(ctx context.Context, incidentID, resolvedBy, notes string) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var orderID string
+	var status model.IncidentStatus
+	err = tx.QueryRow(ctx, `SELECT order_id, status FROM incidents WHERE id = $1 FOR UPDATE`, incidentID).Scan(&orderID, &status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrIncidentNotFound
+		}
+		return fmt.Errorf("failed to get incident: %w", err)
+	}
+	if status != model.IncidentOpen {
+		return ErrIncidentNotOpen
+	}
+
+	now := time.Now()
+	updateIncident := `
+		UPDATE incidents
+		SET status = $2, resolved_by = $3, resolution_notes = $4, updated_at = $5, resolved_at = $5
+		WHERE id = $1
+	`
+	_, err = tx.Exec(ctx, updateIncident, incidentID, model.IncidentResolved, resolvedBy, notes, now)
+	if err != nil {
+		return fmt.Errorf("failed to resolve incident: %w", err)
+	}
+
+	unfreeze := `
+		UPDATE orders
+		SET frozen = EXISTS(SELECT 1 FROM incidents WHERE order_id = $1 AND status = $2), updated_at = $3
+		WHERE id = $1
+	`
+	_, err = tx.Exec(ctx, unfreeze, orderID, model.IncidentOpen, now)
+	if err != nil {
+		return fmt.Errorf("failed to unfreeze order: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func scanIncident(row pgx.Row) (*model.Incident, error) {
+	incident := &model.Incident{}
+	err := row.Scan(
+		&incident.ID,
+		&incident.OrderID,
+		&incident.ReportedBy,
+		&incident.ReporterRole,
+		&incident.Description,
+		&incident.Location,
+		&incident.Status,
+		&incident.ResolvedBy,
+		&incident.ResolutionNotes,
+		&incident.CreatedAt,
+		&incident.UpdatedAt,
+		&incident.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// LedgerRepository handles database operations for the provider payout ledger
+type LedgerRepository struct {
+	db *database.PostgresDB
+}
+
+// NewLedgerRepository creates a new ledger repository
+func NewLedgerRepository(db *database.PostgresDB) *LedgerRepository {
+	return &LedgerRepository{
+		db: db,
+	}
+}
+
+// AddTip sets an order's tip and credits it to the provider's ledger
+func (r *LedgerRepository) AddTip(ctx context.Context, entry *model.LedgerEntry) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE orders
+		SET tip_amount = $2, updated_at = $3
+		WHERE id = $1 AND tip_amount = 0
+	`, entry.OrderID, entry.Amount, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update order tip: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTipAlreadyAdded
+	}
+
+	if err := insertLedgerEntryTx(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListProviderEntries lists a provider's ledger entries, newest first, with the provider's total earnings
+func (r *LedgerRepository) ListProviderEntries(ctx context.Context, providerID string, page, limit int) ([]*model.LedgerEntry, int, int64, error) {
+	var total int
+	var earnings int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(amount), 0)::BIGINT
+		FROM provider_ledger_entries
+		WHERE provider_id = $1
+	`, providerID).Scan(&total, &earnings)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to count ledger entries: %w", err)
+	}
+
+	// Set reasonable defaults and boundaries
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	query := `
+		SELECT id, provider_id, order_id, entry_type, amount, COALESCE(payment_id, ''), created_at
+		FROM provider_ledger_entries
+		WHERE provider_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, providerID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to query ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*model.LedgerEntry{}
+	for rows.Next() {
+		entry := &model.LedgerEntry{}
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ProviderID,
+			&entry.OrderID,
+			&entry.EntryType,
+			&entry.Amount,
+			&entry.PaymentID,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, fmt.Errorf("error iterating ledger entries: %w", err)
+	}
+
+	return entries, total, earnings, nil
+}
+
+// insertLedgerEntryTx stores a ledger entry within tx
+func insertLedgerEntryTx(ctx context.Context, tx pgx.Tx, entry *model.LedgerEntry) error {
+	query := `
+		INSERT INTO provider_ledger_entries (provider_id, order_id, entry_type, amount, payment_id, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		RETURNING id
+	`
+
+	err := tx.QueryRow(ctx, query,
+		entry.ProviderID,
+		entry.OrderID,
+		entry.EntryType,
+		entry.Amount,
+		entry.PaymentID,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create ledger entry: %w", err)
+	}
+
+	return nil
+}
+
+// creditFareTx credits a completed order's provider fee to its provider's ledger within tx.
+// Providers the order was handed off from are credited their shares of the fee, and its
+// provider the rest. It is a no-op for orders without a provider or whose fare is already
+// credited.
+func creditFareTx(ctx context.Context, tx pgx.Tx, orderID string) error {
+	now := time.Now()
+
+	handoffs, err := listOrderHandoffsTx(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+	var handedOff int64
+	if len(handoffs) > 0 {
+		var providerFee int64
+		if err := tx.QueryRow(ctx, `SELECT provider_fee FROM orders WHERE id = $1`, orderID).Scan(&providerFee); err != nil {
+			return fmt.Errorf("failed to get provider fee: %w", err)
+		}
+		shares, remaining := model.HandoffFareShares(providerFee, handoffs)
+		for _, share := range shares {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO provider_ledger_entries (provider_id, order_id, entry_type, amount, created_at)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT DO NOTHING
+			`, share.ProviderID, orderID, share.EntryType, share.Amount, now)
+			if err != nil {
+				return fmt.Errorf("failed to credit handed off fare: %w", err)
+			}
+		}
+		handedOff = providerFee - remaining
+	}
+
+	query := `
+		INSERT INTO provider_ledger_entries (provider_id, order_id, entry_type, amount, created_at)
+		SELECT provider_id, id, $2, provider_fee - $4, $3
+		FROM orders
+		WHERE id = $1 AND provider_id IS NOT NULL AND provider_id <> ''
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err = tx.Exec(ctx, query, orderID, model.LedgerFare, now, handedOff)
+	if err != nil {
+		return fmt.Errorf("failed to credit provider fare: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const referralColumns = `
+	referred_user_id, referrer_id, code, device_id, status, reject_reason,
+	COALESCE(order_id, ''), payment_fingerprint, created_at, settled_at
+`
+
+const pointsTransactionColumns = `
+	id, user_id, type, points, balance_after, COALESCE(order_id, ''), reference,
+	description, created_at
+`
+
+// LoyaltyRepository handles database operations for referrals and loyalty points
+type LoyaltyRepository struct {
+	db *database.PostgresDB
+}
+
+// NewLoyaltyRepository creates a new loyalty repository
+func NewLoyaltyRepository(db *database.PostgresDB) *LoyaltyRepository {
+	return &LoyaltyRepository{
+		db: db,
+	}
+}
+
+// CreateReferralCode stores a user's referral code. It fails with ErrReferralCodeTaken if
+// the user already has a code or the code is another user's.
+func (r *LoyaltyRepository) CreateReferralCode(ctx context.Context, code *model.ReferralCode) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO referral_codes (user_id, code, created_at) VALUES ($1, $2, $3)`,
+		code.UserID, code.Code, code.CreatedAt,
+	)
+	if err != nil {
+		if database.IsUniqueViolation(err, "") {
+			return ErrReferralCodeTaken
+		}
+		return fmt.Errorf("failed to create referral code: %w", err)
+	}
+	return nil
+}
+
+// GetReferralCode retrieves a user's referral code
+func (r *LoyaltyRepository) GetReferralCode(ctx context.Context, userID string) (*model.ReferralCode, error) {
+	return r.getReferralCode(ctx, `SELECT user_id, code, created_at FROM referral_codes WHERE user_id = $1`, userID)
+}
+
+// GetReferralCodeByCode retrieves a referral code by the code itself
+func (r *LoyaltyRepository) GetReferralCodeByCode(ctx context.Context, code string) (*model.ReferralCode, error) {
+	return r.getReferralCode(ctx, `SELECT user_id, code, created_at FROM referral_codes WHERE code = $1`, code)
+}
+
+func (r *LoyaltyRepository) getReferralCode(ctx context.Context, query, arg string) (*model.ReferralCode, error) {
+	code := &model.ReferralCode{}
+	err := r.db.QueryRowContext(ctx, query, arg).Scan(&code.UserID, &code.Code, &code.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrReferralCodeNotFound
+		}
+		return nil, fmt.Errorf("failed to get referral code: %w", err)
+	}
+	return code, nil
+}
+
+// CreateReferral stores who referred a user. It fails with ErrAlreadyReferred if the user
+// was already referred, and with ErrReferralDeviceUsed if their device was used for
+// another referral.
+func (r *LoyaltyRepository) CreateReferral(ctx context.Context, referral *model.Referral) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO referrals (referred_user_id, referrer_id, code, device_id, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`,
+		referral.ReferredUserID,
+		referral.ReferrerID,
+		referral.Code,
+		referral.DeviceID,
+		referral.Status,
+		referral.CreatedAt,
+	)
+	if err != nil {
+		if database.IsUniqueViolation(err, "referrals_pkey") {
+			return ErrAlreadyReferred
+		}
+		if database.IsUniqueViolation(err, "idx_referrals_device_id") {
+			return ErrReferralDeviceUsed
+		}
+		return fmt.Errorf("failed to create referral: %w", err)
+	}
+	return nil
+}
+
+// GetReferral retrieves the referral of a referred user
+func (r *LoyaltyRepository) GetReferral(ctx context.Context, referredUserID string) (*model.Referral, error) {
+	query := `SELECT ` + referralColumns + ` FROM referrals WHERE referred_user_id = $1`
+
+	referral := &model.Referral{}
+	err := r.db.QueryRowContext(ctx, query, referredUserID).Scan(
+		&referral.ReferredUserID,
+		&referral.ReferrerID,
+		&referral.Code,
+		&referral.DeviceID,
+		&referral.Status,
+		&referral.RejectReason,
+		&referral.OrderID,
+		&referral.PaymentFingerprint,
+		&referral.CreatedAt,
+		&referral.SettledAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrReferralNotFound
+		}
+		return nil, fmt.Errorf("failed to get referral: %w", err)
+	}
+	return referral, nil
+}
+
+// SettleReferral moves a pending referral to referral.Status, applying its rewards in the
+// same transaction, and reports whether it was still pending. Rewarding a referral whose
+// payment fingerprint already rewarded another fails with ErrReferralFingerprintUsed.
+func (r *LoyaltyRepository) SettleReferral(ctx context.Context, referral *model.Referral, rewards []*model.PointsTransaction) (bool, error) {
+	var settled bool
+	err := r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE referrals
+			SET status = $2, reject_reason = $3, order_id = NULLIF($4, ''), payment_fingerprint = $5, settled_at = $6
+			WHERE referred_user_id = $1 AND status = $7
+		`,
+			referral.ReferredUserID,
+			referral.Status,
+			referral.RejectReason,
+			referral.OrderID,
+			referral.PaymentFingerprint,
+			referral.SettledAt,
+			model.ReferralPending,
+		)
+		if err != nil {
+			if database.IsUniqueViolation(err, "idx_referrals_payment_fingerprint") {
+				return ErrReferralFingerprintUsed
+			}
+			return fmt.Errorf("failed to settle referral: %w", err)
+		}
+		settled = tag.RowsAffected() > 0
+		if !settled {
+			return nil
+		}
+
+		for _, txn := range rewards {
+			if err := applyPointsTransaction(ctx, tx, txn); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return settled, err
+}
+
+// ApplyPointsTransaction changes a user's points balance by txn.Points and records the
+// change, setting txn.BalanceAfter. Redeeming more than the balance fails with
+// ErrInsufficientPoints. A transaction whose reference the user already has is not
+// applied again; txn is set to the one applied before.
+func (r *LoyaltyRepository) ApplyPointsTransaction(ctx context.Context, txn *model.PointsTransaction) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		return applyPointsTransaction(ctx, tx, txn)
+	})
+}
+
+func applyPointsTransaction(ctx context.Context, tx pgx.Tx, txn *model.PointsTransaction) error {
+	// Lock the account, so changes to it are applied one at a time
+	_, err := tx.Exec(ctx, `
+		INSERT INTO points_accounts (user_id, balance, created_at, updated_at)
+		VALUES ($1, 0, $2, $2)
+		ON CONFLICT (user_id) DO NOTHING
+	`, txn.UserID, txn.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create points account: %w", err)
+	}
+	var balance int64
+	if err := tx.QueryRow(ctx, `SELECT balance FROM points_accounts WHERE user_id = $1 FOR UPDATE`, txn.UserID).Scan(&balance); err != nil {
+		return fmt.Errorf("failed to lock points account: %w", err)
+	}
+
+	query := `SELECT ` + pointsTransactionColumns + ` FROM points_transactions WHERE user_id = $1 AND reference = $2`
+	applied, err := scanPointsTransaction(tx.QueryRow(ctx, query, txn.UserID, txn.Reference))
+	if err == nil {
+		*txn = *applied
+		return nil
+	}
+	if err != pgx.ErrNoRows {
+		return fmt.Errorf("failed to check points transaction: %w", err)
+	}
+
+	if balance+txn.Points < 0 {
+		return ErrInsufficientPoints
+	}
+	txn.BalanceAfter = balance + txn.Points
+
+	_, err = tx.Exec(ctx, `UPDATE points_accounts SET balance = $2, updated_at = $3 WHERE user_id = $1`, txn.UserID, txn.BalanceAfter, txn.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update points balance: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO points_transactions (
+			id, user_id, type, points, balance_after, order_id, reference,
+			description, created_at
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9)
+	`,
+		txn.ID,
+		txn.UserID,
+		txn.Type,
+		txn.Points,
+		txn.BalanceAfter,
+		txn.OrderID,
+		txn.Reference,
+		txn.Description,
+		txn.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create points transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetPointsAccount retrieves a user's points account. Users without one have no points.
+func (r *LoyaltyRepository) GetPointsAccount(ctx context.Context, userID string) (*model.PointsAccount, error) {
+	account := &model.PointsAccount{UserID: userID}
+
+	err := r.db.QueryRowContext(ctx,
+		`SELECT balance, updated_at FROM points_accounts WHERE user_id = $1`,
+		userID,
+	).Scan(&account.Balance, &account.UpdatedAt)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to get points account: %w", err)
+	}
+
+	return account, nil
+}
+
+// ListPointsTransactions lists a page of a user's points transactions, newest first, and
+// the total number they have
+func (r *LoyaltyRepository) ListPointsTransactions(ctx context.Context, userID string, page, limit int) ([]*model.PointsTransaction, int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM points_transactions WHERE user_id = $1`, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count points transactions: %w", err)
+	}
+
+	// Set reasonable defaults and boundaries
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	query := `
+		SELECT ` + pointsTransactionColumns + `
+		FROM points_transactions
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query points transactions: %w", err)
+	}
+	defer rows.Close()
+
+	txns := []*model.PointsTransaction{}
+	for rows.Next() {
+		txn, err := scanPointsTransaction(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan points transaction: %w", err)
+		}
+		txns = append(txns, txn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate points transactions: %w", err)
+	}
+
+	return txns, total, nil
+}
+
+func scanPointsTransaction(row pgx.Row) (*model.PointsTransaction, error) {
+	txn := &model.PointsTransaction{}
+	err := row.Scan(
+		&txn.ID,
+		&txn.UserID,
+		&txn.Type,
+		&txn.Points,
+		&txn.BalanceAfter,
+		&txn.OrderID,
+		&txn.Reference,
+		&txn.Description,
+		&txn.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
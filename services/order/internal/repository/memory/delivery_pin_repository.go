@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// DeliveryPINRepository keeps delivery PINs in memory
+type DeliveryPINRepository struct {
+	mu   sync.Mutex
+	pins map[string]*model.DeliveryPIN // By order ID
+}
+
+// NewDeliveryPINRepository creates an empty delivery PIN repository
+func NewDeliveryPINRepository() *DeliveryPINRepository {
+	return &DeliveryPINRepository{
+		pins: make(map[string]*model.DeliveryPIN),
+	}
+}
+
+// CreatePIN stores the delivery PIN of a new order
+func (r *DeliveryPINRepository) CreatePIN(ctx context.Context, pin *model.DeliveryPIN) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pins[pin.OrderID]; ok {
+		return repository.ErrInvalidData
+	}
+	r.pins[pin.OrderID] = clonePIN(pin)
+
+	return nil
+}
+
+// GetPIN retrieves the delivery PIN of an order
+func (r *DeliveryPINRepository) GetPIN(ctx context.Context, orderID string) (*model.DeliveryPIN, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pin, ok := r.pins[orderID]
+	if !ok {
+		return nil, repository.ErrDeliveryPINNotFound
+	}
+
+	return clonePIN(pin), nil
+}
+
+// ReplacePIN swaps an order's PIN for a newly issued one. Failed attempts and any
+// lockout carry over, so resending cannot be used to reset them.
+func (r *DeliveryPINRepository) ReplacePIN(ctx context.Context, orderID, pinHash string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pin, ok := r.pins[orderID]
+	if !ok {
+		return repository.ErrDeliveryPINNotFound
+	}
+	pin.PINHash = pinHash
+	pin.IssuedAt = at
+	pin.UpdatedAt = at
+
+	return nil
+}
+
+// VerifyPIN checks a submitted PIN hash against an order's PIN with the same attempt
+// counting and lockout as the Postgres repository
+func (r *DeliveryPINRepository) VerifyPIN(ctx context.Context, orderID, pinHash string, maxAttempts int, lockout time.Duration, at time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pin, ok := r.pins[orderID]
+	if !ok {
+		return 0, repository.ErrDeliveryPINNotFound
+	}
+	if pin.Locked(at) {
+		return 0, repository.ErrDeliveryPINLocked
+	}
+
+	matched, remaining := pin.Attempt(pinHash, maxAttempts, lockout, at)
+	switch {
+	case matched:
+		return remaining, nil
+	case remaining == 0:
+		return 0, repository.ErrDeliveryPINLocked
+	default:
+		return remaining, repository.ErrDeliveryPINMismatch
+	}
+}
+
+// clonePIN copies a PIN so callers cannot change what is stored
+func clonePIN(pin *model.DeliveryPIN) *model.DeliveryPIN {
+	clone := *pin
+	if pin.LockedUntil != nil {
+		until := *pin.LockedUntil
+		clone.LockedUntil = &until
+	}
+	return &clone
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// DeliveryProofRepository keeps proofs of delivery in memory, delivering their orders in
+// orders
+type DeliveryProofRepository struct {
+	mu     sync.RWMutex
+	orders *OrderRepository
+	proofs map[string]model.DeliveryProof // By order ID
+}
+
+// NewDeliveryProofRepository creates an empty delivery proof repository over orders
+func NewDeliveryProofRepository(orders *OrderRepository) *DeliveryProofRepository {
+	return &DeliveryProofRepository{
+		orders: orders,
+		proofs: make(map[string]model.DeliveryProof),
+	}
+}
+
+// CompleteDelivery stores a proof of delivery and moves its order from ARRIVED to DELIVERED
+func (r *DeliveryProofRepository) CompleteDelivery(ctx context.Context, proof *model.DeliveryProof) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.orders.mu.Lock()
+	defer r.orders.mu.Unlock()
+
+	order, ok := r.orders.orders[proof.OrderID]
+	if !ok {
+		return repository.ErrOrderNotFound
+	}
+	if order.Status != model.StatusArrived {
+		return repository.ErrOrderNotArrived
+	}
+
+	if err := r.orders.changeStatus(proof.OrderID, model.StatusDelivered, proof.ProviderID, "Delivered with proof "+proof.ProofHash); err != nil {
+		return err
+	}
+	order.DeliveryProofHash = proof.ProofHash
+	r.proofs[proof.OrderID] = *proof
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// CreateDestinationChange stores a pending destination change. A change of the order
+// still pending from before lapsedBefore expires first; if one pending since later is
+// left, it fails with ErrDestinationChangePending.
+func (r *OrderRepository) CreateDestinationChange(ctx context.Context, change *model.DestinationChange, lapsedBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.destinationChanges {
+		if stored.OrderID != change.OrderID || stored.Status != model.DestinationChangePending {
+			continue
+		}
+		if !stored.RequestedAt.Before(lapsedBefore) {
+			return repository.ErrDestinationChangePending
+		}
+		stored.Status = model.DestinationChangeExpired
+		stored.RespondedAt = copyTime(&change.RequestedAt)
+	}
+	r.destinationChanges[change.ID] = cloneDestinationChange(change)
+
+	return nil
+}
+
+// GetDestinationChange gets a destination change by its ID
+func (r *OrderRepository) GetDestinationChange(ctx context.Context, changeID string) (*model.DestinationChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	change, ok := r.destinationChanges[changeID]
+	if !ok {
+		return nil, repository.ErrDestinationChangeNotFound
+	}
+
+	return cloneDestinationChange(change), nil
+}
+
+// AcceptDestinationChange accepts a pending destination change, stores the order's new
+// destination, total and fees, given in order, and appends entry to its status history.
+// It fails with ErrDestinationChangeNotPending if the change was already answered, and
+// with ErrOrderStatusChanged if the order is no longer in transit or is frozen.
+func (r *OrderRepository) AcceptDestinationChange(ctx context.Context, change *model.DestinationChange, order *model.Order, entry model.StatusHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.orders[order.ID]
+	if !ok {
+		return repository.ErrOrderNotFound
+	}
+	if stored.Status != model.StatusInTransit || stored.Frozen {
+		return repository.ErrOrderStatusChanged
+	}
+	if err := r.respondDestinationChange(change); err != nil {
+		return err
+	}
+	stored.DestinationLocation = order.DestinationLocation
+	stored.TotalPrice = order.TotalPrice
+	stored.PlatformFee = order.PlatformFee
+	stored.ProviderFee = order.ProviderFee
+	stored.StatusHistory = append(stored.StatusHistory, entry)
+	stored.UpdatedAt = entry.Timestamp
+
+	return nil
+}
+
+// DeclineDestinationChange declines a pending destination change. It fails with
+// ErrDestinationChangeNotPending if the change was already answered.
+func (r *OrderRepository) DeclineDestinationChange(ctx context.Context, change *model.DestinationChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.respondDestinationChange(change)
+}
+
+// respondDestinationChange records the answer to a pending destination change. The
+// caller holds the lock.
+func (r *OrderRepository) respondDestinationChange(change *model.DestinationChange) error {
+	stored, ok := r.destinationChanges[change.ID]
+	if !ok || stored.Status != model.DestinationChangePending {
+		return repository.ErrDestinationChangeNotPending
+	}
+	stored.Status = change.Status
+	stored.PaymentID = change.PaymentID
+	stored.RespondedAt = copyTime(change.RespondedAt)
+
+	return nil
+}
+
+// cloneDestinationChange copies a destination change so callers cannot change what is stored
+func cloneDestinationChange(change *model.DestinationChange) *model.DestinationChange {
+	clone := *change
+	clone.RespondedAt = copyTime(change.RespondedAt)
+	return &clone
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// LedgerRepository keeps the provider payout ledger in memory, setting tips on orders in
+// orders
+type LedgerRepository struct {
+	mu      sync.RWMutex
+	orders  *OrderRepository
+	entries []model.LedgerEntry
+}
+
+// NewLedgerRepository creates an empty ledger over orders
+func NewLedgerRepository(orders *OrderRepository) *LedgerRepository {
+	return &LedgerRepository{orders: orders}
+}
+
+// AddTip sets an order's tip and credits it to the provider's ledger. Like the Postgres
+// repository, it fails with ErrTipAlreadyAdded if the order was already tipped.
+func (r *LedgerRepository) AddTip(ctx context.Context, entry *model.LedgerEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.orders.mu.Lock()
+	defer r.orders.mu.Unlock()
+
+	order, ok := r.orders.orders[entry.OrderID]
+	if !ok || order.TipAmount != 0 {
+		return repository.ErrTipAlreadyAdded
+	}
+	order.TipAmount = entry.Amount
+	order.UpdatedAt = time.Now()
+
+	entry.ID = int64(len(r.entries) + 1)
+	r.entries = append(r.entries, *entry)
+
+	return nil
+}
+
+// ListProviderEntries lists a provider's ledger entries, newest first, with the provider's total earnings
+func (r *LedgerRepository) ListProviderEntries(ctx context.Context, providerID string, page, limit int) ([]*model.LedgerEntry, int, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []*model.LedgerEntry{}
+	var earnings int64
+	for _, entry := range r.entries {
+		if entry.ProviderID == providerID {
+			entry := entry
+			entries = append(entries, &entry)
+			earnings += entry.Amount
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.After(entries[j].CreatedAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+
+	total := len(entries)
+	start, end := pageBounds(total, page, limit)
+	return entries[start:end], total, earnings, nil
+}
@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// LoyaltyRepository keeps referrals and loyalty points in memory
+type LoyaltyRepository struct {
+	mu           sync.Mutex
+	codes        map[string]*model.ReferralCode        // By user ID
+	referrals    map[string]*model.Referral            // By referred user ID
+	accounts     map[string]*model.PointsAccount       // By user ID
+	transactions map[string][]*model.PointsTransaction // By user ID, oldest first
+}
+
+// NewLoyaltyRepository creates an empty loyalty repository
+func NewLoyaltyRepository() *LoyaltyRepository {
+	return &LoyaltyRepository{
+		codes:        make(map[string]*model.ReferralCode),
+		referrals:    make(map[string]*model.Referral),
+		accounts:     make(map[string]*model.PointsAccount),
+		transactions: make(map[string][]*model.PointsTransaction),
+	}
+}
+
+// CreateReferralCode stores a copy of a user's referral code, unless the user or the code
+// already has one
+func (r *LoyaltyRepository) CreateReferralCode(ctx context.Context, code *model.ReferralCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.codes[code.UserID]; ok {
+		return repository.ErrReferralCodeTaken
+	}
+	for _, taken := range r.codes {
+		if taken.Code == code.Code {
+			return repository.ErrReferralCodeTaken
+		}
+	}
+	stored := *code
+	r.codes[code.UserID] = &stored
+	return nil
+}
+
+// GetReferralCode returns a copy of a user's referral code
+func (r *LoyaltyRepository) GetReferralCode(ctx context.Context, userID string) (*model.ReferralCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code, ok := r.codes[userID]
+	if !ok {
+		return nil, repository.ErrReferralCodeNotFound
+	}
+	stored := *code
+	return &stored, nil
+}
+
+// GetReferralCodeByCode returns a copy of the referral code with a code
+func (r *LoyaltyRepository) GetReferralCodeByCode(ctx context.Context, code string) (*model.ReferralCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.codes {
+		if stored.Code == code {
+			found := *stored
+			return &found, nil
+		}
+	}
+	return nil, repository.ErrReferralCodeNotFound
+}
+
+// CreateReferral stores a copy of a referral, once per referred user and per device
+func (r *LoyaltyRepository) CreateReferral(ctx context.Context, referral *model.Referral) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.referrals[referral.ReferredUserID]; ok {
+		return repository.ErrAlreadyReferred
+	}
+	for _, other := range r.referrals {
+		if other.DeviceID == referral.DeviceID {
+			return repository.ErrReferralDeviceUsed
+		}
+	}
+	stored := *referral
+	r.referrals[referral.ReferredUserID] = &stored
+	return nil
+}
+
+// GetReferral returns a copy of a referred user's referral
+func (r *LoyaltyRepository) GetReferral(ctx context.Context, referredUserID string) (*model.Referral, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	referral, ok := r.referrals[referredUserID]
+	if !ok {
+		return nil, repository.ErrReferralNotFound
+	}
+	stored := *referral
+	return &stored, nil
+}
+
+// SettleReferral moves a pending referral to referral.Status and applies its rewards
+func (r *LoyaltyRepository) SettleReferral(ctx context.Context, referral *model.Referral, rewards []*model.PointsTransaction) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.referrals[referral.ReferredUserID]
+	if !ok || stored.Status != model.ReferralPending {
+		return false, nil
+	}
+	if referral.Status == model.ReferralRewarded && referral.PaymentFingerprint != "" {
+		for _, other := range r.referrals {
+			if other.Status == model.ReferralRewarded && other.PaymentFingerprint == referral.PaymentFingerprint {
+				return false, repository.ErrReferralFingerprintUsed
+			}
+		}
+	}
+
+	for _, txn := range rewards {
+		if err := r.applyPointsTransaction(txn); err != nil {
+			return false, err
+		}
+	}
+	stored.Status = referral.Status
+	stored.RejectReason = referral.RejectReason
+	stored.OrderID = referral.OrderID
+	stored.PaymentFingerprint = referral.PaymentFingerprint
+	stored.SettledAt = referral.SettledAt
+	return true, nil
+}
+
+// ApplyPointsTransaction changes a user's points balance by txn.Points and records the
+// change, once per reference
+func (r *LoyaltyRepository) ApplyPointsTransaction(ctx context.Context, txn *model.PointsTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.applyPointsTransaction(txn)
+}
+
+func (r *LoyaltyRepository) applyPointsTransaction(txn *model.PointsTransaction) error {
+	for _, applied := range r.transactions[txn.UserID] {
+		if applied.Reference == txn.Reference {
+			*txn = *applied
+			return nil
+		}
+	}
+
+	account, ok := r.accounts[txn.UserID]
+	if !ok {
+		account = &model.PointsAccount{UserID: txn.UserID}
+		r.accounts[txn.UserID] = account
+	}
+	if account.Balance+txn.Points < 0 {
+		return repository.ErrInsufficientPoints
+	}
+
+	account.Balance += txn.Points
+	account.UpdatedAt = txn.CreatedAt
+	txn.BalanceAfter = account.Balance
+	stored := *txn
+	r.transactions[txn.UserID] = append(r.transactions[txn.UserID], &stored)
+
+	return nil
+}
+
+// GetPointsAccount returns a copy of a user's points account, empty if they have none
+func (r *LoyaltyRepository) GetPointsAccount(ctx context.Context, userID string) (*model.PointsAccount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if account, ok := r.accounts[userID]; ok {
+		stored := *account
+		return &stored, nil
+	}
+	return &model.PointsAccount{UserID: userID}, nil
+}
+
+// ListPointsTransactions lists a page of a user's points transactions, most recently
+// applied first
+func (r *LoyaltyRepository) ListPointsTransactions(ctx context.Context, userID string, page, limit int) ([]*model.PointsTransaction, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	all := r.transactions[userID]
+	newest := make([]*model.PointsTransaction, len(all))
+	for i, txn := range all {
+		stored := *txn
+		newest[len(all)-1-i] = &stored
+	}
+
+	start := min((page-1)*limit, len(newest))
+	end := min(start+limit, len(newest))
+	return newest[start:end], len(newest), nil
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// MerchantRepository keeps merchants and their catalogs in memory
+type MerchantRepository struct {
+	mu        sync.RWMutex
+	merchants map[string]model.Merchant
+	items     map[string]map[string]model.CatalogItem // By merchant ID, then item ID
+}
+
+// NewMerchantRepository creates an empty merchant repository
+func NewMerchantRepository() *MerchantRepository {
+	return &MerchantRepository{
+		merchants: make(map[string]model.Merchant),
+		items:     make(map[string]map[string]model.CatalogItem),
+	}
+}
+
+// CreateMerchant stores a merchant
+func (r *MerchantRepository) CreateMerchant(ctx context.Context, merchant *model.Merchant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.merchants[merchant.ID]; ok {
+		return repository.ErrInvalidData
+	}
+	r.merchants[merchant.ID] = *merchant
+
+	return nil
+}
+
+// GetMerchant gets a merchant by its ID
+func (r *MerchantRepository) GetMerchant(ctx context.Context, merchantID string) (*model.Merchant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	merchant, ok := r.merchants[merchantID]
+	if !ok {
+		return nil, repository.ErrMerchantNotFound
+	}
+
+	return &merchant, nil
+}
+
+// CreateCatalogItem adds an item to its merchant's catalog
+func (r *MerchantRepository) CreateCatalogItem(ctx context.Context, item *model.CatalogItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.merchants[item.MerchantID]; !ok {
+		return repository.ErrMerchantNotFound
+	}
+	if r.items[item.MerchantID] == nil {
+		r.items[item.MerchantID] = make(map[string]model.CatalogItem)
+	}
+	r.items[item.MerchantID][item.ID] = cloneCatalogItem(item)
+
+	return nil
+}
+
+// GetCatalogItems gets the items of a merchant's catalog with the given IDs, keyed by
+// ID. IDs that are not in the catalog are left out.
+func (r *MerchantRepository) GetCatalogItems(ctx context.Context, merchantID string, itemIDs []string) (map[string]*model.CatalogItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byID := make(map[string]*model.CatalogItem, len(itemIDs))
+	for _, id := range itemIDs {
+		if item, ok := r.items[merchantID][id]; ok {
+			clone := cloneCatalogItem(&item)
+			byID[id] = &clone
+		}
+	}
+
+	return byID, nil
+}
+
+// cloneCatalogItem copies a catalog item so callers cannot change its stock
+func cloneCatalogItem(item *model.CatalogItem) model.CatalogItem {
+	clone := *item
+	if item.Stock != nil {
+		stock := *item.Stock
+		clone.Stock = &stock
+	}
+	return clone
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// OrderBatchRepository keeps batched deliveries in memory
+type OrderBatchRepository struct {
+	mu      sync.RWMutex
+	batches map[string]*model.OrderBatch
+}
+
+// NewOrderBatchRepository creates an empty order batch repository
+func NewOrderBatchRepository() *OrderBatchRepository {
+	return &OrderBatchRepository{
+		batches: make(map[string]*model.OrderBatch),
+	}
+}
+
+// SaveBatch stores a new batch, or replaces the orders and stops of an existing one
+func (r *OrderBatchRepository) SaveBatch(ctx context.Context, batch *model.OrderBatch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := cloneBatch(batch)
+	if stored, ok := r.batches[batch.ID]; ok {
+		saved.ProviderID = stored.ProviderID
+		saved.CreatedAt = stored.CreatedAt
+	}
+	r.batches[batch.ID] = saved
+
+	return nil
+}
+
+// GetBatchByOrder gets the latest batch an order belongs to
+func (r *OrderBatchRepository) GetBatchByOrder(ctx context.Context, orderID string) (*model.OrderBatch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *model.OrderBatch
+	for _, batch := range r.batches {
+		for _, id := range batch.OrderIDs {
+			if id == orderID && (latest == nil || batch.CreatedAt.After(latest.CreatedAt)) {
+				latest = batch
+			}
+		}
+	}
+	if latest == nil {
+		return nil, repository.ErrOrderBatchNotFound
+	}
+
+	return cloneBatch(latest), nil
+}
+
+// cloneBatch copies a batch so callers cannot change what is stored
+func cloneBatch(batch *model.OrderBatch) *model.OrderBatch {
+	clone := *batch
+	clone.OrderIDs = append([]string(nil), batch.OrderIDs...)
+	clone.Stops = append(model.BatchStops(nil), batch.Stops...)
+	return &clone
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// HandOffOrder moves an order to its new provider, storing the handoff and appending
+// entry to the order's status history in the status the order is in. It fails with
+// ErrProviderChanged if the order no longer has the provider it is handed off from, with
+// ErrOrderStatusChanged if it is no longer taken, and with ErrOrderFrozen if it is frozen.
+func (r *OrderRepository) HandOffOrder(ctx context.Context, handoff *model.OrderHandoff, entry model.StatusHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.orders[handoff.OrderID]
+	switch {
+	case !ok:
+		return repository.ErrOrderNotFound
+	case stored.ProviderID != handoff.FromProviderID:
+		return repository.ErrProviderChanged
+	case !stored.Status.Taken():
+		return repository.ErrOrderStatusChanged
+	case stored.Frozen:
+		return repository.ErrOrderFrozen
+	}
+	entry.Status = stored.Status
+	stored.ProviderID = handoff.ToProviderID
+	stored.StatusHistory = append(stored.StatusHistory, entry)
+	stored.UpdatedAt = entry.Timestamp
+	r.handoffs[handoff.OrderID] = append(r.handoffs[handoff.OrderID], *handoff)
+
+	return nil
+}
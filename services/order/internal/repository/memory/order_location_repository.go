@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// LocationRepository keeps order locations and archived tracks in memory
+type LocationRepository struct {
+	mu        sync.RWMutex
+	locations map[string][]model.OrderLocation // By order ID, in the order they were added
+	tracks    map[string]model.OrderTrack
+}
+
+// NewLocationRepository creates an empty location repository
+func NewLocationRepository() *LocationRepository {
+	return &LocationRepository{
+		locations: make(map[string][]model.OrderLocation),
+		tracks:    make(map[string]model.OrderTrack),
+	}
+}
+
+// CreateOrderLocation stores a location entry stamped with the current time
+func (r *LocationRepository) CreateOrderLocation(ctx context.Context, orderLocation *model.OrderLocation) error {
+	if orderLocation.ID == "" {
+		orderLocation.ID = uuid.New().String()
+	}
+	orderLocation.Timestamp = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.locations[orderLocation.OrderID] = append(r.locations[orderLocation.OrderID], *orderLocation)
+
+	return nil
+}
+
+// CreateOrderLocations stores location entries, keeping their timestamps
+func (r *LocationRepository) CreateOrderLocations(ctx context.Context, locations []*model.OrderLocation) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, location := range locations {
+		if location.ID == "" {
+			location.ID = uuid.New().String()
+		}
+		r.locations[location.OrderID] = append(r.locations[location.OrderID], *location)
+	}
+
+	return int64(len(locations)), nil
+}
+
+// GetLatestOrderLocation gets the latest location for an order
+func (r *LocationRepository) GetLatestOrderLocation(ctx context.Context, orderID string) (*model.OrderLocation, error) {
+	locations := r.sorted(orderID)
+	if len(locations) == 0 {
+		return nil, repository.ErrOrderLocationNotFound
+	}
+
+	return locations[len(locations)-1], nil
+}
+
+// GetOrderLocationHistory gets up to limit of an order's locations, newest first
+func (r *LocationRepository) GetOrderLocationHistory(ctx context.Context, orderID string, limit int) ([]*model.OrderLocation, error) {
+	locations := r.sorted(orderID)
+
+	var history []*model.OrderLocation
+	for i := len(locations) - 1; i >= 0 && len(history) < limit; i-- {
+		history = append(history, locations[i])
+	}
+
+	return history, nil
+}
+
+// ListOrderLocations lists every location recorded for an order, oldest first
+func (r *LocationRepository) ListOrderLocations(ctx context.Context, orderID string) ([]*model.OrderLocation, error) {
+	return r.sorted(orderID), nil
+}
+
+// PutTrack stores an order's archived track, replacing any it had
+func (r *LocationRepository) PutTrack(track *model.OrderTrack) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tracks[track.OrderID] = *track
+}
+
+// GetTrack gets an order's archived track
+func (r *LocationRepository) GetTrack(ctx context.Context, orderID string) (*model.OrderTrack, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	track, ok := r.tracks[orderID]
+	if !ok {
+		return nil, repository.ErrOrderTrackNotFound
+	}
+
+	return &track, nil
+}
+
+// sorted copies an order's locations, oldest first
+func (r *LocationRepository) sorted(orderID string) []*model.OrderLocation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	locations := make([]*model.OrderLocation, 0, len(r.locations[orderID]))
+	for _, location := range r.locations[orderID] {
+		location := location
+		locations = append(locations, &location)
+	}
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].Timestamp.Before(locations[j].Timestamp)
+	})
+
+	return locations
+}
@@ -0,0 +1,509 @@
+// Package memory holds in-memory implementations of the order service's repositories, so
+// the services can be exercised without Postgres. They keep the data the Postgres
+// repositories keep and return the same errors, but leave out the side effects those
+// repositories write alongside it, such as ledger entries, webhook events and analytics
+// events.
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"github.com/order-api-microservices/services/order/internal/service"
+)
+
+// The in-memory repositories stand in for the Postgres ones the order service uses
+var (
+	_ service.OrderRepository                = (*OrderRepository)(nil)
+	_ service.LocationRepository             = (*LocationRepository)(nil)
+	_ service.RefundRepository               = (*RefundRepository)(nil)
+	_ service.LedgerRepository               = (*LedgerRepository)(nil)
+	_ service.PaymentShareRepository         = (*PaymentShareRepository)(nil)
+	_ service.DeliveryProofRepository        = (*DeliveryProofRepository)(nil)
+	_ service.DeliveryPINRepository          = (*DeliveryPINRepository)(nil)
+	_ service.OrderBatchRepository           = (*OrderBatchRepository)(nil)
+	_ service.RentalRepository               = (*RentalRepository)(nil)
+	_ service.OrderVehicleRepository         = (*OrderVehicleRepository)(nil)
+	_ service.CatalogRepository              = (*MerchantRepository)(nil)
+	_ service.StockRepository                = (*StockRepository)(nil)
+	_ service.PaymentMethodRepository        = (*PaymentMethodRepository)(nil)
+	_ service.PaymentAuthorizationRepository = (*PaymentAuthorizationRepository)(nil)
+	_ service.WalletRepository               = (*WalletRepository)(nil)
+	_ service.LoyaltyRepository              = (*LoyaltyRepository)(nil)
+)
+
+// OrderRepository keeps orders in memory
+type OrderRepository struct {
+	mu                 sync.RWMutex
+	orders             map[string]*model.Order
+	pickupArrivedAt    map[string]time.Time
+	destinationChanges map[string]*model.DestinationChange // By change ID
+	handoffs           map[string][]model.OrderHandoff     // By order ID, oldest first
+}
+
+// NewOrderRepository creates an empty order repository
+func NewOrderRepository() *OrderRepository {
+	return &OrderRepository{
+		orders:             make(map[string]*model.Order),
+		pickupArrivedAt:    make(map[string]time.Time),
+		destinationChanges: make(map[string]*model.DestinationChange),
+		handoffs:           make(map[string][]model.OrderHandoff),
+	}
+}
+
+// CreateOrder stores a new order. Like the Postgres repository, when duplicateSince is set
+// it refuses an order that repeats one the user placed since then.
+func (r *OrderRepository) CreateOrder(ctx context.Context, order *model.Order, duplicateSince time.Time) error {
+	if order.ID == "" || order.UserID == "" {
+		return repository.ErrInvalidData
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[order.ID]; ok {
+		return repository.ErrDuplicateOrder
+	}
+	if !duplicateSince.IsZero() {
+		if existing := r.findDuplicate(order, duplicateSince); existing != nil {
+			return &repository.DuplicateOrderError{OrderID: existing.ID}
+		}
+	}
+	if order.ReturnOfOrderID != "" {
+		for _, stored := range r.orders {
+			if stored.ReturnOfOrderID == order.ReturnOfOrderID && stored.Status != model.StatusCancelled {
+				return repository.ErrReturnExists
+			}
+		}
+	}
+	r.orders[order.ID] = cloneOrder(order)
+
+	return nil
+}
+
+// findDuplicate finds the latest order the user placed since a time with the same type,
+// pickup and destination as order that was not cancelled
+func (r *OrderRepository) findDuplicate(order *model.Order, since time.Time) *model.Order {
+	samePlace := func(a, b model.Location) bool {
+		return math.Abs(a.Latitude-b.Latitude) < repository.DuplicateLocationTolerance &&
+			math.Abs(a.Longitude-b.Longitude) < repository.DuplicateLocationTolerance
+	}
+
+	var latest *model.Order
+	for _, stored := range r.orders {
+		if stored.UserID != order.UserID || stored.OrderType != order.OrderType ||
+			stored.CreatedAt.Before(since) || stored.Status == model.StatusCancelled {
+			continue
+		}
+		if !samePlace(stored.PickupLocation, order.PickupLocation) || !samePlace(stored.DestinationLocation, order.DestinationLocation) {
+			continue
+		}
+		if latest == nil || stored.CreatedAt.After(latest.CreatedAt) {
+			latest = stored
+		}
+	}
+	return latest
+}
+
+// GetOrderByID gets a copy of an order
+func (r *OrderRepository) GetOrderByID(ctx context.Context, orderID string) (*model.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	order, ok := r.orders[orderID]
+	if !ok {
+		return nil, repository.ErrOrderNotFound
+	}
+
+	return cloneOrder(order), nil
+}
+
+// UpdateOrder replaces an order. Like the Postgres repository, it keeps the stored tip,
+// cancellation fee, proof of delivery and frozen and at-risk flags, which are changed on
+// their own.
+func (r *OrderRepository) UpdateOrder(ctx context.Context, order *model.Order) error {
+	if order.ID == "" {
+		return repository.ErrInvalidData
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.orders[order.ID]
+	if !ok {
+		return repository.ErrOrderNotFound
+	}
+
+	order.UpdatedAt = time.Now()
+	updated := cloneOrder(order)
+	updated.CreatedAt = stored.CreatedAt
+	updated.TipAmount = stored.TipAmount
+	updated.CancellationFee = stored.CancellationFee
+	updated.DeliveryProofHash = stored.DeliveryProofHash
+	updated.Frozen = stored.Frozen
+	updated.AtRisk = stored.AtRisk
+	r.orders[order.ID] = updated
+
+	return nil
+}
+
+// UpdateOrderDetails saves the destination, items, notes, total and fees of an order
+// changed before pickup, moving it to the status of entry and appending entry to its
+// status history, only while it is still in status from and not frozen
+func (r *OrderRepository) UpdateOrderDetails(ctx context.Context, order *model.Order, from model.OrderStatus, entry model.StatusHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.orders[order.ID]
+	if !ok || stored.Status != from || stored.Frozen {
+		return repository.ErrOrderStatusChanged
+	}
+
+	changed := cloneOrder(order)
+	stored.Status = entry.Status
+	stored.DestinationLocation = changed.DestinationLocation
+	stored.Items = changed.Items
+	stored.Notes = changed.Notes
+	stored.TotalPrice = changed.TotalPrice
+	stored.PlatformFee = changed.PlatformFee
+	stored.ProviderFee = changed.ProviderFee
+	stored.StatusHistory = append(stored.StatusHistory, entry)
+	stored.UpdatedAt = entry.Timestamp
+
+	return nil
+}
+
+// SetBlockchainTxHash sets just the blockchain transaction hash of an order
+func (r *OrderRepository) SetBlockchainTxHash(ctx context.Context, orderID, txHash string) error {
+	return r.set(orderID, func(order *model.Order) { order.BlockchainTxHash = txHash })
+}
+
+// SetProviderID sets just the provider of an order; an empty ID leaves it unassigned
+func (r *OrderRepository) SetProviderID(ctx context.Context, orderID, providerID string) error {
+	return r.set(orderID, func(order *model.Order) { order.ProviderID = providerID })
+}
+
+// SetTransactionID sets just the payment transaction ID of an order
+func (r *OrderRepository) SetTransactionID(ctx context.Context, orderID, transactionID string) error {
+	return r.set(orderID, func(order *model.Order) { order.TransactionID = transactionID })
+}
+
+// set changes one field of a stored order
+func (r *OrderRepository) set(orderID string, change func(order *model.Order)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[orderID]
+	if !ok {
+		return repository.ErrOrderNotFound
+	}
+	change(order)
+	order.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// MarkPickupArrival records when the provider first reached an order's pickup location.
+// It reports false, without error, when the arrival was already recorded.
+func (r *OrderRepository) MarkPickupArrival(ctx context.Context, orderID string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[orderID]
+	if !ok {
+		return false, nil
+	}
+	if _, arrived := r.pickupArrivedAt[orderID]; arrived {
+		return false, nil
+	}
+	r.pickupArrivedAt[orderID] = at
+	order.UpdatedAt = at
+
+	return true, nil
+}
+
+// UpdateOrderStatus changes an order's status and appends to its history
+func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID string, status model.OrderStatus, updatedBy, notes string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.changeStatus(orderID, status, updatedBy, notes)
+}
+
+// UpdateOrderStatusFrom changes an order's status only if it is still in the expected status.
+// It reports false, without error, when the order has already moved on.
+func (r *OrderRepository) UpdateOrderStatusFrom(ctx context.Context, orderID string, from, to model.OrderStatus, updatedBy, notes string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[orderID]
+	if !ok {
+		return false, repository.ErrOrderNotFound
+	}
+	if order.Status != from {
+		return false, nil
+	}
+
+	if err := r.changeStatus(orderID, to, updatedBy, notes); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CancelOrder cancels an order and records the cancellation fee charged for it, only
+// while it is still in status from unless from is empty
+func (r *OrderRepository) CancelOrder(ctx context.Context, orderID string, from model.OrderStatus, cancelledBy, reason, notes string, fee int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if order, ok := r.orders[orderID]; ok && from != "" && order.Status != from {
+		return repository.ErrOrderStatusChanged
+	}
+	if err := r.changeStatus(orderID, model.StatusCancelled, cancelledBy, notes); err != nil {
+		return err
+	}
+	r.orders[orderID].CancellationFee = fee
+
+	return nil
+}
+
+// CountActiveProviderOrders counts a provider's orders in any of statuses by order type,
+// leaving out excludeOrderID
+func (r *OrderRepository) CountActiveProviderOrders(ctx context.Context, providerID, excludeOrderID string, statuses []model.OrderStatus) (map[model.OrderType]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[model.OrderType]int)
+	for _, order := range r.orders {
+		if order.ProviderID == providerID && order.ID != excludeOrderID && hasStatus(statuses, order.Status) {
+			counts[order.OrderType]++
+		}
+	}
+
+	return counts, nil
+}
+
+// ListBatchAnchorIDs lists the IDs of orders of orderType that a provider holds in any of
+// statuses and whose pickup lies within the given bounds, oldest first, leaving out
+// excludeOrderID
+func (r *OrderRepository) ListBatchAnchorIDs(ctx context.Context, orderType model.OrderType, statuses []model.OrderStatus, excludeOrderID string, minLat, maxLat, minLon, maxLon float64) ([]string, error) {
+	orders := r.filter(func(order *model.Order) bool {
+		pickup := order.PickupLocation
+		return order.OrderType == orderType && hasStatus(statuses, order.Status) &&
+			order.ID != excludeOrderID && order.ProviderID != "" &&
+			pickup.Latitude >= minLat && pickup.Latitude <= maxLat &&
+			pickup.Longitude >= minLon && pickup.Longitude <= maxLon
+	})
+	sortOldestFirst(orders)
+
+	orderIDs := make([]string, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+
+	return orderIDs, nil
+}
+
+// ListUserOrders gets a page of a user's orders, newest first, along with how many there are
+func (r *OrderRepository) ListUserOrders(ctx context.Context, userID string, page, limit int, status model.OrderStatus) ([]*model.Order, int, error) {
+	orders := r.filter(func(order *model.Order) bool {
+		return order.UserID == userID && (status == "" || order.Status == status)
+	})
+	return paginate(orders, page, limit)
+}
+
+// ListActiveUserIDs lists up to limit IDs, after afterID in ID order, of the users who
+// placed an order since a time
+func (r *OrderRepository) ListActiveUserIDs(ctx context.Context, since time.Time, afterID string, limit int) ([]string, error) {
+	orders := r.filter(func(order *model.Order) bool {
+		return !order.CreatedAt.Before(since) && order.UserID > afterID
+	})
+
+	seen := make(map[string]bool)
+	userIDs := []string{}
+	for _, order := range orders {
+		if !seen[order.UserID] {
+			seen[order.UserID] = true
+			userIDs = append(userIDs, order.UserID)
+		}
+	}
+	sort.Strings(userIDs)
+	if len(userIDs) > limit {
+		userIDs = userIDs[:limit]
+	}
+
+	return userIDs, nil
+}
+
+// ListUnpaidOrders lists up to limit IDs of orders that have been waiting for payment
+// since before a time, oldest first
+func (r *OrderRepository) ListUnpaidOrders(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	orders := r.filter(func(order *model.Order) bool {
+		return order.Status == model.StatusPaymentPending && order.StatusSince().Before(before)
+	})
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].StatusSince().Before(orders[j].StatusSince())
+	})
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+
+	orderIDs := make([]string, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+	return orderIDs, nil
+}
+
+// ListProviderOrders gets a page of a provider's orders, newest first, along with how many there are
+func (r *OrderRepository) ListProviderOrders(ctx context.Context, providerID string, page, limit int, status model.OrderStatus) ([]*model.Order, int, error) {
+	orders := r.filter(func(order *model.Order) bool {
+		return order.ProviderID == providerID && (status == "" || order.Status == status)
+	})
+	return paginate(orders, page, limit)
+}
+
+// ExportOrders calls fn with every order matching filter, oldest first. An error from fn
+// stops the export and is returned.
+func (r *OrderRepository) ExportOrders(ctx context.Context, filter model.OrderExportFilter, fn func(*model.Order) error) error {
+	orders := r.filter(func(order *model.Order) bool {
+		return (filter.UserID == "" || order.UserID == filter.UserID) &&
+			(filter.ProviderID == "" || order.ProviderID == filter.ProviderID) &&
+			(filter.Status == "" || order.Status == filter.Status) &&
+			(filter.OrderType == "" || order.OrderType == filter.OrderType) &&
+			(filter.From.IsZero() || !order.CreatedAt.Before(filter.From)) &&
+			(filter.To.IsZero() || order.CreatedAt.Before(filter.To))
+	})
+	sortOldestFirst(orders)
+
+	for _, order := range orders {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setCharges stores an order's total and fees
+func (r *OrderRepository) setCharges(order *model.Order) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.orders[order.ID]; ok {
+		stored.TotalPrice = order.TotalPrice
+		stored.PlatformFee = order.PlatformFee
+		stored.ProviderFee = order.ProviderFee
+		stored.UpdatedAt = order.UpdatedAt
+	}
+}
+
+// changeStatus changes an order's status and appends to its history. The caller holds the lock.
+func (r *OrderRepository) changeStatus(orderID string, status model.OrderStatus, updatedBy, notes string) error {
+	order, ok := r.orders[orderID]
+	if !ok {
+		return repository.ErrOrderNotFound
+	}
+
+	// An open safety incident holds the order where it is until it is reviewed
+	if order.Frozen {
+		return repository.ErrOrderFrozen
+	}
+
+	now := time.Now()
+	order.Status = status
+	order.StatusHistory = append(order.StatusHistory, model.StatusHistory{
+		Status:    status,
+		UpdatedBy: updatedBy,
+		Notes:     notes,
+		Timestamp: now,
+	})
+	order.UpdatedAt = now
+
+	return nil
+}
+
+// filter copies the orders that match
+func (r *OrderRepository) filter(match func(order *model.Order) bool) []*model.Order {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orders := []*model.Order{}
+	for _, order := range r.orders {
+		if match(order) {
+			orders = append(orders, cloneOrder(order))
+		}
+	}
+
+	return orders
+}
+
+// paginate sorts orders newest first and returns a page of them
+func paginate(orders []*model.Order, page, limit int) ([]*model.Order, int, error) {
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+
+	total := len(orders)
+	start, end := pageBounds(total, page, limit)
+	return orders[start:end], total, nil
+}
+
+// pageBounds returns the bounds of a page of total results, with the same page and limit
+// defaults as the Postgres repositories
+func pageBounds(total, page, limit int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	start := (page - 1) * limit
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// sortOldestFirst sorts orders by when they were created, then by ID
+func sortOldestFirst(orders []*model.Order) {
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+		}
+		return orders[i].ID < orders[j].ID
+	})
+}
+
+// hasStatus reports whether status is one of statuses
+func hasStatus(statuses []model.OrderStatus, status model.OrderStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneOrder copies an order so callers cannot change what is stored
+func cloneOrder(order *model.Order) *model.Order {
+	clone := *order
+	clone.Items = append(model.OrderItems(nil), order.Items...)
+	clone.StatusHistory = append(model.StatusHistories(nil), order.StatusHistory...)
+	clone.PaymentShares = nil
+	return &clone
+}
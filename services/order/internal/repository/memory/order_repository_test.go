@@ -0,0 +1,98 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"github.com/order-api-microservices/services/order/internal/repository/memory"
+)
+
+func TestOrderRepositoryRefusesDuplicateOrders(t *testing.T) {
+	placedAt := time.Now()
+	since := placedAt.Add(-2 * time.Minute)
+
+	placed := func() *model.Order {
+		return &model.Order{
+			ID:                  "placed",
+			UserID:              "user-1",
+			OrderType:           model.TypeRide,
+			Status:              model.StatusCreated,
+			PickupLocation:      model.Location{Latitude: -6.2, Longitude: 106.8166},
+			DestinationLocation: model.Location{Latitude: -6.182, Longitude: 106.8166},
+			CreatedAt:           placedAt,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		placed    func(order *model.Order)
+		repeat    func(order *model.Order)
+		since     time.Time
+		duplicate bool
+	}{
+		{name: "same trip", since: since, duplicate: true},
+		{name: "pickup a few metres off", repeat: func(o *model.Order) { o.PickupLocation.Latitude += 0.00005 }, since: since, duplicate: true},
+		{name: "check off", since: time.Time{}},
+		{name: "other user", repeat: func(o *model.Order) { o.UserID = "user-2" }, since: since},
+		{name: "other order type", repeat: func(o *model.Order) { o.OrderType = model.TypeFoodDelivery }, since: since},
+		{name: "other pickup", repeat: func(o *model.Order) { o.PickupLocation.Latitude += 0.001 }, since: since},
+		{name: "other destination", repeat: func(o *model.Order) { o.DestinationLocation.Longitude -= 0.001 }, since: since},
+		{name: "placed before the window", placed: func(o *model.Order) { o.CreatedAt = since.Add(-time.Second) }, since: since},
+		{name: "placed order cancelled", placed: func(o *model.Order) { o.Status = model.StatusCancelled }, since: since},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := memory.NewOrderRepository()
+
+			first := placed()
+			if tt.placed != nil {
+				tt.placed(first)
+			}
+			if err := repo.CreateOrder(ctx, first, time.Time{}); err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+
+			repeat := placed()
+			repeat.ID = "repeat"
+			if tt.repeat != nil {
+				tt.repeat(repeat)
+			}
+			err := repo.CreateOrder(ctx, repeat, tt.since)
+
+			if !tt.duplicate {
+				if err != nil {
+					t.Fatalf("CreateOrder: %v, want the order stored", err)
+				}
+				return
+			}
+			var duplicate *repository.DuplicateOrderError
+			if !errors.As(err, &duplicate) || duplicate.OrderID != first.ID {
+				t.Fatalf("CreateOrder: got %v, want a duplicate of %s", err, first.ID)
+			}
+			if _, err := repo.GetOrderByID(ctx, repeat.ID); !errors.Is(err, repository.ErrOrderNotFound) {
+				t.Errorf("refused order was stored: %v", err)
+			}
+		})
+	}
+}
+
+func TestOrderRepositoryRefusesTakenID(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewOrderRepository()
+	order := &model.Order{ID: "order-1", UserID: "user-1", CreatedAt: time.Now()}
+
+	if err := repo.CreateOrder(ctx, order, time.Time{}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	err := repo.CreateOrder(ctx, order, time.Time{})
+	var duplicate *repository.DuplicateOrderError
+	if !errors.Is(err, repository.ErrDuplicateOrder) || errors.As(err, &duplicate) {
+		t.Errorf("CreateOrder with a taken ID: got %v, want plain ErrDuplicateOrder", err)
+	}
+}
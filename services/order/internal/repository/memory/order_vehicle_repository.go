@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// OrderVehicleRepository keeps the vehicles orders were accepted in in memory
+type OrderVehicleRepository struct {
+	mu       sync.RWMutex
+	vehicles map[string]model.OrderVehicle // By order ID
+}
+
+// NewOrderVehicleRepository creates an empty order vehicle repository
+func NewOrderVehicleRepository() *OrderVehicleRepository {
+	return &OrderVehicleRepository{
+		vehicles: make(map[string]model.OrderVehicle),
+	}
+}
+
+// ReplaceVehicle records the vehicle an order was accepted in, replacing any recorded
+// for an earlier provider. A nil vehicle only forgets the earlier one.
+func (r *OrderVehicleRepository) ReplaceVehicle(ctx context.Context, orderID string, vehicle *model.OrderVehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.vehicles, orderID)
+	if vehicle != nil {
+		stored := *vehicle
+		stored.OrderID = orderID
+		r.vehicles[orderID] = stored
+	}
+
+	return nil
+}
+
+// GetVehicle retrieves the vehicle an order was accepted in, or nil if none was recorded
+func (r *OrderVehicleRepository) GetVehicle(ctx context.Context, orderID string) (*model.OrderVehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	vehicle, ok := r.vehicles[orderID]
+	if !ok {
+		return nil, nil
+	}
+
+	return &vehicle, nil
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// PaymentAuthorizationRepository keeps the payments authorized for orders in memory
+type PaymentAuthorizationRepository struct {
+	mu    sync.Mutex
+	auths map[string]*model.PaymentAuthorization // By order ID
+}
+
+// NewPaymentAuthorizationRepository creates an empty payment authorization repository
+func NewPaymentAuthorizationRepository() *PaymentAuthorizationRepository {
+	return &PaymentAuthorizationRepository{
+		auths: make(map[string]*model.PaymentAuthorization),
+	}
+}
+
+// CreateAuthorization stores the payment authorized for a new order
+func (r *PaymentAuthorizationRepository) CreateAuthorization(ctx context.Context, auth *model.PaymentAuthorization) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.auths[auth.OrderID]; ok {
+		return repository.ErrInvalidData
+	}
+	clone := *auth
+	r.auths[auth.OrderID] = &clone
+
+	return nil
+}
+
+// GetAuthorization retrieves the payment authorized for an order
+func (r *PaymentAuthorizationRepository) GetAuthorization(ctx context.Context, orderID string) (*model.PaymentAuthorization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	auth, ok := r.auths[orderID]
+	if !ok {
+		return nil, repository.ErrPaymentAuthorizationNotFound
+	}
+	clone := *auth
+	return &clone, nil
+}
+
+// SettleAuthorization records that an order's authorized payment was captured or released
+func (r *PaymentAuthorizationRepository) SettleAuthorization(ctx context.Context, orderID string, status model.AuthorizationStatus, capturedAmount int64, paymentID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	auth, ok := r.auths[orderID]
+	if !ok {
+		return repository.ErrPaymentAuthorizationNotFound
+	}
+	if auth.Status != model.AuthorizationAuthorized {
+		return repository.ErrPaymentAuthorizationSettled
+	}
+	auth.Status = status
+	auth.CapturedAmount = capturedAmount
+	auth.PaymentID = paymentID
+	auth.SettledAt = &at
+	auth.UpdatedAt = at
+
+	return nil
+}
+
+// ListLapsingAuthorizations lists up to limit authorizations still held that lapse before
+// a time, soonest first
+func (r *PaymentAuthorizationRepository) ListLapsingAuthorizations(ctx context.Context, before time.Time, limit int) ([]*model.PaymentAuthorization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	auths := []*model.PaymentAuthorization{}
+	for _, auth := range r.auths {
+		if auth.Status == model.AuthorizationAuthorized && auth.ExpiresAt.Before(before) {
+			clone := *auth
+			auths = append(auths, &clone)
+		}
+	}
+	sort.Slice(auths, func(i, j int) bool {
+		return auths[i].ExpiresAt.Before(auths[j].ExpiresAt)
+	})
+	if len(auths) > limit {
+		auths = auths[:limit]
+	}
+
+	return auths, nil
+}
@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// PaymentMethodRepository keeps the payment methods users saved in memory
+type PaymentMethodRepository struct {
+	mu      sync.RWMutex
+	methods map[string]*model.SavedPaymentMethod // By ID
+}
+
+// NewPaymentMethodRepository creates an empty payment method repository
+func NewPaymentMethodRepository() *PaymentMethodRepository {
+	return &PaymentMethodRepository{
+		methods: make(map[string]*model.SavedPaymentMethod),
+	}
+}
+
+// SavePaymentMethod stores a payment method, refreshing a card saved with the same
+// fingerprint instead of adding it again
+func (r *PaymentMethodRepository) SavePaymentMethod(ctx context.Context, method *model.SavedPaymentMethod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var current, existing *model.SavedPaymentMethod
+	for _, stored := range r.methods {
+		if stored.UserID != method.UserID {
+			continue
+		}
+		if stored.IsDefault {
+			current = stored
+		}
+		if method.Fingerprint != "" && stored.Gateway == method.Gateway && stored.Fingerprint == method.Fingerprint {
+			existing = stored
+		}
+	}
+
+	if method.IsDefault && current != nil {
+		current.IsDefault = false
+	}
+	isDefault := method.IsDefault || current == nil
+
+	if existing != nil {
+		existing.Type = method.Type
+		existing.GatewayToken = method.GatewayToken
+		existing.Brand = method.Brand
+		existing.Last4 = method.Last4
+		existing.ExpMonth = method.ExpMonth
+		existing.ExpYear = method.ExpYear
+		existing.IsDefault = existing.IsDefault || isDefault
+		existing.UpdatedAt = method.UpdatedAt
+		*method = *existing
+		return nil
+	}
+
+	stored := *method
+	stored.IsDefault = isDefault
+	r.methods[stored.ID] = &stored
+	*method = stored
+	return nil
+}
+
+// GetPaymentMethod gets one of a user's payment methods
+func (r *PaymentMethodRepository) GetPaymentMethod(ctx context.Context, userID, methodID string) (*model.SavedPaymentMethod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	method, ok := r.methods[methodID]
+	if !ok || method.UserID != userID {
+		return nil, repository.ErrPaymentMethodNotFound
+	}
+	clone := *method
+	return &clone, nil
+}
+
+// GetDefaultPaymentMethod gets a user's default payment method
+func (r *PaymentMethodRepository) GetDefaultPaymentMethod(ctx context.Context, userID string) (*model.SavedPaymentMethod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, method := range r.methods {
+		if method.UserID == userID && method.IsDefault {
+			clone := *method
+			return &clone, nil
+		}
+	}
+	return nil, repository.ErrPaymentMethodNotFound
+}
+
+// ListPaymentMethods lists a user's payment methods, the default first, then newest first
+func (r *PaymentMethodRepository) ListPaymentMethods(ctx context.Context, userID string) ([]*model.SavedPaymentMethod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	methods := []*model.SavedPaymentMethod{}
+	for _, method := range r.methods {
+		if method.UserID == userID {
+			clone := *method
+			methods = append(methods, &clone)
+		}
+	}
+	sortPaymentMethods(methods)
+
+	return methods, nil
+}
+
+// SetDefaultPaymentMethod makes one of a user's payment methods their default
+func (r *PaymentMethodRepository) SetDefaultPaymentMethod(ctx context.Context, userID, methodID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	method, ok := r.methods[methodID]
+	if !ok || method.UserID != userID {
+		return repository.ErrPaymentMethodNotFound
+	}
+	for _, stored := range r.methods {
+		if stored.UserID == userID {
+			stored.IsDefault = stored.ID == methodID
+		}
+	}
+
+	return nil
+}
+
+// DeletePaymentMethod removes one of a user's payment methods, making the most recently
+// saved of the others the default when it was the default
+func (r *PaymentMethodRepository) DeletePaymentMethod(ctx context.Context, userID, methodID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	method, ok := r.methods[methodID]
+	if !ok || method.UserID != userID {
+		return repository.ErrPaymentMethodNotFound
+	}
+	delete(r.methods, methodID)
+
+	if method.IsDefault {
+		var newest *model.SavedPaymentMethod
+		for _, stored := range r.methods {
+			if stored.UserID == userID && (newest == nil || stored.CreatedAt.After(newest.CreatedAt)) {
+				newest = stored
+			}
+		}
+		if newest != nil {
+			newest.IsDefault = true
+		}
+	}
+
+	return nil
+}
+
+// sortPaymentMethods orders methods the default first, then newest first
+func sortPaymentMethods(methods []*model.SavedPaymentMethod) {
+	sort.SliceStable(methods, func(i, j int) bool {
+		if methods[i].IsDefault != methods[j].IsDefault {
+			return methods[i].IsDefault
+		}
+		return methods[i].CreatedAt.After(methods[j].CreatedAt)
+	})
+}
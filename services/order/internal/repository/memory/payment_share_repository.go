@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// PaymentShareRepository keeps the shares of split payments in memory
+type PaymentShareRepository struct {
+	mu     sync.RWMutex
+	shares map[string][]model.PaymentShare // By order ID
+}
+
+// NewPaymentShareRepository creates an empty payment share repository
+func NewPaymentShareRepository() *PaymentShareRepository {
+	return &PaymentShareRepository{
+		shares: make(map[string][]model.PaymentShare),
+	}
+}
+
+// CreateShares stores the payment shares of an order
+func (r *PaymentShareRepository) CreateShares(ctx context.Context, shares []*model.PaymentShare) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, share := range shares {
+		r.shares[share.OrderID] = append(r.shares[share.OrderID], *share)
+	}
+
+	return nil
+}
+
+// ListOrderShares lists the payment shares of an order
+func (r *PaymentShareRepository) ListOrderShares(ctx context.Context, orderID string) ([]*model.PaymentShare, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	shares := []*model.PaymentShare{}
+	for _, share := range r.shares[orderID] {
+		share := share
+		shares = append(shares, &share)
+	}
+
+	sort.SliceStable(shares, func(i, j int) bool {
+		if !shares[i].CreatedAt.Equal(shares[j].CreatedAt) {
+			return shares[i].CreatedAt.Before(shares[j].CreatedAt)
+		}
+		return shares[i].ID < shares[j].ID
+	})
+
+	return shares, nil
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// RefundRepository keeps refunds in memory, moving their orders in orders
+type RefundRepository struct {
+	mu      sync.RWMutex
+	orders  *OrderRepository
+	refunds []model.Refund
+}
+
+// NewRefundRepository creates an empty refund repository over orders
+func NewRefundRepository(orders *OrderRepository) *RefundRepository {
+	return &RefundRepository{orders: orders}
+}
+
+// CreateRefund stores a refund and moves its order to REFUNDED
+func (r *RefundRepository) CreateRefund(ctx context.Context, refund *model.Refund) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.orders.mu.Lock()
+	defer r.orders.mu.Unlock()
+
+	if err := r.orders.changeStatus(refund.OrderID, model.StatusRefunded, refund.RequestedBy, refund.Reason); err != nil {
+		return err
+	}
+	r.refunds = append(r.refunds, *refund)
+
+	return nil
+}
+
+// ListOrderRefunds lists the refunds issued for an order, oldest first
+func (r *RefundRepository) ListOrderRefunds(ctx context.Context, orderID string) ([]*model.Refund, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	refunds := []*model.Refund{}
+	for _, refund := range r.refunds {
+		if refund.OrderID == orderID {
+			refund := refund
+			refunds = append(refunds, &refund)
+		}
+	}
+
+	return refunds, nil
+}
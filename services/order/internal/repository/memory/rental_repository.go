@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// RentalRepository keeps rentals and their extensions in memory, charging their orders
+// in orders
+type RentalRepository struct {
+	mu         sync.RWMutex
+	orders     *OrderRepository
+	rentals    map[string]*model.Rental          // By order ID
+	extensions map[string]*model.RentalExtension // By extension ID
+}
+
+// NewRentalRepository creates an empty rental repository over orders
+func NewRentalRepository(orders *OrderRepository) *RentalRepository {
+	return &RentalRepository{
+		orders:     orders,
+		rentals:    make(map[string]*model.Rental),
+		extensions: make(map[string]*model.RentalExtension),
+	}
+}
+
+// CreateRental stores the booking of a rental order
+func (r *RentalRepository) CreateRental(ctx context.Context, rental *model.Rental) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rentals[rental.OrderID]; ok {
+		return repository.ErrInvalidData
+	}
+	r.rentals[rental.OrderID] = cloneRental(rental)
+
+	return nil
+}
+
+// GetRental gets the booking of a rental order
+func (r *RentalRepository) GetRental(ctx context.Context, orderID string) (*model.Rental, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rental, ok := r.rentals[orderID]
+	if !ok {
+		return nil, repository.ErrRentalNotFound
+	}
+
+	return cloneRental(rental), nil
+}
+
+// StartRental starts a rental's clock unless it is already running. It reports whether
+// the clock was started.
+func (r *RentalRepository) StartRental(ctx context.Context, orderID string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rental, ok := r.rentals[orderID]
+	if !ok || rental.StartedAt != nil {
+		return false, nil
+	}
+	rental.StartedAt = &at
+	rental.UpdatedAt = at
+
+	return true, nil
+}
+
+// BillOvertime ends a rental with its overtime and adds the overtime to the order's
+// total and fees, given in order. It fails with ErrRentalEnded if the rental has
+// already ended.
+func (r *RentalRepository) BillOvertime(ctx context.Context, rental *model.Rental, order *model.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.rentals[rental.OrderID]
+	if !ok || stored.EndedAt != nil {
+		return repository.ErrRentalEnded
+	}
+	stored.EndedAt = copyTime(rental.EndedAt)
+	stored.OvertimeMinutes = rental.OvertimeMinutes
+	stored.OvertimeCharge = rental.OvertimeCharge
+	stored.OvertimePaymentID = rental.OvertimePaymentID
+	stored.UpdatedAt = rental.UpdatedAt
+
+	r.orders.setCharges(order)
+
+	return nil
+}
+
+// CreateExtension stores a pending extension request. It fails with
+// ErrRentalExtensionPending if the rental already has one.
+func (r *RentalRepository) CreateExtension(ctx context.Context, extension *model.RentalExtension) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.extensions {
+		if stored.OrderID == extension.OrderID && stored.Status == model.ExtensionPending {
+			return repository.ErrRentalExtensionPending
+		}
+	}
+	r.extensions[extension.ID] = cloneExtension(extension)
+
+	return nil
+}
+
+// GetExtension gets an extension request by its ID
+func (r *RentalRepository) GetExtension(ctx context.Context, extensionID string) (*model.RentalExtension, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	extension, ok := r.extensions[extensionID]
+	if !ok {
+		return nil, repository.ErrRentalExtensionNotFound
+	}
+
+	return cloneExtension(extension), nil
+}
+
+// ListExtensions lists a rental's extension requests, oldest first
+func (r *RentalRepository) ListExtensions(ctx context.Context, orderID string) ([]*model.RentalExtension, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	extensions := []*model.RentalExtension{}
+	for _, extension := range r.extensions {
+		if extension.OrderID == orderID {
+			extensions = append(extensions, cloneExtension(extension))
+		}
+	}
+	sort.Slice(extensions, func(i, j int) bool {
+		return extensions[i].RequestedAt.Before(extensions[j].RequestedAt)
+	})
+
+	return extensions, nil
+}
+
+// ApproveExtension approves a pending extension, adds its hours to the booking and its
+// amount to the order's total and fees, given in order. It fails with
+// ErrRentalExtensionNotPending if the extension was already answered.
+func (r *RentalRepository) ApproveExtension(ctx context.Context, extension *model.RentalExtension, order *model.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.respond(extension); err != nil {
+		return err
+	}
+	if rental, ok := r.rentals[extension.OrderID]; ok {
+		rental.BookedHours += extension.Hours
+		if extension.RespondedAt != nil {
+			rental.UpdatedAt = *extension.RespondedAt
+		}
+	}
+
+	r.orders.setCharges(order)
+
+	return nil
+}
+
+// DeclineExtension declines a pending extension. It fails with
+// ErrRentalExtensionNotPending if the extension was already answered.
+func (r *RentalRepository) DeclineExtension(ctx context.Context, extension *model.RentalExtension) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.respond(extension)
+}
+
+// respond records the answer to a pending extension. The caller holds the lock.
+func (r *RentalRepository) respond(extension *model.RentalExtension) error {
+	stored, ok := r.extensions[extension.ID]
+	if !ok || stored.Status != model.ExtensionPending {
+		return repository.ErrRentalExtensionNotPending
+	}
+	stored.Status = extension.Status
+	stored.PaymentID = extension.PaymentID
+	stored.RespondedAt = copyTime(extension.RespondedAt)
+
+	return nil
+}
+
+// cloneRental copies a rental so callers cannot change what is stored
+func cloneRental(rental *model.Rental) *model.Rental {
+	clone := *rental
+	clone.StartedAt = copyTime(rental.StartedAt)
+	clone.EndedAt = copyTime(rental.EndedAt)
+	return &clone
+}
+
+// cloneExtension copies an extension so callers cannot change what is stored
+func cloneExtension(extension *model.RentalExtension) *model.RentalExtension {
+	clone := *extension
+	clone.RespondedAt = copyTime(extension.RespondedAt)
+	return &clone
+}
+
+// copyTime copies an optional time
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
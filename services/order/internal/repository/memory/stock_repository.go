@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// stockHold is the stock of one catalog item held for an order
+type stockHold struct {
+	merchantID string
+	quantity   int
+	until      time.Time
+}
+
+// StockRepository holds the stock of a merchant repository's catalog items for orders
+type StockRepository struct {
+	mu        sync.Mutex
+	merchants *MerchantRepository
+	holds     map[string]map[string]stockHold // By order ID, then item ID
+}
+
+// NewStockRepository creates a stock repository holding the stock of merchants' catalogs
+func NewStockRepository(merchants *MerchantRepository) *StockRepository {
+	return &StockRepository{
+		merchants: merchants,
+		holds:     make(map[string]map[string]stockHold),
+	}
+}
+
+// Reserve takes the stock of an order's items off its merchant's catalog and holds it for
+// the order until a time. Items whose stock is not tracked are not held. When any tracked
+// item has too few left it fails with ErrOutOfStock and takes nothing.
+func (r *StockRepository) Reserve(ctx context.Context, orderID, merchantID string, items model.OrderItems, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.merchants.mu.Lock()
+	defer r.merchants.mu.Unlock()
+
+	catalog := r.merchants.items[merchantID]
+	wanted := make(map[string]int)
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			continue
+		}
+		entry, ok := catalog[item.ItemID]
+		if !ok {
+			return repository.ErrCatalogItemNotFound
+		}
+		wanted[item.ItemID] += item.Quantity
+		if entry.Stock != nil && *entry.Stock < wanted[item.ItemID] {
+			return fmt.Errorf("%s: %w", item.Name, repository.ErrOutOfStock)
+		}
+	}
+
+	for itemID, quantity := range wanted {
+		entry := catalog[itemID]
+		if entry.Stock == nil {
+			continue
+		}
+		*entry.Stock -= quantity
+
+		if r.holds[orderID] == nil {
+			r.holds[orderID] = make(map[string]stockHold)
+		}
+		hold := r.holds[orderID][itemID]
+		r.holds[orderID][itemID] = stockHold{merchantID: merchantID, quantity: hold.quantity + quantity, until: until}
+	}
+
+	return nil
+}
+
+// Release gives the stock held for an order back to the catalog. Releasing an order
+// that holds nothing does nothing.
+func (r *StockRepository) Release(ctx context.Context, orderID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.merchants.mu.Lock()
+	defer r.merchants.mu.Unlock()
+
+	for itemID, hold := range r.holds[orderID] {
+		if entry, ok := r.merchants.items[hold.merchantID][itemID]; ok && entry.Stock != nil {
+			*entry.Stock += hold.quantity
+		}
+	}
+	delete(r.holds, orderID)
+
+	return nil
+}
+
+// Confirm keeps the stock held for an order off the catalog for good
+func (r *StockRepository) Confirm(ctx context.Context, orderID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.holds, orderID)
+
+	return nil
+}
+
+// ListExpiredReservations lists up to limit orders holding stock whose hold ended before
+// a time, oldest first
+func (r *StockRepository) ListExpiredReservations(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expiries := make(map[string]time.Time)
+	for orderID, holds := range r.holds {
+		for _, hold := range holds {
+			if earliest, ok := expiries[orderID]; hold.until.Before(before) && (!ok || hold.until.Before(earliest)) {
+				expiries[orderID] = hold.until
+			}
+		}
+	}
+
+	orderIDs := make([]string, 0, len(expiries))
+	for orderID := range expiries {
+		orderIDs = append(orderIDs, orderID)
+	}
+	sort.Slice(orderIDs, func(i, j int) bool {
+		return expiries[orderIDs[i]].Before(expiries[orderIDs[j]])
+	})
+	if limit > 0 && len(orderIDs) > limit {
+		orderIDs = orderIDs[:limit]
+	}
+
+	return orderIDs, nil
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// WalletRepository keeps user wallets and their transactions in memory
+type WalletRepository struct {
+	mu           sync.Mutex
+	wallets      map[string]*model.Wallet              // By user ID
+	transactions map[string][]*model.WalletTransaction // By user ID, oldest first
+}
+
+// NewWalletRepository creates an empty wallet repository
+func NewWalletRepository() *WalletRepository {
+	return &WalletRepository{
+		wallets:      make(map[string]*model.Wallet),
+		transactions: make(map[string][]*model.WalletTransaction),
+	}
+}
+
+// ApplyWalletTransaction changes a wallet's balance by txn.Amount and records the change,
+// once per reference
+func (r *WalletRepository) ApplyWalletTransaction(ctx context.Context, txn *model.WalletTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, applied := range r.transactions[txn.UserID] {
+		if applied.Reference == txn.Reference {
+			*txn = *applied
+			return nil
+		}
+	}
+
+	wallet, ok := r.wallets[txn.UserID]
+	if !ok {
+		wallet = &model.Wallet{UserID: txn.UserID}
+		r.wallets[txn.UserID] = wallet
+	}
+	if wallet.Balance+txn.Amount < 0 {
+		return repository.ErrInsufficientWalletBalance
+	}
+
+	wallet.Balance += txn.Amount
+	wallet.UpdatedAt = txn.CreatedAt
+	txn.BalanceAfter = wallet.Balance
+	stored := *txn
+	r.transactions[txn.UserID] = append(r.transactions[txn.UserID], &stored)
+
+	return nil
+}
+
+// GetWallet returns a copy of a user's wallet, empty if they have none
+func (r *WalletRepository) GetWallet(ctx context.Context, userID string) (*model.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if wallet, ok := r.wallets[userID]; ok {
+		stored := *wallet
+		return &stored, nil
+	}
+	return &model.Wallet{UserID: userID}, nil
+}
+
+// ListWalletTransactions lists a page of a user's wallet transactions, most recently
+// applied first
+func (r *WalletRepository) ListWalletTransactions(ctx context.Context, userID string, page, limit int) ([]*model.WalletTransaction, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	all := r.transactions[userID]
+	newest := make([]*model.WalletTransaction, len(all))
+	for i, txn := range all {
+		stored := *txn
+		newest[len(all)-1-i] = &stored
+	}
+
+	start := min((page-1)*limit, len(newest))
+	end := min(start+limit, len(newest))
+	return newest[start:end], len(newest), nil
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const (
+	merchantColumns    = `id, name, city, address, latitude, longitude, active, created_at, updated_at`
+	catalogItemColumns = `id, merchant_id, name, description, category, price, available, stock, created_at, updated_at`
+)
+
+// MerchantRepository handles database operations for merchants and their catalogs
+type MerchantRepository struct {
+	db *database.PostgresDB
+}
+
+// NewMerchantRepository creates a new merchant repository
+func NewMerchantRepository(db *database.PostgresDB) *MerchantRepository {
+	return &MerchantRepository{
+		db: db,
+	}
+}
+
+// CreateMerchant stores a merchant
+func (r *MerchantRepository) CreateMerchant(ctx context.Context, merchant *model.Merchant) error {
+	query := fmt.Sprintf(`
+		INSERT INTO merchants (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, merchantColumns)
+
+	_, err := r.db.ExecContext(ctx, query,
+		merchant.ID,
+		merchant.Name,
+		merchant.City,
+		merchant.Address,
+		merchant.Latitude,
+		merchant.Longitude,
+		merchant.Active,
+		merchant.CreatedAt,
+		merchant.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create merchant: %w", err)
+	}
+
+	return nil
+}
+
+// GetMerchant gets a merchant by its ID
+func (r *MerchantRepository) GetMerchant(ctx context.Context, merchantID string) (*model.Merchant, error) {
+	query := fmt.Sprintf(`SELECT %s FROM merchants WHERE id = $1`, merchantColumns)
+
+	merchant, err := scanMerchant(r.db.QueryRowContext(ctx, query, merchantID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrMerchantNotFound
+		}
+		return nil, fmt.Errorf("failed to get merchant: %w", err)
+	}
+
+	return merchant, nil
+}
+
+// UpdateMerchant replaces a merchant's name, city, address, location and active flag
+func (r *MerchantRepository) UpdateMerchant(ctx context.Context, merchant *model.Merchant) error {
+	query := `
+		UPDATE merchants
+		SET name = $2, city = $3, address = $4, latitude = $5, longitude = $6, active = $7, updated_at = $8
+		WHERE id = $1
+	`
+
+	tag, err := r.db.ExecContext(ctx, query,
+		merchant.ID,
+		merchant.Name,
+		merchant.City,
+		merchant.Address,
+		merchant.Latitude,
+		merchant.Longitude,
+		merchant.Active,
+		merchant.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update merchant: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMerchantNotFound
+	}
+
+	return nil
+}
+
+// ListMerchants lists the merchants of a city, or of every city when city is empty,
+// ordered by city and name
+func (r *MerchantRepository) ListMerchants(ctx context.Context, city string) ([]*model.Merchant, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM merchants
+		WHERE $1 = '' OR city = $1
+		ORDER BY city, name
+	`, merchantColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, city)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchants: %w", err)
+	}
+	defer rows.Close()
+
+	merchants := []*model.Merchant{}
+	for rows.Next() {
+		merchant, err := scanMerchant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan merchant: %w", err)
+		}
+		merchants = append(merchants, merchant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating merchants: %w", err)
+	}
+
+	return merchants, nil
+}
+
+// CreateCatalogItem adds an item to a merchant's catalog
+func (r *MerchantRepository) CreateCatalogItem(ctx context.Context, item *model.CatalogItem) error {
+	query := fmt.Sprintf(`
+		INSERT INTO catalog_items (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, catalogItemColumns)
+
+	_, err := r.db.ExecContext(ctx, query,
+		item.ID,
+		item.MerchantID,
+		item.Name,
+		item.Description,
+		item.Category,
+		item.Price,
+		item.Available,
+		item.Stock,
+		item.CreatedAt,
+		item.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create catalog item: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateCatalogItem replaces the details of one of a merchant's catalog items. Its stock
+// is what is left to order; stock held for orders already placed stays held.
+func (r *MerchantRepository) UpdateCatalogItem(ctx context.Context, item *model.CatalogItem) error {
+	query := `
+		UPDATE catalog_items
+		SET name = $3, description = $4, category = $5, price = $6, available = $7, stock = $8, updated_at = $9
+		WHERE id = $1 AND merchant_id = $2
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		item.ID,
+		item.MerchantID,
+		item.Name,
+		item.Description,
+		item.Category,
+		item.Price,
+		item.Available,
+		item.Stock,
+		item.UpdatedAt,
+	).Scan(&item.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrCatalogItemNotFound
+		}
+		return fmt.Errorf("failed to update catalog item: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCatalogItem removes an item from a merchant's catalog. Orders already placed
+// keep the name and price they were placed with.
+func (r *MerchantRepository) DeleteCatalogItem(ctx context.Context, merchantID, itemID string) error {
+	tag, err := r.db.ExecContext(ctx, `DELETE FROM catalog_items WHERE id = $1 AND merchant_id = $2`, itemID, merchantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete catalog item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCatalogItemNotFound
+	}
+
+	return nil
+}
+
+// ListCatalogItems lists a merchant's catalog, ordered by category and name
+func (r *MerchantRepository) ListCatalogItems(ctx context.Context, merchantID string) ([]*model.CatalogItem, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM catalog_items
+		WHERE merchant_id = $1
+		ORDER BY category, name
+	`, catalogItemColumns)
+
+	return r.queryCatalogItems(ctx, query, merchantID)
+}
+
+// GetCatalogItems gets the items of a merchant's catalog with the given IDs, keyed by
+// ID. IDs that are not in the catalog are left out.
+func (r *MerchantRepository) GetCatalogItems(ctx context.Context, merchantID string, itemIDs []string) (map[string]*model.CatalogItem, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM catalog_items
+		WHERE merchant_id = $1 AND id = ANY($2)
+	`, catalogItemColumns)
+
+	items, err := r.queryCatalogItems(ctx, query, merchantID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*model.CatalogItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+	return byID, nil
+}
+
+func (r *MerchantRepository) queryCatalogItems(ctx context.Context, query string, args ...interface{}) ([]*model.CatalogItem, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query catalog items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*model.CatalogItem{}
+	for rows.Next() {
+		item := &model.CatalogItem{}
+		err := rows.Scan(
+			&item.ID,
+			&item.MerchantID,
+			&item.Name,
+			&item.Description,
+			&item.Category,
+			&item.Price,
+			&item.Available,
+			&item.Stock,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan catalog item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating catalog items: %w", err)
+	}
+
+	return items, nil
+}
+
+func scanMerchant(row pgx.Row) (*model.Merchant, error) {
+	merchant := &model.Merchant{}
+	err := row.Scan(
+		&merchant.ID,
+		&merchant.Name,
+		&merchant.City,
+		&merchant.Address,
+		&merchant.Latitude,
+		&merchant.Longitude,
+		&merchant.Active,
+		&merchant.CreatedAt,
+		&merchant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return merchant, nil
+}
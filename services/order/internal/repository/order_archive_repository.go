@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// archivedColumns are the columns of orders that are copied into orders_archive
+const archivedColumns = `
+	id, user_id, provider_id, order_type, status,
+	pickup_location, destination_location, items,
+	total_price, platform_fee, provider_fee, tip_amount, cancellation_fee,
+	delivery_proof_hash, frozen, at_risk, pickup_arrived_at,
+	transaction_id, blockchain_tx_hash, payment_method, payment_method_id, wallet_amount, points_redeemed, points_discount, return_of_order_id,
+	notes, created_at, updated_at, status_history, anonymized_at
+`
+
+// ArchiveOrders moves up to limit orders that are in one of statuses and were last updated
+// before a time from orders into orders_archive, oldest first, and reports how many it
+// moved. Frozen orders stay, as do orders whose raw locations have not been compressed
+// into a track yet, since their locations are deleted with them.
+func (r *OrderRepository) ArchiveOrders(ctx context.Context, statuses []model.OrderStatus, before time.Time, limit int) (int, error) {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+
+	query := `
+		SELECT id, created_at
+		FROM orders o
+		WHERE status = ANY($1)
+		  AND updated_at < $2
+		  AND NOT frozen
+		  AND (EXISTS (SELECT 1 FROM order_tracks t WHERE t.order_id = o.id)
+		       OR NOT EXISTS (SELECT 1 FROM order_locations l WHERE l.order_id = o.id))
+		ORDER BY updated_at
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	`
+
+	var archived int
+	err := r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		archived = 0
+
+		rows, err := tx.Query(ctx, query, names, before, limit)
+		if err != nil {
+			return fmt.Errorf("failed to query orders to archive: %w", err)
+		}
+		var orderIDs []string
+		years := make(map[int]bool)
+		for rows.Next() {
+			var orderID string
+			var createdAt time.Time
+			if err := rows.Scan(&orderID, &createdAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan order to archive: %w", err)
+			}
+			orderIDs = append(orderIDs, orderID)
+			years[createdAt.Year()] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating orders to archive: %w", err)
+		}
+		if len(orderIDs) == 0 {
+			return nil
+		}
+
+		for year := range years {
+			if err := ensureArchivePartitionTx(ctx, tx, year); err != nil {
+				return err
+			}
+		}
+
+		insert := `
+			INSERT INTO orders_archive (` + archivedColumns + `, archived_at)
+			SELECT ` + archivedColumns + `, $2
+			FROM orders
+			WHERE id = ANY($1)
+		`
+		if _, err := tx.Exec(ctx, insert, orderIDs, time.Now()); err != nil {
+			return fmt.Errorf("failed to copy orders to the archive: %w", err)
+		}
+
+		// Raw locations, delivery PINs, contact tokens and tracking links go with the order
+		if _, err := tx.Exec(ctx, `DELETE FROM orders WHERE id = ANY($1)`, orderIDs); err != nil {
+			return fmt.Errorf("failed to delete archived orders: %w", err)
+		}
+
+		archived = len(orderIDs)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return archived, nil
+}
+
+// ensureArchivePartitionTx creates the partition of orders_archive holding orders created
+// in a year, unless it exists. Instances archiving at once create it one at a time.
+func ensureArchivePartitionTx(ctx context.Context, tx pgx.Tx, year int) error {
+	name := fmt.Sprintf("orders_archive_y%d", year)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up archive partition: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('orders_archive_partitions'))`); err != nil {
+		return fmt.Errorf("failed to lock archive partitions: %w", err)
+	}
+
+	ddl := fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s PARTITION OF orders_archive FOR VALUES FROM ('%d-01-01') TO ('%d-01-01')`,
+		pgx.Identifier{name}.Sanitize(), year, year+1,
+	)
+	if _, err := tx.Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create archive partition for %d: %w", year, err)
+	}
+
+	return nil
+}
+
+// ListArchivedUserOrders lists every archived order of a user, newest first
+func (r *OrderRepository) ListArchivedUserOrders(ctx context.Context, userID string) ([]*model.Order, error) {
+	query := `
+		SELECT
+			id, user_id, provider_id, order_type, status,
+			pickup_location, destination_location, items,
+			total_price, platform_fee, provider_fee, tip_amount, cancellation_fee,
+			COALESCE(delivery_proof_hash, ''), frozen, at_risk,
+			transaction_id, blockchain_tx_hash, payment_method, COALESCE(payment_method_id, ''), wallet_amount, points_redeemed, points_discount, COALESCE(return_of_order_id, ''),
+			notes, created_at, updated_at, status_history
+		FROM orders_archive
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archived orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []*model.Order{}
+	for rows.Next() {
+		order := &model.Order{}
+		err := rows.Scan(
+			&order.ID,
+			&order.UserID,
+			&order.ProviderID,
+			&order.OrderType,
+			&order.Status,
+			&order.PickupLocation,
+			&order.DestinationLocation,
+			&order.Items,
+			&order.TotalPrice,
+			&order.PlatformFee,
+			&order.ProviderFee,
+			&order.TipAmount,
+			&order.CancellationFee,
+			&order.DeliveryProofHash,
+			&order.Frozen,
+			&order.AtRisk,
+			&order.TransactionID,
+			&order.BlockchainTxHash,
+			&order.PaymentMethod,
+			&order.PaymentMethodID,
+			&order.WalletAmount,
+			&order.PointsRedeemed,
+			&order.PointsDiscount,
+			&order.ReturnOfOrderID,
+			&order.Notes,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+			&order.StatusHistory,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan archived order: %w", err)
+		}
+		if err := r.decryptFields(order); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating archived orders: %w", err)
+	}
+
+	return orders, nil
+}
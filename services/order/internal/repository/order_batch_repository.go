@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// OrderBatchRepository handles database operations for batched deliveries
+type OrderBatchRepository struct {
+	db *database.PostgresDB
+}
+
+// NewOrderBatchRepository creates a new order batch repository
+func NewOrderBatchRepository(db *database.PostgresDB) *OrderBatchRepository {
+	return &OrderBatchRepository{
+		db: db,
+	}
+}
+
+// SaveBatch stores a new batch, or replaces the orders and stops of an existing one
+func (r *OrderBatchRepository) SaveBatch(ctx context.Context, batch *model.OrderBatch) error {
+	query := `
+		INSERT INTO order_batches (id, provider_id, order_ids, stops, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE
+		SET order_ids = EXCLUDED.order_ids, stops = EXCLUDED.stops, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		batch.ID,
+		batch.ProviderID,
+		batch.OrderIDs,
+		batch.Stops,
+		batch.CreatedAt,
+		batch.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save order batch: %w", err)
+	}
+
+	return nil
+}
+
+// GetBatchByOrder gets the latest batch an order belongs to
+func (r *OrderBatchRepository) GetBatchByOrder(ctx context.Context, orderID string) (*model.OrderBatch, error) {
+	query := `
+		SELECT id, provider_id, order_ids, stops, created_at, updated_at
+		FROM order_batches
+		WHERE $1 = ANY(order_ids)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	batch := &model.OrderBatch{}
+	err := r.db.QueryRowContext(ctx, query, orderID).Scan(
+		&batch.ID,
+		&batch.ProviderID,
+		&batch.OrderIDs,
+		&batch.Stops,
+		&batch.CreatedAt,
+		&batch.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrOrderBatchNotFound
+		}
+		return nil, fmt.Errorf("failed to get order batch: %w", err)
+	}
+
+	return batch, nil
+}
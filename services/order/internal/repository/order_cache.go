@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/pkg/cache"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// orderChangedChannel is the channel Postgres notifies with the ID of every order updated
+// or deleted in orders or orders_archive
+const orderChangedChannel = "order_changed"
+
+// orderCacheKeyPrefix prefixes the cache keys of orders, which may share a Redis
+// database with other services
+const orderCacheKeyPrefix = "order-service:order:"
+
+// orderCacheRetryDelay is how long the cache waits before listening for changes again
+// after losing its connection
+const orderCacheRetryDelay = 5 * time.Second
+
+var (
+	orderCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "order_cache_lookups_total",
+		Help: "Orders looked up by ID in the order cache, by result (hit or miss)",
+	}, []string{"result"})
+
+	orderCacheInvalidations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "order_cache_invalidations_total",
+		Help: "Orders dropped from the order cache because they changed",
+	})
+)
+
+// OrderCache keeps orders read by ID for a short time, so the many reads of the same
+// order while it is in progress do not each reach the database. Orders are cached as
+// stored, with addresses and notes still encrypted. The repository drops an order after
+// its own writes, and Listen drops it after writes made anywhere else.
+type OrderCache struct {
+	store cache.Store
+	ttl   time.Duration
+}
+
+// NewOrderCache creates an order cache keeping entries in store for ttl
+func NewOrderCache(store cache.Store, ttl time.Duration) *OrderCache {
+	return &OrderCache{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// get returns a cached order, or false when it is not cached. A cache that cannot be
+// read counts as a miss.
+func (c *OrderCache) get(ctx context.Context, orderID string) (*model.Order, bool) {
+	data, found, err := c.store.Get(ctx, orderCacheKeyPrefix+orderID)
+	if err != nil {
+		log.Printf("Failed to read order %s from cache: %v", orderID, err)
+	}
+	if err != nil || !found {
+		orderCacheLookups.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	order := &model.Order{}
+	if err := json.Unmarshal(data, order); err != nil {
+		log.Printf("Failed to decode cached order %s: %v", orderID, err)
+		orderCacheLookups.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	orderCacheLookups.WithLabelValues("hit").Inc()
+	return order, true
+}
+
+// set caches an order as stored
+func (c *OrderCache) set(ctx context.Context, order *model.Order) {
+	data, err := json.Marshal(order)
+	if err != nil {
+		log.Printf("Failed to encode order %s for cache: %v", order.ID, err)
+		return
+	}
+
+	if err := c.store.Set(ctx, orderCacheKeyPrefix+order.ID, data, c.ttl); err != nil {
+		log.Printf("Failed to cache order %s: %v", order.ID, err)
+	}
+}
+
+// Invalidate drops orders from the cache, so their next read goes to the database
+func (c *OrderCache) Invalidate(ctx context.Context, orderIDs ...string) {
+	keys := make([]string, len(orderIDs))
+	for i, orderID := range orderIDs {
+		keys[i] = orderCacheKeyPrefix + orderID
+	}
+
+	if err := c.store.Delete(ctx, keys...); err != nil {
+		log.Printf("Failed to drop orders from cache: %v", err)
+		return
+	}
+	orderCacheInvalidations.Add(float64(len(keys)))
+}
+
+// Listen drops orders from the cache as Postgres reports them changed, by any instance
+// and any repository, until ctx is cancelled. It listens again after losing its
+// connection, dropping every cached order since changes may have been missed meanwhile.
+func (c *OrderCache) Listen(ctx context.Context, db *database.PostgresDB) {
+	for {
+		err := c.listen(ctx, db)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Stopped listening for order changes, retrying in %s: %v", orderCacheRetryDelay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(orderCacheRetryDelay):
+		}
+	}
+}
+
+// listen listens for order changes on a connection of its own until it fails
+func (c *OrderCache) listen(ctx context.Context, db *database.PostgresDB) error {
+	pooled, err := db.Pool().Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// A listening connection must not go back to the pool, where queries would share it
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+orderChangedChannel); err != nil {
+		return fmt.Errorf("failed to listen for order changes: %w", err)
+	}
+	if err := c.store.DeletePrefix(ctx, orderCacheKeyPrefix); err != nil {
+		return fmt.Errorf("failed to clear order cache: %w", err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		c.Invalidate(ctx, notification.Payload)
+	}
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// HandOffOrder moves an order to its new provider, storing the handoff and appending
+// entry to the order's status history in the status the order is in. It fails with
+// ErrProviderChanged if the order no longer has the provider it is handed off from, with
+// ErrOrderStatusChanged if it is no longer taken, and with ErrOrderFrozen if it is frozen.
+func (r *OrderRepository) HandOffOrder(ctx context.Context, handoff *model.OrderHandoff, entry model.StatusHistory) error {
+	defer r.invalidate(ctx, handoff.OrderID)
+
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		var providerID string
+		var currentStatus model.OrderStatus
+		var frozen bool
+		err := tx.QueryRow(ctx, `
+			SELECT COALESCE(provider_id, ''), status, frozen
+			FROM orders
+			WHERE id = $1
+			FOR UPDATE
+		`, handoff.OrderID).Scan(&providerID, &currentStatus, &frozen)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+		switch {
+		case providerID != handoff.FromProviderID:
+			return ErrProviderChanged
+		case !currentStatus.Taken():
+			return ErrOrderStatusChanged
+		case frozen:
+			return ErrOrderFrozen
+		}
+
+		entry.Status = currentStatus
+		_, err = tx.Exec(ctx, `
+			UPDATE orders
+			SET provider_id = $2, status_history = COALESCE(status_history, '[]'::JSONB) || $3::JSONB, updated_at = $4
+			WHERE id = $1
+		`, handoff.OrderID, handoff.ToProviderID, model.StatusHistories{entry}, entry.Timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to hand off order: %w", err)
+		}
+
+		return createOrderHandoffTx(ctx, tx, handoff)
+	})
+}
+
+// createOrderHandoffTx stores a handoff within tx
+func createOrderHandoffTx(ctx context.Context, tx pgx.Tx, handoff *model.OrderHandoff) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO order_handoffs (id, order_id, from_provider_id, to_provider_id, requested_by, reason,
+		                            latitude, longitude, trip_progress, handed_off_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`,
+		handoff.ID,
+		handoff.OrderID,
+		handoff.FromProviderID,
+		handoff.ToProviderID,
+		handoff.RequestedBy,
+		handoff.Reason,
+		handoff.Latitude,
+		handoff.Longitude,
+		handoff.TripProgress,
+		handoff.HandedOffAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create order handoff: %w", err)
+	}
+
+	return nil
+}
+
+// listOrderHandoffsTx lists an order's handoffs within tx, oldest first
+func listOrderHandoffsTx(ctx context.Context, tx pgx.Tx, orderID string) ([]*model.OrderHandoff, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, order_id, from_provider_id, to_provider_id, requested_by, reason,
+		       latitude, longitude, trip_progress, handed_off_at
+		FROM order_handoffs
+		WHERE order_id = $1
+		ORDER BY handed_off_at, id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order handoffs: %w", err)
+	}
+	defer rows.Close()
+
+	var handoffs []*model.OrderHandoff
+	for rows.Next() {
+		handoff := &model.OrderHandoff{}
+		err := rows.Scan(
+			&handoff.ID,
+			&handoff.OrderID,
+			&handoff.FromProviderID,
+			&handoff.ToProviderID,
+			&handoff.RequestedBy,
+			&handoff.Reason,
+			&handoff.Latitude,
+			&handoff.Longitude,
+			&handoff.TripProgress,
+			&handoff.HandedOffAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order handoff: %w", err)
+		}
+		handoffs = append(handoffs, handoff)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list order handoffs: %w", err)
+	}
+
+	return handoffs, nil
+}
@@ -3,15 +3,19 @@ package repository
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/order-api-microservices/pkg/database"
 	"github.com/order-api-microservices/services/order/internal/model"
 )
 
+// locationPartitionName matches the names of the monthly partitions of order_locations
+var locationPartitionName = regexp.MustCompile(`^order_locations_(\d{4})_(\d{2})$`)
+
 // OrderLocationRepository handles operations related to order locations
 type OrderLocationRepository struct {
 	db *database.PostgresDB
@@ -53,6 +57,35 @@ func (r *OrderLocationRepository) CreateOrderLocation(ctx context.Context, order
 	return nil
 }
 
+// CreateOrderLocations bulk inserts location entries with COPY, keeping their timestamps
+func (r *OrderLocationRepository) CreateOrderLocations(ctx context.Context, locations []*model.OrderLocation) (int64, error) {
+	for _, location := range locations {
+		if location.ID == "" {
+			location.ID = uuid.New().String()
+		}
+	}
+
+	columns := []string{"id", "order_id", "provider_id", "latitude", "longitude", "timestamp"}
+	copied, err := r.db.CopyFrom(ctx, pgx.Identifier{"order_locations"}, columns,
+		pgx.CopyFromSlice(len(locations), func(i int) ([]interface{}, error) {
+			location := locations[i]
+			return []interface{}{
+				location.ID,
+				location.OrderID,
+				location.ProviderID,
+				location.Latitude,
+				location.Longitude,
+				location.Timestamp,
+			}, nil
+		}),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy order locations: %w", err)
+	}
+
+	return copied, nil
+}
+
 // GetLatestOrderLocation gets the latest location for an order
 func (r *OrderLocationRepository) GetLatestOrderLocation(ctx context.Context, orderID string) (*model.OrderLocation, error) {
 	query := `
@@ -164,6 +197,258 @@ func (r *OrderLocationRepository) GetProviderCurrentOrders(ctx context.Context,
 	return orderIDs, nil
 }
 
+// ListOrderLocations gets every location entry for an order, oldest first
+func (r *OrderLocationRepository) ListOrderLocations(ctx context.Context, orderID string) ([]*model.OrderLocation, error) {
+	query := `
+		SELECT id, order_id, provider_id, latitude, longitude, timestamp
+		FROM order_locations
+		WHERE order_id = $1
+		ORDER BY timestamp
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := []*model.OrderLocation{}
+	for rows.Next() {
+		var location model.OrderLocation
+		err := rows.Scan(
+			&location.ID,
+			&location.OrderID,
+			&location.ProviderID,
+			&location.Latitude,
+			&location.Longitude,
+			&location.Timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order location: %w", err)
+		}
+
+		locations = append(locations, &location)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order locations: %w", err)
+	}
+
+	return locations, nil
+}
+
+// ListOrdersToArchive lists up to limit orders in any of statuses that have location
+// entries but no archived track
+func (r *OrderLocationRepository) ListOrdersToArchive(ctx context.Context, statuses []model.OrderStatus, limit int) ([]string, error) {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+
+	query := `
+		SELECT o.id
+		FROM orders o
+		WHERE o.status = ANY($1)
+		  AND EXISTS (SELECT 1 FROM order_locations l WHERE l.order_id = o.id)
+		  AND NOT EXISTS (SELECT 1 FROM order_tracks t WHERE t.order_id = o.id)
+		ORDER BY o.updated_at
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, names, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders to archive: %w", err)
+	}
+	defer rows.Close()
+
+	orderIDs := []string{}
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan order ID: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating orders to archive: %w", err)
+	}
+
+	return orderIDs, nil
+}
+
+// CreateTrack stores an order's archived track, replacing any earlier one
+func (r *OrderLocationRepository) CreateTrack(ctx context.Context, track *model.OrderTrack) error {
+	query := `
+		INSERT INTO order_tracks (order_id, provider_id, polyline, point_count, started_at, ended_at, archived_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (order_id) DO UPDATE
+		SET provider_id = EXCLUDED.provider_id, polyline = EXCLUDED.polyline, point_count = EXCLUDED.point_count,
+			started_at = EXCLUDED.started_at, ended_at = EXCLUDED.ended_at, archived_at = EXCLUDED.archived_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		track.OrderID,
+		track.ProviderID,
+		track.Polyline,
+		track.PointCount,
+		track.StartedAt,
+		track.EndedAt,
+		track.ArchivedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create order track: %w", err)
+	}
+
+	return nil
+}
+
+// GetTrack gets an order's archived track
+func (r *OrderLocationRepository) GetTrack(ctx context.Context, orderID string) (*model.OrderTrack, error) {
+	query := `
+		SELECT order_id, provider_id, polyline, point_count, started_at, ended_at, archived_at
+		FROM order_tracks
+		WHERE order_id = $1
+	`
+
+	track := &model.OrderTrack{}
+	err := r.db.QueryRowContext(ctx, query, orderID).Scan(
+		&track.OrderID,
+		&track.ProviderID,
+		&track.Polyline,
+		&track.PointCount,
+		&track.StartedAt,
+		&track.EndedAt,
+		&track.ArchivedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrOrderTrackNotFound
+		}
+		return nil, fmt.Errorf("failed to get order track: %w", err)
+	}
+
+	return track, nil
+}
+
+// DeleteArchivedLocations deletes location entries in the default partition recorded
+// before cutoff whose order has an archived track, and reports how many were deleted.
+// Entries in monthly partitions go when their partition is dropped.
+func (r *OrderLocationRepository) DeleteArchivedLocations(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `
+		DELETE FROM order_locations_default l
+		USING order_tracks t
+		WHERE l.order_id = t.order_id AND l.timestamp < $1
+	`
+
+	ct, err := r.db.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete archived order locations: %w", err)
+	}
+
+	return ct.RowsAffected(), nil
+}
+
+// CreateLocationPartitions creates the monthly partitions of order_locations for the
+// month holding from and the months after it, months in all, unless they exist
+func (r *OrderLocationRepository) CreateLocationPartitions(ctx context.Context, from time.Time, months int) error {
+	from = from.UTC()
+
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		// Instances creating the same partition at once would collide on its name
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('order_locations_partitions'))`); err != nil {
+			return fmt.Errorf("failed to lock location partitions: %w", err)
+		}
+
+		for i := 0; i < months; i++ {
+			month := time.Date(from.Year(), from.Month()+time.Month(i), 1, 0, 0, 0, 0, time.UTC)
+			if _, err := tx.Exec(ctx, `SELECT create_order_locations_partition($1)`, month); err != nil {
+				return fmt.Errorf("failed to create location partition for %s: %w", month.Format("2006-01"), err)
+			}
+		}
+		return nil
+	})
+}
+
+// DropExpiredLocationPartitions drops the monthly partitions of order_locations whose
+// whole month is before cutoff, and returns their names. Locations in them whose order
+// has no archived track yet are moved to the default partition first, so they are not
+// lost before they are compressed.
+func (r *OrderLocationRepository) DropExpiredLocationPartitions(ctx context.Context, cutoff time.Time) ([]string, error) {
+	query := `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'order_locations'::regclass
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list location partitions: %w", err)
+	}
+	var expired []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan location partition: %w", err)
+		}
+
+		match := locationPartitionName.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		year, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		monthEnd := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+		if !monthEnd.After(cutoff) {
+			expired = append(expired, name)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating location partitions: %w", err)
+	}
+
+	var dropped []string
+	for _, name := range expired {
+		if err := r.dropLocationPartition(ctx, name); err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, name)
+	}
+
+	return dropped, nil
+}
+
+// dropLocationPartition detaches a monthly partition, moves its locations of orders
+// without a track into the default partition and drops it
+func (r *OrderLocationRepository) dropLocationPartition(ctx context.Context, name string) error {
+	partition := pgx.Identifier{name}.Sanitize()
+
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `ALTER TABLE order_locations DETACH PARTITION `+partition); err != nil {
+			return fmt.Errorf("failed to detach location partition %s: %w", name, err)
+		}
+
+		// Once detached, its month is no longer covered, so the rows route to the default partition
+		move := `
+			INSERT INTO order_locations (id, order_id, provider_id, latitude, longitude, timestamp)
+			SELECT id, order_id, provider_id, latitude, longitude, timestamp
+			FROM ` + partition + ` l
+			WHERE NOT EXISTS (SELECT 1 FROM order_tracks t WHERE t.order_id = l.order_id)
+		`
+		if _, err := tx.Exec(ctx, move); err != nil {
+			return fmt.Errorf("failed to keep unarchived locations of %s: %w", name, err)
+		}
+
+		if _, err := tx.Exec(ctx, `DROP TABLE `+partition); err != nil {
+			return fmt.Errorf("failed to drop location partition %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
 // DeleteOrderLocations deletes all location entries for an order
 func (r *OrderLocationRepository) DeleteOrderLocations(ctx context.Context, orderID string) error {
 	query := `
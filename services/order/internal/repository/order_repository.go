@@ -4,53 +4,101 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/crypto"
 	"github.com/order-api-microservices/pkg/database"
+	eventspb "github.com/order-api-microservices/proto/events"
 	"github.com/order-api-microservices/services/order/internal/model"
 )
 
 var (
-	ErrOrderNotFound = errors.New("order not found")
-	ErrInvalidData   = errors.New("invalid data")
+	ErrInvalidData = errors.New("invalid data")
 )
 
-// OrderRepository handles database operations for orders
+// OrderRepository handles database operations for orders. The pickup and destination
+// addresses and the notes of an order are encrypted with keys before they are stored and
+// decrypted when read.
 type OrderRepository struct {
-	db *database.PostgresDB
+	db    *database.PostgresDB
+	keys  *crypto.KeyRing
+	cache *OrderCache
 }
 
-// NewOrderRepository creates a new order repository
-func NewOrderRepository(db *database.PostgresDB) *OrderRepository {
+// NewOrderRepository creates a new order repository. A nil key ring stores addresses and
+// notes in plaintext.
+func NewOrderRepository(db *database.PostgresDB, keys *crypto.KeyRing) *OrderRepository {
 	return &OrderRepository{
-		db: db,
+		db:   db,
+		keys: keys,
 	}
 }
 
-// CreateOrder creates a new order in the database
-func (r *OrderRepository) CreateOrder(ctx context.Context, order *model.Order) error {
+// UseCache makes GetOrderByID read orders through cache
+func (r *OrderRepository) UseCache(cache *OrderCache) {
+	r.cache = cache
+}
+
+// invalidate drops an order written to from the cache, if there is one
+func (r *OrderRepository) invalidate(ctx context.Context, orderID string) {
+	if r.cache != nil {
+		r.cache.Invalidate(ctx, orderID)
+	}
+}
+
+// DuplicateLocationTolerance is how far apart, in degrees of latitude and longitude, two
+// pickups or destinations can be and still count as the same place; about 11 m
+const DuplicateLocationTolerance = 0.0001
+
+// CreateOrder creates a new order in the database. An order whose ID is taken fails with
+// ErrDuplicateOrder. When duplicateSince is set, an order that repeats one the user placed
+// since then, with the same type, pickup and destination and not cancelled, fails with a
+// DuplicateOrderError naming that order.
+func (r *OrderRepository) CreateOrder(ctx context.Context, order *model.Order, duplicateSince time.Time) error {
 	if order.ID == "" || order.UserID == "" {
 		return ErrInvalidData
 	}
 
+	pickup, destination, notes, err := r.encryptFields(order)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO orders (
 			id, user_id, provider_id, order_type, status, 
 			pickup_location, destination_location, items, 
 			total_price, platform_fee, provider_fee, 
-			transaction_id, blockchain_tx_hash, payment_method, 
+			transaction_id, blockchain_tx_hash, payment_method, payment_method_id, wallet_amount, points_redeemed, points_discount, return_of_order_id, 
 			notes, created_at, updated_at, status_history
 		) VALUES (
 			$1, $2, $3, $4, $5, 
 			$6, $7, $8, 
 			$9, $10, $11, 
-			$12, $13, $14, 
+			$12, $13, $14, NULLIF($19, ''), $20, $21, $22, NULLIF($23, ''), 
 			$15, $16, $17, $18
 		)
 	`
 
-	_, err := r.db.ExecContext(
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if !duplicateSince.IsZero() {
+		existingID, err := findDuplicateOrderTx(ctx, tx, order, duplicateSince)
+		if err != nil {
+			return err
+		}
+		if existingID != "" {
+			return &DuplicateOrderError{OrderID: existingID}
+		}
+	}
+
+	_, err = tx.Exec(
 		ctx,
 		query,
 		order.ID,
@@ -58,8 +106,8 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *model.Order) e
 		order.ProviderID,
 		order.OrderType,
 		order.Status,
-		order.PickupLocation,
-		order.DestinationLocation,
+		pickup,
+		destination,
 		order.Items,
 		order.TotalPrice,
 		order.PlatformFee,
@@ -67,29 +115,150 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *model.Order) e
 		order.TransactionID,
 		order.BlockchainTxHash,
 		order.PaymentMethod,
-		order.Notes,
+		notes,
 		order.CreatedAt,
 		order.UpdatedAt,
 		order.StatusHistory,
+		order.PaymentMethodID,
+		order.WalletAmount,
+		order.PointsRedeemed,
+		order.PointsDiscount,
+		order.ReturnOfOrderID,
 	)
 
 	if err != nil {
+		if database.IsUniqueViolation(err, "orders_pkey") {
+			return ErrDuplicateOrder
+		}
+		if database.IsUniqueViolation(err, "idx_orders_open_return") {
+			return ErrReturnExists
+		}
 		return fmt.Errorf("failed to create order: %w", err)
 	}
 
+	// Partners hear about the order only once it is committed
+	err = enqueueWebhookEventTx(ctx, tx, model.WebhookOrderCreated, order.OrderType, &eventspb.OrderCreated{
+		OrderId:    order.ID,
+		UserId:     order.UserID,
+		ProviderId: order.ProviderID,
+		OrderType:  string(order.OrderType),
+		Status:     string(order.Status),
+		TotalPrice: order.TotalPrice,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = recordOrderEventTx(ctx, tx, &model.OrderEvent{
+		OrderID:     order.ID,
+		EventType:   model.OrderEventCreated,
+		OrderType:   order.OrderType,
+		City:        order.PickupLocation.City,
+		ProviderID:  order.ProviderID,
+		UpdatedBy:   order.UserID,
+		Status:      order.Status,
+		TotalPrice:  order.TotalPrice,
+		PlatformFee: order.PlatformFee,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-// GetOrderByID gets an order by its ID
+// findDuplicateOrderTx finds the latest order the user placed since a time with the same
+// type, pickup and destination as order that was not cancelled, and returns its ID, or an
+// empty ID if there is none. It first locks the user's new orders until the transaction
+// ends, so two copies of an order sent at once cannot both miss each other.
+func findDuplicateOrderTx(ctx context.Context, tx pgx.Tx, order *model.Order, since time.Time) (string, error) {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "create-order:"+order.UserID); err != nil {
+		return "", fmt.Errorf("failed to lock user orders: %w", err)
+	}
+
+	query := `
+		SELECT id
+		FROM orders
+		WHERE user_id = $1
+			AND order_type = $2
+			AND created_at >= $3
+			AND status <> $4
+			AND ABS((pickup_location->>'latitude')::float8 - $5) < $9
+			AND ABS((pickup_location->>'longitude')::float8 - $6) < $9
+			AND ABS((destination_location->>'latitude')::float8 - $7) < $9
+			AND ABS((destination_location->>'longitude')::float8 - $8) < $9
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var orderID string
+	err := tx.QueryRow(ctx, query,
+		order.UserID,
+		order.OrderType,
+		since,
+		model.StatusCancelled,
+		order.PickupLocation.Latitude,
+		order.PickupLocation.Longitude,
+		order.DestinationLocation.Latitude,
+		order.DestinationLocation.Longitude,
+		DuplicateLocationTolerance,
+	).Scan(&orderID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to look for duplicate orders: %w", err)
+	}
+
+	return orderID, nil
+}
+
+// GetOrderByID gets an order by its ID, reading it from the archive once it has been
+// archived. With a cache, recently read orders come from the cache.
 func (r *OrderRepository) GetOrderByID(ctx context.Context, orderID string) (*model.Order, error) {
+	var order *model.Order
+	var cached bool
+	if r.cache != nil {
+		order, cached = r.cache.get(ctx, orderID)
+	}
+
+	if !cached {
+		var err error
+		order, err = r.getOrder(ctx, "orders", orderID)
+		if errors.Is(err, ErrOrderNotFound) {
+			order, err = r.getOrder(ctx, "orders_archive", orderID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if r.cache != nil {
+			r.cache.set(ctx, order)
+		}
+	}
+
+	if err := r.decryptFields(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// getOrder gets an order as stored, still encrypted, from table, which is orders or
+// orders_archive
+func (r *OrderRepository) getOrder(ctx context.Context, table, orderID string) (*model.Order, error) {
 	query := `
 		SELECT
 			id, user_id, provider_id, order_type, status, 
 			pickup_location, destination_location, items, 
-			total_price, platform_fee, provider_fee, 
-			transaction_id, blockchain_tx_hash, payment_method, 
+			total_price, platform_fee, provider_fee, tip_amount, cancellation_fee, 
+			COALESCE(delivery_proof_hash, ''), frozen, at_risk, 
+			transaction_id, blockchain_tx_hash, payment_method, COALESCE(payment_method_id, ''), wallet_amount, points_redeemed, points_discount, COALESCE(return_of_order_id, ''), 
 			notes, created_at, updated_at, status_history
-		FROM orders
+		FROM ` + table + `
 		WHERE id = $1
 	`
 
@@ -106,9 +275,19 @@ func (r *OrderRepository) GetOrderByID(ctx context.Context, orderID string) (*mo
 		&order.TotalPrice,
 		&order.PlatformFee,
 		&order.ProviderFee,
+		&order.TipAmount,
+		&order.CancellationFee,
+		&order.DeliveryProofHash,
+		&order.Frozen,
+		&order.AtRisk,
 		&order.TransactionID,
 		&order.BlockchainTxHash,
 		&order.PaymentMethod,
+		&order.PaymentMethodID,
+		&order.WalletAmount,
+		&order.PointsRedeemed,
+		&order.PointsDiscount,
+		&order.ReturnOfOrderID,
 		&order.Notes,
 		&order.CreatedAt,
 		&order.UpdatedAt,
@@ -130,6 +309,7 @@ func (r *OrderRepository) UpdateOrder(ctx context.Context, order *model.Order) e
 	if order.ID == "" {
 		return ErrInvalidData
 	}
+	defer r.invalidate(ctx, order.ID)
 
 	query := `
 		UPDATE orders
@@ -155,6 +335,11 @@ func (r *OrderRepository) UpdateOrder(ctx context.Context, order *model.Order) e
 
 	order.UpdatedAt = time.Now()
 
+	pickup, destination, notes, err := r.encryptFields(order)
+	if err != nil {
+		return err
+	}
+
 	ct, err := r.db.ExecContext(
 		ctx,
 		query,
@@ -163,8 +348,8 @@ func (r *OrderRepository) UpdateOrder(ctx context.Context, order *model.Order) e
 		order.ProviderID,
 		order.OrderType,
 		order.Status,
-		order.PickupLocation,
-		order.DestinationLocation,
+		pickup,
+		destination,
 		order.Items,
 		order.TotalPrice,
 		order.PlatformFee,
@@ -172,7 +357,7 @@ func (r *OrderRepository) UpdateOrder(ctx context.Context, order *model.Order) e
 		order.TransactionID,
 		order.BlockchainTxHash,
 		order.PaymentMethod,
-		order.Notes,
+		notes,
 		order.UpdatedAt,
 		order.StatusHistory,
 	)
@@ -188,25 +373,528 @@ func (r *OrderRepository) UpdateOrder(ctx context.Context, order *model.Order) e
 	return nil
 }
 
+// UpdateOrderDetails saves the destination, items, notes, total and fees of an order
+// changed before pickup, moving it to the status of entry and appending entry to its
+// status history. The order is only changed while it is still in status from and not
+// frozen; ErrOrderStatusChanged is returned otherwise.
+func (r *OrderRepository) UpdateOrderDetails(ctx context.Context, order *model.Order, from model.OrderStatus, entry model.StatusHistory) error {
+	defer r.invalidate(ctx, order.ID)
+
+	query := `
+		UPDATE orders
+		SET
+			status = $3,
+			destination_location = $4,
+			items = $5,
+			notes = $6,
+			total_price = $7,
+			platform_fee = $8,
+			provider_fee = $9,
+			status_history = COALESCE(status_history, '[]'::JSONB) || $10::JSONB,
+			updated_at = $11
+		WHERE id = $1 AND status = $2 AND NOT frozen
+	`
+
+	_, destination, notes, err := r.encryptFields(order)
+	if err != nil {
+		return err
+	}
+
+	ct, err := r.db.ExecContext(
+		ctx,
+		query,
+		order.ID,
+		from,
+		entry.Status,
+		destination,
+		order.Items,
+		notes,
+		order.TotalPrice,
+		order.PlatformFee,
+		order.ProviderFee,
+		model.StatusHistories{entry},
+		entry.Timestamp,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update order details: %w", err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return ErrOrderStatusChanged
+	}
+
+	return nil
+}
+
+// SetBlockchainTxHash sets just the blockchain transaction hash of an order
+func (r *OrderRepository) SetBlockchainTxHash(ctx context.Context, orderID, txHash string) error {
+	return r.setColumn(ctx, orderID, "blockchain_tx_hash", txHash)
+}
+
+// SetProviderID sets just the provider of an order; an empty ID leaves it unassigned.
+// The status change that goes with it is recorded on its own, with UpdateOrderStatus.
+func (r *OrderRepository) SetProviderID(ctx context.Context, orderID, providerID string) error {
+	return r.setColumn(ctx, orderID, "provider_id", providerID)
+}
+
+// SetTransactionID sets just the payment transaction ID of an order
+func (r *OrderRepository) SetTransactionID(ctx context.Context, orderID, transactionID string) error {
+	return r.setColumn(ctx, orderID, "transaction_id", transactionID)
+}
+
+// setColumn updates one column of an order, so a write made in the background cannot
+// undo changes made to the rest of the order meanwhile, as a full UpdateOrder would.
+// column is always a literal of the callers above.
+func (r *OrderRepository) setColumn(ctx context.Context, orderID, column string, value interface{}) error {
+	defer r.invalidate(ctx, orderID)
+
+	query := fmt.Sprintf(`
+		UPDATE orders
+		SET %s = $2, updated_at = $3
+		WHERE id = $1
+	`, column)
+
+	ct, err := r.db.ExecContext(ctx, query, orderID, value, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", strings.ReplaceAll(column, "_", " "), err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return ErrOrderNotFound
+	}
+
+	return nil
+}
+
+// MarkPickupArrival records when the provider first reached an order's pickup location.
+// It reports false, without error, when the arrival was already recorded.
+func (r *OrderRepository) MarkPickupArrival(ctx context.Context, orderID string, at time.Time) (bool, error) {
+	defer r.invalidate(ctx, orderID)
+
+	query := `
+		UPDATE orders
+		SET pickup_arrived_at = $2, updated_at = $2
+		WHERE id = $1 AND pickup_arrived_at IS NULL
+	`
+
+	ct, err := r.db.ExecContext(ctx, query, orderID, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to record pickup arrival: %w", err)
+	}
+
+	return ct.RowsAffected() > 0, nil
+}
+
+// GetPickupArrival gets when the provider first reached an order's pickup location, or
+// nil if they have not yet
+func (r *OrderRepository) GetPickupArrival(ctx context.Context, orderID string) (*time.Time, error) {
+	var arrivedAt *time.Time
+	err := r.db.QueryRowContext(ctx, `SELECT pickup_arrived_at FROM orders WHERE id = $1`, orderID).Scan(&arrivedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to get pickup arrival: %w", err)
+	}
+
+	return arrivedAt, nil
+}
+
 // UpdateOrderStatus updates just the status of an order
 func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID string, status model.OrderStatus, updatedBy, notes string) error {
-	// Start a transaction
-	tx, err := r.db.BeginTx(ctx)
+	defer r.invalidate(ctx, orderID)
+
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		return updateOrderStatusTx(ctx, tx, orderID, status, updatedBy, notes)
+	})
+}
+
+// UpdateOrderStatusFrom changes an order's status only if it is still in the expected status.
+// It reports false, without error, when the order has already moved on.
+func (r *OrderRepository) UpdateOrderStatusFrom(ctx context.Context, orderID string, from, to model.OrderStatus, updatedBy, notes string) (bool, error) {
+	defer r.invalidate(ctx, orderID)
+
+	var updated bool
+	err := r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		updated = false
+
+		var currentStatus model.OrderStatus
+		err := tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&currentStatus)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+		if currentStatus != from {
+			return nil
+		}
+
+		if err := updateOrderStatusTx(ctx, tx, orderID, to, updatedBy, notes); err != nil {
+			return err
+		}
+		updated = true
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return false, err
+	}
+
+	return updated, nil
+}
+
+// CancelOrder cancels an order and records the cancellation fee charged for it. The
+// notes go into the status history; the reason alone is counted by analytics. Unless
+// from is empty, the order is only cancelled while it is still in that status, and
+// ErrOrderStatusChanged is returned otherwise.
+func (r *OrderRepository) CancelOrder(ctx context.Context, orderID string, from model.OrderStatus, cancelledBy, reason, notes string, fee int64) error {
+	defer r.invalidate(ctx, orderID)
+
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		if from != "" {
+			var currentStatus model.OrderStatus
+			err := tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&currentStatus)
+			if err != nil {
+				if err == pgx.ErrNoRows {
+					return ErrOrderNotFound
+				}
+				return fmt.Errorf("failed to get order: %w", err)
+			}
+			if currentStatus != from {
+				return ErrOrderStatusChanged
+			}
+		}
+
+		if err := changeOrderStatusTx(ctx, tx, orderID, model.StatusCancelled, cancelledBy, notes, reason); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `UPDATE orders SET cancellation_fee = $2 WHERE id = $1`, orderID, fee)
+		if err != nil {
+			return fmt.Errorf("failed to record cancellation fee: %w", err)
+		}
+		return nil
+	})
+}
+
+// CountActiveOrders counts the orders in each status other than the finished ones
+func (r *OrderRepository) CountActiveOrders(ctx context.Context, finished []model.OrderStatus) (map[model.OrderStatus]int64, error) {
+	names := make([]string, len(finished))
+	for i, status := range finished {
+		names[i] = string(status)
+	}
+
+	query := `
+		SELECT status, COUNT(*)
+		FROM orders
+		WHERE status <> ALL($1)
+		GROUP BY status
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count active orders: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[model.OrderStatus]int64)
+	for rows.Next() {
+		var status model.OrderStatus
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan active order count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating active order counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// ListAwaitingPayment lists the IDs of orders paid with the given method that are still waiting for payment
+func (r *OrderRepository) ListAwaitingPayment(ctx context.Context, method model.PaymentMethod) ([]string, error) {
+	query := `
+		SELECT id
+		FROM orders
+		WHERE payment_method = $1 AND status = $2
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, method, model.StatusPaymentPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders awaiting payment: %w", err)
+	}
+	defer rows.Close()
+
+	orderIDs := []string{}
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan order ID: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating orders awaiting payment: %w", err)
+	}
+
+	return orderIDs, nil
+}
+
+// ListUnpaidOrders lists up to limit IDs of orders that have been waiting for payment
+// since before a time, oldest first. An order waits from its latest move to
+// PAYMENT_PENDING in its status history, or from when it was created if there is none.
+func (r *OrderRepository) ListUnpaidOrders(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT id
+		FROM (
+			SELECT id, COALESCE(
+				(SELECT MAX((entry->>'timestamp')::TIMESTAMPTZ)
+				 FROM jsonb_array_elements(status_history) AS entry
+				 WHERE entry->>'status' = $1),
+				created_at) AS pending_since
+			FROM orders
+			WHERE status = $1
+		) AS unpaid
+		WHERE pending_since < $2
+		ORDER BY pending_since
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, model.StatusPaymentPending, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unpaid orders: %w", err)
+	}
+	defer rows.Close()
+
+	orderIDs := []string{}
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan order ID: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
 	}
-	defer tx.Rollback(ctx)
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating unpaid orders: %w", err)
+	}
+
+	return orderIDs, nil
+}
+
+// ListOrdersByStatus lists the IDs of orders in any of statuses, oldest first
+func (r *OrderRepository) ListOrdersByStatus(ctx context.Context, statuses ...model.OrderStatus) ([]string, error) {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+
+	query := `
+		SELECT id
+		FROM orders
+		WHERE status = ANY($1)
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders by status: %w", err)
+	}
+	defer rows.Close()
+
+	orderIDs := []string{}
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan order ID: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating orders by status: %w", err)
+	}
+
+	return orderIDs, nil
+}
+
+// ListOrdersByStatusOrUpdatedSince lists the IDs of orders in any of statuses, or updated
+// since a time whatever their status, oldest first
+func (r *OrderRepository) ListOrdersByStatusOrUpdatedSince(ctx context.Context, since time.Time, statuses ...model.OrderStatus) ([]string, error) {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+
+	query := `
+		SELECT id
+		FROM orders
+		WHERE status = ANY($1) OR updated_at >= $2
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, names, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders by status: %w", err)
+	}
+	defer rows.Close()
+
+	orderIDs := []string{}
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan order ID: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating orders by status: %w", err)
+	}
+
+	return orderIDs, nil
+}
+
+// CountActiveProviderOrders counts a provider's orders in any of statuses by order type,
+// leaving out excludeOrderID
+func (r *OrderRepository) CountActiveProviderOrders(ctx context.Context, providerID, excludeOrderID string, statuses []model.OrderStatus) (map[model.OrderType]int, error) {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+
+	query := `
+		SELECT order_type, COUNT(*)
+		FROM orders
+		WHERE provider_id = $1 AND id <> $2 AND status = ANY($3)
+		GROUP BY order_type
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, providerID, excludeOrderID, names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count active provider orders: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[model.OrderType]int)
+	for rows.Next() {
+		var orderType model.OrderType
+		var count int
+		if err := rows.Scan(&orderType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan active provider order count: %w", err)
+		}
+		counts[orderType] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating active provider order counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// ListPickupLocations lists the pickup locations of orders created in [from, to)
+func (r *OrderRepository) ListPickupLocations(ctx context.Context, from, to time.Time) ([]model.Location, error) {
+	query := `
+		SELECT pickup_location
+		FROM orders
+		WHERE created_at >= $1 AND created_at < $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pickup locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := []model.Location{}
+	for rows.Next() {
+		var location model.Location
+		if err := rows.Scan(&location); err != nil {
+			return nil, fmt.Errorf("failed to scan pickup location: %w", err)
+		}
+		locations = append(locations, location)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pickup locations: %w", err)
+	}
+
+	return locations, nil
+}
+
+// ListBatchAnchorIDs lists the IDs of orders of orderType that a provider holds in any of
+// statuses and whose pickup lies within the given bounds, oldest first, leaving out
+// excludeOrderID
+func (r *OrderRepository) ListBatchAnchorIDs(ctx context.Context, orderType model.OrderType, statuses []model.OrderStatus, excludeOrderID string, minLat, maxLat, minLon, maxLon float64) ([]string, error) {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+
+	query := `
+		SELECT id
+		FROM orders
+		WHERE order_type = $1 AND status = ANY($2) AND id <> $3
+			AND provider_id IS NOT NULL AND provider_id <> ''
+			AND (pickup_location->>'latitude')::DOUBLE PRECISION BETWEEN $4 AND $5
+			AND (pickup_location->>'longitude')::DOUBLE PRECISION BETWEEN $6 AND $7
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, string(orderType), names, excludeOrderID, minLat, maxLat, minLon, maxLon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch anchors: %w", err)
+	}
+	defer rows.Close()
+
+	orderIDs := []string{}
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan order ID: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating batch anchors: %w", err)
+	}
+
+	return orderIDs, nil
+}
+
+// updateOrderStatusTx changes an order's status and appends to its history within tx
+func updateOrderStatusTx(ctx context.Context, tx pgx.Tx, orderID string, status model.OrderStatus, updatedBy, notes string) error {
+	return changeOrderStatusTx(ctx, tx, orderID, status, updatedBy, notes, notes)
+}
+
+// changeOrderStatusTx is updateOrderStatusTx with the reason for a cancellation given
+// apart from the notes kept in the status history
+func changeOrderStatusTx(ctx context.Context, tx pgx.Tx, orderID string, status model.OrderStatus, updatedBy, notes, reason string) error {
 	// Get the current order
 	query := `
-		SELECT status_history, status
+		SELECT status_history, status, frozen, user_id, COALESCE(provider_id, ''), order_type, total_price,
+		       platform_fee, COALESCE(pickup_location->>'city', '')
 		FROM orders
 		WHERE id = $1
 		FOR UPDATE
 	`
 	var statusHistory model.StatusHistories
 	var currentStatus model.OrderStatus
-	err = tx.QueryRow(ctx, query, orderID).Scan(&statusHistory, &currentStatus)
+	var frozen bool
+	event := &eventspb.OrderStatusChanged{OrderId: orderID, Status: string(status)}
+	orderEvent := &model.OrderEvent{OrderID: orderID, EventType: model.OrderEventStatusChanged, Status: status}
+	err := tx.QueryRow(ctx, query, orderID).Scan(
+		&statusHistory, &currentStatus, &frozen,
+		&event.UserId, &event.ProviderId, &orderEvent.OrderType, &event.TotalPrice,
+		&orderEvent.PlatformFee, &orderEvent.City,
+	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return ErrOrderNotFound
@@ -214,6 +902,11 @@ func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID string,
 		return fmt.Errorf("failed to get order: %w", err)
 	}
 
+	// An open safety incident holds the order where it is until it is reviewed
+	if frozen {
+		return ErrOrderFrozen
+	}
+
 	// Add the new status history entry
 	newEntry := model.StatusHistory{
 		Status:    status,
@@ -234,15 +927,72 @@ func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID string,
 		return fmt.Errorf("failed to update order status: %w", err)
 	}
 
-	// Commit the transaction
-	err = tx.Commit(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	// Completing an order pays out the provider's fare
+	if status == model.StatusCompleted {
+		if err := creditFareTx(ctx, tx, orderID); err != nil {
+			return err
+		}
+	}
+
+	// A finished order's parties can no longer call each other
+	if status.Finished() {
+		if err := revokeContactTokensTx(ctx, tx, orderID, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	event.OrderType = string(orderEvent.OrderType)
+	event.PreviousStatus = string(currentStatus)
+	if err := enqueueWebhookEventTx(ctx, tx, model.WebhookOrderStatusChanged, orderEvent.OrderType, event); err != nil {
+		return err
+	}
+
+	orderEvent.PreviousStatus = currentStatus
+	orderEvent.ProviderID = event.ProviderId
+	orderEvent.UpdatedBy = updatedBy
+	orderEvent.TotalPrice = event.TotalPrice
+	if status == model.StatusCancelled {
+		orderEvent.Reason = reason
+	}
+	if err := recordOrderEventTx(ctx, tx, orderEvent); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// ListActiveUserIDs lists up to limit IDs, after afterID in ID order, of the users who
+// placed an order since a time
+func (r *OrderRepository) ListActiveUserIDs(ctx context.Context, since time.Time, afterID string, limit int) ([]string, error) {
+	query := `
+		SELECT DISTINCT user_id FROM orders
+		WHERE created_at >= $1 AND user_id > $2
+		ORDER BY user_id
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, since, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active users: %w", err)
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user IDs: %w", err)
+	}
+
+	return userIDs, nil
+}
+
 // ListUserOrders gets all orders for a specific user
 func (r *OrderRepository) ListUserOrders(ctx context.Context, userID string, page, limit int, status model.OrderStatus) ([]*model.Order, int, error) {
 	var whereClause string
@@ -278,8 +1028,9 @@ func (r *OrderRepository) ListUserOrders(ctx context.Context, userID string, pag
 		SELECT
 			id, user_id, provider_id, order_type, status, 
 			pickup_location, destination_location, items, 
-			total_price, platform_fee, provider_fee, 
-			transaction_id, blockchain_tx_hash, payment_method, 
+			total_price, platform_fee, provider_fee, tip_amount, cancellation_fee, 
+			COALESCE(delivery_proof_hash, ''), frozen, at_risk, 
+			transaction_id, blockchain_tx_hash, payment_method, COALESCE(payment_method_id, ''), wallet_amount, points_redeemed, points_discount, COALESCE(return_of_order_id, ''), 
 			notes, created_at, updated_at, status_history
 		FROM orders
 		WHERE user_id = $1%s
@@ -308,9 +1059,19 @@ func (r *OrderRepository) ListUserOrders(ctx context.Context, userID string, pag
 			&order.TotalPrice,
 			&order.PlatformFee,
 			&order.ProviderFee,
+			&order.TipAmount,
+			&order.CancellationFee,
+			&order.DeliveryProofHash,
+			&order.Frozen,
+			&order.AtRisk,
 			&order.TransactionID,
 			&order.BlockchainTxHash,
 			&order.PaymentMethod,
+			&order.PaymentMethodID,
+			&order.WalletAmount,
+			&order.PointsRedeemed,
+			&order.PointsDiscount,
+			&order.ReturnOfOrderID,
 			&order.Notes,
 			&order.CreatedAt,
 			&order.UpdatedAt,
@@ -319,6 +1080,9 @@ func (r *OrderRepository) ListUserOrders(ctx context.Context, userID string, pag
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan order: %w", err)
 		}
+		if err := r.decryptFields(order); err != nil {
+			return nil, 0, err
+		}
 		orders = append(orders, order)
 	}
 
@@ -364,8 +1128,9 @@ func (r *OrderRepository) ListProviderOrders(ctx context.Context, providerID str
 		SELECT
 			id, user_id, provider_id, order_type, status, 
 			pickup_location, destination_location, items, 
-			total_price, platform_fee, provider_fee, 
-			transaction_id, blockchain_tx_hash, payment_method, 
+			total_price, platform_fee, provider_fee, tip_amount, cancellation_fee, 
+			COALESCE(delivery_proof_hash, ''), frozen, at_risk, 
+			transaction_id, blockchain_tx_hash, payment_method, COALESCE(payment_method_id, ''), wallet_amount, points_redeemed, points_discount, COALESCE(return_of_order_id, ''), 
 			notes, created_at, updated_at, status_history
 		FROM orders
 		WHERE provider_id = $1%s
@@ -394,9 +1159,19 @@ func (r *OrderRepository) ListProviderOrders(ctx context.Context, providerID str
 			&order.TotalPrice,
 			&order.PlatformFee,
 			&order.ProviderFee,
+			&order.TipAmount,
+			&order.CancellationFee,
+			&order.DeliveryProofHash,
+			&order.Frozen,
+			&order.AtRisk,
 			&order.TransactionID,
 			&order.BlockchainTxHash,
 			&order.PaymentMethod,
+			&order.PaymentMethodID,
+			&order.WalletAmount,
+			&order.PointsRedeemed,
+			&order.PointsDiscount,
+			&order.ReturnOfOrderID,
 			&order.Notes,
 			&order.CreatedAt,
 			&order.UpdatedAt,
@@ -405,6 +1180,9 @@ func (r *OrderRepository) ListProviderOrders(ctx context.Context, providerID str
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan order: %w", err)
 		}
+		if err := r.decryptFields(order); err != nil {
+			return nil, 0, err
+		}
 		orders = append(orders, order)
 	}
 
@@ -415,6 +1193,108 @@ func (r *OrderRepository) ListProviderOrders(ctx context.Context, providerID str
 	return orders, total, nil
 }
 
+// ExportOrders calls fn with every order matching filter, oldest first. Orders are read
+// from the database one row at a time, so an export of any size is never held in memory;
+// an error from fn stops the export and is returned.
+func (r *OrderRepository) ExportOrders(ctx context.Context, filter model.OrderExportFilter, fn func(*model.Order) error) error {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.UserID != "" {
+		addCondition("user_id = $%d", filter.UserID)
+	}
+	if filter.ProviderID != "" {
+		addCondition("provider_id = $%d", filter.ProviderID)
+	}
+	if filter.Status != "" {
+		addCondition("status = $%d", filter.Status)
+	}
+	if filter.OrderType != "" {
+		addCondition("order_type = $%d", filter.OrderType)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			id, user_id, provider_id, order_type, status, 
+			pickup_location, destination_location, items, 
+			total_price, platform_fee, provider_fee, tip_amount, cancellation_fee, 
+			COALESCE(delivery_proof_hash, ''), frozen, at_risk, 
+			transaction_id, blockchain_tx_hash, payment_method, COALESCE(payment_method_id, ''), wallet_amount, points_redeemed, points_discount, COALESCE(return_of_order_id, ''), 
+			notes, created_at, updated_at, status_history
+		FROM orders%s
+		ORDER BY created_at, id
+	`, where)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		order := &model.Order{}
+		err := rows.Scan(
+			&order.ID,
+			&order.UserID,
+			&order.ProviderID,
+			&order.OrderType,
+			&order.Status,
+			&order.PickupLocation,
+			&order.DestinationLocation,
+			&order.Items,
+			&order.TotalPrice,
+			&order.PlatformFee,
+			&order.ProviderFee,
+			&order.TipAmount,
+			&order.CancellationFee,
+			&order.DeliveryProofHash,
+			&order.Frozen,
+			&order.AtRisk,
+			&order.TransactionID,
+			&order.BlockchainTxHash,
+			&order.PaymentMethod,
+			&order.PaymentMethodID,
+			&order.WalletAmount,
+			&order.PointsRedeemed,
+			&order.PointsDiscount,
+			&order.ReturnOfOrderID,
+			&order.Notes,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+			&order.StatusHistory,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan order: %w", err)
+		}
+		if err := r.decryptFields(order); err != nil {
+			return err
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating orders: %w", err)
+	}
+
+	return nil
+}
+
 // AddOrderLocation adds a location update for an order
 func (r *OrderRepository) AddOrderLocation(ctx context.Context, location *model.OrderLocation) error {
 	query := `
@@ -515,4 +1395,183 @@ func (r *OrderRepository) GetOrderLocationsHistory(ctx context.Context, orderID
 	}
 
 	return locations, nil
-} 
\ No newline at end of file
+}
+
+// ReencryptOrders encrypts up to limit orders' addresses, stop instructions and notes that are stored in
+// plaintext or under an older key with the primary key, and reports how many it changed.
+// Archived orders are re-encrypted once no order in orders needs it.
+func (r *OrderRepository) ReencryptOrders(ctx context.Context, limit int) (int, error) {
+	if r.keys == nil {
+		return 0, nil
+	}
+
+	changed, err := r.reencryptTable(ctx, "orders", limit)
+	if err != nil || changed >= limit {
+		return changed, err
+	}
+
+	archived, err := r.reencryptTable(ctx, "orders_archive", limit-changed)
+	return changed + archived, err
+}
+
+// encryptedLocationPaths are the paths of the encrypted values within a stored location
+var encryptedLocationPaths = []string{"{address}", "{instructions,gate_code}", "{instructions,notes}"}
+
+// reencryptTable is ReencryptOrders for table, which is orders or orders_archive
+func (r *OrderRepository) reencryptTable(ctx context.Context, table string, limit int) (int, error) {
+	// Each encrypted value is selected in turn: the pickup's, then the destination's, then the notes
+	var values, stale []string
+	for _, column := range []string{"pickup_location", "destination_location"} {
+		for _, path := range encryptedLocationPaths {
+			value := fmt.Sprintf("COALESCE(%s#>>'%s', '')", column, path)
+			values = append(values, value)
+			stale = append(stale, fmt.Sprintf("(%s <> '' AND %s NOT LIKE $1)", value, value))
+		}
+	}
+	values = append(values, "COALESCE(notes, '')")
+	stale = append(stale, "(COALESCE(notes, '') <> '' AND notes NOT LIKE $1)")
+
+	query := `
+		SELECT id, ` + strings.Join(values, ", ") + `
+		FROM ` + table + `
+		WHERE ` + strings.Join(stale, "\n\t\t   OR ") + `
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, r.keys.PrimaryPrefix()+"%", limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query orders to re-encrypt: %w", err)
+	}
+
+	type sensitiveFields struct {
+		id     string
+		values []string
+	}
+	var pending []sensitiveFields
+	for rows.Next() {
+		f := sensitiveFields{values: make([]string, len(values))}
+		dest := []interface{}{&f.id}
+		for i := range f.values {
+			dest = append(dest, &f.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan order fields: %w", err)
+		}
+		pending = append(pending, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating orders to re-encrypt: %w", err)
+	}
+
+	// Paths that are not there, like the instructions of stops without any, are left out
+	var sets []string
+	arg := 2
+	for _, column := range []string{"pickup_location", "destination_location"} {
+		value := column
+		for _, path := range encryptedLocationPaths {
+			value = fmt.Sprintf("jsonb_set(%s, '%s', to_jsonb($%d::TEXT), false)", value, path, arg)
+			arg++
+		}
+		sets = append(sets, column+" = "+value)
+	}
+	sets = append(sets, fmt.Sprintf("notes = $%d", arg))
+
+	update := `
+		UPDATE ` + table + `
+		SET ` + strings.Join(sets, ",\n\t\t    ") + `
+		WHERE id = $1
+	`
+
+	for _, f := range pending {
+		args := []interface{}{f.id}
+		for i := range f.values {
+			if f.values[i], _, err = r.keys.Rotate(f.values[i]); err != nil {
+				return 0, fmt.Errorf("failed to re-encrypt order %s: %w", f.id, err)
+			}
+			args = append(args, f.values[i])
+		}
+
+		if _, err := r.db.ExecContext(ctx, update, args...); err != nil {
+			return 0, fmt.Errorf("failed to store re-encrypted order fields: %w", err)
+		}
+	}
+
+	return len(pending), nil
+}
+
+// encryptFields returns an order's pickup and destination with their addresses and stop
+// instructions encrypted, and its notes encrypted, for storage. The order itself is left
+// unchanged.
+func (r *OrderRepository) encryptFields(order *model.Order) (model.Location, model.Location, string, error) {
+	pickup, destination := order.PickupLocation, order.DestinationLocation
+
+	if err := r.encryptLocation(&pickup); err != nil {
+		return pickup, destination, "", fmt.Errorf("failed to encrypt pickup: %w", err)
+	}
+	if err := r.encryptLocation(&destination); err != nil {
+		return pickup, destination, "", fmt.Errorf("failed to encrypt destination: %w", err)
+	}
+	notes, err := r.keys.Encrypt(order.Notes)
+	if err != nil {
+		return pickup, destination, "", fmt.Errorf("failed to encrypt order notes: %w", err)
+	}
+
+	return pickup, destination, notes, nil
+}
+
+// decryptFields decrypts an order's addresses, stop instructions and notes in place
+func (r *OrderRepository) decryptFields(order *model.Order) error {
+	if err := r.decryptLocation(&order.PickupLocation); err != nil {
+		return fmt.Errorf("failed to decrypt pickup of order %s: %w", order.ID, err)
+	}
+	if err := r.decryptLocation(&order.DestinationLocation); err != nil {
+		return fmt.Errorf("failed to decrypt destination of order %s: %w", order.ID, err)
+	}
+	var err error
+	if order.Notes, err = r.keys.Decrypt(order.Notes); err != nil {
+		return fmt.Errorf("failed to decrypt notes of order %s: %w", order.ID, err)
+	}
+	return nil
+}
+
+// encryptLocation encrypts a location's address and the gate code and notes of its
+// instructions. The instructions are copied so the order they came from is left unchanged.
+func (r *OrderRepository) encryptLocation(location *model.Location) error {
+	var err error
+	if location.Address, err = r.keys.Encrypt(location.Address); err != nil {
+		return fmt.Errorf("failed to encrypt address: %w", err)
+	}
+	if location.Instructions == nil {
+		return nil
+	}
+
+	instructions := *location.Instructions
+	if instructions.GateCode, err = r.keys.Encrypt(instructions.GateCode); err != nil {
+		return fmt.Errorf("failed to encrypt gate code: %w", err)
+	}
+	if instructions.Notes, err = r.keys.Encrypt(instructions.Notes); err != nil {
+		return fmt.Errorf("failed to encrypt instructions: %w", err)
+	}
+	location.Instructions = &instructions
+	return nil
+}
+
+// decryptLocation decrypts a location's address and instructions in place
+func (r *OrderRepository) decryptLocation(location *model.Location) error {
+	var err error
+	if location.Address, err = r.keys.Decrypt(location.Address); err != nil {
+		return fmt.Errorf("failed to decrypt address: %w", err)
+	}
+	if location.Instructions == nil {
+		return nil
+	}
+	if location.Instructions.GateCode, err = r.keys.Decrypt(location.Instructions.GateCode); err != nil {
+		return fmt.Errorf("failed to decrypt gate code: %w", err)
+	}
+	if location.Instructions.Notes, err = r.keys.Decrypt(location.Instructions.Notes); err != nil {
+		return fmt.Errorf("failed to decrypt instructions: %w", err)
+	}
+	return nil
+}
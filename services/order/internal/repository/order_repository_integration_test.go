@@ -0,0 +1,534 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/internal/testharness"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+func TestOrderRepositoryCreateAndGet(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	repo := repository.NewOrderRepository(db, nil)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	order := &model.Order{
+		ID:        uuid.New().String(),
+		UserID:    uuid.New().String(),
+		OrderType: model.TypeFoodDelivery,
+		Status:    model.StatusCreated,
+		PickupLocation: model.Location{
+			Latitude: -6.2, Longitude: 106.8166, Address: "Jl. Sudirman 1", City: "jakarta",
+		},
+		DestinationLocation: model.Location{
+			Latitude: -6.182, Longitude: 106.8166, Address: "Jl. Thamrin 9", City: "jakarta",
+		},
+		Items: model.OrderItems{
+			{ItemID: "item-1", Name: "Nasi goreng", Quantity: 2, Price: 25000},
+		},
+		TotalPrice:    50000,
+		PlatformFee:   10000,
+		ProviderFee:   40000,
+		PaymentMethod: model.PaymentDigitalWallet,
+		Notes:         "Extra spicy",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	order.AddStatusHistory(model.StatusCreated, "system", "Order created")
+
+	if err := repo.CreateOrder(ctx, order, time.Time{}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if err := repo.CreateOrder(ctx, order, time.Time{}); !errors.Is(err, repository.ErrDuplicateOrder) {
+		t.Fatalf("CreateOrder with a taken ID: got %v, want ErrDuplicateOrder", err)
+	}
+
+	got, err := repo.GetOrderByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if got.UserID != order.UserID || got.OrderType != order.OrderType || got.Status != order.Status {
+		t.Errorf("got order %s/%s/%s, want %s/%s/%s", got.UserID, got.OrderType, got.Status, order.UserID, order.OrderType, order.Status)
+	}
+	if got.TotalPrice != order.TotalPrice || got.PlatformFee != order.PlatformFee || got.ProviderFee != order.ProviderFee {
+		t.Errorf("got charges %d/%d/%d, want %d/%d/%d", got.TotalPrice, got.PlatformFee, got.ProviderFee, order.TotalPrice, order.PlatformFee, order.ProviderFee)
+	}
+	if got.PickupLocation.Address != order.PickupLocation.Address || got.Notes != order.Notes {
+		t.Errorf("got pickup %q and notes %q, want %q and %q", got.PickupLocation.Address, got.Notes, order.PickupLocation.Address, order.Notes)
+	}
+	if len(got.Items) != 1 || got.Items[0].Quantity != 2 {
+		t.Errorf("got items %+v, want one item of quantity 2", got.Items)
+	}
+	if len(got.StatusHistory) != 1 {
+		t.Errorf("got %d status history entries, want 1", len(got.StatusHistory))
+	}
+
+	if _, err := repo.GetOrderByID(ctx, uuid.New().String()); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("GetOrderByID of a missing order: got %v, want ErrOrderNotFound", err)
+	}
+}
+
+func TestOrderRepositoryRefusesDuplicateOrders(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	repo := repository.NewOrderRepository(db, nil)
+
+	userID := uuid.New().String()
+	newOrder := func(pickupLat float64) *model.Order {
+		now := time.Now().UTC()
+		return &model.Order{
+			ID:                  uuid.New().String(),
+			UserID:              userID,
+			OrderType:           model.TypeRide,
+			Status:              model.StatusCreated,
+			PickupLocation:      model.Location{Latitude: pickupLat, Longitude: 106.8166},
+			DestinationLocation: model.Location{Latitude: -6.182, Longitude: 106.8166},
+			TotalPrice:          20000,
+			PaymentMethod:       model.PaymentDigitalWallet,
+			CreatedAt:           now,
+			UpdatedAt:           now,
+		}
+	}
+	since := time.Now().Add(-2 * time.Minute)
+
+	placed := newOrder(-6.2)
+	if err := repo.CreateOrder(ctx, placed, since); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	var duplicate *repository.DuplicateOrderError
+	err := repo.CreateOrder(ctx, newOrder(-6.20005), since)
+	if !errors.As(err, &duplicate) || duplicate.OrderID != placed.ID {
+		t.Fatalf("CreateOrder of the same trip: got %v, want a duplicate of %s", err, placed.ID)
+	}
+	if err := repo.CreateOrder(ctx, newOrder(-6.21), since); err != nil {
+		t.Errorf("CreateOrder from another pickup: %v", err)
+	}
+	unchecked := newOrder(-6.2)
+	if err := repo.CreateOrder(ctx, unchecked, time.Time{}); err != nil {
+		t.Errorf("CreateOrder with the check off: %v", err)
+	}
+
+	for _, orderID := range []string{placed.ID, unchecked.ID} {
+		if err := repo.CancelOrder(ctx, orderID, "", userID, "changed my mind", "", 0); err != nil {
+			t.Fatalf("CancelOrder: %v", err)
+		}
+	}
+	if err := repo.CreateOrder(ctx, newOrder(-6.2), since); err != nil {
+		t.Errorf("CreateOrder after cancelling: %v", err)
+	}
+}
+
+func TestOrderRepositoryAllowsOneOpenReturn(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	repo := repository.NewOrderRepository(db, nil)
+
+	userID := uuid.New().String()
+	originalID := uuid.New().String()
+	newReturn := func() *model.Order {
+		now := time.Now().UTC()
+		return &model.Order{
+			ID:                  uuid.New().String(),
+			UserID:              userID,
+			OrderType:           model.TypeReturn,
+			Status:              model.StatusCreated,
+			PickupLocation:      model.Location{Latitude: -6.182, Longitude: 106.8166},
+			DestinationLocation: model.Location{Latitude: -6.2, Longitude: 106.8166},
+			TotalPrice:          5000,
+			PaymentMethod:       model.PaymentCash,
+			ReturnOfOrderID:     originalID,
+			CreatedAt:           now,
+			UpdatedAt:           now,
+		}
+	}
+
+	first := newReturn()
+	if err := repo.CreateOrder(ctx, first, time.Time{}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	got, err := repo.GetOrderByID(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if got.ReturnOfOrderID != originalID {
+		t.Errorf("got return of %q, want %s", got.ReturnOfOrderID, originalID)
+	}
+
+	if err := repo.CreateOrder(ctx, newReturn(), time.Time{}); !errors.Is(err, repository.ErrReturnExists) {
+		t.Fatalf("CreateOrder of a second return: got %v, want ErrReturnExists", err)
+	}
+	if err := repo.CancelOrder(ctx, first.ID, "", userID, "changed my mind", "", 0); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if err := repo.CreateOrder(ctx, newReturn(), time.Time{}); err != nil {
+		t.Errorf("CreateOrder after cancelling the return: %v", err)
+	}
+}
+
+func TestOrderRepositoryUpdateOrderStatusFrom(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	repo := repository.NewOrderRepository(db, nil)
+
+	orderID := testharness.InsertOrder(t, db, testharness.OrderFixture{
+		ProviderID: uuid.New().String(),
+		Status:     string(model.StatusProviderAssigned),
+	})
+
+	changed, err := repo.UpdateOrderStatusFrom(ctx, orderID, model.StatusProviderAssigned, model.StatusProviderAccepted, "provider", "Accepted")
+	if err != nil || !changed {
+		t.Fatalf("UpdateOrderStatusFrom: got %v, %v; want true", changed, err)
+	}
+
+	// The order has moved on, so a second change from the old status is refused
+	changed, err = repo.UpdateOrderStatusFrom(ctx, orderID, model.StatusProviderAssigned, model.StatusCancelled, "system", "Expired")
+	if err != nil || changed {
+		t.Fatalf("UpdateOrderStatusFrom from a stale status: got %v, %v; want false", changed, err)
+	}
+
+	order, err := repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if order.Status != model.StatusProviderAccepted {
+		t.Errorf("status = %s, want %s", order.Status, model.StatusProviderAccepted)
+	}
+	if n := len(order.StatusHistory); n != 2 || order.StatusHistory[n-1].Status != model.StatusProviderAccepted {
+		t.Errorf("got status history %+v, want the fixture's entry and the acceptance", order.StatusHistory)
+	}
+}
+
+func TestOrderRepositoryUpdateOrderDetails(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	repo := repository.NewOrderRepository(db, nil)
+
+	orderID := testharness.InsertOrder(t, db, testharness.OrderFixture{
+		ProviderID: uuid.New().String(),
+		Status:     string(model.StatusProviderAccepted),
+	})
+	order, err := repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+
+	order.DestinationLocation.Latitude += 0.05
+	order.TotalPrice += 10000
+	entry := model.StatusHistory{Status: model.StatusProviderAssigned, UpdatedBy: order.UserID, Notes: "Order changed", Timestamp: time.Now()}
+	if err := repo.UpdateOrderDetails(ctx, order, model.StatusProviderAccepted, entry); err != nil {
+		t.Fatalf("UpdateOrderDetails: %v", err)
+	}
+
+	// The order has moved on, so a change made from the old status is refused
+	if err := repo.UpdateOrderDetails(ctx, order, model.StatusProviderAccepted, entry); !errors.Is(err, repository.ErrOrderStatusChanged) {
+		t.Fatalf("UpdateOrderDetails from a stale status: got %v, want ErrOrderStatusChanged", err)
+	}
+
+	got, err := repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if got.Status != model.StatusProviderAssigned || got.TotalPrice != order.TotalPrice ||
+		got.DestinationLocation.Latitude != order.DestinationLocation.Latitude {
+		t.Errorf("got %s, total %d, destination %v; want the changed order", got.Status, got.TotalPrice, got.DestinationLocation)
+	}
+	if n := len(got.StatusHistory); n != 2 || got.StatusHistory[n-1].Notes != entry.Notes {
+		t.Errorf("got status history %+v, want the fixture's entry and the change", got.StatusHistory)
+	}
+}
+
+func TestDeliveryPINRepositoryLocksOut(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	repo := repository.NewDeliveryPINRepository(db)
+
+	orderID := testharness.InsertOrder(t, db, testharness.OrderFixture{Status: string(model.StatusArrived)})
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	err := repo.CreatePIN(ctx, &model.DeliveryPIN{
+		OrderID:   orderID,
+		PINHash:   model.HashDeliveryPIN(orderID, "1234"),
+		IssuedAt:  now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("CreatePIN: %v", err)
+	}
+
+	const maxAttempts = 3
+	lockout := 15 * time.Minute
+	wrong := model.HashDeliveryPIN(orderID, "0000")
+
+	remaining, err := repo.VerifyPIN(ctx, orderID, wrong, maxAttempts, lockout, now)
+	if !errors.Is(err, repository.ErrDeliveryPINMismatch) || remaining != 2 {
+		t.Fatalf("first wrong PIN: got %d, %v; want 2, ErrDeliveryPINMismatch", remaining, err)
+	}
+	remaining, err = repo.VerifyPIN(ctx, orderID, wrong, maxAttempts, lockout, now)
+	if !errors.Is(err, repository.ErrDeliveryPINMismatch) || remaining != 1 {
+		t.Fatalf("second wrong PIN: got %d, %v; want 1, ErrDeliveryPINMismatch", remaining, err)
+	}
+	if _, err := repo.VerifyPIN(ctx, orderID, wrong, maxAttempts, lockout, now); !errors.Is(err, repository.ErrDeliveryPINLocked) {
+		t.Fatalf("third wrong PIN: got %v, want ErrDeliveryPINLocked", err)
+	}
+
+	// The lockout was committed, so even the right PIN is refused until it passes
+	right := model.HashDeliveryPIN(orderID, "1234")
+	if _, err := repo.VerifyPIN(ctx, orderID, right, maxAttempts, lockout, now.Add(time.Minute)); !errors.Is(err, repository.ErrDeliveryPINLocked) {
+		t.Fatalf("right PIN while locked: got %v, want ErrDeliveryPINLocked", err)
+	}
+
+	remaining, err = repo.VerifyPIN(ctx, orderID, right, maxAttempts, lockout, now.Add(lockout))
+	if err != nil || remaining != maxAttempts {
+		t.Fatalf("right PIN after the lockout: got %d, %v; want %d, nil", remaining, err, maxAttempts)
+	}
+
+	pin, err := repo.GetPIN(ctx, orderID)
+	if err != nil {
+		t.Fatalf("GetPIN: %v", err)
+	}
+	if pin.FailedAttempts != 0 || pin.LockedUntil != nil {
+		t.Errorf("got failed_attempts=%d locked_until=%v after a match, want both cleared", pin.FailedAttempts, pin.LockedUntil)
+	}
+}
+
+func TestPaymentMethodRepositoryKeepsOneDefault(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	repo := repository.NewPaymentMethodRepository(db)
+
+	userID := uuid.New().String()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	card := func(fingerprint string, makeDefault bool, at time.Time) *model.SavedPaymentMethod {
+		return &model.SavedPaymentMethod{
+			ID: uuid.New().String(), UserID: userID, Type: model.PaymentCreditCard, Gateway: "STRIPE",
+			GatewayToken: "pm_" + fingerprint, Fingerprint: fingerprint, Brand: "VISA", Last4: "4242",
+			ExpMonth: 12, ExpYear: 2099, IsDefault: makeDefault, CreatedAt: at, UpdatedAt: at,
+		}
+	}
+
+	first := card("fp-1", false, now)
+	if err := repo.SavePaymentMethod(ctx, first); err != nil {
+		t.Fatalf("SavePaymentMethod: %v", err)
+	}
+	if !first.IsDefault {
+		t.Errorf("first method is_default = false, want true")
+	}
+	second := card("fp-2", true, now.Add(time.Minute))
+	if err := repo.SavePaymentMethod(ctx, second); err != nil {
+		t.Fatalf("SavePaymentMethod: %v", err)
+	}
+
+	// Saving the first card again refreshes it without taking the default back
+	again := card("fp-1", false, now.Add(2*time.Minute))
+	again.GatewayToken = "pm_refreshed"
+	if err := repo.SavePaymentMethod(ctx, again); err != nil {
+		t.Fatalf("SavePaymentMethod: %v", err)
+	}
+	if again.ID != first.ID || again.IsDefault {
+		t.Errorf("saving again gave %s default=%v, want %s default=false", again.ID, again.IsDefault, first.ID)
+	}
+
+	def, err := repo.GetDefaultPaymentMethod(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetDefaultPaymentMethod: %v", err)
+	}
+	if def.ID != second.ID {
+		t.Errorf("default = %s, want %s", def.ID, second.ID)
+	}
+
+	if err := repo.DeletePaymentMethod(ctx, userID, second.ID); err != nil {
+		t.Fatalf("DeletePaymentMethod: %v", err)
+	}
+	if def, err = repo.GetDefaultPaymentMethod(ctx, userID); err != nil {
+		t.Fatalf("GetDefaultPaymentMethod after delete: %v", err)
+	}
+	if def.ID != first.ID || def.GatewayToken != "pm_refreshed" {
+		t.Errorf("default after delete = %s (%s), want %s (pm_refreshed)", def.ID, def.GatewayToken, first.ID)
+	}
+
+	if err := repo.SetDefaultPaymentMethod(ctx, uuid.New().String(), first.ID); !errors.Is(err, repository.ErrPaymentMethodNotFound) {
+		t.Errorf("SetDefaultPaymentMethod for another user: got %v, want ErrPaymentMethodNotFound", err)
+	}
+}
+
+func TestPaymentAuthorizationRepositorySettlesOnce(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	repo := repository.NewPaymentAuthorizationRepository(db)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	hold := func(expiresAt time.Time) *model.PaymentAuthorization {
+		orderID := uuid.New().String()
+		return &model.PaymentAuthorization{
+			OrderID: orderID, AuthorizationID: "auth-" + orderID, Amount: 25000,
+			Status: model.AuthorizationAuthorized, ExpiresAt: expiresAt, CreatedAt: now, UpdatedAt: now,
+		}
+	}
+	soon, later := hold(now.Add(time.Hour)), hold(now.Add(48*time.Hour))
+	for _, auth := range []*model.PaymentAuthorization{soon, later} {
+		if err := repo.CreateAuthorization(ctx, auth); err != nil {
+			t.Fatalf("CreateAuthorization: %v", err)
+		}
+	}
+
+	lapsing, err := repo.ListLapsingAuthorizations(ctx, now.Add(2*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("ListLapsingAuthorizations: %v", err)
+	}
+	if len(lapsing) != 1 || lapsing[0].OrderID != soon.OrderID {
+		t.Fatalf("lapsing = %v, want only %s", lapsing, soon.OrderID)
+	}
+
+	if err := repo.SettleAuthorization(ctx, soon.OrderID, model.AuthorizationCaptured, 20000, "payment-1", now); err != nil {
+		t.Fatalf("SettleAuthorization: %v", err)
+	}
+	if err := repo.SettleAuthorization(ctx, soon.OrderID, model.AuthorizationReleased, 0, "", now); !errors.Is(err, repository.ErrPaymentAuthorizationSettled) {
+		t.Errorf("settling twice: got %v, want ErrPaymentAuthorizationSettled", err)
+	}
+	if err := repo.SettleAuthorization(ctx, uuid.New().String(), model.AuthorizationReleased, 0, "", now); !errors.Is(err, repository.ErrPaymentAuthorizationNotFound) {
+		t.Errorf("settling an unknown hold: got %v, want ErrPaymentAuthorizationNotFound", err)
+	}
+
+	got, err := repo.GetAuthorization(ctx, soon.OrderID)
+	if err != nil {
+		t.Fatalf("GetAuthorization: %v", err)
+	}
+	if got.Status != model.AuthorizationCaptured || got.CapturedAmount != 20000 || got.PaymentID != "payment-1" || got.SettledAt == nil {
+		t.Errorf("settled hold = %+v, want CAPTURED 20000 by payment-1", got)
+	}
+	if lapsing, err = repo.ListLapsingAuthorizations(ctx, now.Add(72*time.Hour), 10); err != nil {
+		t.Fatalf("ListLapsingAuthorizations: %v", err)
+	}
+	if len(lapsing) != 1 || lapsing[0].OrderID != later.OrderID {
+		t.Errorf("lapsing after settling = %v, want only %s", lapsing, later.OrderID)
+	}
+}
+
+func TestWalletRepositoryAppliesEachReferenceOnce(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	repo := repository.NewWalletRepository(db)
+
+	userID := uuid.New().String()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	txn := func(amount int64, reference string, at time.Time) *model.WalletTransaction {
+		return &model.WalletTransaction{
+			ID: uuid.New().String(), UserID: userID, Type: model.WalletTopUp, Amount: amount,
+			Reference: reference, Description: "test", CreatedAt: at,
+		}
+	}
+
+	if wallet, err := repo.GetWallet(ctx, userID); err != nil || wallet.Balance != 0 {
+		t.Fatalf("GetWallet before any transaction = %v, %v; want an empty wallet", wallet, err)
+	}
+
+	topUp := txn(50000, "topup-1", now)
+	if err := repo.ApplyWalletTransaction(ctx, topUp); err != nil {
+		t.Fatalf("ApplyWalletTransaction: %v", err)
+	}
+	retried := txn(50000, "topup-1", now.Add(time.Second))
+	if err := repo.ApplyWalletTransaction(ctx, retried); err != nil {
+		t.Fatalf("ApplyWalletTransaction retried: %v", err)
+	}
+	if retried.ID != topUp.ID || retried.BalanceAfter != 50000 {
+		t.Errorf("retried transaction = %s with balance %d, want %s with 50000", retried.ID, retried.BalanceAfter, topUp.ID)
+	}
+
+	if err := repo.ApplyWalletTransaction(ctx, txn(-60000, "order-1", now.Add(time.Minute))); !errors.Is(err, repository.ErrInsufficientWalletBalance) {
+		t.Errorf("overdrawing: got %v, want ErrInsufficientWalletBalance", err)
+	}
+	if err := repo.ApplyWalletTransaction(ctx, txn(-20000, "order-2", now.Add(2*time.Minute))); err != nil {
+		t.Fatalf("ApplyWalletTransaction debit: %v", err)
+	}
+
+	wallet, err := repo.GetWallet(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetWallet: %v", err)
+	}
+	if wallet.Balance != 30000 {
+		t.Errorf("balance = %d, want 30000", wallet.Balance)
+	}
+
+	txns, total, err := repo.ListWalletTransactions(ctx, userID, 1, 10)
+	if err != nil {
+		t.Fatalf("ListWalletTransactions: %v", err)
+	}
+	if total != 2 || len(txns) != 2 || txns[0].Reference != "order-2" || txns[0].BalanceAfter != 30000 {
+		t.Errorf("transactions = %v (%d), want order-2 then topup-1", txns, total)
+	}
+}
+
+func TestLoyaltyRepositoryRewardsEachCardOnce(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	repo := repository.NewLoyaltyRepository(db)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	referrerID := uuid.New().String()
+	if err := repo.CreateReferralCode(ctx, &model.ReferralCode{UserID: referrerID, Code: "TESTCODE", CreatedAt: now}); err != nil {
+		t.Fatalf("CreateReferralCode: %v", err)
+	}
+	if err := repo.CreateReferralCode(ctx, &model.ReferralCode{UserID: uuid.New().String(), Code: "TESTCODE", CreatedAt: now}); !errors.Is(err, repository.ErrReferralCodeTaken) {
+		t.Errorf("reusing a code: got %v, want ErrReferralCodeTaken", err)
+	}
+
+	refer := func(deviceID string) *model.Referral {
+		referral := &model.Referral{
+			ReferredUserID: uuid.New().String(), ReferrerID: referrerID, Code: "TESTCODE",
+			DeviceID: deviceID, Status: model.ReferralPending, CreatedAt: now,
+		}
+		if err := repo.CreateReferral(ctx, referral); err != nil {
+			t.Fatalf("CreateReferral: %v", err)
+		}
+		return referral
+	}
+	first, second := refer("device-1"), refer("device-2")
+	sameDevice := &model.Referral{ReferredUserID: uuid.New().String(), ReferrerID: referrerID, Code: "TESTCODE", DeviceID: "device-1", Status: model.ReferralPending, CreatedAt: now}
+	if err := repo.CreateReferral(ctx, sameDevice); !errors.Is(err, repository.ErrReferralDeviceUsed) {
+		t.Errorf("reusing a device: got %v, want ErrReferralDeviceUsed", err)
+	}
+
+	reward := func(referral *model.Referral) (bool, error) {
+		referral.Status = model.ReferralRewarded
+		referral.OrderID = uuid.New().String()
+		referral.PaymentFingerprint = "fp-1"
+		referral.SettledAt = &now
+		return repo.SettleReferral(ctx, referral, []*model.PointsTransaction{{
+			ID: uuid.New().String(), UserID: referrerID, Type: model.PointsReferralReward, Points: 500,
+			OrderID: referral.OrderID, Reference: "referral-" + referral.ReferredUserID, CreatedAt: now,
+		}})
+	}
+	if settled, err := reward(first); err != nil || !settled {
+		t.Fatalf("SettleReferral = %t, %v; want settled", settled, err)
+	}
+	if settled, err := reward(first); err != nil || settled {
+		t.Errorf("SettleReferral again = %t, %v; want not settled", settled, err)
+	}
+	if _, err := reward(second); !errors.Is(err, repository.ErrReferralFingerprintUsed) {
+		t.Errorf("rewarding a second referral paid with the same card: got %v, want ErrReferralFingerprintUsed", err)
+	}
+
+	account, err := repo.GetPointsAccount(ctx, referrerID)
+	if err != nil {
+		t.Fatalf("GetPointsAccount: %v", err)
+	}
+	if account.Balance != 500 {
+		t.Errorf("referrer has %d points, want 500 for one referral", account.Balance)
+	}
+	stored, err := repo.GetReferral(ctx, second.ReferredUserID)
+	if err != nil {
+		t.Fatalf("GetReferral: %v", err)
+	}
+	if stored.Status != model.ReferralPending {
+		t.Errorf("second referral is %s, want still PENDING", stored.Status)
+	}
+}
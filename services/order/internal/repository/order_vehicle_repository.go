@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// OrderVehicleRepository handles database operations for the vehicles orders were
+// accepted in
+type OrderVehicleRepository struct {
+	db *database.PostgresDB
+}
+
+// NewOrderVehicleRepository creates a new order vehicle repository
+func NewOrderVehicleRepository(db *database.PostgresDB) *OrderVehicleRepository {
+	return &OrderVehicleRepository{
+		db: db,
+	}
+}
+
+// ReplaceVehicle records the vehicle an order was accepted in, replacing any recorded
+// for an earlier provider. A nil vehicle only forgets the earlier one.
+func (r *OrderVehicleRepository) ReplaceVehicle(ctx context.Context, orderID string, vehicle *model.OrderVehicle) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM order_vehicles WHERE order_id = $1`, orderID); err != nil {
+			return fmt.Errorf("failed to delete order vehicle: %w", err)
+		}
+		if vehicle == nil {
+			return nil
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO order_vehicles (order_id, provider_id, vehicle_id, plate, type, description, recorded_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, orderID, vehicle.ProviderID, vehicle.VehicleID, vehicle.Plate, vehicle.Type, vehicle.Description, vehicle.RecordedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record order vehicle: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetVehicle retrieves the vehicle an order was accepted in, or nil if none was recorded
+func (r *OrderVehicleRepository) GetVehicle(ctx context.Context, orderID string) (*model.OrderVehicle, error) {
+	query := `
+		SELECT order_id, provider_id, vehicle_id, plate, type, description, recorded_at
+		FROM order_vehicles
+		WHERE order_id = $1
+	`
+
+	var vehicle model.OrderVehicle
+	err := r.db.QueryRowContext(ctx, query, orderID).Scan(
+		&vehicle.OrderID,
+		&vehicle.ProviderID,
+		&vehicle.VehicleID,
+		&vehicle.Plate,
+		&vehicle.Type,
+		&vehicle.Description,
+		&vehicle.RecordedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get order vehicle: %w", err)
+	}
+
+	return &vehicle, nil
+}
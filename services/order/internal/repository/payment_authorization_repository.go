@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const paymentAuthorizationColumns = `
+	order_id, authorization_id, amount, captured_amount, payment_id, status,
+	expires_at, settled_at, created_at, updated_at
+`
+
+// PaymentAuthorizationRepository handles database operations for the payments authorized
+// when orders are placed
+type PaymentAuthorizationRepository struct {
+	db *database.PostgresDB
+}
+
+// NewPaymentAuthorizationRepository creates a new payment authorization repository
+func NewPaymentAuthorizationRepository(db *database.PostgresDB) *PaymentAuthorizationRepository {
+	return &PaymentAuthorizationRepository{
+		db: db,
+	}
+}
+
+// CreateAuthorization stores the payment authorized for a new order
+func (r *PaymentAuthorizationRepository) CreateAuthorization(ctx context.Context, auth *model.PaymentAuthorization) error {
+	query := `
+		INSERT INTO payment_authorizations (` + paymentAuthorizationColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		auth.OrderID,
+		auth.AuthorizationID,
+		auth.Amount,
+		auth.CapturedAmount,
+		auth.PaymentID,
+		auth.Status,
+		auth.ExpiresAt,
+		auth.SettledAt,
+		auth.CreatedAt,
+		auth.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create payment authorization: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuthorization retrieves the payment authorized for an order
+func (r *PaymentAuthorizationRepository) GetAuthorization(ctx context.Context, orderID string) (*model.PaymentAuthorization, error) {
+	query := `SELECT ` + paymentAuthorizationColumns + ` FROM payment_authorizations WHERE order_id = $1`
+
+	auth, err := scanPaymentAuthorization(r.db.QueryRowContext(ctx, query, orderID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrPaymentAuthorizationNotFound
+		}
+		return nil, fmt.Errorf("failed to get payment authorization: %w", err)
+	}
+
+	return auth, nil
+}
+
+// SettleAuthorization records that an order's authorized payment was captured or released.
+// It fails with ErrPaymentAuthorizationSettled if it already was.
+func (r *PaymentAuthorizationRepository) SettleAuthorization(ctx context.Context, orderID string, status model.AuthorizationStatus, capturedAmount int64, paymentID string, at time.Time) error {
+	query := `
+		UPDATE payment_authorizations
+		SET status = $2, captured_amount = $3, payment_id = $4, settled_at = $5, updated_at = $5
+		WHERE order_id = $1 AND status = $6
+	`
+
+	result, err := r.db.ExecContext(ctx, query, orderID, status, capturedAmount, paymentID, at, model.AuthorizationAuthorized)
+	if err != nil {
+		return fmt.Errorf("failed to settle payment authorization: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		if _, err := r.GetAuthorization(ctx, orderID); err != nil {
+			return err
+		}
+		return ErrPaymentAuthorizationSettled
+	}
+
+	return nil
+}
+
+// ListLapsingAuthorizations lists up to limit authorizations still held that lapse before
+// a time, soonest first
+func (r *PaymentAuthorizationRepository) ListLapsingAuthorizations(ctx context.Context, before time.Time, limit int) ([]*model.PaymentAuthorization, error) {
+	query := `
+		SELECT ` + paymentAuthorizationColumns + `
+		FROM payment_authorizations
+		WHERE status = $1 AND expires_at < $2
+		ORDER BY expires_at
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, model.AuthorizationAuthorized, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lapsing payment authorizations: %w", err)
+	}
+	defer rows.Close()
+
+	auths := []*model.PaymentAuthorization{}
+	for rows.Next() {
+		auth, err := scanPaymentAuthorization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment authorization: %w", err)
+		}
+		auths = append(auths, auth)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list lapsing payment authorizations: %w", err)
+	}
+
+	return auths, nil
+}
+
+func scanPaymentAuthorization(row pgx.Row) (*model.PaymentAuthorization, error) {
+	var auth model.PaymentAuthorization
+	err := row.Scan(
+		&auth.OrderID,
+		&auth.AuthorizationID,
+		&auth.Amount,
+		&auth.CapturedAmount,
+		&auth.PaymentID,
+		&auth.Status,
+		&auth.ExpiresAt,
+		&auth.SettledAt,
+		&auth.CreatedAt,
+		&auth.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &auth, nil
+}
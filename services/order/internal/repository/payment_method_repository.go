@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const paymentMethodColumns = `
+	id, user_id, method_type, gateway, gateway_token, fingerprint, brand, last4,
+	exp_month, exp_year, is_default, created_at, updated_at
+`
+
+// PaymentMethodRepository handles database operations for the payment methods users saved
+type PaymentMethodRepository struct {
+	db *database.PostgresDB
+}
+
+// NewPaymentMethodRepository creates a new payment method repository
+func NewPaymentMethodRepository(db *database.PostgresDB) *PaymentMethodRepository {
+	return &PaymentMethodRepository{
+		db: db,
+	}
+}
+
+// SavePaymentMethod stores a payment method. A card the user already saved with the same
+// gateway fingerprint is refreshed with the new token and expiry instead, keeping its ID.
+// The method becomes the user's default when it is marked so or the user has no default
+// yet. The stored method is written back to method.
+func (r *PaymentMethodRepository) SavePaymentMethod(ctx context.Context, method *model.SavedPaymentMethod) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		var hasDefault bool
+		err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM payment_methods WHERE user_id = $1 AND is_default FOR UPDATE)`,
+			method.UserID,
+		).Scan(&hasDefault)
+		if err != nil {
+			return fmt.Errorf("failed to check default payment method: %w", err)
+		}
+
+		if method.IsDefault && hasDefault {
+			if _, err := tx.Exec(ctx, `UPDATE payment_methods SET is_default = FALSE WHERE user_id = $1 AND is_default`, method.UserID); err != nil {
+				return fmt.Errorf("failed to clear default payment method: %w", err)
+			}
+		}
+		isDefault := method.IsDefault || !hasDefault
+
+		query := `
+			INSERT INTO payment_methods (` + paymentMethodColumns + `)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			ON CONFLICT (user_id, gateway, fingerprint) WHERE fingerprint <> '' DO UPDATE
+			SET method_type = EXCLUDED.method_type,
+			    gateway_token = EXCLUDED.gateway_token,
+			    brand = EXCLUDED.brand,
+			    last4 = EXCLUDED.last4,
+			    exp_month = EXCLUDED.exp_month,
+			    exp_year = EXCLUDED.exp_year,
+			    is_default = payment_methods.is_default OR EXCLUDED.is_default,
+			    updated_at = EXCLUDED.updated_at
+			RETURNING ` + paymentMethodColumns
+
+		stored, err := scanPaymentMethod(tx.QueryRow(ctx, query,
+			method.ID,
+			method.UserID,
+			method.Type,
+			method.Gateway,
+			method.GatewayToken,
+			method.Fingerprint,
+			method.Brand,
+			method.Last4,
+			method.ExpMonth,
+			method.ExpYear,
+			isDefault,
+			method.CreatedAt,
+			method.UpdatedAt,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to save payment method: %w", err)
+		}
+		*method = *stored
+
+		return nil
+	})
+}
+
+// GetPaymentMethod gets one of a user's payment methods
+func (r *PaymentMethodRepository) GetPaymentMethod(ctx context.Context, userID, methodID string) (*model.SavedPaymentMethod, error) {
+	query := `SELECT ` + paymentMethodColumns + ` FROM payment_methods WHERE id = $1 AND user_id = $2`
+
+	method, err := scanPaymentMethod(r.db.QueryRowContext(ctx, query, methodID, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrPaymentMethodNotFound
+		}
+		return nil, fmt.Errorf("failed to get payment method: %w", err)
+	}
+
+	return method, nil
+}
+
+// GetDefaultPaymentMethod gets a user's default payment method
+func (r *PaymentMethodRepository) GetDefaultPaymentMethod(ctx context.Context, userID string) (*model.SavedPaymentMethod, error) {
+	query := `SELECT ` + paymentMethodColumns + ` FROM payment_methods WHERE user_id = $1 AND is_default`
+
+	method, err := scanPaymentMethod(r.db.QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrPaymentMethodNotFound
+		}
+		return nil, fmt.Errorf("failed to get default payment method: %w", err)
+	}
+
+	return method, nil
+}
+
+// ListPaymentMethods lists a user's payment methods, the default first, then newest first
+func (r *PaymentMethodRepository) ListPaymentMethods(ctx context.Context, userID string) ([]*model.SavedPaymentMethod, error) {
+	query := `
+		SELECT ` + paymentMethodColumns + `
+		FROM payment_methods
+		WHERE user_id = $1
+		ORDER BY is_default DESC, created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payment methods: %w", err)
+	}
+	defer rows.Close()
+
+	methods := []*model.SavedPaymentMethod{}
+	for rows.Next() {
+		method, err := scanPaymentMethod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment method: %w", err)
+		}
+		methods = append(methods, method)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating payment methods: %w", err)
+	}
+
+	return methods, nil
+}
+
+// SetDefaultPaymentMethod makes one of a user's payment methods their default
+func (r *PaymentMethodRepository) SetDefaultPaymentMethod(ctx context.Context, userID, methodID string) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		var exists bool
+		err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM payment_methods WHERE id = $1 AND user_id = $2 FOR UPDATE)`,
+			methodID, userID,
+		).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to get payment method: %w", err)
+		}
+		if !exists {
+			return ErrPaymentMethodNotFound
+		}
+
+		// The old default is cleared first so the unique index never sees two
+		statements := []string{
+			`UPDATE payment_methods SET is_default = FALSE WHERE user_id = $1 AND is_default AND id <> $2`,
+			`UPDATE payment_methods SET is_default = TRUE WHERE user_id = $1 AND id = $2`,
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(ctx, statement, userID, methodID); err != nil {
+				return fmt.Errorf("failed to set default payment method: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeletePaymentMethod removes one of a user's payment methods. When it was the default,
+// the most recently saved of the others becomes the default.
+func (r *PaymentMethodRepository) DeletePaymentMethod(ctx context.Context, userID, methodID string) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		var wasDefault bool
+		err := tx.QueryRow(ctx,
+			`DELETE FROM payment_methods WHERE id = $1 AND user_id = $2 RETURNING is_default`,
+			methodID, userID,
+		).Scan(&wasDefault)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrPaymentMethodNotFound
+			}
+			return fmt.Errorf("failed to delete payment method: %w", err)
+		}
+
+		if wasDefault {
+			_, err := tx.Exec(ctx, `
+				UPDATE payment_methods SET is_default = TRUE
+				WHERE id = (
+					SELECT id FROM payment_methods
+					WHERE user_id = $1
+					ORDER BY created_at DESC
+					LIMIT 1
+				)
+			`, userID)
+			if err != nil {
+				return fmt.Errorf("failed to promote default payment method: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// scanPaymentMethod reads a payment method selected with paymentMethodColumns
+func scanPaymentMethod(row pgx.Row) (*model.SavedPaymentMethod, error) {
+	var method model.SavedPaymentMethod
+	err := row.Scan(
+		&method.ID,
+		&method.UserID,
+		&method.Type,
+		&method.Gateway,
+		&method.GatewayToken,
+		&method.Fingerprint,
+		&method.Brand,
+		&method.Last4,
+		&method.ExpMonth,
+		&method.ExpYear,
+		&method.IsDefault,
+		&method.CreatedAt,
+		&method.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// PaymentShareRepository handles database operations for split payments
+type PaymentShareRepository struct {
+	db *database.PostgresDB
+}
+
+// NewPaymentShareRepository creates a new payment share repository
+func NewPaymentShareRepository(db *database.PostgresDB) *PaymentShareRepository {
+	return &PaymentShareRepository{
+		db: db,
+	}
+}
+
+// CreateShares stores the payment shares of an order
+func (r *PaymentShareRepository) CreateShares(ctx context.Context, shares []*model.PaymentShare) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, share := range shares {
+		if err := insertShareTx(ctx, tx, share); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListOrderShares lists the payment shares of an order
+func (r *PaymentShareRepository) ListOrderShares(ctx context.Context, orderID string) ([]*model.PaymentShare, error) {
+	query := `
+		SELECT id, order_id, user_id, percentage, amount, status, COALESCE(payment_id, ''),
+			attempts, COALESCE(last_error, ''), created_at, updated_at
+		FROM payment_shares
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payment shares: %w", err)
+	}
+	defer rows.Close()
+
+	shares := []*model.PaymentShare{}
+	for rows.Next() {
+		share := &model.PaymentShare{}
+		err := rows.Scan(
+			&share.ID,
+			&share.OrderID,
+			&share.UserID,
+			&share.Percentage,
+			&share.Amount,
+			&share.Status,
+			&share.PaymentID,
+			&share.Attempts,
+			&share.LastError,
+			&share.CreatedAt,
+			&share.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment share: %w", err)
+		}
+		shares = append(shares, share)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating payment shares: %w", err)
+	}
+
+	return shares, nil
+}
+
+// ListPendingOrders lists the IDs of orders still waiting for payment shares
+func (r *PaymentShareRepository) ListPendingOrders(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT DISTINCT s.order_id
+		FROM payment_shares s
+		JOIN orders o ON o.id = s.order_id
+		WHERE s.status IN ($1, $2) AND o.status = $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, model.SharePending, model.ShareFailed, model.StatusPaymentPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending split payments: %w", err)
+	}
+	defer rows.Close()
+
+	orderIDs := []string{}
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan order ID: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending split payments: %w", err)
+	}
+
+	return orderIDs, nil
+}
+
+// MarkShareCaptured records a successful collection of a share
+func (r *PaymentShareRepository) MarkShareCaptured(ctx context.Context, shareID, paymentID string) error {
+	query := `
+		UPDATE payment_shares
+		SET status = $2, payment_id = $3, attempts = attempts + 1, last_error = NULL, updated_at = $4
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, shareID, model.ShareCaptured, paymentID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to mark payment share captured: %w", err)
+	}
+
+	return nil
+}
+
+// MarkShareFailed records a failed collection attempt; the share is retried until the split times out
+func (r *PaymentShareRepository) MarkShareFailed(ctx context.Context, shareID, reason string) error {
+	query := `
+		UPDATE payment_shares
+		SET status = $2, attempts = attempts + 1, last_error = $3, updated_at = $4
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, shareID, model.ShareFailed, reason, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to mark payment share failed: %w", err)
+	}
+
+	return nil
+}
+
+// ReassignShares moves the outstanding shares of an order onto the primary payer's fallback share
+func (r *PaymentShareRepository) ReassignShares(ctx context.Context, orderID string, fallback *model.PaymentShare) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE payment_shares
+		SET status = $2, updated_at = $3
+		WHERE order_id = $1 AND status IN ($4, $5)
+	`
+	_, err = tx.Exec(ctx, query, orderID, model.ShareReassigned, time.Now(), model.SharePending, model.ShareFailed)
+	if err != nil {
+		return fmt.Errorf("failed to reassign payment shares: %w", err)
+	}
+
+	if err := insertShareTx(ctx, tx, fallback); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteSplitPayment moves an order to PAYMENT_COMPLETED once every share is collected.
+// It reports false, without error, when shares are outstanding or the order is no longer awaiting payment.
+func (r *PaymentShareRepository) CompleteSplitPayment(ctx context.Context, orderID string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var orderStatus model.OrderStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&orderStatus)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, ErrOrderNotFound
+		}
+		return false, fmt.Errorf("failed to get order: %w", err)
+	}
+	if orderStatus != model.StatusPaymentPending {
+		return false, nil
+	}
+
+	var outstanding int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM payment_shares WHERE order_id = $1 AND status IN ($2, $3)`,
+		orderID, model.SharePending, model.ShareFailed).Scan(&outstanding)
+	if err != nil {
+		return false, fmt.Errorf("failed to count outstanding payment shares: %w", err)
+	}
+	if outstanding > 0 {
+		return false, nil
+	}
+
+	if err := updateOrderStatusTx(ctx, tx, orderID, model.StatusPaymentComplete, "system", "All payment shares collected"); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// insertShareTx stores a payment share within tx
+func insertShareTx(ctx context.Context, tx pgx.Tx, share *model.PaymentShare) error {
+	query := `
+		INSERT INTO payment_shares (
+			id, order_id, user_id, percentage, amount, status, payment_id,
+			attempts, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10)
+	`
+
+	_, err := tx.Exec(ctx, query,
+		share.ID,
+		share.OrderID,
+		share.UserID,
+		share.Percentage,
+		share.Amount,
+		share.Status,
+		share.PaymentID,
+		share.Attempts,
+		share.CreatedAt,
+		share.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create payment share: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// userOrdersSubquery selects the IDs of a user's orders, archived ones included, the user
+// ID being $1
+const userOrdersSubquery = `(SELECT id FROM orders WHERE user_id = $1 UNION ALL SELECT id FROM orders_archive WHERE user_id = $1)`
+
+// PrivacyRepository handles the database operations behind data protection requests
+type PrivacyRepository struct {
+	db *database.PostgresDB
+}
+
+// NewPrivacyRepository creates a new privacy repository
+func NewPrivacyRepository(db *database.PostgresDB) *PrivacyRepository {
+	return &PrivacyRepository{
+		db: db,
+	}
+}
+
+// CountUnfinishedOrders counts the orders of a user, or of a provider, whose status is
+// not one of finished. Exactly one of userID and providerID should be set.
+func (r *PrivacyRepository) CountUnfinishedOrders(ctx context.Context, userID, providerID string, finished []model.OrderStatus) (int, error) {
+	names := make([]string, len(finished))
+	for i, status := range finished {
+		names[i] = string(status)
+	}
+
+	query := `
+		SELECT COUNT(*)
+		FROM orders
+		WHERE ($1 = '' OR user_id = $1)
+		  AND ($2 = '' OR provider_id = $2)
+		  AND status <> ALL($3)
+	`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID, providerID, names).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unfinished orders: %w", err)
+	}
+
+	return count, nil
+}
+
+// AnonymizeUser erases a user's personal data from their orders. Addresses, stop
+// instructions, notes, item options, status notes and the cancellation reasons in order events are cleared, and
+// pickup and destination coordinates are rounded. Amounts, fees, payment references and
+// blockchain hashes are kept. Archived orders are anonymized too. The
+// positions recorded during the orders, their chat messages, delivery photos, contact
+// tokens, tracking links, the user's favorite and blocked providers and their saved
+// payment methods are deleted.
+// Anonymizing a user again only deletes what was added since.
+func (r *PrivacyRepository) AnonymizeUser(ctx context.Context, userID string, at time.Time) (model.ErasureResult, error) {
+	var result model.ErasureResult
+
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE %s
+		SET pickup_location = jsonb_build_object(
+		        'latitude', ROUND((pickup_location->>'latitude')::NUMERIC, $2),
+		        'longitude', ROUND((pickup_location->>'longitude')::NUMERIC, $2),
+		        'address', '',
+		        'city', COALESCE(pickup_location->>'city', ''),
+		        'country', COALESCE(pickup_location->>'country', '')),
+		    destination_location = jsonb_build_object(
+		        'latitude', ROUND((destination_location->>'latitude')::NUMERIC, $2),
+		        'longitude', ROUND((destination_location->>'longitude')::NUMERIC, $2),
+		        'address', '',
+		        'city', COALESCE(destination_location->>'city', ''),
+		        'country', COALESCE(destination_location->>'country', '')),
+		    items = (SELECT COALESCE(jsonb_agg(item - 'properties'), '[]'::JSONB) FROM jsonb_array_elements(items) AS item),
+		    status_history = (SELECT COALESCE(jsonb_agg(entry - 'notes'), '[]'::JSONB) FROM jsonb_array_elements(status_history) AS entry),
+		    notes = '',
+		    anonymized_at = $3
+		WHERE user_id = $1 AND anonymized_at IS NULL
+	`
+
+	for _, table := range []string{"orders", "orders_archive"} {
+		tag, err := tx.Exec(ctx, fmt.Sprintf(query, table), userID, model.ErasedCoordinateDecimals, at)
+		if err != nil {
+			return result, fmt.Errorf("failed to anonymize %s: %w", table, err)
+		}
+		result.OrdersAnonymized += tag.RowsAffected()
+	}
+
+	for _, table := range []string{"order_locations", "order_tracks", "route_deviations"} {
+		tag, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE order_id IN `+userOrdersSubquery, userID)
+		if err != nil {
+			return result, fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+		result.LocationsDeleted += tag.RowsAffected()
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM chat_messages WHERE order_id IN `+userOrdersSubquery, userID)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete chat messages: %w", err)
+	}
+	result.ChatMessagesDeleted = tag.RowsAffected()
+
+	statements := []string{
+		`UPDATE delivery_proofs SET photo_ref = NULL WHERE photo_ref IS NOT NULL AND order_id IN ` + userOrdersSubquery,
+		`DELETE FROM contact_tokens WHERE order_id IN ` + userOrdersSubquery,
+		`DELETE FROM destination_changes WHERE order_id IN ` + userOrdersSubquery,
+		`UPDATE order_handoffs SET latitude = 0, longitude = 0 WHERE order_id IN ` + userOrdersSubquery,
+		`DELETE FROM tracking_links WHERE order_id IN ` + userOrdersSubquery,
+		`UPDATE order_events SET reason = '' WHERE reason <> '' AND order_id IN ` + userOrdersSubquery,
+		`DELETE FROM user_provider_preferences WHERE user_id = $1`,
+		`DELETE FROM payment_methods WHERE user_id = $1`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement, userID); err != nil {
+			return result, fmt.Errorf("failed to erase user data: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// DeleteProviderLocations deletes the positions a provider reported during orders and
+// reports how many were deleted
+func (r *PrivacyRepository) DeleteProviderLocations(ctx context.Context, providerID string) (int64, error) {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deleted int64
+	for _, table := range []string{"order_locations", "order_tracks", "route_deviations"} {
+		tag, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE provider_id = $1`, providerID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+		deleted += tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// EraseProviderVehicles erases the plates and descriptions of the vehicles a provider
+// accepted orders in. The vehicles' types are kept for reporting.
+func (r *PrivacyRepository) EraseProviderVehicles(ctx context.Context, providerID string) error {
+	query := `
+		UPDATE order_vehicles
+		SET plate = '', description = ''
+		WHERE provider_id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, providerID); err != nil {
+		return fmt.Errorf("failed to erase provider vehicles: %w", err)
+	}
+
+	return nil
+}
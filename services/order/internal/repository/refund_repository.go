@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// RefundRepository handles database operations for refunds
+type RefundRepository struct {
+	db *database.PostgresDB
+}
+
+// NewRefundRepository creates a new refund repository
+func NewRefundRepository(db *database.PostgresDB) *RefundRepository {
+	return &RefundRepository{
+		db: db,
+	}
+}
+
+// CreateRefund stores a refund and moves its order to REFUNDED
+func (r *RefundRepository) CreateRefund(ctx context.Context, refund *model.Refund) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertRefundTx(ctx, tx, refund); err != nil {
+		return err
+	}
+
+	if err := updateOrderStatusTx(ctx, tx, refund.OrderID, model.StatusRefunded, refund.RequestedBy, refund.Reason); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListOrderRefunds lists the refunds issued for an order, oldest first
+func (r *RefundRepository) ListOrderRefunds(ctx context.Context, orderID string) ([]*model.Refund, error) {
+	query := `
+		SELECT id, order_id, amount, reason, requested_by, COALESCE(dispute_id, ''), payment_refund_id, wallet_amount, created_at
+		FROM refunds
+		WHERE order_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refunds: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := []*model.Refund{}
+	for rows.Next() {
+		refund := &model.Refund{}
+		err := rows.Scan(
+			&refund.ID,
+			&refund.OrderID,
+			&refund.Amount,
+			&refund.Reason,
+			&refund.RequestedBy,
+			&refund.DisputeID,
+			&refund.PaymentRefundID,
+			&refund.WalletAmount,
+			&refund.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		refunds = append(refunds, refund)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating refunds: %w", err)
+	}
+
+	return refunds, nil
+}
+
+// insertRefundTx stores a refund within tx
+func insertRefundTx(ctx context.Context, tx pgx.Tx, refund *model.Refund) error {
+	query := `
+		INSERT INTO refunds (id, order_id, amount, reason, requested_by, dispute_id, payment_refund_id, wallet_amount, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9)
+	`
+
+	_, err := tx.Exec(ctx, query,
+		refund.ID,
+		refund.OrderID,
+		refund.Amount,
+		refund.Reason,
+		refund.RequestedBy,
+		refund.DisputeID,
+		refund.PaymentRefundID,
+		refund.WalletAmount,
+		refund.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// RentalRepository handles database operations for hourly rentals and their extensions
+type RentalRepository struct {
+	db *database.PostgresDB
+}
+
+// NewRentalRepository creates a new rental repository
+func NewRentalRepository(db *database.PostgresDB) *RentalRepository {
+	return &RentalRepository{
+		db: db,
+	}
+}
+
+// CreateRental stores the booking of a rental order
+func (r *RentalRepository) CreateRental(ctx context.Context, rental *model.Rental) error {
+	query := `
+		INSERT INTO order_rentals (order_id, hourly_rate, overtime_rate, booked_hours, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		rental.OrderID,
+		rental.HourlyRate,
+		rental.OvertimeRate,
+		rental.BookedHours,
+		rental.CreatedAt,
+		rental.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create rental: %w", err)
+	}
+
+	return nil
+}
+
+// GetRental gets the booking of a rental order
+func (r *RentalRepository) GetRental(ctx context.Context, orderID string) (*model.Rental, error) {
+	query := `
+		SELECT order_id, hourly_rate, overtime_rate, booked_hours, started_at, ended_at,
+			overtime_minutes, overtime_charge, COALESCE(overtime_payment_id, ''), created_at, updated_at
+		FROM order_rentals
+		WHERE order_id = $1
+	`
+
+	rental := &model.Rental{}
+	err := r.db.QueryRowContext(ctx, query, orderID).Scan(
+		&rental.OrderID,
+		&rental.HourlyRate,
+		&rental.OvertimeRate,
+		&rental.BookedHours,
+		&rental.StartedAt,
+		&rental.EndedAt,
+		&rental.OvertimeMinutes,
+		&rental.OvertimeCharge,
+		&rental.OvertimePaymentID,
+		&rental.CreatedAt,
+		&rental.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrRentalNotFound
+		}
+		return nil, fmt.Errorf("failed to get rental: %w", err)
+	}
+
+	return rental, nil
+}
+
+// StartRental starts a rental's clock unless it is already running. It reports whether
+// the clock was started.
+func (r *RentalRepository) StartRental(ctx context.Context, orderID string, at time.Time) (bool, error) {
+	tag, err := r.db.ExecContext(ctx, `
+		UPDATE order_rentals
+		SET started_at = $2, updated_at = $2
+		WHERE order_id = $1 AND started_at IS NULL
+	`, orderID, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to start rental: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// BillOvertime ends a rental with its overtime and adds the overtime to the order's
+// total and fees, given in order. It fails with ErrRentalEnded if the rental has
+// already ended.
+func (r *RentalRepository) BillOvertime(ctx context.Context, rental *model.Rental, order *model.Order) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE order_rentals
+		SET ended_at = $2, overtime_minutes = $3, overtime_charge = $4,
+			overtime_payment_id = NULLIF($5, ''), updated_at = $6
+		WHERE order_id = $1 AND ended_at IS NULL
+	`, rental.OrderID, rental.EndedAt, rental.OvertimeMinutes, rental.OvertimeCharge, rental.OvertimePaymentID, rental.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to end rental: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRentalEnded
+	}
+
+	if err := updateOrderChargesTx(ctx, tx, order); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CreateExtension stores a pending extension request. It fails with
+// ErrRentalExtensionPending if the rental already has one.
+func (r *RentalRepository) CreateExtension(ctx context.Context, extension *model.RentalExtension) error {
+	query := `
+		INSERT INTO rental_extensions (id, order_id, hours, amount, status, requested_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (order_id) WHERE status = 'PENDING' DO NOTHING
+	`
+
+	tag, err := r.db.ExecContext(ctx, query,
+		extension.ID,
+		extension.OrderID,
+		extension.Hours,
+		extension.Amount,
+		extension.Status,
+		extension.RequestedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create rental extension: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRentalExtensionPending
+	}
+
+	return nil
+}
+
+// GetExtension gets an extension request by its ID
+func (r *RentalRepository) GetExtension(ctx context.Context, extensionID string) (*model.RentalExtension, error) {
+	query := fmt.Sprintf(`SELECT %s FROM rental_extensions WHERE id = $1`, rentalExtensionColumns)
+
+	extension, err := scanRentalExtension(r.db.QueryRowContext(ctx, query, extensionID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrRentalExtensionNotFound
+		}
+		return nil, fmt.Errorf("failed to get rental extension: %w", err)
+	}
+
+	return extension, nil
+}
+
+// ListExtensions lists a rental's extension requests, oldest first
+func (r *RentalRepository) ListExtensions(ctx context.Context, orderID string) ([]*model.RentalExtension, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM rental_extensions
+		WHERE order_id = $1
+		ORDER BY requested_at
+	`, rentalExtensionColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rental extensions: %w", err)
+	}
+	defer rows.Close()
+
+	extensions := []*model.RentalExtension{}
+	for rows.Next() {
+		extension, err := scanRentalExtension(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rental extension: %w", err)
+		}
+		extensions = append(extensions, extension)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rental extensions: %w", err)
+	}
+
+	return extensions, nil
+}
+
+// ApproveExtension approves a pending extension, adds its hours to the booking and its
+// amount to the order's total and fees, given in order. It fails with
+// ErrRentalExtensionNotPending if the extension was already answered.
+func (r *RentalRepository) ApproveExtension(ctx context.Context, extension *model.RentalExtension, order *model.Order) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := respondExtensionTx(ctx, tx, extension); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE order_rentals
+		SET booked_hours = booked_hours + $2, updated_at = $3
+		WHERE order_id = $1
+	`, extension.OrderID, extension.Hours, extension.RespondedAt)
+	if err != nil {
+		return fmt.Errorf("failed to extend rental: %w", err)
+	}
+
+	if err := updateOrderChargesTx(ctx, tx, order); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeclineExtension declines a pending extension. It fails with
+// ErrRentalExtensionNotPending if the extension was already answered.
+func (r *RentalRepository) DeclineExtension(ctx context.Context, extension *model.RentalExtension) error {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := respondExtensionTx(ctx, tx, extension); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// respondExtensionTx records the answer to a pending extension within tx
+func respondExtensionTx(ctx context.Context, tx pgx.Tx, extension *model.RentalExtension) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE rental_extensions
+		SET status = $2, payment_id = NULLIF($3, ''), responded_at = $4
+		WHERE id = $1 AND status = $5
+	`, extension.ID, extension.Status, extension.PaymentID, extension.RespondedAt, model.ExtensionPending)
+	if err != nil {
+		return fmt.Errorf("failed to update rental extension: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRentalExtensionNotPending
+	}
+
+	return nil
+}
+
+// updateOrderChargesTx stores an order's total and fees within tx
+func updateOrderChargesTx(ctx context.Context, tx pgx.Tx, order *model.Order) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE orders
+		SET total_price = $2, platform_fee = $3, provider_fee = $4, updated_at = $5
+		WHERE id = $1
+	`, order.ID, order.TotalPrice, order.PlatformFee, order.ProviderFee, order.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update order charges: %w", err)
+	}
+
+	return nil
+}
+
+const rentalExtensionColumns = `id, order_id, hours, amount, status, COALESCE(payment_id, ''), requested_at, responded_at`
+
+func scanRentalExtension(row pgx.Row) (*model.RentalExtension, error) {
+	extension := &model.RentalExtension{}
+	err := row.Scan(
+		&extension.ID,
+		&extension.OrderID,
+		&extension.Hours,
+		&extension.Amount,
+		&extension.Status,
+		&extension.PaymentID,
+		&extension.RequestedAt,
+		&extension.RespondedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return extension, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// RouteDeviationRepository handles database operations for route deviations
+type RouteDeviationRepository struct {
+	db *database.PostgresDB
+}
+
+// NewRouteDeviationRepository creates a new route deviation repository
+func NewRouteDeviationRepository(db *database.PostgresDB) *RouteDeviationRepository {
+	return &RouteDeviationRepository{
+		db: db,
+	}
+}
+
+// CreateDeviation stores a deviation. It reports false, without error, when the order
+// already has an active deviation.
+func (r *RouteDeviationRepository) CreateDeviation(ctx context.Context, deviation *model.RouteDeviation) (bool, error) {
+	query := `
+		INSERT INTO route_deviations (
+			id, order_id, provider_id, distance_km, latitude, longitude, started_at, detected_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (order_id) WHERE cleared_at IS NULL DO NOTHING
+	`
+	tag, err := r.db.ExecContext(ctx, query,
+		deviation.ID,
+		deviation.OrderID,
+		deviation.ProviderID,
+		deviation.DistanceKm,
+		deviation.Latitude,
+		deviation.Longitude,
+		deviation.StartedAt,
+		deviation.DetectedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create route deviation: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetActiveDeviation gets an order's active deviation
+func (r *RouteDeviationRepository) GetActiveDeviation(ctx context.Context, orderID string) (*model.RouteDeviation, error) {
+	query := `
+		SELECT id, order_id, provider_id, distance_km, latitude, longitude, started_at, detected_at, cleared_at
+		FROM route_deviations
+		WHERE order_id = $1 AND cleared_at IS NULL
+	`
+
+	deviation := &model.RouteDeviation{}
+	err := r.db.QueryRowContext(ctx, query, orderID).Scan(
+		&deviation.ID,
+		&deviation.OrderID,
+		&deviation.ProviderID,
+		&deviation.DistanceKm,
+		&deviation.Latitude,
+		&deviation.Longitude,
+		&deviation.StartedAt,
+		&deviation.DetectedAt,
+		&deviation.ClearedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrRouteDeviationNotFound
+		}
+		return nil, fmt.Errorf("failed to get route deviation: %w", err)
+	}
+
+	return deviation, nil
+}
+
+// ListOrderDeviations lists every deviation of an order, active or cleared, oldest first
+func (r *RouteDeviationRepository) ListOrderDeviations(ctx context.Context, orderID string) ([]*model.RouteDeviation, error) {
+	query := `
+		SELECT id, order_id, provider_id, distance_km, latitude, longitude, started_at, detected_at, cleared_at
+		FROM route_deviations
+		WHERE order_id = $1
+		ORDER BY started_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query route deviations: %w", err)
+	}
+	defer rows.Close()
+
+	deviations := []*model.RouteDeviation{}
+	for rows.Next() {
+		deviation := &model.RouteDeviation{}
+		err := rows.Scan(
+			&deviation.ID,
+			&deviation.OrderID,
+			&deviation.ProviderID,
+			&deviation.DistanceKm,
+			&deviation.Latitude,
+			&deviation.Longitude,
+			&deviation.StartedAt,
+			&deviation.DetectedAt,
+			&deviation.ClearedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan route deviation: %w", err)
+		}
+		deviations = append(deviations, deviation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating route deviations: %w", err)
+	}
+
+	return deviations, nil
+}
+
+// ClearDeviation marks an order's active deviation as over
+func (r *RouteDeviationRepository) ClearDeviation(ctx context.Context, orderID string, at time.Time) error {
+	query := `UPDATE route_deviations SET cleared_at = $2 WHERE order_id = $1 AND cleared_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, orderID, at)
+	if err != nil {
+		return fmt.Errorf("failed to clear route deviation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const serviceAreaColumns = `id, city, name, boundary, active, created_at, updated_at`
+
+// ServiceAreaRepository handles database operations for service areas
+type ServiceAreaRepository struct {
+	db *database.PostgresDB
+}
+
+// NewServiceAreaRepository creates a new service area repository
+func NewServiceAreaRepository(db *database.PostgresDB) *ServiceAreaRepository {
+	return &ServiceAreaRepository{
+		db: db,
+	}
+}
+
+// CreateArea stores a service area
+func (r *ServiceAreaRepository) CreateArea(ctx context.Context, area *model.ServiceArea) error {
+	query := `
+		INSERT INTO service_areas (id, city, name, boundary, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		area.ID,
+		area.City,
+		area.Name,
+		area.Boundary,
+		area.Active,
+		area.CreatedAt,
+		area.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create service area: %w", err)
+	}
+
+	return nil
+}
+
+// GetArea gets a service area by its ID
+func (r *ServiceAreaRepository) GetArea(ctx context.Context, areaID string) (*model.ServiceArea, error) {
+	query := fmt.Sprintf(`SELECT %s FROM service_areas WHERE id = $1`, serviceAreaColumns)
+
+	area, err := scanServiceArea(r.db.QueryRowContext(ctx, query, areaID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrServiceAreaNotFound
+		}
+		return nil, fmt.Errorf("failed to get service area: %w", err)
+	}
+
+	return area, nil
+}
+
+// UpdateArea changes the name, boundary and active flag of a service area; its city
+// cannot change
+func (r *ServiceAreaRepository) UpdateArea(ctx context.Context, area *model.ServiceArea) error {
+	query := `
+		UPDATE service_areas
+		SET name = $2, boundary = $3, active = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	tag, err := r.db.ExecContext(ctx, query,
+		area.ID,
+		area.Name,
+		area.Boundary,
+		area.Active,
+		area.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update service area: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrServiceAreaNotFound
+	}
+
+	return nil
+}
+
+// DeleteArea deletes a service area
+func (r *ServiceAreaRepository) DeleteArea(ctx context.Context, areaID string) error {
+	tag, err := r.db.ExecContext(ctx, `DELETE FROM service_areas WHERE id = $1`, areaID)
+	if err != nil {
+		return fmt.Errorf("failed to delete service area: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrServiceAreaNotFound
+	}
+
+	return nil
+}
+
+// ListAreas lists the service areas of a city, or of every city when city is empty,
+// ordered by city and name
+func (r *ServiceAreaRepository) ListAreas(ctx context.Context, city string) ([]*model.ServiceArea, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM service_areas
+		WHERE $1 = '' OR city = $1
+		ORDER BY city, name
+	`, serviceAreaColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, city)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service areas: %w", err)
+	}
+	defer rows.Close()
+
+	areas := []*model.ServiceArea{}
+	for rows.Next() {
+		area, err := scanServiceArea(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan service area: %w", err)
+		}
+		areas = append(areas, area)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating service areas: %w", err)
+	}
+
+	return areas, nil
+}
+
+func scanServiceArea(row pgx.Row) (*model.ServiceArea, error) {
+	area := &model.ServiceArea{}
+	err := row.Scan(
+		&area.ID,
+		&area.City,
+		&area.Name,
+		&area.Boundary,
+		&area.Active,
+		&area.CreatedAt,
+		&area.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return area, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// SLARepository handles database operations for SLA results
+type SLARepository struct {
+	db *database.PostgresDB
+}
+
+// NewSLARepository creates a new SLA repository
+func NewSLARepository(db *database.PostgresDB) *SLARepository {
+	return &SLARepository{
+		db: db,
+	}
+}
+
+// RecordResult stores the outcome of an order's stage and, in the same transaction,
+// records an SLA event for the analytics aggregator. A breach also marks the order at
+// risk. It reports false, without error, when the stage already has an outcome, so each
+// stage is counted once.
+func (r *SLARepository) RecordResult(ctx context.Context, result *model.SLAResult) (bool, error) {
+	var recorded bool
+	err := r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO sla_results (order_id, stage, outcome, started_at, deadline, ended_at, recorded_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (order_id, stage) DO NOTHING
+		`,
+			result.OrderID,
+			result.Stage,
+			result.Outcome,
+			result.StartedAt,
+			result.Deadline,
+			result.EndedAt,
+			result.RecordedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record SLA result: %w", err)
+		}
+		recorded = tag.RowsAffected() > 0
+		if !recorded {
+			return nil
+		}
+
+		event := &model.OrderEvent{
+			OrderID:   result.OrderID,
+			EventType: model.OrderEventSLAMet,
+			SLAStage:  result.Stage,
+			CreatedAt: result.RecordedAt,
+		}
+		if result.Outcome == model.SLABreached {
+			event.EventType = model.OrderEventSLABreached
+		}
+		err = tx.QueryRow(ctx, `
+			SELECT order_type, COALESCE(pickup_location->>'city', ''), COALESCE(provider_id, ''), status,
+			       total_price, platform_fee
+			FROM orders
+			WHERE id = $1
+			FOR UPDATE
+		`, result.OrderID).Scan(
+			&event.OrderType,
+			&event.City,
+			&event.ProviderID,
+			&event.Status,
+			&event.TotalPrice,
+			&event.PlatformFee,
+		)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+
+		if result.Outcome == model.SLABreached {
+			if _, err := tx.Exec(ctx, `UPDATE orders SET at_risk = TRUE WHERE id = $1`, result.OrderID); err != nil {
+				return fmt.Errorf("failed to mark order at risk: %w", err)
+			}
+		}
+
+		return recordOrderEventTx(ctx, tx, event)
+	})
+	return recorded, err
+}
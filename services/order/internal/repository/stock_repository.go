@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// StockRepository holds the stock of merchants' catalog items for orders. It is the
+// order service's built-in stock reservation client.
+type StockRepository struct {
+	db *database.PostgresDB
+}
+
+// NewStockRepository creates a new stock repository
+func NewStockRepository(db *database.PostgresDB) *StockRepository {
+	return &StockRepository{
+		db: db,
+	}
+}
+
+// Reserve takes the stock of an order's items off its merchant's catalog and holds it for
+// the order until a time. Items whose stock is not tracked are not held. When any tracked
+// item has too few left it fails with ErrOutOfStock and takes nothing.
+func (r *StockRepository) Reserve(ctx context.Context, orderID, merchantID string, items model.OrderItems, until time.Time) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		now := time.Now()
+		for _, item := range items {
+			if item.Quantity <= 0 {
+				continue
+			}
+
+			tag, err := tx.Exec(ctx, `
+				UPDATE catalog_items
+				SET stock = stock - $3, updated_at = $4
+				WHERE id = $1 AND merchant_id = $2 AND stock >= $3
+			`, item.ItemID, merchantID, item.Quantity, now)
+			if err != nil {
+				return fmt.Errorf("failed to reserve stock: %w", err)
+			}
+			if tag.RowsAffected() == 0 {
+				var tracked bool
+				err := tx.QueryRow(ctx, `SELECT stock IS NOT NULL FROM catalog_items WHERE id = $1 AND merchant_id = $2`,
+					item.ItemID, merchantID).Scan(&tracked)
+				if err != nil {
+					if err == pgx.ErrNoRows {
+						return ErrCatalogItemNotFound
+					}
+					return fmt.Errorf("failed to get stock: %w", err)
+				}
+				if tracked {
+					return fmt.Errorf("%s: %w", item.Name, ErrOutOfStock)
+				}
+				continue
+			}
+
+			_, err = tx.Exec(ctx, `
+				INSERT INTO stock_reservations (order_id, item_id, quantity, expires_at, created_at)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (order_id, item_id) DO UPDATE SET quantity = stock_reservations.quantity + EXCLUDED.quantity
+			`, orderID, item.ItemID, item.Quantity, until, now)
+			if err != nil {
+				return fmt.Errorf("failed to store stock reservation: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Release gives the stock held for an order back to the catalog. Releasing an order
+// that holds nothing does nothing.
+func (r *StockRepository) Release(ctx context.Context, orderID string) error {
+	query := `
+		WITH released AS (
+			DELETE FROM stock_reservations WHERE order_id = $1
+			RETURNING item_id, quantity
+		)
+		UPDATE catalog_items c
+		SET stock = c.stock + released.quantity, updated_at = $2
+		FROM released
+		WHERE c.id = released.item_id AND c.stock IS NOT NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, orderID, time.Now()); err != nil {
+		return fmt.Errorf("failed to release stock: %w", err)
+	}
+
+	return nil
+}
+
+// Confirm keeps the stock held for an order off the catalog for good, once the order can
+// no longer give it back
+func (r *StockRepository) Confirm(ctx context.Context, orderID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM stock_reservations WHERE order_id = $1`, orderID); err != nil {
+		return fmt.Errorf("failed to confirm stock reservation: %w", err)
+	}
+
+	return nil
+}
+
+// ListExpiredReservations lists up to limit orders holding stock whose hold ended before
+// a time, oldest first
+func (r *StockRepository) ListExpiredReservations(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT order_id
+		FROM stock_reservations
+		WHERE expires_at < $1
+		GROUP BY order_id
+		ORDER BY MIN(expires_at)
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired stock reservations: %w", err)
+	}
+	defer rows.Close()
+
+	orderIDs := []string{}
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, fmt.Errorf("failed to scan stock reservation: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock reservations: %w", err)
+	}
+
+	return orderIDs, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const supportSessionColumns = `id, admin_id, user_id, reason, scope, token_hash, created_at, expires_at, ended_at, ended_by`
+
+// SupportSessionRepository handles database operations for the sessions in which admins
+// act on behalf of users
+type SupportSessionRepository struct {
+	db *database.PostgresDB
+}
+
+// NewSupportSessionRepository creates a new support session repository
+func NewSupportSessionRepository(db *database.PostgresDB) *SupportSessionRepository {
+	return &SupportSessionRepository{
+		db: db,
+	}
+}
+
+// CreateSession stores a support session
+func (r *SupportSessionRepository) CreateSession(ctx context.Context, session *model.SupportSession) error {
+	query := `
+		INSERT INTO support_sessions (id, admin_id, user_id, reason, scope, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		session.ID,
+		session.AdminID,
+		session.UserID,
+		session.Reason,
+		session.Scope,
+		session.TokenHash,
+		session.CreatedAt,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create support session: %w", err)
+	}
+
+	return nil
+}
+
+// GetSession gets a support session by ID
+func (r *SupportSessionRepository) GetSession(ctx context.Context, id string) (*model.SupportSession, error) {
+	query := fmt.Sprintf(`SELECT %s FROM support_sessions WHERE id = $1`, supportSessionColumns)
+
+	session, err := scanSupportSession(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrSupportSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get support session: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSessionByHash gets a support session by the hash of its token
+func (r *SupportSessionRepository) GetSessionByHash(ctx context.Context, tokenHash string) (*model.SupportSession, error) {
+	query := fmt.Sprintf(`SELECT %s FROM support_sessions WHERE token_hash = $1`, supportSessionColumns)
+
+	session, err := scanSupportSession(r.db.QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrSupportSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get support session: %w", err)
+	}
+
+	return session, nil
+}
+
+// EndSession ends a session that has not been ended yet; ending one twice fails with
+// ErrSupportSessionEnded
+func (r *SupportSessionRepository) EndSession(ctx context.Context, id, endedBy string, endedAt time.Time) error {
+	tag, err := r.db.ExecContext(ctx, `
+		UPDATE support_sessions
+		SET ended_at = $2, ended_by = $3
+		WHERE id = $1 AND ended_at IS NULL
+	`, id, endedAt, endedBy)
+	if err != nil {
+		return fmt.Errorf("failed to end support session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := r.GetSession(ctx, id); err != nil {
+			return err
+		}
+		return ErrSupportSessionEnded
+	}
+
+	return nil
+}
+
+// ListSessions lists the sessions for a user, by an admin, or both, newest first. With
+// activeAt set, only the sessions still active then are listed.
+func (r *SupportSessionRepository) ListSessions(ctx context.Context, userID, adminID string, activeAt *time.Time) ([]*model.SupportSession, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM support_sessions
+		WHERE ($1 = '' OR user_id = $1)
+		  AND ($2 = '' OR admin_id = $2)
+		  AND ($3::timestamp IS NULL OR (ended_at IS NULL AND expires_at > $3))
+		ORDER BY created_at DESC
+		LIMIT 100
+	`, supportSessionColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, userID, adminID, activeAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query support sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*model.SupportSession{}
+	for rows.Next() {
+		session, err := scanSupportSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan support session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating support sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func scanSupportSession(row pgx.Row) (*model.SupportSession, error) {
+	session := &model.SupportSession{}
+	err := row.Scan(
+		&session.ID,
+		&session.AdminID,
+		&session.UserID,
+		&session.Reason,
+		&session.Scope,
+		&session.TokenHash,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&session.EndedAt,
+		&session.EndedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
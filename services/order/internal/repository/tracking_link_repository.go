@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// TrackingLinkRepository handles database operations for public tracking links
+type TrackingLinkRepository struct {
+	db *database.PostgresDB
+}
+
+// NewTrackingLinkRepository creates a new tracking link repository
+func NewTrackingLinkRepository(db *database.PostgresDB) *TrackingLinkRepository {
+	return &TrackingLinkRepository{
+		db: db,
+	}
+}
+
+// CreateLink stores a tracking link
+func (r *TrackingLinkRepository) CreateLink(ctx context.Context, link *model.TrackingLink) error {
+	query := `
+		INSERT INTO tracking_links (id, order_id, created_by, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		link.ID,
+		link.OrderID,
+		link.CreatedBy,
+		link.TokenHash,
+		link.ExpiresAt,
+		link.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create tracking link: %w", err)
+	}
+
+	return nil
+}
+
+// GetLinkByHash retrieves a tracking link by the hash of its token
+func (r *TrackingLinkRepository) GetLinkByHash(ctx context.Context, tokenHash string) (*model.TrackingLink, error) {
+	query := `
+		SELECT id, order_id, created_by, token_hash, expires_at, created_at
+		FROM tracking_links
+		WHERE token_hash = $1
+	`
+
+	var link model.TrackingLink
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&link.ID,
+		&link.OrderID,
+		&link.CreatedBy,
+		&link.TokenHash,
+		&link.ExpiresAt,
+		&link.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrTrackingLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to get tracking link: %w", err)
+	}
+
+	return &link, nil
+}
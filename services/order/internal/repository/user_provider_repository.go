@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const userProviderPreferenceColumns = `user_id, provider_id, preference, created_at, updated_at`
+
+// UserProviderRepository handles database operations for the providers users have
+// favorited or blocked
+type UserProviderRepository struct {
+	db *database.PostgresDB
+}
+
+// NewUserProviderRepository creates a new user provider repository
+func NewUserProviderRepository(db *database.PostgresDB) *UserProviderRepository {
+	return &UserProviderRepository{
+		db: db,
+	}
+}
+
+// SetPreference stores a user's preference for a provider, replacing any earlier one.
+// The stored preference is written back to pref, keeping its original creation time.
+func (r *UserProviderRepository) SetPreference(ctx context.Context, pref *model.UserProviderPreference) error {
+	query := fmt.Sprintf(`
+		INSERT INTO user_provider_preferences (user_id, provider_id, preference, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, provider_id) DO UPDATE
+		SET preference = EXCLUDED.preference,
+		    updated_at = EXCLUDED.updated_at
+		RETURNING %s
+	`, userProviderPreferenceColumns)
+
+	stored, err := scanUserProviderPreference(r.db.QueryRowContext(ctx, query,
+		pref.UserID,
+		pref.ProviderID,
+		pref.Preference,
+		pref.CreatedAt,
+		pref.UpdatedAt,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to set user provider preference: %w", err)
+	}
+	*pref = *stored
+
+	return nil
+}
+
+// GetPreference gets a user's preference for a provider
+func (r *UserProviderRepository) GetPreference(ctx context.Context, userID, providerID string) (*model.UserProviderPreference, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM user_provider_preferences
+		WHERE user_id = $1 AND provider_id = $2
+	`, userProviderPreferenceColumns)
+
+	pref, err := scanUserProviderPreference(r.db.QueryRowContext(ctx, query, userID, providerID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrUserProviderPreferenceNotFound
+		}
+		return nil, fmt.Errorf("failed to get user provider preference: %w", err)
+	}
+
+	return pref, nil
+}
+
+// DeletePreference removes a user's preference for a provider
+func (r *UserProviderRepository) DeletePreference(ctx context.Context, userID, providerID string) error {
+	tag, err := r.db.ExecContext(ctx,
+		`DELETE FROM user_provider_preferences WHERE user_id = $1 AND provider_id = $2`,
+		userID, providerID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete user provider preference: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserProviderPreferenceNotFound
+	}
+
+	return nil
+}
+
+// ListPreferences lists a user's preferences of one kind, or of every kind when
+// preference is empty, newest first
+func (r *UserProviderRepository) ListPreferences(ctx context.Context, userID string, preference model.ProviderPreference) ([]*model.UserProviderPreference, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM user_provider_preferences
+		WHERE user_id = $1 AND ($2 = '' OR preference = $2)
+		ORDER BY updated_at DESC
+	`, userProviderPreferenceColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, userID, preference)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user provider preferences: %w", err)
+	}
+	defer rows.Close()
+
+	prefs := []*model.UserProviderPreference{}
+	for rows.Next() {
+		pref, err := scanUserProviderPreference(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user provider preference: %w", err)
+		}
+		prefs = append(prefs, pref)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user provider preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+func scanUserProviderPreference(row pgx.Row) (*model.UserProviderPreference, error) {
+	pref := &model.UserProviderPreference{}
+	err := row.Scan(
+		&pref.UserID,
+		&pref.ProviderID,
+		&pref.Preference,
+		&pref.CreatedAt,
+		&pref.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pref, nil
+}
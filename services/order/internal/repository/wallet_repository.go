@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+const walletTransactionColumns = `
+	id, user_id, type, amount, balance_after, COALESCE(order_id, ''), payment_id,
+	reference, description, created_at
+`
+
+// WalletRepository handles database operations for user wallets and their transactions
+type WalletRepository struct {
+	db *database.PostgresDB
+}
+
+// NewWalletRepository creates a new wallet repository
+func NewWalletRepository(db *database.PostgresDB) *WalletRepository {
+	return &WalletRepository{
+		db: db,
+	}
+}
+
+// ApplyWalletTransaction changes a wallet's balance by txn.Amount and records the change,
+// setting txn.BalanceAfter. A debit more than the balance fails with
+// ErrInsufficientWalletBalance. A transaction whose reference the user already has is
+// not applied again; txn is set to the one applied before.
+func (r *WalletRepository) ApplyWalletTransaction(ctx context.Context, txn *model.WalletTransaction) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		// Lock the wallet, so changes to it are applied one at a time
+		_, err := tx.Exec(ctx, `
+			INSERT INTO wallets (user_id, balance, created_at, updated_at)
+			VALUES ($1, 0, $2, $2)
+			ON CONFLICT (user_id) DO NOTHING
+		`, txn.UserID, txn.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create wallet: %w", err)
+		}
+		var balance int64
+		if err := tx.QueryRow(ctx, `SELECT balance FROM wallets WHERE user_id = $1 FOR UPDATE`, txn.UserID).Scan(&balance); err != nil {
+			return fmt.Errorf("failed to lock wallet: %w", err)
+		}
+
+		query := `SELECT ` + walletTransactionColumns + ` FROM wallet_transactions WHERE user_id = $1 AND reference = $2`
+		applied, err := scanWalletTransaction(tx.QueryRow(ctx, query, txn.UserID, txn.Reference))
+		if err == nil {
+			*txn = *applied
+			return nil
+		}
+		if err != pgx.ErrNoRows {
+			return fmt.Errorf("failed to check wallet transaction: %w", err)
+		}
+
+		if balance+txn.Amount < 0 {
+			return ErrInsufficientWalletBalance
+		}
+		txn.BalanceAfter = balance + txn.Amount
+
+		_, err = tx.Exec(ctx, `UPDATE wallets SET balance = $2, updated_at = $3 WHERE user_id = $1`, txn.UserID, txn.BalanceAfter, txn.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update wallet balance: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO wallet_transactions (
+				id, user_id, type, amount, balance_after, order_id, payment_id,
+				reference, description, created_at
+			) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10)
+		`,
+			txn.ID,
+			txn.UserID,
+			txn.Type,
+			txn.Amount,
+			txn.BalanceAfter,
+			txn.OrderID,
+			txn.PaymentID,
+			txn.Reference,
+			txn.Description,
+			txn.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create wallet transaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetWallet retrieves a user's wallet. Users without one get an empty wallet.
+func (r *WalletRepository) GetWallet(ctx context.Context, userID string) (*model.Wallet, error) {
+	wallet := &model.Wallet{UserID: userID}
+
+	err := r.db.QueryRowContext(ctx,
+		`SELECT balance, updated_at FROM wallets WHERE user_id = $1`,
+		userID,
+	).Scan(&wallet.Balance, &wallet.UpdatedAt)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// ListWalletTransactions lists a page of a user's wallet transactions, newest first, and
+// the total number they have
+func (r *WalletRepository) ListWalletTransactions(ctx context.Context, userID string, page, limit int) ([]*model.WalletTransaction, int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM wallet_transactions WHERE user_id = $1`, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count wallet transactions: %w", err)
+	}
+
+	// Set reasonable defaults and boundaries
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	query := `
+		SELECT ` + walletTransactionColumns + `
+		FROM wallet_transactions
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query wallet transactions: %w", err)
+	}
+	defer rows.Close()
+
+	txns := []*model.WalletTransaction{}
+	for rows.Next() {
+		txn, err := scanWalletTransaction(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan wallet transaction: %w", err)
+		}
+		txns = append(txns, txn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate wallet transactions: %w", err)
+	}
+
+	return txns, total, nil
+}
+
+func scanWalletTransaction(row pgx.Row) (*model.WalletTransaction, error) {
+	txn := &model.WalletTransaction{}
+	err := row.Scan(
+		&txn.ID,
+		&txn.UserID,
+		&txn.Type,
+		&txn.Amount,
+		&txn.BalanceAfter,
+		&txn.OrderID,
+		&txn.PaymentID,
+		&txn.Reference,
+		&txn.Description,
+		&txn.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
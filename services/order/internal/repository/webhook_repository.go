@@ -0,0 +1,457 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/pkg/events"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/protobuf/proto"
+)
+
+// eventSource is the CloudEvents source of the events the order service sends
+const eventSource = "/order-service"
+
+const webhookSubscriptionColumns = `id, partner_id, url, secret, event_types, order_types, active, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_attempt_at, response_code, last_error, created_at, delivered_at`
+
+// WebhookRepository handles database operations for webhook subscriptions and their deliveries
+type WebhookRepository struct {
+	db *database.PostgresDB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *database.PostgresDB) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+// CreateSubscription stores a new webhook subscription
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (
+			id, partner_id, url, secret, event_types, order_types, active, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		sub.ID,
+		sub.PartnerID,
+		sub.URL,
+		sub.Secret,
+		webhookEventTypeStrings(sub.EventTypes),
+		orderTypeStrings(sub.OrderTypes),
+		sub.Active,
+		sub.CreatedAt,
+		sub.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	return nil
+}
+
+// GetSubscription gets a webhook subscription by its ID
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id string) (*model.WebhookSubscription, error) {
+	query := fmt.Sprintf(`SELECT %s FROM webhook_subscriptions WHERE id = $1`, webhookSubscriptionColumns)
+
+	sub, err := scanWebhookSubscription(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+
+	return sub, nil
+}
+
+// ListSubscriptions lists a partner's webhook subscriptions, or every subscription when
+// partnerID is empty, newest first
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context, partnerID string) ([]*model.WebhookSubscription, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webhook_subscriptions
+		WHERE $1 = '' OR partner_id = $1
+		ORDER BY created_at DESC
+	`, webhookSubscriptionColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, partnerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []*model.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// UpdateSubscription replaces a subscription's URL, event filters and active flag
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, sub *model.WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = $2, event_types = $3, order_types = $4, active = $5, updated_at = $6
+		WHERE id = $1
+	`
+
+	tag, err := r.db.ExecContext(ctx, query,
+		sub.ID,
+		sub.URL,
+		webhookEventTypeStrings(sub.EventTypes),
+		orderTypeStrings(sub.OrderTypes),
+		sub.Active,
+		sub.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// DeleteSubscription removes a subscription along with its delivery log
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	tag, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// ListDeliveries lists a subscription's deliveries, optionally only those in one status,
+// newest first, along with how many match in total
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID string, status model.WebhookDeliveryStatus, page, limit int) ([]*model.WebhookDelivery, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+	`, subscriptionID, status).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`, webhookDeliveryColumns)
+
+	deliveries, err := r.queryDeliveries(ctx, query, subscriptionID, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// GetDelivery gets one of a subscription's deliveries
+func (r *WebhookRepository) GetDelivery(ctx context.Context, subscriptionID, deliveryID string) (*model.WebhookDelivery, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM webhook_deliveries
+		WHERE id = $1 AND subscription_id = $2
+	`, webhookDeliveryColumns)
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, deliveryID, subscriptionID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// RedeliverDelivery queues a delivery to be sent again as soon as possible, whatever its
+// status, with a fresh set of attempts
+func (r *WebhookRepository) RedeliverDelivery(ctx context.Context, subscriptionID, deliveryID string, now time.Time) error {
+	tag, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $3, attempts = 0, next_attempt_at = $4, delivered_at = NULL
+		WHERE id = $1 AND subscription_id = $2
+	`, deliveryID, subscriptionID, model.WebhookDeliveryPending, now)
+	if err != nil {
+		return fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	return nil
+}
+
+// ClaimDueDeliveries picks up to limit pending deliveries of active subscriptions that are
+// due, and leases them by pushing their next attempt to leaseUntil so no other dispatcher
+// sends them meanwhile. Each delivery comes with its subscription's URL and secret.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = $1 AND d.next_attempt_at <= $2 AND s.active
+			ORDER BY d.next_attempt_at
+			LIMIT $4
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $3
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+			d.next_attempt_at, d.last_attempt_at, d.response_code, d.last_error, d.created_at, d.delivered_at,
+			s.url, s.secret
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, model.WebhookDeliveryPending, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*model.WebhookDelivery{}
+	for rows.Next() {
+		delivery := &model.WebhookDelivery{}
+		var payload []byte
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastAttemptAt,
+			&delivery.ResponseCode,
+			&delivery.LastError,
+			&delivery.CreatedAt,
+			&delivery.DeliveredAt,
+			&delivery.URL,
+			&delivery.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		delivery.Payload = payload
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// RecordDeliveryAttempt stores the outcome of sending a delivery: its new status, attempt
+// count, response and, while it is still pending, when to try again
+func (r *WebhookRepository) RecordDeliveryAttempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_attempt_at = $5,
+		    response_code = $6, last_error = $7, delivered_at = $8
+		WHERE id = $1
+	`,
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastAttemptAt,
+		delivery.ResponseCode,
+		delivery.LastError,
+		delivery.DeliveredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+
+	return nil
+}
+
+// enqueueWebhookEventTx queues an event, as a CloudEvent in JSON carrying data, for every
+// active subscription to its type and the order's type, within tx so the event is recorded
+// if and only if the change it describes is committed
+func enqueueWebhookEventTx(ctx context.Context, tx pgx.Tx, eventType model.WebhookEventType, orderType model.OrderType, data proto.Message) error {
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM webhook_subscriptions
+		WHERE active AND $1 = ANY(event_types) AND (cardinality(order_types) = 0 OR $2 = ANY(order_types))
+	`, eventType, orderType)
+	if err != nil {
+		return fmt.Errorf("failed to query webhook subscriptions: %w", err)
+	}
+
+	var subscriptionIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subscriptionIDs = append(subscriptionIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating webhook subscriptions: %w", err)
+	}
+
+	if len(subscriptionIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	event, err := events.New(eventSource, string(eventType), data, now)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook event: %w", err)
+	}
+	payload, err := events.MarshalJSON(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	for _, subscriptionID := range subscriptionIDs {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO webhook_deliveries (
+				id, subscription_id, event_id, event_type, payload, status, next_attempt_at, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		`, uuid.New().String(), subscriptionID, event.Id, eventType, payload, model.WebhookDeliveryPending, now)
+		if err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// queryDeliveries runs a query selecting webhookDeliveryColumns
+func (r *WebhookRepository) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]*model.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*model.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// webhookEventTypeStrings converts event types to the text array they are stored as
+func webhookEventTypeStrings(eventTypes []model.WebhookEventType) []string {
+	values := make([]string, len(eventTypes))
+	for i, eventType := range eventTypes {
+		values[i] = string(eventType)
+	}
+	return values
+}
+
+// orderTypeStrings converts order types to the text array they are stored as
+func orderTypeStrings(orderTypes []model.OrderType) []string {
+	values := make([]string, len(orderTypes))
+	for i, orderType := range orderTypes {
+		values[i] = string(orderType)
+	}
+	return values
+}
+
+func scanWebhookSubscription(row pgx.Row) (*model.WebhookSubscription, error) {
+	sub := &model.WebhookSubscription{}
+	var eventTypes, orderTypes []string
+	err := row.Scan(
+		&sub.ID,
+		&sub.PartnerID,
+		&sub.URL,
+		&sub.Secret,
+		&eventTypes,
+		&orderTypes,
+		&sub.Active,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.EventTypes = make([]model.WebhookEventType, len(eventTypes))
+	for i, eventType := range eventTypes {
+		sub.EventTypes[i] = model.WebhookEventType(eventType)
+	}
+	sub.OrderTypes = make([]model.OrderType, len(orderTypes))
+	for i, orderType := range orderTypes {
+		sub.OrderTypes[i] = model.OrderType(orderType)
+	}
+
+	return sub, nil
+}
+
+func scanWebhookDelivery(row pgx.Row) (*model.WebhookDelivery, error) {
+	delivery := &model.WebhookDelivery{}
+	var payload []byte
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.SubscriptionID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastAttemptAt,
+		&delivery.ResponseCode,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&delivery.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = payload
+	return delivery, nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// AnalyticsConfig controls how often order events are folded into the daily aggregates
+type AnalyticsConfig struct {
+	Interval  time.Duration // How often unaggregated events are picked up
+	BatchSize int           // Most events aggregated in one transaction
+}
+
+// AnalyticsAggregator materializes the daily order aggregates from the order events
+// recorded alongside every order change. Each batch of events is aggregated and marked
+// in one transaction, so running several instances never counts an event twice.
+type AnalyticsAggregator struct {
+	analyticsRepo *repository.AnalyticsRepository
+	cfg           AnalyticsConfig
+}
+
+// NewAnalyticsAggregator creates a new analytics aggregator
+func NewAnalyticsAggregator(analyticsRepo *repository.AnalyticsRepository, cfg AnalyticsConfig) *AnalyticsAggregator {
+	return &AnalyticsAggregator{
+		analyticsRepo: analyticsRepo,
+		cfg:           cfg,
+	}
+}
+
+// Run aggregates waiting events every interval until ctx is cancelled
+func (a *AnalyticsAggregator) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.Aggregate(ctx)
+		}
+	}
+}
+
+// Aggregate folds batches of events into the aggregates until none are left waiting
+func (a *AnalyticsAggregator) Aggregate(ctx context.Context) {
+	for ctx.Err() == nil {
+		aggregated, err := a.analyticsRepo.AggregateEvents(ctx, a.cfg.BatchSize)
+		if err != nil {
+			log.Printf("Failed to aggregate order events: %v", err)
+			return
+		}
+		if aggregated < a.cfg.BatchSize {
+			return
+		}
+	}
+}
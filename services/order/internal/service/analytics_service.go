@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/analytics"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Bounds of the day range an analytics query covers
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 366
+)
+
+// analyticsDayFormat is how days are given in analytics queries
+const analyticsDayFormat = "2006-01-02"
+
+// AnalyticsService answers queries over the daily order aggregates for the admin dashboard
+type AnalyticsService struct {
+	pb.UnimplementedAnalyticsServiceServer
+	repo *repository.AnalyticsRepository
+}
+
+// NewAnalyticsService creates a new analytics service
+func NewAnalyticsService(repo *repository.AnalyticsRepository) *AnalyticsService {
+	return &AnalyticsService{
+		repo: repo,
+	}
+}
+
+// GetDailyMetrics gets order counts, completion rate, pickup ETA error and revenue per
+// day, city and order type, or summed over all but one of them
+func (s *AnalyticsService) GetDailyMetrics(ctx context.Context, req *pb.GetDailyMetricsRequest) (*pb.GetDailyMetricsResponse, error) {
+	filter, err := parseAnalyticsFilter(req.From, req.To, req.City, req.OrderType)
+	if err != nil {
+		return nil, err
+	}
+
+	groupBy := model.AnalyticsGroupBy(req.GroupBy)
+	switch groupBy {
+	case model.AnalyticsGroupByNone, model.AnalyticsGroupByDay, model.AnalyticsGroupByCity, model.AnalyticsGroupByOrderType:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "group by must be day, city or order_type")
+	}
+
+	metrics, err := s.repo.GetDailyMetrics(ctx, filter, groupBy)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get daily metrics: %v", err)
+	}
+
+	resp := &pb.GetDailyMetricsResponse{
+		Metrics: make([]*pb.DailyMetrics, 0, len(metrics)),
+		From:    filter.From.Format(analyticsDayFormat),
+		To:      filter.To.Format(analyticsDayFormat),
+	}
+	for _, m := range metrics {
+		resp.Metrics = append(resp.Metrics, convertDailyMetricsToProto(m))
+	}
+
+	return resp, nil
+}
+
+// ListCancellationReasons gets the reasons orders were cancelled for, most given first
+func (s *AnalyticsService) ListCancellationReasons(ctx context.Context, req *pb.ListCancellationReasonsRequest) (*pb.ListCancellationReasonsResponse, error) {
+	filter, err := parseAnalyticsFilter(req.From, req.To, req.City, req.OrderType)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons, err := s.repo.ListCancellationReasons(ctx, filter, int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list cancellation reasons: %v", err)
+	}
+
+	resp := &pb.ListCancellationReasonsResponse{
+		Reasons: make([]*pb.CancellationReason, 0, len(reasons)),
+		From:    filter.From.Format(analyticsDayFormat),
+		To:      filter.To.Format(analyticsDayFormat),
+	}
+	for _, reason := range reasons {
+		resp.Reasons = append(resp.Reasons, &pb.CancellationReason{
+			Reason: reason.Reason,
+			Count:  reason.Count,
+		})
+	}
+
+	return resp, nil
+}
+
+// GetSLAAttainment gets how often each stage of an order finished within its SLA
+func (s *AnalyticsService) GetSLAAttainment(ctx context.Context, req *pb.GetSLAAttainmentRequest) (*pb.GetSLAAttainmentResponse, error) {
+	filter, err := parseAnalyticsFilter(req.From, req.To, req.City, req.OrderType)
+	if err != nil {
+		return nil, err
+	}
+
+	attainments, err := s.repo.GetSLAAttainment(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get SLA attainment: %v", err)
+	}
+
+	resp := &pb.GetSLAAttainmentResponse{
+		Stages: make([]*pb.SLAAttainment, 0, len(attainments)),
+		From:   filter.From.Format(analyticsDayFormat),
+		To:     filter.To.Format(analyticsDayFormat),
+	}
+	for _, attainment := range attainments {
+		resp.Stages = append(resp.Stages, &pb.SLAAttainment{
+			Stage:          string(attainment.Stage),
+			Met:            attainment.Met,
+			Breached:       attainment.Breached,
+			AttainmentRate: attainment.Rate(),
+		})
+	}
+
+	return resp, nil
+}
+
+// parseAnalyticsFilter validates a query's days and filters. The range ends today and
+// starts defaultAnalyticsDays earlier unless given.
+func parseAnalyticsFilter(from, to, city, orderType string) (model.AnalyticsFilter, error) {
+	var filter model.AnalyticsFilter
+	var err error
+
+	filter.To = time.Now().UTC().Truncate(24 * time.Hour)
+	if to != "" {
+		if filter.To, err = time.Parse(analyticsDayFormat, to); err != nil {
+			return filter, status.Errorf(codes.InvalidArgument, "to must be a day in the form YYYY-MM-DD")
+		}
+	}
+	filter.From = filter.To.AddDate(0, 0, -(defaultAnalyticsDays - 1))
+	if from != "" {
+		if filter.From, err = time.Parse(analyticsDayFormat, from); err != nil {
+			return filter, status.Errorf(codes.InvalidArgument, "from must be a day in the form YYYY-MM-DD")
+		}
+	}
+
+	if filter.From.After(filter.To) {
+		return filter, status.Errorf(codes.InvalidArgument, "from must not be after to")
+	}
+	if filter.To.Sub(filter.From) >= maxAnalyticsDays*24*time.Hour {
+		return filter, status.Errorf(codes.InvalidArgument, "at most %d days can be queried at once", maxAnalyticsDays)
+	}
+
+	if filter.OrderType, err = parseFeeOrderType(orderType); err != nil {
+		return filter, err
+	}
+	filter.City = city
+
+	return filter, nil
+}
+
+// convertDailyMetricsToProto converts daily metrics to their protobuf representation
+func convertDailyMetricsToProto(m *model.DailyMetrics) *pb.DailyMetrics {
+	return &pb.DailyMetrics{
+		Day:                    m.Day,
+		City:                   m.City,
+		OrderType:              string(m.OrderType),
+		OrdersCreated:          m.OrdersCreated,
+		OrdersCompleted:        m.OrdersCompleted,
+		OrdersCancelled:        m.OrdersCancelled,
+		CompletionRate:         m.CompletionRate(),
+		Revenue:                m.Revenue,
+		PlatformRevenue:        m.PlatformRevenue,
+		AverageEtaErrorMinutes: m.AverageETAErrorMinutes(),
+		EtaSamples:             m.ETASamples,
+	}
+}
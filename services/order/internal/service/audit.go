@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+
+	denylistPb "github.com/order-api-microservices/proto/denylist"
+	pb "github.com/order-api-microservices/proto/order"
+	supportPb "github.com/order-api-microservices/proto/support"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// Resource types in the audit log
+const (
+	auditResourceOrder          = "order"
+	auditResourceDenylistEntry  = "denylist_entry"
+	auditResourceSupportSession = "support_session"
+)
+
+// OrderAuditSnapshotter records the state of the order a call changes in the audit log.
+// Addresses and notes are left out, so the log holds no personal data that erasure
+// could not reach. Calls about denylist entries record the entry instead, so every
+// request the gateway blocks shows up as a change to the entry that blocked it, and
+// support sessions record the session.
+type OrderAuditSnapshotter struct {
+	repo         *repository.OrderRepository
+	denylistRepo *repository.DenylistRepository
+	supportRepo  *repository.SupportSessionRepository
+}
+
+// NewOrderAuditSnapshotter creates a new order audit snapshotter
+func NewOrderAuditSnapshotter(repo *repository.OrderRepository, denylistRepo *repository.DenylistRepository, supportRepo *repository.SupportSessionRepository) *OrderAuditSnapshotter {
+	return &OrderAuditSnapshotter{
+		repo:         repo,
+		denylistRepo: denylistRepo,
+		supportRepo:  supportRepo,
+	}
+}
+
+// Resource names the order, denylist entry or support session a request or response
+// refers to
+func (s *OrderAuditSnapshotter) Resource(message interface{}) (string, string, bool) {
+	if m, ok := message.(interface{ GetOrderId() string }); ok && m.GetOrderId() != "" {
+		return auditResourceOrder, m.GetOrderId(), true
+	}
+	if m, ok := message.(interface{ GetOrder() *pb.Order }); ok && m.GetOrder().GetId() != "" {
+		return auditResourceOrder, m.GetOrder().GetId(), true
+	}
+	if m, ok := message.(interface{ GetDenylistEntryId() string }); ok && m.GetDenylistEntryId() != "" {
+		return auditResourceDenylistEntry, m.GetDenylistEntryId(), true
+	}
+	if m, ok := message.(interface {
+		GetEntry() *denylistPb.DenylistEntry
+	}); ok && m.GetEntry().GetId() != "" {
+		return auditResourceDenylistEntry, m.GetEntry().GetId(), true
+	}
+	if m, ok := message.(interface{ GetSupportSessionId() string }); ok && m.GetSupportSessionId() != "" {
+		return auditResourceSupportSession, m.GetSupportSessionId(), true
+	}
+	if m, ok := message.(interface {
+		GetSession() *supportPb.SupportSession
+	}); ok && m.GetSession().GetId() != "" {
+		return auditResourceSupportSession, m.GetSession().GetId(), true
+	}
+	return "", "", false
+}
+
+// Snapshot loads a denylist entry, a support session without its token hash, or an
+// order without its addresses, stop instructions and notes
+func (s *OrderAuditSnapshotter) Snapshot(ctx context.Context, resourceType, resourceID string) (interface{}, error) {
+	switch resourceType {
+	case auditResourceDenylistEntry:
+		entry, err := s.denylistRepo.GetEntry(ctx, resourceID)
+		if err != nil {
+			return nil, err
+		}
+		return entry, nil
+	case auditResourceSupportSession:
+		session, err := s.supportRepo.GetSession(ctx, resourceID)
+		if err != nil {
+			return nil, err
+		}
+		return session, nil
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	order.PickupLocation.Address = ""
+	order.DestinationLocation.Address = ""
+	order.PickupLocation.Instructions = nil
+	order.DestinationLocation.Instructions = nil
+	order.Notes = ""
+	return order, nil
+}
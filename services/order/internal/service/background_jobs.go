@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/order-api-microservices/pkg/jobs"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// Kinds of the order service's background jobs
+const (
+	JobRecordOrder      = "blockchain.record_order"
+	JobSendNotification = "notification.send"
+)
+
+// recordOrderJob is the payload of a JobRecordOrder job
+type recordOrderJob struct {
+	OrderID string `json:"order_id"`
+}
+
+// OrderRecorder records orders on the blockchain. It is implemented by BlockchainRecorder.
+type OrderRecorder interface {
+	// Record records an order in the background, without failing the caller
+	Record(ctx context.Context, orderID string)
+}
+
+// BlockchainRecorder records orders on the blockchain through background jobs, so a
+// record the blockchain service cannot take now is retried instead of lost
+type BlockchainRecorder struct {
+	queue  *jobs.Queue
+	repo   OrderRepository
+	client BlockchainClient
+}
+
+// NewBlockchainRecorder creates a blockchain recorder and registers its jobs with queue
+func NewBlockchainRecorder(queue *jobs.Queue, repo OrderRepository, client BlockchainClient, policy jobs.Policy) *BlockchainRecorder {
+	r := &BlockchainRecorder{
+		queue:  queue,
+		repo:   repo,
+		client: client,
+	}
+	queue.Register(JobRecordOrder, policy, r.record)
+	return r
+}
+
+// Record queues recording an order on the blockchain. The order is recorded as it is
+// when the job runs, so a retried record never overwrites a newer one with stale data.
+func (r *BlockchainRecorder) Record(ctx context.Context, orderID string) {
+	if err := r.queue.Enqueue(ctx, JobRecordOrder, recordOrderJob{OrderID: orderID}); err != nil {
+		log.Printf("Failed to queue blockchain record of order %s: %v", orderID, err)
+	}
+}
+
+// record records an order on the blockchain and stores the transaction hash
+func (r *BlockchainRecorder) record(ctx context.Context, payload json.RawMessage) error {
+	var job recordOrderJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	order, err := r.repo.GetOrderByID(ctx, job.OrderID)
+	if err != nil {
+		// An archived order was recorded before it finished
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+
+	txHash, err := r.client.RecordOrder(ctx, order.ID, order.UserID, order.ProviderID, order)
+	if err != nil {
+		return fmt.Errorf("failed to record order on blockchain: %w", err)
+	}
+
+	// Only the hash is written, so changes made to the order meanwhile are kept
+	if err := r.repo.SetBlockchainTxHash(ctx, order.ID, txHash); err != nil {
+		return fmt.Errorf("failed to update order with blockchain hash: %w", err)
+	}
+	return nil
+}
+
+// sendNotificationJob is the payload of a JobSendNotification job
+type sendNotificationJob struct {
+	RecipientID      string                 `json:"recipient_id"`
+	RecipientType    string                 `json:"recipient_type"`
+	NotificationType string                 `json:"notification_type"`
+	Title            string                 `json:"title"`
+	Message          string                 `json:"message"`
+	Payload          map[string]interface{} `json:"payload"`
+}
+
+// QueuedNotifications sends notifications through background jobs, so a notification
+// the notification service cannot take now is retried instead of lost. It is the
+// NotificationClient of the order service's services.
+type QueuedNotifications struct {
+	queue *jobs.Queue
+}
+
+// NewQueuedNotifications creates queued notifications sent with client and registers
+// their jobs with queue
+func NewQueuedNotifications(queue *jobs.Queue, client NotificationClient, policy jobs.Policy) *QueuedNotifications {
+	queue.Register(JobSendNotification, policy, func(ctx context.Context, payload json.RawMessage) error {
+		var job sendNotificationJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return client.SendNotification(ctx, job.RecipientID, job.RecipientType, job.NotificationType, job.Title, job.Message, job.Payload)
+	})
+
+	return &QueuedNotifications{
+		queue: queue,
+	}
+}
+
+// SendNotification queues a notification; it fails only when the notification cannot be queued
+func (n *QueuedNotifications) SendNotification(ctx context.Context, recipientID, recipientType, notificationType, title, message string, payload map[string]interface{}) error {
+	return n.queue.Enqueue(ctx, JobSendNotification, sendNotificationJob{
+		RecipientID:      recipientID,
+		RecipientType:    recipientType,
+		NotificationType: notificationType,
+		Title:            title,
+		Message:          message,
+		Payload:          payload,
+	})
+}
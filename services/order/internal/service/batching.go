@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// kmPerDegreeLatitude is the length of a degree of latitude
+const kmPerDegreeLatitude = earthRadiusKm * math.Pi / 180
+
+// batchAnchorStatuses are the statuses in which a provider has not yet picked up an order,
+// so another order can still be stacked onto their trip
+var batchAnchorStatuses = []model.OrderStatus{
+	model.StatusProviderAssigned,
+	model.StatusProviderAccepted,
+	model.StatusInProgress,
+}
+
+// BatchingPolicy controls when a delivery order is stacked onto a trip a provider is
+// already making. MaxOrders below 2 turns batching off.
+type BatchingPolicy struct {
+	PickupRadiusKm    float64 // Farthest the new pickup can be from the trip's pickup
+	MaxBearingDegrees float64 // Widest angle between the two orders' pickup-to-destination directions
+	MaxOrders         int     // Most unfinished orders on one trip
+}
+
+// batches reports whether orders of orderType can be stacked
+func (p BatchingPolicy) batches(orderType model.OrderType) bool {
+	if p.MaxOrders < 2 {
+		return false
+	}
+	return orderType == model.TypeFoodDelivery || orderType == model.TypeGroceryDelivery
+}
+
+// batchAnchor is a trip an order can be stacked onto
+type batchAnchor struct {
+	order    *model.Order
+	provider Provider
+	batch    *model.OrderBatch // The trip's existing batch, or nil
+	members  []*model.Order    // The trip's unfinished orders
+}
+
+// findBatchAnchor looks for an order whose provider can also take order on the same
+// trip: its pickup is nearby, it heads the same way and the provider has room for
+// another order. It returns nil when there is none.
+func (s *OrderService) findBatchAnchor(ctx context.Context, order *model.Order) *batchAnchor {
+	if !s.batchingPolicy.batches(order.OrderType) {
+		return nil
+	}
+
+	pickup := order.PickupLocation
+	latSpan := s.batchingPolicy.PickupRadiusKm / kmPerDegreeLatitude
+	lonSpan := latSpan / math.Max(math.Cos(toRadians(pickup.Latitude)), 0.01)
+	anchorIDs, err := s.repo.ListBatchAnchorIDs(ctx, order.OrderType, batchAnchorStatuses, order.ID,
+		pickup.Latitude-latSpan, pickup.Latitude+latSpan, pickup.Longitude-lonSpan, pickup.Longitude+lonSpan)
+	if err != nil {
+		fmt.Printf("Failed to list batch anchors for order %s: %v\n", order.ID, err)
+		return nil
+	}
+
+	heading := bearingDegrees(order.PickupLocation, order.DestinationLocation)
+	for _, anchorID := range anchorIDs {
+		anchorOrder, err := s.repo.GetOrderByID(ctx, anchorID)
+		if err != nil {
+			fmt.Printf("Failed to get batch anchor %s: %v\n", anchorID, err)
+			continue
+		}
+		if haversineKm(pickup.Latitude, pickup.Longitude, anchorOrder.PickupLocation.Latitude, anchorOrder.PickupLocation.Longitude) > s.batchingPolicy.PickupRadiusKm {
+			continue
+		}
+		if bearingDifference(heading, bearingDegrees(anchorOrder.PickupLocation, anchorOrder.DestinationLocation)) > s.batchingPolicy.MaxBearingDegrees {
+			continue
+		}
+
+		anchor, err := s.loadBatchAnchor(ctx, anchorOrder)
+		if err != nil {
+			fmt.Printf("Failed to load trip of order %s: %v\n", anchorID, err)
+			continue
+		}
+		if len(anchor.members)+1 > s.batchingPolicy.MaxOrders {
+			continue
+		}
+
+		blocked, err := s.providerMatcher.Blocked(ctx, order.UserID, anchorOrder.ProviderID)
+		if err != nil {
+			fmt.Printf("Failed to check whether provider %s is blocked: %v\n", anchorOrder.ProviderID, err)
+			continue
+		}
+		if blocked {
+			continue
+		}
+
+		provider, err := s.providerClient.GetProviderDetails(ctx, anchorOrder.ProviderID)
+		if err != nil {
+			fmt.Printf("Failed to get provider %s: %v\n", anchorOrder.ProviderID, err)
+			continue
+		}
+		ok, err := s.providerHasCapacity(ctx, order, *provider)
+		if err != nil {
+			fmt.Printf("Failed to count active orders of provider %s: %v\n", provider.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		anchor.provider = *provider
+		return anchor
+	}
+
+	return nil
+}
+
+// loadBatchAnchor loads the batch an anchor order already belongs to and its unfinished
+// orders. A batch left behind by a provider who has since lost the order is ignored.
+func (s *OrderService) loadBatchAnchor(ctx context.Context, anchorOrder *model.Order) (*batchAnchor, error) {
+	anchor := &batchAnchor{
+		order:   anchorOrder,
+		members: []*model.Order{anchorOrder},
+	}
+
+	batch, err := s.batchRepo.GetBatchByOrder(ctx, anchorOrder.ID)
+	if errors.Is(err, repository.ErrOrderBatchNotFound) {
+		return anchor, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if batch.ProviderID != anchorOrder.ProviderID {
+		return anchor, nil
+	}
+
+	anchor.batch = batch
+	anchor.members = nil
+	for _, orderID := range batch.OrderIDs {
+		member := anchorOrder
+		if orderID != anchorOrder.ID {
+			if member, err = s.repo.GetOrderByID(ctx, orderID); err != nil {
+				return nil, err
+			}
+		}
+		if member.ProviderID == batch.ProviderID && !member.Status.Finished() {
+			anchor.members = append(anchor.members, member)
+		}
+	}
+
+	return anchor, nil
+}
+
+// addToBatch stacks an order assigned to the anchor's provider onto their trip, replans
+// the stops and lets the provider know. Failures are logged; the assignment stands.
+func (s *OrderService) addToBatch(ctx context.Context, anchor *batchAnchor, order *model.Order) {
+	now := time.Now()
+	batch := anchor.batch
+	if batch == nil {
+		batch = &model.OrderBatch{
+			ID:         uuid.New().String(),
+			ProviderID: anchor.provider.ID,
+			CreatedAt:  now,
+		}
+	}
+
+	members := append(anchor.members, order)
+	batch.OrderIDs = make([]string, len(members))
+	for i, member := range members {
+		batch.OrderIDs[i] = member.ID
+	}
+	start := anchor.provider.Location
+	if location, err := s.locationRepo.GetLatestOrderLocation(ctx, anchor.order.ID); err == nil {
+		start = model.Location{Latitude: location.Latitude, Longitude: location.Longitude}
+	}
+	batch.Stops = planBatchStops(start, members)
+	batch.UpdatedAt = now
+
+	if err := s.batchRepo.SaveBatch(ctx, batch); err != nil {
+		fmt.Printf("Failed to save batch for order %s: %v\n", order.ID, err)
+		return
+	}
+
+	go func() {
+		err := s.notificationClient.SendNotification(context.Background(), batch.ProviderID, "PROVIDER", "ORDER_BATCHED",
+			"Another order on your trip", fmt.Sprintf("Order %s was added to your trip; check the updated stops", order.ID),
+			map[string]interface{}{
+				"order_id": order.ID,
+				"batch_id": batch.ID,
+			})
+		if err != nil {
+			fmt.Printf("Failed to notify provider of batched order: %v\n", err)
+		}
+	}()
+}
+
+// planBatchStops orders the stops of a batch's unfinished orders. Pickups already made
+// stay at the front; the rest are visited nearest first from start, never dropping an
+// order off before picking it up.
+func planBatchStops(start model.Location, orders []*model.Order) model.BatchStops {
+	stops := model.BatchStops{}
+	pending := []model.BatchStop{}
+	pickedUp := make(map[string]bool)
+	for _, order := range orders {
+		pickup := model.BatchStop{OrderID: order.ID, Kind: model.StopPickup, Location: order.PickupLocation}
+		if model.StopPickup.DoneIn(order.Status) {
+			stops = append(stops, pickup)
+			pickedUp[order.ID] = true
+		} else {
+			pending = append(pending, pickup)
+		}
+		pending = append(pending, model.BatchStop{OrderID: order.ID, Kind: model.StopDropoff, Location: order.DestinationLocation})
+	}
+
+	current := start
+	for len(pending) > 0 {
+		next, nextKm := -1, 0.0
+		for i, stop := range pending {
+			if stop.Kind == model.StopDropoff && !pickedUp[stop.OrderID] {
+				continue
+			}
+			km := haversineKm(current.Latitude, current.Longitude, stop.Location.Latitude, stop.Location.Longitude)
+			if next < 0 || km < nextKm {
+				next, nextKm = i, km
+			}
+		}
+
+		stop := pending[next]
+		stops = append(stops, stop)
+		if stop.Kind == model.StopPickup {
+			pickedUp[stop.OrderID] = true
+		}
+		current = stop.Location
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+
+	return stops
+}
+
+// GetOrderBatch returns the trip an order is batched on: its stops in visiting order,
+// the status of each stop's order, and the predicted time from the provider's current
+// position to every stop still ahead
+func (s *OrderService) GetOrderBatch(ctx context.Context, req *pb.GetOrderBatchRequest) (*pb.OrderBatchResponse, error) {
+	batch, err := s.batchRepo.GetBatchByOrder(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderBatchNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order is not batched")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order batch: %v", err)
+	}
+
+	statuses := make(map[string]model.OrderStatus, len(batch.OrderIDs))
+	var position *model.OrderLocation
+	for _, orderID := range batch.OrderIDs {
+		order, err := s.repo.GetOrderByID(ctx, orderID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get batched order %s: %v", orderID, err)
+		}
+		statuses[orderID] = order.Status
+
+		location, err := s.locationRepo.GetLatestOrderLocation(ctx, orderID)
+		if err != nil && !errors.Is(err, repository.ErrOrderLocationNotFound) {
+			return nil, status.Errorf(codes.Internal, "failed to get latest location: %v", err)
+		}
+		if err == nil && location.ProviderID == batch.ProviderID && (position == nil || location.Timestamp.After(position.Timestamp)) {
+			position = location
+		}
+	}
+
+	// Predict from the provider's last report, or from the last stop behind them
+	var current *model.Location
+	if position != nil {
+		current = &model.Location{Latitude: position.Latitude, Longitude: position.Longitude}
+	}
+
+	resp := &pb.OrderBatchResponse{
+		BatchId:    batch.ID,
+		ProviderId: batch.ProviderID,
+		OrderIds:   batch.OrderIDs,
+		CreatedAt:  timestamppb.New(batch.CreatedAt),
+		UpdatedAt:  timestamppb.New(batch.UpdatedAt),
+	}
+
+	var elapsed time.Duration
+	for _, stop := range batch.Stops {
+		orderStatus := statuses[stop.OrderID]
+		protoStop := &pb.BatchStop{
+			OrderId:     stop.OrderID,
+			Kind:        string(stop.Kind),
+			Location:    convertLocationToProto(stop.Location),
+			OrderStatus: convertOrderStatusToProto(orderStatus),
+			Done:        stop.Kind.DoneIn(orderStatus),
+		}
+
+		if !protoStop.Done && current != nil {
+			leg, err := s.predictor.PredictETA(ctx, []model.Location{*current, stop.Location})
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to predict stop ETA: %v", err)
+			}
+			elapsed += leg
+			protoStop.EtaSeconds = int64(elapsed.Seconds())
+		}
+		if !protoStop.Done || position == nil {
+			location := stop.Location
+			current = &location
+		}
+
+		resp.Stops = append(resp.Stops, protoStop)
+	}
+	resp.TotalEtaSeconds = int64(elapsed.Seconds())
+
+	return resp, nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// BulkOrderConfig controls how bulk order jobs are imported
+type BulkOrderConfig struct {
+	Interval  time.Duration // How often waiting jobs are picked up
+	BatchSize int           // Rows processed between renewals of a job's lease
+	Lease     time.Duration // How long a job stays with an importer that stops renewing it
+}
+
+// BulkOrderImporter creates the orders of bulk order jobs in the background. Each row
+// goes through the same CreateOrder as a single order, so it is priced, checked against
+// the service areas and announced to webhooks in the same way.
+type BulkOrderImporter struct {
+	repo         *repository.BulkOrderRepository
+	orderService *OrderService
+	cfg          BulkOrderConfig
+}
+
+// NewBulkOrderImporter creates a new bulk order importer
+func NewBulkOrderImporter(repo *repository.BulkOrderRepository, orderService *OrderService, cfg BulkOrderConfig) *BulkOrderImporter {
+	return &BulkOrderImporter{
+		repo:         repo,
+		orderService: orderService,
+		cfg:          cfg,
+	}
+}
+
+// Run imports waiting jobs every interval until ctx is cancelled
+func (i *BulkOrderImporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(i.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				now := time.Now()
+				job, err := i.repo.ClaimJob(ctx, now, now.Add(i.cfg.Lease))
+				if err != nil {
+					log.Printf("Failed to claim bulk order job: %v", err)
+					break
+				}
+				if job == nil {
+					break
+				}
+				if err := i.Import(ctx, job); err != nil {
+					log.Printf("Failed to import bulk order job %s: %v", job.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// Import creates the orders of a claimed job's unprocessed rows and completes the job.
+// A row an earlier importer was interrupted on is failed rather than retried, since its
+// order may already exist.
+func (i *BulkOrderImporter) Import(ctx context.Context, job *model.BulkOrderJob) error {
+	interrupted, err := i.repo.FailInterruptedRows(ctx, job.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if interrupted > 0 {
+		log.Printf("Bulk order job %s: failed %d rows interrupted by an earlier importer", job.ID, interrupted)
+	}
+
+	for {
+		rows, err := i.repo.NextPendingRows(ctx, job.ID, i.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		for _, row := range rows {
+			if err := i.importRow(ctx, row); err != nil {
+				return err
+			}
+		}
+
+		if err := i.repo.ExtendLease(ctx, job.ID, time.Now().Add(i.cfg.Lease)); err != nil {
+			return err
+		}
+	}
+
+	if err := i.repo.CompleteJob(ctx, job.ID, time.Now()); err != nil {
+		return err
+	}
+
+	log.Printf("Imported bulk order job %s of customer %s", job.ID, job.CustomerID)
+	return nil
+}
+
+// importRow creates one row's order and records the result. Only failing to record
+// the result is an error; a refused order is the row's result.
+func (i *BulkOrderImporter) importRow(ctx context.Context, row *model.BulkOrderRow) error {
+	if err := i.repo.MarkRowProcessing(ctx, row.JobID, row.RowNumber); err != nil {
+		return err
+	}
+
+	req := &pb.CreateOrderRequest{}
+	if err := protojson.Unmarshal([]byte(row.Request), req); err != nil {
+		row.Status = model.BulkRowFailed
+		row.Errors = []string{"stored order request is unreadable"}
+	} else if resp, err := i.orderService.CreateOrder(ctx, req); err != nil {
+		row.Status = model.BulkRowFailed
+		row.Errors = []string{status.Convert(err).Message()}
+	} else {
+		row.Status = model.BulkRowCreated
+		row.OrderID = resp.Order.Id
+	}
+
+	now := time.Now()
+	row.ProcessedAt = &now
+	return i.repo.CompleteRow(ctx, row)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/grpcserver"
+	pb "github.com/order-api-microservices/proto/bulkorder"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// BulkOrderService accepts batches of orders from B2B customers and reports how the
+// import of each batch is going. The orders are created by a BulkOrderImporter.
+type BulkOrderService struct {
+	pb.UnimplementedBulkOrderServiceServer
+	repo    *repository.BulkOrderRepository
+	maxRows int
+}
+
+// NewBulkOrderService creates a new bulk order service that accepts up to maxRows orders per job
+func NewBulkOrderService(repo *repository.BulkOrderRepository, maxRows int) *BulkOrderService {
+	return &BulkOrderService{
+		repo:    repo,
+		maxRows: maxRows,
+	}
+}
+
+// SubmitBulkOrders stores a batch of orders as a job and returns it at once. Rows that
+// arrive with validation errors are recorded as INVALID and never submitted.
+func (s *BulkOrderService) SubmitBulkOrders(ctx context.Context, req *pb.SubmitBulkOrdersRequest) (*pb.BulkOrderJobResponse, error) {
+	if req.CustomerId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "customer ID is required")
+	}
+	if len(req.Rows) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "at least one order is required")
+	}
+	if len(req.Rows) > s.maxRows {
+		return nil, status.Errorf(codes.InvalidArgument, "a bulk import takes at most %d orders, got %d", s.maxRows, len(req.Rows))
+	}
+	if req.Source != "JSON" && req.Source != "CSV" {
+		return nil, status.Errorf(codes.InvalidArgument, "source must be JSON or CSV")
+	}
+
+	now := time.Now()
+	job := &model.BulkOrderJob{
+		ID:         uuid.New().String(),
+		CustomerID: req.CustomerId,
+		Source:     req.Source,
+		Status:     model.BulkJobPending,
+		TotalRows:  len(req.Rows),
+		CreatedAt:  now,
+	}
+
+	rows := make([]*model.BulkOrderRow, 0, len(req.Rows))
+	seen := make(map[int32]bool, len(req.Rows))
+	for _, submitted := range req.Rows {
+		if seen[submitted.RowNumber] {
+			return nil, status.Errorf(codes.InvalidArgument, "row %d appears more than once", submitted.RowNumber)
+		}
+		seen[submitted.RowNumber] = true
+
+		row := &model.BulkOrderRow{
+			RowNumber: int(submitted.RowNumber),
+			Status:    model.BulkRowPending,
+		}
+		switch {
+		case len(submitted.Errors) > 0:
+			row.Status = model.BulkRowInvalid
+			row.Errors = submitted.Errors
+		case submitted.Order == nil:
+			row.Status = model.BulkRowInvalid
+			row.Errors = []string{"order is required"}
+		default:
+			if err := grpcserver.Validate(submitted.Order); err != nil {
+				row.Status = model.BulkRowInvalid
+				row.Errors = []string{status.Convert(err).Message()}
+				break
+			}
+			request, err := protojson.Marshal(submitted.Order)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "row %d cannot be encoded: %v", submitted.RowNumber, err)
+			}
+			row.Request = string(request)
+		}
+		if row.Status == model.BulkRowInvalid {
+			row.ProcessedAt = &now
+		}
+		rows = append(rows, row)
+	}
+
+	if err := s.repo.CreateJob(ctx, job, rows); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create bulk order job: %v", err)
+	}
+
+	stored, err := s.repo.GetJob(ctx, job.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get bulk order job: %v", err)
+	}
+
+	return &pb.BulkOrderJobResponse{
+		Job:     convertBulkOrderJobToProto(stored),
+		Success: true,
+		Message: fmt.Sprintf("Accepted %d orders for import", len(rows)),
+	}, nil
+}
+
+// GetBulkOrderJob gets a job and how many of its rows have each result
+func (s *BulkOrderService) GetBulkOrderJob(ctx context.Context, req *pb.GetBulkOrderJobRequest) (*pb.BulkOrderJobResponse, error) {
+	job, err := s.getJob(ctx, req.JobId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.BulkOrderJobResponse{
+		Job:     convertBulkOrderJobToProto(job),
+		Success: true,
+		Message: "Bulk order job retrieved successfully",
+	}, nil
+}
+
+// ListBulkOrderRows lists a job's rows with the order created for each, or why none was
+func (s *BulkOrderService) ListBulkOrderRows(ctx context.Context, req *pb.ListBulkOrderRowsRequest) (*pb.ListBulkOrderRowsResponse, error) {
+	if _, err := s.getJob(ctx, req.JobId); err != nil {
+		return nil, err
+	}
+
+	rowStatus := model.BulkOrderRowStatus(req.Status)
+	switch rowStatus {
+	case "", model.BulkRowPending, model.BulkRowProcessing, model.BulkRowCreated, model.BulkRowFailed, model.BulkRowInvalid:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "status must be PENDING, PROCESSING, CREATED, FAILED or INVALID")
+	}
+
+	rows, total, err := s.repo.ListRows(ctx, req.JobId, rowStatus, int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list bulk order rows: %v", err)
+	}
+
+	protoRows := make([]*pb.BulkOrderRow, 0, len(rows))
+	for _, row := range rows {
+		protoRow := &pb.BulkOrderRow{
+			RowNumber: int32(row.RowNumber),
+			Status:    string(row.Status),
+			OrderId:   row.OrderID,
+			Errors:    row.Errors,
+		}
+		if row.ProcessedAt != nil {
+			protoRow.ProcessedAt = timestamppb.New(*row.ProcessedAt)
+		}
+		protoRows = append(protoRows, protoRow)
+	}
+
+	return &pb.ListBulkOrderRowsResponse{
+		Rows:  protoRows,
+		Total: int32(total),
+		Page:  req.Page,
+		Limit: req.Limit,
+	}, nil
+}
+
+func (s *BulkOrderService) getJob(ctx context.Context, jobID string) (*model.BulkOrderJob, error) {
+	if jobID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "job ID is required")
+	}
+
+	job, err := s.repo.GetJob(ctx, jobID)
+	if err != nil {
+		if errors.Is(err, repository.ErrBulkOrderJobNotFound) {
+			return nil, status.Errorf(codes.NotFound, "bulk order job not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get bulk order job: %v", err)
+	}
+
+	return job, nil
+}
+
+func convertBulkOrderJobToProto(job *model.BulkOrderJob) *pb.BulkOrderJob {
+	protoJob := &pb.BulkOrderJob{
+		Id:          job.ID,
+		CustomerId:  job.CustomerID,
+		Source:      job.Source,
+		Status:      string(job.Status),
+		TotalRows:   int32(job.TotalRows),
+		PendingRows: int32(job.PendingRows),
+		CreatedRows: int32(job.CreatedRows),
+		FailedRows:  int32(job.FailedRows),
+		InvalidRows: int32(job.InvalidRows),
+		CreatedAt:   timestamppb.New(job.CreatedAt),
+	}
+	if job.StartedAt != nil {
+		protoJob.StartedAt = timestamppb.New(*job.StartedAt)
+	}
+	if job.CompletedAt != nil {
+		protoJob.CompletedAt = timestamppb.New(*job.CompletedAt)
+	}
+	return protoJob
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"time"
+
+	"github.com/order-api-microservices/pkg/money"
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// CancellationPolicy decides what a user pays to cancel an order
+type CancellationPolicy struct {
+	FreeWindow         time.Duration // Cancelling this soon after ordering is free until pickup
+	AcceptedFeePercent float64       // Share of the total charged once a provider has accepted
+}
+
+// Fee returns the fee for cancelling an order at the given time, with the reason it applies.
+// Once the order is picked up the full total is due; before that, cancelling within the free
+// window or before a provider accepts is free.
+func (p CancellationPolicy) Fee(order *model.Order, at time.Time) (int64, string) {
+	switch order.Status {
+	case model.StatusPickedUp, model.StatusInTransit, model.StatusArrived, model.StatusDelivered:
+		return order.TotalPrice, "order was already picked up"
+	case model.StatusProviderAccepted, model.StatusInProgress:
+		if at.Sub(order.CreatedAt) < p.FreeWindow {
+			return 0, "cancelled within the free cancellation window"
+		}
+		return money.Percent(order.TotalPrice, p.AcceptedFeePercent), "a provider had already accepted the order"
+	default:
+		return 0, "no provider had accepted the order yet"
+	}
+}
+
+// FreeUntil returns when cancelling an order stops being free, or false if it is not
+// free at the given time or stays free until a provider accepts it
+func (p CancellationPolicy) FreeUntil(order *model.Order, at time.Time) (time.Time, bool) {
+	switch order.Status {
+	case model.StatusProviderAccepted, model.StatusInProgress:
+		until := order.CreatedAt.Add(p.FreeWindow)
+		return until, at.Before(until)
+	default:
+		return time.Time{}, false
+	}
+}
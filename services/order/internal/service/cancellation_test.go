@@ -0,0 +1,85 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+func TestCancellationPolicyFee(t *testing.T) {
+	policy := CancellationPolicy{FreeWindow: 5 * time.Minute, AcceptedFeePercent: 20}
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		status model.OrderStatus
+		after  time.Duration
+		want   int64
+	}{
+		{status: model.StatusCreated, after: time.Hour, want: 0},
+		{status: model.StatusPaymentComplete, after: time.Hour, want: 0},
+		{status: model.StatusProviderAssigned, after: time.Hour, want: 0},
+		{status: model.StatusProviderAccepted, after: 4 * time.Minute, want: 0},
+		{status: model.StatusProviderAccepted, after: 5 * time.Minute, want: 2000},
+		{status: model.StatusInProgress, after: time.Hour, want: 2000},
+		{status: model.StatusPickedUp, after: time.Minute, want: 10000},
+		{status: model.StatusInTransit, after: time.Minute, want: 10000},
+		{status: model.StatusArrived, after: time.Minute, want: 10000},
+	}
+
+	for _, tt := range tests {
+		order := &model.Order{Status: tt.status, TotalPrice: 10000, CreatedAt: created}
+		fee, reason := policy.Fee(order, created.Add(tt.after))
+		if fee != tt.want {
+			t.Errorf("%s after %v: fee = %d, want %d", tt.status, tt.after, fee, tt.want)
+		}
+		if reason == "" {
+			t.Errorf("%s after %v: no reason given", tt.status, tt.after)
+		}
+	}
+}
+
+func TestCancellationPolicyFreeUntil(t *testing.T) {
+	policy := CancellationPolicy{FreeWindow: 5 * time.Minute, AcceptedFeePercent: 20}
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	accepted := &model.Order{Status: model.StatusProviderAccepted, CreatedAt: created}
+
+	until, ok := policy.FreeUntil(accepted, created.Add(time.Minute))
+	if !ok || !until.Equal(created.Add(5*time.Minute)) {
+		t.Errorf("FreeUntil within the window = %v, %v; want %v, true", until, ok, created.Add(5*time.Minute))
+	}
+	if _, ok := policy.FreeUntil(accepted, created.Add(5*time.Minute)); ok {
+		t.Errorf("FreeUntil once the window closed reported the order as free")
+	}
+
+	// Before a provider accepts, cancelling stays free with no deadline
+	pending := &model.Order{Status: model.StatusCreated, CreatedAt: created}
+	if _, ok := policy.FreeUntil(pending, created.Add(time.Minute)); ok {
+		t.Errorf("FreeUntil gave a deadline to an order no provider accepted")
+	}
+}
+
+func TestCancellationFeeOnlyChargesUsersPayingByCard(t *testing.T) {
+	s := &OrderService{cancellationPolicy: CancellationPolicy{AcceptedFeePercent: 20}}
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	order := &model.Order{
+		UserID:        "user-1",
+		ProviderID:    "provider-1",
+		Status:        model.StatusProviderAccepted,
+		PaymentMethod: model.PaymentCreditCard,
+		TotalPrice:    10000,
+		CreatedAt:     created,
+	}
+
+	if fee, _ := s.cancellationFee(order, "user-1", created.Add(time.Hour)); fee != 2000 {
+		t.Errorf("user cancelling: fee = %d, want 2000", fee)
+	}
+	if fee, _ := s.cancellationFee(order, "provider-1", created.Add(time.Hour)); fee != 0 {
+		t.Errorf("provider cancelling: fee = %d, want 0", fee)
+	}
+
+	order.PaymentMethod = model.PaymentCash
+	if fee, _ := s.cancellationFee(order, "user-1", created.Add(time.Hour)); fee != 0 {
+		t.Errorf("cash order: fee = %d, want 0", fee)
+	}
+}
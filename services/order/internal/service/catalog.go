@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// priceFromCatalog checks every item of an order placed with a merchant is an available
+// item of its catalog, and names and prices the items from the catalog. A client may
+// send the price it showed the user, and the order is refused if that is not the
+// catalog's price, since the menu has changed since the user saw it.
+func (s *OrderService) priceFromCatalog(ctx context.Context, orderType model.OrderType, merchantID string, items model.OrderItems) error {
+	if orderType != model.TypeFoodDelivery && orderType != model.TypeGroceryDelivery {
+		return status.Errorf(codes.InvalidArgument, "only food and grocery deliveries can be ordered from a merchant")
+	}
+	if len(items) == 0 {
+		return status.Errorf(codes.InvalidArgument, "orders from a merchant need at least one item")
+	}
+
+	merchant, err := s.merchantRepo.GetMerchant(ctx, merchantID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMerchantNotFound) {
+			return status.Errorf(codes.InvalidArgument, "merchant %q not found", merchantID)
+		}
+		return status.Errorf(codes.Internal, "failed to get merchant: %v", err)
+	}
+	if !merchant.Active {
+		return status.Errorf(codes.FailedPrecondition, "merchant is not taking orders")
+	}
+
+	itemIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ItemID)
+	}
+	catalog, err := s.merchantRepo.GetCatalogItems(ctx, merchantID, itemIDs)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to get catalog items: %v", err)
+	}
+
+	for i := range items {
+		entry, ok := catalog[items[i].ItemID]
+		if !ok {
+			return status.Errorf(codes.InvalidArgument, "item %q is not on the merchant's menu", items[i].ItemID)
+		}
+		if !entry.Available {
+			return status.Errorf(codes.FailedPrecondition, "%s is not available", entry.Name)
+		}
+		if items[i].Price != 0 && items[i].Price != entry.Price {
+			return status.Errorf(codes.InvalidArgument, "price of %s is %d, not %d", entry.Name, entry.Price, items[i].Price)
+		}
+		items[i].Name = entry.Name
+		items[i].Price = entry.Price
+	}
+
+	return nil
+}
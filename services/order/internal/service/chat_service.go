@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/chat"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// maxChatMessageLength bounds the body of a chat message
+	maxChatMessageLength = 2000
+	// chatPollInterval is how often a message stream checks for new messages and receipts
+	chatPollInterval = time.Second
+)
+
+// ChatService lets an order's user and provider message each other while the order is active
+type ChatService struct {
+	pb.UnimplementedChatServiceServer
+	repo               *repository.ChatRepository
+	orderRepo          *repository.OrderRepository
+	notificationClient NotificationClient
+}
+
+// NewChatService creates a new chat service
+func NewChatService(repo *repository.ChatRepository, orderRepo *repository.OrderRepository, notificationClient NotificationClient) *ChatService {
+	return &ChatService{
+		repo:               repo,
+		orderRepo:          orderRepo,
+		notificationClient: notificationClient,
+	}
+}
+
+// SendMessage sends a message to the other party of an active order
+func (s *ChatService) SendMessage(ctx context.Context, req *pb.SendMessageRequest) (*pb.ChatMessageResponse, error) {
+	if req.OrderId == "" || req.SenderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order ID and sender ID are required")
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "message body is required")
+	}
+	if len(body) > maxChatMessageLength {
+		return nil, status.Errorf(codes.InvalidArgument, "message body must be at most %d characters", maxChatMessageLength)
+	}
+
+	order, role, err := s.getParticipantOrder(ctx, req.OrderId, req.SenderId)
+	if err != nil {
+		return nil, err
+	}
+	if !order.ChatOpen() {
+		return nil, status.Errorf(codes.FailedPrecondition, "chat is not open for order with status %s", order.Status)
+	}
+
+	now := time.Now()
+	message := &model.ChatMessage{
+		ID:         uuid.New().String(),
+		OrderID:    order.ID,
+		SenderID:   req.SenderId,
+		SenderRole: role,
+		Body:       body,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := s.repo.CreateMessage(ctx, message); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to send message: %v", err)
+	}
+
+	// Let the other party know in case they are not watching the chat
+	go func() {
+		recipientID, recipientType := order.ProviderID, "PROVIDER"
+		if role == model.PartyProvider {
+			recipientID, recipientType = order.UserID, "USER"
+		}
+		err := s.notificationClient.SendNotification(context.Background(), recipientID, recipientType, "CHAT_MESSAGE", "New message",
+			message.Body,
+			map[string]interface{}{
+				"order_id":   order.ID,
+				"message_id": message.ID,
+			})
+		if err != nil {
+			fmt.Printf("Failed to notify chat recipient: %v\n", err)
+		}
+	}()
+
+	return &pb.ChatMessageResponse{
+		Message: convertChatMessageToProto(message),
+		Success: true,
+	}, nil
+}
+
+// ListMessages lists an order's chat history, newest first; history stays readable after the chat closes
+func (s *ChatService) ListMessages(ctx context.Context, req *pb.ListMessagesRequest) (*pb.ListMessagesResponse, error) {
+	if req.OrderId == "" || req.ParticipantId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order ID and participant ID are required")
+	}
+
+	order, _, err := s.getParticipantOrder(ctx, req.OrderId, req.ParticipantId)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, total, err := s.repo.ListMessages(ctx, order.ID, int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list messages: %v", err)
+	}
+
+	protoMessages := []*pb.ChatMessage{}
+	for _, message := range messages {
+		protoMessages = append(protoMessages, convertChatMessageToProto(message))
+	}
+
+	return &pb.ListMessagesResponse{
+		Messages: protoMessages,
+		Total:    int32(total),
+		Page:     req.Page,
+		Limit:    req.Limit,
+		Open:     order.ChatOpen(),
+	}, nil
+}
+
+// StreamMessages streams new messages and read receipts on an order's chat. A message is
+// sent again when it is read. The stream ends once the chat closes.
+func (s *ChatService) StreamMessages(req *pb.StreamMessagesRequest, stream pb.ChatService_StreamMessagesServer) error {
+	if req.OrderId == "" || req.ParticipantId == "" {
+		return status.Errorf(codes.InvalidArgument, "order ID and participant ID are required")
+	}
+
+	ctx := stream.Context()
+	if _, _, err := s.getParticipantOrder(ctx, req.OrderId, req.ParticipantId); err != nil {
+		return err
+	}
+
+	since := time.Now()
+	if req.Since != nil {
+		since = req.Since.AsTime()
+	}
+
+	ticker := time.NewTicker(chatPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			messages, err := s.repo.ListMessagesSince(ctx, req.OrderId, since)
+			if err != nil {
+				fmt.Printf("Error getting chat messages: %v\n", err)
+				continue
+			}
+
+			for _, message := range messages {
+				if err := stream.Send(convertChatMessageToProto(message)); err != nil {
+					return status.Errorf(codes.Internal, "failed to send message: %v", err)
+				}
+				since = message.UpdatedAt
+			}
+
+			// Messages sent before the chat closed have been delivered; end the stream
+			order, err := s.orderRepo.GetOrderByID(ctx, req.OrderId)
+			if err != nil {
+				fmt.Printf("Error getting current order: %v\n", err)
+				continue
+			}
+			if order.Status.Finished() {
+				return nil
+			}
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// MarkRead marks the messages the other party sent so far as read
+func (s *ChatService) MarkRead(ctx context.Context, req *pb.MarkReadRequest) (*pb.MarkReadResponse, error) {
+	if req.OrderId == "" || req.ParticipantId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order ID and participant ID are required")
+	}
+
+	order, _, err := s.getParticipantOrder(ctx, req.OrderId, req.ParticipantId)
+	if err != nil {
+		return nil, err
+	}
+
+	marked, err := s.repo.MarkRead(ctx, order.ID, req.ParticipantId, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to mark messages read: %v", err)
+	}
+
+	return &pb.MarkReadResponse{
+		Marked:  int32(marked),
+		Success: true,
+	}, nil
+}
+
+// getParticipantOrder gets an order and the chat role of one of its parties
+func (s *ChatService) getParticipantOrder(ctx context.Context, orderID, participantID string) (*model.Order, model.PartyRole, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, "", status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, "", status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	role, ok := order.RoleOf(participantID)
+	if !ok {
+		return nil, "", status.Errorf(codes.PermissionDenied, "only the order's user and provider can use its chat")
+	}
+	return order, role, nil
+}
+
+func convertChatMessageToProto(message *model.ChatMessage) *pb.ChatMessage {
+	protoMessage := &pb.ChatMessage{
+		Id:         message.ID,
+		OrderId:    message.OrderID,
+		SenderId:   message.SenderID,
+		SenderRole: string(message.SenderRole),
+		Body:       message.Body,
+		CreatedAt:  timestamppb.New(message.CreatedAt),
+	}
+	if message.ReadAt != nil {
+		protoMessage.ReadAt = timestamppb.New(*message.ReadAt)
+	}
+	return protoMessage
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// activeStatuses are the statuses in which an order takes up one of its provider's slots
+var activeStatuses = []model.OrderStatus{
+	model.StatusProviderAssigned,
+	model.StatusProviderAccepted,
+	model.StatusInProgress,
+	model.StatusPickedUp,
+	model.StatusInTransit,
+	model.StatusArrived,
+}
+
+// ConcurrencyPolicy limits how many orders a provider works at once
+type ConcurrencyPolicy struct {
+	Limits map[model.OrderType]int // Active orders of a type a provider can hold; types without a limit are not capped
+}
+
+// ParseConcurrencyLimits parses per-type limits written as TYPE=N pairs separated by commas,
+// e.g. "RIDE=1,FOOD_DELIVERY=3"
+func ParseConcurrencyLimits(s string) (map[model.OrderType]int, error) {
+	limits := make(map[model.OrderType]int)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		orderType, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid concurrency limit %q: want TYPE=N", pair)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("invalid concurrency limit %q: N must be a positive integer", pair)
+		}
+		limits[model.OrderType(strings.ToUpper(strings.TrimSpace(orderType)))] = limit
+	}
+	return limits, nil
+}
+
+// allows reports whether a provider holding active orders, counted by type, can take one
+// more order of orderType. maxConcurrent caps the provider's active orders of any type.
+func (p ConcurrencyPolicy) allows(orderType model.OrderType, active map[model.OrderType]int, maxConcurrent int) bool {
+	if limit, ok := p.Limits[orderType]; ok && active[orderType] >= limit {
+		return false
+	}
+	if maxConcurrent > 0 {
+		total := 0
+		for _, count := range active {
+			total += count
+		}
+		if total >= maxConcurrent {
+			return false
+		}
+	}
+	return true
+}
+
+// providerHasCapacity reports whether a provider can take the order on top of the orders
+// they already hold
+func (s *OrderService) providerHasCapacity(ctx context.Context, order *model.Order, provider Provider) (bool, error) {
+	active, err := s.repo.CountActiveProviderOrders(ctx, provider.ID, order.ID, activeStatuses)
+	if err != nil {
+		return false, err
+	}
+	return s.concurrencyPolicy.allows(order.OrderType, active, provider.MaxConcurrentOrders), nil
+}
+
+// filterProvidersWithCapacity keeps the matched providers who can take the order. Providers
+// whose active orders cannot be counted are dropped.
+func (s *OrderService) filterProvidersWithCapacity(ctx context.Context, order *model.Order, providers []Provider) []Provider {
+	available := providers[:0]
+	for _, provider := range providers {
+		ok, err := s.providerHasCapacity(ctx, order, provider)
+		if err != nil {
+			log.Printf("Failed to count active orders of provider %s: %v", provider.ID, err)
+			continue
+		}
+		if ok {
+			available = append(available, provider)
+		}
+	}
+	return available
+}
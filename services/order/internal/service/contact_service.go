@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/contact"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ContactPolicy controls the masked contact tokens issued for orders
+type ContactPolicy struct {
+	TokenTTL     time.Duration // How long a token works unless its order finishes first
+	BridgeNumber string        // Number the parties dial to reach the call bridge
+}
+
+// ContactService issues masked contact tokens so an order's user and provider can call
+// each other without seeing each other's phone numbers
+type ContactService struct {
+	pb.UnimplementedContactServiceServer
+	repo           *repository.ContactTokenRepository
+	orderRepo      *repository.OrderRepository
+	providerClient ProviderClient
+	policy         ContactPolicy
+}
+
+// NewContactService creates a new contact service
+func NewContactService(repo *repository.ContactTokenRepository, orderRepo *repository.OrderRepository, providerClient ProviderClient, policy ContactPolicy) *ContactService {
+	return &ContactService{
+		repo:           repo,
+		orderRepo:      orderRepo,
+		providerClient: providerClient,
+		policy:         policy,
+	}
+}
+
+// IssueContactToken issues a party to an active order a token for calling the other party.
+// Tokens stop working when they expire or when the order finishes.
+func (s *ContactService) IssueContactToken(ctx context.Context, req *pb.IssueContactTokenRequest) (*pb.ContactTokenResponse, error) {
+	if req.OrderId == "" || req.ParticipantId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order ID and participant ID are required")
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if _, ok := order.RoleOf(req.ParticipantId); !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "only the order's user and provider can contact each other")
+	}
+	if order.ProviderID == "" || order.Status.Finished() {
+		return nil, status.Errorf(codes.FailedPrecondition, "contact is not available for order with status %s", order.Status)
+	}
+
+	value, err := generateContactToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate contact token: %v", err)
+	}
+
+	now := time.Now()
+	token := &model.ContactToken{
+		ID:            uuid.New().String(),
+		OrderID:       order.ID,
+		ParticipantID: req.ParticipantId,
+		TokenHash:     model.HashContactToken(value),
+		ExpiresAt:     now.Add(s.policy.TokenTTL),
+		CreatedAt:     now,
+	}
+
+	if err := s.repo.CreateToken(ctx, token); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create contact token: %v", err)
+	}
+
+	return &pb.ContactTokenResponse{
+		Contact: &pb.ContactToken{
+			Token:        value,
+			OrderId:      order.ID,
+			BridgeNumber: s.policy.BridgeNumber,
+			ExpiresAt:    timestamppb.New(token.ExpiresAt),
+		},
+		Message: "Contact token issued",
+		Success: true,
+	}, nil
+}
+
+// ResolveContactToken tells the call bridge who a token's holder is calling
+func (s *ContactService) ResolveContactToken(ctx context.Context, req *pb.ResolveContactTokenRequest) (*pb.ResolveContactTokenResponse, error) {
+	if req.Token == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "token is required")
+	}
+
+	token, err := s.repo.GetTokenByHash(ctx, model.HashContactToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrContactTokenNotFound) {
+			return nil, status.Errorf(codes.NotFound, "contact token not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get contact token: %v", err)
+	}
+	if !token.Valid(time.Now()) {
+		return nil, status.Errorf(codes.FailedPrecondition, "contact token has expired")
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, token.OrderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+	if order.Status.Finished() {
+		return nil, status.Errorf(codes.FailedPrecondition, "contact token has expired")
+	}
+
+	// The caller must still be a party; a rejected provider is no longer one
+	callerRole, ok := order.RoleOf(token.ParticipantID)
+	if !ok || order.ProviderID == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "contact token has expired")
+	}
+
+	resp := &pb.ResolveContactTokenResponse{
+		OrderId:    order.ID,
+		CallerId:   token.ParticipantID,
+		CallerRole: string(callerRole),
+	}
+	if callerRole == model.PartyUser {
+		resp.CalleeId = order.ProviderID
+		resp.CalleeRole = string(model.PartyProvider)
+
+		provider, err := s.providerClient.GetProviderDetails(ctx, order.ProviderID)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to get provider: %v", err)
+		}
+		resp.CalleePhone = provider.Phone
+	} else {
+		resp.CalleeId = order.UserID
+		resp.CalleeRole = string(model.PartyUser)
+	}
+
+	return resp, nil
+}
+
+// generateContactToken returns a random URL-safe token
+func generateContactToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// Crypto payment statuses reported by the blockchain service
+const (
+	cryptoPaymentConfirmed = "CONFIRMED"
+	cryptoPaymentExpired   = "EXPIRED"
+)
+
+// CryptoPaymentMonitor completes crypto orders once the blockchain service has seen
+// their payment confirmed, and cancels them when the payment request expires
+type CryptoPaymentMonitor struct {
+	orderRepo          *repository.OrderRepository
+	blockchainClient   BlockchainClient
+	notificationClient NotificationClient
+	interval           time.Duration
+}
+
+// NewCryptoPaymentMonitor creates a new crypto payment monitor
+func NewCryptoPaymentMonitor(
+	orderRepo *repository.OrderRepository,
+	blockchainClient BlockchainClient,
+	notificationClient NotificationClient,
+	interval time.Duration,
+) *CryptoPaymentMonitor {
+	return &CryptoPaymentMonitor{
+		orderRepo:          orderRepo,
+		blockchainClient:   blockchainClient,
+		notificationClient: notificationClient,
+		interval:           interval,
+	}
+}
+
+// Run checks every order awaiting a crypto payment each interval until ctx is cancelled
+func (m *CryptoPaymentMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			orderIDs, err := m.orderRepo.ListAwaitingPayment(ctx, model.PaymentCrypto)
+			if err != nil {
+				log.Printf("Failed to list orders awaiting crypto payment: %v", err)
+				continue
+			}
+			for _, orderID := range orderIDs {
+				if err := m.Check(ctx, orderID); err != nil {
+					log.Printf("Failed to check crypto payment for order %s: %v", orderID, err)
+				}
+			}
+		}
+	}
+}
+
+// Check applies the latest state of an order's crypto payment to the order
+func (m *CryptoPaymentMonitor) Check(ctx context.Context, orderID string) error {
+	order, err := m.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != model.StatusPaymentPending {
+		return nil
+	}
+
+	paymentStatus, err := m.blockchainClient.GetCryptoPaymentStatus(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	var updated bool
+	switch paymentStatus {
+	case "":
+		// Requesting the payment failed when the order was created
+		return m.blockchainClient.CreateCryptoPayment(ctx, order.ID, order.TotalPrice)
+	case cryptoPaymentConfirmed:
+		updated, err = m.orderRepo.UpdateOrderStatusFrom(ctx, order.ID, model.StatusPaymentPending, model.StatusPaymentComplete,
+			"system", "Crypto payment confirmed on-chain")
+		if err != nil || !updated {
+			return err
+		}
+		go m.notifyUser(order, "CRYPTO_PAYMENT_CONFIRMED", "Payment confirmed",
+			fmt.Sprintf("Your crypto payment for order %s has been confirmed", order.ID))
+	case cryptoPaymentExpired:
+		updated, err = m.orderRepo.UpdateOrderStatusFrom(ctx, order.ID, model.StatusPaymentPending, model.StatusCancelled,
+			"system", "Crypto payment expired")
+		if err != nil || !updated {
+			return err
+		}
+		go m.notifyUser(order, "CRYPTO_PAYMENT_EXPIRED", "Payment expired",
+			fmt.Sprintf("Order %s was cancelled because its crypto payment was not received in time", order.ID))
+	}
+
+	return nil
+}
+
+// notifyUser tells the ordering user what happened to their crypto payment
+func (m *CryptoPaymentMonitor) notifyUser(order *model.Order, notificationType, title, message string) {
+	err := m.notificationClient.SendNotification(context.Background(), order.UserID, "USER", notificationType, title, message,
+		map[string]interface{}{
+			"order_id": order.ID,
+		})
+	if err != nil {
+		fmt.Printf("Failed to notify user of crypto payment: %v\n", err)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxPhotoRefLength bounds the photo reference a provider can attach to a delivery
+const maxPhotoRefLength = 500
+
+// CompleteDelivery moves an arrived order to DELIVERED once its provider submits valid proof of delivery
+func (s *OrderService) CompleteDelivery(ctx context.Context, req *pb.CompleteDeliveryRequest) (*pb.OrderResponse, error) {
+	if err := validateDeliveryProof(req); err != nil {
+		return nil, err
+	}
+
+	// Get current order
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if err := checkOrderNotFrozen(order); err != nil {
+		return nil, err
+	}
+
+	if order.ProviderID != req.ProviderId {
+		return nil, status.Errorf(codes.PermissionDenied, "only the order's provider can complete its delivery")
+	}
+	if order.Status != model.StatusArrived {
+		return nil, status.Errorf(codes.FailedPrecondition, "an order can only be delivered after its provider has arrived")
+	}
+	if err := s.verifyDeliveryPIN(ctx, order.ID, req.RecipientOtp); err != nil {
+		return nil, err
+	}
+
+	proof := &model.DeliveryProof{
+		ID:            uuid.New().String(),
+		OrderID:       order.ID,
+		ProviderID:    req.ProviderId,
+		PhotoRef:      req.PhotoRef,
+		SignatureHash: req.SignatureHash,
+		RecipientOTP:  req.RecipientOtp,
+		CreatedAt:     time.Now(),
+	}
+	proof.ProofHash = proof.ComputeHash()
+
+	if err := s.proofRepo.CompleteDelivery(ctx, proof); err != nil {
+		if errors.Is(err, repository.ErrOrderNotArrived) {
+			return nil, status.Errorf(codes.FailedPrecondition, "an order can only be delivered after its provider has arrived")
+		}
+		if errors.Is(err, repository.ErrOrderFrozen) {
+			return nil, errOrderFrozen
+		}
+		return nil, status.Errorf(codes.Internal, "failed to complete delivery: %v", err)
+	}
+
+	// Get updated order
+	updatedOrder, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get updated order: %v", err)
+	}
+
+	// Record delivery on blockchain; the record commits to the proof hash
+	s.blockchainRecorder.Record(ctx, updatedOrder.ID)
+
+	// Let the user know
+	go func() {
+		err := s.notificationClient.SendNotification(context.Background(), updatedOrder.UserID, "USER", "ORDER_DELIVERED", "Order delivered",
+			fmt.Sprintf("Your order %s has been delivered", updatedOrder.ID),
+			map[string]interface{}{
+				"order_id":   updatedOrder.ID,
+				"proof_hash": proof.ProofHash,
+			})
+		if err != nil {
+			fmt.Printf("Failed to notify user of delivery: %v\n", err)
+		}
+	}()
+
+	return &pb.OrderResponse{
+		Order:   convertOrderToProto(updatedOrder),
+		Message: "Order delivered successfully",
+		Success: true,
+	}, nil
+}
+
+// validateDeliveryProof checks that a proof of delivery has a photo or a signature and
+// that each part is well formed
+func validateDeliveryProof(req *pb.CompleteDeliveryRequest) error {
+	if req.PhotoRef == "" && req.SignatureHash == "" {
+		return status.Errorf(codes.InvalidArgument, "a photo reference or signature hash is required")
+	}
+	if len(req.PhotoRef) > maxPhotoRefLength {
+		return status.Errorf(codes.InvalidArgument, "photo reference must be at most %d characters", maxPhotoRefLength)
+	}
+	if req.SignatureHash != "" {
+		if b, err := hex.DecodeString(req.SignatureHash); err != nil || len(b) != 32 {
+			return status.Errorf(codes.InvalidArgument, "signature hash must be a hex-encoded SHA-256 digest")
+		}
+	}
+	if req.RecipientOtp != "" && !isDigits(req.RecipientOtp, 4, 6) {
+		return status.Errorf(codes.InvalidArgument, "delivery PIN must be 4 to 6 digits")
+	}
+	return nil
+}
+
+// isDigits reports whether s is between min and max ASCII digits long
+func isDigits(s string, min, max int) bool {
+	if len(s) < min || len(s) > max {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DeliveryPINPolicy controls the PINs users give their provider to confirm a delivery
+type DeliveryPINPolicy struct {
+	Length         int           // Digits in a PIN, 4 to 6
+	MaxAttempts    int           // Wrong PINs allowed before a lockout
+	Lockout        time.Duration // How long PIN attempts are refused after too many wrong PINs
+	ResendInterval time.Duration // Minimum time between PINs sent for an order
+}
+
+// issueDeliveryPIN generates the delivery PIN of a new order and sends it to the user
+func (s *OrderService) issueDeliveryPIN(ctx context.Context, order *model.Order) error {
+	code, err := generateDeliveryPIN(s.deliveryPINPolicy.Length)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	pin := &model.DeliveryPIN{
+		OrderID:   order.ID,
+		PINHash:   model.HashDeliveryPIN(order.ID, code),
+		IssuedAt:  now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.pinRepo.CreatePIN(ctx, pin); err != nil {
+		return err
+	}
+
+	go s.sendDeliveryPIN(order, code)
+	return nil
+}
+
+// ResendDeliveryPIN sends the user a new delivery PIN for their order, replacing the old one
+func (s *OrderService) ResendDeliveryPIN(ctx context.Context, req *pb.ResendDeliveryPINRequest) (*pb.ResendDeliveryPINResponse, error) {
+	// Get current order
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if order.UserID != req.UserId {
+		return nil, status.Errorf(codes.PermissionDenied, "only the ordering user can request their delivery PIN")
+	}
+	switch order.Status {
+	case model.StatusDelivered, model.StatusCompleted, model.StatusCancelled, model.StatusRefunded:
+		return nil, status.Errorf(codes.FailedPrecondition, "order with status %s no longer needs a delivery PIN", order.Status)
+	}
+
+	pin, err := s.pinRepo.GetPIN(ctx, order.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrDeliveryPINNotFound) {
+			return nil, status.Errorf(codes.FailedPrecondition, "order does not use a delivery PIN")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get delivery PIN: %v", err)
+	}
+
+	now := time.Now()
+	if wait := pin.IssuedAt.Add(s.deliveryPINPolicy.ResendInterval).Sub(now); wait > 0 {
+		return nil, status.Errorf(codes.ResourceExhausted, "a new delivery PIN can be requested in %d seconds", int(wait.Seconds())+1)
+	}
+
+	code, err := generateDeliveryPIN(s.deliveryPINPolicy.Length)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate delivery PIN: %v", err)
+	}
+	if err := s.pinRepo.ReplacePIN(ctx, order.ID, model.HashDeliveryPIN(order.ID, code), now); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to replace delivery PIN: %v", err)
+	}
+
+	go s.sendDeliveryPIN(order, code)
+
+	return &pb.ResendDeliveryPINResponse{
+		Message: "A new delivery PIN has been sent",
+		Success: true,
+	}, nil
+}
+
+// verifyDeliveryPIN checks the PIN a provider submitted when delivering an order. Orders
+// placed before delivery PINs were introduced have none and are not checked.
+func (s *OrderService) verifyDeliveryPIN(ctx context.Context, orderID, code string) error {
+	if _, err := s.pinRepo.GetPIN(ctx, orderID); err != nil {
+		if errors.Is(err, repository.ErrDeliveryPINNotFound) {
+			return nil
+		}
+		return status.Errorf(codes.Internal, "failed to get delivery PIN: %v", err)
+	}
+	if code == "" {
+		return status.Errorf(codes.InvalidArgument, "the recipient's delivery PIN is required")
+	}
+
+	policy := s.deliveryPINPolicy
+	remaining, err := s.pinRepo.VerifyPIN(ctx, orderID, model.HashDeliveryPIN(orderID, code), policy.MaxAttempts, policy.Lockout, time.Now())
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repository.ErrDeliveryPINMismatch):
+		return status.Errorf(codes.PermissionDenied, "incorrect delivery PIN, %d attempts left", remaining)
+	case errors.Is(err, repository.ErrDeliveryPINLocked):
+		return status.Errorf(codes.ResourceExhausted, "too many incorrect delivery PINs, try again later")
+	default:
+		return status.Errorf(codes.Internal, "failed to verify delivery PIN: %v", err)
+	}
+}
+
+// sendDeliveryPIN notifies the user of their order's delivery PIN
+func (s *OrderService) sendDeliveryPIN(order *model.Order, code string) {
+	err := s.notificationClient.SendNotification(context.Background(), order.UserID, "USER", "DELIVERY_PIN", "Your delivery PIN",
+		fmt.Sprintf("Give PIN %s to your provider when your order %s arrives", code, order.ID),
+		map[string]interface{}{
+			"order_id": order.ID,
+			"pin":      code,
+		})
+	if err != nil {
+		fmt.Printf("Failed to send delivery PIN: %v\n", err)
+	}
+}
+
+// generateDeliveryPIN returns a random PIN of the given number of digits
+func generateDeliveryPIN(length int) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < length; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate delivery PIN: %w", err)
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
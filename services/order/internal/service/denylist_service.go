@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/denylist"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DenylistService lets admins ban users, API keys and networks from the API. The gateway
+// enforces the entries and reports each request it blocks, which the audit log records
+// against the entry.
+type DenylistService struct {
+	pb.UnimplementedDenylistServiceServer
+	repo *repository.DenylistRepository
+}
+
+// NewDenylistService creates a new denylist service
+func NewDenylistService(repo *repository.DenylistRepository) *DenylistService {
+	return &DenylistService{
+		repo: repo,
+	}
+}
+
+// ListDenylistEntries lists the entries in force, or every entry with include_expired,
+// newest first
+func (s *DenylistService) ListDenylistEntries(ctx context.Context, req *pb.ListDenylistEntriesRequest) (*pb.ListDenylistEntriesResponse, error) {
+	entries, err := s.repo.ListEntries(ctx, time.Now(), req.IncludeExpired)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list denylist entries: %v", err)
+	}
+
+	protoEntries := make([]*pb.DenylistEntry, 0, len(entries))
+	for _, entry := range entries {
+		protoEntries = append(protoEntries, convertDenylistEntryToProto(entry))
+	}
+
+	return &pb.ListDenylistEntriesResponse{
+		Entries: protoEntries,
+	}, nil
+}
+
+// AddDenylistEntry bans a user, API key or network. Gateways start refusing its requests
+// the next time they load the denylist.
+func (s *DenylistService) AddDenylistEntry(ctx context.Context, req *pb.AddDenylistEntryRequest) (*pb.DenylistEntryResponse, error) {
+	if req.Value == "" || req.Reason == "" || req.CreatedBy == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "value, reason and created by are required")
+	}
+
+	now := time.Now()
+	entry := &model.DenylistEntry{
+		ID:        uuid.New().String(),
+		Kind:      model.DenylistKind(strings.ToUpper(req.Kind)),
+		Reason:    req.Reason,
+		CreatedBy: req.CreatedBy,
+		CreatedAt: now,
+	}
+
+	value, err := denylistValue(entry.Kind, strings.TrimSpace(req.Value))
+	if err != nil {
+		return nil, err
+	}
+	entry.Value = value
+
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.AsTime()
+		if !expiresAt.After(now) {
+			return nil, status.Errorf(codes.InvalidArgument, "expiry must be in the future")
+		}
+		entry.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateEntry(ctx, entry); err != nil {
+		if errors.Is(err, repository.ErrDenylistEntryExists) {
+			return nil, status.Errorf(codes.AlreadyExists, "this %s is already on the denylist", entry.Kind)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to add denylist entry: %v", err)
+	}
+
+	return &pb.DenylistEntryResponse{
+		Entry:   convertDenylistEntryToProto(entry),
+		Message: "Denylist entry added",
+		Success: true,
+	}, nil
+}
+
+// RemoveDenylistEntry lifts a ban
+func (s *DenylistService) RemoveDenylistEntry(ctx context.Context, req *pb.RemoveDenylistEntryRequest) (*pb.RemoveDenylistEntryResponse, error) {
+	if req.DenylistEntryId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "denylist entry ID is required")
+	}
+
+	if err := s.repo.DeleteEntry(ctx, req.DenylistEntryId); err != nil {
+		return nil, denylistError(err, "failed to remove denylist entry")
+	}
+
+	return &pb.RemoveDenylistEntryResponse{
+		Message: "Denylist entry removed",
+		Success: true,
+	}, nil
+}
+
+// RecordBlockedRequest counts a request a gateway refused because of an entry. The call
+// itself is what puts the block in the audit log, with the client's address and actor.
+func (s *DenylistService) RecordBlockedRequest(ctx context.Context, req *pb.RecordBlockedRequestRequest) (*pb.RecordBlockedRequestResponse, error) {
+	if req.DenylistEntryId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "denylist entry ID is required")
+	}
+
+	if err := s.repo.RecordBlock(ctx, req.DenylistEntryId, req.Request, time.Now()); err != nil {
+		return nil, denylistError(err, "failed to record blocked request")
+	}
+
+	return &pb.RecordBlockedRequestResponse{
+		Success: true,
+	}, nil
+}
+
+// denylistValue validates what an entry of a kind denies and converts it to the form it
+// is stored and matched in: API keys become their SHA-256, and networks and single
+// addresses their canonical CIDR notation
+func denylistValue(kind model.DenylistKind, value string) (string, error) {
+	switch kind {
+	case model.DenylistUser:
+		return value, nil
+	case model.DenylistAPIKey:
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:]), nil
+	case model.DenylistCIDR:
+		if _, network, err := net.ParseCIDR(value); err == nil {
+			return network.String(), nil
+		}
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", status.Errorf(codes.InvalidArgument, "value must be a network in CIDR notation or an IP address")
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		network := &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		return network.String(), nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "kind must be USER, API_KEY or CIDR")
+	}
+}
+
+// denylistError maps a repository error to a gRPC status
+func denylistError(err error, message string) error {
+	if errors.Is(err, repository.ErrDenylistEntryNotFound) {
+		return status.Errorf(codes.NotFound, "denylist entry not found")
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
+
+func convertDenylistEntryToProto(entry *model.DenylistEntry) *pb.DenylistEntry {
+	protoEntry := &pb.DenylistEntry{
+		Id:                 entry.ID,
+		Kind:               string(entry.Kind),
+		Value:              entry.Value,
+		Reason:             entry.Reason,
+		CreatedBy:          entry.CreatedBy,
+		CreatedAt:          timestamppb.New(entry.CreatedAt),
+		BlockedCount:       entry.BlockedCount,
+		LastBlockedRequest: entry.LastBlockedRequest,
+	}
+	if entry.ExpiresAt != nil {
+		protoEntry.ExpiresAt = timestamppb.New(*entry.ExpiresAt)
+	}
+	if entry.LastBlockedAt != nil {
+		protoEntry.LastBlockedAt = timestamppb.New(*entry.LastBlockedAt)
+	}
+	return protoEntry
+}
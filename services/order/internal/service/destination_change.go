@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/money"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RequestDestinationChange asks the provider of a ride in transit to take the user
+// somewhere else. The fare for the new route is quoted now and charged once the provider
+// accepts.
+func (s *OrderService) RequestDestinationChange(ctx context.Context, req *pb.RequestDestinationChangeRequest) (*pb.DestinationChangeResponse, error) {
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if err := checkOrderNotFrozen(order); err != nil {
+		return nil, err
+	}
+	if order.UserID != req.UserId {
+		return nil, status.Errorf(codes.PermissionDenied, "only the order's user can change its destination")
+	}
+	if err := checkRideInTransit(order); err != nil {
+		return nil, err
+	}
+
+	// The new route is priced from where the provider is now
+	lat, lon, err := s.tripPosition(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	destination := convertLocation(req.DestinationLocation)
+
+	change := &model.DestinationChange{
+		ID:          uuid.New().String(),
+		OrderID:     order.ID,
+		Destination: destination,
+		Fare:        order.TotalPrice + s.pricingPolicy.rerouteCharge(lat, lon, order.DestinationLocation, destination),
+		Status:      model.DestinationChangePending,
+		RequestedAt: time.Now(),
+	}
+	if err := s.repo.CreateDestinationChange(ctx, change, s.editPolicy.destinationChangesLapsedBefore(change.RequestedAt)); err != nil {
+		if errors.Is(err, repository.ErrDestinationChangePending) {
+			return nil, status.Errorf(codes.AlreadyExists, "order already has a destination change awaiting the provider")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to request destination change: %v", err)
+	}
+
+	go func() {
+		err := s.notificationClient.SendNotification(context.Background(), order.ProviderID, "PROVIDER", "DESTINATION_CHANGE_REQUESTED",
+			"New destination requested", fmt.Sprintf("The user of order %s asked to go somewhere else for %s", order.ID, money.Format(change.Fare)),
+			map[string]interface{}{
+				"order_id":  order.ID,
+				"change_id": change.ID,
+				"fare":      change.Fare,
+			})
+		if err != nil {
+			fmt.Printf("Failed to notify provider of destination change: %v\n", err)
+		}
+	}()
+
+	return &pb.DestinationChangeResponse{
+		Change:  convertDestinationChangeToProto(change),
+		Order:   convertOrderToProto(order),
+		Message: "Destination change requested successfully",
+		Success: true,
+	}, nil
+}
+
+// RespondDestinationChange lets the provider of a ride accept or decline a destination
+// change. Accepting charges the user any rise in the fare, moves the destination and
+// pushes the new ETA to the order's TrackOrder streams.
+func (s *OrderService) RespondDestinationChange(ctx context.Context, req *pb.RespondDestinationChangeRequest) (*pb.DestinationChangeResponse, error) {
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if err := checkOrderNotFrozen(order); err != nil {
+		return nil, err
+	}
+	if order.ProviderID != req.ProviderId {
+		return nil, status.Errorf(codes.PermissionDenied, "only the order's provider can answer a destination change")
+	}
+
+	change, err := s.repo.GetDestinationChange(ctx, req.ChangeId)
+	if err != nil {
+		if errors.Is(err, repository.ErrDestinationChangeNotFound) {
+			return nil, status.Errorf(codes.NotFound, "destination change not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get destination change: %v", err)
+	}
+	if change.OrderID != order.ID {
+		return nil, status.Errorf(codes.NotFound, "destination change not found")
+	}
+	if change.Status != model.DestinationChangePending {
+		return nil, status.Errorf(codes.FailedPrecondition, "destination change has already been answered")
+	}
+	now := time.Now()
+	if change.RequestedAt.Before(s.editPolicy.destinationChangesLapsedBefore(now)) {
+		return nil, status.Errorf(codes.FailedPrecondition, "destination change has expired")
+	}
+
+	change.RespondedAt = &now
+	if !req.Accept {
+		change.Status = model.DestinationChangeDeclined
+		err = s.repo.DeclineDestinationChange(ctx, change)
+	} else {
+		if err := checkRideInTransit(order); err != nil {
+			return nil, err
+		}
+
+		// Cash rides settle a higher fare with the provider
+		extra := change.Fare - order.TotalPrice
+		if extra > 0 && order.PaymentMethod != model.PaymentCash {
+			change.PaymentID, err = s.paymentClient.CapturePayment(ctx, order.ID, order.UserID, extra,
+				fmt.Sprintf("New destination for order %s", order.ID), "destination-change-"+change.ID)
+			if err != nil {
+				return nil, status.Errorf(codes.Unavailable, "failed to capture the new fare: %v", err)
+			}
+		}
+
+		moved := haversineKm(order.DestinationLocation.Latitude, order.DestinationLocation.Longitude,
+			change.Destination.Latitude, change.Destination.Longitude)
+		notes := fmt.Sprintf("Destination moved %.1f km; fare from %s to %s",
+			moved, money.Format(order.TotalPrice), money.Format(change.Fare))
+
+		change.Status = model.DestinationChangeAccepted
+		order.DestinationLocation = change.Destination
+		order.TotalPrice = change.Fare
+		s.feeSchedule.Apply(order)
+		order.AddStatusHistory(order.Status, req.ProviderId, notes)
+		err = s.repo.AcceptDestinationChange(ctx, change, order, order.StatusHistory[len(order.StatusHistory)-1])
+		if err != nil {
+			s.refundDestinationChange(ctx, order, change, extra)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrDestinationChangeNotPending) {
+			return nil, status.Errorf(codes.FailedPrecondition, "destination change has already been answered")
+		}
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			return nil, status.Errorf(codes.FailedPrecondition, "only a ride in transit can change its destination")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to answer destination change: %v", err)
+	}
+
+	if change.Status == model.DestinationChangeAccepted {
+		s.pushNewETA(ctx, order.ID)
+		s.blockchainRecorder.Record(ctx, order.ID)
+	}
+
+	go func() {
+		title, message := "Destination change declined", fmt.Sprintf("Your provider declined to change the destination of order %s", order.ID)
+		if change.Status == model.DestinationChangeAccepted {
+			title, message = "Destination changed", fmt.Sprintf("Your provider is taking you to your new destination for %s", money.Format(change.Fare))
+		}
+		err := s.notificationClient.SendNotification(context.Background(), order.UserID, "USER", "DESTINATION_CHANGE_"+string(change.Status),
+			title, message,
+			map[string]interface{}{
+				"order_id":  order.ID,
+				"change_id": change.ID,
+			})
+		if err != nil {
+			fmt.Printf("Failed to notify user of destination change: %v\n", err)
+		}
+	}()
+
+	return &pb.DestinationChangeResponse{
+		Change:  convertDestinationChangeToProto(change),
+		Order:   convertOrderToProto(order),
+		Message: "Destination change answered successfully",
+		Success: true,
+	}, nil
+}
+
+// refundDestinationChange gives back the rise in fare captured for a destination change
+// that could not be accepted after all. A change accepted meanwhile by a repeated answer
+// was charged by the same capture, which it keeps.
+func (s *OrderService) refundDestinationChange(ctx context.Context, order *model.Order, change *model.DestinationChange, extra int64) {
+	if change.PaymentID == "" {
+		return
+	}
+	stored, err := s.repo.GetDestinationChange(ctx, change.ID)
+	if err != nil {
+		fmt.Printf("Failed to get destination change %s to refund its new fare: %v\n", change.ID, err)
+		return
+	}
+	if stored.Status == model.DestinationChangeAccepted {
+		return
+	}
+	_, err = s.paymentClient.RefundPayment(ctx, order.ID, order.UserID, extra,
+		fmt.Sprintf("Destination change for order %s was not made", order.ID), "destination-change-refund-"+change.ID)
+	if err != nil {
+		fmt.Printf("Failed to refund the new fare of destination change %s: %v\n", change.ID, err)
+	}
+}
+
+// checkRideInTransit checks that an order is a ride under way, so its destination can change
+func checkRideInTransit(order *model.Order) error {
+	if order.OrderType != model.TypeRide || order.Status != model.StatusInTransit {
+		return status.Errorf(codes.FailedPrecondition, "only a ride in transit can change its destination")
+	}
+	return nil
+}
+
+// tripPosition is where the provider of an order last reported being, or its pickup if
+// they have not reported a location
+func (s *OrderService) tripPosition(ctx context.Context, order *model.Order) (float64, float64, error) {
+	location, err := s.locationRepo.GetLatestOrderLocation(ctx, order.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderLocationNotFound) {
+			return order.PickupLocation.Latitude, order.PickupLocation.Longitude, nil
+		}
+		return 0, 0, status.Errorf(codes.Internal, "failed to get latest location: %v", err)
+	}
+	return location.Latitude, location.Longitude, nil
+}
+
+// pushNewETA publishes an order's latest location again, so its TrackOrder streams send
+// it with the ETA to the order's new destination
+func (s *OrderService) pushNewETA(ctx context.Context, orderID string) {
+	location, err := s.locationRepo.GetLatestOrderLocation(ctx, orderID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrOrderLocationNotFound) {
+			fmt.Printf("Failed to get latest location of order %s: %v\n", orderID, err)
+		}
+		return
+	}
+	s.publishLocation(ctx, location)
+}
+
+func convertDestinationChangeToProto(change *model.DestinationChange) *pb.DestinationChange {
+	protoChange := &pb.DestinationChange{
+		Id:                  change.ID,
+		DestinationLocation: convertLocationToProto(change.Destination),
+		Fare:                change.Fare,
+		Status:              string(change.Status),
+		RequestedAt:         timestamppb.New(change.RequestedAt),
+	}
+	if change.RespondedAt != nil {
+		protoChange.RespondedAt = timestamppb.New(*change.RespondedAt)
+	}
+	return protoChange
+}
@@ -0,0 +1,160 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/grpc/codes"
+)
+
+const testRideID = "3c9e5a7b-1d2f-4e8a-b6c4-0f7d2a9e5b13"
+
+var newDestination = &pb.Location{Latitude: -6.15, Longitude: 106.8166, Address: "Station"}
+
+func TestDestinationChangeNeedsTheProvider(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          bool
+		wantStatus      string
+		wantDestination float64 // Latitude
+	}{
+		{name: "accepted", accept: true, wantStatus: "ACCEPTED", wantDestination: -6.15},
+		{name: "declined", wantStatus: "DECLINED", wantDestination: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, repos := newTestOrderService(&capturePayments{})
+			storeTestOrder(t, repos, testRideID, model.TypeRide, model.StatusInTransit)
+
+			requested, err := s.RequestDestinationChange(ctx, &pb.RequestDestinationChangeRequest{
+				OrderId: testRideID, UserId: testUserID, DestinationLocation: newDestination,
+			})
+			if err != nil {
+				t.Fatalf("RequestDestinationChange: %v", err)
+			}
+			if requested.Change.Status != "PENDING" {
+				t.Fatalf("change status = %s, want PENDING", requested.Change.Status)
+			}
+
+			// Only one change waits for the provider at a time
+			_, err = s.RequestDestinationChange(ctx, &pb.RequestDestinationChangeRequest{
+				OrderId: testRideID, UserId: testUserID, DestinationLocation: newDestination,
+			})
+			wantCode(t, err, codes.AlreadyExists)
+
+			resp, err := s.RespondDestinationChange(ctx, &pb.RespondDestinationChangeRequest{
+				OrderId: testRideID, ChangeId: requested.Change.Id, ProviderId: testProviderID, Accept: tt.accept,
+			})
+			if err != nil {
+				t.Fatalf("RespondDestinationChange: %v", err)
+			}
+			if resp.Change.Status != tt.wantStatus {
+				t.Errorf("change status = %s, want %s", resp.Change.Status, tt.wantStatus)
+			}
+
+			order, err := repos.orders.GetOrderByID(ctx, testRideID)
+			if err != nil {
+				t.Fatalf("GetOrderByID: %v", err)
+			}
+			if order.DestinationLocation.Latitude != tt.wantDestination {
+				t.Errorf("destination latitude = %v, want %v", order.DestinationLocation.Latitude, tt.wantDestination)
+			}
+			if order.Status != model.StatusInTransit {
+				t.Errorf("status = %s, want the ride still IN_TRANSIT", order.Status)
+			}
+
+			// An answered change cannot be answered again
+			_, err = s.RespondDestinationChange(ctx, &pb.RespondDestinationChangeRequest{
+				OrderId: testRideID, ChangeId: requested.Change.Id, ProviderId: testProviderID, Accept: true,
+			})
+			wantCode(t, err, codes.FailedPrecondition)
+		})
+	}
+}
+
+func TestDestinationChangeRefusals(t *testing.T) {
+	tests := []struct {
+		name      string
+		orderType model.OrderType
+		status    model.OrderStatus
+		userID    string
+		wantCode  codes.Code
+	}{
+		{name: "someone else's ride", orderType: model.TypeRide, status: model.StatusInTransit, userID: testProviderID, wantCode: codes.PermissionDenied},
+		{name: "ride not yet picked up", orderType: model.TypeRide, status: model.StatusProviderAccepted, userID: testUserID, wantCode: codes.FailedPrecondition},
+		{name: "delivery in transit", orderType: model.TypePackageDelivery, status: model.StatusInTransit, userID: testUserID, wantCode: codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repos := newTestOrderService(&capturePayments{})
+			storeTestOrder(t, repos, testRideID, tt.orderType, tt.status)
+
+			_, err := s.RequestDestinationChange(context.Background(), &pb.RequestDestinationChangeRequest{
+				OrderId: testRideID, UserId: tt.userID, DestinationLocation: newDestination,
+			})
+			wantCode(t, err, tt.wantCode)
+		})
+	}
+}
+
+func TestDestinationChangeOnlyAnsweredByTheProvider(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	storeTestOrder(t, repos, testRideID, model.TypeRide, model.StatusInTransit)
+
+	requested, err := s.RequestDestinationChange(ctx, &pb.RequestDestinationChangeRequest{
+		OrderId: testRideID, UserId: testUserID, DestinationLocation: newDestination,
+	})
+	if err != nil {
+		t.Fatalf("RequestDestinationChange: %v", err)
+	}
+
+	_, err = s.RespondDestinationChange(ctx, &pb.RespondDestinationChangeRequest{
+		OrderId: testRideID, ChangeId: requested.Change.Id, ProviderId: testUserID, Accept: true,
+	})
+	wantCode(t, err, codes.PermissionDenied)
+}
+
+func TestDestinationChangeExpires(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	storeTestOrder(t, repos, testRideID, model.TypeRide, model.StatusInTransit)
+
+	// The provider never answered a change asked for two minutes ago
+	lapsed := &model.DestinationChange{
+		ID:          "8b1f3d5e-7a9c-4e2b-9d4f-6a8c0e2b4d6f",
+		OrderID:     testRideID,
+		Destination: model.Location{Latitude: -6.17, Longitude: 106.8166},
+		Fare:        100000,
+		Status:      model.DestinationChangePending,
+		RequestedAt: time.Now().Add(-2 * time.Minute),
+	}
+	if err := repos.orders.CreateDestinationChange(ctx, lapsed, time.Time{}); err != nil {
+		t.Fatalf("CreateDestinationChange: %v", err)
+	}
+
+	_, err := s.RespondDestinationChange(ctx, &pb.RespondDestinationChangeRequest{
+		OrderId: testRideID, ChangeId: lapsed.ID, ProviderId: testProviderID, Accept: true,
+	})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	// A new change can be asked for in its place
+	if _, err := s.RequestDestinationChange(ctx, &pb.RequestDestinationChangeRequest{
+		OrderId: testRideID, UserId: testUserID, DestinationLocation: newDestination,
+	}); err != nil {
+		t.Fatalf("RequestDestinationChange: %v", err)
+	}
+	change, err := repos.orders.GetDestinationChange(ctx, lapsed.ID)
+	if err != nil {
+		t.Fatalf("GetDestinationChange: %v", err)
+	}
+	if change.Status != model.DestinationChangeExpired {
+		t.Errorf("lapsed change status = %s, want %s", change.Status, model.DestinationChangeExpired)
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// maxScoredDistanceKm is the distance at which a provider's distance score reaches zero
+const maxScoredDistanceKm = 10.0
+
+// maxScoredPickupMinutes is the predicted time to the pickup at which a provider's
+// distance score reaches zero; the time to cover maxScoredDistanceKm at 30 km/h
+const maxScoredPickupMinutes = 20.0
+
+// DispatchConfig controls the fairness signals in dispatch scoring
+type DispatchConfig struct {
+	EarningsWindow   time.Duration // How far back a provider's earnings count
+	IdleCap          time.Duration // Idle time at which a provider gets the full idle boost
+	RefreshInterval  time.Duration // How often the weights are reloaded
+	DeclineWindow    time.Duration // How far back a provider's answers to offers count
+	DeclineMinOffers int           // Answered offers in the window before a provider can be penalized
+	DeclineRate      float64       // Share of answered offers declined or let expire at which a provider is penalized
+	DeclinePenalty   float64       // Share of a penalized provider's score taken off
+}
+
+// Dispatcher scores matched providers on distance, rating and fairness signals, and
+// records each dispatch decision for auditing. The weights are held in memory; admin
+// changes on other replicas are picked up on the next refresh.
+type Dispatcher struct {
+	repo      *repository.DispatchRepository
+	predictor Predictor
+	cfg       DispatchConfig
+
+	mu      sync.RWMutex
+	weights model.DispatchWeights
+}
+
+// NewDispatcher creates a new dispatcher using the default weights; call Refresh to load
+// the current ones
+func NewDispatcher(repo *repository.DispatchRepository, predictor Predictor, cfg DispatchConfig) *Dispatcher {
+	return &Dispatcher{
+		repo:      repo,
+		predictor: predictor,
+		cfg:       cfg,
+		weights:   model.DefaultDispatchWeights,
+	}
+}
+
+// Refresh reloads the weights from the database
+func (d *Dispatcher) Refresh(ctx context.Context) error {
+	weights, err := d.repo.GetWeights(ctx)
+	if err != nil {
+		return err
+	}
+	d.SetWeights(weights)
+	return nil
+}
+
+// Run refreshes the weights every interval until ctx is cancelled. A failed refresh
+// keeps the previous weights.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh dispatch weights: %v", err)
+			}
+		}
+	}
+}
+
+// Weights returns the weights in use
+func (d *Dispatcher) Weights() model.DispatchWeights {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.weights
+}
+
+// SetWeights replaces the weights in use
+func (d *Dispatcher) SetWeights(weights model.DispatchWeights) {
+	d.mu.Lock()
+	d.weights = weights
+	d.mu.Unlock()
+}
+
+// Rank scores providers and sorts them best first. The distance signal scores the
+// predicted time for each provider to reach the pickup, or their distance from it when
+// no prediction is available. Providers the user has favorited, who have gone longest
+// without an order or who earned least recently get a boost; providers who never had an
+// order get the full idle boost. If activity cannot be loaded, the idle and earnings
+// signals are left out. The quality signals score the share of offers a provider
+// accepted recently and the completion and on-time rates on their record. Chronic
+// decliners, who declined or let expire most of the offers they answered recently, lose
+// part of their score.
+func (d *Dispatcher) Rank(ctx context.Context, pickup model.Location, providers []Provider) {
+	if len(providers) == 0 {
+		return
+	}
+
+	now := time.Now()
+	ids := make([]string, len(providers))
+	for i, provider := range providers {
+		ids[i] = provider.ID
+	}
+
+	activity, err := d.repo.GetProviderActivity(ctx, ids, now.Add(-d.cfg.EarningsWindow))
+	if err != nil {
+		log.Printf("Failed to load provider activity for dispatch: %v", err)
+	}
+	offerStats, offerErr := d.repo.GetProviderOfferStats(ctx, ids, now.Add(-d.cfg.DeclineWindow))
+	if offerErr != nil {
+		log.Printf("Failed to load provider offer answers for dispatch: %v", offerErr)
+	}
+
+	var maxEarnings int64
+	for i := range providers {
+		a := activity[providers[i].ID]
+		providers[i].RecentEarnings = a.RecentEarnings
+		if a.LastOrderAt == nil {
+			providers[i].IdleMinutes = d.cfg.IdleCap.Minutes()
+		} else {
+			providers[i].IdleMinutes = now.Sub(*a.LastOrderAt).Minutes()
+		}
+		if a.RecentEarnings > maxEarnings {
+			maxEarnings = a.RecentEarnings
+		}
+	}
+
+	weights := d.Weights()
+	for i := range providers {
+		closeness := 1.0 - math.Min(providers[i].Distance/maxScoredDistanceKm, 1.0)
+		eta, etaErr := d.predictor.PredictETA(ctx, []model.Location{providers[i].Location, pickup})
+		if etaErr != nil {
+			log.Printf("Failed to predict pickup time for provider %s: %v", providers[i].ID, etaErr)
+		} else {
+			providers[i].PickupETAMinutes = eta.Minutes()
+			closeness = 1.0 - math.Min(providers[i].PickupETAMinutes/maxScoredPickupMinutes, 1.0)
+		}
+		stats := offerStats[providers[i].ID]
+		providers[i].AcceptanceRate = stats.AcceptanceRate()
+		providers[i].Score = d.score(weights, providers[i], closeness, maxEarnings, err == nil)
+
+		if d.chronicDecliner(stats) {
+			providers[i].DeclinePenalized = true
+			providers[i].Score *= 1 - d.cfg.DeclinePenalty
+		}
+	}
+
+	sort.SliceStable(providers, func(i, j int) bool {
+		return providers[i].Score > providers[j].Score
+	})
+}
+
+// score weighs a provider's signals, each scored from 0 to 1. closeness is the provider's
+// distance signal; maxEarnings is the most any of the candidates earned recently.
+func (d *Dispatcher) score(weights model.DispatchWeights, provider Provider, closeness float64, maxEarnings int64, withActivity bool) float64 {
+	ratingScore := provider.Rating / 5.0
+	score := weights.Distance*closeness + weights.Rating*ratingScore
+	score += weights.Acceptance*provider.AcceptanceRate + weights.Completion*provider.CompletionRate + weights.OnTime*provider.OnTimeRate
+	if provider.Favorite {
+		score += weights.Favorite
+	}
+	if !withActivity {
+		return score
+	}
+
+	idleScore := 1.0
+	if d.cfg.IdleCap > 0 {
+		idleScore = math.Min(provider.IdleMinutes/d.cfg.IdleCap.Minutes(), 1.0)
+	}
+	earningsScore := 1.0
+	if maxEarnings > 0 {
+		earningsScore = 1.0 - float64(provider.RecentEarnings)/float64(maxEarnings)
+	}
+
+	return score + weights.Idle*idleScore + weights.Earnings*earningsScore
+}
+
+// chronicDecliner reports whether a provider has declined or let expire enough of the
+// offers they answered to be penalized
+func (d *Dispatcher) chronicDecliner(stats model.ProviderOfferStats) bool {
+	if d.cfg.DeclinePenalty <= 0 || stats.Answered() == 0 || stats.Answered() < d.cfg.DeclineMinOffers {
+		return false
+	}
+	return 1-stats.AcceptanceRate() >= d.cfg.DeclineRate
+}
+
+// Record stores which of the ranked providers was picked for an order, with the signals
+// and weights behind the pick. Failures are logged; they never hold up dispatch.
+func (d *Dispatcher) Record(ctx context.Context, order *model.Order, providers []Provider, selectedID string, autoAccepted bool) {
+	decision := &model.DispatchDecision{
+		ID:                 uuid.New().String(),
+		OrderID:            order.ID,
+		SelectedProviderID: selectedID,
+		AutoAccepted:       autoAccepted,
+		Weights:            d.Weights(),
+		Candidates:         make(model.DispatchCandidates, 0, len(providers)),
+		CreatedAt:          time.Now(),
+	}
+	for _, provider := range providers {
+		decision.Candidates = append(decision.Candidates, model.DispatchCandidate{
+			ProviderID:       provider.ID,
+			DistanceKm:       provider.Distance,
+			PickupETAMinutes: provider.PickupETAMinutes,
+			Rating:           provider.Rating,
+			IdleMinutes:      provider.IdleMinutes,
+			RecentEarnings:   provider.RecentEarnings,
+			Favorite:         provider.Favorite,
+			AcceptanceRate:   provider.AcceptanceRate,
+			DeclinePenalized: provider.DeclinePenalized,
+			CompletionRate:   provider.CompletionRate,
+			OnTimeRate:       provider.OnTimeRate,
+			Score:            provider.Score,
+		})
+	}
+
+	log.Printf("Dispatch decision %s: order %s assigned to provider %s from %d candidates (auto-accepted: %t)",
+		decision.ID, order.ID, selectedID, len(providers), autoAccepted)
+
+	if err := d.repo.CreateDecision(ctx, decision); err != nil {
+		log.Printf("Failed to record dispatch decision for order %s: %v", order.ID, err)
+	}
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/fanout"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Outcomes of offers sent over dispatch channels
+const (
+	offerAcknowledged   = "acknowledged"
+	offerUnacknowledged = "unacknowledged" // No app acknowledged it in time, so it was pushed instead
+	offerFailed         = "failed"
+)
+
+var dispatchOffersCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "dispatch_offers_total",
+	Help: "Order offers sent over provider dispatch channels, by outcome",
+}, []string{"outcome"})
+
+// dispatchOfferTopic is the fan-out topic of the offers sent to a provider
+func dispatchOfferTopic(providerID string) string {
+	return "dispatch-offers:" + providerID
+}
+
+// dispatchAckTopic is the fan-out topic on which an offer is acknowledged
+func dispatchAckTopic(offerID string) string {
+	return "dispatch-ack:" + offerID
+}
+
+// DispatchOfferConfig controls the offers sent over dispatch channels
+type DispatchOfferConfig struct {
+	TTL           time.Duration // How long a provider has to answer an offer
+	AckTimeout    time.Duration // How long an app has to acknowledge an offer before it is pushed instead
+	SweepInterval time.Duration // How often unanswered offers are closed once they expire
+	SweepBatch    int           // Most offers closed per query
+}
+
+// dispatchOffer is an offer as published to a provider's dispatch channels
+type dispatchOffer struct {
+	ID        string          `json:"id"`
+	OrderID   string          `json:"order_id"`
+	Details   json.RawMessage `json:"details"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// DispatchOffers sends order offers to the dispatch channels providers' apps hold open,
+// on whichever instance holds them, and hears back when an app acknowledges one. Every
+// offer and its answer is stored, however it reached the provider.
+type DispatchOffers struct {
+	repo *repository.DispatchRepository
+	bus  fanout.Bus
+	cfg  DispatchOfferConfig
+}
+
+// NewDispatchOffers creates dispatch offers sent over a fan-out bus
+func NewDispatchOffers(repo *repository.DispatchRepository, bus fanout.Bus, cfg DispatchOfferConfig) *DispatchOffers {
+	return &DispatchOffers{
+		repo: repo,
+		bus:  bus,
+		cfg:  cfg,
+	}
+}
+
+// Run closes expired offers every interval until ctx is cancelled
+func (o *DispatchOffers) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.cfg.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.Sweep(ctx)
+		}
+	}
+}
+
+// Sweep closes every offer that expired without an answer
+func (o *DispatchOffers) Sweep(ctx context.Context) {
+	now := time.Now()
+	for ctx.Err() == nil {
+		closed, err := o.repo.ExpireOffers(ctx, now, o.cfg.SweepBatch)
+		if err != nil {
+			log.Printf("Failed to expire dispatch offers: %v", err)
+			return
+		}
+		if closed < int64(o.cfg.SweepBatch) {
+			return
+		}
+	}
+}
+
+// Respond records a provider's answer to their pending offers of an order. Failures are
+// logged; they never hold up the answer itself.
+func (o *DispatchOffers) Respond(ctx context.Context, orderID, providerID string, response model.OfferResponse) {
+	if _, err := o.repo.RespondToOffers(ctx, orderID, providerID, response, time.Now()); err != nil {
+		log.Printf("Failed to record provider %s's answer to their offer of order %s: %v", providerID, orderID, err)
+	}
+}
+
+// Offer sends an order offer to a provider's dispatch channels and waits for their app
+// to acknowledge it. It reports false when no app did in time, such as when the provider
+// has no channel open, so the offer must reach them another way.
+func (o *DispatchOffers) Offer(ctx context.Context, providerID, orderID string, details interface{}) (bool, error) {
+	encodedDetails, err := json.Marshal(details)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode offer details: %w", err)
+	}
+	now := time.Now()
+	offer := dispatchOffer{
+		ID:        uuid.New().String(),
+		OrderID:   orderID,
+		Details:   encodedDetails,
+		ExpiresAt: now.Add(o.cfg.TTL),
+	}
+	message, err := json.Marshal(offer)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode offer: %w", err)
+	}
+
+	// Store the offer whether or not it reaches a dispatch channel, since an offer pushed
+	// instead is answered too
+	err = o.repo.CreateOffer(ctx, &model.DispatchOffer{
+		ID:         offer.ID,
+		OrderID:    orderID,
+		ProviderID: providerID,
+		OfferedAt:  now,
+		ExpiresAt:  offer.ExpiresAt,
+		Response:   model.OfferPending,
+	})
+	if err != nil {
+		log.Printf("Failed to store offer of order %s to provider %s: %v", orderID, providerID, err)
+	}
+
+	// Listen for the acknowledgement before offering, so a quick one is not missed
+	acks, err := o.bus.Subscribe(ctx, dispatchAckTopic(offer.ID))
+	if err != nil {
+		dispatchOffersCounter.WithLabelValues(offerFailed).Inc()
+		return false, fmt.Errorf("failed to subscribe to offer acknowledgements: %w", err)
+	}
+	defer acks.Close()
+
+	if err := o.bus.Publish(ctx, dispatchOfferTopic(providerID), message); err != nil {
+		dispatchOffersCounter.WithLabelValues(offerFailed).Inc()
+		return false, fmt.Errorf("failed to publish offer: %w", err)
+	}
+
+	timer := time.NewTimer(o.cfg.AckTimeout)
+	defer timer.Stop()
+	select {
+	case <-acks.C:
+		dispatchOffersCounter.WithLabelValues(offerAcknowledged).Inc()
+		return true, nil
+	case <-timer.C:
+		dispatchOffersCounter.WithLabelValues(offerUnacknowledged).Inc()
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// subscribe starts receiving the offers sent to a provider
+func (o *DispatchOffers) subscribe(ctx context.Context, providerID string) (*fanout.Subscription, error) {
+	return o.bus.Subscribe(ctx, dispatchOfferTopic(providerID))
+}
+
+// acknowledge records that the provider's app received an offer, and tells the instance
+// that sent it
+func (o *DispatchOffers) acknowledge(ctx context.Context, offerID string) {
+	if err := o.repo.AcknowledgeOffer(ctx, offerID, time.Now()); err != nil {
+		log.Printf("Failed to record acknowledgement of offer %s: %v", offerID, err)
+	}
+	if err := o.bus.Publish(ctx, dispatchAckTopic(offerID), []byte(offerID)); err != nil {
+		log.Printf("Failed to acknowledge offer %s: %v", offerID, err)
+	}
+}
+
+// DispatchChannel holds a provider app's dispatch channel open. Offers made to the
+// provider are sent down it as they are made, and the app acknowledges and answers them
+// on it; each answer gets a result. The app's first message must connect the provider.
+func (s *OrderService) DispatchChannel(stream pb.OrderService_DispatchChannelServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	connect := first.GetConnect()
+	if connect == nil {
+		return status.Errorf(codes.InvalidArgument, "the first message must connect a provider")
+	}
+	providerID := connect.ProviderId
+
+	ctx := stream.Context()
+	offers, err := s.dispatchOffers.subscribe(ctx, providerID)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to open dispatch channel: %v", err)
+	}
+	defer offers.Close()
+
+	channel := &dispatchChannel{
+		service:    s,
+		stream:     stream,
+		providerID: providerID,
+		offers:     make(map[string]dispatchOffer),
+	}
+
+	// Handle the app's acknowledgements and answers until it goes away
+	received := make(chan error, 1)
+	go func() {
+		received <- channel.receive(ctx)
+	}()
+
+	for {
+		select {
+		case err := <-received:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case message := <-offers.C:
+			if err := channel.offer(message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// dispatchChannel is a provider app's open dispatch channel
+type dispatchChannel struct {
+	service    *OrderService
+	stream     pb.OrderService_DispatchChannelServer
+	providerID string
+
+	sendMu sync.Mutex // A stream allows one sender at a time
+
+	mu     sync.Mutex
+	offers map[string]dispatchOffer // Offers sent and not yet answered, by ID
+}
+
+// send sends a message to the app
+func (c *dispatchChannel) send(message *pb.DispatchServerMessage) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return c.stream.Send(message)
+}
+
+// offer sends a published offer to the app and remembers it until it is answered
+func (c *dispatchChannel) offer(message []byte) error {
+	var offer dispatchOffer
+	if err := json.Unmarshal(message, &offer); err != nil {
+		log.Printf("Failed to decode offer for provider %s: %v", c.providerID, err)
+		return nil
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	for id, sent := range c.offers {
+		if now.After(sent.ExpiresAt) {
+			delete(c.offers, id)
+		}
+	}
+	c.offers[offer.ID] = offer
+	c.mu.Unlock()
+
+	return c.send(&pb.DispatchServerMessage{
+		Message: &pb.DispatchServerMessage_Offer{
+			Offer: &pb.DispatchOffer{
+				OfferId:   offer.ID,
+				OrderId:   offer.OrderID,
+				Details:   string(offer.Details),
+				ExpiresAt: timestamppb.New(offer.ExpiresAt),
+			},
+		},
+	})
+}
+
+// receive handles the app's messages until the stream ends
+func (c *dispatchChannel) receive(ctx context.Context) error {
+	for {
+		message, err := c.stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		switch m := message.Message.(type) {
+		case *pb.DispatchClientMessage_Ack:
+			c.mu.Lock()
+			_, ok := c.offers[m.Ack.OfferId]
+			c.mu.Unlock()
+			if ok {
+				c.service.dispatchOffers.acknowledge(ctx, m.Ack.OfferId)
+			}
+		case *pb.DispatchClientMessage_Answer:
+			result := c.answer(ctx, m.Answer)
+			if err := c.send(&pb.DispatchServerMessage{
+				Message: &pb.DispatchServerMessage_Result{Result: result},
+			}); err != nil {
+				return err
+			}
+		default:
+			return status.Errorf(codes.InvalidArgument, "expected an acknowledgement or an answer")
+		}
+	}
+}
+
+// answer accepts or declines an offer for the provider
+func (c *dispatchChannel) answer(ctx context.Context, answer *pb.DispatchAnswer) *pb.DispatchResult {
+	result := &pb.DispatchResult{OfferId: answer.OfferId}
+
+	c.mu.Lock()
+	offer, ok := c.offers[answer.OfferId]
+	delete(c.offers, answer.OfferId)
+	c.mu.Unlock()
+	if !ok {
+		result.Message = "offer not found or expired"
+		return result
+	}
+	// An answer shows the offer arrived, even if the acknowledgement was lost
+	c.service.dispatchOffers.acknowledge(ctx, offer.ID)
+	if time.Now().After(offer.ExpiresAt) {
+		result.Message = "offer expired"
+		return result
+	}
+
+	var resp *pb.OrderResponse
+	var err error
+	if answer.Accept {
+		resp, err = c.service.AcceptOrder(ctx, &pb.AcceptOrderRequest{
+			OrderId:         offer.OrderID,
+			ProviderId:      c.providerID,
+			CurrentLocation: answer.CurrentLocation,
+		})
+	} else {
+		resp, err = c.service.RejectOrder(ctx, &pb.RejectOrderRequest{
+			OrderId:    offer.OrderID,
+			ProviderId: c.providerID,
+			Reason:     answer.Reason,
+		})
+		if status.Code(err) == codes.PermissionDenied {
+			// Offers also go to providers the order was not assigned to; declining one
+			// leaves nothing to undo
+			result.Success = true
+			result.Message = "Offer declined"
+			return result
+		}
+	}
+	if err != nil {
+		result.Message = status.Convert(err).Message()
+		return result
+	}
+
+	result.Success = true
+	result.Message = resp.Message
+	if answer.Accept {
+		result.Order = resp.Order
+	}
+	return result
+}
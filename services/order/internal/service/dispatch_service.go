@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/dispatch"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DispatchService lets admins tune the dispatch weights, audit dispatch decisions and
+// check demand forecasts
+type DispatchService struct {
+	pb.UnimplementedDispatchServiceServer
+	repo         *repository.DispatchRepository
+	dispatcher   *Dispatcher
+	predictor    Predictor
+	serviceAreas *ServiceAreas
+}
+
+// NewDispatchService creates a new dispatch service
+func NewDispatchService(repo *repository.DispatchRepository, dispatcher *Dispatcher, predictor Predictor, serviceAreas *ServiceAreas) *DispatchService {
+	return &DispatchService{
+		repo:         repo,
+		dispatcher:   dispatcher,
+		predictor:    predictor,
+		serviceAreas: serviceAreas,
+	}
+}
+
+// GetDispatchWeights returns the weights the matcher scores providers with
+func (s *DispatchService) GetDispatchWeights(ctx context.Context, req *pb.GetDispatchWeightsRequest) (*pb.DispatchWeightsResponse, error) {
+	weights, err := s.repo.GetWeights(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get dispatch weights: %v", err)
+	}
+
+	return &pb.DispatchWeightsResponse{
+		Weights: convertDispatchWeightsToProto(weights),
+		Success: true,
+		Message: "Dispatch weights retrieved successfully",
+	}, nil
+}
+
+// UpdateDispatchWeights replaces the weights. They take effect on this replica at once and
+// on the others at their next refresh.
+func (s *DispatchService) UpdateDispatchWeights(ctx context.Context, req *pb.UpdateDispatchWeightsRequest) (*pb.DispatchWeightsResponse, error) {
+	if req.Weights == nil || req.UpdatedBy == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "weights and updated by are required")
+	}
+
+	w := req.Weights
+	if w.Distance < 0 || w.Rating < 0 || w.Idle < 0 || w.Earnings < 0 || w.Favorite < 0 || w.Acceptance < 0 || w.Completion < 0 || w.OnTime < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "weights cannot be negative")
+	}
+	if w.Distance+w.Rating+w.Idle+w.Earnings+w.Favorite+w.Acceptance+w.Completion+w.OnTime == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "at least one weight must be positive")
+	}
+
+	weights := model.DispatchWeights{
+		Distance:   w.Distance,
+		Rating:     w.Rating,
+		Idle:       w.Idle,
+		Earnings:   w.Earnings,
+		Favorite:   w.Favorite,
+		Acceptance: w.Acceptance,
+		Completion: w.Completion,
+		OnTime:     w.OnTime,
+		UpdatedBy:  req.UpdatedBy,
+		UpdatedAt:  time.Now(),
+	}
+
+	if err := s.repo.SaveWeights(ctx, weights); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update dispatch weights: %v", err)
+	}
+	s.dispatcher.SetWeights(weights)
+
+	return &pb.DispatchWeightsResponse{
+		Weights: convertDispatchWeightsToProto(weights),
+		Success: true,
+		Message: "Dispatch weights updated",
+	}, nil
+}
+
+// ListDispatchDecisions lists dispatch decisions, newest first
+func (s *DispatchService) ListDispatchDecisions(ctx context.Context, req *pb.ListDispatchDecisionsRequest) (*pb.ListDispatchDecisionsResponse, error) {
+	decisions, total, err := s.repo.ListDecisions(ctx, req.OrderId, req.ProviderId, int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list dispatch decisions: %v", err)
+	}
+
+	protoDecisions := make([]*pb.DispatchDecision, 0, len(decisions))
+	for _, decision := range decisions {
+		protoDecisions = append(protoDecisions, convertDispatchDecisionToProto(decision))
+	}
+
+	return &pb.ListDispatchDecisionsResponse{
+		Decisions: protoDecisions,
+		Total:     int32(total),
+		Page:      req.Page,
+		Limit:     req.Limit,
+	}, nil
+}
+
+// GetDemandForecast returns the orders expected to be picked up in a zone during an hour
+func (s *DispatchService) GetDemandForecast(ctx context.Context, req *pb.GetDemandForecastRequest) (*pb.DemandForecastResponse, error) {
+	if req.Zone != "" && s.serviceAreas.Get(req.Zone) == nil {
+		return nil, status.Errorf(codes.NotFound, "service area not found")
+	}
+
+	at := time.Now()
+	if req.At != nil {
+		at = req.At.AsTime()
+	}
+	at = at.Truncate(time.Hour)
+
+	demand, err := s.predictor.PredictDemand(ctx, req.Zone, at)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to predict demand: %v", err)
+	}
+
+	return &pb.DemandForecastResponse{
+		Zone:           req.Zone,
+		At:             timestamppb.New(at),
+		ExpectedOrders: demand,
+	}, nil
+}
+
+func convertDispatchWeightsToProto(weights model.DispatchWeights) *pb.DispatchWeights {
+	protoWeights := &pb.DispatchWeights{
+		Distance:   weights.Distance,
+		Rating:     weights.Rating,
+		Idle:       weights.Idle,
+		Earnings:   weights.Earnings,
+		Favorite:   weights.Favorite,
+		Acceptance: weights.Acceptance,
+		Completion: weights.Completion,
+		OnTime:     weights.OnTime,
+		UpdatedBy:  weights.UpdatedBy,
+	}
+	if !weights.UpdatedAt.IsZero() {
+		protoWeights.UpdatedAt = timestamppb.New(weights.UpdatedAt)
+	}
+	return protoWeights
+}
+
+func convertDispatchDecisionToProto(decision *model.DispatchDecision) *pb.DispatchDecision {
+	protoDecision := &pb.DispatchDecision{
+		Id:                 decision.ID,
+		OrderId:            decision.OrderID,
+		SelectedProviderId: decision.SelectedProviderID,
+		AutoAccepted:       decision.AutoAccepted,
+		Weights:            convertDispatchWeightsToProto(decision.Weights),
+		CreatedAt:          timestamppb.New(decision.CreatedAt),
+	}
+	for _, candidate := range decision.Candidates {
+		protoDecision.Candidates = append(protoDecision.Candidates, &pb.DispatchCandidate{
+			ProviderId:       candidate.ProviderID,
+			DistanceKm:       candidate.DistanceKm,
+			PickupEtaMinutes: candidate.PickupETAMinutes,
+			Rating:           candidate.Rating,
+			IdleMinutes:      candidate.IdleMinutes,
+			RecentEarnings:   candidate.RecentEarnings,
+			Favorite:         candidate.Favorite,
+			AcceptanceRate:   candidate.AcceptanceRate,
+			DeclinePenalized: candidate.DeclinePenalized,
+			CompletionRate:   candidate.CompletionRate,
+			OnTimeRate:       candidate.OnTimeRate,
+			Score:            candidate.Score,
+		})
+	}
+	return protoDecision
+}
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/money"
+	pb "github.com/order-api-microservices/proto/dispute"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DisputeService handles disputes raised against orders and the payment holds they place
+type DisputeService struct {
+	pb.UnimplementedDisputeServiceServer
+	repo               *repository.DisputeRepository
+	orderRepo          *repository.OrderRepository
+	blockchainRecorder *BlockchainRecorder
+	paymentClient      PaymentClient
+	authorizations     *PaymentAuthorizations
+}
+
+// NewDisputeService creates a new dispute service
+func NewDisputeService(
+	repo *repository.DisputeRepository,
+	orderRepo *repository.OrderRepository,
+	blockchainRecorder *BlockchainRecorder,
+	paymentClient PaymentClient,
+	authorizations *PaymentAuthorizations,
+) *DisputeService {
+	return &DisputeService{
+		repo:               repo,
+		orderRepo:          orderRepo,
+		blockchainRecorder: blockchainRecorder,
+		paymentClient:      paymentClient,
+		authorizations:     authorizations,
+	}
+}
+
+// OpenDispute opens a dispute on an order and holds its payment until an admin resolves it
+func (s *DisputeService) OpenDispute(ctx context.Context, req *pb.OpenDisputeRequest) (*pb.DisputeResponse, error) {
+	if req.OrderId == "" || req.OpenedBy == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order ID and opened by are required")
+	}
+	if req.Reason == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "reason is required")
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	// Only the parties to the order can dispute it
+	role := model.DisputeRole(req.Role)
+	switch role {
+	case model.RoleUser:
+		if order.UserID != req.OpenedBy {
+			return nil, status.Errorf(codes.PermissionDenied, "only the order's user can open a dispute as USER")
+		}
+	case model.RoleProvider:
+		if order.ProviderID == "" || order.ProviderID != req.OpenedBy {
+			return nil, status.Errorf(codes.PermissionDenied, "only the order's provider can open a dispute as PROVIDER")
+		}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "role must be USER or PROVIDER")
+	}
+
+	// There is nothing to hold before payment, or after the money has already moved back
+	switch order.Status {
+	case model.StatusCreated, model.StatusPaymentPending, model.StatusCancelled, model.StatusRefunded:
+		return nil, status.Errorf(codes.FailedPrecondition, "order cannot be disputed in its current state")
+	case model.StatusDisputed:
+		return nil, status.Errorf(codes.AlreadyExists, "order already has an open dispute")
+	}
+
+	now := time.Now()
+	dispute := &model.Dispute{
+		ID:           uuid.New().String(),
+		OrderID:      order.ID,
+		OpenedBy:     req.OpenedBy,
+		OpenedByRole: role,
+		Reason:       req.Reason,
+		Description:  req.Description,
+		Status:       model.DisputeOpen,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	hold := &model.PaymentHold{
+		ID:        uuid.New().String(),
+		OrderID:   order.ID,
+		DisputeID: dispute.ID,
+		Amount:    order.TotalPrice,
+		Status:    model.HoldHeld,
+		CreatedAt: now,
+	}
+
+	if err := s.repo.OpenDispute(ctx, dispute, hold); err != nil {
+		if errors.Is(err, repository.ErrDisputeAlreadyOpen) {
+			return nil, status.Errorf(codes.AlreadyExists, "order already has an open dispute")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to open dispute: %v", err)
+	}
+	dispute.PaymentHold = hold
+
+	s.blockchainRecorder.Record(ctx, order.ID)
+
+	return &pb.DisputeResponse{
+		Dispute: convertDisputeToProto(dispute),
+		Message: "Dispute opened successfully",
+		Success: true,
+	}, nil
+}
+
+// AddEvidence attaches a statement or file to an open dispute
+func (s *DisputeService) AddEvidence(ctx context.Context, req *pb.AddEvidenceRequest) (*pb.DisputeResponse, error) {
+	if req.DisputeId == "" || req.SubmittedBy == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "dispute ID and submitted by are required")
+	}
+	if req.Description == "" && req.AttachmentUrl == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "description or attachment URL is required")
+	}
+
+	dispute, err := s.getDispute(ctx, req.DisputeId)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, dispute.OrderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	role := model.DisputeRole(req.Role)
+	switch role {
+	case model.RoleUser:
+		if order.UserID != req.SubmittedBy {
+			return nil, status.Errorf(codes.PermissionDenied, "only the order's user can submit evidence as USER")
+		}
+	case model.RoleProvider:
+		if order.ProviderID != req.SubmittedBy {
+			return nil, status.Errorf(codes.PermissionDenied, "only the order's provider can submit evidence as PROVIDER")
+		}
+	case model.RoleAdmin:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "role must be USER, PROVIDER or ADMIN")
+	}
+
+	evidence := &model.DisputeEvidence{
+		ID:            uuid.New().String(),
+		DisputeID:     dispute.ID,
+		SubmittedBy:   req.SubmittedBy,
+		Role:          role,
+		Description:   req.Description,
+		AttachmentURL: req.AttachmentUrl,
+		CreatedAt:     time.Now(),
+	}
+
+	if err := s.repo.AddEvidence(ctx, evidence); err != nil {
+		if errors.Is(err, repository.ErrDisputeNotOpen) {
+			return nil, status.Errorf(codes.FailedPrecondition, "dispute is already resolved")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to add evidence: %v", err)
+	}
+
+	updated, err := s.getDispute(ctx, dispute.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.DisputeResponse{
+		Dispute: convertDisputeToProto(updated),
+		Message: "Evidence added successfully",
+		Success: true,
+	}, nil
+}
+
+// ResolveDispute settles a dispute: the held payment is refunded in full or in part, or released to the provider
+func (s *DisputeService) ResolveDispute(ctx context.Context, req *pb.ResolveDisputeRequest) (*pb.DisputeResponse, error) {
+	if req.DisputeId == "" || req.ResolvedBy == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "dispute ID and resolved by are required")
+	}
+
+	dispute, err := s.getDispute(ctx, req.DisputeId)
+	if err != nil {
+		return nil, err
+	}
+	if dispute.Status != model.DisputeOpen {
+		return nil, status.Errorf(codes.FailedPrecondition, "dispute is already resolved")
+	}
+	if dispute.PaymentHold == nil {
+		return nil, status.Errorf(codes.Internal, "dispute has no payment hold")
+	}
+
+	var holdStatus model.HoldStatus
+	var orderStatus model.OrderStatus
+	resolution := model.DisputeResolution(req.Resolution)
+	switch resolution {
+	case model.ResolutionRefundFull:
+		dispute.RefundAmount = dispute.PaymentHold.Amount
+		holdStatus = model.HoldRefunded
+		orderStatus = model.StatusRefunded
+	case model.ResolutionRefundPartial:
+		if req.RefundAmount <= 0 || req.RefundAmount >= dispute.PaymentHold.Amount {
+			return nil, status.Errorf(codes.InvalidArgument, "partial refund must be greater than 0 and less than %s", money.Format(dispute.PaymentHold.Amount))
+		}
+		dispute.RefundAmount = req.RefundAmount
+		holdStatus = model.HoldPartiallyRefunded
+		orderStatus = model.StatusCompleted
+	case model.ResolutionNoRefund:
+		dispute.RefundAmount = 0
+		holdStatus = model.HoldReleased
+		orderStatus = model.StatusCompleted
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "resolution must be REFUND_FULL, REFUND_PARTIAL or NO_REFUND")
+	}
+
+	dispute.Resolution = resolution
+	dispute.ResolvedBy = req.ResolvedBy
+	dispute.ResolutionNotes = req.Notes
+
+	auth, err := s.authorizations.get(ctx, dispute.OrderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get payment authorization: %v", err)
+	}
+
+	// Return the refunded part of the held payment to the user before settling. A payment
+	// still held on the user's card is settled from the hold instead: only what they keep
+	// paying is captured, so nothing needs refunding.
+	var refund *model.Refund
+	reason := fmt.Sprintf("Dispute %s resolved: %s", dispute.ID, resolution)
+	switch {
+	case auth != nil && auth.Status == model.AuthorizationAuthorized:
+		charged := min(dispute.PaymentHold.Amount-dispute.RefundAmount, auth.Amount)
+		if charged > 0 {
+			err = s.authorizations.capture(ctx, auth, charged, time.Now())
+		} else {
+			err = s.authorizations.release(ctx, auth, reason, time.Now())
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to settle held payment: %v", err)
+		}
+	case dispute.RefundAmount > 0:
+		order, err := s.orderRepo.GetOrderByID(ctx, dispute.OrderID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+		}
+
+		refundID, err := s.paymentClient.RefundPayment(ctx, order.ID, order.UserID, dispute.RefundAmount, reason, "dispute-"+dispute.ID)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to refund payment: %v", err)
+		}
+
+		refund = &model.Refund{
+			ID:              uuid.New().String(),
+			OrderID:         order.ID,
+			Amount:          dispute.RefundAmount,
+			Reason:          reason,
+			RequestedBy:     req.ResolvedBy,
+			DisputeID:       dispute.ID,
+			PaymentRefundID: refundID,
+			CreatedAt:       time.Now(),
+		}
+	}
+
+	if err := s.repo.ResolveDispute(ctx, dispute, holdStatus, orderStatus, refund); err != nil {
+		if errors.Is(err, repository.ErrDisputeNotOpen) {
+			return nil, status.Errorf(codes.FailedPrecondition, "dispute is already resolved")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to resolve dispute: %v", err)
+	}
+
+	s.blockchainRecorder.Record(ctx, dispute.OrderID)
+
+	updated, err := s.getDispute(ctx, dispute.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.DisputeResponse{
+		Dispute: convertDisputeToProto(updated),
+		Message: "Dispute resolved successfully",
+		Success: true,
+	}, nil
+}
+
+// GetDispute gets a dispute with its evidence and payment hold
+func (s *DisputeService) GetDispute(ctx context.Context, req *pb.GetDisputeRequest) (*pb.DisputeResponse, error) {
+	if req.DisputeId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "dispute ID is required")
+	}
+
+	dispute, err := s.getDispute(ctx, req.DisputeId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.DisputeResponse{
+		Dispute: convertDisputeToProto(dispute),
+		Message: "Dispute retrieved successfully",
+		Success: true,
+	}, nil
+}
+
+// ListDisputes lists disputes, optionally for a single order or status
+func (s *DisputeService) ListDisputes(ctx context.Context, req *pb.ListDisputesRequest) (*pb.ListDisputesResponse, error) {
+	disputeStatus := model.DisputeStatus(req.Status)
+	switch disputeStatus {
+	case "", model.DisputeOpen, model.DisputeResolved:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "status must be OPEN or RESOLVED")
+	}
+
+	disputes, total, err := s.repo.ListDisputes(ctx, req.OrderId, disputeStatus, int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list disputes: %v", err)
+	}
+
+	protoDisputes := []*pb.Dispute{}
+	for _, dispute := range disputes {
+		protoDisputes = append(protoDisputes, convertDisputeToProto(dispute))
+	}
+
+	return &pb.ListDisputesResponse{
+		Disputes: protoDisputes,
+		Total:    int32(total),
+		Page:     req.Page,
+		Limit:    req.Limit,
+	}, nil
+}
+
+func (s *DisputeService) getDispute(ctx context.Context, disputeID string) (*model.Dispute, error) {
+	dispute, err := s.repo.GetDispute(ctx, disputeID)
+	if err != nil {
+		if errors.Is(err, repository.ErrDisputeNotFound) {
+			return nil, status.Errorf(codes.NotFound, "dispute not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get dispute: %v", err)
+	}
+	return dispute, nil
+}
+
+func convertDisputeToProto(dispute *model.Dispute) *pb.Dispute {
+	protoDispute := &pb.Dispute{
+		Id:              dispute.ID,
+		OrderId:         dispute.OrderID,
+		OpenedBy:        dispute.OpenedBy,
+		OpenedByRole:    string(dispute.OpenedByRole),
+		Reason:          dispute.Reason,
+		Description:     dispute.Description,
+		Status:          string(dispute.Status),
+		Resolution:      string(dispute.Resolution),
+		RefundAmount:    dispute.RefundAmount,
+		ResolvedBy:      dispute.ResolvedBy,
+		ResolutionNotes: dispute.ResolutionNotes,
+		CreatedAt:       timestamppb.New(dispute.CreatedAt),
+		UpdatedAt:       timestamppb.New(dispute.UpdatedAt),
+	}
+	if dispute.ResolvedAt != nil {
+		protoDispute.ResolvedAt = timestamppb.New(*dispute.ResolvedAt)
+	}
+
+	for _, e := range dispute.Evidence {
+		protoDispute.Evidence = append(protoDispute.Evidence, &pb.Evidence{
+			Id:            e.ID,
+			DisputeId:     e.DisputeID,
+			SubmittedBy:   e.SubmittedBy,
+			Role:          string(e.Role),
+			Description:   e.Description,
+			AttachmentUrl: e.AttachmentURL,
+			CreatedAt:     timestamppb.New(e.CreatedAt),
+		})
+	}
+
+	if hold := dispute.PaymentHold; hold != nil {
+		protoDispute.PaymentHold = &pb.PaymentHold{
+			Id:             hold.ID,
+			OrderId:        hold.OrderID,
+			Amount:         hold.Amount,
+			RefundedAmount: hold.RefundedAmount,
+			Status:         string(hold.Status),
+			CreatedAt:      timestamppb.New(hold.CreatedAt),
+		}
+		if hold.ReleasedAt != nil {
+			protoDispute.PaymentHold.ReleasedAt = timestamppb.New(*hold.ReleasedAt)
+		}
+	}
+
+	return protoDispute
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DuplicateOrderIDKey is the trailer that names the existing order when CreateOrder
+// refuses an order as a duplicate
+const DuplicateOrderIDKey = "duplicate-order-id"
+
+// DuplicatePolicy refuses an order that repeats one its user has just placed, which is
+// usually a double tap or a retried request rather than a second order
+type DuplicatePolicy struct {
+	Window time.Duration // How long an order blocks an identical one; 0 turns the check off
+}
+
+// since returns the time after which an order placed at now blocks an identical one, or
+// the zero time when the check is off
+func (p DuplicatePolicy) since(now time.Time) time.Time {
+	if p.Window <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-p.Window)
+}
+
+// duplicateOrderError maps an ErrDuplicateOrder from creating an order to AlreadyExists.
+// A repeated order is named in the message and in the DuplicateOrderIDKey trailer, so the
+// client can go on with the order it already has.
+func duplicateOrderError(ctx context.Context, orderID string, err error) error {
+	var duplicate *repository.DuplicateOrderError
+	if !errors.As(err, &duplicate) {
+		return status.Errorf(codes.AlreadyExists, "order %s already exists", orderID)
+	}
+
+	// Fails only when not called through gRPC, as bulk imports do
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(DuplicateOrderIDKey, duplicate.OrderID))
+	return status.Errorf(codes.AlreadyExists, "an identical order %s was placed moments ago", duplicate.OrderID)
+}
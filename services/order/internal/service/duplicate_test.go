@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDuplicatePolicySince(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if since := (DuplicatePolicy{}).since(now); !since.IsZero() {
+		t.Errorf("no window: since = %v, want the zero time", since)
+	}
+	if since := (DuplicatePolicy{Window: -time.Minute}).since(now); !since.IsZero() {
+		t.Errorf("negative window: since = %v, want the zero time", since)
+	}
+	if since := (DuplicatePolicy{Window: 2 * time.Minute}).since(now); !since.Equal(now.Add(-2 * time.Minute)) {
+		t.Errorf("2m window: since = %v, want %v", since, now.Add(-2*time.Minute))
+	}
+}
+
+func TestDuplicateOrderError(t *testing.T) {
+	ctx := context.Background()
+
+	err := duplicateOrderError(ctx, "new-order", &repository.DuplicateOrderError{OrderID: "placed-order"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("repeated order: code = %s, want AlreadyExists", status.Code(err))
+	}
+	if msg := status.Convert(err).Message(); !strings.Contains(msg, "placed-order") {
+		t.Errorf("repeated order: message %q does not name the order already placed", msg)
+	}
+
+	err = duplicateOrderError(ctx, "new-order", repository.ErrDuplicateOrder)
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("taken ID: code = %s, want AlreadyExists", status.Code(err))
+	}
+	if msg := status.Convert(err).Message(); !strings.Contains(msg, "new-order") {
+		t.Errorf("taken ID: message %q does not name the order", msg)
+	}
+}
+
+func TestDuplicateOrderErrorMatchesErrDuplicateOrder(t *testing.T) {
+	var err error = &repository.DuplicateOrderError{OrderID: "placed-order"}
+	if !errors.Is(err, repository.ErrDuplicateOrder) {
+		t.Error("DuplicateOrderError does not match ErrDuplicateOrder")
+	}
+}
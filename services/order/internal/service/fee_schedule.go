@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// FeeSchedule holds the fee rules and waivers in memory so orders can be priced
+// without a database round trip. Admin changes are picked up on the next refresh.
+type FeeSchedule struct {
+	repo     *repository.FeeRepository
+	interval time.Duration
+
+	mu      sync.RWMutex
+	rules   []*model.FeeRule
+	waivers []*model.FeeWaiver
+}
+
+// NewFeeSchedule creates a new fee schedule; call Refresh to load it
+func NewFeeSchedule(repo *repository.FeeRepository, interval time.Duration) *FeeSchedule {
+	return &FeeSchedule{
+		repo:     repo,
+		interval: interval,
+	}
+}
+
+// Refresh reloads the rules and waivers from the database
+func (f *FeeSchedule) Refresh(ctx context.Context) error {
+	rules, err := f.repo.ListRules(ctx)
+	if err != nil {
+		return err
+	}
+	waivers, err := f.repo.ListWaivers(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.rules = rules
+	f.waivers = waivers
+	f.mu.Unlock()
+
+	return nil
+}
+
+// Run refreshes the schedule every interval until ctx is cancelled. A failed refresh
+// keeps the previous schedule.
+func (f *FeeSchedule) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh fee schedule: %v", err)
+			}
+		}
+	}
+}
+
+// Apply sets an order's fees from the most specific matching rule, and waives its
+// platform fee if a promotion covers it
+func (f *FeeSchedule) Apply(order *model.Order) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	city := order.PickupLocation.City
+	rule := model.DefaultFeeRule
+	best := -1
+	for _, candidate := range f.rules {
+		if candidate.Matches(order.OrderType, city) && candidate.Specificity() > best {
+			rule = *candidate
+			best = candidate.Specificity()
+		}
+	}
+	order.CalculateFees(rule)
+
+	for _, waiver := range f.waivers {
+		if waiver.Applies(order.OrderType, city, order.CreatedAt) {
+			order.PlatformFee = 0
+			return
+		}
+	}
+}
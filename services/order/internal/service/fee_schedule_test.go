@@ -0,0 +1,114 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+func feeTestOrder(orderType model.OrderType, city string, total int64, createdAt time.Time) *model.Order {
+	return &model.Order{
+		OrderType:      orderType,
+		PickupLocation: model.Location{City: city},
+		TotalPrice:     total,
+		CreatedAt:      createdAt,
+	}
+}
+
+func TestFeeScheduleAppliesMostSpecificRule(t *testing.T) {
+	schedule := &FeeSchedule{
+		rules: []*model.FeeRule{
+			{OrderType: model.TypeRide, PlatformFeePercent: 12, ProviderFeePercent: 75},
+			{City: "jakarta", PlatformFeePercent: 15, ProviderFeePercent: 70},
+			{OrderType: model.TypeRide, City: "jakarta", PlatformFeePercent: 20, ProviderFeePercent: 65},
+			{PlatformFeePercent: 5, ProviderFeePercent: 90},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		orderType    model.OrderType
+		city         string
+		wantPlatform int64
+		wantProvider int64
+	}{
+		{name: "type and city", orderType: model.TypeRide, city: "Jakarta", wantPlatform: 2000, wantProvider: 6500},
+		{name: "city beats type", orderType: model.TypeFoodDelivery, city: "JAKARTA", wantPlatform: 1500, wantProvider: 7000},
+		{name: "type only", orderType: model.TypeRide, city: "Bandung", wantPlatform: 1200, wantProvider: 7500},
+		{name: "stored default", orderType: model.TypeFoodDelivery, city: "Bandung", wantPlatform: 500, wantProvider: 9000},
+	}
+
+	for _, tt := range tests {
+		order := feeTestOrder(tt.orderType, tt.city, 10000, time.Now())
+		schedule.Apply(order)
+		if order.PlatformFee != tt.wantPlatform || order.ProviderFee != tt.wantProvider {
+			t.Errorf("%s: fees = %d/%d, want %d/%d", tt.name,
+				order.PlatformFee, order.ProviderFee, tt.wantPlatform, tt.wantProvider)
+		}
+	}
+}
+
+func TestFeeScheduleFallsBackToDefaultRule(t *testing.T) {
+	order := feeTestOrder(model.TypeRide, "Jakarta", 10000, time.Now())
+	(&FeeSchedule{}).Apply(order)
+
+	if order.PlatformFee != 1000 || order.ProviderFee != 8000 {
+		t.Errorf("fees = %d/%d, want the default 1000/8000", order.PlatformFee, order.ProviderFee)
+	}
+}
+
+func TestFeeScheduleMinimumPlatformFee(t *testing.T) {
+	schedule := &FeeSchedule{
+		rules: []*model.FeeRule{{PlatformFeePercent: 10, ProviderFeePercent: 80, MinPlatformFee: 300}},
+	}
+
+	small := feeTestOrder(model.TypeRide, "", 1000, time.Now())
+	schedule.Apply(small)
+	if small.PlatformFee != 300 {
+		t.Errorf("platform fee on 10.00 = %d, want the 300 minimum", small.PlatformFee)
+	}
+
+	tiny := feeTestOrder(model.TypeRide, "", 200, time.Now())
+	schedule.Apply(tiny)
+	if tiny.PlatformFee != 200 {
+		t.Errorf("platform fee on 2.00 = %d, want it capped at the 200 total", tiny.PlatformFee)
+	}
+}
+
+func TestFeeScheduleWaivers(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	schedule := &FeeSchedule{
+		waivers: []*model.FeeWaiver{{
+			OrderType: model.TypeFoodDelivery,
+			City:      "jakarta",
+			StartsAt:  start,
+			EndsAt:    start.Add(24 * time.Hour),
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		orderType model.OrderType
+		city      string
+		createdAt time.Time
+		waived    bool
+	}{
+		{name: "covered", orderType: model.TypeFoodDelivery, city: "Jakarta", createdAt: start, waived: true},
+		{name: "other type", orderType: model.TypeRide, city: "Jakarta", createdAt: start.Add(time.Hour)},
+		{name: "other city", orderType: model.TypeFoodDelivery, city: "Bandung", createdAt: start.Add(time.Hour)},
+		{name: "before start", orderType: model.TypeFoodDelivery, city: "Jakarta", createdAt: start.Add(-time.Second)},
+		{name: "at end", orderType: model.TypeFoodDelivery, city: "Jakarta", createdAt: start.Add(24 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		order := feeTestOrder(tt.orderType, tt.city, 10000, tt.createdAt)
+		schedule.Apply(order)
+		if waived := order.PlatformFee == 0; waived != tt.waived {
+			t.Errorf("%s: platform fee = %d, waived %v, want waived %v", tt.name, order.PlatformFee, waived, tt.waived)
+		}
+		if order.ProviderFee != 8000 {
+			t.Errorf("%s: provider fee = %d, want 8000 whether or not waived", tt.name, order.ProviderFee)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/fee"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FeeService lets admins manage the fee rules and waivers applied to new orders
+type FeeService struct {
+	pb.UnimplementedFeeServiceServer
+	repo     *repository.FeeRepository
+	schedule *FeeSchedule
+}
+
+// NewFeeService creates a new fee service
+func NewFeeService(repo *repository.FeeRepository, schedule *FeeSchedule) *FeeService {
+	return &FeeService{
+		repo:     repo,
+		schedule: schedule,
+	}
+}
+
+// ListFeeRules lists every fee rule and the default that applies when none matches
+func (s *FeeService) ListFeeRules(ctx context.Context, req *pb.ListFeeRulesRequest) (*pb.ListFeeRulesResponse, error) {
+	rules, err := s.repo.ListRules(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list fee rules: %v", err)
+	}
+
+	protoRules := []*pb.FeeRule{}
+	for _, rule := range rules {
+		protoRules = append(protoRules, convertFeeRuleToProto(rule))
+	}
+
+	return &pb.ListFeeRulesResponse{
+		Rules:       protoRules,
+		DefaultRule: convertFeeRuleToProto(&model.DefaultFeeRule),
+	}, nil
+}
+
+// CreateFeeRule adds a fee rule for an order type, a city, or both
+func (s *FeeService) CreateFeeRule(ctx context.Context, req *pb.CreateFeeRuleRequest) (*pb.FeeRuleResponse, error) {
+	orderType, err := parseFeeOrderType(req.OrderType)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateFees(req.PlatformFeePercent, req.ProviderFeePercent, req.MinPlatformFee); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule := &model.FeeRule{
+		ID:                 uuid.New().String(),
+		OrderType:          orderType,
+		City:               normalizeCity(req.City),
+		PlatformFeePercent: req.PlatformFeePercent,
+		ProviderFeePercent: req.ProviderFeePercent,
+		MinPlatformFee:     req.MinPlatformFee,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+
+	if err := s.repo.CreateRule(ctx, rule); err != nil {
+		if errors.Is(err, repository.ErrFeeRuleExists) {
+			return nil, status.Errorf(codes.AlreadyExists, "a fee rule already exists for this order type and city")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create fee rule: %v", err)
+	}
+	s.refreshSchedule(ctx)
+
+	return &pb.FeeRuleResponse{
+		Rule:    convertFeeRuleToProto(rule),
+		Message: "Fee rule created successfully",
+		Success: true,
+	}, nil
+}
+
+// UpdateFeeRule changes the fees of a rule
+func (s *FeeService) UpdateFeeRule(ctx context.Context, req *pb.UpdateFeeRuleRequest) (*pb.FeeRuleResponse, error) {
+	if req.RuleId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "rule ID is required")
+	}
+	if err := validateFees(req.PlatformFeePercent, req.ProviderFeePercent, req.MinPlatformFee); err != nil {
+		return nil, err
+	}
+
+	rule, err := s.repo.GetRule(ctx, req.RuleId)
+	if err != nil {
+		if errors.Is(err, repository.ErrFeeRuleNotFound) {
+			return nil, status.Errorf(codes.NotFound, "fee rule not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get fee rule: %v", err)
+	}
+
+	rule.PlatformFeePercent = req.PlatformFeePercent
+	rule.ProviderFeePercent = req.ProviderFeePercent
+	rule.MinPlatformFee = req.MinPlatformFee
+	rule.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateRule(ctx, rule); err != nil {
+		if errors.Is(err, repository.ErrFeeRuleNotFound) {
+			return nil, status.Errorf(codes.NotFound, "fee rule not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update fee rule: %v", err)
+	}
+	s.refreshSchedule(ctx)
+
+	return &pb.FeeRuleResponse{
+		Rule:    convertFeeRuleToProto(rule),
+		Message: "Fee rule updated successfully",
+		Success: true,
+	}, nil
+}
+
+// DeleteFeeRule deletes a fee rule; orders it covered fall back to a broader rule
+func (s *FeeService) DeleteFeeRule(ctx context.Context, req *pb.DeleteFeeRuleRequest) (*pb.DeleteFeeRuleResponse, error) {
+	if req.RuleId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "rule ID is required")
+	}
+
+	if err := s.repo.DeleteRule(ctx, req.RuleId); err != nil {
+		if errors.Is(err, repository.ErrFeeRuleNotFound) {
+			return nil, status.Errorf(codes.NotFound, "fee rule not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete fee rule: %v", err)
+	}
+	s.refreshSchedule(ctx)
+
+	return &pb.DeleteFeeRuleResponse{
+		Message: "Fee rule deleted successfully",
+		Success: true,
+	}, nil
+}
+
+// ListFeeWaivers lists the current and upcoming fee waivers
+func (s *FeeService) ListFeeWaivers(ctx context.Context, req *pb.ListFeeWaiversRequest) (*pb.ListFeeWaiversResponse, error) {
+	waivers, err := s.repo.ListWaivers(ctx, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list fee waivers: %v", err)
+	}
+
+	protoWaivers := []*pb.FeeWaiver{}
+	for _, waiver := range waivers {
+		protoWaivers = append(protoWaivers, convertFeeWaiverToProto(waiver))
+	}
+
+	return &pb.ListFeeWaiversResponse{
+		Waivers: protoWaivers,
+	}, nil
+}
+
+// CreateFeeWaiver adds a promotion that waives the platform fee for a period
+func (s *FeeService) CreateFeeWaiver(ctx context.Context, req *pb.CreateFeeWaiverRequest) (*pb.FeeWaiverResponse, error) {
+	if req.Name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "name is required")
+	}
+	if req.StartsAt == nil || req.EndsAt == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "starts at and ends at are required")
+	}
+	orderType, err := parseFeeOrderType(req.OrderType)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	waiver := &model.FeeWaiver{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		OrderType: orderType,
+		City:      normalizeCity(req.City),
+		StartsAt:  req.StartsAt.AsTime(),
+		EndsAt:    req.EndsAt.AsTime(),
+		CreatedAt: now,
+	}
+	if !waiver.EndsAt.After(waiver.StartsAt) {
+		return nil, status.Errorf(codes.InvalidArgument, "ends at must be after starts at")
+	}
+	if !waiver.EndsAt.After(now) {
+		return nil, status.Errorf(codes.InvalidArgument, "ends at must be in the future")
+	}
+
+	if err := s.repo.CreateWaiver(ctx, waiver); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create fee waiver: %v", err)
+	}
+	s.refreshSchedule(ctx)
+
+	return &pb.FeeWaiverResponse{
+		Waiver:  convertFeeWaiverToProto(waiver),
+		Message: "Fee waiver created successfully",
+		Success: true,
+	}, nil
+}
+
+// DeleteFeeWaiver deletes a fee waiver, ending its promotion
+func (s *FeeService) DeleteFeeWaiver(ctx context.Context, req *pb.DeleteFeeWaiverRequest) (*pb.DeleteFeeWaiverResponse, error) {
+	if req.WaiverId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "waiver ID is required")
+	}
+
+	if err := s.repo.DeleteWaiver(ctx, req.WaiverId); err != nil {
+		if errors.Is(err, repository.ErrFeeWaiverNotFound) {
+			return nil, status.Errorf(codes.NotFound, "fee waiver not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete fee waiver: %v", err)
+	}
+	s.refreshSchedule(ctx)
+
+	return &pb.DeleteFeeWaiverResponse{
+		Message: "Fee waiver deleted successfully",
+		Success: true,
+	}, nil
+}
+
+// refreshSchedule applies a change on this instance immediately; other instances
+// pick it up on their next periodic refresh
+func (s *FeeService) refreshSchedule(ctx context.Context) {
+	if err := s.schedule.Refresh(ctx); err != nil {
+		fmt.Printf("Failed to refresh fee schedule: %v\n", err)
+	}
+}
+
+// parseFeeOrderType validates an order type name; empty matches any order type
+func parseFeeOrderType(orderType string) (model.OrderType, error) {
+	switch t := model.OrderType(orderType); t {
+	case "", model.TypeRide, model.TypeFoodDelivery, model.TypePackageDelivery, model.TypeGroceryDelivery, model.TypeServiceBooking, model.TypeRental, model.TypeReturn:
+		return t, nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "unknown order type %q", orderType)
+	}
+}
+
+// validateFees checks that fee percentages are sane and never add up to more than the order total
+func validateFees(platformPercent, providerPercent float64, minPlatformFee int64) error {
+	if platformPercent < 0 || platformPercent > 100 || providerPercent < 0 || providerPercent > 100 {
+		return status.Errorf(codes.InvalidArgument, "fee percentages must be between 0 and 100")
+	}
+	if platformPercent+providerPercent > 100 {
+		return status.Errorf(codes.InvalidArgument, "platform and provider fees cannot exceed 100%% of the order total")
+	}
+	if minPlatformFee < 0 {
+		return status.Errorf(codes.InvalidArgument, "minimum platform fee cannot be negative")
+	}
+	return nil
+}
+
+func normalizeCity(city string) string {
+	return strings.ToLower(strings.TrimSpace(city))
+}
+
+func convertFeeRuleToProto(rule *model.FeeRule) *pb.FeeRule {
+	protoRule := &pb.FeeRule{
+		Id:                 rule.ID,
+		OrderType:          string(rule.OrderType),
+		City:               rule.City,
+		PlatformFeePercent: rule.PlatformFeePercent,
+		ProviderFeePercent: rule.ProviderFeePercent,
+		MinPlatformFee:     rule.MinPlatformFee,
+	}
+	if !rule.CreatedAt.IsZero() {
+		protoRule.CreatedAt = timestamppb.New(rule.CreatedAt)
+		protoRule.UpdatedAt = timestamppb.New(rule.UpdatedAt)
+	}
+	return protoRule
+}
+
+func convertFeeWaiverToProto(waiver *model.FeeWaiver) *pb.FeeWaiver {
+	return &pb.FeeWaiver{
+		Id:        waiver.ID,
+		Name:      waiver.Name,
+		OrderType: string(waiver.OrderType),
+		City:      waiver.City,
+		StartsAt:  timestamppb.New(waiver.StartsAt),
+		EndsAt:    timestamppb.New(waiver.EndsAt),
+		CreatedAt: timestamppb.New(waiver.CreatedAt),
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"math"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// haversineKm returns the great-circle distance between two points in kilometers
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// distanceToSegmentKm returns the distance in kilometers from a point to the straight
+// segment between a and b. The segment is projected onto a plane around the point,
+// which is accurate enough over the length of an order's route.
+func distanceToSegmentKm(lat, lon float64, a, b model.Location) float64 {
+	kmPerDegLat := earthRadiusKm * math.Pi / 180
+	kmPerDegLon := kmPerDegLat * math.Cos(toRadians(lat))
+
+	// Place the point at the origin
+	ax, ay := (a.Longitude-lon)*kmPerDegLon, (a.Latitude-lat)*kmPerDegLat
+	bx, by := (b.Longitude-lon)*kmPerDegLon, (b.Latitude-lat)*kmPerDegLat
+
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lengthSq))
+	}
+
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// bearingDegrees returns the initial compass bearing from one point to another, in
+// degrees clockwise from north in [0, 360)
+func bearingDegrees(from, to model.Location) float64 {
+	lat1, lat2 := toRadians(from.Latitude), toRadians(to.Latitude)
+	dLon := toRadians(to.Longitude - from.Longitude)
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// bearingDifference returns the smaller angle between two bearings, in [0, 180]
+func bearingDifference(a, b float64) float64 {
+	diff := math.Abs(math.Mod(a-b, 360))
+	if diff > 180 {
+		diff = 360 - diff
+	}
+	return diff
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// GeofencePolicy sets the radius around an order's pickup and destination inside which a
+// provider counts as having arrived. A radius of zero turns that geofence off.
+type GeofencePolicy struct {
+	PickupRadiusKm      float64
+	DestinationRadiusKm float64
+}
+
+// applyGeofences acts on a provider location update. Entering the pickup geofence on the
+// way to pickup lets the user know; entering the destination geofence in transit moves the
+// order to ARRIVED. order is updated to match.
+func (s *OrderService) applyGeofences(ctx context.Context, order *model.Order, location *model.OrderLocation) {
+	switch order.Status {
+	case model.StatusProviderAccepted, model.StatusInProgress:
+		if !withinRadius(location, order.PickupLocation, s.geofencePolicy.PickupRadiusKm) {
+			return
+		}
+		marked, err := s.repo.MarkPickupArrival(ctx, order.ID, location.Timestamp)
+		if err != nil {
+			fmt.Printf("Failed to record pickup arrival for order %s: %v\n", order.ID, err)
+			return
+		}
+		if !marked {
+			return
+		}
+		go s.notifyGeofenceUser(order, "PROVIDER_AT_PICKUP", "Your provider is here",
+			fmt.Sprintf("Your provider has arrived at the pickup location of order %s", order.ID))
+
+	case model.StatusPickedUp, model.StatusInTransit:
+		if !withinRadius(location, order.DestinationLocation, s.geofencePolicy.DestinationRadiusKm) {
+			return
+		}
+		updated, err := s.repo.UpdateOrderStatusFrom(ctx, order.ID, order.Status, model.StatusArrived,
+			location.ProviderID, "Provider entered the destination geofence")
+		if err != nil {
+			if !errors.Is(err, repository.ErrOrderFrozen) {
+				fmt.Printf("Failed to mark order %s as arrived: %v\n", order.ID, err)
+			}
+			return
+		}
+		if !updated {
+			return
+		}
+		order.AddStatusHistory(model.StatusArrived, location.ProviderID, "Provider entered the destination geofence")
+
+		s.blockchainRecorder.Record(ctx, order.ID)
+		go s.notifyGeofenceUser(order, "PROVIDER_ARRIVED", "Your provider has arrived",
+			fmt.Sprintf("Your provider has arrived at the destination of order %s", order.ID))
+	}
+}
+
+func (s *OrderService) notifyGeofenceUser(order *model.Order, notificationType, title, message string) {
+	err := s.notificationClient.SendNotification(context.Background(), order.UserID, "USER", notificationType, title, message,
+		map[string]interface{}{
+			"order_id":    order.ID,
+			"provider_id": order.ProviderID,
+			"arrived_at":  time.Now(),
+		})
+	if err != nil {
+		fmt.Printf("Failed to notify user of provider arrival: %v\n", err)
+	}
+}
+
+// withinRadius reports whether a location is within radiusKm of target; a zero radius never matches
+func withinRadius(location *model.OrderLocation, target model.Location, radiusKm float64) bool {
+	if radiusKm <= 0 {
+		return false
+	}
+	return haversineKm(location.Latitude, location.Longitude, target.Latitude, target.Longitude) <= radiusKm
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/incident"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const maxIncidentDescriptionLength = 2000
+
+// errOrderFrozen is returned when an order's state is changed while a safety incident is open
+var errOrderFrozen = status.Errorf(codes.FailedPrecondition, "order is frozen while a safety incident is under review")
+
+// checkOrderNotFrozen rejects changes to an order held by an open safety incident
+func checkOrderNotFrozen(order *model.Order) error {
+	if order.Frozen {
+		return errOrderFrozen
+	}
+	return nil
+}
+
+// IncidentService records SOS reports raised during orders and alerts safety staff and
+// the reporter's emergency contacts
+type IncidentService struct {
+	pb.UnimplementedIncidentServiceServer
+	repo               *repository.IncidentRepository
+	orderRepo          *repository.OrderRepository
+	notificationClient NotificationClient
+	adminChannel       string
+}
+
+// NewIncidentService creates a new incident service. SOS alerts for safety staff are sent to
+// adminChannel.
+func NewIncidentService(repo *repository.IncidentRepository, orderRepo *repository.OrderRepository, notificationClient NotificationClient, adminChannel string) *IncidentService {
+	return &IncidentService{
+		repo:               repo,
+		orderRepo:          orderRepo,
+		notificationClient: notificationClient,
+		adminChannel:       adminChannel,
+	}
+}
+
+// ReportIncident records an SOS from a party to an order, freezes the order for review and
+// alerts the admin channel and the reporter's emergency contacts
+func (s *IncidentService) ReportIncident(ctx context.Context, req *pb.ReportIncidentRequest) (*pb.IncidentResponse, error) {
+	if req.OrderId == "" || req.ReportedBy == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order ID and reported by are required")
+	}
+	if len(req.Description) > maxIncidentDescriptionLength {
+		return nil, status.Errorf(codes.InvalidArgument, "description must be at most %d characters", maxIncidentDescriptionLength)
+	}
+	if loc := req.Location; loc != nil && (loc.Latitude < -90 || loc.Latitude > 90 || loc.Longitude < -180 || loc.Longitude > 180) {
+		return nil, status.Errorf(codes.InvalidArgument, "location is out of range")
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	role, ok := order.RoleOf(req.ReportedBy)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "only the order's user and provider can report an incident")
+	}
+
+	now := time.Now()
+	incident := &model.Incident{
+		ID:           uuid.New().String(),
+		OrderID:      order.ID,
+		ReportedBy:   req.ReportedBy,
+		ReporterRole: role,
+		Description:  req.Description,
+		Location:     s.incidentLocation(ctx, order.ID, req.Location),
+		Status:       model.IncidentOpen,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := s.repo.CreateIncident(ctx, incident); err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to report incident: %v", err)
+	}
+
+	s.sendAlerts(incident)
+
+	return &pb.IncidentResponse{
+		Incident: convertIncidentToProto(incident),
+		Success:  true,
+		Message:  "Incident reported; safety staff have been alerted",
+	}, nil
+}
+
+// GetIncident gets an incident by its ID
+func (s *IncidentService) GetIncident(ctx context.Context, req *pb.GetIncidentRequest) (*pb.IncidentResponse, error) {
+	if req.IncidentId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "incident ID is required")
+	}
+
+	incident, err := s.getIncident(ctx, req.IncidentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.IncidentResponse{
+		Incident: convertIncidentToProto(incident),
+		Success:  true,
+	}, nil
+}
+
+// ListIncidents lists incidents, newest first, optionally filtered by order and status
+func (s *IncidentService) ListIncidents(ctx context.Context, req *pb.ListIncidentsRequest) (*pb.ListIncidentsResponse, error) {
+	incidentStatus := model.IncidentStatus(req.Status)
+	switch incidentStatus {
+	case "", model.IncidentOpen, model.IncidentResolved:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "status must be OPEN or RESOLVED")
+	}
+
+	incidents, total, err := s.repo.ListIncidents(ctx, req.OrderId, incidentStatus, int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list incidents: %v", err)
+	}
+
+	protoIncidents := []*pb.Incident{}
+	for _, incident := range incidents {
+		protoIncidents = append(protoIncidents, convertIncidentToProto(incident))
+	}
+
+	return &pb.ListIncidentsResponse{
+		Incidents: protoIncidents,
+		Total:     int32(total),
+		Page:      req.Page,
+		Limit:     req.Limit,
+	}, nil
+}
+
+// ResolveIncident closes an open incident after review. Its order is unfrozen once none of
+// its incidents remain open.
+func (s *IncidentService) ResolveIncident(ctx context.Context, req *pb.ResolveIncidentRequest) (*pb.IncidentResponse, error) {
+	if req.IncidentId == "" || req.ResolvedBy == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "incident ID and resolved by are required")
+	}
+
+	err := s.repo.ResolveIncident(ctx, req.IncidentId, req.ResolvedBy, req.Notes)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrIncidentNotFound):
+			return nil, status.Errorf(codes.NotFound, "incident not found")
+		case errors.Is(err, repository.ErrIncidentNotOpen):
+			return nil, status.Errorf(codes.FailedPrecondition, "incident is already resolved")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to resolve incident: %v", err)
+	}
+
+	incident, err := s.getIncident(ctx, req.IncidentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.IncidentResponse{
+		Incident: convertIncidentToProto(incident),
+		Success:  true,
+		Message:  "Incident resolved",
+	}, nil
+}
+
+// incidentLocation uses the location sent with the report, falling back to the provider's
+// last tracked location for the order. It returns nil when neither is known.
+func (s *IncidentService) incidentLocation(ctx context.Context, orderID string, reported *pb.Location) *model.Location {
+	if reported != nil {
+		return &model.Location{
+			Latitude:  reported.Latitude,
+			Longitude: reported.Longitude,
+			Address:   reported.Address,
+		}
+	}
+
+	latest, err := s.orderRepo.GetLatestOrderLocation(ctx, orderID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrOrderNotFound) {
+			fmt.Printf("Failed to get latest location for incident on order %s: %v\n", orderID, err)
+		}
+		return nil
+	}
+	return &model.Location{
+		Latitude:  latest.Latitude,
+		Longitude: latest.Longitude,
+	}
+}
+
+// sendAlerts notifies the admin channel and the reporter's emergency contacts of an incident.
+// The notification service fans the EMERGENCY_CONTACTS alert out to the contacts the
+// reporter registered with it.
+func (s *IncidentService) sendAlerts(incident *model.Incident) {
+	payload := map[string]interface{}{
+		"incident_id":   incident.ID,
+		"order_id":      incident.OrderID,
+		"reported_by":   incident.ReportedBy,
+		"reporter_role": string(incident.ReporterRole),
+		"description":   incident.Description,
+		"reported_at":   incident.CreatedAt,
+	}
+	if incident.Location != nil {
+		payload["latitude"] = incident.Location.Latitude
+		payload["longitude"] = incident.Location.Longitude
+		payload["address"] = incident.Location.Address
+	}
+
+	go func() {
+		err := s.notificationClient.SendNotification(context.Background(), s.adminChannel, "ADMIN", "SOS", "SOS reported",
+			fmt.Sprintf("SOS reported by the %s of order %s", roleName(incident.ReporterRole), incident.OrderID),
+			payload)
+		if err != nil {
+			fmt.Printf("Failed to alert admin channel of incident %s: %v\n", incident.ID, err)
+		}
+	}()
+
+	go func() {
+		err := s.notificationClient.SendNotification(context.Background(), incident.ReportedBy, "EMERGENCY_CONTACTS", "SOS", "SOS alert",
+			"Your contact has raised an SOS during an order; safety staff have been alerted",
+			payload)
+		if err != nil {
+			fmt.Printf("Failed to alert emergency contacts of incident %s: %v\n", incident.ID, err)
+		}
+	}()
+}
+
+func (s *IncidentService) getIncident(ctx context.Context, incidentID string) (*model.Incident, error) {
+	incident, err := s.repo.GetIncident(ctx, incidentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrIncidentNotFound) {
+			return nil, status.Errorf(codes.NotFound, "incident not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get incident: %v", err)
+	}
+	return incident, nil
+}
+
+// roleName returns a lower-case name for a party role, for use in messages
+func roleName(role model.PartyRole) string {
+	if role == model.PartyProvider {
+		return "provider"
+	}
+	return "user"
+}
+
+func convertIncidentToProto(incident *model.Incident) *pb.Incident {
+	protoIncident := &pb.Incident{
+		Id:              incident.ID,
+		OrderId:         incident.OrderID,
+		ReportedBy:      incident.ReportedBy,
+		ReporterRole:    string(incident.ReporterRole),
+		Description:     incident.Description,
+		Status:          string(incident.Status),
+		ResolvedBy:      incident.ResolvedBy,
+		ResolutionNotes: incident.ResolutionNotes,
+		CreatedAt:       timestamppb.New(incident.CreatedAt),
+	}
+	if incident.Location != nil {
+		protoIncident.Location = &pb.Location{
+			Latitude:  incident.Location.Latitude,
+			Longitude: incident.Location.Longitude,
+			Address:   incident.Location.Address,
+		}
+	}
+	if incident.ResolvedAt != nil {
+		protoIncident.ResolvedAt = timestamppb.New(*incident.ResolvedAt)
+	}
+	return protoIncident
+}
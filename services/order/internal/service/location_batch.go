@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxLocationClockSkew is how far in the future a device timestamp can be
+const maxLocationClockSkew = time.Minute
+
+// LocationSamplingPolicy controls how batched provider locations are downsampled. A point
+// is stored when the provider has moved at least MinDistanceKm or MinInterval has passed
+// since the last stored point. The newest point of a batch is always stored so tracking
+// stays current, unless a stored point is already as new, as when a batch is retried.
+type LocationSamplingPolicy struct {
+	MinDistanceKm float64
+	MinInterval   time.Duration
+	MaxBatchSize  int
+}
+
+// BatchUpdateLocation stores a batch of provider locations recorded by the device,
+// downsampled, in one bulk insert. Geofences and the ETA use the newest point.
+func (s *OrderService) BatchUpdateLocation(ctx context.Context, req *pb.BatchUpdateLocationRequest) (*pb.BatchUpdateLocationResponse, error) {
+	if len(req.Points) > s.samplingPolicy.MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "a batch can hold at most %d points", s.samplingPolicy.MaxBatchSize)
+	}
+
+	points, err := validateLocationPoints(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// Get current order
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	// Verify the provider is assigned to this order
+	if order.ProviderID != req.ProviderId {
+		return nil, status.Errorf(codes.PermissionDenied, "provider is not assigned to this order")
+	}
+
+	// Downsample against the last stored point so consecutive batches thin out too
+	last, err := s.locationRepo.GetLatestOrderLocation(ctx, req.OrderId)
+	if err != nil && !errors.Is(err, repository.ErrOrderLocationNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get latest location: %v", err)
+	}
+	kept := s.samplingPolicy.downsample(last, points)
+
+	if _, err := s.locationRepo.CreateOrderLocations(ctx, kept); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to store locations: %v", err)
+	}
+	if len(kept) > 0 {
+		s.publishLocation(ctx, kept[len(kept)-1])
+	}
+
+	latest := points[len(points)-1]
+	s.applyGeofences(ctx, order, latest)
+
+	return &pb.BatchUpdateLocationResponse{
+		Success:                 true,
+		Message:                 "Locations updated successfully",
+		Received:                int32(len(points)),
+		Stored:                  int32(len(kept)),
+		EstimatedArrivalMinutes: estimateOrderArrivalMinutes(order, latest),
+	}, nil
+}
+
+// validateLocationPoints checks a batch's points and converts them, oldest first
+func validateLocationPoints(req *pb.BatchUpdateLocationRequest, now time.Time) ([]*model.OrderLocation, error) {
+	points := make([]*model.OrderLocation, 0, len(req.Points))
+	for i, point := range req.Points {
+		recordedAt := point.RecordedAt.AsTime()
+		if recordedAt.After(now.Add(maxLocationClockSkew)) {
+			return nil, status.Errorf(codes.InvalidArgument, "point %d is recorded in the future", i)
+		}
+		if i > 0 && recordedAt.Before(points[i-1].Timestamp) {
+			return nil, status.Errorf(codes.InvalidArgument, "points must be ordered oldest first")
+		}
+
+		points = append(points, &model.OrderLocation{
+			OrderID:    req.OrderId,
+			ProviderID: req.ProviderId,
+			Latitude:   point.Location.Latitude,
+			Longitude:  point.Location.Longitude,
+			Timestamp:  recordedAt,
+		})
+	}
+	return points, nil
+}
+
+// downsample returns the points worth storing after last, the most recently stored point,
+// which may be nil
+func (p LocationSamplingPolicy) downsample(last *model.OrderLocation, points []*model.OrderLocation) []*model.OrderLocation {
+	kept := []*model.OrderLocation{}
+	for i, point := range points {
+		if last != nil && !point.Timestamp.After(last.Timestamp) {
+			// Already covered by a stored point
+			continue
+		}
+		if last == nil || i == len(points)-1 ||
+			haversineKm(last.Latitude, last.Longitude, point.Latitude, point.Longitude) >= p.MinDistanceKm ||
+			point.Timestamp.Sub(last.Timestamp) >= p.MinInterval {
+			kept = append(kept, point)
+			last = point
+		}
+	}
+	return kept
+}
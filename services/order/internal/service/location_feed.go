@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// trackOrderPollInterval is how often a TrackOrder stream reads the latest location, in
+// case the fan-out lost one
+const trackOrderPollInterval = 30 * time.Second
+
+// locationTopic is the fan-out topic of an order's stored provider locations
+func locationTopic(orderID string) string {
+	return "order-location:" + orderID
+}
+
+// publishLocation tells the order's TrackOrder streams on every instance about a stored
+// location. A location that cannot be published still reaches them by polling.
+func (s *OrderService) publishLocation(ctx context.Context, location *model.OrderLocation) {
+	message, err := json.Marshal(location)
+	if err != nil {
+		fmt.Printf("Failed to encode location update: %v\n", err)
+		return
+	}
+	if err := s.locationBus.Publish(ctx, locationTopic(location.OrderID), message); err != nil {
+		fmt.Printf("Failed to publish location update of order %s: %v\n", location.OrderID, err)
+	}
+}
+
+// locationTracker sends an order's locations down a TrackOrder stream, each once and
+// never one older than a location already sent. A location is sent again when the order's
+// destination moves, so the stream gets the new ETA.
+type locationTracker struct {
+	service     *OrderService
+	stream      pb.OrderService_TrackOrderServer
+	orderID     string
+	last        *model.OrderLocation
+	destination model.Location // The order's destination when last was sent
+}
+
+// poll sends the order's latest stored location
+func (t *locationTracker) poll(ctx context.Context) error {
+	location, err := t.service.locationRepo.GetLatestOrderLocation(ctx, t.orderID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrOrderLocationNotFound) {
+			fmt.Printf("Error getting latest location: %v\n", err)
+		}
+		return nil
+	}
+	return t.send(ctx, location)
+}
+
+// send sends a location with the order's current status, unless it was sent already
+// to the same destination
+func (t *locationTracker) send(ctx context.Context, location *model.OrderLocation) error {
+	if t.last != nil && location.Timestamp.Before(t.last.Timestamp) {
+		return nil
+	}
+
+	currentOrder, err := t.service.repo.GetOrderByID(ctx, t.orderID)
+	if err != nil {
+		fmt.Printf("Error getting current order: %v\n", err)
+		return nil
+	}
+
+	destination := currentOrder.DestinationLocation
+	if t.last != nil && location.ID == t.last.ID &&
+		destination.Latitude == t.destination.Latitude && destination.Longitude == t.destination.Longitude {
+		return nil
+	}
+
+	if err := t.stream.Send(buildLocationUpdate(currentOrder, location)); err != nil {
+		return status.Errorf(codes.Internal, "failed to send update: %v", err)
+	}
+	t.last = location
+	t.destination = destination
+	return nil
+}
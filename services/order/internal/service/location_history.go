@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/order-api-microservices/pkg/polyline"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultLocationHistoryLimit = 500
+	maxLocationHistoryLimit     = 5000
+)
+
+// GetLocationHistory returns the path an order's provider travelled. Once the order's
+// locations are archived the whole path comes from its track, without per-point times.
+func (s *OrderService) GetLocationHistory(ctx context.Context, req *pb.GetLocationHistoryRequest) (*pb.LocationHistoryResponse, error) {
+	limit := int(req.Limit)
+	if limit < 1 || limit > maxLocationHistoryLimit {
+		limit = defaultLocationHistoryLimit
+	}
+
+	if _, err := s.repo.GetOrderByID(ctx, req.OrderId); err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	track, err := s.locationRepo.GetTrack(ctx, req.OrderId)
+	if err == nil {
+		path, err := polyline.Decode(track.Polyline)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to decode order track: %v", err)
+		}
+
+		resp := &pb.LocationHistoryResponse{
+			OrderId:   req.OrderId,
+			Polyline:  track.Polyline,
+			Archived:  true,
+			StartedAt: timestamppb.New(track.StartedAt),
+			EndedAt:   timestamppb.New(track.EndedAt),
+		}
+		for _, point := range path {
+			resp.Points = append(resp.Points, &pb.LocationPoint{
+				Location: &pb.Location{Latitude: point.Latitude, Longitude: point.Longitude},
+			})
+		}
+		return resp, nil
+	}
+	if !errors.Is(err, repository.ErrOrderTrackNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get order track: %v", err)
+	}
+
+	// History is newest first
+	history, err := s.locationRepo.GetOrderLocationHistory(ctx, req.OrderId, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get location history: %v", err)
+	}
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+
+	resp := &pb.LocationHistoryResponse{
+		OrderId:  req.OrderId,
+		Polyline: polyline.Encode(toPolylinePoints(history)),
+	}
+	for _, location := range history {
+		resp.Points = append(resp.Points, &pb.LocationPoint{
+			Location:   &pb.Location{Latitude: location.Latitude, Longitude: location.Longitude},
+			RecordedAt: timestamppb.New(location.Timestamp),
+		})
+	}
+	if len(history) > 0 {
+		resp.StartedAt = timestamppb.New(history[0].Timestamp)
+		resp.EndedAt = timestamppb.New(history[len(history)-1].Timestamp)
+	}
+	return resp, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/pkg/polyline"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// finishedStatuses are the statuses after which an order records no more locations
+var finishedStatuses = []model.OrderStatus{
+	model.StatusDelivered,
+	model.StatusCompleted,
+	model.StatusCancelled,
+	model.StatusRefunded,
+	model.StatusDisputed,
+}
+
+// locationPartitionsAhead is how many months past the current one have their location
+// partition created in advance
+const locationPartitionsAhead = 2
+
+// LocationRetentionConfig controls how long raw provider locations are kept
+type LocationRetentionConfig struct {
+	Retention time.Duration // Raw locations of archived orders older than this are deleted
+	Interval  time.Duration // How often finished orders are archived and old locations deleted
+	BatchSize int           // Most orders archived per run
+}
+
+// LocationRetention compresses the location history of finished orders into tracks and
+// deletes their raw locations once they pass the retention period
+type LocationRetention struct {
+	locationRepo *repository.OrderLocationRepository
+	cfg          LocationRetentionConfig
+}
+
+// NewLocationRetention creates a new location retention job
+func NewLocationRetention(locationRepo *repository.OrderLocationRepository, cfg LocationRetentionConfig) *LocationRetention {
+	return &LocationRetention{
+		locationRepo: locationRepo,
+		cfg:          cfg,
+	}
+}
+
+// Run archives and prunes locations every interval until ctx is cancelled. Each run also
+// creates the location partitions of the coming months and drops expired ones.
+func (r *LocationRetention) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.locationRepo.CreateLocationPartitions(ctx, time.Now(), locationPartitionsAhead+1); err != nil {
+				log.Printf("Failed to create location partitions: %v", err)
+			}
+
+			orderIDs, err := r.locationRepo.ListOrdersToArchive(ctx, finishedStatuses, r.cfg.BatchSize)
+			if err != nil {
+				log.Printf("Failed to list orders to archive: %v", err)
+				continue
+			}
+			for _, orderID := range orderIDs {
+				if err := r.Archive(ctx, orderID); err != nil {
+					log.Printf("Failed to archive locations of order %s: %v", orderID, err)
+				}
+			}
+
+			cutoff := time.Now().Add(-r.cfg.Retention)
+			dropped, err := r.locationRepo.DropExpiredLocationPartitions(ctx, cutoff)
+			for _, name := range dropped {
+				log.Printf("Dropped expired location partition %s", name)
+			}
+			if err != nil {
+				log.Printf("Failed to drop expired location partitions: %v", err)
+			}
+
+			deleted, err := r.locationRepo.DeleteArchivedLocations(ctx, cutoff)
+			if err != nil {
+				log.Printf("Failed to delete archived locations: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Deleted %d archived order locations", deleted)
+			}
+		}
+	}
+}
+
+// Archive compresses an order's locations into its track. The raw locations are kept
+// until they pass the retention period.
+func (r *LocationRetention) Archive(ctx context.Context, orderID string) error {
+	locations, err := r.locationRepo.ListOrderLocations(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if len(locations) == 0 {
+		return nil
+	}
+
+	return r.locationRepo.CreateTrack(ctx, buildOrderTrack(orderID, locations))
+}
+
+// buildOrderTrack compresses locations, oldest first, into a track
+func buildOrderTrack(orderID string, locations []*model.OrderLocation) *model.OrderTrack {
+	return &model.OrderTrack{
+		OrderID:    orderID,
+		ProviderID: locations[len(locations)-1].ProviderID,
+		Polyline:   polyline.Encode(toPolylinePoints(locations)),
+		PointCount: len(locations),
+		StartedAt:  locations[0].Timestamp,
+		EndedAt:    locations[len(locations)-1].Timestamp,
+		ArchivedAt: time.Now(),
+	}
+}
+
+func toPolylinePoints(locations []*model.OrderLocation) []polyline.Point {
+	points := make([]polyline.Point, len(locations))
+	for i, location := range locations {
+		points[i] = polyline.Point{Latitude: location.Latitude, Longitude: location.Longitude}
+	}
+	return points
+}
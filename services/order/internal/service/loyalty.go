@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LoyaltyRepository holds referral codes, referrals and loyalty points. It is implemented
+// by repository.LoyaltyRepository on Postgres and by memory.LoyaltyRepository for tests.
+type LoyaltyRepository interface {
+	// CreateReferralCode stores a user's referral code, failing with
+	// repository.ErrReferralCodeTaken if the user or the code already has one
+	CreateReferralCode(ctx context.Context, code *model.ReferralCode) error
+	GetReferralCode(ctx context.Context, userID string) (*model.ReferralCode, error)
+	GetReferralCodeByCode(ctx context.Context, code string) (*model.ReferralCode, error)
+	// CreateReferral stores who referred a user, failing with repository.ErrAlreadyReferred
+	// if they were referred before and with repository.ErrReferralDeviceUsed if their device
+	// was used for another referral
+	CreateReferral(ctx context.Context, referral *model.Referral) error
+	GetReferral(ctx context.Context, referredUserID string) (*model.Referral, error)
+	// SettleReferral moves a pending referral to referral.Status together with applying its
+	// rewards, and reports whether it was still pending. Rewarding a referral whose payment
+	// fingerprint already rewarded another fails with repository.ErrReferralFingerprintUsed.
+	SettleReferral(ctx context.Context, referral *model.Referral, rewards []*model.PointsTransaction) (bool, error)
+	// ApplyPointsTransaction changes a user's points balance by txn.Points and records the
+	// change, setting txn.BalanceAfter. Redeeming more than the balance fails with
+	// repository.ErrInsufficientPoints. A transaction whose reference the user already has
+	// is not applied again; txn is set to the one applied before.
+	ApplyPointsTransaction(ctx context.Context, txn *model.PointsTransaction) error
+	GetPointsAccount(ctx context.Context, userID string) (*model.PointsAccount, error)
+	ListPointsTransactions(ctx context.Context, userID string, page, limit int) ([]*model.PointsTransaction, int, error)
+}
+
+// LoyaltyPolicy sets what referrals earn and what points are worth
+type LoyaltyPolicy struct {
+	ReferrerPoints int64 // Earned by a referrer when the user they referred completes a first order
+	ReferredPoints int64 // Earned by the referred user then
+	PointValue     int64 // Minor units taken off an order's total per point redeemed
+}
+
+// Validate checks the loyalty policy
+func (p LoyaltyPolicy) Validate() error {
+	if p.ReferrerPoints < 0 || p.ReferredPoints < 0 {
+		return fmt.Errorf("referral rewards must not be negative")
+	}
+	if p.PointValue <= 0 {
+		return fmt.Errorf("point value must be positive, not %d", p.PointValue)
+	}
+	return nil
+}
+
+// Loyalty redeems the loyalty points of new orders and rewards referrals once a referred
+// user's first order completes. Referrals are not rewarded when that order is paid with
+// the referrer's own card, or with a card that already earned another referral its
+// rewards, so one person cannot refer themselves over and over.
+type Loyalty struct {
+	repo    LoyaltyRepository
+	methods PaymentMethodRepository
+	policy  LoyaltyPolicy
+}
+
+// NewLoyalty creates a new loyalty program
+func NewLoyalty(repo LoyaltyRepository, methods PaymentMethodRepository, policy LoyaltyPolicy) *Loyalty {
+	return &Loyalty{
+		repo:    repo,
+		methods: methods,
+		policy:  policy,
+	}
+}
+
+// redeem takes the points a new order redeems, at most what its total is worth, and takes
+// their value off the total. The order's fees were worked out on the full total, so the
+// platform bears the discount. If the order is then not placed, returnRedemption gives
+// the points back.
+func (l *Loyalty) redeem(ctx context.Context, order *model.Order, requested int64) error {
+	if requested == 0 {
+		return nil
+	}
+	if requested < 0 {
+		return status.Errorf(codes.InvalidArgument, "points to redeem cannot be negative")
+	}
+
+	points := min(requested, order.TotalPrice/l.policy.PointValue)
+	if points == 0 {
+		return nil
+	}
+
+	txn := &model.PointsTransaction{
+		ID:          uuid.New().String(),
+		UserID:      order.UserID,
+		Type:        model.PointsOrderRedemption,
+		Points:      -points,
+		OrderID:     order.ID,
+		Reference:   "order-" + order.ID,
+		Description: fmt.Sprintf("Redeemed on order %s", order.ID),
+		CreatedAt:   time.Now(),
+	}
+	if err := l.repo.ApplyPointsTransaction(ctx, txn); err != nil {
+		if errors.Is(err, repository.ErrInsufficientPoints) {
+			return status.Errorf(codes.FailedPrecondition, "user has fewer than %d loyalty points", points)
+		}
+		return status.Errorf(codes.Internal, "failed to redeem loyalty points: %v", err)
+	}
+	order.PointsRedeemed = points
+	order.PointsDiscount = points * l.policy.PointValue
+	order.TotalPrice -= order.PointsDiscount
+
+	return nil
+}
+
+// returnRedemption gives back the points an order redeemed, as the order was cancelled
+// or not placed. The reference makes a retried return happen once. Failures are logged.
+func (l *Loyalty) returnRedemption(ctx context.Context, order *model.Order, reference, description string) {
+	if order.PointsRedeemed == 0 {
+		return
+	}
+	err := l.repo.ApplyPointsTransaction(ctx, &model.PointsTransaction{
+		ID:          uuid.New().String(),
+		UserID:      order.UserID,
+		Type:        model.PointsRedemptionReturned,
+		Points:      order.PointsRedeemed,
+		OrderID:     order.ID,
+		Reference:   reference,
+		Description: description,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		fmt.Printf("Failed to return %d loyalty points of order %s: %v\n", order.PointsRedeemed, order.ID, err)
+	}
+}
+
+// rewardReferral settles the referral of the user whose order just completed, if it is
+// still pending: both users earn their points, unless the order was paid with a card the
+// anti-abuse checks turn down.
+func (l *Loyalty) rewardReferral(ctx context.Context, order *model.Order) error {
+	referral, err := l.repo.GetReferral(ctx, order.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrReferralNotFound) {
+			return nil
+		}
+		return err
+	}
+	if referral.Status != model.ReferralPending {
+		return nil
+	}
+
+	now := time.Now()
+	referral.OrderID = order.ID
+	referral.SettledAt = &now
+	if referral.PaymentFingerprint, err = l.paymentFingerprint(ctx, order); err != nil {
+		return err
+	}
+
+	reason, err := l.checkReferral(ctx, referral)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return l.rejectReferral(ctx, referral, reason)
+	}
+
+	referral.Status = model.ReferralRewarded
+	_, err = l.repo.SettleReferral(ctx, referral, l.referralRewards(referral, now))
+	if errors.Is(err, repository.ErrReferralFingerprintUsed) {
+		return l.rejectReferral(ctx, referral, "the card paying for the first order already earned a referral")
+	}
+	return err
+}
+
+// checkReferral returns why a referral must not be rewarded, or "" if it can be
+func (l *Loyalty) checkReferral(ctx context.Context, referral *model.Referral) (string, error) {
+	if referral.PaymentFingerprint == "" {
+		return "", nil
+	}
+	methods, err := l.methods.ListPaymentMethods(ctx, referral.ReferrerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list referrer's payment methods: %w", err)
+	}
+	for _, method := range methods {
+		if method.Fingerprint == referral.PaymentFingerprint {
+			return "the first order was paid with the referrer's card", nil
+		}
+	}
+	return "", nil
+}
+
+// paymentFingerprint is the gateway fingerprint of the saved card an order was paid with,
+// or "" if it was paid otherwise
+func (l *Loyalty) paymentFingerprint(ctx context.Context, order *model.Order) (string, error) {
+	if order.PaymentMethodID == "" {
+		return "", nil
+	}
+	method, err := l.methods.GetPaymentMethod(ctx, order.UserID, order.PaymentMethodID)
+	if err != nil {
+		// A method deleted since the order was placed can no longer be checked
+		if errors.Is(err, repository.ErrPaymentMethodNotFound) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get payment method: %w", err)
+	}
+	return method.Fingerprint, nil
+}
+
+func (l *Loyalty) rejectReferral(ctx context.Context, referral *model.Referral, reason string) error {
+	referral.Status = model.ReferralRejected
+	referral.RejectReason = reason
+	_, err := l.repo.SettleReferral(ctx, referral, nil)
+	return err
+}
+
+// referralRewards are the points a rewarded referral earns the two users. Each user is
+// referred once, so the referred user's ID makes both rewards unique.
+func (l *Loyalty) referralRewards(referral *model.Referral, at time.Time) []*model.PointsTransaction {
+	var rewards []*model.PointsTransaction
+	reward := func(userID string, points int64, description string) {
+		if points == 0 {
+			return
+		}
+		rewards = append(rewards, &model.PointsTransaction{
+			ID:          uuid.New().String(),
+			UserID:      userID,
+			Type:        model.PointsReferralReward,
+			Points:      points,
+			OrderID:     referral.OrderID,
+			Reference:   "referral-" + referral.ReferredUserID,
+			Description: description,
+			CreatedAt:   at,
+		})
+	}
+	reward(referral.ReferrerID, l.policy.ReferrerPoints, "A friend you referred completed their first order")
+	reward(referral.ReferredUserID, l.policy.ReferredPoints, "Your first order with a referral code completed")
+	return rewards
+}
+
+// returnRedeemedPoints gives back the loyalty points a new order that was not placed
+// redeemed
+func (s *OrderService) returnRedeemedPoints(ctx context.Context, order *model.Order) {
+	s.loyalty.returnRedemption(ctx, order, "order-"+order.ID+"-returned", fmt.Sprintf("Order %s was not placed", order.ID))
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/loyalty"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// referralCodeAlphabet leaves out letters and digits that are easily mistaken for each
+// other when a code is read out
+const referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const referralCodeLength = 8
+
+// LoyaltyService hands out referral codes, records who referred new users and shows
+// users their loyalty points. Points are earned and redeemed through OrderService.
+type LoyaltyService struct {
+	pb.UnimplementedLoyaltyServiceServer
+	repo      LoyaltyRepository
+	orderRepo OrderRepository
+	policy    LoyaltyPolicy
+}
+
+// NewLoyaltyService creates a new loyalty service
+func NewLoyaltyService(repo LoyaltyRepository, orderRepo OrderRepository, policy LoyaltyPolicy) *LoyaltyService {
+	return &LoyaltyService{
+		repo:      repo,
+		orderRepo: orderRepo,
+		policy:    policy,
+	}
+}
+
+// GetReferralCode returns the code a user shares with friends, creating it the first time
+func (s *LoyaltyService) GetReferralCode(ctx context.Context, req *pb.GetReferralCodeRequest) (*pb.ReferralCodeResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
+	}
+
+	// A new code can clash with another user's, or the user's code be created meanwhile
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := s.repo.GetReferralCode(ctx, req.UserId)
+		if err == nil {
+			return &pb.ReferralCodeResponse{ReferralCode: convertReferralCodeToProto(code)}, nil
+		}
+		if !errors.Is(err, repository.ErrReferralCodeNotFound) {
+			return nil, status.Errorf(codes.Internal, "failed to get referral code: %v", err)
+		}
+
+		generated, err := generateReferralCode()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to generate referral code: %v", err)
+		}
+		code = &model.ReferralCode{
+			UserID:    req.UserId,
+			Code:      generated,
+			CreatedAt: time.Now(),
+		}
+		err = s.repo.CreateReferralCode(ctx, code)
+		if err == nil {
+			return &pb.ReferralCodeResponse{ReferralCode: convertReferralCodeToProto(code)}, nil
+		}
+		if !errors.Is(err, repository.ErrReferralCodeTaken) {
+			return nil, status.Errorf(codes.Internal, "failed to create referral code: %v", err)
+		}
+	}
+
+	return nil, status.Errorf(codes.Aborted, "failed to create a referral code no one else has; try again")
+}
+
+// ApplyReferralCode records who referred a user. Only users who have not ordered yet can
+// be referred, so the reward is for bringing in a new user, and each device they sign up
+// on can only be used for one referral.
+func (s *LoyaltyService) ApplyReferralCode(ctx context.Context, req *pb.ApplyReferralCodeRequest) (*pb.ReferralResponse, error) {
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if req.UserId == "" || code == "" || req.DeviceId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID, code and device ID are required")
+	}
+
+	owner, err := s.repo.GetReferralCodeByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, repository.ErrReferralCodeNotFound) {
+			return nil, status.Errorf(codes.NotFound, "referral code not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get referral code: %v", err)
+	}
+	if owner.UserID == req.UserId {
+		return nil, status.Errorf(codes.InvalidArgument, "users cannot refer themselves")
+	}
+
+	_, ordered, err := s.orderRepo.ListUserOrders(ctx, req.UserId, 1, 1, "")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list user orders: %v", err)
+	}
+	if ordered > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "only users who have not ordered yet can be referred")
+	}
+
+	referral := &model.Referral{
+		ReferredUserID: req.UserId,
+		ReferrerID:     owner.UserID,
+		Code:           owner.Code,
+		DeviceID:       req.DeviceId,
+		Status:         model.ReferralPending,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.repo.CreateReferral(ctx, referral); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrAlreadyReferred):
+			return nil, status.Errorf(codes.AlreadyExists, "user was already referred")
+		case errors.Is(err, repository.ErrReferralDeviceUsed):
+			return nil, status.Errorf(codes.FailedPrecondition, "this device was already used for a referral")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to create referral: %v", err)
+		}
+	}
+
+	return &pb.ReferralResponse{
+		Referral: convertReferralToProto(referral),
+		Message:  "Referral recorded; points are earned once the first order completes",
+		Success:  true,
+	}, nil
+}
+
+// GetReferral returns who referred a user and whether it earned points yet
+func (s *LoyaltyService) GetReferral(ctx context.Context, req *pb.GetReferralRequest) (*pb.ReferralResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
+	}
+
+	referral, err := s.repo.GetReferral(ctx, req.UserId)
+	if err != nil {
+		if errors.Is(err, repository.ErrReferralNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user was not referred")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get referral: %v", err)
+	}
+
+	return &pb.ReferralResponse{
+		Referral: convertReferralToProto(referral),
+		Message:  "Referral retrieved successfully",
+		Success:  true,
+	}, nil
+}
+
+// GetPoints returns a user's points balance and what each point is worth
+func (s *LoyaltyService) GetPoints(ctx context.Context, req *pb.GetPointsRequest) (*pb.PointsResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
+	}
+
+	account, err := s.repo.GetPointsAccount(ctx, req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get points: %v", err)
+	}
+
+	return &pb.PointsResponse{
+		UserId:     account.UserID,
+		Balance:    account.Balance,
+		PointValue: s.policy.PointValue,
+	}, nil
+}
+
+// ListPointsTransactions lists a page of a user's points transactions, newest first
+func (s *LoyaltyService) ListPointsTransactions(ctx context.Context, req *pb.ListPointsTransactionsRequest) (*pb.ListPointsTransactionsResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
+	}
+
+	txns, total, err := s.repo.ListPointsTransactions(ctx, req.UserId, int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list points transactions: %v", err)
+	}
+
+	protoTxns := make([]*pb.PointsTransaction, 0, len(txns))
+	for _, txn := range txns {
+		protoTxns = append(protoTxns, convertPointsTransactionToProto(txn))
+	}
+
+	return &pb.ListPointsTransactionsResponse{
+		Transactions: protoTxns,
+		Total:        int32(total),
+		Page:         req.Page,
+		Limit:        req.Limit,
+	}, nil
+}
+
+// generateReferralCode returns a random referral code
+func generateReferralCode() (string, error) {
+	max := big.NewInt(int64(len(referralCodeAlphabet)))
+	code := make([]byte, referralCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = referralCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func convertReferralCodeToProto(code *model.ReferralCode) *pb.ReferralCode {
+	return &pb.ReferralCode{
+		UserId:    code.UserID,
+		Code:      code.Code,
+		CreatedAt: timestamppb.New(code.CreatedAt),
+	}
+}
+
+func convertReferralToProto(referral *model.Referral) *pb.Referral {
+	protoReferral := &pb.Referral{
+		ReferredUserId: referral.ReferredUserID,
+		ReferrerId:     referral.ReferrerID,
+		Code:           referral.Code,
+		Status:         string(referral.Status),
+		RejectReason:   referral.RejectReason,
+		OrderId:        referral.OrderID,
+		CreatedAt:      timestamppb.New(referral.CreatedAt),
+	}
+	if referral.SettledAt != nil {
+		protoReferral.SettledAt = timestamppb.New(*referral.SettledAt)
+	}
+	return protoReferral
+}
+
+func convertPointsTransactionToProto(txn *model.PointsTransaction) *pb.PointsTransaction {
+	return &pb.PointsTransaction{
+		Id:           txn.ID,
+		Type:         string(txn.Type),
+		Points:       txn.Points,
+		BalanceAfter: txn.BalanceAfter,
+		OrderId:      txn.OrderID,
+		Description:  txn.Description,
+		CreatedAt:    timestamppb.New(txn.CreatedAt),
+	}
+}
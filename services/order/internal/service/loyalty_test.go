@@ -0,0 +1,183 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	loyaltyPb "github.com/order-api-microservices/proto/loyalty"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/service"
+	"google.golang.org/grpc/codes"
+)
+
+const testReferrerID = "3b9e7c1d-8f2a-4e6b-a5c4-1d7f9e2b6a33"
+
+var testLoyaltyPolicy = service.LoyaltyPolicy{ReferrerPoints: 500, ReferredPoints: 300, PointValue: 10}
+
+// referTestUser has the test user sign up with the test referrer's code
+func referTestUser(t *testing.T, loyalty *service.LoyaltyService) {
+	t.Helper()
+
+	ctx := context.Background()
+	code, err := loyalty.GetReferralCode(ctx, &loyaltyPb.GetReferralCodeRequest{UserId: testReferrerID})
+	if err != nil {
+		t.Fatalf("GetReferralCode: %v", err)
+	}
+	_, err = loyalty.ApplyReferralCode(ctx, &loyaltyPb.ApplyReferralCodeRequest{UserId: testUserID, Code: code.ReferralCode.Code, DeviceId: "device-1"})
+	if err != nil {
+		t.Fatalf("ApplyReferralCode: %v", err)
+	}
+}
+
+// saveCard saves a card for a user straight into the repository
+func saveCard(t *testing.T, repos testRepos, userID, fingerprint string) *model.SavedPaymentMethod {
+	t.Helper()
+
+	method := &model.SavedPaymentMethod{
+		ID: "pm-" + userID + "-" + fingerprint, UserID: userID, Type: model.PaymentCreditCard,
+		Gateway: "stripe", Fingerprint: fingerprint, Last4: "4242", CreatedAt: time.Now(),
+	}
+	if err := repos.methods.SavePaymentMethod(context.Background(), method); err != nil {
+		t.Fatalf("SavePaymentMethod: %v", err)
+	}
+	return method
+}
+
+// pointsBalance is a user's loyalty points balance
+func pointsBalance(t *testing.T, repos testRepos, userID string) int64 {
+	t.Helper()
+
+	account, err := repos.loyalty.GetPointsAccount(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("GetPointsAccount: %v", err)
+	}
+	return account.Balance
+}
+
+func TestReferralSettledWhenFirstOrderCompletes(t *testing.T) {
+	tests := []struct {
+		name             string
+		referrerCard     bool // The referrer saved the card the first order is paid with
+		wantStatus       model.ReferralStatus
+		wantReferrer     int64
+		wantReferredUser int64
+	}{
+		{name: "rewarded", wantStatus: model.ReferralRewarded, wantReferrer: 500, wantReferredUser: 300},
+		{name: "paid with the referrer's card", referrerCard: true, wantStatus: model.ReferralRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, repos := newTestOrderService(&capturePayments{})
+			loyalty := service.NewLoyaltyService(repos.loyalty, repos.orders, testLoyaltyPolicy)
+			stockTestMerchant(t, repos, 5)
+			referTestUser(t, loyalty)
+
+			card := saveCard(t, repos, testUserID, "fp-user")
+			if tt.referrerCard {
+				saveCard(t, repos, testReferrerID, "fp-user")
+			}
+
+			req := orderFromMerchant(1)
+			req.PaymentMethodId = card.ID
+			resp, err := s.CreateOrder(ctx, req)
+			if err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+			_, err = s.UpdateOrderStatus(ctx, &pb.UpdateOrderStatusRequest{OrderId: resp.Order.Id, Status: pb.OrderStatus_ORDER_STATUS_COMPLETED, UpdatedBy: testProviderID})
+			if err != nil {
+				t.Fatalf("UpdateOrderStatus: %v", err)
+			}
+
+			referral, err := loyalty.GetReferral(ctx, &loyaltyPb.GetReferralRequest{UserId: testUserID})
+			if err != nil {
+				t.Fatalf("GetReferral: %v", err)
+			}
+			if referral.Referral.Status != string(tt.wantStatus) || referral.Referral.OrderId != resp.Order.Id {
+				t.Errorf("referral %s by order %q, want %s by %s", referral.Referral.Status, referral.Referral.OrderId, tt.wantStatus, resp.Order.Id)
+			}
+			if got := pointsBalance(t, repos, testReferrerID); got != tt.wantReferrer {
+				t.Errorf("referrer has %d points, want %d", got, tt.wantReferrer)
+			}
+			if got := pointsBalance(t, repos, testUserID); got != tt.wantReferredUser {
+				t.Errorf("referred user has %d points, want %d", got, tt.wantReferredUser)
+			}
+		})
+	}
+}
+
+func TestApplyReferralCodeRefusesAbuse(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	loyalty := service.NewLoyaltyService(repos.loyalty, repos.orders, testLoyaltyPolicy)
+	stockTestMerchant(t, repos, 5)
+
+	resp, err := loyalty.GetReferralCode(ctx, &loyaltyPb.GetReferralCodeRequest{UserId: testReferrerID})
+	if err != nil {
+		t.Fatalf("GetReferralCode: %v", err)
+	}
+	code := resp.ReferralCode.Code
+
+	_, err = loyalty.ApplyReferralCode(ctx, &loyaltyPb.ApplyReferralCodeRequest{UserId: testReferrerID, Code: code, DeviceId: "device-0"})
+	wantCode(t, err, codes.InvalidArgument)
+
+	_, err = loyalty.ApplyReferralCode(ctx, &loyaltyPb.ApplyReferralCodeRequest{UserId: testUserID, Code: "NOSUCHCODE", DeviceId: "device-1"})
+	wantCode(t, err, codes.NotFound)
+
+	// Another account signing up on a device already used for a referral earns nothing
+	referTestUser(t, loyalty)
+	_, err = loyalty.ApplyReferralCode(ctx, &loyaltyPb.ApplyReferralCodeRequest{UserId: testProviderID, Code: code, DeviceId: "device-1"})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	_, err = loyalty.ApplyReferralCode(ctx, &loyaltyPb.ApplyReferralCodeRequest{UserId: testUserID, Code: code, DeviceId: "device-2"})
+	wantCode(t, err, codes.AlreadyExists)
+
+	// Only users who have not ordered yet can be referred
+	req := orderFromMerchant(1)
+	req.UserId = "9c2d4e6f-1a3b-4c5d-8e7f-0a1b2c3d4e55"
+	if _, err := s.CreateOrder(ctx, req); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	_, err = loyalty.ApplyReferralCode(ctx, &loyaltyPb.ApplyReferralCodeRequest{UserId: req.UserId, Code: code, DeviceId: "device-3"})
+	wantCode(t, err, codes.FailedPrecondition)
+}
+
+func TestRedeemedPointsDiscountTheOrder(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	stockTestMerchant(t, repos, 5)
+	err := repos.loyalty.ApplyPointsTransaction(ctx, &model.PointsTransaction{
+		ID: "earned", UserID: testUserID, Type: model.PointsReferralReward, Points: 1000, Reference: "earned", CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("ApplyPointsTransaction: %v", err)
+	}
+
+	req := orderFromMerchant(1)
+	req.RedeemPoints = 5000
+	_, err = s.CreateOrder(ctx, req)
+	wantCode(t, err, codes.FailedPrecondition)
+
+	req.RedeemPoints = 1000
+	resp, err := s.CreateOrder(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if resp.Order.PointsDiscount != 10000 || resp.Order.TotalPrice != 15000 {
+		t.Errorf("discount %d off a total of %d, want 10000 off 15000", resp.Order.PointsDiscount, resp.Order.TotalPrice)
+	}
+	if got := pointsBalance(t, repos, testUserID); got != 0 {
+		t.Errorf("points after ordering = %d, want 0", got)
+	}
+
+	_, err = s.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: resp.Order.Id, CancelledBy: testUserID, Reason: "changed my mind"})
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if got := pointsBalance(t, repos, testUserID); got != 1000 {
+		t.Errorf("points after cancelling = %d, want 1000 back", got)
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/merchant"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MerchantService lets admins list the restaurants and shops users order from, and
+// keep their menus and prices
+type MerchantService struct {
+	pb.UnimplementedMerchantServiceServer
+	repo *repository.MerchantRepository
+}
+
+// NewMerchantService creates a new merchant service
+func NewMerchantService(repo *repository.MerchantRepository) *MerchantService {
+	return &MerchantService{
+		repo: repo,
+	}
+}
+
+// ListMerchants lists the merchants of a city, or of every city
+func (s *MerchantService) ListMerchants(ctx context.Context, req *pb.ListMerchantsRequest) (*pb.ListMerchantsResponse, error) {
+	merchants, err := s.repo.ListMerchants(ctx, normalizeCity(req.City))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list merchants: %v", err)
+	}
+
+	protoMerchants := []*pb.Merchant{}
+	for _, merchant := range merchants {
+		protoMerchants = append(protoMerchants, convertMerchantToProto(merchant, nil))
+	}
+
+	return &pb.ListMerchantsResponse{
+		Merchants: protoMerchants,
+	}, nil
+}
+
+// GetMerchant gets a merchant with its menu
+func (s *MerchantService) GetMerchant(ctx context.Context, req *pb.GetMerchantRequest) (*pb.MerchantResponse, error) {
+	if req.MerchantId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "merchant ID is required")
+	}
+
+	merchant, err := s.getMerchant(ctx, req.MerchantId)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.repo.ListCatalogItems(ctx, merchant.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list catalog items: %v", err)
+	}
+
+	return &pb.MerchantResponse{
+		Merchant: convertMerchantToProto(merchant, items),
+		Message:  "Merchant retrieved successfully",
+		Success:  true,
+	}, nil
+}
+
+// CreateMerchant adds a merchant users can order from
+func (s *MerchantService) CreateMerchant(ctx context.Context, req *pb.CreateMerchantRequest) (*pb.MerchantResponse, error) {
+	now := time.Now()
+	merchant := &model.Merchant{
+		ID:        uuid.New().String(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := applyMerchantDetails(merchant, req.Merchant); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateMerchant(ctx, merchant); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create merchant: %v", err)
+	}
+
+	return &pb.MerchantResponse{
+		Merchant: convertMerchantToProto(merchant, nil),
+		Message:  "Merchant created successfully",
+		Success:  true,
+	}, nil
+}
+
+// UpdateMerchant replaces a merchant's details. A merchant made inactive takes no new
+// orders; orders already placed are unaffected.
+func (s *MerchantService) UpdateMerchant(ctx context.Context, req *pb.UpdateMerchantRequest) (*pb.MerchantResponse, error) {
+	merchant, err := s.getMerchant(ctx, req.MerchantId)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyMerchantDetails(merchant, req.Merchant); err != nil {
+		return nil, err
+	}
+	merchant.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateMerchant(ctx, merchant); err != nil {
+		if errors.Is(err, repository.ErrMerchantNotFound) {
+			return nil, status.Errorf(codes.NotFound, "merchant not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update merchant: %v", err)
+	}
+
+	return &pb.MerchantResponse{
+		Merchant: convertMerchantToProto(merchant, nil),
+		Message:  "Merchant updated successfully",
+		Success:  true,
+	}, nil
+}
+
+// CreateCatalogItem adds an item to a merchant's menu
+func (s *MerchantService) CreateCatalogItem(ctx context.Context, req *pb.CreateCatalogItemRequest) (*pb.CatalogItemResponse, error) {
+	if _, err := s.getMerchant(ctx, req.MerchantId); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	item := &model.CatalogItem{
+		ID:         uuid.New().String(),
+		MerchantID: req.MerchantId,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := applyCatalogItemDetails(item, req.Item); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateCatalogItem(ctx, item); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create catalog item: %v", err)
+	}
+
+	return &pb.CatalogItemResponse{
+		Item:    convertCatalogItemToProto(item),
+		Message: "Catalog item created successfully",
+		Success: true,
+	}, nil
+}
+
+// UpdateCatalogItem replaces the details of an item on a merchant's menu. New prices
+// apply to orders placed from then on.
+func (s *MerchantService) UpdateCatalogItem(ctx context.Context, req *pb.UpdateCatalogItemRequest) (*pb.CatalogItemResponse, error) {
+	item := &model.CatalogItem{
+		ID:         req.ItemId,
+		MerchantID: req.MerchantId,
+		UpdatedAt:  time.Now(),
+	}
+	if err := applyCatalogItemDetails(item, req.Item); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateCatalogItem(ctx, item); err != nil {
+		if errors.Is(err, repository.ErrCatalogItemNotFound) {
+			return nil, status.Errorf(codes.NotFound, "catalog item not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update catalog item: %v", err)
+	}
+
+	return &pb.CatalogItemResponse{
+		Item:    convertCatalogItemToProto(item),
+		Message: "Catalog item updated successfully",
+		Success: true,
+	}, nil
+}
+
+// DeleteCatalogItem removes an item from a merchant's menu
+func (s *MerchantService) DeleteCatalogItem(ctx context.Context, req *pb.DeleteCatalogItemRequest) (*pb.DeleteCatalogItemResponse, error) {
+	if err := s.repo.DeleteCatalogItem(ctx, req.MerchantId, req.ItemId); err != nil {
+		if errors.Is(err, repository.ErrCatalogItemNotFound) {
+			return nil, status.Errorf(codes.NotFound, "catalog item not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete catalog item: %v", err)
+	}
+
+	return &pb.DeleteCatalogItemResponse{
+		Message: "Catalog item deleted successfully",
+		Success: true,
+	}, nil
+}
+
+func (s *MerchantService) getMerchant(ctx context.Context, merchantID string) (*model.Merchant, error) {
+	merchant, err := s.repo.GetMerchant(ctx, merchantID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMerchantNotFound) {
+			return nil, status.Errorf(codes.NotFound, "merchant not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get merchant: %v", err)
+	}
+	return merchant, nil
+}
+
+// applyMerchantDetails validates a merchant's details and copies them onto it
+func applyMerchantDetails(merchant *model.Merchant, details *pb.MerchantDetails) error {
+	if details == nil || details.Name == "" || normalizeCity(details.City) == "" {
+		return status.Errorf(codes.InvalidArgument, "merchant name and city are required")
+	}
+	if details.Latitude < -90 || details.Latitude > 90 || details.Longitude < -180 || details.Longitude > 180 {
+		return status.Errorf(codes.InvalidArgument, "merchant location (%v, %v) is out of range", details.Latitude, details.Longitude)
+	}
+
+	merchant.Name = details.Name
+	merchant.City = normalizeCity(details.City)
+	merchant.Address = details.Address
+	merchant.Latitude = details.Latitude
+	merchant.Longitude = details.Longitude
+	merchant.Active = details.Active
+	return nil
+}
+
+// applyCatalogItemDetails validates a catalog item's details and copies them onto it
+func applyCatalogItemDetails(item *model.CatalogItem, details *pb.CatalogItemDetails) error {
+	if details == nil || details.Name == "" {
+		return status.Errorf(codes.InvalidArgument, "catalog item name is required")
+	}
+	if details.Price < 0 {
+		return status.Errorf(codes.InvalidArgument, "catalog item price cannot be negative")
+	}
+	if details.StockTracked && details.Stock < 0 {
+		return status.Errorf(codes.InvalidArgument, "catalog item stock cannot be negative")
+	}
+
+	item.Name = details.Name
+	item.Description = details.Description
+	item.Category = details.Category
+	item.Price = details.Price
+	item.Available = details.Available
+	item.Stock = nil
+	if details.StockTracked {
+		stock := int(details.Stock)
+		item.Stock = &stock
+	}
+	return nil
+}
+
+// convertMerchantToProto converts a merchant, with its menu when items is not nil
+func convertMerchantToProto(merchant *model.Merchant, items []*model.CatalogItem) *pb.Merchant {
+	protoItems := make([]*pb.CatalogItem, 0, len(items))
+	for _, item := range items {
+		protoItems = append(protoItems, convertCatalogItemToProto(item))
+	}
+
+	return &pb.Merchant{
+		Id:        merchant.ID,
+		Name:      merchant.Name,
+		City:      merchant.City,
+		Address:   merchant.Address,
+		Latitude:  merchant.Latitude,
+		Longitude: merchant.Longitude,
+		Active:    merchant.Active,
+		Items:     protoItems,
+		CreatedAt: timestamppb.New(merchant.CreatedAt),
+		UpdatedAt: timestamppb.New(merchant.UpdatedAt),
+	}
+}
+
+func convertCatalogItemToProto(item *model.CatalogItem) *pb.CatalogItem {
+	protoItem := &pb.CatalogItem{
+		Id:          item.ID,
+		MerchantId:  item.MerchantID,
+		Name:        item.Name,
+		Description: item.Description,
+		Category:    item.Category,
+		Price:       item.Price,
+		Available:   item.Available,
+		CreatedAt:   timestamppb.New(item.CreatedAt),
+		UpdatedAt:   timestamppb.New(item.UpdatedAt),
+	}
+	if item.Stock != nil {
+		protoItem.StockTracked = true
+		protoItem.Stock = int32(*item.Stock)
+	}
+	return protoItem
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/operations"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Bounds of the window dispatch latency is measured over
+const (
+	defaultLatencyWindow = 15 * time.Minute
+	maxLatencyWindow     = 24 * time.Hour
+)
+
+// ProviderAvailability counts the providers available for orders
+type ProviderAvailability struct {
+	Available              int64
+	AvailableByServiceArea map[string]int64 // By service area ID; a provider in several areas counts in each
+	AvailableWithoutArea   int64
+}
+
+// OperationsProviderClient counts available providers in the provider service
+type OperationsProviderClient interface {
+	GetAvailabilityCounts(ctx context.Context) (*ProviderAvailability, error)
+}
+
+// OperationsService gathers the live counters shown on the operations dashboard
+type OperationsService struct {
+	pb.UnimplementedOperationsServiceServer
+	orderRepo      *repository.OrderRepository
+	dispatchRepo   *repository.DispatchRepository
+	serviceAreas   *ServiceAreas
+	providerClient OperationsProviderClient
+}
+
+// NewOperationsService creates a new operations service
+func NewOperationsService(
+	orderRepo *repository.OrderRepository,
+	dispatchRepo *repository.DispatchRepository,
+	serviceAreas *ServiceAreas,
+	providerClient OperationsProviderClient,
+) *OperationsService {
+	return &OperationsService{
+		orderRepo:      orderRepo,
+		dispatchRepo:   dispatchRepo,
+		serviceAreas:   serviceAreas,
+		providerClient: providerClient,
+	}
+}
+
+// GetLiveCounters counts the active orders by status and the available providers by
+// service area, and measures recent dispatch latency. If the provider service cannot
+// be reached the order counters are still returned, flagged as missing the providers.
+func (s *OperationsService) GetLiveCounters(ctx context.Context, req *pb.GetLiveCountersRequest) (*pb.LiveCounters, error) {
+	window := time.Duration(req.LatencyWindowSeconds) * time.Second
+	if window <= 0 {
+		window = defaultLatencyWindow
+	}
+	if window > maxLatencyWindow {
+		return nil, status.Errorf(codes.InvalidArgument, "latency window can be at most %d seconds", int(maxLatencyWindow.Seconds()))
+	}
+
+	now := time.Now()
+	counters := &pb.LiveCounters{
+		GeneratedAt:          timestamppb.New(now),
+		ActiveOrdersByStatus: make(map[string]int64),
+	}
+
+	active, err := s.orderRepo.CountActiveOrders(ctx, finishedStatuses)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to count active orders: %v", err)
+	}
+	for orderStatus, count := range active {
+		counters.ActiveOrdersByStatus[string(orderStatus)] = count
+		counters.ActiveOrders += count
+	}
+
+	latency, err := s.dispatchRepo.GetDispatchLatency(ctx, now.Add(-window))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to measure dispatch latency: %v", err)
+	}
+	counters.DispatchLatency = &pb.DispatchLatency{
+		WindowSeconds:  int32(window.Seconds()),
+		Decisions:      latency.Decisions,
+		AverageSeconds: latency.AverageSeconds,
+		P50Seconds:     latency.P50Seconds,
+		P95Seconds:     latency.P95Seconds,
+		MaxSeconds:     latency.MaxSeconds,
+	}
+
+	availability, err := s.providerClient.GetAvailabilityCounts(ctx)
+	if err != nil {
+		log.Printf("Failed to count available providers: %v", err)
+		counters.ProvidersUnavailable = true
+		return counters, nil
+	}
+
+	counters.OnlineProviders = availability.Available
+	counters.OnlineProvidersWithoutZone = availability.AvailableWithoutArea
+	for areaID, count := range availability.AvailableByServiceArea {
+		zone := &pb.ZoneCounter{
+			ServiceAreaId:   areaID,
+			OnlineProviders: count,
+		}
+		if area := s.serviceAreas.Get(areaID); area != nil {
+			zone.Name = area.Name
+			zone.City = area.City
+		}
+		counters.OnlineProvidersByZone = append(counters.OnlineProvidersByZone, zone)
+	}
+	sort.Slice(counters.OnlineProvidersByZone, func(i, j int) bool {
+		a, b := counters.OnlineProvidersByZone[i], counters.OnlineProvidersByZone[j]
+		if a.OnlineProviders != b.OnlineProviders {
+			return a.OnlineProviders > b.OnlineProviders
+		}
+		return a.ServiceAreaId < b.ServiceAreaId
+	})
+
+	return counters, nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// archivableStatuses are the statuses in which an order is settled for good. Delivered
+// orders still await completion and disputed ones a resolution.
+var archivableStatuses = []model.OrderStatus{
+	model.StatusCompleted,
+	model.StatusCancelled,
+	model.StatusRefunded,
+}
+
+// OrderArchiveConfig controls when finished orders move to the archive
+type OrderArchiveConfig struct {
+	Age       time.Duration // Finished orders last updated longer ago than this are archived
+	Interval  time.Duration // How often old orders are archived
+	BatchSize int           // Most orders moved in one transaction
+}
+
+// OrderArchiver moves old finished orders from the orders table into the archive, where
+// GetOrderByID still finds them
+type OrderArchiver struct {
+	orderRepo *repository.OrderRepository
+	cfg       OrderArchiveConfig
+}
+
+// NewOrderArchiver creates a new order archival job
+func NewOrderArchiver(orderRepo *repository.OrderRepository, cfg OrderArchiveConfig) *OrderArchiver {
+	return &OrderArchiver{
+		orderRepo: orderRepo,
+		cfg:       cfg,
+	}
+}
+
+// Run archives old orders every interval until ctx is cancelled
+func (a *OrderArchiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			archived, err := a.Archive(ctx)
+			if err != nil {
+				log.Printf("Failed to archive orders: %v", err)
+			}
+			if archived > 0 {
+				log.Printf("Archived %d orders", archived)
+			}
+		}
+	}
+}
+
+// Archive moves every order that is due into the archive, a batch at a time, and reports
+// how many it moved
+func (a *OrderArchiver) Archive(ctx context.Context) (int, error) {
+	before := time.Now().Add(-a.cfg.Age)
+
+	var total int
+	for ctx.Err() == nil {
+		archived, err := a.orderRepo.ArchiveOrders(ctx, archivableStatuses, before, a.cfg.BatchSize)
+		total += archived
+		if err != nil || archived == 0 || archived < a.cfg.BatchSize {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
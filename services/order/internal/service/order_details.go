@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/order-api-microservices/pkg/money"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OrderEditPolicy decides which changes to an order waiting for pickup are material, so
+// that a provider who accepted the order has to accept it again, and how long a provider
+// has to answer a destination change during a ride
+type OrderEditPolicy struct {
+	MaterialDistanceKm   float64       // How far the destination may move before the change is material; 0 makes any move material
+	MaterialPricePercent int           // How far the total may move, as a percent of the old total, before the change is material
+	DestinationChangeTTL time.Duration // How long a destination change waits for the provider before it expires; 0 waits until answered
+}
+
+// Validate checks the order edit policy
+func (p OrderEditPolicy) Validate() error {
+	if p.MaterialDistanceKm < 0 {
+		return fmt.Errorf("material distance must not be negative")
+	}
+	if p.MaterialPricePercent < 0 {
+		return fmt.Errorf("material price change must not be a negative percent")
+	}
+	if p.DestinationChangeTTL < 0 {
+		return fmt.Errorf("destination change TTL must not be negative")
+	}
+	return nil
+}
+
+// destinationChangesLapsedBefore returns the time a destination change still pending at
+// now must have been requested after, or the zero time if changes do not expire
+func (p OrderEditPolicy) destinationChangesLapsedBefore(now time.Time) time.Time {
+	if p.DestinationChangeTTL == 0 {
+		return time.Time{}
+	}
+	return now.Add(-p.DestinationChangeTTL)
+}
+
+// material reports whether the change from before to after is one the provider has to
+// accept again: the destination moved too far, the total moved too much, or a package
+// changed its size class
+func (p OrderEditPolicy) material(before, after *model.Order) bool {
+	moved := haversineKm(before.DestinationLocation.Latitude, before.DestinationLocation.Longitude,
+		after.DestinationLocation.Latitude, after.DestinationLocation.Longitude)
+	if moved > p.MaterialDistanceKm {
+		return true
+	}
+
+	if delta := after.TotalPrice - before.TotalPrice; delta != 0 {
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta*100 > before.TotalPrice*int64(p.MaterialPricePercent) {
+			return true
+		}
+	}
+
+	return after.OrderType == model.TypePackageDelivery &&
+		before.Items.PackageSize().Class() != after.Items.PackageSize().Class()
+}
+
+// changeableBeforePickup reports whether an order's details can still be changed, which
+// is until its provider is on the way to pick it up
+func changeableBeforePickup(orderStatus model.OrderStatus) bool {
+	switch orderStatus {
+	case model.StatusCreated, model.StatusPaymentPending, model.StatusPaymentComplete,
+		model.StatusProviderAssigned, model.StatusProviderAccepted, model.StatusProviderRejected:
+		return true
+	default:
+		return false
+	}
+}
+
+// itemsChangeable reports whether the items of an order type can be changed. Goods may
+// come from a merchant's catalog and be prepared already, rentals are priced by the hour
+// rather than by their items, and returns carry goods of the order they return.
+func itemsChangeable(orderType model.OrderType) bool {
+	return !carriesGoods(orderType) && orderType != model.TypeRental && orderType != model.TypeReturn
+}
+
+// UpdateOrderDetails changes the destination, items or notes of an order before pickup.
+// New items reprice the order. A material change sends an accepted order back to its
+// provider to accept again, and every change is logged in the status history.
+func (s *OrderService) UpdateOrderDetails(ctx context.Context, req *pb.UpdateOrderDetailsRequest) (*pb.OrderResponse, error) {
+	if req.DestinationLocation == nil && len(req.Items) == 0 && req.Notes == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nothing to change")
+	}
+
+	// Get current order
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if err := checkOrderNotFrozen(order); err != nil {
+		return nil, err
+	}
+	if order.UserID != req.UserId {
+		return nil, status.Errorf(codes.PermissionDenied, "only the order's user can change it")
+	}
+	if !changeableBeforePickup(order.Status) {
+		return nil, status.Errorf(codes.FailedPrecondition, "order can only be changed before pickup")
+	}
+	if len(req.Items) > 0 && !itemsChangeable(order.OrderType) {
+		return nil, status.Errorf(codes.FailedPrecondition, "items of %s orders cannot be changed", order.OrderType)
+	}
+
+	before := *order
+	var changes []string
+
+	if req.DestinationLocation != nil {
+		destination := convertLocation(req.DestinationLocation)
+		// A destination sent without instructions keeps the ones the user gave before
+		if destination.Instructions == nil && order.DestinationLocation.Instructions != nil {
+			instructions := *order.DestinationLocation.Instructions
+			destination.Instructions = &instructions
+		}
+		moved := haversineKm(order.DestinationLocation.Latitude, order.DestinationLocation.Longitude,
+			destination.Latitude, destination.Longitude)
+		order.DestinationLocation = destination
+		changes = append(changes, fmt.Sprintf("destination moved %.1f km", moved))
+	}
+
+	if len(req.Items) > 0 {
+		order.Items = convertOrderItems(req.Items)
+		changes = append(changes, fmt.Sprintf("items changed to %d", len(order.Items)))
+	}
+
+	// Notes are kept where CreateOrder put them: on the order when the destination has
+	// notes of its own, else in the destination's instructions
+	if req.Notes != "" {
+		if order.Notes != "" {
+			order.Notes = req.Notes
+		} else {
+			instructions := model.StopInstructions{}
+			if order.DestinationLocation.Instructions != nil {
+				instructions = *order.DestinationLocation.Instructions
+			}
+			instructions.Notes = req.Notes
+			order.DestinationLocation.Instructions = &instructions
+		}
+		changes = append(changes, "notes changed")
+	}
+
+	// Client-priced orders are checked against their new trip and repriced from their
+	// items; goods and rentals keep their total
+	if itemsChangeable(order.OrderType) && (len(req.Items) > 0 || req.DestinationLocation != nil) {
+		if err := s.repriceOrder(order, req.QuotedTotal); err != nil {
+			return nil, err
+		}
+	}
+	if order.TotalPrice != before.TotalPrice {
+		if err := s.checkTotalChangeable(ctx, order); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("total changed from %s to %s",
+			money.Format(before.TotalPrice), money.Format(order.TotalPrice)))
+	}
+
+	// The provider agreed to the order as it was, so a material change needs their
+	// agreement again
+	summary := "Order changed: " + strings.Join(changes, "; ")
+	reconfirm := order.Status == model.StatusProviderAccepted && s.editPolicy.material(&before, order)
+	if reconfirm {
+		order.AddStatusHistory(model.StatusProviderAssigned, req.UserId, summary+"; waiting for the provider to accept again")
+	} else {
+		order.AddStatusHistory(order.Status, req.UserId, summary)
+	}
+
+	// Only the changed details are written, and only while the order is as it was read
+	entry := order.StatusHistory[len(order.StatusHistory)-1]
+	if err := s.repo.UpdateOrderDetails(ctx, order, before.Status, entry); err != nil {
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			return nil, status.Errorf(codes.FailedPrecondition, "order changed while it was being updated; try again")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update order: %v", err)
+	}
+
+	s.notifyOrderChanged(order, summary, reconfirm)
+
+	// Record the change on blockchain
+	s.blockchainRecorder.Record(ctx, order.ID)
+
+	return &pb.OrderResponse{
+		Order:   convertOrderToProto(order),
+		Message: "Order details updated successfully",
+		Success: true,
+	}, nil
+}
+
+// repriceOrder prices an order from its items the way CreateOrder does, checking the
+// total fits the trip and what the user was quoted. Points redeemed when the order was
+// placed still come off the new total.
+func (s *OrderService) repriceOrder(order *model.Order, quotedTotal int64) error {
+	order.TotalPrice = calculateTotalPrice(order.Items)
+
+	var surcharge int64
+	if order.OrderType == model.TypePackageDelivery {
+		charge, err := s.packagePolicy.surcharge(order.Items)
+		if err != nil {
+			return err
+		}
+		surcharge = charge
+		order.TotalPrice += surcharge
+	}
+
+	if err := s.pricingPolicy.validate(order, true, surcharge, quotedTotal); err != nil {
+		return err
+	}
+	s.feeSchedule.Apply(order)
+
+	order.TotalPrice -= order.PointsDiscount
+	if order.TotalPrice < order.WalletAmount {
+		return status.Errorf(codes.FailedPrecondition, "new total %s is less than the %s already paid from the wallet",
+			money.Format(order.TotalPrice), money.Format(order.WalletAmount))
+	}
+	return nil
+}
+
+// checkTotalChangeable refuses a new total the order's payment cannot follow: one whose
+// payment was already requested or made, or one above what is held on the payment method
+func (s *OrderService) checkTotalChangeable(ctx context.Context, order *model.Order) error {
+	if order.Status == model.StatusPaymentPending || order.WasPaid() {
+		return status.Errorf(codes.FailedPrecondition, "the order's total cannot change once its payment was requested")
+	}
+
+	auth, err := s.authorizations.get(ctx, order.ID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to get payment authorization: %v", err)
+	}
+	if auth != nil && auth.Status == model.AuthorizationAuthorized && order.PaymentMethodAmount() > auth.Amount {
+		return status.Errorf(codes.FailedPrecondition, "new total is more than the %s held on the payment method",
+			money.Format(auth.Amount))
+	}
+	return nil
+}
+
+// notifyOrderChanged tells the order's provider, if it has one, what the user changed
+func (s *OrderService) notifyOrderChanged(order *model.Order, summary string, reconfirm bool) {
+	if order.ProviderID == "" {
+		return
+	}
+
+	message := fmt.Sprintf("Order %s was changed by the user", order.ID)
+	if reconfirm {
+		message = fmt.Sprintf("Order %s was changed by the user; accept it again to keep it", order.ID)
+	}
+	go func() {
+		err := s.notificationClient.SendNotification(context.Background(), order.ProviderID, "PROVIDER", "ORDER_CHANGED",
+			"Order changed", message,
+			map[string]interface{}{
+				"order_id":  order.ID,
+				"changes":   summary,
+				"reconfirm": reconfirm,
+			})
+		if err != nil {
+			fmt.Printf("Failed to notify provider of order change: %v\n", err)
+		}
+	}()
+}
@@ -0,0 +1,159 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/service"
+	"google.golang.org/grpc/codes"
+)
+
+// acceptedRide places a held card ride for 50000 and has the test provider accept it
+func acceptedRide(t *testing.T, s *service.OrderService, repos testRepos) string {
+	t.Helper()
+
+	ctx := context.Background()
+	resp, err := s.CreateOrder(ctx, &pb.CreateOrderRequest{
+		UserId:              testUserID,
+		OrderType:           pb.OrderType_ORDER_TYPE_RIDE,
+		PickupLocation:      &pb.Location{Latitude: -6.2, Longitude: 106.8166, Address: "Office"},
+		DestinationLocation: &pb.Location{Latitude: -6.182, Longitude: 106.8166, Address: "Home"},
+		Items:               []*pb.OrderItem{{Name: "Ride", Quantity: 1, Price: 50000}},
+		PaymentMethod:       pb.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD,
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	order, err := repos.orders.GetOrderByID(ctx, resp.Order.Id)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	order.ProviderID = testProviderID
+	order.AddStatusHistory(model.StatusProviderAccepted, testProviderID, "Provider accepted the order")
+	if err := repos.orders.UpdateOrder(ctx, order); err != nil {
+		t.Fatalf("UpdateOrder: %v", err)
+	}
+	return order.ID
+}
+
+func TestUpdateOrderDetailsReconfirmsMaterialChanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *pb.UpdateOrderDetailsRequest
+		wantCode   codes.Code
+		wantStatus model.OrderStatus
+		wantTotal  int64
+	}{
+		{
+			name:       "notes only",
+			req:        &pb.UpdateOrderDetailsRequest{Notes: "Ring twice"},
+			wantStatus: model.StatusProviderAccepted,
+			wantTotal:  50000,
+		},
+		{
+			name:       "destination moved next door",
+			req:        &pb.UpdateOrderDetailsRequest{DestinationLocation: &pb.Location{Latitude: -6.1845, Longitude: 106.8166, Address: "Neighbour"}},
+			wantStatus: model.StatusProviderAccepted,
+			wantTotal:  50000,
+		},
+		{
+			name:       "destination moved across town",
+			req:        &pb.UpdateOrderDetailsRequest{DestinationLocation: &pb.Location{Latitude: -6.15, Longitude: 106.8166, Address: "Station"}},
+			wantStatus: model.StatusProviderAssigned,
+			wantTotal:  50000,
+		},
+		{
+			name:       "cheaper items",
+			req:        &pb.UpdateOrderDetailsRequest{Items: []*pb.OrderItem{{Name: "Ride", Quantity: 1, Price: 40000}}},
+			wantStatus: model.StatusProviderAssigned,
+			wantTotal:  40000,
+		},
+		{
+			name:     "more than the held payment",
+			req:      &pb.UpdateOrderDetailsRequest{Items: []*pb.OrderItem{{Name: "Ride", Quantity: 1, Price: 60000}}},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "quoted a different total",
+			req:      &pb.UpdateOrderDetailsRequest{Items: []*pb.OrderItem{{Name: "Ride", Quantity: 1, Price: 40000}}, QuotedTotal: 30000},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, repos := newTestOrderService(newHoldPayments())
+			orderID := acceptedRide(t, s, repos)
+
+			tt.req.OrderId = orderID
+			tt.req.UserId = testUserID
+			_, err := s.UpdateOrderDetails(ctx, tt.req)
+			if tt.wantCode != codes.OK {
+				wantCode(t, err, tt.wantCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateOrderDetails: %v", err)
+			}
+
+			order, err := repos.orders.GetOrderByID(ctx, orderID)
+			if err != nil {
+				t.Fatalf("GetOrderByID: %v", err)
+			}
+			if order.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", order.Status, tt.wantStatus)
+			}
+			if order.TotalPrice != tt.wantTotal {
+				t.Errorf("total = %d, want %d", order.TotalPrice, tt.wantTotal)
+			}
+			last := order.StatusHistory[len(order.StatusHistory)-1]
+			if last.UpdatedBy != testUserID || last.Notes == "" {
+				t.Errorf("last history entry by %s with notes %q, want the user's change log", last.UpdatedBy, last.Notes)
+			}
+		})
+	}
+}
+
+func TestUpdateOrderDetailsRefusesChanges(t *testing.T) {
+	const orderID = "8e3a1d5c-2b4f-4a67-9c18-5f0d7e2b6a94"
+	otherUserID := "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"
+	items := []*pb.OrderItem{{Name: "Ride", Quantity: 1, Price: 40000}}
+
+	tests := []struct {
+		name      string
+		orderType model.OrderType
+		status    model.OrderStatus
+		userID    string
+		items     []*pb.OrderItem
+		wantCode  codes.Code
+	}{
+		{name: "someone else's order", orderType: model.TypeRide, status: model.StatusProviderAccepted, userID: otherUserID, items: items, wantCode: codes.PermissionDenied},
+		{name: "after pickup", orderType: model.TypeRide, status: model.StatusInTransit, userID: testUserID, items: items, wantCode: codes.FailedPrecondition},
+		{name: "food items", orderType: model.TypeFoodDelivery, status: model.StatusProviderAccepted, userID: testUserID, items: items, wantCode: codes.FailedPrecondition},
+		{name: "rental items", orderType: model.TypeRental, status: model.StatusCreated, userID: testUserID, items: items, wantCode: codes.FailedPrecondition},
+		{name: "nothing", orderType: model.TypeRide, status: model.StatusCreated, userID: testUserID, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, repos := newTestOrderService(&capturePayments{})
+			storeTestOrder(t, repos, orderID, tt.orderType, tt.status)
+
+			_, err := s.UpdateOrderDetails(ctx, &pb.UpdateOrderDetailsRequest{OrderId: orderID, UserId: tt.userID, Items: tt.items})
+			wantCode(t, err, tt.wantCode)
+
+			order, err := repos.orders.GetOrderByID(ctx, orderID)
+			if err != nil {
+				t.Fatalf("GetOrderByID: %v", err)
+			}
+			if order.Status != tt.status || order.TotalPrice != 100000 {
+				t.Errorf("order now %s for %d, want it unchanged", order.Status, order.TotalPrice)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// HandOffOrder moves an active order to another provider mid-delivery, after a breakdown
+// or an emergency. The new provider carries on from where the order was handed off, and
+// once it completes the fare is split by how far along the trip each provider took it.
+func (s *OrderService) HandOffOrder(ctx context.Context, req *pb.HandOffOrderRequest) (*pb.HandOffOrderResponse, error) {
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if err := checkOrderNotFrozen(order); err != nil {
+		return nil, err
+	}
+	if !req.ByAdmin && order.ProviderID != req.RequestedBy {
+		return nil, status.Errorf(codes.PermissionDenied, "only an admin or the order's provider can hand it off")
+	}
+	if !order.Status.Taken() {
+		return nil, status.Errorf(codes.FailedPrecondition, "only an order a provider has taken can be handed off")
+	}
+	if order.ProviderID == req.NewProviderId {
+		return nil, status.Errorf(codes.InvalidArgument, "order is already with this provider")
+	}
+
+	blocked, err := s.providerMatcher.Blocked(ctx, order.UserID, req.NewProviderId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	if blocked {
+		return nil, status.Errorf(codes.FailedPrecondition, "the user has blocked this provider")
+	}
+	provider, err := s.providerClient.GetProviderDetails(ctx, req.NewProviderId)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to get provider: %v", err)
+	}
+	// Suspended and offline providers are kept out of matching, and out of handoffs too
+	if provider.Suspended {
+		return nil, status.Errorf(codes.FailedPrecondition, "provider is suspended")
+	}
+	if !provider.IsAvailable {
+		return nil, status.Errorf(codes.FailedPrecondition, "provider is not available")
+	}
+	ok, err := s.providerHasCapacity(ctx, order, *provider)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to count provider's active orders: %v", err)
+	}
+	if !ok {
+		return nil, status.Errorf(codes.ResourceExhausted, "provider cannot take more %s orders at once", order.OrderType)
+	}
+
+	// The new provider takes over where the order's provider last reported being
+	lat, lon, err := s.tripPosition(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	handoff := &model.OrderHandoff{
+		ID:             uuid.New().String(),
+		OrderID:        order.ID,
+		FromProviderID: order.ProviderID,
+		ToProviderID:   req.NewProviderId,
+		RequestedBy:    req.RequestedBy,
+		Reason:         req.Reason,
+		Latitude:       lat,
+		Longitude:      lon,
+		TripProgress:   tripProgress(order, lat, lon),
+		HandedOffAt:    time.Now(),
+	}
+	order.ProviderID = handoff.ToProviderID
+	order.AddStatusHistory(order.Status, req.RequestedBy, fmt.Sprintf("Handed off from provider %s to %s %.0f%% of the way: %s",
+		handoff.FromProviderID, handoff.ToProviderID, handoff.TripProgress*100, handoff.Reason))
+
+	if err := s.repo.HandOffOrder(ctx, handoff, order.StatusHistory[len(order.StatusHistory)-1]); err != nil {
+		if errors.Is(err, repository.ErrProviderChanged) {
+			return nil, status.Errorf(codes.FailedPrecondition, "order's provider changed meanwhile")
+		}
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			return nil, status.Errorf(codes.FailedPrecondition, "only an order a provider has taken can be handed off")
+		}
+		if errors.Is(err, repository.ErrOrderFrozen) {
+			return nil, errOrderFrozen
+		}
+		return nil, status.Errorf(codes.Internal, "failed to hand off order: %v", err)
+	}
+	s.recordVehicle(ctx, order.ID, handoff.ToProviderID)
+	s.continueLocationHistory(ctx, handoff)
+
+	// Record on blockchain asynchronously
+	s.blockchainRecorder.Record(ctx, order.ID)
+
+	s.notifyHandoff(order, handoff)
+
+	return &pb.HandOffOrderResponse{
+		Handoff: convertOrderHandoffToProto(handoff),
+		Order:   convertOrderToProto(order),
+		Message: "Order handed off successfully",
+		Success: true,
+	}, nil
+}
+
+// tripProgress is the share of an order's trip made once its provider is at a position:
+// none before pickup, then the distance from the pickup over that and the distance left
+func tripProgress(order *model.Order, lat, lon float64) float64 {
+	switch order.Status {
+	case model.StatusPickedUp, model.StatusInTransit, model.StatusArrived:
+	default:
+		return 0
+	}
+
+	covered := haversineKm(order.PickupLocation.Latitude, order.PickupLocation.Longitude, lat, lon)
+	remaining := haversineKm(lat, lon, order.DestinationLocation.Latitude, order.DestinationLocation.Longitude)
+	if covered+remaining == 0 {
+		return 0
+	}
+	return covered / (covered + remaining)
+}
+
+// continueLocationHistory starts the new provider's locations for a handed off order at
+// the last position reported for it, so its route and TrackOrder streams carry on without
+// a gap. Orders without a reported location are left alone.
+func (s *OrderService) continueLocationHistory(ctx context.Context, handoff *model.OrderHandoff) {
+	if _, err := s.locationRepo.GetLatestOrderLocation(ctx, handoff.OrderID); err != nil {
+		if !errors.Is(err, repository.ErrOrderLocationNotFound) {
+			fmt.Printf("Failed to get latest location of order %s: %v\n", handoff.OrderID, err)
+		}
+		return
+	}
+
+	location := &model.OrderLocation{
+		OrderID:    handoff.OrderID,
+		ProviderID: handoff.ToProviderID,
+		Latitude:   handoff.Latitude,
+		Longitude:  handoff.Longitude,
+		Timestamp:  handoff.HandedOffAt,
+	}
+	if err := s.locationRepo.CreateOrderLocation(ctx, location); err != nil {
+		fmt.Printf("Failed to save handoff location of order %s: %v\n", handoff.OrderID, err)
+		return
+	}
+	s.publishLocation(ctx, location)
+}
+
+// notifyHandoff tells the user of a handed off order about their new provider, and both
+// providers about the handoff
+func (s *OrderService) notifyHandoff(order *model.Order, handoff *model.OrderHandoff) {
+	payload := map[string]interface{}{
+		"order_id":         order.ID,
+		"handoff_id":       handoff.ID,
+		"from_provider_id": handoff.FromProviderID,
+		"to_provider_id":   handoff.ToProviderID,
+	}
+
+	go func() {
+		ctx := context.Background()
+		notifications := []struct {
+			recipientID, recipientType, notificationType, title, message string
+		}{
+			{order.UserID, "USER", "PROVIDER_REPLACED", "New provider",
+				fmt.Sprintf("Another provider is taking over order %s", order.ID)},
+			{handoff.ToProviderID, "PROVIDER", "ORDER_HANDED_OVER", "Order handed over to you",
+				fmt.Sprintf("Take over order %s from where it was handed off", order.ID)},
+			{handoff.FromProviderID, "PROVIDER", "ORDER_HANDED_OFF", "Order handed off",
+				fmt.Sprintf("Order %s was handed off to another provider: %s", order.ID, handoff.Reason)},
+		}
+		for _, n := range notifications {
+			err := s.notificationClient.SendNotification(ctx, n.recipientID, n.recipientType, n.notificationType, n.title, n.message, payload)
+			if err != nil {
+				fmt.Printf("Failed to notify %s of order handoff: %v\n", n.recipientType, err)
+			}
+		}
+	}()
+}
+
+func convertOrderHandoffToProto(handoff *model.OrderHandoff) *pb.OrderHandoff {
+	return &pb.OrderHandoff{
+		Id:             handoff.ID,
+		FromProviderId: handoff.FromProviderID,
+		ToProviderId:   handoff.ToProviderID,
+		RequestedBy:    handoff.RequestedBy,
+		Reason:         handoff.Reason,
+		Location:       &pb.Location{Latitude: handoff.Latitude, Longitude: handoff.Longitude},
+		TripProgress:   handoff.TripProgress,
+		HandedOffAt:    timestamppb.New(handoff.HandedOffAt),
+	}
+}
@@ -0,0 +1,45 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/grpc/codes"
+)
+
+func TestHandOffOrderRefusals(t *testing.T) {
+	const orderID = "6f1c8e2a-9b3d-4a57-8e0c-2d4b6a8f1e35"
+	newProviderID := "0d9e8f7a-6b5c-4d3e-9f2a-1b0c9d8e7f6a"
+
+	tests := []struct {
+		name          string
+		status        model.OrderStatus
+		requestedBy   string
+		byAdmin       bool
+		newProviderID string
+		wantCode      codes.Code
+	}{
+		{name: "missing order", status: model.StatusInTransit, requestedBy: testProviderID, newProviderID: newProviderID, wantCode: codes.NotFound},
+		{name: "someone else's order", status: model.StatusInTransit, requestedBy: testUserID, newProviderID: newProviderID, wantCode: codes.PermissionDenied},
+		{name: "not yet accepted", status: model.StatusProviderAssigned, requestedBy: testProviderID, newProviderID: newProviderID, wantCode: codes.FailedPrecondition},
+		{name: "already delivered", status: model.StatusDelivered, requestedBy: "admin", byAdmin: true, newProviderID: newProviderID, wantCode: codes.FailedPrecondition},
+		{name: "to the same provider", status: model.StatusInTransit, requestedBy: "admin", byAdmin: true, newProviderID: testProviderID, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, repos := newTestOrderService(&capturePayments{})
+			if tt.wantCode != codes.NotFound {
+				storeTestOrder(t, repos, orderID, model.TypeRide, tt.status)
+			}
+
+			_, err := s.HandOffOrder(ctx, &pb.HandOffOrderRequest{
+				OrderId: orderID, NewProviderId: tt.newProviderID, RequestedBy: tt.requestedBy, ByAdmin: tt.byAdmin, Reason: "Breakdown",
+			})
+			wantCode(t, err, tt.wantCode)
+		})
+	}
+}
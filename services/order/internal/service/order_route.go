@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/order-api-microservices/pkg/polyline"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GetOrderRoute returns the whole path an order's provider travelled as an encoded
+// polyline, with its distance, duration and average speed
+func (s *OrderService) GetOrderRoute(ctx context.Context, req *pb.GetOrderRouteRequest) (*pb.OrderRouteResponse, error) {
+	if _, err := s.repo.GetOrderByID(ctx, req.OrderId); err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	resp := &pb.OrderRouteResponse{OrderId: req.OrderId}
+
+	var path []polyline.Point
+	var startedAt, endedAt time.Time
+
+	track, err := s.locationRepo.GetTrack(ctx, req.OrderId)
+	switch {
+	case err == nil:
+		path, err = polyline.Decode(track.Polyline)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to decode order track: %v", err)
+		}
+		resp.Polyline = track.Polyline
+		resp.Archived = true
+		startedAt, endedAt = track.StartedAt, track.EndedAt
+	case errors.Is(err, repository.ErrOrderTrackNotFound):
+		locations, err := s.locationRepo.ListOrderLocations(ctx, req.OrderId)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get location history: %v", err)
+		}
+		if len(locations) == 0 {
+			return resp, nil
+		}
+		path = toPolylinePoints(locations)
+		resp.Polyline = polyline.Encode(path)
+		startedAt, endedAt = locations[0].Timestamp, locations[len(locations)-1].Timestamp
+	default:
+		return nil, status.Errorf(codes.Internal, "failed to get order track: %v", err)
+	}
+
+	resp.PointCount = int32(len(path))
+	resp.DistanceKm = pathDistanceKm(path)
+	resp.StartedAt = timestamppb.New(startedAt)
+	resp.EndedAt = timestamppb.New(endedAt)
+
+	duration := endedAt.Sub(startedAt)
+	resp.DurationSeconds = int64(duration.Seconds())
+	if duration > 0 {
+		resp.AverageSpeedKmh = resp.DistanceKm / duration.Hours()
+	}
+
+	return resp, nil
+}
+
+// pathDistanceKm returns the length of a path in kilometers
+func pathDistanceKm(path []polyline.Point) float64 {
+	distance := 0.0
+	for i := 1; i < len(path); i++ {
+		distance += haversineKm(path[i-1].Latitude, path[i-1].Longitude, path[i].Latitude, path[i].Longitude)
+	}
+	return distance
+}
@@ -2,14 +2,17 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/fanout"
+	"github.com/order-api-microservices/pkg/money"
+	pb "github.com/order-api-microservices/proto/order"
 	"github.com/order-api-microservices/services/order/internal/model"
 	"github.com/order-api-microservices/services/order/internal/repository"
-	pb "github.com/order-api-microservices/proto/order"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -19,74 +22,370 @@ import (
 type BlockchainClient interface {
 	RecordOrder(ctx context.Context, orderID, userID, providerID string, orderData interface{}) (string, error)
 	VerifyOrder(ctx context.Context, orderID, txHash string) (bool, error)
+	CreateCryptoPayment(ctx context.Context, orderID string, amount int64) error
+	GetCryptoPaymentStatus(ctx context.Context, orderID string) (string, error)
+}
+
+// PaymentClient is an interface for interacting with the payment service
+type PaymentClient interface {
+	RefundPayment(ctx context.Context, orderID, userID string, amount int64, reason, idempotencyKey string) (string, error)
+	CapturePayment(ctx context.Context, orderID, userID string, amount int64, description, idempotencyKey string) (string, error)
+}
+
+// NotificationClient is an interface for interacting with the notification service
+type NotificationClient interface {
+	SendNotification(ctx context.Context, recipientID, recipientType, notificationType, title, message string, payload map[string]interface{}) error
+}
+
+// OrderRepository stores the orders the order service works on. It is implemented by
+// repository.OrderRepository on Postgres and by memory.OrderRepository for tests.
+type OrderRepository interface {
+	CreateOrder(ctx context.Context, order *model.Order, duplicateSince time.Time) error
+	GetOrderByID(ctx context.Context, orderID string) (*model.Order, error)
+	UpdateOrder(ctx context.Context, order *model.Order) error
+	UpdateOrderDetails(ctx context.Context, order *model.Order, from model.OrderStatus, entry model.StatusHistory) error
+	SetBlockchainTxHash(ctx context.Context, orderID, txHash string) error
+	SetProviderID(ctx context.Context, orderID, providerID string) error
+	MarkPickupArrival(ctx context.Context, orderID string, at time.Time) (bool, error)
+	UpdateOrderStatus(ctx context.Context, orderID string, status model.OrderStatus, updatedBy, notes string) error
+	UpdateOrderStatusFrom(ctx context.Context, orderID string, from, to model.OrderStatus, updatedBy, notes string) (bool, error)
+	CancelOrder(ctx context.Context, orderID string, from model.OrderStatus, cancelledBy, reason, notes string, fee int64) error
+	CountActiveProviderOrders(ctx context.Context, providerID, excludeOrderID string, statuses []model.OrderStatus) (map[model.OrderType]int, error)
+	ListBatchAnchorIDs(ctx context.Context, orderType model.OrderType, statuses []model.OrderStatus, excludeOrderID string, minLat, maxLat, minLon, maxLon float64) ([]string, error)
+	ListUserOrders(ctx context.Context, userID string, page, limit int, status model.OrderStatus) ([]*model.Order, int, error)
+	ListActiveUserIDs(ctx context.Context, since time.Time, afterID string, limit int) ([]string, error)
+	ListProviderOrders(ctx context.Context, providerID string, page, limit int, status model.OrderStatus) ([]*model.Order, int, error)
+	ExportOrders(ctx context.Context, filter model.OrderExportFilter, fn func(*model.Order) error) error
+	CreateDestinationChange(ctx context.Context, change *model.DestinationChange, lapsedBefore time.Time) error
+	GetDestinationChange(ctx context.Context, changeID string) (*model.DestinationChange, error)
+	AcceptDestinationChange(ctx context.Context, change *model.DestinationChange, order *model.Order, entry model.StatusHistory) error
+	DeclineDestinationChange(ctx context.Context, change *model.DestinationChange) error
+	HandOffOrder(ctx context.Context, handoff *model.OrderHandoff, entry model.StatusHistory) error
+}
+
+// LocationRepository stores the locations providers report while carrying out orders.
+// It is implemented by repository.OrderLocationRepository on Postgres and by
+// memory.LocationRepository for tests.
+type LocationRepository interface {
+	CreateOrderLocation(ctx context.Context, orderLocation *model.OrderLocation) error
+	CreateOrderLocations(ctx context.Context, locations []*model.OrderLocation) (int64, error)
+	GetLatestOrderLocation(ctx context.Context, orderID string) (*model.OrderLocation, error)
+	GetOrderLocationHistory(ctx context.Context, orderID string, limit int) ([]*model.OrderLocation, error)
+	ListOrderLocations(ctx context.Context, orderID string) ([]*model.OrderLocation, error)
+	GetTrack(ctx context.Context, orderID string) (*model.OrderTrack, error)
+}
+
+// RefundRepository stores the refunds issued for orders. It is implemented by
+// repository.RefundRepository on Postgres and by memory.RefundRepository for tests.
+type RefundRepository interface {
+	CreateRefund(ctx context.Context, refund *model.Refund) error
 }
 
-// ProviderClient is an interface for interacting with the provider service
-type ProviderClient interface {
-	FindBestProviders(ctx context.Context, order *model.Order, count int) ([]Provider, error)
-	NotifyProviders(ctx context.Context, order *model.Order, providers []Provider) error
+// LedgerRepository stores the fares and tips credited to providers. It is implemented by
+// repository.LedgerRepository on Postgres and by memory.LedgerRepository for tests.
+type LedgerRepository interface {
+	AddTip(ctx context.Context, entry *model.LedgerEntry) error
+	ListProviderEntries(ctx context.Context, providerID string, page, limit int) ([]*model.LedgerEntry, int, int64, error)
+}
+
+// PaymentShareRepository stores the shares of split payments. It is implemented by
+// repository.PaymentShareRepository on Postgres and by memory.PaymentShareRepository for
+// tests.
+type PaymentShareRepository interface {
+	CreateShares(ctx context.Context, shares []*model.PaymentShare) error
+	ListOrderShares(ctx context.Context, orderID string) ([]*model.PaymentShare, error)
+}
+
+// DeliveryProofRepository stores proofs of delivery. It is implemented by
+// repository.DeliveryProofRepository on Postgres and by memory.DeliveryProofRepository
+// for tests.
+type DeliveryProofRepository interface {
+	CompleteDelivery(ctx context.Context, proof *model.DeliveryProof) error
+}
+
+// DeliveryPINRepository stores the PINs users give their provider to confirm a delivery.
+// It is implemented by repository.DeliveryPINRepository on Postgres and by
+// memory.DeliveryPINRepository for tests.
+type DeliveryPINRepository interface {
+	CreatePIN(ctx context.Context, pin *model.DeliveryPIN) error
+	GetPIN(ctx context.Context, orderID string) (*model.DeliveryPIN, error)
+	ReplacePIN(ctx context.Context, orderID, pinHash string, at time.Time) error
+	VerifyPIN(ctx context.Context, orderID, pinHash string, maxAttempts int, lockout time.Duration, at time.Time) (int, error)
+}
+
+// OrderBatchRepository stores the batches providers deliver orders in. It is implemented
+// by repository.OrderBatchRepository on Postgres and by memory.OrderBatchRepository for
+// tests.
+type OrderBatchRepository interface {
+	SaveBatch(ctx context.Context, batch *model.OrderBatch) error
+	GetBatchByOrder(ctx context.Context, orderID string) (*model.OrderBatch, error)
+}
+
+// RentalRepository stores the bookings of rental orders and their extensions. It is
+// implemented by repository.RentalRepository on Postgres and by memory.RentalRepository
+// for tests.
+type RentalRepository interface {
+	CreateRental(ctx context.Context, rental *model.Rental) error
+	GetRental(ctx context.Context, orderID string) (*model.Rental, error)
+	StartRental(ctx context.Context, orderID string, at time.Time) (bool, error)
+	BillOvertime(ctx context.Context, rental *model.Rental, order *model.Order) error
+	CreateExtension(ctx context.Context, extension *model.RentalExtension) error
+	GetExtension(ctx context.Context, extensionID string) (*model.RentalExtension, error)
+	ListExtensions(ctx context.Context, orderID string) ([]*model.RentalExtension, error)
+	ApproveExtension(ctx context.Context, extension *model.RentalExtension, order *model.Order) error
+	DeclineExtension(ctx context.Context, extension *model.RentalExtension) error
+}
+
+// OrderVehicleRepository stores the vehicles orders were accepted in. It is implemented
+// by repository.OrderVehicleRepository on Postgres and by memory.OrderVehicleRepository
+// for tests.
+type OrderVehicleRepository interface {
+	ReplaceVehicle(ctx context.Context, orderID string, vehicle *model.OrderVehicle) error
+	GetVehicle(ctx context.Context, orderID string) (*model.OrderVehicle, error)
+}
+
+// CatalogRepository reads the merchants and catalogs merchant orders are priced from. It
+// is implemented by repository.MerchantRepository on Postgres and by
+// memory.MerchantRepository for tests.
+type CatalogRepository interface {
+	GetMerchant(ctx context.Context, merchantID string) (*model.Merchant, error)
+	GetCatalogItems(ctx context.Context, merchantID string, itemIDs []string) (map[string]*model.CatalogItem, error)
 }
 
 // OrderService handles the business logic for orders
 type OrderService struct {
 	pb.UnimplementedOrderServiceServer
-	repo               *repository.OrderRepository
-	locationRepo       *repository.OrderLocationRepository
+	repo               OrderRepository
+	locationRepo       LocationRepository
+	refundRepo         RefundRepository
+	ledgerRepo         LedgerRepository
+	shareRepo          PaymentShareRepository
+	proofRepo          DeliveryProofRepository
+	pinRepo            DeliveryPINRepository
+	batchRepo          OrderBatchRepository
+	rentalRepo         RentalRepository
+	vehicleRepo        OrderVehicleRepository
+	merchantRepo       CatalogRepository
+	paymentMethods     PaymentMethodRepository
+	wallets            WalletRepository
+	loyalty            *Loyalty
+	reservations       ReservationClient
+	stockHold          time.Duration
 	blockchainClient   BlockchainClient
+	blockchainRecorder OrderRecorder
 	providerClient     ProviderClient
+	paymentClient      PaymentClient
+	notificationClient NotificationClient
 	providerMatcher    *ProviderMatcher
+	splitCollector     *SplitPaymentCollector
+	authorizations     *PaymentAuthorizations
+	feeSchedule        *FeeSchedule
+	cancellationPolicy CancellationPolicy
+	deliveryPINPolicy  DeliveryPINPolicy
+	geofencePolicy     GeofencePolicy
+	samplingPolicy     LocationSamplingPolicy
+	concurrencyPolicy  ConcurrencyPolicy
+	batchingPolicy     BatchingPolicy
+	rentalPolicy       RentalPolicy
+	packagePolicy      PackagePolicy
+	duplicatePolicy    DuplicatePolicy
+	pricingPolicy      PricingPolicy
+	editPolicy         OrderEditPolicy
+	returnPolicy       ReturnPolicy
+	dispatcher         *Dispatcher
+	dispatchOffers     *DispatchOffers
+	serviceAreas       *ServiceAreas
+	predictor          Predictor
+	locationBus        fanout.Bus
 }
 
 // NewOrderService creates a new order service
 func NewOrderService(
-	repo *repository.OrderRepository,
-	locationRepo *repository.OrderLocationRepository,
+	repo OrderRepository,
+	locationRepo LocationRepository,
+	refundRepo RefundRepository,
+	ledgerRepo LedgerRepository,
+	shareRepo PaymentShareRepository,
+	proofRepo DeliveryProofRepository,
+	pinRepo DeliveryPINRepository,
+	batchRepo OrderBatchRepository,
+	rentalRepo RentalRepository,
+	vehicleRepo OrderVehicleRepository,
+	merchantRepo CatalogRepository,
+	paymentMethods PaymentMethodRepository,
+	wallets WalletRepository,
+	loyalty *Loyalty,
+	reservations ReservationClient,
+	stockHold time.Duration,
+	userProviderRepo *repository.UserProviderRepository,
 	blockchainClient BlockchainClient,
+	blockchainRecorder OrderRecorder,
 	providerClient ProviderClient,
+	paymentClient PaymentClient,
+	notificationClient NotificationClient,
+	splitCollector *SplitPaymentCollector,
+	authorizations *PaymentAuthorizations,
+	feeSchedule *FeeSchedule,
+	cancellationPolicy CancellationPolicy,
+	deliveryPINPolicy DeliveryPINPolicy,
+	geofencePolicy GeofencePolicy,
+	samplingPolicy LocationSamplingPolicy,
+	concurrencyPolicy ConcurrencyPolicy,
+	batchingPolicy BatchingPolicy,
+	rentalPolicy RentalPolicy,
+	packagePolicy PackagePolicy,
+	duplicatePolicy DuplicatePolicy,
+	pricingPolicy PricingPolicy,
+	editPolicy OrderEditPolicy,
+	returnPolicy ReturnPolicy,
+	dispatcher *Dispatcher,
+	dispatchOffers *DispatchOffers,
+	serviceAreas *ServiceAreas,
+	predictor Predictor,
+	locationBus fanout.Bus,
 ) *OrderService {
-	providerMatcher := NewProviderMatcher(providerClient)
-	
+	providerMatcher := NewProviderMatcher(providerClient, dispatcher, dispatchOffers, serviceAreas, userProviderRepo)
+
 	return &OrderService{
 		repo:               repo,
 		locationRepo:       locationRepo,
+		refundRepo:         refundRepo,
+		ledgerRepo:         ledgerRepo,
+		shareRepo:          shareRepo,
+		proofRepo:          proofRepo,
+		pinRepo:            pinRepo,
+		batchRepo:          batchRepo,
+		rentalRepo:         rentalRepo,
+		vehicleRepo:        vehicleRepo,
+		merchantRepo:       merchantRepo,
+		paymentMethods:     paymentMethods,
+		wallets:            wallets,
+		loyalty:            loyalty,
+		reservations:       reservations,
+		stockHold:          stockHold,
 		blockchainClient:   blockchainClient,
+		blockchainRecorder: blockchainRecorder,
 		providerClient:     providerClient,
+		paymentClient:      paymentClient,
+		notificationClient: notificationClient,
 		providerMatcher:    providerMatcher,
+		splitCollector:     splitCollector,
+		authorizations:     authorizations,
+		feeSchedule:        feeSchedule,
+		cancellationPolicy: cancellationPolicy,
+		deliveryPINPolicy:  deliveryPINPolicy,
+		geofencePolicy:     geofencePolicy,
+		samplingPolicy:     samplingPolicy,
+		concurrencyPolicy:  concurrencyPolicy,
+		batchingPolicy:     batchingPolicy,
+		rentalPolicy:       rentalPolicy,
+		packagePolicy:      packagePolicy,
+		duplicatePolicy:    duplicatePolicy,
+		pricingPolicy:      pricingPolicy,
+		editPolicy:         editPolicy,
+		returnPolicy:       returnPolicy,
+		dispatcher:         dispatcher,
+		dispatchOffers:     dispatchOffers,
+		serviceAreas:       serviceAreas,
+		predictor:          predictor,
+		locationBus:        locationBus,
 	}
 }
 
 // CreateOrder creates a new order
 func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.OrderResponse, error) {
-	// Validate the request
-	if req.UserId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
-	}
-	if req.PickupLocation == nil || req.DestinationLocation == nil {
-		return nil, status.Errorf(codes.InvalidArgument, "pickup and destination locations are required")
-	}
-
 	// Create new order
 	orderID := uuid.New().String()
 	now := time.Now()
-	
+
 	// Initialize order with data from request
 	order := &model.Order{
-		ID:                 orderID,
-		UserID:             req.UserId,
-		OrderType:          convertOrderType(req.OrderType),
-		Status:             model.StatusCreated,
-		PickupLocation:     convertLocation(req.PickupLocation),
+		ID:                  orderID,
+		UserID:              req.UserId,
+		OrderType:           convertOrderType(req.OrderType),
+		Status:              model.StatusCreated,
+		PickupLocation:      convertLocation(req.PickupLocation),
 		DestinationLocation: convertLocation(req.DestinationLocation),
-		Items:              convertOrderItems(req.Items),
-		PaymentMethod:      convertPaymentMethod(req.PaymentMethod),
-		Notes:              req.Notes,
-		CreatedAt:          now,
-		UpdatedAt:          now,
+		Items:               convertOrderItems(req.Items),
+		PaymentMethod:       convertPaymentMethod(req.PaymentMethod),
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+
+	// Returns go back the way the delivery they return came
+	if order.OrderType == model.TypeReturn {
+		if err := s.prepareReturn(ctx, order, req.ReturnOfOrderId, req.MerchantId, now); err != nil {
+			return nil, err
+		}
 	}
 
-	// Calculate total price and fees
-	order.TotalPrice = calculateTotalPrice(order.Items)
-	order.CalculateFees()
+	// Notes for the whole order are kept as the destination's instructions, unless the
+	// destination has its own
+	if req.Notes != "" {
+		if order.DestinationLocation.Instructions == nil {
+			order.DestinationLocation.Instructions = &model.StopInstructions{}
+		}
+		if order.DestinationLocation.Instructions.Notes == "" {
+			order.DestinationLocation.Instructions.Notes = req.Notes
+		} else {
+			order.Notes = req.Notes
+		}
+	}
+
+	// Orders are only taken inside the active service areas
+	if _, ok := s.serviceAreas.Locate(order.PickupLocation); !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "pickup location is outside our service areas")
+	}
+
+	// Saved payment methods set the order's payment method
+	if err := s.applySavedPaymentMethod(ctx, req, order, now); err != nil {
+		return nil, err
+	}
+
+	// Items ordered from a merchant are named and priced by its catalog, not the client
+	if req.MerchantId != "" {
+		if err := s.priceFromCatalog(ctx, order.OrderType, req.MerchantId, order.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	// Calculate total price and fees; rentals are priced by the hours booked, and returns
+	// by their trip alone
+	var rental *model.Rental
+	if order.OrderType == model.TypeRental {
+		booked, err := s.rentalPolicy.book(order.ID, req.RentalHours, now)
+		if err != nil {
+			return nil, err
+		}
+		rental = booked
+		order.TotalPrice = rental.HourlyRate * int64(rental.BookedHours)
+	} else if order.OrderType == model.TypeReturn {
+		order.TotalPrice = s.returnPolicy.fare(s.pricingPolicy.expectedTotal(order, 0))
+	} else {
+		order.TotalPrice = calculateTotalPrice(order.Items)
+	}
+
+	// Packages cost more the bigger their size class
+	var surcharge int64
+	if order.OrderType == model.TypePackageDelivery {
+		charge, err := s.packagePolicy.surcharge(order.Items)
+		if err != nil {
+			return nil, err
+		}
+		surcharge = charge
+		order.TotalPrice += surcharge
+	}
+
+	// Prices the client chose must fit the trip, and match what the user was quoted
+	if err := s.pricingPolicy.validate(order, rental == nil && req.MerchantId == "" && order.ReturnOfOrderID == "", surcharge, req.QuotedTotal); err != nil {
+		return nil, err
+	}
+	s.feeSchedule.Apply(order)
+
+	// Loyalty points the user redeems come off the total, taken now
+	if err := s.loyalty.redeem(ctx, order, req.RedeemPoints); err != nil {
+		return nil, err
+	}
 
 	// Add initial status history
 	order.StatusHistory = []model.StatusHistory{
@@ -98,29 +397,113 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderReque
 		},
 	}
 
-	// Store order in database
-	err := s.repo.CreateOrder(ctx, order)
+	// A split order waits for every payer's share before its payment completes
+	if len(req.PaymentShares) > 0 {
+		shares, err := buildPaymentShares(order, req.PaymentShares)
+		if err != nil {
+			s.returnRedeemedPoints(ctx, order)
+			return nil, err
+		}
+		order.PaymentShares = shares
+		order.AddStatusHistory(model.StatusPaymentPending, "system", fmt.Sprintf("Payment split between %d payers", len(shares)))
+	}
+
+	// A crypto order waits for its on-chain payment to be confirmed
+	if order.PaymentMethod == model.PaymentCrypto {
+		order.AddStatusHistory(model.StatusPaymentPending, "system", "Awaiting crypto payment")
+	}
+
+	// Part or all of the total may be paid from the user's wallet balance, taken now
+	if err := s.payFromWallet(ctx, order, req.WalletAmount); err != nil {
+		s.returnRedeemedPoints(ctx, order)
+		return nil, err
+	}
+
+	// Card and wallet payments are held now and only captured once the order completes
+	if s.authorizations.authorizes(order) {
+		auth, err := s.authorizations.authorize(ctx, order)
+		if err != nil {
+			s.returnWalletPayment(ctx, order)
+			s.returnRedeemedPoints(ctx, order)
+			return nil, err
+		}
+		order.Authorization = auth
+	}
+
+	// Hold the stock of catalog items first, so an order is never stored without it
+	if req.MerchantId != "" {
+		if err := s.reserveStock(ctx, order, req.MerchantId); err != nil {
+			s.authorizations.discard(ctx, order.Authorization)
+			s.returnWalletPayment(ctx, order)
+			s.returnRedeemedPoints(ctx, order)
+			return nil, err
+		}
+	}
+
+	// Store order in database, unless the user has just placed the same order
+	err := s.repo.CreateOrder(ctx, order, s.duplicatePolicy.since(now))
 	if err != nil {
+		if req.MerchantId != "" {
+			s.releaseStock(ctx, order.ID)
+		}
+		s.authorizations.discard(ctx, order.Authorization)
+		s.returnWalletPayment(ctx, order)
+		s.returnRedeemedPoints(ctx, order)
+		if errors.Is(err, repository.ErrDuplicateOrder) {
+			return nil, duplicateOrderError(ctx, order.ID, err)
+		}
+		if errors.Is(err, repository.ErrReturnExists) {
+			return nil, status.Errorf(codes.AlreadyExists, "order %s already has an open return", order.ReturnOfOrderID)
+		}
 		return nil, status.Errorf(codes.Internal, "failed to create order: %v", err)
 	}
+	// An order stored without the rows below is cancelled again and everything taken or
+	// held for it given back, so the user can place it again
+	if rental != nil {
+		if err := s.rentalRepo.CreateRental(ctx, rental); err != nil {
+			s.abandonOrder(ctx, order, req.MerchantId)
+			return nil, status.Errorf(codes.Internal, "failed to create rental: %v", err)
+		}
+	}
 
-	// Record order on blockchain
-	go func() {
-		// Using background context for async operation
-		bCtx := context.Background()
-		txHash, err := s.blockchainClient.RecordOrder(bCtx, order.ID, order.UserID, order.ProviderID, order)
-		if err != nil {
-			// In production, would use a retry mechanism or queue
-			fmt.Printf("Failed to record order on blockchain: %v\n", err)
-			return
+	if order.Authorization != nil {
+		if err := s.authorizations.record(ctx, order.Authorization); err != nil {
+			s.abandonOrder(ctx, order, req.MerchantId)
+			return nil, status.Errorf(codes.Internal, "failed to record payment authorization: %v", err)
 		}
+	}
 
-		// Update order with blockchain transaction hash
-		order.BlockchainTxHash = txHash
-		if err := s.repo.UpdateOrder(bCtx, order); err != nil {
-			fmt.Printf("Failed to update order with blockchain hash: %v\n", err)
+	if len(order.PaymentShares) > 0 {
+		if err := s.shareRepo.CreateShares(ctx, order.PaymentShares); err != nil {
+			s.abandonOrder(ctx, order, req.MerchantId)
+			return nil, status.Errorf(codes.Internal, "failed to create payment shares: %v", err)
 		}
-	}()
+	}
+
+	// The user gives this PIN to their provider to confirm the delivery
+	if err := s.issueDeliveryPIN(ctx, order); err != nil {
+		s.abandonOrder(ctx, order, req.MerchantId)
+		return nil, status.Errorf(codes.Internal, "failed to issue delivery PIN: %v", err)
+	}
+
+	if len(order.PaymentShares) > 0 {
+		// Start collecting right away; the collector retries anything that fails
+		go func() {
+			if err := s.splitCollector.Collect(context.Background(), order.ID); err != nil {
+				fmt.Printf("Failed to collect split payment: %v\n", err)
+			}
+		}()
+	}
+
+	// Request the crypto payment; if this fails the payment monitor requests it again
+	if order.PaymentMethod == model.PaymentCrypto {
+		if err := s.blockchainClient.CreateCryptoPayment(ctx, order.ID, order.TotalPrice); err != nil {
+			fmt.Printf("Failed to request crypto payment: %v\n", err)
+		}
+	}
+
+	// Record order on blockchain
+	s.blockchainRecorder.Record(ctx, order.ID)
 
 	// Build response
 	response := &pb.OrderResponse{
@@ -132,12 +515,26 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderReque
 	return response, nil
 }
 
-// GetOrder retrieves an order by ID
-func (s *OrderService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.OrderResponse, error) {
-	if req.OrderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "order ID is required")
+// abandonOrder undoes an order that was stored but could not be set up in full. The order
+// is cancelled by the system, which lets the same order be placed again, and its stock,
+// payment hold, wallet payment and redeemed points are given back as for an order that
+// was never stored. Failures are logged.
+func (s *OrderService) abandonOrder(ctx context.Context, order *model.Order, merchantID string) {
+	const reason = "Order could not be placed"
+	if err := s.repo.CancelOrder(ctx, order.ID, order.Status, "system", reason, reason, 0); err != nil {
+		fmt.Printf("Failed to cancel order %s that could not be placed: %v\n", order.ID, err)
 	}
 
+	if merchantID != "" {
+		s.releaseStock(ctx, order.ID)
+	}
+	s.authorizations.discard(ctx, order.Authorization)
+	s.returnWalletPayment(ctx, order)
+	s.returnRedeemedPoints(ctx, order)
+}
+
+// GetOrder retrieves an order by ID
+func (s *OrderService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.OrderResponse, error) {
 	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
 	if err != nil {
 		if errors.Is(err, repository.ErrOrderNotFound) {
@@ -146,6 +543,21 @@ func (s *OrderService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*
 		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
 	}
 
+	order.PaymentShares, err = s.shareRepo.ListOrderShares(ctx, order.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get payment shares: %v", err)
+	}
+
+	order.Vehicle, err = s.vehicleRepo.GetVehicle(ctx, order.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get order vehicle: %v", err)
+	}
+
+	order.Authorization, err = s.authorizations.get(ctx, order.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get payment authorization: %v", err)
+	}
+
 	return &pb.OrderResponse{
 		Order:   convertOrderToProto(order),
 		Message: "Order retrieved successfully",
@@ -155,10 +567,6 @@ func (s *OrderService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*
 
 // UpdateOrderStatus updates the status of an order
 func (s *OrderService) UpdateOrderStatus(ctx context.Context, req *pb.UpdateOrderStatusRequest) (*pb.OrderResponse, error) {
-	if req.OrderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "order ID is required")
-	}
-
 	// Get current order
 	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
 	if err != nil {
@@ -168,10 +576,37 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, req *pb.UpdateOrde
 		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
 	}
 
+	if err := checkOrderNotFrozen(order); err != nil {
+		return nil, err
+	}
+
 	// Update order status
 	newStatus := convertOrderStatusFromProto(req.Status)
+	if newStatus == model.StatusRefunded {
+		return nil, status.Errorf(codes.InvalidArgument, "use RefundOrder to refund an order")
+	}
+	if newStatus == model.StatusDelivered {
+		return nil, status.Errorf(codes.InvalidArgument, "use CompleteDelivery to deliver an order with proof")
+	}
+	if newStatus == model.StatusCancelled {
+		return nil, status.Errorf(codes.InvalidArgument, "use CancelOrder to cancel an order")
+	}
+	if newStatus == model.StatusCompleted && order.OrderType == model.TypeRental {
+		if err := s.billRentalOvertime(ctx, order); err != nil {
+			return nil, err
+		}
+	}
+	// A held payment is only charged once the order completes
+	if newStatus == model.StatusCompleted {
+		if err := s.captureAuthorization(ctx, order); err != nil {
+			return nil, err
+		}
+	}
 	err = s.repo.UpdateOrderStatus(ctx, req.OrderId, newStatus, req.UpdatedBy, req.Notes)
 	if err != nil {
+		if errors.Is(err, repository.ErrOrderFrozen) {
+			return nil, errOrderFrozen
+		}
 		return nil, status.Errorf(codes.Internal, "failed to update order status: %v", err)
 	}
 
@@ -180,22 +615,19 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, req *pb.UpdateOrde
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get updated order: %v", err)
 	}
+	if newStatus == model.StatusInProgress && updatedOrder.OrderType == model.TypeRental {
+		s.startRental(ctx, updatedOrder.ID)
+	}
 
-	// Record status change on blockchain
-	go func() {
-		bCtx := context.Background()
-		txHash, err := s.blockchainClient.RecordOrder(bCtx, updatedOrder.ID, updatedOrder.UserID, updatedOrder.ProviderID, updatedOrder)
-		if err != nil {
-			fmt.Printf("Failed to record order status change on blockchain: %v\n", err)
-			return
+	// A referred user's first completed order earns them and their referrer points
+	if newStatus == model.StatusCompleted {
+		if err := s.loyalty.rewardReferral(ctx, updatedOrder); err != nil {
+			fmt.Printf("Failed to reward the referral of order %s: %v\n", updatedOrder.ID, err)
 		}
+	}
 
-		// Update order with new blockchain transaction hash
-		updatedOrder.BlockchainTxHash = txHash
-		if err := s.repo.UpdateOrder(bCtx, updatedOrder); err != nil {
-			fmt.Printf("Failed to update order with blockchain hash: %v\n", err)
-		}
-	}()
+	// Record status change on blockchain
+	s.blockchainRecorder.Record(ctx, updatedOrder.ID)
 
 	return &pb.OrderResponse{
 		Order:   convertOrderToProto(updatedOrder),
@@ -206,10 +638,22 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, req *pb.UpdateOrde
 
 // CancelOrder cancels an order
 func (s *OrderService) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.OrderResponse, error) {
-	if req.OrderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "order ID is required")
-	}
+	return s.cancelOrder(ctx, req, "")
+}
+
+// CancelUnpaidOrder cancels an order for the system, but only while it is still waiting
+// for payment. An order that was paid or moved on meanwhile fails with FailedPrecondition.
+func (s *OrderService) CancelUnpaidOrder(ctx context.Context, orderID, reason string) error {
+	_, err := s.cancelOrder(ctx, &pb.CancelOrderRequest{
+		OrderId:     orderID,
+		CancelledBy: "system",
+		Reason:      reason,
+	}, model.StatusPaymentPending)
+	return err
+}
 
+// cancelOrder cancels an order, only while it is still in status from unless from is empty
+func (s *OrderService) cancelOrder(ctx context.Context, req *pb.CancelOrderRequest, from model.OrderStatus) (*pb.OrderResponse, error) {
 	// Get current order
 	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
 	if err != nil {
@@ -219,19 +663,72 @@ func (s *OrderService) CancelOrder(ctx context.Context, req *pb.CancelOrderReque
 		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
 	}
 
-	// Check if order can be cancelled
-	if order.Status == model.StatusCompleted || 
-	   order.Status == model.StatusCancelled || 
-	   order.Status == model.StatusRefunded {
-		return nil, status.Errorf(codes.FailedPrecondition, "order cannot be cancelled in its current state")
+	if err := checkOrderCancellable(order); err != nil {
+		return nil, err
+	}
+	if from != "" && order.Status != from {
+		return nil, status.Errorf(codes.FailedPrecondition, "order is no longer %s", from)
+	}
+
+	notes := req.Reason
+	fee, feeReason := s.cancellationFee(order, req.CancelledBy, time.Now())
+	if fee > 0 {
+		notes = fmt.Sprintf("%s (cancellation fee %s: %s)", req.Reason, money.Format(fee), feeReason)
+	}
+
+	order.Authorization, err = s.authorizations.get(ctx, order.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get payment authorization: %v", err)
+	}
+
+	// What the wallet paid covers the fee first. Beyond that, a paid order keeps the fee
+	// out of its refund; a held payment is charged the fee and the rest released; otherwise
+	// the fee is charged before cancelling.
+	walletFee := min(fee, order.WalletAmount)
+	feeFromHold := false
+	switch charged := fee - walletFee; {
+	case charged == 0 || order.WasPaid():
+	case order.Authorization != nil && order.Authorization.Status == model.AuthorizationAuthorized:
+		if err := s.authorizations.capture(ctx, order.Authorization, min(charged, order.Authorization.Amount), time.Now()); err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to charge cancellation fee: %v", err)
+		}
+		feeFromHold = true
+	default:
+		description := fmt.Sprintf("Cancellation fee for order %s", order.ID)
+		if _, err := s.paymentClient.CapturePayment(ctx, order.ID, order.UserID, charged, description, "cancel-"+order.ID); err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to charge cancellation fee: %v", err)
+		}
 	}
 
 	// Update order status to cancelled
-	err = s.repo.UpdateOrderStatus(ctx, req.OrderId, model.StatusCancelled, req.CancelledBy, req.Reason)
+	err = s.repo.CancelOrder(ctx, req.OrderId, from, req.CancelledBy, req.Reason, notes, fee)
 	if err != nil {
+		if errors.Is(err, repository.ErrOrderFrozen) {
+			return nil, errOrderFrozen
+		}
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			return nil, status.Errorf(codes.FailedPrecondition, "order is no longer %s", from)
+		}
 		return nil, status.Errorf(codes.Internal, "failed to cancel order: %v", err)
 	}
 
+	// Stock held for the order goes back unless a provider had already taken it
+	if !order.Status.Taken() {
+		s.releaseStock(ctx, order.ID)
+	}
+
+	// So does the rest of its payment, what the wallet paid straight back to the wallet
+	if order.Authorization != nil && !feeFromHold {
+		s.settleCancelledAuthorization(ctx, order.Authorization, req)
+	}
+	if returned := order.WalletAmount - walletFee; returned > 0 {
+		description := fmt.Sprintf("Refund for cancelled order %s", order.ID)
+		if err := s.creditWallet(ctx, order, returned, "cancel-"+order.ID, description); err != nil {
+			fmt.Printf("Failed to return %s to the wallet of cancelled order %s: %v\n", money.Format(returned), order.ID, err)
+		}
+	}
+	s.loyalty.returnRedemption(ctx, order, "cancel-"+order.ID, fmt.Sprintf("Order %s was cancelled", order.ID))
+
 	// Get updated order
 	updatedOrder, err := s.repo.GetOrderByID(ctx, req.OrderId)
 	if err != nil {
@@ -239,40 +736,84 @@ func (s *OrderService) CancelOrder(ctx context.Context, req *pb.CancelOrderReque
 	}
 
 	// Record cancellation on blockchain
-	go func() {
-		bCtx := context.Background()
-		txHash, err := s.blockchainClient.RecordOrder(bCtx, updatedOrder.ID, updatedOrder.UserID, updatedOrder.ProviderID, updatedOrder)
-		if err != nil {
-			fmt.Printf("Failed to record order cancellation on blockchain: %v\n", err)
-			return
-		}
+	s.blockchainRecorder.Record(ctx, updatedOrder.ID)
 
-		// Update order with new blockchain transaction hash
-		updatedOrder.BlockchainTxHash = txHash
-		if err := s.repo.UpdateOrder(bCtx, updatedOrder); err != nil {
-			fmt.Printf("Failed to update order with blockchain hash: %v\n", err)
-		}
-	}()
+	message := "Order cancelled successfully"
+	if fee > 0 {
+		message = fmt.Sprintf("Order cancelled with a cancellation fee of %s", money.Format(fee))
+	}
 
 	return &pb.OrderResponse{
 		Order:   convertOrderToProto(updatedOrder),
-		Message: "Order cancelled successfully",
+		Message: message,
 		Success: true,
 	}, nil
 }
 
-// ListUserOrders lists orders for a specific user
-func (s *OrderService) ListUserOrders(ctx context.Context, req *pb.ListUserOrdersRequest) (*pb.ListOrdersResponse, error) {
-	if req.UserId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
+// PreviewCancellation says whether an order can be cancelled now and what it would cost,
+// so apps can show it before the user confirms
+func (s *OrderService) PreviewCancellation(ctx context.Context, req *pb.PreviewCancellationRequest) (*pb.CancellationPreviewResponse, error) {
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if err := checkOrderCancellable(order); err != nil {
+		return &pb.CancellationPreviewResponse{
+			CanCancel: false,
+			Reason:    status.Convert(err).Message(),
+		}, nil
 	}
 
-	var status model.OrderStatus
+	now := time.Now()
+	fee, feeReason := s.cancellationFee(order, req.CancelledBy, now)
+	resp := &pb.CancellationPreviewResponse{
+		CanCancel: true,
+		Fee:       fee,
+		FeeReason: feeReason,
+	}
+	if freeUntil, ok := s.cancellationPolicy.FreeUntil(order, now); ok {
+		resp.FreeUntil = timestamppb.New(freeUntil)
+	}
+	return resp, nil
+}
+
+// checkOrderCancellable rejects cancelling an order that is frozen or already finished
+func checkOrderCancellable(order *model.Order) error {
+	if err := checkOrderNotFrozen(order); err != nil {
+		return err
+	}
+	if order.Status == model.StatusCompleted ||
+		order.Status == model.StatusCancelled ||
+		order.Status == model.StatusRefunded {
+		return status.Errorf(codes.FailedPrecondition, "order cannot be cancelled in its current state")
+	}
+	return nil
+}
+
+// cancellationFee returns what cancelling an order costs the canceller, with the reason.
+// Only the user pays for cancelling; cash cannot be charged through the payment service.
+func (s *OrderService) cancellationFee(order *model.Order, cancelledBy string, at time.Time) (int64, string) {
+	if cancelledBy != order.UserID {
+		return 0, "only the ordering user pays to cancel"
+	}
+	if order.PaymentMethod == model.PaymentCash {
+		return 0, "cash orders are cancelled free of charge"
+	}
+	return s.cancellationPolicy.Fee(order, at)
+}
+
+// ListUserOrders lists orders for a specific user
+func (s *OrderService) ListUserOrders(ctx context.Context, req *pb.ListUserOrdersRequest) (*pb.ListOrdersResponse, error) {
+	var statusFilter model.OrderStatus
 	if req.Status != pb.OrderStatus_ORDER_STATUS_UNSPECIFIED {
-		status = convertOrderStatusFromProto(req.Status)
+		statusFilter = convertOrderStatusFromProto(req.Status)
 	}
 
-	orders, total, err := s.repo.ListUserOrders(ctx, req.UserId, int(req.Page), int(req.Limit), status)
+	orders, total, err := s.repo.ListUserOrders(ctx, req.UserId, int(req.Page), int(req.Limit), statusFilter)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to list user orders: %v", err)
 	}
@@ -291,18 +832,32 @@ func (s *OrderService) ListUserOrders(ctx context.Context, req *pb.ListUserOrder
 	}, nil
 }
 
-// ListProviderOrders lists orders for a specific provider
-func (s *OrderService) ListProviderOrders(ctx context.Context, req *pb.ListProviderOrdersRequest) (*pb.ListOrdersResponse, error) {
-	if req.ProviderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "provider ID is required")
+// ListActiveUserIDs pages through the IDs of the users who placed an order since a time,
+// so the notification service can broadcast to recent customers
+func (s *OrderService) ListActiveUserIDs(ctx context.Context, req *pb.ListActiveUserIDsRequest) (*pb.ListActiveUserIDsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 100
+	}
+
+	userIDs, err := s.repo.ListActiveUserIDs(ctx, req.Since.AsTime(), req.AfterId, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list active users: %v", err)
 	}
 
-	var status model.OrderStatus
+	return &pb.ListActiveUserIDsResponse{
+		UserIds: userIDs,
+	}, nil
+}
+
+// ListProviderOrders lists orders for a specific provider
+func (s *OrderService) ListProviderOrders(ctx context.Context, req *pb.ListProviderOrdersRequest) (*pb.ListOrdersResponse, error) {
+	var statusFilter model.OrderStatus
 	if req.Status != pb.OrderStatus_ORDER_STATUS_UNSPECIFIED {
-		status = convertOrderStatusFromProto(req.Status)
+		statusFilter = convertOrderStatusFromProto(req.Status)
 	}
 
-	orders, total, err := s.repo.ListProviderOrders(ctx, req.ProviderId, int(req.Page), int(req.Limit), status)
+	orders, total, err := s.repo.ListProviderOrders(ctx, req.ProviderId, int(req.Page), int(req.Limit), statusFilter)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to list provider orders: %v", err)
 	}
@@ -321,88 +876,130 @@ func (s *OrderService) ListProviderOrders(ctx context.Context, req *pb.ListProvi
 	}, nil
 }
 
-// TrackOrder streams real-time updates of an order's location
-func (s *OrderService) TrackOrder(req *pb.TrackOrderRequest, stream pb.OrderService_TrackOrderServer) error {
-	if req.OrderId == "" {
-		return status.Errorf(codes.InvalidArgument, "order ID is required")
+// ExportOrders streams every order matching the request, oldest first, for finance and
+// support exports. Orders are sent as they are read, so exports of any size stream
+// without being buffered.
+func (s *OrderService) ExportOrders(req *pb.ExportOrdersRequest, stream pb.OrderService_ExportOrdersServer) error {
+	filter := model.OrderExportFilter{
+		UserID:     req.UserId,
+		ProviderID: req.ProviderId,
 	}
-	
-	// Get order to verify it exists
-	order, err := s.repo.GetOrderByID(stream.Context(), req.OrderId)
+	if req.Status != pb.OrderStatus_ORDER_STATUS_UNSPECIFIED {
+		filter.Status = convertOrderStatusFromProto(req.Status)
+	}
+	if req.OrderType != pb.OrderType_ORDER_TYPE_UNSPECIFIED {
+		filter.OrderType = convertOrderType(req.OrderType)
+	}
+	if req.From != nil {
+		filter.From = req.From.AsTime()
+	}
+	if req.To != nil {
+		filter.To = req.To.AsTime()
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return status.Errorf(codes.InvalidArgument, "from must be before to")
+	}
+
+	err := s.repo.ExportOrders(stream.Context(), filter, func(order *model.Order) error {
+		return stream.Send(convertOrderToProto(order))
+	})
 	if err != nil {
+		if stream.Context().Err() != nil {
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+		return status.Errorf(codes.Internal, "failed to export orders: %v", err)
+	}
+
+	return nil
+}
+
+// TrackOrder streams real-time updates of an order's location. Locations are pushed as
+// they are stored, by whichever instance stores them, through the location fan-out.
+func (s *OrderService) TrackOrder(req *pb.TrackOrderRequest, stream pb.OrderService_TrackOrderServer) error {
+	ctx := stream.Context()
+
+	// Get order to verify it exists
+	if _, err := s.repo.GetOrderByID(ctx, req.OrderId); err != nil {
 		if errors.Is(err, repository.ErrOrderNotFound) {
 			return status.Errorf(codes.NotFound, "order not found")
 		}
 		return status.Errorf(codes.Internal, "failed to get order: %v", err)
 	}
-	
-	// Create a ticker to poll for updates
-	ticker := time.NewTicker(5 * time.Second)
+
+	// Subscribe before reading the latest location, so none stored in between is missed
+	subscription, err := s.locationBus.Subscribe(ctx, locationTopic(req.OrderId))
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to subscribe to location updates: %v", err)
+	}
+	defer subscription.Close()
+
+	// Poll now and then as well, to catch up on locations the fan-out lost
+	ticker := time.NewTicker(trackOrderPollInterval)
 	defer ticker.Stop()
-	
-	// Keep track of the last location sent to avoid duplicates
-	var lastLocationID string
-	
+
+	tracker := &locationTracker{service: s, stream: stream, orderID: req.OrderId}
+	if err := tracker.poll(ctx); err != nil {
+		return err
+	}
+
 	for {
 		select {
-		case <-ticker.C:
-			// Get latest location
-			location, err := s.locationRepo.GetLatestOrderLocation(stream.Context(), req.OrderId)
-			if err != nil {
-				if errors.Is(err, repository.ErrOrderLocationNotFound) {
-					// No location updates yet, just continue
-					continue
-				}
-				fmt.Printf("Error getting latest location: %v\n", err)
+		case message := <-subscription.C:
+			var location model.OrderLocation
+			if err := json.Unmarshal(message, &location); err != nil {
+				fmt.Printf("Error decoding location update: %v\n", err)
 				continue
 			}
-			
-			// Skip if this is the same location we already sent
-			if location.ID == lastLocationID {
-				continue
+			if err := tracker.send(ctx, &location); err != nil {
+				return err
 			}
-			
-			// Update last location ID
-			lastLocationID = location.ID
-			
-			// Get latest order status
-			currentOrder, err := s.repo.GetOrderByID(stream.Context(), req.OrderId)
-			if err != nil {
-				fmt.Printf("Error getting current order: %v\n", err)
-				continue
-			}
-			
-			// Calculate ETA
-			var estimatedArrivalMinutes float32
-			if currentOrder.Status == model.StatusInTransit || currentOrder.Status == model.StatusPickedUp {
-				estimatedArrivalMinutes = estimateArrivalMinutes(location, currentOrder.DestinationLocation)
-			} else {
-				estimatedArrivalMinutes = estimateArrivalMinutes(location, currentOrder.PickupLocation)
-			}
-			
-			// Create update
-			update := &pb.OrderLocationUpdate{
-				OrderId:    req.OrderId,
-				ProviderId: location.ProviderID,
-				CurrentLocation: &pb.Location{
-					Latitude:  location.Latitude,
-					Longitude: location.Longitude,
-				},
-				EstimatedArrivalMinutes: estimatedArrivalMinutes,
-				Timestamp:              timestamppb.New(location.Timestamp),
-			}
-			
-			// Send update to client
-			if err := stream.Send(update); err != nil {
-				return status.Errorf(codes.Internal, "failed to send update: %v", err)
+
+		case <-ticker.C:
+			if err := tracker.poll(ctx); err != nil {
+				return err
 			}
-			
-		case <-stream.Context().Done():
+
+		case <-ctx.Done():
 			return nil
 		}
 	}
 }
 
+// GetLatestLocation returns the most recent provider location for an order
+func (s *OrderService) GetLatestLocation(ctx context.Context, req *pb.GetLatestLocationRequest) (*pb.OrderLocationUpdate, error) {
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	location, err := s.locationRepo.GetLatestOrderLocation(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderLocationNotFound) {
+			return nil, status.Errorf(codes.NotFound, "no location reported for order")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get latest location: %v", err)
+	}
+
+	return buildLocationUpdate(order, location), nil
+}
+
+// buildLocationUpdate converts a location report into an update with an ETA to the next stop
+func buildLocationUpdate(order *model.Order, location *model.OrderLocation) *pb.OrderLocationUpdate {
+	return &pb.OrderLocationUpdate{
+		OrderId:    order.ID,
+		ProviderId: location.ProviderID,
+		CurrentLocation: &pb.Location{
+			Latitude:  location.Latitude,
+			Longitude: location.Longitude,
+		},
+		EstimatedArrivalMinutes: estimateOrderArrivalMinutes(order, location),
+		Timestamp:               timestamppb.New(location.Timestamp),
+	}
+}
+
 // Helper functions for conversions between domain models and protocol buffer messages
 
 func convertOrderType(ot pb.OrderType) model.OrderType {
@@ -417,6 +1014,10 @@ func convertOrderType(ot pb.OrderType) model.OrderType {
 		return model.TypeGroceryDelivery
 	case pb.OrderType_ORDER_TYPE_SERVICE_BOOKING:
 		return model.TypeServiceBooking
+	case pb.OrderType_ORDER_TYPE_RENTAL:
+		return model.TypeRental
+	case pb.OrderType_ORDER_TYPE_RETURN:
+		return model.TypeReturn
 	default:
 		return model.TypeRide
 	}
@@ -434,6 +1035,10 @@ func convertOrderTypeToProto(ot model.OrderType) pb.OrderType {
 		return pb.OrderType_ORDER_TYPE_GROCERY_DELIVERY
 	case model.TypeServiceBooking:
 		return pb.OrderType_ORDER_TYPE_SERVICE_BOOKING
+	case model.TypeRental:
+		return pb.OrderType_ORDER_TYPE_RENTAL
+	case model.TypeReturn:
+		return pb.OrderType_ORDER_TYPE_RETURN
 	default:
 		return pb.OrderType_ORDER_TYPE_UNSPECIFIED
 	}
@@ -558,13 +1163,14 @@ func convertLocation(loc *pb.Location) model.Location {
 	}
 
 	return model.Location{
-		Latitude:      loc.Latitude,
-		Longitude:     loc.Longitude,
-		Address:       loc.Address,
-		PostalCode:    loc.PostalCode,
-		City:          loc.City,
-		Country:       loc.Country,
+		Latitude:       loc.Latitude,
+		Longitude:      loc.Longitude,
+		Address:        loc.Address,
+		PostalCode:     loc.PostalCode,
+		City:           loc.City,
+		Country:        loc.Country,
 		AdditionalInfo: additionalInfo,
+		Instructions:   convertStopInstructions(loc.Instructions),
 	}
 }
 
@@ -575,13 +1181,43 @@ func convertLocationToProto(loc model.Location) *pb.Location {
 	}
 
 	return &pb.Location{
-		Latitude:      loc.Latitude,
-		Longitude:     loc.Longitude,
-		Address:       loc.Address,
-		PostalCode:    loc.PostalCode,
-		City:          loc.City,
-		Country:       loc.Country,
+		Latitude:       loc.Latitude,
+		Longitude:      loc.Longitude,
+		Address:        loc.Address,
+		PostalCode:     loc.PostalCode,
+		City:           loc.City,
+		Country:        loc.Country,
 		AdditionalInfo: additionalInfo,
+		Instructions:   convertStopInstructionsToProto(loc.Instructions),
+	}
+}
+
+// convertStopInstructions converts a stop's instructions, leaving out empty ones
+func convertStopInstructions(instructions *pb.StopInstructions) *model.StopInstructions {
+	if instructions == nil {
+		return nil
+	}
+	converted := &model.StopInstructions{
+		GateCode:    instructions.GateCode,
+		Floor:       instructions.Floor,
+		LeaveAtDoor: instructions.LeaveAtDoor,
+		Notes:       instructions.Notes,
+	}
+	if *converted == (model.StopInstructions{}) {
+		return nil
+	}
+	return converted
+}
+
+func convertStopInstructionsToProto(instructions *model.StopInstructions) *pb.StopInstructions {
+	if instructions == nil {
+		return nil
+	}
+	return &pb.StopInstructions{
+		GateCode:    instructions.GateCode,
+		Floor:       instructions.Floor,
+		LeaveAtDoor: instructions.LeaveAtDoor,
+		Notes:       instructions.Notes,
 	}
 }
 
@@ -594,11 +1230,15 @@ func convertOrderItems(items []*pb.OrderItem) model.OrderItems {
 		}
 
 		orderItems = append(orderItems, model.OrderItem{
-			ItemID:     item.ItemId,
-			Name:       item.Name,
-			Quantity:   int(item.Quantity),
-			Price:      float64(item.Price),
-			Properties: properties,
+			ItemID:      item.ItemId,
+			Name:        item.Name,
+			Quantity:    int(item.Quantity),
+			Price:       item.Price,
+			Properties:  properties,
+			WeightGrams: int(item.WeightGrams),
+			LengthCm:    int(item.LengthCm),
+			WidthCm:     int(item.WidthCm),
+			HeightCm:    int(item.HeightCm),
 		})
 	}
 	return orderItems
@@ -613,11 +1253,15 @@ func convertOrderItemsToProto(items model.OrderItems) []*pb.OrderItem {
 		}
 
 		protoItems = append(protoItems, &pb.OrderItem{
-			ItemId:     item.ItemID,
-			Name:       item.Name,
-			Quantity:   int32(item.Quantity),
-			Price:      float32(item.Price),
-			Properties: properties,
+			ItemId:      item.ItemID,
+			Name:        item.Name,
+			Quantity:    int32(item.Quantity),
+			Price:       item.Price,
+			Properties:  properties,
+			WeightGrams: int32(item.WeightGrams),
+			LengthCm:    int32(item.LengthCm),
+			WidthCm:     int32(item.WidthCm),
+			HeightCm:    int32(item.HeightCm),
 		})
 	}
 	return protoItems
@@ -638,63 +1282,81 @@ func convertStatusHistoryToProto(history model.StatusHistories) []*pb.OrderStatu
 
 func convertOrderToProto(order *model.Order) *pb.Order {
 	return &pb.Order{
-		Id:                  order.ID,
-		UserId:              order.UserID,
-		ProviderId:          order.ProviderID,
-		OrderType:           convertOrderTypeToProto(order.OrderType),
-		Status:              convertOrderStatusToProto(order.Status),
-		PickupLocation:      convertLocationToProto(order.PickupLocation),
-		DestinationLocation: convertLocationToProto(order.DestinationLocation),
-		Items:               convertOrderItemsToProto(order.Items),
-		TotalPrice:          float32(order.TotalPrice),
-		PlatformFee:         float32(order.PlatformFee),
-		ProviderFee:         float32(order.ProviderFee),
-		TransactionId:       order.TransactionID,
-		BlockchainTxHash:    order.BlockchainTxHash,
-		PaymentMethod:       convertPaymentMethodToProto(order.PaymentMethod),
-		Notes:               order.Notes,
-		CreatedAt:           timestamppb.New(order.CreatedAt),
-		UpdatedAt:           timestamppb.New(order.UpdatedAt),
-		StatusHistory:       convertStatusHistoryToProto(order.StatusHistory),
-	}
-}
-
-func calculateTotalPrice(items model.OrderItems) float64 {
-	var total float64
+		Id:                   order.ID,
+		UserId:               order.UserID,
+		ProviderId:           order.ProviderID,
+		OrderType:            convertOrderTypeToProto(order.OrderType),
+		Status:               convertOrderStatusToProto(order.Status),
+		PickupLocation:       convertLocationToProto(order.PickupLocation),
+		DestinationLocation:  convertLocationToProto(order.DestinationLocation),
+		Items:                convertOrderItemsToProto(order.Items),
+		TotalPrice:           order.TotalPrice,
+		PlatformFee:          order.PlatformFee,
+		ProviderFee:          order.ProviderFee,
+		TipAmount:            order.TipAmount,
+		CancellationFee:      order.CancellationFee,
+		DeliveryProofHash:    order.DeliveryProofHash,
+		Frozen:               order.Frozen,
+		AtRisk:               order.AtRisk,
+		SizeClass:            string(order.SizeClass()),
+		TransactionId:        order.TransactionID,
+		BlockchainTxHash:     order.BlockchainTxHash,
+		PaymentMethod:        convertPaymentMethodToProto(order.PaymentMethod),
+		PaymentMethodId:      order.PaymentMethodID,
+		WalletAmount:         order.WalletAmount,
+		PointsRedeemed:       order.PointsRedeemed,
+		PointsDiscount:       order.PointsDiscount,
+		ReturnOfOrderId:      order.ReturnOfOrderID,
+		Notes:                order.Notes,
+		CreatedAt:            timestamppb.New(order.CreatedAt),
+		UpdatedAt:            timestamppb.New(order.UpdatedAt),
+		StatusHistory:        convertStatusHistoryToProto(order.StatusHistory),
+		PaymentShares:        convertPaymentSharesToProto(order.PaymentShares),
+		Vehicle:              convertOrderVehicleToProto(order.Vehicle),
+		PaymentAuthorization: convertPaymentAuthorizationToProto(order.Authorization),
+	}
+}
+
+func calculateTotalPrice(items model.OrderItems) int64 {
+	var total int64
 	for _, item := range items {
-		total += item.Price * float64(item.Quantity)
+		total += item.Price * int64(item.Quantity)
 	}
 	return total
 }
 
+// estimateOrderArrivalMinutes estimates when the provider reaches the order's next stop:
+// the destination once the order is picked up, otherwise the pickup. A provider that has
+// arrived needs no time.
+func estimateOrderArrivalMinutes(order *model.Order, location *model.OrderLocation) float32 {
+	switch order.Status {
+	case model.StatusArrived, model.StatusDelivered, model.StatusCompleted:
+		return 0
+	case model.StatusInTransit, model.StatusPickedUp:
+		return estimateArrivalMinutes(location, order.DestinationLocation)
+	default:
+		return estimateArrivalMinutes(location, order.PickupLocation)
+	}
+}
+
 // estimateArrivalMinutes is a simplified function that estimates arrival time
 // In a real implementation, this would use a routing service or algorithm
 func estimateArrivalMinutes(location *model.OrderLocation, destination model.Location) float32 {
 	// This is a very simplified estimation
 	// In reality, you would use a distance matrix API or routing engine
-	
-	// Haversine distance (simplified)
-	dLat := destination.Latitude - location.Latitude
-	dLon := destination.Longitude - location.Longitude
-	
-	// Simplified distance calculation (not accurate for large distances)
-	distance := (dLat*dLat + dLon*dLon) * 111.0 // Approximate km per degree at the equator
-	
+	distance := haversineKm(location.Latitude, location.Longitude, destination.Latitude, destination.Longitude)
+
 	// Assume average speed of 30 km/h
-	averageSpeed := 30.0 
-	
+	averageSpeed := 30.0
+
 	// Calculate estimated time in minutes
 	estimatedMinutes := (distance / averageSpeed) * 60.0
-	
+
 	return float32(estimatedMinutes)
 }
 
 // AssignProvider assigns a provider to an order
 func (s *OrderService) AssignProvider(ctx context.Context, req *pb.AssignProviderRequest) (*pb.OrderResponse, error) {
-	if req.OrderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "order ID is required")
-	}
-	
 	// Get current order
 	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
 	if err != nil {
@@ -703,63 +1365,96 @@ func (s *OrderService) AssignProvider(ctx context.Context, req *pb.AssignProvide
 		}
 		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
 	}
-	
+
+	if err := checkOrderNotFrozen(order); err != nil {
+		return nil, err
+	}
+
 	var providers []Provider
 	var selectedProviderID string
-	
+	var autoAccepted bool
+	var anchor *batchAnchor
+
 	if req.ProviderId != "" {
 		// Manual provider assignment
+		blocked, err := s.providerMatcher.Blocked(ctx, order.UserID, req.ProviderId)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%v", err)
+		}
+		if blocked {
+			return nil, status.Errorf(codes.FailedPrecondition, "the user has blocked this provider")
+		}
+		provider, err := s.providerClient.GetProviderDetails(ctx, req.ProviderId)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to get provider: %v", err)
+		}
+		ok, err := s.providerHasCapacity(ctx, order, *provider)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to count provider's active orders: %v", err)
+		}
+		if !ok {
+			return nil, status.Errorf(codes.ResourceExhausted, "provider cannot take more %s orders at once", order.OrderType)
+		}
 		selectedProviderID = req.ProviderId
+	} else if anchor = s.findBatchAnchor(ctx, order); anchor != nil {
+		// Stack the delivery onto a trip a provider is already making nearby
+		selectedProviderID = anchor.provider.ID
 	} else {
-		// Auto-match providers
+		// Auto-match providers who can take another order
 		providers, err = s.providerMatcher.FindBestProviders(ctx, order, 3)
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "failed to find providers: %v", err)
 		}
-		
+		providers = s.filterProvidersWithCapacity(ctx, order, providers)
+
 		if len(providers) == 0 {
 			return nil, status.Errorf(codes.NotFound, "no available providers found")
 		}
-		
+
 		// Notify all providers about the order
 		err = s.providerMatcher.NotifyProviders(ctx, order, providers)
 		if err != nil {
 			// Log but continue - we still want to assign the order
 			fmt.Printf("Failed to notify providers: %v\n", err)
 		}
-		
-		// For automatic matching, we'll select the first provider
-		selectedProviderID = providers[0].ID
+
+		// For automatic matching, select the best provider, preferring one who auto-accepts
+		var selected Provider
+		selected, autoAccepted = selectProvider(providers)
+		selectedProviderID = selected.ID
 	}
-	
+
 	// Update order with provider
 	updatedOrder, err := s.providerMatcher.AssignProvider(ctx, order, selectedProviderID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to assign provider: %v", err)
 	}
-	
+	if autoAccepted {
+		updatedOrder.AddStatusHistory(model.StatusProviderAccepted, selectedProviderID, "Provider auto-accepted the order")
+	}
+
 	// Save to database
 	err = s.repo.UpdateOrder(ctx, updatedOrder)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to update order: %v", err)
 	}
-	
+	if autoAccepted {
+		s.dispatchOffers.Respond(ctx, updatedOrder.ID, selectedProviderID, model.OfferAccepted)
+		s.recordVehicle(ctx, updatedOrder.ID, selectedProviderID)
+		s.confirmStock(ctx, updatedOrder.ID)
+	}
+
+	// Keep an audit trail of automatic matches
+	if len(providers) > 0 {
+		s.dispatcher.Record(ctx, updatedOrder, providers, selectedProviderID, autoAccepted)
+	}
+	if anchor != nil {
+		s.addToBatch(ctx, anchor, updatedOrder)
+	}
+
 	// Record on blockchain asynchronously
-	go func() {
-		bCtx := context.Background()
-		txHash, err := s.blockchainClient.RecordOrder(bCtx, updatedOrder.ID, updatedOrder.UserID, updatedOrder.ProviderID, updatedOrder)
-		if err != nil {
-			fmt.Printf("Failed to record provider assignment on blockchain: %v\n", err)
-			return
-		}
+	s.blockchainRecorder.Record(ctx, updatedOrder.ID)
 
-		// Update order with blockchain transaction hash
-		updatedOrder.BlockchainTxHash = txHash
-		if err := s.repo.UpdateOrder(bCtx, updatedOrder); err != nil {
-			fmt.Printf("Failed to update order with blockchain hash: %v\n", err)
-		}
-	}()
-	
 	return &pb.OrderResponse{
 		Order:   convertOrderToProto(updatedOrder),
 		Message: "Provider assigned successfully",
@@ -769,10 +1464,6 @@ func (s *OrderService) AssignProvider(ctx context.Context, req *pb.AssignProvide
 
 // AcceptOrder is called when a provider accepts an order
 func (s *OrderService) AcceptOrder(ctx context.Context, req *pb.AcceptOrderRequest) (*pb.OrderResponse, error) {
-	if req.OrderId == "" || req.ProviderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "order ID and provider ID are required")
-	}
-	
 	// Get current order
 	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
 	if err != nil {
@@ -781,22 +1472,31 @@ func (s *OrderService) AcceptOrder(ctx context.Context, req *pb.AcceptOrderReque
 		}
 		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
 	}
-	
+
+	if err := checkOrderNotFrozen(order); err != nil {
+		return nil, err
+	}
+
 	// Verify the provider is assigned to this order
 	if order.ProviderID != req.ProviderId {
 		return nil, status.Errorf(codes.PermissionDenied, "provider is not assigned to this order")
 	}
-	
+
 	// Update order status
 	order.AddStatusHistory(model.StatusProviderAccepted, req.ProviderId, "Provider accepted the order")
 	order.UpdatedAt = time.Now()
-	
+
 	// Save to database
 	err = s.repo.UpdateOrder(ctx, order)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to update order: %v", err)
 	}
-	
+	s.dispatchOffers.Respond(ctx, order.ID, req.ProviderId, model.OfferAccepted)
+	s.recordVehicle(ctx, order.ID, req.ProviderId)
+
+	// A taken order keeps the stock held for it
+	s.confirmStock(ctx, order.ID)
+
 	// Save initial provider location if provided
 	if req.CurrentLocation != nil {
 		orderLocation := &model.OrderLocation{
@@ -806,30 +1506,19 @@ func (s *OrderService) AcceptOrder(ctx context.Context, req *pb.AcceptOrderReque
 			Longitude:  req.CurrentLocation.Longitude,
 			Timestamp:  time.Now(),
 		}
-		
+
 		err = s.locationRepo.CreateOrderLocation(ctx, orderLocation)
 		if err != nil {
 			// Log but continue - this is not critical
 			fmt.Printf("Failed to save initial provider location: %v\n", err)
+		} else {
+			s.publishLocation(ctx, orderLocation)
 		}
 	}
-	
+
 	// Record on blockchain asynchronously
-	go func() {
-		bCtx := context.Background()
-		txHash, err := s.blockchainClient.RecordOrder(bCtx, order.ID, order.UserID, order.ProviderID, order)
-		if err != nil {
-			fmt.Printf("Failed to record provider acceptance on blockchain: %v\n", err)
-			return
-		}
+	s.blockchainRecorder.Record(ctx, order.ID)
 
-		// Update order with blockchain transaction hash
-		order.BlockchainTxHash = txHash
-		if err := s.repo.UpdateOrder(bCtx, order); err != nil {
-			fmt.Printf("Failed to update order with blockchain hash: %v\n", err)
-		}
-	}()
-	
 	return &pb.OrderResponse{
 		Order:   convertOrderToProto(order),
 		Message: "Order accepted successfully",
@@ -839,10 +1528,6 @@ func (s *OrderService) AcceptOrder(ctx context.Context, req *pb.AcceptOrderReque
 
 // RejectOrder is called when a provider rejects an order
 func (s *OrderService) RejectOrder(ctx context.Context, req *pb.RejectOrderRequest) (*pb.OrderResponse, error) {
-	if req.OrderId == "" || req.ProviderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "order ID and provider ID are required")
-	}
-	
 	// Get current order
 	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
 	if err != nil {
@@ -851,39 +1536,31 @@ func (s *OrderService) RejectOrder(ctx context.Context, req *pb.RejectOrderReque
 		}
 		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
 	}
-	
+
+	if err := checkOrderNotFrozen(order); err != nil {
+		return nil, err
+	}
+
 	// Verify the provider is assigned to this order
 	if order.ProviderID != req.ProviderId {
 		return nil, status.Errorf(codes.PermissionDenied, "provider is not assigned to this order")
 	}
-	
+
 	// Update order status
 	order.AddStatusHistory(model.StatusProviderRejected, req.ProviderId, req.Reason)
 	order.ProviderID = "" // Clear provider ID to allow reassignment
 	order.UpdatedAt = time.Now()
-	
+
 	// Save to database
 	err = s.repo.UpdateOrder(ctx, order)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to update order: %v", err)
 	}
-	
+	s.dispatchOffers.Respond(ctx, order.ID, req.ProviderId, model.OfferDeclined)
+
 	// Record on blockchain asynchronously
-	go func() {
-		bCtx := context.Background()
-		txHash, err := s.blockchainClient.RecordOrder(bCtx, order.ID, order.UserID, order.ProviderID, order)
-		if err != nil {
-			fmt.Printf("Failed to record provider rejection on blockchain: %v\n", err)
-			return
-		}
+	s.blockchainRecorder.Record(ctx, order.ID)
 
-		// Update order with blockchain transaction hash
-		order.BlockchainTxHash = txHash
-		if err := s.repo.UpdateOrder(bCtx, order); err != nil {
-			fmt.Printf("Failed to update order with blockchain hash: %v\n", err)
-		}
-	}()
-	
 	// Try to find another provider asynchronously
 	go func() {
 		bCtx := context.Background()
@@ -892,25 +1569,46 @@ func (s *OrderService) RejectOrder(ctx context.Context, req *pb.RejectOrderReque
 			fmt.Printf("Failed to find new providers: %v\n", err)
 			return
 		}
-		
+		providers = s.filterProvidersWithCapacity(bCtx, order, providers)
+
 		if len(providers) > 0 {
-			// Notify providers and select the first one
+			// Notify providers and select one
 			s.providerMatcher.NotifyProviders(bCtx, order, providers)
-			
-			// Auto-assign to the first provider
-			updatedOrder, err := s.providerMatcher.AssignProvider(bCtx, order, providers[0].ID)
+
+			// Auto-assign to the best provider, preferring one who auto-accepts
+			selected, autoAccepted := selectProvider(providers)
+			updatedOrder, err := s.providerMatcher.AssignProvider(bCtx, order, selected.ID)
 			if err != nil {
 				fmt.Printf("Failed to auto-assign new provider: %v\n", err)
 				return
 			}
-			
-			err = s.repo.UpdateOrder(bCtx, updatedOrder)
-			if err != nil {
+			if autoAccepted {
+				updatedOrder.AddStatusHistory(model.StatusProviderAccepted, selected.ID, "Provider auto-accepted the order")
+			}
+
+			// Write only the provider and the status changes, so the rest of the order
+			// cannot be overwritten with what it was when it was rejected
+			if err := s.repo.SetProviderID(bCtx, order.ID, selected.ID); err != nil {
 				fmt.Printf("Failed to update order with new provider: %v\n", err)
+				return
+			}
+			err = s.repo.UpdateOrderStatus(bCtx, order.ID, model.StatusProviderAssigned, "system", fmt.Sprintf("Provider %s assigned", selected.ID))
+			if err == nil && autoAccepted {
+				err = s.repo.UpdateOrderStatus(bCtx, order.ID, model.StatusProviderAccepted, selected.ID, "Provider auto-accepted the order")
+			}
+			if err != nil {
+				fmt.Printf("Failed to update status of order with new provider: %v\n", err)
+				return
 			}
+			if autoAccepted {
+				s.dispatchOffers.Respond(bCtx, order.ID, selected.ID, model.OfferAccepted)
+				s.recordVehicle(bCtx, order.ID, selected.ID)
+				s.confirmStock(bCtx, order.ID)
+			}
+			s.dispatcher.Record(bCtx, updatedOrder, providers, selected.ID, autoAccepted)
 		}
 	}()
-	
+
 	return &pb.OrderResponse{
 		Order:   convertOrderToProto(order),
 		Message: "Order rejected successfully",
@@ -920,10 +1618,6 @@ func (s *OrderService) RejectOrder(ctx context.Context, req *pb.RejectOrderReque
 
 // UpdateLocation updates the location of a provider for an order
 func (s *OrderService) UpdateLocation(ctx context.Context, req *pb.UpdateLocationRequest) (*pb.UpdateLocationResponse, error) {
-	if req.OrderId == "" || req.ProviderId == "" || req.Location == nil {
-		return nil, status.Errorf(codes.InvalidArgument, "order ID, provider ID, and location are required")
-	}
-	
 	// Get current order
 	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
 	if err != nil {
@@ -932,12 +1626,12 @@ func (s *OrderService) UpdateLocation(ctx context.Context, req *pb.UpdateLocatio
 		}
 		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
 	}
-	
+
 	// Verify the provider is assigned to this order
 	if order.ProviderID != req.ProviderId {
 		return nil, status.Errorf(codes.PermissionDenied, "provider is not assigned to this order")
 	}
-	
+
 	// Create new location entry
 	orderLocation := &model.OrderLocation{
 		OrderID:    req.OrderId,
@@ -946,26 +1640,20 @@ func (s *OrderService) UpdateLocation(ctx context.Context, req *pb.UpdateLocatio
 		Longitude:  req.Location.Longitude,
 		Timestamp:  time.Now(),
 	}
-	
+
 	// Save to database
 	err = s.locationRepo.CreateOrderLocation(ctx, orderLocation)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to update location: %v", err)
 	}
-	
-	// Calculate estimated arrival time
-	var estimatedArrivalMinutes float32
-	if order.Status == model.StatusInTransit || order.Status == model.StatusPickedUp {
-		// Use destination location for ETA calculation
-		estimatedArrivalMinutes = estimateArrivalMinutes(orderLocation, order.DestinationLocation)
-	} else {
-		// Use pickup location for ETA calculation
-		estimatedArrivalMinutes = estimateArrivalMinutes(orderLocation, order.PickupLocation)
-	}
-	
+	s.publishLocation(ctx, orderLocation)
+
+	// Arrive automatically when the provider enters the pickup or destination geofence
+	s.applyGeofences(ctx, order, orderLocation)
+
 	return &pb.UpdateLocationResponse{
-		Success:                true,
-		Message:                "Location updated successfully",
-		EstimatedArrivalMinutes: estimatedArrivalMinutes,
+		Success:                 true,
+		Message:                 "Location updated successfully",
+		EstimatedArrivalMinutes: estimateOrderArrivalMinutes(order, orderLocation),
 	}, nil
-} 
\ No newline at end of file
+}
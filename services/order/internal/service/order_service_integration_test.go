@@ -0,0 +1,415 @@
+//go:build integration
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/internal/testharness"
+	"github.com/order-api-microservices/pkg/database"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"github.com/order-api-microservices/services/order/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// noProviders is a provider service that knows no providers
+type noProviders struct{}
+
+func (noProviders) FindAvailableProviders(ctx context.Context, location model.Location, radius float64, serviceType, serviceAreaID string) ([]service.Provider, error) {
+	return nil, nil
+}
+
+func (noProviders) NotifyProvider(ctx context.Context, providerID string, orderID string, details interface{}) error {
+	return nil
+}
+
+func (noProviders) GetProviderDetails(ctx context.Context, providerID string) (*service.Provider, error) {
+	return nil, errors.New("provider not found")
+}
+
+// anyProvider is a provider service that knows every provider, all of them available and
+// none of them in a vehicle
+type anyProvider struct {
+	noProviders
+}
+
+func (anyProvider) GetProviderDetails(ctx context.Context, providerID string) (*service.Provider, error) {
+	return &service.Provider{ID: providerID, IsAvailable: true}, nil
+}
+
+// oneProvider is a provider service that knows a single provider as given
+type oneProvider struct {
+	noProviders
+	provider service.Provider
+}
+
+func (p oneProvider) GetProviderDetails(ctx context.Context, providerID string) (*service.Provider, error) {
+	provider := p.provider
+	provider.ID = providerID
+	return &provider, nil
+}
+
+// serveOrderService serves an order service on Postgres repositories over gRPC
+func serveOrderService(t *testing.T, db *database.PostgresDB, payments service.PaymentClient) pb.OrderServiceClient {
+	t.Helper()
+
+	s := newPostgresOrderService(db, payments, noProviders{})
+	conn := testharness.ServeGRPC(t, func(server *grpc.Server) {
+		pb.RegisterOrderServiceServer(server, s)
+	})
+	return pb.NewOrderServiceClient(conn)
+}
+
+// newPostgresOrderService creates an order service on Postgres repositories
+func newPostgresOrderService(db *database.PostgresDB, payments service.PaymentClient, providers service.ProviderClient) *service.OrderService {
+	orderRepo := repository.NewOrderRepository(db, nil)
+	methods := repository.NewPaymentMethodRepository(db)
+	loyalty := service.NewLoyalty(repository.NewLoyaltyRepository(db), methods, testLoyaltyPolicy)
+	return service.NewOrderService(orderRepo, repository.NewOrderLocationRepository(db), repository.NewRefundRepository(db),
+		repository.NewLedgerRepository(db), repository.NewPaymentShareRepository(db), repository.NewDeliveryProofRepository(db),
+		repository.NewDeliveryPINRepository(db), repository.NewOrderBatchRepository(db), repository.NewRentalRepository(db),
+		repository.NewOrderVehicleRepository(db), repository.NewMerchantRepository(db),
+		methods, repository.NewWalletRepository(db), loyalty, repository.NewStockRepository(db), time.Minute, repository.NewUserProviderRepository(db),
+		nil, recordNothing{}, providers, payments, discardNotifications{}, nil, nil,
+		service.NewFeeSchedule(repository.NewFeeRepository(db), time.Minute),
+		service.CancellationPolicy{},
+		service.DeliveryPINPolicy{Length: 4, MaxAttempts: 3, Lockout: 15 * time.Minute, ResendInterval: time.Minute},
+		service.GeofencePolicy{}, service.LocationSamplingPolicy{}, service.ConcurrencyPolicy{}, service.BatchingPolicy{},
+		service.RentalPolicy{HourlyRate: 50000, MinHours: 1, MaxHours: 12},
+		service.PackagePolicy{}, service.DuplicatePolicy{}, service.PricingPolicy{}, service.OrderEditPolicy{}, service.ReturnPolicy{Window: 24 * time.Hour},
+		nil, service.NewDispatchOffers(repository.NewDispatchRepository(db), nil, service.DispatchOfferConfig{}),
+		service.NewServiceAreas(repository.NewServiceAreaRepository(db), time.Minute), nil, nil)
+}
+
+func TestOrderServiceTipEndToEnd(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	payments := &capturePayments{}
+	client := serveOrderService(t, db, payments)
+
+	userID, providerID := uuid.New().String(), uuid.New().String()
+	orderID := testharness.InsertOrder(t, db, testharness.OrderFixture{
+		UserID:     userID,
+		ProviderID: providerID,
+		Status:     string(model.StatusCompleted),
+	})
+
+	resp, err := client.AddTip(ctx, &pb.AddTipRequest{OrderId: orderID, UserId: userID, Amount: 5000})
+	if err != nil {
+		t.Fatalf("AddTip: %v", err)
+	}
+	if resp.Order.TipAmount != 5000 {
+		t.Errorf("tip amount = %d, want 5000", resp.Order.TipAmount)
+	}
+
+	_, err = client.AddTip(ctx, &pb.AddTipRequest{OrderId: orderID, UserId: userID, Amount: 5000})
+	wantCode(t, err, codes.AlreadyExists)
+
+	ledger, err := client.ListProviderLedger(ctx, &pb.ListProviderLedgerRequest{ProviderId: providerID, Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListProviderLedger: %v", err)
+	}
+	if len(ledger.Entries) != 1 || ledger.Entries[0].EntryType != string(model.LedgerTip) || ledger.TotalEarnings != 5000 {
+		t.Errorf("got ledger %+v, want one 5000 tip", ledger.Entries)
+	}
+
+	got, err := client.GetOrder(ctx, &pb.GetOrderRequest{OrderId: orderID})
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got.Order.TipAmount != 5000 {
+		t.Errorf("stored tip amount = %d, want 5000", got.Order.TipAmount)
+	}
+}
+
+func TestOrderServiceDeliveryPINLockoutEndToEnd(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	client := serveOrderService(t, db, &capturePayments{})
+
+	providerID := uuid.New().String()
+	orderID := testharness.InsertOrder(t, db, testharness.OrderFixture{
+		ProviderID: providerID,
+		OrderType:  string(model.TypeFoodDelivery),
+		Status:     string(model.StatusArrived),
+	})
+	now := time.Now()
+	err := repository.NewDeliveryPINRepository(db).CreatePIN(ctx, &model.DeliveryPIN{
+		OrderID:   orderID,
+		PINHash:   model.HashDeliveryPIN(orderID, "1234"),
+		IssuedAt:  now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("CreatePIN: %v", err)
+	}
+
+	deliver := func(pin string) error {
+		_, err := client.CompleteDelivery(ctx, &pb.CompleteDeliveryRequest{
+			OrderId:      orderID,
+			ProviderId:   providerID,
+			PhotoRef:     "photos/delivery.jpg",
+			RecipientOtp: pin,
+		})
+		return err
+	}
+
+	wantCode(t, deliver("0000"), codes.PermissionDenied)
+	wantCode(t, deliver("1111"), codes.PermissionDenied)
+	wantCode(t, deliver("2222"), codes.ResourceExhausted)
+	wantCode(t, deliver("1234"), codes.ResourceExhausted)
+
+	got, err := client.GetOrder(ctx, &pb.GetOrderRequest{OrderId: orderID})
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got.Order.Status != pb.OrderStatus_ORDER_STATUS_ARRIVED {
+		t.Errorf("status = %s, want ARRIVED", got.Order.Status)
+	}
+}
+
+func TestOrderServiceStockReservationEndToEnd(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	s := newPostgresOrderService(db, &capturePayments{}, noProviders{})
+	conn := testharness.ServeGRPC(t, func(server *grpc.Server) {
+		pb.RegisterOrderServiceServer(server, s)
+	})
+	client := pb.NewOrderServiceClient(conn)
+
+	merchants := repository.NewMerchantRepository(db)
+	merchantID, itemID := uuid.New().String(), uuid.New().String()
+	if err := merchants.CreateMerchant(ctx, &model.Merchant{ID: merchantID, Name: "Warung", City: "jakarta", Active: true}); err != nil {
+		t.Fatalf("CreateMerchant: %v", err)
+	}
+	stock := 5
+	item := &model.CatalogItem{ID: itemID, MerchantID: merchantID, Name: "Nasi goreng", Price: 25000, Available: true, Stock: &stock}
+	if err := merchants.CreateCatalogItem(ctx, item); err != nil {
+		t.Fatalf("CreateCatalogItem: %v", err)
+	}
+	stockLeft := func() int {
+		t.Helper()
+		items, err := merchants.GetCatalogItems(ctx, merchantID, []string{itemID})
+		if err != nil {
+			t.Fatalf("GetCatalogItems: %v", err)
+		}
+		return *items[itemID].Stock
+	}
+	order := func(userID string) string {
+		t.Helper()
+		resp, err := client.CreateOrder(ctx, &pb.CreateOrderRequest{
+			UserId:              userID,
+			OrderType:           pb.OrderType_ORDER_TYPE_FOOD_DELIVERY,
+			MerchantId:          merchantID,
+			PickupLocation:      &pb.Location{Latitude: -6.2, Longitude: 106.8166, Address: "Warung"},
+			DestinationLocation: &pb.Location{Latitude: -6.182, Longitude: 106.8166, Address: "Home"},
+			Items:               []*pb.OrderItem{{ItemId: itemID, Quantity: 2}},
+			PaymentMethod:       pb.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD,
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+		return resp.Order.Id
+	}
+
+	// A provider accepting an order keeps its stock for good
+	orderRepo := repository.NewOrderRepository(db, nil)
+	providerID := uuid.New().String()
+	accepted := order(uuid.New().String())
+	if err := orderRepo.SetProviderID(ctx, accepted, providerID); err != nil {
+		t.Fatalf("SetProviderID: %v", err)
+	}
+	if _, err := client.AcceptOrder(ctx, &pb.AcceptOrderRequest{OrderId: accepted, ProviderId: providerID}); err != nil {
+		t.Fatalf("AcceptOrder: %v", err)
+	}
+	stockRepo := repository.NewStockRepository(db)
+	held, err := stockRepo.ListExpiredReservations(ctx, time.Now().Add(24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("ListExpiredReservations: %v", err)
+	}
+	if len(held) != 0 {
+		t.Errorf("accepted order still holds its stock: %v", held)
+	}
+
+	// An order no provider takes is cancelled once its hold expires, giving its stock back
+	expired := order(uuid.New().String())
+	if left := stockLeft(); left != 1 {
+		t.Fatalf("stock left after two orders = %d, want 1", left)
+	}
+	sweeper := service.NewStockSweeper(stockRepo, orderRepo, s, service.StockSweeperConfig{BatchSize: 10})
+	if err := sweeper.Sweep(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+
+	if left := stockLeft(); left != 3 {
+		t.Errorf("stock left after the sweep = %d, want 3", left)
+	}
+	got, err := client.GetOrder(ctx, &pb.GetOrderRequest{OrderId: expired})
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got.Order.Status != pb.OrderStatus_ORDER_STATUS_CANCELLED {
+		t.Errorf("status of the expired order = %s, want CANCELLED", got.Order.Status)
+	}
+	held, err = stockRepo.ListExpiredReservations(ctx, time.Now().Add(24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("ListExpiredReservations: %v", err)
+	}
+	if len(held) != 0 {
+		t.Errorf("orders still holding stock = %v, want none", held)
+	}
+
+	// Out of stock orders fail and hold nothing
+	_, err = client.CreateOrder(ctx, &pb.CreateOrderRequest{
+		UserId:              uuid.New().String(),
+		OrderType:           pb.OrderType_ORDER_TYPE_FOOD_DELIVERY,
+		MerchantId:          merchantID,
+		PickupLocation:      &pb.Location{Latitude: -6.2, Longitude: 106.8166},
+		DestinationLocation: &pb.Location{Latitude: -6.182, Longitude: 106.8166},
+		Items:               []*pb.OrderItem{{ItemId: itemID, Quantity: 4}},
+		PaymentMethod:       pb.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD,
+	})
+	wantCode(t, err, codes.FailedPrecondition)
+	if left := stockLeft(); left != 3 {
+		t.Errorf("stock left after a failed order = %d, want 3", left)
+	}
+}
+
+func TestOrderServiceHandOffSplitsFareEndToEnd(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	s := newPostgresOrderService(db, &capturePayments{}, anyProvider{})
+	conn := testharness.ServeGRPC(t, func(server *grpc.Server) {
+		pb.RegisterOrderServiceServer(server, s)
+	})
+	client := pb.NewOrderServiceClient(conn)
+
+	fromID, toID := uuid.New().String(), uuid.New().String()
+	orderID := testharness.InsertOrder(t, db, testharness.OrderFixture{
+		ProviderID: fromID,
+		Status:     string(model.StatusInTransit),
+	})
+
+	// The provider breaks down halfway to the destination
+	locations := repository.NewOrderLocationRepository(db)
+	err := locations.CreateOrderLocation(ctx, &model.OrderLocation{
+		OrderID: orderID, ProviderID: fromID, Latitude: -6.191, Longitude: 106.8166, Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("CreateOrderLocation: %v", err)
+	}
+
+	resp, err := client.HandOffOrder(ctx, &pb.HandOffOrderRequest{
+		OrderId: orderID, NewProviderId: toID, RequestedBy: "admin", ByAdmin: true, Reason: "Flat tyre",
+	})
+	if err != nil {
+		t.Fatalf("HandOffOrder: %v", err)
+	}
+	if resp.Order.ProviderId != toID || resp.Handoff.FromProviderId != fromID {
+		t.Errorf("handed off from %s to %s, want from %s to %s", resp.Handoff.FromProviderId, resp.Order.ProviderId, fromID, toID)
+	}
+	if math.Abs(resp.Handoff.TripProgress-0.5) > 0.01 {
+		t.Errorf("trip progress = %v, want 0.5", resp.Handoff.TripProgress)
+	}
+
+	// The new provider's locations start where the old provider's stopped
+	latest, err := locations.GetLatestOrderLocation(ctx, orderID)
+	if err != nil {
+		t.Fatalf("GetLatestOrderLocation: %v", err)
+	}
+	if latest.ProviderID != toID || latest.Latitude != -6.191 {
+		t.Errorf("latest location by %s at %v, want by %s at -6.191", latest.ProviderID, latest.Latitude, toID)
+	}
+
+	orderRepo := repository.NewOrderRepository(db, nil)
+	if err := orderRepo.UpdateOrderStatus(ctx, orderID, model.StatusCompleted, toID, "Completed"); err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+
+	// The fixture's provider fee of 8000 is split down the middle
+	for providerID, entryType := range map[string]model.LedgerEntryType{fromID: model.LedgerHandoffFare, toID: model.LedgerFare} {
+		ledger, err := client.ListProviderLedger(ctx, &pb.ListProviderLedgerRequest{ProviderId: providerID, Page: 1, Limit: 10})
+		if err != nil {
+			t.Fatalf("ListProviderLedger: %v", err)
+		}
+		if len(ledger.Entries) != 1 || ledger.Entries[0].EntryType != string(entryType) || ledger.TotalEarnings != 4000 {
+			t.Errorf("got ledger %+v, want one 4000 %s", ledger.Entries, entryType)
+		}
+	}
+}
+
+func TestOrderServiceHandOffRefusesUnavailableProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider service.Provider
+	}{
+		{name: "suspended", provider: service.Provider{IsAvailable: true, Suspended: true}},
+		{name: "offline", provider: service.Provider{IsAvailable: false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := testharness.Postgres(t).Database(t, "order")
+			s := newPostgresOrderService(db, &capturePayments{}, oneProvider{provider: tt.provider})
+
+			fromID := uuid.New().String()
+			orderID := testharness.InsertOrder(t, db, testharness.OrderFixture{
+				ProviderID: fromID,
+				Status:     string(model.StatusInTransit),
+			})
+
+			_, err := s.HandOffOrder(ctx, &pb.HandOffOrderRequest{
+				OrderId: orderID, NewProviderId: uuid.New().String(), RequestedBy: "admin", ByAdmin: true, Reason: "Flat tyre",
+			})
+			wantCode(t, err, codes.FailedPrecondition)
+
+			order, err := repository.NewOrderRepository(db, nil).GetOrderByID(ctx, orderID)
+			if err != nil {
+				t.Fatalf("GetOrderByID: %v", err)
+			}
+			if order.ProviderID != fromID {
+				t.Errorf("provider = %s, want the order kept by %s", order.ProviderID, fromID)
+			}
+		})
+	}
+}
+
+func TestOrderServiceCancelsOrderThatCannotBeSetUp(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "order")
+	client := serveOrderService(t, db, &capturePayments{})
+
+	// Without a place to keep its delivery PIN the order cannot be set up in full
+	if _, err := db.ExecContext(ctx, `DROP TABLE delivery_pins`); err != nil {
+		t.Fatalf("drop delivery_pins: %v", err)
+	}
+
+	userID := uuid.New().String()
+	_, err := client.CreateOrder(ctx, &pb.CreateOrderRequest{
+		UserId:              userID,
+		OrderType:           pb.OrderType_ORDER_TYPE_FOOD_DELIVERY,
+		PickupLocation:      &pb.Location{Latitude: -6.2, Longitude: 106.8166, Address: "Warung"},
+		DestinationLocation: &pb.Location{Latitude: -6.182, Longitude: 106.8166, Address: "Home"},
+		Items:               []*pb.OrderItem{{ItemId: "item-1", Name: "Nasi goreng", Quantity: 1, Price: 25000}},
+		PaymentMethod:       pb.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD,
+	})
+	wantCode(t, err, codes.Internal)
+
+	orders, err := client.ListUserOrders(ctx, &pb.ListUserOrdersRequest{UserId: userID, Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListUserOrders: %v", err)
+	}
+	if len(orders.Orders) != 1 || orders.Orders[0].Status != pb.OrderStatus_ORDER_STATUS_CANCELLED {
+		t.Errorf("got orders %+v, want the one order cancelled", orders.Orders)
+	}
+}
@@ -0,0 +1,326 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository/memory"
+	"github.com/order-api-microservices/services/order/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testUserID     = "6f1c2a9e-0b7d-4c57-9a53-2d9e1f0c4b11"
+	testProviderID = "0d4b8e2f-5a61-4f3c-8b27-9c1e6a7d3f22"
+)
+
+// capturePayments records the payments it is asked to capture
+type capturePayments struct {
+	mu       sync.Mutex
+	captured []int64
+}
+
+func (p *capturePayments) RefundPayment(ctx context.Context, orderID, userID string, amount int64, reason, idempotencyKey string) (string, error) {
+	return "refund-" + orderID, nil
+}
+
+func (p *capturePayments) CapturePayment(ctx context.Context, orderID, userID string, amount int64, description, idempotencyKey string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.captured = append(p.captured, amount)
+	return idempotencyKey, nil
+}
+
+// discardNotifications drops every notification
+type discardNotifications struct{}
+
+func (discardNotifications) SendNotification(ctx context.Context, recipientID, recipientType, notificationType, title, message string, payload map[string]interface{}) error {
+	return nil
+}
+
+// recordNothing drops every blockchain record
+type recordNothing struct{}
+
+func (recordNothing) Record(ctx context.Context, orderID string) {}
+
+// testRepos are the in-memory repositories behind a test order service
+type testRepos struct {
+	orders    *memory.OrderRepository
+	shares    *memory.PaymentShareRepository
+	pins      *memory.DeliveryPINRepository
+	rentals   *memory.RentalRepository
+	vehicles  *memory.OrderVehicleRepository
+	merchants *memory.MerchantRepository
+	stock     *memory.StockRepository
+	methods   *memory.PaymentMethodRepository
+	holds     *memory.PaymentAuthorizationRepository
+	wallets   *memory.WalletRepository
+	loyalty   *memory.LoyaltyRepository
+}
+
+func newTestOrderService(payments service.PaymentClient) (*service.OrderService, testRepos) {
+	orders := memory.NewOrderRepository()
+	merchants := memory.NewMerchantRepository()
+	repos := testRepos{
+		orders:    orders,
+		shares:    memory.NewPaymentShareRepository(),
+		pins:      memory.NewDeliveryPINRepository(),
+		rentals:   memory.NewRentalRepository(orders),
+		vehicles:  memory.NewOrderVehicleRepository(),
+		merchants: merchants,
+		stock:     memory.NewStockRepository(merchants),
+		methods:   memory.NewPaymentMethodRepository(),
+		holds:     memory.NewPaymentAuthorizationRepository(),
+		wallets:   memory.NewWalletRepository(),
+		loyalty:   memory.NewLoyaltyRepository(),
+	}
+
+	// Payment clients that can authorize have card and wallet payments held
+	var authorizations *service.PaymentAuthorizations
+	if authorizer, ok := payments.(service.PaymentAuthorizer); ok {
+		authorizations = service.NewPaymentAuthorizations(repos.holds, orders, authorizer, service.PaymentAuthorizationConfig{
+			CaptureMargin: time.Hour,
+			BatchSize:     10,
+		})
+	}
+
+	s := service.NewOrderService(orders, memory.NewLocationRepository(), memory.NewRefundRepository(orders),
+		memory.NewLedgerRepository(orders), repos.shares, memory.NewDeliveryProofRepository(orders), repos.pins,
+		memory.NewOrderBatchRepository(), repos.rentals, repos.vehicles, repos.merchants,
+		repos.methods, repos.wallets, service.NewLoyalty(repos.loyalty, repos.methods, testLoyaltyPolicy), repos.stock, 30*time.Minute, nil, nil, recordNothing{}, nil, payments, discardNotifications{}, nil, authorizations,
+		service.NewFeeSchedule(nil, time.Minute),
+		service.CancellationPolicy{},
+		service.DeliveryPINPolicy{Length: 4, MaxAttempts: 3, Lockout: 15 * time.Minute, ResendInterval: time.Minute},
+		service.GeofencePolicy{}, service.LocationSamplingPolicy{}, service.ConcurrencyPolicy{}, service.BatchingPolicy{},
+		service.RentalPolicy{HourlyRate: 50000, MinHours: 1, MaxHours: 12},
+		service.PackagePolicy{}, service.DuplicatePolicy{Window: time.Minute},
+		service.PricingPolicy{BaseFares: map[model.OrderType]int64{model.TypeReturn: 20000}}, service.OrderEditPolicy{MaterialDistanceKm: 1, MaterialPricePercent: 10, DestinationChangeTTL: time.Minute},
+		service.ReturnPolicy{Window: 7 * 24 * time.Hour, DiscountPercent: 25},
+		nil, nil, service.NewServiceAreas(nil, time.Minute), nil, nil)
+	return s, repos
+}
+
+func storeTestOrder(t *testing.T, repos testRepos, id string, orderType model.OrderType, orderStatus model.OrderStatus) *model.Order {
+	t.Helper()
+
+	now := time.Now()
+	order := &model.Order{
+		ID:            id,
+		UserID:        testUserID,
+		ProviderID:    testProviderID,
+		OrderType:     orderType,
+		Status:        orderStatus,
+		PaymentMethod: model.PaymentCreditCard,
+		TotalPrice:    100000,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := repos.orders.CreateOrder(context.Background(), order, time.Time{}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	return order
+}
+
+func wantCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Fatalf("got error %v, want code %s", err, code)
+	}
+}
+
+func TestGetOrderIncludesSharesAndVehicle(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	order := storeTestOrder(t, repos, "5b0e7a4c-3f2d-4e19-a8c6-7d1f9b2e0a31", model.TypeRide, model.StatusProviderAccepted)
+
+	shares := []*model.PaymentShare{
+		{ID: "share-1", OrderID: order.ID, UserID: testUserID, Percentage: 60, Amount: 60000, Status: model.SharePending, CreatedAt: order.CreatedAt},
+		{ID: "share-2", OrderID: order.ID, UserID: testProviderID, Percentage: 40, Amount: 40000, Status: model.SharePending, CreatedAt: order.CreatedAt},
+	}
+	if err := repos.shares.CreateShares(ctx, shares); err != nil {
+		t.Fatalf("CreateShares: %v", err)
+	}
+	vehicle := &model.OrderVehicle{ProviderID: testProviderID, VehicleID: "vehicle-1", Plate: "B 1234 XY", Type: "CAR"}
+	if err := repos.vehicles.ReplaceVehicle(ctx, order.ID, vehicle); err != nil {
+		t.Fatalf("ReplaceVehicle: %v", err)
+	}
+
+	resp, err := s.GetOrder(ctx, &pb.GetOrderRequest{OrderId: order.ID})
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got := len(resp.Order.PaymentShares); got != 2 {
+		t.Errorf("got %d payment shares, want 2", got)
+	}
+	if resp.Order.Vehicle == nil || resp.Order.Vehicle.Plate != vehicle.Plate {
+		t.Errorf("got vehicle %v, want plate %s", resp.Order.Vehicle, vehicle.Plate)
+	}
+
+	_, err = s.GetOrder(ctx, &pb.GetOrderRequest{OrderId: "8a3e5c1d-2b4f-4d6a-9e7c-0f1b2d3c4e5f"})
+	wantCode(t, err, codes.NotFound)
+}
+
+func TestAddTipCreditsProviderOnce(t *testing.T) {
+	ctx := context.Background()
+	payments := &capturePayments{}
+	s, repos := newTestOrderService(payments)
+	order := storeTestOrder(t, repos, "2c7d9e1f-4a3b-4c5d-8e6f-1a2b3c4d5e6f", model.TypeRide, model.StatusCompleted)
+
+	resp, err := s.AddTip(ctx, &pb.AddTipRequest{OrderId: order.ID, UserId: testUserID, Amount: 15000})
+	if err != nil {
+		t.Fatalf("AddTip: %v", err)
+	}
+	if resp.Order.TipAmount != 15000 {
+		t.Errorf("tip amount = %d, want 15000", resp.Order.TipAmount)
+	}
+
+	_, err = s.AddTip(ctx, &pb.AddTipRequest{OrderId: order.ID, UserId: testUserID, Amount: 5000})
+	wantCode(t, err, codes.AlreadyExists)
+	if len(payments.captured) != 1 {
+		t.Errorf("captured %d payments, want 1", len(payments.captured))
+	}
+
+	ledger, err := s.ListProviderLedger(ctx, &pb.ListProviderLedgerRequest{ProviderId: testProviderID, Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListProviderLedger: %v", err)
+	}
+	if len(ledger.Entries) != 1 || ledger.TotalEarnings != 15000 {
+		t.Errorf("got %d entries earning %d, want 1 earning 15000", len(ledger.Entries), ledger.TotalEarnings)
+	}
+}
+
+func TestCompleteDeliveryLocksOutWrongPINs(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	order := storeTestOrder(t, repos, "9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b", model.TypeFoodDelivery, model.StatusArrived)
+
+	now := time.Now()
+	err := repos.pins.CreatePIN(ctx, &model.DeliveryPIN{
+		OrderID:   order.ID,
+		PINHash:   model.HashDeliveryPIN(order.ID, "1234"),
+		IssuedAt:  now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("CreatePIN: %v", err)
+	}
+
+	deliver := func(pin string) error {
+		_, err := s.CompleteDelivery(ctx, &pb.CompleteDeliveryRequest{
+			OrderId:      order.ID,
+			ProviderId:   testProviderID,
+			PhotoRef:     "photos/delivery.jpg",
+			RecipientOtp: pin,
+		})
+		return err
+	}
+
+	wantCode(t, deliver(""), codes.InvalidArgument)
+	wantCode(t, deliver("0000"), codes.PermissionDenied)
+	wantCode(t, deliver("1111"), codes.PermissionDenied)
+	wantCode(t, deliver("2222"), codes.ResourceExhausted)
+
+	// The right PIN is refused too until the lockout passes
+	wantCode(t, deliver("1234"), codes.ResourceExhausted)
+
+	pin, err := repos.pins.GetPIN(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetPIN: %v", err)
+	}
+	if !pin.Locked(time.Now()) {
+		t.Error("PIN is not locked after three wrong attempts")
+	}
+
+	stored, err := repos.orders.GetOrderByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if stored.Status != model.StatusArrived {
+		t.Errorf("order status = %s, want %s", stored.Status, model.StatusArrived)
+	}
+}
+
+func TestRentalExtensionApprovalChargesOrder(t *testing.T) {
+	ctx := context.Background()
+	payments := &capturePayments{}
+	s, repos := newTestOrderService(payments)
+	order := storeTestOrder(t, repos, "4d3c2b1a-0f9e-4d8c-b7a6-5f4e3d2c1b0a", model.TypeRental, model.StatusInProgress)
+
+	started := time.Now().Add(-30 * time.Minute)
+	err := repos.rentals.CreateRental(ctx, &model.Rental{
+		OrderID:      order.ID,
+		HourlyRate:   50000,
+		OvertimeRate: 75000,
+		BookedHours:  2,
+		StartedAt:    &started,
+		CreatedAt:    started,
+		UpdatedAt:    started,
+	})
+	if err != nil {
+		t.Fatalf("CreateRental: %v", err)
+	}
+
+	resp, err := s.RequestRentalExtension(ctx, &pb.RequestRentalExtensionRequest{OrderId: order.ID, UserId: testUserID, Hours: 3})
+	if err != nil {
+		t.Fatalf("RequestRentalExtension: %v", err)
+	}
+	if len(resp.Rental.Extensions) != 1 {
+		t.Fatalf("got %d extensions, want 1", len(resp.Rental.Extensions))
+	}
+	extensionID := resp.Rental.Extensions[0].Id
+
+	_, err = s.RequestRentalExtension(ctx, &pb.RequestRentalExtensionRequest{OrderId: order.ID, UserId: testUserID, Hours: 1})
+	wantCode(t, err, codes.AlreadyExists)
+
+	_, err = s.RequestRentalExtension(ctx, &pb.RequestRentalExtensionRequest{OrderId: order.ID, UserId: testProviderID, Hours: 1})
+	wantCode(t, err, codes.PermissionDenied)
+
+	respond := &pb.RespondRentalExtensionRequest{OrderId: order.ID, ExtensionId: extensionID, ProviderId: testProviderID, Approve: true}
+	resp, err = s.RespondRentalExtension(ctx, respond)
+	if err != nil {
+		t.Fatalf("RespondRentalExtension: %v", err)
+	}
+	if resp.Rental.BookedHours != 5 {
+		t.Errorf("booked hours = %d, want 5", resp.Rental.BookedHours)
+	}
+
+	stored, err := repos.orders.GetOrderByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if stored.TotalPrice != 250000 {
+		t.Errorf("order total = %d, want 250000", stored.TotalPrice)
+	}
+	if stored.PlatformFee+stored.ProviderFee > stored.TotalPrice {
+		t.Errorf("fees %d + %d exceed total %d", stored.PlatformFee, stored.ProviderFee, stored.TotalPrice)
+	}
+	if len(payments.captured) != 1 || payments.captured[0] != 150000 {
+		t.Errorf("captured %v, want [150000]", payments.captured)
+	}
+
+	_, err = s.RespondRentalExtension(ctx, respond)
+	wantCode(t, err, codes.FailedPrecondition)
+}
+
+func TestUpdateOrderStatusLeavesCancellingToCancelOrder(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	order := storeTestOrder(t, repos, "2d7c9e41-6a3b-4f85-b0e2-9c1d8a4f7b53", model.TypeRide, model.StatusProviderAccepted)
+
+	_, err := s.UpdateOrderStatus(ctx, &pb.UpdateOrderStatusRequest{OrderId: order.ID, Status: pb.OrderStatus_ORDER_STATUS_CANCELLED, UpdatedBy: testUserID})
+	wantCode(t, err, codes.InvalidArgument)
+
+	got, err := repos.orders.GetOrderByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if got.Status != model.StatusProviderAccepted {
+		t.Errorf("status = %s, want it left at %s", got.Status, model.StatusProviderAccepted)
+	}
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	blockchainPb "github.com/order-api-microservices/proto/blockchain"
+	pb "github.com/order-api-microservices/proto/timeline"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Sources of the events on an order's timeline
+const (
+	timelineStatus       = "STATUS"
+	timelineLocation     = "LOCATION"
+	timelineNotification = "NOTIFICATION"
+	timelinePayment      = "PAYMENT"
+	timelineBlockchain   = "BLOCKCHAIN"
+)
+
+// TimelineNotificationClient lists the notifications sent about an order
+type TimelineNotificationClient interface {
+	ListOrderNotifications(ctx context.Context, orderID string) ([]*model.ExportedNotification, error)
+}
+
+// TimelineBlockchainClient reads the records of an order on the blockchain
+type TimelineBlockchainClient interface {
+	GetOrderHistory(ctx context.Context, orderID string) ([]*blockchainPb.OrderHistoryItem, error)
+}
+
+// TimelinePolicy controls how an order's timeline is put together
+type TimelinePolicy struct {
+	SourceTimeout time.Duration // How long each source has to answer before it is left out; zero waits as long as the call
+}
+
+// timelineSource reads one source's events about an order
+type timelineSource struct {
+	name string
+	read func(ctx context.Context, order *model.Order) ([]*pb.TimelineEvent, error)
+}
+
+// OrderTimelineService merges the status changes, location milestones, notifications,
+// payment events and blockchain records of an order into one feed, oldest first
+type OrderTimelineService struct {
+	pb.UnimplementedOrderTimelineServiceServer
+	orderRepo          *repository.OrderRepository
+	deviationRepo      *repository.RouteDeviationRepository
+	authorizationRepo  *repository.PaymentAuthorizationRepository
+	shareRepo          *repository.PaymentShareRepository
+	refundRepo         *repository.RefundRepository
+	notificationClient TimelineNotificationClient
+	blockchainClient   TimelineBlockchainClient
+	policy             TimelinePolicy
+}
+
+// NewOrderTimelineService creates a new order timeline service
+func NewOrderTimelineService(
+	orderRepo *repository.OrderRepository,
+	deviationRepo *repository.RouteDeviationRepository,
+	authorizationRepo *repository.PaymentAuthorizationRepository,
+	shareRepo *repository.PaymentShareRepository,
+	refundRepo *repository.RefundRepository,
+	notificationClient TimelineNotificationClient,
+	blockchainClient TimelineBlockchainClient,
+	policy TimelinePolicy,
+) *OrderTimelineService {
+	return &OrderTimelineService{
+		orderRepo:          orderRepo,
+		deviationRepo:      deviationRepo,
+		authorizationRepo:  authorizationRepo,
+		shareRepo:          shareRepo,
+		refundRepo:         refundRepo,
+		notificationClient: notificationClient,
+		blockchainClient:   blockchainClient,
+		policy:             policy,
+	}
+}
+
+// GetOrderTimeline reads every source of events about an order in parallel and merges
+// them. A source that fails or runs out of time is reported in the response's errors and
+// its events are left out.
+func (s *OrderTimelineService) GetOrderTimeline(ctx context.Context, req *pb.GetOrderTimelineRequest) (*pb.GetOrderTimelineResponse, error) {
+	if req.OrderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order ID is required")
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	sources := []timelineSource{
+		{name: timelineStatus, read: s.statusEvents},
+		{name: timelineLocation, read: s.locationEvents},
+		{name: timelineNotification, read: s.notificationEvents},
+		{name: timelinePayment, read: s.paymentEvents},
+		{name: timelineBlockchain, read: s.blockchainEvents},
+	}
+
+	events := make([][]*pb.TimelineEvent, len(sources))
+	errs := make([]error, len(sources))
+
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source timelineSource) {
+			defer wg.Done()
+
+			sourceCtx := ctx
+			if s.policy.SourceTimeout > 0 {
+				var cancel context.CancelFunc
+				sourceCtx, cancel = context.WithTimeout(ctx, s.policy.SourceTimeout)
+				defer cancel()
+			}
+			events[i], errs[i] = source.read(sourceCtx, order)
+		}(i, source)
+	}
+	wg.Wait()
+
+	resp := &pb.GetOrderTimelineResponse{OrderId: order.ID}
+	for i, source := range sources {
+		if errs[i] != nil {
+			resp.Errors = append(resp.Errors, &pb.SourceError{Source: source.name, Error: errs[i].Error()})
+			continue
+		}
+		resp.Events = append(resp.Events, events[i]...)
+	}
+	resp.Partial = len(resp.Errors) > 0
+
+	// Sources are appended in a fixed order, so events at the same time keep it
+	sort.SliceStable(resp.Events, func(i, j int) bool {
+		return resp.Events[i].Timestamp.AsTime().Before(resp.Events[j].Timestamp.AsTime())
+	})
+
+	return resp, nil
+}
+
+// statusEvents turns an order's status history into events
+func (s *OrderTimelineService) statusEvents(ctx context.Context, order *model.Order) ([]*pb.TimelineEvent, error) {
+	events := make([]*pb.TimelineEvent, 0, len(order.StatusHistory))
+	for _, change := range order.StatusHistory {
+		event := timelineEvent(change.Timestamp, timelineStatus, string(change.Status), fmt.Sprintf("Order status changed to %s", change.Status), change.UpdatedBy)
+		if change.Notes != "" {
+			event.Details["notes"] = change.Notes
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// locationEvents lists when the provider reached the pickup and when they left and
+// rejoined the expected route. Arriving at the destination is a status change.
+func (s *OrderTimelineService) locationEvents(ctx context.Context, order *model.Order) ([]*pb.TimelineEvent, error) {
+	events := []*pb.TimelineEvent{}
+
+	arrivedAt, err := s.orderRepo.GetPickupArrival(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if arrivedAt != nil {
+		events = append(events, timelineEvent(*arrivedAt, timelineLocation, "PICKUP_ARRIVED", "Provider arrived at the pickup location", order.ProviderID))
+	}
+
+	deviations, err := s.deviationRepo.ListOrderDeviations(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, deviation := range deviations {
+		event := timelineEvent(deviation.StartedAt, timelineLocation, "ROUTE_DEVIATION_STARTED",
+			fmt.Sprintf("Provider left the expected route by %.1f km", deviation.DistanceKm), deviation.ProviderID)
+		event.Details["distance_km"] = strconv.FormatFloat(deviation.DistanceKm, 'f', 2, 64)
+		event.Details["latitude"] = strconv.FormatFloat(deviation.Latitude, 'f', -1, 64)
+		event.Details["longitude"] = strconv.FormatFloat(deviation.Longitude, 'f', -1, 64)
+		events = append(events, event)
+
+		if deviation.ClearedAt != nil {
+			events = append(events, timelineEvent(*deviation.ClearedAt, timelineLocation, "ROUTE_DEVIATION_CLEARED",
+				"Provider rejoined the expected route", deviation.ProviderID))
+		}
+	}
+
+	return events, nil
+}
+
+// notificationEvents lists the notifications sent about an order to its user and provider
+func (s *OrderTimelineService) notificationEvents(ctx context.Context, order *model.Order) ([]*pb.TimelineEvent, error) {
+	notifications, err := s.notificationClient.ListOrderNotifications(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*pb.TimelineEvent, 0, len(notifications))
+	for _, notification := range notifications {
+		event := timelineEvent(notification.CreatedAt, timelineNotification, notification.NotificationType, notification.Title, "")
+		event.Details["notification_id"] = notification.ID
+		event.Details["recipient_type"] = notification.RecipientType
+		event.Details["read"] = strconv.FormatBool(notification.Read)
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// paymentEvents lists the authorization and settlement of an order's payment hold, the
+// payments of its split shares, and its refunds
+func (s *OrderTimelineService) paymentEvents(ctx context.Context, order *model.Order) ([]*pb.TimelineEvent, error) {
+	events := []*pb.TimelineEvent{}
+
+	auth, err := s.authorizationRepo.GetAuthorization(ctx, order.ID)
+	if err != nil && !errors.Is(err, repository.ErrPaymentAuthorizationNotFound) {
+		return nil, err
+	}
+	if auth != nil {
+		event := timelineEvent(auth.CreatedAt, timelinePayment, "PAYMENT_AUTHORIZED",
+			fmt.Sprintf("%d held on the payer's %s", auth.Amount, order.PaymentMethod), order.UserID)
+		event.Details["amount"] = strconv.FormatInt(auth.Amount, 10)
+		events = append(events, event)
+
+		if auth.SettledAt != nil {
+			switch auth.Status {
+			case model.AuthorizationCaptured:
+				event := timelineEvent(*auth.SettledAt, timelinePayment, "PAYMENT_CAPTURED",
+					fmt.Sprintf("%d charged", auth.CapturedAmount), "")
+				event.Details["amount"] = strconv.FormatInt(auth.CapturedAmount, 10)
+				event.Details["payment_id"] = auth.PaymentID
+				events = append(events, event)
+			case model.AuthorizationReleased:
+				events = append(events, timelineEvent(*auth.SettledAt, timelinePayment, "PAYMENT_RELEASED",
+					"Payment hold released without charging", ""))
+			}
+		}
+	}
+
+	shares, err := s.shareRepo.ListOrderShares(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, share := range shares {
+		if share.Status == model.SharePending {
+			continue
+		}
+		event := timelineEvent(share.UpdatedAt, timelinePayment, "SHARE_"+string(share.Status),
+			fmt.Sprintf("Split share of %d %s", share.Amount, share.Status), share.UserID)
+		event.Details["amount"] = strconv.FormatInt(share.Amount, 10)
+		if share.PaymentID != "" {
+			event.Details["payment_id"] = share.PaymentID
+		}
+		events = append(events, event)
+	}
+
+	refunds, err := s.refundRepo.ListOrderRefunds(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, refund := range refunds {
+		event := timelineEvent(refund.CreatedAt, timelinePayment, "REFUND_ISSUED",
+			fmt.Sprintf("%d refunded", refund.Amount), refund.RequestedBy)
+		event.Details["amount"] = strconv.FormatInt(refund.Amount, 10)
+		event.Details["reason"] = refund.Reason
+		if refund.WalletAmount > 0 {
+			event.Details["wallet_amount"] = strconv.FormatInt(refund.WalletAmount, 10)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// blockchainEvents lists the confirmations of an order's records on the blockchain.
+// Orders never recorded there have none.
+func (s *OrderTimelineService) blockchainEvents(ctx context.Context, order *model.Order) ([]*pb.TimelineEvent, error) {
+	if order.BlockchainTxHash == "" {
+		return nil, nil
+	}
+
+	history, err := s.blockchainClient.GetOrderHistory(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*pb.TimelineEvent, 0, len(history))
+	for _, item := range history {
+		if item.Timestamp == nil {
+			continue
+		}
+		event := timelineEvent(item.Timestamp.AsTime(), timelineBlockchain, "BLOCKCHAIN_CONFIRMED",
+			fmt.Sprintf("Recorded in block %s", item.BlockNumber), item.UpdatedBy)
+		event.Details["transaction_hash"] = item.TransactionHash
+		event.Details["block_number"] = item.BlockNumber
+		event.Details["status"] = item.Status.String()
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// timelineEvent creates an event with no details yet
+func timelineEvent(at time.Time, source, eventType, summary, actor string) *pb.TimelineEvent {
+	return &pb.TimelineEvent{
+		Timestamp: timestamppb.New(at),
+		Source:    source,
+		Type:      eventType,
+		Summary:   summary,
+		Actor:     actor,
+		Details:   map[string]string{},
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// recordVehicle records the vehicle a provider is on shift in as the one they accepted
+// an order in, for receipts and support. It is best effort: the order stays accepted
+// whether or not the vehicle could be recorded.
+func (s *OrderService) recordVehicle(ctx context.Context, orderID, providerID string) {
+	provider, err := s.providerClient.GetProviderDetails(ctx, providerID)
+	if err != nil {
+		fmt.Printf("Failed to get vehicle of provider %s for order %s: %v\n", providerID, orderID, err)
+		return
+	}
+
+	vehicle := provider.Vehicle
+	if vehicle != nil {
+		vehicle.OrderID = orderID
+		vehicle.RecordedAt = time.Now()
+	}
+	if err := s.vehicleRepo.ReplaceVehicle(ctx, orderID, vehicle); err != nil {
+		fmt.Printf("Failed to record vehicle of order %s: %v\n", orderID, err)
+	}
+}
+
+// convertOrderVehicleToProto converts the vehicle an order was accepted in to its
+// protobuf representation, or nil if none was recorded
+func convertOrderVehicleToProto(vehicle *model.OrderVehicle) *pb.OrderVehicle {
+	if vehicle == nil {
+		return nil
+	}
+	return &pb.OrderVehicle{
+		VehicleId:   vehicle.VehicleID,
+		Plate:       vehicle.Plate,
+		Type:        vehicle.Type,
+		Description: vehicle.Description,
+		RecordedAt:  timestamppb.New(vehicle.RecordedAt),
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PackagePolicy prices package deliveries by their size class. Small packages cost
+// nothing extra; the surcharges are in minor units.
+type PackagePolicy struct {
+	MediumSurcharge    int64
+	LargeSurcharge     int64
+	OversizedSurcharge int64
+}
+
+// surcharge checks every item of a package delivery was weighed and measured, and
+// returns what the package's size class adds to its total
+func (p PackagePolicy) surcharge(items model.OrderItems) (int64, error) {
+	if len(items) == 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "package deliveries need at least one item")
+	}
+	for _, item := range items {
+		if !item.Measured() {
+			return 0, status.Errorf(codes.InvalidArgument, "package item %q needs a weight, length, width and height", item.Name)
+		}
+	}
+
+	switch items.PackageSize().Class() {
+	case model.SizeMedium:
+		return p.MediumSurcharge, nil
+	case model.SizeLarge:
+		return p.LargeSurcharge, nil
+	case model.SizeOversized:
+		return p.OversizedSurcharge, nil
+	default:
+		return 0, nil
+	}
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// PaymentAuthorizer holds order payments on the payer's card or wallet and later captures
+// or voids them. It is implemented by clients.PaymentGRPCClient.
+type PaymentAuthorizer interface {
+	// AuthorizePayment holds amount and gives back the authorization ID and when it lapses
+	AuthorizePayment(ctx context.Context, orderID, userID, paymentMethod, paymentMethodID string, amount int64, idempotencyKey string) (string, time.Time, error)
+	// CaptureAuthorization charges up to the authorized amount, releasing the rest
+	CaptureAuthorization(ctx context.Context, orderID, authorizationID string, amount int64, idempotencyKey string) (string, error)
+	VoidAuthorization(ctx context.Context, orderID, authorizationID, reason, idempotencyKey string) error
+}
+
+// PaymentAuthorizationRepository holds the payments authorized when orders are placed. It
+// is implemented by repository.PaymentAuthorizationRepository on Postgres and by
+// memory.PaymentAuthorizationRepository for tests.
+type PaymentAuthorizationRepository interface {
+	CreateAuthorization(ctx context.Context, auth *model.PaymentAuthorization) error
+	GetAuthorization(ctx context.Context, orderID string) (*model.PaymentAuthorization, error)
+	// SettleAuthorization records a capture or release, failing with
+	// repository.ErrPaymentAuthorizationSettled if the authorization was already settled
+	SettleAuthorization(ctx context.Context, orderID string, status model.AuthorizationStatus, capturedAmount int64, paymentID string, at time.Time) error
+	// ListLapsingAuthorizations lists up to limit authorizations still held that lapse
+	// before a time, soonest first
+	ListLapsingAuthorizations(ctx context.Context, before time.Time, limit int) ([]*model.PaymentAuthorization, error)
+}
+
+// PaymentAuthorizationConfig controls how held payments are settled in the background
+type PaymentAuthorizationConfig struct {
+	CaptureMargin time.Duration // Holds still open this long before they lapse are settled
+	Interval      time.Duration // How often lapsing holds are settled
+	BatchSize     int           // Most holds settled per run
+}
+
+// PaymentAuthorizations holds the total of card and wallet orders when they are placed,
+// captures it when the order completes and releases it when the order is cancelled. A
+// nil *PaymentAuthorizations authorizes nothing, and orders are paid as before.
+type PaymentAuthorizations struct {
+	repo      PaymentAuthorizationRepository
+	orderRepo OrderRepository
+	client    PaymentAuthorizer
+	cfg       PaymentAuthorizationConfig
+}
+
+// NewPaymentAuthorizations creates new payment authorizations
+func NewPaymentAuthorizations(repo PaymentAuthorizationRepository, orderRepo OrderRepository, client PaymentAuthorizer, cfg PaymentAuthorizationConfig) *PaymentAuthorizations {
+	return &PaymentAuthorizations{
+		repo:      repo,
+		orderRepo: orderRepo,
+		client:    client,
+		cfg:       cfg,
+	}
+}
+
+// authorizes reports whether a new order's payment is authorized up front. Cash and
+// crypto are paid on their own terms, and split payments are collected share by share.
+func (a *PaymentAuthorizations) authorizes(order *model.Order) bool {
+	if a == nil || order.PaymentMethodAmount() <= 0 || len(order.PaymentShares) > 0 {
+		return false
+	}
+	switch order.PaymentMethod {
+	case model.PaymentCreditCard, model.PaymentDebitCard, model.PaymentDigitalWallet:
+		return true
+	default:
+		return false
+	}
+}
+
+// authorize holds a new order's total before the order is stored. If the order is then
+// not placed, discard gives the hold back.
+func (a *PaymentAuthorizations) authorize(ctx context.Context, order *model.Order) (*model.PaymentAuthorization, error) {
+	authorizationID, expiresAt, err := a.client.AuthorizePayment(ctx, order.ID, order.UserID, string(order.PaymentMethod),
+		order.PaymentMethodID, order.PaymentMethodAmount(), "authorize-"+order.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "payment could not be authorized: %v", err)
+	}
+
+	return &model.PaymentAuthorization{
+		OrderID:         order.ID,
+		AuthorizationID: authorizationID,
+		Amount:          order.PaymentMethodAmount(),
+		Status:          model.AuthorizationAuthorized,
+		ExpiresAt:       expiresAt,
+		CreatedAt:       order.CreatedAt,
+		UpdatedAt:       order.CreatedAt,
+	}, nil
+}
+
+// record stores the hold of an order once the order is stored, voiding it if it cannot
+// be stored
+func (a *PaymentAuthorizations) record(ctx context.Context, auth *model.PaymentAuthorization) error {
+	if err := a.repo.CreateAuthorization(ctx, auth); err != nil {
+		a.discard(ctx, auth)
+		return err
+	}
+	return nil
+}
+
+// discard voids the hold of an order that was not placed. Failures are logged; the hold
+// lapses on its own.
+func (a *PaymentAuthorizations) discard(ctx context.Context, auth *model.PaymentAuthorization) {
+	if auth == nil {
+		return
+	}
+	if err := a.client.VoidAuthorization(ctx, auth.OrderID, auth.AuthorizationID, "Order not placed", "void-"+auth.OrderID); err != nil {
+		log.Printf("Failed to void payment authorization of order %s: %v", auth.OrderID, err)
+	}
+}
+
+// get retrieves an order's authorization, or nil if its payment was not authorized up front
+func (a *PaymentAuthorizations) get(ctx context.Context, orderID string) (*model.PaymentAuthorization, error) {
+	if a == nil {
+		return nil, nil
+	}
+	auth, err := a.repo.GetAuthorization(ctx, orderID)
+	if errors.Is(err, repository.ErrPaymentAuthorizationNotFound) {
+		return nil, nil
+	}
+	return auth, err
+}
+
+// capture charges amount of a held payment and releases the rest
+func (a *PaymentAuthorizations) capture(ctx context.Context, auth *model.PaymentAuthorization, amount int64, at time.Time) error {
+	paymentID, err := a.client.CaptureAuthorization(ctx, auth.OrderID, auth.AuthorizationID, amount, "capture-"+auth.OrderID)
+	if err != nil {
+		return err
+	}
+	err = a.repo.SettleAuthorization(ctx, auth.OrderID, model.AuthorizationCaptured, amount, paymentID, at)
+	if err != nil && !errors.Is(err, repository.ErrPaymentAuthorizationSettled) {
+		return fmt.Errorf("payment captured as %s but failed to record it: %w", paymentID, err)
+	}
+
+	auth.Status = model.AuthorizationCaptured
+	auth.CapturedAmount = amount
+	auth.PaymentID = paymentID
+	auth.SettledAt = &at
+	return nil
+}
+
+// release voids a held payment without charging any of it
+func (a *PaymentAuthorizations) release(ctx context.Context, auth *model.PaymentAuthorization, reason string, at time.Time) error {
+	if err := a.client.VoidAuthorization(ctx, auth.OrderID, auth.AuthorizationID, reason, "void-"+auth.OrderID); err != nil {
+		return err
+	}
+	err := a.repo.SettleAuthorization(ctx, auth.OrderID, model.AuthorizationReleased, 0, "", at)
+	if err != nil && !errors.Is(err, repository.ErrPaymentAuthorizationSettled) {
+		return fmt.Errorf("payment released but failed to record it: %w", err)
+	}
+
+	auth.Status = model.AuthorizationReleased
+	auth.SettledAt = &at
+	return nil
+}
+
+// Run settles lapsing holds every interval until ctx is cancelled
+func (a *PaymentAuthorizations) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.Sweep(ctx, time.Now()); err != nil {
+				log.Printf("Failed to list lapsing payment authorizations: %v", err)
+			}
+		}
+	}
+}
+
+// Sweep settles one batch of the holds that lapse within the capture margin of now. A
+// completed order's hold is captured and a cancelled one's released, finishing a capture
+// or release that failed before. A hold of an order still under way is captured in full
+// rather than lost; cancelling the order later refunds it, less any cancellation fee.
+// Holds that fail to settle are logged and left for the next sweep.
+func (a *PaymentAuthorizations) Sweep(ctx context.Context, now time.Time) error {
+	auths, err := a.repo.ListLapsingAuthorizations(ctx, now.Add(a.cfg.CaptureMargin), a.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, auth := range auths {
+		if err := a.settle(ctx, auth, now); err != nil {
+			log.Printf("Failed to settle payment authorization of order %s: %v", auth.OrderID, err)
+		}
+	}
+	return nil
+}
+
+// settle captures or releases one lapsing hold
+func (a *PaymentAuthorizations) settle(ctx context.Context, auth *model.PaymentAuthorization, now time.Time) error {
+	order, err := a.orderRepo.GetOrderByID(ctx, auth.OrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return a.release(ctx, auth, "Order no longer exists", now)
+		}
+		return err
+	}
+
+	switch {
+	case order.Status == model.StatusCancelled || order.Status == model.StatusRefunded:
+		return a.release(ctx, auth, "Order cancelled", now)
+	case order.Status == model.StatusCompleted:
+		return a.capture(ctx, auth, min(order.PaymentMethodAmount(), auth.Amount), now)
+	default:
+		return a.capture(ctx, auth, auth.Amount, now)
+	}
+}
+
+// captureAuthorization charges a completing order's held payment. Orders whose payment
+// was not authorized up front, or was already captured, are left alone.
+func (s *OrderService) captureAuthorization(ctx context.Context, order *model.Order) error {
+	auth, err := s.authorizations.get(ctx, order.ID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to get payment authorization: %v", err)
+	}
+	if auth == nil || auth.Status != model.AuthorizationAuthorized {
+		return nil
+	}
+
+	if err := s.authorizations.capture(ctx, auth, min(order.PaymentMethodAmount(), auth.Amount), time.Now()); err != nil {
+		return status.Errorf(codes.Unavailable, "failed to capture payment: %v", err)
+	}
+	return nil
+}
+
+// settleCancelledAuthorization gives back a cancelled order's held payment. A payment
+// still held is released, keeping only the cancellation fee; one captured before the
+// order was cancelled is refunded less the fee.
+func (s *OrderService) settleCancelledAuthorization(ctx context.Context, auth *model.PaymentAuthorization, req *pb.CancelOrderRequest) {
+	switch auth.Status {
+	case model.AuthorizationAuthorized:
+		if err := s.authorizations.release(ctx, auth, req.Reason, time.Now()); err != nil {
+			log.Printf("Failed to release payment authorization of order %s: %v", auth.OrderID, err)
+		}
+	case model.AuthorizationCaptured:
+		_, err := s.RefundOrder(ctx, &pb.RefundOrderRequest{
+			OrderId:     auth.OrderID,
+			RequestedBy: req.CancelledBy,
+			Reason:      "Order cancelled after its payment was captured",
+		})
+		if err != nil {
+			log.Printf("Failed to refund cancelled order %s: %v", auth.OrderID, err)
+		}
+	}
+}
+
+func convertPaymentAuthorizationToProto(auth *model.PaymentAuthorization) *pb.PaymentAuthorization {
+	if auth == nil {
+		return nil
+	}
+	protoAuth := &pb.PaymentAuthorization{
+		AuthorizationId: auth.AuthorizationID,
+		Amount:          auth.Amount,
+		CapturedAmount:  auth.CapturedAmount,
+		Status:          string(auth.Status),
+		ExpiresAt:       timestamppb.New(auth.ExpiresAt),
+	}
+	if auth.SettledAt != nil {
+		protoAuth.SettledAt = timestamppb.New(*auth.SettledAt)
+	}
+	return protoAuth
+}
@@ -0,0 +1,195 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/service"
+	"google.golang.org/grpc/codes"
+)
+
+// holdPayments authorizes payments for a day and records what is captured, voided and refunded
+type holdPayments struct {
+	capturePayments
+	mu       sync.Mutex
+	decline  bool
+	captures map[string]int64 // By order ID
+	voided   []string
+	refunded []int64
+}
+
+func newHoldPayments() *holdPayments {
+	return &holdPayments{captures: make(map[string]int64)}
+}
+
+func (p *holdPayments) AuthorizePayment(ctx context.Context, orderID, userID, paymentMethod, paymentMethodID string, amount int64, idempotencyKey string) (string, time.Time, error) {
+	if p.decline {
+		return "", time.Time{}, errors.New("card declined")
+	}
+	return "auth-" + orderID, time.Now().Add(24 * time.Hour), nil
+}
+
+func (p *holdPayments) CaptureAuthorization(ctx context.Context, orderID, authorizationID string, amount int64, idempotencyKey string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.captures[orderID] = amount
+	return "payment-" + orderID, nil
+}
+
+func (p *holdPayments) VoidAuthorization(ctx context.Context, orderID, authorizationID, reason, idempotencyKey string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.voided = append(p.voided, orderID)
+	return nil
+}
+
+func (p *holdPayments) RefundPayment(ctx context.Context, orderID, userID string, amount int64, reason, idempotencyKey string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refunded = append(p.refunded, amount)
+	return "refund-" + orderID, nil
+}
+
+// heldOrder is a card order for the test merchant's item delivered to destination
+func heldOrder(destination string) *pb.CreateOrderRequest {
+	req := orderFromMerchant(1)
+	req.DestinationLocation.Address = destination
+	return req
+}
+
+func TestHeldPaymentIsCapturedOnCompletion(t *testing.T) {
+	ctx := context.Background()
+	payments := newHoldPayments()
+	s, repos := newTestOrderService(payments)
+	stockTestMerchant(t, repos, 5)
+
+	resp, err := s.CreateOrder(ctx, heldOrder("Home"))
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	auth := resp.Order.PaymentAuthorization
+	if auth == nil || auth.Status != "AUTHORIZED" || auth.Amount != resp.Order.TotalPrice {
+		t.Fatalf("authorization = %v, want %d AUTHORIZED", auth, resp.Order.TotalPrice)
+	}
+
+	// Nothing was charged yet, so there is nothing to refund
+	_, err = s.RefundOrder(ctx, &pb.RefundOrderRequest{OrderId: resp.Order.Id, RequestedBy: "admin", Reason: "test"})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	_, err = s.UpdateOrderStatus(ctx, &pb.UpdateOrderStatusRequest{OrderId: resp.Order.Id, Status: pb.OrderStatus_ORDER_STATUS_COMPLETED, UpdatedBy: testProviderID})
+	if err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+	if got := payments.captures[resp.Order.Id]; got != resp.Order.TotalPrice {
+		t.Errorf("captured %d, want %d", got, resp.Order.TotalPrice)
+	}
+
+	got, err := s.GetOrder(ctx, &pb.GetOrderRequest{OrderId: resp.Order.Id})
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got.Order.PaymentAuthorization.Status != "CAPTURED" {
+		t.Errorf("authorization status = %s, want CAPTURED", got.Order.PaymentAuthorization.Status)
+	}
+}
+
+func TestHeldPaymentIsReleasedOnCancellation(t *testing.T) {
+	ctx := context.Background()
+	payments := newHoldPayments()
+	s, repos := newTestOrderService(payments)
+	stockTestMerchant(t, repos, 5)
+
+	resp, err := s.CreateOrder(ctx, heldOrder("Home"))
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	_, err = s.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: resp.Order.Id, CancelledBy: testUserID, Reason: "changed my mind"})
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+
+	if len(payments.voided) != 1 || len(payments.captures) != 0 {
+		t.Errorf("voided %v and captured %v, want only the hold voided", payments.voided, payments.captures)
+	}
+	auth, err := repos.holds.GetAuthorization(ctx, resp.Order.Id)
+	if err != nil {
+		t.Fatalf("GetAuthorization: %v", err)
+	}
+	if auth.Status != model.AuthorizationReleased {
+		t.Errorf("authorization status = %s, want RELEASED", auth.Status)
+	}
+}
+
+func TestDeclinedAuthorizationRefusesOrder(t *testing.T) {
+	ctx := context.Background()
+	payments := newHoldPayments()
+	payments.decline = true
+	s, repos := newTestOrderService(payments)
+	stockTestMerchant(t, repos, 5)
+
+	_, err := s.CreateOrder(ctx, heldOrder("Home"))
+	wantCode(t, err, codes.FailedPrecondition)
+
+	_, total, err := repos.orders.ListUserOrders(ctx, testUserID, 1, 10, "")
+	if err != nil {
+		t.Fatalf("ListUserOrders: %v", err)
+	}
+	if total != 0 {
+		t.Errorf("stored %d orders, want none", total)
+	}
+}
+
+func TestSweepCapturesLapsingHoldsAndCancellingRefundsThem(t *testing.T) {
+	ctx := context.Background()
+	payments := newHoldPayments()
+	s, repos := newTestOrderService(payments)
+	stockTestMerchant(t, repos, 5)
+	sweeper := service.NewPaymentAuthorizations(repos.holds, repos.orders, payments, service.PaymentAuthorizationConfig{
+		CaptureMargin: time.Hour,
+		BatchSize:     10,
+	})
+
+	active, err := s.CreateOrder(ctx, heldOrder("Home"))
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	other := heldOrder("Monas")
+	other.DestinationLocation.Latitude = -6.1754
+	cancelled, err := s.CreateOrder(ctx, other)
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	// Not lapsing yet
+	if err := sweeper.Sweep(ctx, time.Now()); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if len(payments.captures) != 0 {
+		t.Fatalf("captured %v before the holds were lapsing", payments.captures)
+	}
+
+	// The order still under way is captured rather than losing its hold
+	if err := sweeper.Sweep(ctx, time.Now().Add(24*time.Hour)); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if payments.captures[active.Order.Id] != active.Order.TotalPrice || payments.captures[cancelled.Order.Id] != cancelled.Order.TotalPrice {
+		t.Fatalf("captured %v, want both orders in full", payments.captures)
+	}
+
+	// Cancelling afterwards gives the captured payment back
+	resp, err := s.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: cancelled.Order.Id, CancelledBy: testUserID, Reason: "driver never came"})
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if len(payments.refunded) != 1 || payments.refunded[0] != cancelled.Order.TotalPrice {
+		t.Errorf("refunded %v, want %d", payments.refunded, cancelled.Order.TotalPrice)
+	}
+	if resp.Order.Status != pb.OrderStatus_ORDER_STATUS_REFUNDED {
+		t.Errorf("order status = %s, want REFUNDED", resp.Order.Status)
+	}
+}
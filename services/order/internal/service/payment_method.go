@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// applySavedPaymentMethod pays an order with one of the user's saved payment methods:
+// the one the order names, or the user's default when it names neither a saved method
+// nor a payment method. Orders naming only a payment method keep it.
+func (s *OrderService) applySavedPaymentMethod(ctx context.Context, req *pb.CreateOrderRequest, order *model.Order, now time.Time) error {
+	var method *model.SavedPaymentMethod
+	switch {
+	case req.PaymentMethodId != "":
+		saved, err := s.paymentMethods.GetPaymentMethod(ctx, order.UserID, req.PaymentMethodId)
+		if err != nil {
+			if errors.Is(err, repository.ErrPaymentMethodNotFound) {
+				return status.Errorf(codes.NotFound, "payment method not found")
+			}
+			return status.Errorf(codes.Internal, "failed to get payment method: %v", err)
+		}
+		if req.PaymentMethod != pb.PaymentMethod_PAYMENT_METHOD_UNSPECIFIED && convertPaymentMethod(req.PaymentMethod) != saved.Type {
+			return status.Errorf(codes.InvalidArgument, "payment method %s does not match the saved %s", req.PaymentMethod, saved.Type)
+		}
+		method = saved
+	case req.PaymentMethod == pb.PaymentMethod_PAYMENT_METHOD_UNSPECIFIED:
+		saved, err := s.paymentMethods.GetDefaultPaymentMethod(ctx, order.UserID)
+		if err != nil {
+			if errors.Is(err, repository.ErrPaymentMethodNotFound) {
+				return nil
+			}
+			return status.Errorf(codes.Internal, "failed to get default payment method: %v", err)
+		}
+		method = saved
+	default:
+		return nil
+	}
+
+	if method.Expired(now) {
+		return status.Errorf(codes.FailedPrecondition, "card ending %s has expired", method.Last4)
+	}
+	order.PaymentMethod = method.Type
+	order.PaymentMethodID = method.ID
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/paymentmethod"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// PaymentMethodRepository holds the cards and wallets users saved. It is implemented by
+// repository.PaymentMethodRepository on Postgres and by memory.PaymentMethodRepository
+// for tests.
+type PaymentMethodRepository interface {
+	// SavePaymentMethod stores a method, refreshing the one saved with the same gateway
+	// fingerprint. The user's first method becomes their default.
+	SavePaymentMethod(ctx context.Context, method *model.SavedPaymentMethod) error
+	GetPaymentMethod(ctx context.Context, userID, methodID string) (*model.SavedPaymentMethod, error)
+	GetDefaultPaymentMethod(ctx context.Context, userID string) (*model.SavedPaymentMethod, error)
+	ListPaymentMethods(ctx context.Context, userID string) ([]*model.SavedPaymentMethod, error)
+	SetDefaultPaymentMethod(ctx context.Context, userID, methodID string) error
+	// DeletePaymentMethod removes a method, promoting the newest of the others when it
+	// was the default
+	DeletePaymentMethod(ctx context.Context, userID, methodID string) error
+}
+
+var (
+	last4Pattern = regexp.MustCompile(`^[0-9]{4}$`)
+	// A raw card number is 13 to 19 digits, possibly grouped. Gateways never hand those
+	// out as tokens, so one means the client skipped tokenization.
+	cardNumberPattern = regexp.MustCompile(`^[0-9][0-9 -]{11,22}[0-9]$`)
+)
+
+// PaymentMethodService lets users save tokenized cards and wallets and pick the default
+// used for orders that name no payment method
+type PaymentMethodService struct {
+	pb.UnimplementedPaymentMethodServiceServer
+	repo PaymentMethodRepository
+}
+
+// NewPaymentMethodService creates a new payment method service
+func NewPaymentMethodService(repo PaymentMethodRepository) *PaymentMethodService {
+	return &PaymentMethodService{
+		repo: repo,
+	}
+}
+
+// SavePaymentMethod saves a method tokenized by the payment gateway
+func (s *PaymentMethodService) SavePaymentMethod(ctx context.Context, req *pb.SavePaymentMethodRequest) (*pb.PaymentMethodResponse, error) {
+	if req.UserId == "" || req.Gateway == "" || req.Token == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID, gateway and token are required")
+	}
+	if isCardNumber(req.Token) {
+		return nil, status.Errorf(codes.InvalidArgument, "token must come from the payment gateway, not be a card number")
+	}
+
+	now := time.Now()
+	method := &model.SavedPaymentMethod{
+		ID:           uuid.New().String(),
+		UserID:       req.UserId,
+		Type:         model.PaymentMethod(strings.ToUpper(req.Type)),
+		Gateway:      strings.ToUpper(req.Gateway),
+		GatewayToken: req.Token,
+		Fingerprint:  req.Fingerprint,
+		Brand:        strings.ToUpper(req.Brand),
+		Last4:        req.Last4,
+		ExpMonth:     int(req.ExpMonth),
+		ExpYear:      int(req.ExpYear),
+		IsDefault:    req.MakeDefault,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	switch method.Type {
+	case model.PaymentCreditCard, model.PaymentDebitCard:
+		if !last4Pattern.MatchString(method.Last4) {
+			return nil, status.Errorf(codes.InvalidArgument, "cards need the last four digits")
+		}
+		if method.ExpMonth < 1 || method.ExpMonth > 12 || method.ExpYear == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "cards need an expiry month and year")
+		}
+		if method.Expired(now) {
+			return nil, status.Errorf(codes.InvalidArgument, "card has expired")
+		}
+	case model.PaymentDigitalWallet:
+		if method.Last4 != "" || method.ExpMonth != 0 || method.ExpYear != 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "wallets have no card digits or expiry")
+		}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "type must be CREDIT_CARD, DEBIT_CARD or DIGITAL_WALLET")
+	}
+
+	if err := s.repo.SavePaymentMethod(ctx, method); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to save payment method: %v", err)
+	}
+
+	return &pb.PaymentMethodResponse{
+		PaymentMethod: convertSavedPaymentMethodToProto(method),
+		Message:       "Payment method saved",
+		Success:       true,
+	}, nil
+}
+
+// ListPaymentMethods lists a user's payment methods, the default first
+func (s *PaymentMethodService) ListPaymentMethods(ctx context.Context, req *pb.ListPaymentMethodsRequest) (*pb.ListPaymentMethodsResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
+	}
+
+	methods, err := s.repo.ListPaymentMethods(ctx, req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list payment methods: %v", err)
+	}
+
+	protoMethods := make([]*pb.PaymentMethod, 0, len(methods))
+	for _, method := range methods {
+		protoMethods = append(protoMethods, convertSavedPaymentMethodToProto(method))
+	}
+
+	return &pb.ListPaymentMethodsResponse{
+		PaymentMethods: protoMethods,
+	}, nil
+}
+
+// SetDefaultPaymentMethod makes one of a user's methods the one used for orders that
+// name no payment method
+func (s *PaymentMethodService) SetDefaultPaymentMethod(ctx context.Context, req *pb.SetDefaultPaymentMethodRequest) (*pb.PaymentMethodResponse, error) {
+	if req.UserId == "" || req.PaymentMethodId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID and payment method ID are required")
+	}
+
+	method, err := s.repo.GetPaymentMethod(ctx, req.UserId, req.PaymentMethodId)
+	if err != nil {
+		return nil, paymentMethodError(err, "failed to get payment method")
+	}
+	if method.Expired(time.Now()) {
+		return nil, status.Errorf(codes.FailedPrecondition, "card has expired")
+	}
+
+	if err := s.repo.SetDefaultPaymentMethod(ctx, req.UserId, req.PaymentMethodId); err != nil {
+		return nil, paymentMethodError(err, "failed to set default payment method")
+	}
+	method.IsDefault = true
+
+	return &pb.PaymentMethodResponse{
+		PaymentMethod: convertSavedPaymentMethodToProto(method),
+		Message:       "Default payment method set",
+		Success:       true,
+	}, nil
+}
+
+// DeletePaymentMethod removes one of a user's methods. Orders already placed with it
+// keep their payment.
+func (s *PaymentMethodService) DeletePaymentMethod(ctx context.Context, req *pb.DeletePaymentMethodRequest) (*pb.DeletePaymentMethodResponse, error) {
+	if req.UserId == "" || req.PaymentMethodId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID and payment method ID are required")
+	}
+
+	if err := s.repo.DeletePaymentMethod(ctx, req.UserId, req.PaymentMethodId); err != nil {
+		return nil, paymentMethodError(err, "failed to delete payment method")
+	}
+
+	return &pb.DeletePaymentMethodResponse{
+		Message: "Payment method deleted",
+		Success: true,
+	}, nil
+}
+
+// isCardNumber reports whether a token looks like a raw card number
+func isCardNumber(token string) bool {
+	if !cardNumberPattern.MatchString(token) {
+		return false
+	}
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(token)
+	return len(digits) >= 13 && len(digits) <= 19
+}
+
+func paymentMethodError(err error, message string) error {
+	if errors.Is(err, repository.ErrPaymentMethodNotFound) {
+		return status.Errorf(codes.NotFound, "payment method not found")
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
+
+func convertSavedPaymentMethodToProto(method *model.SavedPaymentMethod) *pb.PaymentMethod {
+	return &pb.PaymentMethod{
+		Id:        method.ID,
+		UserId:    method.UserID,
+		Type:      string(method.Type),
+		Gateway:   method.Gateway,
+		Brand:     method.Brand,
+		Last4:     method.Last4,
+		ExpMonth:  int32(method.ExpMonth),
+		ExpYear:   int32(method.ExpYear),
+		IsDefault: method.IsDefault,
+		CreatedAt: timestamppb.New(method.CreatedAt),
+		UpdatedAt: timestamppb.New(method.UpdatedAt),
+	}
+}
@@ -0,0 +1,153 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	paymentMethodPb "github.com/order-api-microservices/proto/paymentmethod"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository/memory"
+	"github.com/order-api-microservices/services/order/internal/service"
+	"google.golang.org/grpc/codes"
+)
+
+// saveTestCard saves a card for the test user that expires in a year
+func saveTestCard(t *testing.T, s *service.PaymentMethodService, fingerprint string, makeDefault bool) *paymentMethodPb.PaymentMethod {
+	t.Helper()
+
+	expiry := time.Now().AddDate(1, 0, 0)
+	resp, err := s.SavePaymentMethod(context.Background(), &paymentMethodPb.SavePaymentMethodRequest{
+		UserId:      testUserID,
+		Type:        "CREDIT_CARD",
+		Gateway:     "stripe",
+		Token:       "pm_" + fingerprint,
+		Fingerprint: fingerprint,
+		Brand:       "visa",
+		Last4:       "4242",
+		ExpMonth:    int32(expiry.Month()),
+		ExpYear:     int32(expiry.Year()),
+		MakeDefault: makeDefault,
+	})
+	if err != nil {
+		t.Fatalf("SavePaymentMethod: %v", err)
+	}
+	return resp.PaymentMethod
+}
+
+func TestSavePaymentMethodRejectsCardNumbersAndExpiredCards(t *testing.T) {
+	s := service.NewPaymentMethodService(memory.NewPaymentMethodRepository())
+
+	lastMonth := time.Now().AddDate(0, -1, 0)
+	tests := []struct {
+		name string
+		req  *paymentMethodPb.SavePaymentMethodRequest
+	}{
+		{"card number as token", &paymentMethodPb.SavePaymentMethodRequest{Type: "CREDIT_CARD", Token: "4242 4242 4242 4242", Last4: "4242", ExpMonth: 12, ExpYear: 2099}},
+		{"expired card", &paymentMethodPb.SavePaymentMethodRequest{Type: "DEBIT_CARD", Token: "pm_old", Last4: "4242", ExpMonth: int32(lastMonth.Month()), ExpYear: int32(lastMonth.Year())}},
+		{"card without expiry", &paymentMethodPb.SavePaymentMethodRequest{Type: "CREDIT_CARD", Token: "pm_new", Last4: "4242"}},
+		{"wallet with card digits", &paymentMethodPb.SavePaymentMethodRequest{Type: "DIGITAL_WALLET", Token: "wallet_1", Last4: "4242"}},
+		{"cash", &paymentMethodPb.SavePaymentMethodRequest{Type: "CASH", Token: "cash"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.UserId = testUserID
+			tt.req.Gateway = "stripe"
+			_, err := s.SavePaymentMethod(context.Background(), tt.req)
+			wantCode(t, err, codes.InvalidArgument)
+		})
+	}
+}
+
+func TestPaymentMethodsKeepOneDefault(t *testing.T) {
+	ctx := context.Background()
+	s := service.NewPaymentMethodService(memory.NewPaymentMethodRepository())
+
+	first := saveTestCard(t, s, "fp-1", false)
+	if !first.IsDefault {
+		t.Errorf("first method is_default = false, want true")
+	}
+	second := saveTestCard(t, s, "fp-2", true)
+	again := saveTestCard(t, s, "fp-1", false)
+	if again.Id != first.Id {
+		t.Errorf("saving the same card again gave ID %s, want %s", again.Id, first.Id)
+	}
+
+	list, err := s.ListPaymentMethods(ctx, &paymentMethodPb.ListPaymentMethodsRequest{UserId: testUserID})
+	if err != nil {
+		t.Fatalf("ListPaymentMethods: %v", err)
+	}
+	if len(list.PaymentMethods) != 2 || list.PaymentMethods[0].Id != second.Id || list.PaymentMethods[1].IsDefault {
+		t.Fatalf("methods = %v, want 2 with %s the only default", list.PaymentMethods, second.Id)
+	}
+
+	_, err = s.DeletePaymentMethod(ctx, &paymentMethodPb.DeletePaymentMethodRequest{UserId: testUserID, PaymentMethodId: second.Id})
+	if err != nil {
+		t.Fatalf("DeletePaymentMethod: %v", err)
+	}
+	list, err = s.ListPaymentMethods(ctx, &paymentMethodPb.ListPaymentMethodsRequest{UserId: testUserID})
+	if err != nil {
+		t.Fatalf("ListPaymentMethods: %v", err)
+	}
+	if len(list.PaymentMethods) != 1 || !list.PaymentMethods[0].IsDefault {
+		t.Errorf("methods after deleting the default = %v, want %s promoted", list.PaymentMethods, first.Id)
+	}
+
+	_, err = s.SetDefaultPaymentMethod(ctx, &paymentMethodPb.SetDefaultPaymentMethodRequest{UserId: "someone-else", PaymentMethodId: first.Id})
+	wantCode(t, err, codes.NotFound)
+}
+
+func TestCreateOrderPaysWithSavedPaymentMethod(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	methods := service.NewPaymentMethodService(repos.methods)
+	card := saveTestCard(t, methods, "fp-1", false)
+
+	order := &pb.CreateOrderRequest{
+		UserId:              testUserID,
+		OrderType:           pb.OrderType_ORDER_TYPE_RIDE,
+		PickupLocation:      &pb.Location{Latitude: -6.2, Longitude: 106.8166, Address: "Office"},
+		DestinationLocation: &pb.Location{Latitude: -6.182, Longitude: 106.8166, Address: "Home"},
+	}
+
+	// With no payment method named, the default saved method pays
+	resp, err := s.CreateOrder(ctx, order)
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if resp.Order.PaymentMethodId != card.Id || resp.Order.PaymentMethod != pb.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD {
+		t.Errorf("order paid with %s (%s), want %s (CREDIT_CARD)", resp.Order.PaymentMethodId, resp.Order.PaymentMethod, card.Id)
+	}
+
+	// Naming cash keeps cash
+	order.PaymentMethod = pb.PaymentMethod_PAYMENT_METHOD_CASH
+	order.DestinationLocation = &pb.Location{Latitude: -6.175, Longitude: 106.8272, Address: "Monas"}
+	if resp, err = s.CreateOrder(ctx, order); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if resp.Order.PaymentMethodId != "" {
+		t.Errorf("cash order paid with saved method %s", resp.Order.PaymentMethodId)
+	}
+
+	// A saved method that does not match the payment method named is refused
+	order.PaymentMethodId = card.Id
+	_, err = s.CreateOrder(ctx, order)
+	wantCode(t, err, codes.InvalidArgument)
+
+	// So is another user's
+	order.PaymentMethod = pb.PaymentMethod_PAYMENT_METHOD_UNSPECIFIED
+	order.UserId = testProviderID
+	_, err = s.CreateOrder(ctx, order)
+	wantCode(t, err, codes.NotFound)
+
+	// And an expired card
+	expired := &model.SavedPaymentMethod{ID: "expired-card", UserID: testUserID, Type: model.PaymentDebitCard, Gateway: "STRIPE", Last4: "0005", ExpMonth: 1, ExpYear: 2020}
+	if err := repos.methods.SavePaymentMethod(ctx, expired); err != nil {
+		t.Fatalf("SavePaymentMethod: %v", err)
+	}
+	order.UserId = testUserID
+	order.PaymentMethodId = expired.ID
+	_, err = s.CreateOrder(ctx, order)
+	wantCode(t, err, codes.FailedPrecondition)
+}
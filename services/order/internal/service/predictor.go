@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// Predictor forecasts demand and trip times. Dispatch only depends on this interface,
+// so an external model can replace the heuristics without changing it.
+type Predictor interface {
+	// PredictDemand returns the orders expected to be picked up in a zone during the
+	// hour starting at at. The zone is a service area ID; empty means the whole platform.
+	PredictDemand(ctx context.Context, zone string, at time.Time) (float64, error)
+	// PredictETA returns the time to travel a route from its first point to its last
+	PredictETA(ctx context.Context, route []model.Location) (time.Duration, error)
+}
+
+// HeuristicPredictorConfig controls the built-in predictor
+type HeuristicPredictorConfig struct {
+	AverageSpeedKmh float64 // Speed assumed along a route
+	DemandWeeks     int     // Past weeks averaged to forecast an hour's demand
+}
+
+// HeuristicPredictor is the default predictor. It forecasts an hour's demand as the
+// average number of orders picked up in the same hour of the week over the past weeks,
+// and travel time from the straight-line length of a route at an average speed.
+type HeuristicPredictor struct {
+	repo  *repository.OrderRepository
+	areas *ServiceAreas
+	cfg   HeuristicPredictorConfig
+}
+
+// NewHeuristicPredictor creates a new heuristic predictor
+func NewHeuristicPredictor(repo *repository.OrderRepository, areas *ServiceAreas, cfg HeuristicPredictorConfig) *HeuristicPredictor {
+	return &HeuristicPredictor{
+		repo:  repo,
+		areas: areas,
+		cfg:   cfg,
+	}
+}
+
+// PredictDemand averages the pickups in the zone during the same hour in past weeks
+func (p *HeuristicPredictor) PredictDemand(ctx context.Context, zone string, at time.Time) (float64, error) {
+	var area *model.ServiceArea
+	if zone != "" {
+		if area = p.areas.Get(zone); area == nil {
+			return 0, fmt.Errorf("unknown zone %q", zone)
+		}
+	}
+	if p.cfg.DemandWeeks <= 0 {
+		return 0, nil
+	}
+
+	hour := at.Truncate(time.Hour)
+	pickups := 0
+	for week := 1; week <= p.cfg.DemandWeeks; week++ {
+		from := hour.AddDate(0, 0, -7*week)
+		locations, err := p.repo.ListPickupLocations(ctx, from, from.Add(time.Hour))
+		if err != nil {
+			return 0, err
+		}
+		for _, location := range locations {
+			if area == nil || area.Boundary.Contains(location.Latitude, location.Longitude) {
+				pickups++
+			}
+		}
+	}
+
+	return float64(pickups) / float64(p.cfg.DemandWeeks), nil
+}
+
+// PredictETA assumes the average speed along the straight lines between route points
+func (p *HeuristicPredictor) PredictETA(ctx context.Context, route []model.Location) (time.Duration, error) {
+	if p.cfg.AverageSpeedKmh <= 0 {
+		return 0, fmt.Errorf("average speed must be positive")
+	}
+
+	distanceKm := 0.0
+	for i := 1; i < len(route); i++ {
+		distanceKm += haversineKm(route[i-1].Latitude, route[i-1].Longitude, route[i].Latitude, route[i].Longitude)
+	}
+
+	return time.Duration(distanceKm / p.cfg.AverageSpeedKmh * float64(time.Hour)), nil
+}
+
+// FallbackPredictor asks a primary predictor, usually an external model, and falls back
+// to another when it fails
+type FallbackPredictor struct {
+	primary  Predictor
+	fallback Predictor
+}
+
+// NewFallbackPredictor creates a predictor that falls back when primary fails
+func NewFallbackPredictor(primary, fallback Predictor) *FallbackPredictor {
+	return &FallbackPredictor{
+		primary:  primary,
+		fallback: fallback,
+	}
+}
+
+// PredictDemand asks the primary predictor, then the fallback
+func (p *FallbackPredictor) PredictDemand(ctx context.Context, zone string, at time.Time) (float64, error) {
+	demand, err := p.primary.PredictDemand(ctx, zone, at)
+	if err != nil {
+		log.Printf("Demand prediction failed, falling back: %v", err)
+		return p.fallback.PredictDemand(ctx, zone, at)
+	}
+	return demand, nil
+}
+
+// PredictETA asks the primary predictor, then the fallback
+func (p *FallbackPredictor) PredictETA(ctx context.Context, route []model.Location) (time.Duration, error) {
+	eta, err := p.primary.PredictETA(ctx, route)
+	if err != nil {
+		log.Printf("ETA prediction failed, falling back: %v", err)
+		return p.fallback.PredictETA(ctx, route)
+	}
+	return eta, nil
+}
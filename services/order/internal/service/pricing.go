@@ -0,0 +1,115 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PricingPolicy checks the prices clients put on orders. Orders not priced by a catalog
+// or by the hour carry the client's item prices, so the order service recomputes what
+// the trip should cost and refuses totals too far from it. Fees are taken out of the
+// total, so a total that covers the trip covers them too.
+type PricingPolicy struct {
+	BaseFares        map[model.OrderType]int64 // Least an order of a type costs before distance, in minor units; types without one start from 0
+	PerKmRate        int64                     // Added to the base fare per kilometer from pickup to destination, in minor units
+	TolerancePercent int                       // How far from the expected total, or from a quoted total, an order may be, from 0 to 100
+}
+
+// Validate checks the pricing policy
+func (p PricingPolicy) Validate() error {
+	if p.TolerancePercent < 0 || p.TolerancePercent > 100 {
+		return fmt.Errorf("price tolerance must be between 0 and 100 percent, not %d", p.TolerancePercent)
+	}
+	if p.PerKmRate < 0 {
+		return fmt.Errorf("per-km rate must not be negative")
+	}
+	for orderType, fare := range p.BaseFares {
+		if fare < 0 {
+			return fmt.Errorf("base fare of %s must not be negative", orderType)
+		}
+	}
+	return nil
+}
+
+// ParseBaseFares parses per-type base fares written as TYPE=N pairs separated by commas,
+// e.g. "RIDE=300,SERVICE_BOOKING=500"
+func ParseBaseFares(s string) (map[model.OrderType]int64, error) {
+	fares := make(map[model.OrderType]int64)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		orderType, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid base fare %q: want TYPE=N", pair)
+		}
+		fare, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || fare < 0 {
+			return nil, fmt.Errorf("invalid base fare %q: N must be a non-negative integer", pair)
+		}
+		fares[model.OrderType(strings.ToUpper(strings.TrimSpace(orderType)))] = fare
+	}
+	return fares, nil
+}
+
+// expectedTotal is what the trip of an order should cost: the base fare of its type plus
+// the distance from pickup to destination, plus the charges the order service added
+// itself, such as a package's size surcharge.
+func (p PricingPolicy) expectedTotal(order *model.Order, added int64) int64 {
+	km := haversineKm(order.PickupLocation.Latitude, order.PickupLocation.Longitude,
+		order.DestinationLocation.Latitude, order.DestinationLocation.Longitude)
+	return p.BaseFares[order.OrderType] + int64(math.Round(km*float64(p.PerKmRate))) + added
+}
+
+// rerouteCharge is what moving a trip's destination while the provider is at lat, lon
+// adds to its total: the distance left to the new destination, less the distance left to
+// the old one, at the per-km rate. A shorter route adds nothing, since part of the fare
+// may already be charged.
+func (p PricingPolicy) rerouteCharge(lat, lon float64, from, to model.Location) int64 {
+	before := haversineKm(lat, lon, from.Latitude, from.Longitude)
+	after := haversineKm(lat, lon, to.Latitude, to.Longitude)
+	return max(int64(math.Round((after-before)*float64(p.PerKmRate))), 0)
+}
+
+// carriesGoods reports whether an order's items include goods bought for the user, whose
+// value the client prices on top of the trip
+func carriesGoods(orderType model.OrderType) bool {
+	return orderType == model.TypeFoodDelivery || orderType == model.TypeGroceryDelivery
+}
+
+// validate refuses a client-priced order whose total is further than the tolerance from
+// its expected total, and any order whose total is further than the tolerance from the
+// total the client quoted the user. Goods are worth what they cost, so an order carrying
+// them is only refused below its expected total. added is what the order service charged
+// on top of the client's prices; a quotedTotal of 0 means the client quoted none, and an
+// expected total of 0, as with no fares configured, leaves the client's prices unchecked.
+func (p PricingPolicy) validate(order *model.Order, clientPriced bool, added, quotedTotal int64) error {
+	if expected := p.expectedTotal(order, added); clientPriced && expected > 0 {
+		if order.TotalPrice < within(expected, -p.TolerancePercent) {
+			return status.Errorf(codes.InvalidArgument, "order total %d is below the %d expected for this trip", order.TotalPrice, expected)
+		}
+		if !carriesGoods(order.OrderType) && order.TotalPrice > within(expected, p.TolerancePercent) {
+			return status.Errorf(codes.InvalidArgument, "order total %d is above the %d expected for this trip", order.TotalPrice, expected)
+		}
+	}
+
+	if quotedTotal > 0 {
+		if order.TotalPrice < within(quotedTotal, -p.TolerancePercent) || order.TotalPrice > within(quotedTotal, p.TolerancePercent) {
+			return status.Errorf(codes.InvalidArgument, "order total is %d, not the quoted %d", order.TotalPrice, quotedTotal)
+		}
+	}
+
+	return nil
+}
+
+// within moves total by percent of itself, rounding toward total
+func within(total int64, percent int) int64 {
+	return total + total*int64(percent)/100
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pricingTestOrder is an order of a type and total whose destination is about km north of
+// its pickup
+func pricingTestOrder(orderType model.OrderType, total int64, km float64) *model.Order {
+	return &model.Order{
+		OrderType:           orderType,
+		PickupLocation:      model.Location{Latitude: -6.2, Longitude: 106.8166},
+		DestinationLocation: model.Location{Latitude: -6.2 + km/111.195, Longitude: 106.8166},
+		TotalPrice:          total,
+	}
+}
+
+func TestPricingPolicyValidate(t *testing.T) {
+	policy := PricingPolicy{
+		BaseFares:        map[model.OrderType]int64{model.TypeRide: 1000},
+		PerKmRate:        500,
+		TolerancePercent: 20,
+	}
+
+	tests := []struct {
+		name         string
+		order        *model.Order
+		clientPriced bool
+		added        int64
+		quoted       int64
+		wantOK       bool
+	}{
+		// A 2km ride is expected to cost 1000 + 2*500 = 2000
+		{name: "ride at the expected total", order: pricingTestOrder(model.TypeRide, 2000, 2), clientPriced: true, wantOK: true},
+		{name: "ride at the floor", order: pricingTestOrder(model.TypeRide, 1600, 2), clientPriced: true, wantOK: true},
+		{name: "ride below the floor", order: pricingTestOrder(model.TypeRide, 1590, 2), clientPriced: true},
+		{name: "ride at the ceiling", order: pricingTestOrder(model.TypeRide, 2400, 2), clientPriced: true, wantOK: true},
+		{name: "ride above the ceiling", order: pricingTestOrder(model.TypeRide, 2410, 2), clientPriced: true},
+
+		// Types without a base fare are still priced by distance: 4km is 2000
+		{name: "food below the distance", order: pricingTestOrder(model.TypeFoodDelivery, 1000, 4), clientPriced: true},
+		{name: "food worth more than the trip", order: pricingTestOrder(model.TypeFoodDelivery, 50000, 4), clientPriced: true, wantOK: true},
+		{name: "groceries below the distance", order: pricingTestOrder(model.TypeGroceryDelivery, 1000, 4), clientPriced: true},
+		{name: "groceries worth more than the trip", order: pricingTestOrder(model.TypeGroceryDelivery, 50000, 4), clientPriced: true, wantOK: true},
+
+		// A package's size surcharge is part of what it is expected to cost: 2000 + 1000
+		{name: "package covering its surcharge", order: pricingTestOrder(model.TypePackageDelivery, 3000, 4), clientPriced: true, added: 1000, wantOK: true},
+		{name: "package short of its surcharge", order: pricingTestOrder(model.TypePackageDelivery, 2000, 4), clientPriced: true, added: 1000},
+		{name: "package priced far above the trip", order: pricingTestOrder(model.TypePackageDelivery, 9000, 4), clientPriced: true, added: 1000},
+
+		// Catalog and rental prices are not the client's to check
+		{name: "catalog priced order", order: pricingTestOrder(model.TypeFoodDelivery, 100, 4), wantOK: true},
+
+		// Quotes bound the total both ways, within the tolerance of the quote
+		{name: "at the quote", order: pricingTestOrder(model.TypeFoodDelivery, 10000, 0), quoted: 10000, wantOK: true},
+		{name: "below the quote within tolerance", order: pricingTestOrder(model.TypeFoodDelivery, 8000, 0), quoted: 10000, wantOK: true},
+		{name: "below the quote beyond tolerance", order: pricingTestOrder(model.TypeFoodDelivery, 7990, 0), quoted: 10000},
+		{name: "above the quote within tolerance", order: pricingTestOrder(model.TypeFoodDelivery, 12000, 0), quoted: 10000, wantOK: true},
+		{name: "above the quote beyond tolerance", order: pricingTestOrder(model.TypeFoodDelivery, 12010, 0), quoted: 10000},
+		{name: "client-priced order off its quote", order: pricingTestOrder(model.TypeRide, 2000, 2), clientPriced: true, quoted: 3000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.validate(tt.order, tt.clientPriced, tt.added, tt.quoted)
+			if tt.wantOK {
+				if err != nil {
+					t.Fatalf("validate: %v, want the order accepted", err)
+				}
+				return
+			}
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("validate: got %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestPricingPolicyWithoutFaresLeavesPricesUnchecked(t *testing.T) {
+	if err := (PricingPolicy{}).validate(pricingTestOrder(model.TypeRide, 1, 10), true, 0, 0); err != nil {
+		t.Errorf("validate: %v, want the order accepted", err)
+	}
+}
+
+func TestPricingPolicyRerouteCharge(t *testing.T) {
+	policy := PricingPolicy{PerKmRate: 500}
+	order := pricingTestOrder(model.TypeRide, 10000, 2)
+
+	tests := []struct {
+		name string
+		km   float64 // North of the pickup, where the provider is
+		want int64
+	}{
+		{name: "further", km: 5, want: 1500},
+		{name: "same distance", km: 2, want: 0},
+		{name: "shorter adds nothing", km: 1, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := pricingTestOrder(model.TypeRide, 0, tt.km).DestinationLocation
+			got := policy.rerouteCharge(order.PickupLocation.Latitude, order.PickupLocation.Longitude, order.DestinationLocation, to)
+			if got != tt.want {
+				t.Errorf("rerouteCharge = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPricingPolicyValidateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy PricingPolicy
+		wantOK bool
+	}{
+		{name: "zero", policy: PricingPolicy{}, wantOK: true},
+		{name: "full tolerance", policy: PricingPolicy{PerKmRate: 100, TolerancePercent: 100}, wantOK: true},
+		{name: "tolerance above 100", policy: PricingPolicy{TolerancePercent: 101}},
+		{name: "negative tolerance", policy: PricingPolicy{TolerancePercent: -1}},
+		{name: "negative rate", policy: PricingPolicy{PerKmRate: -1}},
+		{name: "negative base fare", policy: PricingPolicy{BaseFares: map[model.OrderType]int64{model.TypeRide: -1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantOK && err != nil {
+				t.Errorf("Validate: %v, want the policy accepted", err)
+			}
+			if !tt.wantOK && err == nil {
+				t.Error("Validate accepted an invalid policy")
+			}
+		})
+	}
+}
+
+func TestParseBaseFares(t *testing.T) {
+	fares, err := ParseBaseFares(" ride=300, SERVICE_BOOKING = 500 ,")
+	if err != nil {
+		t.Fatalf("ParseBaseFares: %v", err)
+	}
+	if len(fares) != 2 || fares[model.TypeRide] != 300 || fares[model.TypeServiceBooking] != 500 {
+		t.Errorf("got %v, want RIDE=300 and SERVICE_BOOKING=500", fares)
+	}
+
+	for _, s := range []string{"RIDE", "RIDE=abc", "RIDE=-1"} {
+		if _, err := ParseBaseFares(s); err == nil {
+			t.Errorf("ParseBaseFares(%q) accepted an invalid fare", s)
+		}
+	}
+}
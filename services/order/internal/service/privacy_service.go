@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/privacy"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// exportPageSize is how many orders an export reads per page
+const exportPageSize = 100
+
+// PrivacyNotificationClient exports and anonymizes the notifications sent to a user or provider
+type PrivacyNotificationClient interface {
+	ExportNotifications(ctx context.Context, recipientID string) ([]*model.ExportedNotification, error)
+	AnonymizeNotifications(ctx context.Context, recipientID string) (int64, error)
+}
+
+// PrivacyProviderClient erases a provider's personal data from the provider service
+type PrivacyProviderClient interface {
+	ForgetProvider(ctx context.Context, providerID string) (int64, error)
+}
+
+// PrivacyService handles data subject requests. Exports collect everything held about a
+// user into one JSON archive. Erasure anonymizes personal data in orders, providers and
+// notifications but keeps amounts, payment references and blockchain hashes, so the
+// financial records and the on-chain order history stay verifiable.
+type PrivacyService struct {
+	pb.UnimplementedPrivacyServiceServer
+	privacyRepo        *repository.PrivacyRepository
+	orderRepo          *repository.OrderRepository
+	locationRepo       *repository.OrderLocationRepository
+	chatRepo           *repository.ChatRepository
+	userProviderRepo   *repository.UserProviderRepository
+	paymentMethodRepo  *repository.PaymentMethodRepository
+	walletRepo         *repository.WalletRepository
+	loyaltyRepo        *repository.LoyaltyRepository
+	notificationClient PrivacyNotificationClient
+	providerClient     PrivacyProviderClient
+}
+
+// NewPrivacyService creates a new privacy service
+func NewPrivacyService(
+	privacyRepo *repository.PrivacyRepository,
+	orderRepo *repository.OrderRepository,
+	locationRepo *repository.OrderLocationRepository,
+	chatRepo *repository.ChatRepository,
+	userProviderRepo *repository.UserProviderRepository,
+	paymentMethodRepo *repository.PaymentMethodRepository,
+	walletRepo *repository.WalletRepository,
+	loyaltyRepo *repository.LoyaltyRepository,
+	notificationClient PrivacyNotificationClient,
+	providerClient PrivacyProviderClient,
+) *PrivacyService {
+	return &PrivacyService{
+		privacyRepo:        privacyRepo,
+		orderRepo:          orderRepo,
+		locationRepo:       locationRepo,
+		chatRepo:           chatRepo,
+		userProviderRepo:   userProviderRepo,
+		paymentMethodRepo:  paymentMethodRepo,
+		walletRepo:         walletRepo,
+		loyaltyRepo:        loyaltyRepo,
+		notificationClient: notificationClient,
+		providerClient:     providerClient,
+	}
+}
+
+// ExportUserData builds a JSON archive of a user's orders, the locations recorded during
+// them, their chat messages, favorite and blocked providers, saved payment methods, wallet,
+// referrals, loyalty points and notifications
+func (s *PrivacyService) ExportUserData(ctx context.Context, req *pb.ExportUserDataRequest) (*pb.ExportUserDataResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
+	}
+
+	export := &model.UserDataExport{
+		UserID:      req.UserId,
+		GeneratedAt: time.Now(),
+		Locations:   []*model.OrderLocation{},
+		Tracks:      []*model.OrderTrack{},
+	}
+
+	for page := 1; ; page++ {
+		orders, total, err := s.orderRepo.ListUserOrders(ctx, req.UserId, page, exportPageSize, "")
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list orders: %v", err)
+		}
+		export.Orders = append(export.Orders, orders...)
+
+		for _, order := range orders {
+			locations, err := s.locationRepo.ListOrderLocations(ctx, order.ID)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to list locations of order %s: %v", order.ID, err)
+			}
+			export.Locations = append(export.Locations, locations...)
+
+			track, err := s.locationRepo.GetTrack(ctx, order.ID)
+			if err != nil {
+				if errors.Is(err, repository.ErrOrderTrackNotFound) {
+					continue
+				}
+				return nil, status.Errorf(codes.Internal, "failed to get track of order %s: %v", order.ID, err)
+			}
+			export.Tracks = append(export.Tracks, track)
+		}
+
+		if len(orders) < exportPageSize || len(export.Orders) >= total {
+			break
+		}
+	}
+
+	// Archived orders had their raw locations deleted when they were archived
+	archived, err := s.orderRepo.ListArchivedUserOrders(ctx, req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list archived orders: %v", err)
+	}
+	for _, order := range archived {
+		track, err := s.locationRepo.GetTrack(ctx, order.ID)
+		if err != nil {
+			if errors.Is(err, repository.ErrOrderTrackNotFound) {
+				continue
+			}
+			return nil, status.Errorf(codes.Internal, "failed to get track of order %s: %v", order.ID, err)
+		}
+		export.Tracks = append(export.Tracks, track)
+	}
+	export.Orders = append(export.Orders, archived...)
+	if export.Orders == nil {
+		export.Orders = []*model.Order{}
+	}
+
+	if export.ChatMessages, err = s.chatRepo.ListUserOrderMessages(ctx, req.UserId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list chat messages: %v", err)
+	}
+	if export.Providers, err = s.userProviderRepo.ListPreferences(ctx, req.UserId, ""); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list user providers: %v", err)
+	}
+	if export.PaymentMethods, err = s.paymentMethodRepo.ListPaymentMethods(ctx, req.UserId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list payment methods: %v", err)
+	}
+	if export.Wallet, err = s.walletRepo.GetWallet(ctx, req.UserId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get wallet: %v", err)
+	}
+	export.WalletTransactions = []*model.WalletTransaction{}
+	for page := 1; ; page++ {
+		txns, total, err := s.walletRepo.ListWalletTransactions(ctx, req.UserId, page, exportPageSize)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list wallet transactions: %v", err)
+		}
+		export.WalletTransactions = append(export.WalletTransactions, txns...)
+		if len(txns) < exportPageSize || len(export.WalletTransactions) >= total {
+			break
+		}
+	}
+	if export.ReferralCode, err = s.loyaltyRepo.GetReferralCode(ctx, req.UserId); err != nil && !errors.Is(err, repository.ErrReferralCodeNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get referral code: %v", err)
+	}
+	if export.Referral, err = s.loyaltyRepo.GetReferral(ctx, req.UserId); err != nil && !errors.Is(err, repository.ErrReferralNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get referral: %v", err)
+	}
+	if export.Points, err = s.loyaltyRepo.GetPointsAccount(ctx, req.UserId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get loyalty points: %v", err)
+	}
+	export.PointsTransactions = []*model.PointsTransaction{}
+	for page := 1; ; page++ {
+		txns, total, err := s.loyaltyRepo.ListPointsTransactions(ctx, req.UserId, page, exportPageSize)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list points transactions: %v", err)
+		}
+		export.PointsTransactions = append(export.PointsTransactions, txns...)
+		if len(txns) < exportPageSize || len(export.PointsTransactions) >= total {
+			break
+		}
+	}
+	if export.Notifications, err = s.notificationClient.ExportNotifications(ctx, req.UserId); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to export notifications: %v", err)
+	}
+
+	archive, err := json.Marshal(export)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode export: %v", err)
+	}
+
+	return &pb.ExportUserDataResponse{
+		Archive:     archive,
+		GeneratedAt: timestamppb.New(export.GeneratedAt),
+	}, nil
+}
+
+// ForgetUser anonymizes a user's personal data. It is refused while the user has orders
+// in progress. If the notification service cannot be reached the orders stay
+// anonymized and the request can simply be retried.
+func (s *PrivacyService) ForgetUser(ctx context.Context, req *pb.ForgetUserRequest) (*pb.ErasureResponse, error) {
+	if req.UserId == "" || req.RequestedBy == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID and requested by are required")
+	}
+
+	unfinished, err := s.privacyRepo.CountUnfinishedOrders(ctx, req.UserId, "", finishedStatuses)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check orders: %v", err)
+	}
+	if unfinished > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "user has %d orders in progress", unfinished)
+	}
+
+	result, err := s.privacyRepo.AnonymizeUser(ctx, req.UserId, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to anonymize user: %v", err)
+	}
+
+	if result.NotificationsAnonymized, err = s.notificationClient.AnonymizeNotifications(ctx, req.UserId); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "orders anonymized but notifications were not, retry the request: %v", err)
+	}
+
+	log.Printf("Forgot user %s on request of %s: %d orders anonymized, %d locations and %d chat messages deleted, %d notifications anonymized",
+		req.UserId, req.RequestedBy, result.OrdersAnonymized, result.LocationsDeleted, result.ChatMessagesDeleted, result.NotificationsAnonymized)
+
+	return convertErasureResultToProto(result, "User data erased"), nil
+}
+
+// ForgetProvider anonymizes a provider's personal data: their profile and location
+// history in the provider service, the positions they reported during orders and their
+// notifications. Orders keep the provider ID so earnings and payouts still reconcile.
+func (s *PrivacyService) ForgetProvider(ctx context.Context, req *pb.ForgetProviderRequest) (*pb.ErasureResponse, error) {
+	if req.ProviderId == "" || req.RequestedBy == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "provider ID and requested by are required")
+	}
+
+	unfinished, err := s.privacyRepo.CountUnfinishedOrders(ctx, "", req.ProviderId, finishedStatuses)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check orders: %v", err)
+	}
+	if unfinished > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "provider has %d orders in progress", unfinished)
+	}
+
+	var result model.ErasureResult
+	if result.LocationsDeleted, err = s.privacyRepo.DeleteProviderLocations(ctx, req.ProviderId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete provider locations: %v", err)
+	}
+	if err := s.privacyRepo.EraseProviderVehicles(ctx, req.ProviderId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to erase provider vehicles: %v", err)
+	}
+
+	deleted, err := s.providerClient.ForgetProvider(ctx, req.ProviderId)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to anonymize provider profile, retry the request: %v", err)
+	}
+	result.LocationsDeleted += deleted
+
+	if result.NotificationsAnonymized, err = s.notificationClient.AnonymizeNotifications(ctx, req.ProviderId); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "provider anonymized but notifications were not, retry the request: %v", err)
+	}
+
+	log.Printf("Forgot provider %s on request of %s: %d locations deleted, %d notifications anonymized",
+		req.ProviderId, req.RequestedBy, result.LocationsDeleted, result.NotificationsAnonymized)
+
+	return convertErasureResultToProto(result, "Provider data erased"), nil
+}
+
+// convertErasureResultToProto converts an erasure result to its protobuf representation
+func convertErasureResultToProto(result model.ErasureResult, message string) *pb.ErasureResponse {
+	return &pb.ErasureResponse{
+		OrdersAnonymized:        result.OrdersAnonymized,
+		LocationsDeleted:        result.LocationsDeleted,
+		ChatMessagesDeleted:     result.ChatMessagesDeleted,
+		NotificationsAnonymized: result.NotificationsAnonymized,
+		Success:                 true,
+		Message:                 message,
+	}
+}
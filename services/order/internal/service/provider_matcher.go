@@ -2,40 +2,65 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math"
-	"sort"
+	"sync"
 	"time"
 
 	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
 )
 
 // ProviderClient is an interface for interacting with the provider service
 type ProviderClient interface {
-	FindAvailableProviders(ctx context.Context, location model.Location, radius float64, serviceType string) ([]Provider, error)
+	FindAvailableProviders(ctx context.Context, location model.Location, radius float64, serviceType, serviceAreaID string) ([]Provider, error)
 	NotifyProvider(ctx context.Context, providerID string, orderID string, details interface{}) error
+	GetProviderDetails(ctx context.Context, providerID string) (*Provider, error)
 }
 
 // Provider represents a service provider in the system
 type Provider struct {
-	ID           string         `json:"id"`
-	Name         string         `json:"name"`
-	Rating       float64        `json:"rating"`
-	ServiceTypes []string       `json:"service_types"`
-	Location     model.Location `json:"location"`
-	IsAvailable  bool           `json:"is_available"`
-	Distance     float64        `json:"distance,omitempty"` // Distance from requested location
+	ID                  string              `json:"id"`
+	Name                string              `json:"name"`
+	Rating              float64             `json:"rating"`
+	ServiceTypes        []string            `json:"service_types"`
+	Location            model.Location      `json:"location"`
+	IsAvailable         bool                `json:"is_available"`
+	Suspended           bool                `json:"-"` // Set by GetProviderDetails while the provider is suspended
+	Distance            float64             `json:"distance,omitempty"` // Distance from requested location
+	Phone               string              `json:"-"`                  // Only handed to the call bridge, never sent on
+	Preferences         ProviderPreferences `json:"-"`
+	MaxConcurrentOrders int                 `json:"-"` // Cap on active orders of any type; 0 leaves only the per-type limits
+	Favorite            bool                `json:"-"` // Set by the matcher when the order's user has favorited the provider
+	PickupETAMinutes    float64             `json:"-"` // Set by the dispatcher when ranking
+	IdleMinutes         float64             `json:"-"` // Set by the dispatcher when ranking
+	RecentEarnings      int64               `json:"-"` // Set by the dispatcher when ranking
+	AcceptanceRate      float64             `json:"-"` // Set by the dispatcher when ranking
+	DeclinePenalized    bool                `json:"-"` // Set by the dispatcher when ranking
+	CompletionRate      float64             `json:"-"` // Share of orders taken that were completed, from the provider record
+	OnTimeRate          float64             `json:"-"` // Share of timed pickups reached on time, from the provider record
+	VehicleCapacity     model.VehicleCapacity `json:"-"` // What the provider's vehicle can carry; zero if never given
+	Vehicle             *model.OrderVehicle `json:"-"` // The registered vehicle the provider is on shift in, set by GetProviderDetails; nil if none
+	Score               float64             `json:"-"` // Set by the dispatcher when ranking
 }
 
 // ProviderMatcher handles the matching of orders to providers
 type ProviderMatcher struct {
 	providerClient ProviderClient
+	dispatcher     *Dispatcher
+	offers         *DispatchOffers
+	serviceAreas   *ServiceAreas
+	userProviders  *repository.UserProviderRepository
 }
 
 // NewProviderMatcher creates a new provider matcher
-func NewProviderMatcher(providerClient ProviderClient) *ProviderMatcher {
+func NewProviderMatcher(providerClient ProviderClient, dispatcher *Dispatcher, offers *DispatchOffers, serviceAreas *ServiceAreas, userProviders *repository.UserProviderRepository) *ProviderMatcher {
 	return &ProviderMatcher{
 		providerClient: providerClient,
+		dispatcher:     dispatcher,
+		offers:         offers,
+		serviceAreas:   serviceAreas,
+		userProviders:  userProviders,
 	}
 }
 
@@ -50,8 +75,14 @@ func (m *ProviderMatcher) FindBestProviders(ctx context.Context, order *model.Or
 	// Get location from order (pickup location most of the time)
 	location := order.PickupLocation
 	
+	// Only providers registered in the pickup's service area are considered
+	serviceAreaID := ""
+	if area, _ := m.serviceAreas.Locate(location); area != nil {
+		serviceAreaID = area.ID
+	}
+	
 	// Find available providers from the provider service
-	providers, err := m.providerClient.FindAvailableProviders(ctx, location, radius, serviceType)
+	providers, err := m.providerClient.FindAvailableProviders(ctx, location, radius, serviceType, serviceAreaID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find providers: %w", err)
 	}
@@ -59,14 +90,28 @@ func (m *ProviderMatcher) FindBestProviders(ctx context.Context, order *model.Or
 	// If we don't have enough providers, increase the search radius
 	if len(providers) < maxProviders {
 		radius = 10.0 // kilometers
-		providers, err = m.providerClient.FindAvailableProviders(ctx, location, radius, serviceType)
+		providers, err = m.providerClient.FindAvailableProviders(ctx, location, radius, serviceType, serviceAreaID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to find providers with increased radius: %w", err)
 		}
 	}
 	
-	// Sort providers by a weighted score of distance and rating
-	sortProvidersByScore(providers)
+	// Drop providers who do not want this order
+	providers = filterProvidersByPreferences(providers, order, serviceType)
+	
+	// Drop providers whose vehicle cannot carry the package
+	if order.OrderType == model.TypePackageDelivery {
+		providers = filterProvidersByCapacity(providers, order.Items.PackageSize())
+	}
+	
+	// Drop providers the user has blocked and mark their favorites for the dispatcher
+	providers, err = m.applyUserPreferences(ctx, order.UserID, providers)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Sort providers by a weighted score of distance, rating and fairness signals
+	m.dispatcher.Rank(ctx, location, providers)
 	
 	// Limit the number of providers
 	if len(providers) > maxProviders {
@@ -76,30 +121,117 @@ func (m *ProviderMatcher) FindBestProviders(ctx context.Context, order *model.Or
 	return providers, nil
 }
 
-// NotifyProviders sends notifications to providers about a new order
+// NotifyProviders offers a new order to providers. An offer goes over the provider's
+// dispatch channel, and is sent as a notification instead when their app does not
+// acknowledge it in time. Providers are offered the order at once rather than in turn.
+// Offers carry each stop's instructions except the gate code, which only the provider
+// who takes the order sees.
 func (m *ProviderMatcher) NotifyProviders(ctx context.Context, order *model.Order, providers []Provider) error {
+	// Create order details to send to providers
+	orderDetails := map[string]interface{}{
+		"order_id":             order.ID,
+		"order_type":           order.OrderType,
+		"pickup_location":      offeredLocation(order.PickupLocation),
+		"destination_location": offeredLocation(order.DestinationLocation),
+		"items_count":          len(order.Items),
+		"total_price":          order.TotalPrice,
+		"provider_fee":         order.ProviderFee,
+		"created_at":           order.CreatedAt,
+	}
+	if order.OrderType == model.TypePackageDelivery {
+		size := order.Items.PackageSize()
+		orderDetails["size_class"] = size.Class()
+		orderDetails["weight_grams"] = size.WeightGrams
+	}
+	
+	var wg sync.WaitGroup
 	for _, provider := range providers {
-		// Create order details to send to provider
-		orderDetails := map[string]interface{}{
-			"order_id":             order.ID,
-			"order_type":           order.OrderType,
-			"pickup_location":      order.PickupLocation,
-			"destination_location": order.DestinationLocation,
-			"items_count":          len(order.Items),
-			"total_price":          order.TotalPrice,
-			"provider_fee":         order.ProviderFee,
-			"created_at":           order.CreatedAt,
+		wg.Add(1)
+		go func(providerID string) {
+			defer wg.Done()
+			
+			delivered, err := m.offers.Offer(ctx, providerID, order.ID, orderDetails)
+			if err != nil {
+				fmt.Printf("Failed to offer order to provider %s: %v\n", providerID, err)
+			}
+			if delivered {
+				return
+			}
+			
+			// Send notification to provider
+			err = m.providerClient.NotifyProvider(ctx, providerID, order.ID, orderDetails)
+			if err != nil {
+				// Log error but continue with other providers
+				fmt.Printf("Failed to notify provider %s: %v\n", providerID, err)
+			}
+		}(provider.ID)
+	}
+	wg.Wait()
+	
+	return nil
+}
+
+// filterProvidersByCapacity keeps the providers whose vehicle can carry a package
+func filterProvidersByCapacity(providers []Provider, size model.PackageSize) []Provider {
+	kept := providers[:0]
+	for _, provider := range providers {
+		if provider.VehicleCapacity.Carries(size) {
+			kept = append(kept, provider)
 		}
-		
-		// Send notification to provider
-		err := m.providerClient.NotifyProvider(ctx, provider.ID, order.ID, orderDetails)
-		if err != nil {
-			// Log error but continue with other providers
-			fmt.Printf("Failed to notify provider %s: %v\n", provider.ID, err)
+	}
+	return kept
+}
+
+// offeredLocation returns a stop as offered to providers, without its gate code
+func offeredLocation(location model.Location) model.Location {
+	if location.Instructions != nil && location.Instructions.GateCode != "" {
+		instructions := *location.Instructions
+		instructions.GateCode = ""
+		location.Instructions = &instructions
+	}
+	return location
+}
+
+// Blocked reports whether a user has blocked a provider
+func (m *ProviderMatcher) Blocked(ctx context.Context, userID, providerID string) (bool, error) {
+	pref, err := m.userProviders.GetPreference(ctx, userID, providerID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserProviderPreferenceNotFound) {
+			return false, nil
 		}
+		return false, fmt.Errorf("failed to get user's provider preference: %w", err)
+	}
+	return pref.Preference == model.PreferenceBlocked, nil
+}
+
+// applyUserPreferences drops the providers a user has blocked and marks the ones they
+// have favorited. A user must never be matched with a provider they blocked, so matching
+// fails when the preferences cannot be loaded.
+func (m *ProviderMatcher) applyUserPreferences(ctx context.Context, userID string, providers []Provider) ([]Provider, error) {
+	prefs, err := m.userProviders.ListPreferences(ctx, userID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user's provider preferences: %w", err)
+	}
+	if len(prefs) == 0 {
+		return providers, nil
 	}
 	
-	return nil
+	byProvider := make(map[string]model.ProviderPreference, len(prefs))
+	for _, pref := range prefs {
+		byProvider[pref.ProviderID] = pref.Preference
+	}
+	
+	kept := providers[:0]
+	for _, provider := range providers {
+		switch byProvider[provider.ID] {
+		case model.PreferenceBlocked:
+			continue
+		case model.PreferenceFavorite:
+			provider.Favorite = true
+		}
+		kept = append(kept, provider)
+	}
+	return kept, nil
 }
 
 // AssignProvider assigns a provider to an order
@@ -121,33 +253,15 @@ func orderTypeToServiceType(orderType model.OrderType) string {
 		return "ride"
 	case model.TypeFoodDelivery:
 		return "food_delivery"
-	case model.TypePackageDelivery:
+	case model.TypePackageDelivery, model.TypeReturn:
 		return "package_delivery"
 	case model.TypeGroceryDelivery:
 		return "grocery_delivery"
 	case model.TypeServiceBooking:
 		return "service_booking"
+	case model.TypeRental:
+		return "rental"
 	default:
 		return "general"
 	}
 }
-
-// sortProvidersByScore sorts providers by a weighted score of distance and rating
-func sortProvidersByScore(providers []Provider) {
-	// Sort providers by a weighted score of distance and rating
-	sort.Slice(providers, func(i, j int) bool {
-		// Calculate scores (lower is better for distance, higher is better for rating)
-		// We normalize distance by assuming max 10km and rating is 0-5
-		distanceScoreI := 1.0 - math.Min(providers[i].Distance/10.0, 1.0)
-		distanceScoreJ := 1.0 - math.Min(providers[j].Distance/10.0, 1.0)
-		
-		ratingScoreI := providers[i].Rating / 5.0
-		ratingScoreJ := providers[j].Rating / 5.0
-		
-		// Weighted score (70% distance, 30% rating)
-		scoreI := 0.7*distanceScoreI + 0.3*ratingScoreI
-		scoreJ := 0.7*distanceScoreJ + 0.3*ratingScoreJ
-		
-		return scoreI > scoreJ
-	})
-} 
\ No newline at end of file
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"github.com/order-api-microservices/services/order/internal/model"
+)
+
+// ProviderPreferences filter the orders a provider is offered. They are set in the
+// provider service; zero values mean no preference.
+type ProviderPreferences struct {
+	AutoAcceptRadiusKm float64  // Orders picked up within this distance are accepted without asking
+	OrderTypes         []string // Service types the provider wants; empty means all of theirs
+	MinFare            int64    // Smallest provider fee worth taking, in minor units
+}
+
+// Wants reports whether an order of the given service type suits the preferences
+func (p ProviderPreferences) Wants(order *model.Order, serviceType string) bool {
+	if p.MinFare > 0 && order.ProviderFee < p.MinFare {
+		return false
+	}
+	if len(p.OrderTypes) == 0 {
+		return true
+	}
+	for _, orderType := range p.OrderTypes {
+		if orderType == serviceType {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoAccepts reports whether a provider accepts an order without being asked. The
+// distance is the provider's distance from the order's pickup.
+func (p ProviderPreferences) AutoAccepts(distanceKm float64) bool {
+	return p.AutoAcceptRadiusKm > 0 && distanceKm <= p.AutoAcceptRadiusKm
+}
+
+// filterProvidersByPreferences keeps the providers whose preferences want the order
+func filterProvidersByPreferences(providers []Provider, order *model.Order, serviceType string) []Provider {
+	wanted := providers[:0]
+	for _, provider := range providers {
+		if provider.Preferences.Wants(order, serviceType) {
+			wanted = append(wanted, provider)
+		}
+	}
+	return wanted
+}
+
+// selectProvider picks the provider to assign from matched providers, best first. The
+// best provider who auto-accepts the order wins, so it is accepted straight away;
+// otherwise the best provider is picked and must accept it.
+func selectProvider(providers []Provider) (Provider, bool) {
+	for _, provider := range providers {
+		if provider.Preferences.AutoAccepts(provider.Distance) {
+			return provider, true
+		}
+	}
+	return providers[0], false
+}
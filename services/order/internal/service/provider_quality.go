@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// ProviderQualityClient stores providers' quality on their provider records
+type ProviderQualityClient interface {
+	UpdateProviderQuality(ctx context.Context, quality *model.ProviderQuality) error
+}
+
+// ProviderQualityConfig controls how providers' quality is worked out and published
+type ProviderQualityConfig struct {
+	Window    time.Duration // How far back offers and orders count
+	Interval  time.Duration // How often quality is published
+	BatchSize int           // Most providers loaded per query
+}
+
+// ProviderQualityPublisher works out each provider's acceptance, completion and on-time
+// rates from the offers they answered and the order aggregates folded from order events,
+// and stores them on the provider's record, where GetProvider shows them and the matcher
+// picks them up. Providers with nothing in the window keep the rates last published.
+type ProviderQualityPublisher struct {
+	analyticsRepo *repository.AnalyticsRepository
+	client        ProviderQualityClient
+	cfg           ProviderQualityConfig
+}
+
+// NewProviderQualityPublisher creates a new provider quality publisher
+func NewProviderQualityPublisher(analyticsRepo *repository.AnalyticsRepository, client ProviderQualityClient, cfg ProviderQualityConfig) *ProviderQualityPublisher {
+	return &ProviderQualityPublisher{
+		analyticsRepo: analyticsRepo,
+		client:        client,
+		cfg:           cfg,
+	}
+}
+
+// Run publishes providers' quality every interval until ctx is cancelled
+func (p *ProviderQualityPublisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.Publish(ctx)
+		}
+	}
+}
+
+// Publish works out the quality of every provider active in the window and stores it on
+// their record. A provider whose record cannot be updated is skipped until the next run.
+func (p *ProviderQualityPublisher) Publish(ctx context.Context) {
+	since := time.Now().Add(-p.cfg.Window)
+	var after string
+	published, failed := 0, 0
+	for ctx.Err() == nil {
+		qualities, err := p.analyticsRepo.ListProviderQuality(ctx, since, after, p.cfg.BatchSize)
+		if err != nil {
+			log.Printf("Failed to load provider quality: %v", err)
+			break
+		}
+
+		for _, quality := range qualities {
+			if err := p.client.UpdateProviderQuality(ctx, quality); err != nil {
+				log.Printf("Failed to publish quality of provider %s: %v", quality.ProviderID, err)
+				failed++
+				continue
+			}
+			published++
+		}
+
+		if len(qualities) < p.cfg.BatchSize {
+			break
+		}
+		after = qualities[len(qualities)-1].ProviderID
+	}
+
+	if published > 0 || failed > 0 {
+		log.Printf("Published the quality of %d providers (%d failed)", published, failed)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/money"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RefundOrder refunds an order's payment through the payment service and moves the order to REFUNDED
+func (s *OrderService) RefundOrder(ctx context.Context, req *pb.RefundOrderRequest) (*pb.OrderResponse, error) {
+	// Get current order
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if err := checkOrderNotFrozen(order); err != nil {
+		return nil, err
+	}
+
+	order.Authorization, err = s.authorizations.get(ctx, order.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get payment authorization: %v", err)
+	}
+
+	if err := checkRefundEligibility(order); err != nil {
+		return nil, err
+	}
+
+	// A cancellation fee is kept out of the refund, and so is what cancelling gave back to
+	// the wallet
+	refundable := order.TotalPrice - order.CancellationFee - order.WalletReturnedOnCancel()
+	if refundable <= 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "the order's cancellation fee covers its full payment")
+	}
+
+	amount := req.Amount
+	if amount == 0 {
+		amount = refundable
+	}
+	if amount < 0 || amount > refundable {
+		return nil, status.Errorf(codes.InvalidArgument, "refund amount must be between 0 and %s", money.Format(refundable))
+	}
+
+	// What the wallet paid goes back to it first, and the user may have all of the refund
+	// credited there at once instead of waiting for their payment method
+	toWallet := min(amount, order.WalletRefundable())
+	if req.ToWallet {
+		toWallet = amount
+	}
+
+	// The order ID doubles as the idempotency key, so a retried request refunds at most once
+	var refundID string
+	if toPayment := amount - toWallet; toPayment > 0 {
+		refundID, err = s.paymentClient.RefundPayment(ctx, order.ID, order.UserID, toPayment, req.Reason, "refund-"+order.ID)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to refund payment: %v", err)
+		}
+	}
+	if toWallet > 0 {
+		description := fmt.Sprintf("Refund for order %s", order.ID)
+		if err := s.creditWallet(ctx, order, toWallet, "refund-"+order.ID, description); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to credit refund to wallet: %v", err)
+		}
+	}
+
+	refund := &model.Refund{
+		ID:              uuid.New().String(),
+		OrderID:         order.ID,
+		Amount:          amount,
+		Reason:          req.Reason,
+		RequestedBy:     req.RequestedBy,
+		PaymentRefundID: refundID,
+		WalletAmount:    toWallet,
+		CreatedAt:       time.Now(),
+	}
+	if err := s.refundRepo.CreateRefund(ctx, refund); err != nil {
+		return nil, status.Errorf(codes.Internal, "payment refunded as %q but failed to record the refund: %v", refundID, err)
+	}
+
+	// Get updated order
+	updatedOrder, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get updated order: %v", err)
+	}
+
+	// Record refund on blockchain
+	s.blockchainRecorder.Record(ctx, updatedOrder.ID)
+
+	// Notify both parties
+	go s.notifyOrderParties(context.Background(), updatedOrder, "ORDER_REFUNDED", "Order refunded",
+		fmt.Sprintf("%s has been refunded for order %s", money.Format(amount), updatedOrder.ID),
+		map[string]interface{}{
+			"order_id":  updatedOrder.ID,
+			"refund_id": refund.ID,
+			"amount":    amount,
+			"reason":    req.Reason,
+		})
+
+	return &pb.OrderResponse{
+		Order:   convertOrderToProto(updatedOrder),
+		Message: "Order refunded successfully",
+		Success: true,
+	}, nil
+}
+
+// checkRefundEligibility rejects refunds for orders that were never paid, are still
+// in flight, are disputed or have already been refunded
+func checkRefundEligibility(order *model.Order) error {
+	if order.PaymentMethod == model.PaymentCash {
+		return status.Errorf(codes.FailedPrecondition, "cash payments cannot be refunded through the payment service")
+	}
+	if order.Authorization != nil && order.Authorization.Status == model.AuthorizationAuthorized {
+		return status.Errorf(codes.FailedPrecondition, "order's payment is only held; cancelling the order releases it")
+	}
+
+	switch order.Status {
+	case model.StatusPaymentComplete,
+		model.StatusProviderAssigned,
+		model.StatusProviderAccepted,
+		model.StatusProviderRejected,
+		model.StatusDelivered,
+		model.StatusCompleted:
+		return nil
+	case model.StatusCancelled:
+		if order.WasPaid() {
+			return nil
+		}
+		return status.Errorf(codes.FailedPrecondition, "order was cancelled before payment")
+	case model.StatusCreated, model.StatusPaymentPending:
+		return status.Errorf(codes.FailedPrecondition, "order has not been paid")
+	case model.StatusInProgress, model.StatusPickedUp, model.StatusInTransit, model.StatusArrived:
+		return status.Errorf(codes.FailedPrecondition, "order is in progress; cancel it before refunding")
+	case model.StatusDisputed:
+		return status.Errorf(codes.FailedPrecondition, "order is disputed; refunds are issued by resolving the dispute")
+	case model.StatusRefunded:
+		return status.Errorf(codes.AlreadyExists, "order has already been refunded")
+	default:
+		return status.Errorf(codes.FailedPrecondition, "order cannot be refunded in its current state")
+	}
+}
+
+// notifyOrderParties sends the same notification to the order's user and, if assigned, its provider
+func (s *OrderService) notifyOrderParties(ctx context.Context, order *model.Order, notificationType, title, message string, payload map[string]interface{}) {
+	if err := s.notificationClient.SendNotification(ctx, order.UserID, "USER", notificationType, title, message, payload); err != nil {
+		fmt.Printf("Failed to notify user %s: %v\n", order.UserID, err)
+	}
+
+	if order.ProviderID != "" {
+		if err := s.notificationClient.SendNotification(ctx, order.ProviderID, "PROVIDER", notificationType, title, message, payload); err != nil {
+			fmt.Printf("Failed to notify provider %s: %v\n", order.ProviderID, err)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/money"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RentalPolicy prices orders that hire a provider by the hour
+type RentalPolicy struct {
+	HourlyRate         int64         // Minor units per booked hour
+	MinHours           int           // Fewest hours a rental can be booked for
+	MaxHours           int           // Most hours a rental can be booked for, extensions included
+	OvertimeMultiplier float64       // Overtime is billed at the hourly rate times this
+	OvertimeIncrement  time.Duration // Overtime is rounded up to a multiple of this
+	OvertimeGrace      time.Duration // Overtime up to this long is not billed
+}
+
+// book prices a new rental of hours for an order
+func (p RentalPolicy) book(orderID string, hours int32, now time.Time) (*model.Rental, error) {
+	if int(hours) < p.MinHours || int(hours) > p.MaxHours {
+		return nil, status.Errorf(codes.InvalidArgument, "rental hours must be between %d and %d", p.MinHours, p.MaxHours)
+	}
+
+	return &model.Rental{
+		OrderID:      orderID,
+		HourlyRate:   p.HourlyRate,
+		OvertimeRate: int64(math.Round(float64(p.HourlyRate) * p.OvertimeMultiplier)),
+		BookedHours:  int(hours),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}, nil
+}
+
+// overtime returns the billed overtime of a started rental ending at endedAt. Overtime
+// past the grace period is billed in full, rounded up to the increment.
+func (p RentalPolicy) overtime(rental *model.Rental, endedAt time.Time) (minutes int, charge int64) {
+	over := endedAt.Sub(*rental.BookedUntil())
+	if over <= p.OvertimeGrace {
+		return 0, 0
+	}
+
+	increment := p.OvertimeIncrement
+	if increment < time.Minute {
+		increment = time.Minute
+	}
+	blocks := (over + increment - 1) / increment
+	minutes = int(blocks * increment / time.Minute)
+
+	return minutes, rental.OvertimeRate * int64(minutes) / 60
+}
+
+// startRental starts the clock of a rental order when its provider starts the job
+func (s *OrderService) startRental(ctx context.Context, orderID string) {
+	if _, err := s.rentalRepo.StartRental(ctx, orderID, time.Now()); err != nil {
+		fmt.Printf("Failed to start rental of order %s: %v\n", orderID, err)
+	}
+}
+
+// billRentalOvertime ends a rental order's clock and charges the user for the time past
+// the booked hours. It runs before the order completes so the provider's fare includes
+// the overtime, and does nothing once the rental has ended.
+func (s *OrderService) billRentalOvertime(ctx context.Context, order *model.Order) error {
+	rental, err := s.rentalRepo.GetRental(ctx, order.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRentalNotFound) {
+			return nil
+		}
+		return status.Errorf(codes.Internal, "failed to get rental: %v", err)
+	}
+	if rental.EndedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	rental.EndedAt = &now
+	rental.UpdatedAt = now
+	if rental.StartedAt != nil {
+		rental.OvertimeMinutes, rental.OvertimeCharge = s.rentalPolicy.overtime(rental, now)
+	}
+
+	// Cash rentals settle overtime with the provider
+	if rental.OvertimeCharge > 0 && order.PaymentMethod != model.PaymentCash {
+		paymentID, err := s.paymentClient.CapturePayment(ctx, order.ID, order.UserID, rental.OvertimeCharge,
+			fmt.Sprintf("Overtime for rental order %s", order.ID), "rental-overtime-"+order.ID)
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "failed to capture rental overtime: %v", err)
+		}
+		rental.OvertimePaymentID = paymentID
+	}
+
+	order.TotalPrice += rental.OvertimeCharge
+	s.feeSchedule.Apply(order)
+	order.UpdatedAt = now
+	if err := s.rentalRepo.BillOvertime(ctx, rental, order); err != nil {
+		if errors.Is(err, repository.ErrRentalEnded) {
+			return nil
+		}
+		return status.Errorf(codes.Internal, "failed to bill rental overtime: %v", err)
+	}
+
+	if rental.OvertimeCharge > 0 {
+		go func() {
+			err := s.notificationClient.SendNotification(context.Background(), order.UserID, "USER", "RENTAL_OVERTIME",
+				"Rental overtime", fmt.Sprintf("Your rental ran %d minutes over and was charged %s", rental.OvertimeMinutes, money.Format(rental.OvertimeCharge)),
+				map[string]interface{}{
+					"order_id": order.ID,
+					"minutes":  rental.OvertimeMinutes,
+					"amount":   rental.OvertimeCharge,
+				})
+			if err != nil {
+				fmt.Printf("Failed to notify user of rental overtime: %v\n", err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// GetRental returns the booking of a rental order with its extension requests
+func (s *OrderService) GetRental(ctx context.Context, req *pb.GetRentalRequest) (*pb.RentalResponse, error) {
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	rental, err := s.getRental(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.rentalResponse(ctx, order, rental, "Rental retrieved successfully")
+}
+
+// RequestRentalExtension asks the provider of a running rental for more hours. The
+// hours are charged once the provider approves.
+func (s *OrderService) RequestRentalExtension(ctx context.Context, req *pb.RequestRentalExtensionRequest) (*pb.RentalResponse, error) {
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+	if order.UserID != req.UserId {
+		return nil, status.Errorf(codes.PermissionDenied, "only the order's user can extend the rental")
+	}
+
+	rental, err := s.getRental(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRentalRunning(order, rental); err != nil {
+		return nil, err
+	}
+	if rental.BookedHours+int(req.Hours) > s.rentalPolicy.MaxHours {
+		return nil, status.Errorf(codes.InvalidArgument, "a rental can be booked for at most %d hours", s.rentalPolicy.MaxHours)
+	}
+
+	extension := &model.RentalExtension{
+		ID:          uuid.New().String(),
+		OrderID:     order.ID,
+		Hours:       int(req.Hours),
+		Amount:      rental.HourlyRate * int64(req.Hours),
+		Status:      model.ExtensionPending,
+		RequestedAt: time.Now(),
+	}
+	if err := s.rentalRepo.CreateExtension(ctx, extension); err != nil {
+		if errors.Is(err, repository.ErrRentalExtensionPending) {
+			return nil, status.Errorf(codes.AlreadyExists, "rental already has an extension awaiting the provider")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to request extension: %v", err)
+	}
+
+	go func() {
+		err := s.notificationClient.SendNotification(context.Background(), order.ProviderID, "PROVIDER", "RENTAL_EXTENSION_REQUESTED",
+			"Rental extension requested", fmt.Sprintf("The user of order %s asked to extend the rental by %d hours", order.ID, extension.Hours),
+			map[string]interface{}{
+				"order_id":     order.ID,
+				"extension_id": extension.ID,
+				"hours":        extension.Hours,
+			})
+		if err != nil {
+			fmt.Printf("Failed to notify provider of rental extension: %v\n", err)
+		}
+	}()
+
+	return s.rentalResponse(ctx, order, rental, "Rental extension requested successfully")
+}
+
+// RespondRentalExtension lets the provider of a rental approve or decline an extension.
+// Approving charges the user for the extra hours and adds them to the booking.
+func (s *OrderService) RespondRentalExtension(ctx context.Context, req *pb.RespondRentalExtensionRequest) (*pb.RentalResponse, error) {
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+	if order.ProviderID != req.ProviderId {
+		return nil, status.Errorf(codes.PermissionDenied, "only the order's provider can answer an extension")
+	}
+
+	extension, err := s.rentalRepo.GetExtension(ctx, req.ExtensionId)
+	if err != nil {
+		if errors.Is(err, repository.ErrRentalExtensionNotFound) {
+			return nil, status.Errorf(codes.NotFound, "rental extension not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get rental extension: %v", err)
+	}
+	if extension.OrderID != order.ID {
+		return nil, status.Errorf(codes.NotFound, "rental extension not found")
+	}
+	if extension.Status != model.ExtensionPending {
+		return nil, status.Errorf(codes.FailedPrecondition, "rental extension has already been answered")
+	}
+
+	rental, err := s.getRental(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	extension.RespondedAt = &now
+	if !req.Approve {
+		extension.Status = model.ExtensionDeclined
+		err = s.rentalRepo.DeclineExtension(ctx, extension)
+	} else {
+		if err := checkRentalRunning(order, rental); err != nil {
+			return nil, err
+		}
+
+		// Cash rentals settle extensions with the provider
+		if order.PaymentMethod != model.PaymentCash {
+			extension.PaymentID, err = s.paymentClient.CapturePayment(ctx, order.ID, order.UserID, extension.Amount,
+				fmt.Sprintf("Rental extension for order %s", order.ID), "rental-extension-"+extension.ID)
+			if err != nil {
+				return nil, status.Errorf(codes.Unavailable, "failed to capture rental extension: %v", err)
+			}
+		}
+
+		extension.Status = model.ExtensionApproved
+		order.TotalPrice += extension.Amount
+		s.feeSchedule.Apply(order)
+		order.UpdatedAt = now
+		err = s.rentalRepo.ApproveExtension(ctx, extension, order)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrRentalExtensionNotPending) {
+			return nil, status.Errorf(codes.FailedPrecondition, "rental extension has already been answered")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to answer rental extension: %v", err)
+	}
+
+	go func() {
+		title, message := "Rental extension declined", fmt.Sprintf("Your provider declined to extend the rental of order %s", order.ID)
+		if extension.Status == model.ExtensionApproved {
+			title, message = "Rental extended", fmt.Sprintf("Your rental of order %s was extended by %d hours for %s", order.ID, extension.Hours, money.Format(extension.Amount))
+		}
+		err := s.notificationClient.SendNotification(context.Background(), order.UserID, "USER", "RENTAL_EXTENSION_"+string(extension.Status),
+			title, message,
+			map[string]interface{}{
+				"order_id":     order.ID,
+				"extension_id": extension.ID,
+			})
+		if err != nil {
+			fmt.Printf("Failed to notify user of rental extension: %v\n", err)
+		}
+	}()
+
+	if extension.Status == model.ExtensionApproved {
+		rental.BookedHours += extension.Hours
+	}
+	return s.rentalResponse(ctx, order, rental, "Rental extension answered successfully")
+}
+
+// getRental gets the booking of a rental order
+func (s *OrderService) getRental(ctx context.Context, orderID string) (*model.Rental, error) {
+	rental, err := s.rentalRepo.GetRental(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRentalNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order is not a rental")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get rental: %v", err)
+	}
+	return rental, nil
+}
+
+// checkRentalRunning checks that a rental's clock is running, so it can be extended
+func checkRentalRunning(order *model.Order, rental *model.Rental) error {
+	if rental.StartedAt == nil || rental.EndedAt != nil || order.Status.Finished() {
+		return status.Errorf(codes.FailedPrecondition, "only a running rental can be extended")
+	}
+	return nil
+}
+
+// rentalResponse builds a response with a rental order, its booking and its extension requests
+func (s *OrderService) rentalResponse(ctx context.Context, order *model.Order, rental *model.Rental, message string) (*pb.RentalResponse, error) {
+	extensions, err := s.rentalRepo.ListExtensions(ctx, rental.OrderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list rental extensions: %v", err)
+	}
+
+	protoRental := &pb.Rental{
+		OrderId:         rental.OrderID,
+		HourlyRate:      rental.HourlyRate,
+		OvertimeRate:    rental.OvertimeRate,
+		BookedHours:     int32(rental.BookedHours),
+		OvertimeMinutes: int32(rental.OvertimeMinutes),
+		OvertimeCharge:  rental.OvertimeCharge,
+	}
+	if rental.StartedAt != nil {
+		protoRental.StartedAt = timestamppb.New(*rental.StartedAt)
+		protoRental.BookedUntil = timestamppb.New(*rental.BookedUntil())
+	}
+	if rental.EndedAt != nil {
+		protoRental.EndedAt = timestamppb.New(*rental.EndedAt)
+	}
+	for _, extension := range extensions {
+		protoExtension := &pb.RentalExtension{
+			Id:          extension.ID,
+			Hours:       int32(extension.Hours),
+			Amount:      extension.Amount,
+			Status:      string(extension.Status),
+			RequestedAt: timestamppb.New(extension.RequestedAt),
+		}
+		if extension.RespondedAt != nil {
+			protoExtension.RespondedAt = timestamppb.New(*extension.RespondedAt)
+		}
+		protoRental.Extensions = append(protoRental.Extensions, protoExtension)
+	}
+
+	return &pb.RentalResponse{
+		Order:   convertOrderToProto(order),
+		Rental:  protoRental,
+		Message: message,
+		Success: true,
+	}, nil
+}
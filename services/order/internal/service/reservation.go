@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReservationClient holds merchants' stock for the items of catalog-backed orders. It is
+// implemented by repository.StockRepository on the catalog's own stock counts.
+type ReservationClient interface {
+	// Reserve holds an order's items until a time, or fails with
+	// repository.ErrOutOfStock and holds nothing
+	Reserve(ctx context.Context, orderID, merchantID string, items model.OrderItems, until time.Time) error
+	// Release gives back whatever is held for an order
+	Release(ctx context.Context, orderID string) error
+	// Confirm keeps what is held for an order, which can no longer give it back
+	Confirm(ctx context.Context, orderID string) error
+}
+
+// reserveStock holds the stock of a catalog-backed order before the order is stored. It
+// is the first step of placing the order; if a later step fails, releaseStock undoes it.
+func (s *OrderService) reserveStock(ctx context.Context, order *model.Order, merchantID string) error {
+	err := s.reservations.Reserve(ctx, order.ID, merchantID, order.Items, time.Now().Add(s.stockHold))
+	if err != nil {
+		if errors.Is(err, repository.ErrOutOfStock) {
+			return status.Errorf(codes.FailedPrecondition, "%v", err)
+		}
+		if errors.Is(err, repository.ErrCatalogItemNotFound) {
+			return status.Errorf(codes.InvalidArgument, "an item is no longer on the merchant's menu")
+		}
+		return status.Errorf(codes.Unavailable, "failed to reserve stock: %v", err)
+	}
+	return nil
+}
+
+// releaseStock gives back the stock held for an order. Failures are logged; the stock
+// sweeper releases the hold once it expires.
+func (s *OrderService) releaseStock(ctx context.Context, orderID string) {
+	if err := s.reservations.Release(ctx, orderID); err != nil {
+		fmt.Printf("Failed to release stock of order %s: %v\n", orderID, err)
+	}
+}
+
+// confirmStock keeps the stock held for an order once a provider has taken it. Failures
+// are logged; the stock sweeper confirms the hold of a taken order once it expires.
+func (s *OrderService) confirmStock(ctx context.Context, orderID string) {
+	if err := s.reservations.Confirm(ctx, orderID); err != nil {
+		fmt.Printf("Failed to confirm stock of order %s: %v\n", orderID, err)
+	}
+}
+
+// StockRepository holds catalog stock for orders and finds the holds that have expired.
+// It is implemented by repository.StockRepository on Postgres and by
+// memory.StockRepository for tests.
+type StockRepository interface {
+	ReservationClient
+	// ListExpiredReservations lists up to limit orders holding stock whose hold ended
+	// before a time, oldest first
+	ListExpiredReservations(ctx context.Context, before time.Time, limit int) ([]string, error)
+}
+
+// OrderCanceller cancels orders the way users and providers do, charging any fee,
+// releasing stock and recording the cancellation. It is implemented by OrderService.
+type OrderCanceller interface {
+	CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.OrderResponse, error)
+}
+
+// StockSweeperConfig configures the stock sweeper
+type StockSweeperConfig struct {
+	Interval  time.Duration // How often expired stock holds are settled
+	BatchSize int           // Most orders settled per run
+}
+
+// StockSweeper settles the stock holds that outlive their expiry. An order that was
+// cancelled, or never stored, gets its stock back. One still waiting for a provider is
+// cancelled, since it has not been taken in time to keep the stock from other users.
+// One a provider has taken keeps its stock.
+type StockSweeper struct {
+	stockRepo StockRepository
+	orderRepo OrderRepository
+	orders    OrderCanceller
+	cfg       StockSweeperConfig
+}
+
+// NewStockSweeper creates a new stock sweeper
+func NewStockSweeper(stockRepo StockRepository, orderRepo OrderRepository, orders OrderCanceller, cfg StockSweeperConfig) *StockSweeper {
+	return &StockSweeper{
+		stockRepo: stockRepo,
+		orderRepo: orderRepo,
+		orders:    orders,
+		cfg:       cfg,
+	}
+}
+
+// Run settles expired stock holds every interval until ctx is cancelled
+func (w *StockSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Sweep(ctx, time.Now()); err != nil {
+				log.Printf("Failed to list expired stock reservations: %v", err)
+			}
+		}
+	}
+}
+
+// Sweep settles one batch of the stock holds that expired before now. Orders that fail
+// to settle are logged and left for the next sweep.
+func (w *StockSweeper) Sweep(ctx context.Context, now time.Time) error {
+	orderIDs, err := w.stockRepo.ListExpiredReservations(ctx, now, w.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, orderID := range orderIDs {
+		if err := w.settle(ctx, orderID); err != nil {
+			log.Printf("Failed to settle stock of order %s: %v", orderID, err)
+		}
+	}
+	return nil
+}
+
+// settle releases or confirms the expired stock hold of one order
+func (w *StockSweeper) settle(ctx context.Context, orderID string) error {
+	order, err := w.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return w.stockRepo.Release(ctx, orderID)
+		}
+		return err
+	}
+
+	switch {
+	case order.Status == model.StatusCancelled || order.Status == model.StatusRefunded:
+		return w.stockRepo.Release(ctx, orderID)
+	case order.Status.Taken() || order.Status.Finished():
+		return w.stockRepo.Confirm(ctx, orderID)
+	default:
+		// Cancelling gives the stock back, and tells the user like any other cancellation
+		_, err := w.orders.CancelOrder(ctx, &pb.CancelOrderRequest{
+			OrderId:     orderID,
+			CancelledBy: "system",
+			Reason:      "Stock hold expired before a provider took the order",
+		})
+		if status.Code(err) == codes.FailedPrecondition {
+			// The order moved on meanwhile; settle it on the next run
+			return nil
+		}
+		return err
+	}
+}
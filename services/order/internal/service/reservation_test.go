@@ -0,0 +1,199 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/service"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	testMerchantID = "merchant-1"
+	testItemID     = "item-1"
+)
+
+// stockTestMerchant adds an open merchant selling one item of which stock are left
+func stockTestMerchant(t *testing.T, repos testRepos, stock int) {
+	t.Helper()
+
+	ctx := context.Background()
+	if err := repos.merchants.CreateMerchant(ctx, &model.Merchant{ID: testMerchantID, Name: "Warung", Active: true}); err != nil {
+		t.Fatalf("CreateMerchant: %v", err)
+	}
+	item := &model.CatalogItem{ID: testItemID, MerchantID: testMerchantID, Name: "Nasi goreng", Price: 25000, Available: true, Stock: &stock}
+	if err := repos.merchants.CreateCatalogItem(ctx, item); err != nil {
+		t.Fatalf("CreateCatalogItem: %v", err)
+	}
+}
+
+// stockLeft is how many of the test item the merchant has left to sell
+func stockLeft(t *testing.T, repos testRepos) int {
+	t.Helper()
+
+	items, err := repos.merchants.GetCatalogItems(context.Background(), testMerchantID, []string{testItemID})
+	if err != nil {
+		t.Fatalf("GetCatalogItems: %v", err)
+	}
+	return *items[testItemID].Stock
+}
+
+func orderFromMerchant(quantity int32) *pb.CreateOrderRequest {
+	return &pb.CreateOrderRequest{
+		UserId:              testUserID,
+		OrderType:           pb.OrderType_ORDER_TYPE_FOOD_DELIVERY,
+		MerchantId:          testMerchantID,
+		PickupLocation:      &pb.Location{Latitude: -6.2, Longitude: 106.8166, Address: "Warung"},
+		DestinationLocation: &pb.Location{Latitude: -6.182, Longitude: 106.8166, Address: "Home"},
+		Items:               []*pb.OrderItem{{ItemId: testItemID, Quantity: quantity}},
+		PaymentMethod:       pb.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD,
+	}
+}
+
+func TestCreateOrderHoldsStockUntilCancelled(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	stockTestMerchant(t, repos, 5)
+
+	resp, err := s.CreateOrder(ctx, orderFromMerchant(2))
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if left := stockLeft(t, repos); left != 3 {
+		t.Errorf("stock left after ordering 2 = %d, want 3", left)
+	}
+
+	_, err = s.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: resp.Order.Id, CancelledBy: testUserID, Reason: "changed my mind"})
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if left := stockLeft(t, repos); left != 5 {
+		t.Errorf("stock left after cancelling = %d, want 5", left)
+	}
+}
+
+func TestCreateOrderFailsWithoutStock(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	stockTestMerchant(t, repos, 1)
+
+	_, err := s.CreateOrder(ctx, orderFromMerchant(2))
+	wantCode(t, err, codes.FailedPrecondition)
+
+	if left := stockLeft(t, repos); left != 1 {
+		t.Errorf("stock left = %d, want 1", left)
+	}
+	_, total, err := repos.orders.ListUserOrders(ctx, testUserID, 1, 10, "")
+	if err != nil {
+		t.Fatalf("ListUserOrders: %v", err)
+	}
+	if total != 0 {
+		t.Errorf("stored %d orders, want none", total)
+	}
+}
+
+func TestCreateOrderReleasesStockWhenStoringFails(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	stockTestMerchant(t, repos, 5)
+
+	if _, err := s.CreateOrder(ctx, orderFromMerchant(2)); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	// The same order again is refused as a duplicate after its stock was reserved
+	_, err := s.CreateOrder(ctx, orderFromMerchant(2))
+	wantCode(t, err, codes.AlreadyExists)
+
+	if left := stockLeft(t, repos); left != 3 {
+		t.Errorf("stock left = %d, want 3 held for the first order only", left)
+	}
+}
+
+func TestStockSweeperSettlesExpiredHolds(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     model.OrderStatus // Moved to before the hold expires; empty leaves the order waiting
+		sweepAfter time.Duration
+		wantStatus model.OrderStatus
+		wantStock  int
+		wantHeld   bool
+	}{
+		{name: "untaken order is cancelled", sweepAfter: time.Hour, wantStatus: model.StatusCancelled, wantStock: 5},
+		{name: "taken order keeps its stock", status: model.StatusProviderAccepted, sweepAfter: time.Hour, wantStatus: model.StatusProviderAccepted, wantStock: 3},
+		{name: "cancelled order gets its stock back", status: model.StatusCancelled, sweepAfter: time.Hour, wantStatus: model.StatusCancelled, wantStock: 5},
+		{name: "hold not yet expired", sweepAfter: time.Minute, wantStatus: model.StatusCreated, wantStock: 3, wantHeld: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, repos := newTestOrderService(&capturePayments{})
+			stockTestMerchant(t, repos, 5)
+			sweeper := service.NewStockSweeper(repos.stock, repos.orders, s, service.StockSweeperConfig{BatchSize: 10})
+
+			resp, err := s.CreateOrder(ctx, orderFromMerchant(2))
+			if err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+			orderID := resp.Order.Id
+			if tt.status != "" {
+				if err := repos.orders.UpdateOrderStatus(ctx, orderID, tt.status, testProviderID, ""); err != nil {
+					t.Fatalf("UpdateOrderStatus: %v", err)
+				}
+			}
+
+			if err := sweeper.Sweep(ctx, time.Now().Add(tt.sweepAfter)); err != nil {
+				t.Fatalf("Sweep: %v", err)
+			}
+
+			order, err := repos.orders.GetOrderByID(ctx, orderID)
+			if err != nil {
+				t.Fatalf("GetOrderByID: %v", err)
+			}
+			if order.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", order.Status, tt.wantStatus)
+			}
+			if left := stockLeft(t, repos); left != tt.wantStock {
+				t.Errorf("stock left = %d, want %d", left, tt.wantStock)
+			}
+			held, err := repos.stock.ListExpiredReservations(ctx, time.Now().Add(24*time.Hour), 10)
+			if err != nil {
+				t.Fatalf("ListExpiredReservations: %v", err)
+			}
+			if (len(held) > 0) != tt.wantHeld {
+				t.Errorf("orders still holding stock = %v, want held %t", held, tt.wantHeld)
+			}
+		})
+	}
+}
+
+func TestStockSweeperCancelsThroughCancelOrder(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	stockTestMerchant(t, repos, 5)
+	sweeper := service.NewStockSweeper(repos.stock, repos.orders, s, service.StockSweeperConfig{BatchSize: 10})
+
+	resp, err := s.CreateOrder(ctx, orderFromMerchant(2))
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if err := sweeper.Sweep(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+
+	order, err := repos.orders.GetOrderByID(ctx, resp.Order.Id)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	last := order.StatusHistory[len(order.StatusHistory)-1]
+	if last.Status != model.StatusCancelled || last.UpdatedBy != "system" {
+		t.Errorf("last status change %s by %s, want CANCELLED by system", last.Status, last.UpdatedBy)
+	}
+	if order.CancellationFee != 0 {
+		t.Errorf("cancellation fee = %d, want none for an expired hold", order.CancellationFee)
+	}
+}
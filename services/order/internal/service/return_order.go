@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReturnPolicy decides how long a delivered order can be returned for and what sending
+// its goods back costs. A return is priced as a trip, from the RETURN base fare and the
+// per-km rate of the pricing policy, since the goods were paid for with the original.
+type ReturnPolicy struct {
+	Window          time.Duration // How long after its delivery an order can be returned
+	DiscountPercent int           // Taken off the trip fare of a return, from 0 to 100
+}
+
+// Validate checks the return policy
+func (p ReturnPolicy) Validate() error {
+	if p.Window < 0 {
+		return fmt.Errorf("return window must not be negative")
+	}
+	if p.DiscountPercent < 0 || p.DiscountPercent > 100 {
+		return fmt.Errorf("return discount must be between 0 and 100 percent, not %d", p.DiscountPercent)
+	}
+	return nil
+}
+
+// fare takes the return discount off the trip fare of a return
+func (p ReturnPolicy) fare(tripFare int64) int64 {
+	return within(tripFare, -p.DiscountPercent)
+}
+
+// prepareReturn links a return order to the delivered order whose goods it sends back.
+// The return is picked up where the original was delivered and dropped where it was
+// picked up, which is the merchant or sender; locations the client gave are ignored. A
+// user can only return their own orders, within the return window of the delivery.
+func (s *OrderService) prepareReturn(ctx context.Context, order *model.Order, originalID, merchantID string, now time.Time) error {
+	if originalID == "" {
+		return status.Errorf(codes.InvalidArgument, "return orders need the order they return")
+	}
+	if merchantID != "" {
+		return status.Errorf(codes.InvalidArgument, "return orders are not ordered from a merchant's catalog")
+	}
+
+	original, err := s.repo.GetOrderByID(ctx, originalID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return status.Errorf(codes.NotFound, "order not found: %s", originalID)
+		}
+		return status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+	if original.UserID != order.UserID {
+		return status.Errorf(codes.PermissionDenied, "only the user of an order can return it")
+	}
+	if !original.Returnable() {
+		return status.Errorf(codes.FailedPrecondition, "only delivered package and grocery orders can be returned")
+	}
+	if deliveredAt, _ := original.DeliveredAt(); now.Sub(deliveredAt) > s.returnPolicy.Window {
+		return status.Errorf(codes.FailedPrecondition, "orders can only be returned within %s of their delivery", s.returnPolicy.Window)
+	}
+
+	items, err := returnedItems(original.Items, order.Items)
+	if err != nil {
+		return err
+	}
+
+	order.ReturnOfOrderID = original.ID
+	order.PickupLocation = original.DestinationLocation
+	order.DestinationLocation = original.PickupLocation
+	order.Items = items
+	return nil
+}
+
+// returnedItems picks the items a return sends back out of those delivered. Naming none
+// returns them all. Each named item must be a delivered one, matched by item ID or else
+// by name, and no more of it can be returned than was delivered. Returned goods are not
+// bought again, so they carry no price.
+func returnedItems(delivered, named model.OrderItems) (model.OrderItems, error) {
+	if len(named) == 0 {
+		named = delivered
+	}
+
+	left := make([]int, len(delivered))
+	for i, item := range delivered {
+		left[i] = item.Quantity
+	}
+
+	items := make(model.OrderItems, 0, len(named))
+	for _, item := range named {
+		i := deliveredItem(delivered, item)
+		if i < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "%q is not an item of the returned order", item.Name)
+		}
+		if item.Quantity <= 0 || item.Quantity > left[i] {
+			return nil, status.Errorf(codes.InvalidArgument, "at most %d of %q can be returned", left[i], delivered[i].Name)
+		}
+		left[i] -= item.Quantity
+
+		returned := delivered[i]
+		returned.Quantity = item.Quantity
+		returned.Price = 0
+		items = append(items, returned)
+	}
+	return items, nil
+}
+
+// deliveredItem returns the index of the delivered item that item names, or -1
+func deliveredItem(delivered model.OrderItems, item model.OrderItem) int {
+	for i, candidate := range delivered {
+		if item.ItemID != "" && candidate.ItemID == item.ItemID {
+			return i
+		}
+	}
+	for i, candidate := range delivered {
+		if item.Name != "" && candidate.Name == item.Name {
+			return i
+		}
+	}
+	return -1
+}
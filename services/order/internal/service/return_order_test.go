@@ -0,0 +1,151 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/service"
+	"google.golang.org/grpc/codes"
+)
+
+// storeDeliveredOrder stores a card order of the test user that was delivered at
+// deliveredAt, with two pairs of shoes and a belt picked up at a shop
+func storeDeliveredOrder(t *testing.T, repos testRepos, id string, orderType model.OrderType, orderStatus model.OrderStatus, deliveredAt time.Time) *model.Order {
+	t.Helper()
+
+	order := &model.Order{
+		ID:                  id,
+		UserID:              testUserID,
+		ProviderID:          testProviderID,
+		OrderType:           orderType,
+		Status:              orderStatus,
+		PickupLocation:      model.Location{Latitude: -6.2, Longitude: 106.8166, Address: "Shop"},
+		DestinationLocation: model.Location{Latitude: -6.182, Longitude: 106.8166, Address: "Home"},
+		Items: model.OrderItems{
+			{ItemID: "shoes", Name: "Shoes", Quantity: 2, Price: 40000},
+			{ItemID: "belt", Name: "Belt", Quantity: 1, Price: 20000},
+		},
+		PaymentMethod: model.PaymentCreditCard,
+		TotalPrice:    100000,
+		CreatedAt:     deliveredAt.Add(-time.Hour),
+		UpdatedAt:     deliveredAt,
+		StatusHistory: model.StatusHistories{
+			{Status: model.StatusCreated, UpdatedBy: "system", Timestamp: deliveredAt.Add(-time.Hour)},
+		},
+	}
+	if orderStatus == model.StatusDelivered || orderStatus == model.StatusCompleted {
+		order.StatusHistory = append(order.StatusHistory, model.StatusHistory{Status: orderStatus, UpdatedBy: testProviderID, Timestamp: deliveredAt})
+	}
+	if err := repos.orders.CreateOrder(context.Background(), order, time.Time{}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	return order
+}
+
+func returnRequest(originalID string, items ...*pb.OrderItem) *pb.CreateOrderRequest {
+	return &pb.CreateOrderRequest{
+		UserId:          testUserID,
+		OrderType:       pb.OrderType_ORDER_TYPE_RETURN,
+		ReturnOfOrderId: originalID,
+		Items:           items,
+		PaymentMethod:   pb.PaymentMethod_PAYMENT_METHOD_CASH,
+	}
+}
+
+func TestCreateReturnOrderGoesBackToTheSender(t *testing.T) {
+	const originalID = "3c9e5a17-8b2d-4f60-a4e1-6d7b0c2f9e85"
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	original := storeDeliveredOrder(t, repos, originalID, model.TypePackageDelivery, model.StatusDelivered, time.Now().Add(-24*time.Hour))
+
+	resp, err := s.CreateOrder(ctx, returnRequest(originalID, &pb.OrderItem{Name: "Shoes", Quantity: 1, Price: 99999}))
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	order, err := repos.orders.GetOrderByID(ctx, resp.Order.Id)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if order.OrderType != model.TypeReturn || order.ReturnOfOrderID != originalID {
+		t.Errorf("order is a %s of %q, want a RETURN of %s", order.OrderType, order.ReturnOfOrderID, originalID)
+	}
+	if order.PickupLocation.Address != original.DestinationLocation.Address || order.DestinationLocation.Address != original.PickupLocation.Address {
+		t.Errorf("return goes from %s to %s, want from Home to Shop", order.PickupLocation.Address, order.DestinationLocation.Address)
+	}
+	if len(order.Items) != 1 || order.Items[0].ItemID != "shoes" || order.Items[0].Quantity != 1 || order.Items[0].Price != 0 {
+		t.Errorf("items = %+v, want one pair of shoes at no price", order.Items)
+	}
+	// The RETURN base fare of 20000, less the 25 percent return discount
+	if order.TotalPrice != 15000 {
+		t.Errorf("total = %d, want 15000", order.TotalPrice)
+	}
+	if resp.Order.ReturnOfOrderId != originalID {
+		t.Errorf("response return_of_order_id = %q, want %s", resp.Order.ReturnOfOrderId, originalID)
+	}
+
+	_, err = s.CreateOrder(ctx, returnRequest(originalID))
+	wantCode(t, err, codes.AlreadyExists)
+}
+
+func TestCreateReturnOrderRefusals(t *testing.T) {
+	const originalID = "7f2b8d40-1e6c-4a93-b5d7-0c8e3f9a2b16"
+	otherUserID := "1a2b3c4d-5e6f-4a1b-8c2d-3e4f5a6b7c8d"
+
+	tests := []struct {
+		name        string
+		orderType   model.OrderType
+		status      model.OrderStatus
+		deliveredAt time.Duration // Before now
+		req         *pb.CreateOrderRequest
+		wantCode    codes.Code
+	}{
+		{name: "no returned order", orderType: model.TypePackageDelivery, status: model.StatusDelivered, deliveredAt: time.Hour, req: returnRequest(""), wantCode: codes.InvalidArgument},
+		{name: "unknown order", orderType: model.TypePackageDelivery, status: model.StatusDelivered, deliveredAt: time.Hour, req: returnRequest("0d4e6f8a-2b3c-4d5e-8f9a-1b2c3d4e5f6a"), wantCode: codes.NotFound},
+		{name: "someone else's order", orderType: model.TypePackageDelivery, status: model.StatusDelivered, deliveredAt: time.Hour, req: &pb.CreateOrderRequest{UserId: otherUserID, OrderType: pb.OrderType_ORDER_TYPE_RETURN, ReturnOfOrderId: originalID}, wantCode: codes.PermissionDenied},
+		{name: "food order", orderType: model.TypeFoodDelivery, status: model.StatusDelivered, deliveredAt: time.Hour, req: returnRequest(originalID), wantCode: codes.FailedPrecondition},
+		{name: "not delivered yet", orderType: model.TypeGroceryDelivery, status: model.StatusInTransit, deliveredAt: time.Hour, req: returnRequest(originalID), wantCode: codes.FailedPrecondition},
+		{name: "past the return window", orderType: model.TypeGroceryDelivery, status: model.StatusCompleted, deliveredAt: 8 * 24 * time.Hour, req: returnRequest(originalID), wantCode: codes.FailedPrecondition},
+		{name: "item not delivered", orderType: model.TypePackageDelivery, status: model.StatusDelivered, deliveredAt: time.Hour, req: returnRequest(originalID, &pb.OrderItem{Name: "Hat", Quantity: 1}), wantCode: codes.InvalidArgument},
+		{
+			name: "more than delivered", orderType: model.TypePackageDelivery, status: model.StatusDelivered, deliveredAt: time.Hour,
+			req:      returnRequest(originalID, &pb.OrderItem{ItemId: "shoes", Quantity: 2}, &pb.OrderItem{Name: "Shoes", Quantity: 1}),
+			wantCode: codes.InvalidArgument,
+		},
+		{name: "from a catalog", orderType: model.TypePackageDelivery, status: model.StatusDelivered, deliveredAt: time.Hour, req: &pb.CreateOrderRequest{UserId: testUserID, OrderType: pb.OrderType_ORDER_TYPE_RETURN, ReturnOfOrderId: originalID, MerchantId: "merchant-1"}, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repos := newTestOrderService(&capturePayments{})
+			storeDeliveredOrder(t, repos, originalID, tt.orderType, tt.status, time.Now().Add(-tt.deliveredAt))
+
+			_, err := s.CreateOrder(context.Background(), tt.req)
+			wantCode(t, err, tt.wantCode)
+		})
+	}
+}
+
+func TestReturnPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  service.ReturnPolicy
+		wantErr bool
+	}{
+		{name: "defaults", policy: service.ReturnPolicy{Window: 14 * 24 * time.Hour}},
+		{name: "free returns", policy: service.ReturnPolicy{Window: time.Hour, DiscountPercent: 100}},
+		{name: "negative window", policy: service.ReturnPolicy{Window: -time.Hour}, wantErr: true},
+		{name: "discount over 100", policy: service.ReturnPolicy{Window: time.Hour, DiscountPercent: 101}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
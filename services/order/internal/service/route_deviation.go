@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// routeDeviationHistoryLimit caps how many recent locations are read per order
+const routeDeviationHistoryLimit = 200
+
+// RouteDeviationConfig controls when a provider is considered off route
+type RouteDeviationConfig struct {
+	MaxDistanceKm float64       // How far from the route a provider can stray
+	MinDuration   time.Duration // How long a provider must stay too far before the order is flagged
+	Interval      time.Duration // How often orders in transit are analyzed
+	AdminChannel  string        // Notification channel that safety staff watch
+}
+
+// RouteDeviationAnalyzer flags orders in transit whose provider has left the expected route
+// and alerts the user and safety staff. The expected route is the straight line from
+// pickup to destination.
+type RouteDeviationAnalyzer struct {
+	repo               *repository.RouteDeviationRepository
+	orderRepo          *repository.OrderRepository
+	locationRepo       *repository.OrderLocationRepository
+	notificationClient NotificationClient
+	cfg                RouteDeviationConfig
+}
+
+// NewRouteDeviationAnalyzer creates a new route deviation analyzer
+func NewRouteDeviationAnalyzer(
+	repo *repository.RouteDeviationRepository,
+	orderRepo *repository.OrderRepository,
+	locationRepo *repository.OrderLocationRepository,
+	notificationClient NotificationClient,
+	cfg RouteDeviationConfig,
+) *RouteDeviationAnalyzer {
+	return &RouteDeviationAnalyzer{
+		repo:               repo,
+		orderRepo:          orderRepo,
+		locationRepo:       locationRepo,
+		notificationClient: notificationClient,
+		cfg:                cfg,
+	}
+}
+
+// Run analyzes every order in transit each interval until ctx is cancelled
+func (a *RouteDeviationAnalyzer) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			orderIDs, err := a.orderRepo.ListOrdersByStatus(ctx, model.StatusPickedUp, model.StatusInTransit)
+			if err != nil {
+				log.Printf("Failed to list orders in transit: %v", err)
+				continue
+			}
+			for _, orderID := range orderIDs {
+				if err := a.Analyze(ctx, orderID); err != nil {
+					log.Printf("Failed to analyze route of order %s: %v", orderID, err)
+				}
+			}
+		}
+	}
+}
+
+// Analyze compares an order's recent locations with its route. The order is flagged once
+// its provider has been off route for the configured duration, and the flag is cleared
+// when the provider is back on route.
+func (a *RouteDeviationAnalyzer) Analyze(ctx context.Context, orderID string) error {
+	order, err := a.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != model.StatusPickedUp && order.Status != model.StatusInTransit {
+		return nil
+	}
+
+	history, err := a.locationRepo.GetOrderLocationHistory(ctx, orderID, routeDeviationHistoryLimit)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		return nil
+	}
+
+	active, err := a.repo.GetActiveDeviation(ctx, orderID)
+	if err != nil && !errors.Is(err, repository.ErrRouteDeviationNotFound) {
+		return err
+	}
+
+	// History is newest first
+	latest := history[0]
+	distance := a.distanceFromRoute(order, latest)
+	if distance <= a.cfg.MaxDistanceKm {
+		if active != nil {
+			return a.repo.ClearDeviation(ctx, orderID, time.Now())
+		}
+		return nil
+	}
+	if active != nil {
+		return nil
+	}
+
+	// Find when the provider left the route
+	startedAt := latest.Timestamp
+	for _, location := range history[1:] {
+		if a.distanceFromRoute(order, location) <= a.cfg.MaxDistanceKm {
+			break
+		}
+		startedAt = location.Timestamp
+	}
+	if latest.Timestamp.Sub(startedAt) < a.cfg.MinDuration {
+		return nil
+	}
+
+	deviation := &model.RouteDeviation{
+		ID:         uuid.New().String(),
+		OrderID:    order.ID,
+		ProviderID: latest.ProviderID,
+		DistanceKm: distance,
+		Latitude:   latest.Latitude,
+		Longitude:  latest.Longitude,
+		StartedAt:  startedAt,
+		DetectedAt: time.Now(),
+	}
+	created, err := a.repo.CreateDeviation(ctx, deviation)
+	if err != nil {
+		return err
+	}
+	if created {
+		a.sendAlerts(ctx, order, deviation)
+	}
+
+	return nil
+}
+
+func (a *RouteDeviationAnalyzer) distanceFromRoute(order *model.Order, location *model.OrderLocation) float64 {
+	return distanceToSegmentKm(location.Latitude, location.Longitude, order.PickupLocation, order.DestinationLocation)
+}
+
+// sendAlerts lets the user and safety staff know that an order's provider is off route
+func (a *RouteDeviationAnalyzer) sendAlerts(ctx context.Context, order *model.Order, deviation *model.RouteDeviation) {
+	payload := map[string]interface{}{
+		"order_id":    order.ID,
+		"provider_id": deviation.ProviderID,
+		"distance_km": deviation.DistanceKm,
+		"latitude":    deviation.Latitude,
+		"longitude":   deviation.Longitude,
+		"started_at":  deviation.StartedAt,
+	}
+
+	err := a.notificationClient.SendNotification(ctx, order.UserID, "USER", "ROUTE_DEVIATION", "Your provider has left the expected route",
+		fmt.Sprintf("Your provider is %.1f km from the expected route of order %s. If you feel unsafe, raise an SOS from the order.", deviation.DistanceKm, order.ID),
+		payload)
+	if err != nil {
+		log.Printf("Failed to notify user of route deviation on order %s: %v", order.ID, err)
+	}
+
+	err = a.notificationClient.SendNotification(ctx, a.cfg.AdminChannel, "ADMIN", "ROUTE_DEVIATION", "Route deviation",
+		fmt.Sprintf("The provider of order %s has been %.1f km off route since %s", order.ID, deviation.DistanceKm, deviation.StartedAt.Format(time.RFC3339)),
+		payload)
+	if err != nil {
+		log.Printf("Failed to alert admin channel of route deviation on order %s: %v", order.ID, err)
+	}
+}
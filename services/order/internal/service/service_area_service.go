@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/servicearea"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ServiceAreaService lets admins define the zones of each city where orders are taken
+type ServiceAreaService struct {
+	pb.UnimplementedServiceAreaServiceServer
+	repo  *repository.ServiceAreaRepository
+	areas *ServiceAreas
+}
+
+// NewServiceAreaService creates a new service area service
+func NewServiceAreaService(repo *repository.ServiceAreaRepository, areas *ServiceAreas) *ServiceAreaService {
+	return &ServiceAreaService{
+		repo:  repo,
+		areas: areas,
+	}
+}
+
+// ListServiceAreas lists the service areas of a city, or of every city
+func (s *ServiceAreaService) ListServiceAreas(ctx context.Context, req *pb.ListServiceAreasRequest) (*pb.ListServiceAreasResponse, error) {
+	areas, err := s.repo.ListAreas(ctx, normalizeCity(req.City))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list service areas: %v", err)
+	}
+
+	protoAreas := []*pb.ServiceArea{}
+	for _, area := range areas {
+		protoAreas = append(protoAreas, convertServiceAreaToProto(area))
+	}
+
+	return &pb.ListServiceAreasResponse{
+		Areas: protoAreas,
+	}, nil
+}
+
+// GetServiceArea gets a service area by its ID
+func (s *ServiceAreaService) GetServiceArea(ctx context.Context, req *pb.GetServiceAreaRequest) (*pb.ServiceAreaResponse, error) {
+	if req.AreaId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "area ID is required")
+	}
+
+	area, err := s.repo.GetArea(ctx, req.AreaId)
+	if err != nil {
+		if errors.Is(err, repository.ErrServiceAreaNotFound) {
+			return nil, status.Errorf(codes.NotFound, "service area not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get service area: %v", err)
+	}
+
+	return &pb.ServiceAreaResponse{
+		Area:    convertServiceAreaToProto(area),
+		Message: "Service area retrieved successfully",
+		Success: true,
+	}, nil
+}
+
+// CreateServiceArea adds a zone to a city. The first active area restricts new orders
+// to pickups inside the active areas.
+func (s *ServiceAreaService) CreateServiceArea(ctx context.Context, req *pb.CreateServiceAreaRequest) (*pb.ServiceAreaResponse, error) {
+	city := normalizeCity(req.City)
+	if city == "" || req.Name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "city and name are required")
+	}
+	boundary, err := convertBoundary(req.Boundary)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	area := &model.ServiceArea{
+		ID:        uuid.New().String(),
+		City:      city,
+		Name:      req.Name,
+		Boundary:  boundary,
+		Active:    req.Active,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.repo.CreateArea(ctx, area); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create service area: %v", err)
+	}
+	s.refreshAreas(ctx)
+
+	return &pb.ServiceAreaResponse{
+		Area:    convertServiceAreaToProto(area),
+		Message: "Service area created successfully",
+		Success: true,
+	}, nil
+}
+
+// UpdateServiceArea replaces the name, boundary and active flag of a service area.
+// Orders already taken keep their providers.
+func (s *ServiceAreaService) UpdateServiceArea(ctx context.Context, req *pb.UpdateServiceAreaRequest) (*pb.ServiceAreaResponse, error) {
+	if req.AreaId == "" || req.Name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "area ID and name are required")
+	}
+	boundary, err := convertBoundary(req.Boundary)
+	if err != nil {
+		return nil, err
+	}
+
+	area, err := s.repo.GetArea(ctx, req.AreaId)
+	if err != nil {
+		if errors.Is(err, repository.ErrServiceAreaNotFound) {
+			return nil, status.Errorf(codes.NotFound, "service area not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get service area: %v", err)
+	}
+
+	area.Name = req.Name
+	area.Boundary = boundary
+	area.Active = req.Active
+	area.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateArea(ctx, area); err != nil {
+		if errors.Is(err, repository.ErrServiceAreaNotFound) {
+			return nil, status.Errorf(codes.NotFound, "service area not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update service area: %v", err)
+	}
+	s.refreshAreas(ctx)
+
+	return &pb.ServiceAreaResponse{
+		Area:    convertServiceAreaToProto(area),
+		Message: "Service area updated successfully",
+		Success: true,
+	}, nil
+}
+
+// DeleteServiceArea deletes a service area; new orders can no longer be picked up in it
+func (s *ServiceAreaService) DeleteServiceArea(ctx context.Context, req *pb.DeleteServiceAreaRequest) (*pb.DeleteServiceAreaResponse, error) {
+	if req.AreaId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "area ID is required")
+	}
+
+	if err := s.repo.DeleteArea(ctx, req.AreaId); err != nil {
+		if errors.Is(err, repository.ErrServiceAreaNotFound) {
+			return nil, status.Errorf(codes.NotFound, "service area not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete service area: %v", err)
+	}
+	s.refreshAreas(ctx)
+
+	return &pb.DeleteServiceAreaResponse{
+		Message: "Service area deleted successfully",
+		Success: true,
+	}, nil
+}
+
+// refreshAreas applies a change on this instance immediately; other instances pick it
+// up on their next periodic refresh
+func (s *ServiceAreaService) refreshAreas(ctx context.Context) {
+	if err := s.areas.Refresh(ctx); err != nil {
+		fmt.Printf("Failed to refresh service areas: %v\n", err)
+	}
+}
+
+// convertBoundary validates a boundary polygon and converts it to the model
+func convertBoundary(points []*pb.BoundaryPoint) (model.Boundary, error) {
+	if len(points) < 3 {
+		return nil, status.Errorf(codes.InvalidArgument, "boundary needs at least 3 points")
+	}
+
+	boundary := make(model.Boundary, 0, len(points))
+	for _, point := range points {
+		if point == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "boundary points cannot be empty")
+		}
+		if point.Latitude < -90 || point.Latitude > 90 || point.Longitude < -180 || point.Longitude > 180 {
+			return nil, status.Errorf(codes.InvalidArgument, "boundary point (%v, %v) is out of range", point.Latitude, point.Longitude)
+		}
+		boundary = append(boundary, model.BoundaryPoint{
+			Latitude:  point.Latitude,
+			Longitude: point.Longitude,
+		})
+	}
+	return boundary, nil
+}
+
+func convertServiceAreaToProto(area *model.ServiceArea) *pb.ServiceArea {
+	boundary := make([]*pb.BoundaryPoint, 0, len(area.Boundary))
+	for _, point := range area.Boundary {
+		boundary = append(boundary, &pb.BoundaryPoint{
+			Latitude:  point.Latitude,
+			Longitude: point.Longitude,
+		})
+	}
+
+	return &pb.ServiceArea{
+		Id:        area.ID,
+		City:      area.City,
+		Name:      area.Name,
+		Boundary:  boundary,
+		Active:    area.Active,
+		CreatedAt: timestamppb.New(area.CreatedAt),
+		UpdatedAt: timestamppb.New(area.UpdatedAt),
+	}
+}
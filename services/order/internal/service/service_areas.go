@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// ServiceAreas holds the active service areas in memory so pickups can be checked
+// without a database round trip. Admin changes are picked up on the next refresh.
+type ServiceAreas struct {
+	repo     *repository.ServiceAreaRepository
+	interval time.Duration
+
+	mu    sync.RWMutex
+	areas []*model.ServiceArea
+}
+
+// NewServiceAreas creates a new service area catalog; call Refresh to load it
+func NewServiceAreas(repo *repository.ServiceAreaRepository, interval time.Duration) *ServiceAreas {
+	return &ServiceAreas{
+		repo:     repo,
+		interval: interval,
+	}
+}
+
+// Refresh reloads the active service areas from the database
+func (a *ServiceAreas) Refresh(ctx context.Context) error {
+	areas, err := a.repo.ListAreas(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	active := make([]*model.ServiceArea, 0, len(areas))
+	for _, area := range areas {
+		if area.Active {
+			active = append(active, area)
+		}
+	}
+
+	a.mu.Lock()
+	a.areas = active
+	a.mu.Unlock()
+
+	return nil
+}
+
+// Run refreshes the catalog every interval until ctx is cancelled. A failed refresh
+// keeps the previous areas.
+func (a *ServiceAreas) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh service areas: %v", err)
+			}
+		}
+	}
+}
+
+// Get returns an active area by its ID, or nil if there is none
+func (a *ServiceAreas) Get(areaID string) *model.ServiceArea {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, area := range a.areas {
+		if area.ID == areaID {
+			return area
+		}
+	}
+	return nil
+}
+
+// Locate finds the active area a location is in. With no active areas every location
+// is served, so ok is true and area is nil; otherwise ok is false when no area contains
+// the location.
+func (a *ServiceAreas) Locate(location model.Location) (area *model.ServiceArea, ok bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if len(a.areas) == 0 {
+		return nil, true
+	}
+	for _, candidate := range a.areas {
+		if candidate.Boundary.Contains(location.Latitude, location.Longitude) {
+			return candidate, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// SLAConfig controls how long each stage of an order may take and how often orders are checked
+type SLAConfig struct {
+	MaxDurations map[model.SLAStage]time.Duration // Stages without a duration are not tracked
+	Interval     time.Duration                    // How often orders are checked
+	Lookback     time.Duration                    // How far back to look for orders that left a stage, so stages completed between checks are counted
+	OpsChannel   string                           // Notification channel that operations staff watch
+}
+
+// SLAMonitor checks each stage of every order against its maximum duration. A stage that
+// runs past its deadline is breached as soon as the deadline passes: the order is marked
+// at risk and operations staff are alerted. Stages that complete in time are met. Every
+// outcome is recorded once as an order event, which feeds SLA attainment analytics.
+type SLAMonitor struct {
+	repo               *repository.SLARepository
+	orderRepo          *repository.OrderRepository
+	notificationClient NotificationClient
+	cfg                SLAConfig
+}
+
+// NewSLAMonitor creates a new SLA monitor
+func NewSLAMonitor(
+	repo *repository.SLARepository,
+	orderRepo *repository.OrderRepository,
+	notificationClient NotificationClient,
+	cfg SLAConfig,
+) *SLAMonitor {
+	return &SLAMonitor{
+		repo:               repo,
+		orderRepo:          orderRepo,
+		notificationClient: notificationClient,
+		cfg:                cfg,
+	}
+}
+
+// Run checks orders each interval until ctx is cancelled
+func (m *SLAMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Check(ctx, time.Now()); err != nil {
+				log.Printf("Failed to check SLAs: %v", err)
+			}
+		}
+	}
+}
+
+// Check checks every order that is in a tracked stage, or was updated within the
+// lookback and so may have just left one
+func (m *SLAMonitor) Check(ctx context.Context, now time.Time) error {
+	var statuses []model.OrderStatus
+	for _, stage := range model.SLAStages {
+		if m.cfg.MaxDurations[stage] > 0 {
+			statuses = append(statuses, stage.During()...)
+		}
+	}
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	orderIDs, err := m.orderRepo.ListOrdersByStatusOrUpdatedSince(ctx, now.Add(-m.cfg.Lookback), statuses...)
+	if err != nil {
+		return err
+	}
+	for _, orderID := range orderIDs {
+		if err := m.CheckOrder(ctx, orderID, now); err != nil {
+			log.Printf("Failed to check SLAs of order %s: %v", orderID, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckOrder records the outcome of each of an order's stages that has one by now
+func (m *SLAMonitor) CheckOrder(ctx context.Context, orderID string, now time.Time) error {
+	order, err := m.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, stage := range model.SLAStages {
+		maxDuration := m.cfg.MaxDurations[stage]
+		if maxDuration <= 0 {
+			continue
+		}
+		start, end, ok := stage.Span(order.StatusHistory)
+		if !ok {
+			continue
+		}
+
+		result := &model.SLAResult{
+			OrderID:    order.ID,
+			Stage:      stage,
+			Outcome:    model.SLAMet,
+			StartedAt:  start,
+			Deadline:   start.Add(maxDuration),
+			RecordedAt: now,
+		}
+		switch {
+		case end.IsZero():
+			// Still under way; it can only be breached so far
+			if !now.After(result.Deadline) {
+				continue
+			}
+			result.Outcome = model.SLABreached
+		case end.After(result.Deadline):
+			result.Outcome = model.SLABreached
+			result.EndedAt = &end
+		default:
+			result.EndedAt = &end
+		}
+
+		recorded, err := m.repo.RecordResult(ctx, result)
+		if err != nil {
+			return fmt.Errorf("failed to record %s SLA: %w", stage, err)
+		}
+		if recorded && result.Outcome == model.SLABreached {
+			m.sendAlert(ctx, order, result)
+		}
+	}
+
+	return nil
+}
+
+// sendAlert lets operations staff know that a stage of an order missed its SLA
+func (m *SLAMonitor) sendAlert(ctx context.Context, order *model.Order, result *model.SLAResult) {
+	payload := map[string]interface{}{
+		"order_id":    order.ID,
+		"provider_id": order.ProviderID,
+		"stage":       result.Stage,
+		"started_at":  result.StartedAt,
+		"deadline":    result.Deadline,
+	}
+
+	err := m.notificationClient.SendNotification(ctx, m.cfg.OpsChannel, "ADMIN", "SLA_BREACHED", "SLA breached",
+		fmt.Sprintf("The %s stage of order %s, started at %s, was not done by %s. The order is now at risk.",
+			result.Stage, order.ID, result.StartedAt.Format(time.RFC3339), result.Deadline.Format(time.RFC3339)),
+		payload)
+	if err != nil {
+		log.Printf("Failed to alert operations of SLA breach on order %s: %v", order.ID, err)
+	}
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/money"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxPaymentShares caps how many payers can split one order
+const maxPaymentShares = 10
+
+// SplitPaymentConfig controls how split payments are collected
+type SplitPaymentConfig struct {
+	Timeout  time.Duration // After this, outstanding shares are charged to the primary payer
+	Interval time.Duration // How often outstanding shares are retried
+}
+
+// SplitPaymentCollector collects each payer's share of split orders and completes the
+// order's payment once every share succeeds
+type SplitPaymentCollector struct {
+	orderRepo          *repository.OrderRepository
+	shareRepo          *repository.PaymentShareRepository
+	paymentClient      PaymentClient
+	notificationClient NotificationClient
+	cfg                SplitPaymentConfig
+}
+
+// NewSplitPaymentCollector creates a new split payment collector
+func NewSplitPaymentCollector(
+	orderRepo *repository.OrderRepository,
+	shareRepo *repository.PaymentShareRepository,
+	paymentClient PaymentClient,
+	notificationClient NotificationClient,
+	cfg SplitPaymentConfig,
+) *SplitPaymentCollector {
+	return &SplitPaymentCollector{
+		orderRepo:          orderRepo,
+		shareRepo:          shareRepo,
+		paymentClient:      paymentClient,
+		notificationClient: notificationClient,
+		cfg:                cfg,
+	}
+}
+
+// Run retries outstanding shares every interval until ctx is cancelled
+func (c *SplitPaymentCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			orderIDs, err := c.shareRepo.ListPendingOrders(ctx)
+			if err != nil {
+				log.Printf("Failed to list pending split payments: %v", err)
+				continue
+			}
+			for _, orderID := range orderIDs {
+				if err := c.Collect(ctx, orderID); err != nil {
+					log.Printf("Failed to collect split payment for order %s: %v", orderID, err)
+				}
+			}
+		}
+	}
+}
+
+// Collect charges every outstanding share of an order. Once the split has timed out, the
+// outstanding amount is charged to the primary payer instead.
+func (c *SplitPaymentCollector) Collect(ctx context.Context, orderID string) error {
+	order, err := c.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != model.StatusPaymentPending {
+		return nil
+	}
+
+	shares, err := c.shareRepo.ListOrderShares(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	if time.Since(order.CreatedAt) >= c.cfg.Timeout {
+		if err := c.fallBackToPrimary(ctx, order, shares); err != nil {
+			return err
+		}
+	} else {
+		for _, share := range shares {
+			if !share.Outstanding() {
+				continue
+			}
+
+			// The share ID is the idempotency key, so retries never charge a payer twice
+			paymentID, err := c.paymentClient.CapturePayment(ctx, order.ID, share.UserID, share.Amount,
+				fmt.Sprintf("%.2f%% share of order %s", share.Percentage, order.ID), "share-"+share.ID)
+			if err != nil {
+				if markErr := c.shareRepo.MarkShareFailed(ctx, share.ID, err.Error()); markErr != nil {
+					return markErr
+				}
+				continue
+			}
+			if err := c.shareRepo.MarkShareCaptured(ctx, share.ID, paymentID); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = c.shareRepo.CompleteSplitPayment(ctx, orderID)
+	return err
+}
+
+// fallBackToPrimary charges the primary payer for every share that has not been collected
+func (c *SplitPaymentCollector) fallBackToPrimary(ctx context.Context, order *model.Order, shares []*model.PaymentShare) error {
+	var amount int64
+	var percentage float64
+	for _, share := range shares {
+		if share.Outstanding() {
+			amount += share.Amount
+			percentage += share.Percentage
+		}
+	}
+	if amount == 0 {
+		return nil
+	}
+
+	paymentID, err := c.paymentClient.CapturePayment(ctx, order.ID, order.UserID, amount,
+		fmt.Sprintf("Uncollected shares of order %s", order.ID), "split-fallback-"+order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to charge primary payer: %v", err)
+	}
+
+	now := time.Now()
+	fallback := &model.PaymentShare{
+		ID:         uuid.New().String(),
+		OrderID:    order.ID,
+		UserID:     order.UserID,
+		Percentage: percentage,
+		Amount:     amount,
+		Status:     model.ShareCaptured,
+		PaymentID:  paymentID,
+		Attempts:   1,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := c.shareRepo.ReassignShares(ctx, order.ID, fallback); err != nil {
+		return err
+	}
+
+	go func() {
+		err := c.notificationClient.SendNotification(context.Background(), order.UserID, "USER", "SPLIT_PAYMENT_FALLBACK",
+			"Split payment timed out", fmt.Sprintf("%s in unpaid shares of order %s was charged to you", money.Format(amount), order.ID),
+			map[string]interface{}{
+				"order_id": order.ID,
+				"amount":   amount,
+			})
+		if err != nil {
+			fmt.Printf("Failed to notify primary payer of split fallback: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// buildPaymentShares validates the requested split and works out each payer's amount.
+// Amounts are rounded to the nearest minor unit, and the primary payer absorbs the rounding difference.
+func buildPaymentShares(order *model.Order, requested []*pb.PaymentShare) ([]*model.PaymentShare, error) {
+	if len(requested) > maxPaymentShares {
+		return nil, status.Errorf(codes.InvalidArgument, "an order can be split between at most %d payers", maxPaymentShares)
+	}
+	if order.PaymentMethod == model.PaymentCash {
+		return nil, status.Errorf(codes.InvalidArgument, "cash orders cannot be split")
+	}
+	if order.PaymentMethod == model.PaymentCrypto {
+		return nil, status.Errorf(codes.InvalidArgument, "crypto orders cannot be split")
+	}
+
+	seen := make(map[string]bool)
+	var total float64
+	var primary *model.PaymentShare
+	shares := make([]*model.PaymentShare, 0, len(requested))
+	for _, req := range requested {
+		if seen[req.UserId] {
+			return nil, status.Errorf(codes.InvalidArgument, "user %s has more than one payment share", req.UserId)
+		}
+		seen[req.UserId] = true
+		total += req.Percentage
+
+		share := &model.PaymentShare{
+			ID:         uuid.New().String(),
+			OrderID:    order.ID,
+			UserID:     req.UserId,
+			Percentage: req.Percentage,
+			Amount:     money.Percent(order.TotalPrice, req.Percentage),
+			Status:     model.SharePending,
+			CreatedAt:  order.CreatedAt,
+			UpdatedAt:  order.CreatedAt,
+		}
+		if req.UserId == order.UserID {
+			primary = share
+		}
+		shares = append(shares, share)
+	}
+
+	if math.Abs(total-100) > 0.01 {
+		return nil, status.Errorf(codes.InvalidArgument, "payment share percentages must add up to 100, got %.2f", total)
+	}
+	if primary == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "payment shares must include the ordering user")
+	}
+
+	var allocated int64
+	for _, share := range shares {
+		allocated += share.Amount
+	}
+	primary.Amount += order.TotalPrice - allocated
+
+	return shares, nil
+}
+
+func convertPaymentSharesToProto(shares []*model.PaymentShare) []*pb.PaymentShare {
+	protoShares := []*pb.PaymentShare{}
+	for _, share := range shares {
+		protoShares = append(protoShares, &pb.PaymentShare{
+			UserId:     share.UserID,
+			Percentage: share.Percentage,
+			Amount:     share.Amount,
+			Status:     string(share.Status),
+			PaymentId:  share.PaymentID,
+		})
+	}
+	return protoShares
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/support"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SupportAccessPolicy controls how long admins can act on behalf of users
+type SupportAccessPolicy struct {
+	DefaultTTL time.Duration // How long a session lasts when the admin does not ask for a TTL
+	MaxTTL     time.Duration // Longest TTL an admin can ask for
+}
+
+// SupportAccessService issues the time-limited tokens with which admins act on behalf of
+// a user while troubleshooting. The gateway verifies the token on every request and
+// tags the calls it makes, so the audit log records the admin behind each one.
+type SupportAccessService struct {
+	pb.UnimplementedSupportAccessServiceServer
+	repo   *repository.SupportSessionRepository
+	policy SupportAccessPolicy
+}
+
+// NewSupportAccessService creates a new support access service
+func NewSupportAccessService(repo *repository.SupportSessionRepository, policy SupportAccessPolicy) *SupportAccessService {
+	return &SupportAccessService{
+		repo:   repo,
+		policy: policy,
+	}
+}
+
+// StartSupportSession gives an admin a token to act as a user until it expires. The
+// token is returned only once.
+func (s *SupportAccessService) StartSupportSession(ctx context.Context, req *pb.StartSupportSessionRequest) (*pb.StartSupportSessionResponse, error) {
+	if req.RequestedBy == "" || req.UserId == "" || req.Reason == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "requested by, user ID and reason are required")
+	}
+	if req.TtlMinutes < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "TTL cannot be negative")
+	}
+
+	scope := model.SupportScopeReadOnly
+	if req.Scope != "" {
+		scope = model.SupportScope(strings.ToUpper(req.Scope))
+		if scope != model.SupportScopeReadOnly && scope != model.SupportScopeReadWrite {
+			return nil, status.Errorf(codes.InvalidArgument, "scope must be READ_ONLY or READ_WRITE")
+		}
+	}
+
+	ttl := s.policy.DefaultTTL
+	if req.TtlMinutes > 0 {
+		ttl = time.Duration(req.TtlMinutes) * time.Minute
+	}
+	if ttl > s.policy.MaxTTL {
+		ttl = s.policy.MaxTTL
+	}
+
+	token, err := generateContactToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate support token: %v", err)
+	}
+
+	now := time.Now()
+	session := &model.SupportSession{
+		ID:        uuid.New().String(),
+		AdminID:   req.RequestedBy,
+		UserID:    req.UserId,
+		Reason:    req.Reason,
+		Scope:     scope,
+		TokenHash: model.HashSupportToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create support session: %v", err)
+	}
+
+	return &pb.StartSupportSessionResponse{
+		Session: convertSupportSessionToProto(session),
+		Token:   token,
+		Message: "Support session started",
+		Success: true,
+	}, nil
+}
+
+// VerifySupportToken returns the session a token belongs to. Ended and expired sessions
+// look the same as unknown tokens.
+func (s *SupportAccessService) VerifySupportToken(ctx context.Context, req *pb.VerifySupportTokenRequest) (*pb.SupportSession, error) {
+	if req.Token == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "token is required")
+	}
+
+	session, err := s.repo.GetSessionByHash(ctx, model.HashSupportToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrSupportSessionNotFound) {
+			return nil, status.Errorf(codes.NotFound, "support session not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get support session: %v", err)
+	}
+	if !session.Active(time.Now()) {
+		return nil, status.Errorf(codes.NotFound, "support session not found")
+	}
+
+	return convertSupportSessionToProto(session), nil
+}
+
+// EndSupportSession revokes a session's token before it expires
+func (s *SupportAccessService) EndSupportSession(ctx context.Context, req *pb.EndSupportSessionRequest) (*pb.SupportSessionResponse, error) {
+	if req.SupportSessionId == "" || req.RequestedBy == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "support session ID and requested by are required")
+	}
+
+	if err := s.repo.EndSession(ctx, req.SupportSessionId, req.RequestedBy, time.Now()); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrSupportSessionNotFound):
+			return nil, status.Errorf(codes.NotFound, "support session not found")
+		case errors.Is(err, repository.ErrSupportSessionEnded):
+			return nil, status.Errorf(codes.FailedPrecondition, "support session already ended")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to end support session: %v", err)
+	}
+
+	session, err := s.repo.GetSession(ctx, req.SupportSessionId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get support session: %v", err)
+	}
+
+	return &pb.SupportSessionResponse{
+		Session: convertSupportSessionToProto(session),
+		Message: "Support session ended",
+		Success: true,
+	}, nil
+}
+
+// ListSupportSessions lists the latest sessions for a user, by an admin, or both
+func (s *SupportAccessService) ListSupportSessions(ctx context.Context, req *pb.ListSupportSessionsRequest) (*pb.ListSupportSessionsResponse, error) {
+	var activeAt *time.Time
+	if req.ActiveOnly {
+		now := time.Now()
+		activeAt = &now
+	}
+
+	sessions, err := s.repo.ListSessions(ctx, req.UserId, req.AdminId, activeAt)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list support sessions: %v", err)
+	}
+
+	protoSessions := make([]*pb.SupportSession, 0, len(sessions))
+	for _, session := range sessions {
+		protoSessions = append(protoSessions, convertSupportSessionToProto(session))
+	}
+
+	return &pb.ListSupportSessionsResponse{
+		Sessions: protoSessions,
+	}, nil
+}
+
+func convertSupportSessionToProto(session *model.SupportSession) *pb.SupportSession {
+	protoSession := &pb.SupportSession{
+		Id:        session.ID,
+		AdminId:   session.AdminID,
+		UserId:    session.UserID,
+		Reason:    session.Reason,
+		Scope:     string(session.Scope),
+		CreatedAt: timestamppb.New(session.CreatedAt),
+		ExpiresAt: timestamppb.New(session.ExpiresAt),
+		EndedBy:   session.EndedBy,
+	}
+	if session.EndedAt != nil {
+		protoSession.EndedAt = timestamppb.New(*session.EndedAt)
+	}
+	return protoSession
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/pkg/money"
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AddTip charges the user a tip for a completed order and credits it to the provider
+func (s *OrderService) AddTip(ctx context.Context, req *pb.AddTipRequest) (*pb.OrderResponse, error) {
+	// Get current order
+	order, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if order.UserID != req.UserId {
+		return nil, status.Errorf(codes.PermissionDenied, "only the order's user can add a tip")
+	}
+	if order.Status != model.StatusCompleted {
+		return nil, status.Errorf(codes.FailedPrecondition, "tips can only be added to completed orders")
+	}
+	if order.ProviderID == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "order has no provider to tip")
+	}
+	if order.PaymentMethod == model.PaymentCash {
+		return nil, status.Errorf(codes.FailedPrecondition, "cash orders are tipped in cash")
+	}
+	if order.TipAmount > 0 {
+		return nil, status.Errorf(codes.AlreadyExists, "order has already been tipped")
+	}
+
+	// One tip per order, so the order ID doubles as the idempotency key
+	paymentID, err := s.paymentClient.CapturePayment(ctx, order.ID, order.UserID, req.Amount,
+		fmt.Sprintf("Tip for order %s", order.ID), "tip-"+order.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to capture tip: %v", err)
+	}
+
+	entry := &model.LedgerEntry{
+		ProviderID: order.ProviderID,
+		OrderID:    order.ID,
+		EntryType:  model.LedgerTip,
+		Amount:     req.Amount,
+		PaymentID:  paymentID,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.ledgerRepo.AddTip(ctx, entry); err != nil {
+		if errors.Is(err, repository.ErrTipAlreadyAdded) {
+			return nil, status.Errorf(codes.AlreadyExists, "order has already been tipped")
+		}
+		return nil, status.Errorf(codes.Internal, "tip captured as %s but failed to record it: %v", paymentID, err)
+	}
+
+	// Get updated order
+	updatedOrder, err := s.repo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get updated order: %v", err)
+	}
+
+	// Let the provider know
+	go func() {
+		err := s.notificationClient.SendNotification(context.Background(), updatedOrder.ProviderID, "PROVIDER", "ORDER_TIPPED",
+			"You received a tip", fmt.Sprintf("You received a %s tip for order %s", money.Format(req.Amount), updatedOrder.ID),
+			map[string]interface{}{
+				"order_id": updatedOrder.ID,
+				"amount":   req.Amount,
+			})
+		if err != nil {
+			fmt.Printf("Failed to notify provider of tip: %v\n", err)
+		}
+	}()
+
+	return &pb.OrderResponse{
+		Order:   convertOrderToProto(updatedOrder),
+		Message: "Tip added successfully",
+		Success: true,
+	}, nil
+}
+
+// ListProviderLedger lists the fares and tips credited to a provider
+func (s *OrderService) ListProviderLedger(ctx context.Context, req *pb.ListProviderLedgerRequest) (*pb.ListProviderLedgerResponse, error) {
+	entries, total, earnings, err := s.ledgerRepo.ListProviderEntries(ctx, req.ProviderId, int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list ledger entries: %v", err)
+	}
+
+	protoEntries := []*pb.LedgerEntry{}
+	for _, entry := range entries {
+		protoEntries = append(protoEntries, &pb.LedgerEntry{
+			Id:         entry.ID,
+			ProviderId: entry.ProviderID,
+			OrderId:    entry.OrderID,
+			EntryType:  string(entry.EntryType),
+			Amount:     entry.Amount,
+			PaymentId:  entry.PaymentID,
+			CreatedAt:  timestamppb.New(entry.CreatedAt),
+		})
+	}
+
+	return &pb.ListProviderLedgerResponse{
+		Entries:       protoEntries,
+		TotalEarnings: earnings,
+		Total:         int32(total),
+		Page:          req.Page,
+		Limit:         req.Limit,
+	}, nil
+}
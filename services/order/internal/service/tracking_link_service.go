@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/tracking"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TrackingLinkPolicy controls the public tracking links users can share
+type TrackingLinkPolicy struct {
+	DefaultTTL time.Duration // How long a link works when the user does not ask for a TTL
+	MaxTTL     time.Duration // Longest TTL a user can ask for
+}
+
+// TrackingLinkService issues time-limited public links to one order's live tracking
+type TrackingLinkService struct {
+	pb.UnimplementedTrackingLinkServiceServer
+	repo         *repository.TrackingLinkRepository
+	orderRepo    *repository.OrderRepository
+	locationRepo *repository.OrderLocationRepository
+	policy       TrackingLinkPolicy
+}
+
+// NewTrackingLinkService creates a new tracking link service
+func NewTrackingLinkService(repo *repository.TrackingLinkRepository, orderRepo *repository.OrderRepository, locationRepo *repository.OrderLocationRepository, policy TrackingLinkPolicy) *TrackingLinkService {
+	return &TrackingLinkService{
+		repo:         repo,
+		orderRepo:    orderRepo,
+		locationRepo: locationRepo,
+		policy:       policy,
+	}
+}
+
+// CreateTrackingLink lets an order's user share its live tracking until the link expires
+func (s *TrackingLinkService) CreateTrackingLink(ctx context.Context, req *pb.CreateTrackingLinkRequest) (*pb.TrackingLinkResponse, error) {
+	if req.OrderId == "" || req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order ID and user ID are required")
+	}
+	if req.TtlMinutes < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "TTL cannot be negative")
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	if order.UserID != req.UserId {
+		return nil, status.Errorf(codes.PermissionDenied, "only the order's user can share its tracking")
+	}
+	if order.Status.Finished() {
+		return nil, status.Errorf(codes.FailedPrecondition, "tracking cannot be shared for order with status %s", order.Status)
+	}
+
+	ttl := s.policy.DefaultTTL
+	if req.TtlMinutes > 0 {
+		ttl = time.Duration(req.TtlMinutes) * time.Minute
+	}
+	if ttl > s.policy.MaxTTL {
+		ttl = s.policy.MaxTTL
+	}
+
+	value, err := generateContactToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate tracking token: %v", err)
+	}
+
+	now := time.Now()
+	link := &model.TrackingLink{
+		ID:        uuid.New().String(),
+		OrderID:   order.ID,
+		CreatedBy: req.UserId,
+		TokenHash: model.HashTrackingToken(value),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+
+	if err := s.repo.CreateLink(ctx, link); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create tracking link: %v", err)
+	}
+
+	return &pb.TrackingLinkResponse{
+		Link: &pb.TrackingLink{
+			Token:     value,
+			OrderId:   order.ID,
+			Path:      "/track/" + value,
+			ExpiresAt: timestamppb.New(link.ExpiresAt),
+		},
+		Message: "Tracking link created",
+		Success: true,
+	}, nil
+}
+
+// GetSharedTracking returns the live tracking of the order a link was created for. The
+// provider's location is withheld once the order finishes.
+func (s *TrackingLinkService) GetSharedTracking(ctx context.Context, req *pb.GetSharedTrackingRequest) (*pb.SharedTrackingResponse, error) {
+	if req.Token == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "token is required")
+	}
+
+	link, err := s.repo.GetLinkByHash(ctx, model.HashTrackingToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrTrackingLinkNotFound) {
+			return nil, status.Errorf(codes.NotFound, "tracking link not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get tracking link: %v", err)
+	}
+	// Expired links look the same as unknown ones
+	if !link.Valid(time.Now()) {
+		return nil, status.Errorf(codes.NotFound, "tracking link not found")
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, link.OrderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+
+	resp := &pb.SharedTrackingResponse{
+		Status: string(order.Status),
+		DestinationLocation: &pb.Location{
+			Latitude:  order.DestinationLocation.Latitude,
+			Longitude: order.DestinationLocation.Longitude,
+		},
+		ExpiresAt: timestamppb.New(link.ExpiresAt),
+	}
+	if order.Status.Finished() {
+		return resp, nil
+	}
+
+	location, err := s.locationRepo.GetLatestOrderLocation(ctx, order.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderLocationNotFound) {
+			return resp, nil
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get latest location: %v", err)
+	}
+
+	resp.CurrentLocation = &pb.Location{Latitude: location.Latitude, Longitude: location.Longitude}
+	resp.EstimatedArrivalMinutes = estimateOrderArrivalMinutes(order, location)
+	resp.LocationUpdatedAt = timestamppb.New(location.Timestamp)
+	return resp, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnpaidOrderRepository finds the orders left waiting for payment. It is implemented by
+// repository.OrderRepository on Postgres and by memory.OrderRepository for tests.
+type UnpaidOrderRepository interface {
+	GetOrderByID(ctx context.Context, orderID string) (*model.Order, error)
+	// ListUnpaidOrders lists up to limit IDs of orders that have been waiting for
+	// payment since before a time, oldest first
+	ListUnpaidOrders(ctx context.Context, before time.Time, limit int) ([]string, error)
+}
+
+// UnpaidOrderCanceller cancels an order only while it is still waiting for payment,
+// failing with FailedPrecondition otherwise. It is implemented by OrderService.
+type UnpaidOrderCanceller interface {
+	CancelUnpaidOrder(ctx context.Context, orderID, reason string) error
+}
+
+// UnpaidOrderConfig configures the unpaid order sweeper
+type UnpaidOrderConfig struct {
+	TTL       time.Duration // How long an order can wait for payment before it is cancelled
+	Interval  time.Duration // How often unpaid orders are looked for
+	BatchSize int           // Most orders cancelled per run
+}
+
+// UnpaidOrderSweeper cancels orders that have waited for payment longer than the TTL, so
+// abandoned orders do not pile up. Orders are cancelled the way users cancel them, which
+// gives back held stock, held payments, wallet payments and redeemed points, without a
+// fee. The user, and the provider if one was assigned, are told.
+type UnpaidOrderSweeper struct {
+	orderRepo          UnpaidOrderRepository
+	orders             UnpaidOrderCanceller
+	notificationClient NotificationClient
+	cfg                UnpaidOrderConfig
+}
+
+// NewUnpaidOrderSweeper creates a new unpaid order sweeper
+func NewUnpaidOrderSweeper(orderRepo UnpaidOrderRepository, orders UnpaidOrderCanceller, notificationClient NotificationClient, cfg UnpaidOrderConfig) *UnpaidOrderSweeper {
+	return &UnpaidOrderSweeper{
+		orderRepo:          orderRepo,
+		orders:             orders,
+		notificationClient: notificationClient,
+		cfg:                cfg,
+	}
+}
+
+// Run cancels unpaid orders every interval until ctx is cancelled
+func (w *UnpaidOrderSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Sweep(ctx, time.Now()); err != nil {
+				log.Printf("Failed to list unpaid orders: %v", err)
+			}
+		}
+	}
+}
+
+// Sweep cancels one batch of the orders that have waited for payment for longer than the
+// TTL by now. Orders that fail to cancel are logged and left for the next sweep.
+func (w *UnpaidOrderSweeper) Sweep(ctx context.Context, now time.Time) error {
+	orderIDs, err := w.orderRepo.ListUnpaidOrders(ctx, now.Add(-w.cfg.TTL), w.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, orderID := range orderIDs {
+		if err := w.cancel(ctx, orderID); err != nil {
+			log.Printf("Failed to cancel unpaid order %s: %v", orderID, err)
+		}
+	}
+	return nil
+}
+
+// cancel cancels one unpaid order and tells its parties
+func (w *UnpaidOrderSweeper) cancel(ctx context.Context, orderID string) error {
+	order, err := w.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != model.StatusPaymentPending {
+		return nil
+	}
+
+	err = w.orders.CancelUnpaidOrder(ctx, orderID, "Payment was not received in time")
+	if status.Code(err) == codes.FailedPrecondition {
+		// The order was paid, moved on or was frozen meanwhile
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	w.notify(ctx, order)
+	return nil
+}
+
+// notify tells the user, and the provider if one was assigned, that an unpaid order was
+// cancelled
+func (w *UnpaidOrderSweeper) notify(ctx context.Context, order *model.Order) {
+	payload := map[string]interface{}{
+		"order_id": order.ID,
+	}
+
+	err := w.notificationClient.SendNotification(ctx, order.UserID, "USER", "UNPAID_ORDER_CANCELLED", "Order cancelled",
+		fmt.Sprintf("Order %s was cancelled because its payment was not received in time", order.ID), payload)
+	if err != nil {
+		log.Printf("Failed to notify user of cancelled unpaid order %s: %v", order.ID, err)
+	}
+
+	if order.ProviderID == "" {
+		return
+	}
+	err = w.notificationClient.SendNotification(ctx, order.ProviderID, "PROVIDER", "UNPAID_ORDER_CANCELLED", "Order cancelled",
+		fmt.Sprintf("Order %s was cancelled because its payment was not received in time", order.ID), payload)
+	if err != nil {
+		log.Printf("Failed to notify provider of cancelled unpaid order %s: %v", order.ID, err)
+	}
+}
@@ -0,0 +1,134 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/service"
+	"google.golang.org/grpc/codes"
+)
+
+// captureNotifications records who each notification is sent to
+type captureNotifications struct {
+	mu         sync.Mutex
+	recipients []string
+}
+
+func (n *captureNotifications) SendNotification(ctx context.Context, recipientID, recipientType, notificationType, title, message string, payload map[string]interface{}) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.recipients = append(n.recipients, recipientID)
+	return nil
+}
+
+func TestUnpaidOrderSweeperCancelsExpiredOrders(t *testing.T) {
+	const orderID = "3c9a1e7b-2d4f-4a86-b0e5-8f7d6c5b4a39"
+	tests := []struct {
+		name           string
+		status         model.OrderStatus
+		sweepAfter     time.Duration
+		wantStatus     model.OrderStatus
+		wantStock      int
+		wantRecipients int
+	}{
+		{name: "unpaid past the TTL is cancelled", status: model.StatusPaymentPending, sweepAfter: time.Hour, wantStatus: model.StatusCancelled, wantStock: 5, wantRecipients: 2},
+		{name: "unpaid within the TTL waits", status: model.StatusPaymentPending, sweepAfter: time.Minute, wantStatus: model.StatusPaymentPending, wantStock: 3},
+		{name: "paid order is left alone", status: model.StatusPaymentComplete, sweepAfter: time.Hour, wantStatus: model.StatusPaymentComplete, wantStock: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, repos := newTestOrderService(&capturePayments{})
+			stockTestMerchant(t, repos, 5)
+			notifications := &captureNotifications{}
+			sweeper := service.NewUnpaidOrderSweeper(repos.orders, s, notifications, service.UnpaidOrderConfig{TTL: 30 * time.Minute, BatchSize: 10})
+
+			storeTestOrder(t, repos, orderID, model.TypeFoodDelivery, tt.status)
+			items := model.OrderItems{{ItemID: testItemID, Quantity: 2}}
+			if err := repos.stock.Reserve(ctx, orderID, testMerchantID, items, time.Now().Add(time.Hour)); err != nil {
+				t.Fatalf("Reserve: %v", err)
+			}
+
+			if err := sweeper.Sweep(ctx, time.Now().Add(tt.sweepAfter)); err != nil {
+				t.Fatalf("Sweep: %v", err)
+			}
+
+			order, err := repos.orders.GetOrderByID(ctx, orderID)
+			if err != nil {
+				t.Fatalf("GetOrderByID: %v", err)
+			}
+			if order.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", order.Status, tt.wantStatus)
+			}
+			if order.CancellationFee != 0 {
+				t.Errorf("cancellation fee = %d, want none", order.CancellationFee)
+			}
+			if left := stockLeft(t, repos); left != tt.wantStock {
+				t.Errorf("stock left = %d, want %d", left, tt.wantStock)
+			}
+			if len(notifications.recipients) != tt.wantRecipients {
+				t.Errorf("notified %v, want %d recipients", notifications.recipients, tt.wantRecipients)
+			}
+		})
+	}
+}
+
+func TestUnpaidOrderSweeperWaitsFromPaymentPending(t *testing.T) {
+	const orderID = "7e2b9c41-5a3d-4f08-9c6e-1d2a3b4c5d6e"
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	sweeper := service.NewUnpaidOrderSweeper(repos.orders, s, &captureNotifications{}, service.UnpaidOrderConfig{TTL: 30 * time.Minute, BatchSize: 10})
+
+	// Created two hours ago, but only waiting for payment since now
+	now := time.Now()
+	order := &model.Order{
+		ID:            orderID,
+		UserID:        testUserID,
+		OrderType:     model.TypeFoodDelivery,
+		Status:        model.StatusPaymentPending,
+		PaymentMethod: model.PaymentCreditCard,
+		TotalPrice:    100000,
+		CreatedAt:     now.Add(-2 * time.Hour),
+		UpdatedAt:     now,
+		StatusHistory: model.StatusHistories{
+			{Status: model.StatusCreated, UpdatedBy: "system", Timestamp: now.Add(-2 * time.Hour)},
+			{Status: model.StatusPaymentPending, UpdatedBy: "system", Timestamp: now},
+		},
+	}
+	if err := repos.orders.CreateOrder(ctx, order, time.Time{}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	if err := sweeper.Sweep(ctx, now.Add(10*time.Minute)); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	got, err := repos.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if got.Status != model.StatusPaymentPending {
+		t.Errorf("status = %s, want %s", got.Status, model.StatusPaymentPending)
+	}
+}
+
+func TestCancelUnpaidOrderLeavesPaidOrder(t *testing.T) {
+	const orderID = "0f4e8d2c-6b1a-4c39-8e57-a9b8c7d6e5f4"
+	ctx := context.Background()
+	s, repos := newTestOrderService(&capturePayments{})
+	storeTestOrder(t, repos, orderID, model.TypeFoodDelivery, model.StatusPaymentComplete)
+
+	err := s.CancelUnpaidOrder(ctx, orderID, "Payment was not received in time")
+	wantCode(t, err, codes.FailedPrecondition)
+
+	order, err := repos.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if order.Status != model.StatusPaymentComplete {
+		t.Errorf("status = %s, want %s", order.Status, model.StatusPaymentComplete)
+	}
+}
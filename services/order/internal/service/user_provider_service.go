@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/userprovider"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UserProviderService lets users favorite or block providers. Favorites are boosted by
+// the dispatcher; blocked providers are never assigned to the user's orders.
+type UserProviderService struct {
+	pb.UnimplementedUserProviderServiceServer
+	repo *repository.UserProviderRepository
+}
+
+// NewUserProviderService creates a new user provider service
+func NewUserProviderService(repo *repository.UserProviderRepository) *UserProviderService {
+	return &UserProviderService{
+		repo: repo,
+	}
+}
+
+// ListUserProviders lists the providers a user has favorited or blocked, newest first
+func (s *UserProviderService) ListUserProviders(ctx context.Context, req *pb.ListUserProvidersRequest) (*pb.ListUserProvidersResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
+	}
+
+	var preference model.ProviderPreference
+	if req.Preference != "" {
+		var err error
+		if preference, err = parseProviderPreference(req.Preference); err != nil {
+			return nil, err
+		}
+	}
+
+	prefs, err := s.repo.ListPreferences(ctx, req.UserId, preference)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list user providers: %v", err)
+	}
+
+	protoPrefs := make([]*pb.UserProvider, 0, len(prefs))
+	for _, pref := range prefs {
+		protoPrefs = append(protoPrefs, convertUserProviderToProto(pref))
+	}
+
+	return &pb.ListUserProvidersResponse{
+		Providers: protoPrefs,
+	}, nil
+}
+
+// SetUserProvider favorites or blocks a provider for a user, replacing any earlier
+// preference. Blocking only affects orders matched from now on.
+func (s *UserProviderService) SetUserProvider(ctx context.Context, req *pb.SetUserProviderRequest) (*pb.UserProviderResponse, error) {
+	if req.UserId == "" || req.ProviderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID and provider ID are required")
+	}
+	preference, err := parseProviderPreference(req.Preference)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	pref := &model.UserProviderPreference{
+		UserID:     req.UserId,
+		ProviderID: req.ProviderId,
+		Preference: preference,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := s.repo.SetPreference(ctx, pref); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to set user provider: %v", err)
+	}
+
+	message := "Provider added to favorites"
+	if preference == model.PreferenceBlocked {
+		message = "Provider blocked"
+	}
+
+	return &pb.UserProviderResponse{
+		Provider: convertUserProviderToProto(pref),
+		Message:  message,
+		Success:  true,
+	}, nil
+}
+
+// RemoveUserProvider clears a user's favorite or block of a provider
+func (s *UserProviderService) RemoveUserProvider(ctx context.Context, req *pb.RemoveUserProviderRequest) (*pb.RemoveUserProviderResponse, error) {
+	if req.UserId == "" || req.ProviderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID and provider ID are required")
+	}
+
+	if err := s.repo.DeletePreference(ctx, req.UserId, req.ProviderId); err != nil {
+		if errors.Is(err, repository.ErrUserProviderPreferenceNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user has not favorited or blocked this provider")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to remove user provider: %v", err)
+	}
+
+	return &pb.RemoveUserProviderResponse{
+		Message: "Provider preference removed",
+		Success: true,
+	}, nil
+}
+
+// parseProviderPreference converts a preference name to the model
+func parseProviderPreference(preference string) (model.ProviderPreference, error) {
+	switch model.ProviderPreference(strings.ToUpper(preference)) {
+	case model.PreferenceFavorite:
+		return model.PreferenceFavorite, nil
+	case model.PreferenceBlocked:
+		return model.PreferenceBlocked, nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "preference must be FAVORITE or BLOCKED")
+	}
+}
+
+func convertUserProviderToProto(pref *model.UserProviderPreference) *pb.UserProvider {
+	return &pb.UserProvider{
+		UserId:     pref.UserID,
+		ProviderId: pref.ProviderID,
+		Preference: string(pref.Preference),
+		CreatedAt:  timestamppb.New(pref.CreatedAt),
+		UpdatedAt:  timestamppb.New(pref.UpdatedAt),
+	}
+}
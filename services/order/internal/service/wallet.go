@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/money"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// payFromWallet takes the part of a new order's total the user asked to pay from their
+// wallet balance, at most the total, before the order is stored. If the order is then
+// not placed, returnWalletPayment gives it back.
+func (s *OrderService) payFromWallet(ctx context.Context, order *model.Order, requested int64) error {
+	if requested == 0 {
+		return nil
+	}
+	if requested < 0 {
+		return status.Errorf(codes.InvalidArgument, "wallet amount cannot be negative")
+	}
+	if len(order.PaymentShares) > 0 || order.PaymentMethod == model.PaymentCrypto {
+		return status.Errorf(codes.InvalidArgument, "split and crypto orders cannot be paid from the wallet")
+	}
+
+	amount := min(requested, order.TotalPrice)
+	if amount == 0 {
+		return nil
+	}
+
+	txn := &model.WalletTransaction{
+		ID:          uuid.New().String(),
+		UserID:      order.UserID,
+		Type:        model.WalletOrderPayment,
+		Amount:      -amount,
+		OrderID:     order.ID,
+		Reference:   "order-" + order.ID,
+		Description: fmt.Sprintf("Payment for order %s", order.ID),
+		CreatedAt:   time.Now(),
+	}
+	if err := s.wallets.ApplyWalletTransaction(ctx, txn); err != nil {
+		if errors.Is(err, repository.ErrInsufficientWalletBalance) {
+			return status.Errorf(codes.FailedPrecondition, "wallet balance is less than %s", money.Format(amount))
+		}
+		return status.Errorf(codes.Internal, "failed to pay from wallet: %v", err)
+	}
+	order.WalletAmount = amount
+
+	return nil
+}
+
+// returnWalletPayment gives back what a new order that was not placed took from the
+// wallet. Failures are logged, as the order is being refused anyway.
+func (s *OrderService) returnWalletPayment(ctx context.Context, order *model.Order) {
+	if order.WalletAmount == 0 {
+		return
+	}
+	description := fmt.Sprintf("Order %s was not placed", order.ID)
+	if err := s.creditWallet(ctx, order, order.WalletAmount, "order-"+order.ID+"-returned", description); err != nil {
+		fmt.Printf("Failed to return %s to the wallet for order %s: %v\n", money.Format(order.WalletAmount), order.ID, err)
+	}
+}
+
+// creditWallet gives amount back to the wallet of the order's user at once. The reference
+// makes a retried credit happen once.
+func (s *OrderService) creditWallet(ctx context.Context, order *model.Order, amount int64, reference, description string) error {
+	return s.wallets.ApplyWalletTransaction(ctx, &model.WalletTransaction{
+		ID:          uuid.New().String(),
+		UserID:      order.UserID,
+		Type:        model.WalletRefund,
+		Amount:      amount,
+		OrderID:     order.ID,
+		Reference:   reference,
+		Description: description,
+		CreatedAt:   time.Now(),
+	})
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/pkg/money"
+	pb "github.com/order-api-microservices/proto/wallet"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// WalletRepository holds user wallet balances and their transactions. It is implemented
+// by repository.WalletRepository on Postgres and by memory.WalletRepository for tests.
+type WalletRepository interface {
+	// ApplyWalletTransaction changes a wallet's balance by txn.Amount and records the
+	// change, setting txn.BalanceAfter. A debit more than the balance fails with
+	// repository.ErrInsufficientWalletBalance. A transaction whose reference the user
+	// already has is not applied again; txn is set to the one applied before.
+	ApplyWalletTransaction(ctx context.Context, txn *model.WalletTransaction) error
+	GetWallet(ctx context.Context, userID string) (*model.Wallet, error)
+	ListWalletTransactions(ctx context.Context, userID string, page, limit int) ([]*model.WalletTransaction, int, error)
+}
+
+// WalletCharger charges the saved payment methods wallets are topped up from. It is
+// implemented by clients.PaymentGRPCClient.
+type WalletCharger interface {
+	ChargePaymentMethod(ctx context.Context, userID, paymentMethodID string, amount int64, description, idempotencyKey string) (string, error)
+}
+
+// WalletService lets users top up the balance they hold with us and see what it was
+// spent on. Orders are paid from it by OrderService.
+type WalletService struct {
+	pb.UnimplementedWalletServiceServer
+	repo    WalletRepository
+	methods PaymentMethodRepository
+	charger WalletCharger
+}
+
+// NewWalletService creates a new wallet service
+func NewWalletService(repo WalletRepository, methods PaymentMethodRepository, charger WalletCharger) *WalletService {
+	return &WalletService{
+		repo:    repo,
+		methods: methods,
+		charger: charger,
+	}
+}
+
+// GetWallet returns a user's balance
+func (s *WalletService) GetWallet(ctx context.Context, req *pb.GetWalletRequest) (*pb.WalletResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
+	}
+
+	wallet, err := s.repo.GetWallet(ctx, req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get wallet: %v", err)
+	}
+
+	return &pb.WalletResponse{
+		Wallet: convertWalletToProto(wallet),
+	}, nil
+}
+
+// TopUpWallet charges one of the user's saved payment methods, their default unless the
+// request names another, and adds the amount to their balance
+func (s *WalletService) TopUpWallet(ctx context.Context, req *pb.TopUpWalletRequest) (*pb.WalletTransactionResponse, error) {
+	if req.UserId == "" || req.IdempotencyKey == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID and idempotency key are required")
+	}
+	if req.Amount <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "top-up amount must be positive")
+	}
+
+	var method *model.SavedPaymentMethod
+	var err error
+	if req.PaymentMethodId != "" {
+		method, err = s.methods.GetPaymentMethod(ctx, req.UserId, req.PaymentMethodId)
+	} else {
+		method, err = s.methods.GetDefaultPaymentMethod(ctx, req.UserId)
+	}
+	if err != nil {
+		if req.PaymentMethodId == "" && errors.Is(err, repository.ErrPaymentMethodNotFound) {
+			return nil, status.Errorf(codes.FailedPrecondition, "user has no saved payment method to top up from")
+		}
+		return nil, paymentMethodError(err, "failed to get payment method")
+	}
+
+	now := time.Now()
+	if method.Expired(now) {
+		return nil, status.Errorf(codes.FailedPrecondition, "card ending %s has expired", method.Last4)
+	}
+
+	// The key makes both the charge and the credit happen once for a retried top-up
+	reference := "topup-" + req.IdempotencyKey
+	paymentID, err := s.charger.ChargePaymentMethod(ctx, req.UserId, method.ID, req.Amount, "Wallet top-up", reference+"-"+req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to charge payment method: %v", err)
+	}
+
+	txn := &model.WalletTransaction{
+		ID:          uuid.New().String(),
+		UserID:      req.UserId,
+		Type:        model.WalletTopUp,
+		Amount:      req.Amount,
+		PaymentID:   paymentID,
+		Reference:   reference,
+		Description: topUpDescription(method),
+		CreatedAt:   now,
+	}
+	if err := s.repo.ApplyWalletTransaction(ctx, txn); err != nil {
+		return nil, status.Errorf(codes.Internal, "charged as %s but failed to top up the wallet: %v", paymentID, err)
+	}
+
+	return &pb.WalletTransactionResponse{
+		Transaction: convertWalletTransactionToProto(txn),
+		Balance:     txn.BalanceAfter,
+		Message:     fmt.Sprintf("Wallet topped up with %s", money.Format(txn.Amount)),
+		Success:     true,
+	}, nil
+}
+
+// ListWalletTransactions lists a page of a user's wallet transactions, newest first
+func (s *WalletService) ListWalletTransactions(ctx context.Context, req *pb.ListWalletTransactionsRequest) (*pb.ListWalletTransactionsResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID is required")
+	}
+
+	txns, total, err := s.repo.ListWalletTransactions(ctx, req.UserId, int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list wallet transactions: %v", err)
+	}
+
+	protoTxns := make([]*pb.WalletTransaction, 0, len(txns))
+	for _, txn := range txns {
+		protoTxns = append(protoTxns, convertWalletTransactionToProto(txn))
+	}
+
+	return &pb.ListWalletTransactionsResponse{
+		Transactions: protoTxns,
+		Total:        int32(total),
+		Page:         req.Page,
+		Limit:        req.Limit,
+	}, nil
+}
+
+// CreditWallet gives a user credit as compensation, taking effect at once
+func (s *WalletService) CreditWallet(ctx context.Context, req *pb.CreditWalletRequest) (*pb.WalletTransactionResponse, error) {
+	if req.UserId == "" || req.CreditedBy == "" || req.Reason == "" || req.IdempotencyKey == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user ID, credited by, reason and idempotency key are required")
+	}
+	if req.Amount <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "credit amount must be positive")
+	}
+
+	txn := &model.WalletTransaction{
+		ID:          uuid.New().String(),
+		UserID:      req.UserId,
+		Type:        model.WalletCompensation,
+		Amount:      req.Amount,
+		OrderID:     req.OrderId,
+		Reference:   "credit-" + req.IdempotencyKey,
+		Description: fmt.Sprintf("%s (credited by %s)", req.Reason, req.CreditedBy),
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.ApplyWalletTransaction(ctx, txn); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to credit wallet: %v", err)
+	}
+
+	return &pb.WalletTransactionResponse{
+		Transaction: convertWalletTransactionToProto(txn),
+		Balance:     txn.BalanceAfter,
+		Message:     fmt.Sprintf("Wallet credited with %s", money.Format(txn.Amount)),
+		Success:     true,
+	}, nil
+}
+
+// topUpDescription names the payment method a top-up came from, as users know it
+func topUpDescription(method *model.SavedPaymentMethod) string {
+	if method.Last4 != "" {
+		return fmt.Sprintf("Top-up from %s ending %s", method.Brand, method.Last4)
+	}
+	return fmt.Sprintf("Top-up from %s wallet", method.Gateway)
+}
+
+func convertWalletToProto(wallet *model.Wallet) *pb.Wallet {
+	protoWallet := &pb.Wallet{
+		UserId:  wallet.UserID,
+		Balance: wallet.Balance,
+	}
+	if !wallet.UpdatedAt.IsZero() {
+		protoWallet.UpdatedAt = timestamppb.New(wallet.UpdatedAt)
+	}
+	return protoWallet
+}
+
+func convertWalletTransactionToProto(txn *model.WalletTransaction) *pb.WalletTransaction {
+	return &pb.WalletTransaction{
+		Id:           txn.ID,
+		Type:         string(txn.Type),
+		Amount:       txn.Amount,
+		BalanceAfter: txn.BalanceAfter,
+		OrderId:      txn.OrderID,
+		Description:  txn.Description,
+		CreatedAt:    timestamppb.New(txn.CreatedAt),
+	}
+}
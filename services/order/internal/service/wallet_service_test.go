@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	walletPb "github.com/order-api-microservices/proto/wallet"
+	"github.com/order-api-microservices/services/order/internal/repository/memory"
+	"github.com/order-api-microservices/services/order/internal/service"
+	"google.golang.org/grpc/codes"
+)
+
+// recordCharges records the idempotency key of every charge
+type recordCharges struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (c *recordCharges) ChargePaymentMethod(ctx context.Context, userID, paymentMethodID string, amount int64, description, idempotencyKey string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = append(c.keys, idempotencyKey)
+	return "payment-" + idempotencyKey, nil
+}
+
+func TestTopUpWalletCreditsOncePerKey(t *testing.T) {
+	ctx := context.Background()
+	methods := memory.NewPaymentMethodRepository()
+	card := saveTestCard(t, service.NewPaymentMethodService(methods), "fp-1", true)
+	charges := &recordCharges{}
+	s := service.NewWalletService(memory.NewWalletRepository(), methods, charges)
+
+	topUp := &walletPb.TopUpWalletRequest{UserId: testUserID, Amount: 50000, IdempotencyKey: "key-1"}
+	for i := 0; i < 2; i++ {
+		resp, err := s.TopUpWallet(ctx, topUp)
+		if err != nil {
+			t.Fatalf("TopUpWallet: %v", err)
+		}
+		if resp.Balance != 50000 {
+			t.Errorf("balance after top-up %d = %d, want 50000", i+1, resp.Balance)
+		}
+	}
+	if len(charges.keys) != 2 || charges.keys[0] != charges.keys[1] {
+		t.Errorf("charge keys = %v, want the same key twice", charges.keys)
+	}
+
+	// A new key tops up again, from the card named
+	topUp = &walletPb.TopUpWalletRequest{UserId: testUserID, Amount: 20000, PaymentMethodId: card.Id, IdempotencyKey: "key-2"}
+	if _, err := s.TopUpWallet(ctx, topUp); err != nil {
+		t.Fatalf("TopUpWallet: %v", err)
+	}
+
+	list, err := s.ListWalletTransactions(ctx, &walletPb.ListWalletTransactionsRequest{UserId: testUserID})
+	if err != nil {
+		t.Fatalf("ListWalletTransactions: %v", err)
+	}
+	if list.Total != 2 || list.Transactions[0].Amount != 20000 || list.Transactions[0].BalanceAfter != 70000 {
+		t.Errorf("transactions = %v, want the 20000 top-up first and 2 in all", list.Transactions)
+	}
+}
+
+func TestTopUpWalletNeedsASavedPaymentMethod(t *testing.T) {
+	s := service.NewWalletService(memory.NewWalletRepository(), memory.NewPaymentMethodRepository(), &recordCharges{})
+
+	_, err := s.TopUpWallet(context.Background(), &walletPb.TopUpWalletRequest{UserId: testUserID, Amount: 50000, IdempotencyKey: "key-1"})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	_, err = s.TopUpWallet(context.Background(), &walletPb.TopUpWalletRequest{UserId: testUserID, Amount: 50000, PaymentMethodId: "missing", IdempotencyKey: "key-1"})
+	wantCode(t, err, codes.NotFound)
+}
+
+func TestCreditWalletAsCompensation(t *testing.T) {
+	ctx := context.Background()
+	s := service.NewWalletService(memory.NewWalletRepository(), memory.NewPaymentMethodRepository(), &recordCharges{})
+
+	credit := &walletPb.CreditWalletRequest{UserId: testUserID, Amount: 15000, Reason: "Driver cancelled twice", CreditedBy: "admin-1", IdempotencyKey: "ticket-42"}
+	for i := 0; i < 2; i++ {
+		if _, err := s.CreditWallet(ctx, credit); err != nil {
+			t.Fatalf("CreditWallet: %v", err)
+		}
+	}
+
+	resp, err := s.GetWallet(ctx, &walletPb.GetWalletRequest{UserId: testUserID})
+	if err != nil {
+		t.Fatalf("GetWallet: %v", err)
+	}
+	if resp.Wallet.Balance != 15000 {
+		t.Errorf("balance = %d, want 15000 credited once", resp.Wallet.Balance)
+	}
+}
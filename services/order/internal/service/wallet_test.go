@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/order"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"google.golang.org/grpc/codes"
+)
+
+// fundWallet tops up the test user's wallet
+func fundWallet(t *testing.T, repos testRepos, amount int64) {
+	t.Helper()
+
+	err := repos.wallets.ApplyWalletTransaction(context.Background(), &model.WalletTransaction{
+		ID: "funding", UserID: testUserID, Type: model.WalletTopUp, Amount: amount, Reference: "funding", CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("ApplyWalletTransaction: %v", err)
+	}
+}
+
+// walletBalance is the test user's wallet balance
+func walletBalance(t *testing.T, repos testRepos) int64 {
+	t.Helper()
+
+	wallet, err := repos.wallets.GetWallet(context.Background(), testUserID)
+	if err != nil {
+		t.Fatalf("GetWallet: %v", err)
+	}
+	return wallet.Balance
+}
+
+func TestOrderPaidPartlyFromWalletHoldsTheRest(t *testing.T) {
+	ctx := context.Background()
+	payments := newHoldPayments()
+	s, repos := newTestOrderService(payments)
+	stockTestMerchant(t, repos, 5)
+	fundWallet(t, repos, 10000)
+
+	req := orderFromMerchant(1)
+	req.WalletAmount = 10000
+	resp, err := s.CreateOrder(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if resp.Order.WalletAmount != 10000 || resp.Order.PaymentAuthorization.Amount != resp.Order.TotalPrice-10000 {
+		t.Errorf("wallet paid %d and %d held, want 10000 and the rest of %d", resp.Order.WalletAmount, resp.Order.PaymentAuthorization.Amount, resp.Order.TotalPrice)
+	}
+	if balance := walletBalance(t, repos); balance != 0 {
+		t.Errorf("balance after ordering = %d, want 0", balance)
+	}
+
+	// Cancelling releases the hold and gives the wallet its part back at once
+	_, err = s.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: resp.Order.Id, CancelledBy: testUserID, Reason: "changed my mind"})
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if balance := walletBalance(t, repos); balance != 10000 {
+		t.Errorf("balance after cancelling = %d, want 10000", balance)
+	}
+	if len(payments.voided) != 1 {
+		t.Errorf("voided %v, want the hold voided", payments.voided)
+	}
+}
+
+func TestOrderPaidFromWalletNeedsTheBalance(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newTestOrderService(newHoldPayments())
+	stockTestMerchant(t, repos, 5)
+	fundWallet(t, repos, 5000)
+
+	req := orderFromMerchant(1)
+	req.WalletAmount = 25000
+	_, err := s.CreateOrder(ctx, req)
+	wantCode(t, err, codes.FailedPrecondition)
+
+	if balance := walletBalance(t, repos); balance != 5000 {
+		t.Errorf("balance = %d, want 5000", balance)
+	}
+	if left := stockLeft(t, repos); left != 5 {
+		t.Errorf("stock left = %d, want 5", left)
+	}
+}
+
+func TestOrderRefundsGoToTheWallet(t *testing.T) {
+	tests := []struct {
+		name         string
+		walletAmount int64 // More than the total pays all of it
+		toWallet     bool
+		wantRefunded bool // Through the payment service
+	}{
+		{name: "paid from the wallet", walletAmount: 1000000},
+		{name: "paid by card, refunded to the wallet", toWallet: true},
+		{name: "paid by card, refunded to the card", wantRefunded: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			payments := newHoldPayments()
+			s, repos := newTestOrderService(payments)
+			stockTestMerchant(t, repos, 5)
+			fundWallet(t, repos, 1000000)
+
+			req := orderFromMerchant(1)
+			req.WalletAmount = tt.walletAmount
+			resp, err := s.CreateOrder(ctx, req)
+			if err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+			total := resp.Order.TotalPrice
+			_, err = s.UpdateOrderStatus(ctx, &pb.UpdateOrderStatusRequest{OrderId: resp.Order.Id, Status: pb.OrderStatus_ORDER_STATUS_COMPLETED, UpdatedBy: testProviderID})
+			if err != nil {
+				t.Fatalf("UpdateOrderStatus: %v", err)
+			}
+
+			_, err = s.RefundOrder(ctx, &pb.RefundOrderRequest{OrderId: resp.Order.Id, RequestedBy: "admin", Reason: "cold food", ToWallet: tt.toWallet})
+			if err != nil {
+				t.Fatalf("RefundOrder: %v", err)
+			}
+
+			wantBalance := int64(1000000)
+			if tt.walletAmount == 0 && !tt.wantRefunded {
+				wantBalance += total
+			}
+			if balance := walletBalance(t, repos); balance != wantBalance {
+				t.Errorf("balance after refund = %d, want %d", balance, wantBalance)
+			}
+			if refunded := len(payments.refunded) > 0; refunded != tt.wantRefunded {
+				t.Errorf("refunded through the payment service %v, want %t", payments.refunded, tt.wantRefunded)
+			}
+		})
+	}
+}
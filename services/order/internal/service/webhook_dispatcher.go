@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/order-api-microservices/pkg/events"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+)
+
+// Headers sent with every webhook request
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// maxWebhookErrorLength caps how much of a failed response is kept in the delivery log
+const maxWebhookErrorLength = 500
+
+// WebhookConfig controls how webhook deliveries are sent and retried
+type WebhookConfig struct {
+	Interval    time.Duration // How often due deliveries are picked up
+	Timeout     time.Duration // How long a partner has to answer one request
+	MaxAttempts int           // After this many failed attempts a delivery is marked failed
+	BackoffBase time.Duration // Wait after the first failed attempt; doubles after each one
+	BackoffMax  time.Duration // Longest wait between attempts
+	BatchSize   int           // Most deliveries sent per interval
+}
+
+// WebhookDispatcher sends the webhook deliveries queued by the order repository to the
+// partners' callback URLs. Requests are signed with the subscription's secret, and failed
+// deliveries are retried with exponential backoff.
+type WebhookDispatcher struct {
+	webhookRepo *repository.WebhookRepository
+	client      *http.Client
+	cfg         WebhookConfig
+}
+
+// NewWebhookDispatcher creates a new webhook dispatcher
+func NewWebhookDispatcher(webhookRepo *repository.WebhookRepository, cfg WebhookConfig) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		webhookRepo: webhookRepo,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		cfg:         cfg,
+	}
+}
+
+// Run sends due deliveries every interval until ctx is cancelled
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Claimed deliveries are leased for longer than it can take to send them all,
+			// so a crashed dispatcher's deliveries are picked up again afterwards
+			now := time.Now()
+			lease := now.Add(d.cfg.Timeout*time.Duration(d.cfg.BatchSize) + d.cfg.Interval)
+			deliveries, err := d.webhookRepo.ClaimDueDeliveries(ctx, now, lease, d.cfg.BatchSize)
+			if err != nil {
+				log.Printf("Failed to claim webhook deliveries: %v", err)
+				continue
+			}
+			for _, delivery := range deliveries {
+				if err := d.Deliver(ctx, delivery); err != nil {
+					log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// Deliver makes one attempt at sending a delivery and records its outcome. A 2xx answer
+// delivers it; anything else schedules a retry until the attempts run out.
+func (d *WebhookDispatcher) Deliver(ctx context.Context, delivery *model.WebhookDelivery) error {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	code, err := d.send(ctx, delivery, now)
+	delivery.ResponseCode = code
+	switch {
+	case err == nil:
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = model.WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+		log.Printf("Webhook delivery %s to %s failed after %d attempts: %v", delivery.ID, delivery.URL, delivery.Attempts, err)
+	default:
+		delivery.Status = model.WebhookDeliveryPending
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	return d.webhookRepo.RecordDeliveryAttempt(ctx, delivery)
+}
+
+// send posts a delivery's payload to its subscription's URL and returns the response code
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *model.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", events.ContentTypeJSON)
+	req.Header.Set(WebhookEventHeader, string(delivery.EventType))
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorLength))
+		return resp.StatusCode, fmt.Errorf("partner answered %d: %s", resp.StatusCode, body)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff is how long to wait after the given number of failed attempts: the base wait
+// doubled for each attempt after the first, capped, less up to a fifth at random so
+// deliveries that failed together are not all retried together
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.BackoffMax
+	if attempts < 32 {
+		if doubled := d.cfg.BackoffBase << (attempts - 1); doubled > 0 && doubled < wait {
+			wait = doubled
+		}
+	}
+	return wait - time.Duration(rand.Int63n(int64(wait)/5+1))
+}
+
+// SignWebhook computes the signature partners use to check a webhook came from us: the
+// hex HMAC-SHA256, keyed with the subscription's secret, of the timestamp header, a dot
+// and the request body. Signing the timestamp lets partners reject replayed requests.
+func SignWebhook(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/order-api-microservices/proto/webhook"
+	"github.com/order-api-microservices/services/order/internal/model"
+	"github.com/order-api-microservices/services/order/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// webhookSecretBytes is the length of a webhook's random signing secret
+const webhookSecretBytes = 32
+
+// WebhookService lets partners register callback URLs for order events and inspect
+// or replay what was delivered to them
+type WebhookService struct {
+	pb.UnimplementedWebhookServiceServer
+	repo *repository.WebhookRepository
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(repo *repository.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		repo: repo,
+	}
+}
+
+// CreateWebhook registers a callback URL. The signing secret is returned only here;
+// partners verify every request with it.
+func (s *WebhookService) CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.WebhookResponse, error) {
+	if req.PartnerId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "partner ID is required")
+	}
+	eventTypes, orderTypes, err := parseWebhookFilters(req.Url, req.EventTypes, req.OrderTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate webhook secret: %v", err)
+	}
+
+	now := time.Now()
+	sub := &model.WebhookSubscription{
+		ID:         uuid.New().String(),
+		PartnerID:  req.PartnerId,
+		URL:        req.Url,
+		Secret:     hex.EncodeToString(secret),
+		EventTypes: eventTypes,
+		OrderTypes: orderTypes,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create webhook: %v", err)
+	}
+
+	return &pb.WebhookResponse{
+		Webhook: convertWebhookToProto(sub),
+		Secret:  sub.Secret,
+		Success: true,
+		Message: "Webhook created",
+	}, nil
+}
+
+// GetWebhook gets a webhook subscription
+func (s *WebhookService) GetWebhook(ctx context.Context, req *pb.GetWebhookRequest) (*pb.WebhookResponse, error) {
+	sub, err := s.getWebhook(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.WebhookResponse{
+		Webhook: convertWebhookToProto(sub),
+		Success: true,
+		Message: "Webhook retrieved successfully",
+	}, nil
+}
+
+// ListWebhooks lists a partner's webhook subscriptions, newest first
+func (s *WebhookService) ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	subs, err := s.repo.ListSubscriptions(ctx, req.PartnerId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list webhooks: %v", err)
+	}
+
+	protoSubs := make([]*pb.Webhook, 0, len(subs))
+	for _, sub := range subs {
+		protoSubs = append(protoSubs, convertWebhookToProto(sub))
+	}
+
+	return &pb.ListWebhooksResponse{
+		Webhooks: protoSubs,
+	}, nil
+}
+
+// UpdateWebhook replaces a webhook's URL and event filters, or pauses and resumes it.
+// Events raised while a webhook is paused are still queued and are sent once it resumes.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, req *pb.UpdateWebhookRequest) (*pb.WebhookResponse, error) {
+	sub, err := s.getWebhook(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.EventTypes, sub.OrderTypes, err = parseWebhookFilters(req.Url, req.EventTypes, req.OrderTypes); err != nil {
+		return nil, err
+	}
+	sub.URL = req.Url
+	sub.Active = req.Active
+	sub.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateSubscription(ctx, sub); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return nil, status.Errorf(codes.NotFound, "webhook not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update webhook: %v", err)
+	}
+
+	return &pb.WebhookResponse{
+		Webhook: convertWebhookToProto(sub),
+		Success: true,
+		Message: "Webhook updated",
+	}, nil
+}
+
+// DeleteWebhook removes a webhook subscription and its delivery log
+func (s *WebhookService) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	if req.Id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "webhook ID is required")
+	}
+
+	if err := s.repo.DeleteSubscription(ctx, req.Id); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return nil, status.Errorf(codes.NotFound, "webhook not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete webhook: %v", err)
+	}
+
+	return &pb.DeleteWebhookResponse{
+		Success: true,
+		Message: "Webhook deleted",
+	}, nil
+}
+
+// ListWebhookDeliveries lists the events queued for a webhook and the outcome of
+// sending them, newest first
+func (s *WebhookService) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	if _, err := s.getWebhook(ctx, req.WebhookId); err != nil {
+		return nil, err
+	}
+
+	deliveryStatus := model.WebhookDeliveryStatus(req.Status)
+	switch deliveryStatus {
+	case "", model.WebhookDeliveryPending, model.WebhookDeliveryDelivered, model.WebhookDeliveryFailed:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "status must be PENDING, DELIVERED or FAILED")
+	}
+
+	deliveries, total, err := s.repo.ListDeliveries(ctx, req.WebhookId, deliveryStatus, int(req.Page), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list webhook deliveries: %v", err)
+	}
+
+	protoDeliveries := make([]*pb.WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		protoDeliveries = append(protoDeliveries, convertWebhookDeliveryToProto(delivery))
+	}
+
+	return &pb.ListWebhookDeliveriesResponse{
+		Deliveries: protoDeliveries,
+		Total:      int32(total),
+		Page:       req.Page,
+		Limit:      req.Limit,
+	}, nil
+}
+
+// RedeliverWebhook queues a delivery to be sent again on the dispatcher's next pass,
+// whether it was delivered, failed or is still being retried
+func (s *WebhookService) RedeliverWebhook(ctx context.Context, req *pb.RedeliverWebhookRequest) (*pb.WebhookDeliveryResponse, error) {
+	if req.WebhookId == "" || req.DeliveryId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "webhook ID and delivery ID are required")
+	}
+
+	if err := s.repo.RedeliverDelivery(ctx, req.WebhookId, req.DeliveryId, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrWebhookDeliveryNotFound) {
+			return nil, status.Errorf(codes.NotFound, "webhook delivery not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to redeliver webhook: %v", err)
+	}
+
+	delivery, err := s.repo.GetDelivery(ctx, req.WebhookId, req.DeliveryId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get webhook delivery: %v", err)
+	}
+
+	return &pb.WebhookDeliveryResponse{
+		Delivery: convertWebhookDeliveryToProto(delivery),
+		Success:  true,
+		Message:  "Webhook delivery queued",
+	}, nil
+}
+
+func (s *WebhookService) getWebhook(ctx context.Context, id string) (*model.WebhookSubscription, error) {
+	if id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "webhook ID is required")
+	}
+
+	sub, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return nil, status.Errorf(codes.NotFound, "webhook not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get webhook: %v", err)
+	}
+
+	return sub, nil
+}
+
+// parseWebhookFilters validates a callback URL and converts the event and order type
+// names a webhook subscribes to
+func parseWebhookFilters(callbackURL string, eventNames, orderNames []string) ([]model.WebhookEventType, []model.OrderType, error) {
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "URL must be an absolute http or https URL")
+	}
+
+	if len(eventNames) == 0 {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "at least one event type is required")
+	}
+	eventTypes := make([]model.WebhookEventType, 0, len(eventNames))
+	for _, name := range eventNames {
+		eventType, ok := parseWebhookEventType(name)
+		if !ok {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "unknown event type %q", name)
+		}
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	orderTypes := make([]model.OrderType, 0, len(orderNames))
+	for _, name := range orderNames {
+		orderType, err := parseFeeOrderType(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if orderType == "" {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "order type cannot be empty")
+		}
+		orderTypes = append(orderTypes, orderType)
+	}
+
+	return eventTypes, orderTypes, nil
+}
+
+func parseWebhookEventType(name string) (model.WebhookEventType, bool) {
+	for _, eventType := range model.WebhookEventTypes {
+		if string(eventType) == name {
+			return eventType, true
+		}
+	}
+	return "", false
+}
+
+func convertWebhookToProto(sub *model.WebhookSubscription) *pb.Webhook {
+	eventTypes := make([]string, len(sub.EventTypes))
+	for i, eventType := range sub.EventTypes {
+		eventTypes[i] = string(eventType)
+	}
+	orderTypes := make([]string, len(sub.OrderTypes))
+	for i, orderType := range sub.OrderTypes {
+		orderTypes[i] = string(orderType)
+	}
+
+	return &pb.Webhook{
+		Id:         sub.ID,
+		PartnerId:  sub.PartnerID,
+		Url:        sub.URL,
+		EventTypes: eventTypes,
+		OrderTypes: orderTypes,
+		Active:     sub.Active,
+		CreatedAt:  timestamppb.New(sub.CreatedAt),
+		UpdatedAt:  timestamppb.New(sub.UpdatedAt),
+	}
+}
+
+func convertWebhookDeliveryToProto(delivery *model.WebhookDelivery) *pb.WebhookDelivery {
+	protoDelivery := &pb.WebhookDelivery{
+		Id:            delivery.ID,
+		WebhookId:     delivery.SubscriptionID,
+		EventId:       delivery.EventID,
+		EventType:     string(delivery.EventType),
+		Payload:       string(delivery.Payload),
+		Status:        string(delivery.Status),
+		Attempts:      int32(delivery.Attempts),
+		NextAttemptAt: timestamppb.New(delivery.NextAttemptAt),
+		ResponseCode:  int32(delivery.ResponseCode),
+		LastError:     delivery.LastError,
+		CreatedAt:     timestamppb.New(delivery.CreatedAt),
+	}
+	if delivery.LastAttemptAt != nil {
+		protoDelivery.LastAttemptAt = timestamppb.New(*delivery.LastAttemptAt)
+	}
+	if delivery.DeliveredAt != nil {
+		protoDelivery.DeliveredAt = timestamppb.New(*delivery.DeliveredAt)
+	}
+	return protoDelivery
+}
@@ -8,9 +8,14 @@ CREATE TABLE IF NOT EXISTS orders (
     pickup_location JSONB NOT NULL,
     destination_location JSONB NOT NULL,
     items JSONB NOT NULL,
-    total_price NUMERIC(10, 2) NOT NULL,
-    platform_fee NUMERIC(10, 2) NOT NULL,
-    provider_fee NUMERIC(10, 2) NOT NULL,
+    total_price BIGINT NOT NULL,
+    platform_fee BIGINT NOT NULL,
+    provider_fee BIGINT NOT NULL,
+    tip_amount BIGINT NOT NULL DEFAULT 0,
+    cancellation_fee BIGINT NOT NULL DEFAULT 0,
+    delivery_proof_hash VARCHAR(64),
+    frozen BOOLEAN NOT NULL DEFAULT FALSE,
+    pickup_arrived_at TIMESTAMP,
     transaction_id VARCHAR(100),
     blockchain_tx_hash VARCHAR(100),
     payment_method VARCHAR(20) NOT NULL,
@@ -20,16 +25,106 @@ CREATE TABLE IF NOT EXISTS orders (
     status_history JSONB NOT NULL
 );
 
--- Create order_locations table for tracking
+-- Add columns introduced after the initial schema
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tip_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancellation_fee BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_proof_hash VARCHAR(64);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS frozen BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS pickup_arrived_at TIMESTAMP;
+-- Set when the user's personal data is erased; the order's amounts and hashes are kept
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP;
+-- The saved payment method the order is paid with; orders paid otherwise have none
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_method_id VARCHAR(36);
+-- The part of the total paid from the user's wallet balance
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS wallet_amount BIGINT NOT NULL DEFAULT 0;
+-- Loyalty points redeemed on the order, and what they took off total_price
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS points_redeemed BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS points_discount BIGINT NOT NULL DEFAULT 0;
+-- Set once a stage of the order misses its SLA
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS at_risk BOOLEAN NOT NULL DEFAULT FALSE;
+-- The delivered order a RETURN order sends goods back from; not a foreign key, since the
+-- original may be archived first
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS return_of_order_id VARCHAR(36);
+
+-- order_locations used to be a single table. It is renamed out of the way, and its rows
+-- are copied into the partitioned table once the partitions are created below.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_class WHERE relname = 'order_locations' AND relkind = 'r') THEN
+        ALTER TABLE order_locations RENAME TO order_locations_unpartitioned;
+        DROP INDEX IF EXISTS idx_order_locations_order_id;
+        DROP INDEX IF EXISTS idx_order_locations_provider_id;
+        DROP INDEX IF EXISTS idx_order_locations_timestamp;
+        DROP INDEX IF EXISTS idx_order_locations_spatial;
+    END IF;
+END
+$$;
+
+-- Create order_locations table for tracking, partitioned by month so expired months are
+-- dropped whole instead of deleted row by row. Locations outside every month's partition
+-- go to order_locations_default.
 CREATE TABLE IF NOT EXISTS order_locations (
-    id VARCHAR(36) PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
     order_id VARCHAR(36) NOT NULL,
     provider_id VARCHAR(36) NOT NULL,
     latitude DOUBLE PRECISION NOT NULL,
     longitude DOUBLE PRECISION NOT NULL,
     timestamp TIMESTAMP NOT NULL,
+    PRIMARY KEY (id, timestamp),
     FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
-);
+) PARTITION BY RANGE (timestamp);
+
+CREATE TABLE IF NOT EXISTS order_locations_default PARTITION OF order_locations DEFAULT;
+
+-- create_order_locations_partition creates the partition of order_locations for the month
+-- holding a day, named order_locations_YYYY_MM, unless it exists. Locations of that month
+-- already in the default partition are moved into it. The location retention job calls
+-- it for the coming months.
+CREATE OR REPLACE FUNCTION create_order_locations_partition(day DATE)
+RETURNS VOID AS $fn$
+DECLARE
+    month_start TIMESTAMP := date_trunc('month', day);
+    month_end TIMESTAMP := date_trunc('month', day) + INTERVAL '1 month';
+    partition_name TEXT := 'order_locations_' || to_char(day, 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN;
+    END IF;
+
+    EXECUTE format('CREATE TABLE %I (LIKE order_locations INCLUDING DEFAULTS)', partition_name);
+    EXECUTE format(
+        'WITH moved AS (DELETE FROM order_locations_default WHERE timestamp >= %L AND timestamp < %L RETURNING *) '
+        'INSERT INTO %I SELECT * FROM moved',
+        month_start, month_end, partition_name);
+    EXECUTE format('ALTER TABLE order_locations ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+        partition_name, month_start, month_end);
+END
+$fn$ LANGUAGE plpgsql;
+
+-- Create the partitions from the oldest location up to two months ahead, then copy the
+-- locations of the unpartitioned table into them
+DO $$
+DECLARE
+    oldest TIMESTAMP := NOW();
+    month DATE;
+BEGIN
+    IF to_regclass('order_locations_unpartitioned') IS NOT NULL THEN
+        EXECUTE 'SELECT COALESCE(MIN(timestamp), NOW()) FROM order_locations_unpartitioned' INTO oldest;
+    END IF;
+
+    FOR month IN SELECT generate_series(date_trunc('month', oldest), date_trunc('month', NOW()) + INTERVAL '2 months', INTERVAL '1 month')::DATE
+    LOOP
+        PERFORM create_order_locations_partition(month);
+    END LOOP;
+
+    IF to_regclass('order_locations_unpartitioned') IS NOT NULL THEN
+        INSERT INTO order_locations (id, order_id, provider_id, latitude, longitude, timestamp)
+        SELECT id, order_id, provider_id, latitude, longitude, timestamp
+        FROM order_locations_unpartitioned;
+        DROP TABLE order_locations_unpartitioned;
+    END IF;
+END
+$$;
 
 -- Create indexes for faster queries
 CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
@@ -37,6 +132,9 @@ CREATE INDEX IF NOT EXISTS idx_orders_provider_id ON orders(provider_id);
 CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
 CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at);
 CREATE INDEX IF NOT EXISTS idx_orders_updated_at ON orders(updated_at);
+-- An order has at most one return that was not cancelled
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_open_return ON orders(return_of_order_id)
+    WHERE return_of_order_id IS NOT NULL AND status <> 'CANCELLED';
 
 -- Create indexes for order_locations
 CREATE INDEX IF NOT EXISTS idx_order_locations_order_id ON order_locations(order_id);
@@ -71,4 +169,947 @@ BEGIN
         CREATE INDEX IF NOT EXISTS idx_order_locations_spatial ON order_locations USING GIST(location);
     END IF;
 END
-$$; 
\ No newline at end of file
+$$; 
+
+-- Create disputes table
+CREATE TABLE IF NOT EXISTS disputes (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    opened_by VARCHAR(36) NOT NULL,
+    opened_by_role VARCHAR(20) NOT NULL,
+    reason VARCHAR(100) NOT NULL,
+    description TEXT,
+    status VARCHAR(20) NOT NULL,
+    resolution VARCHAR(20),
+    refund_amount BIGINT NOT NULL DEFAULT 0,
+    resolved_by VARCHAR(36),
+    resolution_notes TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+-- Only one dispute may be open per order
+CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_open_order ON disputes(order_id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_disputes_order_id ON disputes(order_id);
+CREATE INDEX IF NOT EXISTS idx_disputes_status ON disputes(status);
+
+-- Create dispute_evidence table
+CREATE TABLE IF NOT EXISTS dispute_evidence (
+    id VARCHAR(36) PRIMARY KEY,
+    dispute_id VARCHAR(36) NOT NULL,
+    submitted_by VARCHAR(36) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    description TEXT NOT NULL,
+    attachment_url TEXT,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (dispute_id) REFERENCES disputes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispute_evidence_dispute_id ON dispute_evidence(dispute_id);
+
+-- Create payment_holds table; a hold freezes the order's payment while a dispute is open
+CREATE TABLE IF NOT EXISTS payment_holds (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    dispute_id VARCHAR(36) NOT NULL,
+    amount BIGINT NOT NULL,
+    refunded_amount BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    released_at TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (dispute_id) REFERENCES disputes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_holds_order_id ON payment_holds(order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_holds_dispute_id ON payment_holds(dispute_id);
+
+-- Create refunds table; one row per refund issued through the payment service
+CREATE TABLE IF NOT EXISTS refunds (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    amount BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    requested_by VARCHAR(36) NOT NULL,
+    dispute_id VARCHAR(36),
+    payment_refund_id VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_order_id ON refunds(order_id);
+-- The part of a refund credited to the user's wallet; payment_refund_id is empty when
+-- that is all of it
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS wallet_amount BIGINT NOT NULL DEFAULT 0;
+
+-- Create provider_ledger_entries table; the provider's payout ledger of fares and tips
+CREATE TABLE IF NOT EXISTS provider_ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    provider_id VARCHAR(36) NOT NULL,
+    order_id VARCHAR(36) NOT NULL,
+    entry_type VARCHAR(20) NOT NULL,
+    amount BIGINT NOT NULL,
+    payment_id VARCHAR(100),
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+-- An order pays its fare once; tips are limited to one per order by the service
+CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_ledger_fare ON provider_ledger_entries(order_id) WHERE entry_type = 'FARE';
+-- The providers an order was handed off from are each credited their share of its fare once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_ledger_handoff_fare ON provider_ledger_entries(order_id, provider_id) WHERE entry_type = 'HANDOFF_FARE';
+CREATE INDEX IF NOT EXISTS idx_provider_ledger_provider_id ON provider_ledger_entries(provider_id, created_at);
+
+-- Create dispatch_weights table; a single row holding the matcher's scoring weights
+CREATE TABLE IF NOT EXISTS dispatch_weights (
+    id SMALLINT PRIMARY KEY CHECK (id = 1),
+    distance_weight DOUBLE PRECISION NOT NULL,
+    rating_weight DOUBLE PRECISION NOT NULL,
+    idle_weight DOUBLE PRECISION NOT NULL,
+    earnings_weight DOUBLE PRECISION NOT NULL,
+    favorite_weight DOUBLE PRECISION NOT NULL DEFAULT 0.2,
+    updated_by VARCHAR(36) NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE dispatch_weights ADD COLUMN IF NOT EXISTS favorite_weight DOUBLE PRECISION NOT NULL DEFAULT 0.2;
+
+-- Create dispatch_decisions table for auditing the fairness of dispatch
+CREATE TABLE IF NOT EXISTS dispatch_decisions (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    selected_provider_id VARCHAR(36) NOT NULL,
+    auto_accepted BOOLEAN NOT NULL DEFAULT false,
+    weights JSONB NOT NULL,
+    candidates JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispatch_decisions_order_id ON dispatch_decisions(order_id);
+CREATE INDEX IF NOT EXISTS idx_dispatch_decisions_provider ON dispatch_decisions(selected_provider_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_dispatch_decisions_created_at ON dispatch_decisions(created_at);
+
+-- Create dispatch_offers table; each order offered to a provider and how they answered it.
+-- Offers outlive their order's row, so they do not reference it.
+CREATE TABLE IF NOT EXISTS dispatch_offers (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    provider_id VARCHAR(36) NOT NULL,
+    offered_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    acknowledged_at TIMESTAMP,
+    response VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    responded_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispatch_offers_order ON dispatch_offers(order_id, provider_id);
+CREATE INDEX IF NOT EXISTS idx_dispatch_offers_provider ON dispatch_offers(provider_id, offered_at);
+CREATE INDEX IF NOT EXISTS idx_dispatch_offers_pending ON dispatch_offers(expires_at) WHERE response = 'PENDING';
+
+-- Create service_areas table; while any area is active, orders are only taken inside one
+CREATE TABLE IF NOT EXISTS service_areas (
+    id VARCHAR(36) PRIMARY KEY,
+    city VARCHAR(100) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    boundary JSONB NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_areas_city ON service_areas(city);
+
+-- Create merchants table; the restaurants and shops food and grocery orders are placed with
+CREATE TABLE IF NOT EXISTS merchants (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    city VARCHAR(100) NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION NOT NULL DEFAULT 0,
+    longitude DOUBLE PRECISION NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchants_city ON merchants(city);
+
+-- Create catalog_items table; each merchant's menu, which prices the orders placed with it
+CREATE TABLE IF NOT EXISTS catalog_items (
+    id VARCHAR(36) PRIMARY KEY,
+    merchant_id VARCHAR(36) NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    name VARCHAR(200) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    category VARCHAR(100) NOT NULL DEFAULT '',
+    price BIGINT NOT NULL CHECK (price >= 0),
+    available BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_items_merchant_id ON catalog_items(merchant_id);
+
+-- Catalog items with a stock count can only be ordered while units are left
+ALTER TABLE catalog_items ADD COLUMN IF NOT EXISTS stock INTEGER CHECK (stock >= 0);
+
+-- Create stock_reservations table; stock taken off the catalog for orders that could still give it back.
+-- Holds are taken before their order is stored, so there is no foreign key to orders.
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    order_id VARCHAR(36) NOT NULL,
+    item_id VARCHAR(36) NOT NULL REFERENCES catalog_items(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (order_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_expires_at ON stock_reservations(expires_at);
+
+-- Create user_provider_preferences table; the providers each user has favorited or blocked
+CREATE TABLE IF NOT EXISTS user_provider_preferences (
+    user_id VARCHAR(36) NOT NULL,
+    provider_id VARCHAR(36) NOT NULL,
+    preference VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, provider_id)
+);
+
+-- Create order_batches table; a provider carries a batch's delivery orders on one trip
+CREATE TABLE IF NOT EXISTS order_batches (
+    id VARCHAR(36) PRIMARY KEY,
+    provider_id VARCHAR(36) NOT NULL,
+    order_ids TEXT[] NOT NULL,
+    stops JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_batches_order_ids ON order_batches USING GIN(order_ids);
+
+-- Create order_rentals table; the hourly booking behind each RENTAL order
+CREATE TABLE IF NOT EXISTS order_rentals (
+    order_id VARCHAR(36) PRIMARY KEY,
+    hourly_rate BIGINT NOT NULL,
+    overtime_rate BIGINT NOT NULL,
+    booked_hours INTEGER NOT NULL,
+    started_at TIMESTAMP,
+    ended_at TIMESTAMP,
+    overtime_minutes INTEGER NOT NULL DEFAULT 0,
+    overtime_charge BIGINT NOT NULL DEFAULT 0,
+    overtime_payment_id VARCHAR(100),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+-- Create rental_extensions table; users ask for more hours and providers respond
+CREATE TABLE IF NOT EXISTS rental_extensions (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    hours INTEGER NOT NULL,
+    amount BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    payment_id VARCHAR(100),
+    requested_at TIMESTAMP NOT NULL,
+    responded_at TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES order_rentals(order_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_rental_extensions_order_id ON rental_extensions(order_id, requested_at);
+-- A rental has at most one extension waiting for its provider
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rental_extensions_pending ON rental_extensions(order_id) WHERE status = 'PENDING';
+
+-- Create destination_changes table; riders ask to go somewhere else mid-trip and
+-- providers respond. The destination's address and instructions are encrypted like an
+-- order's.
+CREATE TABLE IF NOT EXISTS destination_changes (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    destination_location JSONB NOT NULL,
+    fare BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    payment_id VARCHAR(100),
+    requested_at TIMESTAMP NOT NULL,
+    responded_at TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_destination_changes_order_id ON destination_changes(order_id, requested_at);
+-- An order has at most one destination change waiting for its provider
+CREATE UNIQUE INDEX IF NOT EXISTS idx_destination_changes_pending ON destination_changes(order_id) WHERE status = 'PENDING';
+
+-- Create order_handoffs table; active orders moved to another provider mid-delivery. The
+-- fare is split between the providers by the share of the trip each made.
+CREATE TABLE IF NOT EXISTS order_handoffs (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    from_provider_id VARCHAR(36) NOT NULL,
+    to_provider_id VARCHAR(36) NOT NULL,
+    requested_by VARCHAR(100) NOT NULL,
+    reason TEXT NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    trip_progress DOUBLE PRECISION NOT NULL,
+    handed_off_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_handoffs_order_id ON order_handoffs(order_id, handed_off_at);
+CREATE INDEX IF NOT EXISTS idx_order_handoffs_from_provider ON order_handoffs(from_provider_id, handed_off_at);
+
+-- Create payment_methods table; the cards and wallets users saved, as tokens of the
+-- payment gateway, never card numbers
+CREATE TABLE IF NOT EXISTS payment_methods (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    method_type VARCHAR(20) NOT NULL,
+    gateway VARCHAR(20) NOT NULL,
+    gateway_token VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(100) NOT NULL DEFAULT '',
+    brand VARCHAR(20) NOT NULL DEFAULT '',
+    last4 VARCHAR(4) NOT NULL DEFAULT '',
+    exp_month INTEGER NOT NULL DEFAULT 0,
+    exp_year INTEGER NOT NULL DEFAULT 0,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_methods_user_id ON payment_methods(user_id, created_at);
+-- A user has at most one default method, and saves each card once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_methods_default ON payment_methods(user_id) WHERE is_default;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_methods_fingerprint ON payment_methods(user_id, gateway, fingerprint) WHERE fingerprint <> '';
+
+-- Create payment_authorizations table; the payment held for an order when it was placed,
+-- until it is captured or released. Rows outlive archived orders, as payment records.
+CREATE TABLE IF NOT EXISTS payment_authorizations (
+    order_id VARCHAR(36) PRIMARY KEY,
+    authorization_id VARCHAR(100) NOT NULL,
+    amount BIGINT NOT NULL,
+    captured_amount BIGINT NOT NULL DEFAULT 0,
+    payment_id VARCHAR(100) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    settled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- The authorization sweeper settles holds about to lapse
+CREATE INDEX IF NOT EXISTS idx_payment_authorizations_lapsing ON payment_authorizations(expires_at) WHERE status = 'AUTHORIZED';
+
+-- Create wallets table; the balance each user holds with us, which can pay for orders.
+-- Users get a row with their first wallet transaction.
+CREATE TABLE IF NOT EXISTS wallets (
+    user_id VARCHAR(36) PRIMARY KEY,
+    balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Create wallet_transactions table; every change to a wallet balance, credits positive
+-- and debits negative
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    amount BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    order_id VARCHAR(36),
+    payment_id VARCHAR(100) NOT NULL DEFAULT '',
+    reference VARCHAR(200) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_user_id ON wallet_transactions(user_id, created_at DESC);
+-- A retried change carries the same reference, so it is applied once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallet_transactions_reference ON wallet_transactions(user_id, reference);
+
+-- Create referral_codes table; the code each user shares with friends, created the first
+-- time they ask for it
+CREATE TABLE IF NOT EXISTS referral_codes (
+    user_id VARCHAR(36) PRIMARY KEY,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Create referrals table; who referred each referred user, and whether it earned points
+CREATE TABLE IF NOT EXISTS referrals (
+    referred_user_id VARCHAR(36) PRIMARY KEY,
+    referrer_id VARCHAR(36) NOT NULL,
+    code VARCHAR(20) NOT NULL,
+    device_id VARCHAR(200) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    reject_reason TEXT NOT NULL DEFAULT '',
+    order_id VARCHAR(36),
+    payment_fingerprint VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    settled_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_referrals_referrer_id ON referrals(referrer_id);
+-- A device, or a card paying for the first order, is only good for one referral
+CREATE UNIQUE INDEX IF NOT EXISTS idx_referrals_device_id ON referrals(device_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_referrals_payment_fingerprint ON referrals(payment_fingerprint) WHERE status = 'REWARDED' AND payment_fingerprint <> '';
+
+-- Create points_accounts table; the loyalty points each user holds. Users get a row with
+-- their first points transaction.
+CREATE TABLE IF NOT EXISTS points_accounts (
+    user_id VARCHAR(36) PRIMARY KEY,
+    balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Create points_transactions table; every change to a points balance, earned points
+-- positive and redeemed ones negative
+CREATE TABLE IF NOT EXISTS points_transactions (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    points BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    order_id VARCHAR(36),
+    reference VARCHAR(200) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_points_transactions_user_id ON points_transactions(user_id, created_at DESC);
+-- A retried change carries the same reference, so it is applied once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_points_transactions_reference ON points_transactions(user_id, reference);
+
+-- Create payment_shares table; one row per payer of a split order payment
+CREATE TABLE IF NOT EXISTS payment_shares (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    percentage NUMERIC(5, 2) NOT NULL,
+    amount BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    payment_id VARCHAR(100),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_shares_order_id ON payment_shares(order_id);
+CREATE INDEX IF NOT EXISTS idx_payment_shares_status ON payment_shares(status);
+
+-- Create delivery_proofs table; an order is delivered once
+CREATE TABLE IF NOT EXISTS delivery_proofs (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL UNIQUE,
+    provider_id VARCHAR(36) NOT NULL,
+    photo_ref VARCHAR(500),
+    signature_hash VARCHAR(64),
+    proof_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+-- Create delivery_pins table; only a hash of each PIN is stored
+CREATE TABLE IF NOT EXISTS delivery_pins (
+    order_id VARCHAR(36) PRIMARY KEY,
+    pin_hash VARCHAR(64) NOT NULL,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    issued_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+-- Create order_vehicles table; the vehicle each order was accepted in, as it was then
+CREATE TABLE IF NOT EXISTS order_vehicles (
+    order_id VARCHAR(36) PRIMARY KEY,
+    provider_id VARCHAR(36) NOT NULL,
+    vehicle_id VARCHAR(36) NOT NULL,
+    plate VARCHAR(20) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    recorded_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_vehicles_provider_id ON order_vehicles(provider_id);
+
+-- Create chat_messages table; updated_at changes when a message is read
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    sender_id VARCHAR(36) NOT NULL,
+    sender_role VARCHAR(20) NOT NULL,
+    body TEXT NOT NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_order_created ON chat_messages(order_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_order_updated ON chat_messages(order_id, updated_at);
+
+-- Create contact_tokens table; only a hash of each token is stored
+CREATE TABLE IF NOT EXISTS contact_tokens (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    participant_id VARCHAR(36) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_tokens_order_id ON contact_tokens(order_id);
+
+-- Create tracking_links table for sharing an order's live tracking; only a hash of each token is stored
+CREATE TABLE IF NOT EXISTS tracking_links (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    created_by VARCHAR(36) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tracking_links_order_id ON tracking_links(order_id);
+
+-- Create incidents table; an order is frozen while any of its incidents is open
+CREATE TABLE IF NOT EXISTS incidents (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    reported_by VARCHAR(36) NOT NULL,
+    reporter_role VARCHAR(20) NOT NULL,
+    description TEXT,
+    location JSONB,
+    status VARCHAR(20) NOT NULL,
+    resolved_by VARCHAR(36),
+    resolution_notes TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_order_id ON incidents(order_id);
+CREATE INDEX IF NOT EXISTS idx_incidents_status ON incidents(status);
+
+-- Create route_deviations table; an order has at most one active deviation
+CREATE TABLE IF NOT EXISTS route_deviations (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    provider_id VARCHAR(36) NOT NULL,
+    distance_km DOUBLE PRECISION NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    detected_at TIMESTAMP NOT NULL,
+    cleared_at TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_route_deviations_active_order ON route_deviations(order_id) WHERE cleared_at IS NULL;
+
+-- An order's deviations, active and cleared, are listed on its timeline
+CREATE INDEX IF NOT EXISTS idx_route_deviations_order_started ON route_deviations(order_id, started_at);
+
+-- Create order_tracks table; a finished order's locations compressed into an encoded polyline
+CREATE TABLE IF NOT EXISTS order_tracks (
+    order_id VARCHAR(36) PRIMARY KEY,
+    provider_id VARCHAR(36) NOT NULL,
+    polyline TEXT NOT NULL,
+    point_count INTEGER NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP NOT NULL,
+    archived_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+-- Create fee_rules table; an empty order type or city matches any
+CREATE TABLE IF NOT EXISTS fee_rules (
+    id VARCHAR(36) PRIMARY KEY,
+    order_type VARCHAR(20) NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    platform_fee_percent DOUBLE PRECISION NOT NULL,
+    provider_fee_percent DOUBLE PRECISION NOT NULL,
+    min_platform_fee BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (order_type, city)
+);
+
+-- Create fee_waivers table for promotions that waive the platform fee
+CREATE TABLE IF NOT EXISTS fee_waivers (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    order_type VARCHAR(20) NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_fee_waivers_ends_at ON fee_waivers(ends_at);
+
+-- Migrate money columns from NUMERIC currency units to BIGINT minor units (cents).
+-- Each table is converted once; the column type shows whether it already has been.
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'orders' AND column_name = 'total_price') = 'numeric' THEN
+        ALTER TABLE orders
+            ALTER COLUMN total_price TYPE BIGINT USING ROUND(total_price * 100),
+            ALTER COLUMN platform_fee TYPE BIGINT USING ROUND(platform_fee * 100),
+            ALTER COLUMN provider_fee TYPE BIGINT USING ROUND(provider_fee * 100),
+            ALTER COLUMN tip_amount TYPE BIGINT USING ROUND(tip_amount * 100);
+
+        -- Item prices are stored inside the items JSON
+        UPDATE orders SET items = (
+            SELECT COALESCE(jsonb_agg(item || jsonb_build_object('price', ROUND((item->>'price')::NUMERIC * 100))), '[]'::JSONB)
+            FROM jsonb_array_elements(items) AS item
+        );
+    END IF;
+
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'disputes' AND column_name = 'refund_amount') = 'numeric' THEN
+        ALTER TABLE disputes ALTER COLUMN refund_amount TYPE BIGINT USING ROUND(refund_amount * 100);
+    END IF;
+
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'payment_holds' AND column_name = 'amount') = 'numeric' THEN
+        ALTER TABLE payment_holds
+            ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100),
+            ALTER COLUMN refunded_amount TYPE BIGINT USING ROUND(refunded_amount * 100);
+    END IF;
+
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'refunds' AND column_name = 'amount') = 'numeric' THEN
+        ALTER TABLE refunds ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100);
+    END IF;
+
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'provider_ledger_entries' AND column_name = 'amount') = 'numeric' THEN
+        ALTER TABLE provider_ledger_entries ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100);
+    END IF;
+
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'payment_shares' AND column_name = 'amount') = 'numeric' THEN
+        ALTER TABLE payment_shares ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100);
+    END IF;
+END
+$$;
+
+-- Create audit_log table; an append-only record of every mutating call. Snapshots are JSON,
+-- not JSONB, so hash-chained entries read back byte for byte as they were hashed.
+CREATE TABLE IF NOT EXISTS audit_log (
+    seq BIGSERIAL PRIMARY KEY,
+    service VARCHAR(50) NOT NULL,
+    method VARCHAR(200) NOT NULL,
+    actor_id VARCHAR(100) NOT NULL DEFAULT '',
+    source_ip VARCHAR(100) NOT NULL DEFAULT '',
+    resource_type VARCHAR(50) NOT NULL DEFAULT '',
+    resource_id VARCHAR(100) NOT NULL DEFAULT '',
+    before JSON,
+    after JSON,
+    diff JSON,
+    status_code VARCHAR(30) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    prev_hash VARCHAR(64),
+    hash VARCHAR(64)
+);
+
+-- The admin who made a call as the actor, and the support session it was made in
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS impersonated_by VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS support_session_id VARCHAR(36) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_support_session_id ON audit_log(support_session_id, seq) WHERE support_session_id <> '';
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log(resource_type, resource_id, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+
+-- Reject any change to recorded entries
+CREATE OR REPLACE FUNCTION reject_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trig_audit_log_append_only ON audit_log;
+CREATE TRIGGER trig_audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_log_change();
+
+-- Create denylist_entries table; the users, API keys and networks the gateway refuses to
+-- serve. API keys are stored as their SHA-256.
+CREATE TABLE IF NOT EXISTS denylist_entries (
+    id VARCHAR(36) PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    value VARCHAR(200) NOT NULL,
+    reason TEXT NOT NULL,
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    blocked_count BIGINT NOT NULL DEFAULT 0,
+    last_blocked_at TIMESTAMP,
+    last_blocked_request VARCHAR(500) NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_denylist_entries_kind_value ON denylist_entries(kind, value);
+
+-- Create support_sessions table; the time-limited tokens with which admins act on behalf
+-- of a user. Only a hash of each token is kept.
+CREATE TABLE IF NOT EXISTS support_sessions (
+    id VARCHAR(36) PRIMARY KEY,
+    admin_id VARCHAR(100) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    reason TEXT NOT NULL,
+    scope VARCHAR(20) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    ended_by VARCHAR(100) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_support_sessions_user_id ON support_sessions(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_support_sessions_admin_id ON support_sessions(admin_id, created_at DESC);
+
+-- Create webhook_subscriptions table; the callback URLs partners registered and the
+-- events sent to each
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id VARCHAR(36) PRIMARY KEY,
+    partner_id VARCHAR(36) NOT NULL,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    event_types TEXT[] NOT NULL,
+    order_types TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_partner_id ON webhook_subscriptions(partner_id);
+
+-- Create webhook_deliveries table; every event queued for a subscription, written in the
+-- same transaction as the change it describes, with the outcome of its attempts
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id VARCHAR(36) PRIMARY KEY,
+    subscription_id VARCHAR(36) NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id VARCHAR(36) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_attempt_at TIMESTAMP,
+    response_code INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at);
+
+-- Create bulk_order_jobs table; batches of orders uploaded at once by B2B customers
+CREATE TABLE IF NOT EXISTS bulk_order_jobs (
+    id VARCHAR(36) PRIMARY KEY,
+    customer_id VARCHAR(36) NOT NULL,
+    source VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    total_rows INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    lease_until TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_order_jobs_status ON bulk_order_jobs(status, created_at);
+
+-- Create bulk_order_rows table; each uploaded order and the result of importing it. The
+-- request holds addresses, so it is encrypted like the order's and cleared once processed.
+CREATE TABLE IF NOT EXISTS bulk_order_rows (
+    job_id VARCHAR(36) NOT NULL REFERENCES bulk_order_jobs(id) ON DELETE CASCADE,
+    row_number INTEGER NOT NULL,
+    request TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    order_id VARCHAR(36),
+    errors TEXT[] NOT NULL DEFAULT '{}',
+    processed_at TIMESTAMP,
+    PRIMARY KEY (job_id, row_number)
+);
+
+-- Create order_events table; domain events about orders, written in the same transaction
+-- as the change they describe and consumed once by the analytics aggregator
+CREATE TABLE IF NOT EXISTS order_events (
+    seq BIGSERIAL PRIMARY KEY,
+    order_id VARCHAR(36) NOT NULL,
+    event_type VARCHAR(20) NOT NULL,
+    order_type VARCHAR(50) NOT NULL,
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    status VARCHAR(50) NOT NULL,
+    previous_status VARCHAR(50) NOT NULL DEFAULT '',
+    total_price BIGINT NOT NULL DEFAULT 0,
+    platform_fee BIGINT NOT NULL DEFAULT 0,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    aggregated_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_events_unaggregated ON order_events(seq) WHERE aggregated_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_order_events_order_id ON order_events(order_id);
+
+-- The order's provider when the event happened, and who made the change, so provider
+-- quality can be worked out from the events
+ALTER TABLE order_events ADD COLUMN IF NOT EXISTS provider_id VARCHAR(36) NOT NULL DEFAULT '';
+ALTER TABLE order_events ADD COLUMN IF NOT EXISTS updated_by TEXT NOT NULL DEFAULT '';
+-- The stage an SLA_MET or SLA_BREACHED event is about
+ALTER TABLE order_events ADD COLUMN IF NOT EXISTS sla_stage VARCHAR(20) NOT NULL DEFAULT '';
+
+-- Create analytics_daily table; each day's order aggregates per city and order type
+CREATE TABLE IF NOT EXISTS analytics_daily (
+    day DATE NOT NULL,
+    city VARCHAR(100) NOT NULL,
+    order_type VARCHAR(50) NOT NULL,
+    orders_created BIGINT NOT NULL DEFAULT 0,
+    orders_completed BIGINT NOT NULL DEFAULT 0,
+    orders_cancelled BIGINT NOT NULL DEFAULT 0,
+    revenue BIGINT NOT NULL DEFAULT 0,
+    platform_revenue BIGINT NOT NULL DEFAULT 0,
+    eta_error_minutes_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    eta_samples BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, city, order_type)
+);
+
+-- Create analytics_cancellation_reasons table; how often each normalized cancellation
+-- reason was given per day, city and order type
+CREATE TABLE IF NOT EXISTS analytics_cancellation_reasons (
+    day DATE NOT NULL,
+    city VARCHAR(100) NOT NULL,
+    order_type VARCHAR(50) NOT NULL,
+    reason VARCHAR(100) NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, city, order_type, reason)
+);
+
+-- Create analytics_sla_daily table; how many order stages met and missed their SLA per
+-- day, city, order type and stage
+CREATE TABLE IF NOT EXISTS analytics_sla_daily (
+    day DATE NOT NULL,
+    city VARCHAR(100) NOT NULL,
+    order_type VARCHAR(50) NOT NULL,
+    stage VARCHAR(20) NOT NULL,
+    met BIGINT NOT NULL DEFAULT 0,
+    breached BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, city, order_type, stage)
+);
+
+-- Create sla_results table; the outcome of each stage of an order against its SLA, kept
+-- once per stage so the stage is counted once
+CREATE TABLE IF NOT EXISTS sla_results (
+    order_id VARCHAR(36) NOT NULL,
+    stage VARCHAR(20) NOT NULL,
+    outcome VARCHAR(20) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    deadline TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    recorded_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (order_id, stage)
+);
+
+-- Create provider_quality_daily table; each day's aggregates of how reliably each provider
+-- served the orders they took, folded from the order events with the daily analytics
+CREATE TABLE IF NOT EXISTS provider_quality_daily (
+    day DATE NOT NULL,
+    provider_id VARCHAR(36) NOT NULL,
+    orders_completed BIGINT NOT NULL DEFAULT 0,
+    orders_abandoned BIGINT NOT NULL DEFAULT 0,
+    pickups_timed BIGINT NOT NULL DEFAULT 0,
+    pickups_on_time BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, provider_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_quality_daily_provider ON provider_quality_daily(provider_id, day);
+
+-- Create orders_archive table; finished orders move here from orders once they are old, so
+-- orders stays small. It is partitioned by the year orders were created in, and the
+-- archival job creates each year's partition before moving orders into it. Columns added
+-- to orders must be added here too.
+CREATE TABLE IF NOT EXISTS orders_archive (
+    LIKE orders INCLUDING DEFAULTS,
+    archived_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE INDEX IF NOT EXISTS idx_orders_archive_user_id ON orders_archive(user_id);
+
+-- Columns added to orders after orders_archive was created
+ALTER TABLE orders_archive ADD COLUMN IF NOT EXISTS payment_method_id VARCHAR(36);
+ALTER TABLE orders_archive ADD COLUMN IF NOT EXISTS wallet_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders_archive ADD COLUMN IF NOT EXISTS points_redeemed BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders_archive ADD COLUMN IF NOT EXISTS points_discount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders_archive ADD COLUMN IF NOT EXISTS at_risk BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE orders_archive ADD COLUMN IF NOT EXISTS return_of_order_id VARCHAR(36);
+
+-- Records about an order outlive its row in orders, so they no longer reference it. Its
+-- raw locations, delivery PIN, contact tokens and tracking links are deleted with it.
+DO $$
+DECLARE
+    dependent TEXT;
+BEGIN
+    FOREACH dependent IN ARRAY ARRAY[
+        'disputes', 'payment_holds', 'refunds', 'provider_ledger_entries', 'dispatch_decisions',
+        'order_rentals', 'payment_shares', 'delivery_proofs', 'chat_messages', 'incidents',
+        'route_deviations', 'order_tracks', 'order_vehicles', 'order_handoffs'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I DROP CONSTRAINT IF EXISTS %I', dependent, dependent || '_order_id_fkey');
+    END LOOP;
+END $$;
+
+-- Notify the order cache of every order updated or deleted, with the order's ID
+CREATE OR REPLACE FUNCTION notify_order_changed()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('order_changed', OLD.id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trig_orders_notify_changed ON orders;
+CREATE TRIGGER trig_orders_notify_changed
+AFTER UPDATE OR DELETE ON orders
+FOR EACH ROW EXECUTE FUNCTION notify_order_changed();
+
+DROP TRIGGER IF EXISTS trig_orders_archive_notify_changed ON orders_archive;
+CREATE TRIGGER trig_orders_archive_notify_changed
+AFTER UPDATE OR DELETE ON orders_archive
+FOR EACH ROW EXECUTE FUNCTION notify_order_changed();
+
+-- Create jobs table; background work such as blockchain writes and notifications, retried until dead
+CREATE TABLE IF NOT EXISTS jobs (
+    id VARCHAR(36) PRIMARY KEY,
+    service VARCHAR(50) NOT NULL,
+    kind VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(service, status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_kind ON jobs(service, kind, status);
@@ -8,12 +8,23 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/order-api-microservices/pkg/audit"
+	"github.com/order-api-microservices/pkg/chaos"
+	"github.com/order-api-microservices/pkg/crypto"
 	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/grpcserver"
+	"github.com/order-api-microservices/pkg/metrics"
+	"github.com/order-api-microservices/services/provider/internal/clients"
+	"github.com/order-api-microservices/services/provider/internal/model"
 	"github.com/order-api-microservices/services/provider/internal/repository"
 	"github.com/order-api-microservices/services/provider/internal/service"
+	auditPb "github.com/order-api-microservices/proto/audit"
+	chaosPb "github.com/order-api-microservices/proto/chaos"
 	pb "github.com/order-api-microservices/proto/provider"
 	"google.golang.org/grpc"
 )
@@ -26,9 +37,31 @@ func main() {
 	dbPassword := flag.String("db-password", getEnv("DB_PASSWORD", "postgres"), "Database password")
 	dbName := flag.String("db-name", getEnv("DB_NAME", "providerdb"), "Database name")
 	dbSSLMode := flag.String("db-sslmode", getEnv("DB_SSLMODE", "disable"), "Database SSL mode")
+	dbSlowQueryThreshold := flag.Duration("db-slow-query-threshold", getEnvDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond), "Queries taking longer than this are logged with their arguments sanitized (0 logs none)")
 	
 	notificationServiceAddr := flag.String("notification-service", getEnv("NOTIFICATION_SERVICE", "localhost:50054"), "Notification service address")
 	port := flag.Int("port", getEnvInt("PORT", 50053), "Server port")
+	metricsPort := flag.Int("metrics-port", getEnvInt("METRICS_PORT", 9093), "Metrics server port")
+	piiKeysFile := flag.String("pii-keys-file", getEnv("PII_KEYS_FILE", ""), "File of keys that encrypt providers' email and phone, one id=base64key per line with the primary first; empty stores them in plaintext")
+	auditHashChain := flag.Bool("audit-hash-chain", getEnv("AUDIT_HASH_CHAIN", "") == "true", "Chain each audit log entry to the one before it with a hash, so rewriting the log is detectable")
+	piiReencryptBatch := flag.Int("pii-reencrypt-batch", getEnvInt("PII_REENCRYPT_BATCH", 100), "Providers re-encrypted at a time after a key rotation")
+	grpcAuthToken := flag.String("grpc-auth-token", getEnv("GRPC_AUTH_TOKEN", ""), "Token gRPC callers must present, and that this service presents to the services it calls; empty turns authentication off")
+	grpcDefaultTimeout := flag.Duration("grpc-default-timeout", getEnvDuration("GRPC_DEFAULT_TIMEOUT", 30*time.Second), "Deadline of gRPC calls that arrive without one (0 leaves them without)")
+	grpcMaxTimeout := flag.Duration("grpc-max-timeout", getEnvDuration("GRPC_MAX_TIMEOUT", 2*time.Minute), "Longest deadline a gRPC call may have (0 leaves it uncapped)")
+	grpcClientTimeouts := flag.String("grpc-client-timeouts", getEnv("GRPC_CLIENT_TIMEOUTS", ""), "Timeouts of calls to other services that replace the built-in ones, as method=duration pairs separated by commas, such as /notification.NotificationService/SendNotification=3s")
+	grpcClientReserve := flag.Duration("grpc-client-reserve", getEnvDuration("GRPC_CLIENT_RESERVE", 200*time.Millisecond), "Time kept back from a call's own deadline to handle replies from the services it calls")
+	heartbeatTimeout := flag.Duration("heartbeat-timeout", getEnvDuration("HEARTBEAT_TIMEOUT", 90*time.Second), "How long an available provider's app may send neither a heartbeat nor a location before they are marked unavailable (0 turns it off)")
+	heartbeatSweepInterval := flag.Duration("heartbeat-sweep-interval", getEnvDuration("HEARTBEAT_SWEEP_INTERVAL", 15*time.Second), "How often providers without a recent heartbeat are looked for")
+	heartbeatSweepBatch := flag.Int("heartbeat-sweep-batch", getEnvInt("HEARTBEAT_SWEEP_BATCH", 500), "Most providers marked unavailable per query")
+	suspensionMinRating := flag.Float64("suspension-min-rating", getEnvFloat("SUSPENSION_MIN_RATING", 3.0), "Rated providers below this rating are suspended (0 turns the rule off)")
+	suspensionMinCompletion := flag.Int("suspension-min-completion", getEnvInt("SUSPENSION_MIN_COMPLETION", 70), "Providers who completed a smaller percentage of the orders they took are suspended (0 turns the rule off)")
+	suspensionMinOrders := flag.Int("suspension-min-orders", getEnvInt("SUSPENSION_MIN_ORDERS", 20), "Orders a provider must have finished before their completion rate can suspend them")
+	suspensionMaxIncidents := flag.Int("suspension-max-incidents", getEnvInt("SUSPENSION_MAX_INCIDENTS", 3), "SOS incidents raised by users on a provider's orders that suspend them (0 turns the rule off)")
+	suspensionDuration := flag.Duration("suspension-duration", getEnvDuration("SUSPENSION_DURATION", 7*24*time.Hour), "How long an automatic suspension lasts")
+	suspensionCooldown := flag.Duration("suspension-cooldown", getEnvDuration("SUSPENSION_COOLDOWN", 7*24*time.Hour), "How long after a suspension ends before the provider can be suspended again")
+	suspensionInterval := flag.Duration("suspension-interval", getEnvDuration("SUSPENSION_INTERVAL", time.Hour), "How often providers are checked against the suspension rules (0 turns automatic suspension off)")
+	suspensionBatch := flag.Int("suspension-batch", getEnvInt("SUSPENSION_BATCH", 100), "Most providers suspended per transaction")
+	chaosEnabled := flag.Bool("chaos-enabled", getEnv("CHAOS_ENABLED", "") == "true", "Let admins inject latency and errors into calls to other services, for resilience testing in staging")
 	
 	flag.Parse()
 
@@ -41,6 +74,7 @@ func main() {
 		*dbName,
 		*dbSSLMode,
 	)
+	dbConfig.SlowQueryThreshold = *dbSlowQueryThreshold
 	
 	db, err := database.NewPostgresDB(dbConfig)
 	if err != nil {
@@ -48,15 +82,86 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize repository
-	providerRepo := repository.NewProviderRepository(db)
+	// Load the keys that encrypt personal data at rest
+	keyRing, err := crypto.LoadKeyRing(*piiKeysFile)
+	if err != nil {
+		log.Fatalf("Failed to load PII encryption keys: %v", err)
+	}
+
+	// Initialize repositories
+	providerRepo := repository.NewProviderRepository(db, keyRing)
+	preferencesRepo := repository.NewPreferencesRepository(db)
+
+	// Bound calls to other services by the deadline of the call that makes them
+	clientMethodTimeouts, err := deadline.ParseMethods(*grpcClientTimeouts)
+	if err != nil {
+		log.Fatalf("Failed to parse gRPC client timeouts: %v", err)
+	}
+	clientTimeouts := deadline.Timeouts{Methods: clientMethodTimeouts, Reserve: *grpcClientReserve}
+
+	// Inject faults into calls to other services only when enabled, and then only once
+	// they are set through the admin API
+	clientOpts := []grpc.DialOption{grpcserver.WithToken(*grpcAuthToken)}
+	var faultInjector *chaos.Injector
+	if *chaosEnabled {
+		faultInjector = chaos.NewInjector()
+		clientOpts = append(clientOpts, faultInjector.DialOption(), faultInjector.StreamDialOption())
+		log.Printf("Fault injection enabled")
+	}
+
+	// Initialize clients
+	notificationClient, err := clients.NewNotificationGRPCClient(*notificationServiceAddr, clientTimeouts, clientOpts...)
+	if err != nil {
+		log.Fatalf("Failed to connect to notification service: %v", err)
+	}
+	defer notificationClient.Close()
 
-	// For simplicity, we're not implementing the notification client in this example
-	// In a real implementation, you would connect to the notification service here
-	var notificationClient service.NotificationClient = nil
+	// Encrypt contact details stored in plaintext or under a retired key
+	if keyRing != nil {
+		go func() {
+			err := crypto.Reencrypt(context.Background(), "provider contacts", func(ctx context.Context) (int, error) {
+				return providerRepo.ReencryptProviders(ctx, *piiReencryptBatch)
+			})
+			if err != nil {
+				log.Printf("Failed to re-encrypt provider contacts: %v", err)
+			}
+		}()
+	}
+
+	// Expose metrics, including downstream circuit breaker state
+	metrics.Serve(*metricsPort)
 
 	// Initialize service
-	providerService := service.NewProviderService(providerRepo, notificationClient)
+	providerService := service.NewProviderService(providerRepo, preferencesRepo, notificationClient, *heartbeatTimeout)
+
+	// Take providers whose app went silent out of matching
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	defer stopMonitor()
+	if *heartbeatTimeout > 0 {
+		presenceMonitor := service.NewPresenceMonitor(providerRepo, notificationClient, service.PresenceConfig{
+			Timeout:   *heartbeatTimeout,
+			Interval:  *heartbeatSweepInterval,
+			BatchSize: *heartbeatSweepBatch,
+		})
+		go presenceMonitor.Run(monitorCtx)
+	}
+
+	// Suspend providers who fall below the rating, completion or incident thresholds
+	if *suspensionInterval > 0 {
+		suspensionEngine := service.NewSuspensionEngine(providerRepo, notificationClient, service.SuspensionConfig{
+			Rules: model.SuspensionRules{
+				MinRating:     *suspensionMinRating,
+				MinCompletion: float64(*suspensionMinCompletion) / 100,
+				MinOrders:     *suspensionMinOrders,
+				MaxIncidents:  *suspensionMaxIncidents,
+			},
+			Duration:  *suspensionDuration,
+			Cooldown:  *suspensionCooldown,
+			Interval:  *suspensionInterval,
+			BatchSize: *suspensionBatch,
+		})
+		go suspensionEngine.Run(monitorCtx)
+	}
 
 	// Set up gRPC server
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
@@ -64,8 +169,22 @@ func main() {
 		log.Fatalf("Failed to listen on port %d: %v", *port, err)
 	}
 
-	grpcServer := grpc.NewServer()
+	// Record every mutating call except location pings, which provider_locations already
+	// keeps, and heartbeats, which only say the app is online
+	auditLog := audit.NewLog(db, "provider", *auditHashChain)
+	grpcServer := grpcserver.New(grpcserver.Config{
+		AuthToken:      *grpcAuthToken,
+		DefaultTimeout: *grpcDefaultTimeout,
+		MaxTimeout:     *grpcMaxTimeout,
+		UnaryInterceptors: []grpc.UnaryServerInterceptor{
+			audit.UnaryServerInterceptor(auditLog, service.NewProviderAuditSnapshotter(providerRepo), "UpdateLocation", "Heartbeat", "UpdateQuality"),
+		},
+	})
 	pb.RegisterProviderServiceServer(grpcServer, providerService)
+	auditPb.RegisterAuditServiceServer(grpcServer, audit.NewServer(auditLog))
+	if faultInjector != nil {
+		chaosPb.RegisterChaosServiceServer(grpcServer, chaos.NewServer(faultInjector))
+	}
 
 	// Handle graceful shutdown
 	go func() {
@@ -74,6 +193,7 @@ func main() {
 		
 		<-signals
 		log.Println("Received signal, stopping server...")
+		stopMonitor()
 		
 		// Give connections time to drain
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -117,10 +237,40 @@ func getEnvInt(key string, defaultValue int) int {
 		return defaultValue
 	}
 	
-	intValue, err := fmt.Sscanf(value, "%d")
-	if err != nil || len(intValue) == 0 {
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
 		return defaultValue
 	}
 	
-	return intValue[0]
-} 
\ No newline at end of file
+	return intValue
+} 
+
+// Helper function to get environment variables as floats
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return floatValue
+}
+
+// Helper function to get environment variables as durations
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	
+	return duration
+}
@@ -0,0 +1,86 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/order-api-microservices/pkg/breaker"
+	"github.com/order-api-microservices/pkg/deadline"
+	"github.com/order-api-microservices/pkg/requestid"
+	pb "github.com/order-api-microservices/proto/notification"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// notificationTimeouts are the timeouts of calls to the notification service, unless configured otherwise
+var notificationTimeouts = deadline.Timeouts{Default: 10 * time.Second}
+
+// NotificationGRPCClient is a client for the notification service
+type NotificationGRPCClient struct {
+	client pb.NotificationServiceClient
+	conn   *grpc.ClientConn
+}
+
+// NewNotificationGRPCClient creates a new notification service client. Its calls take the
+// timeouts of notificationTimeouts merged with timeouts, and are dialled with any extra opts.
+func NewNotificationGRPCClient(address string, timeouts deadline.Timeouts, opts ...grpc.DialOption) (*NotificationGRPCClient, error) {
+	conn, err := grpc.Dial(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		deadline.DialOption(notificationTimeouts.Merge(timeouts)),
+		requestid.DialOption(),
+		breaker.DialOption("notification", breaker.DefaultConfig()),
+	}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to notification service: %v", err)
+	}
+
+	client := pb.NewNotificationServiceClient(conn)
+	return &NotificationGRPCClient{
+		client: client,
+		conn:   conn,
+	}, nil
+}
+
+// Close closes the connection to the notification service
+func (c *NotificationGRPCClient) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// SendNotification sends a notification to a provider
+func (c *NotificationGRPCClient) SendNotification(ctx context.Context, recipientID, notificationType string, payload interface{}) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %v", err)
+	}
+
+	// Create the request
+	req := &pb.SendNotificationRequest{
+		RecipientId:      recipientID,
+		RecipientType:    "PROVIDER",
+		NotificationType: notificationType,
+		Payload:          payloadBytes,
+	}
+	if details, ok := payload.(map[string]interface{}); ok {
+		if orderID, ok := details["order_id"].(string); ok {
+			req.ReferenceId = orderID
+		}
+	}
+
+	// Call the service
+	resp, err := c.client.SendNotification(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %v", err)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("notification service failed to send notification: %s", resp.Message)
+	}
+
+	return nil
+}
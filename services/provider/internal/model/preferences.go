@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Preferences filter the orders a provider is offered. Zero values mean no preference.
+type Preferences struct {
+	ProviderID         string    `json:"provider_id"`
+	AutoAcceptRadiusKm float64   `json:"auto_accept_radius_km"` // Orders picked up within this distance are accepted without asking
+	OrderTypes         []string  `json:"order_types"`           // Service types the provider wants; empty means all of theirs
+	MinFare            int64     `json:"min_fare"`              // Smallest provider fee worth taking, in minor units
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for the Preferences model
+func (Preferences) TableName() string {
+	return "provider_preferences"
+}
@@ -1 +1,152 @@
- 
\ No newline at end of file
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Provider represents a service provider in the system
+type Provider struct {
+	ID                  string          `json:"id"`
+	Name                string          `json:"name"`
+	Email               string          `json:"email"`
+	Phone               string          `json:"phone"`
+	Rating              float64         `json:"rating"`
+	ServiceTypes        ServiceTypes    `json:"service_types"`
+	Location            Location        `json:"location"`
+	IsAvailable         bool            `json:"is_available"`
+	MaxConcurrentOrders int             `json:"max_concurrent_orders"` // Cap on active orders of any type; 0 leaves only the per-type limits
+	ServiceAreaIDs      []string        `json:"service_area_ids"`      // Service areas the provider is registered to work in
+	LastHeartbeatAt     *time.Time      `json:"last_heartbeat_at"`     // When the provider's app last showed it was online; nil if never
+	Quality             Quality         `json:"quality"`               // How reliably the provider serves orders
+	VehicleCapacity     VehicleCapacity `json:"vehicle_capacity"`      // What the provider's vehicle can carry; zero if never given
+	ActiveVehicleID     string          `json:"active_vehicle_id"`     // The registered vehicle the provider drives this shift; empty if none
+	ProfileImage        string          `json:"profile_image"`
+	Metadata            Metadata        `json:"metadata"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+// TableName returns the table name for the Provider model
+func (Provider) TableName() string {
+	return "providers"
+}
+
+// Quality is how reliably a provider served orders recently, as last worked out by the
+// order service. The zero value means it never was.
+type Quality struct {
+	AcceptanceRate float64   `json:"acceptance_rate"`
+	CompletionRate float64   `json:"completion_rate"`
+	OnTimeRate     float64   `json:"on_time_rate"`
+	OffersAnswered int       `json:"offers_answered"`
+	OrdersFinished int       `json:"orders_finished"`
+	PickupsTimed   int       `json:"pickups_timed"`
+	Incidents      int       `json:"incidents"` // SOS incidents users raised on the provider's orders
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Value implements the driver.Valuer interface for JSON serialization
+func (q Quality) Value() (driver.Value, error) {
+	return json.Marshal(q)
+}
+
+// Scan implements the sql.Scanner interface for JSON deserialization
+func (q *Quality) Scan(value interface{}) error {
+	if value == nil {
+		*q = Quality{}
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, q)
+}
+
+// VehicleCapacity is the heaviest load and the largest cargo space a provider's vehicle
+// takes. The zero value means the provider never gave it.
+type VehicleCapacity struct {
+	MaxWeightGrams int `json:"max_weight_grams"`
+	LengthCm       int `json:"length_cm"`
+	WidthCm        int `json:"width_cm"`
+	HeightCm       int `json:"height_cm"`
+}
+
+// Value implements the driver.Valuer interface for JSON serialization
+func (c VehicleCapacity) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface for JSON deserialization
+func (c *VehicleCapacity) Scan(value interface{}) error {
+	if value == nil {
+		*c = VehicleCapacity{}
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, c)
+}
+
+// Location represents a geographical location
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Address   string  `json:"address"`
+}
+
+// Value implements the driver.Valuer interface for JSON serialization
+func (l Location) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+// Scan implements the sql.Scanner interface for JSON deserialization
+func (l *Location) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, l)
+}
+
+// ServiceTypes is the list of service types a provider offers
+type ServiceTypes []string
+
+// Value implements the driver.Valuer interface for JSON serialization
+func (s ServiceTypes) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan implements the sql.Scanner interface for JSON deserialization
+func (s *ServiceTypes) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, s)
+}
+
+// Metadata holds free-form details about a provider, such as their vehicle
+type Metadata map[string]string
+
+// Value implements the driver.Valuer interface for JSON serialization
+func (m Metadata) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+// Scan implements the sql.Scanner interface for JSON deserialization
+func (m *Metadata) Scan(value interface{}) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, m)
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// SuspensionRule names the threshold a provider fell below to be suspended
+type SuspensionRule string
+
+// Suspension rules
+const (
+	SuspensionLowRating     SuspensionRule = "LOW_RATING"
+	SuspensionLowCompletion SuspensionRule = "LOW_COMPLETION"
+	SuspensionIncidents     SuspensionRule = "INCIDENTS"
+)
+
+// SuspensionRules are the thresholds below which providers are suspended. A zero
+// threshold turns its rule off.
+type SuspensionRules struct {
+	MinRating     float64 // Rated providers below this rating are suspended
+	MinCompletion float64 // Providers who completed a smaller share of the orders they took are suspended
+	MinOrders     int     // Orders a provider must have finished before their completion rate counts
+	MaxIncidents  int     // Providers with this many SOS incidents raised on their orders are suspended
+}
+
+// Violation returns the first rule a provider breaks and why, or false if they break none
+func (r SuspensionRules) Violation(provider *Provider) (SuspensionRule, string, bool) {
+	if r.MinRating > 0 && provider.Rating > 0 && provider.Rating < r.MinRating {
+		return SuspensionLowRating, fmt.Sprintf("rating %.2f is below %.2f", provider.Rating, r.MinRating), true
+	}
+	quality := provider.Quality
+	if r.MinCompletion > 0 && quality.OrdersFinished > 0 && quality.OrdersFinished >= r.MinOrders && quality.CompletionRate < r.MinCompletion {
+		return SuspensionLowCompletion, fmt.Sprintf("completed %.0f%% of %d orders, below %.0f%%",
+			quality.CompletionRate*100, quality.OrdersFinished, r.MinCompletion*100), true
+	}
+	if r.MaxIncidents > 0 && quality.Incidents >= r.MaxIncidents {
+		return SuspensionIncidents, fmt.Sprintf("%d SOS incidents raised on their orders", quality.Incidents), true
+	}
+	return "", "", false
+}
+
+// Suspension takes a provider out of matching until it expires
+type Suspension struct {
+	ID          string         `json:"id"`
+	ProviderID  string         `json:"provider_id"`
+	Rule        SuspensionRule `json:"rule"`
+	Reason      string         `json:"reason"`
+	SuspendedAt time.Time      `json:"suspended_at"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+}
+
+// TableName returns the table name for the Suspension model
+func (Suspension) TableName() string {
+	return "provider_suspensions"
+}
+
+// Active reports whether the suspension is in force at a time
+func (s *Suspension) Active(at time.Time) bool {
+	return at.Before(s.ExpiresAt)
+}
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// VehicleType is the kind of vehicle a provider drives
+type VehicleType string
+
+// Vehicle types
+const (
+	VehicleBicycle    VehicleType = "BICYCLE"
+	VehicleMotorcycle VehicleType = "MOTORCYCLE"
+	VehicleCar        VehicleType = "CAR"
+	VehicleVan        VehicleType = "VAN"
+	VehicleTruck      VehicleType = "TRUCK"
+)
+
+// ValidVehicleType reports whether t is a known vehicle type
+func ValidVehicleType(t string) bool {
+	switch VehicleType(t) {
+	case VehicleBicycle, VehicleMotorcycle, VehicleCar, VehicleVan, VehicleTruck:
+		return true
+	default:
+		return false
+	}
+}
+
+// Vehicle is a vehicle a provider has registered. A provider picks one of theirs when
+// they start a shift, and it is used for the orders they take until the shift ends.
+type Vehicle struct {
+	ID                 string          `json:"id"`
+	ProviderID         string          `json:"provider_id"`
+	Plate              string          `json:"plate"`
+	Type               VehicleType     `json:"type"`
+	Description        string          `json:"description,omitempty"` // Make, model and color, for users to spot it
+	Capacity           VehicleCapacity `json:"capacity"`
+	InsuranceExpiresAt time.Time       `json:"insurance_expires_at"`
+	CreatedAt          time.Time       `json:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+}
+
+// TableName returns the table name for the Vehicle model
+func (Vehicle) TableName() string {
+	return "provider_vehicles"
+}
+
+// Insured reports whether the vehicle's insurance covers a time
+func (v *Vehicle) Insured(at time.Time) bool {
+	return at.Before(v.InsuranceExpiresAt)
+}
@@ -8,4 +8,10 @@ var (
 	
 	// ErrDuplicateProvider is returned when attempting to create a provider with an ID that already exists
 	ErrDuplicateProvider = errors.New("duplicate provider")
+
+	// ErrVehicleNotFound is returned when a provider has no vehicle with an ID
+	ErrVehicleNotFound = errors.New("vehicle not found")
+
+	// ErrDuplicateVehicle is returned when a provider already has a vehicle with a plate
+	ErrDuplicateVehicle = errors.New("duplicate vehicle")
 ) 
\ No newline at end of file
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/order-api-microservices/services/provider/internal/model"
+)
+
+// PreferencesRepository keeps providers' preferences in memory
+type PreferencesRepository struct {
+	mu          sync.RWMutex
+	preferences map[string]model.Preferences
+}
+
+// NewPreferencesRepository creates an empty preferences repository
+func NewPreferencesRepository() *PreferencesRepository {
+	return &PreferencesRepository{
+		preferences: make(map[string]model.Preferences),
+	}
+}
+
+// GetPreferences retrieves a provider's preferences. Providers who never set any get
+// the zero preferences, which filter nothing.
+func (r *PreferencesRepository) GetPreferences(ctx context.Context, providerID string) (*model.Preferences, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	preferences, ok := r.preferences[providerID]
+	if !ok {
+		return &model.Preferences{ProviderID: providerID}, nil
+	}
+
+	return clonePreferences(preferences), nil
+}
+
+// ListPreferences retrieves the preferences of several providers, keyed by provider ID.
+// Providers who never set any are left out.
+func (r *PreferencesRepository) ListPreferences(ctx context.Context, providerIDs []string) (map[string]*model.Preferences, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	preferences := make(map[string]*model.Preferences)
+	for _, providerID := range providerIDs {
+		if p, ok := r.preferences[providerID]; ok {
+			preferences[providerID] = clonePreferences(p)
+		}
+	}
+
+	return preferences, nil
+}
+
+// UpsertPreferences stores a provider's preferences, replacing any they had
+func (r *PreferencesRepository) UpsertPreferences(ctx context.Context, preferences *model.Preferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.preferences[preferences.ProviderID] = *clonePreferences(*preferences)
+
+	return nil
+}
+
+// delete forgets a provider's preferences
+func (r *PreferencesRepository) delete(providerID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.preferences, providerID)
+}
+
+// clonePreferences copies preferences so callers cannot change what is stored
+func clonePreferences(preferences model.Preferences) *model.Preferences {
+	preferences.OrderTypes = append([]string(nil), preferences.OrderTypes...)
+	return &preferences
+}
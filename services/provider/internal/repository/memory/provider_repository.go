@@ -0,0 +1,487 @@
+// Package memory holds in-memory implementations of the provider service's
+// repositories, so the service can be exercised without Postgres. They keep the data the
+// Postgres repositories keep and return the same errors. Contact details are kept as
+// given, since nothing leaves the process to be protected from.
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/services/provider/internal/model"
+	"github.com/order-api-microservices/services/provider/internal/repository"
+	"github.com/order-api-microservices/services/provider/internal/service"
+)
+
+// The in-memory repositories stand in for the Postgres ones the provider service uses
+var (
+	_ service.ProviderRepository    = (*ProviderRepository)(nil)
+	_ service.PreferencesRepository = (*PreferencesRepository)(nil)
+)
+
+// earthRadiusKm is the radius FindNearbyProviders measures distances on, as in Postgres
+const earthRadiusKm = 6371
+
+// ProviderRepository keeps providers, their vehicles and their location history in memory
+type ProviderRepository struct {
+	mu              sync.RWMutex
+	providers       map[string]*model.Provider
+	anonymized      map[string]bool
+	locationHistory map[string]int // Location history entries by provider ID
+	suspensions     map[string][]*model.Suspension
+	vehicles        map[string]*model.Vehicle
+	preferences     *PreferencesRepository
+}
+
+// NewProviderRepository creates an empty provider repository. Anonymizing a provider
+// deletes their preferences from preferences, when it is given.
+func NewProviderRepository(preferences *PreferencesRepository) *ProviderRepository {
+	return &ProviderRepository{
+		providers:       make(map[string]*model.Provider),
+		anonymized:      make(map[string]bool),
+		locationHistory: make(map[string]int),
+		suspensions:     make(map[string][]*model.Suspension),
+		vehicles:        make(map[string]*model.Vehicle),
+		preferences:     preferences,
+	}
+}
+
+// CreateProvider stores a new provider
+func (r *ProviderRepository) CreateProvider(ctx context.Context, provider *model.Provider) error {
+	if provider.ID == "" {
+		provider.ID = uuid.New().String()
+	}
+
+	now := time.Now()
+	provider.CreatedAt = now
+	provider.UpdatedAt = now
+	if provider.ServiceAreaIDs == nil {
+		provider.ServiceAreaIDs = []string{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.providers[provider.ID]; ok {
+		return repository.ErrDuplicateProvider
+	}
+	r.providers[provider.ID] = cloneProvider(provider)
+
+	return nil
+}
+
+// GetProviderByID gets a copy of a provider
+func (r *ProviderRepository) GetProviderByID(ctx context.Context, providerID string) (*model.Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	provider, ok := r.providers[providerID]
+	if !ok {
+		return nil, repository.ErrProviderNotFound
+	}
+
+	return cloneProvider(provider), nil
+}
+
+// UpdateProvider replaces a provider's details. Like the Postgres repository, it keeps
+// their service areas and creation time, and ignores providers that do not exist.
+func (r *ProviderRepository) UpdateProvider(ctx context.Context, provider *model.Provider) error {
+	provider.UpdatedAt = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.providers[provider.ID]
+	if !ok {
+		return nil
+	}
+
+	updated := cloneProvider(provider)
+	updated.ServiceAreaIDs = stored.ServiceAreaIDs
+	updated.CreatedAt = stored.CreatedAt
+	r.providers[provider.ID] = updated
+
+	return nil
+}
+
+// UpdateProviderLocation updates a provider's location and adds it to their history. It
+// counts as a heartbeat.
+func (r *ProviderRepository) UpdateProviderLocation(ctx context.Context, providerID string, location model.Location) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if provider, ok := r.providers[providerID]; ok {
+		now := time.Now()
+		provider.Location = location
+		provider.LastHeartbeatAt = &now
+		provider.UpdatedAt = now
+	}
+	r.locationHistory[providerID]++
+
+	return nil
+}
+
+// UpdateProviderAvailability updates a provider's availability status. Going available
+// counts as a heartbeat and starts a shift in vehicleID, which may be empty; going
+// unavailable ends the shift.
+func (r *ProviderRepository) UpdateProviderAvailability(ctx context.Context, providerID string, isAvailable bool, vehicleID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if provider, ok := r.providers[providerID]; ok {
+		now := time.Now()
+		provider.IsAvailable = isAvailable
+		provider.ActiveVehicleID = ""
+		if isAvailable {
+			provider.LastHeartbeatAt = &now
+			provider.ActiveVehicleID = vehicleID
+		}
+		provider.UpdatedAt = now
+	}
+
+	return nil
+}
+
+// RecordHeartbeat records that a provider's app is online at a time, and reports whether
+// the provider is available
+func (r *ProviderRepository) RecordHeartbeat(ctx context.Context, providerID string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	provider, ok := r.providers[providerID]
+	if !ok || r.anonymized[providerID] {
+		return false, repository.ErrProviderNotFound
+	}
+	provider.LastHeartbeatAt = &at
+
+	return provider.IsAvailable, nil
+}
+
+// UpdateProviderQuality replaces a provider's quality
+func (r *ProviderRepository) UpdateProviderQuality(ctx context.Context, providerID string, quality model.Quality) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	provider, ok := r.providers[providerID]
+	if !ok || r.anonymized[providerID] {
+		return repository.ErrProviderNotFound
+	}
+	provider.Quality = quality
+
+	return nil
+}
+
+// SuspendProviders suspends up to limit providers who break rules until until, and
+// returns their suspensions. Providers already suspended, or whose last suspension ended
+// after endedAfter, are left alone.
+func (r *ProviderRepository) SuspendProviders(ctx context.Context, rules model.SuspensionRules, at, until, endedAfter time.Time, limit int) ([]*model.Suspension, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	providerIDs := make([]string, 0, len(r.providers))
+	for providerID := range r.providers {
+		providerIDs = append(providerIDs, providerID)
+	}
+	sort.Strings(providerIDs)
+
+	var suspensions []*model.Suspension
+	for _, providerID := range providerIDs {
+		if len(suspensions) >= limit {
+			break
+		}
+		if r.anonymized[providerID] || r.suspendedAfter(providerID, endedAfter) {
+			continue
+		}
+		rule, reason, ok := rules.Violation(r.providers[providerID])
+		if !ok {
+			continue
+		}
+
+		suspension := &model.Suspension{
+			ID:          uuid.New().String(),
+			ProviderID:  providerID,
+			Rule:        rule,
+			Reason:      reason,
+			SuspendedAt: at,
+			ExpiresAt:   until,
+		}
+		r.suspensions[providerID] = append(r.suspensions[providerID], suspension)
+		copied := *suspension
+		suspensions = append(suspensions, &copied)
+	}
+
+	return suspensions, nil
+}
+
+// GetActiveSuspension gets the suspension a provider is under at a time, or nil if they
+// are not suspended
+func (r *ProviderRepository) GetActiveSuspension(ctx context.Context, providerID string, at time.Time) (*model.Suspension, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	suspensions := r.suspensions[providerID]
+	for i := len(suspensions) - 1; i >= 0; i-- {
+		if suspensions[i].Active(at) {
+			suspension := *suspensions[i]
+			return &suspension, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// suspendedAfter reports whether a provider's last suspension lasts past a time. The
+// caller holds r.mu.
+func (r *ProviderRepository) suspendedAfter(providerID string, at time.Time) bool {
+	suspensions := r.suspensions[providerID]
+	return len(suspensions) > 0 && suspensions[len(suspensions)-1].Active(at)
+}
+
+// MarkSilentProvidersUnavailable marks up to limit available providers unavailable whose
+// app has not been heard from since before cutoff, judging those that never sent a
+// heartbeat by their last update, ends their shifts and returns their IDs
+func (r *ProviderRepository) MarkSilentProvidersUnavailable(ctx context.Context, cutoff time.Time, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var providerIDs []string
+	for _, provider := range r.providers {
+		if len(providerIDs) >= limit {
+			break
+		}
+		lastSeen := provider.UpdatedAt
+		if provider.LastHeartbeatAt != nil {
+			lastSeen = *provider.LastHeartbeatAt
+		}
+		if !provider.IsAvailable || !lastSeen.Before(cutoff) {
+			continue
+		}
+
+		provider.IsAvailable = false
+		provider.ActiveVehicleID = ""
+		provider.UpdatedAt = now
+		providerIDs = append(providerIDs, provider.ID)
+	}
+
+	return providerIDs, nil
+}
+
+// UpdateProviderServiceAreas replaces the service areas a provider is registered in
+func (r *ProviderRepository) UpdateProviderServiceAreas(ctx context.Context, providerID string, serviceAreaIDs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	provider, ok := r.providers[providerID]
+	if !ok {
+		return repository.ErrProviderNotFound
+	}
+	provider.ServiceAreaIDs = append([]string{}, serviceAreaIDs...)
+	provider.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// UpdateProviderVehicleCapacity replaces what a provider's vehicle can carry
+func (r *ProviderRepository) UpdateProviderVehicleCapacity(ctx context.Context, providerID string, capacity model.VehicleCapacity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	provider, ok := r.providers[providerID]
+	if !ok {
+		return repository.ErrProviderNotFound
+	}
+	provider.VehicleCapacity = capacity
+	provider.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// AnonymizeProvider erases a provider's personal data, vehicles, location history and
+// preferences, keeping the provider unavailable, and reports how many location history
+// entries were deleted
+func (r *ProviderRepository) AnonymizeProvider(ctx context.Context, providerID string, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	provider, ok := r.providers[providerID]
+	if !ok {
+		return 0, repository.ErrProviderNotFound
+	}
+
+	provider.Name = "Erased provider"
+	provider.Email = ""
+	provider.Phone = ""
+	provider.ProfileImage = ""
+	provider.Metadata = model.Metadata{}
+	provider.Location = model.Location{}
+	provider.IsAvailable = false
+	provider.ServiceAreaIDs = []string{}
+	provider.ActiveVehicleID = ""
+	provider.UpdatedAt = at
+	r.anonymized[providerID] = true
+
+	for vehicleID, vehicle := range r.vehicles {
+		if vehicle.ProviderID == providerID {
+			delete(r.vehicles, vehicleID)
+		}
+	}
+
+	locationsDeleted := int64(r.locationHistory[providerID])
+	delete(r.locationHistory, providerID)
+
+	if r.preferences != nil {
+		r.preferences.delete(providerID)
+	}
+
+	return locationsDeleted, nil
+}
+
+// CountAvailableProviders counts the available providers, in total and by service area.
+// A provider registered in several areas counts in each; providers registered in none
+// are counted under the empty area ID. Suspended providers are left out.
+func (r *ProviderRepository) CountAvailableProviders(ctx context.Context) (int64, map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var total int64
+	byArea := make(map[string]int64)
+	for _, provider := range r.providers {
+		if !provider.IsAvailable || r.suspendedAfter(provider.ID, now) {
+			continue
+		}
+		total++
+		if len(provider.ServiceAreaIDs) == 0 {
+			byArea[""]++
+		}
+		for _, areaID := range provider.ServiceAreaIDs {
+			byArea[areaID]++
+		}
+	}
+
+	return total, byArea, nil
+}
+
+// ListProviderIDsInAreas lists up to limit IDs, after afterID in ID order, of the
+// providers registered in any of serviceAreaIDs
+func (r *ProviderRepository) ListProviderIDsInAreas(ctx context.Context, serviceAreaIDs []string, afterID string, limit int) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	providerIDs := []string{}
+	for _, provider := range r.providers {
+		if provider.ID <= afterID {
+			continue
+		}
+		for _, areaID := range serviceAreaIDs {
+			if contains(provider.ServiceAreaIDs, areaID) {
+				providerIDs = append(providerIDs, provider.ID)
+				break
+			}
+		}
+	}
+	sort.Strings(providerIDs)
+	if len(providerIDs) > limit {
+		providerIDs = providerIDs[:limit]
+	}
+
+	return providerIDs, nil
+}
+
+// FindNearbyProviders finds available providers offering serviceType within radiusKm of
+// a location, nearest first. When serviceAreaID is set, only providers registered in that
+// service area are found. Suspended providers are never found, nor are providers whose
+// vehicle this shift is no longer insured. A provider's vehicle capacity is their shift
+// vehicle's, when they have one.
+func (r *ProviderRepository) FindNearbyProviders(ctx context.Context, latitude, longitude float64, radiusKm float64, serviceType, serviceAreaID string) ([]*model.Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	type candidate struct {
+		provider *model.Provider
+		distance float64
+	}
+	var candidates []candidate
+	for _, provider := range r.providers {
+		if !provider.IsAvailable || r.anonymized[provider.ID] || r.suspendedAfter(provider.ID, now) {
+			continue
+		}
+		if serviceType != "" && !contains(provider.ServiceTypes, serviceType) {
+			continue
+		}
+		if serviceAreaID != "" && !contains(provider.ServiceAreaIDs, serviceAreaID) {
+			continue
+		}
+
+		vehicle := r.vehicles[provider.ActiveVehicleID]
+		if vehicle != nil && !vehicle.Insured(now) {
+			continue
+		}
+
+		distance := haversineKm(latitude, longitude, provider.Location.Latitude, provider.Location.Longitude)
+		if distance < radiusKm {
+			found := cloneProvider(provider)
+			if vehicle != nil {
+				found.VehicleCapacity = vehicle.Capacity
+			}
+			candidates = append(candidates, candidate{provider: found, distance: distance})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var providers []*model.Provider
+	for _, c := range candidates {
+		providers = append(providers, c.provider)
+	}
+
+	return providers, nil
+}
+
+// haversineKm is the great-circle distance between two points in kilometers
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// contains reports whether value is one of values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneProvider copies a provider so callers cannot change what is stored
+func cloneProvider(provider *model.Provider) *model.Provider {
+	clone := *provider
+	clone.ServiceTypes = append(model.ServiceTypes(nil), provider.ServiceTypes...)
+	clone.ServiceAreaIDs = append([]string{}, provider.ServiceAreaIDs...)
+	if provider.LastHeartbeatAt != nil {
+		lastHeartbeatAt := *provider.LastHeartbeatAt
+		clone.LastHeartbeatAt = &lastHeartbeatAt
+	}
+	if provider.Metadata != nil {
+		clone.Metadata = make(model.Metadata, len(provider.Metadata))
+		for key, value := range provider.Metadata {
+			clone.Metadata[key] = value
+		}
+	}
+	return &clone
+}
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/services/provider/internal/model"
+	"github.com/order-api-microservices/services/provider/internal/repository"
+)
+
+// CreateVehicle registers a vehicle for a provider
+func (r *ProviderRepository) CreateVehicle(ctx context.Context, vehicle *model.Vehicle) error {
+	if vehicle.ID == "" {
+		vehicle.ID = uuid.New().String()
+	}
+
+	now := time.Now()
+	vehicle.CreatedAt = now
+	vehicle.UpdatedAt = now
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.plateTaken(vehicle) {
+		return repository.ErrDuplicateVehicle
+	}
+	copied := *vehicle
+	r.vehicles[vehicle.ID] = &copied
+
+	return nil
+}
+
+// GetVehicle gets a copy of one of a provider's vehicles
+func (r *ProviderRepository) GetVehicle(ctx context.Context, providerID, vehicleID string) (*model.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	vehicle, ok := r.vehicles[vehicleID]
+	if !ok || vehicle.ProviderID != providerID {
+		return nil, repository.ErrVehicleNotFound
+	}
+
+	copied := *vehicle
+	return &copied, nil
+}
+
+// ListVehicles lists copies of a provider's vehicles, oldest first
+func (r *ProviderRepository) ListVehicles(ctx context.Context, providerID string) ([]*model.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var vehicles []*model.Vehicle
+	for _, vehicle := range r.vehicles {
+		if vehicle.ProviderID == providerID {
+			copied := *vehicle
+			vehicles = append(vehicles, &copied)
+		}
+	}
+
+	sort.Slice(vehicles, func(i, j int) bool {
+		return vehicles[i].CreatedAt.Before(vehicles[j].CreatedAt)
+	})
+
+	return vehicles, nil
+}
+
+// UpdateVehicle replaces a vehicle's plate, type, description, capacity and insurance
+// expiry
+func (r *ProviderRepository) UpdateVehicle(ctx context.Context, vehicle *model.Vehicle) error {
+	vehicle.UpdatedAt = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.vehicles[vehicle.ID]
+	if !ok || stored.ProviderID != vehicle.ProviderID {
+		return repository.ErrVehicleNotFound
+	}
+	if r.plateTaken(vehicle) {
+		return repository.ErrDuplicateVehicle
+	}
+
+	vehicle.CreatedAt = stored.CreatedAt
+	copied := *vehicle
+	r.vehicles[vehicle.ID] = &copied
+
+	return nil
+}
+
+// DeleteVehicle deletes one of a provider's vehicles. A provider driving it this shift is
+// left without a shift vehicle.
+func (r *ProviderRepository) DeleteVehicle(ctx context.Context, providerID, vehicleID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vehicle, ok := r.vehicles[vehicleID]
+	if !ok || vehicle.ProviderID != providerID {
+		return repository.ErrVehicleNotFound
+	}
+	delete(r.vehicles, vehicleID)
+
+	if provider, ok := r.providers[providerID]; ok && provider.ActiveVehicleID == vehicleID {
+		provider.ActiveVehicleID = ""
+		provider.UpdatedAt = time.Now()
+	}
+
+	return nil
+}
+
+// plateTaken reports whether the vehicle's provider has another vehicle with its plate.
+// The caller holds r.mu.
+func (r *ProviderRepository) plateTaken(vehicle *model.Vehicle) bool {
+	for _, other := range r.vehicles {
+		if other.ID != vehicle.ID && other.ProviderID == vehicle.ProviderID && other.Plate == vehicle.Plate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/provider/internal/model"
+)
+
+// PreferencesRepository handles database operations for provider preferences
+type PreferencesRepository struct {
+	db *database.PostgresDB
+}
+
+// NewPreferencesRepository creates a new preferences repository
+func NewPreferencesRepository(db *database.PostgresDB) *PreferencesRepository {
+	return &PreferencesRepository{
+		db: db,
+	}
+}
+
+// GetPreferences retrieves a provider's preferences. Providers who never set any get
+// the zero preferences, which filter nothing.
+func (r *PreferencesRepository) GetPreferences(ctx context.Context, providerID string) (*model.Preferences, error) {
+	query := `
+		SELECT provider_id, auto_accept_radius_km, order_types, min_fare, updated_at
+		FROM provider_preferences
+		WHERE provider_id = $1
+	`
+
+	var preferences model.Preferences
+	err := r.db.QueryRowContext(ctx, query, providerID).Scan(
+		&preferences.ProviderID,
+		&preferences.AutoAcceptRadiusKm,
+		&preferences.OrderTypes,
+		&preferences.MinFare,
+		&preferences.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &model.Preferences{ProviderID: providerID}, nil
+		}
+		return nil, fmt.Errorf("failed to get provider preferences: %w", err)
+	}
+
+	return &preferences, nil
+}
+
+// ListPreferences retrieves the preferences of several providers, keyed by provider ID.
+// Providers who never set any are left out.
+func (r *PreferencesRepository) ListPreferences(ctx context.Context, providerIDs []string) (map[string]*model.Preferences, error) {
+	query := `
+		SELECT provider_id, auto_accept_radius_km, order_types, min_fare, updated_at
+		FROM provider_preferences
+		WHERE provider_id = ANY($1)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, providerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list provider preferences: %w", err)
+	}
+	defer rows.Close()
+
+	preferences := make(map[string]*model.Preferences)
+	for rows.Next() {
+		var p model.Preferences
+		if err := rows.Scan(
+			&p.ProviderID,
+			&p.AutoAcceptRadiusKm,
+			&p.OrderTypes,
+			&p.MinFare,
+			&p.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan provider preferences: %w", err)
+		}
+		preferences[p.ProviderID] = &p
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating provider preferences rows: %w", err)
+	}
+
+	return preferences, nil
+}
+
+// UpsertPreferences stores a provider's preferences, replacing any they had
+func (r *PreferencesRepository) UpsertPreferences(ctx context.Context, preferences *model.Preferences) error {
+	query := `
+		INSERT INTO provider_preferences (provider_id, auto_accept_radius_km, order_types, min_fare, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider_id) DO UPDATE
+		SET auto_accept_radius_km = EXCLUDED.auto_accept_radius_km,
+		    order_types = EXCLUDED.order_types,
+		    min_fare = EXCLUDED.min_fare,
+		    updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		preferences.ProviderID,
+		preferences.AutoAcceptRadiusKm,
+		preferences.OrderTypes,
+		preferences.MinFare,
+		preferences.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save provider preferences: %w", err)
+	}
+
+	return nil
+}
@@ -7,19 +7,24 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/crypto"
 	"github.com/order-api-microservices/pkg/database"
 	"github.com/order-api-microservices/services/provider/internal/model"
 )
 
-// ProviderRepository handles operations related to providers
+// ProviderRepository handles operations related to providers. Providers' email and phone
+// are encrypted with keys before they are stored and decrypted when read.
 type ProviderRepository struct {
-	db *database.PostgresDB
+	db   *database.PostgresDB
+	keys *crypto.KeyRing
 }
 
-// NewProviderRepository creates a new provider repository
-func NewProviderRepository(db *database.PostgresDB) *ProviderRepository {
+// NewProviderRepository creates a new provider repository. A nil key ring stores contact
+// details in plaintext.
+func NewProviderRepository(db *database.PostgresDB, keys *crypto.KeyRing) *ProviderRepository {
 	return &ProviderRepository{
-		db: db,
+		db:   db,
+		keys: keys,
 	}
 }
 
@@ -32,24 +37,35 @@ func (r *ProviderRepository) CreateProvider(ctx context.Context, provider *model
 	now := time.Now()
 	provider.CreatedAt = now
 	provider.UpdatedAt = now
+	if provider.ServiceAreaIDs == nil {
+		provider.ServiceAreaIDs = []string{}
+	}
+
+	email, phone, err := r.encryptContact(provider)
+	if err != nil {
+		return err
+	}
 
 	query := `
 		INSERT INTO providers (
 			id, name, email, phone, rating, service_types, location, is_available, 
-			profile_image, metadata, created_at, updated_at
+			max_concurrent_orders, service_area_ids, vehicle_capacity, profile_image, metadata, created_at, updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err = r.db.ExecContext(ctx, query,
 		provider.ID,
 		provider.Name,
-		provider.Email,
-		provider.Phone,
+		email,
+		phone,
 		provider.Rating,
 		model.ServiceTypes(provider.ServiceTypes),
 		provider.Location,
 		provider.IsAvailable,
+		provider.MaxConcurrentOrders,
+		provider.ServiceAreaIDs,
+		provider.VehicleCapacity,
 		provider.ProfileImage,
 		model.Metadata(provider.Metadata),
 		provider.CreatedAt,
@@ -67,6 +83,7 @@ func (r *ProviderRepository) CreateProvider(ctx context.Context, provider *model
 func (r *ProviderRepository) GetProviderByID(ctx context.Context, providerID string) (*model.Provider, error) {
 	query := `
 		SELECT id, name, email, phone, rating, service_types, location, is_available, 
+		       max_concurrent_orders, service_area_ids, last_heartbeat_at, quality, vehicle_capacity, COALESCE(active_vehicle_id, ''),
 		       profile_image, metadata, created_at, updated_at
 		FROM providers
 		WHERE id = $1
@@ -87,6 +104,12 @@ func (r *ProviderRepository) GetProviderByID(ctx context.Context, providerID str
 		&serviceTypes,
 		&provider.Location,
 		&provider.IsAvailable,
+		&provider.MaxConcurrentOrders,
+		&provider.ServiceAreaIDs,
+		&provider.LastHeartbeatAt,
+		&provider.Quality,
+		&provider.VehicleCapacity,
+		&provider.ActiveVehicleID,
 		&provider.ProfileImage,
 		&metadata,
 		&provider.CreatedAt,
@@ -102,6 +125,9 @@ func (r *ProviderRepository) GetProviderByID(ctx context.Context, providerID str
 
 	provider.ServiceTypes = serviceTypes
 	provider.Metadata = metadata
+	if err := r.decryptContact(&provider); err != nil {
+		return nil, err
+	}
 
 	return &provider, nil
 }
@@ -110,22 +136,29 @@ func (r *ProviderRepository) GetProviderByID(ctx context.Context, providerID str
 func (r *ProviderRepository) UpdateProvider(ctx context.Context, provider *model.Provider) error {
 	provider.UpdatedAt = time.Now()
 
+	email, phone, err := r.encryptContact(provider)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE providers
 		SET name = $2, email = $3, phone = $4, rating = $5, service_types = $6, 
-		    location = $7, is_available = $8, profile_image = $9, metadata = $10, updated_at = $11
+		    location = $7, is_available = $8, max_concurrent_orders = $9, vehicle_capacity = $10, profile_image = $11, metadata = $12, updated_at = $13
 		WHERE id = $1
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err = r.db.ExecContext(ctx, query,
 		provider.ID,
 		provider.Name,
-		provider.Email,
-		provider.Phone,
+		email,
+		phone,
 		provider.Rating,
 		model.ServiceTypes(provider.ServiceTypes),
 		provider.Location,
 		provider.IsAvailable,
+		provider.MaxConcurrentOrders,
+		provider.VehicleCapacity,
 		provider.ProfileImage,
 		model.Metadata(provider.Metadata),
 		provider.UpdatedAt,
@@ -138,12 +171,13 @@ func (r *ProviderRepository) UpdateProvider(ctx context.Context, provider *model
 	return nil
 }
 
-// UpdateProviderLocation updates a provider's location
+// UpdateProviderLocation updates a provider's location. A location update also shows
+// the provider's app is online, so it counts as a heartbeat.
 func (r *ProviderRepository) UpdateProviderLocation(ctx context.Context, providerID string, location model.Location) error {
 	// Update the location in the provider record
 	query1 := `
 		UPDATE providers
-		SET location = $2, updated_at = $3
+		SET location = $2, last_heartbeat_at = $3, updated_at = $3
 		WHERE id = $1
 	`
 
@@ -177,15 +211,150 @@ func (r *ProviderRepository) UpdateProviderLocation(ctx context.Context, provide
 	return nil
 }
 
-// UpdateProviderAvailability updates a provider's availability status
-func (r *ProviderRepository) UpdateProviderAvailability(ctx context.Context, providerID string, isAvailable bool) error {
+// UpdateProviderServiceAreas replaces the service areas a provider is registered in
+func (r *ProviderRepository) UpdateProviderServiceAreas(ctx context.Context, providerID string, serviceAreaIDs []string) error {
+	query := `
+		UPDATE providers
+		SET service_area_ids = $2, updated_at = $3
+		WHERE id = $1
+	`
+
+	tag, err := r.db.ExecContext(ctx, query, providerID, serviceAreaIDs, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update provider service areas: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProviderNotFound
+	}
+
+	return nil
+}
+
+// UpdateProviderVehicleCapacity replaces what a provider's vehicle can carry
+func (r *ProviderRepository) UpdateProviderVehicleCapacity(ctx context.Context, providerID string, capacity model.VehicleCapacity) error {
+	query := `
+		UPDATE providers
+		SET vehicle_capacity = $2, updated_at = $3
+		WHERE id = $1
+	`
+
+	tag, err := r.db.ExecContext(ctx, query, providerID, capacity, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update provider vehicle capacity: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProviderNotFound
+	}
+
+	return nil
+}
+
+// AnonymizeProvider erases a provider's personal data: their name, contact details,
+// photo, metadata, vehicles and location history. The row is kept so orders and payouts still
+// reference it, but the provider is marked unavailable and never matched again. It
+// reports how many location history entries were deleted.
+func (r *ProviderRepository) AnonymizeProvider(ctx context.Context, providerID string, at time.Time) (int64, error) {
+	tx, err := r.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE providers
+		SET name = 'Erased provider', email = '', phone = '', profile_image = '', metadata = '{}',
+		    location = jsonb_build_object('latitude', 0, 'longitude', 0, 'address', ''),
+		    is_available = false, service_area_ids = '{}', active_vehicle_id = NULL, anonymized_at = COALESCE(anonymized_at, $2), updated_at = $2
+		WHERE id = $1
+	`
+
+	tag, err := tx.Exec(ctx, query, providerID, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize provider: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, ErrProviderNotFound
+	}
+
+	tag, err = tx.Exec(ctx, `DELETE FROM provider_locations WHERE provider_id = $1`, providerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete provider location history: %w", err)
+	}
+	locationsDeleted := tag.RowsAffected()
+
+	if _, err := tx.Exec(ctx, `DELETE FROM provider_preferences WHERE provider_id = $1`, providerID); err != nil {
+		return 0, fmt.Errorf("failed to delete provider preferences: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM provider_vehicles WHERE provider_id = $1`, providerID); err != nil {
+		return 0, fmt.Errorf("failed to delete provider vehicles: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return locationsDeleted, nil
+}
+
+// CountAvailableProviders counts the available providers, in total and by service area.
+// A provider registered in several areas counts in each; providers registered in none
+// are counted under the empty area ID. Suspended providers are left out.
+func (r *ProviderRepository) CountAvailableProviders(ctx context.Context) (int64, map[string]int64, error) {
+	now := time.Now()
+	var total int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM providers
+		WHERE is_available AND (suspended_until IS NULL OR suspended_until <= $1)
+	`, now).Scan(&total)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to count available providers: %w", err)
+	}
+
+	query := `
+		SELECT COALESCE(area.id, ''), COUNT(*)
+		FROM providers p
+		LEFT JOIN LATERAL unnest(p.service_area_ids) AS area(id) ON true
+		WHERE p.is_available AND (p.suspended_until IS NULL OR p.suspended_until <= $1)
+		GROUP BY area.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to count available providers by service area: %w", err)
+	}
+	defer rows.Close()
+
+	byArea := make(map[string]int64)
+	for rows.Next() {
+		var areaID string
+		var count int64
+		if err := rows.Scan(&areaID, &count); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan available provider count: %w", err)
+		}
+		byArea[areaID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("error iterating available provider counts: %w", err)
+	}
+
+	return total, byArea, nil
+}
+
+// UpdateProviderAvailability updates a provider's availability status. Going available
+// counts as a heartbeat, so the provider is not marked unavailable again before their
+// app sends one, and starts a shift in vehicleID, which may be empty. Going unavailable
+// ends the shift.
+func (r *ProviderRepository) UpdateProviderAvailability(ctx context.Context, providerID string, isAvailable bool, vehicleID string) error {
 	query := `
 		UPDATE providers
-		SET is_available = $2, updated_at = $3
+		SET is_available = $2, last_heartbeat_at = CASE WHEN $2 THEN $3 ELSE last_heartbeat_at END,
+		    active_vehicle_id = CASE WHEN $2 THEN NULLIF($4, '') ELSE NULL END, updated_at = $3
 		WHERE id = $1
 	`
 
-	_, err := r.db.ExecContext(ctx, query, providerID, isAvailable, time.Now())
+	_, err := r.db.ExecContext(ctx, query, providerID, isAvailable, time.Now(), vehicleID)
 	if err != nil {
 		return fmt.Errorf("failed to update provider availability: %w", err)
 	}
@@ -193,29 +362,226 @@ func (r *ProviderRepository) UpdateProviderAvailability(ctx context.Context, pro
 	return nil
 }
 
-// FindNearbyProviders finds providers near a location with specified service type
-func (r *ProviderRepository) FindNearbyProviders(ctx context.Context, latitude, longitude float64, radiusKm float64, serviceType string) ([]*model.Provider, error) {
+// RecordHeartbeat records that a provider's app is online at a time, and reports whether
+// the provider is available
+func (r *ProviderRepository) RecordHeartbeat(ctx context.Context, providerID string, at time.Time) (bool, error) {
+	query := `
+		UPDATE providers
+		SET last_heartbeat_at = $2
+		WHERE id = $1 AND anonymized_at IS NULL
+		RETURNING is_available
+	`
+
+	var isAvailable bool
+	if err := r.db.QueryRowContext(ctx, query, providerID, at).Scan(&isAvailable); err != nil {
+		if err == pgx.ErrNoRows {
+			return false, ErrProviderNotFound
+		}
+		return false, fmt.Errorf("failed to record provider heartbeat: %w", err)
+	}
+
+	return isAvailable, nil
+}
+
+// UpdateProviderQuality replaces a provider's quality
+func (r *ProviderRepository) UpdateProviderQuality(ctx context.Context, providerID string, quality model.Quality) error {
+	query := `
+		UPDATE providers
+		SET quality = $2
+		WHERE id = $1 AND anonymized_at IS NULL
+	`
+
+	tag, err := r.db.ExecContext(ctx, query, providerID, quality)
+	if err != nil {
+		return fmt.Errorf("failed to update provider quality: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProviderNotFound
+	}
+
+	return nil
+}
+
+// SuspendProviders suspends up to limit providers who break rules until until, and
+// returns their suspensions. Providers already suspended, or whose last suspension ended
+// after endedAfter, are left alone. Each provider is locked while they are suspended and
+// providers locked elsewhere are skipped, so several instances can suspend together.
+func (r *ProviderRepository) SuspendProviders(ctx context.Context, rules model.SuspensionRules, at, until, endedAfter time.Time, limit int) ([]*model.Suspension, error) {
+	var suspensions []*model.Suspension
+	err := r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		suspensions = nil
+		rows, err := tx.Query(ctx, `
+			SELECT id, rating, quality
+			FROM providers
+			WHERE anonymized_at IS NULL
+			AND (suspended_until IS NULL OR suspended_until <= $1)
+			AND (
+				($2::DOUBLE PRECISION > 0 AND rating > 0 AND rating < $2::DOUBLE PRECISION)
+				OR ($3::DOUBLE PRECISION > 0
+					AND COALESCE((quality->>'orders_finished')::INT, 0) >= GREATEST($4::INT, 1)
+					AND (quality->>'completion_rate')::DOUBLE PRECISION < $3::DOUBLE PRECISION)
+				OR ($5::INT > 0 AND COALESCE((quality->>'incidents')::INT, 0) >= $5::INT)
+			)
+			ORDER BY id
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		`, endedAfter, rules.MinRating, rules.MinCompletion, rules.MinOrders, rules.MaxIncidents, limit)
+		if err != nil {
+			return fmt.Errorf("failed to find providers to suspend: %w", err)
+		}
+
+		var providers []*model.Provider
+		for rows.Next() {
+			provider := &model.Provider{}
+			if err := rows.Scan(&provider.ID, &provider.Rating, &provider.Quality); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan provider to suspend: %w", err)
+			}
+			providers = append(providers, provider)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating providers to suspend: %w", err)
+		}
+
+		for _, provider := range providers {
+			rule, reason, ok := rules.Violation(provider)
+			if !ok {
+				continue
+			}
+			suspension := &model.Suspension{
+				ID:          uuid.New().String(),
+				ProviderID:  provider.ID,
+				Rule:        rule,
+				Reason:      reason,
+				SuspendedAt: at,
+				ExpiresAt:   until,
+			}
+
+			_, err := tx.Exec(ctx, `
+				INSERT INTO provider_suspensions (id, provider_id, rule, reason, suspended_at, expires_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`, suspension.ID, suspension.ProviderID, suspension.Rule, suspension.Reason, suspension.SuspendedAt, suspension.ExpiresAt)
+			if err != nil {
+				return fmt.Errorf("failed to record provider suspension: %w", err)
+			}
+
+			_, err = tx.Exec(ctx, `UPDATE providers SET suspended_until = $2 WHERE id = $1`, provider.ID, until)
+			if err != nil {
+				return fmt.Errorf("failed to suspend provider: %w", err)
+			}
+			suspensions = append(suspensions, suspension)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return suspensions, nil
+}
+
+// GetActiveSuspension gets the suspension a provider is under at a time, or nil if they
+// are not suspended
+func (r *ProviderRepository) GetActiveSuspension(ctx context.Context, providerID string, at time.Time) (*model.Suspension, error) {
+	query := `
+		SELECT id, provider_id, rule, reason, suspended_at, expires_at
+		FROM provider_suspensions
+		WHERE provider_id = $1 AND expires_at > $2
+		ORDER BY suspended_at DESC
+		LIMIT 1
+	`
+
+	var suspension model.Suspension
+	err := r.db.QueryRowContext(ctx, query, providerID, at).Scan(
+		&suspension.ID,
+		&suspension.ProviderID,
+		&suspension.Rule,
+		&suspension.Reason,
+		&suspension.SuspendedAt,
+		&suspension.ExpiresAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get provider suspension: %w", err)
+	}
+
+	return &suspension, nil
+}
+
+// MarkSilentProvidersUnavailable marks up to limit available providers unavailable whose
+// app has not been heard from since before cutoff, ending their shifts, and returns their
+// IDs. Providers that never sent a heartbeat are judged by their last update.
+func (r *ProviderRepository) MarkSilentProvidersUnavailable(ctx context.Context, cutoff time.Time, limit int) ([]string, error) {
+	query := `
+		UPDATE providers
+		SET is_available = false, active_vehicle_id = NULL, updated_at = $3
+		WHERE id IN (
+			SELECT id FROM providers
+			WHERE is_available AND COALESCE(last_heartbeat_at, updated_at) < $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, cutoff, limit, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark silent providers unavailable: %w", err)
+	}
+	defer rows.Close()
+
+	var providerIDs []string
+	for rows.Next() {
+		var providerID string
+		if err := rows.Scan(&providerID); err != nil {
+			return nil, fmt.Errorf("failed to scan provider ID: %w", err)
+		}
+		providerIDs = append(providerIDs, providerID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating silent providers: %w", err)
+	}
+
+	return providerIDs, nil
+}
+
+// FindNearbyProviders finds providers near a location with specified service type. When
+// serviceAreaID is set, only providers registered in that service area are found.
+// Suspended providers are never found, nor are providers whose vehicle this shift is no
+// longer insured. A provider's vehicle capacity is their shift vehicle's, when they have
+// one.
+func (r *ProviderRepository) FindNearbyProviders(ctx context.Context, latitude, longitude float64, radiusKm float64, serviceType, serviceAreaID string) ([]*model.Provider, error) {
 	// Query using Haversine formula to calculate distance in kilometers
 	query := `
 		SELECT 
 			p.id, p.name, p.email, p.phone, p.rating, p.service_types, p.location, 
-			p.is_available, p.profile_image, p.metadata, p.created_at, p.updated_at,
+			p.is_available, p.max_concurrent_orders, p.service_area_ids, p.last_heartbeat_at, p.quality,
+			COALESCE(v.capacity, p.vehicle_capacity), COALESCE(p.active_vehicle_id, ''), p.profile_image, p.metadata, p.created_at, p.updated_at,
 			6371 * acos(cos(radians($1)) * cos(radians((p.location->>'latitude')::float)) * 
 			cos(radians((p.location->>'longitude')::float) - radians($2)) + 
 			sin(radians($1)) * sin(radians((p.location->>'latitude')::float))) AS distance
 		FROM providers p
+		LEFT JOIN provider_vehicles v ON v.id = p.active_vehicle_id
 		WHERE p.is_available = true
+		AND p.anonymized_at IS NULL
+		AND (p.suspended_until IS NULL OR p.suspended_until <= $6)
+		AND (v.id IS NULL OR v.insurance_expires_at > $6)
 		AND CASE 
-			WHEN $3 <> '' THEN $3 = ANY(p.service_types)
+			WHEN $3 <> '' THEN p.service_types ? $3
 			ELSE true
 		END
+		AND ($5 = '' OR $5 = ANY(p.service_area_ids))
 		AND 6371 * acos(cos(radians($1)) * cos(radians((p.location->>'latitude')::float)) * 
 			cos(radians((p.location->>'longitude')::float) - radians($2)) + 
 			sin(radians($1)) * sin(radians((p.location->>'latitude')::float))) < $4
 		ORDER BY distance
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, latitude, longitude, serviceType, radiusKm)
+	rows, err := r.db.QueryContext(ctx, query, latitude, longitude, serviceType, radiusKm, serviceAreaID, time.Now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to find nearby providers: %w", err)
 	}
@@ -237,6 +603,12 @@ func (r *ProviderRepository) FindNearbyProviders(ctx context.Context, latitude,
 			&serviceTypes,
 			&provider.Location,
 			&provider.IsAvailable,
+			&provider.MaxConcurrentOrders,
+			&provider.ServiceAreaIDs,
+			&provider.LastHeartbeatAt,
+			&provider.Quality,
+			&provider.VehicleCapacity,
+			&provider.ActiveVehicleID,
 			&provider.ProfileImage,
 			&metadata,
 			&provider.CreatedAt,
@@ -250,6 +622,9 @@ func (r *ProviderRepository) FindNearbyProviders(ctx context.Context, latitude,
 
 		provider.ServiceTypes = serviceTypes
 		provider.Metadata = metadata
+		if err := r.decryptContact(&provider); err != nil {
+			return nil, err
+		}
 
 		// Add the provider to the result set
 		providers = append(providers, &provider)
@@ -260,4 +635,113 @@ func (r *ProviderRepository) FindNearbyProviders(ctx context.Context, latitude,
 	}
 
 	return providers, nil
-} 
\ No newline at end of file
+}
+
+// ReencryptProviders encrypts up to limit providers' contact details that are stored in
+// plaintext or under an older key with the primary key, and reports how many it changed
+func (r *ProviderRepository) ReencryptProviders(ctx context.Context, limit int) (int, error) {
+	if r.keys == nil {
+		return 0, nil
+	}
+
+	query := `
+		SELECT id, email, phone
+		FROM providers
+		WHERE (email <> '' AND email NOT LIKE $1) OR (phone <> '' AND phone NOT LIKE $1)
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, r.keys.PrimaryPrefix()+"%", limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query providers to re-encrypt: %w", err)
+	}
+
+	type contact struct {
+		id, email, phone string
+	}
+	var contacts []contact
+	for rows.Next() {
+		var c contact
+		if err := rows.Scan(&c.id, &c.email, &c.phone); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan provider contact: %w", err)
+		}
+		contacts = append(contacts, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating providers to re-encrypt: %w", err)
+	}
+
+	for _, c := range contacts {
+		if c.email, _, err = r.keys.Rotate(c.email); err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt email of provider %s: %w", c.id, err)
+		}
+		if c.phone, _, err = r.keys.Rotate(c.phone); err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt phone of provider %s: %w", c.id, err)
+		}
+
+		if _, err := r.db.ExecContext(ctx, `UPDATE providers SET email = $2, phone = $3 WHERE id = $1`, c.id, c.email, c.phone); err != nil {
+			return 0, fmt.Errorf("failed to store re-encrypted provider contact: %w", err)
+		}
+	}
+
+	return len(contacts), nil
+}
+
+// encryptContact returns a provider's email and phone encrypted for storage
+func (r *ProviderRepository) encryptContact(provider *model.Provider) (string, string, error) {
+	email, err := r.keys.Encrypt(provider.Email)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt provider email: %w", err)
+	}
+	phone, err := r.keys.Encrypt(provider.Phone)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt provider phone: %w", err)
+	}
+	return email, phone, nil
+}
+
+// decryptContact decrypts a provider's email and phone in place
+func (r *ProviderRepository) decryptContact(provider *model.Provider) error {
+	var err error
+	if provider.Email, err = r.keys.Decrypt(provider.Email); err != nil {
+		return fmt.Errorf("failed to decrypt email of provider %s: %w", provider.ID, err)
+	}
+	if provider.Phone, err = r.keys.Decrypt(provider.Phone); err != nil {
+		return fmt.Errorf("failed to decrypt phone of provider %s: %w", provider.ID, err)
+	}
+	return nil
+}
+
+// ListProviderIDsInAreas lists up to limit IDs, after afterID in ID order, of the
+// providers registered in any of serviceAreaIDs. Erased providers are registered in none.
+func (r *ProviderRepository) ListProviderIDsInAreas(ctx context.Context, serviceAreaIDs []string, afterID string, limit int) ([]string, error) {
+	query := `
+		SELECT id FROM providers
+		WHERE service_area_ids && $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, serviceAreaIDs, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list providers in service areas: %w", err)
+	}
+	defer rows.Close()
+
+	providerIDs := []string{}
+	for rows.Next() {
+		var providerID string
+		if err := rows.Scan(&providerID); err != nil {
+			return nil, fmt.Errorf("failed to scan provider ID: %w", err)
+		}
+		providerIDs = append(providerIDs, providerID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating provider IDs: %w", err)
+	}
+
+	return providerIDs, nil
+}
@@ -0,0 +1,79 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/internal/testharness"
+	"github.com/order-api-microservices/services/provider/internal/repository"
+)
+
+func TestProviderRepositoryFindNearbyProviders(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "provider")
+	repo := repository.NewProviderRepository(db, nil)
+
+	// About 1 km, 3 km and 30 km north of the search location
+	near := testharness.InsertProvider(t, db, testharness.ProviderFixture{Lat: -6.191, Lon: 106.8166, Available: true})
+	courier := testharness.InsertProvider(t, db, testharness.ProviderFixture{
+		Lat: -6.173, Lon: 106.8166, Available: true, ServiceTypes: []string{"FOOD_DELIVERY"}, ServiceAreaIDs: []string{"area-1"},
+	})
+	testharness.InsertProvider(t, db, testharness.ProviderFixture{Lat: -5.93, Lon: 106.8166, Available: true})
+	testharness.InsertProvider(t, db, testharness.ProviderFixture{Lat: -6.192, Lon: 106.8166, Available: false})
+
+	tests := []struct {
+		name          string
+		serviceType   string
+		serviceAreaID string
+		want          []string
+	}{
+		{name: "any service, nearest first", want: []string{near, courier}},
+		{name: "service type", serviceType: "FOOD_DELIVERY", want: []string{courier}},
+		{name: "service area", serviceAreaID: "area-1", want: []string{courier}},
+		{name: "service nobody offers", serviceType: "RENTAL", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers, err := repo.FindNearbyProviders(ctx, -6.2, 106.8166, 10, tt.serviceType, tt.serviceAreaID)
+			if err != nil {
+				t.Fatalf("FindNearbyProviders: %v", err)
+			}
+			if len(providers) != len(tt.want) {
+				t.Fatalf("found %d providers, want %d", len(providers), len(tt.want))
+			}
+			for i, provider := range providers {
+				if provider.ID != tt.want[i] {
+					t.Errorf("provider %d = %s, want %s", i, provider.ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProviderRepositoryUpdateAvailability(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "provider")
+	repo := repository.NewProviderRepository(db, nil)
+
+	providerID := testharness.InsertProvider(t, db, testharness.ProviderFixture{})
+
+	if err := repo.UpdateProviderAvailability(ctx, providerID, true, ""); err != nil {
+		t.Fatalf("UpdateProviderAvailability: %v", err)
+	}
+	provider, err := repo.GetProviderByID(ctx, providerID)
+	if err != nil {
+		t.Fatalf("GetProviderByID: %v", err)
+	}
+	if !provider.IsAvailable || provider.LastHeartbeatAt == nil {
+		t.Errorf("got available=%v last_heartbeat_at=%v, want available with a heartbeat", provider.IsAvailable, provider.LastHeartbeatAt)
+	}
+
+	if _, err := repo.GetProviderByID(ctx, uuid.New().String()); !errors.Is(err, repository.ErrProviderNotFound) {
+		t.Errorf("GetProviderByID of a missing provider: got %v, want ErrProviderNotFound", err)
+	}
+}
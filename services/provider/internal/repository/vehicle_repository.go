@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/order-api-microservices/pkg/database"
+	"github.com/order-api-microservices/services/provider/internal/model"
+)
+
+// vehiclePlateConstraint keeps a provider's plates unique among their vehicles
+const vehiclePlateConstraint = "provider_vehicles_plate_key"
+
+// CreateVehicle registers a vehicle for a provider
+func (r *ProviderRepository) CreateVehicle(ctx context.Context, vehicle *model.Vehicle) error {
+	if vehicle.ID == "" {
+		vehicle.ID = uuid.New().String()
+	}
+
+	now := time.Now()
+	vehicle.CreatedAt = now
+	vehicle.UpdatedAt = now
+
+	query := `
+		INSERT INTO provider_vehicles (
+			id, provider_id, plate, type, description, capacity, insurance_expires_at, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		vehicle.ID,
+		vehicle.ProviderID,
+		vehicle.Plate,
+		vehicle.Type,
+		vehicle.Description,
+		vehicle.Capacity,
+		vehicle.InsuranceExpiresAt,
+		vehicle.CreatedAt,
+		vehicle.UpdatedAt,
+	)
+	if err != nil {
+		if database.IsUniqueViolation(err, vehiclePlateConstraint) {
+			return ErrDuplicateVehicle
+		}
+		return fmt.Errorf("failed to create vehicle: %w", err)
+	}
+
+	return nil
+}
+
+// GetVehicle gets one of a provider's vehicles
+func (r *ProviderRepository) GetVehicle(ctx context.Context, providerID, vehicleID string) (*model.Vehicle, error) {
+	query := `
+		SELECT id, provider_id, plate, type, description, capacity, insurance_expires_at, created_at, updated_at
+		FROM provider_vehicles
+		WHERE id = $1 AND provider_id = $2
+	`
+
+	var vehicle model.Vehicle
+	err := r.db.QueryRowContext(ctx, query, vehicleID, providerID).Scan(
+		&vehicle.ID,
+		&vehicle.ProviderID,
+		&vehicle.Plate,
+		&vehicle.Type,
+		&vehicle.Description,
+		&vehicle.Capacity,
+		&vehicle.InsuranceExpiresAt,
+		&vehicle.CreatedAt,
+		&vehicle.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrVehicleNotFound
+		}
+		return nil, fmt.Errorf("failed to get vehicle: %w", err)
+	}
+
+	return &vehicle, nil
+}
+
+// ListVehicles lists a provider's vehicles, oldest first
+func (r *ProviderRepository) ListVehicles(ctx context.Context, providerID string) ([]*model.Vehicle, error) {
+	query := `
+		SELECT id, provider_id, plate, type, description, capacity, insurance_expires_at, created_at, updated_at
+		FROM provider_vehicles
+		WHERE provider_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, providerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vehicles: %w", err)
+	}
+	defer rows.Close()
+
+	var vehicles []*model.Vehicle
+	for rows.Next() {
+		var vehicle model.Vehicle
+		if err := rows.Scan(
+			&vehicle.ID,
+			&vehicle.ProviderID,
+			&vehicle.Plate,
+			&vehicle.Type,
+			&vehicle.Description,
+			&vehicle.Capacity,
+			&vehicle.InsuranceExpiresAt,
+			&vehicle.CreatedAt,
+			&vehicle.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan vehicle: %w", err)
+		}
+		vehicles = append(vehicles, &vehicle)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating vehicles rows: %w", err)
+	}
+
+	return vehicles, nil
+}
+
+// UpdateVehicle replaces a vehicle's plate, type, description, capacity and insurance
+// expiry
+func (r *ProviderRepository) UpdateVehicle(ctx context.Context, vehicle *model.Vehicle) error {
+	vehicle.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE provider_vehicles
+		SET plate = $3, type = $4, description = $5, capacity = $6, insurance_expires_at = $7, updated_at = $8
+		WHERE id = $1 AND provider_id = $2
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		vehicle.ID,
+		vehicle.ProviderID,
+		vehicle.Plate,
+		vehicle.Type,
+		vehicle.Description,
+		vehicle.Capacity,
+		vehicle.InsuranceExpiresAt,
+		vehicle.UpdatedAt,
+	).Scan(&vehicle.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrVehicleNotFound
+		}
+		if database.IsUniqueViolation(err, vehiclePlateConstraint) {
+			return ErrDuplicateVehicle
+		}
+		return fmt.Errorf("failed to update vehicle: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteVehicle deletes one of a provider's vehicles. A provider driving it this shift is
+// left without a shift vehicle.
+func (r *ProviderRepository) DeleteVehicle(ctx context.Context, providerID, vehicleID string) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM provider_vehicles WHERE id = $1 AND provider_id = $2`, vehicleID, providerID)
+		if err != nil {
+			return fmt.Errorf("failed to delete vehicle: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrVehicleNotFound
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE providers
+			SET active_vehicle_id = NULL, updated_at = $3
+			WHERE id = $1 AND active_vehicle_id = $2
+		`, providerID, vehicleID, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to clear provider's shift vehicle: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+
+	"github.com/order-api-microservices/services/provider/internal/repository"
+)
+
+// auditResourceProvider is the resource type of providers in the audit log
+const auditResourceProvider = "provider"
+
+// ProviderAuditSnapshotter records the state of the provider a call changes in the audit
+// log. Contact details and the address of the provider's location are left out, so the
+// log holds no personal data that erasure could not reach.
+type ProviderAuditSnapshotter struct {
+	repo *repository.ProviderRepository
+}
+
+// NewProviderAuditSnapshotter creates a new provider audit snapshotter
+func NewProviderAuditSnapshotter(repo *repository.ProviderRepository) *ProviderAuditSnapshotter {
+	return &ProviderAuditSnapshotter{
+		repo: repo,
+	}
+}
+
+// Resource names the provider a request refers to
+func (s *ProviderAuditSnapshotter) Resource(message interface{}) (string, string, bool) {
+	if m, ok := message.(interface{ GetProviderId() string }); ok && m.GetProviderId() != "" {
+		return auditResourceProvider, m.GetProviderId(), true
+	}
+	return "", "", false
+}
+
+// Snapshot loads a provider without their contact details
+func (s *ProviderAuditSnapshotter) Snapshot(ctx context.Context, resourceType, resourceID string) (interface{}, error) {
+	provider, err := s.repo.GetProviderByID(ctx, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.Email = ""
+	provider.Phone = ""
+	provider.Location.Address = ""
+	return provider, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// NotificationProviderOffline tells a provider they were marked unavailable because their
+// app stopped sending heartbeats
+const NotificationProviderOffline = "PROVIDER_OFFLINE"
+
+// heartbeatsPerTimeout is how many heartbeats apps are asked to send within the timeout,
+// so one or two lost to a bad connection do not take a provider offline
+const heartbeatsPerTimeout = 3
+
+// heartbeatInterval is how often apps are asked to send heartbeats for a timeout; 0 when
+// silent providers are never marked unavailable, so none are needed
+func heartbeatInterval(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return 0
+	}
+	interval := timeout / heartbeatsPerTimeout
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return interval
+}
+
+// PresenceConfig controls when silent providers are marked unavailable
+type PresenceConfig struct {
+	Timeout   time.Duration // How long an available provider's app may stay silent
+	Interval  time.Duration // How often silent providers are looked for
+	BatchSize int           // Most providers marked unavailable per query
+}
+
+// PresenceMonitor marks available providers unavailable once their app has sent neither
+// a heartbeat nor a location for the timeout, so drivers who went offline without
+// switching off are no longer matched. Each provider is marked by one statement that
+// skips rows other instances hold, so several instances can run it together.
+type PresenceMonitor struct {
+	repo               ProviderRepository
+	notificationClient NotificationClient
+	cfg                PresenceConfig
+}
+
+// NewPresenceMonitor creates a new presence monitor
+func NewPresenceMonitor(repo ProviderRepository, notificationClient NotificationClient, cfg PresenceConfig) *PresenceMonitor {
+	return &PresenceMonitor{
+		repo:               repo,
+		notificationClient: notificationClient,
+		cfg:                cfg,
+	}
+}
+
+// Run marks silent providers unavailable every interval until ctx is cancelled
+func (m *PresenceMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Sweep(ctx)
+		}
+	}
+}
+
+// Sweep marks every provider silent for longer than the timeout unavailable, and tells
+// them so
+func (m *PresenceMonitor) Sweep(ctx context.Context) {
+	cutoff := time.Now().Add(-m.cfg.Timeout)
+	for ctx.Err() == nil {
+		providerIDs, err := m.repo.MarkSilentProvidersUnavailable(ctx, cutoff, m.cfg.BatchSize)
+		if err != nil {
+			log.Printf("Failed to mark silent providers unavailable: %v", err)
+			return
+		}
+		if len(providerIDs) > 0 {
+			log.Printf("Marked %d providers unavailable after %s without a heartbeat", len(providerIDs), m.cfg.Timeout)
+		}
+
+		for _, providerID := range providerIDs {
+			err := m.notificationClient.SendNotification(ctx, providerID, NotificationProviderOffline, map[string]interface{}{
+				"reason": "no heartbeat received",
+			})
+			if err != nil {
+				log.Printf("Failed to tell provider %s they were marked unavailable: %v", providerID, err)
+			}
+		}
+
+		if len(providerIDs) < m.cfg.BatchSize {
+			return
+		}
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/order-api-microservices/services/provider/internal/model"
 	"github.com/order-api-microservices/services/provider/internal/repository"
 	pb "github.com/order-api-microservices/proto/provider"
@@ -21,42 +20,92 @@ type NotificationClient interface {
 	SendNotification(ctx context.Context, recipientID, notificationType string, payload interface{}) error
 }
 
+// ProviderRepository stores providers. It is implemented by repository.ProviderRepository
+// on Postgres and by memory.ProviderRepository for tests.
+type ProviderRepository interface {
+	GetProviderByID(ctx context.Context, providerID string) (*model.Provider, error)
+	UpdateProvider(ctx context.Context, provider *model.Provider) error
+	UpdateProviderLocation(ctx context.Context, providerID string, location model.Location) error
+	UpdateProviderAvailability(ctx context.Context, providerID string, isAvailable bool, vehicleID string) error
+	RecordHeartbeat(ctx context.Context, providerID string, at time.Time) (bool, error)
+	UpdateProviderQuality(ctx context.Context, providerID string, quality model.Quality) error
+	SuspendProviders(ctx context.Context, rules model.SuspensionRules, at, until, endedAfter time.Time, limit int) ([]*model.Suspension, error)
+	GetActiveSuspension(ctx context.Context, providerID string, at time.Time) (*model.Suspension, error)
+	MarkSilentProvidersUnavailable(ctx context.Context, cutoff time.Time, limit int) ([]string, error)
+	UpdateProviderServiceAreas(ctx context.Context, providerID string, serviceAreaIDs []string) error
+	UpdateProviderVehicleCapacity(ctx context.Context, providerID string, capacity model.VehicleCapacity) error
+	CreateVehicle(ctx context.Context, vehicle *model.Vehicle) error
+	GetVehicle(ctx context.Context, providerID, vehicleID string) (*model.Vehicle, error)
+	ListVehicles(ctx context.Context, providerID string) ([]*model.Vehicle, error)
+	UpdateVehicle(ctx context.Context, vehicle *model.Vehicle) error
+	DeleteVehicle(ctx context.Context, providerID, vehicleID string) error
+	AnonymizeProvider(ctx context.Context, providerID string, at time.Time) (int64, error)
+	CountAvailableProviders(ctx context.Context) (int64, map[string]int64, error)
+	FindNearbyProviders(ctx context.Context, latitude, longitude float64, radiusKm float64, serviceType, serviceAreaID string) ([]*model.Provider, error)
+	ListProviderIDsInAreas(ctx context.Context, serviceAreaIDs []string, afterID string, limit int) ([]string, error)
+}
+
+// PreferencesRepository stores providers' order preferences. It is implemented by
+// repository.PreferencesRepository on Postgres and by memory.PreferencesRepository for tests.
+type PreferencesRepository interface {
+	GetPreferences(ctx context.Context, providerID string) (*model.Preferences, error)
+	ListPreferences(ctx context.Context, providerIDs []string) (map[string]*model.Preferences, error)
+	UpsertPreferences(ctx context.Context, preferences *model.Preferences) error
+}
+
 // ProviderService handles the business logic for providers
 type ProviderService struct {
 	pb.UnimplementedProviderServiceServer
-	repo               *repository.ProviderRepository
+	repo               ProviderRepository
+	preferencesRepo    PreferencesRepository
 	notificationClient NotificationClient
+	heartbeatTimeout   time.Duration
 }
 
-// NewProviderService creates a new provider service
-func NewProviderService(repo *repository.ProviderRepository, notificationClient NotificationClient) *ProviderService {
+// NewProviderService creates a new provider service. Providers' apps are asked to send
+// heartbeats often enough that a provider is only marked unavailable after missing
+// several within heartbeatTimeout.
+func NewProviderService(repo ProviderRepository, preferencesRepo PreferencesRepository, notificationClient NotificationClient, heartbeatTimeout time.Duration) *ProviderService {
 	return &ProviderService{
 		repo:               repo,
+		preferencesRepo:    preferencesRepo,
 		notificationClient: notificationClient,
+		heartbeatTimeout:   heartbeatTimeout,
 	}
 }
 
 // FindProviders finds providers near a location with specified service type
 func (s *ProviderService) FindProviders(ctx context.Context, req *pb.FindProvidersRequest) (*pb.FindProvidersResponse, error) {
-	if req.Location == nil {
-		return nil, status.Errorf(codes.InvalidArgument, "location is required")
-	}
-
 	providers, err := s.repo.FindNearbyProviders(
 		ctx,
 		req.Location.Latitude,
 		req.Location.Longitude,
 		float64(req.Radius),
 		req.ServiceType,
+		req.ServiceAreaId,
 	)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to find providers: %v", err)
 	}
 
+	// Attach preferences so the order service's matcher can honor them
+	providerIDs := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		providerIDs = append(providerIDs, provider.ID)
+	}
+	preferences, err := s.preferencesRepo.ListPreferences(ctx, providerIDs)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get provider preferences: %v", err)
+	}
+
 	// Convert providers to protobuf format
 	protoProviders := make([]*pb.Provider, 0, len(providers))
 	for _, provider := range providers {
-		protoProviders = append(protoProviders, convertProviderToProto(provider))
+		protoProvider := convertProviderToProto(provider)
+		if p, ok := preferences[provider.ID]; ok {
+			protoProvider.Preferences = convertPreferencesToProto(p)
+		}
+		protoProviders = append(protoProviders, protoProvider)
 	}
 
 	return &pb.FindProvidersResponse{
@@ -68,10 +117,6 @@ func (s *ProviderService) FindProviders(ctx context.Context, req *pb.FindProvide
 
 // GetProvider gets a provider by ID
 func (s *ProviderService) GetProvider(ctx context.Context, req *pb.GetProviderRequest) (*pb.GetProviderResponse, error) {
-	if req.ProviderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "provider ID is required")
-	}
-
 	provider, err := s.repo.GetProviderByID(ctx, req.ProviderId)
 	if err != nil {
 		if errors.Is(err, repository.ErrProviderNotFound) {
@@ -80,8 +125,39 @@ func (s *ProviderService) GetProvider(ctx context.Context, req *pb.GetProviderRe
 		return nil, status.Errorf(codes.Internal, "failed to get provider: %v", err)
 	}
 
+	suspension, err := s.repo.GetActiveSuspension(ctx, req.ProviderId, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get provider suspension: %v", err)
+	}
+
+	var vehicle *model.Vehicle
+	if provider.ActiveVehicleID != "" {
+		vehicle, err = s.repo.GetVehicle(ctx, provider.ID, provider.ActiveVehicleID)
+		if err != nil && !errors.Is(err, repository.ErrVehicleNotFound) {
+			return nil, status.Errorf(codes.Internal, "failed to get provider vehicle: %v", err)
+		}
+	}
+
+	// The shift vehicle's capacity stands in for the one the provider gave
+	if vehicle != nil {
+		provider.VehicleCapacity = vehicle.Capacity
+	}
+	protoProvider := convertProviderToProto(provider)
+	if vehicle != nil {
+		protoProvider.ActiveVehicle = convertVehicleToProto(vehicle)
+	}
+	if suspension != nil {
+		protoProvider.Suspension = &pb.ProviderSuspension{
+			Id:          suspension.ID,
+			Rule:        string(suspension.Rule),
+			Reason:      suspension.Reason,
+			SuspendedAt: timestamppb.New(suspension.SuspendedAt),
+			ExpiresAt:   timestamppb.New(suspension.ExpiresAt),
+		}
+	}
+
 	return &pb.GetProviderResponse{
-		Provider: convertProviderToProto(provider),
+		Provider: protoProvider,
 		Success:  true,
 		Message:  "Provider retrieved successfully",
 	}, nil
@@ -89,13 +165,6 @@ func (s *ProviderService) GetProvider(ctx context.Context, req *pb.GetProviderRe
 
 // UpdateLocation updates a provider's location
 func (s *ProviderService) UpdateLocation(ctx context.Context, req *pb.UpdateLocationRequest) (*pb.UpdateLocationResponse, error) {
-	if req.ProviderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "provider ID is required")
-	}
-	if req.Location == nil {
-		return nil, status.Errorf(codes.InvalidArgument, "location is required")
-	}
-
 	location := model.Location{
 		Latitude:  req.Location.Latitude,
 		Longitude: req.Location.Longitude,
@@ -115,10 +184,6 @@ func (s *ProviderService) UpdateLocation(ctx context.Context, req *pb.UpdateLoca
 
 // NotifyProvider sends a notification to a provider
 func (s *ProviderService) NotifyProvider(ctx context.Context, req *pb.NotifyProviderRequest) (*pb.NotifyProviderResponse, error) {
-	if req.ProviderId == "" || req.OrderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "provider ID and order ID are required")
-	}
-
 	// Verify the provider exists
 	_, err := s.repo.GetProviderByID(ctx, req.ProviderId)
 	if err != nil {
@@ -158,13 +223,26 @@ func (s *ProviderService) NotifyProvider(ctx context.Context, req *pb.NotifyProv
 	}, nil
 }
 
-// UpdateAvailability updates a provider's availability status
+// UpdateAvailability updates a provider's availability status. A provider going
+// available may pick one of their registered vehicles for the shift, which must be
+// insured; it is used for their orders until they go unavailable.
 func (s *ProviderService) UpdateAvailability(ctx context.Context, req *pb.UpdateAvailabilityRequest) (*pb.UpdateAvailabilityResponse, error) {
-	if req.ProviderId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "provider ID is required")
+	vehicleID := ""
+	if req.IsAvailable && req.VehicleId != "" {
+		vehicle, err := s.repo.GetVehicle(ctx, req.ProviderId, req.VehicleId)
+		if err != nil {
+			if errors.Is(err, repository.ErrVehicleNotFound) {
+				return nil, status.Errorf(codes.NotFound, "vehicle not found")
+			}
+			return nil, status.Errorf(codes.Internal, "failed to get vehicle: %v", err)
+		}
+		if !vehicle.Insured(time.Now()) {
+			return nil, status.Errorf(codes.FailedPrecondition, "vehicle's insurance expired at %s", vehicle.InsuranceExpiresAt.Format(time.RFC3339))
+		}
+		vehicleID = vehicle.ID
 	}
 
-	err := s.repo.UpdateProviderAvailability(ctx, req.ProviderId, req.IsAvailable)
+	err := s.repo.UpdateProviderAvailability(ctx, req.ProviderId, req.IsAvailable, vehicleID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to update availability: %v", err)
 	}
@@ -175,12 +253,54 @@ func (s *ProviderService) UpdateAvailability(ctx context.Context, req *pb.Update
 	}, nil
 }
 
-// UpdateProfile updates a provider's profile information
-func (s *ProviderService) UpdateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.UpdateProfileResponse, error) {
-	if req.ProviderId == "" || req.Profile == nil {
-		return nil, status.Errorf(codes.InvalidArgument, "provider ID and profile are required")
+// Heartbeat records that a provider's app is online and tells it how often to send
+// heartbeats, and whether the provider is still available
+func (s *ProviderService) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	isAvailable, err := s.repo.RecordHeartbeat(ctx, req.ProviderId, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrProviderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "provider not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to record heartbeat: %v", err)
+	}
+
+	return &pb.HeartbeatResponse{
+		IsAvailable:     isAvailable,
+		IntervalSeconds: int32(heartbeatInterval(s.heartbeatTimeout).Seconds()),
+		Success:         true,
+		Message:         "Heartbeat recorded",
+	}, nil
+}
+
+// UpdateQuality stores a provider's quality as worked out by the order service
+func (s *ProviderService) UpdateQuality(ctx context.Context, req *pb.UpdateQualityRequest) (*pb.UpdateQualityResponse, error) {
+	q := req.Quality
+	quality := model.Quality{
+		AcceptanceRate: q.AcceptanceRate,
+		CompletionRate: q.CompletionRate,
+		OnTimeRate:     q.OnTimeRate,
+		OffersAnswered: int(q.OffersAnswered),
+		OrdersFinished: int(q.OrdersFinished),
+		PickupsTimed:   int(q.PickupsTimed),
+		Incidents:      int(q.Incidents),
+		UpdatedAt:      time.Now(),
+	}
+
+	if err := s.repo.UpdateProviderQuality(ctx, req.ProviderId, quality); err != nil {
+		if errors.Is(err, repository.ErrProviderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "provider not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update provider quality: %v", err)
 	}
 
+	return &pb.UpdateQualityResponse{
+		Success: true,
+		Message: "Provider quality updated",
+	}, nil
+}
+
+// UpdateProfile updates a provider's profile information
+func (s *ProviderService) UpdateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.UpdateProfileResponse, error) {
 	// Get current provider
 	provider, err := s.repo.GetProviderByID(ctx, req.ProviderId)
 	if err != nil {
@@ -198,6 +318,7 @@ func (s *ProviderService) UpdateProfile(ctx context.Context, req *pb.UpdateProfi
 		provider.ServiceTypes = req.Profile.ServiceTypes
 	}
 	provider.ProfileImage = req.Profile.ProfileImage
+	provider.MaxConcurrentOrders = int(req.Profile.MaxConcurrentOrders)
 
 	// Convert metadata from protobuf to model
 	if req.Profile.Metadata != nil {
@@ -234,6 +355,180 @@ func (s *ProviderService) ListOrders(ctx context.Context, req *pb.ListOrdersRequ
 	}, nil
 }
 
+// GetPreferences gets the preferences that filter the orders a provider is offered
+func (s *ProviderService) GetPreferences(ctx context.Context, req *pb.GetPreferencesRequest) (*pb.PreferencesResponse, error) {
+	if _, err := s.repo.GetProviderByID(ctx, req.ProviderId); err != nil {
+		if errors.Is(err, repository.ErrProviderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "provider not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get provider: %v", err)
+	}
+
+	preferences, err := s.preferencesRepo.GetPreferences(ctx, req.ProviderId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get provider preferences: %v", err)
+	}
+
+	return &pb.PreferencesResponse{
+		Preferences: convertPreferencesToProto(preferences),
+		Success:     true,
+		Message:     "Provider preferences retrieved successfully",
+	}, nil
+}
+
+// UpdatePreferences replaces a provider's preferences. The order types they want must be
+// among the service types they offer.
+func (s *ProviderService) UpdatePreferences(ctx context.Context, req *pb.UpdatePreferencesRequest) (*pb.PreferencesResponse, error) {
+	provider, err := s.repo.GetProviderByID(ctx, req.ProviderId)
+	if err != nil {
+		if errors.Is(err, repository.ErrProviderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "provider not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get provider: %v", err)
+	}
+
+	offered := make(map[string]bool, len(provider.ServiceTypes))
+	for _, serviceType := range provider.ServiceTypes {
+		offered[serviceType] = true
+	}
+	orderTypes := make([]string, 0, len(req.Preferences.OrderTypes))
+	for _, orderType := range req.Preferences.OrderTypes {
+		if !offered[orderType] {
+			return nil, status.Errorf(codes.InvalidArgument, "provider does not offer order type %q", orderType)
+		}
+		orderTypes = append(orderTypes, orderType)
+	}
+
+	preferences := &model.Preferences{
+		ProviderID:         req.ProviderId,
+		AutoAcceptRadiusKm: req.Preferences.AutoAcceptRadiusKm,
+		OrderTypes:         orderTypes,
+		MinFare:            req.Preferences.MinFare,
+		UpdatedAt:          time.Now(),
+	}
+
+	if err := s.preferencesRepo.UpsertPreferences(ctx, preferences); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update provider preferences: %v", err)
+	}
+
+	return &pb.PreferencesResponse{
+		Preferences: convertPreferencesToProto(preferences),
+		Success:     true,
+		Message:     "Provider preferences updated successfully",
+	}, nil
+}
+
+// UpdateServiceAreas replaces the service areas a provider is registered to work in.
+// The areas belong to the order service, which only matches a provider to orders picked
+// up in one of them.
+func (s *ProviderService) UpdateServiceAreas(ctx context.Context, req *pb.UpdateServiceAreasRequest) (*pb.UpdateServiceAreasResponse, error) {
+	seen := make(map[string]bool, len(req.ServiceAreaIds))
+	serviceAreaIDs := make([]string, 0, len(req.ServiceAreaIds))
+	for _, id := range req.ServiceAreaIds {
+		if !seen[id] {
+			seen[id] = true
+			serviceAreaIDs = append(serviceAreaIDs, id)
+		}
+	}
+
+	if err := s.repo.UpdateProviderServiceAreas(ctx, req.ProviderId, serviceAreaIDs); err != nil {
+		if errors.Is(err, repository.ErrProviderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "provider not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update provider service areas: %v", err)
+	}
+
+	return &pb.UpdateServiceAreasResponse{
+		ServiceAreaIds: serviceAreaIDs,
+		Success:        true,
+		Message:        "Provider service areas updated successfully",
+	}, nil
+}
+
+// UpdateVehicleCapacity replaces what a provider's vehicle can carry. Package deliveries
+// are only offered to providers whose vehicle can carry the package.
+func (s *ProviderService) UpdateVehicleCapacity(ctx context.Context, req *pb.UpdateVehicleCapacityRequest) (*pb.UpdateVehicleCapacityResponse, error) {
+	capacity := model.VehicleCapacity{
+		MaxWeightGrams: int(req.VehicleCapacity.MaxWeightGrams),
+		LengthCm:       int(req.VehicleCapacity.LengthCm),
+		WidthCm:        int(req.VehicleCapacity.WidthCm),
+		HeightCm:       int(req.VehicleCapacity.HeightCm),
+	}
+
+	if err := s.repo.UpdateProviderVehicleCapacity(ctx, req.ProviderId, capacity); err != nil {
+		if errors.Is(err, repository.ErrProviderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "provider not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update provider vehicle capacity: %v", err)
+	}
+
+	return &pb.UpdateVehicleCapacityResponse{
+		VehicleCapacity: convertVehicleCapacityToProto(capacity),
+		Success:         true,
+		Message:         "Provider vehicle capacity updated successfully",
+	}, nil
+}
+
+// ForgetProvider erases a provider's personal data for a right-to-be-forgotten request.
+// The provider keeps their ID so orders and payouts still add up, but is never matched
+// again. Erasing an erased provider succeeds and changes nothing more.
+func (s *ProviderService) ForgetProvider(ctx context.Context, req *pb.ForgetProviderRequest) (*pb.ForgetProviderResponse, error) {
+	locationsDeleted, err := s.repo.AnonymizeProvider(ctx, req.ProviderId, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrProviderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "provider not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to forget provider: %v", err)
+	}
+
+	return &pb.ForgetProviderResponse{
+		LocationsDeleted: locationsDeleted,
+		Success:          true,
+		Message:          "Provider's personal data erased",
+	}, nil
+}
+
+// GetAvailabilityCounts counts the providers currently available for orders, in total
+// and per service area, for the operations dashboard
+func (s *ProviderService) GetAvailabilityCounts(ctx context.Context, req *pb.GetAvailabilityCountsRequest) (*pb.GetAvailabilityCountsResponse, error) {
+	total, byArea, err := s.repo.CountAvailableProviders(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to count available providers: %v", err)
+	}
+
+	resp := &pb.GetAvailabilityCountsResponse{
+		Available:              total,
+		AvailableByServiceArea: make(map[string]int64, len(byArea)),
+	}
+	for areaID, count := range byArea {
+		if areaID == "" {
+			resp.AvailableWithoutServiceArea = count
+			continue
+		}
+		resp.AvailableByServiceArea[areaID] = count
+	}
+
+	return resp, nil
+}
+
+// ListProviderIDs pages through the IDs of the providers registered in any of some
+// service areas, so the notification service can broadcast to a city's providers
+func (s *ProviderService) ListProviderIDs(ctx context.Context, req *pb.ListProviderIDsRequest) (*pb.ListProviderIDsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 100
+	}
+
+	providerIDs, err := s.repo.ListProviderIDsInAreas(ctx, req.ServiceAreaIds, req.AfterId, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list providers: %v", err)
+	}
+
+	return &pb.ListProviderIDsResponse{
+		ProviderIds: providerIDs,
+	}, nil
+}
+
 // Helper functions
 
 // Convert provider model to protobuf
@@ -243,7 +538,7 @@ func convertProviderToProto(provider *model.Provider) *pb.Provider {
 		metadata[k] = v
 	}
 
-	return &pb.Provider{
+	protoProvider := &pb.Provider{
 		Id:           provider.ID,
 		Name:         provider.Name,
 		Rating:       float32(provider.Rating),
@@ -253,14 +548,59 @@ func convertProviderToProto(provider *model.Provider) *pb.Provider {
 			Longitude: provider.Location.Longitude,
 			Address:   provider.Location.Address,
 		},
-		IsAvailable:  provider.IsAvailable,
-		Email:        provider.Email,
-		Phone:        provider.Phone,
-		ProfileImage: provider.ProfileImage,
-		Metadata:     metadata,
-		CreatedAt:    timestamppb.New(provider.CreatedAt),
-		UpdatedAt:    timestamppb.New(provider.UpdatedAt),
+		IsAvailable:         provider.IsAvailable,
+		MaxConcurrentOrders: int32(provider.MaxConcurrentOrders),
+		ServiceAreaIds:      provider.ServiceAreaIDs,
+		Email:               provider.Email,
+		Phone:               provider.Phone,
+		ProfileImage:        provider.ProfileImage,
+		Metadata:            metadata,
+		CreatedAt:           timestamppb.New(provider.CreatedAt),
+		UpdatedAt:           timestamppb.New(provider.UpdatedAt),
+	}
+	if provider.LastHeartbeatAt != nil {
+		protoProvider.LastHeartbeatAt = timestamppb.New(*provider.LastHeartbeatAt)
+	}
+	if !provider.Quality.UpdatedAt.IsZero() {
+		protoProvider.Quality = &pb.ProviderQuality{
+			AcceptanceRate: provider.Quality.AcceptanceRate,
+			CompletionRate: provider.Quality.CompletionRate,
+			OnTimeRate:     provider.Quality.OnTimeRate,
+			OffersAnswered: int32(provider.Quality.OffersAnswered),
+			OrdersFinished: int32(provider.Quality.OrdersFinished),
+			PickupsTimed:   int32(provider.Quality.PickupsTimed),
+			Incidents:      int32(provider.Quality.Incidents),
+			UpdatedAt:      timestamppb.New(provider.Quality.UpdatedAt),
+		}
+	}
+	if provider.VehicleCapacity != (model.VehicleCapacity{}) {
+		protoProvider.VehicleCapacity = convertVehicleCapacityToProto(provider.VehicleCapacity)
+	}
+
+	return protoProvider
+}
+
+// Convert vehicle capacity model to protobuf
+func convertVehicleCapacityToProto(capacity model.VehicleCapacity) *pb.VehicleCapacity {
+	return &pb.VehicleCapacity{
+		MaxWeightGrams: int32(capacity.MaxWeightGrams),
+		LengthCm:       int32(capacity.LengthCm),
+		WidthCm:        int32(capacity.WidthCm),
+		HeightCm:       int32(capacity.HeightCm),
+	}
+}
+
+// Convert provider preferences model to protobuf
+func convertPreferencesToProto(preferences *model.Preferences) *pb.ProviderPreferences {
+	protoPreferences := &pb.ProviderPreferences{
+		AutoAcceptRadiusKm: preferences.AutoAcceptRadiusKm,
+		OrderTypes:         preferences.OrderTypes,
+		MinFare:            preferences.MinFare,
+	}
+	if !preferences.UpdatedAt.IsZero() {
+		protoPreferences.UpdatedAt = timestamppb.New(preferences.UpdatedAt)
 	}
+	return protoPreferences
 }
 
 // Helper to convert availability boolean to string
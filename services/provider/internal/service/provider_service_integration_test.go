@@ -0,0 +1,81 @@
+//go:build integration
+
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/order-api-microservices/internal/testharness"
+	pb "github.com/order-api-microservices/proto/provider"
+	"github.com/order-api-microservices/services/provider/internal/repository"
+	"github.com/order-api-microservices/services/provider/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// discardNotifications drops every notification
+type discardNotifications struct{}
+
+func (discardNotifications) SendNotification(ctx context.Context, recipientID, notificationType string, payload interface{}) error {
+	return nil
+}
+
+func TestProviderServiceEndToEnd(t *testing.T) {
+	ctx := context.Background()
+	db := testharness.Postgres(t).Database(t, "provider")
+
+	s := service.NewProviderService(repository.NewProviderRepository(db, nil), repository.NewPreferencesRepository(db), discardNotifications{}, time.Minute)
+	conn := testharness.ServeGRPC(t, func(server *grpc.Server) {
+		pb.RegisterProviderServiceServer(server, s)
+	})
+	client := pb.NewProviderServiceClient(conn)
+
+	providerID := testharness.InsertProvider(t, db, testharness.ProviderFixture{
+		Name: "Budi", Lat: -6.191, Lon: 106.8166, ServiceTypes: []string{"RIDE", "FOOD_DELIVERY"},
+	})
+
+	got, err := client.GetProvider(ctx, &pb.GetProviderRequest{ProviderId: providerID})
+	if err != nil {
+		t.Fatalf("GetProvider: %v", err)
+	}
+	if got.Provider.Name != "Budi" || got.Provider.IsAvailable {
+		t.Errorf("got provider %q available=%v, want an unavailable Budi", got.Provider.Name, got.Provider.IsAvailable)
+	}
+
+	search := &pb.FindProvidersRequest{
+		Location:    &pb.Location{Latitude: -6.2, Longitude: 106.8166},
+		Radius:      5,
+		ServiceType: "FOOD_DELIVERY",
+	}
+	found, err := client.FindProviders(ctx, search)
+	if err != nil {
+		t.Fatalf("FindProviders: %v", err)
+	}
+	if len(found.Providers) != 0 {
+		t.Fatalf("found %d providers before going available, want 0", len(found.Providers))
+	}
+
+	if _, err := client.UpdateAvailability(ctx, &pb.UpdateAvailabilityRequest{ProviderId: providerID, IsAvailable: true}); err != nil {
+		t.Fatalf("UpdateAvailability: %v", err)
+	}
+
+	found, err = client.FindProviders(ctx, search)
+	if err != nil {
+		t.Fatalf("FindProviders: %v", err)
+	}
+	if len(found.Providers) != 1 || found.Providers[0].Id != providerID {
+		t.Fatalf("got providers %+v, want only %s", found.Providers, providerID)
+	}
+	if d := found.Providers[0].Distance; d < 0.5 || d > 1.5 {
+		t.Errorf("distance = %.2f km, want about 1 km", d)
+	}
+
+	_, err = client.GetProvider(ctx, &pb.GetProviderRequest{ProviderId: uuid.New().String()})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetProvider of a missing provider: got %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/order-api-microservices/services/provider/internal/model"
+)
+
+// NotificationProviderSuspended tells a provider they were suspended and why
+const NotificationProviderSuspended = "PROVIDER_SUSPENDED"
+
+// SuspensionConfig controls when and for how long providers are suspended
+type SuspensionConfig struct {
+	Rules     model.SuspensionRules
+	Duration  time.Duration // How long a suspension lasts
+	Cooldown  time.Duration // How long after a suspension ends before the provider can be suspended again
+	Interval  time.Duration // How often providers are checked against the rules
+	BatchSize int           // Most providers suspended per transaction
+}
+
+// SuspensionEngine suspends providers who fall below the rules' thresholds on rating,
+// completion rate or SOS incidents, so they are no longer matched until the suspension
+// expires. Once it does, the provider has the cooldown to improve before the rules apply
+// to them again. Each batch locks the providers it suspends and skips those other
+// instances hold, so several instances can run it together.
+type SuspensionEngine struct {
+	repo               ProviderRepository
+	notificationClient NotificationClient
+	cfg                SuspensionConfig
+}
+
+// NewSuspensionEngine creates a new suspension engine
+func NewSuspensionEngine(repo ProviderRepository, notificationClient NotificationClient, cfg SuspensionConfig) *SuspensionEngine {
+	return &SuspensionEngine{
+		repo:               repo,
+		notificationClient: notificationClient,
+		cfg:                cfg,
+	}
+}
+
+// Run checks providers against the rules every interval until ctx is cancelled
+func (e *SuspensionEngine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.Sweep(ctx)
+		}
+	}
+}
+
+// Sweep suspends every provider who breaks a rule, and tells them so
+func (e *SuspensionEngine) Sweep(ctx context.Context) {
+	now := time.Now()
+	for ctx.Err() == nil {
+		suspensions, err := e.repo.SuspendProviders(ctx, e.cfg.Rules, now, now.Add(e.cfg.Duration), now.Add(-e.cfg.Cooldown), e.cfg.BatchSize)
+		if err != nil {
+			log.Printf("Failed to suspend providers: %v", err)
+			return
+		}
+
+		for _, suspension := range suspensions {
+			log.Printf("Suspended provider %s until %s: %s", suspension.ProviderID, suspension.ExpiresAt.Format(time.RFC3339), suspension.Reason)
+			err := e.notificationClient.SendNotification(ctx, suspension.ProviderID, NotificationProviderSuspended, map[string]interface{}{
+				"rule":       string(suspension.Rule),
+				"reason":     suspension.Reason,
+				"expires_at": suspension.ExpiresAt.Format(time.RFC3339),
+			})
+			if err != nil {
+				log.Printf("Failed to tell provider %s they were suspended: %v", suspension.ProviderID, err)
+			}
+		}
+
+		if len(suspensions) < e.cfg.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/order-api-microservices/proto/provider"
+	"github.com/order-api-microservices/services/provider/internal/model"
+	"github.com/order-api-microservices/services/provider/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CreateVehicle registers a vehicle for a provider, which they can then pick for a shift
+func (s *ProviderService) CreateVehicle(ctx context.Context, req *pb.CreateVehicleRequest) (*pb.VehicleResponse, error) {
+	if _, err := s.repo.GetProviderByID(ctx, req.ProviderId); err != nil {
+		if errors.Is(err, repository.ErrProviderNotFound) {
+			return nil, status.Errorf(codes.NotFound, "provider not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get provider: %v", err)
+	}
+
+	vehicle, err := vehicleFromDetails(req.ProviderId, req.Vehicle)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateVehicle(ctx, vehicle); err != nil {
+		if errors.Is(err, repository.ErrDuplicateVehicle) {
+			return nil, status.Errorf(codes.AlreadyExists, "provider already has a vehicle with plate %s", vehicle.Plate)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create vehicle: %v", err)
+	}
+
+	return &pb.VehicleResponse{
+		Vehicle: convertVehicleToProto(vehicle),
+		Success: true,
+		Message: "Vehicle created successfully",
+	}, nil
+}
+
+// ListVehicles lists a provider's vehicles, oldest first
+func (s *ProviderService) ListVehicles(ctx context.Context, req *pb.ListVehiclesRequest) (*pb.ListVehiclesResponse, error) {
+	vehicles, err := s.repo.ListVehicles(ctx, req.ProviderId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list vehicles: %v", err)
+	}
+
+	protoVehicles := make([]*pb.Vehicle, 0, len(vehicles))
+	for _, vehicle := range vehicles {
+		protoVehicles = append(protoVehicles, convertVehicleToProto(vehicle))
+	}
+
+	return &pb.ListVehiclesResponse{
+		Vehicles: protoVehicles,
+		Success:  true,
+		Message:  "Vehicles retrieved successfully",
+	}, nil
+}
+
+// UpdateVehicle replaces a vehicle's details, such as when its insurance is renewed. A
+// provider on shift in the vehicle keeps driving it.
+func (s *ProviderService) UpdateVehicle(ctx context.Context, req *pb.UpdateVehicleRequest) (*pb.VehicleResponse, error) {
+	vehicle, err := vehicleFromDetails(req.ProviderId, req.Vehicle)
+	if err != nil {
+		return nil, err
+	}
+	vehicle.ID = req.VehicleId
+
+	if err := s.repo.UpdateVehicle(ctx, vehicle); err != nil {
+		if errors.Is(err, repository.ErrVehicleNotFound) {
+			return nil, status.Errorf(codes.NotFound, "vehicle not found")
+		}
+		if errors.Is(err, repository.ErrDuplicateVehicle) {
+			return nil, status.Errorf(codes.AlreadyExists, "provider already has a vehicle with plate %s", vehicle.Plate)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update vehicle: %v", err)
+	}
+
+	return &pb.VehicleResponse{
+		Vehicle: convertVehicleToProto(vehicle),
+		Success: true,
+		Message: "Vehicle updated successfully",
+	}, nil
+}
+
+// DeleteVehicle deletes one of a provider's vehicles. A provider on shift in it stays
+// available without a shift vehicle.
+func (s *ProviderService) DeleteVehicle(ctx context.Context, req *pb.DeleteVehicleRequest) (*pb.DeleteVehicleResponse, error) {
+	if err := s.repo.DeleteVehicle(ctx, req.ProviderId, req.VehicleId); err != nil {
+		if errors.Is(err, repository.ErrVehicleNotFound) {
+			return nil, status.Errorf(codes.NotFound, "vehicle not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete vehicle: %v", err)
+	}
+
+	return &pb.DeleteVehicleResponse{
+		Success: true,
+		Message: "Vehicle deleted successfully",
+	}, nil
+}
+
+// vehicleFromDetails builds a provider's vehicle from the details they gave. Vehicles
+// whose insurance has already expired are refused.
+func vehicleFromDetails(providerID string, details *pb.VehicleDetails) (*model.Vehicle, error) {
+	if !model.ValidVehicleType(details.Type) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown vehicle type %q", details.Type)
+	}
+
+	vehicle := &model.Vehicle{
+		ProviderID:  providerID,
+		Plate:       details.Plate,
+		Type:        model.VehicleType(details.Type),
+		Description: details.Description,
+		Capacity: model.VehicleCapacity{
+			MaxWeightGrams: int(details.Capacity.MaxWeightGrams),
+			LengthCm:       int(details.Capacity.LengthCm),
+			WidthCm:        int(details.Capacity.WidthCm),
+			HeightCm:       int(details.Capacity.HeightCm),
+		},
+		InsuranceExpiresAt: details.InsuranceExpiresAt.AsTime(),
+	}
+	if !vehicle.Insured(time.Now()) {
+		return nil, status.Errorf(codes.InvalidArgument, "vehicle's insurance has already expired")
+	}
+
+	return vehicle, nil
+}
+
+// Convert vehicle model to protobuf
+func convertVehicleToProto(vehicle *model.Vehicle) *pb.Vehicle {
+	return &pb.Vehicle{
+		Id:                 vehicle.ID,
+		ProviderId:         vehicle.ProviderID,
+		Plate:              vehicle.Plate,
+		Type:               string(vehicle.Type),
+		Description:        vehicle.Description,
+		Capacity:           convertVehicleCapacityToProto(vehicle.Capacity),
+		InsuranceExpiresAt: timestamppb.New(vehicle.InsuranceExpiresAt),
+		CreatedAt:          timestamppb.New(vehicle.CreatedAt),
+		UpdatedAt:          timestamppb.New(vehicle.UpdatedAt),
+	}
+}
@@ -2,18 +2,79 @@
 CREATE TABLE IF NOT EXISTS providers (
     id VARCHAR(36) PRIMARY KEY,
     name VARCHAR(100) NOT NULL,
-    email VARCHAR(100) NOT NULL,
-    phone VARCHAR(20) NOT NULL,
+    email TEXT NOT NULL,
+    phone TEXT NOT NULL,
     rating FLOAT NOT NULL DEFAULT 0,
     service_types JSONB NOT NULL,
     location JSONB NOT NULL,
     is_available BOOLEAN NOT NULL DEFAULT false,
+    max_concurrent_orders INT NOT NULL DEFAULT 0,
+    service_area_ids TEXT[] NOT NULL DEFAULT '{}',
     profile_image VARCHAR(255),
     metadata JSONB,
     created_at TIMESTAMP NOT NULL,
     updated_at TIMESTAMP NOT NULL
 );
 
+-- Cap on a provider's active orders of any type; 0 leaves only the order service's per-type limits
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS max_concurrent_orders INT NOT NULL DEFAULT 0;
+
+-- Service areas (owned by the order service) a provider is registered to work in
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS service_area_ids TEXT[] NOT NULL DEFAULT '{}';
+
+-- Set when a provider's personal data is erased; erased providers are never matched again
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP;
+
+-- When the provider's app last sent a heartbeat or location; available providers silent
+-- for too long are marked unavailable
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS last_heartbeat_at TIMESTAMP;
+
+-- How reliably the provider serves orders, as last worked out by the order service
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS quality JSONB;
+
+-- What the provider's vehicle can carry, matched against package deliveries
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS vehicle_capacity JSONB;
+
+-- When the provider's latest suspension ends; suspended providers are not matched
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMP;
+
+-- Create provider_suspensions table; each automatic suspension of a provider, with the
+-- rule they broke and when it ends
+CREATE TABLE IF NOT EXISTS provider_suspensions (
+    id VARCHAR(36) PRIMARY KEY,
+    provider_id VARCHAR(36) NOT NULL,
+    rule VARCHAR(30) NOT NULL,
+    reason TEXT NOT NULL,
+    suspended_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (provider_id) REFERENCES providers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_suspensions_provider ON provider_suspensions(provider_id, expires_at);
+
+-- Create provider_vehicles table; the vehicles a provider has registered. A provider's
+-- plates are unique among their vehicles.
+CREATE TABLE IF NOT EXISTS provider_vehicles (
+    id VARCHAR(36) PRIMARY KEY,
+    provider_id VARCHAR(36) NOT NULL,
+    plate VARCHAR(20) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    capacity JSONB NOT NULL,
+    insurance_expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (provider_id) REFERENCES providers(id) ON DELETE CASCADE,
+    CONSTRAINT provider_vehicles_plate_key UNIQUE (provider_id, plate)
+);
+
+-- The vehicle the provider picked for their current shift; cleared when they go unavailable
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS active_vehicle_id VARCHAR(36);
+
+-- Email and phone are stored encrypted, which needs more room than the plaintext
+ALTER TABLE providers ALTER COLUMN email TYPE TEXT;
+ALTER TABLE providers ALTER COLUMN phone TYPE TEXT;
+
 -- Create provider_locations table for tracking
 CREATE TABLE IF NOT EXISTS provider_locations (
     id VARCHAR(36) PRIMARY KEY,
@@ -25,9 +86,21 @@ CREATE TABLE IF NOT EXISTS provider_locations (
     FOREIGN KEY (provider_id) REFERENCES providers(id) ON DELETE CASCADE
 );
 
+-- Create provider_preferences table; providers without a row are offered every order they can serve
+CREATE TABLE IF NOT EXISTS provider_preferences (
+    provider_id VARCHAR(36) PRIMARY KEY,
+    auto_accept_radius_km DOUBLE PRECISION NOT NULL DEFAULT 0,
+    order_types TEXT[] NOT NULL DEFAULT '{}',
+    min_fare BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (provider_id) REFERENCES providers(id) ON DELETE CASCADE
+);
+
 -- Create indexes for faster queries
 CREATE INDEX IF NOT EXISTS idx_providers_service_types ON providers USING GIN(service_types);
 CREATE INDEX IF NOT EXISTS idx_providers_is_available ON providers(is_available);
+CREATE INDEX IF NOT EXISTS idx_providers_available_heartbeat ON providers((COALESCE(last_heartbeat_at, updated_at))) WHERE is_available;
+CREATE INDEX IF NOT EXISTS idx_providers_service_area_ids ON providers USING GIN(service_area_ids);
 CREATE INDEX IF NOT EXISTS idx_provider_locations_provider_id ON provider_locations(provider_id);
 CREATE INDEX IF NOT EXISTS idx_provider_locations_timestamp ON provider_locations(timestamp);
 
@@ -61,6 +134,48 @@ BEGIN
 END
 $$;
 
+-- Create audit_log table; an append-only record of every mutating call. Snapshots are JSON,
+-- not JSONB, so hash-chained entries read back byte for byte as they were hashed.
+CREATE TABLE IF NOT EXISTS audit_log (
+    seq BIGSERIAL PRIMARY KEY,
+    service VARCHAR(50) NOT NULL,
+    method VARCHAR(200) NOT NULL,
+    actor_id VARCHAR(100) NOT NULL DEFAULT '',
+    source_ip VARCHAR(100) NOT NULL DEFAULT '',
+    resource_type VARCHAR(50) NOT NULL DEFAULT '',
+    resource_id VARCHAR(100) NOT NULL DEFAULT '',
+    before JSON,
+    after JSON,
+    diff JSON,
+    status_code VARCHAR(30) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    prev_hash VARCHAR(64),
+    hash VARCHAR(64)
+);
+
+-- The admin who made a call as the actor, and the support session it was made in
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS impersonated_by VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS support_session_id VARCHAR(36) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_support_session_id ON audit_log(support_session_id, seq) WHERE support_session_id <> '';
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log(resource_type, resource_id, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+
+-- Reject any change to recorded entries
+CREATE OR REPLACE FUNCTION reject_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trig_audit_log_append_only ON audit_log;
+CREATE TRIGGER trig_audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_log_change();
+
 -- Insert sample data
 INSERT INTO providers (id, name, email, phone, rating, service_types, location, is_available, profile_image, metadata, created_at, updated_at)
 VALUES 